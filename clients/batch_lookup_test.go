@@ -0,0 +1,204 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseInFilter reverses an In filter
+func parseInFilter(filter string) []string {
+	list := strings.TrimSuffix(strings.TrimPrefix(filter, "in.("), ")")
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		values = append(values, strings.Trim(value, `"`))
+	}
+	return values
+}
+
+// chunkStore is a PostgREST stand-in for the chunk, template_slots and chunk_tags
+// queries made by template and tag lookups
+type chunkStore struct {
+	chunks        []models.ChunkRecord
+	templateSlots []templateSlotRelation
+	chunkTags     [][2]string // chunk_id, tag_chunk_id
+}
+
+func (s *chunkStore) serve(t *testing.T, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		query := r.URL.Query()
+		in := func(key string) map[string]bool {
+			set := make(map[string]bool)
+			for _, value := range parseInFilter(query.Get(key)) {
+				set[value] = true
+			}
+			return set
+		}
+
+		var body interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/chunks") && query.Get("content") != "":
+			var found []models.ChunkRecord
+			for _, chunk := range s.chunks {
+				if "eq."+chunk.Content == query.Get("content") {
+					found = append(found, chunk)
+				}
+			}
+			body = found
+		case strings.HasSuffix(r.URL.Path, "/chunks") && query.Get("id") != "":
+			ids := in("id")
+			found := []models.ChunkRecord{}
+			for _, chunk := range s.chunks {
+				if ids[chunk.ID] {
+					found = append(found, chunk)
+				}
+			}
+			body = found
+		case strings.HasSuffix(r.URL.Path, "/chunks") && query.Get("template_chunk_id") != "":
+			templates := in("template_chunk_id")
+			found := []models.ChunkRecord{}
+			for _, chunk := range s.chunks {
+				if chunk.TemplateChunkID != nil && templates[*chunk.TemplateChunkID] && !chunk.IsTemplate && !chunk.IsSlot {
+					found = append(found, chunk)
+				}
+			}
+			body = found
+		case strings.HasSuffix(r.URL.Path, "/template_slots"):
+			templates := in("template_chunk_id")
+			found := []templateSlotRelation{}
+			for _, relation := range s.templateSlots {
+				if templates[relation.TemplateChunkID] {
+					found = append(found, relation)
+				}
+			}
+			body = found
+		case strings.HasSuffix(r.URL.Path, "/chunk_tags"):
+			found := []map[string]string{}
+			for _, relation := range s.chunkTags {
+				if "eq."+relation[0] == query.Get("chunk_id") {
+					found = append(found, map[string]string{"tag_chunk_id": relation[1]})
+				}
+			}
+			body = found
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			body = map[string]string{"code": "404", "message": "unexpected query " + r.URL.String()}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIDBatches(t *testing.T) {
+	ids := []string{"a", "b", "a", "", "c"}
+	assert.Equal(t, [][]string{{"a", "b", "c"}}, idBatches(ids))
+
+	many := make([]string, 2*maxIDsPerRequest+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("id-%d", i)
+	}
+	batches := idBatches(many)
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], maxIDsPerRequest)
+	assert.Equal(t, []string{many[len(many)-1]}, batches[2])
+	assert.Empty(t, idBatches(nil))
+}
+
+func TestGetTemplateByContent_BatchesLookups(t *testing.T) {
+	templateID := "template"
+	store := &chunkStore{chunks: []models.ChunkRecord{{ID: templateID, Content: "Person#template", IsTemplate: true}}}
+	for i := 0; i < 5; i++ {
+		slotID := fmt.Sprintf("slot-%d", i)
+		store.chunks = append(store.chunks, models.ChunkRecord{ID: slotID, Content: fmt.Sprintf("#field%d", i), IsSlot: true})
+		store.templateSlots = append(store.templateSlots, templateSlotRelation{TemplateChunkID: templateID, SlotChunkID: slotID})
+	}
+	for i := 0; i < 10; i++ {
+		instanceID := fmt.Sprintf("instance-%d", i)
+		store.chunks = append(store.chunks, models.ChunkRecord{ID: instanceID, Content: instanceID, TemplateChunkID: &templateID})
+		for j := 0; j < 5; j++ {
+			sequence := j
+			value := fmt.Sprintf("%s-value-%d", instanceID, j)
+			store.chunks = append(store.chunks, models.ChunkRecord{
+				ID:              fmt.Sprintf("%s-%d", instanceID, j),
+				Content:         value,
+				ParentChunkID:   &instanceID,
+				TemplateChunkID: &templateID,
+				SequenceNumber:  &sequence,
+			})
+		}
+	}
+
+	var requests int32
+	server := store.serve(t, &requests)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	result, err := client.GetTemplateByContent(context.Background(), "Person#template")
+	require.NoError(t, err)
+
+	require.Len(t, result.Slots, 5)
+	assert.Equal(t, "#field0", result.Slots[0].Content)
+	require.Len(t, result.Instances, 10)
+	for _, instance := range result.Instances {
+		require.Len(t, instance.SlotValues, 5)
+		assert.Equal(t, instance.Instance.ID+"-value-3", instance.SlotValues["field3"].Content)
+	}
+	// template chunk, slot relations, slot chunks, instances with their slot values
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+}
+
+func TestGetChunkTags_BatchesLookups(t *testing.T) {
+	store := &chunkStore{chunks: []models.ChunkRecord{{ID: "chunk", Content: "note"}}}
+	for i := 0; i < 30; i++ {
+		tagID := fmt.Sprintf("tag-%02d", i)
+		store.chunkTags = append(store.chunkTags, [2]string{"chunk", tagID})
+		if i != 7 {
+			store.chunks = append(store.chunks, models.ChunkRecord{ID: tagID, Content: "#" + tagID})
+		}
+	}
+
+	var requests int32
+	server := store.serve(t, &requests)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	tags, err := client.GetChunkTags(context.Background(), "chunk")
+	require.NoError(t, err)
+	require.Len(t, tags, 29, "relationships to missing chunks are skipped")
+	assert.Equal(t, "tag-00", tags[0].ID)
+	assert.Equal(t, "tag-08", tags[7].ID)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestGetNodeNeighbors_TwoRequestsPerHop(t *testing.T) {
+	var requests int32
+	server := newChainGraphServer(t, 6, 0, &requests)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	result, err := client.GetNodeNeighbors(context.Background(), "n0", 3)
+	require.NoError(t, err)
+	assert.Len(t, result.Nodes, 4)
+	// starting node, then edges and neighbor nodes for n0, n1 and n2
+	assert.Equal(t, int32(7), atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&requests, 0)
+	path, err := client.FindPathBetweenNodes(context.Background(), "n0", "n3", 5)
+	require.NoError(t, err)
+	require.Len(t, path.Nodes, 4)
+	assert.Equal(t, "n0", path.Nodes[0].ID)
+	require.Len(t, path.Edges, 3)
+	assert.Equal(t, "e2", path.Edges[2].ID)
+	// three hops, then the source node; the path itself needs no further requests
+	assert.Equal(t, int32(7), atomic.LoadInt32(&requests))
+}
@@ -0,0 +1,354 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// UpsertGraphNodes stores nodes, merging each into the stored node for the same entity:
+// the same entity type and normalized name (entity_key, see
+// database/graph_upsert_migration.sql). Merged nodes keep their ID, first chunk and
+// spans; incoming properties overwrite theirs, the chunk_ids property gains the incoming
+// chunks and the higher confidence wins. Nodes in the batch for the same entity are merged
+// with each other too. On return every node holds the stored node it was merged into, so
+// callers can point their edges at it with models.RemapGraphEdges.
+//
+// Lookups and writes are separate requests, so concurrent upserts of a new entity may
+// still store it twice; entity resolution merges those later.
+func (c *supabaseHTTPClient) UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(nodes))
+	var names []string
+	for i := range nodes {
+		prepareGraphNode(&nodes[i])
+		keys[i] = nodes[i].EntityKey()
+		if keys[i] == "" {
+			// Nodes whose names normalize to nothing cannot be matched and are stored as given
+			keys[i] = "\x01" + nodes[i].ID
+			continue
+		}
+		names = append(names, models.NormalizeEntityName(nodes[i].EntityName))
+	}
+
+	stored, err := c.storedGraphNodes(ctx, names)
+	if err != nil {
+		return err
+	}
+
+	merged := make(map[string]*models.GraphNode, len(nodes))
+	changed := make(map[string]bool, len(nodes))
+	var order []string
+	for i, node := range nodes {
+		key := keys[i]
+		target, ok := merged[key]
+		if !ok {
+			if existing, found := stored[key]; found {
+				target = &existing
+			} else {
+				target = &models.GraphNode{ID: node.ID, ChunkID: node.ChunkID, EntityName: node.EntityName,
+					EntityType: node.EntityType, CreatedAt: node.CreatedAt}
+				changed[key] = true
+			}
+			if target.Properties == nil {
+				target.Properties = make(map[string]interface{})
+			}
+			merged[key] = target
+			order = append(order, key)
+		}
+		if mergeGraphNode(target, node) {
+			changed[key] = true
+		}
+	}
+
+	var created []models.GraphNode
+	var createdKeys []string
+	for _, key := range order {
+		target := merged[key]
+		if _, found := stored[key]; !found {
+			created = append(created, *target)
+			createdKeys = append(createdKeys, key)
+			continue
+		}
+		if !changed[key] {
+			continue
+		}
+		endpoint := NewPostgRESTQuery("graph_nodes").Where(Eq("id", target.ID)).String()
+		update := map[string]interface{}{"properties": target.Properties, "confidence": target.Confidence}
+		if err := c.makeRequest(ctx, "PATCH", endpoint, update, nil); err != nil {
+			return fmt.Errorf("failed to update graph node %s: %w", target.ID, err)
+		}
+	}
+
+	if len(created) > 0 {
+		var result []models.GraphNode
+		if err := c.makeRequest(ctx, "POST", "/graph_nodes", created, &result); err != nil {
+			return fmt.Errorf("failed to insert graph nodes: %w", err)
+		}
+		if len(result) == len(created) {
+			for i := range result {
+				merged[createdKeys[i]] = &result[i]
+			}
+		}
+	}
+
+	for i := range nodes {
+		nodes[i] = *merged[keys[i]]
+	}
+	return nil
+}
+
+// storedGraphNodes returns the oldest stored node of each entity among the normalized
+// names, by entity key
+func (c *supabaseHTTPClient) storedGraphNodes(ctx context.Context, names []string) (map[string]models.GraphNode, error) {
+	stored := make(map[string]models.GraphNode)
+	for _, batch := range idBatches(names) {
+		endpoint := NewPostgRESTQuery("graph_nodes").
+			Select("*").
+			Where(In("entity_key", batch)).
+			OrderAsc("created_at").
+			String()
+
+		var nodes []models.GraphNode
+		if err := c.makeRequest(ctx, "GET", endpoint, nil, &nodes); err != nil {
+			return nil, fmt.Errorf("failed to get graph nodes by entity: %w", err)
+		}
+		for _, node := range nodes {
+			// The key is compared again here, since the database normalizes with regular
+			// expressions that may disagree on unusual characters
+			key := node.EntityKey()
+			if _, ok := stored[key]; !ok && key != "" {
+				stored[key] = node
+			}
+		}
+	}
+	return stored, nil
+}
+
+// mergeGraphNode merges node into target and reports whether target changed
+func mergeGraphNode(target *models.GraphNode, node models.GraphNode) bool {
+	changed := false
+	chunkIDs := propertyStrings(target.Properties[models.EntityChunkIDsProperty])
+	if len(chunkIDs) == 0 && target.ChunkID != "" {
+		chunkIDs = []string{target.ChunkID}
+		changed = true
+	}
+	incoming := append(propertyStrings(node.Properties[models.EntityChunkIDsProperty]), node.ChunkID)
+	for _, chunkID := range incoming {
+		if chunkID != "" && !containsString(chunkIDs, chunkID) {
+			chunkIDs = append(chunkIDs, chunkID)
+			changed = true
+		}
+	}
+	if len(chunkIDs) > 0 {
+		target.Properties[models.EntityChunkIDsProperty] = chunkIDs
+	}
+
+	if mergeGraphProperties(target.Properties, node.Properties) {
+		changed = true
+	}
+	if higherConfidence(target.Confidence, node.Confidence) {
+		target.Confidence = node.Confidence
+		changed = true
+	}
+	if target.SourceStart == nil && node.SourceStart != nil && node.ChunkID == target.ChunkID {
+		target.SourceStart, target.SourceEnd = node.SourceStart, node.SourceEnd
+	}
+	return changed
+}
+
+// UpsertGraphEdges stores edges, merging each into the stored edge with the same source,
+// target and relationship type. Merged edges keep their ID, spans and validity; incoming
+// properties overwrite theirs and the higher confidence wins, so upserting the same edges
+// again changes nothing. On return every edge holds the stored edge it was merged into.
+func (c *supabaseHTTPClient) UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error {
+	if len(edges) == 0 {
+		return nil
+	}
+
+	var sourceIDs, types []string
+	for i := range edges {
+		prepareGraphEdge(&edges[i])
+		sourceIDs = append(sourceIDs, edges[i].SourceNodeID)
+		if !containsString(types, edges[i].RelationshipType) {
+			types = append(types, edges[i].RelationshipType)
+		}
+	}
+
+	stored, err := c.storedGraphEdges(ctx, sourceIDs, types)
+	if err != nil {
+		return err
+	}
+
+	merged := make(map[string]*models.GraphEdge, len(edges))
+	changed := make(map[string]bool, len(edges))
+	var order []string
+	for _, edge := range edges {
+		key := edge.RelationshipKey()
+		target, ok := merged[key]
+		if !ok {
+			if existing, found := stored[key]; found {
+				target = &existing
+				if target.Properties == nil {
+					target.Properties = make(map[string]interface{})
+				}
+			} else {
+				copied := edge
+				copied.Properties = make(map[string]interface{}, len(edge.Properties))
+				target = &copied
+			}
+			merged[key] = target
+			order = append(order, key)
+		}
+		if mergeGraphEdge(target, edge) {
+			changed[key] = true
+		}
+	}
+
+	var created []models.GraphEdge
+	for _, key := range order {
+		target := merged[key]
+		if _, found := stored[key]; !found {
+			created = append(created, *target)
+			continue
+		}
+		if !changed[key] {
+			continue
+		}
+		endpoint := NewPostgRESTQuery("graph_edges").Where(Eq("id", target.ID)).String()
+		update := map[string]interface{}{"properties": target.Properties, "confidence": target.Confidence}
+		if err := c.makeRequest(ctx, "PATCH", endpoint, update, nil); err != nil {
+			return fmt.Errorf("failed to update graph edge %s: %w", target.ID, err)
+		}
+	}
+
+	if len(created) > 0 {
+		var result []models.GraphEdge
+		if err := c.makeRequest(ctx, "POST", "/graph_edges", created, &result); err != nil {
+			return fmt.Errorf("failed to insert graph edges: %w", err)
+		}
+		if len(result) == len(created) {
+			for i := range result {
+				merged[result[i].RelationshipKey()] = &result[i]
+			}
+		}
+	}
+
+	for i := range edges {
+		edges[i] = *merged[edges[i].RelationshipKey()]
+	}
+	return nil
+}
+
+// storedGraphEdges returns the oldest stored edge of each relationship from the source
+// nodes, by relationship key
+func (c *supabaseHTTPClient) storedGraphEdges(ctx context.Context, sourceIDs, types []string) (map[string]models.GraphEdge, error) {
+	stored := make(map[string]models.GraphEdge)
+	for _, batch := range idBatches(sourceIDs) {
+		endpoint := NewPostgRESTQuery("graph_edges").
+			Select("*").
+			Where(In("source_node_id", batch), In("relationship_type", types)).
+			OrderAsc("created_at").
+			String()
+
+		var edges []models.GraphEdge
+		if err := c.makeRequest(ctx, "GET", endpoint, nil, &edges); err != nil {
+			return nil, fmt.Errorf("failed to get graph edges by source: %w", err)
+		}
+		for _, edge := range edges {
+			if _, ok := stored[edge.RelationshipKey()]; !ok {
+				stored[edge.RelationshipKey()] = edge
+			}
+		}
+	}
+	return stored, nil
+}
+
+// mergeGraphEdge merges edge into target and reports whether target changed
+func mergeGraphEdge(target *models.GraphEdge, edge models.GraphEdge) bool {
+	changed := mergeGraphProperties(target.Properties, edge.Properties)
+	if higherConfidence(target.Confidence, edge.Confidence) {
+		target.Confidence = edge.Confidence
+		changed = true
+	}
+	return changed
+}
+
+// mergeGraphProperties copies incoming properties other than chunk_ids over properties
+// and reports whether any value changed
+func mergeGraphProperties(properties, incoming map[string]interface{}) bool {
+	changed := false
+	for key, value := range incoming {
+		if key == models.EntityChunkIDsProperty {
+			continue
+		}
+		if current, ok := properties[key]; !ok || !reflect.DeepEqual(current, value) {
+			properties[key] = value
+			changed = true
+		}
+	}
+	return changed
+}
+
+// higherConfidence reports whether incoming is a higher confidence than current
+func higherConfidence(current, incoming *float64) bool {
+	return incoming != nil && (current == nil || *incoming > *current)
+}
+
+// propertyStrings reads a JSON array of strings property, as stored or as set in Go
+func propertyStrings(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return append([]string(nil), list...)
+	case []interface{}:
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareGraphNode fills in the fields a new node needs
+func prepareGraphNode(node *models.GraphNode) {
+	if node.ID == "" {
+		node.ID = generateUUID()
+	}
+	if node.CreatedAt.IsZero() {
+		node.CreatedAt = time.Now()
+	}
+	if node.Properties == nil {
+		node.Properties = make(map[string]interface{})
+	}
+}
+
+// prepareGraphEdge fills in the fields a new edge needs
+func prepareGraphEdge(edge *models.GraphEdge) {
+	if edge.ID == "" {
+		edge.ID = generateUUID()
+	}
+	if edge.CreatedAt.IsZero() {
+		edge.CreatedAt = time.Now()
+	}
+	if edge.Properties == nil {
+		edge.Properties = make(map[string]interface{})
+	}
+}
@@ -0,0 +1,182 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// graphStore is a PostgREST stand-in for the graph_nodes and graph_edges queries made by
+// graph upserts
+type graphStore struct {
+	mu      sync.Mutex
+	nodes   []models.GraphNode
+	edges   []models.GraphEdge
+	patches int
+}
+
+func newGraphStoreServer(t *testing.T, store *graphStore) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		store.mu.Lock()
+		defer store.mu.Unlock()
+		query := r.URL.Query()
+
+		var body interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes") && r.Method == http.MethodGet:
+			keys := parseInFilter(query.Get("entity_key"))
+			var nodes []models.GraphNode
+			for _, node := range store.nodes {
+				if containsString(keys, models.NormalizeEntityName(node.EntityName)) {
+					nodes = append(nodes, node)
+				}
+			}
+			body = nodes
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes") && r.Method == http.MethodPost:
+			var nodes []models.GraphNode
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&nodes))
+			store.nodes = append(store.nodes, nodes...)
+			body = nodes
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes") && r.Method == http.MethodPatch:
+			var update models.GraphNode
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+			store.patches++
+			for i := range store.nodes {
+				if store.nodes[i].ID == strings.TrimPrefix(query.Get("id"), "eq.") {
+					store.nodes[i].Properties, store.nodes[i].Confidence = update.Properties, update.Confidence
+				}
+			}
+		case strings.HasSuffix(r.URL.Path, "/graph_edges") && r.Method == http.MethodGet:
+			sources := parseInFilter(query.Get("source_node_id"))
+			types := parseInFilter(query.Get("relationship_type"))
+			var edges []models.GraphEdge
+			for _, edge := range store.edges {
+				if containsString(sources, edge.SourceNodeID) && containsString(types, edge.RelationshipType) {
+					edges = append(edges, edge)
+				}
+			}
+			body = edges
+		case strings.HasSuffix(r.URL.Path, "/graph_edges") && r.Method == http.MethodPost:
+			var edges []models.GraphEdge
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&edges))
+			store.edges = append(store.edges, edges...)
+			body = edges
+		case strings.HasSuffix(r.URL.Path, "/graph_edges") && r.Method == http.MethodPatch:
+			var update models.GraphEdge
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+			store.patches++
+			for i := range store.edges {
+				if store.edges[i].ID == strings.TrimPrefix(query.Get("id"), "eq.") {
+					store.edges[i].Properties, store.edges[i].Confidence = update.Properties, update.Confidence
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			body = map[string]string{"code": "404", "message": "unexpected request " + r.Method + " " + r.URL.Path}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestUpsertGraphNodes_MergesByEntityKey(t *testing.T) {
+	store := &graphStore{nodes: []models.GraphNode{
+		{ID: "acme", ChunkID: "c1", EntityName: "The Acme Corp.", EntityType: "ORG",
+			Properties: map[string]interface{}{"industry": "rockets"}},
+	}}
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: newGraphStoreServer(t, store).URL, APIKey: "test"})
+
+	high, low := 0.9, 0.4
+	nodes := []models.GraphNode{
+		{ID: "n1", ChunkID: "c2", EntityName: "acme corp", EntityType: "ORG", Confidence: &high,
+			Properties: map[string]interface{}{"founded": 1949.0}},
+		{ID: "n2", ChunkID: "c2", EntityName: "Acme", EntityType: "PERSON"},
+		{ID: "n3", ChunkID: "c3", EntityName: "ACME CORP", EntityType: "ORG", Confidence: &low},
+		{ID: "n4", ChunkID: "c3", EntityName: "acme", EntityType: "PERSON"},
+	}
+	require.NoError(t, client.UpsertGraphNodes(context.Background(), nodes))
+
+	assert.Equal(t, "acme", nodes[0].ID, "merged into the stored node")
+	assert.Equal(t, "acme", nodes[2].ID)
+	assert.Equal(t, "n2", nodes[1].ID, "a different entity type is a different entity")
+	assert.Equal(t, "n2", nodes[3].ID)
+
+	require.Len(t, store.nodes, 2)
+	acme := store.nodes[0]
+	assert.Equal(t, "c1", acme.ChunkID)
+	assert.Equal(t, []interface{}{"c1", "c2", "c3"}, acme.Properties[models.EntityChunkIDsProperty])
+	assert.Equal(t, "rockets", acme.Properties["industry"])
+	assert.Equal(t, 1949.0, acme.Properties["founded"])
+	require.NotNil(t, acme.Confidence)
+	assert.Equal(t, 0.9, *acme.Confidence)
+	assert.Equal(t, []interface{}{"c2", "c3"}, store.nodes[1].Properties[models.EntityChunkIDsProperty])
+
+	// Upserting the same nodes again writes nothing
+	patches := store.patches
+	again := []models.GraphNode{{ChunkID: "c3", EntityName: "Acme Corp", EntityType: "ORG"}}
+	require.NoError(t, client.UpsertGraphNodes(context.Background(), again))
+	assert.Equal(t, "acme", again[0].ID)
+	assert.Len(t, store.nodes, 2)
+	assert.Equal(t, patches, store.patches)
+}
+
+func TestUpsertGraphEdges_Idempotent(t *testing.T) {
+	stored := 0.5
+	store := &graphStore{edges: []models.GraphEdge{
+		{ID: "e1", SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "works_at", Confidence: &stored},
+	}}
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: newGraphStoreServer(t, store).URL, APIKey: "test"})
+
+	higher := 0.8
+	edges := []models.GraphEdge{
+		{SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "works_at", Confidence: &higher,
+			Properties: map[string]interface{}{"role": "engineer"}},
+		{SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "knows"},
+		{SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "knows"},
+		{SourceNodeID: "b", TargetNodeID: "a", RelationshipType: "works_at"},
+	}
+	require.NoError(t, client.UpsertGraphEdges(context.Background(), edges))
+
+	assert.Equal(t, "e1", edges[0].ID)
+	assert.Equal(t, edges[1].ID, edges[2].ID, "duplicates in the batch are stored once")
+	assert.NotEqual(t, "e1", edges[3].ID, "direction is part of the relationship")
+	require.Len(t, store.edges, 3)
+	assert.Equal(t, "engineer", store.edges[0].Properties["role"])
+	assert.Equal(t, 0.8, *store.edges[0].Confidence)
+
+	patches := store.patches
+	again := []models.GraphEdge{
+		{SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "works_at", Properties: map[string]interface{}{"role": "engineer"}},
+		{SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "knows"},
+	}
+	require.NoError(t, client.UpsertGraphEdges(context.Background(), again))
+	assert.Len(t, store.edges, 3)
+	assert.Equal(t, patches, store.patches)
+	assert.Equal(t, "e1", again[0].ID)
+}
+
+func TestRemapGraphEdges(t *testing.T) {
+	edges := []models.GraphEdge{
+		{ID: "e1", SourceNodeID: "n1", TargetNodeID: "n2"},
+		{ID: "e2", SourceNodeID: "n1", TargetNodeID: "n3"},
+		{ID: "e3", SourceNodeID: "n4", TargetNodeID: "n2"},
+	}
+	remapped := models.RemapGraphEdges(edges, map[string]string{"n1": "acme", "n3": "acme", "n2": "n2"})
+
+	require.Len(t, remapped, 2, "edges between merged nodes would be self-loops")
+	assert.Equal(t, "acme", remapped[0].SourceNodeID)
+	assert.Equal(t, "n2", remapped[0].TargetNodeID)
+	assert.Equal(t, "n4", remapped[1].SourceNodeID)
+	assert.Equal(t, "n1", edges[0].SourceNodeID, "the edges passed in are left alone")
+}
@@ -0,0 +1,71 @@
+package clients
+
+import (
+	"sort"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// minEdgeRelevance keeps fully decayed edges traversable at a high but finite cost
+const minEdgeRelevance = 1e-6
+
+// SetEdgeDecay sets how edge weights decay in neighbor ordering and path finding
+func (c *supabaseHTTPClient) SetEdgeDecay(decay models.EdgeDecay) {
+	c.edgeDecay = decay
+}
+
+// edgeCost is the cost of crossing an edge in a weighted shortest path: strong, recently
+// seen relationships are cheap
+func edgeCost(edge models.GraphEdge, now time.Time, decay models.EdgeDecay) float64 {
+	relevance := edge.Relevance(now, decay)
+	if relevance < minEdgeRelevance {
+		relevance = minEdgeRelevance
+	}
+	return 1 / relevance
+}
+
+// sortNeighborsByRelevance orders the neighbors of nodeID by their most relevant edge to
+// it, strongest first
+func sortNeighborsByRelevance(nodeID string, neighbors []models.GraphNode, edges []models.GraphEdge, now time.Time, decay models.EdgeDecay) {
+	best := make(map[string]float64, len(edges))
+	for _, edge := range edges {
+		other := edge.TargetNodeID
+		if edge.SourceNodeID != nodeID {
+			other = edge.SourceNodeID
+		}
+		if relevance := edge.Relevance(now, decay); relevance > best[other] {
+			best[other] = relevance
+		}
+	}
+	sort.SliceStable(neighbors, func(i, j int) bool {
+		return best[neighbors[i].ID] > best[neighbors[j].ID]
+	})
+}
+
+// sortEdgesByRelevance orders edges by decayed weight, strongest first
+func sortEdgesByRelevance(edges []models.GraphEdge, now time.Time, decay models.EdgeDecay) {
+	sort.SliceStable(edges, func(i, j int) bool {
+		return edges[i].Relevance(now, decay) > edges[j].Relevance(now, decay)
+	})
+}
+
+// pathEntry is a node reached by a path of the given cost
+type pathEntry struct {
+	nodeID string
+	cost   float64
+}
+
+// pathQueue is a min-heap of path entries by cost, for container/heap
+type pathQueue []pathEntry
+
+func (q pathQueue) Len() int            { return len(q) }
+func (q pathQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x interface{}) { *q = append(*q, x.(pathEntry)) }
+func (q *pathQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
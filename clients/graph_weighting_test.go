@@ -0,0 +1,160 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// newWeightedGraphServer serves a fixed set of edges and the nodes they connect
+func newWeightedGraphServer(t *testing.T, edges []models.GraphEdge) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var body interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes"):
+			ids := []string{strings.TrimPrefix(query.Get("id"), "eq.")}
+			if strings.HasPrefix(query.Get("id"), "in.") {
+				ids = parseInFilter(query.Get("id"))
+			}
+			if name := query.Get("entity_name"); name != "" {
+				ids = []string{strings.TrimPrefix(name, "eq.")}
+			}
+			var nodes []models.GraphNode
+			for _, id := range ids {
+				nodes = append(nodes, models.GraphNode{ID: id, EntityName: id})
+			}
+			body = nodes
+		case strings.HasSuffix(r.URL.Path, "/graph_edges"):
+			id := strings.Trim(strings.TrimPrefix(strings.SplitN(query.Get("or"), ",", 2)[0], "(source_node_id.eq."), `"`)
+			var connected []models.GraphEdge
+			for _, edge := range edges {
+				if edge.SourceNodeID == id || edge.TargetNodeID == id {
+					connected = append(connected, edge)
+				}
+			}
+			body = connected
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			body = map[string]string{"code": "404", "message": "unexpected path " + r.URL.Path}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestGraphEdge_Relevance(t *testing.T) {
+	now := time.Now()
+	decay := models.EdgeDecay{HalfLife: 24 * time.Hour, Floor: 0.1}
+	seen := now.Add(-48 * time.Hour)
+
+	assert.Equal(t, 1.0, models.GraphEdge{}.Relevance(now, decay), "unweighted edges count as 1")
+	assert.InDelta(t, 0.5, models.GraphEdge{Weight: 2, LastSeenAt: &seen}.Relevance(now, decay), 1e-9)
+	assert.InDelta(t, 0.25, models.GraphEdge{Weight: 1, CreatedAt: seen}.Relevance(now, decay), 1e-9)
+
+	old := now.Add(-30 * 24 * time.Hour)
+	assert.InDelta(t, 0.3, models.GraphEdge{Weight: 3, LastSeenAt: &old}.Relevance(now, decay), 1e-9, "decay stops at the floor")
+	assert.Equal(t, 3.0, models.GraphEdge{Weight: 3, LastSeenAt: &old}.Relevance(now, models.EdgeDecay{}))
+}
+
+func TestFindPathBetweenNodes_FollowsStrongestEdges(t *testing.T) {
+	now := time.Now()
+	edges := []models.GraphEdge{
+		{ID: "a-b", SourceNodeID: "a", TargetNodeID: "b", Weight: 1, LastSeenAt: &now},
+		{ID: "b-d", SourceNodeID: "b", TargetNodeID: "d", Weight: 1, LastSeenAt: &now},
+		{ID: "a-c", SourceNodeID: "a", TargetNodeID: "c", Weight: 5, LastSeenAt: &now},
+		{ID: "c-d", SourceNodeID: "c", TargetNodeID: "d", Weight: 5, LastSeenAt: &now},
+		{ID: "a-d", SourceNodeID: "a", TargetNodeID: "d", Weight: 0.1, LastSeenAt: &now},
+	}
+	server := newWeightedGraphServer(t, edges)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	path, err := client.FindPathBetweenNodes(context.Background(), "a", "d", 5)
+	require.NoError(t, err)
+	require.Len(t, path.Edges, 2)
+	assert.Equal(t, "a-c", path.Edges[0].ID)
+	assert.Equal(t, "c-d", path.Edges[1].ID)
+	require.Len(t, path.Nodes, 3)
+	assert.Equal(t, []string{"a", "c", "d"}, []string{path.Nodes[0].ID, path.Nodes[1].ID, path.Nodes[2].ID})
+
+	// A one-hop limit leaves only the weak direct edge
+	path, err = client.FindPathBetweenNodes(context.Background(), "a", "d", 1)
+	require.NoError(t, err)
+	require.Len(t, path.Edges, 1)
+	assert.Equal(t, "a-d", path.Edges[0].ID)
+
+	neighbors, err := client.GetNodeNeighbors(context.Background(), "a", 1)
+	require.NoError(t, err)
+	require.Len(t, neighbors.Nodes, 4)
+	assert.Equal(t, []string{"a", "c", "b", "d"}, []string{neighbors.Nodes[0].ID, neighbors.Nodes[1].ID, neighbors.Nodes[2].ID, neighbors.Nodes[3].ID})
+	assert.Equal(t, "a-c", neighbors.Edges[0].ID)
+}
+
+func TestSearchGraph_MinConfidence(t *testing.T) {
+	confident, doubtful := 0.9, 0.3
+	edges := []models.GraphEdge{
+		{ID: "a-b", SourceNodeID: "a", TargetNodeID: "b", Confidence: &confident},
+		{ID: "a-c", SourceNodeID: "a", TargetNodeID: "c", Confidence: &doubtful},
+		{ID: "c-d", SourceNodeID: "c", TargetNodeID: "d", Confidence: &confident},
+		{ID: "b-e", SourceNodeID: "b", TargetNodeID: "e", Properties: map[string]interface{}{"confidence": 0.7}},
+	}
+	server := newWeightedGraphServer(t, edges)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	result, err := client.SearchGraph(context.Background(), &models.GraphQuery{EntityName: "a", MaxDepth: 3})
+	require.NoError(t, err)
+	assert.Len(t, result.Nodes, 5)
+
+	// The doubtful edge is neither returned nor followed, so d is out of reach
+	result, err = client.SearchGraph(context.Background(), &models.GraphQuery{EntityName: "a", MaxDepth: 3, MinConfidence: 0.5})
+	require.NoError(t, err)
+	var nodeIDs, edgeIDs []string
+	for _, node := range result.Nodes {
+		nodeIDs = append(nodeIDs, node.ID)
+	}
+	for _, edge := range result.Edges {
+		edgeIDs = append(edgeIDs, edge.ID)
+	}
+	assert.ElementsMatch(t, []string{"a", "b", "e"}, nodeIDs)
+	assert.NotContains(t, edgeIDs, "a-c")
+	assert.NotContains(t, edgeIDs, "c-d")
+	assert.Contains(t, edgeIDs, "b-e", "confidence stored in properties counts")
+}
+
+func TestSearchGraph_AsOf(t *testing.T) {
+	joined := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	left := time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)
+	edges := []models.GraphEdge{
+		{ID: "a-b", SourceNodeID: "a", TargetNodeID: "b", RelationshipType: "works_at", ValidFrom: &joined, ValidTo: &left},
+		{ID: "a-c", SourceNodeID: "a", TargetNodeID: "c", RelationshipType: "works_at", ValidFrom: &left},
+		{ID: "a-d", SourceNodeID: "a", TargetNodeID: "d"},
+	}
+	server := newWeightedGraphServer(t, edges)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	edgeIDsAt := func(asOf time.Time) []string {
+		result, err := client.SearchGraph(context.Background(), &models.GraphQuery{EntityName: "a", MaxDepth: 1, AsOf: &asOf})
+		require.NoError(t, err)
+		var edgeIDs []string
+		for _, edge := range result.Edges {
+			edgeIDs = append(edgeIDs, edge.ID)
+		}
+		return edgeIDs
+	}
+
+	assert.ElementsMatch(t, []string{"a-b", "a-d"}, edgeIDsAt(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.ElementsMatch(t, []string{"a-c", "a-d"}, edgeIDsAt(left), "valid_to is exclusive")
+	assert.ElementsMatch(t, []string{"a-d"}, edgeIDsAt(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)))
+}
@@ -0,0 +1,357 @@
+package clients
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// FRACTIONAL SIBLING ORDERING
+// ============================================================================
+//
+// Siblings are ordered by sort_key, a string read as the digits of a fraction in
+// base 62. A key strictly between any two keys always exists, so placing a chunk only
+// writes that chunk. Keys grow by a digit every few inserts into the same gap; once one
+// would exceed maxSortKeyLength, the siblings are respaced evenly instead.
+//
+// sort_key uses the C collation so that Postgres compares keys byte by byte, as here.
+// Chunks written without a key sort after all keyed siblings, by sequence_number.
+
+// sortKeyDigits are the key digits in byte order
+const sortKeyDigits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// maxSortKeyLength is the longest key handed out before the siblings are respaced
+const maxSortKeyLength = 24
+
+// keyBetween returns a key that sorts strictly between a and b, where "" leaves that
+// side open. It is false when a and b are not valid keys in order, or when no key of
+// at most maxSortKeyLength fits between them; the siblings then need respacing.
+func keyBetween(a, b string) (string, bool) {
+	if !validSortKey(a) || !validSortKey(b) || (a != "" && b != "" && a >= b) {
+		return "", false
+	}
+	var key string
+	switch {
+	case b == "" && a != "":
+		key = keyAfter(a)
+	case a == "" && b != "":
+		key = keyBefore(b)
+	default:
+		key = midpointKey(a, b)
+	}
+	return key, len(key) <= maxSortKeyLength
+}
+
+// keyAfter returns the next key after a in steps of one digit, so that appending to a
+// list lengthens keys only every few dozen chunks
+func keyAfter(a string) string {
+	i := 0
+	for i < len(a) && a[i] == sortKeyDigits[len(sortKeyDigits)-1] {
+		i++
+	}
+	return a[:i] + string(sortKeyDigits[digitAt(a, i)+1])
+}
+
+// keyBefore returns the previous key before b in steps of one digit, the counterpart
+// of keyAfter for prepending
+func keyBefore(b string) string {
+	i := 0
+	for b[i] == sortKeyDigits[0] {
+		i++
+	}
+	if b[i] == sortKeyDigits[1] {
+		return b[:i] + string(sortKeyDigits[0]) + string(sortKeyDigits[len(sortKeyDigits)-1])
+	}
+	return b[:i] + string(sortKeyDigits[digitAt(b, i)-1])
+}
+
+// keysBetween returns n increasing keys between a and b, splitting the gap in halves so
+// that key length grows with log(n) rather than n
+func keysBetween(a, b string, n int) ([]string, bool) {
+	if n == 0 {
+		return nil, true
+	}
+	mid, ok := keyBetween(a, b)
+	if !ok {
+		return nil, false
+	}
+	before, ok := keysBetween(a, mid, n/2)
+	if !ok {
+		return nil, false
+	}
+	after, ok := keysBetween(mid, b, n-n/2-1)
+	if !ok {
+		return nil, false
+	}
+	keys := append(before, mid)
+	return append(keys, after...), true
+}
+
+// midpointKey returns a key between a and b, where a may be "" for zero and b may be ""
+// for one. Neither key ends in the zero digit, so neither is a prefix of a key it sorts
+// before, and neither does the result.
+func midpointKey(a, b string) string {
+	if b != "" {
+		// Keep the common prefix, padding a with zero digits
+		n := 0
+		for n < len(b) && digitAt(a, n) == strings.IndexByte(sortKeyDigits, b[n]) {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + midpointKey(suffixFrom(a, n), b[n:])
+		}
+	}
+
+	digitA := digitAt(a, 0)
+	digitB := len(sortKeyDigits)
+	if b != "" {
+		digitB = strings.IndexByte(sortKeyDigits, b[0])
+	}
+	if digitB-digitA > 1 {
+		return string(sortKeyDigits[(digitA+digitB+1)/2])
+	}
+	// The first digits are adjacent: either b shortened still sorts after a, or the
+	// key continues past a's first digit with no upper bound
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(sortKeyDigits[digitA]) + midpointKey(suffixFrom(a, 1), "")
+}
+
+// evenlySpacedKeys returns n increasing keys of equal length, spaced so that at least
+// a full digit of room is left between neighbours
+func evenlySpacedKeys(n int) []string {
+	base := uint64(len(sortKeyDigits))
+	width, space := 1, base
+	for space <= uint64(n)*base {
+		width++
+		space *= base
+	}
+
+	keys := make([]string, n)
+	digits := make([]byte, width)
+	for i := range keys {
+		value := uint64(i+1) * space / uint64(n+1)
+		for d := width - 1; d >= 0; d-- {
+			digits[d] = sortKeyDigits[value%base]
+			value /= base
+		}
+		keys[i] = strings.TrimRight(string(digits), "0")
+	}
+	return keys
+}
+
+// reorderedKeys returns keys for siblings in their new order. The longest run of
+// siblings whose current keys already increase keeps them, so that only the others
+// are written. It is false when the gaps are too small and every key must change.
+func reorderedKeys(siblings []models.ChunkRecord) ([]string, bool) {
+	keys := make([]string, len(siblings))
+	kept := increasingKeyRun(siblings)
+
+	lower, start := "", 0
+	for i := 0; i <= len(siblings); i++ {
+		if i < len(siblings) && !kept[i] {
+			continue
+		}
+		upper := ""
+		if i < len(siblings) {
+			upper = siblings[i].SortKey
+			keys[i] = upper
+		}
+		filled, ok := keysBetween(lower, upper, i-start)
+		if !ok {
+			return nil, false
+		}
+		copy(keys[start:i], filled)
+		lower, start = upper, i+1
+	}
+	return keys, true
+}
+
+// increasingKeyRun marks a longest subsequence of keyed siblings whose keys increase
+func increasingKeyRun(siblings []models.ChunkRecord) []bool {
+	// tails[l] is the index ending the increasing run of length l+1 with the smallest key
+	var tails []int
+	previous := make([]int, len(siblings))
+	for i, sibling := range siblings {
+		previous[i] = -1
+		if !validSortKey(sibling.SortKey) || sibling.SortKey == "" {
+			continue
+		}
+		l := sort.Search(len(tails), func(j int) bool {
+			return siblings[tails[j]].SortKey >= sibling.SortKey
+		})
+		if l > 0 {
+			previous[i] = tails[l-1]
+		}
+		if l == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[l] = i
+		}
+	}
+
+	kept := make([]bool, len(siblings))
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = previous[i] {
+			kept[i] = true
+		}
+	}
+	return kept
+}
+
+// positionKey returns a key for a chunk placed before siblings[pos], or after the last
+// sibling when pos is len(siblings)
+func positionKey(siblings []models.ChunkRecord, pos int) (string, bool) {
+	before, after := "", ""
+	if pos > 0 {
+		if before = siblings[pos-1].SortKey; before == "" {
+			return "", false
+		}
+	}
+	if pos < len(siblings) {
+		// An unkeyed sibling sorts after every keyed one, so it bounds nothing
+		after = siblings[pos].SortKey
+	}
+	return keyBetween(before, after)
+}
+
+func validSortKey(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(sortKeyDigits, key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, "0")
+}
+
+func digitAt(key string, i int) int {
+	if i >= len(key) {
+		return 0
+	}
+	return strings.IndexByte(sortKeyDigits, key[i])
+}
+
+func suffixFrom(key string, i int) string {
+	if i >= len(key) {
+		return ""
+	}
+	return key[i:]
+}
+
+// siblingGroup returns the children of parentID in order, or the root chunks of textID
+// when parentID is nil
+func (c *supabaseHTTPClient) siblingGroup(ctx context.Context, parentID *string, textID string) ([]models.ChunkRecord, error) {
+	query := NewPostgRESTQuery("chunks").
+		Select("*").
+		OrderAsc("sort_key").OrderAsc("sequence_number").OrderAsc("created_at")
+	if parentID != nil {
+		query.Where(Eq("parent_chunk_id", *parentID))
+	} else {
+		query.Where(Eq("text_id", textID), IsNull("parent_chunk_id"))
+	}
+
+	var siblings []models.ChunkRecord
+	if err := c.makeRequest(ctx, "GET", query.String(), nil, &siblings); err != nil {
+		return nil, fmt.Errorf("failed to get sibling chunks: %w", err)
+	}
+	return siblings, nil
+}
+
+// placementKey returns a key for a chunk placed at pos among siblings, respacing the
+// siblings first when the neighbouring keys leave no room
+func (c *supabaseHTTPClient) placementKey(ctx context.Context, siblings []models.ChunkRecord, pos int) (string, error) {
+	pos = max(0, min(pos, len(siblings)))
+	if key, ok := positionKey(siblings, pos); ok {
+		return key, nil
+	}
+	if err := c.writeSortKeys(ctx, siblings, evenlySpacedKeys(len(siblings))); err != nil {
+		return "", err
+	}
+	key, _ := positionKey(siblings, pos)
+	return key, nil
+}
+
+// writeSortKeys stores keys[i] on siblings[i], skipping siblings that already have it
+func (c *supabaseHTTPClient) writeSortKeys(ctx context.Context, siblings []models.ChunkRecord, keys []string) error {
+	for i := range siblings {
+		if siblings[i].SortKey == keys[i] {
+			continue
+		}
+		endpoint := NewPostgRESTQuery("chunks").Where(Eq("id", siblings[i].ID)).String()
+		if err := c.makeRequest(ctx, "PATCH", endpoint, map[string]string{"sort_key": keys[i]}, nil); err != nil {
+			return fmt.Errorf("failed to update sort key of chunk %s: %w", siblings[i].ID, err)
+		}
+		siblings[i].SortKey = keys[i]
+	}
+	return nil
+}
+
+// InsertChunkAt creates a chunk under parentID, or at the root of its text when
+// parentID is empty, directly after afterChunkID, or first when afterChunkID is empty
+func (c *supabaseHTTPClient) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error {
+	chunk.ParentChunkID = nil
+	if parentID != "" {
+		chunk.ParentChunkID = &parentID
+	}
+
+	siblings, err := c.siblingGroup(ctx, chunk.ParentChunkID, chunk.TextID)
+	if err != nil {
+		return err
+	}
+
+	pos := 0
+	if afterChunkID != "" {
+		pos = -1
+		for i, sibling := range siblings {
+			if sibling.ID == afterChunkID {
+				pos = i + 1
+				break
+			}
+		}
+		if pos < 0 {
+			return fmt.Errorf("chunk %s is not a sibling of the new chunk", afterChunkID)
+		}
+	}
+
+	if chunk.SortKey, err = c.placementKey(ctx, siblings, pos); err != nil {
+		return err
+	}
+	return c.InsertChunk(ctx, chunk)
+}
+
+// ReorderChildren puts the children of a chunk in the given order. orderedChildIDs must
+// list every child exactly once. Children already in relative order keep their keys.
+func (c *supabaseHTTPClient) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error {
+	children, err := c.GetChildrenChunks(ctx, parentID)
+	if err != nil {
+		return err
+	}
+	if len(orderedChildIDs) != len(children) {
+		return fmt.Errorf("expected %d child chunk IDs, got %d", len(children), len(orderedChildIDs))
+	}
+
+	byID := make(map[string]models.ChunkRecord, len(children))
+	for _, child := range children {
+		byID[child.ID] = child
+	}
+	ordered := make([]models.ChunkRecord, 0, len(children))
+	for _, id := range orderedChildIDs {
+		child, ok := byID[id]
+		if !ok {
+			return fmt.Errorf("chunk %s is not a child of %s or is listed twice", id, parentID)
+		}
+		delete(byID, id)
+		ordered = append(ordered, child)
+	}
+
+	keys, ok := reorderedKeys(ordered)
+	if !ok {
+		keys = evenlySpacedKeys(len(ordered))
+	}
+	return c.writeSortKeys(ctx, ordered, keys)
+}
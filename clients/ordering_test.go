@@ -0,0 +1,200 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyBetween(t *testing.T) {
+	cases := [][2]string{{"", ""}, {"", "1"}, {"V", ""}, {"z", ""}, {"A", "B"}, {"A", "A1"}, {"0001", "0002"}, {"Az", "B"}, {"", "0001"}}
+	for _, c := range cases {
+		key, ok := keyBetween(c[0], c[1])
+		require.True(t, ok, "%q..%q", c[0], c[1])
+		assert.True(t, validSortKey(key), key)
+		assert.Greater(t, key, c[0])
+		if c[1] != "" {
+			assert.Less(t, key, c[1])
+		}
+	}
+
+	_, ok := keyBetween("B", "A")
+	assert.False(t, ok, "keys out of order")
+	_, ok = keyBetween("A0", "")
+	assert.False(t, ok, "keys ending in the zero digit")
+	_, ok = keyBetween("A", "A")
+	assert.False(t, ok)
+}
+
+func TestKeyBetween_RepeatedInsertsExhaustPrecision(t *testing.T) {
+	// Always inserting right after the same chunk halves one gap each time
+	low, high := "A", "B"
+	inserts := 0
+	for {
+		key, ok := keyBetween(low, high)
+		if !ok {
+			break
+		}
+		require.True(t, low < key && key < high)
+		high = key
+		inserts++
+	}
+	assert.Greater(t, inserts, 100, "many inserts fit before respacing")
+	assert.Less(t, inserts, 1000)
+
+	// Appending keeps keys short
+	last := ""
+	for i := 0; i < 1000; i++ {
+		key, ok := keyBetween(last, "")
+		require.True(t, ok)
+		last = key
+	}
+	assert.LessOrEqual(t, len(last), 20)
+
+	// So does prepending
+	first := ""
+	for i := 0; i < 1000; i++ {
+		key, ok := keyBetween("", first)
+		require.True(t, ok)
+		require.True(t, first == "" || key < first)
+		first = key
+	}
+	assert.LessOrEqual(t, len(first), 20)
+}
+
+func TestKeysBetween(t *testing.T) {
+	keys, ok := keysBetween("A", "B", 500)
+	require.True(t, ok)
+	assert.Len(t, keys, 500)
+	assert.True(t, sort.StringsAreSorted(keys))
+	assert.Greater(t, keys[0], "A")
+	assert.Less(t, keys[499], "B")
+	for _, key := range keys {
+		assert.LessOrEqual(t, len(key), 4)
+	}
+}
+
+func TestEvenlySpacedKeys(t *testing.T) {
+	for _, n := range []int{1, 2, 61, 62, 1000} {
+		keys := evenlySpacedKeys(n)
+		require.Len(t, keys, n)
+		assert.True(t, sort.StringsAreSorted(keys))
+		for i, key := range keys {
+			assert.True(t, validSortKey(key) && key != "", key)
+			if i > 0 {
+				assert.NotEqual(t, keys[i-1], key)
+				_, ok := keyBetween(keys[i-1], key)
+				assert.True(t, ok, "room is left between neighbours")
+			}
+		}
+	}
+	assert.Empty(t, evenlySpacedKeys(0))
+}
+
+func TestReorderedKeys_KeepsIncreasingRun(t *testing.T) {
+	chunks := func(keys ...string) []models.ChunkRecord {
+		records := make([]models.ChunkRecord, len(keys))
+		for i, key := range keys {
+			records[i] = models.ChunkRecord{ID: key, SortKey: key}
+		}
+		return records
+	}
+
+	// Moving the last of five to the front changes one key
+	ordered := chunks("e", "a", "b", "c", "d")
+	keys, ok := reorderedKeys(ordered)
+	require.True(t, ok)
+	assert.True(t, sort.StringsAreSorted(keys))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, keys[1:])
+
+	// Reversing keeps one and rewrites the rest
+	keys, ok = reorderedKeys(chunks("d", "c", "b", "a"))
+	require.True(t, ok)
+	assert.True(t, sort.StringsAreSorted(keys))
+	changed := 0
+	for i, key := range keys {
+		if key != []string{"d", "c", "b", "a"}[i] {
+			changed++
+		}
+	}
+	assert.Equal(t, 3, changed)
+
+	// Unkeyed chunks get keys
+	keys, ok = reorderedKeys(chunks("", "b", ""))
+	require.True(t, ok)
+	assert.True(t, sort.StringsAreSorted(keys))
+	assert.Equal(t, "b", keys[1])
+	assert.NotEmpty(t, keys[0])
+}
+
+// orderingServer serves one sibling group and records the sort keys written to it
+type orderingServer struct {
+	mu       sync.Mutex
+	siblings []models.ChunkRecord
+	patches  map[string]string
+}
+
+func (s *orderingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	id := strings.TrimPrefix(r.URL.Query().Get("id"), "eq.")
+	switch r.Method {
+	case http.MethodGet:
+		if id != "" {
+			for _, sibling := range s.siblings {
+				if sibling.ID == id {
+					json.NewEncoder(w).Encode([]models.ChunkRecord{sibling})
+					return
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(s.siblings)
+	case http.MethodPatch:
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		s.patches[id], _ = body["sort_key"].(string)
+		w.Write([]byte(`[]`))
+	}
+}
+
+func TestMoveChunk_WritesOnlyTheMovedChunk(t *testing.T) {
+	parent := "parent"
+	server := &orderingServer{patches: map[string]string{}}
+	for _, key := range []string{"1", "2", "3", "4"} {
+		server.siblings = append(server.siblings, models.ChunkRecord{ID: "chunk" + key, TextID: "t", ParentChunkID: &parent, SortKey: key})
+	}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: httpServer.URL, APIKey: "test"})
+
+	require.NoError(t, client.MoveChunk(context.Background(), &models.MoveChunkRequest{ChunkID: "chunk4", NewParentID: &parent, NewPosition: 1}))
+	require.Len(t, server.patches, 1)
+	key := server.patches["chunk4"]
+	assert.True(t, "1" < key && key < "2", key)
+
+	// Siblings written without keys are given keys once, then sort by them
+	server.patches = map[string]string{}
+	for i := range server.siblings {
+		server.siblings[i].SortKey = ""
+	}
+	require.NoError(t, client.ReorderChildren(context.Background(), parent, []string{"chunk2", "chunk1", "chunk3", "chunk4"}))
+	assert.Len(t, server.patches, 4)
+	assert.Less(t, server.patches["chunk2"], server.patches["chunk1"])
+	assert.Less(t, server.patches["chunk3"], server.patches["chunk4"])
+
+	err := client.ReorderChildren(context.Background(), parent, []string{"chunk1", "chunk1", "chunk3", "chunk4"})
+	assert.Error(t, err, "every child must be listed once")
+}
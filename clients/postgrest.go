@@ -0,0 +1,233 @@
+package clients
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PostgRESTQuery builds the endpoint of a PostgREST table request. Filters are added
+// with the operator helpers below, which take care of quoting values that would
+// otherwise break the filter syntax.
+type PostgRESTQuery struct {
+	table  string
+	params url.Values
+	orders []string
+}
+
+// NewPostgRESTQuery starts a query against a table
+func NewPostgRESTQuery(table string) *PostgRESTQuery {
+	return &PostgRESTQuery{table: table, params: url.Values{}}
+}
+
+// Select sets the columns to return
+func (q *PostgRESTQuery) Select(columns ...string) *PostgRESTQuery {
+	q.params.Set("select", strings.Join(columns, ","))
+	return q
+}
+
+// Where adds filters; all of them must match. Filtering the same column twice is
+// allowed, e.g. for a range.
+func (q *PostgRESTQuery) Where(filters ...Filter) *PostgRESTQuery {
+	for _, filter := range filters {
+		key, value := filter.param()
+		q.params.Add(key, value)
+	}
+	return q
+}
+
+// OrderAsc sorts by a column in ascending order, after any earlier sort columns
+func (q *PostgRESTQuery) OrderAsc(column string) *PostgRESTQuery {
+	q.orders = append(q.orders, column+".asc")
+	return q
+}
+
+// OrderDesc sorts by a column in descending order, after any earlier sort columns
+func (q *PostgRESTQuery) OrderDesc(column string) *PostgRESTQuery {
+	q.orders = append(q.orders, column+".desc")
+	return q
+}
+
+// Limit caps the number of rows returned
+func (q *PostgRESTQuery) Limit(n int) *PostgRESTQuery {
+	q.params.Set("limit", strconv.Itoa(n))
+	return q
+}
+
+// Offset skips the first n rows
+func (q *PostgRESTQuery) Offset(n int) *PostgRESTQuery {
+	q.params.Set("offset", strconv.Itoa(n))
+	return q
+}
+
+// String returns the endpoint path with its encoded query string
+func (q *PostgRESTQuery) String() string {
+	params := q.params
+	if len(q.orders) > 0 {
+		params = make(url.Values, len(q.params)+1)
+		for key, values := range q.params {
+			params[key] = values
+		}
+		params.Set("order", strings.Join(q.orders, ","))
+	}
+
+	endpoint := "/" + q.table
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	return endpoint
+}
+
+// Filter is a single PostgREST condition or a logical group of conditions
+type Filter struct {
+	column   string
+	operator string
+	value    string
+	values   []string
+	children []Filter
+	negated  bool
+}
+
+// Eq matches rows where column equals value
+func Eq(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "eq", value: formatFilterValue(value)}
+}
+
+// Neq matches rows where column does not equal value
+func Neq(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "neq", value: formatFilterValue(value)}
+}
+
+// Gt matches rows where column is greater than value
+func Gt(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "gt", value: formatFilterValue(value)}
+}
+
+// Gte matches rows where column is greater than or equal to value
+func Gte(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "gte", value: formatFilterValue(value)}
+}
+
+// Lt matches rows where column is less than value
+func Lt(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "lt", value: formatFilterValue(value)}
+}
+
+// Lte matches rows where column is less than or equal to value
+func Lte(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "lte", value: formatFilterValue(value)}
+}
+
+// Like matches rows where column matches a case-sensitive pattern; * is the wildcard
+func Like(column, pattern string) Filter {
+	return Filter{column: column, operator: "like", value: pattern}
+}
+
+// ILike matches rows where column matches a case-insensitive pattern; * is the wildcard
+func ILike(column, pattern string) Filter {
+	return Filter{column: column, operator: "ilike", value: pattern}
+}
+
+// Is matches rows where column is null, true or false. A nil value means null.
+func Is(column string, value interface{}) Filter {
+	return Filter{column: column, operator: "is", value: formatFilterValue(value)}
+}
+
+// IsNull matches rows where column is null
+func IsNull(column string) Filter {
+	return Is(column, nil)
+}
+
+// In matches rows where column equals any of values
+func In(column string, values []string) Filter {
+	return Filter{column: column, operator: "in", values: values}
+}
+
+// Or matches rows matching any of filters
+func Or(filters ...Filter) Filter {
+	return Filter{operator: "or", children: filters}
+}
+
+// And matches rows matching all of filters. Top-level filters are already combined
+// with and, so this is only needed inside Or.
+func And(filters ...Filter) Filter {
+	return Filter{operator: "and", children: filters}
+}
+
+// Not negates a filter
+func Not(filter Filter) Filter {
+	filter.negated = !filter.negated
+	return filter
+}
+
+// param returns the filter as a query string key and value
+func (f Filter) param() (string, string) {
+	if f.children != nil {
+		return f.prefix() + f.operator, f.group()
+	}
+	return f.column, f.prefix() + f.operator + "." + f.operand(false)
+}
+
+// nested returns the filter as an element of a logical group
+func (f Filter) nested() string {
+	if f.children != nil {
+		return f.prefix() + f.operator + f.group()
+	}
+	return f.column + "." + f.prefix() + f.operator + "." + f.operand(true)
+}
+
+func (f Filter) prefix() string {
+	if f.negated {
+		return "not."
+	}
+	return ""
+}
+
+func (f Filter) group() string {
+	elements := make([]string, len(f.children))
+	for i, child := range f.children {
+		elements[i] = child.nested()
+	}
+	return "(" + strings.Join(elements, ",") + ")"
+}
+
+// operand renders the filter's value. Values inside lists and logical groups are
+// quoted, since commas, dots and parentheses are part of the syntax there; a
+// top-level value extends to the end of the parameter and needs no quoting.
+func (f Filter) operand(quoted bool) string {
+	switch {
+	case f.operator == "in":
+		elements := make([]string, len(f.values))
+		for i, value := range f.values {
+			elements[i] = quoteFilterValue(value)
+		}
+		return "(" + strings.Join(elements, ",") + ")"
+	case f.operator == "is" || !quoted:
+		return f.value
+	default:
+		return quoteFilterValue(f.value)
+	}
+}
+
+// quoteFilterValue wraps a value in double quotes, escaping backslashes and quotes
+func quoteFilterValue(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
+
+func formatFilterValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
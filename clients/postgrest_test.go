@@ -0,0 +1,67 @@
+package clients
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decodedQuery splits an endpoint into its path and decoded query parameters
+func decodedQuery(t *testing.T, endpoint string) (string, url.Values) {
+	t.Helper()
+	parsed, err := url.Parse(endpoint)
+	require.NoError(t, err)
+	return parsed.Path, parsed.Query()
+}
+
+func TestPostgRESTQuery_Operators(t *testing.T) {
+	endpoint := NewPostgRESTQuery("chunks").
+		Select("id", "content").
+		Where(Eq("text_id", "t1"), Neq("id", "c1"), Eq("is_slot", false), IsNull("parent_chunk_id")).
+		Where(Gte("indent_level", 1), Lte("indent_level", 3), ILike("content", "*note*")).
+		OrderAsc("sequence_number").OrderDesc("created_at").
+		Limit(20).
+		Offset(40).
+		String()
+
+	path, query := decodedQuery(t, endpoint)
+	assert.Equal(t, "/chunks", path)
+	assert.Equal(t, "id,content", query.Get("select"))
+	assert.Equal(t, "eq.t1", query.Get("text_id"))
+	assert.Equal(t, "neq.c1", query.Get("id"))
+	assert.Equal(t, "eq.false", query.Get("is_slot"))
+	assert.Equal(t, "is.null", query.Get("parent_chunk_id"))
+	assert.Equal(t, []string{"gte.1", "lte.3"}, query["indent_level"], "both ends of a range are kept")
+	assert.Equal(t, "ilike.*note*", query.Get("content"))
+	assert.Equal(t, "sequence_number.asc,created_at.desc", query.Get("order"))
+	assert.Equal(t, "20", query.Get("limit"))
+	assert.Equal(t, "40", query.Get("offset"))
+
+	assert.Equal(t, "/texts", NewPostgRESTQuery("texts").String())
+}
+
+func TestPostgRESTQuery_Escaping(t *testing.T) {
+	tricky := `a,b.c(d)"e\f&g=h`
+
+	// Top-level values run to the end of the parameter, so only URL encoding is needed
+	_, query := decodedQuery(t, NewPostgRESTQuery("chunks").Where(Eq("content", tricky)).String())
+	assert.Equal(t, "eq."+tricky, query.Get("content"))
+
+	_, query = decodedQuery(t, NewPostgRESTQuery("chunks").Where(In("id", []string{"a", "b,c", `d"e`})).String())
+	assert.Equal(t, `in.("a","b,c","d\"e")`, query.Get("id"))
+
+	_, query = decodedQuery(t, NewPostgRESTQuery("graph_edges").
+		Where(Or(Eq("source_node_id", "x.y,z"), And(Eq("target_node_id", tricky), Not(IsNull("weight"))))).
+		String())
+	assert.Equal(t, `(source_node_id.eq."x.y,z",and(target_node_id.eq."a,b.c(d)\"e\\f&g=h",weight.not.is.null))`, query.Get("or"))
+}
+
+func TestPostgRESTQuery_NegatedGroups(t *testing.T) {
+	_, query := decodedQuery(t, NewPostgRESTQuery("chunks").
+		Where(Not(Or(Eq("is_template", true), Eq("is_slot", true))), Not(In("id", []string{"a"}))).
+		String())
+	assert.Equal(t, "(is_template.eq.\"true\",is_slot.eq.\"true\")", query.Get("not.or"))
+	assert.Equal(t, `not.in.("a")`, query.Get("id"))
+}
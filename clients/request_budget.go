@@ -0,0 +1,112 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RequestBudget limits the HTTP requests a single logical operation, such as a graph
+// traversal, may issue against Supabase
+type RequestBudget struct {
+	// MaxRequests caps the requests issued, retries included; 0 means unlimited
+	MaxRequests int
+	// Reserve is kept free before the context deadline so the operation can stop and
+	// return partial results instead of failing at the deadline
+	Reserve time.Duration
+}
+
+// DefaultGraphTraversalBudget applies to graph traversals whose context carries no budget
+var DefaultGraphTraversalBudget = RequestBudget{
+	MaxRequests: 500,
+	Reserve:     100 * time.Millisecond,
+}
+
+// ErrRequestBudgetExhausted is returned for requests an operation can no longer afford
+var ErrRequestBudgetExhausted = errors.New("request budget exhausted")
+
+type requestBudgetKey struct{}
+
+// requestBudgetTracker counts the requests made under a budget and learns their latency
+type requestBudgetTracker struct {
+	budget RequestBudget
+
+	mu         sync.Mutex
+	used       int
+	avgLatency time.Duration
+}
+
+// WithRequestBudget returns a context whose Supabase requests are limited by budget.
+// Requests made after it is spent fail with ErrRequestBudgetExhausted.
+func WithRequestBudget(ctx context.Context, budget RequestBudget) context.Context {
+	return context.WithValue(ctx, requestBudgetKey{}, &requestBudgetTracker{budget: budget})
+}
+
+// withDefaultRequestBudget applies budget unless ctx already carries one
+func withDefaultRequestBudget(ctx context.Context, budget RequestBudget) context.Context {
+	if requestBudgetFrom(ctx) != nil {
+		return ctx
+	}
+	return WithRequestBudget(ctx, budget)
+}
+
+func requestBudgetFrom(ctx context.Context) *requestBudgetTracker {
+	tracker, _ := ctx.Value(requestBudgetKey{}).(*requestBudgetTracker)
+	return tracker
+}
+
+// spend reserves one request, refusing when the budget is used up or when the time left
+// before the deadline would not cover the reserve plus a typical request
+func (t *requestBudgetTracker) spend(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.budget.MaxRequests > 0 && t.used >= t.budget.MaxRequests {
+		return fmt.Errorf("%w: all %d requests used", ErrRequestBudgetExhausted, t.budget.MaxRequests)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < t.budget.Reserve+t.avgLatency {
+			return fmt.Errorf("%w: only %v left (%w)", ErrRequestBudgetExhausted, remaining.Round(time.Millisecond), context.DeadlineExceeded)
+		}
+	}
+	t.used++
+	return nil
+}
+
+// record folds a request's latency into the running estimate
+func (t *requestBudgetTracker) record(latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.avgLatency == 0 {
+		t.avgLatency = latency
+		return
+	}
+	// Exponentially weighted so the estimate follows recent conditions
+	t.avgLatency = (t.avgLatency*3 + latency) / 4
+}
+
+// isBudgetStop reports whether err means the operation ran out of time or requests, as
+// opposed to a request failing
+func isBudgetStop(err error) bool {
+	return errors.Is(err, ErrRequestBudgetExhausted) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled)
+}
+
+// truncationReason describes why a traversal stopped early
+func truncationReason(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline"
+	default:
+		return "request_budget"
+	}
+}
@@ -0,0 +1,135 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newChainGraphServer serves a PostgREST-style graph n0 - n1 - ... - n(size-1) without the
+// search_graph RPC, so traversals fall back to one request per hop
+func newChainGraphServer(t *testing.T, size int, latency time.Duration, requests *int32) *httptest.Server {
+	node := func(i int) models.GraphNode {
+		return models.GraphNode{ID: fmt.Sprintf("n%d", i), EntityName: fmt.Sprintf("entity-%d", i), EntityType: "concept"}
+	}
+	index := func(id string) int {
+		var i int
+		fmt.Sscanf(id, "n%d", &i)
+		return i
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		time.Sleep(latency)
+		query := r.URL.Query()
+
+		var body interface{}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/rpc/search_graph"):
+			w.WriteHeader(http.StatusNotFound)
+			body = map[string]string{"code": "PGRST202", "message": "function not found"}
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes") && query.Get("entity_name") != "":
+			body = []models.GraphNode{node(0)}
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes") && strings.HasPrefix(query.Get("id"), "in."):
+			var nodes []models.GraphNode
+			for _, id := range parseInFilter(query.Get("id")) {
+				nodes = append(nodes, node(index(id)))
+			}
+			body = nodes
+		case strings.HasSuffix(r.URL.Path, "/graph_nodes"):
+			body = []models.GraphNode{node(index(strings.TrimPrefix(query.Get("id"), "eq.")))}
+		case strings.HasSuffix(r.URL.Path, "/graph_edges"):
+			id := strings.Trim(strings.TrimPrefix(strings.SplitN(query.Get("or"), ",", 2)[0], "(source_node_id.eq."), `"`)
+			i := index(id)
+			var edges []models.GraphEdge
+			if i > 0 {
+				edges = append(edges, models.GraphEdge{ID: fmt.Sprintf("e%d", i-1), SourceNodeID: fmt.Sprintf("n%d", i-1), TargetNodeID: id})
+			}
+			if i < size-1 {
+				edges = append(edges, models.GraphEdge{ID: fmt.Sprintf("e%d", i), SourceNodeID: id, TargetNodeID: fmt.Sprintf("n%d", i+1)})
+			}
+			body = edges
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			body = map[string]string{"code": "404", "message": "unexpected path " + r.URL.Path}
+		}
+		json.NewEncoder(w).Encode(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSearchGraph_RequestBudgetTruncates(t *testing.T) {
+	var requests int32
+	server := newChainGraphServer(t, 50, 0, &requests)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	ctx := WithRequestBudget(context.Background(), RequestBudget{MaxRequests: 8})
+	result, err := client.SearchGraph(ctx, &models.GraphQuery{EntityName: "entity-0", MaxDepth: 40, Limit: 50})
+	require.NoError(t, err)
+
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "request_budget", result.TruncatedReason)
+	assert.Greater(t, len(result.Nodes), 1, "nodes found before the budget ran out are returned")
+	assert.Less(t, len(result.Nodes), 50)
+	assert.LessOrEqual(t, atomic.LoadInt32(&requests), int32(8))
+}
+
+func TestSearchGraph_StopsBeforeDeadline(t *testing.T) {
+	var requests int32
+	server := newChainGraphServer(t, 200, 10*time.Millisecond, &requests)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	ctx = WithRequestBudget(ctx, RequestBudget{Reserve: 50 * time.Millisecond})
+
+	result, err := client.GetNodeNeighbors(ctx, "n0", 150)
+	require.NoError(t, err)
+	assert.NoError(t, ctx.Err(), "the traversal returned with time to spare")
+	assert.True(t, result.Truncated)
+	assert.Equal(t, "deadline", result.TruncatedReason)
+	assert.Greater(t, len(result.Nodes), 1)
+}
+
+func TestSearchGraph_CompleteWithinBudget(t *testing.T) {
+	var requests int32
+	server := newChainGraphServer(t, 4, 0, &requests)
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"})
+
+	result, err := client.FindPathBetweenNodes(context.Background(), "n0", "n3", 5)
+	require.NoError(t, err)
+	assert.False(t, result.Truncated)
+	assert.Len(t, result.Nodes, 4)
+	assert.Len(t, result.Edges, 3)
+}
+
+func TestRequestBudget_NotRetried(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(t, isRetryableError(ctx.Err()))
+	assert.False(t, isRetryableError(fmt.Errorf("request failed: %w", context.DeadlineExceeded)))
+	assert.False(t, isRetryableError(ErrRequestBudgetExhausted))
+	assert.True(t, isRetryableError(errors.New("connection reset")))
+
+	tracker := &requestBudgetTracker{budget: RequestBudget{MaxRequests: 1}}
+	require.NoError(t, tracker.spend(context.Background()))
+	err := tracker.spend(context.Background())
+	assert.ErrorIs(t, err, ErrRequestBudgetExhausted)
+	assert.Equal(t, "request_budget", truncationReason(err))
+	assert.Equal(t, "canceled", truncationReason(ctx.Err()))
+}
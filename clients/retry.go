@@ -0,0 +1,215 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RetryConfig defines retry behavior for failed requests
+type RetryConfig struct {
+	// MaxRetries bounds retries of idempotent requests: reads, deletes and read-only RPCs
+	MaxRetries int
+	// MaxWriteRetries bounds retries of inserts and updates. These are only retried when
+	// the server cannot have applied them: 429 and 503 responses and failed connections.
+	MaxWriteRetries int
+	// BaseDelay is the backoff ceiling of the first retry; it doubles with each attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff ceiling
+	MaxDelay time.Duration
+	// MaxRetryAfter is the longest Retry-After the client waits out; a server asking
+	// for longer fails the request instead
+	MaxRetryAfter time.Duration
+}
+
+// DefaultRetryConfig provides sensible defaults for retry behavior
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxRetries:      3,
+		MaxWriteRetries: 2,
+		BaseDelay:       100 * time.Millisecond,
+		MaxDelay:        5 * time.Second,
+		MaxRetryAfter:   30 * time.Second,
+	}
+}
+
+// readOnlyRPCs are the RPC endpoints that only read, so retrying them is safe
+var readOnlyRPCs = map[string]bool{
+	"/rpc/match_chunks": true,
+	"/rpc/search_graph": true,
+}
+
+// isIdempotentRequest reports whether repeating a request cannot change its effect
+func isIdempotentRequest(method, endpoint string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		path, _, _ := strings.Cut(endpoint, "?")
+		return readOnlyRPCs[path]
+	}
+	return false
+}
+
+// executeWithRetry executes an operation with exponential backoff and full jitter,
+// waiting out Retry-After when the server sends one
+func (c *supabaseHTTPClient) executeWithRetry(ctx context.Context, idempotent bool, operation func() error) error {
+	maxRetries, retryable := c.retry.MaxRetries, isRetryableError
+	if !idempotent {
+		maxRetries, retryable = c.retry.MaxWriteRetries, isRetryableWrite
+	}
+
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		delay := c.throttle.remaining()
+		if attempt > 0 {
+			retryDelay, ok := c.retryDelay(attempt, lastErr)
+			if !ok {
+				return lastErr
+			}
+			delay = max(delay, retryDelay)
+		}
+
+		if delay > 0 {
+			// Waiting out the deadline would only turn the last error into a timeout
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+				if lastErr != nil {
+					return lastErr
+				}
+				return fmt.Errorf("rate limited for %v: %w", delay, context.DeadlineExceeded)
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		err := operation()
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		c.noteRateLimit(err)
+
+		if !retryable(err) {
+			return err
+		}
+		if attempt >= maxRetries {
+			return fmt.Errorf("operation failed after %d retries: %w", maxRetries, lastErr)
+		}
+	}
+}
+
+// retryDelay returns how long to wait before the given retry attempt. It is false
+// when the server asked for a longer wait than MaxRetryAfter.
+func (c *supabaseHTTPClient) retryDelay(attempt int, lastErr error) (time.Duration, bool) {
+	var supabaseErr *SupabaseError
+	if errors.As(lastErr, &supabaseErr) && supabaseErr.RetryAfter > 0 {
+		return supabaseErr.RetryAfter, supabaseErr.RetryAfter <= c.retry.MaxRetryAfter
+	}
+	return backoffDelay(c.retry, attempt), true
+}
+
+// backoffDelay picks a random delay below BaseDelay*2^(attempt-1), capped at MaxDelay,
+// so clients failing together do not retry together
+func backoffDelay(retry *RetryConfig, attempt int) time.Duration {
+	ceiling := retry.BaseDelay
+	for i := 1; i < attempt && ceiling < retry.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, retry.MaxDelay)
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) + 1
+}
+
+// noteRateLimit holds back every request on the client after a 429, so concurrent
+// callers slow down together instead of each discovering the limit
+func (c *supabaseHTTPClient) noteRateLimit(err error) {
+	var supabaseErr *SupabaseError
+	if !errors.As(err, &supabaseErr) || supabaseErr.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+	wait := supabaseErr.RetryAfter
+	if wait <= 0 {
+		wait = c.retry.BaseDelay
+	}
+	c.throttle.extend(time.Now().Add(min(wait, c.retry.MaxRetryAfter)))
+}
+
+// rateLimitThrottle records until when the server asked the client to back off
+type rateLimitThrottle struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (t *rateLimitThrottle) extend(until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.until) {
+		t.until = until
+	}
+}
+
+func (t *rateLimitThrottle) remaining() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Until(t.until)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0)
+	}
+	return 0
+}
+
+// isRetryableError determines if an error should trigger a retry of an idempotent request
+func isRetryableError(err error) bool {
+	if isBudgetStop(err) {
+		return false // Retrying cannot help once the caller's time or budget is spent
+	}
+	var supabaseErr *SupabaseError
+	if errors.As(err, &supabaseErr) {
+		if supabaseErr.StatusCode == 0 {
+			return strings.HasPrefix(supabaseErr.Code, "5")
+		}
+		// Don't retry client errors (4xx) other than timeouts and rate limits
+		return supabaseErr.StatusCode >= 500 ||
+			supabaseErr.StatusCode == http.StatusTooManyRequests ||
+			supabaseErr.StatusCode == http.StatusRequestTimeout
+	}
+	return true // Retry network errors and other unknown errors
+}
+
+// isRetryableWrite determines if an error should trigger a retry of a request that is
+// not idempotent. Only failures that guarantee the server did not apply it qualify.
+func isRetryableWrite(err error) bool {
+	if isBudgetStop(err) {
+		return false
+	}
+	var supabaseErr *SupabaseError
+	if errors.As(err, &supabaseErr) {
+		return supabaseErr.StatusCode == http.StatusTooManyRequests ||
+			supabaseErr.StatusCode == http.StatusServiceUnavailable
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
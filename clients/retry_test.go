@@ -0,0 +1,120 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newStatusServer answers every request with the next of statuses, then with 200 and
+// an empty list, counting the requests it receives
+func newStatusServer(t *testing.T, requests *int32, retryAfter string, statuses ...int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(requests, 1))
+		w.Header().Set("Content-Type", "application/json")
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[n-1])
+			w.Write([]byte(`{"code":"X","message":"try again"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func fastRetryClient(url string) SupabaseClient {
+	return NewSupabaseClientWithRetry(&config.SupabaseConfig{URL: url, APIKey: "test"}, &RetryConfig{
+		MaxRetries:      3,
+		MaxWriteRetries: 1,
+		BaseDelay:       time.Millisecond,
+		MaxDelay:        5 * time.Millisecond,
+		MaxRetryAfter:   2 * time.Second,
+	})
+}
+
+func TestExecuteWithRetry_IdempotentAndWriteBudgets(t *testing.T) {
+	ctx := context.Background()
+
+	var reads int32
+	_, err := fastRetryClient(newStatusServer(t, &reads, "", 500, 502).URL).GetChunksByTextID(ctx, "t")
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), reads, "reads are retried after server errors")
+
+	var writes int32
+	err = fastRetryClient(newStatusServer(t, &writes, "", 500).URL).InsertChunk(ctx, &models.ChunkRecord{Content: "x"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), writes, "a write that may have been applied is not repeated")
+
+	var unavailable int32
+	err = fastRetryClient(newStatusServer(t, &unavailable, "", 503, 503, 503).URL).InsertChunk(ctx, &models.ChunkRecord{Content: "x"})
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), unavailable, "writes turned away get their own smaller budget")
+
+	var notFound int32
+	_, err = fastRetryClient(newStatusServer(t, &notFound, "", 404).URL).GetChunksByTextID(ctx, "t")
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), notFound)
+}
+
+func TestExecuteWithRetry_HonorsRetryAfter(t *testing.T) {
+	ctx := context.Background()
+
+	var requests int32
+	start := time.Now()
+	_, err := fastRetryClient(newStatusServer(t, &requests, "1", 429).URL).GetChunksByTextID(ctx, "t")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), requests)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second, "the client waits as long as the server asked")
+
+	var tooLong int32
+	start = time.Now()
+	_, err = fastRetryClient(newStatusServer(t, &tooLong, "120", 429).URL).GetChunksByTextID(ctx, "t")
+	var supabaseErr *SupabaseError
+	require.ErrorAs(t, err, &supabaseErr)
+	assert.Equal(t, http.StatusTooManyRequests, supabaseErr.StatusCode)
+	assert.Equal(t, 120*time.Second, supabaseErr.RetryAfter)
+	assert.Equal(t, int32(1), tooLong)
+	assert.Less(t, time.Since(start), time.Second, "waits beyond MaxRetryAfter fail fast")
+}
+
+func TestBackoffDelay(t *testing.T) {
+	retry := &RetryConfig{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for i := 0; i < 100; i++ {
+		assert.LessOrEqual(t, backoffDelay(retry, 1), 100*time.Millisecond)
+		assert.LessOrEqual(t, backoffDelay(retry, 3), 400*time.Millisecond)
+		delay := backoffDelay(retry, 10)
+		assert.Positive(t, delay)
+		assert.LessOrEqual(t, delay, time.Second)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, 90*time.Second, parseRetryAfter("Mon, 15 Jan 2024 12:01:30 GMT", now))
+	assert.Zero(t, parseRetryAfter("Mon, 15 Jan 2024 11:00:00 GMT", now))
+	assert.Zero(t, parseRetryAfter("-5", now))
+	assert.Zero(t, parseRetryAfter("soon", now))
+	assert.Zero(t, parseRetryAfter("", now))
+}
+
+func TestIsIdempotentRequest(t *testing.T) {
+	assert.True(t, isIdempotentRequest(http.MethodGet, "/chunks?id=eq.a"))
+	assert.True(t, isIdempotentRequest(http.MethodDelete, "/chunks?id=eq.a"))
+	assert.True(t, isIdempotentRequest(http.MethodPost, "/rpc/search_graph"))
+	assert.False(t, isIdempotentRequest(http.MethodPost, "/chunks"))
+	assert.False(t, isIdempotentRequest(http.MethodPatch, "/chunks?id=eq.a"))
+}
@@ -2,8 +2,13 @@ package clients
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
+	"semantic-text-processor/config"
 	"semantic-text-processor/models"
 
 	"github.com/stretchr/testify/assert"
@@ -353,4 +358,35 @@ func TestTask53Requirements(t *testing.T) {
 		assert.GreaterOrEqual(t, expectedResult.Similarity, 0.0)
 		assert.LessOrEqual(t, expectedResult.Similarity, 1.0)
 	})
-}
\ No newline at end of file
+}
+func TestSearchSimilarFiltered_SendsFilterArguments(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/rest/v1/rpc/match_chunks", r.URL.Path)
+		body = nil
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"chunk": map[string]interface{}{"id": "chunk-1", "content": "hello"}, "similarity": 0.9},
+		})
+	}))
+	defer server.Close()
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "test"}).(*supabaseHTTPClient)
+
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	results, err := client.SearchSimilarFiltered(context.Background(), []float64{0.1, 0.2}, 5, models.SimilarityFilter{
+		TagIDs: []string{"tag-a"}, PageID: "page-1", CreatedBefore: &before,
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "chunk-1", results[0].Chunk.ID)
+	assert.Equal(t, []interface{}{"tag-a"}, body["filter_tags"])
+	assert.Equal(t, "page-1", body["filter_page"])
+	assert.Equal(t, "2024-02-01T00:00:00Z", body["filter_created_before"])
+	assert.NotContains(t, body, "filter_created_after")
+
+	// Unfiltered searches send no filter arguments
+	_, err = client.SearchSimilar(context.Background(), []float64{0.1, 0.2}, 5)
+	require.NoError(t, err)
+	assert.NotContains(t, body, "filter_tags")
+	assert.NotContains(t, body, "filter_page")
+}
@@ -0,0 +1,209 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultStreamPageSize is the number of rows requested per page when streaming a query
+const DefaultStreamPageSize = 1000
+
+// maxErrorBodySize bounds how much of an error response is read into a SupabaseError
+const maxErrorBodySize = 64 << 10
+
+// ErrStopStream may be returned by a row callback to end a stream early without an error
+var ErrStopStream = errors.New("stop stream")
+
+// RowStreamer streams large result sets. Rows are decoded one at a time from the
+// response body and handed to a callback instead of being buffered, so exporting
+// hundreds of thousands of chunks takes constant memory.
+type RowStreamer interface {
+	// StreamRows GETs endpoint, which must return a JSON array, and calls fn with each
+	// element. It returns the number of rows passed to fn.
+	StreamRows(ctx context.Context, endpoint string, fn func(row json.RawMessage) error) (int, error)
+
+	// StreamQuery fetches the rows of query in pages of pageSize, using Range headers,
+	// and calls fn with each row. The query should be ordered by a unique column and set
+	// no limit or offset. It returns the number of rows passed to fn.
+	StreamQuery(ctx context.Context, query *PostgRESTQuery, pageSize int, fn func(row json.RawMessage) error) (int, error)
+}
+
+// StreamRows streams the JSON array returned by a single GET request
+func (c *supabaseHTTPClient) StreamRows(ctx context.Context, endpoint string, fn func(row json.RawMessage) error) (int, error) {
+	resp, err := c.openStream(ctx, endpoint, "")
+	if err != nil {
+		return 0, fmt.Errorf("failed to stream %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	n, err := decodeRows(resp.Body, fn)
+	if errors.Is(err, ErrStopStream) {
+		return n, nil
+	}
+	return n, err
+}
+
+// StreamQuery streams a query page by page. Each page is its own request, so a failed
+// page is retried without redelivering the rows of earlier pages.
+func (c *supabaseHTTPClient) StreamQuery(ctx context.Context, query *PostgRESTQuery, pageSize int, fn func(row json.RawMessage) error) (int, error) {
+	if pageSize <= 0 {
+		pageSize = DefaultStreamPageSize
+	}
+	endpoint := query.String()
+
+	total := 0
+	for offset := 0; ; offset += pageSize {
+		resp, err := c.openStream(ctx, endpoint, fmt.Sprintf("%d-%d", offset, offset+pageSize-1))
+		if err != nil {
+			// PostgREST answers a range past the last row with 416
+			var supabaseErr *SupabaseError
+			if errors.As(err, &supabaseErr) && supabaseErr.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+				return total, nil
+			}
+			return total, fmt.Errorf("failed to stream rows from %d: %w", offset, err)
+		}
+
+		n, err := decodeRows(resp.Body, fn)
+		resp.Body.Close()
+		total += n
+		if errors.Is(err, ErrStopStream) {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		if n < pageSize || lastContentRange(resp.Header.Get("Content-Range")) {
+			return total, nil
+		}
+	}
+}
+
+// openStream sends a GET request, retrying until the response headers arrive, and
+// returns the response with its body still unread. rows is the item range to request,
+// if any.
+func (c *supabaseHTTPClient) openStream(ctx context.Context, endpoint, rows string) (*http.Response, error) {
+	var resp *http.Response
+	err := c.executeWithRetry(ctx, true, func() error {
+		return c.withBudget(ctx, func() error {
+			req, err := c.newRequest(ctx, http.MethodGet, endpoint, nil)
+			if err != nil {
+				return err
+			}
+			if rows != "" {
+				req.Header.Set("Range-Unit", "items")
+				req.Header.Set("Range", rows)
+			}
+
+			r, err := c.streamingHTTPClient().Do(req)
+			if err != nil {
+				return fmt.Errorf("request failed: %w", err)
+			}
+			if r.StatusCode >= 400 {
+				defer r.Body.Close()
+				body, _ := io.ReadAll(io.LimitReader(r.Body, maxErrorBodySize))
+				return responseError(r, body)
+			}
+			resp = r
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// streamingHTTPClient returns the client without its overall timeout, which would
+// also cut off reading a long body. The request context still bounds the stream.
+func (c *supabaseHTTPClient) streamingHTTPClient() *http.Client {
+	client := *c.httpClient
+	client.Timeout = 0
+	return &client
+}
+
+// decodeRows calls fn with each element of the JSON array read from body. An error
+// returned by fn ends decoding and is returned unchanged.
+func decodeRows(body io.Reader, fn func(row json.RawMessage) error) (int, error) {
+	decoder := json.NewDecoder(body)
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return 0, fmt.Errorf("failed to decode response: expected a JSON array")
+	}
+
+	n := 0
+	for decoder.More() {
+		var row json.RawMessage
+		if err := decoder.Decode(&row); err != nil {
+			return n, fmt.Errorf("failed to decode row %d: %w", n, err)
+		}
+		n++
+		if err := fn(row); err != nil {
+			return n, err
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return n, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return n, nil
+}
+
+// lastContentRange reports whether a Content-Range header such as "0-999/1200" ends
+// at the last row. An unknown total ("0-999/*") is never the last page.
+func lastContentRange(header string) bool {
+	rows, total, ok := strings.Cut(header, "/")
+	if !ok || total == "*" {
+		return false
+	}
+	count, err := strconv.Atoi(total)
+	if err != nil {
+		return false
+	}
+	_, last, ok := strings.Cut(rows, "-")
+	if !ok {
+		return rows == "*"
+	}
+	end, err := strconv.Atoi(last)
+	return err == nil && end+1 >= count
+}
+
+// StreamAs adapts a callback taking decoded rows of type T to a row callback
+func StreamAs[T any](fn func(row *T) error) func(row json.RawMessage) error {
+	return func(row json.RawMessage) error {
+		var value T
+		if err := json.Unmarshal(row, &value); err != nil {
+			return fmt.Errorf("failed to unmarshal row: %w", err)
+		}
+		return fn(&value)
+	}
+}
+
+// QueryRows iterates over the rows of query decoded as T, streaming them page by page.
+// A failure is yielded once, with the zero T, and ends the iteration; breaking out of
+// the loop ends the stream.
+func QueryRows[T any](ctx context.Context, streamer RowStreamer, query *PostgRESTQuery, pageSize int) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		_, err := streamer.StreamQuery(ctx, query, pageSize, StreamAs(func(row *T) error {
+			if !yield(*row, nil) {
+				return ErrStopStream
+			}
+			return nil
+		}))
+		if err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newRangeServer serves rows chunk-0 to chunk-(size-1) of /chunks, honoring Range
+// headers like PostgREST. Without total the Content-Range total is left unknown.
+func newRangeServer(t *testing.T, size int, total bool, requests *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(requests, 1)
+		from, to := 0, size-1
+		if rows := r.Header.Get("Range"); rows != "" {
+			assert.Equal(t, "items", r.Header.Get("Range-Unit"))
+			first, last, _ := strings.Cut(rows, "-")
+			from, _ = strconv.Atoi(first)
+			to, _ = strconv.Atoi(last)
+		}
+		if from >= size && size > 0 {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			w.Write([]byte(`{"code":"PGRST103","message":"Requested range not satisfiable"}`))
+			return
+		}
+		to = min(to, size-1)
+
+		count := "*"
+		if total {
+			count = strconv.Itoa(size)
+		}
+		w.Header().Set("Content-Range", fmt.Sprintf("%d-%d/%s", from, to, count))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("["))
+		for i := from; i <= to; i++ {
+			if i > from {
+				w.Write([]byte(","))
+			}
+			fmt.Fprintf(w, `{"id":"chunk-%d","content":"row %d"}`, i, i)
+		}
+		w.Write([]byte("]"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newStreamer(url string) RowStreamer {
+	return NewSupabaseClient(&config.SupabaseConfig{URL: url, APIKey: "key"}).(RowStreamer)
+}
+
+func TestStreamQuery_PagesWithRangeHeaders(t *testing.T) {
+	for _, total := range []bool{true, false} {
+		var requests int32
+		streamer := newStreamer(newRangeServer(t, 25, total, &requests).URL)
+
+		var ids []string
+		n, err := streamer.StreamQuery(context.Background(), NewPostgRESTQuery("chunks").OrderAsc("id"), 10,
+			StreamAs(func(chunk *models.ChunkRecord) error {
+				ids = append(ids, chunk.ID)
+				return nil
+			}))
+		require.NoError(t, err)
+		assert.Equal(t, 25, n)
+		require.Len(t, ids, 25)
+		assert.Equal(t, "chunk-24", ids[24])
+		assert.Equal(t, int32(3), requests, "a short last page ends the stream")
+	}
+
+	// A known total saves the request for the empty page after a full last page
+	var requests int32
+	streamer := newStreamer(newRangeServer(t, 20, true, &requests).URL)
+	n, err := streamer.StreamQuery(context.Background(), NewPostgRESTQuery("chunks"), 10, func(json.RawMessage) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, int32(2), requests)
+
+	// Without a total the range past the end answers 416
+	requests = 0
+	streamer = newStreamer(newRangeServer(t, 20, false, &requests).URL)
+	n, err = streamer.StreamQuery(context.Background(), NewPostgRESTQuery("chunks"), 10, func(json.RawMessage) error { return nil })
+	require.NoError(t, err)
+	assert.Equal(t, 20, n)
+	assert.Equal(t, int32(3), requests)
+}
+
+func TestStreamQuery_StopsEarly(t *testing.T) {
+	var requests int32
+	streamer := newStreamer(newRangeServer(t, 100, true, &requests).URL)
+
+	n, err := streamer.StreamQuery(context.Background(), NewPostgRESTQuery("chunks"), 10, func(json.RawMessage) error {
+		return ErrStopStream
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, int32(1), requests)
+
+	var ids []string
+	for chunk, err := range QueryRows[models.ChunkRecord](context.Background(), streamer, NewPostgRESTQuery("chunks"), 10) {
+		require.NoError(t, err)
+		ids = append(ids, chunk.ID)
+		if len(ids) == 15 {
+			break
+		}
+	}
+	assert.Len(t, ids, 15)
+	assert.Equal(t, int32(3), requests)
+}
+
+func TestStreamRows_ReportsErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rest/v1/missing":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"42P01","message":"relation does not exist"}`))
+		case "/rest/v1/truncated":
+			w.Write([]byte(`[{"chunk_id":"a"},{"chunk_id":`))
+		case "/rest/v1/object":
+			w.Write([]byte(`{"chunk_id":"a"}`))
+		default:
+			w.Write([]byte(`[{"chunk_id":"a"},{"chunk_id":"b"}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	streamer := newStreamer(server.URL)
+	ignore := func(json.RawMessage) error { return nil }
+
+	n, err := streamer.StreamRows(context.Background(), "/chunks", ignore)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	_, err = streamer.StreamRows(context.Background(), "/missing", ignore)
+	var supabaseErr *SupabaseError
+	require.ErrorAs(t, err, &supabaseErr)
+	assert.Equal(t, "42P01", supabaseErr.Code)
+
+	n, err = streamer.StreamRows(context.Background(), "/truncated", ignore)
+	assert.Error(t, err)
+	assert.Equal(t, 1, n, "rows decoded before the failure are delivered")
+
+	_, err = streamer.StreamRows(context.Background(), "/object", ignore)
+	assert.ErrorContains(t, err, "expected a JSON array")
+}
+
+func TestLastContentRange(t *testing.T) {
+	assert.True(t, lastContentRange("0-9/10"))
+	assert.True(t, lastContentRange("*/0"))
+	assert.False(t, lastContentRange("0-9/11"))
+	assert.False(t, lastContentRange("0-9/*"))
+	assert.False(t, lastContentRange(""))
+}
@@ -2,12 +2,13 @@ package clients
 
 import (
 	"bytes"
+	"container/heap"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -31,6 +32,7 @@ type SupabaseClient interface {
 	InsertChunk(ctx context.Context, chunk *models.ChunkRecord) error
 	InsertChunks(ctx context.Context, chunks []models.ChunkRecord) error
 	GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error)
+	GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error)
 	GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error)
 	UpdateChunk(ctx context.Context, chunk *models.ChunkRecord) error
 	DeleteChunk(ctx context.Context, id string) error
@@ -45,6 +47,7 @@ type SupabaseClient interface {
 	CreateTemplateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error)
 	GetTemplateInstances(ctx context.Context, templateChunkID string) ([]models.TemplateInstance, error)
 	UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error
+	UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error)
 
 	// Tag operations
 	AddTag(ctx context.Context, chunkID string, tagContent string) error
@@ -57,6 +60,8 @@ type SupabaseClient interface {
 	GetChildrenChunks(ctx context.Context, parentChunkID string) ([]models.ChunkRecord, error)
 	GetSiblingChunks(ctx context.Context, chunkID string) ([]models.ChunkRecord, error)
 	MoveChunk(ctx context.Context, req *models.MoveChunkRequest) error
+	InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error
+	ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error
 	BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error
 
 	// Search operations
@@ -70,11 +75,14 @@ type SupabaseClient interface {
 	// Graph operations
 	InsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error
 	InsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error
+	UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error
+	UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error
 	SearchGraph(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error)
 	GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error)
 	GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error)
 	FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error)
 	GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error)
+	GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error)
 	GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error)
 
 	// Health check
@@ -85,17 +93,32 @@ type SupabaseClient interface {
 type supabaseHTTPClient struct {
 	baseURL    string
 	apiKey     string
+	anonKey    string // sent as apikey for requests made with a user token
 	httpClient *http.Client
+	retry      *RetryConfig
+	throttle   rateLimitThrottle
+	edgeDecay  models.EdgeDecay // decays edge weights in neighbor ordering and path finding
 }
 
-// NewSupabaseClient creates a new Supabase HTTP client
+// NewSupabaseClient creates a new Supabase HTTP client with the default retry behavior
 func NewSupabaseClient(cfg *config.SupabaseConfig) SupabaseClient {
+	return NewSupabaseClientWithRetry(cfg, DefaultRetryConfig())
+}
+
+// NewSupabaseClientWithRetry creates a new Supabase HTTP client with its own retry behavior
+func NewSupabaseClientWithRetry(cfg *config.SupabaseConfig, retry *RetryConfig) SupabaseClient {
+	if retry == nil {
+		retry = DefaultRetryConfig()
+	}
 	return &supabaseHTTPClient{
 		baseURL: strings.TrimSuffix(cfg.URL, "/") + "/rest/v1",
 		apiKey:  cfg.APIKey,
+		anonKey: cfg.AnonKey,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		retry:     retry,
+		edgeDecay: models.DefaultEdgeDecay(),
 	}
 }
 
@@ -105,84 +128,49 @@ type SupabaseError struct {
 	Message string `json:"message"`
 	Details string `json:"details"`
 	Hint    string `json:"hint"`
+	// StatusCode is the HTTP status of the response
+	StatusCode int `json:"-"`
+	// RetryAfter is how long the server asked the client to wait, from the Retry-After header
+	RetryAfter time.Duration `json:"-"`
 }
 
 func (e *SupabaseError) Error() string {
 	return fmt.Sprintf("supabase error [%s]: %s", e.Code, e.Message)
 }
 
-// RetryConfig defines retry behavior for failed requests
-type RetryConfig struct {
-	MaxRetries int
-	BaseDelay  time.Duration
-	MaxDelay   time.Duration
-}
-
-// DefaultRetryConfig provides sensible defaults for retry behavior
-var DefaultRetryConfig = &RetryConfig{
-	MaxRetries: 3,
-	BaseDelay:  100 * time.Millisecond,
-	MaxDelay:   5 * time.Second,
+// makeRequest performs HTTP request to Supabase with authentication
+func (c *supabaseHTTPClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
+	return c.executeWithRetry(ctx, isIdempotentRequest(method, endpoint), func() error {
+		return c.withBudget(ctx, func() error {
+			return c.doRequest(ctx, method, endpoint, body, result)
+		})
+	})
 }
 
-// executeWithRetry executes an operation with exponential backoff retry
-func (c *supabaseHTTPClient) executeWithRetry(ctx context.Context, operation func() error) error {
-	var lastErr error
-	
-	for attempt := 0; attempt <= DefaultRetryConfig.MaxRetries; attempt++ {
-		if attempt > 0 {
-			// Calculate delay with exponential backoff
-			delay := time.Duration(attempt) * DefaultRetryConfig.BaseDelay
-			if delay > DefaultRetryConfig.MaxDelay {
-				delay = DefaultRetryConfig.MaxDelay
-			}
-			
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(delay):
-			}
-		}
-		
-		if err := operation(); err != nil {
-			lastErr = err
-			// Check if error is retryable
-			if !isRetryableError(err) {
-				return err
-			}
-			continue
-		}
-		
-		return nil
+// withBudget runs one request attempt against the request budget of ctx, if any
+func (c *supabaseHTTPClient) withBudget(ctx context.Context, attempt func() error) error {
+	tracker := requestBudgetFrom(ctx)
+	if tracker == nil {
+		return attempt()
 	}
-	
-	return fmt.Errorf("operation failed after %d retries: %w", DefaultRetryConfig.MaxRetries, lastErr)
-}
 
-// isRetryableError determines if an error should trigger a retry
-func isRetryableError(err error) bool {
-	if supabaseErr, ok := err.(*SupabaseError); ok {
-		// Don't retry client errors (4xx), but retry server errors (5xx)
-		return strings.HasPrefix(supabaseErr.Code, "5")
+	if err := tracker.spend(ctx); err != nil {
+		return err
 	}
-	return true // Retry network errors and other unknown errors
+	start := time.Now()
+	err := attempt()
+	tracker.record(time.Since(start))
+	return err
 }
 
-// makeRequest performs HTTP request to Supabase with authentication
-func (c *supabaseHTTPClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
-	return c.executeWithRetry(ctx, func() error {
-		return c.doRequest(ctx, method, endpoint, body, result)
-	})
-}
-
-// doRequest performs the actual HTTP request
-func (c *supabaseHTTPClient) doRequest(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
+// newRequest creates an authenticated request with the given JSON body
+func (c *supabaseHTTPClient) newRequest(ctx context.Context, method, endpoint string, body interface{}) (*http.Request, error) {
 	var reqBody io.Reader
 	
 	if body != nil {
 		jsonData, err := json.Marshal(body)
 		if err != nil {
-			return fmt.Errorf("failed to marshal request body: %w", err)
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
 		reqBody = bytes.NewBuffer(jsonData)
 	}
@@ -190,14 +178,38 @@ func (c *supabaseHTTPClient) doRequest(ctx context.Context, method, endpoint str
 	url := c.baseURL + endpoint
 	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	
 	// Set required headers
-	req.Header.Set("apikey", c.apiKey)
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	apiKey, bearer, err := c.authHeaders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("apikey", apiKey)
+	req.Header.Set("Authorization", "Bearer "+bearer)
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Prefer", "return=representation")
+	return req, nil
+}
+
+// responseError converts an error response and its body into a SupabaseError
+func responseError(resp *http.Response, respBody []byte) *SupabaseError {
+	supabaseErr := &SupabaseError{}
+	if err := json.Unmarshal(respBody, supabaseErr); err != nil || supabaseErr.Code == "" && supabaseErr.Message == "" {
+		supabaseErr = &SupabaseError{Code: strconv.Itoa(resp.StatusCode), Message: string(respBody)}
+	}
+	supabaseErr.StatusCode = resp.StatusCode
+	supabaseErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	return supabaseErr
+}
+
+// doRequest performs the actual HTTP request
+func (c *supabaseHTTPClient) doRequest(ctx context.Context, method, endpoint string, body interface{}, result interface{}) error {
+	req, err := c.newRequest(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
 	
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -212,11 +224,7 @@ func (c *supabaseHTTPClient) doRequest(ctx context.Context, method, endpoint str
 	
 	// Handle error responses
 	if resp.StatusCode >= 400 {
-		var supabaseErr SupabaseError
-		if err := json.Unmarshal(respBody, &supabaseErr); err != nil {
-			return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
-		}
-		return &supabaseErr
+		return responseError(resp, respBody)
 	}
 	
 	// Parse successful response
@@ -236,7 +244,7 @@ func (c *supabaseHTTPClient) HealthCheck(ctx context.Context) error {
 	}
 	
 	// Simple health check by querying a system table
-	endpoint := "/texts?select=count&limit=1"
+	endpoint := NewPostgRESTQuery("texts").Select("count").Limit(1).String()
 	var result []map[string]interface{}
 	
 	return c.makeRequest(ctx, "GET", endpoint, nil, &result)
@@ -247,18 +255,34 @@ func generateUUID() string {
 	return uuid.New().String()
 }
 
-// Helper function to build query parameters
-func buildQueryParams(params map[string]string) string {
-	if len(params) == 0 {
-		return ""
+// maxIDsPerRequest bounds the IDs in a single in.() filter so request URLs stay well
+// below proxy and PostgREST length limits
+const maxIDsPerRequest = 100
+
+// dedupeIDs drops empty and repeated IDs, keeping first occurrences in order
+func dedupeIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
 	}
-	
-	values := url.Values{}
-	for key, value := range params {
-		values.Add(key, value)
+	return unique
+}
+
+// idBatches splits the distinct ids into groups of at most maxIDsPerRequest
+func idBatches(ids []string) [][]string {
+	unique := dedupeIDs(ids)
+	var batches [][]string
+	for len(unique) > 0 {
+		n := min(len(unique), maxIDsPerRequest)
+		batches = append(batches, unique[:n])
+		unique = unique[n:]
 	}
-	
-	return "?" + values.Encode()
+	return batches
 }
 
 // InsertText creates a new text record in Supabase
@@ -296,14 +320,12 @@ func (c *supabaseHTTPClient) GetTexts(ctx context.Context, pagination *models.Pa
 	}
 	
 	offset := (pagination.Page - 1) * pagination.PageSize
-	params := map[string]string{
-		"select": "*",
-		"limit":  strconv.Itoa(pagination.PageSize),
-		"offset": strconv.Itoa(offset),
-		"order":  "created_at.desc",
-	}
-	
-	endpoint := "/texts" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("texts").
+		Select("*").
+		OrderDesc("created_at").
+		Limit(pagination.PageSize).
+		Offset(offset).
+		String()
 	var texts []models.TextRecord
 	
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &texts)
@@ -312,10 +334,7 @@ func (c *supabaseHTTPClient) GetTexts(ctx context.Context, pagination *models.Pa
 	}
 	
 	// Get total count for pagination
-	countParams := map[string]string{
-		"select": "count",
-	}
-	countEndpoint := "/texts" + buildQueryParams(countParams)
+	countEndpoint := NewPostgRESTQuery("texts").Select("count").String()
 	var countResult []map[string]interface{}
 	
 	err = c.makeRequest(ctx, "GET", countEndpoint, nil, &countResult)
@@ -341,11 +360,7 @@ func (c *supabaseHTTPClient) GetTexts(ctx context.Context, pagination *models.Pa
 // GetTextByID retrieves a specific text with its chunks
 func (c *supabaseHTTPClient) GetTextByID(ctx context.Context, id string) (*models.TextDetail, error) {
 	// Get text record
-	params := map[string]string{
-		"select": "*",
-		"id":     "eq." + id,
-	}
-	endpoint := "/texts" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("texts").Select("*").Where(Eq("id", id)).String()
 	
 	var texts []models.TextRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &texts)
@@ -373,10 +388,7 @@ func (c *supabaseHTTPClient) GetTextByID(ctx context.Context, id string) (*model
 func (c *supabaseHTTPClient) UpdateText(ctx context.Context, text *models.TextRecord) error {
 	text.UpdatedAt = time.Now()
 	
-	params := map[string]string{
-		"id": "eq." + text.ID,
-	}
-	endpoint := "/texts" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("texts").Where(Eq("id", text.ID)).String()
 	
 	var result []models.TextRecord
 	err := c.makeRequest(ctx, "PATCH", endpoint, text, &result)
@@ -393,10 +405,7 @@ func (c *supabaseHTTPClient) UpdateText(ctx context.Context, text *models.TextRe
 
 // DeleteText removes a text record and its associated chunks
 func (c *supabaseHTTPClient) DeleteText(ctx context.Context, id string) error {
-	params := map[string]string{
-		"id": "eq." + id,
-	}
-	endpoint := "/texts" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("texts").Where(Eq("id", id)).String()
 	
 	err := c.makeRequest(ctx, "DELETE", endpoint, nil, nil)
 	if err != nil {
@@ -466,11 +475,7 @@ func (c *supabaseHTTPClient) InsertChunks(ctx context.Context, chunks []models.C
 
 // GetChunkByID retrieves a specific chunk by ID
 func (c *supabaseHTTPClient) GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error) {
-	params := map[string]string{
-		"select": "*",
-		"id":     "eq." + id,
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").Select("*").Where(Eq("id", id)).String()
 	
 	var chunks []models.ChunkRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
@@ -485,14 +490,39 @@ func (c *supabaseHTTPClient) GetChunkByID(ctx context.Context, id string) (*mode
 	return &chunks[0], nil
 }
 
+// GetChunksByIDs retrieves chunks by ID with one request per maxIDsPerRequest IDs,
+// returned in the order requested. IDs without a chunk are omitted.
+func (c *supabaseHTTPClient) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) {
+	byID := make(map[string]models.ChunkRecord, len(ids))
+	for _, batch := range idBatches(ids) {
+		endpoint := NewPostgRESTQuery("chunks").Select("*").Where(In("id", batch)).String()
+		
+		var chunks []models.ChunkRecord
+		err := c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chunks by IDs: %w", err)
+		}
+		for _, chunk := range chunks {
+			byID[chunk.ID] = chunk
+		}
+	}
+	
+	chunks := make([]models.ChunkRecord, 0, len(byID))
+	for _, id := range dedupeIDs(ids) {
+		if chunk, ok := byID[id]; ok {
+			chunks = append(chunks, chunk)
+		}
+	}
+	return chunks, nil
+}
+
 // GetChunkByContent retrieves a chunk by its content
 func (c *supabaseHTTPClient) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) {
-	params := map[string]string{
-		"select":  "*",
-		"content": "eq." + content,
-		"limit":   "1",
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(Eq("content", content)).
+		Limit(1).
+		String()
 	
 	var chunks []models.ChunkRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
@@ -511,10 +541,7 @@ func (c *supabaseHTTPClient) GetChunkByContent(ctx context.Context, content stri
 func (c *supabaseHTTPClient) UpdateChunk(ctx context.Context, chunk *models.ChunkRecord) error {
 	chunk.UpdatedAt = time.Now()
 	
-	params := map[string]string{
-		"id": "eq." + chunk.ID,
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").Where(Eq("id", chunk.ID)).String()
 	
 	var result []models.ChunkRecord
 	err := c.makeRequest(ctx, "PATCH", endpoint, chunk, &result)
@@ -531,10 +558,7 @@ func (c *supabaseHTTPClient) UpdateChunk(ctx context.Context, chunk *models.Chun
 
 // DeleteChunk removes a chunk record
 func (c *supabaseHTTPClient) DeleteChunk(ctx context.Context, id string) error {
-	params := map[string]string{
-		"id": "eq." + id,
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").Where(Eq("id", id)).String()
 	
 	err := c.makeRequest(ctx, "DELETE", endpoint, nil, nil)
 	if err != nil {
@@ -546,12 +570,11 @@ func (c *supabaseHTTPClient) DeleteChunk(ctx context.Context, id string) error {
 
 // GetChunksByTextID retrieves all chunks for a specific text
 func (c *supabaseHTTPClient) GetChunksByTextID(ctx context.Context, textID string) ([]models.ChunkRecord, error) {
-	params := map[string]string{
-		"select":  "*",
-		"text_id": "eq." + textID,
-		"order":   "sequence_number.asc,created_at.asc",
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(Eq("text_id", textID)).
+		OrderAsc("sequence_number").OrderAsc("created_at").
+		String()
 	
 	var chunks []models.ChunkRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
@@ -672,10 +695,11 @@ func (c *supabaseHTTPClient) GetTemplateByContent(ctx context.Context, templateC
 	}
 	
 	// Get template instances
-	instances, err := c.GetTemplateInstances(ctx, templateChunk.ID)
+	instancesByTemplate, err := c.getTemplateInstancesBatch(ctx, map[string][]models.ChunkRecord{templateChunk.ID: slots})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get template instances: %w", err)
 	}
+	instances := instancesByTemplate[templateChunk.ID]
 	
 	return &models.TemplateWithInstances{
 		Template:  templateChunk,
@@ -687,12 +711,11 @@ func (c *supabaseHTTPClient) GetTemplateByContent(ctx context.Context, templateC
 // GetAllTemplates retrieves all templates in the system
 func (c *supabaseHTTPClient) GetAllTemplates(ctx context.Context) ([]models.TemplateWithInstances, error) {
 	// Get all template chunks
-	params := map[string]string{
-		"select":      "*",
-		"is_template": "eq.true",
-		"order":       "created_at.desc",
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(Eq("is_template", true)).
+		OrderDesc("created_at").
+		String()
 	
 	var templateChunks []models.ChunkRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &templateChunks)
@@ -700,23 +723,26 @@ func (c *supabaseHTTPClient) GetAllTemplates(ctx context.Context) ([]models.Temp
 		return nil, fmt.Errorf("failed to get template chunks: %w", err)
 	}
 	
-	// For each template, get its slots and instances
+	// Get slots and instances for all templates at once
+	templateIDs := make([]string, len(templateChunks))
+	for i, templateChunk := range templateChunks {
+		templateIDs[i] = templateChunk.ID
+	}
+	slotsByTemplate, err := c.getTemplateSlotsBatch(ctx, templateIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template slots: %w", err)
+	}
+	instancesByTemplate, err := c.getTemplateInstancesBatch(ctx, slotsByTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template instances: %w", err)
+	}
+	
 	var templates []models.TemplateWithInstances
 	for _, templateChunk := range templateChunks {
-		slots, err := c.getTemplateSlots(ctx, templateChunk.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get slots for template %s: %w", templateChunk.ID, err)
-		}
-		
-		instances, err := c.GetTemplateInstances(ctx, templateChunk.ID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get instances for template %s: %w", templateChunk.ID, err)
-		}
-		
 		templates = append(templates, models.TemplateWithInstances{
 			Template:  &templateChunk,
-			Slots:     slots,
-			Instances: instances,
+			Slots:     slotsByTemplate[templateChunk.ID],
+			Instances: instancesByTemplate[templateChunk.ID],
 		})
 	}
 	
@@ -725,33 +751,63 @@ func (c *supabaseHTTPClient) GetAllTemplates(ctx context.Context) ([]models.Temp
 
 // getTemplateSlots retrieves slots for a specific template
 func (c *supabaseHTTPClient) getTemplateSlots(ctx context.Context, templateChunkID string) ([]models.ChunkRecord, error) {
+	slotsByTemplate, err := c.getTemplateSlotsBatch(ctx, []string{templateChunkID})
+	if err != nil {
+		return nil, err
+	}
+	return slotsByTemplate[templateChunkID], nil
+}
+
+// templateSlotRelation is a row of the template_slots table
+type templateSlotRelation struct {
+	TemplateChunkID string `json:"template_chunk_id"`
+	SlotChunkID     string `json:"slot_chunk_id"`
+}
+
+// getTemplateSlotsBatch retrieves the slots of several templates, in slot order, with
+// one request for the relationships and one for the slot chunks
+func (c *supabaseHTTPClient) getTemplateSlotsBatch(ctx context.Context, templateChunkIDs []string) (map[string][]models.ChunkRecord, error) {
 	// Get template slot relationships
-	params := map[string]string{
-		"select":            "slot_chunk_id",
-		"template_chunk_id": "eq." + templateChunkID,
-		"order":             "slot_order.asc",
+	var slotRelations []templateSlotRelation
+	for _, batch := range idBatches(templateChunkIDs) {
+		endpoint := NewPostgRESTQuery("template_slots").
+			Select("template_chunk_id", "slot_chunk_id").
+			Where(In("template_chunk_id", batch)).
+			OrderAsc("template_chunk_id").OrderAsc("slot_order").
+			String()
+		
+		var relations []templateSlotRelation
+		err := c.makeRequest(ctx, "GET", endpoint, nil, &relations)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template slot relationships: %w", err)
+		}
+		slotRelations = append(slotRelations, relations...)
 	}
-	endpoint := "/template_slots" + buildQueryParams(params)
 	
-	var slotRelations []map[string]interface{}
-	err := c.makeRequest(ctx, "GET", endpoint, nil, &slotRelations)
+	// Get the actual slot chunks
+	slotChunkIDs := make([]string, len(slotRelations))
+	for i, relation := range slotRelations {
+		slotChunkIDs[i] = relation.SlotChunkID
+	}
+	slotChunks, err := c.GetChunksByIDs(ctx, slotChunkIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get template slot relationships: %w", err)
+		return nil, fmt.Errorf("failed to get slot chunks: %w", err)
+	}
+	slotsByID := make(map[string]models.ChunkRecord, len(slotChunks))
+	for _, slotChunk := range slotChunks {
+		slotsByID[slotChunk.ID] = slotChunk
 	}
 	
-	// Get the actual slot chunks
-	var slots []models.ChunkRecord
+	slotsByTemplate := make(map[string][]models.ChunkRecord, len(templateChunkIDs))
 	for _, relation := range slotRelations {
-		if slotChunkID, ok := relation["slot_chunk_id"].(string); ok {
-			slotChunk, err := c.GetChunkByID(ctx, slotChunkID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to get slot chunk %s: %w", slotChunkID, err)
-			}
-			slots = append(slots, *slotChunk)
+		slotChunk, ok := slotsByID[relation.SlotChunkID]
+		if !ok {
+			return nil, fmt.Errorf("failed to get slot chunk %s: chunk not found", relation.SlotChunkID)
 		}
+		slotsByTemplate[relation.TemplateChunkID] = append(slotsByTemplate[relation.TemplateChunkID], slotChunk)
 	}
 	
-	return slots, nil
+	return slotsByTemplate, nil
 }
 
 // CreateTemplateInstance creates a new instance of a template
@@ -833,67 +889,72 @@ func (c *supabaseHTTPClient) CreateTemplateInstance(ctx context.Context, req *mo
 
 // GetTemplateInstances retrieves all instances of a specific template
 func (c *supabaseHTTPClient) GetTemplateInstances(ctx context.Context, templateChunkID string) ([]models.TemplateInstance, error) {
-	// Get all chunks that reference this template
-	params := map[string]string{
-		"select":            "*",
-		"template_chunk_id": "eq." + templateChunkID,
-		"is_template":       "eq.false",
-		"is_slot":           "eq.false",
-		"order":             "created_at.desc",
+	slots, err := c.getTemplateSlots(ctx, templateChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template slots: %w", err)
 	}
-	endpoint := "/chunks" + buildQueryParams(params)
 	
-	var instanceChunks []models.ChunkRecord
-	err := c.makeRequest(ctx, "GET", endpoint, nil, &instanceChunks)
+	instancesByTemplate, err := c.getTemplateInstancesBatch(ctx, map[string][]models.ChunkRecord{templateChunkID: slots})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get template instances: %w", err)
+		return nil, err
 	}
+	return instancesByTemplate[templateChunkID], nil
+}
+
+// getTemplateInstancesBatch retrieves the instances of the given templates with their
+// slot values. Instances and slot values both reference their template, so a single
+// query per batch of templates returns everything.
+func (c *supabaseHTTPClient) getTemplateInstancesBatch(ctx context.Context, slotsByTemplate map[string][]models.ChunkRecord) (map[string][]models.TemplateInstance, error) {
+	templateChunkIDs := make([]string, 0, len(slotsByTemplate))
+	for templateChunkID := range slotsByTemplate {
+		templateChunkIDs = append(templateChunkIDs, templateChunkID)
+	}
+	sort.Strings(templateChunkIDs)
 	
-	// For each instance, get its slot values
-	var instances []models.TemplateInstance
-	for _, instanceChunk := range instanceChunks {
-		// Skip slot value chunks (they have parent_chunk_id set to instance)
-		if instanceChunk.ParentChunkID != nil {
-			continue
-		}
+	// Get all chunks that reference these templates
+	var chunks []models.ChunkRecord
+	for _, batch := range idBatches(templateChunkIDs) {
+		endpoint := NewPostgRESTQuery("chunks").
+			Select("*").
+			Where(In("template_chunk_id", batch), Eq("is_template", false), Eq("is_slot", false)).
+			OrderDesc("created_at").
+			String()
 		
-		slotValues, err := c.getInstanceSlotValues(ctx, instanceChunk.ID, templateChunkID)
+		var batchChunks []models.ChunkRecord
+		err := c.makeRequest(ctx, "GET", endpoint, nil, &batchChunks)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get slot values for instance %s: %w", instanceChunk.ID, err)
+			return nil, fmt.Errorf("failed to get template instances: %w", err)
 		}
+		chunks = append(chunks, batchChunks...)
+	}
+	
+	// Slot value chunks have parent_chunk_id set to their instance
+	slotValueChunks := make(map[string][]models.ChunkRecord)
+	for _, chunk := range chunks {
+		if chunk.ParentChunkID != nil {
+			slotValueChunks[*chunk.ParentChunkID] = append(slotValueChunks[*chunk.ParentChunkID], chunk)
+		}
+	}
+	
+	instancesByTemplate := make(map[string][]models.TemplateInstance, len(templateChunkIDs))
+	for _, instanceChunk := range chunks {
+		if instanceChunk.ParentChunkID != nil || instanceChunk.TemplateChunkID == nil {
+			continue
+		}
+		templateChunkID := *instanceChunk.TemplateChunkID
 		
-		instances = append(instances, models.TemplateInstance{
+		instancesByTemplate[templateChunkID] = append(instancesByTemplate[templateChunkID], models.TemplateInstance{
 			Instance:   &instanceChunk,
-			SlotValues: slotValues,
+			SlotValues: mapSlotValues(slotValueChunks[instanceChunk.ID], slotsByTemplate[templateChunkID]),
 		})
 	}
 	
-	return instances, nil
+	return instancesByTemplate, nil
 }
 
-// getInstanceSlotValues retrieves slot values for a specific template instance
-func (c *supabaseHTTPClient) getInstanceSlotValues(ctx context.Context, instanceChunkID, templateChunkID string) (map[string]*models.ChunkRecord, error) {
-	// Get slot value chunks for this instance
-	params := map[string]string{
-		"select":            "*",
-		"parent_chunk_id":   "eq." + instanceChunkID,
-		"template_chunk_id": "eq." + templateChunkID,
-		"order":             "sequence_number.asc",
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
-	
-	var slotValueChunks []models.ChunkRecord
-	err := c.makeRequest(ctx, "GET", endpoint, nil, &slotValueChunks)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get slot value chunks: %w", err)
-	}
-	
-	// Get template slots to map slot names
-	templateSlots, err := c.getTemplateSlots(ctx, templateChunkID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get template slots: %w", err)
-	}
-	
+// mapSlotValues names an instance's slot value chunks after the template slot at their
+// sequence number
+func mapSlotValues(slotValueChunks []models.ChunkRecord, templateSlots []models.ChunkRecord) map[string]*models.ChunkRecord {
 	// Create mapping from sequence number to slot name
 	slotNames := make(map[int]string)
 	for i, slot := range templateSlots {
@@ -911,7 +972,7 @@ func (c *supabaseHTTPClient) getInstanceSlotValues(ctx context.Context, instance
 		}
 	}
 	
-	return slotValues, nil
+	return slotValues
 }
 
 // UpdateSlotValue updates the value of a specific slot in a template instance
@@ -946,13 +1007,11 @@ func (c *supabaseHTTPClient) UpdateSlotValue(ctx context.Context, instanceChunkI
 	}
 	
 	// Find the existing slot value chunk
-	params := map[string]string{
-		"select":           "*",
-		"parent_chunk_id":  "eq." + instanceChunkID,
-		"sequence_number":  "eq." + strconv.Itoa(*targetSequenceNumber),
-		"limit":            "1",
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(Eq("parent_chunk_id", instanceChunkID), Eq("sequence_number", *targetSequenceNumber)).
+		Limit(1).
+		String()
 	
 	var slotValueChunks []models.ChunkRecord
 	err = c.makeRequest(ctx, "GET", endpoint, nil, &slotValueChunks)
@@ -977,6 +1036,219 @@ func (c *supabaseHTTPClient) UpdateSlotValue(ctx context.Context, instanceChunkI
 	return nil
 }
 
+// templateSlotChange is the difference between a template's slots and the slots it
+// should have
+type templateSlotChange struct {
+	added     []string
+	removed   []string
+	reordered []string
+	oldIndex  map[string]int
+	newIndex  map[string]int
+}
+
+// diffTemplateSlots compares slot names by name. Kept slots whose position differs are
+// reordered.
+func diffTemplateSlots(current, desired []string) *templateSlotChange {
+	change := &templateSlotChange{
+		oldIndex: make(map[string]int, len(current)),
+		newIndex: make(map[string]int, len(desired)),
+	}
+	for i, name := range current {
+		change.oldIndex[name] = i
+	}
+	for i, name := range desired {
+		change.newIndex[name] = i
+		oldIndex, kept := change.oldIndex[name]
+		switch {
+		case !kept:
+			change.added = append(change.added, name)
+		case oldIndex != i:
+			change.reordered = append(change.reordered, name)
+		}
+	}
+	for _, name := range current {
+		if _, kept := change.newIndex[name]; !kept {
+			change.removed = append(change.removed, name)
+		}
+	}
+	return change
+}
+
+// UpdateTemplateSchema sets the slots of a template and migrates its instances: values of
+// removed slots are archived on their instance, kept values follow their slot's new
+// position, and instances get an empty value for every slot they lack. The steps are
+// separate requests and are not rolled back when a later one fails.
+func (c *supabaseHTTPClient) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) {
+	templateChunk, err := c.GetChunkByID(ctx, templateChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template chunk: %w", err)
+	}
+	
+	if !templateChunk.IsTemplate {
+		return nil, fmt.Errorf("chunk is not a template: %s", templateChunkID)
+	}
+	
+	slots, err := c.getTemplateSlots(ctx, templateChunk.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template slots: %w", err)
+	}
+	instancesByTemplate, err := c.getTemplateInstancesBatch(ctx, map[string][]models.ChunkRecord{templateChunk.ID: slots})
+	if err != nil {
+		return nil, err
+	}
+	instances := instancesByTemplate[templateChunk.ID]
+	
+	currentNames := make([]string, len(slots))
+	for i, slot := range slots {
+		currentNames[i] = strings.TrimPrefix(slot.Content, "#")
+	}
+	change := diffTemplateSlots(currentNames, req.SlotNames)
+	
+	migration := &models.TemplateSchemaMigration{
+		TemplateChunkID: templateChunk.ID,
+		AddedSlots:      change.added,
+		RemovedSlots:    change.removed,
+		ReorderedSlots:  change.reordered,
+		Instances:       len(instances),
+		DryRun:          req.DryRun,
+	}
+	for _, instance := range instances {
+		for _, name := range req.SlotNames {
+			if _, exists := instance.SlotValues[name]; !exists {
+				migration.CreatedSlotValues++
+			}
+		}
+		for _, name := range change.removed {
+			if _, exists := instance.SlotValues[name]; exists {
+				migration.ArchivedSlotValues++
+			}
+		}
+	}
+	if req.DryRun {
+		return migration, nil
+	}
+	
+	// Instances are migrated first, while their values can still be told apart by the
+	// template's current slot order
+	archivedAt := time.Now().UTC().Format(time.RFC3339)
+	for _, instance := range instances {
+		for name, valueChunk := range instance.SlotValues {
+			archived := *valueChunk
+			if newIndex, kept := change.newIndex[name]; kept {
+				if archived.SequenceNumber != nil && *archived.SequenceNumber == newIndex {
+					continue
+				}
+				archived.SequenceNumber = &newIndex
+			} else {
+				metadata := make(map[string]interface{}, len(archived.Metadata)+2)
+				for key, value := range archived.Metadata {
+					metadata[key] = value
+				}
+				metadata["archived_slot"] = name
+				metadata["archived_at"] = archivedAt
+				archived.Metadata = metadata
+				archived.SequenceNumber = nil
+			}
+	
+			if err := c.UpdateChunk(ctx, &archived); err != nil {
+				return nil, fmt.Errorf("failed to migrate slot value %s of instance %s: %w", name, instance.Instance.ID, err)
+			}
+		}
+	}
+	
+	// Then the template's own slots
+	for i, slot := range slots {
+		name := currentNames[i]
+		newIndex, kept := change.newIndex[name]
+		if !kept {
+			endpoint := NewPostgRESTQuery("template_slots").Where(Eq("slot_chunk_id", slot.ID)).String()
+			if err := c.makeRequest(ctx, "DELETE", endpoint, nil, nil); err != nil {
+				return nil, fmt.Errorf("failed to remove template slot relationship: %w", err)
+			}
+			if err := c.DeleteChunk(ctx, slot.ID); err != nil {
+				return nil, fmt.Errorf("failed to remove slot %s: %w", name, err)
+			}
+			continue
+		}
+		if newIndex == i {
+			continue
+		}
+	
+		endpoint := NewPostgRESTQuery("template_slots").Where(Eq("slot_chunk_id", slot.ID)).String()
+		if err := c.makeRequest(ctx, "PATCH", endpoint, map[string]interface{}{"slot_order": newIndex}, nil); err != nil {
+			return nil, fmt.Errorf("failed to reorder slot %s: %w", name, err)
+		}
+		slot.SequenceNumber = &newIndex
+		if err := c.UpdateChunk(ctx, &slot); err != nil {
+			return nil, fmt.Errorf("failed to reorder slot %s: %w", name, err)
+		}
+	}
+	
+	var newSlots []models.ChunkRecord
+	for _, name := range change.added {
+		index := change.newIndex[name]
+		newSlots = append(newSlots, models.ChunkRecord{
+			ID:              generateUUID(),
+			TextID:          templateChunk.TextID,
+			Content:         "#" + name,
+			IsSlot:          true,
+			ParentChunkID:   &templateChunk.ID,
+			TemplateChunkID: &templateChunk.ID,
+			IndentLevel:     1,
+			SequenceNumber:  &index,
+			CreatedAt:       time.Now(),
+			UpdatedAt:       time.Now(),
+		})
+	}
+	if len(newSlots) > 0 {
+		if err := c.InsertChunks(ctx, newSlots); err != nil {
+			return nil, fmt.Errorf("failed to create slot chunks: %w", err)
+		}
+	}
+	for _, slotChunk := range newSlots {
+		templateSlot := models.TemplateSlot{
+			ID:              generateUUID(),
+			TemplateChunkID: templateChunk.ID,
+			SlotChunkID:     slotChunk.ID,
+			SlotOrder:       *slotChunk.SequenceNumber,
+			CreatedAt:       time.Now(),
+		}
+		if err := c.makeRequest(ctx, "POST", "/template_slots", templateSlot, nil); err != nil {
+			return nil, fmt.Errorf("failed to create template slot relationship: %w", err)
+		}
+	}
+	
+	// Finally every instance gets an empty value for the slots it lacks
+	var emptyValues []models.ChunkRecord
+	for _, instance := range instances {
+		for i, name := range req.SlotNames {
+			if _, exists := instance.SlotValues[name]; exists {
+				continue
+			}
+			index := i
+			value := ""
+			emptyValues = append(emptyValues, models.ChunkRecord{
+				ID:              generateUUID(),
+				TextID:          templateChunk.TextID,
+				ParentChunkID:   &instance.Instance.ID,
+				TemplateChunkID: &templateChunk.ID,
+				SlotValue:       &value,
+				IndentLevel:     1,
+				SequenceNumber:  &index,
+				CreatedAt:       time.Now(),
+				UpdatedAt:       time.Now(),
+			})
+		}
+	}
+	if len(emptyValues) > 0 {
+		if err := c.InsertChunks(ctx, emptyValues); err != nil {
+			return nil, fmt.Errorf("failed to create slot value chunks: %w", err)
+		}
+	}
+	
+	return migration, nil
+}
+
 // Note: The new graph methods are implemented above in the main implementation section
 
 // AddTag adds a tag to a chunk by creating or finding a tag chunk and establishing the relationship
@@ -1029,11 +1301,9 @@ func (c *supabaseHTTPClient) AddTag(ctx context.Context, chunkID string, tagCont
 
 // RemoveTag removes a tag relationship between a chunk and a tag chunk
 func (c *supabaseHTTPClient) RemoveTag(ctx context.Context, chunkID string, tagChunkID string) error {
-	params := map[string]string{
-		"chunk_id":     "eq." + chunkID,
-		"tag_chunk_id": "eq." + tagChunkID,
-	}
-	endpoint := "/chunk_tags" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunk_tags").
+		Where(Eq("chunk_id", chunkID), Eq("tag_chunk_id", tagChunkID)).
+		String()
 	
 	err := c.makeRequest(ctx, "DELETE", endpoint, nil, nil)
 	if err != nil {
@@ -1046,11 +1316,10 @@ func (c *supabaseHTTPClient) RemoveTag(ctx context.Context, chunkID string, tagC
 // GetChunkTags retrieves all tag chunks associated with a specific chunk
 func (c *supabaseHTTPClient) GetChunkTags(ctx context.Context, chunkID string) ([]models.ChunkRecord, error) {
 	// First get the tag relationships
-	params := map[string]string{
-		"select":   "tag_chunk_id",
-		"chunk_id": "eq." + chunkID,
-	}
-	endpoint := "/chunk_tags" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunk_tags").
+		Select("tag_chunk_id").
+		Where(Eq("chunk_id", chunkID)).
+		String()
 	
 	var tagRelations []map[string]interface{}
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &tagRelations)
@@ -1058,19 +1327,19 @@ func (c *supabaseHTTPClient) GetChunkTags(ctx context.Context, chunkID string) (
 		return nil, fmt.Errorf("failed to get tag relationships: %w", err)
 	}
 	
-	// Get the actual tag chunks
-	var tags []models.ChunkRecord
+	// Get the actual tag chunks; relationships to missing chunks are skipped
+	var tagChunkIDs []string
 	for _, relation := range tagRelations {
 		if tagChunkID, ok := relation["tag_chunk_id"].(string); ok {
-			tagChunk, err := c.GetChunkByID(ctx, tagChunkID)
-			if err != nil {
-				// Log error but continue with other tags
-				continue
-			}
-			tags = append(tags, *tagChunk)
+			tagChunkIDs = append(tagChunkIDs, tagChunkID)
 		}
 	}
 	
+	tags, err := c.GetChunksByIDs(ctx, tagChunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tag chunks: %w", err)
+	}
+	
 	return tags, nil
 }
 
@@ -1083,11 +1352,10 @@ func (c *supabaseHTTPClient) GetChunksByTag(ctx context.Context, tagContent stri
 	}
 	
 	// Get all chunk relationships for this tag
-	params := map[string]string{
-		"select":       "chunk_id",
-		"tag_chunk_id": "eq." + tagChunk.ID,
-	}
-	endpoint := "/chunk_tags" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunk_tags").
+		Select("chunk_id").
+		Where(Eq("tag_chunk_id", tagChunk.ID)).
+		String()
 	
 	var tagRelations []map[string]interface{}
 	err = c.makeRequest(ctx, "GET", endpoint, nil, &tagRelations)
@@ -1095,19 +1363,19 @@ func (c *supabaseHTTPClient) GetChunksByTag(ctx context.Context, tagContent stri
 		return nil, fmt.Errorf("failed to get tag relationships: %w", err)
 	}
 	
-	// Get the actual chunks
-	var chunks []models.ChunkRecord
+	// Get the actual chunks; relationships to missing chunks are skipped
+	var chunkIDs []string
 	for _, relation := range tagRelations {
 		if chunkID, ok := relation["chunk_id"].(string); ok {
-			chunk, err := c.GetChunkByID(ctx, chunkID)
-			if err != nil {
-				// Log error but continue with other chunks
-				continue
-			}
-			chunks = append(chunks, *chunk)
+			chunkIDs = append(chunkIDs, chunkID)
 		}
 	}
 	
+	chunks, err := c.GetChunksByIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tagged chunks: %w", err)
+	}
+	
 	return chunks, nil
 }
 
@@ -1155,12 +1423,11 @@ func (c *supabaseHTTPClient) buildHierarchy(ctx context.Context, chunk *models.C
 
 // GetChildrenChunks retrieves direct children of a parent chunk
 func (c *supabaseHTTPClient) GetChildrenChunks(ctx context.Context, parentChunkID string) ([]models.ChunkRecord, error) {
-	params := map[string]string{
-		"select":           "*",
-		"parent_chunk_id":  "eq." + parentChunkID,
-		"order":            "sequence_number.asc,created_at.asc",
-	}
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(Eq("parent_chunk_id", parentChunkID)).
+		OrderAsc("sort_key").OrderAsc("sequence_number").OrderAsc("created_at").
+		String()
 	
 	var chunks []models.ChunkRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
@@ -1179,27 +1446,19 @@ func (c *supabaseHTTPClient) GetSiblingChunks(ctx context.Context, chunkID strin
 		return nil, fmt.Errorf("failed to get chunk: %w", err)
 	}
 	
-	var params map[string]string
+	query := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(Neq("id", chunkID)). // Exclude the chunk itself
+		OrderAsc("sort_key").OrderAsc("sequence_number").OrderAsc("created_at")
 	if chunk.ParentChunkID != nil {
 		// Get siblings with the same parent
-		params = map[string]string{
-			"select":          "*",
-			"parent_chunk_id": "eq." + *chunk.ParentChunkID,
-			"id":              "neq." + chunkID, // Exclude the chunk itself
-			"order":           "sequence_number.asc,created_at.asc",
-		}
+		query.Where(Eq("parent_chunk_id", *chunk.ParentChunkID))
 	} else {
 		// Get root-level siblings (chunks with no parent in the same text)
-		params = map[string]string{
-			"select":          "*",
-			"text_id":         "eq." + chunk.TextID,
-			"parent_chunk_id": "is.null",
-			"id":              "neq." + chunkID,
-			"order":           "sequence_number.asc,created_at.asc",
-		}
+		query.Where(Eq("text_id", chunk.TextID), IsNull("parent_chunk_id"))
 	}
 	
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := query.String()
 	
 	var chunks []models.ChunkRecord
 	err = c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
@@ -1210,7 +1469,9 @@ func (c *supabaseHTTPClient) GetSiblingChunks(ctx context.Context, chunkID strin
 	return chunks, nil
 }
 
-// MoveChunk moves a chunk to a new position in the hierarchy
+// MoveChunk moves a chunk under a new parent at NewPosition among its new siblings, or
+// last when NewPosition is negative. Only the moved chunk is written unless the
+// neighbouring sort keys leave no room between them.
 func (c *supabaseHTTPClient) MoveChunk(ctx context.Context, req *models.MoveChunkRequest) error {
 	// Get the chunk to move
 	chunk, err := c.GetChunkByID(ctx, req.ChunkID)
@@ -1218,63 +1479,34 @@ func (c *supabaseHTTPClient) MoveChunk(ctx context.Context, req *models.MoveChun
 		return fmt.Errorf("failed to get chunk to move: %w", err)
 	}
 	
-	// Update chunk with new position
-	chunk.ParentChunkID = req.NewParentID
-	chunk.IndentLevel = req.NewIndentLevel
-	
-	// If sequence number is provided, update it
-	if req.NewPosition >= 0 {
-		chunk.SequenceNumber = &req.NewPosition
-	}
-	
-	// Update the chunk
-	err = c.UpdateChunk(ctx, chunk)
+	siblings, err := c.siblingGroup(ctx, req.NewParentID, chunk.TextID)
 	if err != nil {
-		return fmt.Errorf("failed to update chunk position: %w", err)
+		return fmt.Errorf("failed to get new siblings: %w", err)
+	}
+	others := make([]models.ChunkRecord, 0, len(siblings))
+	for _, sibling := range siblings {
+		if sibling.ID != chunk.ID {
+			others = append(others, sibling)
+		}
 	}
 	
-	// Reorder siblings if necessary
-	err = c.reorderSiblings(ctx, chunk)
+	position := req.NewPosition
+	if position < 0 {
+		position = len(others)
+	}
+	sortKey, err := c.placementKey(ctx, others, position)
 	if err != nil {
 		return fmt.Errorf("failed to reorder siblings: %w", err)
 	}
 	
-	return nil
-}
-
-// reorderSiblings ensures proper sequence numbering for siblings
-func (c *supabaseHTTPClient) reorderSiblings(ctx context.Context, movedChunk *models.ChunkRecord) error {
-	// Get all siblings including the moved chunk
-	var siblings []models.ChunkRecord
-	var err error
-	
-	if movedChunk.ParentChunkID != nil {
-		siblings, err = c.GetChildrenChunks(ctx, *movedChunk.ParentChunkID)
-	} else {
-		// Get root-level chunks for the same text
-		params := map[string]string{
-			"select":          "*",
-			"text_id":         "eq." + movedChunk.TextID,
-			"parent_chunk_id": "is.null",
-			"order":           "sequence_number.asc,created_at.asc",
-		}
-		endpoint := "/chunks" + buildQueryParams(params)
-		err = c.makeRequest(ctx, "GET", endpoint, nil, &siblings)
-	}
+	// Update chunk with new position
+	chunk.ParentChunkID = req.NewParentID
+	chunk.IndentLevel = req.NewIndentLevel
+	chunk.SortKey = sortKey
 	
+	err = c.UpdateChunk(ctx, chunk)
 	if err != nil {
-		return fmt.Errorf("failed to get siblings for reordering: %w", err)
-	}
-	
-	// Update sequence numbers
-	for i, sibling := range siblings {
-		newSeq := i
-		if sibling.SequenceNumber == nil || *sibling.SequenceNumber != newSeq {
-			sibling.SequenceNumber = &newSeq
-			if err := c.UpdateChunk(ctx, &sibling); err != nil {
-				return fmt.Errorf("failed to update sibling sequence: %w", err)
-			}
-		}
+		return fmt.Errorf("failed to update chunk position: %w", err)
 	}
 	
 	return nil
@@ -1319,43 +1551,36 @@ func (c *supabaseHTTPClient) SearchChunks(ctx context.Context, query string, fil
 		return []models.ChunkRecord{}, nil
 	}
 	
-	// Build query parameters
-	params := map[string]string{
-		"select": "*",
-		"order":  "created_at.desc",
-	}
-	
-	// Add text search using PostgreSQL full-text search
-	params["content"] = "ilike.*" + query + "*"
+	// Add text search using a case-insensitive pattern match
+	search := NewPostgRESTQuery("chunks").
+		Select("*").
+		Where(ILike("content", "*"+query+"*")).
+		OrderDesc("created_at").
+		Limit(100) // Default search limit
 	
 	// Apply filters
 	if filters != nil {
 		if textID, ok := filters["text_id"].(string); ok && textID != "" {
-			params["text_id"] = "eq." + textID
+			search.Where(Eq("text_id", textID))
 		}
 		if isTemplate, ok := filters["is_template"].(bool); ok {
-			params["is_template"] = "eq." + strconv.FormatBool(isTemplate)
+			search.Where(Eq("is_template", isTemplate))
 		}
 		if isSlot, ok := filters["is_slot"].(bool); ok {
-			params["is_slot"] = "eq." + strconv.FormatBool(isSlot)
+			search.Where(Eq("is_slot", isSlot))
 		}
 		if minIndentLevel, ok := filters["min_indent_level"].(int); ok {
-			params["indent_level"] = "gte." + strconv.Itoa(minIndentLevel)
+			search.Where(Gte("indent_level", minIndentLevel))
 		}
 		if maxIndentLevel, ok := filters["max_indent_level"].(int); ok {
-			params["indent_level"] = "lte." + strconv.Itoa(maxIndentLevel)
+			search.Where(Lte("indent_level", maxIndentLevel))
 		}
 		if limit, ok := filters["limit"].(int); ok && limit > 0 {
-			params["limit"] = strconv.Itoa(limit)
+			search.Limit(limit)
 		}
 	}
 	
-	// Set default limit if not specified
-	if _, hasLimit := params["limit"]; !hasLimit {
-		params["limit"] = "100" // Default search limit
-	}
-	
-	endpoint := "/chunks" + buildQueryParams(params)
+	endpoint := search.String()
 	
 	var chunks []models.ChunkRecord
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &chunks)
@@ -1424,6 +1649,13 @@ func (c *supabaseHTTPClient) InsertEmbeddings(ctx context.Context, embeddings []
 
 // SearchSimilar performs vector similarity search using PGVector
 func (c *supabaseHTTPClient) SearchSimilar(ctx context.Context, queryVector []float64, limit int) ([]models.SimilarityResult, error) {
+	return c.SearchSimilarFiltered(ctx, queryVector, limit, models.SimilarityFilter{})
+}
+
+// SearchSimilarFiltered performs vector similarity search among the chunks matching filter.
+// The filter is passed to match_chunks, see database/similarity_filter_migration.sql, and
+// applied inside its vector query.
+func (c *supabaseHTTPClient) SearchSimilarFiltered(ctx context.Context, queryVector []float64, limit int, filter models.SimilarityFilter) ([]models.SimilarityResult, error) {
 	if limit <= 0 {
 		limit = 10 // Default limit
 	}
@@ -1440,6 +1672,20 @@ func (c *supabaseHTTPClient) SearchSimilar(ctx context.Context, queryVector []fl
 		"match_threshold": 0.0, // Minimum similarity threshold
 		"match_count":     limit,
 	}
+	// Filter arguments are only sent when set, so unfiltered searches keep working
+	// against match_chunks functions that predate them
+	if len(filter.TagIDs) > 0 {
+		rpcRequest["filter_tags"] = filter.TagIDs
+	}
+	if filter.PageID != "" {
+		rpcRequest["filter_page"] = filter.PageID
+	}
+	if filter.CreatedAfter != nil {
+		rpcRequest["filter_created_after"] = filter.CreatedAfter
+	}
+	if filter.CreatedBefore != nil {
+		rpcRequest["filter_created_before"] = filter.CreatedBefore
+	}
 	
 	var rpcResult []map[string]interface{}
 	err = c.makeRequest(ctx, "POST", "/rpc/match_chunks", rpcRequest, &rpcResult)
@@ -1587,6 +1833,7 @@ func (c *supabaseHTTPClient) SearchGraph(ctx context.Context, query *models.Grap
 	if query.Limit <= 0 {
 		query.Limit = 50 // Default result limit
 	}
+	ctx = withDefaultRequestBudget(ctx, DefaultGraphTraversalBudget)
 	
 	// Try RPC function first, fallback to manual traversal if not available
 	rpcRequest := map[string]interface{}{
@@ -1598,6 +1845,9 @@ func (c *supabaseHTTPClient) SearchGraph(ctx context.Context, query *models.Grap
 	var rpcResult map[string]interface{}
 	err := c.makeRequest(ctx, "POST", "/rpc/search_graph", rpcRequest, &rpcResult)
 	if err != nil {
+		if isBudgetStop(err) {
+			return nil, fmt.Errorf("graph search stopped: %w", err)
+		}
 		// Fallback to manual graph traversal if RPC function doesn't exist
 		return c.manualGraphSearch(ctx, query)
 	}
@@ -1630,12 +1880,45 @@ func (c *supabaseHTTPClient) SearchGraph(ctx context.Context, query *models.Grap
 		}
 	}
 	
+	nodes, edges = filterGraph(nodes, edges, query)
 	return &models.GraphResult{
 		Nodes: nodes,
 		Edges: edges,
 	}, nil
 }
 
+// filterGraph drops the nodes extracted with less than the query's minimum confidence, the
+// edges graphEdgeAllowed rejects and the edges of dropped nodes
+func filterGraph(nodes []models.GraphNode, edges []models.GraphEdge, query *models.GraphQuery) ([]models.GraphNode, []models.GraphEdge) {
+	if query.MinConfidence <= 0 && query.AsOf == nil {
+		return nodes, edges
+	}
+	keptNodes := make([]models.GraphNode, 0, len(nodes))
+	kept := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if node.ExtractionConfidence() >= query.MinConfidence {
+			keptNodes = append(keptNodes, node)
+			kept[node.ID] = true
+		}
+	}
+	keptEdges := make([]models.GraphEdge, 0, len(edges))
+	for _, edge := range edges {
+		if graphEdgeAllowed(edge, query) && kept[edge.SourceNodeID] && kept[edge.TargetNodeID] {
+			keptEdges = append(keptEdges, edge)
+		}
+	}
+	return keptNodes, keptEdges
+}
+
+// graphEdgeAllowed reports whether an edge meets the query's minimum confidence and held
+// at its as-of time
+func graphEdgeAllowed(edge models.GraphEdge, query *models.GraphQuery) bool {
+	if query.MinConfidence > 0 && edge.ExtractionConfidence() < query.MinConfidence {
+		return false
+	}
+	return query.AsOf == nil || edge.ValidAt(*query.AsOf)
+}
+
 // manualGraphSearch performs graph search using manual traversal when RPC is not available
 func (c *supabaseHTTPClient) manualGraphSearch(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) {
 	// Find starting nodes by entity name
@@ -1643,6 +1926,7 @@ func (c *supabaseHTTPClient) manualGraphSearch(ctx context.Context, query *model
 	if err != nil {
 		return nil, fmt.Errorf("failed to find starting nodes: %w", err)
 	}
+	startNodes, _ = filterGraph(startNodes, nil, query)
 	
 	if len(startNodes) == 0 {
 		return &models.GraphResult{
@@ -1669,7 +1953,8 @@ func (c *supabaseHTTPClient) manualGraphSearch(ctx context.Context, query *model
 	}
 	
 	// BFS traversal
-	for len(queue) > 0 && len(allNodes) < query.Limit {
+	var stopErr error
+	for len(queue) > 0 && len(allNodes) < query.Limit && stopErr == nil {
 		current := queue[0]
 		queue = queue[1:]
 		
@@ -1678,19 +1963,33 @@ func (c *supabaseHTTPClient) manualGraphSearch(ctx context.Context, query *model
 			continue
 		}
 		
-		// Get neighbors of current node
+		// Get neighbors of current node; out of time or budget, keep the partial
+		// neighborhood and stop
 		neighbors, edges, err := c.getNodeNeighborsWithEdges(ctx, current.ID)
 		if err != nil {
-			continue // Skip on error, continue with other nodes
+			if !isBudgetStop(err) {
+				continue // Skip on error, continue with other nodes
+			}
+			stopErr = err
 		}
 		
-		// Add edges to result
+		// Edges below the minimum confidence or not holding at the as-of time, and
+		// neighbors below the minimum confidence, are neither returned nor traversed
+		reachable := make(map[string]bool, len(edges))
 		for _, edge := range edges {
+			if !graphEdgeAllowed(edge, query) {
+				continue
+			}
+			reachable[edge.SourceNodeID], reachable[edge.TargetNodeID] = true, true
 			allEdges = append(allEdges, edge)
 		}
+		neighbors, _ = filterGraph(neighbors, nil, query)
 		
 		// Add unvisited neighbors to queue
 		for _, neighbor := range neighbors {
+			if !reachable[neighbor.ID] {
+				continue
+			}
 			if !visited[neighbor.ID] && len(allNodes) < query.Limit {
 				visited[neighbor.ID] = true
 				depthMap[neighbor.ID] = currentDepth + 1
@@ -1700,20 +1999,28 @@ func (c *supabaseHTTPClient) manualGraphSearch(ctx context.Context, query *model
 		}
 	}
 	
-	return &models.GraphResult{
-		Nodes: allNodes,
-		Edges: allEdges,
-	}, nil
+	// Drop edges to neighbors that were left out
+	_, allEdges = filterGraph(allNodes, allEdges, query)
+	return truncatedGraphResult(allNodes, allEdges, stopErr), nil
+}
+
+// truncatedGraphResult builds a traversal result, marking it partial when stopErr stopped it
+func truncatedGraphResult(nodes []models.GraphNode, edges []models.GraphEdge, stopErr error) *models.GraphResult {
+	result := &models.GraphResult{Nodes: nodes, Edges: edges}
+	if stopErr != nil {
+		result.Truncated = true
+		result.TruncatedReason = truncationReason(stopErr)
+	}
+	return result
 }
 
 // GetNodesByEntity retrieves all nodes with a specific entity name
 func (c *supabaseHTTPClient) GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error) {
-	params := map[string]string{
-		"select":      "*",
-		"entity_name": "eq." + entityName,
-		"order":       "created_at.desc",
-	}
-	endpoint := "/graph_nodes" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("graph_nodes").
+		Select("*").
+		Where(Eq("entity_name", entityName)).
+		OrderDesc("created_at").
+		String()
 	
 	var nodes []models.GraphNode
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &nodes)
@@ -1724,11 +2031,15 @@ func (c *supabaseHTTPClient) GetNodesByEntity(ctx context.Context, entityName st
 	return nodes, nil
 }
 
-// GetNodeNeighbors retrieves neighboring nodes within specified depth
+// GetNodeNeighbors retrieves neighboring nodes within specified depth. Nodes are ordered
+// by depth and, within a node's neighbors, by the decayed weight of the edge reaching
+// them; edges are ordered by decayed weight.
 func (c *supabaseHTTPClient) GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error) {
 	if maxDepth <= 0 {
 		maxDepth = 1
 	}
+	ctx = withDefaultRequestBudget(ctx, DefaultGraphTraversalBudget)
+	now := time.Now()
 	
 	visited := make(map[string]bool)
 	var allNodes []models.GraphNode
@@ -1746,7 +2057,8 @@ func (c *supabaseHTTPClient) GetNodeNeighbors(ctx context.Context, nodeID string
 	visited[nodeID] = true
 	allNodes = append(allNodes, *startNode)
 	
-	for len(queue) > 0 {
+	var stopErr error
+	for len(queue) > 0 && stopErr == nil {
 		current := queue[0]
 		queue = queue[1:]
 		
@@ -1757,7 +2069,10 @@ func (c *supabaseHTTPClient) GetNodeNeighbors(ctx context.Context, nodeID string
 		
 		neighbors, edges, err := c.getNodeNeighborsWithEdges(ctx, current.ID)
 		if err != nil {
-			continue
+			if !isBudgetStop(err) {
+				continue
+			}
+			stopErr = err
 		}
 		
 		// Add edges
@@ -1765,7 +2080,8 @@ func (c *supabaseHTTPClient) GetNodeNeighbors(ctx context.Context, nodeID string
 			allEdges = append(allEdges, edge)
 		}
 		
-		// Add unvisited neighbors
+		// Add unvisited neighbors, strongest relationships first
+		sortNeighborsByRelevance(current.ID, neighbors, edges, now, c.edgeDecay)
 		for _, neighbor := range neighbors {
 			if !visited[neighbor.ID] {
 				visited[neighbor.ID] = true
@@ -1776,103 +2092,130 @@ func (c *supabaseHTTPClient) GetNodeNeighbors(ctx context.Context, nodeID string
 		}
 	}
 	
-	return &models.GraphResult{
-		Nodes: allNodes,
-		Edges: allEdges,
-	}, nil
+	sortEdgesByRelevance(allEdges, now, c.edgeDecay)
+	return truncatedGraphResult(allNodes, allEdges, stopErr), nil
 }
 
-// FindPathBetweenNodes finds a path between two nodes using BFS
+// FindPathBetweenNodes finds the weighted shortest path between two nodes with at most
+// maxDepth edges. Crossing an edge costs the inverse of its decayed weight, so the path
+// follows the strongest, most recently seen relationships rather than the fewest hops.
+// Each node is expanded once, by the cheapest path reaching it.
 func (c *supabaseHTTPClient) FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error) {
 	if maxDepth <= 0 {
 		maxDepth = 5 // Default max depth for path finding
 	}
+	ctx = withDefaultRequestBudget(ctx, DefaultGraphTraversalBudget)
+	now := time.Now()
 	
-	// BFS to find shortest path
-	queue := []string{sourceNodeID}
-	visited := make(map[string]bool)
+	// Dijkstra's algorithm, keeping the nodes and edges it fetches so the path can be
+	// rebuilt without further requests
+	frontier := &pathQueue{{nodeID: sourceNodeID}}
+	cost := map[string]float64{sourceNodeID: 0}
+	depth := map[string]int{sourceNodeID: 0}
+	expanded := make(map[string]bool)
 	parent := make(map[string]string)
-	depth := make(map[string]int)
-	
-	visited[sourceNodeID] = true
-	depth[sourceNodeID] = 0
+	parentEdge := make(map[string]models.GraphEdge)
+	nodesByID := make(map[string]models.GraphNode)
 	
 	found := false
+	var stopErr error
 	
-	for len(queue) > 0 && !found {
-		current := queue[0]
-		queue = queue[1:]
+	for frontier.Len() > 0 && stopErr == nil {
+		entry := heap.Pop(frontier).(pathEntry)
+		current := entry.nodeID
+		if expanded[current] || entry.cost > cost[current] {
+			continue // a cheaper path to this node was already taken
+		}
+		expanded[current] = true
 		
+		if current == targetNodeID {
+			found = true
+			break
+		}
 		if depth[current] >= maxDepth {
 			continue
 		}
 		
 		// Get neighbors
-		neighbors, _, err := c.getNodeNeighborsWithEdges(ctx, current)
+		neighbors, edges, err := c.getNodeNeighborsWithEdges(ctx, current)
 		if err != nil {
-			continue
+			if !isBudgetStop(err) {
+				continue
+			}
+			stopErr = err
 		}
 		
+		neighborsByID := make(map[string]models.GraphNode, len(neighbors))
 		for _, neighbor := range neighbors {
-			if !visited[neighbor.ID] {
-				visited[neighbor.ID] = true
-				parent[neighbor.ID] = current
-				depth[neighbor.ID] = depth[current] + 1
-				queue = append(queue, neighbor.ID)
-				
-				if neighbor.ID == targetNodeID {
-					found = true
-					break
-				}
+			neighborsByID[neighbor.ID] = neighbor
+		}
+		
+		for _, edge := range edges {
+			next := edge.TargetNodeID
+			if edge.SourceNodeID != current {
+				next = edge.SourceNodeID
 			}
+			neighbor, ok := neighborsByID[next]
+			if !ok || expanded[next] {
+				continue
+			}
+			nextCost := cost[current] + edgeCost(edge, now, c.edgeDecay)
+			if known, seen := cost[next]; seen && known <= nextCost {
+				continue
+			}
+			cost[next] = nextCost
+			depth[next] = depth[current] + 1
+			parent[next] = current
+			parentEdge[next] = edge
+			nodesByID[next] = neighbor
+			heap.Push(frontier, pathEntry{nodeID: next, cost: nextCost})
 		}
 	}
 	
+	// Out of time or budget, the best path found to the target so far is still a path
+	if !found && stopErr != nil && parent[targetNodeID] != "" {
+		found = true
+	}
+	
 	if !found {
-		return &models.GraphResult{
-			Nodes: []models.GraphNode{},
-			Edges: []models.GraphEdge{},
-		}, nil
+		// A truncated empty result means no path was found within the budget, not that
+		// none exists
+		return truncatedGraphResult([]models.GraphNode{}, []models.GraphEdge{}, stopErr), nil
 	}
 	
 	// Reconstruct path
 	var pathNodes []models.GraphNode
 	var pathEdges []models.GraphEdge
 	
+	// The source is the only path node not fetched as a neighbor
+	if sourceNode, err := c.getNodeByID(ctx, sourceNodeID); err == nil {
+		nodesByID[sourceNodeID] = *sourceNode
+	} else if isBudgetStop(err) {
+		stopErr = err
+	}
+	
 	// Build path from target back to source
 	current := targetNodeID
 	for current != "" {
-		node, err := c.getNodeByID(ctx, current)
-		if err != nil {
-			break
+		if node, ok := nodesByID[current]; ok {
+			pathNodes = append([]models.GraphNode{node}, pathNodes...)
 		}
-		pathNodes = append([]models.GraphNode{*node}, pathNodes...)
-		
 		if parent[current] != "" {
-			// Find edge between parent and current
-			edge, err := c.findEdgeBetweenNodes(ctx, parent[current], current)
-			if err == nil {
-				pathEdges = append([]models.GraphEdge{*edge}, pathEdges...)
-			}
+			pathEdges = append([]models.GraphEdge{parentEdge[current]}, pathEdges...)
 		}
-		
 		current = parent[current]
 	}
 	
-	return &models.GraphResult{
-		Nodes: pathNodes,
-		Edges: pathEdges,
-	}, nil
+	return truncatedGraphResult(pathNodes, pathEdges, stopErr), nil
 }
 
 // GetNodesByChunk retrieves all graph nodes associated with a specific chunk
 func (c *supabaseHTTPClient) GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error) {
-	params := map[string]string{
-		"select":   "*",
-		"chunk_id": "eq." + chunkID,
-		"order":    "created_at.desc",
-	}
-	endpoint := "/graph_nodes" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("graph_nodes").
+		Select("*").
+		Where(Eq("chunk_id", chunkID)).
+		OrderDesc("created_at").
+		String()
 	
 	var nodes []models.GraphNode
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &nodes)
@@ -1885,12 +2228,11 @@ func (c *supabaseHTTPClient) GetNodesByChunk(ctx context.Context, chunkID string
 
 // GetEdgesByRelationType retrieves all edges of a specific relationship type
 func (c *supabaseHTTPClient) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) {
-	params := map[string]string{
-		"select":            "*",
-		"relationship_type": "eq." + relationType,
-		"order":             "created_at.desc",
-	}
-	endpoint := "/graph_edges" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("graph_edges").
+		Select("*").
+		Where(Eq("relationship_type", relationType)).
+		OrderDesc("created_at").
+		String()
 	
 	var edges []models.GraphEdge
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &edges)
@@ -1905,11 +2247,7 @@ func (c *supabaseHTTPClient) GetEdgesByRelationType(ctx context.Context, relatio
 
 // getNodeByID retrieves a single node by ID
 func (c *supabaseHTTPClient) getNodeByID(ctx context.Context, nodeID string) (*models.GraphNode, error) {
-	params := map[string]string{
-		"select": "*",
-		"id":     "eq." + nodeID,
-	}
-	endpoint := "/graph_nodes" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("graph_nodes").Select("*").Where(Eq("id", nodeID)).String()
 	
 	var nodes []models.GraphNode
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &nodes)
@@ -1924,14 +2262,39 @@ func (c *supabaseHTTPClient) getNodeByID(ctx context.Context, nodeID string) (*m
 	return &nodes[0], nil
 }
 
+// GetNodesByIDs retrieves graph nodes by ID with one request per maxIDsPerRequest IDs,
+// returned in the order requested. IDs without a node are omitted.
+func (c *supabaseHTTPClient) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) {
+	byID := make(map[string]models.GraphNode, len(ids))
+	for _, batch := range idBatches(ids) {
+		endpoint := NewPostgRESTQuery("graph_nodes").Select("*").Where(In("id", batch)).String()
+		
+		var nodes []models.GraphNode
+		err := c.makeRequest(ctx, "GET", endpoint, nil, &nodes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get nodes by IDs: %w", err)
+		}
+		for _, node := range nodes {
+			byID[node.ID] = node
+		}
+	}
+	
+	nodes := make([]models.GraphNode, 0, len(byID))
+	for _, id := range dedupeIDs(ids) {
+		if node, ok := byID[id]; ok {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes, nil
+}
+
 // getNodeNeighborsWithEdges retrieves direct neighbors and connecting edges
 func (c *supabaseHTTPClient) getNodeNeighborsWithEdges(ctx context.Context, nodeID string) ([]models.GraphNode, []models.GraphEdge, error) {
 	// Get all edges connected to this node
-	params := map[string]string{
-		"select": "*",
-		"or":     "(source_node_id.eq." + nodeID + ",target_node_id.eq." + nodeID + ")",
-	}
-	endpoint := "/graph_edges" + buildQueryParams(params)
+	endpoint := NewPostgRESTQuery("graph_edges").
+		Select("*").
+		Where(Or(Eq("source_node_id", nodeID), Eq("target_node_id", nodeID))).
+		String()
 	
 	var edges []models.GraphEdge
 	err := c.makeRequest(ctx, "GET", endpoint, nil, &edges)
@@ -1940,48 +2303,23 @@ func (c *supabaseHTTPClient) getNodeNeighborsWithEdges(ctx context.Context, node
 	}
 	
 	// Collect neighbor node IDs
-	neighborIDs := make(map[string]bool)
+	var neighborIDs []string
 	for _, edge := range edges {
 		if edge.SourceNodeID == nodeID {
-			neighborIDs[edge.TargetNodeID] = true
+			neighborIDs = append(neighborIDs, edge.TargetNodeID)
 		} else if edge.TargetNodeID == nodeID {
-			neighborIDs[edge.SourceNodeID] = true
-		}
-	}
-	
-	// Get neighbor nodes
-	var neighbors []models.GraphNode
-	for neighborID := range neighborIDs {
-		node, err := c.getNodeByID(ctx, neighborID)
-		if err != nil {
-			continue // Skip invalid nodes
+			neighborIDs = append(neighborIDs, edge.SourceNodeID)
 		}
-		neighbors = append(neighbors, *node)
 	}
 	
-	return neighbors, edges, nil
-}
-
-// findEdgeBetweenNodes finds an edge between two specific nodes
-func (c *supabaseHTTPClient) findEdgeBetweenNodes(ctx context.Context, sourceID, targetID string) (*models.GraphEdge, error) {
-	params := map[string]string{
-		"select": "*",
-		"or":     "(and(source_node_id.eq." + sourceID + ",target_node_id.eq." + targetID + "),and(source_node_id.eq." + targetID + ",target_node_id.eq." + sourceID + "))",
-		"limit":  "1",
-	}
-	endpoint := "/graph_edges" + buildQueryParams(params)
-	
-	var edges []models.GraphEdge
-	err := c.makeRequest(ctx, "GET", endpoint, nil, &edges)
+	// Get neighbor nodes; nodes missing from graph_nodes are skipped, and when time or
+	// budget runs out the edges are still returned
+	neighbors, err := c.GetNodesByIDs(ctx, neighborIDs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find edge between nodes: %w", err)
+		return nil, edges, err
 	}
 	
-	if len(edges) == 0 {
-		return nil, fmt.Errorf("no edge found between nodes %s and %s", sourceID, targetID)
-	}
-	
-	return &edges[0], nil
+	return neighbors, edges, nil
 }
 
 // mapToGraphNode converts map to GraphNode
@@ -158,6 +158,18 @@ func (m *MockSupabaseClient) GetNodesByChunk(ctx context.Context, chunkID string
 	return result, nil
 }
 
+func (m *MockSupabaseClient) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) {
+	var result []models.GraphNode
+	for _, id := range ids {
+		for _, node := range m.nodes {
+			if node.ID == id {
+				result = append(result, node)
+			}
+		}
+	}
+	return result, nil
+}
+
 func (m *MockSupabaseClient) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) {
 	var result []models.GraphEdge
 	for _, edge := range m.edges {
@@ -130,6 +130,16 @@ func (m *MockSupabaseClient) GetChunkByID(ctx context.Context, id string) (*mode
 	return chunk, nil
 }
 
+func (m *MockSupabaseClient) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) {
+	var result []models.ChunkRecord
+	for _, id := range ids {
+		if chunk, exists := m.chunks[id]; exists {
+			result = append(result, *chunk)
+		}
+	}
+	return result, nil
+}
+
 func (m *MockSupabaseClient) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) {
 	for _, chunk := range m.chunks {
 		if chunk.Content == content {
@@ -572,6 +582,26 @@ func (m *MockSupabaseClient) MoveChunk(ctx context.Context, req *models.MoveChun
 	return nil
 }
 
+func (m *MockSupabaseClient) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error {
+	chunk.ParentChunkID = nil
+	if parentID != "" {
+		chunk.ParentChunkID = &parentID
+	}
+	return m.InsertChunk(ctx, chunk)
+}
+
+func (m *MockSupabaseClient) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error {
+	for i, id := range orderedChildIDs {
+		chunk, exists := m.chunks[id]
+		if !exists {
+			return &SupabaseError{Code: "404", Message: "Chunk not found"}
+		}
+		position := i
+		chunk.SequenceNumber = &position
+	}
+	return nil
+}
+
 func (m *MockSupabaseClient) BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error {
 	for _, update := range req.Updates {
 		chunk, exists := m.chunks[update.ChunkID]
@@ -1003,4 +1033,26 @@ func TestSupabaseClient_TagOperations(t *testing.T) {
 	if len(tagsAfterRemoval) != 0 {
 		t.Errorf("Expected 0 tags after removal, got %d", len(tagsAfterRemoval))
 	}
-}
\ No newline at end of file
+}
+
+func TestDiffTemplateSlots(t *testing.T) {
+	change := diffTemplateSlots([]string{"name", "phone", "email"}, []string{"email", "name", "address"})
+
+	if strings.Join(change.added, ",") != "address" {
+		t.Errorf("Expected added slots [address], got %v", change.added)
+	}
+	if strings.Join(change.removed, ",") != "phone" {
+		t.Errorf("Expected removed slots [phone], got %v", change.removed)
+	}
+	if strings.Join(change.reordered, ",") != "email,name" {
+		t.Errorf("Expected reordered slots [email name], got %v", change.reordered)
+	}
+	if change.newIndex["address"] != 2 || change.oldIndex["email"] != 2 {
+		t.Errorf("Unexpected slot positions: old %v, new %v", change.oldIndex, change.newIndex)
+	}
+
+	unchanged := diffTemplateSlots([]string{"name", "phone"}, []string{"name", "phone"})
+	if len(unchanged.added)+len(unchanged.removed)+len(unchanged.reordered) != 0 {
+		t.Errorf("Expected no changes, got %+v", unchanged)
+	}
+}
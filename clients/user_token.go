@@ -0,0 +1,45 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ErrAnonKeyRequired is returned for requests made with a user token when no anon key
+// is configured. The service key would bypass the row-level security the token is
+// meant to apply.
+var ErrAnonKeyRequired = errors.New("user token requests require SUPABASE_ANON_KEY")
+
+type userTokenKey struct{}
+
+// WithUserToken returns a context whose Supabase requests run as the end user who owns
+// token, a Supabase access token (JWT). Such requests send the anon key as apikey and
+// the user's token as bearer, so PostgREST applies the project's row-level security
+// policies instead of the service key's unrestricted access. An empty token leaves ctx
+// unchanged.
+func WithUserToken(ctx context.Context, token string) context.Context {
+	token = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(token), "Bearer "))
+	if token == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, userTokenKey{}, token)
+}
+
+// UserTokenFromContext returns the token set by WithUserToken, if any
+func UserTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(userTokenKey{}).(string)
+	return token, ok && token != ""
+}
+
+// authHeaders returns the apikey and bearer credential for a request made under ctx
+func (c *supabaseHTTPClient) authHeaders(ctx context.Context) (apiKey, bearer string, err error) {
+	token, ok := UserTokenFromContext(ctx)
+	if !ok {
+		return c.apiKey, c.apiKey, nil
+	}
+	if c.anonKey == "" {
+		return "", "", ErrAnonKeyRequired
+	}
+	return c.anonKey, token, nil
+}
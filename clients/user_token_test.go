@@ -0,0 +1,55 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"semantic-text-processor/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserToken_ForwardsJWTWithAnonKey(t *testing.T) {
+	var apiKey, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKey = r.Header.Get("apikey")
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "service", AnonKey: "anon"})
+
+	_, err := client.GetChunksByTextID(context.Background(), "t")
+	require.NoError(t, err)
+	assert.Equal(t, "service", apiKey)
+	assert.Equal(t, "Bearer service", authorization)
+
+	_, err = client.GetChunksByTextID(WithUserToken(context.Background(), "Bearer user-jwt"), "t")
+	require.NoError(t, err)
+	assert.Equal(t, "anon", apiKey)
+	assert.Equal(t, "Bearer user-jwt", authorization)
+
+	// An empty token keeps the service key
+	_, err = client.GetChunksByTextID(WithUserToken(context.Background(), " "), "t")
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer service", authorization)
+}
+
+func TestUserToken_RequiresAnonKey(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := NewSupabaseClient(&config.SupabaseConfig{URL: server.URL, APIKey: "service"})
+	_, err := client.GetChunksByTextID(WithUserToken(context.Background(), "user-jwt"), "t")
+	assert.ErrorIs(t, err, ErrAnonKeyRequired)
+	assert.Zero(t, requests, "the service key is never sent with a user token")
+}
@@ -0,0 +1,677 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/database"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		showHelp()
+		os.Exit(2)
+	}
+
+	// Load environment variables from .env file if it exists
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "export":
+		err = runExport(os.Args[2:])
+	case "import":
+		err = runImport(os.Args[2:])
+	case "index-images":
+		err = runIndexImages(os.Args[2:])
+	case "graph-analytics":
+		err = runGraphAnalytics(os.Args[2:])
+	case "export-graph":
+		err = runExportGraph(os.Args[2:])
+	case "rebuild-hierarchy":
+		err = runRebuildHierarchy(os.Args[2:])
+	case "create-token":
+		err = runCreateToken(os.Args[2:])
+	case "import-notes":
+		err = runImportNotes(os.Args[2:])
+	case "reencode-embeddings":
+		err = runReencodeEmbeddings(os.Args[2:])
+	case "supabase-schema":
+		err = runSupabaseSchema(os.Args[2:])
+	case "seed":
+		err = runSeed(os.Args[2:])
+	case "attachments":
+		err = runAttachments(os.Args[2:])
+	case "help", "-h", "--help":
+		showHelp()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", os.Args[1])
+		showHelp()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("%s failed: %v", os.Args[1], err)
+	}
+}
+
+// runExport writes a snapshot archive of the knowledge base
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	out := fs.String("out", "backup.tar.gz", "Archive file to write")
+	since := fs.String("since", "", "Only export chunks updated at or after this RFC 3339 time")
+	fs.Parse(args)
+
+	var opts models.SnapshotExportOptions
+	if *since != "" {
+		sinceTime, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			return fmt.Errorf("invalid -since time: %w", err)
+		}
+		opts.Since = &sinceTime
+	}
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+	snapshotService := serviceContainer.SnapshotService
+
+	// Write to a temporary file so a failed export never leaves a truncated archive
+	tmpPath := *out + ".tmp"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	manifest, err := snapshotService.Export(context.Background(), file, opts)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write archive: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, *out); err != nil {
+		return fmt.Errorf("failed to save archive: %w", err)
+	}
+
+	log.Printf("Exported %d chunks (%d pages, %d templates), %d embeddings to %s",
+		manifest.Counts[models.SnapshotChunksFile], manifest.Pages, manifest.Templates,
+		manifest.Counts[models.SnapshotEmbeddingsFile], *out)
+	return nil
+}
+
+// runImport restores a snapshot archive
+func runImport(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	in := fs.String("in", "backup.tar.gz", "Archive file to restore")
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+	snapshotService := serviceContainer.SnapshotService
+
+	file, err := os.Open(*in)
+	if err != nil {
+		return fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer file.Close()
+
+	result, err := snapshotService.Import(context.Background(), file)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Restored %d chunks, %d embeddings, %d tag links and %d references from %s in %v",
+		result.Restored[models.SnapshotChunksFile], result.Restored[models.SnapshotEmbeddingsFile],
+		result.Restored[models.SnapshotChunkTagsFile], result.Restored[models.SnapshotChunkRefsFile],
+		*in, result.Duration)
+	return nil
+}
+
+// runIndexImages computes perceptual hashes and CLIP vectors for stored images
+func runIndexImages(args []string) error {
+	fs := flag.NewFlagSet("index-images", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	reindex := fs.Bool("reindex", false, "Recompute images that are already indexed")
+	concurrency := fs.Int("concurrency", 0, "Images processed in parallel (default IMAGE_SIMILARITY_INDEX_CONCURRENCY)")
+	batchSize := fs.Int("batch-size", 16, "Images per CLIP embedding request")
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+	if *concurrency <= 0 {
+		*concurrency = cfg.ImageSimilarity.IndexConcurrency
+	}
+
+	result, err := serviceContainer.ImageSimilarity.IndexImages(context.Background(), &services.ImageIndexOptions{
+		Reindex:     *reindex,
+		Concurrency: *concurrency,
+		BatchSize:   *batchSize,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, indexErr := range result.Errors {
+		log.Printf("Failed to index %s: %s", indexErr.ChunkID, indexErr.Error)
+	}
+	log.Printf("Indexed %d of %d images (%d already indexed, %d failed, %d without CLIP vectors) in %v",
+		result.Indexed, result.Total, result.Skipped, result.Failed, result.EmbeddingFailures, result.Duration)
+	return nil
+}
+
+// runGraphAnalytics recomputes PageRank, betweenness and communities for the knowledge graph
+func runGraphAnalytics(args []string) error {
+	fs := flag.NewFlagSet("graph-analytics", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	algorithms := fs.String("algorithms", "", "Comma-separated subset of pagerank,betweenness,community (default all)")
+	samples := fs.Int("betweenness-samples", 0, "Approximate betweenness from this many source nodes (default exact)")
+	resolution := fs.Float64("resolution", 1, "Louvain resolution; higher values give smaller communities")
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	opts := models.GraphAnalyticsOptions{
+		BetweennessSamples: *samples,
+		Resolution:         *resolution,
+	}
+	if *algorithms != "" {
+		opts.Algorithms = strings.Split(*algorithms, ",")
+	}
+
+	result, err := serviceContainer.GraphAnalytics.RunAnalytics(context.Background(), opts)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Scored %d nodes and %d edges with %s in %v",
+		result.NodeCount, result.EdgeCount, strings.Join(result.Algorithms, ", "), result.Duration)
+	if result.CommunityCount > 0 {
+		log.Printf("Found %d communities (modularity %.3f)", result.CommunityCount, result.Modularity)
+	}
+	return nil
+}
+
+// runExportGraph writes the knowledge graph, or the subgraph around a seed, for external viewers
+func runExportGraph(args []string) error {
+	fs := flag.NewFlagSet("export-graph", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	formatName := fs.String("format", "graphml", "Output format: graphml, gexf or cytoscape")
+	out := fs.String("out", "", "File to write (default stdout)")
+	entity := fs.String("entity", "", "Start from nodes with this entity name")
+	node := fs.String("node", "", "Start from this graph node ID")
+	page := fs.String("page", "", "Start from nodes extracted from this page")
+	depth := fs.Int("depth", 1, "How many edges to expand from the start nodes")
+	maxNodes := fs.Int("max-nodes", 0, "Maximum nodes to export (default 5000)")
+	minConfidence := fs.Float64("min-confidence", 0, "Leave out nodes and edges extracted with less confidence (0-1)")
+	asOf := fs.String("as-of", "", "Export only relationships holding at this RFC 3339 time")
+	fs.Parse(args)
+
+	format, err := services.ParseGraphExportFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	filter := models.GraphExportFilter{
+		NodeID:        *node,
+		EntityName:    *entity,
+		PageID:        *page,
+		Depth:         *depth,
+		MaxNodes:      *maxNodes,
+		MinConfidence: *minConfidence,
+	}
+	if *asOf != "" {
+		at, err := time.Parse(time.RFC3339, *asOf)
+		if err != nil {
+			return fmt.Errorf("invalid --as-of time: %w", err)
+		}
+		filter.AsOf = &at
+	}
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	output := os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer file.Close()
+		output = file
+	}
+
+	stats, err := serviceContainer.GraphExport.ExportGraph(context.Background(), output, format, filter)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Exported %d nodes and %d edges as %s", stats.NodeCount, stats.EdgeCount, stats.Format)
+	if stats.Truncated {
+		log.Printf("Export was truncated; raise --max-nodes or narrow the start nodes")
+	}
+	return nil
+}
+
+// runRebuildHierarchy recomputes the chunk_hierarchy closure table from parent pointers
+func runRebuildHierarchy(args []string) error {
+	fs := flag.NewFlagSet("rebuild-hierarchy", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	result, err := serviceContainer.ChunkHierarchy.Rebuild(context.Background())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Rebuilt %d hierarchy rows for %d chunks in %v", result.Rows, result.Chunks, result.Duration)
+	return nil
+}
+
+// runReencodeEmbeddings rewrites text embeddings in the EMBEDDING_STORAGE format and
+// EMBEDDING_DIMENSIONS
+func runReencodeEmbeddings(args []string) error {
+	fs := flag.NewFlagSet("reencode-embeddings", flag.ExitOnError)
+	batchSize := fs.Int("batch-size", 500, "embeddings re-encoded per transaction")
+	keepSource := fs.Bool("keep-source", false, "keep the embeddings in their previous format")
+	configOptions := config.RegisterFlags(fs)
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	result, err := serviceContainer.EmbeddingStore.Reencode(context.Background(), *batchSize, *keepSource)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Re-encoded %d embeddings as %s(%d) and cleared %d old copies in %v",
+		result.Reencoded, result.Format, result.Dimensions, result.Cleared, result.Duration)
+	if result.IndexName != "" {
+		log.Printf("Search uses index %s", result.IndexName)
+	}
+	if result.Cleared > 0 {
+		log.Printf("Run VACUUM chunks to reclaim the space of the cleared embeddings")
+	}
+	return nil
+}
+
+// runSupabaseSchema installs or verifies the RPC functions, triggers and indexes the
+// Supabase client needs, over the direct connection in SUPABASE_DB_URL
+func runSupabaseSchema(args []string) error {
+	if len(args) == 0 || (args[0] != "install" && args[0] != "verify") {
+		return fmt.Errorf("usage: ink-gateway supabase-schema install|verify")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("supabase-schema "+action, flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	fs.Parse(args[1:])
+
+	cfg, err := config.LoadAndValidate(*configOptions)
+	if err != nil {
+		return fmt.Errorf("configuration validation failed: %w", err)
+	}
+	if cfg.Supabase.DatabaseURL == "" {
+		return fmt.Errorf("SUPABASE_DB_URL is required")
+	}
+	db, err := sql.Open("pgx", cfg.Supabase.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open Supabase database: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	manager := database.NewSupabaseSchemaManager(db)
+	var statuses []database.SupabaseSchemaStatus
+	if action == "install" {
+		statuses, err = manager.Install(ctx)
+	} else {
+		statuses, err = manager.Verify(ctx)
+	}
+	for _, status := range statuses {
+		state := "ok"
+		if !status.Present {
+			state = "MISSING"
+		}
+		log.Printf("%-9s %-40s %s", status.Kind, status.Name, state)
+	}
+	if err != nil {
+		return err
+	}
+
+	if missing := database.MissingSchemaObjects(statuses); len(missing) > 0 {
+		return fmt.Errorf("%d required objects are missing; run ink-gateway supabase-schema install", len(missing))
+	}
+	log.Printf("All %d required objects are present", len(statuses))
+	return nil
+}
+
+// runAttachments reports how much deduplicated attachment storage saves, or deletes
+// blobs no attachment has referenced for STORAGE_DEDUP_GRACE_PERIOD
+func runAttachments(args []string) error {
+	if len(args) == 0 || (args[0] != "report" && args[0] != "gc") {
+		return fmt.Errorf("usage: ink-gateway attachments report|gc")
+	}
+	action := args[0]
+	fs := flag.NewFlagSet("attachments "+action, flag.ExitOnError)
+	top := fs.Int("top", 10, "duplicated attachments listed in the report")
+	dryRun := fs.Bool("dry-run", false, "count unreferenced blobs without deleting them")
+	configOptions := config.RegisterFlags(fs)
+	fs.Parse(args[1:])
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	dedup := serviceContainer.DedupStorage
+	if dedup == nil {
+		return fmt.Errorf("attachment deduplication is disabled; set STORAGE_DEDUP=true with a supabase or s3 STORAGE_PROVIDER")
+	}
+
+	ctx := context.Background()
+	if action == "gc" {
+		result, err := dedup.CollectGarbage(ctx, *dryRun)
+		if err != nil {
+			return err
+		}
+		verb := "Deleted"
+		if result.DryRun {
+			verb = "Would delete"
+		}
+		log.Printf("%s %d unreferenced blobs (%d bytes) in %v", verb, result.DeletedBlobs, result.DeletedBytes, result.Duration)
+		if result.Failed > 0 {
+			return fmt.Errorf("%d blobs could not be deleted; they are retried on the next run", result.Failed)
+		}
+		return nil
+	}
+
+	report, err := dedup.Report(ctx, *top)
+	if err != nil {
+		return err
+	}
+	log.Printf("%d attachments share %d blobs: %d bytes logical, %d bytes stored, %d bytes saved",
+		report.Objects, report.Blobs, report.LogicalBytes, report.StoredBytes, report.SavedBytes)
+	if report.UnreferencedBlobs > 0 {
+		log.Printf("%d unreferenced blobs (%d bytes) await garbage collection",
+			report.UnreferencedBlobs, report.UnreferencedBytes)
+	}
+	for _, duplicate := range report.TopDuplicates {
+		log.Printf("%s  %d references x %d bytes  saves %d bytes  %s",
+			duplicate.Hash[:12], duplicate.References, duplicate.Size, duplicate.SavedBytes,
+			strings.Join(duplicate.SampleKeys, ", "))
+	}
+	return nil
+}
+
+// runImportNotes imports a Notion export or Logseq graph, given as a folder or zip archive
+func runImportNotes(args []string) error {
+	flags := flag.NewFlagSet("import-notes", flag.ExitOnError)
+	configOptions := config.RegisterFlags(flags)
+	format := flags.String("format", "", "App the export comes from: notion or logseq")
+	in := flags.String("in", "", "Export folder or zip archive")
+	mode := flags.String("mode", "create", "create adds every page anew; upsert merges with earlier imports of the source")
+	source := flags.String("source", "", "Name of the export's source for upserts (default: the format)")
+	deleteMissing := flags.Bool("delete-missing", false, "With upsert, delete chunks whose pages or blocks left the export")
+	overwrite := flags.Bool("overwrite", false, "With upsert, overwrite chunks edited since the last import")
+	flags.Parse(args)
+
+	importFormat, err := services.ParseNoteImportFormat(*format)
+	if err != nil {
+		return err
+	}
+	importMode, err := services.ParseNoteImportMode(*mode)
+	if err != nil {
+		return err
+	}
+	if *in == "" {
+		return fmt.Errorf("--in is required")
+	}
+	export, closeExport, err := openNoteExport(*in)
+	if err != nil {
+		return err
+	}
+	defer closeExport()
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	result, err := serviceContainer.NoteImport.ImportWithOptions(context.Background(), importFormat, export, models.NoteImportOptions{
+		Mode:          importMode,
+		Source:        *source,
+		DeleteMissing: *deleteMissing,
+		Overwrite:     *overwrite,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+	if diff := result.Diff; diff != nil {
+		for _, conflict := range diff.Conflicts {
+			log.Printf("Conflict: chunk %s (%s) was edited since the last import; skipped %s", conflict.ChunkID, conflict.ExternalID, conflict.Change)
+		}
+		log.Printf("Merged %s into source %q: %d created, %d updated, %d unchanged, %d deleted, %d missing, %d conflicts",
+			*in, result.Source, diff.Created, diff.Updated, diff.Unchanged, diff.Deleted, diff.Missing, diff.Conflicted)
+		return nil
+	}
+	log.Printf("Imported %d pages and %d blocks from %s: %d links, %d unresolved, %d assets, %d template instances",
+		len(result.PageIDs), result.Chunks, *in, result.Links, result.UnresolvedLinks, result.Assets, result.TemplateInstances)
+	return nil
+}
+
+// openNoteExport opens an export folder or zip archive as a file system
+func openNoteExport(path string) (fs.FS, func() error, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export: %w", err)
+	}
+	if info.IsDir() {
+		return os.DirFS(path), func() error { return nil }, nil
+	}
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open export archive: %w", err)
+	}
+	return archive, archive.Close, nil
+}
+
+// runSeed generates a knowledge base of nested pages for demos and benchmarks
+func runSeed(args []string) error {
+	defaults := services.DefaultSeedCorpusOptions()
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	pages := fs.Int("pages", defaults.Pages, "Pages to generate")
+	blocksPerPage := fs.Int("blocks-per-page", defaults.BlocksPerPage, "Average blocks per page")
+	depth := fs.Int("depth", defaults.MaxDepth, "Deepest block nesting below a page")
+	languages := fs.String("languages", strings.Join(defaults.Languages, ","), "Comma-separated languages to write pages in")
+	tags := fs.Int("tags", defaults.Tags, "Tags to create")
+	templates := fs.Int("templates", defaults.Templates, "Templates to create; every fifth page is an instance")
+	refRate := fs.Float64("ref-rate", defaults.RefRate, "Share of blocks referencing an earlier page or block")
+	embeddings := fs.String("embeddings", defaults.Embeddings, "Embeddings to store: none, synthetic or service")
+	seed := fs.Int64("seed", defaults.Seed, "Random seed; the same seed and options generate the same chunks")
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	seeder := services.NewCorpusSeeder(serviceContainer.UnifiedChunkService, serviceContainer.EmbeddingStore,
+		serviceContainer.EmbeddingService, cfg.Embedding.Model)
+	result, err := seeder.Seed(context.Background(), models.SeedCorpusOptions{
+		Pages:         *pages,
+		BlocksPerPage: *blocksPerPage,
+		MaxDepth:      *depth,
+		Languages:     strings.Split(*languages, ","),
+		Tags:          *tags,
+		Templates:     *templates,
+		RefRate:       *refRate,
+		Embeddings:    *embeddings,
+		Dimensions:    cfg.Embedding.Dimensions,
+		Seed:          *seed,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Seeded %d pages and %d blocks up to depth %d, %d tags, %d templates with %d instances and %d references in %v",
+		result.Pages, result.Blocks, result.MaxDepth, result.Tags, result.Templates, result.TemplateInstances, result.Refs, result.Duration)
+	if result.Embedded > 0 {
+		log.Printf("Stored %d %s embeddings", result.Embedded, *embeddings)
+	}
+	return nil
+}
+
+// runCreateToken issues an API token, e.g. the first admin token of a workspace
+func runCreateToken(args []string) error {
+	fs := flag.NewFlagSet("create-token", flag.ExitOnError)
+	configOptions := config.RegisterFlags(fs)
+	name := fs.String("name", "", "Name to recognize the token by")
+	role := fs.String("role", string(models.RoleReader), "Role of the token: admin, editor or reader")
+	workspace := fs.String("workspace", services.DefaultWorkspace, "Workspace the token is valid for")
+	expiresIn := fs.String("expires-in", "", "Lifetime such as 720h; empty tokens do not expire")
+	fs.Parse(args)
+
+	cfg, serviceContainer, err := newServiceContainer(configOptions)
+	if err != nil {
+		return err
+	}
+	defer closeServiceContainer(cfg, serviceContainer)
+
+	token, err := serviceContainer.APITokens.CreateToken(context.Background(), *workspace, &models.CreateAPITokenRequest{
+		Name:      *name,
+		Role:      models.Role(*role),
+		ExpiresIn: *expiresIn,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Created %s token %q (%s) for workspace %s", token.Role, token.Name, token.ID, token.Workspace)
+	fmt.Println(token.Token)
+	return nil
+}
+
+func newServiceContainer(opts *config.LoadOptions) (*config.Config, *services.ServiceContainer, error) {
+	cfg, err := config.LoadAndValidate(*opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	serviceContainer, err := services.NewServiceFactory(cfg).CreateServices()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create services: %w", err)
+	}
+	return cfg, serviceContainer, nil
+}
+
+// closeServiceContainer closes the database pools once a command is done. Commands do not
+// start the background workers, so there is nothing else to wait for.
+func closeServiceContainer(cfg *config.Config, serviceContainer *services.ServiceContainer) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+	if err := serviceContainer.Close(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+func showHelp() {
+	fmt.Println("Ink Gateway administration")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  ink-gateway export --out backup.tar.gz [--since 2024-01-01T00:00:00Z]")
+	fmt.Println("  ink-gateway import --in backup.tar.gz")
+	fmt.Println("  ink-gateway index-images [--reindex] [--concurrency 4]")
+	fmt.Println("  ink-gateway graph-analytics [--algorithms pagerank,community] [--betweenness-samples 500]")
+	fmt.Println("  ink-gateway export-graph --format graphml|gexf|cytoscape [--entity NAME] [--depth 2] [--out graph.graphml]")
+	fmt.Println("  ink-gateway rebuild-hierarchy")
+	fmt.Println("  ink-gateway create-token --name NAME --role admin|editor|reader [--workspace default] [--expires-in 720h]")
+	fmt.Println("  ink-gateway import-notes --format notion|logseq --in export.zip|graph-folder [--mode create|upsert] [--source NAME] [--delete-missing] [--overwrite]")
+	fmt.Println("  ink-gateway reencode-embeddings [--batch-size 500] [--keep-source]")
+	fmt.Println("  ink-gateway supabase-schema install|verify")
+	fmt.Println("  ink-gateway seed [--pages 100] [--blocks-per-page 20] [--languages en,zh,ja,es] [--embeddings none|synthetic|service]")
+	fmt.Println("  ink-gateway attachments report [--top 10] | gc [--dry-run]")
+	fmt.Println()
+	fmt.Println("Full snapshots restore only into an empty database; incremental snapshots")
+	fmt.Println("(--since) are applied over existing data. Chunk IDs are preserved.")
+	fmt.Println()
+	fmt.Println("index-images hashes stored images for similarity search and fills missing")
+	fmt.Println("CLIP vectors when CLIP_ENDPOINT is set.")
+	fmt.Println()
+	fmt.Println("graph-analytics stores pagerank, betweenness and community scores as")
+	fmt.Println("graph node properties.")
+	fmt.Println()
+	fmt.Println("export-graph writes the whole graph, or the subgraph around --entity,")
+	fmt.Println("--node or --page, for Gephi, Cytoscape or yEd.")
+	fmt.Println()
+	fmt.Println("rebuild-hierarchy recomputes the chunk_hierarchy table from parent pointers.")
+	fmt.Println()
+	fmt.Println("create-token prints a new API token; it cannot be shown again.")
+	fmt.Println()
+	fmt.Println("reencode-embeddings converts text embeddings to EMBEDDING_STORAGE (vector,")
+	fmt.Println("halfvec or int8) and EMBEDDING_DIMENSIONS, then builds the search index.")
+	fmt.Println()
+	fmt.Println("supabase-schema connects to SUPABASE_DB_URL; install creates or replaces the")
+	fmt.Println("match_chunks and search_graph RPC functions, triggers and indexes, and verify")
+	fmt.Println("lists missing objects and fails if there are any.")
+	fmt.Println()
+	fmt.Println("seed writes a reproducible knowledge base of nested pages with tags, template")
+	fmt.Println("instances and cross-references. Synthetic embeddings cluster by page and")
+	fmt.Println("topic, so semantic search can be benchmarked without an embedding provider.")
+	fmt.Println()
+	fmt.Println("attachments needs STORAGE_DEDUP=true; report shows the space shared blobs")
+	fmt.Println("save and gc deletes blobs unreferenced for STORAGE_DEDUP_GRACE_PERIOD.")
+	fmt.Println()
+	fmt.Println("All commands accept -config and -set KEY=value to select the database.")
+}
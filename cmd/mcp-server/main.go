@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"semantic-text-processor/config"
+	"semantic-text-processor/handlers"
 	"semantic-text-processor/mcp"
 	"semantic-text-processor/services"
 
@@ -19,19 +23,39 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	// Load configuration
-	cfg := config.LoadConfig()
+	// Load layered configuration: file, then environment, then -set flags
+	opts := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
+	cfg, err := config.LoadAndValidate(*opts)
+	if err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
 	}
 
 	// Initialize services using service factory
-	mcpServices, err := initializeServices(cfg)
+	mcpServices, serviceContainer, err := initializeServices(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize services: %v", err)
 	}
+	if err := serviceContainer.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start services: %v", err)
+	}
+
+	// Pick up batch jobs interrupted by the last shutdown
+	if mcpServices.BatchProcessor != nil && cfg.Shutdown.CheckpointPath != "" {
+		jobs, err := mcpServices.BatchProcessor.ResumeFromCheckpoint(context.Background(), cfg.Shutdown.CheckpointPath)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+		}
+		if len(jobs) > 0 {
+			log.Printf("Resumed %d interrupted batch jobs", len(jobs))
+		}
+	}
+
+	// MCP itself runs over stdio, so probes get their own HTTP listener
+	if cfg.Probes.MCPAddr != "" && serviceContainer.Probes != nil {
+		go serveProbes(cfg.Probes.MCPAddr, serviceContainer.Probes)
+	}
 
 	// Create MCP server
 	server := mcp.NewMCPServer(
@@ -45,29 +69,77 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Start MCP server
+	log.Println("Starting Ink Multimodal MCP Server...")
+	serverErr := make(chan error, 1)
 	go func() {
-		<-sigChan
-		log.Println("Received shutdown signal, stopping server...")
-		server.Stop()
-		os.Exit(0)
+		serverErr <- server.Start()
 	}()
 
-	// Start MCP server
-	log.Println("Starting Ink Multimodal MCP Server...")
-	if err := server.Start(); err != nil {
-		log.Fatalf("Server error: %v", err)
+	// Run until a signal arrives or the client closes stdin
+	var startErr error
+	select {
+	case <-sigChan:
+		log.Println("Received shutdown signal, stopping server...")
+	case startErr = <-serverErr:
 	}
 
+	shutdown(cfg, server, mcpServices, serviceContainer)
+	if startErr != nil {
+		log.Fatalf("Server error: %v", startErr)
+	}
 	log.Println("Server stopped")
 }
 
+// shutdown drains in-flight requests, checkpoints running batch jobs and stops the
+// services, all within the configured shutdown timeout
+func shutdown(cfg *config.Config, server *mcp.MCPServer, mcpServices *mcp.MCPServices, serviceContainer *services.ServiceContainer) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+
+	if serviceContainer.Probes != nil {
+		serviceContainer.Probes.StartDraining()
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	if mcpServices.BatchProcessor != nil {
+		if cfg.Shutdown.CheckpointPath == "" {
+			log.Printf("Warning: SHUTDOWN_CHECKPOINT_PATH is not set; running batch jobs are not saved")
+		} else if saved, err := mcpServices.BatchProcessor.Checkpoint(cfg.Shutdown.CheckpointPath); err != nil {
+			log.Printf("Warning: %v", err)
+		} else if saved > 0 {
+			log.Printf("Checkpointed %d interrupted batch jobs to %s", saved, cfg.Shutdown.CheckpointPath)
+		}
+	}
+
+	if err := serviceContainer.Close(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// serveProbes serves the liveness, readiness and startup probes on addr
+func serveProbes(addr string, probes *services.ProbeService) {
+	handler := handlers.NewProbeHandler(probes)
+	mux := http.NewServeMux()
+	mux.HandleFunc(handlers.LivenessPath, handler.Liveness)
+	mux.HandleFunc(handlers.ReadinessPath, handler.Readiness)
+	mux.HandleFunc(handlers.StartupPath, handler.Startup)
+
+	log.Printf("Serving probes on %s", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Printf("Probe server error: %v", err)
+	}
+}
+
 // initializeServices initializes all services using the service factory
-func initializeServices(cfg *config.Config) (*mcp.MCPServices, error) {
+func initializeServices(cfg *config.Config) (*mcp.MCPServices, *services.ServiceContainer, error) {
 	// Create service factory and initialize services
 	serviceFactory := services.NewServiceFactory(cfg)
 	serviceContainer, err := serviceFactory.CreateServices()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Note: MediaProcessor, BatchProcessor, MultimodalSearch etc. need to be added
@@ -77,8 +149,12 @@ func initializeServices(cfg *config.Config) (*mcp.MCPServices, error) {
 		MediaProcessor:      nil, // TODO: Initialize when multimodal features are ready
 		MultimodalSearch:    nil,
 		BatchProcessor:      nil,
-		ImageSimilarity:     nil,
-		SlideRecommendation: nil,
-		StorageService:      nil,
-	}, nil
+		ImageSimilarity:     serviceContainer.ImageSimilarity,
+		SlideRecommendation: serviceContainer.SlideRecommendation,
+		StorageService:      serviceContainer.StorageService,
+		RAGService:          serviceContainer.RAGService,
+		Summarization:       serviceContainer.Summarization,
+		TemplateService:     serviceContainer.TemplateService,
+		Tasks:               serviceContainer.Tasks,
+	}, serviceContainer, nil
 }
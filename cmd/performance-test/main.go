@@ -5,7 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net/http"
+	_ "net/http/pprof"
 	"os"
+	"path/filepath"
 	"semantic-text-processor/config"
 	"semantic-text-processor/performance"
 	"semantic-text-processor/services"
@@ -14,8 +17,8 @@ import (
 
 func main() {
 	// Command line flags
+	configOptions := config.RegisterFlags(flag.CommandLine)
 	var (
-		configFile         = flag.String("config", "", "Configuration file path")
 		datasetSize        = flag.Int("dataset-size", 100000, "Number of test records to generate")
 		maxUsers           = flag.Int("max-users", 50, "Maximum concurrent users for load testing")
 		testDuration       = flag.Duration("duration", 5*time.Minute, "Duration for each load test step")
@@ -23,9 +26,18 @@ func main() {
 		enableRegression   = flag.Bool("regression", false, "Enable regression testing")
 		enableMonitoring   = flag.Bool("monitoring", true, "Enable resource monitoring")
 		outputPath         = flag.String("output", "", "Custom output path for reports")
+		format             = flag.String("format", "json", "Report format: json, or junit to also write a JUnit XML report for CI; with junit, -output names the XML file")
 		slowQueryThreshold = flag.Duration("slow-threshold", 500*time.Millisecond, "Slow query threshold")
 		memoryLimitMB      = flag.Int("memory-limit", 1024, "Memory limit in MB")
 		cpuThreshold       = flag.Float64("cpu-threshold", 80.0, "CPU usage threshold percentage")
+		scenarioPath       = flag.String("scenario", "", "YAML workload scenario describing the load test's operation mix")
+		mode               = flag.String("mode", "standalone", "Run mode: standalone, coordinator or worker")
+		listenAddr         = flag.String("listen", ":9090", "Address the coordinator listens on for workers")
+		expectedWorkers    = flag.Int("workers", 1, "Number of workers the coordinator waits for before starting")
+		coordinatorURL     = flag.String("coordinator", "", "Coordinator URL a worker registers with, e.g. http://host:9090")
+		workerCapacity     = flag.Int("worker-capacity", 0, "Relative share of users this worker takes; 0 on every worker splits load evenly")
+		captureProfiles    = flag.Bool("profile", true, "Capture CPU and heap profiles of every load step next to the report")
+		pprofAddr          = flag.String("pprof-addr", "", "Serve net/http/pprof on this address during the test, e.g. localhost:6060")
 		help               = flag.Bool("help", false, "Show help message")
 	)
 
@@ -36,15 +48,31 @@ func main() {
 		return
 	}
 
-	// Load configuration
-	cfg := config.LoadConfig()
-	if *configFile != "" {
-		// Load from custom config file if specified
-		log.Printf("Loading configuration from: %s", *configFile)
+	switch *mode {
+	case "standalone", "coordinator":
+	case "worker":
+		if *coordinatorURL == "" {
+			log.Fatalf("Worker mode requires -coordinator")
+		}
+	default:
+		log.Fatalf("Unknown mode %q: use standalone, coordinator or worker", *mode)
 	}
 
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
+	if *format != "json" && *format != "junit" {
+		log.Fatalf("Unknown format %q: use json or junit", *format)
+	}
+	// With -format junit, -output names the JUnit report and the JSON report keeps its default path
+	jsonOutputPath, junitOutputPath := *outputPath, ""
+	if *format == "junit" {
+		jsonOutputPath, junitOutputPath = "", *outputPath
+	}
+
+	// Load configuration from the -config file, environment and -set overrides
+	if configOptions.File != "" {
+		log.Printf("Loading configuration from: %s", configOptions.File)
+	}
+	cfg, err := config.LoadAndValidate(*configOptions)
+	if err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
 	}
 
@@ -52,16 +80,16 @@ func main() {
 	logger := log.New(os.Stdout, "[PERF-TEST] ", log.LstdFlags|log.Lshortfile)
 	logger.Printf("Starting performance testing suite...")
 
-	// Create service container
-	serviceFactory := services.NewServiceFactory(cfg)
-	serviceContainer, err := serviceFactory.CreateServices()
-	if err != nil {
-		log.Fatalf("Failed to create services: %v", err)
+	// Load the workload scenario before creating services so a bad file fails fast
+	var scenario *performance.WorkloadScenario
+	if *scenarioPath != "" {
+		scenario, err = performance.LoadWorkloadScenario(*scenarioPath)
+		if err != nil {
+			log.Fatalf("Failed to load workload scenario: %v", err)
+		}
+		logger.Printf("Loaded workload scenario %q from %s", scenario.Name, *scenarioPath)
 	}
 
-	// Create performance test orchestrator
-	orchestrator := performance.NewPerformanceTestOrchestrator(cfg, serviceContainer, logger)
-
 	// Create test configuration
 	testConfig := &performance.PerformanceTestConfig{
 		DatasetSize:            *datasetSize,
@@ -75,6 +103,24 @@ func main() {
 		MemoryLimitMB:          *memoryLimitMB,
 		CPUUsageThreshold:      *cpuThreshold,
 		GenerateMillionRecords: *generateMillion,
+		Scenario:               scenario,
+	}
+
+	if *captureProfiles {
+		testConfig.ProfileDir = "./performance_reports"
+		if *outputPath != "" {
+			testConfig.ProfileDir = filepath.Dir(*outputPath)
+		}
+	}
+
+	// Serve live profiles for inspection while the test runs
+	if *pprofAddr != "" {
+		go func() {
+			logger.Printf("Serving pprof on http://%s/debug/pprof/", *pprofAddr)
+			if err := http.ListenAndServe(*pprofAddr, nil); err != nil {
+				logger.Printf("pprof server stopped: %v", err)
+			}
+		}()
 	}
 
 	// Adjust dataset size for million-level testing
@@ -83,6 +129,32 @@ func main() {
 		logger.Printf("Million-level testing enabled: generating %d records", testConfig.DatasetSize)
 	}
 
+	// The coordinator only hands out work and aggregates results; it needs no services
+	if *mode == "coordinator" {
+		runCoordinator(testConfig, *listenAddr, *expectedWorkers, jsonOutputPath, *format, junitOutputPath, logger)
+		return
+	}
+
+	// Create service container
+	serviceFactory := services.NewServiceFactory(cfg)
+	serviceContainer, err := serviceFactory.CreateServices()
+	if err != nil {
+		log.Fatalf("Failed to create services: %v", err)
+	}
+	// Run the background workers as the server does, so the database sees the same load
+	if err := serviceContainer.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start services: %v", err)
+	}
+
+	if *mode == "worker" {
+		runWorker(serviceContainer, *coordinatorURL, *workerCapacity, logger)
+		closeServices(serviceContainer, cfg, logger)
+		return
+	}
+
+	// Create performance test orchestrator
+	orchestrator := performance.NewPerformanceTestOrchestrator(cfg, serviceContainer, logger)
+
 	// Create context with timeout
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
 	defer cancel()
@@ -100,13 +172,22 @@ func main() {
 	logger.Printf("Performance test completed successfully in %v", totalTime)
 
 	// Save the report
-	if err := orchestrator.SavePerformanceReport(report, *outputPath); err != nil {
+	if err := orchestrator.SavePerformanceReport(report, jsonOutputPath); err != nil {
 		log.Fatalf("Failed to save performance report: %v", err)
 	}
+	if *format == "junit" {
+		path := junitReportPath(junitOutputPath, "performance_report", report.StartTime)
+		if err := performance.SaveJUnitReport(performance.BuildJUnitReport(report, testConfig), path); err != nil {
+			log.Fatalf("Failed to save JUnit report: %v", err)
+		}
+		logger.Printf("JUnit report saved to: %s", path)
+	}
 
 	// Print summary to console
 	printSummary(report, logger)
 
+	closeServices(serviceContainer, cfg, logger)
+
 	// Generate final exit code based on results
 	exitCode := generateExitCode(report)
 	if exitCode != 0 {
@@ -118,6 +199,15 @@ func main() {
 	os.Exit(exitCode)
 }
 
+// closeServices stops the background workers and closes the database pools
+func closeServices(serviceContainer *services.ServiceContainer, cfg *config.Config, logger *log.Logger) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+	defer cancel()
+	if err := serviceContainer.Close(ctx); err != nil {
+		logger.Printf("Warning: failed to close services: %v", err)
+	}
+}
+
 func showHelp() {
 	fmt.Println("Performance Testing Suite for Semantic Text Processor")
 	fmt.Println("====================================================")
@@ -145,9 +235,94 @@ func showHelp() {
 	fmt.Println("  # Regression testing with custom thresholds")
 	fmt.Println("  performance-test -regression -slow-threshold 200ms -cpu-threshold 70")
 	fmt.Println()
+	fmt.Println("  # Replay production traffic described in a scenario file")
+	fmt.Println("  performance-test -scenario performance/scenarios/production.yaml")
+	fmt.Println()
+	fmt.Println("  # Distributed load: one coordinator and three workers on other machines")
+	fmt.Println("  performance-test -mode coordinator -workers 3 -max-users 1000")
+	fmt.Println("  performance-test -mode worker -coordinator http://coordinator:9090")
+	fmt.Println()
+	fmt.Println("  # Inspect a step's CPU profile as a flame graph after the run")
+	fmt.Println("  go tool pprof -http=:0 performance_reports/profiles_<timestamp>/step07_50users_cpu.pprof")
+	fmt.Println()
 	fmt.Println("  # Save report to custom location")
 	fmt.Println("  performance-test -output /path/to/report.json")
 	fmt.Println()
+	fmt.Println("  # Write a JUnit XML report for CI, one test case per load step and threshold check")
+	fmt.Println("  performance-test -format junit -output reports/performance.xml")
+	fmt.Println()
+}
+
+// runCoordinator serves the worker API, runs the load steps across the registered workers
+// and saves the aggregated result
+func runCoordinator(testConfig *performance.PerformanceTestConfig, listenAddr string, expectedWorkers int, outputPath, format, junitOutputPath string, logger *log.Logger) {
+	coordinator := performance.NewLoadCoordinator(performance.NewLoadTestConfig(testConfig), expectedWorkers, logger)
+	server := &http.Server{Addr: listenAddr, Handler: coordinator.Handler()}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Coordinator server failed: %v", err)
+		}
+	}()
+	logger.Printf("Coordinator listening on %s", listenAddr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Hour)
+	defer cancel()
+
+	result, err := coordinator.Run(ctx)
+	if err != nil {
+		log.Fatalf("Distributed load test failed: %v", err)
+	}
+
+	// Keep serving long enough for workers to learn the test is over
+	time.Sleep(3 * time.Second)
+	server.Close()
+
+	if outputPath == "" {
+		outputPath = fmt.Sprintf("./performance_reports/distributed_load_%s.json", result.StartTime.Format("20060102_150405"))
+	}
+	if err := performance.SaveLoadTestResult(result, outputPath); err != nil {
+		log.Fatalf("Failed to save load test result: %v", err)
+	}
+	if format == "junit" {
+		path := junitReportPath(junitOutputPath, "distributed_load", result.StartTime)
+		suites := performance.NewJUnitTestSuites("distributed_load",
+			performance.BuildLoadStepSuite(result.LoadSteps, testConfig.SlowQueryThreshold))
+		if err := performance.SaveJUnitReport(suites, path); err != nil {
+			log.Fatalf("Failed to save JUnit report: %v", err)
+		}
+		logger.Printf("JUnit report saved to: %s", path)
+	}
+
+	logger.Printf("=== DISTRIBUTED LOAD TEST SUMMARY ===")
+	for _, step := range result.LoadSteps {
+		logger.Printf("  - %d users: %.2f QPS, avg %v, error rate %.2f%%",
+			step.UserCount, step.QPS, step.AvgResponseTime, step.ErrorRate*100)
+	}
+	logger.Printf("Result saved to: %s", outputPath)
+}
+
+// runWorker runs the load steps the coordinator assigns until the test is over
+func runWorker(serviceContainer *services.ServiceContainer, coordinatorURL string, capacity int, logger *log.Logger) {
+	hostname, _ := os.Hostname()
+	executor := performance.NewLoadTestExecutor(serviceContainer, logger)
+	worker := performance.NewLoadWorker(coordinatorURL, executor, performance.WorkerRegistration{
+		Hostname: hostname,
+		Capacity: capacity,
+	}, logger)
+
+	if err := worker.Run(context.Background()); err != nil {
+		log.Fatalf("Load worker failed: %v", err)
+	}
+	logger.Printf("Load worker finished")
+}
+
+// junitReportPath returns the -output path when given, otherwise a timestamped file next to
+// the JSON reports
+func junitReportPath(outputPath, name string, startTime time.Time) string {
+	if outputPath != "" {
+		return outputPath
+	}
+	return fmt.Sprintf("./performance_reports/%s_%s.xml", name, startTime.Format("20060102_150405"))
 }
 
 func printSummary(report *performance.ComprehensivePerformanceReport, logger *log.Logger) {
@@ -186,6 +361,9 @@ func printSummary(report *performance.ComprehensivePerformanceReport, logger *lo
 	logger.Printf("  - Peak Memory: %s", formatBytes(report.ResourceUtilization.PeakMemoryUsage))
 	logger.Printf("  - Avg CPU: %.1f%%", report.ResourceUtilization.AverageCPUUsage)
 	logger.Printf("  - GC Count: %d", report.ResourceUtilization.GarbageCollection.NumGC)
+	if container := report.ResourceUtilization.Container; container != nil && container.MemoryLimit > 0 {
+		logger.Printf("  - Container Memory: peak %.1f%% of %s", container.PeakMemoryUtilization, formatBytes(container.MemoryLimit))
+	}
 
 	if len(report.OptimizationAnalysis.SlowQueries) > 0 {
 		logger.Printf("Performance Issues:")
@@ -198,6 +376,11 @@ func printSummary(report *performance.ComprehensivePerformanceReport, logger *lo
 		}
 	}
 
+	if profiles := report.OptimizationAnalysis.Profiles; len(profiles) > 0 {
+		logger.Printf("Profiles: %d captured in %s", len(profiles), filepath.Dir(profiles[0].Path))
+		logger.Printf("  - View with: %s", profiles[len(profiles)-1].ViewCommand)
+	}
+
 	if len(report.Recommendations) > 0 {
 		logger.Printf("Top Recommendations:")
 		for i, rec := range report.Recommendations {
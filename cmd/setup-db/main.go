@@ -1,37 +1,168 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"flag"
 	"fmt"
+	"log"
 	"os"
 
 	"semantic-text-processor/config"
+	"semantic-text-processor/database"
+	"semantic-text-processor/services"
+
+	"github.com/joho/godotenv"
 )
 
+// setup-db creates or upgrades the service database from database/unified_chunk_schema.sql
+// and the migration chain, using the DB_* settings of the configuration
 func main() {
-	fmt.Println("🔧 Setting up database schemas via Go program...")
+	fs := flag.NewFlagSet("setup-db", flag.ExitOnError)
+	dropExisting := fs.Bool("drop-existing", false, "drop the chunk tables and recreate them; all chunk data is lost")
+	baseline := fs.Bool("baseline", false, "record every migration as applied without running it, for databases set up by hand")
+	seedDemoData := fs.Bool("seed-demo-data", false, "create a small demo knowledge base")
+	supabase := fs.Bool("supabase", false, "also set up the Supabase database at SUPABASE_DB_URL and install its RPC functions")
+	configOptions := config.RegisterFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: setup-db [--drop-existing] [--seed-demo-data] [--supabase] [--baseline]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Applies database/unified_chunk_schema.sql to an empty database and then every")
+		fmt.Fprintln(os.Stderr, "migration not yet recorded in schema_migrations. Re-running it is safe.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	fs.Parse(os.Args[1:])
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, using system environment variables")
+	}
+
+	cfg, err := config.LoadAndValidate(*configOptions)
+	if err != nil {
+		log.Fatalf("Configuration validation failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := migrate(ctx, cfg, database.MigrateOptions{DropExisting: *dropExisting, Baseline: *baseline}); err != nil {
+		log.Fatalf("setup-db failed: %v", err)
+	}
+	if *supabase {
+		if err := setupSupabase(ctx, cfg, *dropExisting); err != nil {
+			log.Fatalf("setup-db failed: %v", err)
+		}
+	}
+	if *seedDemoData {
+		if err := seed(ctx, cfg); err != nil {
+			log.Fatalf("setup-db failed: %v", err)
+		}
+	}
+}
+
+// migrate brings the service database to the current schema
+func migrate(ctx context.Context, cfg *config.Config, opts database.MigrateOptions) error {
+	dsn := cfg.Database.WriteDSN
+	if dsn == "" {
+		dsn = (&database.PostgresConfig{
+			Host:     cfg.Database.Host,
+			Port:     cfg.Database.Port,
+			Database: cfg.Database.Database,
+			User:     cfg.Database.User,
+			Password: cfg.Database.Password,
+			SSLMode:  cfg.Database.SSLMode,
+		}).BuildDirectConnectionString()
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer db.Close()
 
-	// 使用 service key 來獲得更高權限
-	_ = &config.SupabaseConfig{
-		URL:    "http://localhost:8000",
-		APIKey: "eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9.eyAgCiAgICAicm9sZSI6ICJzZXJ2aWNlX3JvbGUiLAogICAgImlzcyI6ICJzdXBhYmFzZS1kZW1vIiwKICAgICJpYXQiOiAxNjQxNzY5MjAwLAogICAgImV4cCI6IDE3OTk1MzU2MDAKfQ.DaYlNEoUrrEn2Ig7tqibS-PHK5vgusbcbo7X36XVt4Q",
+	if opts.DropExisting {
+		log.Printf("Dropping the chunk tables of %s", cfg.Database.Database)
 	}
+	result, err := database.NewMigrator(db).Migrate(ctx, opts)
+	if result != nil {
+		if result.CreatedBase {
+			log.Printf("Created the base schema")
+		}
+		for _, name := range result.Applied {
+			log.Printf("Applied %s", name)
+		}
+		for _, name := range result.Skipped {
+			log.Printf("Skipped %s: its prerequisites are missing", name)
+		}
+		if len(result.Baselined) > 0 {
+			log.Printf("Recorded %d migrations as applied", len(result.Baselined))
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if len(result.Applied) == 0 && !result.CreatedBase && len(result.Baselined) == 0 {
+		log.Printf("Database schema is up to date")
+	}
+	return nil
+}
 
-	// 這裡我們不能直接執行 DDL，但可以測試連接和創建一些測試數據
-	fmt.Println("📡 Testing Supabase connection with service key...")
+// setupSupabase creates the legacy content_db, vector_db and graph_db schemas when they are
+// missing (or with --drop-existing) and installs the RPC functions the Supabase client calls
+func setupSupabase(ctx context.Context, cfg *config.Config, dropExisting bool) error {
+	if cfg.Supabase.DatabaseURL == "" {
+		return fmt.Errorf("SUPABASE_DB_URL is required for --supabase")
+	}
+	db, err := sql.Open("pgx", cfg.Supabase.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to open Supabase database: %w", err)
+	}
+	defer db.Close()
 
-	// 嘗試創建一個測試記錄來驗證表格是否存在
-	fmt.Println("🧪 Testing table existence by attempting to create test records...")
+	manager := database.NewSupabaseSchemaManager(db)
+	statuses, err := manager.Verify(ctx)
+	if err != nil {
+		return err
+	}
+	missingTables := false
+	for _, status := range database.MissingSchemaObjects(statuses) {
+		missingTables = missingTables || status.Kind == database.SchemaKindTable
+	}
+	if dropExisting || missingTables {
+		log.Printf("Recreating the Supabase schemas from database/reset_and_recreate.sql")
+		if err := database.ResetSupabaseSchema(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	statuses, err = manager.Install(ctx)
+	if err != nil {
+		return err
+	}
+	if missing := database.MissingSchemaObjects(statuses); len(missing) > 0 {
+		return fmt.Errorf("%d Supabase objects are still missing, e.g. %s %s", len(missing), missing[0].Kind, missing[0].Name)
+	}
+	log.Printf("Installed %d Supabase functions, triggers and indexes", len(statuses))
+	return nil
+}
 
-	// 由於我們不能通過 API 創建表格，我們需要手動在 Supabase Dashboard 中執行 SQL
-	fmt.Println("")
-	fmt.Println("⚠️  Database tables need to be created manually:")
-	fmt.Println("1. Open Supabase Dashboard at http://localhost:8000")
-	fmt.Println("2. Go to SQL Editor")
-	fmt.Println("3. Copy and paste the content of database/reset_and_recreate.sql")
-	fmt.Println("4. Execute the SQL")
-	fmt.Println("")
-	fmt.Println("After creating the tables, run the verification tests:")
-	fmt.Println("  ./scripts/verify-setup.sh")
+// seed creates the demo knowledge base through the chunk service, so hierarchy and tag
+// tables are filled as for any write
+func seed(ctx context.Context, cfg *config.Config) error {
+	serviceContainer, err := services.NewServiceFactory(cfg).CreateServices()
+	if err != nil {
+		return fmt.Errorf("failed to create services: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+		defer cancel()
+		if err := serviceContainer.Close(closeCtx); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}()
 
-	os.Exit(0)
-}
\ No newline at end of file
+	created, err := services.SeedDemoData(ctx, serviceContainer.UnifiedChunkService)
+	if err != nil {
+		return err
+	}
+	log.Printf("Created %d demo chunks", created)
+	return nil
+}
@@ -1,23 +1,47 @@
 package config
 
 import (
-	"os"
+	"encoding/base64"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Server      ServerConfig
-	Database    DatabaseConfig
-	Supabase    SupabaseConfig // Deprecated: Use Database instead
-	LLM         LLMConfig
-	Embedding   EmbeddingConfig
-	Logging     LoggingConfig
-	Cache       CacheConfig
-	Performance PerformanceConfig
-	Features    FeaturesConfig
-	Storage     StorageConfig
+	Server          ServerConfig
+	Database        DatabaseConfig
+	Supabase        SupabaseConfig // Deprecated: Use Database instead
+	LLM             LLMConfig
+	Embedding       EmbeddingConfig
+	Logging         LoggingConfig
+	Cache           CacheConfig
+	SearchCache     SearchCacheConfig
+	Performance     PerformanceConfig
+	Features        FeaturesConfig
+	Storage         StorageConfig
+	VectorIndex     VectorIndexConfig
+	RAG             RAGConfig
+	Rerank          RerankConfig
+	Summary         SummaryConfig
+	Render          RenderConfig
+	Publish         PublishConfig
+	Locks           ChunkLockConfig
+	Capture         CaptureConfig
+	EmailIngest     EmailIngestConfig
+	Journal         JournalConfig
+	Tasks           TaskConfig
+	ImageSimilarity ImageSimilarityConfig
+	ChangeFeed      ChangeFeedConfig
+	Suggestions     QuerySuggestionConfig
+	Encryption      EncryptionConfig
+	PII             PIIConfig
+	Auth            AuthConfig
+	Maintenance     MaintenanceConfig
+	Graph           GraphConfig
+	Probes          ProbeConfig
+	Shutdown        ShutdownConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -26,6 +50,13 @@ type ServerConfig struct {
 	ReadTimeout  time.Duration
 	WriteTimeout time.Duration
 	IdleTimeout  time.Duration
+
+	// HTTPCacheEnabled sends ETag and Last-Modified on chunk and page reads and answers
+	// matching conditional requests with 304 Not Modified
+	HTTPCacheEnabled bool
+	// HTTPCacheMaxAge is how long clients may reuse a read without revalidating; 0 makes
+	// them revalidate every time
+	HTTPCacheMaxAge time.Duration
 }
 
 // DatabaseConfig holds PostgreSQL database configuration
@@ -40,13 +71,48 @@ type DatabaseConfig struct {
 	// Connection pool settings
 	MaxConns int
 	MinConns int
+
+	// database/sql pool settings for the handles services query through;
+	// MaxOpenConns 0 means unlimited
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// Pool diagnostics: warn when this fraction of MaxOpenConns is in use,
+	// sampling the pool every PoolCheckInterval
+	PoolWarnUtilization float64
+	PoolCheckInterval   time.Duration
+
+	// Read/write splitting: WriteDSN overrides the primary connection built from the
+	// fields above, ReadDSNs lists replicas that serve query-heavy operations
+	WriteDSN                string
+	ReadDSNs                []string
+	ReplicaMaxLag           time.Duration
+	ReplicaLagCheckInterval time.Duration
+
+	// UnifiedChunkDriver selects how the unified chunk service talks to PostgreSQL while
+	// it moves to pgx: "database/sql", or "pgx" to read chunks by ID through the native
+	// pool and load batch creates with COPY. The pgx rollout is partial: updates, deletes,
+	// moves and LISTEN/NOTIFY stay on database/sql, so "pgx" opens a second pool against
+	// the primary
+	UnifiedChunkDriver string
+	// StatementCacheCapacity is how many prepared statements each pgx connection caches;
+	// 0 disables the cache, as connection poolers in transaction mode require
+	StatementCacheCapacity int
 }
 
 // SupabaseConfig holds Supabase client configuration
 // Deprecated: Use DatabaseConfig for direct PostgreSQL connection
 type SupabaseConfig struct {
 	URL    string
-	APIKey string
+	APIKey string // service key, used unless a request carries a user token
+	// AnonKey is sent as apikey with end-user tokens so row-level security applies
+	AnonKey string
+	// ForwardUserTokens passes the X-Supabase-Auth request header through to Supabase
+	ForwardUserTokens bool
+	// DatabaseURL is a direct Postgres connection to the Supabase database, used to
+	// install the RPC functions PostgREST cannot create
+	DatabaseURL string
 }
 
 // LLMConfig holds LLM service configuration
@@ -56,11 +122,218 @@ type LLMConfig struct {
 	Timeout  time.Duration
 }
 
+// RAGConfig holds retrieval-augmented answering configuration
+type RAGConfig struct {
+	Provider    string // "gateway", "openai", or "anthropic"
+	Endpoint    string
+	APIKey      string
+	Model       string
+	Timeout     time.Duration
+	Temperature float64
+
+	RetrievalLimit   int
+	SemanticWeight   float64
+	MaxContextTokens int
+	MaxAnswerTokens  int
+}
+
+// RerankConfig holds search result re-ranking configuration. Results are scored by a
+// cross-encoder rerank API, or by the RAG completion provider when the model is "llm".
+type RerankConfig struct {
+	Endpoint   string // cross-encoder rerank API; empty leaves only the LLM scorer
+	APIKey     string
+	Model      string // used when a request names no rerank_model
+	Candidates int    // vector results scored per re-ranked search
+	Timeout    time.Duration
+}
+
+// SummaryConfig holds chunk summarization configuration. Summaries are generated by the
+// RAG completion provider.
+type SummaryConfig struct {
+	MaxTokens      int // length of a generated summary
+	MaxInputTokens int // text summarized per completion; larger pages are reduced in rounds
+	MinLength      int // text of at most this many characters is its own summary
+	Concurrency    int // summaries generated in parallel within a page
+}
+
+// RenderConfig holds page rendering configuration. Rendered pages are cached until a chunk
+// they show changes, and at most for CacheTTL.
+type RenderConfig struct {
+	CacheTTL             time.Duration // 0 disables the cache
+	MaxTransclusionDepth int           // block references and embeds resolved inside one another
+}
+
+// PublishConfig holds public page publishing configuration. Published pages are served
+// without authentication at URLs signed with SigningSecret; changing the secret breaks
+// every published URL.
+type PublishConfig struct {
+	SigningSecret string // empty disables publishing
+	BaseURL       string // prefixed to published URLs; empty returns paths
+}
+
+// ChunkLockConfig holds editor lock configuration. Locks keep other editors from moving,
+// merging or deleting a chunk and its subtree until they are released or expire.
+type ChunkLockConfig struct {
+	Enabled    bool
+	DefaultTTL time.Duration // lifetime of locks acquired without a TTL
+	MaxTTL     time.Duration // longest lifetime one acquire or refresh may request
+}
+
+// CaptureConfig holds quick capture configuration. Captures land on a per-user inbox page
+// and are chunked and tagged in the background.
+type CaptureConfig struct {
+	InboxTitle string // title of inbox pages, followed by the owner for authenticated callers
+	MaxBytes   int    // largest capture accepted
+	QueueSize  int    // captures waiting for processing; more wait for the next sweep
+}
+
+// JournalConfig holds daily note configuration. Each caller has one journal page per day,
+// created when it is first asked for.
+type JournalConfig struct {
+	TitleFormat string // Go time layout of page titles, such as "Jan 2, 2006"
+	Timezone    string // IANA zone deciding when a day starts
+}
+
+// TaskConfig holds task index configuration. Chunks starting with a TODO/DONE marker are
+// indexed with their SCHEDULED and DEADLINE dates, and reminders for due tasks are posted to
+// a webhook.
+type TaskConfig struct {
+	Enabled          bool
+	Timezone         string        // IANA zone of dates without a time and of "today"
+	ReminderInterval time.Duration // how often due tasks are checked for reminders
+	WebhookURL       string        // receives a POST per due task; empty disables webhook reminders
+	WebhookSecret    string        // signs reminder bodies with HMAC-SHA256
+}
+
+// EmailIngestConfig holds email-to-note configuration. Emails arrive through SendGrid or
+// Mailgun inbound webhooks or by polling an IMAP mailbox, and become chunks on one page.
+type EmailIngestConfig struct {
+	PageID            string // page emails are filed on; empty disables email ingestion
+	WebhookSecret     string // password webhooks send with basic auth
+	MailgunSigningKey string // verifies the signature of Mailgun webhooks
+	MaxBytes          int    // largest message accepted, attachments included
+
+	IMAPAddr         string // host:port of an IMAPS server; empty disables polling
+	IMAPUsername     string
+	IMAPPassword     string
+	IMAPMailbox      string
+	IMAPPollInterval time.Duration
+}
+
+// ImageSimilarityConfig holds image similarity search configuration
+type ImageSimilarityConfig struct {
+	CLIPEndpoint string // CLIP embedding service; empty disables image vectors
+
+	MaxHashDistance    int     // pHash Hamming distance treated as a match (0-64)
+	EmbeddingThreshold float64 // CLIP cosine similarity treated as a match (0-1)
+	HashWeight         float64 // weight of hash similarity in the combined score (0-1)
+	IndexConcurrency   int
+}
+
+// ChangeFeedConfig holds real-time chunk change feed configuration
+type ChangeFeedConfig struct {
+	Enabled          bool
+	Channel          string // NOTIFY channel the chunks trigger publishes on
+	HistorySize      int    // events retained for Last-Event-ID replay
+	SubscriberBuffer int    // events buffered per subscriber before dropping
+	Heartbeat        time.Duration
+}
+
+// QuerySuggestionConfig holds search query logging and suggestion configuration
+type QuerySuggestionConfig struct {
+	Enabled        bool
+	MinSimilarity  float64 // trigram similarity (0-1) for typo-tolerant suggestions
+	MaxQueryLength int     // longer queries are not logged
+}
+
+// EncryptionConfig holds content encryption at rest configuration. Chunks flagged
+// sensitive are encrypted with a data key wrapped by the local master key or by KMS.
+type EncryptionConfig struct {
+	Enabled   bool
+	Provider  string // "local" or "aws-kms"
+	MasterKey string // base64 32-byte key for the local provider
+	KeyID     string // identifies the local master key in stored envelopes
+	KMS       KMSConfig
+}
+
+// KMSConfig holds AWS KMS configuration for wrapping data keys
+type KMSConfig struct {
+	KeyID           string // key ID, ARN or alias
+	Region          string
+	Endpoint        string // defaults to https://kms.<region>.amazonaws.com
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// PIIConfig holds personal data scanning configuration for chunk ingestion
+type PIIConfig struct {
+	Enabled           bool
+	DefaultPolicy     string   // "redact", "flag" or "reject"
+	WorkspacePolicies []string // workspace:policy pairs overriding DefaultPolicy
+	Detectors         []string // email, phone, id_number, credit_card; empty enables all
+}
+
+// AuthConfig holds API authentication configuration. When enabled, requests need an API
+// token created through the token API or a JWT signed with JWTSecret.
+type AuthConfig struct {
+	Enabled   bool
+	JWTSecret string // HMAC key for JWTs; empty accepts API tokens only
+	JWTIssuer string // required iss claim when set
+	// PageACLsEnabled enforces the page ACLs of database/page_acl_migration.sql
+	PageACLsEnabled bool
+}
+
+// MaintenanceConfig holds background maintenance configuration. When enabled, the
+// maintenance daemon applies the retention rules of every workspace each interval.
+type MaintenanceConfig struct {
+	Enabled  bool
+	Interval time.Duration
+}
+
+// ProbeConfig holds liveness, readiness and startup probe configuration
+type ProbeConfig struct {
+	Timeout        time.Duration // bounds each dependency check
+	EmbeddingCache time.Duration // how long a successful embedding provider check is reused
+	MCPAddr        string        // address the MCP server serves probes on; empty disables them
+}
+
+// ShutdownConfig holds graceful shutdown configuration. On SIGTERM readiness fails first,
+// then in-flight requests drain until Timeout and background work stops.
+type ShutdownConfig struct {
+	Timeout        time.Duration // bounds draining requests and stopping background work
+	DrainDelay     time.Duration // how long readiness fails before the listener closes
+	CheckpointPath string        // file interrupted batch jobs are saved to; empty disables it
+}
+
+// GraphConfig holds knowledge graph configuration. Edge weights decay by the time since
+// their relationship was last seen when ordering neighbors and finding paths.
+type GraphConfig struct {
+	EdgeDecayHalfLife time.Duration // zero disables decay
+	EdgeDecayFloor    float64       // lowest fraction (0-1) of its weight an edge decays to
+	SyncEnabled       bool          // re-extract the graph nodes of chunks whose contents change
+	SyncQueueSize     int           // chunks waiting for re-extraction before new edits are dropped
+	// ExclusiveRelationships are relationship types an entity holds toward one target at a
+	// time, checked for conflicts; empty uses the built-in list
+	ExclusiveRelationships []string
+}
+
 // EmbeddingConfig holds embedding service configuration
 type EmbeddingConfig struct {
-	APIKey   string
-	Endpoint string
-	Timeout  time.Duration
+	APIKey        string
+	Endpoint      string
+	Timeout       time.Duration
+	Model         string        // recorded in chunks.vector_model for text embeddings
+	Storage       string        // vector (float32), halfvec (float16) or int8; how text embeddings are stored
+	Dimensions    int           // truncate embeddings to this many dimensions; 0 keeps the provider's
+	SyncEnabled   bool          // re-embed chunks automatically when their contents change
+	SyncBatchSize int           // chunks embedded per request
+	SyncQueueSize int           // chunks waiting for re-embedding before new ones are left to the backfill
+	SyncInterval  time.Duration // how often chunks without a current embedding are backfilled
+
+	BatchMaxTokens       int     // tokens sent per provider request by POST /embeddings/batch
+	BatchMaxTexts        int     // texts sent per provider request
+	CostPerMillionTokens float64 // provider price used to estimate the cost of embedding usage
 }
 
 // LoggingConfig holds logging configuration
@@ -75,6 +348,31 @@ type CacheConfig struct {
 	MaxSize         int
 	CleanupInterval time.Duration
 	DefaultTTL      time.Duration
+	Codec           string // json or msgpack; how typed cache entries are serialized
+	MaxBytes        int64  // limit on the estimated size of the entries; 0 leaves only MaxSize
+	EvictionPolicy  string // lru, lfu or arc; which entry is evicted when a limit is reached
+
+	WarmingEnabled       bool          // count hot reads and preload them into the caches on startup
+	WarmLimit            int           // most hot patterns replayed on startup
+	WarmConcurrency      int           // patterns replayed at a time
+	WarmTimeout          time.Duration // bound on the whole warming run
+	HotDataFlushInterval time.Duration // how often read counts are written to cache_hot_patterns
+
+	TTLJitter           float64       // fraction by which TTLs are randomly lengthened or shortened
+	AdaptiveTTL         bool          // scale chunk and tag listing TTLs by their hot data weight
+	AdaptiveTTLMin      time.Duration // TTL of entries read too rarely to be in the hot data
+	AdaptiveTTLMax      time.Duration // longest TTL of the hottest entries
+	AdaptiveTTLPatterns int           // hottest patterns whose weights are loaded
+	AdaptiveTTLRefresh  time.Duration // how often the weights are reloaded
+}
+
+// SearchCacheConfig holds configuration for the PostgreSQL-backed search result cache
+type SearchCacheConfig struct {
+	Enabled              bool
+	DefaultTTL           time.Duration
+	StaleWhileRevalidate time.Duration
+	MaxEntries           int
+	CleanupInterval      time.Duration
 }
 
 // PerformanceConfig holds performance monitoring configuration
@@ -83,6 +381,9 @@ type PerformanceConfig struct {
 	MetricsEndpoint    string
 	MonitoringEnabled  bool
 	SlowQueryThreshold time.Duration
+	ExplainSlowQueries bool          // run EXPLAIN (ANALYZE, BUFFERS) for slow statements
+	ExplainTimeout     time.Duration // bound on each captured EXPLAIN
+	ExplainCooldown    time.Duration // how long a statement's plan is reused before re-explaining
 }
 
 // FeaturesConfig holds feature flag configuration
@@ -90,15 +391,48 @@ type FeaturesConfig struct {
 	UseUnifiedHandlers bool
 }
 
+// VectorIndexConfig holds pgvector index configuration
+type VectorIndexConfig struct {
+	Type          string // "hnsw" or "ivfflat"
+	Table         string
+	Column        string
+	OperatorClass string
+
+	// HNSW parameters
+	HNSWM              int
+	HNSWEfConstruction int
+	HNSWEfSearch       int
+
+	// IVFFlat parameters
+	IVFFlatLists  int
+	IVFFlatProbes int
+}
+
 // StorageConfig holds storage configuration
 type StorageConfig struct {
-	Provider string // "google_drive", "local", or "both"
+	Provider string // "google_drive", "local", "both", "supabase", or "s3"
+
+	// MaxObjectSize limits the size of objects written through StorageService (bytes)
+	MaxObjectSize int64
+
+	// Dedup stores each distinct file once under its content hash; object keys become
+	// counted references to it (database/attachment_dedup_migration.sql)
+	Dedup bool
+	// DedupGracePeriod is how long a blob without references is kept before garbage
+	// collection deletes it
+	DedupGracePeriod time.Duration
 	
 	// Google Drive configuration
 	GoogleDrive GoogleDriveConfig
 	
 	// Local storage configuration
 	Local LocalStorageConfig
+
+	// Supabase Storage configuration
+	Supabase SupabaseStorageConfig
+
+	// S3-compatible storage configuration
+	S3 S3StorageConfig
 }
 
 // GoogleDriveConfig holds Google Drive storage configuration
@@ -115,121 +449,583 @@ type LocalStorageConfig struct {
 	BaseURL string
 }
 
+// SupabaseStorageConfig holds Supabase Storage configuration
+type SupabaseStorageConfig struct {
+	URL    string
+	APIKey string
+	Bucket string
+	Public bool
+}
+
+// S3StorageConfig holds S3-compatible object storage configuration
+type S3StorageConfig struct {
+	Endpoint        string // e.g. https://s3.amazonaws.com or a MinIO/R2 endpoint
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	ForcePathStyle  bool
+	PublicBaseURL   string
+}
+
 // LoadConfig loads configuration from environment variables
 func LoadConfig() *Config {
+	return newLoader(nil, nil).load()
+}
+
+// load builds the configuration, resolving every setting through the loader's layers
+func (l *loader) load() *Config {
 	return &Config{
 		Server: ServerConfig{
-			Port:         getEnv("SERVER_PORT", "8080"),
-			ReadTimeout:  getDurationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
-			WriteTimeout: getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
-			IdleTimeout:  getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+			Port:         l.getEnv("SERVER_PORT", "8080"),
+			ReadTimeout:  l.getDurationEnv("SERVER_READ_TIMEOUT", 30*time.Second),
+			WriteTimeout: l.getDurationEnv("SERVER_WRITE_TIMEOUT", 30*time.Second),
+			IdleTimeout:  l.getDurationEnv("SERVER_IDLE_TIMEOUT", 60*time.Second),
+
+			HTTPCacheEnabled: l.getBoolEnv("HTTP_CACHE_ENABLED", true),
+			HTTPCacheMaxAge:  l.getDurationEnv("HTTP_CACHE_MAX_AGE", 0),
 		},
 		Database: DatabaseConfig{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     getIntEnv("DB_PORT", 5432),
-			Database: getEnv("DB_NAME", "postgres"),
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", ""),
-			SSLMode:  getEnv("DB_SSLMODE", "prefer"),
-			MaxConns: getIntEnv("DB_MAX_CONNS", 10),
-			MinConns: getIntEnv("DB_MIN_CONNS", 2),
+			Host:     l.getEnv("DB_HOST", "localhost"),
+			Port:     l.getIntEnv("DB_PORT", 5432),
+			Database: l.getEnv("DB_NAME", "postgres"),
+			User:     l.getEnv("DB_USER", "postgres"),
+			Password: l.getEnv("DB_PASSWORD", ""),
+			SSLMode:  l.getEnv("DB_SSLMODE", "prefer"),
+			MaxConns: l.getIntEnv("DB_MAX_CONNS", 10),
+			MinConns: l.getIntEnv("DB_MIN_CONNS", 2),
+
+			MaxOpenConns:        l.getIntEnv("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:        l.getIntEnv("DB_MAX_IDLE_CONNS", 5),
+			ConnMaxLifetime:     l.getDurationEnv("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			PoolWarnUtilization: l.getFloatEnv("DB_POOL_WARN_UTILIZATION", 0.9),
+			PoolCheckInterval:   l.getDurationEnv("DB_POOL_CHECK_INTERVAL", 30*time.Second),
+
+			WriteDSN:                l.getEnv("DB_WRITE_DSN", ""),
+			ReadDSNs:                l.getListEnv("DB_READ_DSNS"),
+			ReplicaMaxLag:           l.getDurationEnv("DB_REPLICA_MAX_LAG", 5*time.Second),
+			ReplicaLagCheckInterval: l.getDurationEnv("DB_REPLICA_LAG_CHECK_INTERVAL", 10*time.Second),
+
+			UnifiedChunkDriver:     l.getEnv("DB_UNIFIED_CHUNK_DRIVER", "database/sql"),
+			StatementCacheCapacity: l.getIntEnv("DB_STATEMENT_CACHE_CAPACITY", 512),
 		},
 		Supabase: SupabaseConfig{
-			URL:    getEnv("SUPABASE_URL", ""),
-			APIKey: getEnv("SUPABASE_API_KEY", ""),
+			URL:               l.getEnv("SUPABASE_URL", ""),
+			APIKey:            l.getEnv("SUPABASE_API_KEY", ""),
+			AnonKey:           l.getEnv("SUPABASE_ANON_KEY", ""),
+			ForwardUserTokens: l.getBoolEnv("SUPABASE_FORWARD_USER_TOKENS", false),
+			DatabaseURL:       l.getEnv("SUPABASE_DB_URL", ""),
 		},
 		LLM: LLMConfig{
-			APIKey:   getEnv("LLM_API_KEY", ""),
-			Endpoint: getEnv("LLM_ENDPOINT", ""),
-			Timeout:  getDurationEnv("LLM_TIMEOUT", 60*time.Second),
+			APIKey:   l.getEnv("LLM_API_KEY", ""),
+			Endpoint: l.getEnv("LLM_ENDPOINT", ""),
+			Timeout:  l.getDurationEnv("LLM_TIMEOUT", 60*time.Second),
+		},
+		RAG: RAGConfig{
+			Provider:         l.getEnv("RAG_LLM_PROVIDER", "gateway"),
+			Endpoint:         l.getEnv("RAG_LLM_ENDPOINT", l.getEnv("LLM_ENDPOINT", "")),
+			APIKey:           l.getEnv("RAG_LLM_API_KEY", l.getEnv("LLM_API_KEY", "")),
+			Model:            l.getEnv("RAG_LLM_MODEL", ""),
+			Timeout:          l.getDurationEnv("RAG_LLM_TIMEOUT", l.getDurationEnv("LLM_TIMEOUT", 60*time.Second)),
+			Temperature:      l.getFloatEnv("RAG_LLM_TEMPERATURE", 0.2),
+			RetrievalLimit:   l.getIntEnv("RAG_RETRIEVAL_LIMIT", 20),
+			SemanticWeight:   l.getFloatEnv("RAG_SEMANTIC_WEIGHT", 0.7),
+			MaxContextTokens: l.getIntEnv("RAG_MAX_CONTEXT_TOKENS", 3000),
+			MaxAnswerTokens:  l.getIntEnv("RAG_MAX_ANSWER_TOKENS", 512),
+		},
+		Rerank: RerankConfig{
+			Endpoint:   l.getEnv("RERANK_ENDPOINT", ""),
+			APIKey:     l.getEnv("RERANK_API_KEY", ""),
+			Model:      l.getEnv("RERANK_MODEL", ""),
+			Candidates: l.getIntEnv("RERANK_CANDIDATES", 50),
+			Timeout:    l.getDurationEnv("RERANK_TIMEOUT", 30*time.Second),
+		},
+		Summary: SummaryConfig{
+			MaxTokens:      l.getIntEnv("SUMMARY_MAX_TOKENS", 200),
+			MaxInputTokens: l.getIntEnv("SUMMARY_MAX_INPUT_TOKENS", 3000),
+			MinLength:      l.getIntEnv("SUMMARY_MIN_LENGTH", 280),
+			Concurrency:    l.getIntEnv("SUMMARY_CONCURRENCY", 4),
+		},
+		Render: RenderConfig{
+			CacheTTL:             l.getDurationEnv("RENDER_CACHE_TTL", time.Hour),
+			MaxTransclusionDepth: l.getIntEnv("RENDER_MAX_TRANSCLUSION_DEPTH", 3),
+		},
+		Publish: PublishConfig{
+			SigningSecret: l.getEnv("PUBLISH_SIGNING_SECRET", ""),
+			BaseURL:       strings.TrimRight(l.getEnv("PUBLISH_BASE_URL", ""), "/"),
+		},
+		Locks: ChunkLockConfig{
+			Enabled:    l.getBoolEnv("CHUNK_LOCKS_ENABLED", false),
+			DefaultTTL: l.getDurationEnv("CHUNK_LOCK_DEFAULT_TTL", 2*time.Minute),
+			MaxTTL:     l.getDurationEnv("CHUNK_LOCK_MAX_TTL", 30*time.Minute),
+		},
+		Capture: CaptureConfig{
+			InboxTitle: l.getEnv("CAPTURE_INBOX_TITLE", "Inbox"),
+			MaxBytes:   l.getIntEnv("CAPTURE_MAX_BYTES", 1<<20),
+			QueueSize:  l.getIntEnv("CAPTURE_QUEUE_SIZE", 1000),
+		},
+		Journal: JournalConfig{
+			TitleFormat: l.getEnv("JOURNAL_TITLE_FORMAT", "2006-01-02"),
+			Timezone:    l.getEnv("JOURNAL_TIMEZONE", "UTC"),
+		},
+		Tasks: TaskConfig{
+			Enabled:          l.getBoolEnv("TASKS_ENABLED", false),
+			Timezone:         l.getEnv("TASKS_TIMEZONE", "UTC"),
+			ReminderInterval: l.getDurationEnv("TASK_REMINDER_INTERVAL", time.Minute),
+			WebhookURL:       l.getEnv("TASK_WEBHOOK_URL", ""),
+			WebhookSecret:    l.getEnv("TASK_WEBHOOK_SECRET", ""),
+		},
+		EmailIngest: EmailIngestConfig{
+			PageID:            l.getEnv("EMAIL_INGEST_PAGE_ID", ""),
+			WebhookSecret:     l.getEnv("EMAIL_WEBHOOK_SECRET", ""),
+			MailgunSigningKey: l.getEnv("EMAIL_MAILGUN_SIGNING_KEY", ""),
+			MaxBytes:          l.getIntEnv("EMAIL_MAX_BYTES", 25<<20),
+			IMAPAddr:          l.getEnv("EMAIL_IMAP_ADDR", ""),
+			IMAPUsername:      l.getEnv("EMAIL_IMAP_USERNAME", ""),
+			IMAPPassword:      l.getEnv("EMAIL_IMAP_PASSWORD", ""),
+			IMAPMailbox:       l.getEnv("EMAIL_IMAP_MAILBOX", "INBOX"),
+			IMAPPollInterval:  l.getDurationEnv("EMAIL_IMAP_POLL_INTERVAL", time.Minute),
+		},
+		ImageSimilarity: ImageSimilarityConfig{
+			CLIPEndpoint:       l.getEnv("CLIP_ENDPOINT", ""),
+			MaxHashDistance:    l.getIntEnv("IMAGE_SIMILARITY_MAX_HASH_DISTANCE", 10),
+			EmbeddingThreshold: l.getFloatEnv("IMAGE_SIMILARITY_EMBEDDING_THRESHOLD", 0.85),
+			HashWeight:         l.getFloatEnv("IMAGE_SIMILARITY_HASH_WEIGHT", 0.5),
+			IndexConcurrency:   l.getIntEnv("IMAGE_SIMILARITY_INDEX_CONCURRENCY", 4),
+		},
+		ChangeFeed: ChangeFeedConfig{
+			Enabled:          l.getBoolEnv("CHANGE_FEED_ENABLED", false),
+			Channel:          l.getEnv("CHANGE_FEED_CHANNEL", "chunk_changes"),
+			HistorySize:      l.getIntEnv("CHANGE_FEED_HISTORY_SIZE", 1000),
+			SubscriberBuffer: l.getIntEnv("CHANGE_FEED_SUBSCRIBER_BUFFER", 256),
+			Heartbeat:        l.getDurationEnv("CHANGE_FEED_HEARTBEAT", 15*time.Second),
+		},
+		Suggestions: QuerySuggestionConfig{
+			Enabled:        l.getBoolEnv("QUERY_SUGGESTIONS_ENABLED", false),
+			MinSimilarity:  l.getFloatEnv("QUERY_SUGGESTIONS_MIN_SIMILARITY", 0.3),
+			MaxQueryLength: l.getIntEnv("QUERY_SUGGESTIONS_MAX_QUERY_LENGTH", 200),
+		},
+		Encryption: EncryptionConfig{
+			Enabled:   l.getBoolEnv("ENCRYPTION_ENABLED", false),
+			Provider:  l.getEnv("ENCRYPTION_PROVIDER", "local"),
+			MasterKey: l.getEnv("ENCRYPTION_MASTER_KEY", ""),
+			KeyID:     l.getEnv("ENCRYPTION_KEY_ID", "local-1"),
+			KMS: KMSConfig{
+				KeyID:           l.getEnv("ENCRYPTION_KMS_KEY_ID", ""),
+				Region:          l.getEnv("ENCRYPTION_KMS_REGION", "us-east-1"),
+				Endpoint:        l.getEnv("ENCRYPTION_KMS_ENDPOINT", ""),
+				AccessKeyID:     l.getEnv("ENCRYPTION_KMS_ACCESS_KEY_ID", ""),
+				SecretAccessKey: l.getEnv("ENCRYPTION_KMS_SECRET_ACCESS_KEY", ""),
+				SessionToken:    l.getEnv("ENCRYPTION_KMS_SESSION_TOKEN", ""),
+			},
+		},
+		PII: PIIConfig{
+			Enabled:           l.getBoolEnv("PII_SCANNING_ENABLED", false),
+			DefaultPolicy:     l.getEnv("PII_DEFAULT_POLICY", "flag"),
+			WorkspacePolicies: l.getListEnv("PII_WORKSPACE_POLICIES"),
+			Detectors:         l.getListEnv("PII_DETECTORS"),
+		},
+		Auth: AuthConfig{
+			Enabled:   l.getBoolEnv("AUTH_ENABLED", false),
+			JWTSecret: l.getEnv("AUTH_JWT_SECRET", ""),
+			JWTIssuer: l.getEnv("AUTH_JWT_ISSUER", ""),
+
+			PageACLsEnabled: l.getBoolEnv("AUTH_PAGE_ACLS_ENABLED", false),
+		},
+		Maintenance: MaintenanceConfig{
+			Enabled:  l.getBoolEnv("MAINTENANCE_ENABLED", false),
+			Interval: l.getDurationEnv("MAINTENANCE_INTERVAL", time.Hour),
+		},
+		Graph: GraphConfig{
+			EdgeDecayHalfLife:      l.getDurationEnv("GRAPH_EDGE_DECAY_HALF_LIFE", 90*24*time.Hour),
+			EdgeDecayFloor:         l.getFloatEnv("GRAPH_EDGE_DECAY_FLOOR", 0.1),
+			SyncEnabled:            l.getBoolEnv("GRAPH_SYNC_ENABLED", false),
+			SyncQueueSize:          l.getIntEnv("GRAPH_SYNC_QUEUE_SIZE", 1000),
+			ExclusiveRelationships: l.getListEnv("GRAPH_EXCLUSIVE_RELATIONSHIPS"),
+		},
+		Probes: ProbeConfig{
+			Timeout:        l.getDurationEnv("PROBE_TIMEOUT", 2*time.Second),
+			EmbeddingCache: l.getDurationEnv("PROBE_EMBEDDING_CACHE", 5*time.Minute),
+			MCPAddr:        l.getEnv("MCP_PROBE_ADDR", ""),
+		},
+		Shutdown: ShutdownConfig{
+			Timeout:        l.getDurationEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
+			DrainDelay:     l.getDurationEnv("SHUTDOWN_DRAIN_DELAY", 0),
+			CheckpointPath: l.getEnv("SHUTDOWN_CHECKPOINT_PATH", ""),
 		},
 		Embedding: EmbeddingConfig{
-			APIKey:   getEnv("EMBEDDING_API_KEY", ""),
-			Endpoint: getEnv("EMBEDDING_ENDPOINT", ""),
-			Timeout:  getDurationEnv("EMBEDDING_TIMEOUT", 30*time.Second),
+			APIKey:        l.getEnv("EMBEDDING_API_KEY", ""),
+			Endpoint:      l.getEnv("EMBEDDING_ENDPOINT", ""),
+			Timeout:       l.getDurationEnv("EMBEDDING_TIMEOUT", 30*time.Second),
+			Model:         l.getEnv("EMBEDDING_MODEL", "text-embedding-3-small"),
+			Storage:       l.getEnv("EMBEDDING_STORAGE", "vector"),
+			Dimensions:    l.getIntEnv("EMBEDDING_DIMENSIONS", 0),
+			SyncEnabled:   l.getBoolEnv("EMBEDDING_SYNC_ENABLED", false),
+			SyncBatchSize: l.getIntEnv("EMBEDDING_SYNC_BATCH_SIZE", 32),
+			SyncQueueSize: l.getIntEnv("EMBEDDING_SYNC_QUEUE_SIZE", 10000),
+			SyncInterval:  l.getDurationEnv("EMBEDDING_SYNC_INTERVAL", 5*time.Minute),
+
+			BatchMaxTokens:       l.getIntEnv("EMBEDDING_BATCH_MAX_TOKENS", 8000),
+			BatchMaxTexts:        l.getIntEnv("EMBEDDING_BATCH_MAX_TEXTS", 256),
+			CostPerMillionTokens: l.getFloatEnv("EMBEDDING_COST_PER_MILLION_TOKENS", 0.02),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:  l.getEnv("LOG_LEVEL", "info"),
+			Format: l.getEnv("LOG_FORMAT", "json"),
 		},
 		Cache: CacheConfig{
-			Enabled:         getBoolEnv("CACHE_ENABLED", true),
-			MaxSize:         getIntEnv("CACHE_MAX_SIZE", 1000),
-			CleanupInterval: getDurationEnv("CACHE_CLEANUP_INTERVAL", 5*time.Minute),
-			DefaultTTL:      getDurationEnv("CACHE_DEFAULT_TTL", 30*time.Minute),
+			Enabled:         l.getBoolEnv("CACHE_ENABLED", true),
+			MaxSize:         l.getIntEnv("CACHE_MAX_SIZE", 1000),
+			CleanupInterval: l.getDurationEnv("CACHE_CLEANUP_INTERVAL", 5*time.Minute),
+			DefaultTTL:      l.getDurationEnv("CACHE_DEFAULT_TTL", 30*time.Minute),
+			Codec:           l.getEnv("CACHE_CODEC", "json"),
+			MaxBytes:        int64(l.getIntEnv("CACHE_MAX_BYTES", 0)),
+			EvictionPolicy:  l.getEnv("CACHE_EVICTION_POLICY", "lru"),
+
+			WarmingEnabled:       l.getBoolEnv("CACHE_WARMING_ENABLED", false),
+			WarmLimit:            l.getIntEnv("CACHE_WARM_LIMIT", 500),
+			WarmConcurrency:      l.getIntEnv("CACHE_WARM_CONCURRENCY", 4),
+			WarmTimeout:          l.getDurationEnv("CACHE_WARM_TIMEOUT", 2*time.Minute),
+			HotDataFlushInterval: l.getDurationEnv("CACHE_HOT_DATA_FLUSH_INTERVAL", time.Minute),
+
+			TTLJitter:           l.getFloatEnv("CACHE_TTL_JITTER", 0.1),
+			AdaptiveTTL:         l.getBoolEnv("CACHE_ADAPTIVE_TTL", false),
+			AdaptiveTTLMin:      l.getDurationEnv("CACHE_ADAPTIVE_TTL_MIN", time.Minute),
+			AdaptiveTTLMax:      l.getDurationEnv("CACHE_ADAPTIVE_TTL_MAX", time.Hour),
+			AdaptiveTTLPatterns: l.getIntEnv("CACHE_ADAPTIVE_TTL_PATTERNS", 2000),
+			AdaptiveTTLRefresh:  l.getDurationEnv("CACHE_ADAPTIVE_TTL_REFRESH", 5*time.Minute),
+		},
+		SearchCache: SearchCacheConfig{
+			Enabled:              l.getBoolEnv("SEARCH_CACHE_ENABLED", true),
+			DefaultTTL:           l.getDurationEnv("SEARCH_CACHE_TTL", 15*time.Minute),
+			StaleWhileRevalidate: l.getDurationEnv("SEARCH_CACHE_STALE_WHILE_REVALIDATE", 5*time.Minute),
+			MaxEntries:           l.getIntEnv("SEARCH_CACHE_MAX_ENTRIES", 50000),
+			CleanupInterval:      l.getDurationEnv("SEARCH_CACHE_CLEANUP_INTERVAL", 10*time.Minute),
 		},
 		Performance: PerformanceConfig{
-			MetricsEnabled:     getBoolEnv("METRICS_ENABLED", true),
-			MetricsEndpoint:    getEnv("METRICS_ENDPOINT", "/metrics"),
-			MonitoringEnabled:  getBoolEnv("MONITORING_ENABLED", true),
-			SlowQueryThreshold: getDurationEnv("SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+			MetricsEnabled:     l.getBoolEnv("METRICS_ENABLED", true),
+			MetricsEndpoint:    l.getEnv("METRICS_ENDPOINT", "/metrics"),
+			MonitoringEnabled:  l.getBoolEnv("MONITORING_ENABLED", true),
+			SlowQueryThreshold: l.getDurationEnv("SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+			ExplainSlowQueries: l.getBoolEnv("EXPLAIN_SLOW_QUERIES", false),
+			ExplainTimeout:     l.getDurationEnv("EXPLAIN_TIMEOUT", 5*time.Second),
+			ExplainCooldown:    l.getDurationEnv("EXPLAIN_COOLDOWN", 10*time.Minute),
 		},
 		Features: FeaturesConfig{
-			UseUnifiedHandlers: getBoolEnv("USE_UNIFIED_HANDLERS", false),
+			UseUnifiedHandlers: l.getBoolEnv("USE_UNIFIED_HANDLERS", false),
 		},
 		Storage: StorageConfig{
-			Provider: getEnv("STORAGE_PROVIDER", "local"),
+			Provider:         l.getEnv("STORAGE_PROVIDER", "local"),
+			MaxObjectSize:    int64(l.getIntEnv("STORAGE_MAX_OBJECT_SIZE", 50*1024*1024)),
+			Dedup:            l.getBoolEnv("STORAGE_DEDUP", false),
+			DedupGracePeriod: l.getDurationEnv("STORAGE_DEDUP_GRACE_PERIOD", 24*time.Hour),
 			GoogleDrive: GoogleDriveConfig{
-				Enabled:         getBoolEnv("GOOGLE_DRIVE_ENABLED", false),
-				FolderID:        getEnv("GOOGLE_DRIVE_FOLDER_ID", ""),
-				CredentialsPath: getEnv("GOOGLE_DRIVE_CREDENTIALS_PATH", "./config/google-drive-credentials.json"),
-				BaseURL:         getEnv("GOOGLE_DRIVE_BASE_URL", "https://drive.google.com/file/d/"),
+				Enabled:         l.getBoolEnv("GOOGLE_DRIVE_ENABLED", false),
+				FolderID:        l.getEnv("GOOGLE_DRIVE_FOLDER_ID", ""),
+				CredentialsPath: l.getEnv("GOOGLE_DRIVE_CREDENTIALS_PATH", "./config/google-drive-credentials.json"),
+				BaseURL:         l.getEnv("GOOGLE_DRIVE_BASE_URL", "https://drive.google.com/file/d/"),
 			},
 			Local: LocalStorageConfig{
-				Path:    getEnv("LOCAL_STORAGE_PATH", "./uploads"),
-				BaseURL: getEnv("LOCAL_STORAGE_BASE_URL", "http://localhost:8081/uploads/"),
+				Path:    l.getEnv("LOCAL_STORAGE_PATH", "./uploads"),
+				BaseURL: l.getEnv("LOCAL_STORAGE_BASE_URL", "http://localhost:8081/uploads/"),
 			},
+			Supabase: SupabaseStorageConfig{
+				URL:    l.getEnv("SUPABASE_STORAGE_URL", l.getEnv("SUPABASE_URL", "")),
+				APIKey: l.getEnv("SUPABASE_STORAGE_API_KEY", l.getEnv("SUPABASE_API_KEY", "")),
+				Bucket: l.getEnv("SUPABASE_STORAGE_BUCKET", "media"),
+				Public: l.getBoolEnv("SUPABASE_STORAGE_PUBLIC", false),
+			},
+			S3: S3StorageConfig{
+				Endpoint:        l.getEnv("S3_ENDPOINT", ""),
+				Region:          l.getEnv("S3_REGION", "us-east-1"),
+				Bucket:          l.getEnv("S3_BUCKET", ""),
+				AccessKeyID:     l.getEnv("S3_ACCESS_KEY_ID", ""),
+				SecretAccessKey: l.getEnv("S3_SECRET_ACCESS_KEY", ""),
+				ForcePathStyle:  l.getBoolEnv("S3_FORCE_PATH_STYLE", false),
+				PublicBaseURL:   l.getEnv("S3_PUBLIC_BASE_URL", ""),
+			},
+		},
+		VectorIndex: VectorIndexConfig{
+			Type:               l.getEnv("VECTOR_INDEX_TYPE", "ivfflat"),
+			Table:              l.getEnv("VECTOR_INDEX_TABLE", "chunks"),
+			Column:             l.getEnv("VECTOR_INDEX_COLUMN", "vector"),
+			OperatorClass:      l.getEnv("VECTOR_INDEX_OPERATOR_CLASS", "vector_cosine_ops"),
+			HNSWM:              l.getIntEnv("VECTOR_INDEX_HNSW_M", 16),
+			HNSWEfConstruction: l.getIntEnv("VECTOR_INDEX_HNSW_EF_CONSTRUCTION", 64),
+			HNSWEfSearch:       l.getIntEnv("VECTOR_INDEX_HNSW_EF_SEARCH", 40),
+			IVFFlatLists:       l.getIntEnv("VECTOR_INDEX_IVFFLAT_LISTS", 100),
+			IVFFlatProbes:      l.getIntEnv("VECTOR_INDEX_IVFFLAT_PROBES", 10),
 		},
 	}
 }
 
-// getEnv gets environment variable with default value
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+// getEnv gets a setting with default value
+func (l *loader) getEnv(key, defaultValue string) string {
+	if value, _, ok := l.lookup(key); ok && value != "" {
 		return value
 	}
 	return defaultValue
 }
 
-// getDurationEnv gets duration from environment variable with default value
-func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
-	if value := os.Getenv(key); value != "" {
-		if duration, err := time.ParseDuration(value); err == nil {
+// getListEnv gets a comma-separated list setting
+func (l *loader) getListEnv(key string) []string {
+	raw, _, _ := l.lookup(key)
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getDurationEnv gets a duration setting with default value
+func (l *loader) getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value, origin, ok := l.lookup(key); ok && value != "" {
+		duration, err := time.ParseDuration(value)
+		if err == nil {
 			return duration
 		}
+		l.invalid(origin, "invalid duration %q", value)
 	}
 	return defaultValue
 }
 
-// getIntEnv gets integer from environment variable with default value
-func getIntEnv(key string, defaultValue int) int {
-	if value := os.Getenv(key); value != "" {
-		if intValue, err := strconv.Atoi(value); err == nil {
+// getIntEnv gets an integer setting with default value
+func (l *loader) getIntEnv(key string, defaultValue int) int {
+	if value, origin, ok := l.lookup(key); ok && value != "" {
+		intValue, err := strconv.Atoi(value)
+		if err == nil {
 			return intValue
 		}
+		l.invalid(origin, "invalid integer %q", value)
+	}
+	return defaultValue
+}
+
+// getFloatEnv gets a float setting with default value
+func (l *loader) getFloatEnv(key string, defaultValue float64) float64 {
+	if value, origin, ok := l.lookup(key); ok && value != "" {
+		floatValue, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return floatValue
+		}
+		l.invalid(origin, "invalid number %q", value)
 	}
 	return defaultValue
 }
 
-// getBoolEnv gets boolean from environment variable with default value
-func getBoolEnv(key string, defaultValue bool) bool {
-	if value := os.Getenv(key); value != "" {
-		if boolValue, err := strconv.ParseBool(value); err == nil {
+// getBoolEnv gets a boolean setting with default value
+func (l *loader) getBoolEnv(key string, defaultValue bool) bool {
+	if value, origin, ok := l.lookup(key); ok && value != "" {
+		boolValue, err := strconv.ParseBool(value)
+		if err == nil {
 			return boolValue
 		}
+		l.invalid(origin, "invalid boolean %q", value)
 	}
 	return defaultValue
 }
 
-// Validate validates the configuration
+// Validate validates the configuration, reporting every invalid setting
 func (c *Config) Validate() error {
+	var errs ValidationErrors
 	if c.Supabase.URL == "" {
-		return &ConfigError{Field: "SUPABASE_URL", Message: "Supabase URL is required"}
+		errs = append(errs, &ConfigError{Field: "SUPABASE_URL", Message: "Supabase URL is required"})
 	}
 	if c.Supabase.APIKey == "" {
-		return &ConfigError{Field: "SUPABASE_API_KEY", Message: "Supabase API key is required"}
+		errs = append(errs, &ConfigError{Field: "SUPABASE_API_KEY", Message: "Supabase API key is required"})
+	}
+	if c.Supabase.ForwardUserTokens && c.Supabase.AnonKey == "" {
+		errs = append(errs, &ConfigError{Field: "SUPABASE_ANON_KEY", Message: "the anon key is required to forward user tokens"})
+	}
+	if c.Database.UnifiedChunkDriver != "database/sql" && c.Database.UnifiedChunkDriver != "pgx" {
+		errs = append(errs, &ConfigError{Field: "DB_UNIFIED_CHUNK_DRIVER", Message: "must be database/sql or pgx"})
+	}
+	if c.Database.StatementCacheCapacity < 0 {
+		errs = append(errs, &ConfigError{Field: "DB_STATEMENT_CACHE_CAPACITY", Message: "must not be negative"})
 	}
-	return nil
+	errs = append(errs, c.validateTunables()...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateTunables checks the ranges of settings that can also change on reload
+func (c *Config) validateTunables() ValidationErrors {
+	var errs ValidationErrors
+	check := func(ok bool, field, message string) {
+		if !ok {
+			errs = append(errs, &ConfigError{Field: field, Message: message})
+		}
+	}
+
+	if _, err := strconv.Atoi(c.Server.Port); err != nil {
+		errs = append(errs, &ConfigError{Field: "SERVER_PORT", Message: "must be a port number"})
+	}
+	check(c.Server.HTTPCacheMaxAge >= 0, "HTTP_CACHE_MAX_AGE", "must not be negative")
+	check(c.Database.MaxConns > 0, "DB_MAX_CONNS", "must be positive")
+	check(c.Database.MinConns >= 0 && c.Database.MinConns <= c.Database.MaxConns, "DB_MIN_CONNS", "must be between 0 and DB_MAX_CONNS")
+	check(c.Database.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS", "must not be negative")
+	check(c.Database.MaxIdleConns >= 0 && (c.Database.MaxOpenConns == 0 || c.Database.MaxIdleConns <= c.Database.MaxOpenConns), "DB_MAX_IDLE_CONNS", "must be between 0 and DB_MAX_OPEN_CONNS")
+	check(c.Database.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME", "must not be negative")
+	check(c.Database.PoolWarnUtilization > 0 && c.Database.PoolWarnUtilization <= 1, "DB_POOL_WARN_UTILIZATION", "must be greater than 0 and at most 1")
+	check(c.Database.PoolCheckInterval > 0, "DB_POOL_CHECK_INTERVAL", "must be positive")
+
+	if c.Cache.Enabled {
+		check(c.Cache.MaxSize > 0, "CACHE_MAX_SIZE", "must be positive")
+		check(c.Cache.CleanupInterval > 0, "CACHE_CLEANUP_INTERVAL", "must be positive")
+		check(c.Cache.DefaultTTL > 0, "CACHE_DEFAULT_TTL", "must be positive")
+		check(c.Cache.Codec == "json" || c.Cache.Codec == "msgpack", "CACHE_CODEC", "must be json or msgpack")
+		check(c.Cache.TTLJitter >= 0 && c.Cache.TTLJitter < 1, "CACHE_TTL_JITTER", "must be at least 0 and below 1")
+		check(c.Cache.MaxBytes >= 0, "CACHE_MAX_BYTES", "must not be negative")
+		check(c.Cache.EvictionPolicy == "lru" || c.Cache.EvictionPolicy == "lfu" || c.Cache.EvictionPolicy == "arc", "CACHE_EVICTION_POLICY", "must be lru, lfu or arc")
+	}
+	if c.Cache.WarmingEnabled {
+		check(c.Cache.WarmLimit > 0, "CACHE_WARM_LIMIT", "must be positive")
+		check(c.Cache.WarmConcurrency > 0, "CACHE_WARM_CONCURRENCY", "must be positive")
+		check(c.Cache.WarmTimeout > 0, "CACHE_WARM_TIMEOUT", "must be positive")
+		check(c.Cache.HotDataFlushInterval > 0, "CACHE_HOT_DATA_FLUSH_INTERVAL", "must be positive")
+	}
+	if c.Cache.AdaptiveTTL {
+		check(c.Cache.Enabled && c.Cache.WarmingEnabled, "CACHE_ADAPTIVE_TTL", "requires CACHE_ENABLED and CACHE_WARMING_ENABLED")
+		check(c.Cache.AdaptiveTTLMin > 0, "CACHE_ADAPTIVE_TTL_MIN", "must be positive")
+		check(c.Cache.AdaptiveTTLMax >= c.Cache.AdaptiveTTLMin, "CACHE_ADAPTIVE_TTL_MAX", "must not be below CACHE_ADAPTIVE_TTL_MIN")
+		check(c.Cache.AdaptiveTTLPatterns > 0, "CACHE_ADAPTIVE_TTL_PATTERNS", "must be positive")
+		check(c.Cache.AdaptiveTTLRefresh > 0, "CACHE_ADAPTIVE_TTL_REFRESH", "must be positive")
+	}
+	if c.SearchCache.Enabled {
+		check(c.SearchCache.DefaultTTL > 0, "SEARCH_CACHE_TTL", "must be positive")
+		check(c.SearchCache.StaleWhileRevalidate >= 0, "SEARCH_CACHE_STALE_WHILE_REVALIDATE", "must not be negative")
+		check(c.SearchCache.MaxEntries > 0, "SEARCH_CACHE_MAX_ENTRIES", "must be positive")
+	}
+
+	check(c.RAG.SemanticWeight >= 0 && c.RAG.SemanticWeight <= 1, "RAG_SEMANTIC_WEIGHT", "must be between 0 and 1")
+	check(c.RAG.RetrievalLimit > 0, "RAG_RETRIEVAL_LIMIT", "must be positive")
+	check(c.RAG.MaxContextTokens > 0, "RAG_MAX_CONTEXT_TOKENS", "must be positive")
+	check(c.RAG.MaxAnswerTokens > 0, "RAG_MAX_ANSWER_TOKENS", "must be positive")
+	check(c.Rerank.Candidates > 0 && c.Rerank.Candidates <= 200, "RERANK_CANDIDATES", "must be between 1 and 200")
+	check(c.Summary.MaxTokens > 0, "SUMMARY_MAX_TOKENS", "must be positive")
+	check(c.Summary.MaxInputTokens > c.Summary.MaxTokens, "SUMMARY_MAX_INPUT_TOKENS", "must be greater than SUMMARY_MAX_TOKENS")
+	check(c.Summary.MinLength >= 0, "SUMMARY_MIN_LENGTH", "must not be negative")
+	check(c.Summary.Concurrency > 0, "SUMMARY_CONCURRENCY", "must be positive")
+	check(c.Render.CacheTTL >= 0, "RENDER_CACHE_TTL", "must not be negative")
+	check(c.Render.MaxTransclusionDepth >= 0 && c.Render.MaxTransclusionDepth <= 10, "RENDER_MAX_TRANSCLUSION_DEPTH", "must be between 0 and 10")
+	check(c.Publish.SigningSecret == "" || len(c.Publish.SigningSecret) >= 32, "PUBLISH_SIGNING_SECRET", "must be at least 32 characters")
+	check(c.Locks.DefaultTTL >= time.Second, "CHUNK_LOCK_DEFAULT_TTL", "must be at least 1s")
+	check(c.Locks.MaxTTL >= c.Locks.DefaultTTL, "CHUNK_LOCK_MAX_TTL", "must not be less than CHUNK_LOCK_DEFAULT_TTL")
+	check(strings.TrimSpace(c.Capture.InboxTitle) != "", "CAPTURE_INBOX_TITLE", "must not be empty")
+	check(c.Capture.MaxBytes > 0, "CAPTURE_MAX_BYTES", "must be positive")
+	check(c.Capture.QueueSize > 0, "CAPTURE_QUEUE_SIZE", "must be positive")
+	check(time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC).Format(c.Journal.TitleFormat) != c.Journal.TitleFormat, "JOURNAL_TITLE_FORMAT", "must contain a date, as a Go time layout")
+	_, err := time.LoadLocation(c.Journal.Timezone)
+	check(err == nil, "JOURNAL_TIMEZONE", "must be an IANA time zone such as Europe/Berlin")
+	_, err = time.LoadLocation(c.Tasks.Timezone)
+	check(err == nil, "TASKS_TIMEZONE", "must be an IANA time zone such as Europe/Berlin")
+	check(c.Tasks.ReminderInterval >= time.Second, "TASK_REMINDER_INTERVAL", "must be at least 1s")
+	check(c.Tasks.WebhookURL == "" || strings.HasPrefix(c.Tasks.WebhookURL, "http://") || strings.HasPrefix(c.Tasks.WebhookURL, "https://"), "TASK_WEBHOOK_URL", "must be an http(s) URL")
+	check(c.EmailIngest.MaxBytes > 0, "EMAIL_MAX_BYTES", "must be positive")
+	check(c.EmailIngest.IMAPAddr == "" || c.EmailIngest.IMAPUsername != "", "EMAIL_IMAP_USERNAME", "is required with EMAIL_IMAP_ADDR")
+	check(c.EmailIngest.IMAPAddr == "" || c.EmailIngest.IMAPMailbox != "", "EMAIL_IMAP_MAILBOX", "is required with EMAIL_IMAP_ADDR")
+	check(c.EmailIngest.IMAPPollInterval >= time.Second, "EMAIL_IMAP_POLL_INTERVAL", "must be at least 1s")
+	check(c.ImageSimilarity.MaxHashDistance >= 0 && c.ImageSimilarity.MaxHashDistance <= 64, "IMAGE_SIMILARITY_MAX_HASH_DISTANCE", "must be between 0 and 64")
+	check(c.ImageSimilarity.EmbeddingThreshold >= 0 && c.ImageSimilarity.EmbeddingThreshold <= 1, "IMAGE_SIMILARITY_EMBEDDING_THRESHOLD", "must be between 0 and 1")
+	check(c.ImageSimilarity.HashWeight >= 0 && c.ImageSimilarity.HashWeight <= 1, "IMAGE_SIMILARITY_HASH_WEIGHT", "must be between 0 and 1")
+	check(c.ImageSimilarity.IndexConcurrency > 0, "IMAGE_SIMILARITY_INDEX_CONCURRENCY", "must be positive")
+	if c.Performance.ExplainSlowQueries {
+		check(c.Performance.MonitoringEnabled, "EXPLAIN_SLOW_QUERIES", "requires MONITORING_ENABLED")
+		check(c.Performance.ExplainTimeout > 0, "EXPLAIN_TIMEOUT", "must be positive")
+		check(c.Performance.ExplainCooldown >= 0, "EXPLAIN_COOLDOWN", "must not be negative")
+	}
+	switch c.Embedding.Storage {
+	case "vector", "halfvec", "int8":
+	default:
+		errs = append(errs, &ConfigError{Field: "EMBEDDING_STORAGE", Message: fmt.Sprintf("unsupported storage format %q", c.Embedding.Storage)})
+	}
+	check(c.Embedding.Dimensions >= 0 && c.Embedding.Dimensions <= 16000, "EMBEDDING_DIMENSIONS", "must be between 0 and 16000")
+	check(c.Embedding.Storage != "halfvec" || c.Embedding.Dimensions <= 4000, "EMBEDDING_DIMENSIONS", "must be at most 4000 for indexed halfvec storage")
+	check(c.Storage.DedupGracePeriod >= 0, "STORAGE_DEDUP_GRACE_PERIOD", "must not be negative")
+	if c.Embedding.SyncEnabled {
+		check(c.Embedding.Endpoint != "", "EMBEDDING_ENDPOINT", "is required for EMBEDDING_SYNC_ENABLED")
+		check(c.Embedding.SyncBatchSize > 0, "EMBEDDING_SYNC_BATCH_SIZE", "must be positive")
+		check(c.Embedding.SyncQueueSize > 0, "EMBEDDING_SYNC_QUEUE_SIZE", "must be positive")
+		check(c.Embedding.SyncInterval > 0, "EMBEDDING_SYNC_INTERVAL", "must be positive")
+	}
+	check(c.Embedding.BatchMaxTokens > 0, "EMBEDDING_BATCH_MAX_TOKENS", "must be positive")
+	check(c.Embedding.BatchMaxTexts > 0 && c.Embedding.BatchMaxTexts <= 2048, "EMBEDDING_BATCH_MAX_TEXTS", "must be between 1 and 2048")
+	check(c.Embedding.CostPerMillionTokens >= 0, "EMBEDDING_COST_PER_MILLION_TOKENS", "must not be negative")
+	if c.ChangeFeed.Enabled {
+		check(c.ChangeFeed.Channel != "", "CHANGE_FEED_CHANNEL", "is required")
+		check(c.ChangeFeed.HistorySize > 0, "CHANGE_FEED_HISTORY_SIZE", "must be positive")
+		check(c.ChangeFeed.SubscriberBuffer > 0, "CHANGE_FEED_SUBSCRIBER_BUFFER", "must be positive")
+		check(c.ChangeFeed.Heartbeat > 0, "CHANGE_FEED_HEARTBEAT", "must be positive")
+	}
+	check(c.Graph.EdgeDecayHalfLife >= 0, "GRAPH_EDGE_DECAY_HALF_LIFE", "must not be negative")
+	check(c.Graph.EdgeDecayFloor >= 0 && c.Graph.EdgeDecayFloor <= 1, "GRAPH_EDGE_DECAY_FLOOR", "must be between 0 and 1")
+	if c.Graph.SyncEnabled {
+		check(c.LLM.Endpoint != "", "LLM_ENDPOINT", "is required for GRAPH_SYNC_ENABLED")
+		check(c.Graph.SyncQueueSize > 0, "GRAPH_SYNC_QUEUE_SIZE", "must be positive")
+	}
+	if c.Maintenance.Enabled {
+		check(c.Maintenance.Interval > 0, "MAINTENANCE_INTERVAL", "must be positive")
+	}
+	check(c.Probes.Timeout > 0, "PROBE_TIMEOUT", "must be positive")
+	check(c.Probes.EmbeddingCache >= 0, "PROBE_EMBEDDING_CACHE", "must not be negative")
+	check(c.Shutdown.Timeout > 0, "SHUTDOWN_TIMEOUT", "must be positive")
+	check(c.Shutdown.DrainDelay >= 0 && c.Shutdown.DrainDelay < c.Shutdown.Timeout, "SHUTDOWN_DRAIN_DELAY", "must not be negative and must be less than SHUTDOWN_TIMEOUT")
+	if c.Suggestions.Enabled {
+		check(c.Suggestions.MinSimilarity > 0 && c.Suggestions.MinSimilarity <= 1, "QUERY_SUGGESTIONS_MIN_SIMILARITY", "must be greater than 0 and at most 1")
+		check(c.Suggestions.MaxQueryLength > 0, "QUERY_SUGGESTIONS_MAX_QUERY_LENGTH", "must be positive")
+	}
+	if c.Encryption.Enabled {
+		switch c.Encryption.Provider {
+		case "local":
+			key, err := base64.StdEncoding.DecodeString(c.Encryption.MasterKey)
+			check(err == nil && len(key) == 32, "ENCRYPTION_MASTER_KEY", "must be a base64-encoded 32-byte key")
+			check(c.Encryption.KeyID != "", "ENCRYPTION_KEY_ID", "is required")
+		case "aws-kms":
+			check(c.Encryption.KMS.KeyID != "", "ENCRYPTION_KMS_KEY_ID", "is required")
+			check(c.Encryption.KMS.Region != "", "ENCRYPTION_KMS_REGION", "is required")
+			check(c.Encryption.KMS.AccessKeyID != "", "ENCRYPTION_KMS_ACCESS_KEY_ID", "is required")
+			check(c.Encryption.KMS.SecretAccessKey != "", "ENCRYPTION_KMS_SECRET_ACCESS_KEY", "is required")
+		default:
+			errs = append(errs, &ConfigError{Field: "ENCRYPTION_PROVIDER", Message: fmt.Sprintf("unsupported provider %q", c.Encryption.Provider)})
+		}
+	}
+
+	if c.PII.Enabled {
+		check(validPIIPolicy(c.PII.DefaultPolicy), "PII_DEFAULT_POLICY", "must be redact, flag or reject")
+		for _, entry := range c.PII.WorkspacePolicies {
+			workspace, policy, ok := strings.Cut(entry, ":")
+			check(ok && strings.TrimSpace(workspace) != "" && validPIIPolicy(strings.TrimSpace(policy)),
+				"PII_WORKSPACE_POLICIES", fmt.Sprintf("entry %q must be workspace:policy with policy redact, flag or reject", entry))
+		}
+		for _, detector := range c.PII.Detectors {
+			switch detector {
+			case "email", "phone", "id_number", "credit_card":
+			default:
+				errs = append(errs, &ConfigError{Field: "PII_DETECTORS", Message: fmt.Sprintf("unsupported detector %q", detector)})
+			}
+		}
+	}
+
+	if c.Auth.JWTSecret != "" {
+		check(len(c.Auth.JWTSecret) >= 32, "AUTH_JWT_SECRET", "must be at least 32 bytes")
+	}
+
+	switch c.VectorIndex.Type {
+	case "hnsw", "ivfflat":
+	default:
+		errs = append(errs, &ConfigError{Field: "VECTOR_INDEX_TYPE", Message: fmt.Sprintf("unsupported index type %q", c.VectorIndex.Type)})
+	}
+
+	return errs
+}
+
+func validPIIPolicy(policy string) bool {
+	return policy == "redact" || policy == "flag" || policy == "reject"
 }
 
 // ConfigError represents configuration validation error
@@ -240,4 +1036,15 @@ type ConfigError struct {
 
 func (e *ConfigError) Error() string {
 	return e.Field + ": " + e.Message
-}
\ No newline at end of file
+}
+
+// ValidationErrors collects every configuration error found in one pass
+type ValidationErrors []*ConfigError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,280 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LoadOptions selects the layers read by Load, in addition to environment variables
+type LoadOptions struct {
+	// File is a YAML (.yaml, .yml) or TOML (.toml) configuration file
+	File string
+	// Overrides are command-line settings keyed by environment variable name
+	Overrides map[string]string
+}
+
+// Load builds configuration from defaults, the configuration file, environment
+// variables and command-line overrides, each layer taking precedence over the last.
+//
+// File keys mirror environment variable names: nested sections are joined with
+// underscores, so `search_cache: {ttl: 5m}` sets SEARCH_CACHE_TTL. Malformed values
+// and unknown keys are reported with the file path or flag they came from.
+func Load(opts LoadOptions) (*Config, error) {
+	l, err := newLayeredLoader(opts)
+	if err != nil {
+		return nil, err
+	}
+	return l.loadStrict()
+}
+
+// LoadAndValidate loads layered configuration and validates it, reporting
+// validation errors against the file key or flag that set the offending value
+func LoadAndValidate(opts LoadOptions) (*Config, error) {
+	l, err := newLayeredLoader(opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := l.loadStrict()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, l.localize(err)
+	}
+	return cfg, nil
+}
+
+// RegisterFlags registers the -config and repeatable -set KEY=value flags on fs
+// and returns the options they populate once fs is parsed.
+// CONFIG_FILE provides the default configuration file.
+func RegisterFlags(fs *flag.FlagSet) *LoadOptions {
+	opts := &LoadOptions{Overrides: make(map[string]string)}
+	fs.StringVar(&opts.File, "config", os.Getenv("CONFIG_FILE"), "Configuration file path (YAML or TOML)")
+	fs.Var(overrideFlag(opts.Overrides), "set", "Override a setting, e.g. -set SEARCH_CACHE_TTL=5m (repeatable)")
+	return opts
+}
+
+// overrideFlag collects repeated KEY=value flags
+type overrideFlag map[string]string
+
+func (f overrideFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for key, value := range f {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (f overrideFlag) Set(value string) error {
+	key, setting, ok := strings.Cut(value, "=")
+	if !ok || strings.TrimSpace(key) == "" {
+		return fmt.Errorf("expected KEY=value, got %q", value)
+	}
+	f[strings.TrimSpace(key)] = setting
+	return nil
+}
+
+// settingValue is a raw setting and where it was read from
+type settingValue struct {
+	value  string
+	origin string
+}
+
+// loader resolves settings through flag, environment and file layers
+type loader struct {
+	file      map[string]settingValue
+	overrides map[string]settingValue
+	used      map[string]bool
+	errs      ValidationErrors
+}
+
+func newLoader(file, overrides map[string]settingValue) *loader {
+	return &loader{
+		file:      file,
+		overrides: overrides,
+		used:      make(map[string]bool),
+	}
+}
+
+// newLayeredLoader reads the file and override layers selected by opts
+func newLayeredLoader(opts LoadOptions) (*loader, error) {
+	var fileValues map[string]settingValue
+	if opts.File != "" {
+		values, err := readConfigFile(opts.File)
+		if err != nil {
+			return nil, err
+		}
+		fileValues = values
+	}
+
+	overrides := make(map[string]settingValue, len(opts.Overrides))
+	for key, value := range opts.Overrides {
+		overrides[settingKey(key)] = settingValue{value: value, origin: "flag -set " + key}
+	}
+	return newLoader(fileValues, overrides), nil
+}
+
+// loadStrict builds the configuration, failing on malformed values and unknown keys
+func (l *loader) loadStrict() (*Config, error) {
+	cfg := l.load()
+	l.checkUnusedKeys()
+	if len(l.errs) > 0 {
+		return nil, l.errs
+	}
+	return cfg, nil
+}
+
+// lookup returns the highest-precedence value for key and its origin
+func (l *loader) lookup(key string) (string, string, bool) {
+	l.used[key] = true
+	if setting, ok := l.overrides[key]; ok {
+		return setting.value, setting.origin, true
+	}
+	if value := os.Getenv(key); value != "" {
+		return value, "env " + key, true
+	}
+	if setting, ok := l.file[key]; ok {
+		return setting.value, setting.origin, true
+	}
+	return "", "", false
+}
+
+// invalid records a malformed setting
+func (l *loader) invalid(origin, format string, args ...interface{}) {
+	l.errs = append(l.errs, &ConfigError{Field: origin, Message: fmt.Sprintf(format, args...)})
+}
+
+// checkUnusedKeys reports file keys and overrides that match no setting
+func (l *loader) checkUnusedKeys() {
+	var unknown []settingValue
+	for _, layer := range []map[string]settingValue{l.file, l.overrides} {
+		for key, setting := range layer {
+			if !l.used[key] {
+				unknown = append(unknown, setting)
+			}
+		}
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].origin < unknown[j].origin })
+	for _, setting := range unknown {
+		l.invalid(setting.origin, "unknown configuration key")
+	}
+}
+
+// localize rewrites validation errors raised against environment variable
+// names to the file key or flag that actually supplied the value
+func (l *loader) localize(err error) error {
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	localized := make(ValidationErrors, len(errs))
+	for i, configErr := range errs {
+		localized[i] = configErr
+		if _, origin, ok := l.lookup(configErr.Field); ok && !strings.HasPrefix(origin, "env ") {
+			localized[i] = &ConfigError{Field: origin, Message: configErr.Message}
+		}
+	}
+	return localized
+}
+
+// readConfigFile parses a YAML or TOML file into settings keyed by environment variable name
+func readConfigFile(path string) (map[string]settingValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var document map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var raw map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+		document = normalizeYAMLMap(raw)
+	case ".toml":
+		document, err = parseTOML(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported config file format: %s", path)
+	}
+
+	values := make(map[string]settingValue)
+	if err := flattenSettings(document, nil, filepath.Base(path), values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// flattenSettings walks nested sections, joining their keys into setting names
+func flattenSettings(section map[string]interface{}, path []string, file string, values map[string]settingValue) error {
+	for key, value := range section {
+		keyPath := append(append([]string{}, path...), key)
+		dotted := strings.Join(keyPath, ".")
+
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			if err := flattenSettings(typed, keyPath, file, values); err != nil {
+				return err
+			}
+			continue
+		case []interface{}:
+			items := make([]string, len(typed))
+			for i, item := range typed {
+				if _, nested := item.(map[string]interface{}); nested {
+					return &ConfigError{Field: file + ": " + dotted, Message: "lists of sections are not supported"}
+				}
+				items[i] = fmt.Sprint(item)
+			}
+			value = strings.Join(items, ",")
+		case nil:
+			value = ""
+		}
+
+		name := settingKey(strings.Join(keyPath, "_"))
+		origin := file + ": " + dotted
+		if existing, ok := values[name]; ok {
+			return &ConfigError{Field: origin, Message: "duplicates " + existing.origin}
+		}
+		values[name] = settingValue{value: fmt.Sprint(value), origin: origin}
+	}
+	return nil
+}
+
+// normalizeYAMLMap converts yaml.v2 maps to string-keyed maps
+func normalizeYAMLMap(raw map[interface{}]interface{}) map[string]interface{} {
+	normalized := make(map[string]interface{}, len(raw))
+	for key, value := range raw {
+		normalized[fmt.Sprint(key)] = normalizeYAMLValue(value)
+	}
+	return normalized
+}
+
+func normalizeYAMLValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[interface{}]interface{}:
+		return normalizeYAMLMap(typed)
+	case []interface{}:
+		items := make([]interface{}, len(typed))
+		for i, item := range typed {
+			items[i] = normalizeYAMLValue(item)
+		}
+		return items
+	}
+	return value
+}
+
+// settingKey maps a file key or flag name such as search_cache.ttl to SEARCH_CACHE_TTL
+func settingKey(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(strings.TrimSpace(key)))
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestLoad_Layering(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  port: "9000"
+  read_timeout: 10s
+search_cache:
+  ttl: 20m
+db:
+  read_dsns:
+    - postgres://replica-1
+    - postgres://replica-2
+rag:
+  semantic_weight: 0.4
+`)
+	t.Setenv("SEARCH_CACHE_TTL", "30m")
+	t.Setenv("SERVER_READ_TIMEOUT", "")
+
+	cfg, err := Load(LoadOptions{File: path, Overrides: map[string]string{"server.port": "9100"}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "9100", cfg.Server.Port, "flags override the file")
+	assert.Equal(t, 30*time.Minute, cfg.SearchCache.DefaultTTL, "environment overrides the file")
+	assert.Equal(t, 10*time.Second, cfg.Server.ReadTimeout)
+	assert.Equal(t, []string{"postgres://replica-1", "postgres://replica-2"}, cfg.Database.ReadDSNs)
+	assert.Equal(t, 0.4, cfg.RAG.SemanticWeight)
+	assert.Equal(t, 60*time.Second, cfg.Server.IdleTimeout, "defaults fill unset settings")
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+# Ink gateway
+[server]
+port = "9200" # inline comment
+
+[search_cache]
+ttl = "5m"
+max_entries = 1_000
+
+[db]
+read_dsns = ["postgres://a", 'postgres://b']
+`)
+
+	cfg, err := Load(LoadOptions{File: path})
+	require.NoError(t, err)
+	assert.Equal(t, "9200", cfg.Server.Port)
+	assert.Equal(t, 5*time.Minute, cfg.SearchCache.DefaultTTL)
+	assert.Equal(t, 1000, cfg.SearchCache.MaxEntries)
+	assert.Equal(t, []string{"postgres://a", "postgres://b"}, cfg.Database.ReadDSNs)
+
+	_, err = parseTOML("[server]\nport")
+	assert.EqualError(t, err, "line 2: expected key = value")
+}
+
+func TestLoad_ErrorPaths(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+server:
+  prot: "9000"
+search_cache:
+  ttl: soon
+`)
+
+	_, err := Load(LoadOptions{File: path, Overrides: map[string]string{"CACHE_MAX_SIZE": "many"}})
+	require.Error(t, err)
+	errs, ok := err.(ValidationErrors)
+	require.True(t, ok)
+	assert.ElementsMatch(t, []string{
+		"config.yaml: search_cache.ttl: invalid duration \"soon\"",
+		"flag -set CACHE_MAX_SIZE: invalid integer \"many\"",
+		"config.yaml: server.prot: unknown configuration key",
+	}, errorStrings(errs))
+}
+
+func TestLoadAndValidate_ReportsOrigin(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "rag:\n  semantic_weight: 1.5\n")
+	t.Setenv("SUPABASE_URL", "http://localhost")
+	t.Setenv("SUPABASE_API_KEY", "key")
+	t.Setenv("DB_MAX_CONNS", "0")
+
+	_, err := LoadAndValidate(LoadOptions{File: path})
+	require.Error(t, err)
+	assert.ElementsMatch(t, []string{
+		"DB_MAX_CONNS: must be positive",
+		"DB_MIN_CONNS: must be between 0 and DB_MAX_CONNS",
+		"config.yaml: rag.semantic_weight: must be between 0 and 1",
+	}, errorStrings(err.(ValidationErrors)))
+}
+
+func TestLoadAndValidate_UnifiedChunkDriver(t *testing.T) {
+	t.Setenv("SUPABASE_URL", "http://localhost")
+	t.Setenv("SUPABASE_API_KEY", "key")
+
+	cfg, err := LoadAndValidate(LoadOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "database/sql", cfg.Database.UnifiedChunkDriver)
+	assert.Equal(t, 512, cfg.Database.StatementCacheCapacity)
+
+	t.Setenv("DB_UNIFIED_CHUNK_DRIVER", "pq")
+	t.Setenv("DB_STATEMENT_CACHE_CAPACITY", "-1")
+	_, err = LoadAndValidate(LoadOptions{})
+	require.Error(t, err)
+	assert.ElementsMatch(t, []string{
+		"DB_UNIFIED_CHUNK_DRIVER: must be database/sql or pgx",
+		"DB_STATEMENT_CACHE_CAPACITY: must not be negative",
+	}, errorStrings(err.(ValidationErrors)))
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "search_cache:\n  ttl: 1m\n")
+	t.Setenv("SUPABASE_URL", "http://localhost")
+	t.Setenv("SUPABASE_API_KEY", "key")
+
+	cfg, err := LoadAndValidate(LoadOptions{File: path})
+	require.NoError(t, err)
+
+	watcher := NewWatcher(LoadOptions{File: path}, cfg, nil)
+	var reloaded []*Config
+	watcher.OnReload(func(cfg *Config) { reloaded = append(reloaded, cfg) })
+
+	require.NoError(t, os.WriteFile(path, []byte("search_cache:\n  ttl: 2m\n"), 0o644))
+	require.NoError(t, watcher.Reload())
+	require.Len(t, reloaded, 1)
+	assert.Equal(t, 2*time.Minute, watcher.Current().SearchCache.DefaultTTL)
+
+	require.NoError(t, os.WriteFile(path, []byte("search_cache:\n  ttl: -1m\n"), 0o644))
+	assert.Error(t, watcher.Reload())
+	assert.Len(t, reloaded, 1, "invalid configurations are not applied")
+	assert.Equal(t, 2*time.Minute, watcher.Current().SearchCache.DefaultTTL)
+}
+
+func errorStrings(errs ValidationErrors) []string {
+	messages := make([]string, len(errs))
+	for i, err := range errs {
+		messages[i] = err.Error()
+	}
+	return messages
+}
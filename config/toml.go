@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the TOML subset used by configuration files: [section] and
+// [dotted.section] headers, dotted keys, and string, number, boolean and
+// single-line array values
+func parseTOML(data string) (map[string]interface{}, error) {
+	document := make(map[string]interface{})
+	section := document
+
+	for i, rawLine := range strings.Split(data, "\n") {
+		lineNumber := i + 1
+		line := strings.TrimSpace(stripTOMLComment(rawLine))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNumber, line)
+			}
+			table, err := tomlTable(document, splitTOMLKey(line[1:len(line)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			section = table
+			continue
+		}
+
+		key, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNumber)
+		}
+		keyPath := splitTOMLKey(key)
+		value, err := parseTOMLValue(strings.TrimSpace(rawValue))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		table, err := tomlTable(section, keyPath[:len(keyPath)-1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		name := keyPath[len(keyPath)-1]
+		if _, exists := table[name]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNumber, strings.Join(keyPath, "."))
+		}
+		table[name] = value
+	}
+
+	return document, nil
+}
+
+// tomlTable returns the nested table at path, creating missing tables
+func tomlTable(root map[string]interface{}, path []string) (map[string]interface{}, error) {
+	table := root
+	for _, name := range path {
+		if name == "" {
+			return nil, fmt.Errorf("empty key in table path")
+		}
+		next, exists := table[name]
+		if !exists {
+			created := make(map[string]interface{})
+			table[name] = created
+			table = created
+			continue
+		}
+		nested, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("key %q is not a table", name)
+		}
+		table = nested
+	}
+	return table, nil
+}
+
+// splitTOMLKey splits a dotted key into its bare or quoted parts
+func splitTOMLKey(key string) []string {
+	parts := strings.Split(key, ".")
+	for i, part := range parts {
+		parts[i] = strings.Trim(strings.TrimSpace(part), `"'`)
+	}
+	return parts
+}
+
+// parseTOMLValue parses a scalar or single-line array value
+func parseTOMLValue(raw string) (interface{}, error) {
+	switch {
+	case raw == "":
+		return nil, fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, `"`):
+		value, err := strconv.Unquote(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid string %s", raw)
+		}
+		return value, nil
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return nil, fmt.Errorf("invalid string %s", raw)
+		}
+		return raw[1 : len(raw)-1], nil
+	case strings.HasPrefix(raw, "["):
+		if !strings.HasSuffix(raw, "]") {
+			return nil, fmt.Errorf("arrays must be on a single line")
+		}
+		items := []interface{}{}
+		for _, item := range splitTOMLArray(raw[1 : len(raw)-1]) {
+			value, err := parseTOMLValue(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case raw == "true" || raw == "false":
+		return raw == "true", nil
+	}
+
+	number := strings.ReplaceAll(raw, "_", "")
+	if value, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return value, nil
+	}
+	if value, err := strconv.ParseFloat(number, 64); err == nil {
+		return value, nil
+	}
+	return nil, fmt.Errorf("invalid value %s", raw)
+}
+
+// splitTOMLArray splits array items on commas outside quoted strings
+func splitTOMLArray(raw string) []string {
+	var items []string
+	var current strings.Builder
+	var quote rune
+
+	for _, r := range raw {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == ',':
+			if item := strings.TrimSpace(current.String()); item != "" {
+				items = append(items, item)
+			}
+			current.Reset()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if item := strings.TrimSpace(current.String()); item != "" {
+		items = append(items, item)
+	}
+	return items
+}
+
+// stripTOMLComment removes a trailing # comment outside quoted strings
+func stripTOMLComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
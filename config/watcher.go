@@ -0,0 +1,133 @@
+package config
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultWatchInterval is how often the configuration file is checked for changes
+const defaultWatchInterval = 5 * time.Second
+
+// Watcher reloads layered configuration on SIGHUP and when the configuration file
+// changes, handing each valid configuration to the registered reload callbacks.
+// Invalid configurations are logged and the previous configuration stays active.
+type Watcher struct {
+	opts     LoadOptions
+	interval time.Duration
+	logger   *log.Logger
+
+	mu        sync.RWMutex
+	current   *Config
+	modTime   time.Time
+	callbacks []func(*Config)
+}
+
+// NewWatcher creates a configuration watcher starting from the current configuration
+func NewWatcher(opts LoadOptions, current *Config, logger *log.Logger) *Watcher {
+	if logger == nil {
+		logger = log.New(os.Stderr, "[config] ", log.LstdFlags)
+	}
+	w := &Watcher{
+		opts:     opts,
+		interval: defaultWatchInterval,
+		logger:   logger,
+		current:  current,
+	}
+	w.modTime, _ = w.fileModTime()
+	return w
+}
+
+// OnReload registers a callback invoked with every successfully reloaded configuration
+func (w *Watcher) OnReload(callback func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.callbacks = append(w.callbacks, callback)
+}
+
+// Current returns the active configuration
+func (w *Watcher) Current() *Config {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Reload loads and validates the configuration and notifies callbacks on success
+func (w *Watcher) Reload() error {
+	cfg, err := LoadAndValidate(w.opts)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.current = cfg
+	callbacks := append([]func(*Config){}, w.callbacks...)
+	w.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(cfg)
+	}
+	return nil
+}
+
+// Watch reloads on SIGHUP and configuration file changes until ctx is cancelled
+func (w *Watcher) Watch(ctx context.Context) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			w.reloadAndLog("SIGHUP")
+		case <-ticker.C:
+			if w.fileChanged() {
+				w.reloadAndLog("file change")
+			}
+		}
+	}
+}
+
+func (w *Watcher) reloadAndLog(trigger string) {
+	if err := w.Reload(); err != nil {
+		w.logger.Printf("Configuration reload on %s failed, keeping previous configuration: %v", trigger, err)
+		return
+	}
+	w.logger.Printf("Configuration reloaded on %s", trigger)
+}
+
+// fileChanged reports whether the configuration file was modified since the last check
+func (w *Watcher) fileChanged() bool {
+	modTime, ok := w.fileModTime()
+	if !ok {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if modTime.Equal(w.modTime) {
+		return false
+	}
+	w.modTime = modTime
+	return true
+}
+
+func (w *Watcher) fileModTime() (time.Time, bool) {
+	if w.opts.File == "" {
+		return time.Time{}, false
+	}
+	info, err := os.Stat(w.opts.File)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
@@ -0,0 +1,23 @@
+-- API Tokens Migration
+-- Stores the API tokens callers authenticate with when AUTH_ENABLED=true. Each token
+-- belongs to one workspace and has a role: admin, editor or reader. Only a SHA-256 hash
+-- of the token is kept; the token itself is shown once when it is created.
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name         TEXT NOT NULL,
+    workspace_id TEXT NOT NULL,
+    role         TEXT NOT NULL CHECK (role IN ('admin', 'editor', 'reader')),
+    token_hash   TEXT NOT NULL UNIQUE,
+    -- leading characters of the token, so users can tell their tokens apart
+    token_prefix TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ,
+    -- updated at most once a minute per token
+    last_used_at TIMESTAMPTZ,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_workspace ON api_tokens(workspace_id, created_at DESC);
+
+COMMENT ON TABLE api_tokens IS 'Workspace-scoped API tokens with admin, editor or reader roles';
@@ -0,0 +1,33 @@
+-- Attachment Deduplication Migration
+-- With STORAGE_DEDUP=true object storage keeps each distinct file once, under
+-- blobs/<first two hash digits>/<sha256>. attachment_objects maps the keys callers write to
+-- the blob holding their content and attachment_blobs counts those references; blobs left
+-- without references are deleted by garbage collection after a grace period.
+
+CREATE TABLE IF NOT EXISTS attachment_blobs (
+    hash            TEXT PRIMARY KEY,
+    storage_key     TEXT NOT NULL,
+    size            BIGINT NOT NULL,
+    content_type    TEXT NOT NULL,
+    url             TEXT NOT NULL DEFAULT '',
+    ref_count       INTEGER NOT NULL DEFAULT 0 CHECK (ref_count >= 0),
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    unreferenced_at TIMESTAMPTZ
+);
+
+-- Garbage collection looks for blobs without references by age
+CREATE INDEX IF NOT EXISTS idx_attachment_blobs_unreferenced
+    ON attachment_blobs(unreferenced_at) WHERE ref_count = 0;
+
+CREATE TABLE IF NOT EXISTS attachment_objects (
+    object_key   TEXT PRIMARY KEY,
+    hash         TEXT NOT NULL REFERENCES attachment_blobs(hash),
+    content_type TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachment_objects_hash ON attachment_objects(hash);
+
+COMMENT ON TABLE attachment_blobs IS 'Stored file contents by SHA-256, with the number of object keys referencing each';
+COMMENT ON COLUMN attachment_blobs.unreferenced_at IS 'When ref_count last dropped to 0; garbage collection waits STORAGE_DEDUP_GRACE_PERIOD from it';
+COMMENT ON TABLE attachment_objects IS 'Object keys written through StorageService and the blob holding their content';
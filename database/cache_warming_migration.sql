@@ -0,0 +1,15 @@
+-- Cache Warming Migration
+-- Records which chunks, tag listings and searches are read most, so that a freshly started
+-- instance can preload them into its caches. The service keeps counts in memory and adds
+-- them here periodically; rows not accessed for 30 days are pruned on flush.
+
+CREATE TABLE IF NOT EXISTS cache_hot_patterns (
+    -- chunk:<chunk_id>, tag:<tag_chunk_id> or search:<search query JSON>
+    pattern      TEXT PRIMARY KEY,
+    access_count BIGINT NOT NULL DEFAULT 0,
+    last_access  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_cache_hot_patterns_last_access ON cache_hot_patterns(last_access);
+
+COMMENT ON TABLE cache_hot_patterns IS 'Access counts of cacheable reads, used to warm caches on startup';
@@ -0,0 +1,45 @@
+-- Change Feed Migration
+-- Publishes every chunk insert, update and delete on the chunk_changes channel so that
+-- gateway instances can invalidate caches and stream changes to clients.
+--
+-- Payloads carry identifiers and flags only: NOTIFY payloads are limited to 8000 bytes,
+-- so subscribers load contents themselves when they need them.
+
+CREATE OR REPLACE FUNCTION notify_chunk_change() RETURNS trigger AS $$
+DECLARE
+    rec RECORD;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        rec := OLD;
+    ELSE
+        rec := NEW;
+    END IF;
+
+    -- Updates that change nothing (e.g. idempotent upserts) are not worth waking listeners for
+    IF TG_OP = 'UPDATE' AND NEW IS NOT DISTINCT FROM OLD THEN
+        RETURN NULL;
+    END IF;
+
+    PERFORM pg_notify('chunk_changes', json_build_object(
+        'op', lower(TG_OP),
+        'table', TG_TABLE_NAME,
+        'chunk_id', rec.chunk_id,
+        'page', rec.page,
+        'parent', rec.parent,
+        'is_page', rec.is_page,
+        'is_tag', rec.is_tag,
+        'is_template', rec.is_template,
+        'version', rec.version,
+        'at', NOW()
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS chunks_change_feed ON chunks;
+CREATE TRIGGER chunks_change_feed
+    AFTER INSERT OR UPDATE OR DELETE ON chunks
+    FOR EACH ROW EXECUTE FUNCTION notify_chunk_change();
+
+COMMENT ON FUNCTION notify_chunk_change() IS 'Publishes chunk changes on the chunk_changes NOTIFY channel';
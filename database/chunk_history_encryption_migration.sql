@@ -0,0 +1,39 @@
+-- Chunk History Encryption Migration
+-- Keeps the plaintext of sensitive chunks out of chunk_history. Once a chunk's contents are
+-- stored encrypted, the contents of its earlier versions are replaced with the encrypted
+-- contents, so history no longer holds what the chunk said before it was marked sensitive.
+-- Requires chunk_history_migration.sql.
+
+CREATE OR REPLACE FUNCTION record_chunk_history() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW IS NOT DISTINCT FROM OLD THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE chunk_history SET valid_to = clock_timestamp()
+        WHERE chunk_id = OLD.chunk_id AND valid_to IS NULL;
+    END IF;
+
+    -- Redact plaintext versions when the contents become encrypted
+    IF TG_OP IN ('INSERT', 'UPDATE') AND NEW.contents LIKE 'enc:v1:%'
+       AND (TG_OP = 'INSERT' OR COALESCE(OLD.contents, '') NOT LIKE 'enc:v1:%') THEN
+        UPDATE chunk_history SET record = jsonb_set(record, '{contents}', to_jsonb(NEW.contents))
+        WHERE chunk_id = NEW.chunk_id AND COALESCE(record->>'contents', '') NOT LIKE 'enc:v1:%';
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO chunk_history (chunk_id, version, record, valid_from)
+        VALUES (NEW.chunk_id, NEW.version, to_jsonb(NEW) - 'search_vector', clock_timestamp());
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Redact the versions kept before this migration of chunks that are encrypted now
+UPDATE chunk_history h SET record = jsonb_set(h.record, '{contents}', to_jsonb(c.contents))
+FROM chunks c
+WHERE c.chunk_id = h.chunk_id
+  AND c.contents LIKE 'enc:v1:%'
+  AND COALESCE(h.record->>'contents', '') NOT LIKE 'enc:v1:%';
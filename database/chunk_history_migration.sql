@@ -0,0 +1,59 @@
+-- Chunk History Migration
+-- Keeps every version of every chunk so the knowledge base can be read as it was at any
+-- point in time. Each row is one version, valid from valid_from until valid_to; the current
+-- version has no valid_to, and deleting a chunk closes its last version. record is the
+-- chunks row as JSONB without search_vector, as in chunk_trash, and is read back with
+-- jsonb_populate_record(NULL::chunks, record).
+--
+-- History starts when this migration runs. Chunks that already exist get one version valid
+-- from their created_time, so reads before the migration show their contents at that time.
+
+CREATE TABLE IF NOT EXISTS chunk_history (
+    history_id BIGSERIAL PRIMARY KEY,
+    chunk_id   UUID NOT NULL,
+    version    BIGINT NOT NULL,
+    record     JSONB NOT NULL,
+    valid_from TIMESTAMPTZ NOT NULL,
+    valid_to   TIMESTAMPTZ
+);
+
+-- Point-in-time reads of one chunk and the revision list
+CREATE INDEX IF NOT EXISTS idx_chunk_history_chunk ON chunk_history(chunk_id, valid_from DESC);
+-- Point-in-time reads of children and pages
+CREATE INDEX IF NOT EXISTS idx_chunk_history_parent ON chunk_history((record->>'parent'), valid_from);
+CREATE INDEX IF NOT EXISTS idx_chunk_history_page ON chunk_history((record->>'page'), valid_from);
+-- Only one version of a chunk is current
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chunk_history_current ON chunk_history(chunk_id) WHERE valid_to IS NULL;
+
+-- clock_timestamp() rather than NOW(), so several updates in one transaction keep their order
+CREATE OR REPLACE FUNCTION record_chunk_history() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW IS NOT DISTINCT FROM OLD THEN
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        UPDATE chunk_history SET valid_to = clock_timestamp()
+        WHERE chunk_id = OLD.chunk_id AND valid_to IS NULL;
+    END IF;
+
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        INSERT INTO chunk_history (chunk_id, version, record, valid_from)
+        VALUES (NEW.chunk_id, NEW.version, to_jsonb(NEW) - 'search_vector', clock_timestamp());
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS chunks_history ON chunks;
+CREATE TRIGGER chunks_history
+    AFTER INSERT OR UPDATE OR DELETE ON chunks
+    FOR EACH ROW EXECUTE FUNCTION record_chunk_history();
+
+INSERT INTO chunk_history (chunk_id, version, record, valid_from)
+SELECT c.chunk_id, c.version, to_jsonb(c) - 'search_vector', COALESCE(c.created_time, NOW())
+FROM chunks c
+WHERE NOT EXISTS (SELECT 1 FROM chunk_history h WHERE h.chunk_id = c.chunk_id);
+
+COMMENT ON TABLE chunk_history IS 'Every version of every chunk, for point-in-time reads';
@@ -0,0 +1,16 @@
+-- Chunk Lock Migration
+-- Locks taken by editor sessions on a chunk and its subtree. Only the holder, identified by
+-- the lock's token, may restructure the subtree until the lock is released or expires.
+-- The gateway enforces locks when CHUNK_LOCKS_ENABLED=true.
+
+CREATE TABLE IF NOT EXISTS chunk_locks (
+    chunk_id    UUID PRIMARY KEY REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    holder      TEXT NOT NULL DEFAULT '',
+    token       TEXT NOT NULL,
+    acquired_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_chunk_locks_expires_at ON chunk_locks (expires_at);
+
+COMMENT ON TABLE chunk_locks IS 'Subtree locks held by editor sessions until released or expired';
@@ -0,0 +1,18 @@
+-- Chunk Reference Backfill Migration
+-- Backfills chunk_refs from every existing chunks.ref with the rules the service uses when
+-- it writes references: a ref lists one or more chunk IDs separated by commas, semicolons
+-- or whitespace, each optionally wrapped in (( )) or [[ ]], in any case.
+-- Requires chunk_refs_migration.sql.
+
+INSERT INTO chunk_refs (source_chunk_id, target_chunk_id)
+SELECT DISTINCT c.chunk_id, regexp_replace(t.target, '^urn:uuid:', '', 'i')::uuid
+FROM chunks c
+CROSS JOIN LATERAL regexp_split_to_table(c.ref, '[,; \n\t]+') AS f(field)
+CROSS JOIN LATERAL (
+    SELECT regexp_replace(regexp_replace(f.field, '^(\(\()?(\[\[)?', ''), '(\]\])?(\)\))?$', '') AS target
+) t
+WHERE c.ref IS NOT NULL
+  AND (t.target ~* '^(urn:uuid:)?[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$'
+       OR t.target ~* '^\{[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\}$'
+       OR t.target ~* '^[0-9a-f]{32}$')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,25 @@
+-- Chunk Reference Graph Migration
+-- Resolves the free-form chunks.ref field into an auxiliary table so that
+-- backlinks ("which chunks point at this one?") can be answered with an index lookup.
+
+-- Auxiliary table for chunk references
+-- target_chunk_id intentionally has no foreign key: references to deleted chunks
+-- must survive so they can be reported as broken instead of silently disappearing.
+CREATE TABLE IF NOT EXISTS chunk_refs (
+    source_chunk_id UUID NOT NULL,
+    target_chunk_id UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+
+    PRIMARY KEY (source_chunk_id, target_chunk_id),
+    FOREIGN KEY (source_chunk_id) REFERENCES chunks(chunk_id) ON DELETE CASCADE
+);
+
+-- Backlink lookups go target -> source
+CREATE INDEX IF NOT EXISTS idx_chunk_refs_target ON chunk_refs(target_chunk_id);
+CREATE INDEX IF NOT EXISTS idx_chunk_refs_created ON chunk_refs(created_at DESC);
+
+-- Existing refs are backfilled by chunk_refs_backfill_migration.sql
+
+COMMENT ON TABLE chunk_refs IS 'Auxiliary table resolving chunks.ref into source -> target chunk references';
+COMMENT ON COLUMN chunk_refs.source_chunk_id IS 'The chunk whose ref field contains the reference';
+COMMENT ON COLUMN chunk_refs.target_chunk_id IS 'The referenced chunk (may no longer exist for broken references)';
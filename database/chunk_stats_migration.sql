@@ -0,0 +1,13 @@
+-- Chunk Statistics Migration
+-- Daily snapshots of the knowledge base statistics served by /api/v1/stats, so dashboards
+-- do not scan the chunks table on every request. The maintenance daemon takes one snapshot
+-- a day. stats is the statistics as JSON; the latest snapshot keeps 400 days of daily
+-- growth and the most-linked chunks and most-used tags, older ones only their counts.
+
+CREATE TABLE IF NOT EXISTS chunk_stats_snapshots (
+    snapshot_date DATE PRIMARY KEY,
+    stats         JSONB NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE chunk_stats_snapshots IS 'Daily snapshots of chunk counts, growth, usage rankings and content length statistics';
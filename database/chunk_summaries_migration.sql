@@ -0,0 +1,15 @@
+-- Chunk Summaries Migration
+-- Caches LLM summaries of chunks and rollup summaries of pages and blocks with children.
+-- source_hash is a hash of the summarized text: a chunk's contents, or a parent's contents
+-- with the hashes of its children, so editing any block invalidates the summaries of the
+-- blocks and page above it. Summaries of deleted chunks are removed with them.
+
+CREATE TABLE IF NOT EXISTS chunk_summaries (
+    chunk_id    UUID PRIMARY KEY REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    source_hash TEXT NOT NULL,
+    summary     TEXT NOT NULL,
+    model       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE chunk_summaries IS 'Cached chunk and rollup summaries, valid while source_hash matches the summarized text';
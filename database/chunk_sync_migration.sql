@@ -0,0 +1,90 @@
+-- Chunk Sync Migration
+-- Offline clients pull the chunks changed since their cursor and push the operations they
+-- made while offline. chunk_sync_log records which fields every chunk write changed, from
+-- any writer, so that pushed edits can be merged field by field with concurrent changes.
+-- Values are not logged: pulls read the current chunk, decrypted by the service.
+
+CREATE TABLE IF NOT EXISTS chunk_sync_log (
+    seq BIGSERIAL PRIMARY KEY,
+    chunk_id UUID NOT NULL,
+    op TEXT NOT NULL CHECK (op IN ('insert', 'update', 'delete')),
+    version BIGINT NOT NULL,
+    fields TEXT[] NOT NULL DEFAULT '{}',
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chunk_sync_log_chunk ON chunk_sync_log(chunk_id, version);
+
+CREATE OR REPLACE FUNCTION log_chunk_sync_change() RETURNS trigger AS $$
+DECLARE
+    changed TEXT[] := '{}';
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO chunk_sync_log (chunk_id, op, version) VALUES (OLD.chunk_id, 'delete', OLD.version);
+        RETURN NULL;
+    END IF;
+
+    IF TG_OP = 'INSERT' THEN
+        changed := ARRAY['contents', 'parent', 'page', 'is_page', 'is_tag', 'is_template',
+                         'is_slot', 'ref', 'tags', 'metadata'];
+    ELSE
+        IF NEW.contents IS DISTINCT FROM OLD.contents THEN changed := changed || 'contents'::text; END IF;
+        IF NEW.parent IS DISTINCT FROM OLD.parent THEN changed := changed || 'parent'::text; END IF;
+        IF NEW.page IS DISTINCT FROM OLD.page THEN changed := changed || 'page'::text; END IF;
+        IF NEW.is_page IS DISTINCT FROM OLD.is_page THEN changed := changed || 'is_page'::text; END IF;
+        IF NEW.is_tag IS DISTINCT FROM OLD.is_tag THEN changed := changed || 'is_tag'::text; END IF;
+        IF NEW.is_template IS DISTINCT FROM OLD.is_template THEN changed := changed || 'is_template'::text; END IF;
+        IF NEW.is_slot IS DISTINCT FROM OLD.is_slot THEN changed := changed || 'is_slot'::text; END IF;
+        IF NEW.ref IS DISTINCT FROM OLD.ref THEN changed := changed || 'ref'::text; END IF;
+        IF NEW.tags IS DISTINCT FROM OLD.tags THEN changed := changed || 'tags'::text; END IF;
+        IF NEW.metadata IS DISTINCT FROM OLD.metadata THEN changed := changed || 'metadata'::text; END IF;
+
+        -- Writes that only touch derived columns (vectors, search_vector) are not synced
+        IF cardinality(changed) = 0 THEN
+            RETURN NULL;
+        END IF;
+    END IF;
+
+    INSERT INTO chunk_sync_log (chunk_id, op, version, fields)
+    VALUES (NEW.chunk_id, lower(TG_OP), NEW.version, changed);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS chunks_sync_log ON chunks;
+CREATE TRIGGER chunks_sync_log
+    AFTER INSERT OR UPDATE OR DELETE ON chunks
+    FOR EACH ROW EXECUTE FUNCTION log_chunk_sync_change();
+
+-- Operations already applied, so that a client retrying a push does not apply them twice
+CREATE TABLE IF NOT EXISTS chunk_sync_ops (
+    client_id TEXT NOT NULL,
+    op_id TEXT NOT NULL,
+    chunk_id UUID NOT NULL,
+    result JSONB NOT NULL,
+    applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (client_id, op_id)
+);
+
+-- Values that lost a last-writer-wins merge, kept for manual resolution
+CREATE TABLE IF NOT EXISTS chunk_sync_conflicts (
+    conflict_id UUID PRIMARY KEY,
+    chunk_id UUID NOT NULL,
+    field TEXT NOT NULL,
+    winner TEXT NOT NULL CHECK (winner IN ('server', 'client')),
+    kept_value JSONB,
+    discarded_value JSONB,
+    base_version BIGINT NOT NULL,
+    client_id TEXT NOT NULL,
+    op_id TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved')),
+    resolution TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_chunk_sync_conflicts_open ON chunk_sync_conflicts(chunk_id) WHERE status = 'open';
+
+COMMENT ON TABLE chunk_sync_log IS 'Fields changed by every chunk write, read by offline sync pulls and merges';
+COMMENT ON TABLE chunk_sync_conflicts IS 'Values that lost a last-writer-wins sync merge';
@@ -0,0 +1,30 @@
+-- Chunk Tasks Migration
+-- Indexes the tasks written in chunk contents. A chunk starting with a marker such as TODO
+-- or DONE is a task; its SCHEDULED and DEADLINE timestamps are kept here so overdue and
+-- today's tasks are index lookups. due_at is the deadline, or the scheduled time without
+-- one. notified_due records the due time a reminder was last sent for, so each due time is
+-- reminded once and a recurring task is reminded again when it moves to its next date. Rows
+-- are removed with their chunks.
+
+CREATE TABLE IF NOT EXISTS chunk_tasks (
+    chunk_id     UUID PRIMARY KEY REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    page         UUID,
+    status       TEXT NOT NULL,
+    title        TEXT NOT NULL,
+    tags         TEXT[] NOT NULL DEFAULT '{}',
+    scheduled_at TIMESTAMPTZ,
+    deadline_at  TIMESTAMPTZ,
+    due_at       TIMESTAMPTZ,
+    all_day      BOOLEAN NOT NULL DEFAULT false,
+    repeat       TEXT NOT NULL DEFAULT '',
+    notified_due TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chunk_tasks_open_due
+    ON chunk_tasks(due_at)
+    WHERE status NOT IN ('DONE', 'CANCELED');
+
+CREATE INDEX IF NOT EXISTS idx_chunk_tasks_tags ON chunk_tasks USING GIN (tags);
+
+COMMENT ON TABLE chunk_tasks IS 'Tasks parsed from chunk contents, with their dates and reminder state';
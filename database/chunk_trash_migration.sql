@@ -0,0 +1,19 @@
+-- Chunk Trash Migration
+-- Subtree deletes that are not permanent keep every removed chunk here, so that they can be
+-- recovered. record is the chunks row as JSONB (search_vector excluded, it is rebuilt on
+-- insert), including the tags array; position orders the chunks parents first.
+
+CREATE TABLE IF NOT EXISTS chunk_trash (
+    trash_id UUID NOT NULL,
+    chunk_id UUID NOT NULL,
+    root_chunk_id UUID NOT NULL,
+    record JSONB NOT NULL,
+    position INTEGER NOT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (trash_id, chunk_id)
+);
+
+-- Purging goes by age
+CREATE INDEX IF NOT EXISTS idx_chunk_trash_deleted_at ON chunk_trash(deleted_at);
+CREATE INDEX IF NOT EXISTS idx_chunk_trash_chunk ON chunk_trash(chunk_id);
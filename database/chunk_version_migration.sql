@@ -0,0 +1,8 @@
+-- Chunk Version Migration
+-- Adds a monotonically increasing version to chunks for optimistic concurrency control.
+-- Updates must name the version they read; the UPDATE matches only if it is still current
+-- and bumps it, so concurrent writers get a conflict instead of silently overwriting.
+
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN chunks.version IS 'Optimistic concurrency version, incremented on every update';
@@ -0,0 +1,37 @@
+-- Content encryption at rest
+-- Chunks flagged sensitive are stored with their contents encrypted by the service
+-- ("enc:v1:" followed by the base64 envelope). Ciphertext is not indexed for full-text
+-- search, so those chunks are only found by metadata, tag and hierarchy filters.
+-- Run after language_search_migration.sql.
+
+CREATE OR REPLACE FUNCTION chunks_update_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    -- Encrypted contents have no language and no full-text entry
+    IF NEW.contents LIKE 'enc:v1:%' THEN
+        NEW.lang := 'und';
+        NEW.search_vector := NULL;
+        RETURN NEW;
+    END IF;
+
+    -- Contents changed by a writer that did not detect the language: detect it again
+    IF TG_OP = 'UPDATE' AND NEW.contents IS DISTINCT FROM OLD.contents
+       AND NEW.lang IS NOT DISTINCT FROM OLD.lang THEN
+        NEW.lang := NULL;
+    END IF;
+    IF NEW.lang IS NULL OR NEW.lang = '' THEN
+        NEW.lang := chunk_detect_lang(NEW.contents);
+    END IF;
+
+    NEW.search_vector := to_tsvector(chunk_search_config(NEW.lang), COALESCE(NEW.contents, ''))
+        || to_tsvector('simple', COALESCE(NEW.contents, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Drop any full-text entries of chunks encrypted before this migration
+UPDATE chunks SET lang = 'und', search_vector = NULL
+WHERE contents LIKE 'enc:v1:%' AND search_vector IS NOT NULL;
+
+-- Sensitive chunks are looked up by their flag when listing what is encrypted
+CREATE INDEX IF NOT EXISTS idx_chunks_sensitive ON chunks(chunk_id)
+    WHERE metadata->>'sensitive' = 'true';
@@ -0,0 +1,16 @@
+-- Email Messages Migration
+-- Maps the Message-ID of each ingested email to the chunk it became and the chunk of the
+-- first message of its thread. Webhook retries and repeated IMAP fetches of a message are
+-- recognized by its Message-ID, and replies are filed under their thread's first message by
+-- looking up their In-Reply-To and References headers. Rows are removed with their chunks.
+
+CREATE TABLE IF NOT EXISTS email_messages (
+    message_id  TEXT PRIMARY KEY,
+    chunk_id    UUID NOT NULL REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    thread_id   UUID NOT NULL REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    received_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_messages_chunk ON email_messages(chunk_id);
+
+COMMENT ON TABLE email_messages IS 'Ingested emails by Message-ID, with their chunk and thread';
@@ -0,0 +1,13 @@
+-- Embedding Sync Migration
+-- Records which contents each text embedding was computed from, so that embeddings of
+-- edited chunks can be found and recomputed. Requires multimodal_embeddings_migration.sql.
+-- The hash is a SHA-256 of the plaintext contents, computed by the service because
+-- sensitive chunks are stored encrypted. NULL means the embedding is missing or stale.
+
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS vector_content_hash TEXT;
+
+-- Chunks waiting for an embedding of their current contents
+CREATE INDEX IF NOT EXISTS idx_chunks_vector_pending ON chunks(last_updated)
+    WHERE vector_content_hash IS NULL AND COALESCE(vector_type, 'text') = 'text';
+
+COMMENT ON COLUMN chunks.vector_content_hash IS 'SHA-256 of the plaintext contents the text vector was computed from; NULL when missing or stale';
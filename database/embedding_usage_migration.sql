@@ -0,0 +1,19 @@
+-- Embedding Usage Migration
+-- Daily embedding token usage per workspace and model, recorded by POST /embeddings/batch.
+-- tokens are billed by the provider when it reports them and estimated otherwise;
+-- estimated_tokens counts the estimated share. cost is tokens at the configured price per
+-- million tokens when they were used.
+
+CREATE TABLE IF NOT EXISTS embedding_usage (
+    workspace_id     TEXT NOT NULL DEFAULT 'default',
+    usage_date       DATE NOT NULL DEFAULT CURRENT_DATE,
+    model            TEXT NOT NULL DEFAULT '',
+    requests         BIGINT NOT NULL DEFAULT 0,
+    texts            BIGINT NOT NULL DEFAULT 0,
+    tokens           BIGINT NOT NULL DEFAULT 0,
+    estimated_tokens BIGINT NOT NULL DEFAULT 0,
+    cost             NUMERIC(14, 6) NOT NULL DEFAULT 0,
+    PRIMARY KEY (workspace_id, usage_date, model)
+);
+
+COMMENT ON TABLE embedding_usage IS 'Daily embedding requests, texts, tokens and estimated cost per workspace and model';
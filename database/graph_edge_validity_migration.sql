@@ -0,0 +1,20 @@
+-- Graph Edge Validity Migration
+-- Edges record the interval their relationship held: valid_from inclusive, valid_to
+-- exclusive, NULL unbounded. Superseding a relationship closes its edges at valid_to and
+-- adds the replacement from then, so history stays queryable as of any time. Existing
+-- edges hold at all times.
+
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS valid_from TIMESTAMP WITH TIME ZONE;
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS valid_to TIMESTAMP WITH TIME ZONE;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'graph_edges_validity_check') THEN
+        ALTER TABLE graph_edges ADD CONSTRAINT graph_edges_validity_check
+            CHECK (valid_from IS NULL OR valid_to IS NULL OR valid_from < valid_to);
+    END IF;
+END $$;
+
+-- Conflict detection pairs the edges of each relationship type
+CREATE INDEX IF NOT EXISTS idx_graph_edges_type_validity
+    ON graph_edges(relationship_type, valid_from, valid_to);
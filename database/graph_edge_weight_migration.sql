@@ -0,0 +1,14 @@
+-- Graph Edge Weight Migration
+-- Edges carry a weight from how often their relationship was seen: confidence times
+-- 1 + ln(occurrences), where occurrences counts the edges of the same type between
+-- entities with the same names. last_seen_at is the newest of those edges; traversals
+-- decay the weight by the time since then (GRAPH_EDGE_DECAY_HALF_LIFE). The maintenance
+-- daemon refreshes both; new edges start at weight 1 until the next refresh.
+
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS weight DOUBLE PRECISION NOT NULL DEFAULT 1;
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS occurrence_count INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMP WITH TIME ZONE;
+
+-- Existing edges were last seen when they were created
+UPDATE graph_edges SET last_seen_at = created_at WHERE last_seen_at IS NULL;
+ALTER TABLE graph_edges ALTER COLUMN last_seen_at SET DEFAULT NOW();
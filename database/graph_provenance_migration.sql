@@ -0,0 +1,33 @@
+-- Graph Provenance Migration
+-- Links graph nodes and edges to the text they were extracted from. Nodes record the
+-- character offsets of their mention in their chunk; edges record the chunk and span their
+-- relationship was read from. Both record the extraction's confidence, which graph
+-- queries can filter on. Edges stored before this take their chunk and confidence from
+-- their properties; their spans are found by entity name when evidence is requested.
+
+ALTER TABLE graph_nodes ADD COLUMN IF NOT EXISTS source_start INTEGER;
+ALTER TABLE graph_nodes ADD COLUMN IF NOT EXISTS source_end INTEGER;
+ALTER TABLE graph_nodes ADD COLUMN IF NOT EXISTS confidence DOUBLE PRECISION;
+
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS source_chunk_id UUID;
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS source_start INTEGER;
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS source_end INTEGER;
+ALTER TABLE graph_edges ADD COLUMN IF NOT EXISTS confidence DOUBLE PRECISION;
+
+UPDATE graph_nodes SET confidence = (properties->>'confidence')::double precision
+WHERE confidence IS NULL
+  AND properties->>'confidence' ~ '^[0-9]*\.?[0-9]+([eE][+-]?[0-9]+)?$';
+
+UPDATE graph_edges SET confidence = (properties->>'confidence')::double precision
+WHERE confidence IS NULL
+  AND properties->>'confidence' ~ '^[0-9]*\.?[0-9]+([eE][+-]?[0-9]+)?$';
+
+-- Co-occurrence was read from the chunk of both nodes, hierarchy from the child's chunk
+UPDATE graph_edges e SET source_chunk_id = t.chunk_id::uuid
+FROM graph_nodes t
+WHERE e.source_chunk_id IS NULL
+  AND t.id = e.target_node_id
+  AND t.chunk_id::text ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$';
+
+CREATE INDEX IF NOT EXISTS idx_graph_edges_source_chunk ON graph_edges(source_chunk_id);
+CREATE INDEX IF NOT EXISTS idx_graph_edges_confidence ON graph_edges(confidence);
@@ -0,0 +1,19 @@
+-- Graph Upsert Migration
+-- Nodes get entity_key, their entity name normalized the way the gateway compares
+-- entities: lower case, punctuation dropped, runs of whitespace, '-', '_' and '/' folded
+-- to one space, a leading "the " removed. Upserting graph nodes merges nodes with the same
+-- entity_key and entity_type, and upserting edges merges edges with the same source,
+-- target and relationship type, so re-extracting an entity no longer stores it again.
+-- Duplicates stored before stay until entity resolution merges them.
+
+ALTER TABLE graph_nodes ADD COLUMN IF NOT EXISTS entity_key TEXT GENERATED ALWAYS AS (
+    regexp_replace(
+        btrim(regexp_replace(
+            regexp_replace(lower(entity_name), '[^[:alnum:][:space:]/_-]', '', 'g'),
+            '[[:space:]/_-]+', ' ', 'g')),
+        '^the ', '')
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_graph_nodes_entity_key ON graph_nodes(entity_key, entity_type);
+CREATE INDEX IF NOT EXISTS idx_graph_edges_relationship
+    ON graph_edges(source_node_id, relationship_type, target_node_id);
@@ -0,0 +1,8 @@
+-- Hierarchy closure table maintained by the service
+-- The service now updates chunk_hierarchy in the same transaction as every chunk write,
+-- so the sync_chunk_hierarchy trigger only repeats that work. Managed instances such as
+-- Supabase never had it. After running this, rebuild the table once from parent pointers:
+--   ink-gateway rebuild-hierarchy
+
+DROP TRIGGER IF EXISTS trigger_sync_chunk_hierarchy ON chunks;
+DROP FUNCTION IF EXISTS sync_chunk_hierarchy();
@@ -0,0 +1,21 @@
+-- Import External IDs Migration
+-- Maps the pages and blocks of note imports to the chunks they were imported into, keyed by
+-- the import's source and the export's ID for the page or block. Upsert imports use it to
+-- update the chunks of an earlier import instead of creating duplicates. content_hash is the
+-- imported content, so unchanged pages and blocks are skipped, and chunk_version is the chunk
+-- version the import wrote, so chunks edited since then are reported as conflicts. Mappings
+-- of deleted chunks are removed with them.
+
+CREATE TABLE IF NOT EXISTS import_external_ids (
+    source        TEXT NOT NULL,
+    external_id   TEXT NOT NULL,
+    chunk_id      UUID NOT NULL REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    content_hash  TEXT NOT NULL,
+    chunk_version BIGINT NOT NULL,
+    imported_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (source, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_import_external_ids_chunk ON import_external_ids(chunk_id);
+
+COMMENT ON TABLE import_external_ids IS 'Chunks that note imports created, by import source and external ID';
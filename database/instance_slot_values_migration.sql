@@ -0,0 +1,154 @@
+-- Instance Slot Values Migration
+-- Indexes the slot values of template instances so instances can be queried by slot value
+-- like rows of a table. Each value is kept as text, and as a number or a date when it reads
+-- as one, with indexes per template and slot. Triggers on content_db.chunks and
+-- content_db.template_slots keep the rows in step as values are filled, slots are renamed
+-- or reordered and instances are deleted. Needs the template tables of
+-- reset_and_recreate.sql in the same database.
+
+CREATE TABLE IF NOT EXISTS content_db.instance_slot_values (
+    instance_chunk_id UUID NOT NULL REFERENCES content_db.chunks(id) ON DELETE CASCADE,
+    template_chunk_id UUID NOT NULL REFERENCES content_db.chunks(id) ON DELETE CASCADE,
+    slot_name         TEXT NOT NULL,
+    value_chunk_id    UUID NOT NULL REFERENCES content_db.chunks(id) ON DELETE CASCADE,
+    value_text        TEXT NOT NULL,
+    value_number      NUMERIC,
+    value_date        TIMESTAMPTZ,
+    PRIMARY KEY (instance_chunk_id, slot_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_instance_slot_values_text
+    ON content_db.instance_slot_values(template_chunk_id, slot_name, lower(value_text));
+
+CREATE INDEX IF NOT EXISTS idx_instance_slot_values_number
+    ON content_db.instance_slot_values(template_chunk_id, slot_name, value_number)
+    WHERE value_number IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_instance_slot_values_date
+    ON content_db.instance_slot_values(template_chunk_id, slot_name, value_date)
+    WHERE value_date IS NOT NULL;
+
+-- The number a slot value reads as, in the syntax slotNumberPattern accepts
+CREATE OR REPLACE FUNCTION content_db.slot_value_number(value TEXT)
+RETURNS NUMERIC AS $$
+BEGIN
+    IF value ~ '^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$' THEN
+        RETURN value::NUMERIC;
+    END IF;
+    RETURN NULL;
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- The time a slot value reads as, in the formats slotDateLayouts accepts; dates without a
+-- zone are UTC
+CREATE OR REPLACE FUNCTION content_db.slot_value_date(value TEXT)
+RETURNS TIMESTAMPTZ AS $$
+BEGIN
+    IF value ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}T[0-9]{2}:[0-9]{2}:[0-9]{2}(\.[0-9]+)?(Z|[+-][0-9]{2}:[0-9]{2})$' THEN
+        RETURN value::TIMESTAMPTZ;
+    END IF;
+    IF value ~ '^[0-9]{4}-[0-9]{2}-[0-9]{2}([T ][0-9]{2}:[0-9]{2}(:[0-9]{2})?)?$' THEN
+        RETURN value::TIMESTAMP AT TIME ZONE 'UTC';
+    END IF;
+    RETURN NULL;
+EXCEPTION WHEN OTHERS THEN
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- Rebuilds the rows of one instance. A slot value chunk belongs to the slot at its
+-- sequence number; when several share one, the oldest wins.
+CREATE OR REPLACE FUNCTION content_db.refresh_instance_slot_values(instance UUID)
+RETURNS VOID AS $$
+BEGIN
+    DELETE FROM content_db.instance_slot_values WHERE instance_chunk_id = instance;
+
+    INSERT INTO content_db.instance_slot_values
+        (instance_chunk_id, template_chunk_id, slot_name, value_chunk_id, value_text, value_number, value_date)
+    SELECT DISTINCT ON (slot.name)
+        i.id, i.template_chunk_id, slot.name, v.id, btrim(COALESCE(v.slot_value, v.content)),
+        content_db.slot_value_number(btrim(COALESCE(v.slot_value, v.content))),
+        content_db.slot_value_date(btrim(COALESCE(v.slot_value, v.content)))
+    FROM content_db.chunks i
+    JOIN LATERAL (
+        SELECT regexp_replace(s.content, '^#', '') AS name,
+               (row_number() OVER (ORDER BY ts.slot_order) - 1)::INTEGER AS position
+        FROM content_db.template_slots ts
+        JOIN content_db.chunks s ON s.id = ts.slot_chunk_id
+        WHERE ts.template_chunk_id = i.template_chunk_id
+    ) slot ON true
+    JOIN content_db.chunks v ON v.parent_chunk_id = i.id AND v.sequence_number = slot.position
+    WHERE i.id = instance
+      AND i.template_chunk_id IS NOT NULL
+      AND i.parent_chunk_id IS NULL
+      AND NOT COALESCE(i.is_template, false)
+      AND NOT COALESCE(i.is_slot, false)
+    ORDER BY slot.name, slot.position, v.created_at, v.id;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Rebuilds the rows of every instance of a template
+CREATE OR REPLACE FUNCTION content_db.refresh_template_slot_values(template UUID)
+RETURNS VOID AS $$
+BEGIN
+    PERFORM content_db.refresh_instance_slot_values(id)
+    FROM content_db.chunks
+    WHERE template_chunk_id = template AND parent_chunk_id IS NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Instances and slot value chunks refresh their instance; renamed slot chunks refresh the
+-- templates using them
+CREATE OR REPLACE FUNCTION content_db.sync_instance_slot_values()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' AND OLD.template_chunk_id IS NOT NULL AND NOT COALESCE(OLD.is_template, false) THEN
+        PERFORM content_db.refresh_instance_slot_values(COALESCE(OLD.parent_chunk_id, OLD.id));
+    END IF;
+    IF TG_OP <> 'DELETE' AND NEW.template_chunk_id IS NOT NULL AND NOT COALESCE(NEW.is_template, false) THEN
+        PERFORM content_db.refresh_instance_slot_values(COALESCE(NEW.parent_chunk_id, NEW.id));
+    END IF;
+    IF TG_OP = 'UPDATE' AND COALESCE(NEW.is_slot, false) AND NEW.content IS DISTINCT FROM OLD.content THEN
+        PERFORM content_db.refresh_template_slot_values(ts.template_chunk_id)
+        FROM content_db.template_slots ts
+        WHERE ts.slot_chunk_id = NEW.id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_sync_instance_slot_values ON content_db.chunks;
+CREATE TRIGGER trigger_sync_instance_slot_values
+    AFTER INSERT OR UPDATE OR DELETE ON content_db.chunks
+    FOR EACH ROW EXECUTE FUNCTION content_db.sync_instance_slot_values();
+
+-- Added, removed and reordered slots refresh the template's instances
+CREATE OR REPLACE FUNCTION content_db.sync_template_slot_values()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP <> 'INSERT' THEN
+        PERFORM content_db.refresh_template_slot_values(OLD.template_chunk_id);
+    END IF;
+    IF TG_OP <> 'DELETE' AND (TG_OP = 'INSERT' OR NEW.template_chunk_id IS DISTINCT FROM OLD.template_chunk_id) THEN
+        PERFORM content_db.refresh_template_slot_values(NEW.template_chunk_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_sync_template_slot_values ON content_db.template_slots;
+CREATE TRIGGER trigger_sync_template_slot_values
+    AFTER INSERT OR UPDATE OR DELETE ON content_db.template_slots
+    FOR EACH ROW EXECUTE FUNCTION content_db.sync_template_slot_values();
+
+-- Index the instances that already exist
+SELECT content_db.refresh_instance_slot_values(id)
+FROM content_db.chunks
+WHERE template_chunk_id IS NOT NULL
+  AND parent_chunk_id IS NULL
+  AND NOT COALESCE(is_template, false)
+  AND NOT COALESCE(is_slot, false);
+
+COMMENT ON TABLE content_db.instance_slot_values IS 'Slot values of template instances as text, numbers and dates, for querying instances by slot';
@@ -0,0 +1,8 @@
+-- Journal Pages Migration
+-- Indexes daily note pages by owner and date. Journal pages keep both in metadata
+-- (journal_owner, journal_date as YYYY-MM-DD), so finding today's page and listing the days
+-- of a calendar range are index lookups instead of scans of every page.
+
+CREATE INDEX IF NOT EXISTS idx_chunks_journal_day
+    ON chunks ((metadata->>'journal_owner'), (metadata->>'journal_date'))
+    WHERE is_page = true AND metadata ? 'journal_date';
@@ -0,0 +1,98 @@
+-- Language-Aware Full-Text Search Migration
+-- Stores the detected language of every chunk and indexes contents with a text search
+-- configuration suited to it: english for Latin-script text and a Chinese word segmenter
+-- for Chinese, which PostgreSQL's built-in configurations cannot split into words.
+--
+-- Chinese segmentation needs the zhparser or pg_jieba extension installed on the server.
+-- Without either, Chinese chunks fall back to the simple configuration; rerun this
+-- migration after installing one to reindex them.
+
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS lang TEXT;
+ALTER TABLE chunks ADD COLUMN IF NOT EXISTS search_vector TSVECTOR;
+
+COMMENT ON COLUMN chunks.lang IS 'Detected content language: zh, en or und';
+COMMENT ON COLUMN chunks.search_vector IS 'Full-text vector built with the text search configuration for lang';
+
+-- Create a "chinese" text search configuration from whichever segmenter is available
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'chinese') THEN
+        RETURN;
+    END IF;
+
+    IF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'zhparser') THEN
+        CREATE EXTENSION IF NOT EXISTS zhparser;
+        CREATE TEXT SEARCH CONFIGURATION chinese (PARSER = zhparser);
+        -- nouns, verbs, adjectives, idioms, exclamations, abbreviations, English words
+        ALTER TEXT SEARCH CONFIGURATION chinese ADD MAPPING FOR n, v, a, i, e, l, j, eng WITH simple;
+    ELSIF EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'pg_jieba') THEN
+        CREATE EXTENSION IF NOT EXISTS pg_jieba;
+        CREATE TEXT SEARCH CONFIGURATION chinese (COPY = jiebacfg);
+    ELSE
+        RAISE NOTICE 'zhparser and pg_jieba are not installed; Chinese chunks use the simple configuration';
+    END IF;
+END $$;
+
+-- chunk_detect_lang classifies text by script. A Han character carries about as much
+-- meaning as a short word, so each counts as three Latin letters. Ingestion detects the
+-- language in the application with the same rule; this covers rows written without it.
+CREATE OR REPLACE FUNCTION chunk_detect_lang(contents TEXT) RETURNS TEXT AS $$
+DECLARE
+    han INTEGER := length(regexp_replace(COALESCE(contents, ''),
+        '[^\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff\U00020000-\U0002ffff]', '', 'g'));
+    latin INTEGER := length(regexp_replace(COALESCE(contents, ''), '[^A-Za-z]', '', 'g'));
+BEGIN
+    IF han = 0 AND latin = 0 THEN
+        RETURN 'und';
+    ELSIF han * 3 >= latin THEN
+        RETURN 'zh';
+    END IF;
+    RETURN 'en';
+END;
+$$ LANGUAGE plpgsql IMMUTABLE;
+
+-- chunk_search_config maps a language to its text search configuration
+CREATE OR REPLACE FUNCTION chunk_search_config(lang TEXT) RETURNS regconfig AS $$
+    SELECT COALESCE(
+        (SELECT c.oid::regconfig FROM pg_ts_config c
+         WHERE c.cfgname = CASE lang WHEN 'zh' THEN 'chinese' WHEN 'en' THEN 'english' END
+         LIMIT 1),
+        'simple'::regconfig)
+$$ LANGUAGE sql STABLE;
+
+-- chunk_search_query parses a query with its language's configuration, also matching the
+-- unstemmed words so queries find chunks indexed in another language
+CREATE OR REPLACE FUNCTION chunk_search_query(lang TEXT, query TEXT) RETURNS tsquery AS $$
+    SELECT plainto_tsquery(chunk_search_config(lang), query) || plainto_tsquery('simple', query)
+$$ LANGUAGE sql STABLE;
+
+CREATE OR REPLACE FUNCTION chunks_update_search_vector() RETURNS TRIGGER AS $$
+BEGIN
+    -- Contents changed by a writer that did not detect the language: detect it again
+    IF TG_OP = 'UPDATE' AND NEW.contents IS DISTINCT FROM OLD.contents
+       AND NEW.lang IS NOT DISTINCT FROM OLD.lang THEN
+        NEW.lang := NULL;
+    END IF;
+    IF NEW.lang IS NULL OR NEW.lang = '' THEN
+        NEW.lang := chunk_detect_lang(NEW.contents);
+    END IF;
+
+    NEW.search_vector := to_tsvector(chunk_search_config(NEW.lang), COALESCE(NEW.contents, ''))
+        || to_tsvector('simple', COALESCE(NEW.contents, ''));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_chunks_search_vector ON chunks;
+CREATE TRIGGER trigger_chunks_search_vector
+    BEFORE INSERT OR UPDATE OF contents, lang ON chunks
+    FOR EACH ROW EXECUTE FUNCTION chunks_update_search_vector();
+
+-- Backfill existing chunks; rerunning reindexes them with the current configurations
+UPDATE chunks SET lang = COALESCE(lang, chunk_detect_lang(contents));
+
+CREATE INDEX IF NOT EXISTS idx_chunks_search_vector ON chunks USING gin(search_vector);
+CREATE INDEX IF NOT EXISTS idx_chunks_lang ON chunks(lang);
+
+-- The english-only expression index is superseded by search_vector
+DROP INDEX IF EXISTS idx_chunks_contents_fts;
@@ -0,0 +1,42 @@
+-- Metadata Patch Migration
+-- jsonb_patch_metadata applies a JSON array of metadata operations, in order, to a chunk's
+-- metadata, so PATCH /api/v1/chunks/{id}/metadata changes nested keys in one UPDATE:
+--   {"op": "set",    "path": ["a", "b"], "value": 1}   stores value at the path
+--   {"op": "delete", "path": ["a", "b"]}               removes the key at the path
+--   {"op": "merge",  "path": ["a"], "value": {"c": 2}} merges value's keys into the object
+-- jsonb_set only creates the last key of a path, so set and merge first turn missing or
+-- non-object parents into empty objects.
+
+CREATE OR REPLACE FUNCTION public.jsonb_patch_metadata(target jsonb, ops jsonb)
+RETURNS jsonb
+LANGUAGE plpgsql
+IMMUTABLE
+AS $$
+DECLARE
+    op jsonb;
+    op_path text[];
+    op_value jsonb;
+BEGIN
+    target := COALESCE(target, '{}'::jsonb);
+    FOR op IN SELECT value FROM jsonb_array_elements(ops) LOOP
+        op_path := ARRAY(SELECT jsonb_array_elements_text(op->'path'));
+        op_value := COALESCE(op->'value', 'null'::jsonb);
+
+        IF op->>'op' = 'delete' THEN
+            target := target #- op_path;
+            CONTINUE;
+        END IF;
+
+        IF op->>'op' = 'merge' AND jsonb_typeof(target #> op_path) = 'object' THEN
+            op_value := (target #> op_path) || op_value;
+        END IF;
+        FOR i IN 1 .. array_length(op_path, 1) - 1 LOOP
+            IF jsonb_typeof(target #> op_path[1:i]) IS DISTINCT FROM 'object' THEN
+                target := jsonb_set(target, op_path[1:i], '{}'::jsonb, true);
+            END IF;
+        END LOOP;
+        target := jsonb_set(target, op_path, op_value, true);
+    END LOOP;
+    RETURN target;
+END;
+$$;
@@ -0,0 +1,305 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+//go:embed unified_chunk_schema.sql *_migration.sql reset_and_recreate.sql
+var schemaFiles embed.FS
+
+// baseSchema creates the chunks tables; it drops them first, so it only runs on an empty
+// database or with DropExisting
+const baseSchema = "unified_chunk_schema.sql"
+
+// schemaMigration is one script of the migration chain
+type schemaMigration struct {
+	name string
+	// requires is a query returning whether the migration's prerequisites exist; the
+	// migration is skipped, and retried on the next run, until they do
+	requires string
+}
+
+// schemaMigrations is the migration chain in the order database/README.md lists it
+var schemaMigrations = []schemaMigration{
+	{name: "chunk_version_migration.sql"},
+	{name: "chunk_refs_migration.sql"},
+	{name: "tag_hierarchy_migration.sql"},
+	{name: "language_search_migration.sql"},
+	{name: "content_encryption_migration.sql"},
+	{name: "hierarchy_maintenance_migration.sql"},
+	{name: "chunk_trash_migration.sql"},
+	{name: "search_cache_swr_migration.sql"},
+	{name: "multimodal_embeddings_migration.sql", requires: requireExtension("vector")},
+	{name: "embedding_sync_migration.sql", requires: requireExtension("vector")},
+	{name: "change_feed_migration.sql"},
+	{name: "chunk_sync_migration.sql"},
+	{name: "cache_warming_migration.sql"},
+	{name: "api_tokens_migration.sql"},
+	{name: "retention_migration.sql"},
+	{name: "graph_edge_weight_migration.sql", requires: requireTable("graph_edges")},
+	{name: "page_acl_migration.sql"},
+	{name: "query_suggestions_migration.sql", requires: requireExtension("pg_trgm")},
+	{name: "chunk_stats_migration.sql"},
+	{name: "attachment_dedup_migration.sql"},
+	{name: "chunk_summaries_migration.sql"},
+	{name: "embedding_usage_migration.sql"},
+	{name: "import_external_ids_migration.sql"},
+	{name: "chunk_history_migration.sql"},
+	{name: "page_publication_migration.sql"},
+	{name: "chunk_lock_migration.sql"},
+	{name: "email_messages_migration.sql"},
+	{name: "journal_pages_migration.sql"},
+	{name: "chunk_tasks_migration.sql"},
+	{name: "instance_slot_values_migration.sql", requires: requireTable("content_db.template_slots")},
+	{name: "graph_provenance_migration.sql", requires: requireTable("graph_edges")},
+	{name: "graph_edge_validity_migration.sql", requires: requireTable("graph_edges")},
+	{name: "graph_upsert_migration.sql", requires: requireTable("graph_nodes")},
+	{name: "similarity_filter_migration.sql", requires: requireExtension("vector")},
+	{name: "metadata_patch_migration.sql"},
+	{name: "chunk_history_encryption_migration.sql", requires: requireTable("chunk_history")},
+	{name: "chunk_refs_backfill_migration.sql", requires: requireTable("chunk_refs")},
+}
+
+func requireTable(name string) string {
+	return fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", name)
+}
+
+// requireExtension is met when the server can install the extension
+func requireExtension(name string) string {
+	return fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = '%s')", name)
+}
+
+// createdTablePattern finds the tables a migration creates, which DropExisting removes
+var createdTablePattern = regexp.MustCompile(`(?i)CREATE TABLE IF NOT EXISTS\s+([a-z_.]+)`)
+
+// MigrateOptions controls a Migrate run
+type MigrateOptions struct {
+	// DropExisting drops the chunks tables and every table the migrations created, then
+	// recreates them. All chunk data is lost.
+	DropExisting bool
+	// Baseline records every migration as applied without running it, for databases set
+	// up by hand from the scripts
+	Baseline bool
+}
+
+// MigrationResult reports a Migrate run
+type MigrationResult struct {
+	CreatedBase bool     // the base schema was (re)created
+	Applied     []string // migrations run in this call
+	Skipped     []string // migrations whose prerequisites are missing
+	Baselined   []string // migrations recorded without running
+	Duration    time.Duration
+}
+
+// Migrator brings a database to the current schema by running the base schema and the
+// migrations that have not run yet, recording them in schema_migrations
+type Migrator struct {
+	db *sql.DB
+}
+
+// NewMigrator creates a migrator for a direct connection to the service database
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Migrate applies the base schema if needed and every pending migration, each in its own
+// transaction
+func (m *Migrator) Migrate(ctx context.Context, opts MigrateOptions) (*MigrationResult, error) {
+	start := time.Now()
+	result := &MigrationResult{}
+
+	if _, err := m.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			name       TEXT PRIMARY KEY,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	if opts.DropExisting {
+		if err := m.dropExisting(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var hasChunks bool
+	if err := m.db.QueryRowContext(ctx, requireTable("chunks")).Scan(&hasChunks); err != nil {
+		return nil, fmt.Errorf("failed to check for the chunks table: %w", err)
+	}
+
+	if opts.Baseline {
+		if !hasChunks {
+			return nil, fmt.Errorf("there is no chunks table to baseline; run without --baseline to create it")
+		}
+		for _, name := range append([]string{baseSchema}, migrationNames()...) {
+			if applied[name] {
+				continue
+			}
+			if err := m.record(ctx, m.db, name); err != nil {
+				return nil, err
+			}
+			result.Baselined = append(result.Baselined, name)
+		}
+		result.Duration = time.Since(start)
+		return result, nil
+	}
+
+	if !applied[baseSchema] {
+		if hasChunks {
+			return nil, fmt.Errorf("chunks exists but schema_migrations has no record of it; pass --baseline to record the existing schema as migrated or --drop-existing to recreate it")
+		}
+		if err := m.run(ctx, baseSchema); err != nil {
+			return nil, err
+		}
+		result.CreatedBase = true
+	}
+
+	for _, migration := range schemaMigrations {
+		if applied[migration.name] {
+			continue
+		}
+		if migration.requires != "" {
+			var ok bool
+			if err := m.db.QueryRowContext(ctx, migration.requires).Scan(&ok); err != nil {
+				return result, fmt.Errorf("failed to check prerequisites of %s: %w", migration.name, err)
+			}
+			if !ok {
+				result.Skipped = append(result.Skipped, migration.name)
+				continue
+			}
+		}
+		if err := m.run(ctx, migration.name); err != nil {
+			return result, err
+		}
+		result.Applied = append(result.Applied, migration.name)
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// dropExisting removes the tables of the base schema and of every migration, and forgets
+// which migrations ran
+func (m *Migrator) dropExisting(ctx context.Context) error {
+	var tables []string
+	for i := len(schemaMigrations) - 1; i >= 0; i-- {
+		script, err := schemaFiles.ReadFile(schemaMigrations[i].name)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", schemaMigrations[i].name, err)
+		}
+		for _, match := range createdTablePattern.FindAllStringSubmatch(string(script), -1) {
+			tables = append(tables, match[1])
+		}
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, table := range append(tables, "chunk_search_cache", "chunk_hierarchy", "chunk_tags", "chunks") {
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+table+" CASCADE"); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", table, err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return fmt.Errorf("failed to reset schema_migrations: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit dropped tables: %w", err)
+	}
+	return nil
+}
+
+// run executes one script and records it in the same transaction
+func (m *Migrator) run(ctx context.Context, name string) error {
+	script, err := schemaFiles.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if name == "multimodal_embeddings_migration.sql" {
+		// The migration adds vector columns but leaves the extension to the operator
+		if _, err := tx.ExecContext(ctx, "CREATE EXTENSION IF NOT EXISTS vector"); err != nil {
+			return fmt.Errorf("failed to create the vector extension: %w", err)
+		}
+	}
+	// Without arguments the script runs over the simple protocol, which allows several
+	// statements
+	if _, err := tx.ExecContext(ctx, string(script)); err != nil {
+		return fmt.Errorf("failed to apply %s: %w", name, err)
+	}
+	if err := m.record(ctx, tx, name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit %s: %w", name, err)
+	}
+	return nil
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrator) record(ctx context.Context, q execer, name string) error {
+	if _, err := q.ExecContext(ctx, "INSERT INTO schema_migrations (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", name); err != nil {
+		return fmt.Errorf("failed to record %s: %w", name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[string]bool, error) {
+	rows, err := m.db.QueryContext(ctx, "SELECT name FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan schema migration: %w", err)
+		}
+		applied[name] = true
+	}
+	return applied, rows.Err()
+}
+
+func migrationNames() []string {
+	names := make([]string, len(schemaMigrations))
+	for i, migration := range schemaMigrations {
+		names[i] = migration.name
+	}
+	return names
+}
+
+// ResetSupabaseSchema runs database/reset_and_recreate.sql on the Supabase database,
+// dropping and recreating the content_db, vector_db and graph_db schemas
+func ResetSupabaseSchema(ctx context.Context, db *sql.DB) error {
+	script, err := schemaFiles.ReadFile("reset_and_recreate.sql")
+	if err != nil {
+		return fmt.Errorf("failed to read reset_and_recreate.sql: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, string(script)); err != nil {
+		return fmt.Errorf("failed to recreate the Supabase schema: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaMigrationChain(t *testing.T) {
+	listed := map[string]bool{}
+	for _, migration := range schemaMigrations {
+		assert.False(t, listed[migration.name], "duplicate migration %s", migration.name)
+		listed[migration.name] = true
+		_, err := fs.Stat(schemaFiles, migration.name)
+		assert.NoError(t, err, "%s is embedded", migration.name)
+	}
+
+	// Every migration in the directory is part of the chain, except those for other schemas
+	notInChain := map[string]bool{
+		"sibling_sort_key_migration.sql": true, // legacy content_db.chunks on Supabase
+		"test_multimodal_migration.sql":  true, // checks, not a migration
+	}
+	files, err := filepath.Glob("*_migration.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, files)
+	for _, file := range files {
+		assert.True(t, listed[file] || notInChain[file], "%s is missing from schemaMigrations", file)
+	}
+
+	_, err = os.Stat(baseSchema)
+	assert.NoError(t, err)
+	script, err := schemaFiles.ReadFile("chunk_sync_migration.sql")
+	require.NoError(t, err)
+	var tables []string
+	for _, match := range createdTablePattern.FindAllStringSubmatch(string(script), -1) {
+		tables = append(tables, match[1])
+	}
+	assert.Equal(t, []string{"chunk_sync_log", "chunk_sync_ops", "chunk_sync_conflicts"}, tables)
+}
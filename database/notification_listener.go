@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// NotificationListener receives PostgreSQL NOTIFY payloads on a single channel over a
+// dedicated connection, reconnecting with exponential backoff when the connection drops
+type NotificationListener struct {
+	dsn        string
+	channel    string
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	connected  atomic.Bool
+}
+
+// NewNotificationListener creates a listener for channel on the database at dsn
+func NewNotificationListener(dsn, channel string) *NotificationListener {
+	return &NotificationListener{
+		dsn:        dsn,
+		channel:    channel,
+		minBackoff: time.Second,
+		maxBackoff: 30 * time.Second,
+	}
+}
+
+// Channel returns the channel being listened on
+func (l *NotificationListener) Channel() string {
+	return l.channel
+}
+
+// Connected reports whether the listener currently holds a listening connection
+func (l *NotificationListener) Connected() bool {
+	return l.connected.Load()
+}
+
+// Listen blocks until ctx is cancelled, calling handle with each notification payload.
+// connected is called after every successful LISTEN; reconnect is true when it follows
+// a dropped connection, during which notifications may have been missed.
+func (l *NotificationListener) Listen(ctx context.Context, handle func(payload string), connected func(reconnect bool)) error {
+	backoff := l.minBackoff
+	reconnect := false
+
+	for {
+		err := l.listenOnce(ctx, handle, func() {
+			backoff = l.minBackoff
+			if connected != nil {
+				connected(reconnect)
+			}
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		log.Printf("Warning: LISTEN %s interrupted: %v; reconnecting in %v", l.channel, err, backoff)
+		reconnect = true
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > l.maxBackoff {
+			backoff = l.maxBackoff
+		}
+	}
+}
+
+// listenOnce holds one connection until it fails or ctx is cancelled
+func (l *NotificationListener) listenOnce(ctx context.Context, handle func(payload string), connected func()) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", l.channel, err)
+	}
+	l.connected.Store(true)
+	defer l.connected.Store(false)
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to wait for notification: %w", err)
+		}
+		handle(notification.Payload)
+	}
+}
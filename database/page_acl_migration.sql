@@ -0,0 +1,18 @@
+-- Page ACL Migration
+-- Optional access control lists restricting a page and its blocks to an owner and the users
+-- and groups it is shared with. Subjects are API token names or JWT subjects; groups come
+-- from the JWT groups claim. Pages without a row stay open to their workspace. The gateway
+-- enforces ACLs when AUTH_PAGE_ACLS_ENABLED=true.
+
+CREATE TABLE IF NOT EXISTS page_acls (
+    page_chunk_id UUID PRIMARY KEY REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    owner         TEXT NOT NULL,
+    shared_users  TEXT[] NOT NULL DEFAULT '{}',
+    shared_groups TEXT[] NOT NULL DEFAULT '{}',
+    public_read   BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_by    TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE page_acls IS 'Owners, shared users and groups, and public read access of restricted pages';
@@ -0,0 +1,24 @@
+-- Page Publication Migration
+-- Pages published for reading without authentication. Each publication is identified by a
+-- random token; the public URL carries the token and its HMAC signature, so only the token
+-- is stored. A page has at most one live publication; revoking it sets revoked_at, and
+-- publishing again issues a new token. Views of published pages are counted.
+
+CREATE TABLE IF NOT EXISTS page_publications (
+    token          TEXT PRIMARY KEY,
+    page_chunk_id  UUID NOT NULL REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    workspace_id   TEXT NOT NULL DEFAULT 'default',
+    published_by   TEXT NOT NULL DEFAULT '',
+    published_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at     TIMESTAMPTZ,
+    view_count     BIGINT NOT NULL DEFAULT 0,
+    last_viewed_at TIMESTAMPTZ
+);
+
+-- Only one publication of a page is live
+CREATE UNIQUE INDEX IF NOT EXISTS idx_page_publications_live
+    ON page_publications(page_chunk_id) WHERE revoked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_page_publications_workspace
+    ON page_publications(workspace_id, published_at DESC);
+
+COMMENT ON TABLE page_publications IS 'Pages served read-only without authentication at signed URLs';
@@ -0,0 +1,23 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// SQLPoolConfig holds database/sql connection pool settings
+type SQLPoolConfig struct {
+	// MaxOpenConns caps open connections; 0 means unlimited
+	MaxOpenConns int
+	// MaxIdleConns is how many idle connections are kept for reuse
+	MaxIdleConns int
+	// ConnMaxLifetime closes connections after this age; 0 keeps them forever
+	ConnMaxLifetime time.Duration
+}
+
+// ConfigurePool applies pool settings to a database/sql handle
+func ConfigurePool(db *sql.DB, cfg SQLPoolConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+}
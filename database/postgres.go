@@ -27,6 +27,10 @@ type PostgresConfig struct {
 	MaxConnLife  time.Duration
 	MaxConnIdle  time.Duration
 	HealthCheck  time.Duration
+
+	// StatementCacheCapacity is how many prepared statements each connection caches;
+	// 0 disables the cache
+	StatementCacheCapacity int
 }
 
 // DefaultPostgresConfig returns sensible defaults
@@ -43,6 +47,8 @@ func DefaultPostgresConfig() *PostgresConfig {
 		MaxConnLife:  time.Hour,
 		MaxConnIdle:  30 * time.Minute,
 		HealthCheck:  time.Minute,
+
+		StatementCacheCapacity: 512,
 	}
 }
 
@@ -55,6 +61,15 @@ func (c *PostgresConfig) BuildConnectionString() string {
 	)
 }
 
+// BuildDirectConnectionString builds a connection string for a single connection,
+// without the pgxpool-specific pool parameters
+func (c *PostgresConfig) BuildDirectConnectionString() string {
+	return fmt.Sprintf(
+		"host=%s port=%d dbname=%s user=%s password=%s sslmode=%s search_path=public",
+		c.Host, c.Port, c.Database, c.User, c.Password, c.SSLMode,
+	)
+}
+
 // PostgresService provides PostgreSQL database operations
 type PostgresService struct {
 	pool *pgxpool.Pool
@@ -68,9 +83,9 @@ func NewPostgresService(cfg *PostgresConfig) (*PostgresService, error) {
 	}
 
 	// Build pool config
-	poolConfig, err := pgxpool.ParseConfig(cfg.BuildConnectionString())
+	poolConfig, err := NewPoolConfig(cfg.BuildConnectionString(), cfg.StatementCacheCapacity)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+		return nil, err
 	}
 
 		// Configure connection pool
@@ -82,12 +97,6 @@ func NewPostgresService(cfg *PostgresConfig) (*PostgresService, error) {
 		poolConfig.HealthCheckPeriod = cfg.HealthCheck
 	}
 
-	// Set AfterConnect to ensure search_path is set for each connection
-	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
-		_, err := conn.Exec(ctx, "SET search_path TO public")
-		return err
-	}
-
 	// Create connection pool
 	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
@@ -125,19 +134,57 @@ func (s *PostgresService) Pool() *pgxpool.Pool {
 // with code that uses database/sql instead of pgx directly
 func (s *PostgresService) StdlibDB() (*sql.DB, error) {
 	// Build connection string without pool parameters (which are pgxpool-specific)
-	connStr := fmt.Sprintf(
-		"host=%s port=%d dbname=%s user=%s password=%s sslmode=%s search_path=public",
-		s.cfg.Host, s.cfg.Port, s.cfg.Database, s.cfg.User, s.cfg.Password, s.cfg.SSLMode,
-	)
-
-	connConfig, err := pgx.ParseConfig(connStr)
+	connConfig, err := pgx.ParseConfig(s.cfg.BuildDirectConnectionString())
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse connection config: %w", err)
 	}
 
+	ConfigureStatementCache(connConfig, s.cfg.StatementCacheCapacity)
+
 	return stdlib.OpenDB(*connConfig), nil
 }
 
+// ConfigureStatementCache sizes a connection's prepared statement cache. Without a cache,
+// statements are described and executed unnamed, which also works through connection
+// poolers in transaction mode.
+func ConfigureStatementCache(connConfig *pgx.ConnConfig, capacity int) {
+	connConfig.StatementCacheCapacity = capacity
+	if capacity == 0 {
+		connConfig.DefaultQueryExecMode = pgx.QueryExecModeDescribeExec
+	}
+}
+
+// NewPoolConfig parses a PostgreSQL DSN into the pool configuration shared by every pgx
+// pool: the statement cache is sized and each connection gets the public search_path
+func NewPoolConfig(dsn string, statementCacheCapacity int) (*pgxpool.Config, error) {
+	poolConfig, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection string: %w", err)
+	}
+	ConfigureStatementCache(poolConfig.ConnConfig, statementCacheCapacity)
+
+	// Set AfterConnect to ensure search_path is set for each connection
+	poolConfig.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		_, err := conn.Exec(ctx, "SET search_path TO public")
+		return err
+	}
+	return poolConfig, nil
+}
+
+// OpenPool opens a pgx connection pool for a PostgreSQL DSN
+func OpenPool(ctx context.Context, dsn string, statementCacheCapacity int) (*pgxpool.Pool, error) {
+	poolConfig, err := NewPoolConfig(dsn, statementCacheCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+	return pool, nil
+}
+
 // Ping checks database connectivity
 func (s *PostgresService) Ping(ctx context.Context) error {
 	return s.pool.Ping(ctx)
@@ -0,0 +1,33 @@
+-- Query Suggestions Migration
+-- Logs search queries per workspace with how often they were run, so the search box can
+-- offer prefix completions, typo-tolerant corrections (pg_trgm similarity) and popular
+-- queries. Queries are stored normalized (lowercase, single spaces) as the key, with the
+-- most recent spelling kept for display.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE TABLE IF NOT EXISTS search_query_log (
+    workspace_id TEXT NOT NULL DEFAULT 'default',
+    normalized_query TEXT NOT NULL,
+    display_query TEXT NOT NULL,
+    frequency BIGINT NOT NULL DEFAULT 1,
+    last_result_count INTEGER NOT NULL DEFAULT 0,
+    first_searched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_searched_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, normalized_query)
+);
+
+-- Prefix completion
+CREATE INDEX IF NOT EXISTS idx_search_query_log_prefix
+    ON search_query_log (workspace_id, normalized_query text_pattern_ops);
+
+-- Typo-tolerant suggestions
+CREATE INDEX IF NOT EXISTS idx_search_query_log_trgm
+    ON search_query_log USING GIN (normalized_query gin_trgm_ops);
+
+-- Popular queries
+CREATE INDEX IF NOT EXISTS idx_search_query_log_popular
+    ON search_query_log (workspace_id, frequency DESC, last_searched_at DESC);
+
+COMMENT ON TABLE search_query_log IS 'Search queries per workspace, used for autocomplete and suggestions';
+COMMENT ON COLUMN search_query_log.last_result_count IS 'Results returned the last time the query ran; queries that found nothing are not suggested';
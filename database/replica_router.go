@@ -0,0 +1,225 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// ReplicaConfig holds read replica routing configuration
+type ReplicaConfig struct {
+	// MaxLag is the default replication lag a read may tolerate before falling back to the primary
+	MaxLag time.Duration
+	// LagCheckInterval controls how often replica lag is measured
+	LagCheckInterval time.Duration
+}
+
+// replica tracks a read replica and its last measured state
+type replica struct {
+	db      *sql.DB
+	name    string
+	lag     atomic.Int64 // nanoseconds
+	healthy atomic.Bool
+	// replayedThrough is the primary time (unix nanos) up to which the replica is known to be caught up
+	replayedThrough atomic.Int64
+}
+
+// ReplicaRouter routes mutations to the primary and reads to replicas that are fresh enough
+type ReplicaRouter struct {
+	primary  *sql.DB
+	replicas []*replica
+	cfg      ReplicaConfig
+	next     atomic.Uint64
+	// lastWrite is the time (unix nanos) of the most recent mutation made through this process
+	lastWrite atomic.Int64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewReplicaRouter creates a router over an open primary and zero or more replicas.
+// Replicas start healthy with zero lag until the first lag check runs.
+func NewReplicaRouter(primary *sql.DB, replicas []*sql.DB, cfg ReplicaConfig) *ReplicaRouter {
+	if cfg.MaxLag <= 0 {
+		cfg.MaxLag = 5 * time.Second
+	}
+	if cfg.LagCheckInterval <= 0 {
+		cfg.LagCheckInterval = 10 * time.Second
+	}
+
+	router := &ReplicaRouter{
+		primary: primary,
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+	}
+	for i, db := range replicas {
+		r := &replica{db: db, name: fmt.Sprintf("replica-%d", i)}
+		r.healthy.Store(true)
+		router.replicas = append(router.replicas, r)
+	}
+
+	return router
+}
+
+// OpenStdlibDB opens a database/sql handle for a PostgreSQL DSN, caching up to
+// statementCacheCapacity prepared statements per connection
+func OpenStdlibDB(dsn string, statementCacheCapacity int) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse connection config: %w", err)
+	}
+	ConfigureStatementCache(connConfig, statementCacheCapacity)
+
+	return stdlib.OpenDB(*connConfig), nil
+}
+
+type readPreferenceKey struct{}
+
+// WithPrimaryRead marks a context so reads made with it go to the primary.
+// Use it for read-your-writes flows that cannot tolerate any replication lag.
+func WithPrimaryRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, time.Duration(0))
+}
+
+// WithMaxStaleness marks a context so reads only use replicas lagging at most maxLag
+func WithMaxStaleness(ctx context.Context, maxLag time.Duration) context.Context {
+	return context.WithValue(ctx, readPreferenceKey{}, maxLag)
+}
+
+// Primary returns the handle used for mutations
+func (r *ReplicaRouter) Primary() *sql.DB {
+	return r.primary
+}
+
+// MarkWrite records that a mutation just committed. Until a replica is known to have replayed
+// past this point, reads fall back to the primary so that freshly invalidated caches are not
+// refilled with stale rows.
+func (r *ReplicaRouter) MarkWrite() {
+	r.lastWrite.Store(time.Now().UnixNano())
+}
+
+// Reader returns a handle for a read, falling back to the primary when no replica is fresh enough
+func (r *ReplicaRouter) Reader(ctx context.Context) *sql.DB {
+	if len(r.replicas) == 0 {
+		return r.primary
+	}
+
+	maxLag := r.cfg.MaxLag
+	if v, ok := ctx.Value(readPreferenceKey{}).(time.Duration); ok {
+		if v <= 0 {
+			return r.primary
+		}
+		maxLag = v
+	}
+
+	// Round-robin over replicas, skipping unhealthy, lagging, or not yet caught up ones
+	lastWrite := r.lastWrite.Load()
+	start := r.next.Add(1)
+	for i := 0; i < len(r.replicas); i++ {
+		candidate := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if !candidate.healthy.Load() || time.Duration(candidate.lag.Load()) > maxLag {
+			continue
+		}
+		if candidate.replayedThrough.Load() < lastWrite {
+			continue
+		}
+		return candidate.db
+	}
+
+	return r.primary
+}
+
+// Start begins periodic replica lag checks until ctx is cancelled or Close is called
+func (r *ReplicaRouter) Start(ctx context.Context) {
+	if len(r.replicas) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.cfg.LagCheckInterval)
+		defer ticker.Stop()
+
+		r.CheckLag(ctx)
+		for {
+			select {
+			case <-ticker.C:
+				r.CheckLag(ctx)
+			case <-ctx.Done():
+				return
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// CheckLag measures replication lag on every replica
+func (r *ReplicaRouter) CheckLag(ctx context.Context) {
+	for _, rep := range r.replicas {
+		checkedAt := time.Now()
+		checkCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		var lagSeconds float64
+		// pg_last_xact_replay_timestamp is NULL on a primary or an idle, fully caught-up replica
+		err := rep.db.QueryRowContext(checkCtx, `
+			SELECT CASE
+				WHEN NOT pg_is_in_recovery() THEN 0
+				WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+				ELSE COALESCE(EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()), 0)
+			END`).Scan(&lagSeconds)
+		cancel()
+
+		if err != nil {
+			if rep.healthy.Swap(false) {
+				log.Printf("Warning: read replica %s unavailable, routing reads to primary: %v", rep.name, err)
+			}
+			continue
+		}
+
+		lag := time.Duration(lagSeconds * float64(time.Second))
+		rep.lag.Store(int64(lag))
+		rep.replayedThrough.Store(checkedAt.Add(-lag).UnixNano())
+		if !rep.healthy.Swap(true) {
+			log.Printf("Read replica %s is available again", rep.name)
+		}
+	}
+}
+
+// ReplicaStatus reports the routing state of a replica
+type ReplicaStatus struct {
+	Name    string        `json:"name"`
+	Healthy bool          `json:"healthy"`
+	Lag     time.Duration `json:"lag"`
+}
+
+// Status returns the last measured state of every replica
+func (r *ReplicaRouter) Status() []ReplicaStatus {
+	statuses := make([]ReplicaStatus, 0, len(r.replicas))
+	for _, rep := range r.replicas {
+		statuses = append(statuses, ReplicaStatus{
+			Name:    rep.name,
+			Healthy: rep.healthy.Load(),
+			Lag:     time.Duration(rep.lag.Load()),
+		})
+	}
+	return statuses
+}
+
+// Close stops lag checks and closes replica handles. The primary is owned by the caller.
+func (r *ReplicaRouter) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+
+	var firstErr error
+	for _, rep := range r.replicas {
+		if err := rep.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openUnconnected returns a handle that is never used to reach a server
+func openUnconnected(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("pgx", "host=127.0.0.1 port=1 dbname=unused")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestReplicaRouter_Reader(t *testing.T) {
+	primary := openUnconnected(t)
+	replicaA := openUnconnected(t)
+	replicaB := openUnconnected(t)
+
+	t.Run("no replicas uses primary", func(t *testing.T) {
+		router := NewReplicaRouter(primary, nil, ReplicaConfig{})
+		assert.Same(t, primary, router.Reader(context.Background()))
+	})
+
+	t.Run("round robin over fresh replicas", func(t *testing.T) {
+		router := NewReplicaRouter(primary, []*sql.DB{replicaA, replicaB}, ReplicaConfig{MaxLag: time.Second})
+		first := router.Reader(context.Background())
+		second := router.Reader(context.Background())
+		assert.NotSame(t, primary, first)
+		assert.NotSame(t, primary, second)
+		assert.NotSame(t, first, second)
+	})
+
+	t.Run("lagging and unhealthy replicas are skipped", func(t *testing.T) {
+		router := NewReplicaRouter(primary, []*sql.DB{replicaA, replicaB}, ReplicaConfig{MaxLag: time.Second})
+		router.replicas[0].lag.Store(int64(10 * time.Second))
+		for i := 0; i < 4; i++ {
+			assert.Same(t, replicaB, router.Reader(context.Background()))
+		}
+
+		router.replicas[1].healthy.Store(false)
+		assert.Same(t, primary, router.Reader(context.Background()))
+	})
+
+	t.Run("staleness sensitive reads", func(t *testing.T) {
+		router := NewReplicaRouter(primary, []*sql.DB{replicaA}, ReplicaConfig{MaxLag: 5 * time.Second})
+		router.replicas[0].lag.Store(int64(2 * time.Second))
+
+		assert.Same(t, replicaA, router.Reader(context.Background()))
+		assert.Same(t, primary, router.Reader(WithPrimaryRead(context.Background())))
+		assert.Same(t, primary, router.Reader(WithMaxStaleness(context.Background(), time.Second)))
+		assert.Same(t, replicaA, router.Reader(WithMaxStaleness(context.Background(), 3*time.Second)))
+	})
+
+	t.Run("reads after a write wait for replay", func(t *testing.T) {
+		router := NewReplicaRouter(primary, []*sql.DB{replicaA}, ReplicaConfig{MaxLag: 5 * time.Second})
+		router.replicas[0].replayedThrough.Store(time.Now().Add(-time.Minute).UnixNano())
+
+		router.MarkWrite()
+		assert.Same(t, primary, router.Reader(context.Background()))
+
+		router.replicas[0].replayedThrough.Store(time.Now().Add(time.Second).UnixNano())
+		assert.Same(t, replicaA, router.Reader(context.Background()))
+	})
+}
+
+func TestConfigureStatementCache(t *testing.T) {
+	connConfig, err := pgx.ParseConfig("host=127.0.0.1 port=1 dbname=unused")
+	require.NoError(t, err)
+
+	ConfigureStatementCache(connConfig, 64)
+	assert.Equal(t, 64, connConfig.StatementCacheCapacity)
+	assert.Equal(t, pgx.QueryExecModeCacheStatement, connConfig.DefaultQueryExecMode)
+
+	ConfigureStatementCache(connConfig, 0)
+	assert.Equal(t, 0, connConfig.StatementCacheCapacity)
+	assert.Equal(t, pgx.QueryExecModeDescribeExec, connConfig.DefaultQueryExecMode, "unnamed statements work through transaction poolers")
+}
+
+func TestNewPoolConfig(t *testing.T) {
+	poolConfig, err := NewPoolConfig("host=127.0.0.1 port=1 dbname=unused pool_max_conns=3", 0)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), poolConfig.MaxConns)
+	assert.Equal(t, pgx.QueryExecModeDescribeExec, poolConfig.ConnConfig.DefaultQueryExecMode)
+	assert.NotNil(t, poolConfig.AfterConnect, "every pool sets the search_path")
+
+	_, err = NewPoolConfig("host=127.0.0.1 port=notaport", 0)
+	assert.Error(t, err)
+}
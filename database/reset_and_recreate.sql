@@ -91,7 +91,8 @@ CREATE TABLE content_db.chunks (
     sequence_number INTEGER,
     metadata JSONB DEFAULT '{}',
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    sort_key TEXT COLLATE "C"
 );
 
 -- Chunk 標籤關係表
@@ -157,6 +158,7 @@ CREATE TABLE graph_db.graph_edges (
 -- Content DB 索引
 CREATE INDEX idx_chunks_text_id ON content_db.chunks(text_id);
 CREATE INDEX idx_chunks_parent_id ON content_db.chunks(parent_chunk_id);
+CREATE INDEX idx_chunks_parent_sort_key ON content_db.chunks(parent_chunk_id, sort_key);
 CREATE INDEX idx_chunks_template_id ON content_db.chunks(template_chunk_id);
 CREATE INDEX idx_chunks_content_search ON content_db.chunks USING gin(to_tsvector('english', content));
 CREATE INDEX idx_chunk_tags_chunk_id ON content_db.chunk_tags(chunk_id);
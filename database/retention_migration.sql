@@ -0,0 +1,25 @@
+-- Retention Rules Migration
+-- Per-workspace rules the maintenance daemon applies on every run: archive or trash chunks
+-- not updated for max_age_days, or purge chunk_trash entries older than that. Chunks are
+-- not partitioned by workspace; the workspace decides which admins manage the rule, and
+-- tag_chunk_id or page_chunk_id narrow the chunks it applies to.
+
+CREATE TABLE IF NOT EXISTS retention_rules (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    workspace_id      TEXT NOT NULL,
+    name              TEXT NOT NULL,
+    action            TEXT NOT NULL CHECK (action IN ('archive', 'delete', 'purge_trash')),
+    max_age_days      INTEGER NOT NULL CHECK (max_age_days > 0),
+    tag_chunk_id      UUID REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    page_chunk_id     UUID REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    enabled           BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_run_at       TIMESTAMPTZ,
+    last_run_affected INTEGER NOT NULL DEFAULT 0,
+    last_run_error    TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_retention_rules_workspace ON retention_rules(workspace_id, created_at);
+
+COMMENT ON TABLE retention_rules IS 'Per-workspace rules archiving or deleting stale chunks and purging old trash';
@@ -0,0 +1,8 @@
+-- Search Cache Stale-While-Revalidate Migration
+-- Stores the serialized search response next to the cached chunk IDs so that
+-- similarity scores and totals can be served from cache without recomputing them.
+
+ALTER TABLE chunk_search_cache ADD COLUMN IF NOT EXISTS payload JSONB;
+
+COMMENT ON COLUMN chunk_search_cache.payload IS 'Serialized search response for the cached query (optional)';
+COMMENT ON COLUMN chunk_search_cache.expires_at IS 'Entries past expiry may still be served while a background refresh runs';
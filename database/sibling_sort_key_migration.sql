@@ -0,0 +1,46 @@
+-- Fractional sibling ordering for the legacy content_db.chunks table
+-- Siblings are ordered by sort_key, a base-62 fraction compared byte by byte, so moving
+-- or inserting a chunk writes only that chunk instead of renumbering sequence_number on
+-- every sibling. Safe to re-run: it respaces the keys of every sibling group.
+
+ALTER TABLE content_db.chunks ADD COLUMN IF NOT EXISTS sort_key TEXT COLLATE "C";
+
+-- Give existing siblings evenly spaced keys in their current order. Trailing zero
+-- digits are dropped, as the service never hands out keys ending in one.
+WITH ranked AS (
+    SELECT id, row_number() OVER (
+        PARTITION BY text_id, parent_chunk_id
+        ORDER BY sort_key NULLS LAST, sequence_number, created_at
+    ) AS position
+    FROM content_db.chunks
+)
+UPDATE content_db.chunks c
+SET sort_key = rtrim(lpad(r.position::text, 8, '0'), '0')
+FROM ranked r
+WHERE c.id = r.id;
+
+CREATE INDEX IF NOT EXISTS idx_chunks_parent_sort_key
+    ON content_db.chunks (parent_chunk_id, sort_key);
+
+-- Expose the column through the public view
+CREATE OR REPLACE VIEW public.chunks AS
+SELECT * FROM content_db.chunks;
+
+CREATE OR REPLACE RULE chunks_insert AS ON INSERT TO public.chunks
+DO INSTEAD INSERT INTO content_db.chunks VALUES (NEW.*);
+
+CREATE OR REPLACE RULE chunks_update AS ON UPDATE TO public.chunks
+DO INSTEAD UPDATE content_db.chunks SET
+    text_id = NEW.text_id,
+    content = NEW.content,
+    is_template = NEW.is_template,
+    is_slot = NEW.is_slot,
+    parent_chunk_id = NEW.parent_chunk_id,
+    template_chunk_id = NEW.template_chunk_id,
+    slot_value = NEW.slot_value,
+    indent_level = NEW.indent_level,
+    sequence_number = NEW.sequence_number,
+    sort_key = NEW.sort_key,
+    metadata = NEW.metadata,
+    updated_at = NEW.updated_at
+WHERE id = OLD.id;
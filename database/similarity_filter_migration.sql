@@ -0,0 +1,51 @@
+-- Similarity Filter Migration
+-- match_chunks searches the text embeddings on chunks and takes optional filters applied
+-- inside the vector query, so a filtered search returns match_count matching chunks
+-- instead of filtering a fixed number of nearest ones afterwards:
+--   filter_tags            tag chunk IDs every match must carry
+--   filter_page            a page; matches are the page and the chunks on it
+--   filter_created_after   inclusive lower bound on created_time
+--   filter_created_before  exclusive upper bound on created_time
+-- Rows use the gateway's chunk field names (id, content, parent_chunk_id, ...).
+
+-- The three-argument version would make calls without filters ambiguous
+DROP FUNCTION IF EXISTS public.match_chunks(vector, float, int);
+
+CREATE OR REPLACE FUNCTION public.match_chunks(
+    query_embedding vector,
+    match_threshold float DEFAULT 0.0,
+    match_count int DEFAULT 50,
+    filter_tags text[] DEFAULT NULL,
+    filter_page uuid DEFAULT NULL,
+    filter_created_after timestamptz DEFAULT NULL,
+    filter_created_before timestamptz DEFAULT NULL
+)
+RETURNS TABLE (
+    chunk jsonb,
+    similarity float
+)
+LANGUAGE sql
+STABLE
+AS $$
+    SELECT
+        jsonb_build_object(
+            'id', c.chunk_id,
+            'content', c.contents,
+            'parent_chunk_id', c.parent,
+            'is_template', c.is_template,
+            'is_slot', c.is_slot,
+            'metadata', c.metadata,
+            'created_at', c.created_time,
+            'updated_at', c.last_updated
+        ) AS chunk,
+        1 - (c.vector <=> query_embedding) AS similarity
+    FROM chunks c
+    WHERE c.vector_type = 'text' AND c.vector IS NOT NULL
+      AND 1 - (c.vector <=> query_embedding) > match_threshold
+      AND (filter_tags IS NULL OR c.tags ?& filter_tags)
+      AND (filter_page IS NULL OR c.page = filter_page OR c.chunk_id = filter_page)
+      AND (filter_created_after IS NULL OR c.created_time >= filter_created_after)
+      AND (filter_created_before IS NULL OR c.created_time < filter_created_before)
+    ORDER BY c.vector <=> query_embedding
+    LIMIT match_count;
+$$;
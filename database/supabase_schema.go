@@ -0,0 +1,285 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// SupabaseSchemaObject kinds
+const (
+	SchemaKindExtension = "extension"
+	SchemaKindTable     = "table"
+	SchemaKindFunction  = "function"
+	SchemaKindIndex     = "index"
+	SchemaKindTrigger   = "trigger"
+)
+
+// SupabaseSchemaObject is a database object the Supabase client depends on. PostgREST
+// cannot create SQL functions, so they are installed over a direct connection.
+type SupabaseSchemaObject struct {
+	Name string
+	Kind string
+	// exists is a query returning whether the object is present
+	exists string
+	// install creates or replaces the object idempotently; tables have none and come from
+	// database/reset_and_recreate.sql
+	install string
+}
+
+// SupabaseSchemaStatus reports whether a required object is present
+type SupabaseSchemaStatus struct {
+	Name        string `json:"name"`
+	Kind        string `json:"kind"`
+	Present     bool   `json:"present"`
+	Installable bool   `json:"installable"`
+}
+
+// grantRPCExecute lets the Supabase API roles call a function when those roles exist;
+// plain PostgreSQL has neither
+const grantRPCExecute = `
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'anon')
+       AND EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'authenticated') THEN
+        GRANT EXECUTE ON FUNCTION %s TO anon, authenticated;
+    END IF;
+END $$`
+
+// supabaseSchemaObjects lists the required objects in installation order
+var supabaseSchemaObjects = []SupabaseSchemaObject{
+	{
+		Name:    "vector",
+		Kind:    SchemaKindExtension,
+		exists:  `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'vector')`,
+		install: `CREATE EXTENSION IF NOT EXISTS vector`,
+	},
+	requiredTable("content_db.texts"),
+	requiredTable("content_db.chunks"),
+	requiredTable("vector_db.embeddings"),
+	requiredTable("graph_db.graph_nodes"),
+	requiredTable("graph_db.graph_edges"),
+	{
+		Name:   "vector_db.embeddings_vector_idx",
+		Kind:   SchemaKindIndex,
+		exists: `SELECT to_regclass('vector_db.embeddings_vector_idx') IS NOT NULL`,
+		install: `CREATE INDEX IF NOT EXISTS embeddings_vector_idx ON vector_db.embeddings
+USING ivfflat (vector vector_cosine_ops) WITH (lists = 100)`,
+	},
+	{
+		Name:    "vector_db.idx_embeddings_chunk_id",
+		Kind:    SchemaKindIndex,
+		exists:  `SELECT to_regclass('vector_db.idx_embeddings_chunk_id') IS NOT NULL`,
+		install: `CREATE INDEX IF NOT EXISTS idx_embeddings_chunk_id ON vector_db.embeddings(chunk_id)`,
+	},
+	{
+		Name:    "graph_db.idx_graph_nodes_entity_name",
+		Kind:    SchemaKindIndex,
+		exists:  `SELECT to_regclass('graph_db.idx_graph_nodes_entity_name') IS NOT NULL`,
+		install: `CREATE INDEX IF NOT EXISTS idx_graph_nodes_entity_name ON graph_db.graph_nodes(entity_name)`,
+	},
+	{
+		Name:    "graph_db.idx_graph_edges_source",
+		Kind:    SchemaKindIndex,
+		exists:  `SELECT to_regclass('graph_db.idx_graph_edges_source') IS NOT NULL`,
+		install: `CREATE INDEX IF NOT EXISTS idx_graph_edges_source ON graph_db.graph_edges(source_node_id)`,
+	},
+	{
+		Name:    "graph_db.idx_graph_edges_target",
+		Kind:    SchemaKindIndex,
+		exists:  `SELECT to_regclass('graph_db.idx_graph_edges_target') IS NOT NULL`,
+		install: `CREATE INDEX IF NOT EXISTS idx_graph_edges_target ON graph_db.graph_edges(target_node_id)`,
+	},
+	{
+		Name:   "public.match_chunks",
+		Kind:   SchemaKindFunction,
+		exists: functionExists("match_chunks", "query_embedding vector, match_threshold double precision, match_count integer"),
+		install: `CREATE OR REPLACE FUNCTION public.match_chunks(
+    query_embedding vector,
+    match_threshold float DEFAULT 0.0,
+    match_count int DEFAULT 50
+)
+RETURNS TABLE (
+    chunk jsonb,
+    similarity float
+)
+LANGUAGE sql STABLE
+AS $$
+    SELECT
+        to_jsonb(c.*) as chunk,
+        1 - (e.vector <=> query_embedding) as similarity
+    FROM vector_db.embeddings e
+    JOIN content_db.chunks c ON e.chunk_id = c.id
+    WHERE 1 - (e.vector <=> query_embedding) > match_threshold
+    ORDER BY e.vector <=> query_embedding
+    LIMIT match_count;
+$$;
+` + fmt.Sprintf(grantRPCExecute, "public.match_chunks(vector, float, int)"),
+	},
+	{
+		Name:   "public.search_graph",
+		Kind:   SchemaKindFunction,
+		exists: functionExists("search_graph", "entity_name text, max_depth integer, result_limit integer"),
+		install: `CREATE OR REPLACE FUNCTION public.search_graph(
+    entity_name text,
+    max_depth int DEFAULT 3,
+    result_limit int DEFAULT 50
+)
+RETURNS TABLE (
+    nodes jsonb,
+    edges jsonb
+)
+LANGUAGE sql STABLE
+AS $$
+    WITH RECURSIVE graph_traversal AS (
+        SELECT n.id, n.entity_name, n.entity_type, n.properties, n.chunk_id, n.created_at, 0 as depth
+        FROM graph_db.graph_nodes n
+        WHERE n.entity_name = search_graph.entity_name
+
+        UNION ALL
+
+        SELECT n.id, n.entity_name, n.entity_type, n.properties, n.chunk_id, n.created_at, gt.depth + 1
+        FROM graph_db.graph_nodes n
+        JOIN graph_db.graph_edges e ON (n.id = e.source_node_id OR n.id = e.target_node_id)
+        JOIN graph_traversal gt ON (
+            (e.source_node_id = gt.id AND n.id = e.target_node_id) OR
+            (e.target_node_id = gt.id AND n.id = e.source_node_id)
+        )
+        WHERE gt.depth < search_graph.max_depth
+    ),
+    found_nodes AS (
+        SELECT DISTINCT * FROM graph_traversal
+        LIMIT result_limit
+    ),
+    found_edges AS (
+        SELECT DISTINCT e.*
+        FROM graph_db.graph_edges e
+        WHERE e.source_node_id IN (SELECT id FROM found_nodes)
+           OR e.target_node_id IN (SELECT id FROM found_nodes)
+    )
+    SELECT
+        COALESCE(jsonb_agg(to_jsonb(fn.*)) FILTER (WHERE fn.id IS NOT NULL), '[]'::jsonb) as nodes,
+        COALESCE(jsonb_agg(to_jsonb(fe.*)) FILTER (WHERE fe.id IS NOT NULL), '[]'::jsonb) as edges
+    FROM found_nodes fn
+    FULL OUTER JOIN found_edges fe ON true;
+$$;
+` + fmt.Sprintf(grantRPCExecute, "public.search_graph(text, int, int)"),
+	},
+	// The client never sends updated_at
+	{
+		Name:   "content_db.chunks_set_updated_at",
+		Kind:   SchemaKindTrigger,
+		exists: `SELECT EXISTS (SELECT 1 FROM pg_trigger WHERE tgname = 'chunks_set_updated_at' AND tgrelid = to_regclass('content_db.chunks'))`,
+		install: `CREATE OR REPLACE FUNCTION content_db.set_updated_at()
+RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    NEW.updated_at = NOW();
+    RETURN NEW;
+END;
+$$;
+DROP TRIGGER IF EXISTS chunks_set_updated_at ON content_db.chunks;
+CREATE TRIGGER chunks_set_updated_at
+    BEFORE UPDATE ON content_db.chunks
+    FOR EACH ROW EXECUTE FUNCTION content_db.set_updated_at()`,
+	},
+}
+
+func requiredTable(name string) SupabaseSchemaObject {
+	return SupabaseSchemaObject{
+		Name:   name,
+		Kind:   SchemaKindTable,
+		exists: fmt.Sprintf("SELECT to_regclass('%s') IS NOT NULL", name),
+	}
+}
+
+// functionExists checks the argument list too, so an outdated signature counts as missing
+func functionExists(name, arguments string) string {
+	return fmt.Sprintf(`SELECT EXISTS (
+    SELECT 1 FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace
+    WHERE n.nspname = 'public' AND p.proname = '%s'
+      AND pg_get_function_identity_arguments(p.oid) = '%s')`, name, arguments)
+}
+
+// SupabaseSchemaManager installs and verifies the SQL functions, triggers and indexes the
+// Supabase client relies on, such as the match_chunks and search_graph RPC functions
+type SupabaseSchemaManager struct {
+	db *sql.DB
+}
+
+// NewSupabaseSchemaManager creates a schema manager for a direct connection to the
+// Supabase database
+func NewSupabaseSchemaManager(db *sql.DB) *SupabaseSchemaManager {
+	return &SupabaseSchemaManager{db: db}
+}
+
+// Verify reports every required object and whether it is present
+func (m *SupabaseSchemaManager) Verify(ctx context.Context) ([]SupabaseSchemaStatus, error) {
+	statuses := make([]SupabaseSchemaStatus, 0, len(supabaseSchemaObjects))
+	for _, object := range supabaseSchemaObjects {
+		var present bool
+		if err := m.db.QueryRowContext(ctx, object.exists).Scan(&present); err != nil {
+			return nil, fmt.Errorf("failed to check %s %s: %w", object.Kind, object.Name, err)
+		}
+		statuses = append(statuses, SupabaseSchemaStatus{
+			Name:        object.Name,
+			Kind:        object.Kind,
+			Present:     present,
+			Installable: object.install != "",
+		})
+	}
+	return statuses, nil
+}
+
+// Install creates or replaces every installable object in one transaction. Functions and
+// triggers are always replaced so that upgrades reach existing databases. The tables must
+// exist already.
+func (m *SupabaseSchemaManager) Install(ctx context.Context) ([]SupabaseSchemaStatus, error) {
+	statuses, err := m.Verify(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var missingTables []string
+	for _, status := range statuses {
+		if status.Kind == SchemaKindTable && !status.Present {
+			missingTables = append(missingTables, status.Name)
+		}
+	}
+	if len(missingTables) > 0 {
+		return statuses, fmt.Errorf("missing tables %s; create them with database/reset_and_recreate.sql first",
+			strings.Join(missingTables, ", "))
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, object := range supabaseSchemaObjects {
+		if object.install == "" {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, object.install); err != nil {
+			return nil, fmt.Errorf("failed to install %s %s: %w", object.Kind, object.Name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit schema installation: %w", err)
+	}
+
+	return m.Verify(ctx)
+}
+
+// MissingSchemaObjects returns the statuses of objects that are not present
+func MissingSchemaObjects(statuses []SupabaseSchemaStatus) []SupabaseSchemaStatus {
+	var missing []SupabaseSchemaStatus
+	for _, status := range statuses {
+		if !status.Present {
+			missing = append(missing, status)
+		}
+	}
+	return missing
+}
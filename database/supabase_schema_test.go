@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupabaseSchemaObjects(t *testing.T) {
+	names := make(map[string]bool)
+	for _, object := range supabaseSchemaObjects {
+		assert.False(t, names[object.Name], "duplicate object %s", object.Name)
+		names[object.Name] = true
+		assert.NotEmpty(t, object.exists, object.Name)
+		assert.Equal(t, object.Kind != SchemaKindTable, object.install != "", "only tables are left to the schema scripts: %s", object.Name)
+	}
+	assert.True(t, names["public.match_chunks"])
+	assert.True(t, names["public.search_graph"])
+	assert.Equal(t, SchemaKindExtension, supabaseSchemaObjects[0].Kind, "the vector extension is installed first")
+
+	missing := MissingSchemaObjects([]SupabaseSchemaStatus{
+		{Name: "public.match_chunks", Kind: SchemaKindFunction, Present: true},
+		{Name: "public.search_graph", Kind: SchemaKindFunction},
+	})
+	require.Len(t, missing, 1)
+	assert.Equal(t, "public.search_graph", missing[0].Name)
+}
+
+func TestSupabaseSchemaManager_Unreachable(t *testing.T) {
+	manager := NewSupabaseSchemaManager(openUnconnected(t))
+	_, err := manager.Verify(context.Background())
+	assert.Error(t, err)
+	_, err = manager.Install(context.Background())
+	assert.Error(t, err)
+}
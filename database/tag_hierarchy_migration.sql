@@ -0,0 +1,26 @@
+-- Tag Hierarchy Migration
+-- Allows tags to have parent tags (e.g. #golang under #programming).
+-- The hierarchy is stored as a closure table so that "all descendants of a tag"
+-- is a single indexed lookup instead of a recursive query.
+
+CREATE TABLE IF NOT EXISTS tag_hierarchy (
+    ancestor_tag_id UUID NOT NULL,
+    descendant_tag_id UUID NOT NULL,
+    depth INTEGER NOT NULL,
+
+    PRIMARY KEY (ancestor_tag_id, descendant_tag_id),
+    FOREIGN KEY (ancestor_tag_id) REFERENCES chunks(chunk_id) ON DELETE CASCADE,
+    FOREIGN KEY (descendant_tag_id) REFERENCES chunks(chunk_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_hierarchy_ancestor ON tag_hierarchy(ancestor_tag_id, depth);
+CREATE INDEX IF NOT EXISTS idx_tag_hierarchy_descendant ON tag_hierarchy(descendant_tag_id, depth);
+
+ALTER TABLE tag_hierarchy DROP CONSTRAINT IF EXISTS check_tag_hierarchy_depth;
+ALTER TABLE tag_hierarchy ADD CONSTRAINT check_tag_hierarchy_depth
+    CHECK (depth >= 0 AND (ancestor_tag_id != descendant_tag_id OR depth = 0));
+
+COMMENT ON TABLE tag_hierarchy IS 'Closure table of parent/child tag relationships, including depth 0 self rows';
+COMMENT ON COLUMN tag_hierarchy.ancestor_tag_id IS 'The broader tag';
+COMMENT ON COLUMN tag_hierarchy.descendant_tag_id IS 'The narrower tag';
+COMMENT ON COLUMN tag_hierarchy.depth IS 'Number of parent links between ancestor and descendant';
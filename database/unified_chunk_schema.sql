@@ -24,6 +24,9 @@ CREATE TABLE chunks (
     ref TEXT,
     tags JSONB, -- Array of tag chunk_ids for backup queries
     metadata JSONB, -- Extensible field for future features
+    version BIGINT NOT NULL DEFAULT 1, -- Optimistic concurrency version
+    lang TEXT, -- Detected content language (zh, en, und)
+    search_vector TSVECTOR, -- Language-aware full-text vector, see language_search_migration.sql
     created_time TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     last_updated TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
@@ -57,6 +60,7 @@ CREATE TABLE chunk_search_cache (
     query_params JSONB NOT NULL,
     chunk_ids UUID[] NOT NULL,
     result_count INTEGER NOT NULL,
+    payload JSONB,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
     expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
     hit_count INTEGER DEFAULT 0
@@ -75,7 +79,8 @@ CREATE INDEX idx_chunks_parent ON chunks(parent);
 CREATE INDEX idx_chunks_page ON chunks(page);
 CREATE INDEX idx_chunks_ref ON chunks(ref) WHERE ref IS NOT NULL;
 
--- Full-text search index
+-- Full-text search index (replaced by idx_chunks_search_vector once
+-- language_search_migration.sql has run)
 CREATE INDEX idx_chunks_contents_fts ON chunks USING gin(to_tsvector('english', contents));
 
 -- JSONB indexes for tags and metadata
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// APITokenHandler handles API token management HTTP requests. Tokens are managed in the
+// workspace of the calling admin.
+type APITokenHandler struct {
+	tokenService       services.APITokenService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewAPITokenHandler creates a new API token handler
+func NewAPITokenHandler(
+	tokenService services.APITokenService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *APITokenHandler {
+	return &APITokenHandler{
+		tokenService:       tokenService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// ListTokens handles GET /api/v1/tokens
+func (h *APITokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("list_api_tokens", w, func() (int, error) {
+		tokens, err := h.tokenService.ListTokens(r.Context(), services.WorkspaceFromContext(r.Context()))
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to list api tokens", err)
+			return status, err
+		}
+
+		response := map[string]interface{}{
+			"tokens": tokens,
+			"count":  len(tokens),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// CreateToken handles POST /api/v1/tokens. The response is the only one that contains
+// the token.
+func (h *APITokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("create_api_token", w, func() (int, error) {
+		var req models.CreateAPITokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		token, err := h.tokenService.CreateToken(r.Context(), services.WorkspaceFromContext(r.Context()), &req)
+		if errors.Is(err, services.ErrInvalidAPITokenRequest) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid api token request", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to create api token", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, token)
+		return http.StatusCreated, nil
+	})
+}
+
+// RevokeToken handles DELETE /api/v1/tokens/{id}
+func (h *APITokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("revoke_api_token", w, func() (int, error) {
+		tokenID := mux.Vars(r)["id"]
+
+		err := h.tokenService.RevokeToken(r.Context(), services.WorkspaceFromContext(r.Context()), tokenID)
+		if errors.Is(err, services.ErrAPITokenNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "api token not found", tokenID)
+			return http.StatusNotFound, err
+		}
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to revoke api token", err)
+			return status, err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
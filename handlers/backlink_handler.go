@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// BacklinkHandler handles reference graph HTTP requests
+type BacklinkHandler struct {
+	backlinkService    services.BacklinkService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewBacklinkHandler creates a new backlink handler
+func NewBacklinkHandler(
+	backlinkService services.BacklinkService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *BacklinkHandler {
+	return &BacklinkHandler{
+		backlinkService:    backlinkService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetBacklinks handles GET /api/v1/chunks/{id}/backlinks
+func (h *BacklinkHandler) GetBacklinks(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_backlinks", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		backlinks, err := h.backlinkService.GetBacklinks(r.Context(), chunkID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get backlinks", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"chunk_id":  chunkID,
+			"backlinks": backlinks,
+			"count":     len(backlinks),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// GetOutgoingRefs handles GET /api/v1/chunks/{id}/refs
+func (h *BacklinkHandler) GetOutgoingRefs(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_outgoing_refs", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		refs, err := h.backlinkService.GetOutgoingRefs(r.Context(), chunkID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get references", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, refs)
+		return http.StatusOK, nil
+	})
+}
+
+// GetBrokenReferences handles GET /api/v1/refs/broken
+func (h *BacklinkHandler) GetBrokenReferences(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_broken_references", w, func() (int, error) {
+		limit := 100 // default
+		if l := r.URL.Query().Get("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+
+		broken, err := h.backlinkService.FindBrokenReferences(r.Context(), limit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to find broken references", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"broken_references": broken,
+			"count":             len(broken),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// RebuildReferences handles POST /api/v1/refs/rebuild
+func (h *BacklinkHandler) RebuildReferences(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("rebuild_references", w, func() (int, error) {
+		count, err := h.backlinkService.RebuildRefs(r.Context())
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to rebuild references", err)
+			return status, err
+		}
+
+		response := map[string]interface{}{
+			"reference_count": count,
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
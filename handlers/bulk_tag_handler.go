@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// BulkTagHandler handles bulk tag assignment HTTP requests
+type BulkTagHandler struct {
+	bulkTags           services.BulkTagService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewBulkTagHandler creates a new bulk tag handler
+func NewBulkTagHandler(
+	bulkTags services.BulkTagService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *BulkTagHandler {
+	return &BulkTagHandler{
+		bulkTags:           bulkTags,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// TagChunks handles POST /api/v1/tags/bulk. Clients accepting text/event-stream receive a
+// progress event after each batch and a done event with the result.
+func (h *BulkTagHandler) TagChunks(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("bulk_tag_chunks", w, func() (int, error) {
+		var req models.BulkTagRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		ctx := r.Context()
+		streaming := strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+		var controller *http.ResponseController
+		if streaming {
+			// The stream starts with the first batch, so requests that fail before it
+			// still get an error status
+			controller = http.NewResponseController(w)
+			started := false
+			ctx = services.WithBulkTagProgress(ctx, func(progress models.BulkTagProgress) {
+				if !started {
+					started = true
+					controller.SetWriteDeadline(time.Time{})
+					w.Header().Set("Content-Type", "text/event-stream")
+					w.Header().Set("Cache-Control", "no-cache")
+					w.Header().Set("X-Accel-Buffering", "no")
+					w.WriteHeader(http.StatusOK)
+				}
+				writeBulkTagEvent(w, "progress", progress)
+				controller.Flush()
+			})
+		}
+
+		result, err := h.bulkTags.TagChunks(ctx, &req.Selector, req.TagIDs)
+		if streaming && result != nil && result.Batches > 0 {
+			// Headers are sent; the outcome is the last event
+			if err != nil {
+				writeBulkTagEvent(w, "error", map[string]interface{}{"error": err.Error(), "result": result})
+				controller.Flush()
+				return http.StatusOK, err
+			}
+			writeBulkTagEvent(w, "done", result)
+			controller.Flush()
+			return http.StatusOK, nil
+		}
+
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidBulkTagRequest) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid bulk tag request", err.Error())
+				return http.StatusBadRequest, err
+			}
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not a tag") {
+				writeErrorResponse(w, http.StatusNotFound, "tag not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to tag chunks", err)
+			return status, err
+		}
+
+		if streaming {
+			// Nothing was selected, so no progress was streamed
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.WriteHeader(http.StatusOK)
+			writeBulkTagEvent(w, "done", result)
+			return http.StatusOK, nil
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// writeBulkTagEvent writes one server-sent event
+func writeBulkTagEvent(w http.ResponseWriter, event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
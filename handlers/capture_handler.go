@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// captureEnvelopeSize allows for the JSON around a capture of the largest accepted size
+const captureEnvelopeSize = 64 << 10
+
+// CaptureHandler handles quick capture HTTP requests
+type CaptureHandler struct {
+	capture            services.CaptureService
+	maxBytes           int64
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewCaptureHandler creates a new capture handler accepting captures of up to maxBytes
+func NewCaptureHandler(
+	capture services.CaptureService,
+	maxBytes int,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *CaptureHandler {
+	return &CaptureHandler{
+		capture:            capture,
+		maxBytes:           int64(maxBytes),
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// Capture handles POST /api/v1/capture. A JSON body is a models.CaptureRequest; a
+// text/plain body is captured as text.
+func (h *CaptureHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("capture", w, func() (int, error) {
+		body := http.MaxBytesReader(w, r.Body, h.maxBytes+captureEnvelopeSize)
+		var req models.CaptureRequest
+		var err error
+		if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/plain" {
+			var text []byte
+			text, err = io.ReadAll(body)
+			req.Kind = models.CaptureText
+			req.Text = string(text)
+		} else {
+			err = json.NewDecoder(body).Decode(&req)
+		}
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "capture too large", err.Error())
+				return http.StatusRequestEntityTooLarge, err
+			}
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.capture.Capture(r.Context(), &req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCapture) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid capture", err.Error())
+				return http.StatusBadRequest, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to capture", err)
+			return status, err
+		}
+
+		// Accepted: the capture is stored, but chunking and tagging are still pending
+		writeJSONResponse(w, http.StatusAccepted, result)
+		return http.StatusAccepted, nil
+	})
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+)
+
+// ChangeFeedHandler streams chunk changes to clients as server-sent events
+type ChangeFeedHandler struct {
+	changeFeed         services.ChangeFeedService
+	subscriberBuffer   int
+	heartbeat          time.Duration
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewChangeFeedHandler creates a new change feed handler
+func NewChangeFeedHandler(
+	changeFeed services.ChangeFeedService,
+	subscriberBuffer int,
+	heartbeat time.Duration,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *ChangeFeedHandler {
+	return &ChangeFeedHandler{
+		changeFeed:         changeFeed,
+		subscriberBuffer:   subscriberBuffer,
+		heartbeat:          heartbeat,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// StreamChanges handles GET /api/v1/changes/stream?types=insert,update&page_id=
+//
+// Each event is sent with its sequence as the SSE id, so reconnecting clients resume from
+// Last-Event-ID. When the requested events are no longer retained a resync event is sent
+// first and the client should reload what it displays.
+func (h *ChangeFeedHandler) StreamChanges(w http.ResponseWriter, r *http.Request) {
+	filter := models.ChangeFeedFilter{PageID: r.URL.Query().Get("page_id")}
+	for _, t := range splitQueryList(r.URL.Query()["types"]) {
+		eventType := models.ChangeEventType(t)
+		switch eventType {
+		case models.ChangeEventInsert, models.ChangeEventUpdate, models.ChangeEventDelete:
+			filter.Types = append(filter.Types, eventType)
+		default:
+			writeErrorResponse(w, http.StatusBadRequest, "types must be insert, update or delete", t)
+			return
+		}
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	var resumeFrom uint64
+	resume := lastEventID != ""
+	if resume {
+		parsed, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid Last-Event-ID", err.Error())
+			return
+		}
+		resumeFrom = parsed
+	}
+
+	// Streams outlive the server write timeout
+	controller := http.NewResponseController(w)
+	controller.SetWriteDeadline(time.Time{})
+
+	// Subscribe before replaying so nothing published in between is missed
+	sub := h.changeFeed.Subscribe(filter, h.subscriberBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var delivered uint64
+	if resume {
+		replay, complete := h.changeFeed.EventsSince(resumeFrom, filter)
+		if !complete {
+			if err := writeChangeEvent(w, &models.ChangeEvent{Type: models.ChangeEventResync, OccurredAt: time.Now()}); err != nil {
+				return
+			}
+		}
+		for _, event := range replay {
+			if err := writeChangeEvent(w, event); err != nil {
+				return
+			}
+			delivered = event.Sequence
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		h.logger.Printf("Change stream cannot be flushed: %v", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	var reportedDrops uint64
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if event.Sequence <= delivered {
+				continue
+			}
+			// A client too slow to keep up has missed events and must reload
+			if dropped := sub.Dropped(); dropped > reportedDrops {
+				reportedDrops = dropped
+				if err := writeChangeEvent(w, &models.ChangeEvent{Type: models.ChangeEventResync, OccurredAt: time.Now()}); err != nil {
+					return
+				}
+			}
+			if err := writeChangeEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// GetStatus handles GET /api/v1/changes/status
+func (h *ChangeFeedHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_change_feed_status", w, func() (int, error) {
+		writeJSONResponse(w, http.StatusOK, h.changeFeed.GetStats())
+		return http.StatusOK, nil
+	})
+}
+
+// writeChangeEvent writes one server-sent event; resync events carry no id so they do
+// not move the client's resume position
+func writeChangeEvent(w http.ResponseWriter, event *models.ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if event.Sequence > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.Sequence); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
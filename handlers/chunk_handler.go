@@ -274,6 +274,70 @@ func (h *ChunkHandler) MoveChunk(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// InsertChunkAt handles POST /api/v1/chunks/{id}/children
+func (h *ChunkHandler) InsertChunkAt(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	parentID := vars["id"]
+
+	if parentID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+		return
+	}
+
+	var req models.InsertChunkAtRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	if req.Content == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "content is required", "")
+		return
+	}
+
+	chunk := &models.ChunkRecord{
+		TextID:          req.TextID,
+		Content:         req.Content,
+		IsTemplate:      req.IsTemplate,
+		IsSlot:          req.IsSlot,
+		TemplateChunkID: req.TemplateChunkID,
+		SlotValue:       req.SlotValue,
+		IndentLevel:     req.IndentLevel,
+		Metadata:        req.Metadata,
+	}
+
+	if err := h.supabaseClient.InsertChunkAt(r.Context(), parentID, req.AfterChunkID, chunk); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "failed to insert chunk", err.Error())
+		return
+	}
+
+	writeJSONResponse(w, http.StatusCreated, chunk)
+}
+
+// ReorderChildren handles PUT /api/v1/chunks/{id}/children/order
+func (h *ChunkHandler) ReorderChildren(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	parentID := vars["id"]
+
+	if parentID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+		return
+	}
+
+	var req models.ReorderChildrenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	if err := h.supabaseClient.ReorderChildren(r.Context(), parentID, req.ChildIDs); err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "failed to reorder children", err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // BulkUpdateChunks handles PUT /api/v1/chunks/bulk-update
 func (h *ChunkHandler) BulkUpdateChunks(w http.ResponseWriter, r *http.Request) {
 	var req models.BulkUpdateRequest
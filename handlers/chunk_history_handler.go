@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ChunkHistoryHandler handles point-in-time reads of chunks and pages
+type ChunkHistoryHandler struct {
+	history            services.ChunkHistoryService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewChunkHistoryHandler creates a new chunk history handler
+func NewChunkHistoryHandler(
+	history services.ChunkHistoryService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *ChunkHistoryHandler {
+	return &ChunkHistoryHandler{
+		history:            history,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// parseHistoryTime parses an RFC 3339 time or a date, which means its start in UTC
+func parseHistoryTime(value string) (time.Time, error) {
+	if at, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return at, nil
+	}
+	at, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 time or a YYYY-MM-DD date", value)
+	}
+	return at, nil
+}
+
+// reader opens a reader at the request's as_of time, writing the error response if it
+// cannot
+func (h *ChunkHistoryHandler) reader(w http.ResponseWriter, r *http.Request) (services.ChunkSnapshotReader, int, error) {
+	value := r.URL.Query().Get("as_of")
+	if value == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "as_of is required", "")
+		return nil, http.StatusBadRequest, nil
+	}
+	at, err := parseHistoryTime(value)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid as_of parameter", err.Error())
+		return nil, http.StatusBadRequest, err
+	}
+	reader, err := h.history.AsOf(r.Context(), at)
+	if err != nil {
+		status := h.writeHistoryError(w, err)
+		return nil, status, err
+	}
+	return reader, http.StatusOK, nil
+}
+
+// writeHistoryError maps chunk history errors to responses and returns the status
+func (h *ChunkHistoryHandler) writeHistoryError(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidAsOf):
+		writeErrorResponse(w, http.StatusBadRequest, "invalid time", err.Error())
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrHistoricalChunkNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "chunk not found at that time", err.Error())
+		return http.StatusNotFound
+	default:
+		return writeServiceError(w, http.StatusInternalServerError, "failed to read chunk history", err)
+	}
+}
+
+// GetChunk handles GET /api/v1/history/chunks/{id}?as_of=2024-03-01T00:00:00Z
+func (h *ChunkHistoryHandler) GetChunk(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_chunk_as_of", w, func() (int, error) {
+		reader, status, err := h.reader(w, r)
+		if reader == nil {
+			return status, err
+		}
+		chunk, err := reader.GetChunk(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			return h.writeHistoryError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, chunk)
+		return http.StatusOK, nil
+	})
+}
+
+// GetChildren handles GET /api/v1/history/chunks/{id}/children?as_of=...
+func (h *ChunkHistoryHandler) GetChildren(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_children_as_of", w, func() (int, error) {
+		reader, status, err := h.reader(w, r)
+		if reader == nil {
+			return status, err
+		}
+		children, err := reader.GetChildren(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			return h.writeHistoryError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"as_of":    reader.AsOf(),
+			"children": children,
+			"count":    len(children),
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// GetPage handles GET /api/v1/history/pages/{id}?as_of=...
+func (h *ChunkHistoryHandler) GetPage(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_page_as_of", w, func() (int, error) {
+		reader, status, err := h.reader(w, r)
+		if reader == nil {
+			return status, err
+		}
+		page, err := reader.GetPage(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			return h.writeHistoryError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, page)
+		return http.StatusOK, nil
+	})
+}
+
+// Search handles GET /api/v1/history/search?q=...&as_of=...&limit=20
+func (h *ChunkHistoryHandler) Search(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("search_as_of", w, func() (int, error) {
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid limit parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			limit = parsed
+		}
+		reader, status, err := h.reader(w, r)
+		if reader == nil {
+			return status, err
+		}
+		results, err := reader.Search(r.Context(), r.URL.Query().Get("q"), limit)
+		if err != nil {
+			return h.writeHistoryError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"as_of":   reader.AsOf(),
+			"results": results,
+			"count":   len(results),
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// GetHistory handles GET /api/v1/chunks/{id}/history?limit=50
+func (h *ChunkHistoryHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_chunk_history", w, func() (int, error) {
+		limit := 0
+		if value := r.URL.Query().Get("limit"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid limit parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			limit = parsed
+		}
+		revisions, err := h.history.History(r.Context(), mux.Vars(r)["id"], limit)
+		if err != nil {
+			return h.writeHistoryError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"revisions": revisions,
+			"count":     len(revisions),
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// DiffPage handles GET /api/v1/history/pages/{id}/diff?from=...&to=...; to defaults to now
+func (h *ChunkHistoryHandler) DiffPage(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("diff_page", w, func() (int, error) {
+		query := r.URL.Query()
+		if query.Get("from") == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "from is required", "")
+			return http.StatusBadRequest, nil
+		}
+		from, err := parseHistoryTime(query.Get("from"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid from parameter", err.Error())
+			return http.StatusBadRequest, err
+		}
+		to := time.Now()
+		if value := query.Get("to"); value != "" {
+			if to, err = parseHistoryTime(value); err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid to parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+		}
+
+		diff, err := h.history.DiffPage(r.Context(), mux.Vars(r)["id"], from, to)
+		if err != nil {
+			return h.writeHistoryError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, diff)
+		return http.StatusOK, nil
+	})
+}
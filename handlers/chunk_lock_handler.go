@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// ChunkLockHeader carries the token of the lock a request holds, letting it refresh and
+// release the lock and restructure the subtree it covers
+const ChunkLockHeader = "X-Chunk-Lock"
+
+// ChunkLockHandler handles chunk lock HTTP requests
+type ChunkLockHandler struct {
+	locks              services.ChunkLockService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewChunkLockHandler creates a new chunk lock handler
+func NewChunkLockHandler(
+	locks services.ChunkLockService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *ChunkLockHandler {
+	return &ChunkLockHandler{
+		locks:              locks,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetLock handles GET /api/v1/chunks/{id}/lock
+func (h *ChunkLockHandler) GetLock(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_chunk_lock", w, func() (int, error) {
+		lock, err := h.locks.LockStatus(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			status := h.writeLockError(w, "failed to get chunk lock", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"locked": lock != nil,
+			"lock":   lock,
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// AcquireLock handles POST /api/v1/chunks/{id}/lock
+func (h *ChunkLockHandler) AcquireLock(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("acquire_chunk_lock", w, func() (int, error) {
+		ttl, err := decodeLockTTL(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		lock, err := h.locks.AcquireLock(r.Context(), mux.Vars(r)["id"], ttl)
+		if err != nil {
+			status := h.writeLockError(w, "failed to acquire chunk lock", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, lock)
+		return http.StatusCreated, nil
+	})
+}
+
+// RefreshLock handles PUT /api/v1/chunks/{id}/lock
+func (h *ChunkLockHandler) RefreshLock(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("refresh_chunk_lock", w, func() (int, error) {
+		ttl, err := decodeLockTTL(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		lock, err := h.locks.RefreshLock(r.Context(), mux.Vars(r)["id"], r.Header.Get(ChunkLockHeader), ttl)
+		if err != nil {
+			status := h.writeLockError(w, "failed to refresh chunk lock", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, lock)
+		return http.StatusOK, nil
+	})
+}
+
+// ReleaseLock handles DELETE /api/v1/chunks/{id}/lock
+func (h *ChunkLockHandler) ReleaseLock(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("release_chunk_lock", w, func() (int, error) {
+		if err := h.locks.ReleaseLock(r.Context(), mux.Vars(r)["id"], r.Header.Get(ChunkLockHeader)); err != nil {
+			status := h.writeLockError(w, "failed to release chunk lock", err)
+			return status, err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
+
+// decodeLockTTL reads the optional body of an acquire or refresh; an empty body asks for
+// the default TTL
+func decodeLockTTL(r *http.Request) (time.Duration, error) {
+	var req models.ChunkLockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	return time.Duration(req.TTLSeconds) * time.Second, nil
+}
+
+func (h *ChunkLockHandler) writeLockError(w http.ResponseWriter, message string, err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidChunkLock):
+		writeErrorResponse(w, http.StatusBadRequest, "invalid chunk lock", err.Error())
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrChunkLocked):
+		writeErrorResponse(w, http.StatusConflict, "chunk is locked", err.Error())
+		return http.StatusConflict
+	case errors.Is(err, services.ErrChunkLockNotHeld):
+		writeErrorResponse(w, http.StatusConflict, "chunk lock not held", err.Error())
+		return http.StatusConflict
+	case strings.Contains(err.Error(), "not found"):
+		writeErrorResponse(w, http.StatusNotFound, "chunk not found", err.Error())
+		return http.StatusNotFound
+	}
+	return writeServiceError(w, http.StatusInternalServerError, message, err)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// emailFormMemory is how much of a webhook form is kept in memory; larger attachments are
+// spooled to temporary files
+const emailFormMemory = 8 << 20
+
+// EmailIngestHandler receives inbound email webhooks. Webhooks carry no API token; they
+// authenticate with the webhook secret as their basic auth password, or for Mailgun with
+// the signature Mailgun adds.
+type EmailIngestHandler struct {
+	email              services.EmailIngestService
+	webhookSecret      string
+	mailgunSigningKey  string
+	maxBytes           int64
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewEmailIngestHandler creates a new email ingestion handler accepting messages of up to
+// maxBytes
+func NewEmailIngestHandler(
+	email services.EmailIngestService,
+	webhookSecret string,
+	mailgunSigningKey string,
+	maxBytes int,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *EmailIngestHandler {
+	return &EmailIngestHandler{
+		email:              email,
+		webhookSecret:      webhookSecret,
+		mailgunSigningKey:  mailgunSigningKey,
+		maxBytes:           int64(maxBytes),
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// SendGridWebhook handles POST /api/v1/email/inbound/sendgrid
+func (h *EmailIngestHandler) SendGridWebhook(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("ingest_email_sendgrid", w, func() (int, error) {
+		return h.ingest(w, r, false, services.ParseSendGridForm)
+	})
+}
+
+// MailgunWebhook handles POST /api/v1/email/inbound/mailgun
+func (h *EmailIngestHandler) MailgunWebhook(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("ingest_email_mailgun", w, func() (int, error) {
+		return h.ingest(w, r, true, services.ParseMailgunForm)
+	})
+}
+
+func (h *EmailIngestHandler) ingest(w http.ResponseWriter, r *http.Request, mailgun bool, parse func(*multipart.Form) (*models.InboundEmail, error)) (int, error) {
+	// Refuse unauthenticated webhooks before reading their bodies, unless the credential is
+	// a Mailgun signature, which is part of the body
+	passwordOK := h.passwordAuthorized(r)
+	signed := mailgun && h.mailgunSigningKey != ""
+	if !passwordOK && !signed {
+		return h.unauthorized(w)
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxBytes)
+	if err := r.ParseMultipartForm(emailFormMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeErrorResponse(w, http.StatusRequestEntityTooLarge, "email too large", err.Error())
+			return http.StatusRequestEntityTooLarge, err
+		}
+		writeErrorResponse(w, http.StatusBadRequest, "invalid webhook body", err.Error())
+		return http.StatusBadRequest, err
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	if !passwordOK && !services.VerifyMailgunSignature(h.mailgunSigningKey,
+		strings.TrimSpace(r.FormValue("timestamp")),
+		strings.TrimSpace(r.FormValue("token")),
+		strings.TrimSpace(r.FormValue("signature"))) {
+		return h.unauthorized(w)
+	}
+
+	email, err := parse(r.MultipartForm)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid email", err.Error())
+		return http.StatusBadRequest, err
+	}
+	result, err := h.email.Ingest(r.Context(), email)
+	if err != nil {
+		// Providers retry deliveries that fail with a server error
+		status := writeServiceError(w, http.StatusInternalServerError, "failed to ingest email", err)
+		return status, err
+	}
+	for _, warning := range result.Warnings {
+		h.logger.Printf("Email %s: %s", result.MessageID, warning)
+	}
+
+	writeJSONResponse(w, http.StatusOK, result)
+	return http.StatusOK, nil
+}
+
+// passwordAuthorized checks the webhook's basic auth password
+func (h *EmailIngestHandler) passwordAuthorized(r *http.Request) bool {
+	_, password, ok := r.BasicAuth()
+	return ok && h.webhookSecret != "" &&
+		subtle.ConstantTimeCompare([]byte(password), []byte(h.webhookSecret)) == 1
+}
+
+func (h *EmailIngestHandler) unauthorized(w http.ResponseWriter) (int, error) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="email"`)
+	writeErrorResponse(w, http.StatusUnauthorized, "invalid webhook credentials", "")
+	return http.StatusUnauthorized, nil
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+)
+
+// EmbeddingBatchHandler handles batch embedding HTTP requests
+type EmbeddingBatchHandler struct {
+	embeddingBatch     services.EmbeddingBatchService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewEmbeddingBatchHandler creates a new batch embedding handler
+func NewEmbeddingBatchHandler(
+	embeddingBatch services.EmbeddingBatchService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *EmbeddingBatchHandler {
+	return &EmbeddingBatchHandler{
+		embeddingBatch:     embeddingBatch,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// EmbedBatch handles POST /api/v1/embeddings/batch. Chunks and texts that fail are
+// reported per item; the response is 200 as long as the batch could be processed.
+func (h *EmbeddingBatchHandler) EmbedBatch(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("embed_batch", w, func() (int, error) {
+		var req models.EmbeddingBatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		response, err := h.embeddingBatch.Embed(r.Context(), &req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidEmbeddingBatch) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid embedding batch", err.Error())
+				return http.StatusBadRequest, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to embed batch", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// GetUsage handles GET /api/v1/embeddings/usage?days=30
+func (h *EmbeddingBatchHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_embedding_usage", w, func() (int, error) {
+		var days int
+		if value := r.URL.Query().Get("days"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid days parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			days = parsed
+		}
+
+		report, err := h.embeddingBatch.Usage(r.Context(), days)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidEmbeddingBatch) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid usage request", err.Error())
+				return http.StatusBadRequest, err
+			}
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get embedding usage", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, report)
+		return http.StatusOK, nil
+	})
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+)
+
+// EntityResolutionHandler handles duplicate graph entity HTTP requests
+type EntityResolutionHandler struct {
+	resolutionService  services.EntityResolutionService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewEntityResolutionHandler creates a new entity resolution handler
+func NewEntityResolutionHandler(
+	resolutionService services.EntityResolutionService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *EntityResolutionHandler {
+	return &EntityResolutionHandler{
+		resolutionService:  resolutionService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// FindDuplicates handles GET /api/v1/graph/entities/duplicates?type=&min_similarity=0.9&limit=50&embeddings=true
+func (h *EntityResolutionHandler) FindDuplicates(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("find_duplicate_entities", w, func() (int, error) {
+		query := r.URL.Query()
+		opts := models.EntityResolutionOptions{EntityType: query.Get("type")}
+
+		if v := query.Get("min_similarity"); v != "" {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil || parsed <= 0 || parsed > 1 {
+				writeErrorResponse(w, http.StatusBadRequest, "min_similarity must be between 0 and 1", "")
+				return http.StatusBadRequest, nil
+			}
+			opts.MinSimilarity = parsed
+		}
+		if l := query.Get("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+				opts.Limit = parsed
+			}
+		}
+		if v := query.Get("embeddings"); v != "" {
+			useEmbeddings, err := strconv.ParseBool(v)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "embeddings must be true or false", "")
+				return http.StatusBadRequest, nil
+			}
+			opts.SkipEmbeddings = !useEmbeddings
+		}
+
+		candidates, err := h.resolutionService.FindCandidates(r.Context(), opts)
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to find duplicate entities", err)
+			return status, err
+		}
+
+		response := map[string]interface{}{
+			"candidates": candidates,
+			"count":      len(candidates),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// MergeEntities handles POST /api/v1/graph/entities/merge
+func (h *EntityResolutionHandler) MergeEntities(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("merge_entities", w, func() (int, error) {
+		var req models.EntityMergeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.resolutionService.MergeNodes(r.Context(), &req)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidEntityMerge):
+				writeErrorResponse(w, http.StatusBadRequest, "invalid entity merge", err.Error())
+				return http.StatusBadRequest, err
+			case errors.Is(err, services.ErrGraphNodeNotFound):
+				writeErrorResponse(w, http.StatusNotFound, "graph node not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to merge entities", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+)
+
+// GraphAnalyticsHandler handles knowledge graph analytics HTTP requests
+type GraphAnalyticsHandler struct {
+	analyticsService   services.GraphAnalyticsService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewGraphAnalyticsHandler creates a new graph analytics handler
+func NewGraphAnalyticsHandler(
+	analyticsService services.GraphAnalyticsService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *GraphAnalyticsHandler {
+	return &GraphAnalyticsHandler{
+		analyticsService:   analyticsService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// RunAnalytics handles POST /api/v1/graph/analytics
+func (h *GraphAnalyticsHandler) RunAnalytics(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("run_graph_analytics", w, func() (int, error) {
+		var opts models.GraphAnalyticsOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.analyticsService.RunAnalytics(r.Context(), opts)
+		if err != nil {
+			if errors.Is(err, services.ErrGraphAnalyticsRunning) {
+				writeErrorResponse(w, http.StatusConflict, "graph analytics already running", err.Error())
+				return http.StatusConflict, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to run graph analytics", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// GetTopEntities handles GET /api/v1/graph/entities/top?metric=pagerank&type=&limit=20
+func (h *GraphAnalyticsHandler) GetTopEntities(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_top_entities", w, func() (int, error) {
+		query := r.URL.Query()
+		metric := query.Get("metric")
+		if metric == "" {
+			metric = models.GraphMetricPageRank
+		}
+		if metric != models.GraphMetricPageRank && metric != models.GraphMetricBetweenness {
+			writeErrorResponse(w, http.StatusBadRequest, "metric must be pagerank or betweenness", "")
+			return http.StatusBadRequest, nil
+		}
+
+		limit := 20 // default
+		if l := query.Get("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+				limit = parsed
+			}
+		}
+
+		entities, err := h.analyticsService.TopEntities(r.Context(), metric, query.Get("type"), limit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get top entities", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"metric":   metric,
+			"entities": entities,
+			"count":    len(entities),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// GetCommunities handles GET /api/v1/graph/communities?min_size=2&member_limit=10
+func (h *GraphAnalyticsHandler) GetCommunities(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_communities", w, func() (int, error) {
+		query := r.URL.Query()
+		minSize, _ := strconv.Atoi(query.Get("min_size"))
+		memberLimit, _ := strconv.Atoi(query.Get("member_limit"))
+
+		communities, err := h.analyticsService.GetCommunities(r.Context(), minSize, memberLimit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get communities", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"communities": communities,
+			"count":       len(communities),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/services"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GraphEvidenceHandler handles knowledge graph provenance HTTP requests
+type GraphEvidenceHandler struct {
+	evidenceService    services.GraphEvidenceService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewGraphEvidenceHandler creates a new graph evidence handler
+func NewGraphEvidenceHandler(
+	evidenceService services.GraphEvidenceService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *GraphEvidenceHandler {
+	return &GraphEvidenceHandler{
+		evidenceService:    evidenceService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetEvidence handles GET /api/v1/graph/edges/{id}/evidence
+func (h *GraphEvidenceHandler) GetEvidence(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_graph_evidence", w, func() (int, error) {
+		edgeID := mux.Vars(r)["id"]
+		if edgeID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "edge ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		evidence, err := h.evidenceService.GetEvidence(r.Context(), edgeID)
+		if errors.Is(err, services.ErrGraphEdgeNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "graph edge not found", err.Error())
+			return http.StatusNotFound, nil
+		}
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to get graph evidence", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, evidence)
+		return http.StatusOK, nil
+	})
+}
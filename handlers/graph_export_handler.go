@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GraphExportHandler handles knowledge graph export HTTP requests
+type GraphExportHandler struct {
+	exportService      services.GraphExportService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewGraphExportHandler creates a new graph export handler
+func NewGraphExportHandler(
+	exportService services.GraphExportService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *GraphExportHandler {
+	return &GraphExportHandler{
+		exportService:      exportService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// graphExportContentTypes maps each format to its response content type and file extension
+var graphExportContentTypes = map[models.GraphExportFormat][2]string{
+	models.GraphExportGraphML:   {"application/graphml+xml", "graphml"},
+	models.GraphExportGEXF:      {"application/gexf+xml", "gexf"},
+	models.GraphExportCytoscape: {"application/json", "cyjs"},
+}
+
+// ExportGraph handles GET /api/v1/graph/export?format=graphml&entity=&node_id=&page_id=&depth=1&min_confidence=&as_of=
+func (h *GraphExportHandler) ExportGraph(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("export_graph", w, func() (int, error) {
+		query := r.URL.Query()
+		formatName := query.Get("format")
+		if formatName == "" {
+			formatName = string(models.GraphExportGraphML)
+		}
+		format, err := services.ParseGraphExportFormat(formatName)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid format", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		filter := models.GraphExportFilter{
+			NodeID:            query.Get("node_id"),
+			EntityName:        query.Get("entity"),
+			PageID:            query.Get("page_id"),
+			EntityTypes:       splitQueryList(query["entity_type"]),
+			RelationshipTypes: splitQueryList(query["relationship_type"]),
+		}
+		if d := query.Get("depth"); d != "" {
+			if filter.Depth, err = strconv.Atoi(d); err != nil || filter.Depth < 0 {
+				writeErrorResponse(w, http.StatusBadRequest, "depth must be a non-negative integer", "")
+				return http.StatusBadRequest, nil
+			}
+		}
+		if c := query.Get("min_confidence"); c != "" {
+			if filter.MinConfidence, err = strconv.ParseFloat(c, 64); err != nil || filter.MinConfidence < 0 || filter.MinConfidence > 1 {
+				writeErrorResponse(w, http.StatusBadRequest, "min_confidence must be between 0 and 1", "")
+				return http.StatusBadRequest, nil
+			}
+		}
+		if value := query.Get("as_of"); value != "" {
+			asOf, err := parseHistoryTime(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid as_of parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			filter.AsOf = &asOf
+		}
+		if m := query.Get("max_nodes"); m != "" {
+			if parsed, err := strconv.Atoi(m); err == nil && parsed > 0 {
+				filter.MaxNodes = parsed
+			}
+		}
+
+		// Buffer the export so a failure can still be reported as a JSON error
+		var buf bytes.Buffer
+		stats, err := h.exportService.ExportGraph(r.Context(), &buf, format, filter)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to export graph", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		contentType := graphExportContentTypes[format]
+		w.Header().Set("Content-Type", contentType[0])
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="knowledge-graph.%s"`, contentType[1]))
+		w.Header().Set("X-Graph-Node-Count", strconv.Itoa(stats.NodeCount))
+		w.Header().Set("X-Graph-Edge-Count", strconv.Itoa(stats.EdgeCount))
+		w.Header().Set("X-Graph-Truncated", strconv.FormatBool(stats.Truncated))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			h.logger.Printf("Failed to write graph export: %v", err)
+		}
+		return http.StatusOK, nil
+	})
+}
+
+// splitQueryList accepts both repeated and comma-separated query values
+func splitQueryList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
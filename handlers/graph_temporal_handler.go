@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GraphTemporalHandler handles HTTP requests about when graph relationships held
+type GraphTemporalHandler struct {
+	temporalService    services.GraphTemporalService
+	conflictDetector   *services.ConflictDetector
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewGraphTemporalHandler creates a new graph temporal handler
+func NewGraphTemporalHandler(
+	temporalService services.GraphTemporalService,
+	conflictDetector *services.ConflictDetector,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *GraphTemporalHandler {
+	return &GraphTemporalHandler{
+		temporalService:    temporalService,
+		conflictDetector:   conflictDetector,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// SetValidity handles PUT /api/v1/graph/edges/{id}/validity
+func (h *GraphTemporalHandler) SetValidity(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("set_edge_validity", w, func() (int, error) {
+		var req models.EdgeValidityRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		edge, err := h.temporalService.SetValidity(r.Context(), mux.Vars(r)["id"], &req)
+		if err != nil {
+			status := writeGraphTemporalError(w, "failed to set edge validity", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, edge)
+		return http.StatusOK, nil
+	})
+}
+
+// Supersede handles POST /api/v1/graph/edges/{id}/supersede
+func (h *GraphTemporalHandler) Supersede(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("supersede_edge", w, func() (int, error) {
+		var req models.EdgeSupersedeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.temporalService.Supersede(r.Context(), mux.Vars(r)["id"], &req)
+		if err != nil {
+			status := writeGraphTemporalError(w, "failed to supersede edge", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, result)
+		return http.StatusCreated, nil
+	})
+}
+
+// GetConflicts handles GET /api/v1/graph/conflicts?relationship_type=&as_of=&limit=100
+func (h *GraphTemporalHandler) GetConflicts(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_graph_conflicts", w, func() (int, error) {
+		query := r.URL.Query()
+		opts := models.GraphConflictOptions{RelationshipTypes: splitQueryList(query["relationship_type"])}
+		if value := query.Get("as_of"); value != "" {
+			asOf, err := parseHistoryTime(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid as_of parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			opts.AsOf = &asOf
+		}
+		if l := query.Get("limit"); l != "" {
+			if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+				opts.Limit = parsed
+			}
+		}
+
+		conflicts, err := h.conflictDetector.Detect(r.Context(), opts)
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to detect graph conflicts", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"conflicts": conflicts,
+			"count":     len(conflicts),
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// writeGraphTemporalError maps graph temporal errors to responses and returns the status
+func writeGraphTemporalError(w http.ResponseWriter, message string, err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidEdgeValidity):
+		writeErrorResponse(w, http.StatusBadRequest, "invalid edge validity", err.Error())
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrGraphEdgeNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "graph edge not found", err.Error())
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrGraphNodeNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "graph node not found", err.Error())
+		return http.StatusNotFound
+	}
+	return writeServiceError(w, http.StatusInternalServerError, message, err)
+}
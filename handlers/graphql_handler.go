@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// maxGraphQLRequestSize bounds a posted GraphQL request
+const maxGraphQLRequestSize = 1 << 20
+
+// GraphQLHandler handles GraphQL queries over chunks, tags, templates and graph nodes
+type GraphQLHandler struct {
+	graphql            services.GraphQLService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(
+	graphql services.GraphQLService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *GraphQLHandler {
+	return &GraphQLHandler{
+		graphql:            graphql,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// Query handles POST /api/v1/graphql with a JSON body, and GET /api/v1/graphql with the
+// query, operationName and JSON encoded variables as query parameters
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("graphql_query", w, func() (int, error) {
+		var req models.GraphQLRequest
+		if r.Method == http.MethodGet {
+			params := r.URL.Query()
+			req.Query = params.Get("query")
+			req.OperationName = params.Get("operationName")
+			if variables := params.Get("variables"); variables != "" {
+				if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "invalid variables", err.Error())
+					return http.StatusBadRequest, err
+				}
+			}
+		} else if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxGraphQLRequestSize)).Decode(&req); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "request too large", err.Error())
+				return http.StatusRequestEntityTooLarge, err
+			}
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if req.Query == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "query is required", "")
+			return http.StatusBadRequest, errors.New("query is required")
+		}
+
+		// Field errors still answer 200 with partial data; requests that could not be
+		// executed at all answer 400
+		response := h.graphql.Execute(r.Context(), &req)
+		status := http.StatusOK
+		if response.Data == nil {
+			status = http.StatusBadRequest
+		}
+		writeJSONResponse(w, status, response)
+		return status, nil
+	})
+}
+
+// Schema handles GET /api/v1/graphql/schema, returning the schema definition language
+func (h *GraphQLHandler) Schema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	io.WriteString(w, h.graphql.Schema())
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// HTTPCachePolicy controls the caching headers of chunk and page reads
+type HTTPCachePolicy struct {
+	// Enabled sends validators and answers matching conditional requests with 304
+	Enabled bool
+	// MaxAge is how long clients may reuse a response without revalidating it
+	MaxAge time.Duration
+}
+
+// cacheControl returns the Cache-Control value of responses. Reads depend on the
+// caller's page permissions, so shared caches must not store them.
+func (p HTTPCachePolicy) cacheControl() string {
+	if p.MaxAge <= 0 {
+		return "private, no-cache"
+	}
+	return "private, max-age=" + strconv.Itoa(int(p.MaxAge/time.Second))
+}
+
+// notModified sets the caching headers of a read with the given validators and reports
+// whether the request's conditions match them, in which case a 304 has been written and
+// the body must be skipped. A zero lastModified sends no Last-Modified.
+func (p HTTPCachePolicy) notModified(w http.ResponseWriter, r *http.Request, etag string, lastModified time.Time) bool {
+	if !p.Enabled {
+		return false
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", p.cacheControl())
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	// If-None-Match takes precedence; If-Modified-Since is only consulted without it
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		if !etagMatches(match, etag) {
+			return false
+		}
+	} else {
+		since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+		if err != nil || lastModified.IsZero() || lastModified.Truncate(time.Second).After(since) {
+			return false
+		}
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing weakly
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// chunkValidators derives a weak ETag from the IDs, versions, last_updated times and locks
+// of chunks, so adding, removing, reordering, editing or locking any of them changes it, and
+// returns the latest last_updated
+func chunkValidators(chunks []models.UnifiedChunkRecord) (string, time.Time) {
+	hash := sha256.New()
+	var lastModified time.Time
+	for _, chunk := range chunks {
+		fmt.Fprintf(hash, "%s:%d:%d\n", chunk.ChunkID, chunk.Version, chunk.LastUpdated.UnixNano())
+		if chunk.Lock != nil {
+			fmt.Fprintf(hash, "lock:%s:%s:%d\n", chunk.Lock.ChunkID, chunk.Lock.Holder, chunk.Lock.ExpiresAt.UnixNano())
+		}
+		if chunk.LastUpdated.After(lastModified) {
+			lastModified = chunk.LastUpdated
+		}
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`, lastModified
+}
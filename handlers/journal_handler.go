@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// JournalHandler handles daily note HTTP requests
+type JournalHandler struct {
+	journal            services.JournalService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewJournalHandler creates a new journal handler
+func NewJournalHandler(
+	journal services.JournalService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *JournalHandler {
+	return &JournalHandler{
+		journal:            journal,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetToday handles GET /api/v1/journal/today
+func (h *JournalHandler) GetToday(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_journal_today", w, func() (int, error) {
+		day, err := h.journal.Today(r.Context())
+		if err != nil {
+			status := writeJournalError(w, "failed to get today's journal page", err)
+			return status, err
+		}
+		writeJSONResponse(w, http.StatusOK, day)
+		return http.StatusOK, nil
+	})
+}
+
+// GetDay handles GET /api/v1/journal/days/{date}. Callers who may write get the page
+// created when the day has none.
+func (h *JournalHandler) GetDay(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_journal_day", w, func() (int, error) {
+		create := services.Authorize(r.Context(), services.PermissionWrite) == nil
+		day, err := h.journal.GetDay(r.Context(), mux.Vars(r)["date"], create)
+		if err != nil {
+			status := writeJournalError(w, "failed to get journal page", err)
+			return status, err
+		}
+		writeJSONResponse(w, http.StatusOK, day)
+		return http.StatusOK, nil
+	})
+}
+
+// AppendToToday handles POST /api/v1/journal/today/entries
+func (h *JournalHandler) AppendToToday(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("append_journal_entry", w, func() (int, error) {
+		var req models.JournalAppendRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		entry, err := h.journal.AppendToToday(r.Context(), &req)
+		if err != nil {
+			status := writeJournalError(w, "failed to append journal entry", err)
+			return status, err
+		}
+		writeJSONResponse(w, http.StatusCreated, entry)
+		return http.StatusCreated, nil
+	})
+}
+
+// ListDays handles GET /api/v1/journal?from=YYYY-MM-DD&to=YYYY-MM-DD&entries=true
+func (h *JournalHandler) ListDays(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("list_journal_days", w, func() (int, error) {
+		query := r.URL.Query()
+		req := &models.JournalRangeQuery{From: query.Get("from"), To: query.Get("to")}
+		if raw := query.Get("entries"); raw != "" {
+			includeEntries, err := strconv.ParseBool(raw)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid entries parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			req.IncludeEntries = includeEntries
+		}
+
+		days, err := h.journal.Entries(r.Context(), req)
+		if err != nil {
+			status := writeJournalError(w, "failed to list journal days", err)
+			return status, err
+		}
+		writeJSONResponse(w, http.StatusOK, days)
+		return http.StatusOK, nil
+	})
+}
+
+func writeJournalError(w http.ResponseWriter, message string, err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidJournalDate), errors.Is(err, services.ErrInvalidJournalEntry):
+		writeErrorResponse(w, http.StatusBadRequest, "invalid journal request", err.Error())
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrJournalDayNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "journal day not found", err.Error())
+		return http.StatusNotFound
+	}
+	return writeServiceError(w, http.StatusInternalServerError, message, err)
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// liveMaxMessageSize bounds one client message on a live outline connection
+const liveMaxMessageSize = 1 << 20
+
+// LiveOutlineHandler serves live collaborative editing of a page over WebSocket
+type LiveOutlineHandler struct {
+	liveOutline services.LiveOutlineService
+	heartbeat   time.Duration
+	logger      *log.Logger
+}
+
+// NewLiveOutlineHandler creates a new live outline handler. Connections are pinged every
+// heartbeat and dropped when nothing arrives for two heartbeats.
+func NewLiveOutlineHandler(liveOutline services.LiveOutlineService, heartbeat time.Duration, logger *log.Logger) *LiveOutlineHandler {
+	if heartbeat <= 0 {
+		heartbeat = 30 * time.Second
+	}
+	return &LiveOutlineHandler{
+		liveOutline: liveOutline,
+		heartbeat:   heartbeat,
+		logger:      logger,
+	}
+}
+
+// Connect handles GET /api/v1/pages/{id}/live?name= as a WebSocket.
+//
+// Clients send {"type":"op","operation":{...}} after applying the operation locally and
+// receive an ack with the stored chunk or a reject to roll back. Changes by others arrive
+// as {"type":"event"} in sequence order, cursor moves as {"type":"presence"}. After a
+// {"type":"resync"} the client has missed messages and should reload the page.
+func (h *LiveOutlineHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	pageID := mux.Vars(r)["id"]
+	if pageID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "page ID is required", "")
+		return
+	}
+
+	session, err := h.liveOutline.Join(r.Context(), pageID, r.URL.Query().Get("name"))
+	if err != nil {
+		writeErrorResponse(w, http.StatusNotFound, "page not available for live editing", err.Error())
+		return
+	}
+	defer h.liveOutline.Leave(session)
+
+	conn, err := upgradeWebSocket(w, r, liveMaxMessageSize)
+	if err != nil {
+		return
+	}
+	defer conn.Close(wsCloseNormal, "")
+	conn.SetReadTimeout(2 * h.heartbeat)
+
+	// The request context ends with the handler, not with the hijacked connection
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		h.readMessages(ctx, conn, session)
+	}()
+
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+
+	var reportedDrops uint64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-session.Messages:
+			if !ok {
+				return
+			}
+			// A client too slow to keep up has missed messages and must reload
+			if dropped := session.Dropped(); dropped > reportedDrops {
+				reportedDrops = dropped
+				if err := writeLiveMessage(conn, &models.LiveServerMessage{Type: models.LiveMessageResync}); err != nil {
+					return
+				}
+			}
+			if err := writeLiveMessage(conn, msg); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if err := conn.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readMessages applies client messages until the connection closes
+func (h *LiveOutlineHandler) readMessages(ctx context.Context, conn *wsConn, session *services.LiveSession) {
+	for {
+		opcode, data, err := conn.ReadMessage()
+		if err != nil {
+			if err != errWebSocketClosed {
+				h.logger.Printf("Live connection %s on page %s ended: %v", session.ID, session.PageID, err)
+			}
+			return
+		}
+		if opcode != wsOpText {
+			conn.Close(wsCloseUnsupported, "only text messages are supported")
+			return
+		}
+
+		var msg models.LiveClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			writeLiveMessage(conn, &models.LiveServerMessage{Type: models.LiveMessageError, Code: models.LiveErrorInvalid, Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+
+		switch msg.Type {
+		case models.LiveMessageOperation:
+			h.liveOutline.Apply(ctx, session, msg.Operation)
+		case models.LiveMessagePresence:
+			if msg.Presence != nil {
+				h.liveOutline.UpdatePresence(session, *msg.Presence)
+			}
+		default:
+			writeLiveMessage(conn, &models.LiveServerMessage{Type: models.LiveMessageError, Code: models.LiveErrorInvalid, Error: "unknown message type " + string(msg.Type)})
+		}
+	}
+}
+
+func writeLiveMessage(conn *wsConn, msg *models.LiveServerMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
@@ -58,6 +58,7 @@ func (c *ModelConverter) FromUnifiedChunk(unified *models.UnifiedChunkRecord) *m
 		Metadata:        unified.Metadata,
 		CreatedAt:       unified.CreatedTime,
 		UpdatedAt:       unified.LastUpdated,
+		Lock:            unified.Lock,
 	}
 
 	// Set text_id from page reference if available
@@ -125,6 +126,14 @@ func (c *ModelConverter) ToUnifiedSearchQuery(query string, filters map[string]i
 		if metadata, ok := filters["metadata"].(map[string]interface{}); ok {
 			searchQuery.Metadata = metadata
 		}
+
+		if filter, ok := filters["filter"].(*models.FilterExpression); ok {
+			searchQuery.Filter = filter
+		}
+
+		if language, ok := filters["language"].(string); ok {
+			searchQuery.Language = language
+		}
 	}
 
 	return searchQuery
@@ -176,6 +185,10 @@ func (c *ModelConverter) ApplyUpdateRequest(chunk *models.UnifiedChunkRecord, re
 		chunk.Metadata = req.Metadata
 	}
 
+	if req.Version != nil {
+		chunk.Version = *req.Version
+	}
+
 	// Update last modified time
 	chunk.LastUpdated = time.Now()
 }
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxNoteImportUploadSize bounds an uploaded export archive
+const maxNoteImportUploadSize = 256 << 20
+
+// NoteImportHandler handles imports from other note-taking apps
+type NoteImportHandler struct {
+	imports            services.NoteImportService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewNoteImportHandler creates a new note import handler
+func NewNoteImportHandler(
+	imports services.NoteImportService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *NoteImportHandler {
+	return &NoteImportHandler{
+		imports:            imports,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// Import handles POST /api/v1/import/{format} with the zipped export as the request body.
+// mode=upsert merges the export with earlier imports of the same source, and answers 200
+// with the diff instead of 201.
+func (h *NoteImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("import_notes", w, func() (int, error) {
+		format, err := services.ParseNoteImportFormat(mux.Vars(r)["format"])
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "unsupported import format", err.Error())
+			return http.StatusBadRequest, err
+		}
+		opts, err := noteImportOptions(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid import options", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		// Zip archives are read from the end, so the body is spooled to disk first
+		spool, err := os.CreateTemp("", "note-import-*.zip")
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to store upload", err.Error())
+			return http.StatusInternalServerError, err
+		}
+		defer func() {
+			spool.Close()
+			os.Remove(spool.Name())
+		}()
+
+		size, err := io.Copy(spool, http.MaxBytesReader(w, r.Body, maxNoteImportUploadSize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "export archive too large", err.Error())
+				return http.StatusRequestEntityTooLarge, err
+			}
+			writeErrorResponse(w, http.StatusBadRequest, "failed to read upload", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		archive, err := zip.NewReader(spool, size)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "export must be a zip archive", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.imports.ImportWithOptions(r.Context(), format, archive, opts)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidNoteImport) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid export", err.Error())
+				return http.StatusBadRequest, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to import notes", err)
+			return status, err
+		}
+
+		status := http.StatusCreated
+		if opts.Mode == models.NoteImportUpsert {
+			status = http.StatusOK
+		}
+		writeJSONResponse(w, status, result)
+		return status, nil
+	})
+}
+
+// noteImportOptions reads the mode, source, delete_missing and overwrite query parameters
+func noteImportOptions(r *http.Request) (models.NoteImportOptions, error) {
+	query := r.URL.Query()
+	mode, err := services.ParseNoteImportMode(query.Get("mode"))
+	if err != nil {
+		return models.NoteImportOptions{}, err
+	}
+	opts := models.NoteImportOptions{Mode: mode, Source: query.Get("source")}
+	for name, flag := range map[string]*bool{"delete_missing": &opts.DeleteMissing, "overwrite": &opts.Overwrite} {
+		if value := query.Get(name); value != "" {
+			if *flag, err = strconv.ParseBool(value); err != nil {
+				return opts, fmt.Errorf("invalid %s parameter: %w", name, err)
+			}
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"time"
+)
+
+// OptimizedSearchHandler handles cached semantic search HTTP requests
+type OptimizedSearchHandler struct {
+	searchService      *services.OptimizedSearchService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewOptimizedSearchHandler creates a new optimized search handler
+func NewOptimizedSearchHandler(
+	searchService *services.OptimizedSearchService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *OptimizedSearchHandler {
+	return &OptimizedSearchHandler{
+		searchService:      searchService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// Search handles POST /api/v1/search/optimized
+func (h *OptimizedSearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("optimized_search", w, func() (int, error) {
+		// Caching is on unless the client explicitly opts out
+		req := models.OptimizedSearchRequest{UseCache: true}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if req.Query == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "query is required", "")
+			return http.StatusBadRequest, nil
+		}
+		if req.Workspace == "" {
+			req.Workspace = r.Header.Get(WorkspaceHeader)
+		}
+		if err := services.ValidateSearchFacets(req.Facets); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid facets", err.Error())
+			return http.StatusBadRequest, nil
+		}
+
+		response, err := h.searchService.Search(r.Context(), &req)
+		if errors.Is(err, services.ErrRerankUnavailable) {
+			writeErrorResponse(w, http.StatusBadRequest, "re-ranking is not available", err.Error())
+			return http.StatusBadRequest, nil
+		}
+		if errors.Is(err, services.ErrInvalidMMRLambda) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid mmr_lambda", err.Error())
+			return http.StatusBadRequest, nil
+		}
+		if errors.Is(err, services.ErrInvalidFilter) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid filters", err.Error())
+			return http.StatusBadRequest, nil
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to perform search", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
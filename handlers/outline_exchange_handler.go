@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// maxOPMLImportSize bounds an uploaded OPML document
+const maxOPMLImportSize = 10 << 20
+
+// OutlineExchangeHandler handles OPML import and export HTTP requests
+type OutlineExchangeHandler struct {
+	outlines           services.OutlineExchangeService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewOutlineExchangeHandler creates a new outline exchange handler
+func NewOutlineExchangeHandler(
+	outlines services.OutlineExchangeService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *OutlineExchangeHandler {
+	return &OutlineExchangeHandler{
+		outlines:           outlines,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// ExportOPML handles GET /api/v1/pages/{id}/opml
+func (h *OutlineExchangeHandler) ExportOPML(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("export_opml", w, func() (int, error) {
+		pageID := mux.Vars(r)["id"]
+		if pageID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "page ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		// Buffer the export so a failure can still be reported as a JSON error
+		var buf bytes.Buffer
+		stats, err := h.outlines.ExportOPML(r.Context(), &buf, pageID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not a page") {
+				writeErrorResponse(w, http.StatusNotFound, "page not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to export outline", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="page-%s.opml"`, pageID))
+		w.Header().Set("X-Outline-Chunk-Count", strconv.Itoa(stats.Chunks))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			h.logger.Printf("Failed to write OPML export: %v", err)
+		}
+		return http.StatusOK, nil
+	})
+}
+
+// ImportOPML handles POST /api/v1/pages/import/opml?parent_id=&title= with the OPML
+// document as the request body
+func (h *OutlineExchangeHandler) ImportOPML(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("import_opml", w, func() (int, error) {
+		query := r.URL.Query()
+		opts := models.OutlineImportOptions{
+			ParentID: query.Get("parent_id"),
+			Title:    strings.TrimSpace(query.Get("title")),
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxOPMLImportSize)
+		result, err := h.outlines.ImportOPML(r.Context(), body, opts)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.As(err, &tooLarge):
+				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "OPML document too large", err.Error())
+				return http.StatusRequestEntityTooLarge, err
+			case errors.Is(err, services.ErrInvalidOPML):
+				writeErrorResponse(w, http.StatusBadRequest, "invalid OPML document", err.Error())
+				return http.StatusBadRequest, err
+			case result == nil && strings.Contains(err.Error(), "not found"):
+				writeErrorResponse(w, http.StatusNotFound, "parent chunk not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to import outline", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, result)
+		return http.StatusCreated, nil
+	})
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// PageACLHandler handles page ACL and effective permission HTTP requests
+type PageACLHandler struct {
+	acls               services.PageACLService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewPageACLHandler creates a new page ACL handler
+func NewPageACLHandler(
+	acls services.PageACLService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *PageACLHandler {
+	return &PageACLHandler{
+		acls:               acls,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetACL handles GET /api/v1/pages/{id}/acl
+func (h *PageACLHandler) GetACL(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_page_acl", w, func() (int, error) {
+		acl, err := h.acls.GetACL(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			status := h.writeACLError(w, "failed to get page ACL", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, acl)
+		return http.StatusOK, nil
+	})
+}
+
+// SetACL handles PUT /api/v1/pages/{id}/acl
+func (h *PageACLHandler) SetACL(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("set_page_acl", w, func() (int, error) {
+		var req models.PageACLRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		acl, err := h.acls.SetACL(r.Context(), mux.Vars(r)["id"], &req)
+		if err != nil {
+			status := h.writeACLError(w, "failed to set page ACL", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, acl)
+		return http.StatusOK, nil
+	})
+}
+
+// DeleteACL handles DELETE /api/v1/pages/{id}/acl
+func (h *PageACLHandler) DeleteACL(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("delete_page_acl", w, func() (int, error) {
+		if err := h.acls.DeleteACL(r.Context(), mux.Vars(r)["id"]); err != nil {
+			status := h.writeACLError(w, "failed to delete page ACL", err)
+			return status, err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
+
+// EffectivePermission handles GET /api/v1/chunks/{id}/permissions
+func (h *PageACLHandler) EffectivePermission(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_effective_permission", w, func() (int, error) {
+		permission, err := h.acls.EffectivePermission(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			status := h.writeACLError(w, "failed to resolve permissions", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, permission)
+		return http.StatusOK, nil
+	})
+}
+
+func (h *PageACLHandler) writeACLError(w http.ResponseWriter, message string, err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidPageACL):
+		writeErrorResponse(w, http.StatusBadRequest, "invalid page ACL", err.Error())
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPageACLNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "page ACL not found", err.Error())
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "not found"):
+		writeErrorResponse(w, http.StatusNotFound, "chunk not found", err.Error())
+		return http.StatusNotFound
+	}
+	return writeServiceError(w, http.StatusInternalServerError, message, err)
+}
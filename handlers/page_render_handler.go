@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// PageRenderHandler serves pages rendered for reading
+type PageRenderHandler struct {
+	renderer           services.PageRenderService
+	httpCache          HTTPCachePolicy
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewPageRenderHandler creates a new page render handler
+func NewPageRenderHandler(
+	renderer services.PageRenderService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *PageRenderHandler {
+	return &PageRenderHandler{
+		renderer:           renderer,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// SetHTTPCache sets the caching headers of rendered pages
+func (h *PageRenderHandler) SetHTTPCache(policy HTTPCachePolicy) {
+	h.httpCache = policy
+}
+
+// RenderPage handles GET /api/v1/pages/{id}/render?format=html|markdown. The rendering is
+// the response body unless the client accepts only application/json, which gets it with
+// its counts.
+func (h *PageRenderHandler) RenderPage(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("render_page", w, func() (int, error) {
+		format, err := services.ParseRenderFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid format parameter", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		page, err := h.renderer.RenderPage(r.Context(), mux.Vars(r)["id"], format)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidRenderRequest):
+				writeErrorResponse(w, http.StatusBadRequest, "page cannot be rendered", err.Error())
+				return http.StatusBadRequest, err
+			case strings.Contains(err.Error(), "not found"):
+				writeErrorResponse(w, http.StatusNotFound, "page not found", err.Error())
+				return http.StatusNotFound, err
+			default:
+				return writeServiceError(w, http.StatusInternalServerError, "failed to render page", err), err
+			}
+		}
+
+		if h.httpCache.notModified(w, r, page.ETag, time.Time{}) {
+			return http.StatusNotModified, nil
+		}
+		if r.Header.Get("Accept") == "application/json" {
+			writeJSONResponse(w, http.StatusOK, page)
+			return http.StatusOK, nil
+		}
+
+		contentType := "text/html; charset=utf-8"
+		if format == models.RenderMarkdown {
+			contentType = "text/markdown; charset=utf-8"
+		}
+		cacheStatus := "miss"
+		if page.Cached {
+			cacheStatus = "hit"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Render-Cache", cacheStatus)
+		w.Header().Set("X-Render-Unresolved", strconv.Itoa(page.Unresolved))
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(page.Content)); err != nil {
+			h.logger.Printf("Failed to write rendered page: %v", err)
+		}
+		return http.StatusOK, nil
+	})
+}
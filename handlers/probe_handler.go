@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"semantic-text-processor/services"
+)
+
+// Probe paths, served outside the API prefix and without authentication
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+	StartupPath   = "/startupz"
+)
+
+// IsProbePath reports whether path is one of the probe endpoints
+func IsProbePath(path string) bool {
+	return path == LivenessPath || path == ReadinessPath || path == StartupPath
+}
+
+// ProbeHandler handles liveness, readiness and startup probes
+type ProbeHandler struct {
+	probes *services.ProbeService
+}
+
+// NewProbeHandler creates a new probe handler
+func NewProbeHandler(probes *services.ProbeService) *ProbeHandler {
+	return &ProbeHandler{probes: probes}
+}
+
+// Liveness handles GET /healthz
+func (h *ProbeHandler) Liveness(w http.ResponseWriter, r *http.Request) {
+	writeProbeReport(w, h.probes.Liveness())
+}
+
+// Readiness handles GET /readyz
+func (h *ProbeHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	writeProbeReport(w, h.probes.Readiness(r.Context()))
+}
+
+// Startup handles GET /startupz
+func (h *ProbeHandler) Startup(w http.ResponseWriter, r *http.Request) {
+	writeProbeReport(w, h.probes.Startup(r.Context()))
+}
+
+// writeProbeReport answers 200 when the probe passes and 503 when it fails
+func writeProbeReport(w http.ResponseWriter, report services.ProbeReport) {
+	status := http.StatusOK
+	if !report.OK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSONResponse(w, status, report)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// publicPageCacheControl lets browsers and proxies keep public pages briefly, so revoking a
+// publication takes effect within a minute
+const publicPageCacheControl = "public, max-age=60"
+
+// publicPageCSP forbids scripts, frames and remote styles in public pages; rendered pages
+// only need images
+const publicPageCSP = "default-src 'none'; img-src http: https:; style-src 'unsafe-inline'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// PublicationHandler handles publishing pages and serving the published ones
+type PublicationHandler struct {
+	publishing         services.PublishingService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewPublicationHandler creates a new publication handler
+func NewPublicationHandler(
+	publishing services.PublishingService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *PublicationHandler {
+	return &PublicationHandler{
+		publishing:         publishing,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// writePublicationError maps publishing errors to responses and returns the status
+func (h *PublicationHandler) writePublicationError(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidPublication):
+		writeErrorResponse(w, http.StatusBadRequest, "page cannot be published", err.Error())
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrPublicationNotFound):
+		writeErrorResponse(w, http.StatusNotFound, "page is not published", err.Error())
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "not found"):
+		writeErrorResponse(w, http.StatusNotFound, "page not found", err.Error())
+		return http.StatusNotFound
+	default:
+		return writeServiceError(w, http.StatusInternalServerError, "failed to access publication", err)
+	}
+}
+
+// Publish handles POST /api/v1/pages/{id}/publish. It returns 201 with a new publication,
+// or 200 with the page's live one.
+func (h *PublicationHandler) Publish(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("publish_page", w, func() (int, error) {
+		publication, created, err := h.publishing.Publish(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			return h.writePublicationError(w, err), err
+		}
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		writeJSONResponse(w, status, publication)
+		return status, nil
+	})
+}
+
+// GetPublication handles GET /api/v1/pages/{id}/publish
+func (h *PublicationHandler) GetPublication(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_publication", w, func() (int, error) {
+		publication, err := h.publishing.GetPublication(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			return h.writePublicationError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, publication)
+		return http.StatusOK, nil
+	})
+}
+
+// Unpublish handles DELETE /api/v1/pages/{id}/publish
+func (h *PublicationHandler) Unpublish(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("unpublish_page", w, func() (int, error) {
+		if err := h.publishing.Unpublish(r.Context(), mux.Vars(r)["id"]); err != nil {
+			return h.writePublicationError(w, err), err
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
+
+// ListPublications handles GET /api/v1/publications?include_revoked=true
+func (h *PublicationHandler) ListPublications(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("list_publications", w, func() (int, error) {
+		publications, err := h.publishing.ListPublications(r.Context(), r.URL.Query().Get("include_revoked") == "true")
+		if err != nil {
+			return h.writePublicationError(w, err), err
+		}
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"publications": publications,
+			"count":        len(publications),
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// ServePublic handles GET /api/v1/public/pages/{slug}?format=html|markdown without
+// authentication. Unknown, forged and revoked slugs all get the same 404.
+func (h *PublicationHandler) ServePublic(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("serve_public_page", w, func() (int, error) {
+		format, err := services.ParseRenderFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid format parameter", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		page, err := h.publishing.RenderPublished(r.Context(), mux.Vars(r)["slug"], format)
+		if err != nil {
+			if errors.Is(err, services.ErrPublicationNotFound) {
+				writeErrorResponse(w, http.StatusNotFound, "page not found", "")
+				return http.StatusNotFound, err
+			}
+			// Public callers get no internal details
+			h.logger.Printf("Failed to render published page: %v", err)
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to render page", "")
+			return http.StatusInternalServerError, err
+		}
+
+		w.Header().Set("ETag", page.ETag)
+		w.Header().Set("Cache-Control", publicPageCacheControl)
+		w.Header().Set("Content-Security-Policy", publicPageCSP)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Referrer-Policy", "no-referrer")
+		if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, page.ETag) {
+			w.WriteHeader(http.StatusNotModified)
+			return http.StatusNotModified, nil
+		}
+
+		contentType := "text/html; charset=utf-8"
+		if format == models.RenderMarkdown {
+			contentType = "text/markdown; charset=utf-8"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte(page.Content)); err != nil {
+			h.logger.Printf("Failed to write published page: %v", err)
+		}
+		return http.StatusOK, nil
+	})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+)
+
+// WorkspaceHeader names the workspace whose search history a request uses
+const WorkspaceHeader = "X-Workspace-ID"
+
+// QuerySuggestionHandler serves search autocomplete from the query log
+type QuerySuggestionHandler struct {
+	suggestions        services.QuerySuggestionService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewQuerySuggestionHandler creates a new query suggestion handler
+func NewQuerySuggestionHandler(
+	suggestions services.QuerySuggestionService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *QuerySuggestionHandler {
+	return &QuerySuggestionHandler{
+		suggestions:        suggestions,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// Suggest handles GET /api/v1/search/suggestions?q=mach&limit=10
+//
+// Returns completions of q, then similarly spelled queries when there are too few. An
+// empty q returns the workspace's popular queries.
+func (h *QuerySuggestionHandler) Suggest(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("suggest_search_queries", w, func() (int, error) {
+		workspace, ok := requestWorkspace(w, r)
+		if !ok {
+			return http.StatusBadRequest, nil
+		}
+		input := r.URL.Query().Get("q")
+
+		suggestions, err := h.suggestions.Suggest(r.Context(), workspace, input, queryLimit(r))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get query suggestions", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, models.QuerySuggestionResponse{
+			Workspace:   workspace,
+			Input:       input,
+			Suggestions: suggestions,
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// Popular handles GET /api/v1/search/popular?since=168h&limit=10
+func (h *QuerySuggestionHandler) Popular(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("popular_search_queries", w, func() (int, error) {
+		workspace, ok := requestWorkspace(w, r)
+		if !ok {
+			return http.StatusBadRequest, nil
+		}
+
+		var since time.Time
+		if s := r.URL.Query().Get("since"); s != "" {
+			window, err := time.ParseDuration(s)
+			if err != nil || window <= 0 {
+				writeErrorResponse(w, http.StatusBadRequest, "since must be a positive duration such as 168h", s)
+				return http.StatusBadRequest, nil
+			}
+			since = time.Now().Add(-window)
+		}
+
+		suggestions, err := h.suggestions.PopularQueries(r.Context(), workspace, since, queryLimit(r))
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get popular queries", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, models.QuerySuggestionResponse{
+			Workspace:   workspace,
+			Suggestions: suggestions,
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// requestWorkspace reads the workspace from the X-Workspace-ID header or the workspace
+// query parameter, writing a 400 response when it is invalid
+func requestWorkspace(w http.ResponseWriter, r *http.Request) (string, bool) {
+	workspace := r.Header.Get(WorkspaceHeader)
+	if workspace == "" {
+		workspace = r.URL.Query().Get("workspace")
+	}
+	workspace, err := services.NormalizeWorkspace(workspace)
+	if err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid workspace", err.Error())
+		return "", false
+	}
+	return workspace, true
+}
+
+// queryLimit reads the limit query parameter; 0 lets the service apply its default
+func queryLimit(r *http.Request) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"time"
+)
+
+// RAGHandler handles question answering HTTP requests
+type RAGHandler struct {
+	ragService         *services.RAGService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewRAGHandler creates a new question answering handler
+func NewRAGHandler(
+	ragService *services.RAGService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *RAGHandler {
+	return &RAGHandler{
+		ragService:         ragService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// Ask handles POST /api/v1/ask
+func (h *RAGHandler) Ask(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("ask", w, func() (int, error) {
+		var req models.AskRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if req.Question == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "question is required", "")
+			return http.StatusBadRequest, nil
+		}
+		if req.SemanticWeight != nil && (*req.SemanticWeight < 0 || *req.SemanticWeight > 1) {
+			writeErrorResponse(w, http.StatusBadRequest, "semantic_weight must be between 0 and 1", "")
+			return http.StatusBadRequest, nil
+		}
+
+		response, err := h.ragService.Ask(r.Context(), &req)
+		if errors.Is(err, services.ErrParentContextUnavailable) {
+			writeErrorResponse(w, http.StatusBadRequest, "parent context is not available", err.Error())
+			return http.StatusBadRequest, nil
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to answer question", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// AssembleContext handles POST /api/v1/context, returning retrieved chunks packed into a
+// token budget for callers that prompt their own model
+func (h *RAGHandler) AssembleContext(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("assemble_context", w, func() (int, error) {
+		var req models.ContextRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if req.Query == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "query is required", "")
+			return http.StatusBadRequest, nil
+		}
+		if req.SemanticWeight != nil && (*req.SemanticWeight < 0 || *req.SemanticWeight > 1) {
+			writeErrorResponse(w, http.StatusBadRequest, "semantic_weight must be between 0 and 1", "")
+			return http.StatusBadRequest, nil
+		}
+		if req.Order != "" && req.Order != services.ContextOrderRelevance && req.Order != services.ContextOrderDocument {
+			writeErrorResponse(w, http.StatusBadRequest, "order must be relevance or document", "")
+			return http.StatusBadRequest, nil
+		}
+
+		assembled, err := h.ragService.RetrieveContext(r.Context(), &req)
+		if errors.Is(err, services.ErrParentContextUnavailable) {
+			writeErrorResponse(w, http.StatusBadRequest, "parent context is not available", err.Error())
+			return http.StatusBadRequest, nil
+		}
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to assemble context", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, assembled)
+		return http.StatusOK, nil
+	})
+}
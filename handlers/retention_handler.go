@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// RetentionHandler handles retention rule HTTP requests. Rules are managed in the
+// workspace of the calling admin.
+type RetentionHandler struct {
+	retention          services.RetentionService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(
+	retention services.RetentionService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *RetentionHandler {
+	return &RetentionHandler{
+		retention:          retention,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// ListRules handles GET /api/v1/retention/rules
+func (h *RetentionHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("list_retention_rules", w, func() (int, error) {
+		rules, err := h.retention.ListRules(r.Context(), services.WorkspaceFromContext(r.Context()))
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to list retention rules", err)
+			return status, err
+		}
+
+		response := map[string]interface{}{
+			"rules": rules,
+			"count": len(rules),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// CreateRule handles POST /api/v1/retention/rules
+func (h *RetentionHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("create_retention_rule", w, func() (int, error) {
+		var req models.RetentionRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		rule, err := h.retention.CreateRule(r.Context(), services.WorkspaceFromContext(r.Context()), &req)
+		if errors.Is(err, services.ErrInvalidRetentionRule) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid retention rule", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to create retention rule", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, rule)
+		return http.StatusCreated, nil
+	})
+}
+
+// UpdateRule handles PUT /api/v1/retention/rules/{id}
+func (h *RetentionHandler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("update_retention_rule", w, func() (int, error) {
+		ruleID := mux.Vars(r)["id"]
+
+		var req models.RetentionRuleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		rule, err := h.retention.UpdateRule(r.Context(), services.WorkspaceFromContext(r.Context()), ruleID, &req)
+		if errors.Is(err, services.ErrInvalidRetentionRule) {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid retention rule", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if errors.Is(err, services.ErrRetentionRuleNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "retention rule not found", ruleID)
+			return http.StatusNotFound, err
+		}
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to update retention rule", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, rule)
+		return http.StatusOK, nil
+	})
+}
+
+// DeleteRule handles DELETE /api/v1/retention/rules/{id}
+func (h *RetentionHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("delete_retention_rule", w, func() (int, error) {
+		ruleID := mux.Vars(r)["id"]
+
+		err := h.retention.DeleteRule(r.Context(), services.WorkspaceFromContext(r.Context()), ruleID)
+		if errors.Is(err, services.ErrRetentionRuleNotFound) {
+			writeErrorResponse(w, http.StatusNotFound, "retention rule not found", ruleID)
+			return http.StatusNotFound, err
+		}
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to delete retention rule", err)
+			return status, err
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
+
+// Preview handles GET /api/v1/retention/preview, reporting what the enabled rules would
+// affect without changing anything
+func (h *RetentionHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("preview_retention", w, func() (int, error) {
+		report, err := h.retention.Preview(r.Context(), services.WorkspaceFromContext(r.Context()))
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to preview retention rules", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, report)
+		return http.StatusOK, nil
+	})
+}
+
+// Run handles POST /api/v1/retention/run, applying the enabled rules now
+func (h *RetentionHandler) Run(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("run_retention", w, func() (int, error) {
+		report, err := h.retention.Run(r.Context(), services.WorkspaceFromContext(r.Context()))
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to run retention rules", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, report)
+		return http.StatusOK, nil
+	})
+}
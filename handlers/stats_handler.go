@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+)
+
+// StatsHandler handles chunk statistics HTTP requests
+type StatsHandler struct {
+	statsService       services.StatsService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewStatsHandler creates a new statistics handler
+func NewStatsHandler(
+	statsService services.StatsService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *StatsHandler {
+	return &StatsHandler{
+		statsService:       statsService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetStats handles GET /api/v1/stats?interval=day&periods=30&top=10&fresh=false
+func (h *StatsHandler) GetStats(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_stats", w, func() (int, error) {
+		query := r.URL.Query()
+		opts := models.StatsOptions{Interval: query.Get("interval")}
+		for name, target := range map[string]*int{"periods": &opts.Periods, "top": &opts.Top} {
+			if value := query.Get(name); value != "" {
+				parsed, err := strconv.Atoi(value)
+				if err != nil {
+					writeErrorResponse(w, http.StatusBadRequest, "invalid "+name+" parameter", err.Error())
+					return http.StatusBadRequest, err
+				}
+				*target = parsed
+			}
+		}
+		if fresh := query.Get("fresh"); fresh != "" {
+			parsed, err := strconv.ParseBool(fresh)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid fresh parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			opts.Fresh = parsed
+		}
+
+		stats, err := h.statsService.GetStats(r.Context(), opts)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidStatsRequest) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid statistics request", err.Error())
+				return http.StatusBadRequest, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to get statistics", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, stats)
+		return http.StatusOK, nil
+	})
+}
+
+// GetHistory handles GET /api/v1/stats/history?days=30
+func (h *StatsHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_stats_history", w, func() (int, error) {
+		var days int
+		if value := r.URL.Query().Get("days"); value != "" {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid days parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			days = parsed
+		}
+
+		history, err := h.statsService.History(r.Context(), days)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidStatsRequest) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid statistics request", err.Error())
+				return http.StatusBadRequest, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to get statistics history", err)
+			return status, err
+		}
+
+		response := map[string]interface{}{
+			"snapshots": history,
+			"count":     len(history),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// TakeSnapshot handles POST /api/v1/stats/snapshot
+func (h *StatsHandler) TakeSnapshot(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("take_stats_snapshot", w, func() (int, error) {
+		stats, err := h.statsService.Snapshot(r.Context())
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to take statistics snapshot", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, stats)
+		return http.StatusCreated, nil
+	})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SummaryHandler handles chunk summary HTTP requests
+type SummaryHandler struct {
+	summarization      services.SummarizationService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewSummaryHandler creates a new summary handler
+func NewSummaryHandler(
+	summarization services.SummarizationService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *SummaryHandler {
+	return &SummaryHandler{
+		summarization:      summarization,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// GetSummary handles GET /api/v1/chunks/{id}/summary?refresh=false. Pages and blocks with
+// children get a rollup summary of everything below them.
+func (h *SummaryHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_chunk_summary", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var opts models.SummaryOptions
+		if refresh := r.URL.Query().Get("refresh"); refresh != "" {
+			parsed, err := strconv.ParseBool(refresh)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid refresh parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			opts.Refresh = parsed
+		}
+
+		summary, err := h.summarization.Summarize(r.Context(), chunkID, opts)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				writeErrorResponse(w, http.StatusNotFound, "chunk not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to summarize chunk", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, summary)
+		return http.StatusOK, nil
+	})
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// SyncHandler handles offline sync HTTP requests
+type SyncHandler struct {
+	syncService        services.ChunkSyncService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewSyncHandler creates a new sync handler
+func NewSyncHandler(
+	syncService services.ChunkSyncService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *SyncHandler {
+	return &SyncHandler{
+		syncService:        syncService,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// PullChanges handles GET /api/v1/sync/changes?cursor=0&limit=500
+func (h *SyncHandler) PullChanges(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("sync_pull", w, func() (int, error) {
+		query := r.URL.Query()
+		var cursor int64
+		if c := query.Get("cursor"); c != "" {
+			parsed, err := strconv.ParseInt(c, 10, 64)
+			if err != nil || parsed < 0 {
+				writeErrorResponse(w, http.StatusBadRequest, "cursor must be a non-negative integer", "")
+				return http.StatusBadRequest, nil
+			}
+			cursor = parsed
+		}
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		result, err := h.syncService.Pull(r.Context(), cursor, limit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to pull changes", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// PushOperations handles POST /api/v1/sync/push. Each operation gets its own result, so
+// the response is 200 even when some operations were rejected or conflicted.
+func (h *SyncHandler) PushOperations(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("sync_push", w, func() (int, error) {
+		var req models.SyncPushRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if req.ClientID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "client_id is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		result, err := h.syncService.Push(r.Context(), &req)
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to push operations", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// ListConflicts handles GET /api/v1/sync/conflicts?chunk_id=&status=open&limit=
+func (h *SyncHandler) ListConflicts(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("sync_list_conflicts", w, func() (int, error) {
+		query := r.URL.Query()
+		status := query.Get("status")
+		if status == "" {
+			status = "open"
+		} else if status == "all" {
+			status = ""
+		} else if status != "open" && status != "resolved" {
+			writeErrorResponse(w, http.StatusBadRequest, "status must be open, resolved or all", "")
+			return http.StatusBadRequest, nil
+		}
+		limit, _ := strconv.Atoi(query.Get("limit"))
+
+		conflicts, err := h.syncService.ListConflicts(r.Context(), query.Get("chunk_id"), status, limit)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to list conflicts", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, map[string]interface{}{
+			"conflicts": conflicts,
+			"count":     len(conflicts),
+		})
+		return http.StatusOK, nil
+	})
+}
+
+// ResolveConflict handles POST /api/v1/sync/conflicts/{id}/resolve
+func (h *SyncHandler) ResolveConflict(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("sync_resolve_conflict", w, func() (int, error) {
+		conflictID := mux.Vars(r)["id"]
+		if conflictID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "conflict ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var resolution models.SyncConflictResolution
+		if err := json.NewDecoder(r.Body).Decode(&resolution); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		conflict, err := h.syncService.ResolveConflict(r.Context(), conflictID, &resolution)
+		switch {
+		case errors.Is(err, services.ErrSyncConflictNotFound):
+			writeErrorResponse(w, http.StatusNotFound, "conflict not found", err.Error())
+			return http.StatusNotFound, err
+		case errors.Is(err, services.ErrSyncConflictResolved):
+			writeErrorResponse(w, http.StatusConflict, "conflict already resolved", err.Error())
+			return http.StatusConflict, err
+		case errors.Is(err, services.ErrInvalidSyncResolution):
+			writeErrorResponse(w, http.StatusBadRequest, "invalid resolution", err.Error())
+			return http.StatusBadRequest, err
+		case err != nil:
+			status := writeChunkWriteError(w, "failed to resolve conflict", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, conflict)
+		return http.StatusOK, nil
+	})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// TaskHandler handles task index HTTP requests
+type TaskHandler struct {
+	tasks              services.TaskService
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewTaskHandler creates a new task handler
+func NewTaskHandler(
+	tasks services.TaskService,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *TaskHandler {
+	return &TaskHandler{
+		tasks:              tasks,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// ListTasks handles GET /api/v1/tasks?view=overdue|today|due|open&tag=name&limit=100
+func (h *TaskHandler) ListTasks(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("list_tasks", w, func() (int, error) {
+		query := r.URL.Query()
+		req := &models.TaskQuery{View: models.TaskView(query.Get("view")), Tag: query.Get("tag")}
+		if raw := query.Get("limit"); raw != "" {
+			limit, err := strconv.Atoi(raw)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid limit parameter", err.Error())
+				return http.StatusBadRequest, err
+			}
+			req.Limit = limit
+		}
+
+		tasks, err := h.tasks.List(r.Context(), req)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTaskQuery) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid task query", err.Error())
+				return http.StatusBadRequest, err
+			}
+			return writeServiceError(w, http.StatusInternalServerError, "failed to list tasks", err), err
+		}
+		writeJSONResponse(w, http.StatusOK, tasks)
+		return http.StatusOK, nil
+	})
+}
+
+// Reindex handles POST /api/v1/tasks/reindex
+func (h *TaskHandler) Reindex(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("reindex_tasks", w, func() (int, error) {
+		result, err := h.tasks.Reindex(r.Context())
+		if err != nil {
+			return writeServiceError(w, http.StatusInternalServerError, "failed to reindex tasks", err), err
+		}
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
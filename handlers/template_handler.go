@@ -1,10 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"semantic-text-processor/models"
 	"semantic-text-processor/services"
+	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 )
@@ -43,7 +48,7 @@ func (h *TemplateHandler) CreateTemplate(w http.ResponseWriter, r *http.Request)
 	// Create template
 	template, err := h.templateService.CreateTemplate(r.Context(), &req)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "failed to create template", err.Error())
+		writeServiceError(w, http.StatusInternalServerError, "failed to create template", err)
 		return
 	}
 
@@ -108,13 +113,104 @@ func (h *TemplateHandler) CreateTemplateInstance(w http.ResponseWriter, r *http.
 	// Create instance
 	instance, err := h.templateService.CreateInstance(r.Context(), &req)
 	if err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "failed to create template instance", err.Error())
+		writeServiceError(w, http.StatusInternalServerError, "failed to create template instance", err)
 		return
 	}
 
 	writeJSONResponse(w, http.StatusCreated, instance)
 }
 
+// QueryInstances handles POST /api/v1/templates/{id}/instances/query
+func (h *TemplateHandler) QueryInstances(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["id"]
+	if templateID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "template ID is required", "")
+		return
+	}
+
+	var req models.QueryInstancesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	result, err := h.templateService.QueryInstances(r.Context(), templateID, &req)
+	if err != nil {
+		writeInstanceQueryError(w, "failed to query template instances", err)
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+// GetTableView handles GET /api/v1/templates/{id}/table?filter=slot:op:value&sort=-slot&limit=&offset=&format=json|csv.
+// CSV exports every matching row, ignoring limit and offset.
+func (h *TemplateHandler) GetTableView(w http.ResponseWriter, r *http.Request) {
+	templateID := mux.Vars(r)["id"]
+	if templateID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "template ID is required", "")
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid format", "format must be json or csv")
+		return
+	}
+	req := &models.TableViewRequest{Sort: services.ParseTableSortParam(query.Get("sort")), AllRows: format == "csv"}
+	for _, raw := range query["filter"] {
+		filter, err := services.ParseSlotFilterParam(raw)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid filter", err.Error())
+			return
+		}
+		req.Filters = append(req.Filters, filter)
+	}
+	for name, target := range map[string]*int{"limit": &req.Limit, "offset": &req.Offset} {
+		if raw := query.Get(name); raw != "" {
+			value, err := strconv.Atoi(raw)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid "+name+" parameter", err.Error())
+				return
+			}
+			*target = value
+		}
+	}
+
+	view, err := h.templateService.TableView(r.Context(), templateID, req)
+	if err != nil {
+		writeInstanceQueryError(w, "failed to get template table", err)
+		return
+	}
+	if format != "csv" {
+		writeJSONResponse(w, http.StatusOK, view)
+		return
+	}
+
+	// Buffer the export so a failure can still be reported as a JSON error
+	var buf bytes.Buffer
+	if err := services.WriteTableCSV(&buf, view); err != nil {
+		writeServiceError(w, http.StatusInternalServerError, "failed to export template table", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="template-%s.csv"`, view.TemplateChunkID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+func writeInstanceQueryError(w http.ResponseWriter, message string, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInstanceQuery):
+		writeErrorResponse(w, http.StatusBadRequest, "invalid instance query", err.Error())
+	case strings.Contains(err.Error(), "not a template") || strings.Contains(err.Error(), "not found"):
+		writeErrorResponse(w, http.StatusNotFound, "template not found", err.Error())
+	default:
+		writeServiceError(w, http.StatusInternalServerError, message, err)
+	}
+}
+
 // UpdateSlotValue handles PUT /api/v1/instances/{id}/slots
 func (h *TemplateHandler) UpdateSlotValue(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -139,9 +235,79 @@ func (h *TemplateHandler) UpdateSlotValue(w http.ResponseWriter, r *http.Request
 
 	// Update slot value
 	if err := h.templateService.UpdateSlotValue(r.Context(), instanceID, req.SlotName, req.Value); err != nil {
-		writeErrorResponse(w, http.StatusInternalServerError, "failed to update slot value", err.Error())
+		writeServiceError(w, http.StatusInternalServerError, "failed to update slot value", err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
-}
\ No newline at end of file
+}
+
+// UpdateTemplateSchema handles PUT /api/v1/templates/{id}/slots
+func (h *TemplateHandler) UpdateTemplateSchema(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	templateID := vars["id"]
+
+	if templateID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "template ID is required", "")
+		return
+	}
+
+	var req models.UpdateTemplateSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+		return
+	}
+
+	migration, err := h.templateService.UpdateTemplateSchema(r.Context(), templateID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTemplateSchema):
+			writeErrorResponse(w, http.StatusBadRequest, "invalid template schema", err.Error())
+		case strings.Contains(err.Error(), "not a template") || strings.Contains(err.Error(), "not found"):
+			writeErrorResponse(w, http.StatusNotFound, "template not found", err.Error())
+		default:
+			writeServiceError(w, http.StatusInternalServerError, "failed to update template schema", err)
+		}
+		return
+	}
+
+	writeJSONResponse(w, http.StatusOK, migration)
+}
+
+// RenderInstance handles GET /api/v1/instances/{id}/render?format=text|markdown|json.
+// Text and Markdown are returned as the document itself, JSON as the rendered instance.
+func (h *TemplateHandler) RenderInstance(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	instanceID := vars["id"]
+
+	if instanceID == "" {
+		writeErrorResponse(w, http.StatusBadRequest, "instance ID is required", "")
+		return
+	}
+
+	rendered, err := h.templateService.RenderInstance(r.Context(), instanceID, r.URL.Query().Get("format"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidRenderFormat):
+			writeErrorResponse(w, http.StatusBadRequest, "invalid render format", err.Error())
+		case strings.Contains(err.Error(), "not a template instance") || strings.Contains(err.Error(), "not found"):
+			writeErrorResponse(w, http.StatusNotFound, "template instance not found", err.Error())
+		default:
+			writeServiceError(w, http.StatusInternalServerError, "failed to render template instance", err)
+		}
+		return
+	}
+
+	switch rendered.Format {
+	case models.RenderFormatJSON:
+		writeJSONResponse(w, http.StatusOK, rendered)
+	case models.RenderFormatMarkdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(rendered.Content))
+	default:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(rendered.Content))
+	}
+}
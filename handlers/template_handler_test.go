@@ -59,6 +59,38 @@ func (m *MockTemplateService) UpdateSlotValue(ctx context.Context, instanceChunk
 	return args.Error(0)
 }
 
+func (m *MockTemplateService) RenderInstance(ctx context.Context, instanceChunkID, format string) (*models.RenderedInstance, error) {
+	args := m.Called(ctx, instanceChunkID, format)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RenderedInstance), args.Error(1)
+}
+
+func (m *MockTemplateService) QueryInstances(ctx context.Context, templateChunkID string, req *models.QueryInstancesRequest) (*models.InstanceQueryResult, error) {
+	args := m.Called(ctx, templateChunkID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.InstanceQueryResult), args.Error(1)
+}
+
+func (m *MockTemplateService) TableView(ctx context.Context, templateChunkID string, req *models.TableViewRequest) (*models.TableView, error) {
+	args := m.Called(ctx, templateChunkID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TableView), args.Error(1)
+}
+
+func (m *MockTemplateService) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) {
+	args := m.Called(ctx, templateChunkID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TemplateSchemaMigration), args.Error(1)
+}
+
 func TestTemplateHandler_CreateTemplate(t *testing.T) {
 	tests := []struct {
 		name           string
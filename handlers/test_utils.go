@@ -56,6 +56,14 @@ func (m *MockSupabaseClient) GetChunkByID(ctx context.Context, id string) (*mode
 	return args.Get(0).(*models.ChunkRecord), args.Error(1)
 }
 
+func (m *MockSupabaseClient) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.ChunkRecord), args.Error(1)
+}
+
 func (m *MockSupabaseClient) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) {
 	args := m.Called(ctx, content)
 	return args.Get(0).(*models.ChunkRecord), args.Error(1)
@@ -104,6 +112,16 @@ func (m *MockSupabaseClient) MoveChunk(ctx context.Context, req *models.MoveChun
 	return args.Error(0)
 }
 
+func (m *MockSupabaseClient) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error {
+	args := m.Called(ctx, parentID, afterChunkID, chunk)
+	return args.Error(0)
+}
+
+func (m *MockSupabaseClient) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error {
+	args := m.Called(ctx, parentID, orderedChildIDs)
+	return args.Error(0)
+}
+
 func (m *MockSupabaseClient) BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error {
 	args := m.Called(ctx, req)
 	return args.Error(0)
@@ -116,6 +134,7 @@ func (m *MockSupabaseClient) GetAllTemplates(ctx context.Context) ([]models.Temp
 func (m *MockSupabaseClient) CreateTemplateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error) { return nil, nil }
 func (m *MockSupabaseClient) GetTemplateInstances(ctx context.Context, templateChunkID string) ([]models.TemplateInstance, error) { return nil, nil }
 func (m *MockSupabaseClient) UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error { return nil }
+func (m *MockSupabaseClient) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) { return nil, nil }
 func (m *MockSupabaseClient) AddTag(ctx context.Context, chunkID string, tagContent string) error { return nil }
 func (m *MockSupabaseClient) RemoveTag(ctx context.Context, chunkID string, tagChunkID string) error { return nil }
 func (m *MockSupabaseClient) GetChunkTags(ctx context.Context, chunkID string) ([]models.ChunkRecord, error) { return nil, nil }
@@ -134,11 +153,20 @@ func (m *MockSupabaseClient) InsertGraphEdges(ctx context.Context, edges []model
 	args := m.Called(ctx, edges)
 	return args.Error(0)
 }
+func (m *MockSupabaseClient) UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error {
+	args := m.Called(ctx, nodes)
+	return args.Error(0)
+}
+func (m *MockSupabaseClient) UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error {
+	args := m.Called(ctx, edges)
+	return args.Error(0)
+}
 func (m *MockSupabaseClient) SearchGraph(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClient) GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClient) GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClient) FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClient) GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error) { return nil, nil }
+func (m *MockSupabaseClient) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClient) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) { return nil, nil }
 func (m *MockSupabaseClient) HealthCheck(ctx context.Context) error { return nil }
 
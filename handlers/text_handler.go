@@ -82,11 +82,23 @@ func (h *TextHandler) CreateText(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		writeWarningLog("failed to extract knowledge", err)
 	} else {
-		if err := h.supabaseClient.InsertGraphNodes(r.Context(), graphResult.Nodes); err != nil {
-			writeWarningLog("failed to save graph nodes", err)
+		// Entities already in the graph are merged rather than stored again, so edges are
+		// pointed at the nodes they were merged into
+		extractedIDs := make([]string, len(graphResult.Nodes))
+		for i, node := range graphResult.Nodes {
+			extractedIDs[i] = node.ID
 		}
-		if err := h.supabaseClient.InsertGraphEdges(r.Context(), graphResult.Edges); err != nil {
-			writeWarningLog("failed to save graph edges", err)
+		if err := h.supabaseClient.UpsertGraphNodes(r.Context(), graphResult.Nodes); err != nil {
+			writeWarningLog("failed to save graph nodes", err)
+		} else {
+			nodeIDs := make(map[string]string, len(extractedIDs))
+			for i, id := range extractedIDs {
+				nodeIDs[id] = graphResult.Nodes[i].ID
+			}
+			edges := models.RemapGraphEdges(graphResult.Edges, nodeIDs)
+			if err := h.supabaseClient.UpsertGraphEdges(r.Context(), edges); err != nil {
+				writeWarningLog("failed to save graph edges", err)
+			}
 		}
 	}
 
@@ -116,8 +116,8 @@ func TestCreateText(t *testing.T) {
 		mockTextProcessor.On("GenerateEmbeddings", mock.Anything, processResult.Chunks).Return(embeddings, nil)
 		mockSupabaseClient.On("InsertEmbeddings", mock.Anything, embeddings).Return(nil)
 		mockTextProcessor.On("ExtractKnowledge", mock.Anything, processResult.Chunks).Return(graphResult, nil)
-		mockSupabaseClient.On("InsertGraphNodes", mock.Anything, graphResult.Nodes).Return(nil)
-		mockSupabaseClient.On("InsertGraphEdges", mock.Anything, graphResult.Edges).Return(nil)
+		mockSupabaseClient.On("UpsertGraphNodes", mock.Anything, graphResult.Nodes).Return(nil)
+		mockSupabaseClient.On("UpsertGraphEdges", mock.Anything, graphResult.Edges).Return(nil)
 
 		// Create request
 		reqBody := models.CreateTextRequest{
@@ -117,8 +117,8 @@ func TestTextProcessingIntegration(t *testing.T) {
 		mockTextProcessor.On("GenerateEmbeddings", mock.Anything, expectedChunks).Return(expectedEmbeddings, nil)
 		mockSupabaseClient.On("InsertEmbeddings", mock.Anything, expectedEmbeddings).Return(nil)
 		mockTextProcessor.On("ExtractKnowledge", mock.Anything, expectedChunks).Return(expectedGraph, nil)
-		mockSupabaseClient.On("InsertGraphNodes", mock.Anything, expectedGraph.Nodes).Return(nil)
-		mockSupabaseClient.On("InsertGraphEdges", mock.Anything, expectedGraph.Edges).Return(nil)
+		mockSupabaseClient.On("UpsertGraphNodes", mock.Anything, expectedGraph.Nodes).Return(nil)
+		mockSupabaseClient.On("UpsertGraphEdges", mock.Anything, expectedGraph.Edges).Return(nil)
 
 		// Step 1: Create text
 		createReq := models.CreateTextRequest{
@@ -444,8 +444,8 @@ func TestTextProcessingErrorHandling(t *testing.T) {
 			Edges: []models.GraphEdge{},
 		}
 		freshMockTextProcessor.On("ExtractKnowledge", mock.Anything, chunks).Return(graphResult, nil)
-		freshMockSupabaseClient.On("InsertGraphNodes", mock.Anything, graphResult.Nodes).Return(nil)
-		freshMockSupabaseClient.On("InsertGraphEdges", mock.Anything, graphResult.Edges).Return(nil)
+		freshMockSupabaseClient.On("UpsertGraphNodes", mock.Anything, graphResult.Nodes).Return(nil)
+		freshMockSupabaseClient.On("UpsertGraphEdges", mock.Anything, graphResult.Edges).Return(nil)
 
 		createReq := models.CreateTextRequest{
 			Content: testText,
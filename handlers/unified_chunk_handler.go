@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"log"
 	"net/http"
 	"semantic-text-processor/models"
 	"semantic-text-processor/services"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -18,6 +21,9 @@ type UnifiedChunkHandler struct {
 	converter          *ModelConverter
 	performanceMonitor *PerformanceMonitor
 	cacheService       services.CacheService
+	pageACLs           services.PageACLService
+	chunkLocks         services.ChunkLockService
+	httpCache          HTTPCachePolicy
 	logger             *log.Logger
 }
 
@@ -38,6 +44,23 @@ func NewUnifiedChunkHandler(
 	}
 }
 
+// SetPageACLs checks chunks served from the handler cache against page ACLs, which the
+// chunk service only checks on reads that reach it
+func (h *UnifiedChunkHandler) SetPageACLs(acls services.PageACLService) {
+	h.pageACLs = acls
+}
+
+// SetChunkLocks attaches current lock status to chunks served from the handler cache,
+// which may have been cached before a lock was acquired or released
+func (h *UnifiedChunkHandler) SetChunkLocks(locks services.ChunkLockService) {
+	h.chunkLocks = locks
+}
+
+// SetHTTPCache enables ETags and conditional requests on chunk, children and hierarchy reads
+func (h *UnifiedChunkHandler) SetHTTPCache(policy HTTPCachePolicy) {
+	h.httpCache = policy
+}
+
 // GetChunks handles GET /api/v1/chunks
 func (h *UnifiedChunkHandler) GetChunks(w http.ResponseWriter, r *http.Request) {
 	h.performanceMonitor.MonitoredHTTPOperation("get_chunks", w, func() (int, error) {
@@ -79,6 +102,15 @@ func (h *UnifiedChunkHandler) GetChunks(w http.ResponseWriter, r *http.Request)
 			filters["parent_chunk_id"] = parentID
 		}
 
+		if filter := query.Get("filter"); filter != "" {
+			expr, err := services.ParseFilterExpression(filter)
+			if err != nil {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid filter", err.Error())
+				return http.StatusBadRequest, nil
+			}
+			filters["filter"] = expr
+		}
+
 		// Convert to unified search query
 		unifiedQuery := h.converter.ToUnifiedSearchQuery(searchQuery, filters, limit, offset)
 
@@ -125,8 +157,8 @@ func (h *UnifiedChunkHandler) CreateChunk(w http.ResponseWriter, r *http.Request
 
 		// Create chunk using unified service
 		if err := h.unifiedService.CreateChunk(r.Context(), unifiedChunk); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to create chunk", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeChunkWriteError(w, "failed to create chunk", err)
+			return status, err
 		}
 
 		// Convert back to legacy format for response
@@ -164,11 +196,18 @@ func (h *UnifiedChunkHandler) GetChunkByID(w http.ResponseWriter, r *http.Reques
 			}
 		}
 
+		if chunk != nil && h.pageACLs != nil {
+			if err := h.pageACLs.CheckChunk(r.Context(), chunk, services.PermissionRead); err != nil {
+				status := writeServiceError(w, http.StatusInternalServerError, "failed to check page access", err)
+				return status, err
+			}
+		}
+
 		if chunk == nil {
 			chunk, err = h.unifiedService.GetChunk(r.Context(), chunkID)
 			if err != nil {
-				writeErrorResponse(w, http.StatusNotFound, "chunk not found", err.Error())
-				return http.StatusNotFound, err
+				status := writeServiceError(w, http.StatusNotFound, "chunk not found", err)
+				return status, err
 			}
 
 			// Cache the result
@@ -178,9 +217,21 @@ func (h *UnifiedChunkHandler) GetChunkByID(w http.ResponseWriter, r *http.Reques
 			}
 		}
 
+		if h.chunkLocks != nil {
+			lock, err := h.chunkLocks.LockStatus(r.Context(), chunkID)
+			if err != nil {
+				h.logger.Printf("Warning: failed to read lock status of chunk %s: %v", chunkID, err)
+			}
+			locked := *chunk
+			locked.Lock = lock
+			chunk = &locked
+		}
+
 		// Convert to legacy format
 		legacyChunk := h.converter.FromUnifiedChunk(chunk)
 
+		w.Header().Set("X-Chunk-Version", strconv.FormatInt(chunk.Version, 10))
+
 		// Add cache hit header
 		if cacheHit {
 			w.Header().Set("X-Cache", "HIT")
@@ -188,6 +239,11 @@ func (h *UnifiedChunkHandler) GetChunkByID(w http.ResponseWriter, r *http.Reques
 			w.Header().Set("X-Cache", "MISS")
 		}
 
+		etag, lastModified := chunkValidators([]models.UnifiedChunkRecord{*chunk})
+		if h.httpCache.notModified(w, r, etag, lastModified) {
+			return http.StatusNotModified, nil
+		}
+
 		writeJSONResponse(w, http.StatusOK, legacyChunk)
 		return http.StatusOK, nil
 	})
@@ -220,10 +276,10 @@ func (h *UnifiedChunkHandler) UpdateChunk(w http.ResponseWriter, r *http.Request
 		// Apply updates
 		h.converter.ApplyUpdateRequest(chunk, &req)
 
-		// Update in database
+		// Update in database; the version read above (or the one in the request) guards the write
 		if err := h.unifiedService.UpdateChunk(r.Context(), chunk); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to update chunk", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeChunkWriteError(w, "failed to update chunk", err)
+			return status, err
 		}
 
 		// Invalidate cache
@@ -235,11 +291,125 @@ func (h *UnifiedChunkHandler) UpdateChunk(w http.ResponseWriter, r *http.Request
 		// Convert to legacy format for response
 		legacyChunk := h.converter.FromUnifiedChunk(chunk)
 
+		w.Header().Set("X-Chunk-Version", strconv.FormatInt(chunk.Version, 10))
 		writeJSONResponse(w, http.StatusOK, legacyChunk)
 		return http.StatusOK, nil
 	})
 }
 
+// PatchChunk handles PATCH /api/v1/chunks/{id}, updating only the given fields if
+// expected_version is still current
+func (h *UnifiedChunkHandler) PatchChunk(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("patch_chunk", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var patch models.ChunkPatch
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if patch.ExpectedVersion <= 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "expected_version is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		chunk, err := h.unifiedService.PatchChunk(r.Context(), chunkID, &patch)
+		if err != nil {
+			status := writeChunkWriteError(w, "failed to patch chunk", err)
+			return status, err
+		}
+
+		if h.cacheService != nil {
+			h.cacheService.Delete(r.Context(), "chunk:"+chunkID)
+		}
+
+		w.Header().Set("X-Chunk-Version", strconv.FormatInt(chunk.Version, 10))
+		writeJSONResponse(w, http.StatusOK, chunk)
+		return http.StatusOK, nil
+	})
+}
+
+// PatchMetadata handles PATCH /api/v1/chunks/{id}/metadata, applying set, delete and
+// merge operations on metadata paths if expected_version is still current
+func (h *UnifiedChunkHandler) PatchMetadata(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("patch_chunk_metadata", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var req models.MetadataPatchRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+		if req.ExpectedVersion <= 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "expected_version is required", "")
+			return http.StatusBadRequest, nil
+		}
+		if len(req.Ops) == 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "ops are required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		chunk, err := h.unifiedService.PatchChunk(r.Context(), chunkID, &models.ChunkPatch{
+			ExpectedVersion: req.ExpectedVersion,
+			MetadataOps:     req.Ops,
+		})
+		if err != nil {
+			status := writeChunkWriteError(w, "failed to patch chunk metadata", err)
+			return status, err
+		}
+
+		if h.cacheService != nil {
+			h.cacheService.Delete(r.Context(), "chunk:"+chunkID)
+		}
+
+		w.Header().Set("X-Chunk-Version", strconv.FormatInt(chunk.Version, 10))
+		writeJSONResponse(w, http.StatusOK, chunk)
+		return http.StatusOK, nil
+	})
+}
+
+// writeChunkWriteError reports version conflicts as 409 so clients can re-read and retry,
+// invalid metadata operations as 400, contents refused by the workspace's PII policy as
+// 422 and writes the caller's role does not allow as 403
+func writeChunkWriteError(w http.ResponseWriter, message string, err error) int {
+	if errors.Is(err, services.ErrInvalidMetadataPatch) {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid metadata operations", err.Error())
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, services.ErrVersionConflict) {
+		writeErrorResponse(w, http.StatusConflict, "chunk version conflict", err.Error())
+		return http.StatusConflict
+	}
+	if errors.Is(err, services.ErrPIIRejected) {
+		writeErrorResponse(w, http.StatusUnprocessableEntity, "chunk contents rejected by pii policy", err.Error())
+		return http.StatusUnprocessableEntity
+	}
+	return writeServiceError(w, http.StatusInternalServerError, message, err)
+}
+
+// writeServiceError reports operations the caller's role does not allow as 403, changes
+// to subtrees another editor has locked as 409 and other errors with status
+func writeServiceError(w http.ResponseWriter, status int, message string, err error) int {
+	if errors.Is(err, services.ErrPermissionDenied) {
+		writeErrorResponse(w, http.StatusForbidden, "permission denied", err.Error())
+		return http.StatusForbidden
+	}
+	if errors.Is(err, services.ErrChunkLocked) {
+		writeErrorResponse(w, http.StatusConflict, "chunk is locked", err.Error())
+		return http.StatusConflict
+	}
+	writeErrorResponse(w, status, message, err.Error())
+	return status
+}
+
 // DeleteChunk handles DELETE /api/v1/chunks/{id}
 func (h *UnifiedChunkHandler) DeleteChunk(w http.ResponseWriter, r *http.Request) {
 	h.performanceMonitor.MonitoredHTTPOperation("delete_chunk", w, func() (int, error) {
@@ -252,8 +422,8 @@ func (h *UnifiedChunkHandler) DeleteChunk(w http.ResponseWriter, r *http.Request
 		}
 
 		if err := h.unifiedService.DeleteChunk(r.Context(), chunkID); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to delete chunk", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to delete chunk", err)
+			return status, err
 		}
 
 		// Invalidate cache
@@ -284,6 +454,12 @@ func (h *UnifiedChunkHandler) GetChunkChildren(w http.ResponseWriter, r *http.Re
 			return http.StatusInternalServerError, err
 		}
 
+		// A removed child does not move the latest last_updated, so lists only get an ETag
+		etag, _ := chunkValidators(children)
+		if h.httpCache.notModified(w, r, etag, time.Time{}) {
+			return http.StatusNotModified, nil
+		}
+
 		// Convert to legacy format
 		legacyChildren := h.converter.BatchFromUnified(children)
 
@@ -317,6 +493,11 @@ func (h *UnifiedChunkHandler) GetChunkHierarchy(w http.ResponseWriter, r *http.R
 			return http.StatusInternalServerError, err
 		}
 
+		etag, _ := chunkValidators(descendants)
+		if h.httpCache.notModified(w, r, etag, time.Time{}) {
+			return http.StatusNotModified, nil
+		}
+
 		// Convert to legacy format
 		legacyDescendants := h.converter.BatchFromUnified(descendants)
 
@@ -352,8 +533,8 @@ func (h *UnifiedChunkHandler) MoveChunk(w http.ResponseWriter, r *http.Request)
 		}
 
 		if err := h.unifiedService.MoveChunk(r.Context(), chunkID, newParentID); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to move chunk", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to move chunk", err)
+			return status, err
 		}
 
 		// Invalidate related caches
@@ -369,6 +550,164 @@ func (h *UnifiedChunkHandler) MoveChunk(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// MoveSubtree handles POST /api/v1/chunks/{id}/move-subtree
+func (h *UnifiedChunkHandler) MoveSubtree(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("move_subtree", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var req models.MoveChunkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		newParentID := ""
+		if req.NewParentID != nil {
+			newParentID = *req.NewParentID
+		}
+
+		result, err := h.unifiedService.MoveSubtree(r.Context(), chunkID, newParentID)
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to move subtree", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// CopySubtree handles POST /api/v1/chunks/{id}/copy
+func (h *UnifiedChunkHandler) CopySubtree(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("copy_subtree", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var req models.CopySubtreeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		newParentID := ""
+		if req.NewParentID != nil {
+			newParentID = *req.NewParentID
+		}
+
+		result, err := h.unifiedService.CopySubtree(r.Context(), chunkID, newParentID)
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to copy subtree", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, result)
+		return http.StatusCreated, nil
+	})
+}
+
+// DeleteSubtree handles POST /api/v1/chunks/{id}/delete-subtree. Without "confirm" it
+// only reports what the delete would affect.
+func (h *UnifiedChunkHandler) DeleteSubtree(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("delete_subtree", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var opts models.SubtreeDeleteOptions
+		if err := json.NewDecoder(r.Body).Decode(&opts); err != nil && err != io.EOF {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.unifiedService.DeleteSubtree(r.Context(), chunkID, opts)
+		if err != nil {
+			if errors.Is(err, services.ErrSubtreeChanged) {
+				writeErrorResponse(w, http.StatusConflict, "subtree changed since preview", err.Error())
+				return http.StatusConflict, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to delete subtree", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// MergeChunks handles POST /api/v1/chunks/{id}/merge, merging the source chunk named in
+// the body into the chunk in the path
+func (h *UnifiedChunkHandler) MergeChunks(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("merge_chunks", w, func() (int, error) {
+		chunkID := mux.Vars(r)["id"]
+		if chunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "chunk ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var req models.ChunkMergeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		result, err := h.unifiedService.MergeChunks(r.Context(), chunkID, req.SourceChunkID, req.Strategy)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidChunkMerge) {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid chunk merge", err.Error())
+				return http.StatusBadRequest, err
+			}
+			if strings.Contains(err.Error(), "not found") {
+				writeErrorResponse(w, http.StatusNotFound, "chunk not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to merge chunks", err)
+			return status, err
+		}
+
+		if h.cacheService != nil {
+			h.cacheService.Delete(r.Context(), "chunk:"+chunkID)
+			h.cacheService.Delete(r.Context(), "chunk:"+req.SourceChunkID)
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
+// BulkMove handles POST /api/v1/chunks/bulk-move
+func (h *UnifiedChunkHandler) BulkMove(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("bulk_move", w, func() (int, error) {
+		var req models.BulkMoveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if len(req.Moves) == 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "at least one move is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		result, err := h.unifiedService.BulkMove(r.Context(), req.Moves)
+		if err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to move chunks", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
 // BatchCreateChunks handles POST /api/v1/chunks/batch
 func (h *UnifiedChunkHandler) BatchCreateChunks(w http.ResponseWriter, r *http.Request) {
 	h.performanceMonitor.MonitoredHTTPOperation("batch_create_chunks", w, func() (int, error) {
@@ -392,8 +731,8 @@ func (h *UnifiedChunkHandler) BatchCreateChunks(w http.ResponseWriter, r *http.R
 		})
 
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to create chunks", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeChunkWriteError(w, "failed to create chunks", err)
+			return status, err
 		}
 
 		// Convert back to legacy format for response
@@ -432,8 +771,8 @@ func (h *UnifiedChunkHandler) BatchUpdateChunks(w http.ResponseWriter, r *http.R
 		})
 
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to update chunks", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeChunkWriteError(w, "failed to update chunks", err)
+			return status, err
 		}
 
 		// Invalidate caches for updated chunks
@@ -3,10 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"semantic-text-processor/models"
 	"semantic-text-processor/services"
+	"strconv"
 	"strings"
 	"time"
 
@@ -81,20 +83,21 @@ func (h *UnifiedTagHandler) AddTag(w http.ResponseWriter, r *http.Request) {
 		// First, find or create the tag chunk
 		tagChunkID, err := h.findOrCreateTagChunk(r.Context(), req.TagContent)
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to find or create tag", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to find or create tag", err)
+			return status, err
 		}
 
 		// Add tag relationship
 		if err := h.unifiedService.AddTags(r.Context(), chunkID, []string{tagChunkID}); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to add tag", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to add tag", err)
+			return status, err
 		}
 
 		// Invalidate caches
 		if h.cacheService != nil {
 			h.cacheService.Delete(r.Context(), "chunk_tags:"+chunkID)
 			h.cacheService.Delete(r.Context(), "tag_chunks:"+tagChunkID)
+			h.cacheService.DeletePattern(r.Context(), "tag_chunks:*:rollup:*")
 		}
 
 		w.WriteHeader(http.StatusCreated)
@@ -121,14 +124,15 @@ func (h *UnifiedTagHandler) RemoveTag(w http.ResponseWriter, r *http.Request) {
 
 		// Remove tag relationship
 		if err := h.unifiedService.RemoveTags(r.Context(), chunkID, []string{tagID}); err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to remove tag", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to remove tag", err)
+			return status, err
 		}
 
 		// Invalidate caches
 		if h.cacheService != nil {
 			h.cacheService.Delete(r.Context(), "chunk_tags:"+chunkID)
 			h.cacheService.Delete(r.Context(), "tag_chunks:"+tagID)
+			h.cacheService.DeletePattern(r.Context(), "tag_chunks:*:rollup:*")
 		}
 
 		w.WriteHeader(http.StatusNoContent)
@@ -210,12 +214,27 @@ func (h *UnifiedTagHandler) GetChunksByTag(w http.ResponseWriter, r *http.Reques
 			return http.StatusNotFound, err
 		}
 
+		// Optionally roll up chunks tagged with descendant tags
+		opts := &models.TagQueryOptions{}
+		if v := r.URL.Query().Get("include_descendants"); v != "" {
+			opts.IncludeDescendants, _ = strconv.ParseBool(v)
+		}
+		if v := r.URL.Query().Get("max_depth"); v != "" {
+			if parsed, err := strconv.Atoi(v); err == nil && parsed > 0 {
+				opts.MaxDepth = parsed
+			}
+		}
+
+		cacheKey := "tag_chunks:" + tagChunkID
+		if opts.IncludeDescendants {
+			cacheKey = fmt.Sprintf("tag_chunks:%s:rollup:%d", tagChunkID, opts.MaxDepth)
+		}
+
 		// Try cache first
 		var chunks []models.UnifiedChunkRecord
 		cacheHit := false
 
 		if h.cacheService != nil {
-			cacheKey := "tag_chunks:" + tagChunkID
 			var cached interface{}
 		if h.cacheService.Get(r.Context(), cacheKey, &cached) == nil {
 				if cachedChunks, ok := cached.([]models.UnifiedChunkRecord); ok {
@@ -226,7 +245,7 @@ func (h *UnifiedTagHandler) GetChunksByTag(w http.ResponseWriter, r *http.Reques
 		}
 
 		if chunks == nil {
-			chunks, err = h.unifiedService.GetChunksByTag(r.Context(), tagChunkID)
+			chunks, err = h.unifiedService.GetChunksByTagWithOptions(r.Context(), tagChunkID, opts)
 			if err != nil {
 				writeErrorResponse(w, http.StatusInternalServerError, "failed to get chunks by tag", err.Error())
 				return http.StatusInternalServerError, err
@@ -234,7 +253,6 @@ func (h *UnifiedTagHandler) GetChunksByTag(w http.ResponseWriter, r *http.Reques
 
 			// Cache the result
 			if h.cacheService != nil {
-				cacheKey := "tag_chunks:" + tagChunkID
 				h.cacheService.Set(r.Context(), cacheKey, chunks, 10*time.Minute)
 			}
 		}
@@ -314,6 +332,186 @@ func (h *UnifiedTagHandler) GetChunksByTags(w http.ResponseWriter, r *http.Reque
 	})
 }
 
+// SetTagParent handles PUT /api/v1/tags/{id}/parent
+func (h *UnifiedTagHandler) SetTagParent(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("set_tag_parent", w, func() (int, error) {
+		tagChunkID := mux.Vars(r)["id"]
+		if tagChunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "tag ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var req struct {
+			ParentTagID string `json:"parent_tag_id"` // empty detaches the tag
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if err := h.unifiedService.SetTagParent(r.Context(), tagChunkID, req.ParentTagID); err != nil {
+			if strings.Contains(err.Error(), "circular") || strings.Contains(err.Error(), "own parent") || strings.Contains(err.Error(), "not a tag") {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid tag parent", err.Error())
+				return http.StatusBadRequest, err
+			}
+			if strings.Contains(err.Error(), "not found") {
+				writeErrorResponse(w, http.StatusNotFound, "tag not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to set tag parent", err)
+			return status, err
+		}
+
+		// Rollup results depend on the hierarchy
+		if h.cacheService != nil {
+			h.cacheService.DeletePattern(r.Context(), "tag_chunks:*")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
+
+// GetTagHierarchy handles GET /api/v1/tags/{id}/hierarchy
+func (h *UnifiedTagHandler) GetTagHierarchy(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_tag_hierarchy", w, func() (int, error) {
+		tagChunkID := mux.Vars(r)["id"]
+		if tagChunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "tag ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		ancestors, err := h.unifiedService.GetTagAncestors(r.Context(), tagChunkID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get tag ancestors", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		descendants, err := h.unifiedService.GetTagDescendants(r.Context(), tagChunkID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get tag descendants", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"tag_id":      tagChunkID,
+			"ancestors":   ancestors,
+			"descendants": descendants,
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// GetTagRollup handles GET /api/v1/tags/{id}/rollup
+func (h *UnifiedTagHandler) GetTagRollup(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_tag_rollup", w, func() (int, error) {
+		tagChunkID := mux.Vars(r)["id"]
+		if tagChunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "tag ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		rollup, err := h.unifiedService.GetTagRollup(r.Context(), tagChunkID)
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to get tag rollup", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, rollup)
+		return http.StatusOK, nil
+	})
+}
+
+// RenameTag handles PUT /api/v1/tags/{id}/name
+func (h *UnifiedTagHandler) RenameTag(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("rename_tag", w, func() (int, error) {
+		tagChunkID := mux.Vars(r)["id"]
+		if tagChunkID == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "tag ID is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		var req struct {
+			Name string `json:"name"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if strings.TrimSpace(req.Name) == "" {
+			writeErrorResponse(w, http.StatusBadRequest, "tag name is required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		if err := h.unifiedService.RenameTag(r.Context(), tagChunkID, req.Name); err != nil {
+			if strings.Contains(err.Error(), "already exists") {
+				writeErrorResponse(w, http.StatusConflict, "tag name already in use", err.Error())
+				return http.StatusConflict, err
+			}
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not a tag") {
+				writeErrorResponse(w, http.StatusNotFound, "tag not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to rename tag", err)
+			return status, err
+		}
+
+		if h.cacheService != nil {
+			h.cacheService.DeletePattern(r.Context(), "chunk_tags:*")
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+		return http.StatusNoContent, nil
+	})
+}
+
+// MergeTags handles POST /api/v1/tags/merge
+func (h *UnifiedTagHandler) MergeTags(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("merge_tags", w, func() (int, error) {
+		var req struct {
+			SourceTagIDs []string `json:"source_tag_ids"`
+			TargetTagID  string   `json:"target_tag_id"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if req.TargetTagID == "" || len(req.SourceTagIDs) == 0 {
+			writeErrorResponse(w, http.StatusBadRequest, "source_tag_ids and target_tag_id are required", "")
+			return http.StatusBadRequest, nil
+		}
+
+		result, err := h.unifiedService.MergeTags(r.Context(), req.SourceTagIDs, req.TargetTagID)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "not a tag") {
+				writeErrorResponse(w, http.StatusNotFound, "tag not found", err.Error())
+				return http.StatusNotFound, err
+			}
+			if strings.Contains(err.Error(), "circular") || strings.Contains(err.Error(), "required") {
+				writeErrorResponse(w, http.StatusBadRequest, "invalid tag merge", err.Error())
+				return http.StatusBadRequest, err
+			}
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to merge tags", err)
+			return status, err
+		}
+
+		if h.cacheService != nil {
+			h.cacheService.DeletePattern(r.Context(), "chunk_tags:*")
+			h.cacheService.DeletePattern(r.Context(), "tag_chunks:*")
+		}
+
+		writeJSONResponse(w, http.StatusOK, result)
+		return http.StatusOK, nil
+	})
+}
+
 // BatchTagOperations handles POST /api/v1/chunks/tags/batch
 func (h *UnifiedTagHandler) BatchTagOperations(w http.ResponseWriter, r *http.Request) {
 	h.performanceMonitor.MonitoredHTTPOperation("batch_tag_operations", w, func() (int, error) {
@@ -334,8 +532,8 @@ func (h *UnifiedTagHandler) BatchTagOperations(w http.ResponseWriter, r *http.Re
 		})
 
 		if err != nil {
-			writeErrorResponse(w, http.StatusInternalServerError, "failed to process batch tag operations", err.Error())
-			return http.StatusInternalServerError, err
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to process batch tag operations", err)
+			return status, err
 		}
 
 		response := map[string]interface{}{
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+	"time"
+)
+
+// VectorIndexHandler handles pgvector index management HTTP requests
+type VectorIndexHandler struct {
+	indexManager       services.VectorIndexManager
+	performanceMonitor *PerformanceMonitor
+	logger             *log.Logger
+}
+
+// NewVectorIndexHandler creates a new vector index handler
+func NewVectorIndexHandler(
+	indexManager services.VectorIndexManager,
+	logger *log.Logger,
+	slowQueryThreshold time.Duration,
+	metricsEnabled bool,
+) *VectorIndexHandler {
+	return &VectorIndexHandler{
+		indexManager:       indexManager,
+		performanceMonitor: NewPerformanceMonitor(slowQueryThreshold, logger, metricsEnabled),
+		logger:             logger,
+	}
+}
+
+// ListIndexes handles GET /api/v1/vector-indexes
+func (h *VectorIndexHandler) ListIndexes(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("list_vector_indexes", w, func() (int, error) {
+		indexes, err := h.indexManager.ListIndexes(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to list vector indexes", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"indexes": indexes,
+			"count":   len(indexes),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// CreateIndex handles POST /api/v1/vector-indexes
+func (h *VectorIndexHandler) CreateIndex(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("create_vector_index", w, func() (int, error) {
+		spec, err := h.decodeSpec(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if err := h.indexManager.CreateIndex(r.Context(), spec); err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to create vector index", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusCreated, spec)
+		return http.StatusCreated, nil
+	})
+}
+
+// RebuildIndex handles POST /api/v1/vector-indexes/rebuild
+func (h *VectorIndexHandler) RebuildIndex(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("rebuild_vector_index", w, func() (int, error) {
+		spec, err := h.decodeSpec(r)
+		if err != nil {
+			writeErrorResponse(w, http.StatusBadRequest, "invalid request body", err.Error())
+			return http.StatusBadRequest, err
+		}
+
+		if err := h.indexManager.RebuildIndex(r.Context(), spec); err != nil {
+			status := writeServiceError(w, http.StatusInternalServerError, "failed to rebuild vector index", err)
+			return status, err
+		}
+
+		writeJSONResponse(w, http.StatusOK, spec)
+		return http.StatusOK, nil
+	})
+}
+
+// GetSuggestions handles GET /api/v1/vector-indexes/suggestions
+func (h *VectorIndexHandler) GetSuggestions(w http.ResponseWriter, r *http.Request) {
+	h.performanceMonitor.MonitoredHTTPOperation("get_vector_index_suggestions", w, func() (int, error) {
+		suggestions, err := h.indexManager.SuggestIndexes(r.Context())
+		if err != nil {
+			writeErrorResponse(w, http.StatusInternalServerError, "failed to analyze vector indexes", err.Error())
+			return http.StatusInternalServerError, err
+		}
+
+		response := map[string]interface{}{
+			"index_suggestions": suggestions,
+			"count":             len(suggestions),
+		}
+
+		writeJSONResponse(w, http.StatusOK, response)
+		return http.StatusOK, nil
+	})
+}
+
+// decodeSpec overlays the request body, if any, on the configured default spec
+func (h *VectorIndexHandler) decodeSpec(r *http.Request) (*models.VectorIndexSpec, error) {
+	spec := h.indexManager.DefaultSpec("")
+	if r.ContentLength == 0 {
+		return spec, nil
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
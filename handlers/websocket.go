@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A minimal RFC 6455 server side: text and binary messages, fragmentation, ping/pong and
+// close. Extensions and subprotocols are not negotiated.
+
+const (
+	websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+	wsOpPing         = 0x9
+	wsOpPong         = 0xA
+
+	wsCloseNormal      = 1000
+	wsCloseProtocol    = 1002
+	wsCloseUnsupported = 1003
+	wsCloseTooBig      = 1009
+
+	wsWriteTimeout = 10 * time.Second
+)
+
+// errWebSocketClosed is returned by ReadMessage once the peer has closed the connection
+var errWebSocketClosed = errors.New("websocket closed")
+
+// wsConn is a server side WebSocket connection. Reads must come from one goroutine;
+// writes may come from any.
+type wsConn struct {
+	conn           net.Conn
+	reader         *bufio.Reader
+	maxMessageSize int64
+	readTimeout    time.Duration
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// upgradeWebSocket completes the opening handshake. On failure it has already written an
+// error response.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, maxMessageSize int64) (*wsConn, error) {
+	if r.Method != http.MethodGet ||
+		!headerHasToken(r.Header, "Connection", "upgrade") ||
+		!headerHasToken(r.Header, "Upgrade", "websocket") {
+		writeErrorResponse(w, http.StatusUpgradeRequired, "websocket upgrade required", "")
+		return nil, fmt.Errorf("not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeErrorResponse(w, http.StatusUpgradeRequired, "unsupported websocket version", r.Header.Get("Sec-WebSocket-Version"))
+		return nil, fmt.Errorf("unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		writeErrorResponse(w, http.StatusBadRequest, "invalid Sec-WebSocket-Key", "")
+		return nil, fmt.Errorf("invalid websocket key")
+	}
+
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		writeErrorResponse(w, http.StatusInternalServerError, "websocket upgrade failed", err.Error())
+		return nil, fmt.Errorf("failed to hijack connection: %w", err)
+	}
+	// The server's read and write timeouts do not apply to a long-lived socket
+	conn.SetDeadline(time.Time{})
+
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to write handshake: %w", err)
+	}
+
+	return &wsConn{conn: conn, reader: rw.Reader, maxMessageSize: maxMessageSize}, nil
+}
+
+// headerHasToken reports whether a comma separated header contains token
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SetReadTimeout bounds the wait for each frame, pongs included, so that a peer that
+// stops answering pings is noticed. Zero waits forever.
+func (c *wsConn) SetReadTimeout(d time.Duration) {
+	c.readTimeout = d
+}
+
+// ReadMessage returns the next data message, answering pings on the way. It returns
+// errWebSocketClosed after the peer's close frame.
+func (c *wsConn) ReadMessage() (opcode byte, payload []byte, err error) {
+	var message []byte
+	messageOp := byte(0)
+	for {
+		fin, op, data, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			if err := c.writeFrame(wsOpPong, data); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			code := uint16(wsCloseNormal)
+			if len(data) >= 2 {
+				code = binary.BigEndian.Uint16(data)
+			}
+			c.Close(code, "")
+			return 0, nil, errWebSocketClosed
+		case wsOpText, wsOpBinary:
+			if messageOp != 0 {
+				return 0, nil, c.fail(wsCloseProtocol, "expected a continuation frame")
+			}
+			messageOp = op
+		case wsOpContinuation:
+			if messageOp == 0 {
+				return 0, nil, c.fail(wsCloseProtocol, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, c.fail(wsCloseProtocol, "unknown opcode")
+		}
+
+		if int64(len(message)+len(data)) > c.maxMessageSize {
+			return 0, nil, c.fail(wsCloseTooBig, "message too big")
+		}
+		message = append(message, data...)
+		if fin {
+			return messageOp, message, nil
+		}
+	}
+}
+
+// readFrame reads one frame and unmasks its payload
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	if c.readTimeout > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	}
+
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(wsCloseProtocol, "reserved bits set")
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(wsCloseProtocol, "client frames must be masked")
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, err := io.ReadFull(c.reader, extended[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if opcode >= wsOpClose && (length > 125 || !fin) {
+		return false, 0, nil, c.fail(wsCloseProtocol, "invalid control frame")
+	}
+	if length > uint64(c.maxMessageSize) {
+		return false, 0, nil, c.fail(wsCloseTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteText sends one text message
+func (c *wsConn) WriteText(data []byte) error {
+	return c.writeFrame(wsOpText, data)
+}
+
+// Ping sends a ping, which a live peer answers with a pong
+func (c *wsConn) Ping() error {
+	return c.writeFrame(wsOpPing, nil)
+}
+
+// writeFrame writes one unmasked, unfragmented frame
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return errWebSocketClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+	switch length := len(payload); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if _, err := c.conn.Write(append(header, payload...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// fail closes the connection with a protocol error and returns it
+func (c *wsConn) fail(code uint16, reason string) error {
+	c.Close(code, reason)
+	return fmt.Errorf("websocket protocol error: %s", reason)
+}
+
+// Close sends a close frame and closes the connection; later calls do nothing
+func (c *wsConn) Close(code uint16, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	payload = append(payload, reason...)
+	c.writeFrame(wsOpClose, payload)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
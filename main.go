@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"semantic-text-processor/config"
 	"semantic-text-processor/server"
@@ -14,16 +16,22 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
-	// Load configuration
-	cfg := config.LoadConfig()
-	
-	// Validate configuration
-	if err := cfg.Validate(); err != nil {
+	// Load layered configuration: file, then environment, then -set flags
+	opts := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.LoadAndValidate(*opts)
+	if err != nil {
 		log.Fatalf("Configuration validation failed: %v", err)
 	}
 
 	// Create and start server
 	srv := server.NewServer(cfg)
+
+	// Reload tunables on SIGHUP or configuration file changes
+	watcher := config.NewWatcher(*opts, cfg, nil)
+	watcher.OnReload(srv.ApplyConfig)
+	go watcher.Watch(context.Background())
 	
 	log.Println("Semantic Text Processor starting...")
 	if err := srv.Start(); err != nil {
@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
 	"strings"
 
@@ -24,7 +25,7 @@ func (t *InkBatchProcessImagesTool) GetName() string {
 }
 
 func (t *InkBatchProcessImagesTool) GetDescription() string {
-	return "Start batch processing of images in a folder"
+	return "Start batch processing of images in a folder. When the call carries a progress token, it waits for the batch and reports progress notifications."
 }
 
 func (t *InkBatchProcessImagesTool) GetInputSchema() map[string]interface{} {
@@ -146,6 +147,11 @@ func (t *InkBatchProcessImagesTool) Execute(ctx context.Context, params map[stri
 		}, nil
 	}
 
+	// 客戶端要求進度時等待批次完成並逐步回報，否則立即回傳批次 ID
+	if progressRequested(ctx) {
+		return t.awaitBatch(ctx, batchJob.ID, len(files))
+	}
+
 	return &MCPToolResult{
 		Content: []MCPContent{{
 			Type: "text",
@@ -156,6 +162,62 @@ func (t *InkBatchProcessImagesTool) Execute(ctx context.Context, params map[stri
 	}, nil
 }
 
+// awaitBatch 將批次進度轉為 notifications/progress，直到批次結束或請求取消
+func (t *InkBatchProcessImagesTool) awaitBatch(ctx context.Context, batchID string, totalFiles int) (*MCPToolResult, error) {
+	progress, err := t.server.services.BatchProcessor.GetProgressChannel(batchID)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Failed to follow batch progress: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	reportProgress(ctx, 0, float64(totalFiles), "Batch "+batchID+" started")
+	for done := false; !done; {
+		select {
+		case update, ok := <-progress:
+			if !ok {
+				done = true
+				break
+			}
+			message := update.CurrentFile
+			if message == "" {
+				message = update.Status
+			}
+			reportProgress(ctx, float64(update.ProcessedFiles), float64(update.TotalFiles), message)
+		case <-ctx.Done():
+			// 批次繼續在背景執行，客戶端可用批次 ID 查詢
+			return &MCPToolResult{
+				Content: []MCPContent{{
+					Type: "text",
+					Text: fmt.Sprintf("Stopped waiting for batch %s: %v\n\nThe batch keeps running; check its progress using the batch ID.", batchID, ctx.Err()),
+				}},
+				IsError: false,
+			}, nil
+		}
+	}
+
+	status, err := t.server.services.BatchProcessor.GetBatchStatus(batchID)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Failed to get batch status: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Batch processing finished.\n\nBatch ID: %s\nTotal Files: %d\nProcessed: %d\nFailed: %d\nStatus: %s\n",
+		status.BatchID, status.TotalFiles, status.ProcessedFiles, status.FailedFiles, status.Status))
+	for _, batchErr := range status.Errors {
+		resultText.WriteString(fmt.Sprintf("- %s: %s\n", batchErr.Filename, batchErr.Error))
+	}
+
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: resultText.String()}},
+		IsError: status.FailedFiles > 0 && status.FailedFiles == status.TotalFiles,
+	}, nil
+}
+
 // InkGetImagesForSlidesTool Slide Generator 圖片推薦工具
 type InkGetImagesForSlidesTool struct {
 	server *MCPServer
@@ -286,7 +348,7 @@ func (t *InkSearchImagesTool) GetName() string {
 }
 
 func (t *InkSearchImagesTool) GetDescription() string {
-	return "Search for similar images using image similarity search"
+	return "Search for similar images by perceptual hash and CLIP embedding similarity"
 }
 
 func (t *InkSearchImagesTool) GetInputSchema() map[string]interface{} {
@@ -301,11 +363,27 @@ func (t *InkSearchImagesTool) GetInputSchema() map[string]interface{} {
 				"type":        "string",
 				"description": "Chunk ID of the reference image (alternative to image_url)",
 			},
+			"image_base64": map[string]interface{}{
+				"type":        "string",
+				"description": "Base64-encoded reference image (alternative to image_url)",
+			},
 			"limit": map[string]interface{}{
 				"type":        "integer",
 				"description": "Maximum number of results",
 				"default":     10,
 			},
+			"max_hash_distance": map[string]interface{}{
+				"type":        "integer",
+				"description": "Perceptual hash distance (0-64) treated as a match; overrides the server default",
+			},
+			"embedding_threshold": map[string]interface{}{
+				"type":        "number",
+				"description": "CLIP similarity (0-1) treated as a match; overrides the server default",
+			},
+			"hash_weight": map[string]interface{}{
+				"type":        "number",
+				"description": "Weight of hash similarity in the combined score (0-1); overrides the server default",
+			},
 			"min_similarity": map[string]interface{}{
 				"type":        "number",
 				"description": "Minimum similarity threshold",
@@ -320,6 +398,7 @@ func (t *InkSearchImagesTool) GetInputSchema() map[string]interface{} {
 		"oneOf": []map[string]interface{}{
 			{"required": []string{"image_url"}},
 			{"required": []string{"chunk_id"}},
+			{"required": []string{"image_base64"}},
 		},
 	}
 }
@@ -327,10 +406,11 @@ func (t *InkSearchImagesTool) GetInputSchema() map[string]interface{} {
 func (t *InkSearchImagesTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
 	imageURL, hasURL := params["image_url"].(string)
 	chunkID, hasChunkID := params["chunk_id"].(string)
+	imageBase64, hasImage := params["image_base64"].(string)
 
-	if (!hasURL || imageURL == "") && (!hasChunkID || chunkID == "") {
+	if (!hasURL || imageURL == "") && (!hasChunkID || chunkID == "") && (!hasImage || imageBase64 == "") {
 		return &MCPToolResult{
-			Content: []MCPContent{{Type: "text", Text: "Error: either image_url or chunk_id parameter is required"}},
+			Content: []MCPContent{{Type: "text", Text: "Error: one of image_url, chunk_id or image_base64 parameter is required"}},
 			IsError: true,
 		}, nil
 	}
@@ -360,12 +440,36 @@ func (t *InkSearchImagesTool) Execute(ctx context.Context, params map[string]int
 		SortOrder:           "desc",
 	}
 
-	// 執行搜尋
+	// 執行搜尋：chunk 與原始圖片以感知雜湊加 CLIP 向量比對，URL 以 CLIP 向量比對
 	var searchResponse *services.ImageSearchResponse
 	var err error
 
-	if hasChunkID && chunkID != "" {
-		searchResponse, err = t.server.services.ImageSimilarity.SearchByChunkID(ctx, chunkID, options)
+	if (hasChunkID && chunkID != "") || (hasImage && imageBase64 != "") {
+		req := &services.FindSimilarImagesRequest{
+			ChunkID:  chunkID,
+			Limit:    limit,
+			MinScore: minSimilarity,
+		}
+		if req.ChunkID == "" {
+			req.Data, err = base64.StdEncoding.DecodeString(imageBase64)
+			if err != nil {
+				return &MCPToolResult{
+					Content: []MCPContent{{Type: "text", Text: "Error: image_base64 is not valid base64"}},
+					IsError: true,
+				}, nil
+			}
+		}
+		if distance, ok := params["max_hash_distance"].(float64); ok {
+			maxHashDistance := int(distance)
+			req.MaxHashDistance = &maxHashDistance
+		}
+		if threshold, ok := params["embedding_threshold"].(float64); ok {
+			req.EmbeddingThreshold = &threshold
+		}
+		if weight, ok := params["hash_weight"].(float64); ok {
+			req.HashWeight = &weight
+		}
+		searchResponse, err = t.server.services.ImageSimilarity.FindSimilarImages(ctx, req)
 	} else {
 		searchResponse, err = t.server.services.ImageSimilarity.SearchByImageURL(ctx, imageURL, options)
 	}
@@ -387,6 +491,10 @@ func (t *InkSearchImagesTool) Execute(ctx context.Context, params map[string]int
 	for i, result := range searchResponse.Results {
 		resultText.WriteString(fmt.Sprintf("%d. **%s** (similarity: %.3f)\n", 
 			i+1, result.ChunkID, result.Similarity))
+		if result.HashDistance != nil {
+			resultText.WriteString(fmt.Sprintf("   Hash distance: %d, embedding similarity: %.3f\n",
+				*result.HashDistance, result.EmbeddingSimilarity))
+		}
 		resultText.WriteString(fmt.Sprintf("   Image URL: %s\n", result.ImageURL))
 		resultText.WriteString(fmt.Sprintf("   Description: %s\n", result.Description))
 		if len(result.Tags) > 0 {
@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"semantic-text-processor/models"
+)
+
+const (
+	// pageResourcePrefix 頁面資源 URI 前綴
+	pageResourcePrefix = "ink://pages/"
+	// templateResourcePrefix 模板資源 URI 前綴
+	templateResourcePrefix = "ink://templates/"
+	// browsableResourceLimit resources/list 每種類型最多列出的數量
+	browsableResourceLimit = 500
+)
+
+// PageResource 頁面資源，讀取時回傳整頁內容的 Markdown 大綱
+type PageResource struct {
+	chunkID string
+	title   string
+	server  *MCPServer
+}
+
+// NewPageResource 建立頁面資源
+func NewPageResource(chunkID, title string, server *MCPServer) *PageResource {
+	return &PageResource{
+		chunkID: chunkID,
+		title:   title,
+		server:  server,
+	}
+}
+
+func (r *PageResource) GetURI() string {
+	return pageResourcePrefix + r.chunkID
+}
+
+func (r *PageResource) GetName() string {
+	if r.title != "" {
+		return r.title
+	}
+	return fmt.Sprintf("Page %s", r.chunkID)
+}
+
+func (r *PageResource) GetDescription() string {
+	return fmt.Sprintf("Rendered content of page %s", r.GetName())
+}
+
+func (r *PageResource) GetMimeType() string {
+	return "text/markdown"
+}
+
+func (r *PageResource) Read(ctx context.Context) ([]byte, error) {
+	chunkService := r.server.services.ChunkService
+
+	page, err := chunkService.GetChunk(ctx, r.chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page: %w", err)
+	}
+	if !page.IsPage {
+		return nil, fmt.Errorf("chunk %s is not a page", r.chunkID)
+	}
+
+	descendants, err := chunkService.GetDescendants(ctx, r.chunkID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+
+	return []byte(renderChunkOutline("# "+chunkTitle(page), page.ChunkID, descendants)), nil
+}
+
+// TemplateResource 模板資源，讀取時回傳模板結構與插槽
+type TemplateResource struct {
+	chunkID string
+	title   string
+	server  *MCPServer
+}
+
+// NewTemplateResource 建立模板資源
+func NewTemplateResource(chunkID, title string, server *MCPServer) *TemplateResource {
+	return &TemplateResource{
+		chunkID: chunkID,
+		title:   title,
+		server:  server,
+	}
+}
+
+func (r *TemplateResource) GetURI() string {
+	return templateResourcePrefix + r.chunkID
+}
+
+func (r *TemplateResource) GetName() string {
+	if r.title != "" {
+		return r.title
+	}
+	return fmt.Sprintf("Template %s", r.chunkID)
+}
+
+func (r *TemplateResource) GetDescription() string {
+	return fmt.Sprintf("Structure and slots of template %s", r.GetName())
+}
+
+func (r *TemplateResource) GetMimeType() string {
+	return "text/markdown"
+}
+
+func (r *TemplateResource) Read(ctx context.Context) ([]byte, error) {
+	chunkService := r.server.services.ChunkService
+
+	template, err := chunkService.GetChunk(ctx, r.chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	if !template.IsTemplate {
+		return nil, fmt.Errorf("chunk %s is not a template", r.chunkID)
+	}
+
+	descendants, err := chunkService.GetDescendants(ctx, r.chunkID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template content: %w", err)
+	}
+
+	var content strings.Builder
+	content.WriteString(renderChunkOutline("# Template: "+chunkTitle(template), template.ChunkID, descendants))
+
+	var slots []string
+	for _, chunk := range descendants {
+		if chunk.IsSlot {
+			slots = append(slots, slotName(&chunk))
+		}
+	}
+	if len(slots) > 0 {
+		content.WriteString("\n## Slots\n\n")
+		for _, slot := range slots {
+			content.WriteString(fmt.Sprintf("- %s\n", slot))
+		}
+	}
+
+	return []byte(content.String()), nil
+}
+
+// listBrowsableResources 列出所有頁面與模板資源
+func (s *MCPServer) listBrowsableResources(ctx context.Context) ([]MCPResource, error) {
+	if s.services.ChunkService == nil {
+		return nil, nil
+	}
+
+	isTrue := true
+	pages, err := s.services.ChunkService.SearchChunks(ctx, &models.SearchQuery{IsPage: &isTrue, Limit: browsableResourceLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pages: %w", err)
+	}
+	templates, err := s.services.ChunkService.SearchChunks(ctx, &models.SearchQuery{IsTemplate: &isTrue, Limit: browsableResourceLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	resources := make([]MCPResource, 0, len(pages.Chunks)+len(templates.Chunks))
+	for i := range pages.Chunks {
+		resources = append(resources, NewPageResource(pages.Chunks[i].ChunkID, chunkTitle(&pages.Chunks[i]), s))
+	}
+	for i := range templates.Chunks {
+		resources = append(resources, NewTemplateResource(templates.Chunks[i].ChunkID, chunkTitle(&templates.Chunks[i]), s))
+	}
+	return resources, nil
+}
+
+// resolveResource 依 URI 取得已註冊資源或頁面、模板資源
+func (s *MCPServer) resolveResource(uri string) (MCPResource, bool) {
+	s.mu.RLock()
+	resource, exists := s.resources[uri]
+	s.mu.RUnlock()
+	if exists {
+		return resource, true
+	}
+
+	if s.services.ChunkService == nil {
+		return nil, false
+	}
+	if chunkID := strings.TrimPrefix(uri, pageResourcePrefix); chunkID != uri && chunkID != "" {
+		return NewPageResource(chunkID, "", s), true
+	}
+	if chunkID := strings.TrimPrefix(uri, templateResourcePrefix); chunkID != uri && chunkID != "" {
+		return NewTemplateResource(chunkID, "", s), true
+	}
+	return nil, false
+}
+
+// renderChunkOutline 將子孫區塊依階層渲染為 Markdown 清單，子頁面只以連結呈現
+func renderChunkOutline(heading, rootID string, descendants []models.UnifiedChunkRecord) string {
+	children := make(map[string][]*models.UnifiedChunkRecord)
+	for i := range descendants {
+		chunk := &descendants[i]
+		if chunk.Parent != nil {
+			children[*chunk.Parent] = append(children[*chunk.Parent], chunk)
+		}
+	}
+
+	var out strings.Builder
+	out.WriteString(heading)
+	out.WriteString("\n\n")
+
+	var walk func(parentID string, depth int)
+	walk = func(parentID string, depth int) {
+		for _, chunk := range children[parentID] {
+			indent := strings.Repeat("  ", depth)
+			switch {
+			case chunk.IsPage:
+				out.WriteString(fmt.Sprintf("%s- [[%s]](%s%s)\n", indent, chunkTitle(chunk), pageResourcePrefix, chunk.ChunkID))
+				continue
+			case chunk.IsSlot:
+				out.WriteString(fmt.Sprintf("%s- {{%s}}\n", indent, slotName(chunk)))
+			default:
+				lines := strings.Split(strings.TrimSpace(chunk.Contents), "\n")
+				out.WriteString(fmt.Sprintf("%s- %s\n", indent, lines[0]))
+				for _, line := range lines[1:] {
+					out.WriteString(fmt.Sprintf("%s  %s\n", indent, line))
+				}
+			}
+			walk(chunk.ChunkID, depth+1)
+		}
+	}
+	walk(rootID, 0)
+
+	return out.String()
+}
+
+// chunkTitle 取區塊內容第一行作為標題
+func chunkTitle(chunk *models.UnifiedChunkRecord) string {
+	title := strings.TrimSpace(chunk.Contents)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if title == "" {
+		return chunk.ChunkID
+	}
+	return title
+}
+
+// slotName 取插槽名稱，未設定時使用區塊內容
+func slotName(chunk *models.UnifiedChunkRecord) string {
+	if name, ok := chunk.Metadata["slot_name"].(string); ok && name != "" {
+		return name
+	}
+	return chunkTitle(chunk)
+}
@@ -0,0 +1,205 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChunkService serves pages and templates from memory
+type fakeChunkService struct {
+	services.UnifiedChunkService
+	chunks []models.UnifiedChunkRecord
+}
+
+func (f *fakeChunkService) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	for i := range f.chunks {
+		if f.chunks[i].ChunkID == chunkID {
+			chunk := f.chunks[i]
+			return &chunk, nil
+		}
+	}
+	return nil, fmt.Errorf("chunk not found: %s", chunkID)
+}
+
+func (f *fakeChunkService) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
+	var descendants []models.UnifiedChunkRecord
+	parents := map[string]bool{ancestorChunkID: true}
+	for _, chunk := range f.chunks {
+		if chunk.Parent != nil && parents[*chunk.Parent] {
+			descendants = append(descendants, chunk)
+			parents[chunk.ChunkID] = true
+		}
+	}
+	return descendants, nil
+}
+
+func (f *fakeChunkService) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	result := &models.SearchResult{}
+	for _, chunk := range f.chunks {
+		if (query.IsPage != nil && chunk.IsPage) || (query.IsTemplate != nil && chunk.IsTemplate) {
+			result.Chunks = append(result.Chunks, chunk)
+		}
+	}
+	return result, nil
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func newResourceTestServer(t *testing.T) (*MCPServer, *fakeChunkService, *bytes.Buffer) {
+	chunkService := &fakeChunkService{chunks: []models.UnifiedChunkRecord{
+		{ChunkID: "page-1", Contents: "Project Notes", IsPage: true},
+		{ChunkID: "block-1", Contents: "First idea", Parent: strPtr("page-1")},
+		{ChunkID: "block-2", Contents: "Detail line\nsecond line", Parent: strPtr("block-1")},
+		{ChunkID: "page-2", Contents: "Sub Page", IsPage: true, Parent: strPtr("page-1")},
+		{ChunkID: "block-3", Contents: "Hidden in sub page", Parent: strPtr("page-2")},
+		{ChunkID: "tmpl-1", Contents: "Meeting", IsTemplate: true},
+		{ChunkID: "slot-1", Contents: "Attendees", IsSlot: true, Parent: strPtr("tmpl-1"),
+			Metadata: map[string]interface{}{"slot_name": "attendees"}},
+	}}
+
+	server := NewMCPServer("test", "1.0.0", "test server", &MCPServices{ChunkService: chunkService})
+	out := &bytes.Buffer{}
+	server.SetIO(strings.NewReader(""), out, &bytes.Buffer{})
+	return server, chunkService, out
+}
+
+func decodeMessages(t *testing.T, out *bytes.Buffer) []map[string]interface{} {
+	var messages []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var msg map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &msg))
+		messages = append(messages, msg)
+	}
+	out.Reset()
+	return messages
+}
+
+func TestRenderChunkOutline(t *testing.T) {
+	server, _, _ := newResourceTestServer(t)
+
+	content, err := NewPageResource("page-1", "", server).Read(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "# Project Notes\n\n"+
+		"- First idea\n"+
+		"  - Detail line\n"+
+		"    second line\n"+
+		"- [[Sub Page]](ink://pages/page-2)\n", string(content))
+
+	content, err = NewTemplateResource("tmpl-1", "", server).Read(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "# Template: Meeting")
+	assert.Contains(t, string(content), "- {{attendees}}")
+	assert.Contains(t, string(content), "## Slots\n\n- attendees\n")
+
+	_, err = NewPageResource("tmpl-1", "", server).Read(context.Background())
+	assert.Error(t, err)
+}
+
+func TestMCPServer_PageResources(t *testing.T) {
+	server, chunkService, out := newResourceTestServer(t)
+
+	require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`))
+	messages := decodeMessages(t, out)
+	require.Len(t, messages, 1)
+	resources := messages[0]["result"].(map[string]interface{})["resources"].([]interface{})
+	var uris []string
+	for _, resource := range resources {
+		uris = append(uris, resource.(map[string]interface{})["uri"].(string))
+	}
+	assert.ElementsMatch(t, []string{"ink://pages/page-1", "ink://pages/page-2", "ink://templates/tmpl-1"}, uris)
+
+	require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"ink://pages/page-2"}}`))
+	messages = decodeMessages(t, out)
+	contents := messages[0]["result"].(map[string]interface{})["contents"].([]interface{})
+	assert.Equal(t, "# Sub Page\n\n- Hidden in sub page\n", contents[0].(map[string]interface{})["text"])
+
+	t.Run("subscribers are notified when a page changes", func(t *testing.T) {
+		require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":3,"method":"resources/subscribe","params":{"uri":"ink://pages/page-2"}}`))
+		messages := decodeMessages(t, out)
+		require.Len(t, messages, 1)
+		assert.Nil(t, messages[0]["error"])
+
+		server.checkSubscriptions(context.Background())
+		assert.Empty(t, out.String(), "unchanged pages produce no notification")
+
+		chunkService.chunks[4].Contents = "Edited block"
+		server.checkSubscriptions(context.Background())
+		messages = decodeMessages(t, out)
+		require.Len(t, messages, 1)
+		assert.Equal(t, "notifications/resources/updated", messages[0]["method"])
+		assert.Equal(t, "ink://pages/page-2", messages[0]["params"].(map[string]interface{})["uri"])
+
+		require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":4,"method":"resources/unsubscribe","params":{"uri":"ink://pages/page-2"}}`))
+		decodeMessages(t, out)
+		chunkService.chunks[4].Contents = "Edited again"
+		server.checkSubscriptions(context.Background())
+		assert.Empty(t, out.String())
+	})
+
+	t.Run("subscribing to an unknown resource fails", func(t *testing.T) {
+		require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":5,"method":"resources/subscribe","params":{"uri":"ink://pages/missing"}}`))
+		messages := decodeMessages(t, out)
+		require.Len(t, messages, 1)
+		assert.NotNil(t, messages[0]["error"])
+	})
+}
+
+func TestMCPServer_TemplatePrompts(t *testing.T) {
+	server, chunkService, out := newResourceTestServer(t)
+	chunkService.chunks = append(chunkService.chunks,
+		models.UnifiedChunkRecord{ChunkID: "tmpl-2", Contents: "Summarize Page", IsTemplate: true,
+			Metadata: map[string]interface{}{"prompt_description": "Summarize a page for a reader"}},
+		models.UnifiedChunkRecord{ChunkID: "line-1", Contents: "Summarize {{page}} for {{audience}} in three bullets.", Parent: strPtr("tmpl-2")},
+		models.UnifiedChunkRecord{ChunkID: "slot-2", Contents: "Page to summarize", IsSlot: true, Parent: strPtr("tmpl-2"),
+			Metadata: map[string]interface{}{"slot_name": "page", "required": true}},
+		models.UnifiedChunkRecord{ChunkID: "slot-3", Contents: "audience", IsSlot: true, Parent: strPtr("tmpl-2")},
+	)
+
+	require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`))
+	messages := decodeMessages(t, out)
+	require.Len(t, messages, 1)
+	prompts := messages[0]["result"].(map[string]interface{})["prompts"].([]interface{})
+	require.Len(t, prompts, 2)
+	summarize := prompts[1].(map[string]interface{})
+	assert.Equal(t, "summarize-page", summarize["name"])
+	assert.Equal(t, "Summarize a page for a reader", summarize["description"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "page", "description": "Page to summarize", "required": true},
+		map[string]interface{}{"name": "audience", "description": "Value for slot audience", "required": false},
+	}, summarize["arguments"])
+
+	require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":2,"method":"prompts/get","params":{"name":"summarize-page","arguments":{"page":"Project Notes","audience":"the team"}}}`))
+	messages = decodeMessages(t, out)
+	require.Len(t, messages, 1)
+	text := messages[0]["result"].(map[string]interface{})["messages"].([]interface{})[0].(map[string]interface{})["content"].(map[string]interface{})["text"]
+	assert.Equal(t, "# Summarize Page\n\n"+
+		"- Summarize Project Notes for the team in three bullets.\n"+
+		"- Project Notes\n"+
+		"- the team\n", text)
+
+	for _, params := range []string{
+		`{"name":"summarize-page","arguments":{"audience":"the team"}}`,
+		`{"name":"summarize-page","arguments":{"page":"x","unknown":"y"}}`,
+		`{"name":"missing"}`,
+	} {
+		require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":`+params+`}`))
+		messages = decodeMessages(t, out)
+		require.Len(t, messages, 1)
+		assert.NotNil(t, messages[0]["error"], params)
+	}
+}
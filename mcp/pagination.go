@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// toolResultBudget 單次工具結果文字的位元組上限，超過時其餘結果以游標續取，
+// 避免訊息超過客戶端的大小限制
+const toolResultBudget = 64 * 1024
+
+// cursorSchema 可分頁工具共用的 cursor 參數說明
+var cursorSchema = map[string]interface{}{
+	"type":        "string",
+	"description": "Continue a previous call from its nextCursor; the other arguments of that call are reused",
+}
+
+// toolCursor 分頁游標內容：工具名稱、首次呼叫的參數與下一筆結果的位移
+type toolCursor struct {
+	Tool   string                 `json:"tool"`
+	Args   map[string]interface{} `json:"args"`
+	Offset int                    `json:"offset"`
+}
+
+// encodeToolCursor 將續取位置編碼為不透明的游標字串
+func encodeToolCursor(tool string, args map[string]interface{}, offset int) string {
+	data, err := json.Marshal(toolCursor{Tool: tool, Args: args, Offset: offset})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// resolveToolCursor 取得本次呼叫的參數與起始位移。帶 cursor 時還原首次呼叫的參數，
+// 其他參數忽略；否則使用傳入參數並從頭開始
+func resolveToolCursor(tool string, params map[string]interface{}) (map[string]interface{}, int, error) {
+	raw, ok := params["cursor"].(string)
+	if !ok || raw == "" {
+		return params, 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid cursor")
+	}
+	var cursor toolCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.Offset < 0 {
+		return nil, 0, fmt.Errorf("invalid cursor")
+	}
+	if cursor.Tool != tool {
+		return nil, 0, fmt.Errorf("cursor belongs to %s", cursor.Tool)
+	}
+	if cursor.Args == nil {
+		cursor.Args = make(map[string]interface{})
+	}
+	return cursor.Args, cursor.Offset, nil
+}
+
+// resultPage 在位元組預算內累積一頁結果
+type resultPage struct {
+	text   strings.Builder
+	budget int
+	items  int
+}
+
+func newResultPage(header string) *resultPage {
+	page := &resultPage{budget: toolResultBudget}
+	page.text.WriteString(header)
+	return page
+}
+
+// add 加入一筆結果，超出預算時不加入並回傳 false。每頁至少包含一筆，確保續取能前進
+func (p *resultPage) add(entry string) bool {
+	if p.items > 0 && p.text.Len()+len(entry) > p.budget {
+		return false
+	}
+	p.text.WriteString(entry)
+	p.items++
+	return true
+}
+
+// result 產生工具結果；還有結果時附上 nextCursor，並在文字中提示如何續取
+func (p *resultPage) result(tool string, args map[string]interface{}, nextOffset int, more bool) *MCPToolResult {
+	result := &MCPToolResult{}
+	if more {
+		if cursor := encodeToolCursor(tool, args, nextOffset); cursor != "" {
+			result.NextCursor = cursor
+			p.text.WriteString(fmt.Sprintf("More results are available. Call %s with {\"cursor\": %q} to continue.\n", tool, cursor))
+		}
+	}
+	result.Content = []MCPContent{{Type: "text", Text: p.text.String()}}
+	return result
+}
@@ -0,0 +1,145 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedChunkService answers searches from a fixed result list, honoring offset and limit
+type pagedChunkService struct {
+	services.UnifiedChunkService
+	chunks  []models.UnifiedChunkRecord
+	queries []models.SearchQuery
+}
+
+func (f *pagedChunkService) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	f.queries = append(f.queries, *query)
+	start := min(query.Offset, len(f.chunks))
+	end := min(start+query.Limit, len(f.chunks))
+	return &models.SearchResult{
+		Chunks:     f.chunks[start:end],
+		TotalCount: len(f.chunks),
+		HasMore:    end < len(f.chunks),
+	}, nil
+}
+
+// progressTool reports the progress values it is given
+type progressTool struct {
+	steps []float64
+}
+
+func (t *progressTool) GetName() string                        { return "progress" }
+func (t *progressTool) GetDescription() string                 { return "reports progress" }
+func (t *progressTool) GetInputSchema() map[string]interface{} { return map[string]interface{}{} }
+
+func (t *progressTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	for _, step := range t.steps {
+		reportProgress(ctx, step, 3, fmt.Sprintf("step %v", step))
+	}
+	return &MCPToolResult{Content: []MCPContent{{Type: "text", Text: "done"}}}, nil
+}
+
+func TestInkSearchTextTool_Cursor(t *testing.T) {
+	chunkService := &pagedChunkService{}
+	for i := 1; i <= 7; i++ {
+		chunkService.chunks = append(chunkService.chunks, models.UnifiedChunkRecord{ChunkID: fmt.Sprintf("chunk-%d", i)})
+	}
+	server := NewMCPServer("test", "1.0.0", "test server", &MCPServices{ChunkService: chunkService})
+	tool := NewInkSearchTextTool(server)
+	ctx := context.Background()
+
+	result, err := tool.Execute(ctx, map[string]interface{}{"query": "notes", "limit": float64(3)})
+	require.NoError(t, err)
+	require.NotEmpty(t, result.NextCursor)
+	assert.Contains(t, result.Content[0].Text, "Chunk ID: chunk-3")
+	assert.Contains(t, result.Content[0].Text, result.NextCursor, "the text tells the client how to continue")
+
+	var pages []string
+	for result.NextCursor != "" {
+		result, err = tool.Execute(ctx, map[string]interface{}{"cursor": result.NextCursor, "query": "ignored"})
+		require.NoError(t, err)
+		require.False(t, result.IsError)
+		pages = append(pages, result.Content[0].Text)
+	}
+	require.Len(t, pages, 2)
+	assert.Contains(t, pages[0], "**Result 4**\nChunk ID: chunk-4")
+	assert.Contains(t, pages[1], "**Result 7**\nChunk ID: chunk-7")
+	assert.NotContains(t, pages[1], "More results are available")
+
+	for _, query := range chunkService.queries {
+		assert.Equal(t, "notes", query.Content, "continuations reuse the first call's arguments")
+		assert.Equal(t, 3, query.Limit)
+	}
+	assert.Equal(t, 6, chunkService.queries[2].Offset)
+
+	t.Run("cursors are tied to their tool", func(t *testing.T) {
+		cursor := encodeToolCursor("ink_search_chunks", map[string]interface{}{"query": "x"}, 10)
+		result, err := tool.Execute(ctx, map[string]interface{}{"cursor": cursor})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+		assert.Contains(t, result.Content[0].Text, "cursor belongs to ink_search_chunks")
+
+		result, err = tool.Execute(ctx, map[string]interface{}{"cursor": "not a cursor"})
+		require.NoError(t, err)
+		assert.True(t, result.IsError)
+	})
+}
+
+func TestResultPage_Budget(t *testing.T) {
+	page := newResultPage("header\n")
+	page.budget = 30
+
+	assert.True(t, page.add(strings.Repeat("a", 40)), "the first entry is kept even when it is over the budget")
+	assert.False(t, page.add("b"))
+
+	page = newResultPage("header\n")
+	page.budget = 30
+	assert.True(t, page.add(strings.Repeat("a", 10)))
+	assert.True(t, page.add(strings.Repeat("b", 10)))
+	assert.False(t, page.add(strings.Repeat("c", 10)))
+
+	result := page.result("tool", map[string]interface{}{"query": "q"}, 2, false)
+	assert.Empty(t, result.NextCursor)
+	assert.Equal(t, "header\n"+strings.Repeat("a", 10)+strings.Repeat("b", 10), result.Content[0].Text)
+
+	result = page.result("tool", map[string]interface{}{"query": "q"}, 2, true)
+	args, offset, err := resolveToolCursor("tool", map[string]interface{}{"cursor": result.NextCursor})
+	require.NoError(t, err)
+	assert.Equal(t, 2, offset)
+	assert.Equal(t, map[string]interface{}{"query": "q"}, args)
+}
+
+func TestMCPServer_ProgressNotifications(t *testing.T) {
+	server, _, out := newResourceTestServer(t)
+	server.RegisterTool(&progressTool{steps: []float64{1, 1, 0.5, 2, 3}})
+
+	require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"progress","arguments":{},"_meta":{"progressToken":"tok-1"}}}`))
+	messages := decodeMessages(t, out)
+	require.Len(t, messages, 4, "progress that does not increase is dropped")
+
+	var progress []float64
+	for _, msg := range messages[:3] {
+		assert.Equal(t, "notifications/progress", msg["method"])
+		params := msg["params"].(map[string]interface{})
+		assert.Equal(t, "tok-1", params["progressToken"])
+		assert.Equal(t, float64(3), params["total"])
+		progress = append(progress, params["progress"].(float64))
+	}
+	assert.Equal(t, []float64{1, 2, 3}, progress)
+	assert.Equal(t, float64(1), messages[3]["id"])
+
+	t.Run("calls without a token get no notifications", func(t *testing.T) {
+		require.NoError(t, server.handleMessage(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"progress","arguments":{}}}`))
+		messages := decodeMessages(t, out)
+		require.Len(t, messages, 1)
+		assert.NotNil(t, messages[0]["result"])
+	})
+}
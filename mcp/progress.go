@@ -0,0 +1,72 @@
+package mcp
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// progressKey 工具執行 context 中進度回報函式的鍵
+type progressKey struct{}
+
+// progressReporter 回報工具執行進度；total 為 0 表示總量未知
+type progressReporter func(progress, total float64, message string)
+
+// progressToken 取得請求 _meta 中的 progressToken（字串或數字）
+func progressToken(params map[string]interface{}) (interface{}, bool) {
+	meta, ok := params["_meta"].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	switch token := meta["progressToken"].(type) {
+	case string:
+		return token, token != ""
+	case float64:
+		return token, true
+	default:
+		return nil, false
+	}
+}
+
+// withProgress 讓工具可透過 reportProgress 發送 notifications/progress。
+// 依 MCP 規範進度必須遞增，未增加的回報會被略過
+func (s *MCPServer) withProgress(ctx context.Context, token interface{}) context.Context {
+	var mu sync.Mutex
+	last := -1.0
+	reporter := func(progress, total float64, message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if progress <= last {
+			return
+		}
+		last = progress
+
+		params := map[string]interface{}{
+			"progressToken": token,
+			"progress":      progress,
+		}
+		if total > 0 {
+			params["total"] = total
+		}
+		if message != "" {
+			params["message"] = message
+		}
+		if err := s.sendNotification("notifications/progress", params); err != nil {
+			log.Printf("Error sending progress notification: %v", err)
+		}
+	}
+	return context.WithValue(ctx, progressKey{}, progressReporter(reporter))
+}
+
+// reportProgress 回報進度；請求未帶 progressToken 時不做任何事
+func reportProgress(ctx context.Context, progress, total float64, message string) {
+	if reporter, ok := ctx.Value(progressKey{}).(progressReporter); ok {
+		reporter(progress, total, message)
+	}
+}
+
+// progressRequested 回報請求是否帶有 progressToken
+func progressRequested(ctx context.Context) bool {
+	_, ok := ctx.Value(progressKey{}).(progressReporter)
+	return ok
+}
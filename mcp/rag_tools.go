@@ -0,0 +1,310 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"semantic-text-processor/models"
+)
+
+// InkAskTool 知識庫問答工具，回傳附引用來源的答案
+type InkAskTool struct {
+	server *MCPServer
+}
+
+// NewInkAskTool 建立知識庫問答工具
+func NewInkAskTool(server *MCPServer) *InkAskTool {
+	return &InkAskTool{server: server}
+}
+
+func (t *InkAskTool) GetName() string {
+	return "ink_ask"
+}
+
+func (t *InkAskTool) GetDescription() string {
+	return "Answer a question from the knowledge base. Retrieves relevant chunks and returns an answer with per-sentence chunk citations."
+}
+
+func (t *InkAskTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"question": map[string]interface{}{
+				"type":        "string",
+				"description": "The question to answer",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of chunks to retrieve (optional)",
+				"minimum":     1,
+				"maximum":     100,
+			},
+			"semantic_weight": map[string]interface{}{
+				"type":        "number",
+				"description": "Weight of semantic versus keyword retrieval between 0 and 1 (optional)",
+				"minimum":     0,
+				"maximum":     1,
+			},
+			"parent_context": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Answer from the sections holding the retrieved chunks rather than the chunks alone (optional)",
+			},
+		},
+		"required": []string{"question"},
+	}
+}
+
+func (t *InkAskTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	// 檢查服務是否可用
+	if t.server.services.RAGService == nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: Question answering is not configured"}},
+			IsError: true,
+		}, nil
+	}
+
+	question, ok := params["question"].(string)
+	if !ok || strings.TrimSpace(question) == "" {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: question parameter is required"}},
+			IsError: true,
+		}, nil
+	}
+
+	req := &models.AskRequest{Question: question}
+	if limit, ok := params["limit"].(float64); ok {
+		req.Limit = int(limit)
+	}
+	if weight, ok := params["semantic_weight"].(float64); ok {
+		req.SemanticWeight = &weight
+	}
+	if parentContext, ok := params["parent_context"].(bool); ok {
+		req.ParentContext = parentContext
+	}
+
+	answer, err := t.server.services.RAGService.Ask(ctx, req)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Question answering failed: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	// 格式化答案與引用
+	var resultText strings.Builder
+	resultText.WriteString(answer.Answer)
+	resultText.WriteString("\n")
+
+	if len(answer.Citations) > 0 {
+		resultText.WriteString("\n**Citations**\n")
+		for _, citation := range answer.Citations {
+			resultText.WriteString(fmt.Sprintf("[%d] %s: %s\n", citation.Index, citation.ChunkID, citation.Snippet))
+		}
+	}
+
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: resultText.String()}},
+		IsError: false,
+	}, nil
+}
+
+// InkGetContextTool 取得符合 token 預算的檢索上下文，供客戶端自行組成提示
+type InkGetContextTool struct {
+	server *MCPServer
+}
+
+// NewInkGetContextTool 建立上下文組裝工具
+func NewInkGetContextTool(server *MCPServer) *InkGetContextTool {
+	return &InkGetContextTool{server: server}
+}
+
+func (t *InkGetContextTool) GetName() string {
+	return "ink_get_context"
+}
+
+func (t *InkGetContextTool) GetDescription() string {
+	return "Retrieve knowledge base chunks for a query, deduplicated and packed into a token budget, as numbered blocks with their source chunk IDs."
+}
+
+func (t *InkGetContextTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "What the context should be about",
+			},
+			"max_tokens": map[string]interface{}{
+				"type":        "integer",
+				"description": "Token budget for the context (optional, defaults to the server's RAG context budget)",
+				"minimum":     1,
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of chunks to retrieve (optional)",
+				"minimum":     1,
+				"maximum":     100,
+			},
+			"order": map[string]interface{}{
+				"type":        "string",
+				"description": "relevance (default) or document to keep chunks of the same text in their original order",
+				"enum":        []string{"relevance", "document"},
+			},
+			"parent_context": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Return the sections holding the retrieved chunks, with the matched chunks listed per block (optional)",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *InkGetContextTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	if t.server.services.RAGService == nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: Context retrieval is not configured"}},
+			IsError: true,
+		}, nil
+	}
+
+	query, ok := params["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: query parameter is required"}},
+			IsError: true,
+		}, nil
+	}
+
+	req := &models.ContextRequest{Query: query}
+	if maxTokens, ok := params["max_tokens"].(float64); ok {
+		req.MaxTokens = int(maxTokens)
+	}
+	if limit, ok := params["limit"].(float64); ok {
+		req.Limit = int(limit)
+	}
+	if order, ok := params["order"].(string); ok {
+		req.Order = order
+	}
+	if parentContext, ok := params["parent_context"].(bool); ok {
+		req.ParentContext = parentContext
+	}
+
+	assembled, err := t.server.services.RAGService.RetrieveContext(ctx, req)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Context retrieval failed: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	// 每個區塊標示來源 chunk，方便客戶端引用
+	var resultText strings.Builder
+	if len(assembled.Blocks) == 0 {
+		resultText.WriteString("No matching content found.\n")
+	}
+	for _, block := range assembled.Blocks {
+		resultText.WriteString(fmt.Sprintf("[%d] (chunk %s, similarity %.2f)\n", block.Index, block.ChunkID, block.Similarity))
+		// 父區塊標示命中的子 chunk
+		if len(block.ChildHits) > 0 {
+			matched := make([]string, len(block.ChildHits))
+			for i, hit := range block.ChildHits {
+				matched[i] = fmt.Sprintf("%s (%.2f)", hit.ChunkID, hit.Similarity)
+			}
+			resultText.WriteString(fmt.Sprintf("Matched: %s\n", strings.Join(matched, ", ")))
+		}
+		resultText.WriteString(block.Content + "\n\n")
+	}
+	resultText.WriteString(fmt.Sprintf("%d blocks, %d of %d tokens", len(assembled.Blocks), assembled.TotalTokens, assembled.MaxTokens))
+	if len(assembled.Omitted) > 0 {
+		resultText.WriteString(fmt.Sprintf(", %d results omitted for budget", len(assembled.Omitted)))
+	}
+	resultText.WriteString("\n")
+
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: resultText.String()}},
+		IsError: false,
+	}, nil
+}
+
+// InkSummarizeTool 取得 chunk 摘要；頁面與有子區塊的 chunk 回傳彙整摘要
+type InkSummarizeTool struct {
+	server *MCPServer
+}
+
+// NewInkSummarizeTool 建立摘要工具
+func NewInkSummarizeTool(server *MCPServer) *InkSummarizeTool {
+	return &InkSummarizeTool{server: server}
+}
+
+func (t *InkSummarizeTool) GetName() string {
+	return "ink_summarize"
+}
+
+func (t *InkSummarizeTool) GetDescription() string {
+	return "Summarize a chunk. Pages and blocks with children are summarized from the summaries of everything below them; summaries are cached until the content changes."
+}
+
+func (t *InkSummarizeTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"chunk_id": map[string]interface{}{
+				"type":        "string",
+				"description": "ID of the chunk or page to summarize",
+			},
+			"refresh": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Regenerate the summary even if the cached one is current (optional)",
+			},
+		},
+		"required": []string{"chunk_id"},
+	}
+}
+
+func (t *InkSummarizeTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	if t.server.services.Summarization == nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: Summarization is not configured"}},
+			IsError: true,
+		}, nil
+	}
+
+	chunkID, ok := params["chunk_id"].(string)
+	if !ok || strings.TrimSpace(chunkID) == "" {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: chunk_id parameter is required"}},
+			IsError: true,
+		}, nil
+	}
+
+	var opts models.SummaryOptions
+	if refresh, ok := params["refresh"].(bool); ok {
+		opts.Refresh = refresh
+	}
+
+	summary, err := t.server.services.Summarization.Summarize(ctx, chunkID, opts)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Summarization failed: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	// 彙整摘要註明涵蓋的區塊數
+	var resultText strings.Builder
+	resultText.WriteString(summary.Summary)
+	resultText.WriteString("\n")
+	if summary.Kind == models.SummaryKindRollup {
+		resultText.WriteString(fmt.Sprintf("\nSummarizes %d blocks below chunk %s", summary.Descendants, summary.ChunkID))
+		if summary.Cached {
+			resultText.WriteString(" (cached)")
+		}
+		resultText.WriteString("\n")
+	}
+
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: resultText.String()}},
+		IsError: false,
+	}, nil
+}
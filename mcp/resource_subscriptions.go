@@ -0,0 +1,127 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"time"
+)
+
+// defaultResourcePollInterval 訂閱資源的變更檢查間隔
+const defaultResourcePollInterval = 5 * time.Second
+
+// handleResourcesSubscribe 處理資源訂閱請求
+func (s *MCPServer) handleResourcesSubscribe(msg *MCPMessage) error {
+	uri, ok := resourceURIParam(msg)
+	if !ok {
+		return s.sendError(msg.ID, -32602, "Missing resource URI", nil)
+	}
+
+	resource, exists := s.resolveResource(uri)
+	if !exists {
+		return s.sendError(msg.ID, -32601, "Resource not found", nil)
+	}
+
+	// 記錄目前內容指紋，之後內容變更時才通知
+	data, err := resource.Read(s.ctx)
+	if err != nil {
+		return s.sendError(msg.ID, -32603, "Resource read failed", err.Error())
+	}
+
+	s.subMu.Lock()
+	s.subscriptions[uri] = resourceFingerprint(data)
+	s.subMu.Unlock()
+
+	return s.sendResult(msg.ID, map[string]interface{}{})
+}
+
+// handleResourcesUnsubscribe 處理取消資源訂閱請求
+func (s *MCPServer) handleResourcesUnsubscribe(msg *MCPMessage) error {
+	uri, ok := resourceURIParam(msg)
+	if !ok {
+		return s.sendError(msg.ID, -32602, "Missing resource URI", nil)
+	}
+
+	s.subMu.Lock()
+	delete(s.subscriptions, uri)
+	s.subMu.Unlock()
+
+	return s.sendResult(msg.ID, map[string]interface{}{})
+}
+
+// watchSubscriptions 定期檢查已訂閱資源並發送更新通知
+func (s *MCPServer) watchSubscriptions() {
+	ticker := time.NewTicker(s.resourcePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.checkSubscriptions(s.ctx)
+		}
+	}
+}
+
+// checkSubscriptions 比對訂閱資源的內容指紋，變更時發送 notifications/resources/updated
+func (s *MCPServer) checkSubscriptions(ctx context.Context) {
+	s.subMu.Lock()
+	snapshot := make(map[string]string, len(s.subscriptions))
+	for uri, fingerprint := range s.subscriptions {
+		snapshot[uri] = fingerprint
+	}
+	s.subMu.Unlock()
+
+	for uri, previous := range snapshot {
+		// 資源被刪除或無法讀取時，以空指紋表示並通知一次
+		current := ""
+		if resource, exists := s.resolveResource(uri); exists {
+			if data, err := resource.Read(ctx); err == nil {
+				current = resourceFingerprint(data)
+			}
+		}
+		if current == previous {
+			continue
+		}
+
+		s.subMu.Lock()
+		_, stillSubscribed := s.subscriptions[uri]
+		if stillSubscribed {
+			s.subscriptions[uri] = current
+		}
+		s.subMu.Unlock()
+
+		if stillSubscribed {
+			if err := s.sendNotification("notifications/resources/updated", map[string]interface{}{"uri": uri}); err != nil {
+				log.Printf("Failed to send resource update notification for %s: %v", uri, err)
+			}
+		}
+	}
+}
+
+// sendNotification 發送 JSON-RPC 通知（無 ID）
+func (s *MCPServer) sendNotification(method string, params interface{}) error {
+	return s.sendMessage(MCPMessage{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// resourceURIParam 取得請求中的資源 URI
+func resourceURIParam(msg *MCPMessage) (string, bool) {
+	params, ok := msg.Params.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	uri, ok := params["uri"].(string)
+	return uri, ok && uri != ""
+}
+
+// resourceFingerprint 計算資源內容指紋
+func resourceFingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
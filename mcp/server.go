@@ -9,6 +9,7 @@ import (
 	"log"
 	"os"
 	"sync"
+	"time"
 
 	"semantic-text-processor/services"
 )
@@ -28,6 +29,17 @@ type MCPServer struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	mu          sync.RWMutex
+
+	// 資源訂閱：URI -> 最後一次通知時的內容指紋
+	subscriptions        map[string]string
+	subMu                sync.Mutex
+	resourcePollInterval time.Duration
+	writeMu              sync.Mutex
+
+	// 關機時停止接受新請求並等待處理中的請求
+	inflight   sync.WaitGroup
+	inflightMu sync.Mutex
+	draining   bool
 }
 
 // MCPServices MCP 服務依賴
@@ -37,8 +49,12 @@ type MCPServices struct {
 	BatchProcessor      *services.BatchProcessor
 	ImageSimilarity     *services.ImageSimilaritySearch
 	SlideRecommendation *services.SlideImageRecommendationService
-	StorageService      *services.StorageService
+	StorageService      services.StorageService
 	ChunkService        services.UnifiedChunkService
+	RAGService          *services.RAGService
+	Summarization       services.SummarizationService
+	TemplateService     services.TemplateService
+	Tasks               services.TaskService
 }
 
 // NewMCPServer 建立新的 MCP 伺服器
@@ -58,6 +74,9 @@ func NewMCPServer(name, version, description string, services *MCPServices) *MCP
 		stderr:      os.Stderr,
 		ctx:         ctx,
 		cancel:      cancel,
+
+		subscriptions:        make(map[string]string),
+		resourcePollInterval: defaultResourcePollInterval,
 	}
 	
 	// 註冊預設工具
@@ -91,10 +110,11 @@ type MCPTool interface {
 	Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error)
 }
 
-// MCPToolResult MCP 工具執行結果
+// MCPToolResult MCP 工具執行結果；NextCursor 非空時可用 cursor 參數續取其餘結果
 type MCPToolResult struct {
-	Content []MCPContent `json:"content"`
-	IsError bool         `json:"isError"`
+	Content    []MCPContent `json:"content"`
+	IsError    bool         `json:"isError"`
+	NextCursor string       `json:"nextCursor,omitempty"`
 }
 
 // MCPContent MCP 內容
@@ -133,6 +153,9 @@ func (s *MCPServer) Start() error {
 	log.Printf("Starting MCP Server: %s v%s", s.name, s.version)
 	
 	scanner := bufio.NewScanner(s.stdin)
+
+	// 監看已訂閱資源的變更
+	go s.watchSubscriptions()
 	
 	for scanner.Scan() {
 		select {
@@ -146,7 +169,13 @@ func (s *MCPServer) Start() error {
 			continue
 		}
 		
-		if err := s.handleMessage(line); err != nil {
+		if !s.beginRequest() {
+			s.rejectMessage(line)
+			continue
+		}
+		err := s.handleMessage(line)
+		s.inflight.Done()
+		if err != nil {
 			log.Printf("Error handling message: %v", err)
 		}
 	}
@@ -164,6 +193,51 @@ func (s *MCPServer) Stop() {
 	s.cancel()
 }
 
+// Shutdown 停止接受新請求，等待處理中的請求完成後停止伺服器。
+// ctx 到期時會取消仍在處理的請求並回傳錯誤。
+func (s *MCPServer) Shutdown(ctx context.Context) error {
+	log.Printf("Shutting down MCP Server: %s", s.name)
+	s.inflightMu.Lock()
+	s.draining = true
+	s.inflightMu.Unlock()
+	defer s.cancel()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to drain in-flight requests: %w", ctx.Err())
+	}
+}
+
+// beginRequest 登記一個處理中的請求，關機中時回傳 false
+func (s *MCPServer) beginRequest() bool {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	if s.draining {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// rejectMessage 關機中回覆請求錯誤，通知則直接忽略
+func (s *MCPServer) rejectMessage(line string) {
+	var msg MCPMessage
+	if err := json.Unmarshal([]byte(line), &msg); err != nil || msg.ID == nil {
+		return
+	}
+	if err := s.sendError(msg.ID, -32000, "Server is shutting down", nil); err != nil {
+		log.Printf("Error rejecting message: %v", err)
+	}
+}
+
 // handleMessage 處理 MCP 訊息
 func (s *MCPServer) handleMessage(line string) error {
 	var msg MCPMessage
@@ -182,6 +256,12 @@ func (s *MCPServer) handleMessage(line string) error {
 		return s.handleResourcesList(&msg)
 	case "resources/read":
 		return s.handleResourcesRead(&msg)
+	case "resources/templates/list":
+		return s.handleResourceTemplatesList(&msg)
+	case "resources/subscribe":
+		return s.handleResourcesSubscribe(&msg)
+	case "resources/unsubscribe":
+		return s.handleResourcesUnsubscribe(&msg)
 	case "prompts/list":
 		return s.handlePromptsList(&msg)
 	case "prompts/get":
@@ -200,7 +280,7 @@ func (s *MCPServer) handleInitialize(msg *MCPMessage) error {
 				"listChanged": false,
 			},
 			"resources": map[string]interface{}{
-				"subscribe":   false,
+				"subscribe":   true,
 				"listChanged": false,
 			},
 			"prompts": map[string]interface{}{
@@ -262,8 +342,14 @@ func (s *MCPServer) handleToolsCall(msg *MCPMessage) error {
 		return s.sendError(msg.ID, -32601, "Tool not found", nil)
 	}
 	
+	// 請求帶有 progressToken 時，長時間執行的工具會發送進度通知
+	ctx := s.ctx
+	if token, ok := progressToken(params); ok {
+		ctx = s.withProgress(ctx, token)
+	}
+
 	// 執行工具
-	result, err := tool.Execute(s.ctx, arguments)
+	result, err := tool.Execute(ctx, arguments)
 	if err != nil {
 		return s.sendError(msg.ID, -32603, "Tool execution failed", err)
 	}
@@ -273,11 +359,21 @@ func (s *MCPServer) handleToolsCall(msg *MCPMessage) error {
 
 // handleResourcesList 處理資源列表請求
 func (s *MCPServer) handleResourcesList(msg *MCPMessage) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	// 頁面與模板資源依資料庫內容動態產生
+	browsable, err := s.listBrowsableResources(s.ctx)
+	if err != nil {
+		return s.sendError(msg.ID, -32603, "Resource listing failed", err.Error())
+	}
 	
-	var resources []map[string]interface{}
+	s.mu.RLock()
+	registered := make([]MCPResource, 0, len(s.resources))
 	for _, resource := range s.resources {
+		registered = append(registered, resource)
+	}
+	s.mu.RUnlock()
+	
+	resources := []map[string]interface{}{}
+	for _, resource := range append(registered, browsable...) {
 		resources = append(resources, map[string]interface{}{
 			"uri":         resource.GetURI(),
 			"name":        resource.GetName(),
@@ -293,6 +389,31 @@ func (s *MCPServer) handleResourcesList(msg *MCPMessage) error {
 	return s.sendResult(msg.ID, result)
 }
 
+// handleResourceTemplatesList 處理資源 URI 模板列表請求
+func (s *MCPServer) handleResourceTemplatesList(msg *MCPMessage) error {
+	templates := []map[string]interface{}{}
+	if s.services.ChunkService != nil {
+		templates = append(templates,
+			map[string]interface{}{
+				"uriTemplate": pageResourcePrefix + "{chunk_id}",
+				"name":        "Page",
+				"description": "Rendered Markdown outline of a page and its blocks",
+				"mimeType":    "text/markdown",
+			},
+			map[string]interface{}{
+				"uriTemplate": templateResourcePrefix + "{chunk_id}",
+				"name":        "Template",
+				"description": "Structure and slots of a template",
+				"mimeType":    "text/markdown",
+			},
+		)
+	}
+	
+	return s.sendResult(msg.ID, map[string]interface{}{
+		"resourceTemplates": templates,
+	})
+}
+
 // handleResourcesRead 處理資源讀取請求
 func (s *MCPServer) handleResourcesRead(msg *MCPMessage) error {
 	params, ok := msg.Params.(map[string]interface{})
@@ -305,10 +426,7 @@ func (s *MCPServer) handleResourcesRead(msg *MCPMessage) error {
 		return s.sendError(msg.ID, -32602, "Missing resource URI", nil)
 	}
 	
-	s.mu.RLock()
-	resource, exists := s.resources[uri]
-	s.mu.RUnlock()
-	
+	resource, exists := s.resolveResource(uri)
 	if !exists {
 		return s.sendError(msg.ID, -32601, "Resource not found", nil)
 	}
@@ -316,7 +434,7 @@ func (s *MCPServer) handleResourcesRead(msg *MCPMessage) error {
 	// 讀取資源
 	data, err := resource.Read(s.ctx)
 	if err != nil {
-		return s.sendError(msg.ID, -32603, "Resource read failed", err)
+		return s.sendError(msg.ID, -32603, "Resource read failed", err.Error())
 	}
 	
 	result := map[string]interface{}{
@@ -334,11 +452,27 @@ func (s *MCPServer) handleResourcesRead(msg *MCPMessage) error {
 
 // handlePromptsList 處理提示列表請求
 func (s *MCPServer) handlePromptsList(msg *MCPMessage) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	// 模板提示依資料庫內容動態產生
+	templatePrompts, err := s.listTemplatePrompts(s.ctx)
+	if err != nil {
+		return s.sendError(msg.ID, -32603, "Prompt listing failed", err.Error())
+	}
 	
-	var prompts []map[string]interface{}
+	s.mu.RLock()
+	registered := make([]MCPPrompt, 0, len(s.prompts)+len(templatePrompts))
 	for _, prompt := range s.prompts {
+		registered = append(registered, prompt)
+	}
+	for _, prompt := range templatePrompts {
+		// 與已註冊提示同名的模板會被遮蔽
+		if _, exists := s.prompts[prompt.GetName()]; !exists {
+			registered = append(registered, prompt)
+		}
+	}
+	s.mu.RUnlock()
+	
+	prompts := []map[string]interface{}{}
+	for _, prompt := range registered {
 		prompts = append(prompts, map[string]interface{}{
 			"name":        prompt.GetName(),
 			"description": prompt.GetDescription(),
@@ -370,18 +504,18 @@ func (s *MCPServer) handlePromptsGet(msg *MCPMessage) error {
 		arguments = make(map[string]interface{})
 	}
 	
-	s.mu.RLock()
-	prompt, exists := s.prompts[promptName]
-	s.mu.RUnlock()
-	
-	if !exists {
+	prompt, err := s.resolvePrompt(s.ctx, promptName)
+	if err != nil {
+		return s.sendError(msg.ID, -32603, "Prompt lookup failed", err.Error())
+	}
+	if prompt == nil {
 		return s.sendError(msg.ID, -32601, "Prompt not found", nil)
 	}
 	
 	// 生成提示
 	content, err := prompt.Generate(s.ctx, arguments)
 	if err != nil {
-		return s.sendError(msg.ID, -32603, "Prompt generation failed", err)
+		return s.sendError(msg.ID, -32603, "Prompt generation failed", err.Error())
 	}
 	
 	result := map[string]interface{}{
@@ -433,6 +567,9 @@ func (s *MCPServer) sendMessage(msg MCPMessage) error {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 	
+	// 訂閱通知可能與請求回應同時寫出
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	_, err = fmt.Fprintf(s.stdout, "%s\n", data)
 	return err
 }
@@ -472,6 +609,33 @@ func (s *MCPServer) registerDefaultTools() {
 		log.Printf("Warning: ChunkService not available, skipping text tools")
 	}
 
+	if s.services.RAGService != nil {
+		s.RegisterTool(NewInkAskTool(s))
+		s.RegisterTool(NewInkGetContextTool(s))
+		log.Printf("Registered question answering tools: ink_ask, ink_get_context")
+	}
+
+	if s.services.Summarization != nil {
+		s.RegisterTool(NewInkSummarizeTool(s))
+		log.Printf("Registered summarization tool: ink_summarize")
+	}
+
+	if s.services.TemplateService != nil {
+		s.RegisterTool(NewInkListTemplatesTool(s))
+		s.RegisterTool(NewInkCreateTemplateTool(s))
+		s.RegisterTool(NewInkInstantiateTemplateTool(s))
+		s.RegisterTool(NewInkFillSlotTool(s))
+		s.RegisterTool(NewInkRenderInstanceTool(s))
+		s.RegisterTool(NewInkQueryInstancesTool(s))
+		log.Printf("Registered template tools: ink_list_templates, ink_create_template, ink_instantiate_template, ink_fill_slot, ink_render_instance, ink_query_instances")
+	}
+
+	if s.services.Tasks != nil {
+		s.RegisterTool(NewInkListTasksTool(s))
+		s.RegisterResource(NewDueTasksResource(s))
+		log.Printf("Registered task tool and resource: ink_list_tasks, %s", dueTasksResourceURI)
+	}
+
 	// 多模態工具需要額外的服務（目前尚未整合）
 	if s.services.MultimodalSearch != nil {
 		s.RegisterTool(NewInkSearchChunksTool(s))
@@ -496,7 +660,8 @@ func (s *MCPServer) registerDefaultTools() {
 
 	if s.services.SlideRecommendation != nil {
 		s.RegisterTool(NewInkGetImagesForSlidesTool(s))
-		log.Printf("Registered slide recommendation tool: ink_get_images_for_slides")
+		s.RegisterTool(NewInkGenerateSlideOutlineTool(s))
+		log.Printf("Registered slide tools: ink_get_images_for_slides, ink_generate_slide_outline")
 	}
 
 	log.Printf("MCP tool registration complete")
@@ -0,0 +1,117 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingTool runs until released or cancelled
+type blockingTool struct {
+	started   chan struct{}
+	release   chan struct{}
+	cancelled chan struct{}
+}
+
+func newBlockingTool() *blockingTool {
+	return &blockingTool{
+		started:   make(chan struct{}),
+		release:   make(chan struct{}),
+		cancelled: make(chan struct{}),
+	}
+}
+
+func (t *blockingTool) GetName() string                        { return "block" }
+func (t *blockingTool) GetDescription() string                 { return "blocks until released" }
+func (t *blockingTool) GetInputSchema() map[string]interface{} { return map[string]interface{}{} }
+
+func (t *blockingTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	close(t.started)
+	select {
+	case <-t.release:
+		return &MCPToolResult{Content: []MCPContent{{Type: "text", Text: "done"}}}, nil
+	case <-ctx.Done():
+		close(t.cancelled)
+		return nil, ctx.Err()
+	}
+}
+
+// startBlockingCall starts the server and calls the blocking tool
+func startBlockingCall(t *testing.T) (*MCPServer, *blockingTool, *io.PipeWriter) {
+	tool := newBlockingTool()
+	server := NewMCPServer("test", "1.0.0", "test server", &MCPServices{})
+	server.RegisterTool(tool)
+	stdin, input := io.Pipe()
+	server.SetIO(stdin, &bytes.Buffer{}, &bytes.Buffer{})
+	go server.Start()
+
+	_, err := io.WriteString(input, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block","arguments":{}}}`+"\n")
+	require.NoError(t, err)
+	<-tool.started
+	return server, tool, input
+}
+
+func TestMCPServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	server, tool, input := startBlockingCall(t)
+	defer input.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Shutdown(context.Background())
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("shutdown returned before the in-flight request finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(tool.release)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown did not return after the request finished")
+	}
+}
+
+func TestMCPServer_ShutdownDeadlineCancelsRequests(t *testing.T) {
+	server, tool, input := startBlockingCall(t)
+	defer input.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := server.Shutdown(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	select {
+	case <-tool.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("in-flight request was not cancelled")
+	}
+}
+
+func TestMCPServer_RejectsRequestsWhileDraining(t *testing.T) {
+	server := NewMCPServer("test", "1.0.0", "test server", &MCPServices{})
+	out := &bytes.Buffer{}
+	server.SetIO(strings.NewReader(
+		`{"jsonrpc":"2.0","id":7,"method":"tools/list"}`+"\n"+
+			`{"jsonrpc":"2.0","method":"notifications/initialized"}`+"\n",
+	), out, &bytes.Buffer{})
+	server.draining = true
+
+	require.NoError(t, server.Start())
+
+	messages := decodeMessages(t, out)
+	require.Len(t, messages, 1, "notifications get no reply")
+	assert.Equal(t, float64(7), messages[0]["id"])
+	errObj := messages[0]["error"].(map[string]interface{})
+	assert.Equal(t, float64(-32000), errObj["code"])
+}
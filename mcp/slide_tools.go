@@ -0,0 +1,154 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"semantic-text-processor/services"
+)
+
+// InkGenerateSlideOutlineTool 投影片大綱工具，依主題或頁面產生有序的投影片大綱
+type InkGenerateSlideOutlineTool struct {
+	server *MCPServer
+}
+
+// NewInkGenerateSlideOutlineTool 建立投影片大綱工具
+func NewInkGenerateSlideOutlineTool(server *MCPServer) *InkGenerateSlideOutlineTool {
+	return &InkGenerateSlideOutlineTool{server: server}
+}
+
+func (t *InkGenerateSlideOutlineTool) GetName() string {
+	return "ink_generate_slide_outline"
+}
+
+func (t *InkGenerateSlideOutlineTool) GetDescription() string {
+	return "Generate an ordered slide outline from a topic or a page. Ranks relevant chunks, groups them into slide-sized sections and fills the slots of an optional slide template."
+}
+
+func (t *InkGenerateSlideOutlineTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"topic": map[string]interface{}{
+				"type":        "string",
+				"description": "Presentation topic; searches the knowledge base, or filters the page content when page_chunk_id is given",
+			},
+			"page_chunk_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Page to turn into slides; its top-level blocks become sections",
+			},
+			"template_chunk_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Slide template whose slots (title, points, notes, image) are filled per slide (optional)",
+			},
+			"max_slides": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of slides",
+				"default":     10,
+				"minimum":     1,
+			},
+			"points_per_slide": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum bullet points per slide; a template's max_points metadata takes precedence",
+				"default":     5,
+				"minimum":     1,
+			},
+			"include_images": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Suggest one image per slide",
+				"default":     false,
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"description": "Output format",
+				"enum":        []string{"markdown", "json"},
+				"default":     "markdown",
+			},
+		},
+	}
+}
+
+func (t *InkGenerateSlideOutlineTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	req := &services.SlideOutlineRequest{}
+	req.Topic, _ = params["topic"].(string)
+	req.PageChunkID, _ = params["page_chunk_id"].(string)
+	req.TemplateChunkID, _ = params["template_chunk_id"].(string)
+	req.IncludeImages, _ = params["include_images"].(bool)
+	if maxSlides, ok := params["max_slides"].(float64); ok {
+		req.MaxSlides = int(maxSlides)
+	}
+	if points, ok := params["points_per_slide"].(float64); ok {
+		req.PointsPerSlide = int(points)
+	}
+
+	if req.Topic == "" && req.PageChunkID == "" {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: "Error: topic or page_chunk_id parameter is required"}},
+			IsError: true,
+		}, nil
+	}
+
+	outline, err := t.server.services.SlideRecommendation.GenerateSlideOutline(ctx, req)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Error: Failed to generate slide outline: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
+	if format, _ := params["format"].(string); format == "json" {
+		data, err := json.MarshalIndent(outline, "", "  ")
+		if err != nil {
+			return &MCPToolResult{
+				Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Error: Failed to encode slide outline: %v", err)}},
+				IsError: true,
+			}, nil
+		}
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: string(data)}},
+			IsError: false,
+		}, nil
+	}
+
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: formatSlideOutline(outline)}},
+		IsError: false,
+	}, nil
+}
+
+// formatSlideOutline 將大綱格式化為 Markdown，每張投影片一個章節
+func formatSlideOutline(outline *services.SlideOutline) string {
+	var text strings.Builder
+	text.WriteString(fmt.Sprintf("# %s\n\n", outline.Title))
+	text.WriteString(fmt.Sprintf("**Slides:** %d  **Template:** %s  **Chunks considered:** %d\n", len(outline.Slides), outline.Template, outline.TotalChunks))
+	if outline.Truncated {
+		text.WriteString("_Lower-ranked slides were dropped to fit max_slides._\n")
+	}
+
+	if len(outline.Slides) == 0 {
+		text.WriteString("\nNo relevant content found.\n")
+		return text.String()
+	}
+
+	for _, slide := range outline.Slides {
+		text.WriteString(fmt.Sprintf("\n## %d. %s\n\n", slide.Index, slide.Title))
+		for _, point := range slide.Points {
+			text.WriteString(fmt.Sprintf("- %s `%s`\n", point.Text, point.ChunkID))
+		}
+		if slide.Image != nil {
+			text.WriteString(fmt.Sprintf("\nImage: %s (%s)\n", slide.Image.ImageURL, slide.Image.Title))
+		}
+
+		// 模板插槽中除標題與要點外的內容另外列出
+		for _, slot := range outline.Slots {
+			value := slide.Fields[slot]
+			if value == "" || value == slide.Title || strings.HasPrefix(value, "- ") {
+				continue
+			}
+			text.WriteString(fmt.Sprintf("\n**%s:** %s\n", slot, value))
+		}
+	}
+	return text.String()
+}
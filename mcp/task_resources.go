@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// dueTasksResourceURI 到期任務資源 URI
+const dueTasksResourceURI = "ink://tasks/due"
+
+// DueTasksResource 到期任務資源，列出逾期與今天到期的任務。
+// 任務到期時內容隨之改變，訂閱此資源的客戶端會收到 notifications/resources/updated。
+type DueTasksResource struct {
+	server *MCPServer
+	now    func() time.Time
+}
+
+// NewDueTasksResource 建立到期任務資源
+func NewDueTasksResource(server *MCPServer) *DueTasksResource {
+	return &DueTasksResource{server: server, now: time.Now}
+}
+
+func (r *DueTasksResource) GetURI() string {
+	return dueTasksResourceURI
+}
+
+func (r *DueTasksResource) GetName() string {
+	return "Due tasks"
+}
+
+func (r *DueTasksResource) GetDescription() string {
+	return "Open tasks that are overdue or due today; subscribe to be notified when a task falls due"
+}
+
+func (r *DueTasksResource) GetMimeType() string {
+	return "text/markdown"
+}
+
+func (r *DueTasksResource) Read(ctx context.Context) ([]byte, error) {
+	tasks, err := r.server.services.Tasks.List(ctx, &models.TaskQuery{View: models.TaskViewDue})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due tasks: %w", err)
+	}
+	return []byte(renderTaskList("# Due tasks", tasks.Tasks, r.now())), nil
+}
+
+// renderTaskList 將任務渲染為 Markdown 清單，並標示已到期、逾期或今天稍後到期
+func renderTaskList(heading string, tasks []models.Task, now time.Time) string {
+	var out strings.Builder
+	out.WriteString(heading)
+	out.WriteString("\n\n")
+	if len(tasks) == 0 {
+		out.WriteString("No tasks.\n")
+		return out.String()
+	}
+
+	for _, task := range tasks {
+		out.WriteString(fmt.Sprintf("- %s %s", task.Status, task.Title))
+		if task.DueAt != nil {
+			due := task.DueAt
+			layout := "2006-01-02 15:04"
+			if task.AllDay {
+				layout = "2006-01-02"
+			}
+			// 以任務時區判斷今天，全天任務的日期才不會偏移
+			local := now.In(due.Location())
+			today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, due.Location())
+			state := "due"
+			switch {
+			case due.Before(today):
+				state = "overdue"
+			case due.After(now):
+				state = "later today"
+			}
+			out.WriteString(fmt.Sprintf(" (%s %s", state, due.Format(layout)))
+			if task.Repeat != "" {
+				out.WriteString(", repeats " + task.Repeat)
+			}
+			out.WriteString(")")
+		}
+		if len(task.Tags) > 0 {
+			out.WriteString(" #" + strings.Join(task.Tags, " #"))
+		}
+		out.WriteString(fmt.Sprintf(" `%s`\n", task.ChunkID))
+	}
+	return out.String()
+}
+
+// InkListTasksTool 列出任務工具
+type InkListTasksTool struct {
+	server *MCPServer
+}
+
+// NewInkListTasksTool 建立列出任務工具
+func NewInkListTasksTool(server *MCPServer) *InkListTasksTool {
+	return &InkListTasksTool{server: server}
+}
+
+func (t *InkListTasksTool) GetName() string {
+	return "ink_list_tasks"
+}
+
+func (t *InkListTasksTool) GetDescription() string {
+	return "List open TODO tasks written in chunks: overdue ones, those due today, or all, optionally with a tag"
+}
+
+func (t *InkListTasksTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"view": map[string]interface{}{
+				"type":        "string",
+				"description": "Which tasks to list",
+				"enum":        []string{"due", "overdue", "today", "open"},
+				"default":     "due",
+			},
+			"tag": map[string]interface{}{
+				"type":        "string",
+				"description": "Only tasks with this tag, by name or tag chunk ID (optional)",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of tasks (optional)",
+				"default":     100,
+			},
+		},
+	}
+}
+
+func (t *InkListTasksTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	query := &models.TaskQuery{View: models.TaskViewDue}
+	if view, ok := params["view"].(string); ok && view != "" {
+		query.View = models.TaskView(view)
+	}
+	query.Tag, _ = params["tag"].(string)
+	if limit, ok := params["limit"].(float64); ok {
+		query.Limit = int(limit)
+	}
+
+	tasks, err := t.server.services.Tasks.List(ctx, query)
+	if err != nil {
+		return errorResult("Failed to list tasks: %v", err), nil
+	}
+	heading := fmt.Sprintf("# Tasks: %s", tasks.View)
+	if tasks.Tag != "" {
+		heading += " #" + tasks.Tag
+	}
+	return textResult(renderTaskList(heading, tasks.Tasks, time.Now())), nil
+}
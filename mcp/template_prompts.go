@@ -0,0 +1,202 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// TemplatePrompt 以模板區塊作為提示，模板的插槽即為提示參數。
+// 模板的 metadata 可用 prompt_name 與 prompt_description 覆寫名稱與說明，
+// 插槽的 metadata 設定 required 為 true 時該參數為必填。
+type TemplatePrompt struct {
+	template models.UnifiedChunkRecord
+	name     string
+	server   *MCPServer
+	slots    []*models.UnifiedChunkRecord
+	outline  []models.UnifiedChunkRecord
+	loaded   bool
+}
+
+// NewTemplatePrompt 建立模板提示
+func NewTemplatePrompt(template models.UnifiedChunkRecord, server *MCPServer) *TemplatePrompt {
+	return &TemplatePrompt{
+		template: template,
+		name:     templatePromptName(&template),
+		server:   server,
+	}
+}
+
+func (p *TemplatePrompt) GetName() string {
+	return p.name
+}
+
+func (p *TemplatePrompt) GetDescription() string {
+	if description, ok := p.template.Metadata["prompt_description"].(string); ok && description != "" {
+		return description
+	}
+	return fmt.Sprintf("Render template %s with slot values", chunkTitle(&p.template))
+}
+
+// GetArguments 回傳模板插槽；讀取失敗時回傳空清單，錯誤留待 Generate 回報
+func (p *TemplatePrompt) GetArguments() []MCPPromptArgument {
+	if err := p.load(p.server.ctx); err != nil {
+		return []MCPPromptArgument{}
+	}
+
+	arguments := make([]MCPPromptArgument, 0, len(p.slots))
+	for _, slot := range p.slots {
+		description := chunkTitle(slot)
+		if description == slotName(slot) {
+			description = fmt.Sprintf("Value for slot %s", description)
+		}
+		required, _ := slot.Metadata["required"].(bool)
+		arguments = append(arguments, MCPPromptArgument{
+			Name:        slotName(slot),
+			Description: description,
+			Required:    required,
+		})
+	}
+	return arguments
+}
+
+// Generate 以參數填入插槽後渲染模板大綱，未提供的選填插槽留空
+func (p *TemplatePrompt) Generate(ctx context.Context, args map[string]interface{}) (string, error) {
+	if err := p.load(ctx); err != nil {
+		return "", err
+	}
+
+	values, err := slotValuesParam(args)
+	if err != nil {
+		return "", err
+	}
+
+	slotNames := make([]string, 0, len(p.slots))
+	var missing []string
+	for _, slot := range p.slots {
+		name := slotName(slot)
+		slotNames = append(slotNames, name)
+		if required, _ := slot.Metadata["required"].(bool); required && strings.TrimSpace(values[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if err := services.ValidateSlotValues(slotNames, values); err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing required arguments: %s", strings.Join(missing, ", "))
+	}
+
+	rendered := renderChunkOutline("# "+chunkTitle(&p.template), p.template.ChunkID, p.outline)
+
+	// 插槽行與內文中的 {{slot}} 佔位符一併替換
+	replacements := make([]string, 0, len(slotNames)*2)
+	for _, name := range slotNames {
+		replacements = append(replacements, "{{"+name+"}}", values[name])
+	}
+	return strings.TrimRight(strings.NewReplacer(replacements...).Replace(rendered), "\n") + "\n", nil
+}
+
+// load 讀取模板內容與插槽，只讀取一次
+func (p *TemplatePrompt) load(ctx context.Context) error {
+	if p.loaded {
+		return nil
+	}
+
+	descendants, err := p.server.services.ChunkService.GetDescendants(ctx, p.template.ChunkID, 0)
+	if err != nil {
+		return fmt.Errorf("failed to get template content: %w", err)
+	}
+
+	p.outline = descendants
+	p.slots = nil
+	seen := make(map[string]bool)
+	for i := range p.outline {
+		if chunk := &p.outline[i]; chunk.IsSlot && !seen[slotName(chunk)] {
+			seen[slotName(chunk)] = true
+			p.slots = append(p.slots, chunk)
+		}
+	}
+	p.loaded = true
+	return nil
+}
+
+// templatePromptName 取得模板的提示名稱，預設為標題轉成的小寫連字號格式
+func templatePromptName(template *models.UnifiedChunkRecord) string {
+	if name, ok := template.Metadata["prompt_name"].(string); ok && name != "" {
+		return name
+	}
+
+	title := strings.TrimSuffix(chunkTitle(template), "#template")
+	var name strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && name.Len() > 0 {
+				name.WriteByte('-')
+			}
+			name.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if name.Len() == 0 {
+		return template.ChunkID
+	}
+	return name.String()
+}
+
+// listTemplatePrompts 列出所有模板提示，名稱重複時保留先列出的模板
+func (s *MCPServer) listTemplatePrompts(ctx context.Context) ([]*TemplatePrompt, error) {
+	if s.services.ChunkService == nil {
+		return nil, nil
+	}
+
+	isTrue := true
+	templates, err := s.services.ChunkService.SearchChunks(ctx, &models.SearchQuery{IsTemplate: &isTrue, Limit: browsableResourceLimit})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list templates: %w", err)
+	}
+
+	prompts := make([]*TemplatePrompt, 0, len(templates.Chunks))
+	seen := make(map[string]bool)
+	for _, template := range templates.Chunks {
+		prompt := NewTemplatePrompt(template, s)
+		if seen[prompt.GetName()] {
+			continue
+		}
+		seen[prompt.GetName()] = true
+		prompts = append(prompts, prompt)
+	}
+	sort.Slice(prompts, func(i, j int) bool {
+		return prompts[i].GetName() < prompts[j].GetName()
+	})
+	return prompts, nil
+}
+
+// resolvePrompt 依名稱取得已註冊提示或模板提示，已註冊的提示優先
+func (s *MCPServer) resolvePrompt(ctx context.Context, name string) (MCPPrompt, error) {
+	s.mu.RLock()
+	prompt, exists := s.prompts[name]
+	s.mu.RUnlock()
+	if exists {
+		return prompt, nil
+	}
+
+	templatePrompts, err := s.listTemplatePrompts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, prompt := range templatePrompts {
+		if prompt.GetName() == name {
+			return prompt, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,533 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// errorResult 建立工具錯誤結果
+func errorResult(format string, args ...interface{}) *MCPToolResult {
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: fmt.Sprintf(format, args...)}},
+		IsError: true,
+	}
+}
+
+// textResult 建立工具文字結果
+func textResult(text string) *MCPToolResult {
+	return &MCPToolResult{
+		Content: []MCPContent{{Type: "text", Text: text}},
+		IsError: false,
+	}
+}
+
+// slotValuesParam 將 slot_values 參數轉為字串對照表，數字與布林值轉為文字
+func slotValuesParam(raw interface{}) (map[string]string, error) {
+	if raw == nil {
+		return map[string]string{}, nil
+	}
+	object, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("slot_values must be an object mapping slot names to values")
+	}
+
+	values := make(map[string]string, len(object))
+	for name, value := range object {
+		switch v := value.(type) {
+		case string:
+			values[name] = v
+		case float64:
+			values[name] = strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			values[name] = strconv.FormatBool(v)
+		case nil:
+			values[name] = ""
+		default:
+			return nil, fmt.Errorf("value of slot %q must be a string, number or boolean", name)
+		}
+	}
+	return values, nil
+}
+
+// InkListTemplatesTool 列出模板工具
+type InkListTemplatesTool struct {
+	server *MCPServer
+}
+
+// NewInkListTemplatesTool 建立列出模板工具
+func NewInkListTemplatesTool(server *MCPServer) *InkListTemplatesTool {
+	return &InkListTemplatesTool{server: server}
+}
+
+func (t *InkListTemplatesTool) GetName() string {
+	return "ink_list_templates"
+}
+
+func (t *InkListTemplatesTool) GetDescription() string {
+	return "List the templates available for structured records, with their slots and instance counts"
+}
+
+func (t *InkListTemplatesTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"include_instances": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Also list each template's instances with their IDs (optional)",
+				"default":     false,
+			},
+		},
+	}
+}
+
+func (t *InkListTemplatesTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	includeInstances, _ := params["include_instances"].(bool)
+
+	templates, err := t.server.services.TemplateService.GetAllTemplates(ctx)
+	if err != nil {
+		return errorResult("Failed to list templates: %v", err), nil
+	}
+	if len(templates) == 0 {
+		return textResult("No templates found. Use ink_create_template to define one."), nil
+	}
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Found %d templates:\n\n", len(templates)))
+	for _, template := range templates {
+		resultText.WriteString(fmt.Sprintf("**%s**\n", services.TemplateName(template.Template)))
+		resultText.WriteString(fmt.Sprintf("Template ID: %s\n", template.Template.ID))
+		resultText.WriteString(fmt.Sprintf("Slots: %s\n", strings.Join(services.TemplateSlotNames(&template), ", ")))
+		resultText.WriteString(fmt.Sprintf("Instances: %d\n", len(template.Instances)))
+
+		// 列出實例名稱與 ID，供 ink_fill_slot 使用
+		if includeInstances {
+			for _, instance := range template.Instances {
+				name := strings.SplitN(instance.Instance.Content, "#", 2)[0]
+				resultText.WriteString(fmt.Sprintf("  - %s (%s)\n", name, instance.Instance.ID))
+			}
+		}
+		resultText.WriteString("\n")
+	}
+
+	return textResult(resultText.String()), nil
+}
+
+// InkCreateTemplateTool 建立模板工具
+type InkCreateTemplateTool struct {
+	server *MCPServer
+}
+
+// NewInkCreateTemplateTool 建立模板工具
+func NewInkCreateTemplateTool(server *MCPServer) *InkCreateTemplateTool {
+	return &InkCreateTemplateTool{server: server}
+}
+
+func (t *InkCreateTemplateTool) GetName() string {
+	return "ink_create_template"
+}
+
+func (t *InkCreateTemplateTool) GetDescription() string {
+	return "Define a template for structured records, such as Person with name, email and company slots"
+}
+
+func (t *InkCreateTemplateTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{
+				"type":        "string",
+				"description": "Template name",
+			},
+			"slots": map[string]interface{}{
+				"type":        "array",
+				"description": "Slot names in display order",
+				"items":       map[string]interface{}{"type": "string"},
+				"minItems":    1,
+			},
+			"layout": map[string]interface{}{
+				"type":        "string",
+				"description": "Text used to render instances, with #slot placeholders such as \"#name <#email>\" (optional)",
+			},
+		},
+		"required": []string{"name", "slots"},
+	}
+}
+
+func (t *InkCreateTemplateTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	name, ok := params["name"].(string)
+	if !ok || strings.TrimSpace(name) == "" {
+		return errorResult("Error: name parameter is required"), nil
+	}
+
+	rawSlots, _ := params["slots"].([]interface{})
+	var slotNames []string
+	seen := make(map[string]bool)
+	for _, raw := range rawSlots {
+		slot, ok := raw.(string)
+		slot = strings.TrimPrefix(strings.TrimSpace(slot), "#")
+		if !ok || slot == "" {
+			return errorResult("Error: slots must be non-empty strings"), nil
+		}
+		if seen[slot] {
+			return errorResult("Error: duplicate slot %q", slot), nil
+		}
+		seen[slot] = true
+		slotNames = append(slotNames, slot)
+	}
+	if len(slotNames) == 0 {
+		return errorResult("Error: at least one slot is required"), nil
+	}
+
+	// 同名模板已存在時不重複建立
+	if existing, err := t.server.services.TemplateService.GetTemplate(ctx, services.TemplateContent(strings.TrimSpace(name))); err == nil && existing != nil && existing.Template != nil {
+		return errorResult("Error: template %q already exists (ID %s)", name, existing.Template.ID), nil
+	}
+
+	layout, _ := params["layout"].(string)
+	template, err := t.server.services.TemplateService.CreateTemplate(ctx, &models.CreateTemplateRequest{
+		TemplateName: strings.TrimSpace(name),
+		SlotNames:    slotNames,
+		Layout:       layout,
+	})
+	if err != nil {
+		return errorResult("Failed to create template: %v", err), nil
+	}
+
+	return textResult(fmt.Sprintf("Created template %s\nTemplate ID: %s\nSlots: %s\n",
+		services.TemplateName(template.Template), template.Template.ID, strings.Join(services.TemplateSlotNames(template), ", "))), nil
+}
+
+// InkInstantiateTemplateTool 建立模板實例工具
+type InkInstantiateTemplateTool struct {
+	server *MCPServer
+}
+
+// NewInkInstantiateTemplateTool 建立模板實例工具
+func NewInkInstantiateTemplateTool(server *MCPServer) *InkInstantiateTemplateTool {
+	return &InkInstantiateTemplateTool{server: server}
+}
+
+func (t *InkInstantiateTemplateTool) GetName() string {
+	return "ink_instantiate_template"
+}
+
+func (t *InkInstantiateTemplateTool) GetDescription() string {
+	return "Create a structured record from a template, filling its slots. Slot names must match the template's slots; unfilled slots stay empty."
+}
+
+func (t *InkInstantiateTemplateTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"template": map[string]interface{}{
+				"type":        "string",
+				"description": "Template name, as listed by ink_list_templates",
+			},
+			"instance_name": map[string]interface{}{
+				"type":        "string",
+				"description": "Name of the new record",
+			},
+			"slot_values": map[string]interface{}{
+				"type":                 "object",
+				"description":          "Values keyed by slot name",
+				"additionalProperties": map[string]interface{}{"type": []string{"string", "number", "boolean"}},
+			},
+		},
+		"required": []string{"template", "instance_name"},
+	}
+}
+
+func (t *InkInstantiateTemplateTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	templateName, ok := params["template"].(string)
+	if !ok || strings.TrimSpace(templateName) == "" {
+		return errorResult("Error: template parameter is required"), nil
+	}
+	instanceName, ok := params["instance_name"].(string)
+	if !ok || strings.TrimSpace(instanceName) == "" {
+		return errorResult("Error: instance_name parameter is required"), nil
+	}
+	slotValues, err := slotValuesParam(params["slot_values"])
+	if err != nil {
+		return errorResult("Error: %v", err), nil
+	}
+
+	template, err := t.server.services.TemplateService.GetTemplate(ctx, services.TemplateContent(strings.TrimSpace(templateName)))
+	if err != nil {
+		return errorResult("Template %q not found: %v", templateName, err), nil
+	}
+
+	// 驗證 slot 名稱，避免模型自行發明欄位
+	slotNames := services.TemplateSlotNames(template)
+	if err := services.ValidateSlotValues(slotNames, slotValues); err != nil {
+		return errorResult("Error: %v", err), nil
+	}
+
+	instance, err := t.server.services.TemplateService.CreateInstance(ctx, &models.CreateInstanceRequest{
+		TemplateChunkID: template.Template.ID,
+		InstanceName:    strings.TrimSpace(instanceName),
+		SlotValues:      slotValues,
+	})
+	if err != nil {
+		return errorResult("Failed to create instance: %v", err), nil
+	}
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Created %s record %s\n", services.TemplateName(template.Template), strings.TrimSpace(instanceName)))
+	resultText.WriteString(fmt.Sprintf("Instance ID: %s\n", instance.Instance.ID))
+	var empty []string
+	for _, slotName := range slotNames {
+		value := slotValues[slotName]
+		if value == "" {
+			empty = append(empty, slotName)
+			continue
+		}
+		resultText.WriteString(fmt.Sprintf("%s: %s\n", slotName, value))
+	}
+	if len(empty) > 0 {
+		resultText.WriteString(fmt.Sprintf("Empty slots (fill with ink_fill_slot): %s\n", strings.Join(empty, ", ")))
+	}
+
+	return textResult(resultText.String()), nil
+}
+
+// InkFillSlotTool 填寫模板實例 slot 工具
+type InkFillSlotTool struct {
+	server *MCPServer
+}
+
+// NewInkFillSlotTool 建立填寫 slot 工具
+func NewInkFillSlotTool(server *MCPServer) *InkFillSlotTool {
+	return &InkFillSlotTool{server: server}
+}
+
+func (t *InkFillSlotTool) GetName() string {
+	return "ink_fill_slot"
+}
+
+func (t *InkFillSlotTool) GetDescription() string {
+	return "Set or replace the value of one slot in an existing template instance"
+}
+
+func (t *InkFillSlotTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"instance_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Instance ID returned by ink_instantiate_template or ink_list_templates",
+			},
+			"slot_name": map[string]interface{}{
+				"type":        "string",
+				"description": "Slot to fill",
+			},
+			"value": map[string]interface{}{
+				"type":        []string{"string", "number", "boolean"},
+				"description": "New slot value",
+			},
+		},
+		"required": []string{"instance_id", "slot_name", "value"},
+	}
+}
+
+func (t *InkFillSlotTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	instanceID, ok := params["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return errorResult("Error: instance_id parameter is required"), nil
+	}
+	slotName, ok := params["slot_name"].(string)
+	slotName = strings.TrimPrefix(strings.TrimSpace(slotName), "#")
+	if !ok || slotName == "" {
+		return errorResult("Error: slot_name parameter is required"), nil
+	}
+	if _, ok := params["value"]; !ok {
+		return errorResult("Error: value parameter is required"), nil
+	}
+	values, err := slotValuesParam(map[string]interface{}{slotName: params["value"]})
+	if err != nil {
+		return errorResult("Error: %v", err), nil
+	}
+
+	if err := t.server.services.TemplateService.UpdateSlotValue(ctx, instanceID, slotName, values[slotName]); err != nil {
+		return errorResult("Failed to fill slot %s: %v", slotName, err), nil
+	}
+
+	return textResult(fmt.Sprintf("Set %s of instance %s to: %s\n", slotName, instanceID, values[slotName])), nil
+}
+
+// InkRenderInstanceTool 將模板實例依版面渲染為文字的工具
+type InkRenderInstanceTool struct {
+	server *MCPServer
+}
+
+// NewInkRenderInstanceTool 建立渲染模板實例工具
+func NewInkRenderInstanceTool(server *MCPServer) *InkRenderInstanceTool {
+	return &InkRenderInstanceTool{server: server}
+}
+
+func (t *InkRenderInstanceTool) GetName() string {
+	return "ink_render_instance"
+}
+
+func (t *InkRenderInstanceTool) GetDescription() string {
+	return "Render a template instance as plain text, Markdown or JSON using its template's layout"
+}
+
+func (t *InkRenderInstanceTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"instance_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Instance ID returned by ink_instantiate_template or ink_list_templates",
+			},
+			"format": map[string]interface{}{
+				"type":        "string",
+				"description": "Output format",
+				"enum":        []string{models.RenderFormatText, models.RenderFormatMarkdown, models.RenderFormatJSON},
+				"default":     models.RenderFormatMarkdown,
+			},
+		},
+		"required": []string{"instance_id"},
+	}
+}
+
+func (t *InkRenderInstanceTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	instanceID, ok := params["instance_id"].(string)
+	if !ok || instanceID == "" {
+		return errorResult("Error: instance_id parameter is required"), nil
+	}
+	format, _ := params["format"].(string)
+	if format == "" {
+		format = models.RenderFormatMarkdown
+	}
+
+	rendered, err := t.server.services.TemplateService.RenderInstance(ctx, instanceID, format)
+	if err != nil {
+		return errorResult("Failed to render instance %s: %v", instanceID, err), nil
+	}
+
+	// JSON 格式回傳完整結構，包含各 slot 的值
+	if rendered.Format == models.RenderFormatJSON {
+		data, err := json.MarshalIndent(rendered, "", "  ")
+		if err != nil {
+			return errorResult("Failed to encode instance %s: %v", instanceID, err), nil
+		}
+		return textResult(string(data)), nil
+	}
+	return textResult(rendered.Content), nil
+}
+
+// InkQueryInstancesTool 依 slot 值篩選模板實例的工具
+type InkQueryInstancesTool struct {
+	server *MCPServer
+}
+
+// NewInkQueryInstancesTool 建立查詢模板實例工具
+func NewInkQueryInstancesTool(server *MCPServer) *InkQueryInstancesTool {
+	return &InkQueryInstancesTool{server: server}
+}
+
+func (t *InkQueryInstancesTool) GetName() string {
+	return "ink_query_instances"
+}
+
+func (t *InkQueryInstancesTool) GetDescription() string {
+	return "Find the instances of a template whose slot values match every filter, like querying rows of a table"
+}
+
+func (t *InkQueryInstancesTool) GetInputSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"template_id": map[string]interface{}{
+				"type":        "string",
+				"description": "Template ID returned by ink_list_templates",
+			},
+			"filters": map[string]interface{}{
+				"type":        "array",
+				"description": "Slot predicates; equals and contains compare text case-insensitively, range takes numbers or dates (YYYY-MM-DD) as from and to",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"slot":  map[string]interface{}{"type": "string"},
+						"op":    map[string]interface{}{"type": "string", "enum": []string{models.SlotFilterEquals, models.SlotFilterContains, models.SlotFilterRange}},
+						"value": map[string]interface{}{"type": []string{"string", "number"}},
+						"from":  map[string]interface{}{"type": []string{"string", "number"}},
+						"to":    map[string]interface{}{"type": []string{"string", "number"}},
+					},
+					"required": []string{"slot", "op"},
+				},
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of instances (optional)",
+				"default":     20,
+			},
+		},
+		"required": []string{"template_id"},
+	}
+}
+
+func (t *InkQueryInstancesTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	templateID, ok := params["template_id"].(string)
+	if !ok || templateID == "" {
+		return errorResult("Error: template_id parameter is required"), nil
+	}
+	req := &models.QueryInstancesRequest{Limit: 20}
+	if limit, ok := params["limit"].(float64); ok {
+		req.Limit = int(limit)
+	}
+
+	// 篩選條件的 value、from、to 可為數字，轉為文字後交由服務判斷型別
+	rawFilters, _ := params["filters"].([]interface{})
+	for i, raw := range rawFilters {
+		object, ok := raw.(map[string]interface{})
+		if !ok {
+			return errorResult("Error: filter %d must be an object", i+1), nil
+		}
+		values, err := slotValuesParam(map[string]interface{}{"value": object["value"], "from": object["from"], "to": object["to"]})
+		if err != nil {
+			return errorResult("Error: filter %d: %v", i+1, err), nil
+		}
+		slot, _ := object["slot"].(string)
+		op, _ := object["op"].(string)
+		req.Filters = append(req.Filters, models.SlotFilter{Slot: slot, Op: op, Value: values["value"], From: values["from"], To: values["to"]})
+	}
+
+	result, err := t.server.services.TemplateService.QueryInstances(ctx, templateID, req)
+	if err != nil {
+		return errorResult("Failed to query instances of template %s: %v", templateID, err), nil
+	}
+	if len(result.Instances) == 0 {
+		return textResult("No instances match the filters."), nil
+	}
+
+	var resultText strings.Builder
+	resultText.WriteString(fmt.Sprintf("Found %d instances:\n\n", len(result.Instances)))
+	for _, instance := range result.Instances {
+		resultText.WriteString(fmt.Sprintf("**%s** (%s)\n", services.InstanceName(instance.Instance), instance.Instance.ID))
+
+		// slot 依名稱排序，讓輸出穩定
+		names := make([]string, 0, len(instance.SlotValues))
+		for name := range instance.SlotValues {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := instance.SlotValues[name].Content
+			if instance.SlotValues[name].SlotValue != nil {
+				value = *instance.SlotValues[name].SlotValue
+			}
+			resultText.WriteString(fmt.Sprintf("  %s: %s\n", name, value))
+		}
+		resultText.WriteString("\n")
+	}
+	return textResult(resultText.String()), nil
+}
@@ -23,7 +23,7 @@ func (t *InkSearchTextTool) GetName() string {
 }
 
 func (t *InkSearchTextTool) GetDescription() string {
-	return "Search for text chunks by content. Finds chunks containing specific text or matching search criteria."
+	return "Search for text chunks by content. Finds chunks containing specific text or matching search criteria. Large result sets are paged: pass a result's nextCursor as cursor to continue."
 }
 
 func (t *InkSearchTextTool) GetInputSchema() map[string]interface{} {
@@ -50,8 +50,8 @@ func (t *InkSearchTextTool) GetInputSchema() map[string]interface{} {
 				"minimum":     1,
 				"maximum":     100,
 			},
+			"cursor": cursorSchema,
 		},
-		"required": []string{"query"},
 	}
 }
 
@@ -64,6 +64,15 @@ func (t *InkSearchTextTool) Execute(ctx context.Context, params map[string]inter
 		}, nil
 	}
 
+	// 帶 cursor 時沿用首次呼叫的參數
+	params, offset, err := resolveToolCursor(t.GetName(), params)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
 	// 解析參數
 	query, ok := params["query"].(string)
 	if !ok || query == "" {
@@ -82,6 +91,7 @@ func (t *InkSearchTextTool) Execute(ctx context.Context, params map[string]inter
 	searchQuery := &models.SearchQuery{
 		Content: query,
 		Limit:   limit,
+		Offset:  offset,
 	}
 
 	// 處理可選參數
@@ -111,13 +121,14 @@ func (t *InkSearchTextTool) Execute(ctx context.Context, params map[string]inter
 		}, nil
 	}
 
-	// 格式化結果
-	var resultText strings.Builder
-	resultText.WriteString(fmt.Sprintf("Found %d results (total: %d):\n\n",
+	// 格式化結果，超出大小預算的結果留待續取
+	page := newResultPage(fmt.Sprintf("Found %d results (total: %d):\n\n",
 		len(searchResult.Chunks), searchResult.TotalCount))
 
+	shown := 0
 	for i, chunk := range searchResult.Chunks {
-		resultText.WriteString(fmt.Sprintf("**Result %d**\n", i+1))
+		var resultText strings.Builder
+		resultText.WriteString(fmt.Sprintf("**Result %d**\n", offset+i+1))
 		resultText.WriteString(fmt.Sprintf("Chunk ID: %s\n", chunk.ChunkID))
 
 		// 顯示內容（限制長度）
@@ -138,16 +149,18 @@ func (t *InkSearchTextTool) Execute(ctx context.Context, params map[string]inter
 		resultText.WriteString(fmt.Sprintf("Created: %v\n", chunk.CreatedTime))
 
 		resultText.WriteString("\n")
+		if !page.add(resultText.String()) {
+			break
+		}
+		shown++
 	}
 
 	if len(searchResult.Chunks) == 0 {
-		resultText.WriteString("No results found. Try adjusting your query.\n")
+		page.text.WriteString("No results found. Try adjusting your query.\n")
 	}
 
-	return &MCPToolResult{
-		Content: []MCPContent{{Type: "text", Text: resultText.String()}},
-		IsError: false,
-	}, nil
+	more := shown < len(searchResult.Chunks) || searchResult.HasMore
+	return page.result(t.GetName(), params, offset+shown, more), nil
 }
 
 // InkCreateTextChunkTool 建立文字 chunk 工具
@@ -23,7 +23,7 @@ func (t *InkSearchChunksTool) GetName() string {
 }
 
 func (t *InkSearchChunksTool) GetDescription() string {
-	return "Search for chunks using multimodal search (text, image, or hybrid)"
+	return "Search for chunks using multimodal search (text, image, or hybrid). Large result sets are paged: pass a result's nextCursor as cursor to continue."
 }
 
 func (t *InkSearchChunksTool) GetInputSchema() map[string]interface{} {
@@ -54,12 +54,21 @@ func (t *InkSearchChunksTool) GetInputSchema() map[string]interface{} {
 				"description": "Minimum similarity threshold",
 				"default":     0.7,
 			},
+			"cursor": cursorSchema,
 		},
-		"required": []string{"query"},
 	}
 }
 
 func (t *InkSearchChunksTool) Execute(ctx context.Context, params map[string]interface{}) (*MCPToolResult, error) {
+	// 帶 cursor 時沿用首次呼叫的參數
+	params, offset, err := resolveToolCursor(t.GetName(), params)
+	if err != nil {
+		return &MCPToolResult{
+			Content: []MCPContent{{Type: "text", Text: fmt.Sprintf("Error: %v", err)}},
+			IsError: true,
+		}, nil
+	}
+
 	query, ok := params["query"].(string)
 	if !ok || query == "" {
 		return &MCPToolResult{
@@ -85,18 +94,17 @@ func (t *InkSearchChunksTool) Execute(ctx context.Context, params map[string]int
 		minSimilarity = simFloat
 	}
 
-	// 建立搜尋請求
+	// 建立搜尋請求；多模態搜尋不支援位移，續取時多取前面已回傳的結果再略過
 	searchReq := &models.MultimodalSearchRequest{
 		TextQuery:     query,
 		ImageQuery:    imageURL,
 		VectorType:    "all",
-		Limit:         limit,
+		Limit:         offset + limit,
 		MinSimilarity: minSimilarity,
 	}
 
 	// 執行搜尋
 	var searchResponse *models.MultimodalSearchResponse
-	var err error
 
 	switch searchType {
 	case "text":
@@ -119,14 +127,22 @@ func (t *InkSearchChunksTool) Execute(ctx context.Context, params map[string]int
 		}, nil
 	}
 
-	// 格式化結果
-	var resultText strings.Builder
-	resultText.WriteString(fmt.Sprintf("Found %d results in %v:\n\n", 
+	results := searchResponse.Results
+	if offset < len(results) {
+		results = results[offset:]
+	} else {
+		results = nil
+	}
+
+	// 格式化結果，超出大小預算的結果留待續取
+	page := newResultPage(fmt.Sprintf("Found %d results in %v:\n\n",
 		searchResponse.TotalCount, searchResponse.SearchTime))
 
-	for i, result := range searchResponse.Results {
-		resultText.WriteString(fmt.Sprintf("%d. **%s** (similarity: %.3f)\n", 
-			i+1, result.Chunk.ChunkID, result.Similarity))
+	shown := 0
+	for i, result := range results {
+		var resultText strings.Builder
+		resultText.WriteString(fmt.Sprintf("%d. **%s** (similarity: %.3f)\n",
+			offset+i+1, result.Chunk.ChunkID, result.Similarity))
 		resultText.WriteString(fmt.Sprintf("   Content: %s\n", result.Chunk.Contents))
 		if result.Chunk.IsImageChunk() {
 			resultText.WriteString(fmt.Sprintf("   Image URL: %s\n", result.Chunk.GetImageURL()))
@@ -136,12 +152,15 @@ func (t *InkSearchChunksTool) Execute(ctx context.Context, params map[string]int
 		}
 		resultText.WriteString(fmt.Sprintf("   Match: %s\n", result.Explanation))
 		resultText.WriteString("\n")
+		if !page.add(resultText.String()) {
+			break
+		}
+		shown++
 	}
 
-	return &MCPToolResult{
-		Content: []MCPContent{{Type: "text", Text: resultText.String()}},
-		IsError: false,
-	}, nil
+	// 取滿一整頁表示可能還有後續結果
+	more := shown < len(results) || len(searchResponse.Results) >= offset+limit
+	return page.result(t.GetName(), params, offset+shown, more), nil
 }
 
 // InkAnalyzeImageTool 圖片分析工具
@@ -214,6 +214,13 @@ func (cl *CompatibilityLayer) UpdateChunk(ctx context.Context, chunk *models.Chu
 		return fmt.Errorf("failed to transform legacy chunk: %w", err)
 	}
 
+	// Legacy chunks carry no version, so the update applies to the current one
+	current, err := cl.unifiedService.GetChunk(ctx, unifiedChunk.ChunkID)
+	if err != nil {
+		return fmt.Errorf("unified service get chunk failed: %w", err)
+	}
+	unifiedChunk.Version = current.Version
+
 	// Call unified service
 	if err := cl.unifiedService.UpdateChunk(ctx, unifiedChunk); err != nil {
 		return fmt.Errorf("unified service update chunk failed: %w", err)
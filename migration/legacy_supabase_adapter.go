@@ -81,6 +81,20 @@ func (lsa *LegacySupabaseAdapter) GetChunkByID(ctx context.Context, id string) (
 	return lsa.compatibilityLayer.GetChunkByID(ctx, id)
 }
 
+func (lsa *LegacySupabaseAdapter) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) {
+	lsa.logger.Printf("Legacy GetChunksByIDs called for %d ids", len(ids))
+
+	var chunks []models.ChunkRecord
+	for _, id := range ids {
+		chunk, err := lsa.compatibilityLayer.GetChunkByID(ctx, id)
+		if err != nil {
+			continue
+		}
+		chunks = append(chunks, *chunk)
+	}
+	return chunks, nil
+}
+
 func (lsa *LegacySupabaseAdapter) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) {
 	lsa.logger.Printf("Legacy GetChunkByContent called")
 
@@ -146,6 +160,12 @@ func (lsa *LegacySupabaseAdapter) UpdateSlotValue(ctx context.Context, instanceC
 	return nil
 }
 
+func (lsa *LegacySupabaseAdapter) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) {
+	lsa.logger.Printf("Legacy UpdateTemplateSchema called for: %s", templateChunkID)
+	// Placeholder implementation
+	return &models.TemplateSchemaMigration{TemplateChunkID: templateChunkID, DryRun: req.DryRun}, nil
+}
+
 // Tag operations - these delegate to the compatibility layer
 func (lsa *LegacySupabaseAdapter) AddTag(ctx context.Context, chunkID string, tagContent string) error {
 	return lsa.compatibilityLayer.AddTag(ctx, chunkID, tagContent)
@@ -188,6 +208,18 @@ func (lsa *LegacySupabaseAdapter) MoveChunk(ctx context.Context, req *models.Mov
 	return nil
 }
 
+func (lsa *LegacySupabaseAdapter) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error {
+	lsa.logger.Printf("Legacy InsertChunkAt called under: %s", parentID)
+	// Placeholder implementation
+	return nil
+}
+
+func (lsa *LegacySupabaseAdapter) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error {
+	lsa.logger.Printf("Legacy ReorderChildren called for: %s", parentID)
+	// Placeholder implementation
+	return nil
+}
+
 func (lsa *LegacySupabaseAdapter) BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error {
 	lsa.logger.Printf("Legacy BulkUpdateChunks called")
 	// Placeholder implementation
@@ -234,6 +266,18 @@ func (lsa *LegacySupabaseAdapter) InsertGraphEdges(ctx context.Context, edges []
 	return nil
 }
 
+func (lsa *LegacySupabaseAdapter) UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error {
+	lsa.logger.Printf("Legacy UpsertGraphNodes called for %d nodes", len(nodes))
+	// Placeholder implementation
+	return nil
+}
+
+func (lsa *LegacySupabaseAdapter) UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error {
+	lsa.logger.Printf("Legacy UpsertGraphEdges called for %d edges", len(edges))
+	// Placeholder implementation
+	return nil
+}
+
 func (lsa *LegacySupabaseAdapter) SearchGraph(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) {
 	lsa.logger.Printf("Legacy SearchGraph called")
 	// Placeholder implementation
@@ -264,6 +308,12 @@ func (lsa *LegacySupabaseAdapter) GetNodesByChunk(ctx context.Context, chunkID s
 	return []models.GraphNode{}, nil
 }
 
+func (lsa *LegacySupabaseAdapter) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) {
+	lsa.logger.Printf("Legacy GetNodesByIDs called for %d ids", len(ids))
+	// Placeholder implementation
+	return []models.GraphNode{}, nil
+}
+
 func (lsa *LegacySupabaseAdapter) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) {
 	lsa.logger.Printf("Legacy GetEdgesByRelationType called for: %s", relationType)
 	// Placeholder implementation
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Role is the set of operations an API caller may perform in its workspace
+type Role string
+
+const (
+	// RoleAdmin may also run migrations and consistency repairs and manage tokens
+	RoleAdmin Role = "admin"
+	// RoleEditor may read and write chunks, tags and templates
+	RoleEditor Role = "editor"
+	// RoleReader may only read and search
+	RoleReader Role = "reader"
+)
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	switch r {
+	case RoleAdmin, RoleEditor, RoleReader:
+		return true
+	}
+	return false
+}
+
+// APIToken describes an issued API token. The token itself is only returned once, when
+// it is created; the service keeps a hash of it.
+type APIToken struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Workspace  string     `json:"workspace"`
+	Role       Role       `json:"role"`
+	Prefix     string     `json:"prefix"` // first characters of the token, to recognize it
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateAPITokenRequest creates a token for the caller's workspace
+type CreateAPITokenRequest struct {
+	Name string `json:"name"`
+	Role Role   `json:"role"`
+	// ExpiresIn is a duration such as 720h; empty tokens do not expire
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// CreatedAPIToken is returned when a token is created and is the only response that
+// contains the token
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
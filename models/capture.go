@@ -0,0 +1,61 @@
+package models
+
+// CaptureKind is what a capture holds
+type CaptureKind string
+
+const (
+	CaptureText  CaptureKind = "text"
+	CaptureURL   CaptureKind = "url"
+	CaptureEmail CaptureKind = "email"
+)
+
+// Capture status values, kept in the capture chunk's metadata under CaptureStatusKey
+const (
+	CaptureStatusPending   = "pending"
+	CaptureStatusProcessed = "processed"
+	CaptureStatusFailed    = "failed"
+)
+
+// Metadata keys of captured chunks and inbox pages
+const (
+	CaptureStatusKey = "capture_status"
+	CaptureKindKey   = "capture_kind"
+	CaptureSourceKey = "capture_source"
+	CaptureTagsKey   = "capture_tags"
+	CaptureErrorKey  = "capture_error"
+	SourceURLKey     = "source_url"
+	EmailFromKey     = "email_from"
+	EmailSubjectKey  = "email_subject"
+	InboxOwnerKey    = "inbox_owner"
+)
+
+// CaptureRequest is a quick note sent by an external tool such as a browser extension or a
+// phone shortcut. The kind is inferred from the fields set when it is empty.
+type CaptureRequest struct {
+	Kind CaptureKind `json:"kind,omitempty"`
+	// Text is the note, or for URLs a comment or excerpt
+	Text  string         `json:"text,omitempty"`
+	URL   string         `json:"url,omitempty"`
+	Title string         `json:"title,omitempty"` // the URL's page title
+	Email *CapturedEmail `json:"email,omitempty"`
+	// Tags are applied with the #tags found in the text
+	Tags []string `json:"tags,omitempty"`
+	// Source names the sending tool, such as "chrome-extension"
+	Source string `json:"source,omitempty"`
+}
+
+// CapturedEmail is an email forwarded as a capture
+type CapturedEmail struct {
+	From    string `json:"from,omitempty"`
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body,omitempty"`
+}
+
+// CaptureResult identifies the chunk a capture created. Chunking and tagging finish in the
+// background; the chunk's capture_status metadata turns processed when they have.
+type CaptureResult struct {
+	ChunkID     string      `json:"chunk_id"`
+	InboxPageID string      `json:"inbox_page_id"`
+	Kind        CaptureKind `json:"kind"`
+	Status      string      `json:"status"`
+}
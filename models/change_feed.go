@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// ChangeEventType names the kind of change carried by a ChangeEvent
+type ChangeEventType string
+
+const (
+	ChangeEventInsert ChangeEventType = "insert"
+	ChangeEventUpdate ChangeEventType = "update"
+	ChangeEventDelete ChangeEventType = "delete"
+	// ChangeEventResync tells subscribers that events may have been missed, e.g. while the
+	// database connection was down, and that anything derived from chunks should be reloaded
+	ChangeEventResync ChangeEventType = "resync"
+)
+
+// ChangeEvent is a normalized chunk table change
+type ChangeEvent struct {
+	// Sequence orders events within one gateway process; it restarts at 1 with the process
+	Sequence   uint64          `json:"sequence"`
+	Type       ChangeEventType `json:"type"`
+	Table      string          `json:"table,omitempty"`
+	ChunkID    string          `json:"chunk_id,omitempty"`
+	Page       *string         `json:"page,omitempty"`
+	Parent     *string         `json:"parent,omitempty"`
+	IsPage     bool            `json:"is_page"`
+	IsTag      bool            `json:"is_tag"`
+	IsTemplate bool            `json:"is_template"`
+	Version    int64           `json:"version,omitempty"`
+	OccurredAt time.Time       `json:"occurred_at"`
+}
+
+// ChangeFeedFilter selects the events a subscriber receives. Resync events always match.
+type ChangeFeedFilter struct {
+	// Types keeps only these event types; empty means all
+	Types []ChangeEventType `json:"types,omitempty"`
+	// PageID keeps only changes to the page chunk itself or chunks on that page
+	PageID string `json:"page_id,omitempty"`
+}
+
+// Matches reports whether event passes the filter
+func (f ChangeFeedFilter) Matches(event *ChangeEvent) bool {
+	if event.Type == ChangeEventResync {
+		return true
+	}
+	if len(f.Types) > 0 {
+		matched := false
+		for _, t := range f.Types {
+			if t == event.Type {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.PageID != "" && event.ChunkID != f.PageID && (event.Page == nil || *event.Page != f.PageID) {
+		return false
+	}
+	return true
+}
+
+// ChangeFeedStats describes the state of the change feed
+type ChangeFeedStats struct {
+	Listening   bool       `json:"listening"`
+	Channel     string     `json:"channel"`
+	Subscribers int        `json:"subscribers"`
+	Published   uint64     `json:"published"`
+	Dropped     uint64     `json:"dropped"`
+	Resyncs     uint64     `json:"resyncs"`
+	LastEventAt *time.Time `json:"last_event_at,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// ChunkRevision is one version of a chunk as kept in its history
+type ChunkRevision struct {
+	Chunk     UnifiedChunkRecord `json:"chunk"`
+	ValidFrom time.Time          `json:"valid_from"`
+	// ValidTo is when the next version replaced this one or the chunk was deleted; nil
+	// while the version is current
+	ValidTo *time.Time `json:"valid_to,omitempty"`
+	// Deleted marks the last version of a chunk that no longer exists
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+// PageSnapshot is a page and its blocks as they were at a point in time
+type PageSnapshot struct {
+	AsOf time.Time           `json:"as_of"`
+	Page *UnifiedChunkRecord `json:"page"`
+	// Blocks are the chunks on the page, oldest first
+	Blocks []UnifiedChunkRecord `json:"blocks"`
+}
+
+// Kinds of chunk changes in a page diff
+const (
+	ChunkChangeAdded    = "added"
+	ChunkChangeRemoved  = "removed"
+	ChunkChangeModified = "modified"
+)
+
+// ChunkChange is how one chunk of a page changed between two times
+type ChunkChange struct {
+	ChunkID string `json:"chunk_id"`
+	Change  string `json:"change"`
+	// Before is nil for added chunks and After for removed ones
+	Before *UnifiedChunkRecord `json:"before,omitempty"`
+	After  *UnifiedChunkRecord `json:"after,omitempty"`
+}
+
+// PageDiff lists the chunks of a page that changed between From and To. The page chunk
+// itself is listed like its blocks.
+type PageDiff struct {
+	PageID   string        `json:"page_id"`
+	From     time.Time     `json:"from"`
+	To       time.Time     `json:"to"`
+	Added    int           `json:"added"`
+	Removed  int           `json:"removed"`
+	Modified int           `json:"modified"`
+	Changes  []ChunkChange `json:"changes"`
+}
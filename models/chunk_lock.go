@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ChunkLock is held by an editor session on a chunk and its subtree. While it is live only
+// the holder may move, merge or delete chunks inside the subtree, or move chunks into it.
+type ChunkLock struct {
+	ChunkID string `json:"chunk_id"`
+	// Holder is the subject that acquired the lock
+	Holder string `json:"holder"`
+	// Token identifies the lock to refresh and release it, and is sent in the X-Chunk-Lock
+	// header with writes inside the subtree. Only the caller acquiring the lock receives it.
+	Token      string    `json:"token,omitempty"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// Expired reports whether the lock no longer applies at now
+func (l *ChunkLock) Expired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// ChunkLockRequest acquires or refreshes a lock. A zero TTL uses the configured default.
+type ChunkLockRequest struct {
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Metadata keys of chunks made from ingested emails, besides EmailFromKey and EmailSubjectKey
+const (
+	EmailMessageIDKey = "email_message_id"
+	EmailToKey        = "email_to"
+	EmailDateKey      = "email_date"
+	// EmailThreadKey holds the chunk ID of the first message of the email's thread
+	EmailThreadKey = "email_thread"
+	// Attachment chunks keep the file's name, type and size, and its object storage key
+	// when it was uploaded
+	AttachmentNameKey = "attachment_name"
+	AttachmentTypeKey = "attachment_type"
+	AttachmentSizeKey = "attachment_size"
+	AttachmentKeyKey  = "attachment_key"
+)
+
+// InboundEmail is an email received through a webhook or an IMAP mailbox
+type InboundEmail struct {
+	MessageID string `json:"message_id"`
+	// InReplyTo and References name the messages this one replies to, oldest reference first
+	InReplyTo   []string          `json:"in_reply_to,omitempty"`
+	References  []string          `json:"references,omitempty"`
+	From        string            `json:"from"`
+	To          string            `json:"to,omitempty"`
+	Subject     string            `json:"subject"`
+	Date        time.Time         `json:"date"`
+	Text        string            `json:"text,omitempty"`
+	HTML        string            `json:"html,omitempty"`
+	Attachments []EmailAttachment `json:"attachments,omitempty"`
+}
+
+// EmailAttachment is a file attached to an inbound email
+type EmailAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"-"`
+}
+
+// EmailIngestResult reports the chunk an email became. Duplicate emails, already ingested
+// under the same Message-ID, report the chunk of the first delivery.
+type EmailIngestResult struct {
+	MessageID string `json:"message_id"`
+	ChunkID   string `json:"chunk_id"`
+	// ThreadID is the chunk of the first message of the thread; replies are its children
+	ThreadID    string   `json:"thread_id"`
+	Duplicate   bool     `json:"duplicate,omitempty"`
+	Chunks      int      `json:"chunks"`
+	Attachments int      `json:"attachments"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
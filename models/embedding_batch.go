@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Outcomes of one text in an embedding batch
+const (
+	EmbeddingBatchStatusOK     = "ok"
+	EmbeddingBatchStatusFailed = "failed"
+)
+
+// EmbeddingBatchRequest asks for embeddings of chunks, which are stored, or of raw texts,
+// which are returned
+type EmbeddingBatchRequest struct {
+	ChunkIDs []string `json:"chunk_ids,omitempty"`
+	Texts    []string `json:"texts,omitempty"`
+	// ReturnVectors includes the vectors of chunks in the response too
+	ReturnVectors bool `json:"return_vectors,omitempty"`
+}
+
+// EmbeddingBatchResponse reports the outcome of every chunk and text, in request order:
+// chunks first, then texts
+type EmbeddingBatchResponse struct {
+	Results   []EmbeddingBatchResult `json:"results"`
+	Succeeded int                    `json:"succeeded"`
+	Failed    int                    `json:"failed"`
+	Model     string                 `json:"model"`
+	// Requests counts the calls made to the embedding provider
+	Requests int `json:"requests"`
+	Tokens   int `json:"tokens"`
+	// EstimatedTokens is the share of Tokens the provider did not report
+	EstimatedTokens int           `json:"estimated_tokens"`
+	EstimatedCost   float64       `json:"estimated_cost"`
+	Duration        time.Duration `json:"duration"`
+}
+
+// EmbeddingBatchResult is the outcome of one chunk or text
+type EmbeddingBatchResult struct {
+	Index   int       `json:"index"`
+	ChunkID string    `json:"chunk_id,omitempty"`
+	Status  string    `json:"status"`
+	Error   string    `json:"error,omitempty"`
+	Stored  bool      `json:"stored"`
+	Vector  []float64 `json:"vector,omitempty"`
+}
+
+// EmbeddingUsageReport is a workspace's embedding usage over recent days
+type EmbeddingUsageReport struct {
+	Workspace     string              `json:"workspace"`
+	Since         time.Time           `json:"since"`
+	Requests      int64               `json:"requests"`
+	Texts         int64               `json:"texts"`
+	Tokens        int64               `json:"tokens"`
+	EstimatedCost float64             `json:"estimated_cost"`
+	Days          []EmbeddingUsageDay `json:"days"`
+}
+
+// EmbeddingUsageDay is one day of embedding usage with one model
+type EmbeddingUsageDay struct {
+	Date            time.Time `json:"date"`
+	Model           string    `json:"model"`
+	Requests        int64     `json:"requests"`
+	Texts           int64     `json:"texts"`
+	Tokens          int64     `json:"tokens"`
+	EstimatedTokens int64     `json:"estimated_tokens"`
+	EstimatedCost   float64   `json:"estimated_cost"`
+}
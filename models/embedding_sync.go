@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// EmbeddingCoverage reports how many text chunks have an embedding of their current contents
+type EmbeddingCoverage struct {
+	// TotalChunks counts text chunks with contents; sensitive chunks are never embedded
+	TotalChunks int64 `json:"total_chunks"`
+	// EmbeddedChunks have an embedding of their current contents
+	EmbeddedChunks int64 `json:"embedded_chunks"`
+	// StaleChunks have an embedding of contents that have since changed
+	StaleChunks int64 `json:"stale_chunks"`
+	// MissingChunks have no embedding at all
+	MissingChunks int64 `json:"missing_chunks"`
+	// Coverage is EmbeddedChunks / TotalChunks, 1 when there are no chunks
+	Coverage float64 `json:"coverage"`
+
+	Queued      int        `json:"queued"`
+	Processed   int64      `json:"processed"`
+	Failed      int64      `json:"failed"`
+	Dropped     int64      `json:"dropped"`
+	LastBatchAt *time.Time `json:"last_batch_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+// Text embedding storage formats
+const (
+	// EmbeddingStorageVector stores float32 vectors in chunks.vector
+	EmbeddingStorageVector = "vector"
+	// EmbeddingStorageHalfvec stores float16 vectors in chunks.vector_half, half the size
+	EmbeddingStorageHalfvec = "halfvec"
+	// EmbeddingStorageInt8 stores one byte per dimension in chunks.vector_int8, searched
+	// through the sign bits in chunks.vector_bits and re-scored
+	EmbeddingStorageInt8 = "int8"
+)
+
+// EmbeddingReencodeResult summarizes re-encoding text embeddings into the configured format
+type EmbeddingReencodeResult struct {
+	Format     string `json:"format"`
+	Dimensions int    `json:"dimensions"`
+	// Reencoded counts the embeddings written in the configured format
+	Reencoded int64 `json:"reencoded"`
+	// Cleared counts the embeddings removed from the columns of other formats
+	Cleared   int64         `json:"cleared"`
+	IndexName string        `json:"index_name,omitempty"`
+	Duration  time.Duration `json:"duration"`
+}
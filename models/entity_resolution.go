@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Reasons an entity merge candidate was proposed
+const (
+	EntityMatchNormalizedName = "normalized_name"
+	EntityMatchEmbedding      = "embedding_similarity"
+)
+
+// EntityResolutionOptions controls the search for duplicate graph nodes. Zero values use
+// the defaults.
+type EntityResolutionOptions struct {
+	// EntityType limits the search to nodes of one type; nodes of different types are
+	// never proposed together
+	EntityType string `json:"entity_type,omitempty"`
+	// MinSimilarity is the cosine similarity (0-1) at which differently named entities
+	// are proposed as the same (default 0.9)
+	MinSimilarity float64 `json:"min_similarity,omitempty"`
+	// SkipEmbeddings matches normalized names only
+	SkipEmbeddings bool `json:"skip_embeddings,omitempty"`
+	// Limit caps the candidates returned, most confident first (default 50)
+	Limit int `json:"limit,omitempty"`
+}
+
+// EntityCandidateNode is a graph node in a merge candidate
+type EntityCandidateNode struct {
+	ID         string `json:"id"`
+	ChunkID    string `json:"chunk_id"`
+	EntityName string `json:"entity_name"`
+	EntityType string `json:"entity_type"`
+	Degree     int    `json:"degree"` // edges connected to the node
+}
+
+// EntityMergeCandidate is a group of graph nodes that appear to refer to the same entity
+type EntityMergeCandidate struct {
+	// TargetNodeID is the suggested node to merge the others into: the one with the most
+	// edges, then the oldest
+	TargetNodeID string                `json:"target_node_id"`
+	Nodes        []EntityCandidateNode `json:"nodes"`
+	Names        []string              `json:"names"`
+	EntityType   string                `json:"entity_type"`
+	// Confidence is 1 for identical names, 0.95 for names equal once normalized, and the
+	// lowest similarity that joined the group for embedding matches
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+}
+
+// EntityMergeRequest merges SourceNodeIDs into TargetNodeID
+type EntityMergeRequest struct {
+	TargetNodeID  string   `json:"target_node_id"`
+	SourceNodeIDs []string `json:"source_node_ids"`
+}
+
+// EntityMergeResult reports what an entity merge changed
+type EntityMergeResult struct {
+	TargetNodeID   string   `json:"target_node_id"`
+	MergedNodeIDs  []string `json:"merged_node_ids"`
+	RewrittenEdges int      `json:"rewritten_edges"`
+	// RemovedEdges counts edges between merged nodes, which would have become self-loops
+	RemovedEdges int       `json:"removed_edges"`
+	MergedAt     time.Time `json:"merged_at"`
+}
+
+// Graph node and edge properties recording entity merges and deduplication
+const (
+	// EntityMergedFromProperty lists the merged nodes on the surviving node: their ID,
+	// name, type, chunk, properties and merge time
+	EntityMergedFromProperty = "merged_from"
+	// EntityAliasesProperty lists the names the surviving node has been known by
+	EntityAliasesProperty = "aliases"
+	// EntityChunkIDsProperty lists every chunk an upserted node's entity was extracted from
+	EntityChunkIDsProperty = "chunk_ids"
+	// EdgeOriginalSourceProperty and EdgeOriginalTargetProperty keep an edge's endpoints
+	// from before they were merged
+	EdgeOriginalSourceProperty = "original_source_node_id"
+	EdgeOriginalTargetProperty = "original_target_node_id"
+)
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// Graph analytics metrics stored as graph node properties
+const (
+	GraphMetricPageRank    = "pagerank"
+	GraphMetricBetweenness = "betweenness"
+	GraphMetricCommunity   = "community"
+)
+
+// GraphAnalyticsOptions controls a graph analytics run. Zero values use the defaults.
+type GraphAnalyticsOptions struct {
+	// Algorithms limits the run to a subset of pagerank, betweenness and community; empty runs all
+	Algorithms []string `json:"algorithms,omitempty"`
+	// Damping is the PageRank damping factor (default 0.85)
+	Damping float64 `json:"damping,omitempty"`
+	// MaxIterations bounds PageRank power iteration (default 100)
+	MaxIterations int `json:"max_iterations,omitempty"`
+	// Tolerance is the PageRank L1 convergence threshold (default 1e-6)
+	Tolerance float64 `json:"tolerance,omitempty"`
+	// BetweennessSamples approximates betweenness from this many source nodes; 0 computes it exactly
+	BetweennessSamples int `json:"betweenness_samples,omitempty"`
+	// Resolution is the Louvain modularity resolution; higher values yield smaller communities (default 1)
+	Resolution float64 `json:"resolution,omitempty"`
+}
+
+// GraphAnalyticsResult summarizes a graph analytics run
+type GraphAnalyticsResult struct {
+	NodeCount          int           `json:"node_count"`
+	EdgeCount          int           `json:"edge_count"`
+	Algorithms         []string      `json:"algorithms"`
+	PageRankIterations int           `json:"pagerank_iterations,omitempty"`
+	PageRankConverged  bool          `json:"pagerank_converged,omitempty"`
+	CommunityCount     int           `json:"community_count,omitempty"`
+	Modularity         float64       `json:"modularity,omitempty"`
+	UpdatedNodes       int           `json:"updated_nodes"`
+	ComputedAt         time.Time     `json:"computed_at"`
+	Duration           time.Duration `json:"duration"`
+}
+
+// EntityScore is a graph node ranked by an analytics metric
+type EntityScore struct {
+	Node  GraphNode `json:"node"`
+	Score float64   `json:"score"`
+	Rank  int       `json:"rank"`
+}
+
+// GraphCommunity is a group of densely connected entities found by community detection
+type GraphCommunity struct {
+	ID int `json:"id"`
+	// Label is the name of the community's highest-PageRank entity
+	Label   string        `json:"label"`
+	Size    int           `json:"size"`
+	Members []EntityScore `json:"members"`
+}
+
+// EdgeDecay controls how an edge's relevance fades after its relationship was last seen
+type EdgeDecay struct {
+	// HalfLife is how long it takes relevance to halve; zero disables decay
+	HalfLife time.Duration
+	// Floor is the lowest fraction of its weight an edge decays to, so long-standing
+	// relationships still count
+	Floor float64
+}
+
+// DefaultEdgeDecay halves relevance every 90 days down to a tenth of the weight
+func DefaultEdgeDecay() EdgeDecay {
+	return EdgeDecay{HalfLife: 90 * 24 * time.Hour, Floor: 0.1}
+}
+
+// Relevance returns the edge's weight decayed by the time since it was last seen
+func (e GraphEdge) Relevance(now time.Time, decay EdgeDecay) float64 {
+	weight := e.Weight
+	if weight <= 0 {
+		weight = 1
+	}
+	if decay.HalfLife <= 0 {
+		return weight
+	}
+	seen := e.CreatedAt
+	if e.LastSeenAt != nil {
+		seen = *e.LastSeenAt
+	}
+	age := now.Sub(seen)
+	if age <= 0 || seen.IsZero() {
+		return weight
+	}
+	factor := math.Pow(0.5, age.Hours()/decay.HalfLife.Hours())
+	if factor < decay.Floor {
+		factor = decay.Floor
+	}
+	return weight * factor
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NormalizeEntityName folds case, punctuation and whitespace and drops a leading "the",
+// so "The Acme Corp." and "acme corp" compare equal
+func NormalizeEntityName(name string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		case unicode.IsSpace(r) || r == '-' || r == '_' || r == '/':
+			space = true
+		}
+		// Other punctuation is dropped without separating words: "O'Brien" is "obrien"
+	}
+	return strings.TrimPrefix(b.String(), "the ")
+}
+
+// EntityKey identifies the entity a node stands for: its type and normalized name. It is
+// empty for nodes whose name normalizes to nothing.
+func (n GraphNode) EntityKey() string {
+	normalized := NormalizeEntityName(n.EntityName)
+	if normalized == "" {
+		return ""
+	}
+	return n.EntityType + "\x00" + normalized
+}
+
+// RelationshipKey identifies the relationship an edge states: its endpoints and type
+func (e GraphEdge) RelationshipKey() string {
+	return e.SourceNodeID + "\x00" + e.TargetNodeID + "\x00" + e.RelationshipType
+}
+
+// RemapGraphEdges points edges at the nodes their endpoints were merged into, as given by
+// nodeIDs (old ID to stored ID), and drops the edges that become self-loops
+func RemapGraphEdges(edges []GraphEdge, nodeIDs map[string]string) []GraphEdge {
+	remapped := make([]GraphEdge, 0, len(edges))
+	for _, edge := range edges {
+		if id, ok := nodeIDs[edge.SourceNodeID]; ok {
+			edge.SourceNodeID = id
+		}
+		if id, ok := nodeIDs[edge.TargetNodeID]; ok {
+			edge.TargetNodeID = id
+		}
+		if edge.SourceNodeID == edge.TargetNodeID {
+			continue
+		}
+		remapped = append(remapped, edge)
+	}
+	return remapped
+}
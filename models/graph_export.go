@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// GraphExportFormat names a graph interchange format
+type GraphExportFormat string
+
+const (
+	GraphExportGraphML   GraphExportFormat = "graphml"
+	GraphExportGEXF      GraphExportFormat = "gexf"
+	GraphExportCytoscape GraphExportFormat = "cytoscape"
+)
+
+// GraphExportFilter selects the subgraph to export. Without a seed (NodeID, EntityName or
+// PageID) the whole graph is exported up to MaxNodes.
+type GraphExportFilter struct {
+	// NodeID seeds the subgraph with a single node
+	NodeID string `json:"node_id,omitempty"`
+	// EntityName seeds the subgraph with every node of that name (case-insensitive)
+	EntityName string `json:"entity_name,omitempty"`
+	// PageID seeds the subgraph with nodes extracted from the page chunk or its blocks
+	PageID string `json:"page_id,omitempty"`
+	// Depth is how many edges away from the seeds to expand, in either direction (default 1)
+	Depth int `json:"depth,omitempty"`
+	// EntityTypes keeps only nodes of these types; seeds are always kept
+	EntityTypes []string `json:"entity_types,omitempty"`
+	// RelationshipTypes keeps and follows only edges of these types
+	RelationshipTypes []string `json:"relationship_types,omitempty"`
+	// MaxNodes caps the export size (default 5000)
+	MaxNodes int `json:"max_nodes,omitempty"`
+	// MinConfidence keeps and follows only edges extracted with at least this confidence,
+	// and keeps only such nodes; seeds are always kept
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	// AsOf keeps and follows only edges that held at that time
+	AsOf *time.Time `json:"as_of,omitempty"`
+}
+
+// GraphExportStats describes a finished export
+type GraphExportStats struct {
+	Format    GraphExportFormat `json:"format"`
+	NodeCount int               `json:"node_count"`
+	EdgeCount int               `json:"edge_count"`
+	// Truncated is set when MaxNodes stopped the expansion early
+	Truncated bool `json:"truncated"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// GraphEvidence is the text supporting a relationship: a snippet from every chunk the
+// relationship between the two entity names was extracted from, most confident first
+type GraphEvidence struct {
+	EdgeID           string            `json:"edge_id"`
+	RelationshipType string            `json:"relationship_type"`
+	SourceEntity     string            `json:"source_entity"`
+	TargetEntity     string            `json:"target_entity"`
+	Snippets         []EvidenceSnippet `json:"snippets"`
+}
+
+// EvidenceSnippet is the span of a chunk's contents a relationship was extracted from.
+// Start and End are character offsets into the chunk's current contents.
+type EvidenceSnippet struct {
+	EdgeID     string  `json:"edge_id"`
+	ChunkID    string  `json:"chunk_id"`
+	Start      int     `json:"start"`
+	End        int     `json:"end"`
+	Text       string  `json:"text"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ExtractionConfidence is the node's confidence, read from its properties for nodes
+// stored before confidence had its own column, and 1 when unknown
+func (n GraphNode) ExtractionConfidence() float64 {
+	return extractionConfidence(n.Confidence, n.Properties)
+}
+
+// ExtractionConfidence is the edge's confidence, read from its properties for edges
+// stored before confidence had its own column, and 1 when unknown
+func (e GraphEdge) ExtractionConfidence() float64 {
+	return extractionConfidence(e.Confidence, e.Properties)
+}
+
+func extractionConfidence(confidence *float64, properties map[string]interface{}) float64 {
+	if confidence != nil {
+		return *confidence
+	}
+	if value, ok := properties["confidence"].(float64); ok {
+		return value
+	}
+	return 1
+}
@@ -0,0 +1,13 @@
+package models
+
+// GraphSyncResult reports how re-extracting a chunk changed its graph nodes
+type GraphSyncResult struct {
+	ChunkID string `json:"chunk_id"`
+	// KeptNodes are nodes whose entity is still mentioned; they keep their ID, edges and
+	// properties
+	KeptNodes    int `json:"kept_nodes"`
+	AddedNodes   int `json:"added_nodes"`
+	RemovedNodes int `json:"removed_nodes"`
+	AddedEdges   int `json:"added_edges"`
+	RemovedEdges int `json:"removed_edges"`
+}
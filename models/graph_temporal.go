@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// ValidAt reports whether the edge's relationship held at t
+func (e GraphEdge) ValidAt(t time.Time) bool {
+	if e.ValidFrom != nil && t.Before(*e.ValidFrom) {
+		return false
+	}
+	return e.ValidTo == nil || t.Before(*e.ValidTo)
+}
+
+// EdgeValidityRequest sets when an edge's relationship held; nil leaves that end unbounded
+type EdgeValidityRequest struct {
+	ValidFrom *time.Time `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to"`
+}
+
+// EdgeSupersedeRequest replaces a relationship with one to another target, such as a new
+// employer, from a point in time
+type EdgeSupersedeRequest struct {
+	// TargetNodeID is the node the relationship holds toward from At
+	TargetNodeID string `json:"target_node_id"`
+	// At is when the old relationship stopped and the new one started (default now)
+	At         *time.Time             `json:"at,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// EdgeSupersedeResult reports a superseded relationship
+type EdgeSupersedeResult struct {
+	// ClosedEdgeIDs are the edges stating the old relationship that held at the time,
+	// now ending then
+	ClosedEdgeIDs []string  `json:"closed_edge_ids"`
+	Replacement   GraphEdge `json:"replacement"`
+}
+
+// GraphConflictOptions selects the relationships checked for conflicts
+type GraphConflictOptions struct {
+	// RelationshipTypes to check instead of the configured exclusive relationships
+	RelationshipTypes []string `json:"relationship_types,omitempty"`
+	// AsOf checks only relationships holding at that time instead of any overlap
+	AsOf *time.Time `json:"as_of,omitempty"`
+	// Limit caps the conflicts returned (default 100)
+	Limit int `json:"limit,omitempty"`
+}
+
+// GraphEdgeConflict is an entity holding an exclusive relationship toward two targets at
+// once, such as working at two employers
+type GraphEdgeConflict struct {
+	RelationshipType string                  `json:"relationship_type"`
+	SourceEntity     string                  `json:"source_entity"`
+	Statements       [2]ConflictingStatement `json:"statements"`
+	// OverlapFrom and OverlapTo bound when both held; nil is unbounded
+	OverlapFrom *time.Time `json:"overlap_from,omitempty"`
+	OverlapTo   *time.Time `json:"overlap_to,omitempty"`
+}
+
+// ConflictingStatement is one side of a conflict: a target and the edges stating it
+type ConflictingStatement struct {
+	TargetEntity string   `json:"target_entity"`
+	EdgeIDs      []string `json:"edge_ids"`
+}
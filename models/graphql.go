@@ -0,0 +1,29 @@
+package models
+
+// GraphQLRequest is a GraphQL query posted to the gateway
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse is the result of a GraphQL query. Data is left out when the query could
+// not be executed at all; field errors leave the field null and are listed in Errors.
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError describes a query error, with the positions in the query and the response
+// path of the field it applies to
+type GraphQLError struct {
+	Message   string            `json:"message"`
+	Locations []GraphQLLocation `json:"locations,omitempty"`
+	Path      []interface{}     `json:"path,omitempty"`
+}
+
+// GraphQLLocation is a line and column of a query document
+type GraphQLLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
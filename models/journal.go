@@ -0,0 +1,43 @@
+package models
+
+// Metadata keys of journal pages. Dates are kept as YYYY-MM-DD, whatever the title format,
+// so ranges of days compare as strings.
+const (
+	JournalDateKey  = "journal_date"
+	JournalOwnerKey = "journal_owner"
+)
+
+// JournalDateLayout is the layout of journal dates in requests, responses and metadata
+const JournalDateLayout = "2006-01-02"
+
+// JournalDay is the journal page of one day
+type JournalDay struct {
+	Date   string `json:"date"`
+	PageID string `json:"page_id"`
+	Title  string `json:"title"`
+	// Created is set when the page was created by this request
+	Created    bool                 `json:"created,omitempty"`
+	EntryCount int                  `json:"entry_count"`
+	Entries    []UnifiedChunkRecord `json:"entries,omitempty"`
+}
+
+// JournalAppendRequest adds an entry to today's journal page
+type JournalAppendRequest struct {
+	Contents string                 `json:"contents"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// JournalRangeQuery selects the journal days between two dates, both included
+type JournalRangeQuery struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// IncludeEntries adds the top-level entries of each day
+	IncludeEntries bool `json:"include_entries,omitempty"`
+}
+
+// JournalRange lists the days of a range that have journal pages, oldest first
+type JournalRange struct {
+	From string       `json:"from"`
+	To   string       `json:"to"`
+	Days []JournalDay `json:"days"`
+}
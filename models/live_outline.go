@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// LiveMessageType names the kind of message exchanged on a live outline connection
+type LiveMessageType string
+
+const (
+	// LiveMessageOperation is sent by a client to change the outline
+	LiveMessageOperation LiveMessageType = "op"
+	// LiveMessagePresence is sent by a client to move its cursor and relayed to the others
+	LiveMessagePresence LiveMessageType = "presence"
+	// LiveMessageWelcome is the first message on a connection
+	LiveMessageWelcome LiveMessageType = "welcome"
+	// LiveMessageAck confirms that an operation was applied
+	LiveMessageAck LiveMessageType = "ack"
+	// LiveMessageReject tells the sender that an operation was not applied and should be
+	// rolled back locally
+	LiveMessageReject LiveMessageType = "reject"
+	// LiveMessageEvent carries a change made by another connection or outside the session
+	LiveMessageEvent LiveMessageType = "event"
+	// LiveMessageLeave announces that a connection left the page
+	LiveMessageLeave LiveMessageType = "leave"
+	// LiveMessageResync tells the client that messages were missed and the page should be
+	// reloaded
+	LiveMessageResync LiveMessageType = "resync"
+	// LiveMessageError reports a message the server could not understand
+	LiveMessageError LiveMessageType = "error"
+)
+
+// LiveOperationType names a change to the outline
+type LiveOperationType string
+
+const (
+	LiveOperationCreate LiveOperationType = "create"
+	LiveOperationUpdate LiveOperationType = "update"
+	LiveOperationMove   LiveOperationType = "move"
+	LiveOperationDelete LiveOperationType = "delete"
+)
+
+// LiveOperation is a change a client has already applied locally and asks the server to apply
+type LiveOperation struct {
+	// OpID is chosen by the client and echoed in the ack or reject
+	OpID string            `json:"op_id"`
+	Op   LiveOperationType `json:"op"`
+	// ChunkID is the chunk to change; on create it may be chosen by the client
+	ChunkID string `json:"chunk_id,omitempty"`
+	// Parent is the new parent on create and move; create defaults to the page
+	Parent   *string                `json:"parent,omitempty"`
+	Contents *string                `json:"contents,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// ExpectedVersion is the chunk version the change was based on. It is required for
+	// update and checked on move and delete when set.
+	ExpectedVersion int64 `json:"expected_version,omitempty"`
+}
+
+// LivePresence describes where a participant is in the page
+type LivePresence struct {
+	ConnectionID string `json:"connection_id"`
+	Name         string `json:"name,omitempty"`
+	// ChunkID is the block the participant is editing, if any
+	ChunkID string `json:"chunk_id,omitempty"`
+	// Offset is the caret position within the block
+	Offset    int       `json:"offset,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LiveClientMessage is a message from a client
+type LiveClientMessage struct {
+	Type      LiveMessageType `json:"type"`
+	Operation *LiveOperation  `json:"operation,omitempty"`
+	Presence  *LivePresence   `json:"presence,omitempty"`
+}
+
+// LiveServerMessage is a message to a client. Which fields are set depends on Type.
+type LiveServerMessage struct {
+	Type LiveMessageType `json:"type"`
+	// Sequence orders outline changes within a page; acks carry the sequence of their change
+	Sequence uint64 `json:"sequence,omitempty"`
+	// ConnectionID is the receiver's own ID on welcome and the leaving connection on leave
+	ConnectionID string `json:"connection_id,omitempty"`
+	OpID         string `json:"op_id,omitempty"`
+	// Event is the change an event or ack describes
+	Event LiveOperationType `json:"event,omitempty"`
+	// Origin is the connection that made the change, empty for changes made elsewhere
+	Origin  string              `json:"origin,omitempty"`
+	ChunkID string              `json:"chunk_id,omitempty"`
+	Chunk   *UnifiedChunkRecord `json:"chunk,omitempty"`
+	// ChunkIDs lists every chunk removed by a delete, descendants included
+	ChunkIDs     []string       `json:"chunk_ids,omitempty"`
+	Participants []LivePresence `json:"participants,omitempty"`
+	Presence     *LivePresence  `json:"presence,omitempty"`
+	// Code is conflict, not_found, invalid or failed on reject and error
+	Code  string `json:"code,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Live message error codes
+const (
+	LiveErrorConflict = "conflict"
+	LiveErrorNotFound = "not_found"
+	LiveErrorInvalid  = "invalid"
+	LiveErrorFailed   = "failed"
+)
@@ -17,6 +17,7 @@ const (
 	StorageTypeGoogleDrive StorageType = "google_drive"
 	StorageTypeGooglePhotos StorageType = "google_photos"
 	StorageTypeNAS      StorageType = "nas"
+	StorageTypeS3       StorageType = "s3"
 )
 
 // MediaMetadata 圖片元資料
@@ -37,6 +38,55 @@ type StorageResult struct {
 	UploadedAt  time.Time   `json:"uploaded_at"`
 }
 
+// StorageObject 物件儲存中的物件資訊
+type StorageObject struct {
+	Key          string      `json:"key"`
+	Size         int64       `json:"size"`
+	ContentType  string      `json:"content_type"`
+	ETag         string      `json:"etag,omitempty"`
+	URL          string      `json:"url,omitempty"` // 公開 URL；私有 bucket 需使用 SignedURL
+	StorageType  StorageType `json:"storage_type"`
+	LastModified time.Time   `json:"last_modified"`
+}
+
+// PutObjectOptions 上傳物件選項
+type PutObjectOptions struct {
+	ContentType string // 留空時自動偵測
+	Size        int64  // 已知大小（可選），用於提前檢查大小限制
+	Overwrite   bool   // 是否覆蓋同名物件
+}
+
+// AttachmentDedupReport 依內容雜湊去重的儲存用量報告
+type AttachmentDedupReport struct {
+	Objects           int64                  `json:"objects"`            // 物件鍵值數（參照數）
+	Blobs             int64                  `json:"blobs"`              // 實際儲存的不同內容數
+	LogicalBytes      int64                  `json:"logical_bytes"`      // 未去重時所需的容量
+	StoredBytes       int64                  `json:"stored_bytes"`       // 實際儲存的容量，含待回收的 blob
+	SavedBytes        int64                  `json:"saved_bytes"`        // 去重節省的容量
+	UnreferencedBlobs int64                  `json:"unreferenced_blobs"` // 沒有參照、等待垃圾回收的 blob
+	UnreferencedBytes int64                  `json:"unreferenced_bytes"`
+	TopDuplicates     []DuplicatedAttachment `json:"top_duplicates"`
+}
+
+// DuplicatedAttachment 被多個物件鍵值參照的 blob
+type DuplicatedAttachment struct {
+	Hash        string   `json:"hash"`
+	Size        int64    `json:"size"`
+	ContentType string   `json:"content_type"`
+	References  int      `json:"references"`
+	SavedBytes  int64    `json:"saved_bytes"`
+	SampleKeys  []string `json:"sample_keys"`
+}
+
+// AttachmentGCResult 垃圾回收結果
+type AttachmentGCResult struct {
+	DeletedBlobs int64         `json:"deleted_blobs"`
+	DeletedBytes int64         `json:"deleted_bytes"`
+	Failed       int           `json:"failed"`
+	DryRun       bool          `json:"dry_run"`
+	Duration     time.Duration `json:"duration"`
+}
+
 // MediaFile 掃描到的媒體檔案
 type MediaFile struct {
 	Path         string    `json:"path"`
@@ -108,12 +158,13 @@ type BatchProcessResult struct {
 type BatchProcessStatusType string
 
 const (
-	BatchStatusPending    BatchProcessStatusType = "pending"
-	BatchStatusProcessing BatchProcessStatusType = "processing"
-	BatchStatusCompleted  BatchProcessStatusType = "completed"
-	BatchStatusFailed     BatchProcessStatusType = "failed"
-	BatchStatusPaused     BatchProcessStatusType = "paused"
-	BatchStatusCancelled  BatchProcessStatusType = "cancelled"
+	BatchStatusPending     BatchProcessStatusType = "pending"
+	BatchStatusProcessing  BatchProcessStatusType = "processing"
+	BatchStatusCompleted   BatchProcessStatusType = "completed"
+	BatchStatusFailed      BatchProcessStatusType = "failed"
+	BatchStatusPaused      BatchProcessStatusType = "paused"
+	BatchStatusCancelled   BatchProcessStatusType = "cancelled"
+	BatchStatusInterrupted BatchProcessStatusType = "interrupted"
 )
 
 // BatchProcessStatus 批次處理狀態結構
@@ -128,6 +179,16 @@ type BatchProcessStatus struct {
 	Errors         []BatchError `json:"errors"`
 }
 
+// BatchCheckpoint 關機時中斷的批次任務，重新啟動後從尚未處理的檔案繼續
+type BatchCheckpoint struct {
+	BatchID        string              `json:"batch_id"`
+	Request        BatchProcessRequest `json:"request"` // Files 只包含尚未處理的檔案
+	ProcessedFiles int                 `json:"processed_files"`
+	FailedFiles    int                 `json:"failed_files"`
+	StartedAt      time.Time           `json:"started_at"`
+	CheckpointedAt time.Time           `json:"checkpointed_at"`
+}
+
 // BatchError 批次處理錯誤
 type BatchError struct {
 	Filename  string    `json:"filename"`
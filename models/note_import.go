@@ -0,0 +1,84 @@
+package models
+
+// NoteImportFormat names the note-taking app an import comes from
+type NoteImportFormat string
+
+const (
+	// NoteImportNotion is a Notion "Markdown & CSV" export
+	NoteImportNotion NoteImportFormat = "notion"
+	// NoteImportLogseq is a Logseq graph folder with pages/, journals/ and assets/
+	NoteImportLogseq NoteImportFormat = "logseq"
+)
+
+// NoteImportMode chooses what an import does with pages imported before
+type NoteImportMode string
+
+const (
+	// NoteImportCreate creates every page and block as new chunks
+	NoteImportCreate NoteImportMode = "create"
+	// NoteImportUpsert updates the chunks an earlier import of the same source created,
+	// matched by the export's IDs, and creates the rest
+	NoteImportUpsert NoteImportMode = "upsert"
+)
+
+// NoteImportOptions controls how an export is merged into the knowledge base
+type NoteImportOptions struct {
+	// Mode defaults to NoteImportCreate
+	Mode NoteImportMode `json:"mode,omitempty"`
+	// Source names the export, such as a Notion workspace, so repeated imports of it are
+	// matched up. It defaults to the format.
+	Source string `json:"source,omitempty"`
+	// DeleteMissing deletes chunks of earlier imports whose page or block is no longer in
+	// the export; otherwise they are kept
+	DeleteMissing bool `json:"delete_missing,omitempty"`
+	// Overwrite updates and deletes chunks edited since they were imported instead of
+	// reporting them as conflicts
+	Overwrite bool `json:"overwrite,omitempty"`
+}
+
+// NoteImportDiff summarizes what an upsert import changed
+type NoteImportDiff struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+	// Missing counts chunks of earlier imports no longer in the export that were kept
+	Missing int `json:"missing"`
+	// Conflicted counts chunks edited since they were imported that the import left alone
+	Conflicted int                  `json:"conflicted"`
+	Conflicts  []NoteImportConflict `json:"conflicts"`
+}
+
+// NoteImportConflict is a chunk edited since it was imported that the import would have
+// changed or deleted
+type NoteImportConflict struct {
+	ExternalID string `json:"external_id"`
+	ChunkID    string `json:"chunk_id"`
+	// Change is what the import skipped: update or delete
+	Change string `json:"change"`
+}
+
+// NoteImportResult describes a finished import from another note-taking app
+type NoteImportResult struct {
+	Format NoteImportFormat `json:"format"`
+	Mode   NoteImportMode   `json:"mode"`
+	Source string           `json:"source"`
+	// PageIDs are the imported pages, ordered by their path in the export
+	PageIDs []string `json:"page_ids"`
+	// Chunks counts the blocks created, without the pages
+	Chunks int `json:"chunks"`
+	// Links counts internal links turned into references
+	Links int `json:"links"`
+	// UnresolvedLinks counts links to pages or blocks found neither in the export nor in
+	// the knowledge base; their text is kept as it was
+	UnresolvedLinks int `json:"unresolved_links"`
+	// Assets counts the embedded files uploaded to object storage
+	Assets int `json:"assets"`
+	// TemplateInstances counts pages whose properties filled a template instance
+	TemplateInstances int `json:"template_instances"`
+	// Warnings lists what was imported incompletely, such as assets that could not be uploaded
+	Warnings []string `json:"warnings"`
+	// Diff reports the pages and blocks created, updated, unchanged and deleted by an
+	// upsert import
+	Diff *NoteImportDiff `json:"diff,omitempty"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// OutlineImportOptions places an outline imported from another outliner
+type OutlineImportOptions struct {
+	// ParentID imports the outline under an existing chunk instead of a new page
+	ParentID string `json:"parent_id,omitempty"`
+	// Title names the new page; defaults to the title of the imported document
+	Title string `json:"title,omitempty"`
+}
+
+// OutlineImportResult describes an imported outline
+type OutlineImportResult struct {
+	// PageID is the new page, or the page of the parent the outline was imported under
+	PageID string `json:"page_id,omitempty"`
+	// RootIDs are the chunks of the top-level items, in document order
+	RootIDs []string `json:"root_ids"`
+	// Chunks counts the chunks created for outline items, without the page and tags
+	Chunks int `json:"chunks"`
+	// Tags are the names of the tags applied to imported chunks
+	Tags []string `json:"tags"`
+	// CreatedTags are the tag names that had no tag chunk yet
+	CreatedTags []string `json:"created_tags"`
+}
+
+// OutlineExportStats describes a finished outline export
+type OutlineExportStats struct {
+	Title  string `json:"title"`
+	Chunks int    `json:"chunks"` // outline items written, without the page
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// PageACL restricts who may read and change a page and its blocks. Pages without an ACL are
+// open to every member of the workspace, as their role allows.
+type PageACL struct {
+	PageID string `json:"page_id"`
+	// Owner is the subject that may always read and manage the page
+	Owner string `json:"owner"`
+	// SharedUsers and SharedGroups may read the page, and write it if their role allows
+	SharedUsers  []string `json:"shared_users"`
+	SharedGroups []string `json:"shared_groups"`
+	// PublicRead lets every member of the workspace read the page
+	PublicRead bool      `json:"public_read"`
+	UpdatedBy  string    `json:"updated_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// PageACLRequest sets a page's ACL. An empty owner makes the caller the owner.
+type PageACLRequest struct {
+	Owner        string   `json:"owner,omitempty"`
+	SharedUsers  []string `json:"shared_users"`
+	SharedGroups []string `json:"shared_groups"`
+	PublicRead   bool     `json:"public_read"`
+}
+
+// PageAccessSource explains which rule granted or refused access to a page
+type PageAccessSource string
+
+const (
+	// PageAccessService is the gateway itself: background jobs, command line tools and
+	// requests when authentication is disabled
+	PageAccessService PageAccessSource = "service"
+	// PageAccessAdmin is a workspace admin, who may access every page
+	PageAccessAdmin PageAccessSource = "admin"
+	// PageAccessUnrestricted is a page without an ACL, open as the caller's role allows
+	PageAccessUnrestricted PageAccessSource = "unrestricted"
+	// PageAccessOwner is the page's owner
+	PageAccessOwner PageAccessSource = "owner"
+	// PageAccessSharedUser is a user the page is shared with
+	PageAccessSharedUser PageAccessSource = "shared_user"
+	// PageAccessSharedGroup is a member of a group the page is shared with
+	PageAccessSharedGroup PageAccessSource = "shared_group"
+	// PageAccessPublicRead is any workspace member reading a public page
+	PageAccessPublicRead PageAccessSource = "public_read"
+	// PageAccessDenied is a caller the restricted page is not shared with
+	PageAccessDenied PageAccessSource = "denied"
+)
+
+// EffectivePermission is what the caller may do with a chunk, resolved from its page's ACL
+// and the caller's role
+type EffectivePermission struct {
+	ChunkID string `json:"chunk_id"`
+	// PageID is the page whose ACL applies, empty for chunks outside pages
+	PageID     string           `json:"page_id,omitempty"`
+	Subject    string           `json:"subject,omitempty"`
+	Groups     []string         `json:"groups,omitempty"`
+	Restricted bool             `json:"restricted"`
+	Read       bool             `json:"read"`
+	Write      bool             `json:"write"`
+	Manage     bool             `json:"manage"`
+	Source     PageAccessSource `json:"source"`
+	// Group is the shared group that granted access, for PageAccessSharedGroup
+	Group string `json:"group,omitempty"`
+}
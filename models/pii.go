@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// PIIType names a kind of personal data found in chunk contents
+type PIIType string
+
+const (
+	PIITypeEmail      PIIType = "email"
+	PIITypePhone      PIIType = "phone"
+	PIITypeIDNumber   PIIType = "id_number"
+	PIITypeCreditCard PIIType = "credit_card"
+)
+
+// PIIPolicy says what happens to contents containing personal data
+type PIIPolicy string
+
+const (
+	// PIIPolicyRedact replaces each finding with a placeholder before storing
+	PIIPolicyRedact PIIPolicy = "redact"
+	// PIIPolicyFlag stores the contents unchanged and records the findings
+	PIIPolicyFlag PIIPolicy = "flag"
+	// PIIPolicyReject refuses to store the contents
+	PIIPolicyReject PIIPolicy = "reject"
+)
+
+// PIIMetadataKey is the chunk metadata key scan results are recorded under
+const PIIMetadataKey = "pii"
+
+// PIIFinding is one match of personal data. Start and End are byte offsets into the
+// stored contents, so for redacted contents they locate the placeholder. The matched
+// value itself is never recorded, only a masked hint.
+type PIIFinding struct {
+	Type   PIIType `json:"type"`
+	Start  int     `json:"start"`
+	End    int     `json:"end"`
+	Masked string  `json:"masked"`
+}
+
+// PIIScanRecord is recorded in chunk metadata when a scan finds personal data
+type PIIScanRecord struct {
+	Policy    PIIPolicy       `json:"policy"`
+	Workspace string          `json:"workspace"`
+	Findings  []PIIFinding    `json:"findings"`
+	Counts    map[PIIType]int `json:"counts"`
+	ScannedAt time.Time       `json:"scanned_at"`
+}
+
+// PIIComplianceEntry counts findings of one type recorded under one policy
+type PIIComplianceEntry struct {
+	Type     PIIType   `json:"type"`
+	Policy   PIIPolicy `json:"policy"`
+	Findings int       `json:"findings"`
+	Chunks   int       `json:"chunks"`
+}
+
+// PIIComplianceReport summarizes the personal data recorded in chunk metadata
+type PIIComplianceReport struct {
+	Workspace     string               `json:"workspace,omitempty"`
+	Since         *time.Time           `json:"since,omitempty"`
+	FlaggedChunks int                  `json:"flagged_chunks"`
+	Entries       []PIIComplianceEntry `json:"entries"`
+	GeneratedAt   time.Time            `json:"generated_at"`
+}
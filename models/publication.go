@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// PagePublication is a page served read-only, without authentication, at a signed URL
+type PagePublication struct {
+	PageID string `json:"page_id"`
+	// Slug is the signed token in the public URL; URL is the whole path, or an absolute
+	// URL when PUBLISH_BASE_URL is set
+	Slug         string     `json:"slug"`
+	URL          string     `json:"url"`
+	Workspace    string     `json:"workspace"`
+	PublishedBy  string     `json:"published_by,omitempty"`
+	PublishedAt  time.Time  `json:"published_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	ViewCount    int64      `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at,omitempty"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QueryPlan is the execution plan PostgreSQL reported for a slow statement
+type QueryPlan struct {
+	// Analyzed is true when the plan comes from EXPLAIN ANALYZE and carries actual row
+	// counts and timings; statements that write are only explained, never re-executed
+	Analyzed bool `json:"analyzed"`
+	// Plan is the raw EXPLAIN (FORMAT JSON) output
+	Plan          json.RawMessage `json:"plan"`
+	TotalCost     float64         `json:"total_cost"`
+	PlanningTime  time.Duration   `json:"planning_time,omitempty"`
+	ExecutionTime time.Duration   `json:"execution_time,omitempty"`
+	// SharedBlocksRead counts blocks read from disk rather than shared buffers
+	SharedBlocksRead int64 `json:"shared_blocks_read,omitempty"`
+	// SeqScans lists the sequential scans in the plan, the usual sign of a missing index
+	SeqScans   []PlanSeqScan `json:"seq_scans,omitempty"`
+	CapturedAt time.Time     `json:"captured_at"`
+}
+
+// PlanSeqScan is a sequential scan node of a query plan
+type PlanSeqScan struct {
+	Table  string `json:"table"`
+	Filter string `json:"filter,omitempty"`
+	// Columns are the columns the filter compares, candidates for an index
+	Columns     []string `json:"columns,omitempty"`
+	PlanRows    int64    `json:"plan_rows"`
+	ActualRows  int64    `json:"actual_rows,omitempty"`
+	RowsRemoved int64    `json:"rows_removed,omitempty"`
+}
@@ -0,0 +1,30 @@
+package models
+
+// QuerySuggestionSource says why a query was suggested
+type QuerySuggestionSource string
+
+const (
+	// QuerySuggestionPrefix completes the text typed so far
+	QuerySuggestionPrefix QuerySuggestionSource = "prefix"
+	// QuerySuggestionSimilar is a past query spelled similarly, e.g. when the input has a typo
+	QuerySuggestionSimilar QuerySuggestionSource = "similar"
+	// QuerySuggestionPopular is one of the workspace's most frequent queries
+	QuerySuggestionPopular QuerySuggestionSource = "popular"
+)
+
+// QuerySuggestion is a past search query offered to the user
+type QuerySuggestion struct {
+	Query       string                `json:"query"`
+	Source      QuerySuggestionSource `json:"source"`
+	Frequency   int64                 `json:"frequency"`
+	ResultCount int                   `json:"result_count"`
+	// Similarity is the trigram similarity to the input for similar suggestions
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+// QuerySuggestionResponse is returned by the suggestion endpoints
+type QuerySuggestionResponse struct {
+	Workspace   string            `json:"workspace"`
+	Input       string            `json:"input,omitempty"`
+	Suggestions []QuerySuggestion `json:"suggestions"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// AskRequest asks a question answered from the knowledge base
+type AskRequest struct {
+	Question       string   `json:"question"`
+	Limit          int      `json:"limit,omitempty"`
+	SemanticWeight *float64 `json:"semantic_weight,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	// ParentContext answers from the sections holding the retrieved chunks
+	ParentContext bool `json:"parent_context,omitempty"`
+}
+
+// AskResponse is a synthesized answer with chunk citations
+type AskResponse struct {
+	Question  string           `json:"question"`
+	Answer    string           `json:"answer"`
+	Sentences []AnswerSentence `json:"sentences"`
+	Citations []AnswerCitation `json:"citations"`
+	Sources   []AnswerCitation `json:"sources"`
+	Model     string           `json:"model,omitempty"`
+	Usage     *TokenUsage      `json:"usage,omitempty"`
+	Duration  time.Duration    `json:"duration"`
+}
+
+// AnswerSentence is one sentence of an answer and the chunks it cites
+type AnswerSentence struct {
+	Text      string           `json:"text"`
+	Citations []AnswerCitation `json:"citations"`
+}
+
+// AnswerCitation identifies a chunk used as answer context
+type AnswerCitation struct {
+	Index      int     `json:"index"`
+	ChunkID    string  `json:"chunk_id"`
+	Snippet    string  `json:"snippet"`
+	Similarity float64 `json:"similarity"`
+}
+
+// TokenUsage reports LLM token consumption
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+// ContextRequest asks for retrieved chunks packed into a token budget
+type ContextRequest struct {
+	Query          string   `json:"query"`
+	Limit          int      `json:"limit,omitempty"`
+	SemanticWeight *float64 `json:"semantic_weight,omitempty"`
+	MaxTokens      int      `json:"max_tokens,omitempty"`
+	// Order is "relevance" (default) or "document", which keeps blocks of the same text in
+	// their original sequence
+	Order string `json:"order,omitempty"`
+	// ParentContext returns the sections holding the retrieved chunks rather than the chunks
+	ParentContext bool `json:"parent_context,omitempty"`
+}
+
+// ContextBlock is one retrieved chunk placed in a context window
+type ContextBlock struct {
+	// Index numbers the block for citations, starting at 1
+	Index         int     `json:"index"`
+	ChunkID       string  `json:"chunk_id"`
+	TextID        string  `json:"text_id,omitempty"`
+	ParentChunkID *string `json:"parent_chunk_id,omitempty"`
+	Content       string  `json:"content"`
+	Tokens        int     `json:"tokens"`
+	Similarity    float64 `json:"similarity"`
+	// Truncated is true when the content was cut to fit the budget
+	Truncated bool `json:"truncated,omitempty"`
+	// Covers lists other retrieved chunks whose content this block already contains
+	Covers []string `json:"covers,omitempty"`
+	// ChildHits lists the retrieved chunks a parent context block was expanded from
+	ChildHits []ContextChildHit `json:"child_hits,omitempty"`
+}
+
+// ContextChildHit is a retrieved chunk within a parent context block
+type ContextChildHit struct {
+	ChunkID    string  `json:"chunk_id"`
+	Similarity float64 `json:"similarity"`
+}
+
+// AssembledContext is a token-bounded set of context blocks
+type AssembledContext struct {
+	Blocks      []ContextBlock `json:"blocks"`
+	TotalTokens int            `json:"total_tokens"`
+	MaxTokens   int            `json:"max_tokens"`
+	// Deduplicated counts results dropped because another block contains them
+	Deduplicated int `json:"deduplicated"`
+	// Omitted lists results left out for lack of budget
+	Omitted []string `json:"omitted,omitempty"`
+}
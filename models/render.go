@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// RenderFormat is the output format of a rendered page
+type RenderFormat string
+
+const (
+	// RenderHTML is an HTML fragment: the page title and its blocks as nested lists
+	RenderHTML RenderFormat = "html"
+	// RenderMarkdown is the page as nested Markdown bullets, with transclusions inlined
+	RenderMarkdown RenderFormat = "markdown"
+)
+
+// RenderedPage is a page rendered with its blocks, block references and embeds resolved
+type RenderedPage struct {
+	PageID  string       `json:"page_id"`
+	Title   string       `json:"title"`
+	Format  RenderFormat `json:"format"`
+	Content string       `json:"content"`
+	// Blocks counts the page's own blocks, Transclusions the referenced and embedded chunks
+	// shown in them
+	Blocks        int `json:"blocks"`
+	Transclusions int `json:"transclusions"`
+	// Unresolved counts references to chunks that are missing, unreadable or that would
+	// transclude themselves
+	Unresolved int `json:"unresolved"`
+	// ETag changes whenever a chunk the rendering shows changes
+	ETag       string    `json:"etag"`
+	Cached     bool      `json:"cached"`
+	RenderedAt time.Time `json:"rendered_at"`
+}
@@ -6,6 +6,7 @@ package models
 type CreateTemplateRequest struct {
 	TemplateName string   `json:"template_name"`
 	SlotNames    []string `json:"slot_names"`
+	Layout       string   `json:"layout,omitempty"` // format string with #slot placeholders used to render instances
 }
 
 // CreateInstanceRequest for creating template instances
@@ -29,6 +30,27 @@ type MoveChunkRequest struct {
 	NewIndentLevel int     `json:"new_indent_level"`
 }
 
+// InsertChunkAtRequest for creating a chunk at a position among its siblings
+type InsertChunkAtRequest struct {
+	CreateChunkRequest
+	AfterChunkID string `json:"after_chunk_id,omitempty"` // empty inserts the chunk first
+}
+
+// ReorderChildrenRequest for putting the children of a chunk in a new order
+type ReorderChildrenRequest struct {
+	ChildIDs []string `json:"child_ids"`
+}
+
+// CopySubtreeRequest for deep copying a chunk and its descendants
+type CopySubtreeRequest struct {
+	NewParentID *string `json:"new_parent_id"`
+}
+
+// BulkMoveRequest for reorganizing several chunks atomically
+type BulkMoveRequest struct {
+	Moves []ChunkMove `json:"moves"`
+}
+
 // BulkUpdateRequest for batch chunk updates
 type BulkUpdateRequest struct {
 	Updates []ChunkUpdate `json:"updates"`
@@ -104,6 +126,7 @@ type UpdateChunkRequest struct {
 	IndentLevel    *int                   `json:"indent_level,omitempty"`
 	SequenceNumber *int                   `json:"sequence_number,omitempty"`
 	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	Version        *int64                 `json:"version,omitempty"` // expected chunk version; defaults to the version just read
 }
 
 // TagSearchRequest for searching by tags
@@ -128,4 +151,61 @@ type HybridSearchRequest struct {
 type UpdateSlotValueRequest struct {
 	SlotName string `json:"slot_name"`
 	Value    string `json:"value"`
+}
+
+// UpdateTemplateSchemaRequest sets a template's slots. Slots are kept, added and
+// removed by name, and take the order of SlotNames.
+type UpdateTemplateSchemaRequest struct {
+	SlotNames []string `json:"slot_names"`
+	Layout    *string  `json:"layout,omitempty"` // replaces the template's layout when set
+	DryRun    bool     `json:"dry_run"`          // only report what the migration would change
+}
+
+// Slot filter operators
+const (
+	SlotFilterEquals   = "equals"
+	SlotFilterContains = "contains"
+	SlotFilterRange    = "range"
+)
+
+// SlotFilter is a predicate on one slot of a template instance. Equals and contains
+// compare text case-insensitively, and equals also matches numbers of equal value. Range
+// keeps values between From and To, inclusive; the bounds are numbers or dates and either
+// may be left empty. Values of another type never match a range.
+type SlotFilter struct {
+	Slot  string `json:"slot"`
+	Op    string `json:"op"`
+	Value string `json:"value,omitempty"`
+	From  string `json:"from,omitempty"`
+	To    string `json:"to,omitempty"`
+}
+
+// QueryInstancesRequest finds the instances of a template whose slot values match every
+// filter
+type QueryInstancesRequest struct {
+	Filters []SlotFilter `json:"filters"`
+	Limit   int          `json:"limit,omitempty"`
+}
+
+// Table columns that sort by the instance rather than a slot
+const (
+	TableSortName      = "_name"
+	TableSortCreatedAt = "_created_at"
+)
+
+// TableSort orders table rows by a slot column, or by TableSortName or TableSortCreatedAt
+type TableSort struct {
+	Column string `json:"column"`
+	Desc   bool   `json:"desc,omitempty"`
+}
+
+// TableViewRequest selects a page of a template's instances as table rows. Rows are
+// ordered by each sort in turn, then newest first.
+type TableViewRequest struct {
+	Filters []SlotFilter `json:"filters,omitempty"`
+	Sort    []TableSort  `json:"sort,omitempty"`
+	Limit   int          `json:"limit,omitempty"`
+	Offset  int          `json:"offset,omitempty"`
+	// AllRows returns every matching row, ignoring Limit and Offset, as exports do
+	AllRows bool `json:"all_rows,omitempty"`
 }
\ No newline at end of file
@@ -17,6 +17,85 @@ type TemplateInstance struct {
 	SlotValues map[string]*ChunkRecord `json:"slot_values"`
 }
 
+// Formats a template instance can be rendered to
+const (
+	RenderFormatText     = "text"
+	RenderFormatMarkdown = "markdown"
+	RenderFormatJSON     = "json"
+)
+
+// RenderedSlot is one slot of a rendered template instance
+type RenderedSlot struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// RenderedInstance is a template instance rendered with its template's layout
+type RenderedInstance struct {
+	InstanceChunkID string         `json:"instance_chunk_id"`
+	TemplateChunkID string         `json:"template_chunk_id"`
+	Template        string         `json:"template"`
+	Instance        string         `json:"instance"`
+	Slots           []RenderedSlot `json:"slots"` // in template slot order
+	Format          string         `json:"format"`
+	Content         string         `json:"content"` // the rendering; for JSON, the plain text rendering
+}
+
+// InstanceQueryResult lists the instances of a template matching a slot query, newest
+// first
+type InstanceQueryResult struct {
+	TemplateChunkID string             `json:"template_chunk_id"`
+	Filters         []SlotFilter       `json:"filters"`
+	Instances       []TemplateInstance `json:"instances"`
+}
+
+// Types of table columns, inferred from a slot's values
+const (
+	TableColumnText   = "text"
+	TableColumnNumber = "number"
+	TableColumnDate   = "date"
+)
+
+// TableColumn is a slot shown as a table column
+type TableColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TableRow is a template instance shown as a table row. Cells follow the columns: numbers
+// for number columns, the value as written for text and date columns, and null for
+// empty slots.
+type TableRow struct {
+	InstanceChunkID string        `json:"instance_chunk_id"`
+	Name            string        `json:"name"`
+	CreatedAt       time.Time     `json:"created_at"`
+	Cells           []interface{} `json:"cells"`
+}
+
+// TableView is a page of a template's instances as rows, with its slots as typed columns
+type TableView struct {
+	TemplateChunkID string        `json:"template_chunk_id"`
+	Template        string        `json:"template"`
+	Columns         []TableColumn `json:"columns"`
+	Rows            []TableRow    `json:"rows"`
+	Total           int           `json:"total"` // rows matching the filters
+	Offset          int           `json:"offset"`
+	Limit           int           `json:"limit"`
+}
+
+// TemplateSchemaMigration summarizes how a template's slot change was applied to its
+// instances
+type TemplateSchemaMigration struct {
+	TemplateChunkID    string   `json:"template_chunk_id"`
+	AddedSlots         []string `json:"added_slots"`
+	RemovedSlots       []string `json:"removed_slots"`
+	ReorderedSlots     []string `json:"reordered_slots"`      // kept slots whose position changed
+	Instances          int      `json:"instances"`            // instances of the template
+	CreatedSlotValues  int      `json:"created_slot_values"`  // empty values added for new or missing slots
+	ArchivedSlotValues int      `json:"archived_slot_values"` // values of removed slots kept aside on their instance
+	DryRun             bool     `json:"dry_run"`
+}
+
 // ChunkWithTags represents chunk with its associated tags
 type ChunkWithTags struct {
 	Chunk *ChunkRecord  `json:"chunk"`
@@ -47,12 +126,21 @@ type GraphQuery struct {
 	EntityName string `json:"entity_name"`
 	MaxDepth   int    `json:"max_depth"`
 	Limit      int    `json:"limit"`
+	// MinConfidence leaves out nodes and edges extracted with less confidence, and does
+	// not traverse through them
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	// AsOf leaves out edges that did not hold at that time, and does not traverse them
+	AsOf *time.Time `json:"as_of,omitempty"`
 }
 
 // GraphResult represents graph search results
 type GraphResult struct {
 	Nodes []GraphNode `json:"nodes"`
 	Edges []GraphEdge `json:"edges"`
+	// Truncated is set when a traversal stopped early, at its deadline or request budget,
+	// and returned what it had found so far
+	Truncated       bool   `json:"truncated,omitempty"`
+	TruncatedReason string `json:"truncated_reason,omitempty"`
 }
 
 // ProcessResult represents the result of text processing
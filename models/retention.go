@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// RetentionAction is what a retention rule does with the chunks or trash it selects
+type RetentionAction string
+
+const (
+	// RetentionArchive marks stale chunks archived in their metadata
+	RetentionArchive RetentionAction = "archive"
+	// RetentionDelete moves stale subtrees to the trash
+	RetentionDelete RetentionAction = "delete"
+	// RetentionPurgeTrash permanently removes trash entries older than the rule's age
+	RetentionPurgeTrash RetentionAction = "purge_trash"
+)
+
+// Valid reports whether a is a known retention action
+func (a RetentionAction) Valid() bool {
+	switch a {
+	case RetentionArchive, RetentionDelete, RetentionPurgeTrash:
+		return true
+	}
+	return false
+}
+
+// Metadata keys set on chunks archived by a retention rule
+const (
+	ArchivedMetadataKey     = "archived"
+	ArchivedAtMetadataKey   = "archived_at"
+	ArchivedRuleMetadataKey = "archived_by_rule"
+)
+
+// RetentionRule archives or deletes chunks not updated for MaxAgeDays, or purges trash
+// entries older than that. TagID and PageID narrow a chunk rule to chunks with the tag or
+// on the page.
+type RetentionRule struct {
+	ID         string          `json:"id"`
+	Workspace  string          `json:"workspace"`
+	Name       string          `json:"name"`
+	Action     RetentionAction `json:"action"`
+	MaxAgeDays int             `json:"max_age_days"`
+	TagID      *string         `json:"tag_id,omitempty"`
+	PageID     *string         `json:"page_id,omitempty"`
+	Enabled    bool            `json:"enabled"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+
+	LastRunAt       *time.Time `json:"last_run_at,omitempty"`
+	LastRunAffected int        `json:"last_run_affected"`
+	LastRunError    string     `json:"last_run_error,omitempty"`
+}
+
+// RetentionRuleRequest creates or replaces a retention rule in the caller's workspace
+type RetentionRuleRequest struct {
+	Name       string          `json:"name"`
+	Action     RetentionAction `json:"action"`
+	MaxAgeDays int             `json:"max_age_days"`
+	TagID      *string         `json:"tag_id,omitempty"`
+	PageID     *string         `json:"page_id,omitempty"`
+	// Enabled defaults to true
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// RetentionRuleReport is what one rule selected, and in a run, what it did
+type RetentionRuleReport struct {
+	Rule   RetentionRule `json:"rule"`
+	Cutoff time.Time     `json:"cutoff"`
+	// Matched counts the chunks, or for purge_trash the trashed chunks, older than Cutoff
+	Matched int `json:"matched"`
+	// Sample lists some of the matched chunk IDs, oldest first
+	Sample []string `json:"sample,omitempty"`
+	// Affected counts what a run archived, trashed or purged; runs are capped per rule,
+	// so the rest is left for the next run
+	Affected int    `json:"affected"`
+	Error    string `json:"error,omitempty"`
+}
+
+// RetentionReport covers the enabled rules of a workspace
+type RetentionReport struct {
+	Workspace   string                `json:"workspace"`
+	DryRun      bool                  `json:"dry_run"`
+	GeneratedAt time.Time             `json:"generated_at"`
+	Rules       []RetentionRuleReport `json:"rules"`
+}
@@ -0,0 +1,29 @@
+package models
+
+// Filter operators of a FilterExpression condition
+const (
+	FilterOpEq       = "eq"
+	FilterOpNe       = "ne"
+	FilterOpGt       = "gt"
+	FilterOpGte      = "gte"
+	FilterOpLt       = "lt"
+	FilterOpLte      = "lte"
+	FilterOpIn       = "in"
+	FilterOpNotIn    = "not_in"
+	FilterOpAll      = "all"      // tags: has every listed tag
+	FilterOpContains = "contains" // metadata arrays: holds the value
+	FilterOpExists   = "exists"
+)
+
+// FilterExpression is a query-time filter on chunk fields and metadata paths such as
+// metadata.source. A node is either a condition comparing Field with Value by Op, or a
+// group of And, Or or Not sub-expressions.
+type FilterExpression struct {
+	Field string      `json:"field,omitempty"`
+	Op    string      `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+
+	And []FilterExpression `json:"and,omitempty"`
+	Or  []FilterExpression `json:"or,omitempty"`
+	Not *FilterExpression  `json:"not,omitempty"`
+}
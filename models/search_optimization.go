@@ -15,16 +15,66 @@ type OptimizedSearchRequest struct {
 	IncludeMetadata bool                   `json:"include_metadata"`
 	UseCache        bool                   `json:"use_cache"`
 	PreloadHints    []string               `json:"preload_hints,omitempty"`
+	Workspace       string                 `json:"workspace,omitempty"`    // scopes the query log used for suggestions
+	Facets          []string               `json:"facets,omitempty"`       // tag, page, is_template, created_month
+	FacetLimit      int                    `json:"facet_limit,omitempty"`  // buckets per facet, default 10
+	Rerank          bool                   `json:"rerank,omitempty"`       // score the top vector results with a re-ranker
+	RerankModel     string                 `json:"rerank_model,omitempty"` // cross-encoder model name or "llm"
+	MMR             bool                   `json:"mmr,omitempty"`          // diversify results by maximal marginal relevance
+	MMRLambda       *float64               `json:"mmr_lambda,omitempty"`   // 1 ranks by relevance only, 0 by diversity only; default 0.5
+}
+
+// SimilarityFilter restricts a similarity search inside the vector query, so filtered
+// searches still return a full page of matches. Zero fields do not filter.
+type SimilarityFilter struct {
+	// TagIDs are tag chunk IDs every match must carry
+	TagIDs []string `json:"tags,omitempty"`
+	// PageID keeps the page and the chunks on it
+	PageID string `json:"page_id,omitempty"`
+	// CreatedAfter (inclusive) and CreatedBefore (exclusive) bound the chunks' creation time
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+}
+
+// IsZero reports whether the filter lets every chunk through
+func (f SimilarityFilter) IsZero() bool {
+	return len(f.TagIDs) == 0 && f.PageID == "" && f.CreatedAfter == nil && f.CreatedBefore == nil
 }
 
 // OptimizedSearchResponse represents an enhanced search response with optimization metadata
 type OptimizedSearchResponse struct {
-	Results       []OptimizedSearchResult `json:"results"`
-	TotalCount    int                     `json:"total_count"`
-	Duration      time.Duration           `json:"duration"`
-	CacheHit      bool                    `json:"cache_hit"`
-	Optimizations []string                `json:"optimizations"`
-	Metadata      SearchMetadata          `json:"metadata"`
+	Results       []OptimizedSearchResult  `json:"results"`
+	TotalCount    int                      `json:"total_count"`
+	Duration      time.Duration            `json:"duration"`
+	CacheHit      bool                     `json:"cache_hit"`
+	Optimizations []string                 `json:"optimizations"`
+	Metadata      SearchMetadata           `json:"metadata"`
+	Facets        map[string][]FacetBucket `json:"facets,omitempty"`
+}
+
+// Search facets that can be requested in OptimizedSearchRequest.Facets
+const (
+	SearchFacetTag          = "tag"
+	SearchFacetPage         = "page"
+	SearchFacetIsTemplate   = "is_template"
+	SearchFacetCreatedMonth = "created_month"
+)
+
+// FacetBucket counts the search results sharing one facet value
+type FacetBucket struct {
+	Value string `json:"value"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// ChunkFacetAttributes holds the chunk attributes search facets are counted over
+type ChunkFacetAttributes struct {
+	ChunkID     string
+	PageID      *string
+	PageTitle   string
+	IsTemplate  bool
+	CreatedTime time.Time
+	Tags        map[string]string // tag chunk ID to tag name
 }
 
 // OptimizedSearchResult represents a single search result with enhanced scoring
@@ -109,7 +159,7 @@ type FullTextRequest struct {
 // FullTextResponse represents an enhanced full-text search response
 type FullTextResponse struct {
 	Results       []FullTextResult `json:"results"`
-	Suggestions   []string         `json:"suggestions"`
+	Suggestions   []string         `json:"suggestions"` // similar past queries, see QuerySuggestionService
 	Duration      time.Duration    `json:"duration"`
 	TotalCount    int              `json:"total_count"`
 	CacheHit      bool             `json:"cache_hit"`
@@ -362,6 +412,10 @@ type DatabaseStats struct {
 	QueryCount           int64         `json:"query_count"`
 	SlowQueryCount       int64         `json:"slow_query_count"`
 	AverageQueryDuration time.Duration `json:"average_query_duration"`
+	WaitCount            int64         `json:"wait_count"`
+	WaitDuration         time.Duration `json:"wait_duration"`
+	MaxIdleClosed        int64         `json:"max_idle_closed"`
+	MaxLifetimeClosed    int64         `json:"max_lifetime_closed"`
 }
 
 // CacheStats represents cache performance statistics
@@ -462,6 +516,19 @@ type OptimizationAnalysisResult struct {
 	IndexSuggestions    []IndexSuggestion              `json:"index_suggestions"`
 	ConfigurationTuning []ConfigurationTuning          `json:"configuration_tuning"`
 	PerformanceMetrics  map[string]interface{}         `json:"performance_metrics"`
+	Profiles            []ProfileArtifact              `json:"profiles,omitempty"`
+}
+
+// ProfileArtifact is a pprof profile captured during a load step
+type ProfileArtifact struct {
+	Step      int           `json:"step"`
+	UserCount int           `json:"user_count"`
+	Kind      string        `json:"kind"` // cpu or heap
+	Path      string        `json:"path"`
+	Duration  time.Duration `json:"duration,omitempty"` // CPU sampling time
+	// ViewCommand opens the profile in the pprof web UI, which includes a flame graph view
+	ViewCommand string    `json:"view_command"`
+	CapturedAt  time.Time `json:"captured_at"`
 }
 
 // OptimizationRecommendation represents a performance optimization recommendation
@@ -485,6 +552,7 @@ type SlowQueryAnalysis struct {
 	AffectedTables         []string      `json:"affected_tables"`
 	OptimizationSuggestions []string      `json:"optimization_suggestions"`
 	Impact                 float64       `json:"impact"`
+	Plan                   *QueryPlan    `json:"plan,omitempty"` // captured when the monitor explains slow statements
 }
 
 // IndexSuggestion represents a database index optimization suggestion
@@ -536,6 +604,16 @@ type ResourceUtilizationResult struct {
 	GarbageCollection   GCStats                    `json:"garbage_collection"`
 	DatabaseConnections *DatabaseStats             `json:"database_connections"`
 	ResourceThresholds  map[string]interface{}     `json:"resource_thresholds"`
+	Container           *ContainerResourceStats    `json:"container,omitempty"`
+}
+
+// ContainerResourceStats holds the cgroup v2 limits and the usage measured against them.
+// PeakMemoryUsage and AverageCPUUsage are then container-wide rather than process-wide.
+type ContainerResourceStats struct {
+	MemoryLimit           uint64  `json:"memory_limit"`            // bytes; 0 when unlimited
+	PeakMemoryUtilization float64 `json:"peak_memory_utilization"` // percent of MemoryLimit
+	CPULimit              float64 `json:"cpu_limit"`               // cores; 0 when unlimited
+	MemoryLimitWarnings   int     `json:"memory_limit_warnings"`   // samples within 10% of MemoryLimit
 }
 
 // DiskIOStats represents disk I/O statistics
@@ -630,6 +708,16 @@ type HotDataPattern struct {
 	CacheWeight float64 `json:"cache_weight"`
 }
 
+// CacheWarmResult summarises one cache warming run
+type CacheWarmResult struct {
+	Patterns  int           `json:"patterns"`
+	Warmed    int           `json:"warmed"`
+	Failed    int           `json:"failed"`
+	Skipped   int           `json:"skipped"`
+	Duration  time.Duration `json:"duration"`
+	StartedAt time.Time     `json:"started_at"`
+}
+
 // IndexAnalysisResult represents database index analysis
 type IndexAnalysisResult struct {
 	CurrentIndexes    []CurrentIndex    `json:"current_indexes"`
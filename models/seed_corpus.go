@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// How seeded chunks get text embeddings
+const (
+	// SeedEmbeddingsNone leaves seeded chunks without embeddings
+	SeedEmbeddingsNone = "none"
+	// SeedEmbeddingsSynthetic stores generated vectors that cluster by topic and page, so
+	// semantic search can be benchmarked without an embedding provider
+	SeedEmbeddingsSynthetic = "synthetic"
+	// SeedEmbeddingsService embeds seeded chunks with the configured embedding provider
+	SeedEmbeddingsService = "service"
+)
+
+// SeedCorpusOptions describes a generated knowledge base. Zero pages, blocks per page, depth,
+// languages, embeddings and dimensions take the defaults; tags, templates and refs may be zero.
+type SeedCorpusOptions struct {
+	Pages         int      `json:"pages"`
+	BlocksPerPage int      `json:"blocks_per_page"` // average; pages vary from half to one and a half times it
+	MaxDepth      int      `json:"max_depth"`       // deepest block nesting below a page
+	Languages     []string `json:"languages"`       // en, zh, ja and es
+	Tags          int      `json:"tags"`
+	Templates     int      `json:"templates"` // every fifth page is an instance of one
+	// RefRate is the share of blocks referencing an earlier page or block
+	RefRate    float64 `json:"ref_rate"`
+	Embeddings string  `json:"embeddings"`
+	// Dimensions of synthetic embeddings
+	Dimensions int `json:"dimensions"`
+	// Seed makes the corpus reproducible; the same options generate the same chunks
+	Seed int64 `json:"seed"`
+}
+
+// SeedCorpusResult summarizes a generated knowledge base
+type SeedCorpusResult struct {
+	Pages             int            `json:"pages"`
+	Blocks            int            `json:"blocks"`
+	Tags              int            `json:"tags"`
+	Templates         int            `json:"templates"` // template chunks; their slots count as blocks
+	TemplateInstances int            `json:"template_instances"`
+	Refs              int            `json:"refs"`
+	Tagged            int            `json:"tagged"` // pages and blocks with at least one tag
+	Embedded          int            `json:"embedded"`
+	MaxDepth          int            `json:"max_depth"`
+	Languages         map[string]int `json:"languages"` // pages per language
+	Duration          time.Duration  `json:"duration"`
+}
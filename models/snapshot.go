@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// SnapshotFormatVersion is the archive format written by snapshot export
+const SnapshotFormatVersion = 1
+
+// Snapshot archive entries, in the order they are written
+const (
+	SnapshotManifestFile       = "manifest.json"
+	SnapshotChunksFile         = "chunks.jsonl"
+	SnapshotEmbeddingsFile     = "embeddings.jsonl"
+	SnapshotChunkTagsFile      = "chunk_tags.jsonl"
+	SnapshotChunkHierarchyFile = "chunk_hierarchy.jsonl"
+	SnapshotTagHierarchyFile   = "tag_hierarchy.jsonl"
+	SnapshotChunkRefsFile      = "chunk_refs.jsonl"
+)
+
+// SnapshotManifest describes a snapshot archive
+type SnapshotManifest struct {
+	FormatVersion int            `json:"format_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Since         *time.Time     `json:"since,omitempty"` // set for incremental snapshots
+	Counts        map[string]int `json:"counts"`          // records per archive file
+	Pages         int            `json:"pages"`
+	Templates     int            `json:"templates"`
+}
+
+// Incremental reports whether the snapshot only holds changes since a timestamp
+func (m *SnapshotManifest) Incremental() bool {
+	return m.Since != nil
+}
+
+// SnapshotEmbedding is a chunk vector stored in a snapshot archive
+type SnapshotEmbedding struct {
+	ChunkID        string                 `json:"chunk_id"`
+	Vector         []float64              `json:"vector"`
+	VectorType     *string                `json:"vector_type,omitempty"`
+	VectorModel    *string                `json:"vector_model,omitempty"`
+	VectorMetadata map[string]interface{} `json:"vector_metadata,omitempty"`
+}
+
+// SnapshotExportOptions controls snapshot export
+type SnapshotExportOptions struct {
+	// Since limits the export to chunks updated at or after this time
+	Since *time.Time
+}
+
+// SnapshotImportResult reports what a snapshot import restored
+type SnapshotImportResult struct {
+	Manifest *SnapshotManifest `json:"manifest"`
+	Restored map[string]int    `json:"restored"` // records per archive file
+	Duration time.Duration     `json:"duration"`
+}
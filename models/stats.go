@@ -0,0 +1,78 @@
+package models
+
+import "time"
+
+// Growth intervals of chunk statistics
+const (
+	StatsIntervalDay   = "day"
+	StatsIntervalWeek  = "week" // weeks start on Monday
+	StatsIntervalMonth = "month"
+)
+
+// Sources of chunk statistics
+const (
+	StatsSourceSnapshot = "snapshot"
+	StatsSourceLive     = "live"
+)
+
+// StatsOptions controls a statistics request. Zero values use the defaults.
+type StatsOptions struct {
+	// Interval is the growth bucket size: day (default), week or month
+	Interval string `json:"interval,omitempty"`
+	// Periods is how many growth buckets to return, ending with the current one (default 30)
+	Periods int `json:"periods,omitempty"`
+	// Top is the length of the most-linked and most-used tag lists (default 10)
+	Top int `json:"top,omitempty"`
+	// Fresh computes the statistics live instead of reading today's snapshot
+	Fresh bool `json:"fresh,omitempty"`
+}
+
+// ChunkTypeCounts counts chunks by type. Blocks are chunks that are not pages, tags,
+// templates or slots.
+type ChunkTypeCounts struct {
+	Total     int `json:"total"`
+	Pages     int `json:"pages"`
+	Tags      int `json:"tags"`
+	Templates int `json:"templates"`
+	Slots     int `json:"slots"`
+	Blocks    int `json:"blocks"`
+}
+
+// GrowthBucket counts the chunks created in the period starting at Period. Total is the
+// number of chunks created up to the end of the period that still exist.
+type GrowthBucket struct {
+	Period  time.Time `json:"period"`
+	Created int       `json:"created"`
+	Total   int       `json:"total"`
+}
+
+// ChunkUsage is a chunk ranked by how often it is referenced or used as a tag
+type ChunkUsage struct {
+	ChunkID  string `json:"chunk_id"`
+	Contents string `json:"contents,omitempty"` // snippet; empty for encrypted chunks
+	Count    int    `json:"count"`
+}
+
+// ChunkStats summarizes the knowledge base for dashboards
+type ChunkStats struct {
+	Counts ChunkTypeCounts `json:"counts"`
+	// AverageLength is the mean number of characters of unencrypted chunk contents
+	AverageLength float64 `json:"average_length"`
+	// OrphanChunks counts blocks with neither a parent nor a page
+	OrphanChunks int            `json:"orphan_chunks"`
+	Interval     string         `json:"interval"`
+	Growth       []GrowthBucket `json:"growth"`
+	MostLinked   []ChunkUsage   `json:"most_linked"`
+	MostUsedTags []ChunkUsage   `json:"most_used_tags"`
+	Source       string         `json:"source"`
+	ComputedAt   time.Time      `json:"computed_at"`
+}
+
+// StatsSnapshot is the headline numbers of one daily statistics snapshot
+type StatsSnapshot struct {
+	Date          time.Time       `json:"date"`
+	Counts        ChunkTypeCounts `json:"counts"`
+	AverageLength float64         `json:"average_length"`
+	OrphanChunks  int             `json:"orphan_chunks"`
+	ComputedAt    time.Time       `json:"computed_at"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Kinds of chunk summaries
+const (
+	// SummaryKindChunk summarizes a chunk without children from its contents
+	SummaryKindChunk = "chunk"
+	// SummaryKindRollup summarizes a page or block from its contents and the summaries of
+	// its children
+	SummaryKindRollup = "rollup"
+)
+
+// SummaryOptions controls how a summary is produced
+type SummaryOptions struct {
+	// Refresh regenerates the chunk's own summary even if the cached one is current;
+	// summaries of its descendants are still reused
+	Refresh bool `json:"refresh,omitempty"`
+}
+
+// ChunkSummary is the summary of a chunk, or of a page or block with everything below it
+type ChunkSummary struct {
+	ChunkID    string `json:"chunk_id"`
+	Kind       string `json:"kind"`
+	Summary    string `json:"summary"`
+	Model      string `json:"model,omitempty"` // empty when the text was short enough to be its own summary
+	SourceHash string `json:"source_hash"`
+	// Children and Descendants count the blocks rolled up into the summary
+	Children    int           `json:"children"`
+	Descendants int           `json:"descendants"`
+	Cached      bool          `json:"cached"`
+	Generated   int           `json:"generated"` // completions made for this request, including descendants
+	GeneratedAt time.Time     `json:"generated_at"`
+	Duration    time.Duration `json:"duration"`
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SyncOperationType names a change pushed by an offline client
+type SyncOperationType string
+
+const (
+	SyncOperationCreate SyncOperationType = "create"
+	SyncOperationUpdate SyncOperationType = "update"
+	SyncOperationDelete SyncOperationType = "delete"
+)
+
+// SyncOperationStatus tells a client what became of one pushed operation
+type SyncOperationStatus string
+
+const (
+	// SyncStatusApplied means every field was written as sent
+	SyncStatusApplied SyncOperationStatus = "applied"
+	// SyncStatusMerged means the operation was merged with concurrent server changes to
+	// other fields
+	SyncStatusMerged SyncOperationStatus = "merged"
+	// SyncStatusConflict means some fields were also changed on the server; the later
+	// write won and the other value was kept in a conflict record
+	SyncStatusConflict SyncOperationStatus = "conflict"
+	// SyncStatusDuplicate means the operation was applied by an earlier push
+	SyncStatusDuplicate SyncOperationStatus = "duplicate"
+	// SyncStatusRejected means the operation could not be applied at all
+	SyncStatusRejected SyncOperationStatus = "rejected"
+)
+
+// SyncChange is the latest change to one chunk after a pull cursor
+type SyncChange struct {
+	// Sequence is the log position of the change; pulls resume after it
+	Sequence int64  `json:"sequence"`
+	ChunkID  string `json:"chunk_id"`
+	// Deleted is true when the chunk no longer exists
+	Deleted bool `json:"deleted"`
+	// Fields lists every field changed after the cursor
+	Fields    []string  `json:"fields,omitempty"`
+	Version   int64     `json:"version"`
+	ChangedAt time.Time `json:"changed_at"`
+	// Chunk is the chunk's current state, unless it was deleted
+	Chunk *UnifiedChunkRecord `json:"chunk,omitempty"`
+}
+
+// SyncPullResult is one page of changes after a cursor
+type SyncPullResult struct {
+	Changes []SyncChange `json:"changes"`
+	// Cursor is passed to the next pull
+	Cursor  int64 `json:"cursor"`
+	HasMore bool  `json:"has_more"`
+}
+
+// SyncOperation is a change a client made while offline
+type SyncOperation struct {
+	// OpID is unique per client, so that retried pushes are applied once
+	OpID    string            `json:"op_id"`
+	Op      SyncOperationType `json:"op"`
+	ChunkID string            `json:"chunk_id"`
+	// BaseVersion is the chunk version the client last saw; zero for creates
+	BaseVersion int64 `json:"base_version"`
+	// Timestamp is when the client made the change; it decides which of two concurrent
+	// writes to the same field wins
+	Timestamp time.Time `json:"timestamp"`
+	// Fields maps chunk field names (contents, parent, page, is_page, is_tag, is_template,
+	// is_slot, ref, tags, metadata) to their new values
+	Fields map[string]json.RawMessage `json:"fields,omitempty"`
+}
+
+// SyncPushRequest carries a client's offline operations in the order they were made
+type SyncPushRequest struct {
+	ClientID   string          `json:"client_id"`
+	Operations []SyncOperation `json:"operations"`
+}
+
+// SyncOperationResult reports the outcome of one pushed operation
+type SyncOperationResult struct {
+	OpID    string              `json:"op_id"`
+	ChunkID string              `json:"chunk_id"`
+	Status  SyncOperationStatus `json:"status"`
+	// Version is the chunk version after the operation; zero after deletes and rejects
+	Version     int64    `json:"version,omitempty"`
+	ConflictIDs []string `json:"conflict_ids,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// SyncPushResult reports the outcome of a push
+type SyncPushResult struct {
+	Results []SyncOperationResult `json:"results"`
+	// Cursor is the log position after the push. Clients pull from their previous cursor
+	// to receive the changes they have not seen.
+	Cursor int64 `json:"cursor"`
+}
+
+// SyncConflict records a value that lost a last-writer-wins merge
+type SyncConflict struct {
+	ConflictID string `json:"conflict_id"`
+	ChunkID    string `json:"chunk_id"`
+	// Field is the conflicting field, or "*" when a whole operation lost, e.g. an edit to
+	// a chunk deleted on the server
+	Field string `json:"field"`
+	// Winner is "server" or "client"
+	Winner         string          `json:"winner"`
+	KeptValue      json.RawMessage `json:"kept_value,omitempty"`
+	DiscardedValue json.RawMessage `json:"discarded_value,omitempty"`
+	BaseVersion    int64           `json:"base_version"`
+	ClientID       string          `json:"client_id"`
+	OpID           string          `json:"op_id"`
+	// Status is "open" until the conflict is resolved
+	Status     string     `json:"status"`
+	Resolution string     `json:"resolution,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+// SyncConflictResolution settles a conflict. Use is "kept" to confirm the merge,
+// "discarded" to restore the losing value, or "value" to write Value instead.
+type SyncConflictResolution struct {
+	Use   string          `json:"use"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
@@ -0,0 +1,85 @@
+package models
+
+import "time"
+
+// TaskStatus is the marker a task chunk starts with
+type TaskStatus string
+
+const (
+	TaskStatusTodo     TaskStatus = "TODO"
+	TaskStatusDoing    TaskStatus = "DOING"
+	TaskStatusNow      TaskStatus = "NOW"
+	TaskStatusLater    TaskStatus = "LATER"
+	TaskStatusWaiting  TaskStatus = "WAITING"
+	TaskStatusDone     TaskStatus = "DONE"
+	TaskStatusCanceled TaskStatus = "CANCELED"
+)
+
+// Open reports whether the task still has to be done
+func (s TaskStatus) Open() bool {
+	return s != TaskStatusDone && s != TaskStatusCanceled
+}
+
+// Task is a task written in a chunk's contents
+type Task struct {
+	ChunkID string     `json:"chunk_id"`
+	Page    *string    `json:"page,omitempty"`
+	Status  TaskStatus `json:"status"`
+	// Title is the first line of the chunk without its marker, priority and dates
+	Title       string     `json:"title"`
+	Tags        []string   `json:"tags,omitempty"`
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	DeadlineAt  *time.Time `json:"deadline_at,omitempty"`
+	// DueAt is the deadline, or the scheduled time when the task has no deadline
+	DueAt *time.Time `json:"due_at,omitempty"`
+	// AllDay is set when the due date has no time of day
+	AllDay bool `json:"all_day"`
+	// Repeat is the repeater of the due date, such as "+1w" or ".+1d"
+	Repeat    string    `json:"repeat,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TaskView selects which tasks a query lists
+type TaskView string
+
+const (
+	// TaskViewOpen lists every open task, dated ones first
+	TaskViewOpen TaskView = "open"
+	// TaskViewOverdue lists open tasks due before today
+	TaskViewOverdue TaskView = "overdue"
+	// TaskViewToday lists open tasks due today
+	TaskViewToday TaskView = "today"
+	// TaskViewDue lists overdue tasks and tasks due today
+	TaskViewDue TaskView = "due"
+)
+
+// TaskQuery selects tasks
+type TaskQuery struct {
+	View TaskView `json:"view,omitempty"`
+	// Tag keeps tasks carrying a tag, given by name or tag chunk ID
+	Tag   string `json:"tag,omitempty"`
+	Limit int    `json:"limit,omitempty"`
+}
+
+// TaskList is the result of a task query, ordered by due date
+type TaskList struct {
+	View  TaskView `json:"view"`
+	Tag   string   `json:"tag,omitempty"`
+	Tasks []Task   `json:"tasks"`
+}
+
+// TaskReindexResult reports a rebuild of the task index
+type TaskReindexResult struct {
+	Indexed int `json:"indexed"`
+	Removed int `json:"removed"`
+}
+
+// TaskReminderEvent is the event of reminder webhooks
+const TaskReminderEvent = "task.due"
+
+// TaskReminder is the body posted to the task webhook when a task falls due
+type TaskReminder struct {
+	Event  string    `json:"event"`
+	Task   Task      `json:"task"`
+	SentAt time.Time `json:"sent_at"`
+}
@@ -27,9 +27,11 @@ type ChunkRecord struct {
 	SlotValue       *string                `json:"slot_value" db:"slot_value"`
 	IndentLevel     int                    `json:"indent_level" db:"indent_level"`
 	SequenceNumber  *int                   `json:"sequence_number" db:"sequence_number"`
+	SortKey         string                 `json:"sort_key,omitempty" db:"sort_key"`
 	Metadata        map[string]interface{} `json:"metadata" db:"metadata"`
 	CreatedAt       time.Time              `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at" db:"updated_at"`
+	Lock            *ChunkLock             `json:"lock,omitempty" db:"-"`
 }
 
 // ChunkTag represents the relationship between chunks and tags
@@ -65,6 +67,12 @@ type GraphNode struct {
 	EntityType string                 `json:"entity_type" db:"entity_type"`
 	Properties map[string]interface{} `json:"properties" db:"properties"`
 	CreatedAt  time.Time              `json:"created_at" db:"created_at"`
+	// SourceStart and SourceEnd are the character offsets in the chunk's contents of the
+	// mention the node was extracted from; nil when the mention could not be found
+	SourceStart *int `json:"source_start,omitempty" db:"source_start"`
+	SourceEnd   *int `json:"source_end,omitempty" db:"source_end"`
+	// Confidence is how sure extraction was of the entity, from 0 to 1
+	Confidence *float64 `json:"confidence,omitempty" db:"confidence"`
 }
 
 // GraphEdge represents relationships in knowledge graph
@@ -75,6 +83,22 @@ type GraphEdge struct {
 	RelationshipType string                 `json:"relationship_type" db:"relationship_type"`
 	Properties       map[string]interface{} `json:"properties" db:"properties"`
 	CreatedAt        time.Time              `json:"created_at" db:"created_at"`
+	// Weight is the edge's strength from how often its relationship was seen; zero is
+	// read as 1 for edges stored before weighting
+	Weight          float64    `json:"weight,omitempty" db:"weight"`
+	OccurrenceCount int        `json:"occurrence_count,omitempty" db:"occurrence_count"`
+	LastSeenAt      *time.Time `json:"last_seen_at,omitempty" db:"last_seen_at"`
+	// SourceChunkID, SourceStart and SourceEnd locate the text the relationship was
+	// extracted from, as character offsets in the chunk's contents
+	SourceChunkID *string `json:"source_chunk_id,omitempty" db:"source_chunk_id"`
+	SourceStart   *int    `json:"source_start,omitempty" db:"source_start"`
+	SourceEnd     *int    `json:"source_end,omitempty" db:"source_end"`
+	// Confidence is how sure extraction was of the relationship, from 0 to 1
+	Confidence *float64 `json:"confidence,omitempty" db:"confidence"`
+	// ValidFrom and ValidTo bound when the relationship held, ValidTo exclusive; nil is
+	// unbounded. Superseded relationships are closed rather than deleted.
+	ValidFrom *time.Time `json:"valid_from,omitempty" db:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty" db:"valid_to"`
 }
 
 // Common errors
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -17,12 +18,16 @@ type UnifiedChunkRecord struct {
 	Ref            *string                `json:"ref" db:"ref"`
 	Tags           []string               `json:"tags" db:"tags"`
 	Metadata       map[string]interface{} `json:"metadata" db:"metadata"`
+	Lang           string                 `json:"lang,omitempty" db:"lang"`
 	Vector         []float64              `json:"vector,omitempty" db:"vector"`
 	VectorType     *string                `json:"vector_type,omitempty" db:"vector_type"`
 	VectorModel    *string                `json:"vector_model,omitempty" db:"vector_model"`
 	VectorMetadata map[string]interface{} `json:"vector_metadata,omitempty" db:"vector_metadata"`
+	Version        int64                  `json:"version" db:"version"`
 	CreatedTime    time.Time              `json:"created_time" db:"created_time"`
 	LastUpdated    time.Time              `json:"last_updated" db:"last_updated"`
+	// Lock is the live lock covering the chunk, set on single-chunk reads when locks are enabled
+	Lock           *ChunkLock             `json:"lock,omitempty" db:"-"`
 }
 
 // ChunkTagRelation represents the many-to-many relationship between chunks and tags
@@ -46,9 +51,11 @@ type SearchCacheEntry struct {
 	QueryParams  map[string]interface{} `json:"query_params" db:"query_params"`
 	ChunkIDs     []string               `json:"chunk_ids" db:"chunk_ids"`
 	ResultCount  int                    `json:"result_count" db:"result_count"`
+	Payload      json.RawMessage        `json:"payload,omitempty" db:"payload"`
 	CreatedAt    time.Time              `json:"created_at" db:"created_at"`
 	ExpiresAt    time.Time              `json:"expires_at" db:"expires_at"`
 	HitCount     int                    `json:"hit_count" db:"hit_count"`
+	Stale        bool                   `json:"stale" db:"-"`
 }
 
 // UnifiedChunkWithTags represents a unified chunk with its associated tags
@@ -83,6 +90,44 @@ type BatchUpdateRequest struct {
 	Chunks []UnifiedChunkRecord `json:"chunks"`
 }
 
+// ChunkPatch is a partial chunk update applied only if the chunk is still at ExpectedVersion.
+// Nil fields are left unchanged; an empty Parent or Page clears it, and Metadata keys are
+// merged into the existing metadata. MetadataOps are applied after Metadata, in order.
+type ChunkPatch struct {
+	ExpectedVersion int64                  `json:"expected_version"`
+	Contents        *string                `json:"contents,omitempty"`
+	Parent          *string                `json:"parent,omitempty"`
+	Page            *string                `json:"page,omitempty"`
+	IsPage          *bool                  `json:"is_page,omitempty"`
+	IsTag           *bool                  `json:"is_tag,omitempty"`
+	IsTemplate      *bool                  `json:"is_template,omitempty"`
+	IsSlot          *bool                  `json:"is_slot,omitempty"`
+	Ref             *string                `json:"ref,omitempty"`
+	Tags            *[]string              `json:"tags,omitempty"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	MetadataOps     []MetadataOp           `json:"metadata_ops,omitempty"`
+}
+
+// MetadataPatchRequest changes the metadata of a chunk still at ExpectedVersion
+type MetadataPatchRequest struct {
+	ExpectedVersion int64        `json:"expected_version"`
+	Ops             []MetadataOp `json:"ops"`
+}
+
+// Metadata operations
+const (
+	MetadataOpSet    = "set"    // stores Value at Path, creating missing parent objects
+	MetadataOpDelete = "delete" // removes the key at Path
+	MetadataOpMerge  = "merge"  // merges the keys of the object Value into the object at Path
+)
+
+// MetadataOp changes chunk metadata at a path of nested object keys
+type MetadataOp struct {
+	Op    string      `json:"op"`
+	Path  []string    `json:"path"`
+	Value interface{} `json:"value"`
+}
+
 // SearchQuery represents a search query with filters
 type SearchQuery struct {
 	Content     string                 `json:"content,omitempty"`
@@ -95,6 +140,8 @@ type SearchQuery struct {
 	Parent      *string                `json:"parent,omitempty"`
 	Page        *string                `json:"page,omitempty"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Filter      *FilterExpression      `json:"filter,omitempty"`   // typed filters on fields and metadata paths
+	Language    string                 `json:"language,omitempty"` // query language: zh, en or und; detected when empty
 	Limit       int                    `json:"limit,omitempty"`
 	Offset      int                    `json:"offset,omitempty"`
 }
@@ -240,4 +287,167 @@ func (c *UnifiedChunkRecord) GetVectorModel() VectorModel {
 		return ""
 	}
 	return VectorModel(*c.VectorModel)
-}
\ No newline at end of file
+}
+
+// ChunkRefRelation represents a resolved reference from one chunk's Ref field to another chunk
+type ChunkRefRelation struct {
+	SourceChunkID string    `json:"source_chunk_id" db:"source_chunk_id"`
+	TargetChunkID string    `json:"target_chunk_id" db:"target_chunk_id"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// BrokenReference describes a reference whose target chunk no longer exists
+type BrokenReference struct {
+	SourceChunkID   string    `json:"source_chunk_id"`
+	SourceContents  string    `json:"source_contents"`
+	MissingChunkID  string    `json:"missing_chunk_id"`
+	ReferencedSince time.Time `json:"referenced_since"`
+}
+
+// TagHierarchyRelation represents a row in the tag closure table
+type TagHierarchyRelation struct {
+	AncestorTagID   string `json:"ancestor_tag_id" db:"ancestor_tag_id"`
+	DescendantTagID string `json:"descendant_tag_id" db:"descendant_tag_id"`
+	Depth           int    `json:"depth" db:"depth"`
+}
+
+// TagQueryOptions controls how tag-based chunk lookups are expanded
+type TagQueryOptions struct {
+	IncludeDescendants bool `json:"include_descendants"`
+	MaxDepth           int  `json:"max_depth,omitempty"` // 0 means unlimited
+}
+
+// TagRollup reports chunk counts for a tag and its descendant tags
+type TagRollup struct {
+	TagChunkID  string      `json:"tag_chunk_id"`
+	TagContent  string      `json:"tag_content"`
+	ParentTagID *string     `json:"parent_tag_id,omitempty"`
+	Depth       int         `json:"depth"`
+	DirectCount int         `json:"direct_count"` // chunks tagged with exactly this tag
+	RollupCount int         `json:"rollup_count"` // distinct chunks tagged with this tag or any descendant
+	Children    []TagRollup `json:"children,omitempty"`
+}
+
+// TagMergeResult summarizes a tag merge
+type TagMergeResult struct {
+	TargetTagID    string   `json:"target_tag_id"`
+	MergedTagIDs   []string `json:"merged_tag_ids"`
+	AffectedChunks int      `json:"affected_chunks"` // chunks whose tags array was rewritten
+	ReparentedTags int      `json:"reparented_tags"` // child tags moved under the target
+}
+
+// TagSelector picks the chunks of a bulk tag assignment. Exactly one of Query, Tags and
+// ChunkIDs is set.
+type TagSelector struct {
+	Query    *SearchQuery `json:"query,omitempty"`     // chunks matching a search; Limit caps the total
+	Tags     []string     `json:"tags,omitempty"`      // chunks carrying these tags
+	TagLogic string       `json:"tag_logic,omitempty"` // "AND" or "OR" (default) for Tags
+	ChunkIDs []string     `json:"chunk_ids,omitempty"`
+}
+
+// BulkTagRequest adds tags to every chunk a selector picks
+type BulkTagRequest struct {
+	Selector TagSelector `json:"selector"`
+	TagIDs   []string    `json:"tag_ids"`
+}
+
+// BulkTagProgress is reported after each batch of a bulk tag assignment
+type BulkTagProgress struct {
+	Processed int `json:"processed"` // chunks in the batches applied so far
+	Total     int `json:"total"`
+	Tagged    int `json:"tagged"`
+}
+
+// BulkTagResult summarizes a bulk tag assignment
+type BulkTagResult struct {
+	TagIDs   []string `json:"tag_ids"`
+	Selected int      `json:"selected"` // chunks the selector picked
+	Tagged   int      `json:"tagged"`   // chunks that gained at least one tag
+	Batches  int      `json:"batches"`
+}
+
+// ChunkMove describes one chunk relocation in a bulk move
+type ChunkMove struct {
+	ChunkID     string `json:"chunk_id"`
+	NewParentID string `json:"new_parent_id"` // empty moves the chunk to the top level
+}
+
+// SubtreeMoveResult summarizes a subtree or bulk move
+type SubtreeMoveResult struct {
+	MovedChunkIDs  []string `json:"moved_chunk_ids"`
+	AffectedChunks int      `json:"affected_chunks"` // moved chunks plus all of their descendants
+}
+
+// HierarchyRebuildResult summarizes a full rebuild of the hierarchy closure table
+type HierarchyRebuildResult struct {
+	Chunks   int           `json:"chunks"`
+	Rows     int           `json:"rows"` // closure rows written, self rows included
+	Duration time.Duration `json:"duration"`
+}
+
+// SubtreeCopyResult summarizes a deep subtree copy
+type SubtreeCopyResult struct {
+	SourceChunkID string            `json:"source_chunk_id"`
+	RootChunkID   string            `json:"root_chunk_id"`
+	IDMapping     map[string]string `json:"id_mapping"` // source chunk ID -> copied chunk ID
+	CopiedChunks  int               `json:"copied_chunks"`
+}
+
+// Content strategies of a chunk merge
+const (
+	MergeContentsConcatenate = "concatenate" // target contents, separator, source contents
+	MergeContentsReplace     = "replace"     // source contents replace the target's
+	MergeContentsKeepTarget  = "keep_target" // target contents are kept
+)
+
+// ChunkMergeStrategy controls how a chunk merge reconciles the two chunks
+type ChunkMergeStrategy struct {
+	Contents  string `json:"contents,omitempty"`  // one of the MergeContents strategies; concatenate by default
+	Separator string `json:"separator,omitempty"` // between concatenated contents; a blank line by default
+	Permanent bool   `json:"permanent"`           // delete the source outright instead of moving it to the trash
+
+	// ResolvedContents, when set, is stored as the merged contents instead of applying the
+	// strategy. Wrappers that transform contents, such as encryption, set it.
+	ResolvedContents *string `json:"-"`
+}
+
+// ChunkMergeRequest merges a source chunk into a target chunk
+type ChunkMergeRequest struct {
+	SourceChunkID string             `json:"source_chunk_id"`
+	Strategy      ChunkMergeStrategy `json:"strategy"`
+}
+
+// ChunkMergeResult reports what a chunk merge changed
+type ChunkMergeResult struct {
+	Chunk            *UnifiedChunkRecord `json:"chunk"` // the target after the merge
+	SourceChunkID    string              `json:"source_chunk_id"`
+	ReparentedChunks int                 `json:"reparented_chunks"` // children of the source moved under the target
+	RedirectedRefs   int                 `json:"redirected_refs"`   // chunks whose ref pointed at the source
+	GraphNodes       int                 `json:"graph_nodes"`       // knowledge graph nodes moved to the target
+	TrashID          string              `json:"trash_id,omitempty"`
+}
+
+// SubtreeDeleteOptions controls a cascading subtree delete
+type SubtreeDeleteOptions struct {
+	Confirm        bool `json:"confirm"`                   // without it only the impact is reported
+	Permanent      bool `json:"permanent"`                 // delete outright instead of moving the chunks to the trash
+	ExpectedChunks int  `json:"expected_chunks,omitempty"` // refuse when the subtree no longer has this many chunks
+}
+
+// SubtreeDeleteImpact describes what deleting a subtree affects
+type SubtreeDeleteImpact struct {
+	RootChunkID       string `json:"root_chunk_id"`
+	Chunks            int    `json:"chunks"`             // the root and all of its descendants
+	Descendants       int    `json:"descendants"`        // chunks under the root
+	TaggedChunks      int    `json:"tagged_chunks"`      // chunks outside the subtree tagged with a tag inside it
+	TemplateInstances int    `json:"template_instances"` // chunks outside the subtree instantiating a template inside it
+	GraphNodes        int    `json:"graph_nodes"`        // knowledge graph nodes extracted from the subtree
+}
+
+// SubtreeDeleteResult reports the impact of a subtree delete and, once confirmed, what was removed
+type SubtreeDeleteResult struct {
+	Impact          SubtreeDeleteImpact `json:"impact"`
+	Deleted         bool                `json:"deleted"`
+	DeletedChunkIDs []string            `json:"deleted_chunk_ids,omitempty"`
+	TrashID         string              `json:"trash_id,omitempty"` // set when the chunks were moved to the trash
+}
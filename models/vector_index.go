@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// VectorIndexMethod identifies a pgvector index access method
+type VectorIndexMethod string
+
+const (
+	VectorIndexHNSW    VectorIndexMethod = "hnsw"
+	VectorIndexIVFFlat VectorIndexMethod = "ivfflat"
+)
+
+// VectorIndexSpec describes a pgvector index to create or rebuild
+type VectorIndexSpec struct {
+	Name          string            `json:"name"`
+	Table         string            `json:"table"`
+	Column        string            `json:"column"`
+	Method        VectorIndexMethod `json:"method"`
+	OperatorClass string            `json:"operator_class"`
+
+	// HNSW build parameters
+	M              int `json:"m,omitempty"`
+	EfConstruction int `json:"ef_construction,omitempty"`
+
+	// IVFFlat build parameters
+	Lists int `json:"lists,omitempty"`
+
+	// VectorType restricts the index to one vector_type (e.g. "text" or "image")
+	VectorType string `json:"vector_type,omitempty"`
+}
+
+// VectorIndexInfo reports the state of an existing pgvector index
+type VectorIndexInfo struct {
+	Name        string            `json:"name"`
+	Table       string            `json:"table"`
+	Method      VectorIndexMethod `json:"method"`
+	Definition  string            `json:"definition"`
+	Parameters  map[string]string `json:"parameters"`
+	SizeBytes   int64             `json:"size_bytes"`
+	IndexScans  int64             `json:"index_scans"`
+	IsValid     bool              `json:"is_valid"`
+	InspectedAt time.Time         `json:"inspected_at"`
+}
@@ -0,0 +1,148 @@
+package performance
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroupRoot is where cgroup v2 is mounted
+const cgroupRoot = "/sys/fs/cgroup"
+
+// memoryWarningRatio is the share of the container memory limit above which the monitor warns
+const memoryWarningRatio = 0.9
+
+// CgroupStats is a reading of the limits and usage of the process's cgroup v2
+type CgroupStats struct {
+	MemoryLimit uint64        // memory.max in bytes; 0 when unlimited
+	MemoryUsage uint64        // memory.current in bytes
+	CPULimit    float64       // cpu.max quota in cores; 0 when unlimited
+	CPUUsage    time.Duration // usage_usec from cpu.stat
+	SampledAt   time.Time
+}
+
+// MemoryUtilization returns memory usage as a percentage of the limit, or 0 without a limit
+func (s *CgroupStats) MemoryUtilization() float64 {
+	if s.MemoryLimit == 0 {
+		return 0
+	}
+	return float64(s.MemoryUsage) / float64(s.MemoryLimit) * 100
+}
+
+// CPUUtilization returns the CPU used since prev as a percentage of the cores the cgroup may
+// use: its quota, or every CPU of the host without one
+func (s *CgroupStats) CPUUtilization(prev *CgroupStats) float64 {
+	elapsed := s.SampledAt.Sub(prev.SampledAt)
+	if elapsed <= 0 || s.CPUUsage < prev.CPUUsage {
+		return 0
+	}
+	cores := s.CPULimit
+	if cores == 0 {
+		cores = float64(runtime.NumCPU())
+	}
+	return float64(s.CPUUsage-prev.CPUUsage) / (float64(elapsed) * cores) * 100
+}
+
+// CgroupReader reads the cgroup v2 files of the current process
+type CgroupReader struct {
+	dir string
+}
+
+// NewCgroupReader finds the process's cgroup under root, the cgroup v2 mount point. It
+// fails when the host uses cgroup v1 or exposes no cgroup files, in which case callers
+// fall back to process-wide runtime stats.
+func NewCgroupReader(root string) (*CgroupReader, error) {
+	if root == "" {
+		root = cgroupRoot
+	}
+	dir := root
+	// /proc/self/cgroup lists the v2 cgroup as "0::/path"; inside a cgroup namespace the
+	// path is "/" and the mount point is already the container's cgroup
+	if data, err := os.ReadFile("/proc/self/cgroup"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if path, ok := strings.CutPrefix(line, "0::"); ok {
+				if candidate := filepath.Join(root, path); fileExists(filepath.Join(candidate, "memory.current")) {
+					dir = candidate
+				}
+				break
+			}
+		}
+	}
+	if !fileExists(filepath.Join(dir, "cgroup.controllers")) {
+		return nil, fmt.Errorf("cgroup v2 not found at %s", root)
+	}
+	return &CgroupReader{dir: dir}, nil
+}
+
+// Read returns the cgroup's current limits and usage. Files of controllers that are not
+// enabled leave their values at zero.
+func (r *CgroupReader) Read() (*CgroupStats, error) {
+	stats := &CgroupStats{SampledAt: time.Now()}
+
+	if value, err := r.readFile("memory.max"); err == nil && value != "max" {
+		limit, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse memory.max: %w", err)
+		}
+		stats.MemoryLimit = limit
+	}
+	if value, err := r.readFile("memory.current"); err == nil {
+		usage, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse memory.current: %w", err)
+		}
+		stats.MemoryUsage = usage
+	}
+
+	// cpu.max is "<quota> <period>" in microseconds, with "max" for no quota
+	if value, err := r.readFile("cpu.max"); err == nil {
+		fields := strings.Fields(value)
+		if len(fields) == 2 && fields[0] != "max" {
+			quota, err := strconv.ParseFloat(fields[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse cpu.max quota: %w", err)
+			}
+			period, err := strconv.ParseFloat(fields[1], 64)
+			if err != nil || period <= 0 {
+				return nil, fmt.Errorf("failed to parse cpu.max period: %s", fields[1])
+			}
+			stats.CPULimit = quota / period
+		}
+	}
+
+	file, err := os.Open(filepath.Join(r.dir, "cpu.stat"))
+	if err == nil {
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if value, ok := strings.CutPrefix(scanner.Text(), "usage_usec "); ok {
+				usec, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse cpu.stat usage_usec: %w", err)
+				}
+				stats.CPUUsage = time.Duration(usec) * time.Microsecond
+				break
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func (r *CgroupReader) readFile(name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(r.dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
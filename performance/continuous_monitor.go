@@ -19,6 +19,7 @@ type ContinuousMonitor struct {
 	metrics       *MonitoringMetrics
 	stopChan      chan bool
 	updateInterval time.Duration
+	cgroup        *CgroupReader // nil outside a cgroup v2 container
 }
 
 // MonitoringMetrics holds real-time monitoring data
@@ -32,16 +33,26 @@ type MonitoringMetrics struct {
 	networkIOStats    models.NetworkIOStats
 	gcStats           models.GCStats
 	dbConnectionStats *models.DatabaseStats
+	lastCgroup        *CgroupStats
+	container         *models.ContainerResourceStats
+	memoryWarned      bool
 }
 
 // NewContinuousMonitor creates a new continuous monitor
 func NewContinuousMonitor(services *services.ServiceContainer, logger *log.Logger) *ContinuousMonitor {
+	// Runtime stats only see this process's heap and the host's CPUs; in a container the
+	// cgroup's limits are what the test runs out of
+	cgroup, err := NewCgroupReader("")
+	if err != nil {
+		logger.Printf("Measuring process-wide resource usage: %v", err)
+	}
 	return &ContinuousMonitor{
 		services:       services,
 		logger:         logger,
 		metrics:        &MonitoringMetrics{startTime: time.Now()},
 		stopChan:       make(chan bool, 1),
 		updateInterval: 1 * time.Second,
+		cgroup:         cgroup,
 	}
 }
 
@@ -108,25 +119,43 @@ func (cm *ContinuousMonitor) collectMetrics() {
 	runtime.ReadMemStats(&m)
 
 	currentMemory := m.HeapInuse + m.StackInuse
+
+	// Collect CPU usage (simplified - in production, use proper CPU monitoring)
+	cpuUsage, haveCPU := cm.getCurrentCPUUsage(), true
+
+	// Inside a container, measure against the cgroup's limits instead
+	if stats := cm.readCgroup(); stats != nil {
+		if stats.MemoryUsage > 0 {
+			currentMemory = stats.MemoryUsage
+		}
+		// CPU utilization needs two readings
+		cpuUsage, haveCPU = 0, false
+		if prev := cm.metrics.lastCgroup; prev != nil {
+			cpuUsage, haveCPU = stats.CPUUtilization(prev), true
+		}
+		cm.metrics.lastCgroup = stats
+		cm.recordContainerStats(stats)
+	}
+
 	if currentMemory > cm.metrics.peakMemoryUsage {
 		cm.metrics.peakMemoryUsage = currentMemory
 	}
 
-	// Collect CPU usage (simplified - in production, use proper CPU monitoring)
-	cpuUsage := cm.getCurrentCPUUsage()
-	cm.metrics.cpuSamples = append(cm.metrics.cpuSamples, cpuUsage)
+	if haveCPU {
+		cm.metrics.cpuSamples = append(cm.metrics.cpuSamples, cpuUsage)
 
-	// Keep only last 300 samples (5 minutes at 1-second intervals)
-	if len(cm.metrics.cpuSamples) > 300 {
-		cm.metrics.cpuSamples = cm.metrics.cpuSamples[1:]
-	}
+		// Keep only last 300 samples (5 minutes at 1-second intervals)
+		if len(cm.metrics.cpuSamples) > 300 {
+			cm.metrics.cpuSamples = cm.metrics.cpuSamples[1:]
+		}
 
-	// Calculate average CPU usage
-	total := 0.0
-	for _, sample := range cm.metrics.cpuSamples {
-		total += sample
+		// Calculate average CPU usage
+		total := 0.0
+		for _, sample := range cm.metrics.cpuSamples {
+			total += sample
+		}
+		cm.metrics.avgCPUUsage = total / float64(len(cm.metrics.cpuSamples))
 	}
-	cm.metrics.avgCPUUsage = total / float64(len(cm.metrics.cpuSamples))
 
 	// Update GC stats
 	cm.metrics.gcStats = models.GCStats{
@@ -148,6 +177,47 @@ func (cm *ContinuousMonitor) collectMetrics() {
 	}
 }
 
+// readCgroup reads the container's cgroup, or returns nil outside a container or when the
+// files cannot be read
+func (cm *ContinuousMonitor) readCgroup() *CgroupStats {
+	if cm.cgroup == nil {
+		return nil
+	}
+	stats, err := cm.cgroup.Read()
+	if err != nil {
+		cm.logger.Printf("Failed to read cgroup stats: %v", err)
+		return nil
+	}
+	return stats
+}
+
+// recordContainerStats tracks utilization against the container limits and warns when
+// memory comes within 10% of the limit. Callers hold metrics.mu.
+func (cm *ContinuousMonitor) recordContainerStats(stats *CgroupStats) {
+	if cm.metrics.container == nil {
+		cm.metrics.container = &models.ContainerResourceStats{}
+	}
+	container := cm.metrics.container
+	container.MemoryLimit = stats.MemoryLimit
+	container.CPULimit = stats.CPULimit
+
+	utilization := stats.MemoryUtilization()
+	if utilization > container.PeakMemoryUtilization {
+		container.PeakMemoryUtilization = utilization
+	}
+
+	nearLimit := stats.MemoryLimit > 0 && float64(stats.MemoryUsage) >= float64(stats.MemoryLimit)*memoryWarningRatio
+	if nearLimit {
+		container.MemoryLimitWarnings++
+		// Warn once each time usage crosses into the last 10%
+		if !cm.metrics.memoryWarned {
+			cm.logger.Printf("WARNING: container memory at %.1f%% of its %s limit",
+				utilization, formatBytes(stats.MemoryLimit))
+		}
+	}
+	cm.metrics.memoryWarned = nearLimit
+}
+
 // getCurrentCPUUsage returns current CPU usage percentage (simplified)
 func (cm *ContinuousMonitor) getCurrentCPUUsage() float64 {
 	// This is a simplified CPU usage calculation
@@ -239,11 +309,30 @@ func (cm *ContinuousMonitor) GetDatabaseConnectionStats() *models.DatabaseStats
 	return &stats
 }
 
+// GetContainerStats returns utilization against the cgroup limits, or nil outside a container
+func (cm *ContinuousMonitor) GetContainerStats() *models.ContainerResourceStats {
+	cm.metrics.mu.RLock()
+	defer cm.metrics.mu.RUnlock()
+
+	if cm.metrics.container == nil {
+		return nil
+	}
+
+	stats := *cm.metrics.container
+	return &stats
+}
+
 // GetCurrentMetricsSnapshot returns a snapshot of current metrics
 func (cm *ContinuousMonitor) GetCurrentMetricsSnapshot() *models.ResourceUtilizationResult {
 	cm.metrics.mu.RLock()
 	defer cm.metrics.mu.RUnlock()
 
+	var container *models.ContainerResourceStats
+	if cm.metrics.container != nil {
+		stats := *cm.metrics.container
+		container = &stats
+	}
+
 	return &models.ResourceUtilizationResult{
 		PeakMemoryUsage:     cm.metrics.peakMemoryUsage,
 		AverageCPUUsage:     cm.metrics.avgCPUUsage,
@@ -255,6 +344,7 @@ func (cm *ContinuousMonitor) GetCurrentMetricsSnapshot() *models.ResourceUtiliza
 			"monitoring_duration": time.Since(cm.metrics.startTime).String(),
 			"sample_count":        len(cm.metrics.cpuSamples),
 		},
+		Container: container,
 	}
 }
 
@@ -292,6 +382,9 @@ func (cm *ContinuousMonitor) ResetMetrics() {
 	cm.metrics.networkIOStats = models.NetworkIOStats{}
 	cm.metrics.gcStats = models.GCStats{}
 	cm.metrics.dbConnectionStats = nil
+	cm.metrics.lastCgroup = nil
+	cm.metrics.container = nil
+	cm.metrics.memoryWarned = false
 
 	cm.logger.Printf("Monitoring metrics reset")
 }
\ No newline at end of file
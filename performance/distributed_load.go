@@ -0,0 +1,620 @@
+package performance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"semantic-text-processor/models"
+)
+
+// Distributed load testing: one coordinator and any number of workers. Workers register
+// with the coordinator, poll it for their share of each load step's users, run the step
+// with their own LoadTestExecutor and report the step result back. The coordinator starts
+// every step on all workers at the same moment and merges their results, so a test can
+// simulate more users than a single process can drive.
+
+// WorkerAssignment tells a worker what to run next
+type WorkerAssignment struct {
+	// Step is the load step number, starting at 1; zero means wait and poll again
+	Step int `json:"step"`
+	// UserCount is this worker's share of the step's users
+	UserCount int `json:"user_count"`
+	// StartAt is when every worker starts the step
+	StartAt time.Time      `json:"start_at"`
+	Config  LoadTestConfig `json:"config"`
+	// Done tells the worker the test is over and it can exit
+	Done bool `json:"done"`
+}
+
+// WorkerRegistration is a worker's registration with the coordinator
+type WorkerRegistration struct {
+	Hostname string `json:"hostname"`
+	// Capacity weights the worker's share of each step's users. Users are split evenly
+	// unless every worker declares one.
+	Capacity int `json:"capacity"`
+}
+
+// WorkerReport carries a worker's result for one load step
+type WorkerReport struct {
+	Step   int                   `json:"step"`
+	Result models.LoadStepResult `json:"result"`
+}
+
+// coordinatorWorker is a registered worker
+type coordinatorWorker struct {
+	id           string
+	registration WorkerRegistration
+	assignment   WorkerAssignment
+	lastSeen     time.Time
+}
+
+// LoadCoordinator drives a distributed load test and aggregates the workers' results
+type LoadCoordinator struct {
+	config          *LoadTestConfig
+	expectedWorkers int
+	logger          *log.Logger
+
+	mu       sync.Mutex
+	workers  map[string]*coordinatorWorker
+	reports  map[int]map[string]*models.LoadStepResult
+	changed  chan struct{}
+	finished bool
+}
+
+// NewLoadCoordinator creates a coordinator that runs config's load steps once
+// expectedWorkers workers have registered
+func NewLoadCoordinator(config *LoadTestConfig, expectedWorkers int, logger *log.Logger) *LoadCoordinator {
+	if expectedWorkers < 1 {
+		expectedWorkers = 1
+	}
+	return &LoadCoordinator{
+		config:          config,
+		expectedWorkers: expectedWorkers,
+		logger:          logger,
+		workers:         make(map[string]*coordinatorWorker),
+		reports:         make(map[int]map[string]*models.LoadStepResult),
+		changed:         make(chan struct{}, 1),
+	}
+}
+
+// Handler returns the HTTP API workers talk to
+func (c *LoadCoordinator) Handler() http.Handler {
+	router := mux.NewRouter()
+	router.HandleFunc("/workers", c.handleRegister).Methods("POST")
+	router.HandleFunc("/workers/{id}/assignment", c.handleAssignment).Methods("GET")
+	router.HandleFunc("/workers/{id}/results", c.handleReport).Methods("POST")
+	return router
+}
+
+// Run waits for the expected workers, executes every load step across them and returns the
+// aggregated result
+func (c *LoadCoordinator) Run(ctx context.Context) (*models.LoadTestResult, error) {
+	c.logger.Printf("Waiting for %d load workers to register", c.expectedWorkers)
+	for c.workerCount() < c.expectedWorkers {
+		if err := c.waitForChange(ctx); err != nil {
+			return nil, fmt.Errorf("failed to gather load workers: %w", err)
+		}
+	}
+	defer c.finish()
+
+	result := &models.LoadTestResult{
+		StartTime: time.Now(),
+		Config:    *c.config,
+		LoadSteps: []models.LoadStepResult{},
+	}
+
+	for i, userCount := range c.config.LoadSteps {
+		step := i + 1
+		c.logger.Printf("Executing distributed load step %d/%d: %d users", step, len(c.config.LoadSteps), userCount)
+
+		stepResult, err := c.runStep(ctx, step, userCount)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("distributed load test interrupted: %w", err)
+			}
+			c.logger.Printf("Load step %d failed: %v", step, err)
+			stepResult.Error = err.Error()
+		}
+		result.LoadSteps = append(result.LoadSteps, *stepResult)
+
+		if stepResult.ErrorRate > c.config.ErrorThreshold {
+			c.logger.Printf("Error threshold exceeded (%.2f%% > %.2f%%), stopping test",
+				stepResult.ErrorRate*100, c.config.ErrorThreshold*100)
+			break
+		}
+
+		if i < len(c.config.LoadSteps)-1 {
+			c.logger.Printf("Cooldown for %v", c.config.CooldownTime)
+			time.Sleep(c.config.CooldownTime)
+		}
+	}
+
+	result.EndTime = time.Now()
+	result.TotalDuration = result.EndTime.Sub(result.StartTime)
+	result.OverallStats = overallStatsFromSteps(result.LoadSteps)
+	return result, nil
+}
+
+// runStep splits userCount across the registered workers, waits for their reports and
+// merges them
+func (c *LoadCoordinator) runStep(ctx context.Context, step, userCount int) (*models.LoadStepResult, error) {
+	startAt := time.Now().Add(2 * time.Second)
+	shares := c.assign(step, userCount, startAt)
+
+	// Workers get the step duration plus ramp-up and a grace period to report back
+	deadline := startAt.Add(c.config.TestDuration + c.config.RampUpTime + 30*time.Second)
+	deadlineCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	for {
+		reports, missing := c.stepReports(step, shares)
+		if len(missing) == 0 {
+			merged := AggregateLoadStepResults(userCount, reports)
+			return &merged, nil
+		}
+		if err := c.waitForChange(deadlineCtx); err != nil {
+			merged := AggregateLoadStepResults(userCount, reports)
+			if ctx.Err() != nil {
+				return &merged, err
+			}
+			return &merged, fmt.Errorf("no result from workers %s", strings.Join(missing, ", "))
+		}
+	}
+}
+
+// assign hands each worker its share of the step's users, in proportion to capacity when
+// workers declare one, and returns the shares by worker ID
+func (c *LoadCoordinator) assign(step, userCount int, startAt time.Time) map[string]int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ids := make([]string, 0, len(c.workers))
+	for id := range c.workers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	shares := splitUsers(userCount, ids, func(id string) int { return c.workers[id].registration.Capacity })
+	for id, share := range shares {
+		worker := c.workers[id]
+		worker.assignment = WorkerAssignment{
+			Step:      step,
+			UserCount: share,
+			StartAt:   startAt,
+			Config:    *c.config,
+		}
+	}
+	return shares
+}
+
+// splitUsers divides users across workers, weighted by capacity when every worker has one
+// and evenly otherwise. Workers whose share rounds down to zero get no assignment.
+func splitUsers(users int, ids []string, capacity func(id string) int) map[string]int {
+	shares := make(map[string]int, len(ids))
+	if len(ids) == 0 || users <= 0 {
+		return shares
+	}
+
+	totalCapacity := 0
+	for _, id := range ids {
+		if capacity(id) <= 0 {
+			totalCapacity = 0
+			break
+		}
+		totalCapacity += capacity(id)
+	}
+
+	assigned := 0
+	for _, id := range ids {
+		var share int
+		if totalCapacity > 0 {
+			share = users * capacity(id) / totalCapacity
+		} else {
+			share = users / len(ids)
+		}
+		shares[id] = share
+		assigned += share
+	}
+	// Hand out the remainder one user at a time
+	for i := 0; assigned < users; i++ {
+		shares[ids[i%len(ids)]]++
+		assigned++
+	}
+	for id, share := range shares {
+		if share == 0 {
+			delete(shares, id)
+		}
+	}
+	return shares
+}
+
+// stepReports returns the reports received for a step and the workers still missing
+func (c *LoadCoordinator) stepReports(step int, shares map[string]int) ([]models.LoadStepResult, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var reports []models.LoadStepResult
+	var missing []string
+	for id := range shares {
+		if report := c.reports[step][id]; report != nil {
+			reports = append(reports, *report)
+		} else {
+			missing = append(missing, id)
+		}
+	}
+	sort.Strings(missing)
+	return reports, missing
+}
+
+// AggregateLoadStepResults merges the results workers report for one step into the result
+// of the whole step
+func AggregateLoadStepResults(userCount int, results []models.LoadStepResult) models.LoadStepResult {
+	merged := models.LoadStepResult{
+		UserCount:    userCount,
+		RequestStats: make(map[string]models.RequestTypeStats),
+	}
+
+	var totalResponse time.Duration
+	for i, result := range results {
+		if i == 0 || result.StartTime.Before(merged.StartTime) {
+			merged.StartTime = result.StartTime
+		}
+		if result.EndTime.After(merged.EndTime) {
+			merged.EndTime = result.EndTime
+		}
+		if result.Duration > merged.Duration {
+			merged.Duration = result.Duration
+		}
+		merged.TotalRequests += result.TotalRequests
+		merged.TotalErrors += result.TotalErrors
+		merged.QPS += result.QPS
+		totalResponse += result.AvgResponseTime * time.Duration(result.TotalRequests)
+
+		for queryType, stats := range result.RequestStats {
+			merged.RequestStats[queryType] = mergeRequestTypeStats(merged.RequestStats[queryType], stats)
+		}
+		if result.Error != "" {
+			if merged.Error != "" {
+				merged.Error += "; "
+			}
+			merged.Error += result.Error
+		}
+	}
+
+	merged.ActualDuration = merged.EndTime.Sub(merged.StartTime)
+	if merged.TotalRequests > 0 {
+		merged.ErrorRate = float64(merged.TotalErrors) / float64(merged.TotalRequests)
+		merged.AvgResponseTime = totalResponse / time.Duration(merged.TotalRequests)
+	}
+	return merged
+}
+
+// mergeRequestTypeStats combines two workers' statistics for one request type
+func mergeRequestTypeStats(a, b models.RequestTypeStats) models.RequestTypeStats {
+	if a.Count == 0 {
+		return b
+	}
+	if b.Count == 0 {
+		return a
+	}
+	merged := models.RequestTypeStats{
+		Count:           a.Count + b.Count,
+		Errors:          a.Errors + b.Errors,
+		MinResponseTime: a.MinResponseTime,
+		MaxResponseTime: a.MaxResponseTime,
+	}
+	merged.AvgResponseTime = (a.AvgResponseTime*time.Duration(a.Count) + b.AvgResponseTime*time.Duration(b.Count)) /
+		time.Duration(merged.Count)
+	if b.MinResponseTime < merged.MinResponseTime {
+		merged.MinResponseTime = b.MinResponseTime
+	}
+	if b.MaxResponseTime > merged.MaxResponseTime {
+		merged.MaxResponseTime = b.MaxResponseTime
+	}
+	return merged
+}
+
+// overallStatsFromSteps summarises a distributed test from its steps. Percentiles need the
+// individual response times, which workers do not send, so only averages and counts are set.
+func overallStatsFromSteps(steps []models.LoadStepResult) models.LoadTestStats {
+	var stats models.LoadTestStats
+	var totalResponse time.Duration
+	for _, step := range steps {
+		stats.TotalRequests += step.TotalRequests
+		stats.TotalErrors += step.TotalErrors
+		totalResponse += step.AvgResponseTime * time.Duration(step.TotalRequests)
+	}
+	if stats.TotalRequests > 0 {
+		stats.ErrorRate = float64(stats.TotalErrors) / float64(stats.TotalRequests)
+		stats.AvgResponseTime = totalResponse / time.Duration(stats.TotalRequests)
+	}
+	return stats
+}
+
+// handleRegister registers a worker and returns its ID
+func (c *LoadCoordinator) handleRegister(w http.ResponseWriter, r *http.Request) {
+	var registration WorkerRegistration
+	if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+		http.Error(w, "invalid registration", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	if c.finished {
+		c.mu.Unlock()
+		http.Error(w, "load test is over", http.StatusGone)
+		return
+	}
+	id := uuid.New().String()
+	c.workers[id] = &coordinatorWorker{id: id, registration: registration, lastSeen: time.Now()}
+	count := len(c.workers)
+	c.mu.Unlock()
+
+	c.logger.Printf("Load worker %s registered from %s (%d/%d)", id, registration.Hostname, count, c.expectedWorkers)
+	c.notify()
+	writeCoordinatorJSON(w, http.StatusCreated, map[string]string{"worker_id": id})
+}
+
+// handleAssignment returns a worker's current assignment
+func (c *LoadCoordinator) handleAssignment(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	c.mu.Lock()
+	worker := c.workers[id]
+	if worker == nil {
+		c.mu.Unlock()
+		http.Error(w, "unknown worker", http.StatusNotFound)
+		return
+	}
+	worker.lastSeen = time.Now()
+	assignment := worker.assignment
+	assignment.Done = c.finished
+	c.mu.Unlock()
+
+	writeCoordinatorJSON(w, http.StatusOK, assignment)
+}
+
+// handleReport stores a worker's step result
+func (c *LoadCoordinator) handleReport(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var report WorkerReport
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		http.Error(w, "invalid report", http.StatusBadRequest)
+		return
+	}
+
+	c.mu.Lock()
+	worker := c.workers[id]
+	if worker == nil {
+		c.mu.Unlock()
+		http.Error(w, "unknown worker", http.StatusNotFound)
+		return
+	}
+	worker.lastSeen = time.Now()
+	if c.reports[report.Step] == nil {
+		c.reports[report.Step] = make(map[string]*models.LoadStepResult)
+	}
+	c.reports[report.Step][id] = &report.Result
+	c.mu.Unlock()
+
+	c.logger.Printf("Load worker %s finished step %d: %d requests, %d errors",
+		id, report.Step, report.Result.TotalRequests, report.Result.TotalErrors)
+	c.notify()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// workerCount returns the number of registered workers
+func (c *LoadCoordinator) workerCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.workers)
+}
+
+// notify wakes Run after a registration or report
+func (c *LoadCoordinator) notify() {
+	select {
+	case c.changed <- struct{}{}:
+	default:
+	}
+}
+
+// waitForChange blocks until a worker registers or reports, or ctx ends
+func (c *LoadCoordinator) waitForChange(ctx context.Context) error {
+	select {
+	case <-c.changed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// finish tells workers polling for assignments that the test is over
+func (c *LoadCoordinator) finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.finished = true
+}
+
+// writeCoordinatorJSON writes a JSON response
+func writeCoordinatorJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// SaveLoadTestResult writes a load test result as JSON
+func SaveLoadTestResult(result *models.LoadTestResult, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal load test result: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write load test result: %w", err)
+	}
+	return nil
+}
+
+// maxCoordinatorPollFailures is how many polls in a row may fail before a worker gives up
+const maxCoordinatorPollFailures = 30
+
+// LoadWorker runs the load steps a coordinator assigns to it
+type LoadWorker struct {
+	coordinatorURL string
+	executor       *LoadTestExecutor
+	registration   WorkerRegistration
+	client         *http.Client
+	logger         *log.Logger
+	pollInterval   time.Duration
+}
+
+// NewLoadWorker creates a worker that drives load with executor on behalf of the
+// coordinator at coordinatorURL
+func NewLoadWorker(coordinatorURL string, executor *LoadTestExecutor, registration WorkerRegistration, logger *log.Logger) *LoadWorker {
+	return &LoadWorker{
+		coordinatorURL: strings.TrimSuffix(coordinatorURL, "/"),
+		executor:       executor,
+		registration:   registration,
+		client:         &http.Client{Timeout: 30 * time.Second},
+		logger:         logger,
+		pollInterval:   time.Second,
+	}
+}
+
+// Run registers with the coordinator and executes assignments until the coordinator
+// reports the test is over or ctx ends
+func (w *LoadWorker) Run(ctx context.Context) error {
+	var registered struct {
+		WorkerID string `json:"worker_id"`
+	}
+	if err := w.post(ctx, "/workers", w.registration, &registered); err != nil {
+		return fmt.Errorf("failed to register with coordinator: %w", err)
+	}
+	w.logger.Printf("Registered with coordinator %s as worker %s", w.coordinatorURL, registered.WorkerID)
+
+	lastStep, failures := 0, 0
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var assignment WorkerAssignment
+		err := w.get(ctx, "/workers/"+registered.WorkerID+"/assignment", &assignment)
+		switch {
+		case err != nil:
+			// A coordinator that stays unreachable has most likely finished and exited
+			failures++
+			if failures >= maxCoordinatorPollFailures {
+				return fmt.Errorf("lost contact with coordinator: %w", err)
+			}
+			w.logger.Printf("Failed to poll coordinator: %v", err)
+		case assignment.Done:
+			w.logger.Printf("Coordinator finished the load test")
+			return nil
+		case assignment.Step > lastStep:
+			failures = 0
+			lastStep = assignment.Step
+			report := w.runAssignment(ctx, &assignment)
+			if err := w.post(ctx, "/workers/"+registered.WorkerID+"/results", report, nil); err != nil {
+				w.logger.Printf("Failed to report step %d: %v", assignment.Step, err)
+			}
+		default:
+			failures = 0
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// runAssignment waits for the step's start time and runs this worker's share of users
+func (w *LoadWorker) runAssignment(ctx context.Context, assignment *WorkerAssignment) *WorkerReport {
+	report := &WorkerReport{Step: assignment.Step}
+	if assignment.UserCount == 0 {
+		report.Result = models.LoadStepResult{StartTime: assignment.StartAt, EndTime: assignment.StartAt}
+		return report
+	}
+
+	if wait := time.Until(assignment.StartAt); wait > 0 {
+		select {
+		case <-ctx.Done():
+			report.Result.Error = ctx.Err().Error()
+			return report
+		case <-time.After(wait):
+		}
+	}
+
+	w.logger.Printf("Running step %d with %d users", assignment.Step, assignment.UserCount)
+	if assignment.Config.Scenario != nil {
+		w.executor.sampler = NewScenarioSampler(assignment.Config.Scenario, time.Now().UnixNano())
+	}
+	result, err := w.executor.executeLoadStep(ctx, assignment.UserCount, &assignment.Config)
+	if result != nil {
+		report.Result = *result
+	}
+	if err != nil {
+		report.Result.Error = err.Error()
+	}
+	return report
+}
+
+// get fetches a coordinator resource into out
+func (w *LoadWorker) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, w.coordinatorURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return w.do(req, out)
+}
+
+// post sends body to a coordinator resource and decodes the response into out, if set
+func (w *LoadWorker) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.coordinatorURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return w.do(req, out)
+}
+
+// do sends a request to the coordinator
+func (w *LoadWorker) do(req *http.Request, out interface{}) error {
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach coordinator: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("coordinator returned status %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode coordinator response: %w", err)
+	}
+	return nil
+}
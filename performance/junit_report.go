@@ -0,0 +1,192 @@
+package performance
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"semantic-text-processor/models"
+	"strings"
+	"time"
+)
+
+// junitMaxErrorRate is the error rate above which a load step fails, matching the rate
+// the performance test treats as critical
+const junitMaxErrorRate = 0.1
+
+// JUnitTestSuites is the root element of a JUnit XML report
+type JUnitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     float64          `xml:"time,attr"`
+	Suites   []JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnitTestSuite groups related test cases, such as the load steps of a run
+type JUnitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Time      float64         `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []JUnitTestCase `xml:"testcase"`
+}
+
+// JUnitTestCase is one load step or threshold check
+type JUnitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+// JUnitFailure describes why a test case failed
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// add appends a test case and updates the suite's counts
+func (s *JUnitTestSuite) add(testCase JUnitTestCase) {
+	testCase.ClassName = "performance." + s.Name
+	s.Cases = append(s.Cases, testCase)
+	s.Tests++
+	if testCase.Failure != nil {
+		s.Failures++
+	}
+	s.Time += testCase.Time
+}
+
+// check adds a threshold check that fails when failed is true
+func (s *JUnitTestSuite) check(name string, failed bool, message, details string) {
+	testCase := JUnitTestCase{Name: name, SystemOut: details}
+	if failed {
+		testCase.Failure = &JUnitFailure{Message: message, Type: "threshold", Text: details}
+	}
+	s.add(testCase)
+}
+
+// BuildLoadStepSuite maps each load step to a test case that fails when the step errored,
+// its error rate exceeded 10% or its average response time exceeded slowThreshold.
+// A zero slowThreshold skips the response time check.
+func BuildLoadStepSuite(steps []models.LoadStepResult, slowThreshold time.Duration) JUnitTestSuite {
+	suite := JUnitTestSuite{Name: "load_steps"}
+	if len(steps) > 0 {
+		suite.Timestamp = steps[0].StartTime.Format(time.RFC3339)
+	}
+
+	for i, step := range steps {
+		testCase := JUnitTestCase{
+			Name: fmt.Sprintf("step_%02d_%d_users", i+1, step.UserCount),
+			Time: step.ActualDuration.Seconds(),
+			SystemOut: fmt.Sprintf("requests=%d errors=%d error_rate=%.4f avg_response_time=%v qps=%.2f",
+				step.TotalRequests, step.TotalErrors, step.ErrorRate, step.AvgResponseTime, step.QPS),
+		}
+
+		var problems []string
+		if step.Error != "" {
+			problems = append(problems, "step failed: "+step.Error)
+		}
+		if step.ErrorRate > junitMaxErrorRate {
+			problems = append(problems, fmt.Sprintf("error rate %.2f%% exceeds %.0f%%", step.ErrorRate*100, junitMaxErrorRate*100))
+		}
+		if slowThreshold > 0 && step.AvgResponseTime > slowThreshold {
+			problems = append(problems, fmt.Sprintf("average response time %v exceeds %v", step.AvgResponseTime, slowThreshold))
+		}
+		if len(problems) > 0 {
+			testCase.Failure = &JUnitFailure{
+				Message: problems[0],
+				Type:    "load_step",
+				Text:    strings.Join(problems, "\n"),
+			}
+		}
+		suite.add(testCase)
+	}
+	return suite
+}
+
+// BuildJUnitReport maps a performance report to JUnit test suites: one test case per load
+// step, and one per threshold check against the test configuration
+func BuildJUnitReport(report *ComprehensivePerformanceReport, testConfig *PerformanceTestConfig) *JUnitTestSuites {
+	loadSteps := BuildLoadStepSuite(report.LoadTestResults.LoadSteps, testConfig.SlowQueryThreshold)
+
+	thresholds := JUnitTestSuite{Name: "thresholds", Timestamp: report.StartTime.Format(time.RFC3339)}
+	stats := report.LoadTestResults.OverallStats
+	thresholds.check("error_rate",
+		stats.ErrorRate > junitMaxErrorRate,
+		fmt.Sprintf("error rate %.2f%% exceeds %.0f%%", stats.ErrorRate*100, junitMaxErrorRate*100),
+		fmt.Sprintf("total_requests=%d total_errors=%d error_rate=%.4f", stats.TotalRequests, stats.TotalErrors, stats.ErrorRate))
+	thresholds.check("p95_response_time",
+		testConfig.SlowQueryThreshold > 0 && stats.P95ResponseTime > testConfig.SlowQueryThreshold,
+		fmt.Sprintf("p95 response time %v exceeds %v", stats.P95ResponseTime, testConfig.SlowQueryThreshold),
+		fmt.Sprintf("avg=%v p50=%v p95=%v p99=%v", stats.AvgResponseTime, stats.P50ResponseTime, stats.P95ResponseTime, stats.P99ResponseTime))
+
+	slowQueries := report.OptimizationAnalysis.SlowQueries
+	var slowDetails []string
+	for _, query := range slowQueries {
+		slowDetails = append(slowDetails, fmt.Sprintf("%s: %v", query.QueryType, query.AvgDuration))
+	}
+	thresholds.check("slow_queries",
+		len(slowQueries) > 0,
+		fmt.Sprintf("%d query patterns exceed %v", len(slowQueries), testConfig.SlowQueryThreshold),
+		strings.Join(slowDetails, "\n"))
+
+	resources := report.ResourceUtilization
+	if testConfig.EnableResourceMonitor {
+		memoryLimit := uint64(testConfig.MemoryLimitMB) * 1024 * 1024
+		thresholds.check("peak_memory",
+			memoryLimit > 0 && resources.PeakMemoryUsage > memoryLimit,
+			fmt.Sprintf("peak memory %d MB exceeds %d MB", resources.PeakMemoryUsage/1024/1024, testConfig.MemoryLimitMB),
+			fmt.Sprintf("peak_memory_bytes=%d", resources.PeakMemoryUsage))
+		thresholds.check("average_cpu",
+			testConfig.CPUUsageThreshold > 0 && resources.AverageCPUUsage > testConfig.CPUUsageThreshold,
+			fmt.Sprintf("average CPU %.1f%% exceeds %.1f%%", resources.AverageCPUUsage, testConfig.CPUUsageThreshold),
+			fmt.Sprintf("average_cpu=%.2f", resources.AverageCPUUsage))
+		if container := resources.Container; container != nil && container.MemoryLimit > 0 {
+			thresholds.check("container_memory",
+				container.MemoryLimitWarnings > 0,
+				fmt.Sprintf("memory came within 10%% of the %d MB container limit", container.MemoryLimit/1024/1024),
+				fmt.Sprintf("peak_memory_utilization=%.2f samples_near_limit=%d", container.PeakMemoryUtilization, container.MemoryLimitWarnings))
+		}
+	}
+
+	if regression := report.RegressionResults; regression != nil {
+		thresholds.check("regression",
+			regression.RegressionDetected,
+			"performance regressed against the historical baseline",
+			strings.Join(regression.Comparison.DegradationAreas, "\n"))
+	}
+
+	return NewJUnitTestSuites("performance", loadSteps, thresholds)
+}
+
+// NewJUnitTestSuites wraps suites in a report and totals their counts
+func NewJUnitTestSuites(name string, suites ...JUnitTestSuite) *JUnitTestSuites {
+	report := &JUnitTestSuites{Name: name, Suites: suites}
+	for _, suite := range suites {
+		report.Tests += suite.Tests
+		report.Failures += suite.Failures
+		report.Time += suite.Time
+	}
+	return report
+}
+
+// SaveJUnitReport writes a JUnit XML report to path
+func SaveJUnitReport(report *JUnitTestSuites, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	data, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+	data = append([]byte(xml.Header), data...)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write JUnit report: %w", err)
+	}
+	return nil
+}
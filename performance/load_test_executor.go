@@ -18,6 +18,9 @@ type LoadTestExecutor struct {
 	logger          *log.Logger
 	queryGenerators map[string]QueryGenerator
 	metricsCollector *LoadTestMetricsCollector
+	sampler         *ScenarioSampler
+	profiler        *ProfileCapturer
+	profiles        []models.ProfileArtifact
 }
 
 // LoadTestConfig defines configuration for load testing
@@ -31,6 +34,8 @@ type LoadTestConfig struct {
 	QueryTypes         []string      `json:"query_types"`
 	ThinkTime          time.Duration `json:"think_time"`
 	ErrorThreshold     float64       `json:"error_threshold"`
+	// Scenario, when set, replaces QueryTypes and ThinkTime with a weighted operation mix
+	Scenario *WorkloadScenario `json:"scenario,omitempty"`
 }
 
 // QueryGenerator generates test queries for load testing
@@ -94,6 +99,16 @@ func NewLoadTestExecutor(services *services.ServiceContainer, logger *log.Logger
 	return executor
 }
 
+// SetProfiler captures CPU and heap profiles of every load step; nil turns capture off
+func (lte *LoadTestExecutor) SetProfiler(profiler *ProfileCapturer) {
+	lte.profiler = profiler
+}
+
+// Profiles returns the profiles captured during the last load test
+func (lte *LoadTestExecutor) Profiles() []models.ProfileArtifact {
+	return lte.profiles
+}
+
 // ExecuteProgressiveLoadTest runs a progressive load test
 func (lte *LoadTestExecutor) ExecuteProgressiveLoadTest(ctx context.Context, config *LoadTestConfig) (*models.LoadTestResult, error) {
 	lte.logger.Printf("Starting progressive load test with config: %+v", config)
@@ -107,12 +122,28 @@ func (lte *LoadTestExecutor) ExecuteProgressiveLoadTest(ctx context.Context, con
 
 	// Reset metrics collector
 	lte.metricsCollector.Reset()
+	lte.profiles = nil
+
+	// Replay the scenario's traffic; without one users rotate through QueryTypes
+	lte.sampler = nil
+	if config.Scenario != nil {
+		lte.sampler = NewScenarioSampler(config.Scenario, time.Now().UnixNano())
+		lte.logger.Printf("Using workload scenario %q", config.Scenario.Name)
+	}
 
 	// Execute each load step
 	for i, userCount := range config.LoadSteps {
 		lte.logger.Printf("Executing load step %d/%d: %d users", i+1, len(config.LoadSteps), userCount)
 
+		var stopProfiling func() []models.ProfileArtifact
+		if lte.profiler != nil {
+			stopProfiling = lte.profiler.Start(i+1, userCount)
+		}
+
 		stepResult, err := lte.executeLoadStep(ctx, userCount, config)
+		if stopProfiling != nil {
+			lte.profiles = append(lte.profiles, stopProfiling()...)
+		}
 		if err != nil {
 			lte.logger.Printf("Load step %d failed: %v", i+1, err)
 			stepResult.Error = err.Error()
@@ -241,8 +272,15 @@ func (lte *LoadTestExecutor) runVirtualUser(ctx context.Context, userID int, con
 		}
 
 		// Select query type
-		queryType := config.QueryTypes[userID%len(config.QueryTypes)]
-		generator := lte.queryGenerators[queryType]
+		var queryType string
+		var generator QueryGenerator
+		if lte.sampler != nil {
+			queryType = lte.sampler.NextOperation()
+			generator = &scenarioQueryGenerator{sampler: lte.sampler, operation: queryType}
+		} else {
+			queryType = config.QueryTypes[userID%len(config.QueryTypes)]
+			generator = lte.queryGenerators[queryType]
+		}
 		if generator == nil {
 			lte.logger.Printf("No generator for query type: %s", queryType)
 			continue
@@ -272,8 +310,12 @@ func (lte *LoadTestExecutor) runVirtualUser(ctx context.Context, userID int, con
 		userMetrics.AvgResponse = userMetrics.TotalDuration / time.Duration(userMetrics.RequestCount)
 
 		// Think time between requests
-		if config.ThinkTime > 0 {
-			time.Sleep(config.ThinkTime)
+		thinkTime := config.ThinkTime
+		if lte.sampler != nil {
+			thinkTime = lte.sampler.ThinkTime()
+		}
+		if thinkTime > 0 {
+			time.Sleep(thinkTime)
 		}
 	}
 }
@@ -293,9 +335,17 @@ func (lte *LoadTestExecutor) executeQuery(ctx context.Context, query models.Test
 
 	case "tag_search":
 		req := query.Parameters.(*models.TagSearchRequest)
-		_, err := lte.services.SearchService.SearchByTag(ctx, req.Tags[0]) // Simplified
+		_, err := lte.services.SearchService.SearchByTag(ctx, req.TagContent)
 		return err
 
+	case OperationWrite:
+		params := query.Parameters.(map[string]interface{})
+		chunk := &models.UnifiedChunkRecord{
+			Contents: params["content"].(string),
+			Metadata: map[string]interface{}{"source": "load_test", "tag": params["tag"]},
+		}
+		return lte.services.UnifiedChunkService.CreateChunk(ctx, chunk)
+
 	case "chunk_crud":
 		// Simulate CRUD operations
 		chunks, err := lte.services.UnifiedChunkService.GetChunks(ctx, &services.GetChunksRequest{
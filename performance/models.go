@@ -87,9 +87,7 @@ func (g *TagSearchGenerator) GenerateQuery() models.TestQuery {
 	return models.TestQuery{
 		Type: "tag_search",
 		Parameters: &models.TagSearchRequest{
-			Tags:            tags,
-			CombinationMode: "AND",
-			Limit:          20,
+			TagContent: tags[0],
 		},
 	}
 }
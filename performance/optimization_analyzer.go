@@ -45,6 +45,8 @@ type OptimizationAnalysisData struct {
 	SlowQueries     []models.SlowQueryAnalysis
 	CacheAnalysis   *models.CacheAnalysisResult
 	IndexAnalysis   *models.IndexAnalysisResult
+	// VectorIndexSuggestions come from VectorIndexManager.SuggestIndexes
+	VectorIndexSuggestions []models.IndexSuggestion
 }
 
 // SlowQueryPattern represents a pattern of slow queries
@@ -169,6 +171,15 @@ func (oa *OptimizationAnalyzer) analyzeSlowQueries(ctx context.Context, data *Op
 		analysisResults = append(analysisResults, analysis)
 	}
 
+	// Statements the query monitor recorded, with their plans when captured
+	for _, statement := range data.SlowQueries {
+		statement.Impact = oa.calculateQueryImpact(SlowQueryPattern{
+			Count:       statement.Count,
+			AvgDuration: statement.AvgDuration,
+		})
+		analysisResults = append(analysisResults, statement)
+	}
+
 	// Sort by impact (highest first)
 	sort.Slice(analysisResults, func(i, j int) bool {
 		return analysisResults[i].Impact > analysisResults[j].Impact
@@ -245,9 +256,24 @@ func (oa *OptimizationAnalyzer) analyzeCachePerformance(ctx context.Context, dat
 func (oa *OptimizationAnalyzer) analyzeIndexOptimizations(ctx context.Context, data *OptimizationAnalysisData) ([]models.IndexSuggestion, error) {
 	var suggestions []models.IndexSuggestion
 
+	// Vector index suggestions are based on the live pgvector catalog and take
+	// precedence over the generic semantic search suggestion below
+	suggestions = append(suggestions, data.VectorIndexSuggestions...)
+
+	// Filtered sequential scans in captured plans point at specific missing indexes
+	suggested := make(map[string]bool)
+	for _, slowQuery := range data.SlowQueries {
+		for _, suggestion := range planIndexSuggestions(slowQuery) {
+			if !suggested[suggestion.IndexName] {
+				suggested[suggestion.IndexName] = true
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+	}
+
 	// Analyze slow queries for index opportunities
 	for _, slowQuery := range data.SlowQueries {
-		if slowQuery.QueryType == "semantic_search" {
+		if slowQuery.QueryType == "semantic_search" && len(data.VectorIndexSuggestions) == 0 {
 			suggestions = append(suggestions, models.IndexSuggestion{
 				TableName:    "chunks",
 				IndexName:    "idx_chunks_embedding_cosine",
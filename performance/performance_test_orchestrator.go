@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"path/filepath"
 	"runtime"
 	"semantic-text-processor/config"
 	"semantic-text-processor/models"
@@ -38,6 +39,10 @@ type PerformanceTestConfig struct {
 	MemoryLimitMB          int           `json:"memory_limit_mb"`
 	CPUUsageThreshold      float64       `json:"cpu_usage_threshold"`
 	GenerateMillionRecords bool          `json:"generate_million_records"`
+	// Scenario is the workload the load tests replay; nil keeps the built-in rotation
+	Scenario *WorkloadScenario `json:"scenario,omitempty"`
+	// ProfileDir receives CPU and heap profiles of every load step; empty disables capture
+	ProfileDir string `json:"profile_dir,omitempty"`
 }
 
 // NewPerformanceTestOrchestrator creates a new performance test orchestrator
@@ -46,7 +51,7 @@ func NewPerformanceTestOrchestrator(cfg *config.Config, services *services.Servi
 		config:           cfg,
 		logger:           logger,
 		services:         services,
-		dataGenerator:    NewDataGenerationService(logger),
+		dataGenerator:    NewDataGenerationService(services, logger),
 		loadExecutor:     NewLoadTestExecutor(services, logger),
 		metricsCollector: NewMetricsCollector(logger),
 		optimizer:        NewOptimizationAnalyzer(logger),
@@ -86,6 +91,10 @@ func (pto *PerformanceTestOrchestrator) ExecuteComprehensivePerformanceTest(ctx
 
 	// Phase 3: Execute load and stress tests
 	pto.logger.Printf("Phase 3: Running load and stress tests...")
+	if testConfig.ProfileDir != "" {
+		profileDir := filepath.Join(testConfig.ProfileDir, "profiles_"+startTime.Format("20060102_150405"))
+		pto.loadExecutor.SetProfiler(NewProfileCapturer(profileDir, pto.logger))
+	}
 	loadTestResult, err := pto.runLoadTests(ctx, testConfig)
 	if err != nil {
 		return nil, fmt.Errorf("load tests failed: %w", err)
@@ -100,6 +109,7 @@ func (pto *PerformanceTestOrchestrator) ExecuteComprehensivePerformanceTest(ctx
 	} else {
 		report.OptimizationAnalysis = *optimizationResult
 	}
+	report.OptimizationAnalysis.Profiles = pto.loadExecutor.Profiles()
 
 	// Phase 5: Regression testing if enabled
 	if testConfig.EnableRegression {
@@ -221,16 +231,20 @@ func (pto *PerformanceTestOrchestrator) runBaselineTests(ctx context.Context, te
 
 // runLoadTests executes progressive load testing
 func (pto *PerformanceTestOrchestrator) runLoadTests(ctx context.Context, testConfig *PerformanceTestConfig) (*models.LoadTestResult, error) {
-	loadTestConfig := &LoadTestConfig{
+	return pto.loadExecutor.ExecuteProgressiveLoadTest(ctx, NewLoadTestConfig(testConfig))
+}
+
+// NewLoadTestConfig derives the progressive load test settings from a test configuration
+func NewLoadTestConfig(testConfig *PerformanceTestConfig) *LoadTestConfig {
+	return &LoadTestConfig{
 		MaxConcurrentUsers: testConfig.MaxConcurrentUsers,
 		TestDuration:       testConfig.TestDuration,
 		RampUpTime:         testConfig.RampUpTime,
 		CooldownTime:       testConfig.CooldownTime,
 		ProgressiveLoad:    true,
 		LoadSteps:          []int{1, 5, 10, 25, 50, 100, testConfig.MaxConcurrentUsers},
+		Scenario:           testConfig.Scenario,
 	}
-
-	return pto.loadExecutor.ExecuteProgressiveLoadTest(ctx, loadTestConfig)
 }
 
 // analyzeAndOptimize performs performance analysis and generates optimization recommendations
@@ -241,6 +255,19 @@ func (pto *PerformanceTestOrchestrator) analyzeAndOptimize(ctx context.Context,
 		ResourceUsage:   &report.ResourceUtilization,
 	}
 
+	if pto.services != nil && pto.services.QueryMonitor != nil {
+		analysisData.SlowQueries = slowStatementsFromMonitor(pto.services.QueryMonitor.GetSlowQueries(0))
+	}
+
+	if pto.services != nil && pto.services.VectorIndexManager != nil {
+		vectorSuggestions, err := pto.services.VectorIndexManager.SuggestIndexes(ctx)
+		if err != nil {
+			pto.logger.Printf("Vector index analysis failed: %v", err)
+		} else {
+			analysisData.VectorIndexSuggestions = vectorSuggestions
+		}
+	}
+
 	return pto.optimizer.AnalyzePerformanceAndOptimize(ctx, analysisData)
 }
 
@@ -284,6 +311,7 @@ func (pto *PerformanceTestOrchestrator) analyzeResourceUtilization(ctx context.C
 			"cpu_usage_threshold":  testConfig.CPUUsageThreshold,
 			"slow_query_threshold": testConfig.SlowQueryThreshold,
 		},
+		Container: pto.monitor.GetContainerStats(),
 	}
 }
 
@@ -0,0 +1,99 @@
+package performance
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// ProfileCapturer records a CPU profile for the length of each load step and a heap profile
+// at its end, so hotspots seen in a report can be examined without re-running the test
+type ProfileCapturer struct {
+	dir    string
+	logger *log.Logger
+}
+
+// NewProfileCapturer creates a capturer that writes .pprof files to dir
+func NewProfileCapturer(dir string, logger *log.Logger) *ProfileCapturer {
+	return &ProfileCapturer{dir: dir, logger: logger}
+}
+
+// Start begins capturing for a step. The returned function ends the capture and returns the
+// profiles written; capture failures are logged and never fail the step.
+func (pc *ProfileCapturer) Start(step, userCount int) func() []models.ProfileArtifact {
+	if err := os.MkdirAll(pc.dir, 0755); err != nil {
+		pc.logger.Printf("Profiling disabled for step %d: failed to create %s: %v", step, pc.dir, err)
+		return func() []models.ProfileArtifact { return nil }
+	}
+
+	prefix := filepath.Join(pc.dir, fmt.Sprintf("step%02d_%dusers", step, userCount))
+	cpuPath := prefix + "_cpu.pprof"
+	cpuStart := time.Now()
+
+	// Only one CPU profile can run per process, e.g. not while /debug/pprof/profile serves one
+	cpuFile, err := os.Create(cpuPath)
+	if err != nil {
+		pc.logger.Printf("CPU profiling skipped for step %d: %v", step, err)
+	} else if err := pprof.StartCPUProfile(cpuFile); err != nil {
+		pc.logger.Printf("CPU profiling skipped for step %d: %v", step, err)
+		cpuFile.Close()
+		os.Remove(cpuPath)
+		cpuFile = nil
+	}
+
+	return func() []models.ProfileArtifact {
+		var artifacts []models.ProfileArtifact
+
+		if cpuFile != nil {
+			pprof.StopCPUProfile()
+			if err := cpuFile.Close(); err != nil {
+				pc.logger.Printf("Failed to write CPU profile for step %d: %v", step, err)
+			} else {
+				artifacts = append(artifacts, pc.artifact(step, userCount, "cpu", cpuPath, time.Since(cpuStart)))
+			}
+		}
+
+		heapPath := prefix + "_heap.pprof"
+		if err := writeHeapProfile(heapPath); err != nil {
+			pc.logger.Printf("Failed to write heap profile for step %d: %v", step, err)
+		} else {
+			artifacts = append(artifacts, pc.artifact(step, userCount, "heap", heapPath, 0))
+		}
+		return artifacts
+	}
+}
+
+// artifact describes a written profile
+func (pc *ProfileCapturer) artifact(step, userCount int, kind, path string, duration time.Duration) models.ProfileArtifact {
+	return models.ProfileArtifact{
+		Step:        step,
+		UserCount:   userCount,
+		Kind:        kind,
+		Path:        path,
+		Duration:    duration,
+		ViewCommand: fmt.Sprintf("go tool pprof -http=:0 %s", path),
+		CapturedAt:  time.Now(),
+	}
+}
+
+// writeHeapProfile writes the live heap after a collection, so the profile shows what the
+// step retained rather than garbage awaiting collection
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create heap profile: %w", err)
+	}
+	defer file.Close()
+
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		return fmt.Errorf("failed to write heap profile: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package performance
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+	"semantic-text-processor/services"
+)
+
+// minRowsRemovedForIndex is how many rows an analyzed sequential scan must filter out before
+// an index on the filtered columns is suggested
+const minRowsRemovedForIndex = 1000
+
+// slowStatementsFromMonitor groups the monitor's slow statement records by statement and
+// keeps the most recent captured plan of each
+func slowStatementsFromMonitor(records []services.SlowQueryRecord) []models.SlowQueryAnalysis {
+	byStatement := make(map[string]*models.SlowQueryAnalysis)
+	totals := make(map[string]time.Duration)
+	var order []string
+
+	for _, record := range records {
+		analysis := byStatement[record.Query]
+		if analysis == nil {
+			analysis = &models.SlowQueryAnalysis{
+				QueryPattern: record.Query,
+				QueryType:    "sql",
+			}
+			byStatement[record.Query] = analysis
+			order = append(order, record.Query)
+		}
+		analysis.Count++
+		totals[record.Query] += record.Duration
+		if record.Duration > analysis.MaxDuration {
+			analysis.MaxDuration = record.Duration
+		}
+		if record.Plan != nil {
+			analysis.Plan = record.Plan
+		}
+	}
+
+	analyses := make([]models.SlowQueryAnalysis, 0, len(order))
+	for _, query := range order {
+		analysis := byStatement[query]
+		analysis.AvgDuration = totals[query] / time.Duration(analysis.Count)
+		if analysis.Plan != nil {
+			analysis.AffectedTables = planTables(analysis.Plan)
+			analysis.OptimizationSuggestions = planSuggestions(analysis.Plan)
+		}
+		analyses = append(analyses, *analysis)
+	}
+	return analyses
+}
+
+// planTables lists the tables a plan scans sequentially
+func planTables(plan *models.QueryPlan) []string {
+	seen := make(map[string]bool)
+	var tables []string
+	for _, scan := range plan.SeqScans {
+		if scan.Table != "" && !seen[scan.Table] {
+			seen[scan.Table] = true
+			tables = append(tables, scan.Table)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+// planSuggestions describes what a plan shows in plain words
+func planSuggestions(plan *models.QueryPlan) []string {
+	var suggestions []string
+	for _, scan := range plan.SeqScans {
+		if scan.RowsRemoved > 0 {
+			suggestions = append(suggestions, fmt.Sprintf("Sequential scan on %s discards %d rows with filter %s",
+				scan.Table, scan.RowsRemoved, scan.Filter))
+		} else if scan.Filter != "" {
+			suggestions = append(suggestions, fmt.Sprintf("Sequential scan on %s with filter %s", scan.Table, scan.Filter))
+		}
+	}
+	if plan.SharedBlocksRead > 0 {
+		suggestions = append(suggestions, fmt.Sprintf("Read %d blocks from disk; consider a larger shared_buffers", plan.SharedBlocksRead))
+	}
+	return suggestions
+}
+
+// planIndexSuggestions proposes indexes for the filtered sequential scans in a slow
+// statement's plan
+func planIndexSuggestions(analysis models.SlowQueryAnalysis) []models.IndexSuggestion {
+	if analysis.Plan == nil {
+		return nil
+	}
+
+	var suggestions []models.IndexSuggestion
+	for _, scan := range analysis.Plan.SeqScans {
+		if scan.Table == "" || len(scan.Columns) == 0 {
+			continue
+		}
+		// Without ANALYZE there are no actual counts; a filtered scan is still worth a look
+		if analysis.Plan.Analyzed && scan.RowsRemoved < minRowsRemovedForIndex {
+			continue
+		}
+
+		indexType, using, columns := indexForScan(scan)
+
+		indexName := "idx_" + strings.Trim(nonIdentifierPattern.ReplaceAllString(
+			strings.ToLower(scan.Table+"_"+strings.Join(columns, "_")), "_"), "_")
+		reasoning := fmt.Sprintf("Slow statement scans %s sequentially with filter %s", scan.Table, scan.Filter)
+		if scan.RowsRemoved > 0 {
+			reasoning += fmt.Sprintf(", discarding %d rows", scan.RowsRemoved)
+		}
+
+		priority := "medium"
+		if analysis.AvgDuration >= time.Second || scan.RowsRemoved >= 100*minRowsRemovedForIndex {
+			priority = "high"
+		}
+
+		suggestions = append(suggestions, models.IndexSuggestion{
+			TableName:            scan.Table,
+			IndexName:            indexName,
+			IndexType:            indexType,
+			Columns:              columns,
+			Reasoning:            reasoning,
+			EstimatedImprovement: "Replaces the sequential scan with an index scan",
+			Priority:             priority,
+			SQLCommand: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s%s (%s);",
+				indexName, scan.Table, using, strings.Join(columns, ", ")),
+		})
+	}
+	return suggestions
+}
+
+// nonIdentifierPattern matches the runs of characters an index name cannot contain
+var nonIdentifierPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// jsonKeyPattern matches a jsonb field extraction in a plan filter, e.g. metadata ->> 'status'
+var jsonKeyPattern = regexp.MustCompile(`([a-z_][a-z0-9_]*) ->> '([^']+)'`)
+
+// indexForScan picks the index that serves a scan's filter: GIN for jsonb containment, an
+// expression index for extracted jsonb fields and a btree over the columns otherwise. It
+// returns the index type, the USING clause and the indexed columns or expressions.
+func indexForScan(scan models.PlanSeqScan) (string, string, []string) {
+	for _, column := range scan.Columns {
+		if strings.Contains(scan.Filter, column+" @>") || strings.Contains(scan.Filter, column+" ?") {
+			return "gin", " USING gin", []string{column}
+		}
+	}
+
+	var keys []string
+	for _, match := range jsonKeyPattern.FindAllStringSubmatch(scan.Filter, -1) {
+		keys = append(keys, fmt.Sprintf("(%s ->> '%s')", match[1], match[2]))
+	}
+	if len(keys) > 0 {
+		return "btree", "", keys
+	}
+	return "btree", "", scan.Columns
+}
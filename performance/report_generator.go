@@ -243,6 +243,15 @@ func (rg *ReportGenerator) generateTextReport(report *models.ComprehensivePerfor
 			report.ResourceUtilization.DatabaseConnections.ActiveConnections,
 			report.ResourceUtilization.DatabaseConnections.MaxConnections)
 	}
+	if container := report.ResourceUtilization.Container; container != nil {
+		if container.MemoryLimit > 0 {
+			content += fmt.Sprintf("Container Memory Limit: %s (peak %.1f%%, %d samples within 10%%)\n",
+				formatBytes(container.MemoryLimit), container.PeakMemoryUtilization, container.MemoryLimitWarnings)
+		}
+		if container.CPULimit > 0 {
+			content += fmt.Sprintf("Container CPU Limit: %.2f cores\n", container.CPULimit)
+		}
+	}
 	content += "\n"
 
 	// Optimization Analysis
@@ -258,6 +267,15 @@ func (rg *ReportGenerator) generateTextReport(report *models.ComprehensivePerfor
 		content += "\n"
 	}
 
+	if len(report.OptimizationAnalysis.Profiles) > 0 {
+		content += "=== PROFILES ===\n"
+		for _, profile := range report.OptimizationAnalysis.Profiles {
+			content += fmt.Sprintf("- step %d (%d users) %s: %s\n",
+				profile.Step, profile.UserCount, profile.Kind, profile.Path)
+		}
+		content += "\n"
+	}
+
 	// Recommendations
 	if len(report.Recommendations) > 0 {
 		content += "=== TOP RECOMMENDATIONS ===\n"
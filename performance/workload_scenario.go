@@ -0,0 +1,396 @@
+package performance
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"semantic-text-processor/models"
+)
+
+// Workload operation types a scenario can mix
+const (
+	OperationSemanticSearch = "semantic_search"
+	OperationTagSearch      = "tag_search"
+	OperationChunkRead      = "chunk_crud"
+	OperationWrite          = "write"
+)
+
+// Distributions for think time and scenario values
+const (
+	DistributionConstant    = "constant"
+	DistributionUniform     = "uniform"
+	DistributionExponential = "exponential"
+	DistributionZipf        = "zipf"
+)
+
+// WorkloadScenario describes the traffic a load test replays: which operations virtual users
+// issue and how often, how long they pause between requests, and which values they send
+type WorkloadScenario struct {
+	Name        string              `yaml:"name" json:"name"`
+	Description string              `yaml:"description,omitempty" json:"description,omitempty"`
+	Operations  []ScenarioOperation `yaml:"operations" json:"operations"`
+	ThinkTime   ThinkTimeSpec       `yaml:"think_time" json:"think_time"`
+	Data        ScenarioData        `yaml:"data" json:"data"`
+}
+
+// ScenarioOperation is one operation in the mix. Weight is its share of requests in percent.
+type ScenarioOperation struct {
+	Type   string  `yaml:"type" json:"type"`
+	Weight float64 `yaml:"weight" json:"weight"`
+}
+
+// ThinkTimeSpec describes the pause between a virtual user's requests. Constant pauses for
+// Mean, uniform between Min and Max, and exponential around Mean, capped at Max when set.
+type ThinkTimeSpec struct {
+	Distribution string        `yaml:"distribution" json:"distribution"`
+	Mean         time.Duration `yaml:"mean" json:"mean"`
+	Min          time.Duration `yaml:"min" json:"min"`
+	Max          time.Duration `yaml:"max" json:"max"`
+}
+
+// ScenarioData describes the values operations send
+type ScenarioData struct {
+	Queries       ValueDistribution `yaml:"queries" json:"queries"`
+	Tags          ValueDistribution `yaml:"tags" json:"tags"`
+	Limit         IntRange          `yaml:"limit" json:"limit"`
+	MinSimilarity float64           `yaml:"min_similarity" json:"min_similarity"`
+	ContentLength IntRange          `yaml:"content_length" json:"content_length"`
+}
+
+// ValueDistribution picks values from a list, uniformly or with a zipf skew that favours
+// values earlier in the list the way real traffic favours popular queries
+type ValueDistribution struct {
+	Values       []string `yaml:"values" json:"values"`
+	Distribution string   `yaml:"distribution" json:"distribution"`
+	// Skew is the zipf exponent, greater than 1; the default is 1.2
+	Skew float64 `yaml:"skew,omitempty" json:"skew,omitempty"`
+}
+
+// IntRange is an inclusive range sampled uniformly
+type IntRange struct {
+	Min int `yaml:"min" json:"min"`
+	Max int `yaml:"max" json:"max"`
+}
+
+// DefaultWorkloadScenario returns the mix the load generator used before scenarios existed:
+// equal shares of semantic search, tag search and chunk reads with a fixed think time
+func DefaultWorkloadScenario() *WorkloadScenario {
+	return &WorkloadScenario{
+		Name: "default",
+		Operations: []ScenarioOperation{
+			{Type: OperationSemanticSearch, Weight: 34},
+			{Type: OperationTagSearch, Weight: 33},
+			{Type: OperationChunkRead, Weight: 33},
+		},
+		ThinkTime: ThinkTimeSpec{Distribution: DistributionConstant, Mean: 100 * time.Millisecond},
+		Data: ScenarioData{
+			Queries: ValueDistribution{
+				Values: []string{
+					"machine learning algorithms",
+					"database optimization techniques",
+					"performance monitoring tools",
+					"web application security",
+					"cloud computing services",
+					"data analysis methods",
+					"software architecture patterns",
+					"API design best practices",
+				},
+				Distribution: DistributionUniform,
+			},
+			Tags: ValueDistribution{
+				Values:       []string{"programming", "database", "web", "cloud", "machine-learning", "performance"},
+				Distribution: DistributionUniform,
+			},
+			Limit:         IntRange{Min: 10, Max: 10},
+			MinSimilarity: 0.7,
+			ContentLength: IntRange{Min: 200, Max: 800},
+		},
+	}
+}
+
+// LoadWorkloadScenario reads and validates a YAML scenario file. Unset data falls back to
+// the default scenario's values.
+func LoadWorkloadScenario(path string) (*WorkloadScenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scenario file: %w", err)
+	}
+
+	var scenario WorkloadScenario
+	if err := yaml.UnmarshalStrict(data, &scenario); err != nil {
+		return nil, fmt.Errorf("failed to parse scenario file %s: %w", path, err)
+	}
+	if scenario.Name == "" {
+		scenario.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	scenario.applyDefaults()
+
+	if err := scenario.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid scenario %s: %w", path, err)
+	}
+	return &scenario, nil
+}
+
+// applyDefaults fills unset data and think time from the default scenario
+func (s *WorkloadScenario) applyDefaults() {
+	defaults := DefaultWorkloadScenario()
+	if s.ThinkTime.Distribution == "" {
+		s.ThinkTime.Distribution = DistributionConstant
+	}
+	if len(s.Data.Queries.Values) == 0 {
+		s.Data.Queries.Values = defaults.Data.Queries.Values
+	}
+	if len(s.Data.Tags.Values) == 0 {
+		s.Data.Tags.Values = defaults.Data.Tags.Values
+	}
+	for _, values := range []*ValueDistribution{&s.Data.Queries, &s.Data.Tags} {
+		if values.Distribution == "" {
+			values.Distribution = DistributionUniform
+		}
+		if values.Distribution == DistributionZipf && values.Skew == 0 {
+			values.Skew = 1.2
+		}
+	}
+	if s.Data.Limit.Min == 0 && s.Data.Limit.Max == 0 {
+		s.Data.Limit = defaults.Data.Limit
+	}
+	if s.Data.MinSimilarity == 0 {
+		s.Data.MinSimilarity = defaults.Data.MinSimilarity
+	}
+	if s.Data.ContentLength.Min == 0 && s.Data.ContentLength.Max == 0 {
+		s.Data.ContentLength = defaults.Data.ContentLength
+	}
+}
+
+// Validate checks that the operation weights add up to 100 percent and that every
+// distribution is known and consistent
+func (s *WorkloadScenario) Validate() error {
+	if len(s.Operations) == 0 {
+		return fmt.Errorf("scenario has no operations")
+	}
+
+	total := 0.0
+	seen := make(map[string]bool, len(s.Operations))
+	for _, op := range s.Operations {
+		switch op.Type {
+		case OperationSemanticSearch, OperationTagSearch, OperationChunkRead, OperationWrite:
+		default:
+			return fmt.Errorf("unknown operation type %q", op.Type)
+		}
+		if seen[op.Type] {
+			return fmt.Errorf("operation %s is listed twice", op.Type)
+		}
+		seen[op.Type] = true
+		if op.Weight < 0 {
+			return fmt.Errorf("operation %s has a negative weight", op.Type)
+		}
+		total += op.Weight
+	}
+	if math.Abs(total-100) > 0.01 {
+		return fmt.Errorf("operation weights add up to %.2f%%, not 100%%", total)
+	}
+
+	switch s.ThinkTime.Distribution {
+	case DistributionConstant, DistributionExponential:
+		if s.ThinkTime.Mean < 0 {
+			return fmt.Errorf("think time mean must not be negative")
+		}
+	case DistributionUniform:
+		if s.ThinkTime.Min < 0 || s.ThinkTime.Max < s.ThinkTime.Min {
+			return fmt.Errorf("think time range %v-%v is invalid", s.ThinkTime.Min, s.ThinkTime.Max)
+		}
+	default:
+		return fmt.Errorf("unknown think time distribution %q", s.ThinkTime.Distribution)
+	}
+
+	for name, values := range map[string]ValueDistribution{"queries": s.Data.Queries, "tags": s.Data.Tags} {
+		switch values.Distribution {
+		case DistributionUniform:
+		case DistributionZipf:
+			if values.Skew <= 1 {
+				return fmt.Errorf("%s zipf skew must be greater than 1", name)
+			}
+		default:
+			return fmt.Errorf("unknown %s distribution %q", name, values.Distribution)
+		}
+	}
+	for name, r := range map[string]IntRange{"limit": s.Data.Limit, "content_length": s.Data.ContentLength} {
+		if r.Min <= 0 || r.Max < r.Min {
+			return fmt.Errorf("%s range %d-%d is invalid", name, r.Min, r.Max)
+		}
+	}
+	return nil
+}
+
+// ScenarioSampler draws operations, think times and values from a scenario. It is safe for
+// concurrent use by virtual users.
+type ScenarioSampler struct {
+	scenario   *WorkloadScenario
+	cumulative []float64
+
+	mu      sync.Mutex
+	rng     *rand.Rand
+	queries *rand.Zipf
+	tags    *rand.Zipf
+}
+
+// NewScenarioSampler creates a sampler for a validated scenario
+func NewScenarioSampler(scenario *WorkloadScenario, seed int64) *ScenarioSampler {
+	sampler := &ScenarioSampler{
+		scenario: scenario,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+
+	total := 0.0
+	for _, op := range scenario.Operations {
+		total += op.Weight
+		sampler.cumulative = append(sampler.cumulative, total)
+	}
+
+	data := scenario.Data
+	if data.Queries.Distribution == DistributionZipf && len(data.Queries.Values) > 1 {
+		sampler.queries = rand.NewZipf(sampler.rng, data.Queries.Skew, 1, uint64(len(data.Queries.Values)-1))
+	}
+	if data.Tags.Distribution == DistributionZipf && len(data.Tags.Values) > 1 {
+		sampler.tags = rand.NewZipf(sampler.rng, data.Tags.Skew, 1, uint64(len(data.Tags.Values)-1))
+	}
+	return sampler
+}
+
+// NextOperation picks the type of a virtual user's next request by weight
+func (s *ScenarioSampler) NextOperation() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	point := s.rng.Float64() * s.cumulative[len(s.cumulative)-1]
+	for i, bound := range s.cumulative {
+		if point < bound {
+			return s.scenario.Operations[i].Type
+		}
+	}
+	return s.scenario.Operations[len(s.scenario.Operations)-1].Type
+}
+
+// ThinkTime draws the pause before a virtual user's next request
+func (s *ScenarioSampler) ThinkTime() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	spec := s.scenario.ThinkTime
+	switch spec.Distribution {
+	case DistributionUniform:
+		if spec.Max == spec.Min {
+			return spec.Min
+		}
+		return spec.Min + time.Duration(s.rng.Int63n(int64(spec.Max-spec.Min)+1))
+	case DistributionExponential:
+		pause := time.Duration(s.rng.ExpFloat64() * float64(spec.Mean))
+		if spec.Max > 0 && pause > spec.Max {
+			pause = spec.Max
+		}
+		return pause
+	default:
+		return spec.Mean
+	}
+}
+
+// GenerateQuery builds a request of the given operation type from the scenario's data
+func (s *ScenarioSampler) GenerateQuery(operation string) models.TestQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.scenario.Data
+	switch operation {
+	case OperationSemanticSearch:
+		return models.TestQuery{
+			Type: OperationSemanticSearch,
+			Parameters: &models.OptimizedSearchRequest{
+				Query:         s.pick(data.Queries.Values, s.queries),
+				Limit:         s.between(data.Limit),
+				MinSimilarity: data.MinSimilarity,
+				UseCache:      true,
+			},
+		}
+	case OperationTagSearch:
+		return models.TestQuery{
+			Type: OperationTagSearch,
+			Parameters: &models.TagSearchRequest{
+				TagContent: s.pick(data.Tags.Values, s.tags),
+			},
+		}
+	case OperationWrite:
+		return models.TestQuery{
+			Type: OperationWrite,
+			Parameters: map[string]interface{}{
+				"content": s.content(data),
+				"tag":     s.pick(data.Tags.Values, s.tags),
+			},
+		}
+	default:
+		return models.TestQuery{
+			Type: OperationChunkRead,
+			Parameters: map[string]interface{}{
+				"operation": "read",
+				"limit":     s.between(data.Limit),
+				"offset":    0,
+			},
+		}
+	}
+}
+
+// pick draws a value uniformly, or from zipf when one is set
+func (s *ScenarioSampler) pick(values []string, zipf *rand.Zipf) string {
+	if len(values) == 0 {
+		return ""
+	}
+	if zipf != nil {
+		return values[zipf.Uint64()]
+	}
+	return values[s.rng.Intn(len(values))]
+}
+
+// between draws an integer from an inclusive range
+func (s *ScenarioSampler) between(r IntRange) int {
+	if r.Max <= r.Min {
+		return r.Min
+	}
+	return r.Min + s.rng.Intn(r.Max-r.Min+1)
+}
+
+// content builds note text of a sampled length out of the scenario's queries, so that
+// written chunks are findable by the searches the scenario issues
+func (s *ScenarioSampler) content(data ScenarioData) string {
+	length := s.between(data.ContentLength)
+	var text []rune
+	for len(text) < length {
+		if len(text) > 0 {
+			text = append(text, '.', ' ')
+		}
+		text = append(text, []rune(s.pick(data.Queries.Values, s.queries))...)
+	}
+	return string(text[:length])
+}
+
+// scenarioQueryGenerator adapts a sampler to QueryGenerator for one operation type
+type scenarioQueryGenerator struct {
+	sampler   *ScenarioSampler
+	operation string
+}
+
+// GenerateQuery draws a request from the scenario
+func (g *scenarioQueryGenerator) GenerateQuery() models.TestQuery {
+	return g.sampler.GenerateQuery(g.operation)
+}
+
+// GetQueryType returns the generator's operation type
+func (g *scenarioQueryGenerator) GetQueryType() string {
+	return g.operation
+}
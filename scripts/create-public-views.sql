@@ -61,6 +61,7 @@ DO INSTEAD UPDATE content_db.chunks SET
     slot_value = NEW.slot_value,
     indent_level = NEW.indent_level,
     sequence_number = NEW.sequence_number,
+    sort_key = NEW.sort_key,
     metadata = NEW.metadata,
     updated_at = NEW.updated_at
 WHERE id = OLD.id;
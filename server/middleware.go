@@ -1,8 +1,13 @@
 package server
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"semantic-text-processor/clients"
+	"semantic-text-processor/handlers"
 	"semantic-text-processor/services"
 	"strings"
 	"time"
@@ -20,6 +25,11 @@ func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 		
 		duration := time.Since(start)
 		
+		// Probes arrive every few seconds; log only the failing ones
+		if handlers.IsProbePath(r.URL.Path) && wrapper.statusCode == http.StatusOK {
+			return
+		}
+		
 		// Use structured logger if available
 		if s.services.Logger != nil {
 			s.services.Logger.Info("HTTP request",
@@ -76,7 +86,8 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 		}
 		
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept, Origin")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Requested-With, Accept, Origin, X-Workspace-ID, X-Chunk-Lock, X-Supabase-Auth, If-None-Match, If-Modified-Since")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Chunk-Version")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "86400") // 24 hours
 		
@@ -100,6 +111,107 @@ func (s *Server) contentTypeMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// workspaceMiddleware carries the X-Workspace-ID header in the request context, so
+// services such as PII scanning apply the workspace's policies
+func (s *Server) workspaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if workspace, err := services.NormalizeWorkspace(r.Header.Get(handlers.WorkspaceHeader)); err == nil {
+			r = r.WithContext(services.WithWorkspace(r.Context(), workspace))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// chunkLockMiddleware carries the X-Chunk-Lock header in the request context, so writes
+// from the lock's holder may restructure the subtree it covers
+func (s *Server) chunkLockMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := strings.TrimSpace(r.Header.Get(handlers.ChunkLockHeader)); token != "" {
+			r = r.WithContext(services.WithChunkLockToken(r.Context(), token))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authMiddleware requires a bearer API token or JWT on every request except health
+// checks, probes, published pages and inbound email webhooks, which EmailIngestHandler
+// authenticates with the webhook password or the Mailgun signature. The caller's role and
+// workspace are carried in the request context; a request naming another workspace in
+// X-Workspace-ID is refused.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/v1/health") || handlers.IsProbePath(r.URL.Path) ||
+			strings.HasPrefix(r.URL.Path, services.PublicPagePath) || strings.HasPrefix(r.URL.Path, services.EmailWebhookPath) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		credential, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || strings.TrimSpace(credential) == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAuthError(w, http.StatusUnauthorized, "authorization bearer token required")
+			return
+		}
+
+		principal, err := s.services.APITokens.Authenticate(r.Context(), strings.TrimSpace(credential))
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCredential) {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				writeAuthError(w, http.StatusUnauthorized, "invalid or expired token")
+				return
+			}
+			log.Printf("Failed to authenticate request: %v", err)
+			writeAuthError(w, http.StatusServiceUnavailable, "authentication unavailable")
+			return
+		}
+
+		if requested := r.Header.Get(handlers.WorkspaceHeader); requested != "" && requested != principal.Workspace {
+			writeAuthError(w, http.StatusForbidden, fmt.Sprintf("token is not valid for workspace %s", requested))
+			return
+		}
+		// Handlers that read the workspace header see the token's workspace
+		r.Header.Set(handlers.WorkspaceHeader, principal.Workspace)
+
+		ctx := services.WithWorkspace(r.Context(), principal.Workspace)
+		ctx = services.WithPrincipal(ctx, principal)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// supabaseUserTokenHeader carries the end user's Supabase access token, kept apart from
+// Authorization, which authenticates the caller to this service
+const supabaseUserTokenHeader = "X-Supabase-Auth"
+
+// supabaseUserTokenMiddleware forwards the X-Supabase-Auth token to Supabase, so the
+// request's reads and writes are subject to the project's row-level security policies.
+// Requests without the header keep using the service key.
+func (s *Server) supabaseUserTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.Header.Get(supabaseUserTokenHeader); token != "" {
+			r = r.WithContext(clients.WithUserToken(r.Context(), token))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requirePermission refuses callers whose role lacks perm. Routes served by services
+// that check permissions themselves do not need it.
+func (s *Server) requirePermission(perm services.Permission, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := services.Authorize(r.Context(), perm); err != nil {
+			writeAuthError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func writeAuthError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
 // performanceMiddleware tracks request performance metrics
 func (s *Server) performanceMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -149,4 +261,9 @@ type responseWriter struct {
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
-}
\ No newline at end of file
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController, e.g. for flushing streams
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
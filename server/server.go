@@ -32,6 +32,37 @@ type Server struct {
 	tagHandler      handlers.TagHandlerInterface
 	simpleMediaHandler    *handlers.SimpleMediaHandler
 	aiHandler       *handlers.AIHandler
+	backlinkHandler *handlers.BacklinkHandler
+	graphAnalyticsHandler *handlers.GraphAnalyticsHandler
+	graphExportHandler    *handlers.GraphExportHandler
+	graphEvidenceHandler  *handlers.GraphEvidenceHandler
+	graphTemporalHandler  *handlers.GraphTemporalHandler
+	entityResolutionHandler *handlers.EntityResolutionHandler
+	changeFeedHandler     *handlers.ChangeFeedHandler
+	liveOutlineHandler    *handlers.LiveOutlineHandler
+	syncHandler           *handlers.SyncHandler
+	bulkTagHandler        *handlers.BulkTagHandler
+	outlineExchangeHandler *handlers.OutlineExchangeHandler
+	noteImportHandler      *handlers.NoteImportHandler
+	pageACLHandler         *handlers.PageACLHandler
+	chunkLockHandler       *handlers.ChunkLockHandler
+	captureHandler         *handlers.CaptureHandler
+	emailIngestHandler     *handlers.EmailIngestHandler
+	journalHandler         *handlers.JournalHandler
+	taskHandler            *handlers.TaskHandler
+	graphQLHandler         *handlers.GraphQLHandler
+	statsHandler           *handlers.StatsHandler
+	vectorIndexHandler *handlers.VectorIndexHandler
+	embeddingBatchHandler  *handlers.EmbeddingBatchHandler
+	optimizedSearchHandler *handlers.OptimizedSearchHandler
+	querySuggestionHandler *handlers.QuerySuggestionHandler
+	ragHandler             *handlers.RAGHandler
+	summaryHandler         *handlers.SummaryHandler
+	chunkHistoryHandler    *handlers.ChunkHistoryHandler
+	pageRenderHandler      *handlers.PageRenderHandler
+	publicationHandler     *handlers.PublicationHandler
+	apiTokenHandler        *handlers.APITokenHandler
+	retentionHandler       *handlers.RetentionHandler
 }
 
 // NewServer creates a new server instance
@@ -71,6 +102,333 @@ func NewServer(cfg *config.Config) *Server {
 	tagHandler := handlerFactory.CreateTagHandler()
 	simpleMediaHandler := handlers.NewSimpleMediaHandler(cfg)
 	aiHandler := handlers.NewAIHandler()
+
+	var backlinkHandler *handlers.BacklinkHandler
+	if serviceContainer.BacklinkService != nil {
+		backlinkHandler = handlers.NewBacklinkHandler(
+			serviceContainer.BacklinkService,
+			log.New(os.Stderr, "[backlinks] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var graphAnalyticsHandler *handlers.GraphAnalyticsHandler
+	if serviceContainer.GraphAnalytics != nil {
+		graphAnalyticsHandler = handlers.NewGraphAnalyticsHandler(
+			serviceContainer.GraphAnalytics,
+			log.New(os.Stderr, "[graph] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var entityResolutionHandler *handlers.EntityResolutionHandler
+	if serviceContainer.EntityResolution != nil {
+		entityResolutionHandler = handlers.NewEntityResolutionHandler(
+			serviceContainer.EntityResolution,
+			log.New(os.Stderr, "[graph] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var graphExportHandler *handlers.GraphExportHandler
+	if serviceContainer.GraphExport != nil {
+		graphExportHandler = handlers.NewGraphExportHandler(
+			serviceContainer.GraphExport,
+			log.New(os.Stderr, "[graph] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var graphEvidenceHandler *handlers.GraphEvidenceHandler
+	if serviceContainer.GraphEvidence != nil {
+		graphEvidenceHandler = handlers.NewGraphEvidenceHandler(
+			serviceContainer.GraphEvidence,
+			log.New(os.Stderr, "[graph] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var graphTemporalHandler *handlers.GraphTemporalHandler
+	if serviceContainer.GraphTemporal != nil && serviceContainer.GraphConflicts != nil {
+		graphTemporalHandler = handlers.NewGraphTemporalHandler(
+			serviceContainer.GraphTemporal,
+			serviceContainer.GraphConflicts,
+			log.New(os.Stderr, "[graph] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var changeFeedHandler *handlers.ChangeFeedHandler
+	if serviceContainer.ChangeFeed != nil {
+		changeFeedHandler = handlers.NewChangeFeedHandler(
+			serviceContainer.ChangeFeed,
+			cfg.ChangeFeed.SubscriberBuffer,
+			cfg.ChangeFeed.Heartbeat,
+			log.New(os.Stderr, "[changes] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var liveOutlineHandler *handlers.LiveOutlineHandler
+	if serviceContainer.LiveOutline != nil {
+		liveOutlineHandler = handlers.NewLiveOutlineHandler(
+			serviceContainer.LiveOutline,
+			cfg.ChangeFeed.Heartbeat,
+			log.New(os.Stderr, "[live] ", log.LstdFlags),
+		)
+	}
+
+	var syncHandler *handlers.SyncHandler
+	if serviceContainer.ChunkSync != nil {
+		syncHandler = handlers.NewSyncHandler(
+			serviceContainer.ChunkSync,
+			log.New(os.Stderr, "[sync] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var bulkTagHandler *handlers.BulkTagHandler
+	if serviceContainer.BulkTags != nil {
+		bulkTagHandler = handlers.NewBulkTagHandler(
+			serviceContainer.BulkTags,
+			log.New(os.Stderr, "[bulk-tag] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var outlineExchangeHandler *handlers.OutlineExchangeHandler
+	if serviceContainer.OutlineExchange != nil {
+		outlineExchangeHandler = handlers.NewOutlineExchangeHandler(
+			serviceContainer.OutlineExchange,
+			log.New(os.Stderr, "[outline-exchange] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var noteImportHandler *handlers.NoteImportHandler
+	if serviceContainer.NoteImport != nil {
+		noteImportHandler = handlers.NewNoteImportHandler(
+			serviceContainer.NoteImport,
+			log.New(os.Stderr, "[note-import] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var graphQLHandler *handlers.GraphQLHandler
+	if serviceContainer.GraphQL != nil {
+		graphQLHandler = handlers.NewGraphQLHandler(
+			serviceContainer.GraphQL,
+			log.New(os.Stderr, "[graphql] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var pageACLHandler *handlers.PageACLHandler
+	if serviceContainer.PageACLs != nil {
+		pageACLHandler = handlers.NewPageACLHandler(
+			serviceContainer.PageACLs,
+			log.New(os.Stderr, "[page-acl] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+		if unifiedHandler, ok := chunkHandler.(*handlers.UnifiedChunkHandler); ok {
+			unifiedHandler.SetPageACLs(serviceContainer.PageACLs)
+			unifiedHandler.SetHTTPCache(handlers.HTTPCachePolicy{
+				Enabled: cfg.Server.HTTPCacheEnabled,
+				MaxAge:  cfg.Server.HTTPCacheMaxAge,
+			})
+		}
+	}
+
+	var chunkLockHandler *handlers.ChunkLockHandler
+	if serviceContainer.ChunkLocks != nil {
+		chunkLockHandler = handlers.NewChunkLockHandler(
+			serviceContainer.ChunkLocks,
+			log.New(os.Stderr, "[chunk-lock] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+		if unifiedHandler, ok := chunkHandler.(*handlers.UnifiedChunkHandler); ok {
+			unifiedHandler.SetChunkLocks(serviceContainer.ChunkLocks)
+		}
+	}
+
+	var captureHandler *handlers.CaptureHandler
+	if serviceContainer.Capture != nil {
+		captureHandler = handlers.NewCaptureHandler(
+			serviceContainer.Capture,
+			cfg.Capture.MaxBytes,
+			log.New(os.Stderr, "[capture] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var journalHandler *handlers.JournalHandler
+	if serviceContainer.Journal != nil {
+		journalHandler = handlers.NewJournalHandler(
+			serviceContainer.Journal,
+			log.New(os.Stderr, "[journal] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var taskHandler *handlers.TaskHandler
+	if serviceContainer.Tasks != nil {
+		taskHandler = handlers.NewTaskHandler(
+			serviceContainer.Tasks,
+			log.New(os.Stderr, "[tasks] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var emailIngestHandler *handlers.EmailIngestHandler
+	if serviceContainer.EmailIngest != nil && (cfg.EmailIngest.WebhookSecret != "" || cfg.EmailIngest.MailgunSigningKey != "") {
+		emailIngestHandler = handlers.NewEmailIngestHandler(
+			serviceContainer.EmailIngest,
+			cfg.EmailIngest.WebhookSecret,
+			cfg.EmailIngest.MailgunSigningKey,
+			cfg.EmailIngest.MaxBytes,
+			log.New(os.Stderr, "[email] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var vectorIndexHandler *handlers.VectorIndexHandler
+	if serviceContainer.VectorIndexManager != nil {
+		vectorIndexHandler = handlers.NewVectorIndexHandler(
+			serviceContainer.VectorIndexManager,
+			log.New(os.Stderr, "[vector-index] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var embeddingBatchHandler *handlers.EmbeddingBatchHandler
+	if serviceContainer.EmbeddingBatch != nil {
+		embeddingBatchHandler = handlers.NewEmbeddingBatchHandler(
+			serviceContainer.EmbeddingBatch,
+			log.New(os.Stderr, "[embeddings] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var optimizedSearchHandler *handlers.OptimizedSearchHandler
+	if serviceContainer.OptimizedSearch != nil {
+		optimizedSearchHandler = handlers.NewOptimizedSearchHandler(
+			serviceContainer.OptimizedSearch,
+			log.New(os.Stderr, "[search] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var querySuggestionHandler *handlers.QuerySuggestionHandler
+	if serviceContainer.QuerySuggestions != nil {
+		querySuggestionHandler = handlers.NewQuerySuggestionHandler(
+			serviceContainer.QuerySuggestions,
+			log.New(os.Stderr, "[search] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var ragHandler *handlers.RAGHandler
+	if serviceContainer.RAGService != nil {
+		ragHandler = handlers.NewRAGHandler(
+			serviceContainer.RAGService,
+			log.New(os.Stderr, "[ask] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var summaryHandler *handlers.SummaryHandler
+	if serviceContainer.Summarization != nil {
+		summaryHandler = handlers.NewSummaryHandler(
+			serviceContainer.Summarization,
+			log.New(os.Stderr, "[summary] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var chunkHistoryHandler *handlers.ChunkHistoryHandler
+	if serviceContainer.ChunkHistory != nil {
+		chunkHistoryHandler = handlers.NewChunkHistoryHandler(
+			serviceContainer.ChunkHistory,
+			log.New(os.Stderr, "[history] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var pageRenderHandler *handlers.PageRenderHandler
+	if serviceContainer.PageRender != nil {
+		pageRenderHandler = handlers.NewPageRenderHandler(
+			serviceContainer.PageRender,
+			log.New(os.Stderr, "[render] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+		pageRenderHandler.SetHTTPCache(handlers.HTTPCachePolicy{
+			Enabled: cfg.Server.HTTPCacheEnabled,
+			MaxAge:  cfg.Server.HTTPCacheMaxAge,
+		})
+	}
+
+	var publicationHandler *handlers.PublicationHandler
+	if serviceContainer.Publishing != nil {
+		publicationHandler = handlers.NewPublicationHandler(
+			serviceContainer.Publishing,
+			log.New(os.Stderr, "[publish] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	apiTokenHandler := handlers.NewAPITokenHandler(
+		serviceContainer.APITokens,
+		log.New(os.Stderr, "[auth] ", log.LstdFlags),
+		slowQueryThreshold,
+		cfg.Performance.MetricsEnabled,
+	)
+
+	var statsHandler *handlers.StatsHandler
+	if serviceContainer.Stats != nil {
+		statsHandler = handlers.NewStatsHandler(
+			serviceContainer.Stats,
+			log.New(os.Stderr, "[stats] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
+
+	var retentionHandler *handlers.RetentionHandler
+	if serviceContainer.Retention != nil {
+		retentionHandler = handlers.NewRetentionHandler(
+			serviceContainer.Retention,
+			log.New(os.Stderr, "[retention] ", log.LstdFlags),
+			slowQueryThreshold,
+			cfg.Performance.MetricsEnabled,
+		)
+	}
 	
 	server := &Server{
 		config:          cfg,
@@ -83,6 +441,37 @@ func NewServer(cfg *config.Config) *Server {
 		tagHandler:      tagHandler,
 		simpleMediaHandler:    simpleMediaHandler,
 		aiHandler:       aiHandler,
+		backlinkHandler: backlinkHandler,
+		graphAnalyticsHandler: graphAnalyticsHandler,
+		graphExportHandler:    graphExportHandler,
+		graphEvidenceHandler:  graphEvidenceHandler,
+		graphTemporalHandler:  graphTemporalHandler,
+		entityResolutionHandler: entityResolutionHandler,
+		changeFeedHandler:     changeFeedHandler,
+		liveOutlineHandler:    liveOutlineHandler,
+		syncHandler:           syncHandler,
+		bulkTagHandler:        bulkTagHandler,
+		outlineExchangeHandler: outlineExchangeHandler,
+		noteImportHandler:      noteImportHandler,
+		pageACLHandler:         pageACLHandler,
+		chunkLockHandler:       chunkLockHandler,
+		captureHandler:         captureHandler,
+		emailIngestHandler:     emailIngestHandler,
+		journalHandler:         journalHandler,
+		taskHandler:            taskHandler,
+		graphQLHandler:         graphQLHandler,
+		statsHandler:           statsHandler,
+		vectorIndexHandler: vectorIndexHandler,
+		embeddingBatchHandler:  embeddingBatchHandler,
+		optimizedSearchHandler: optimizedSearchHandler,
+		querySuggestionHandler: querySuggestionHandler,
+		ragHandler:             ragHandler,
+		summaryHandler:         summaryHandler,
+		chunkHistoryHandler:    chunkHistoryHandler,
+		pageRenderHandler:      pageRenderHandler,
+		publicationHandler:     publicationHandler,
+		apiTokenHandler:        apiTokenHandler,
+		retentionHandler:       retentionHandler,
 		httpServer: &http.Server{
 			Addr:         ":" + cfg.Server.Port,
 			Handler:      router,
@@ -101,38 +490,68 @@ func NewServer(cfg *config.Config) *Server {
 // setupRoutes configures all HTTP routes
 func (s *Server) setupRoutes() {
 
+	// Liveness, readiness and startup probes for orchestrators such as Kubernetes
+	if s.services.Probes != nil {
+		probes := handlers.NewProbeHandler(s.services.Probes)
+		s.router.HandleFunc(handlers.LivenessPath, probes.Liveness).Methods("GET")
+		s.router.HandleFunc(handlers.ReadinessPath, probes.Readiness).Methods("GET")
+		s.router.HandleFunc(handlers.StartupPath, probes.Startup).Methods("GET")
+	}
+
 	// API version prefix
 	api := s.router.PathPrefix("/api/v1").Subrouter()
 
 	// Health check
 	api.HandleFunc("/health", s.healthCheck).Methods("GET", "OPTIONS")
+	api.HandleFunc("/health/database", s.databaseHealthHandler).Methods("GET")
+	api.HandleFunc("/compliance/pii", s.piiComplianceHandler).Methods("GET")
 	
 	// Performance and monitoring endpoints
 	if s.config.Performance.MetricsEnabled && s.services.MetricsService != nil {
 		api.HandleFunc(s.config.Performance.MetricsEndpoint, s.metricsHandler).Methods("GET")
 	}
 	api.HandleFunc("/cache/stats", s.cacheStatsHandler).Methods("GET")
-	api.HandleFunc("/cache/clear", s.cacheClearHandler).Methods("POST")
+	api.HandleFunc("/embeddings/stats", s.embeddingStatsHandler).Methods("GET")
+	api.HandleFunc("/cache/clear", s.requirePermission(services.PermissionAdmin, s.cacheClearHandler)).Methods("POST")
+
+	// API token management for admins of the caller's workspace
+	api.HandleFunc("/tokens", s.apiTokenHandler.ListTokens).Methods("GET")
+	api.HandleFunc("/tokens", s.apiTokenHandler.CreateToken).Methods("POST")
+	api.HandleFunc("/tokens/{id}", s.apiTokenHandler.RevokeToken).Methods("DELETE")
+
+	// Retention rules for admins of the caller's workspace
+	if s.retentionHandler != nil {
+		api.HandleFunc("/retention/rules", s.retentionHandler.ListRules).Methods("GET")
+		api.HandleFunc("/retention/rules", s.retentionHandler.CreateRule).Methods("POST")
+		api.HandleFunc("/retention/rules/{id}", s.retentionHandler.UpdateRule).Methods("PUT")
+		api.HandleFunc("/retention/rules/{id}", s.retentionHandler.DeleteRule).Methods("DELETE")
+		api.HandleFunc("/retention/preview", s.retentionHandler.Preview).Methods("GET")
+		api.HandleFunc("/retention/run", s.retentionHandler.Run).Methods("POST")
+	}
 
 	// Text routes
-	api.HandleFunc("/texts", s.textHandler.CreateText).Methods("POST")
+	api.HandleFunc("/texts", s.requirePermission(services.PermissionWrite, s.textHandler.CreateText)).Methods("POST")
 	api.HandleFunc("/texts", s.textHandler.GetTexts).Methods("GET")
 	api.HandleFunc("/texts/{id}", s.textHandler.GetTextByID).Methods("GET")
-	api.HandleFunc("/texts/{id}", s.textHandler.UpdateText).Methods("PUT")
-	api.HandleFunc("/texts/{id}", s.textHandler.DeleteText).Methods("DELETE")
+	api.HandleFunc("/texts/{id}", s.requirePermission(services.PermissionWrite, s.textHandler.UpdateText)).Methods("PUT")
+	api.HandleFunc("/texts/{id}", s.requirePermission(services.PermissionWrite, s.textHandler.DeleteText)).Methods("DELETE")
 	api.HandleFunc("/texts/{id}/structure", s.textHandler.GetTextStructure).Methods("GET")
-	api.HandleFunc("/texts/{id}/structure", s.textHandler.UpdateTextStructure).Methods("PUT")
+	api.HandleFunc("/texts/{id}/structure", s.requirePermission(services.PermissionWrite, s.textHandler.UpdateTextStructure)).Methods("PUT")
 
 	// Template routes
 	api.HandleFunc("/templates", s.templateHandler.CreateTemplate).Methods("POST")
 	api.HandleFunc("/templates", s.templateHandler.GetAllTemplates).Methods("GET")
 	api.HandleFunc("/templates/{content}", s.templateHandler.GetTemplateByContent).Methods("GET")
 	api.HandleFunc("/templates/{id}/instances", s.templateHandler.CreateTemplateInstance).Methods("POST")
+	api.HandleFunc("/templates/{id}/instances/query", s.templateHandler.QueryInstances).Methods("POST")
+	api.HandleFunc("/templates/{id}/table", s.templateHandler.GetTableView).Methods("GET")
+	api.HandleFunc("/templates/{id}/slots", s.templateHandler.UpdateTemplateSchema).Methods("PUT")
 	api.HandleFunc("/instances/{id}/slots", s.templateHandler.UpdateSlotValue).Methods("PUT")
+	api.HandleFunc("/instances/{id}/render", s.templateHandler.RenderInstance).Methods("GET")
 
 	// Tag routes
-	api.HandleFunc("/chunks/{id}/tags", s.tagHandler.AddTag).Methods("POST")
-	api.HandleFunc("/chunks/{id}/tags/{tagId}", s.tagHandler.RemoveTag).Methods("DELETE")
+	api.HandleFunc("/chunks/{id}/tags", s.requirePermission(services.PermissionWrite, s.tagHandler.AddTag)).Methods("POST")
+	api.HandleFunc("/chunks/{id}/tags/{tagId}", s.requirePermission(services.PermissionWrite, s.tagHandler.RemoveTag)).Methods("DELETE")
 	api.HandleFunc("/chunks/{id}/tags", s.tagHandler.GetChunkTags).Methods("GET")
 	api.HandleFunc("/tags/{content}/chunks", s.tagHandler.GetChunksByTag).Methods("GET")
 	
@@ -140,6 +559,14 @@ func (s *Server) setupRoutes() {
 	if unifiedTagHandler, ok := s.tagHandler.(*handlers.UnifiedTagHandler); ok {
 		api.HandleFunc("/chunks/tags/batch", unifiedTagHandler.BatchTagOperations).Methods("POST")
 		api.HandleFunc("/tags/search", unifiedTagHandler.GetChunksByTags).Methods("POST")
+		api.HandleFunc("/tags/{id}/parent", unifiedTagHandler.SetTagParent).Methods("PUT")
+		api.HandleFunc("/tags/{id}/hierarchy", unifiedTagHandler.GetTagHierarchy).Methods("GET")
+		api.HandleFunc("/tags/{id}/rollup", unifiedTagHandler.GetTagRollup).Methods("GET")
+		api.HandleFunc("/tags/{id}/name", unifiedTagHandler.RenameTag).Methods("PUT")
+		api.HandleFunc("/tags/merge", unifiedTagHandler.MergeTags).Methods("POST")
+		if s.bulkTagHandler != nil {
+			api.HandleFunc("/tags/bulk", s.bulkTagHandler.TagChunks).Methods("POST")
+		}
 	}
 
 	// Legacy tag inheritance routes (only for legacy handlers)
@@ -157,18 +584,25 @@ func (s *Server) setupRoutes() {
 
 	// Chunk routes
 	api.HandleFunc("/chunks", s.chunkHandler.GetChunks).Methods("GET")
-	api.HandleFunc("/chunks", s.chunkHandler.CreateChunk).Methods("POST")
+	api.HandleFunc("/chunks", s.requirePermission(services.PermissionWrite, s.chunkHandler.CreateChunk)).Methods("POST")
 	api.HandleFunc("/chunks/{id}", s.chunkHandler.GetChunkByID).Methods("GET")
-	api.HandleFunc("/chunks/{id}", s.chunkHandler.UpdateChunk).Methods("PUT")
-	api.HandleFunc("/chunks/{id}", s.chunkHandler.DeleteChunk).Methods("DELETE")
+	api.HandleFunc("/chunks/{id}", s.requirePermission(services.PermissionWrite, s.chunkHandler.UpdateChunk)).Methods("PUT")
+	api.HandleFunc("/chunks/{id}", s.requirePermission(services.PermissionWrite, s.chunkHandler.DeleteChunk)).Methods("DELETE")
 	api.HandleFunc("/chunks/{id}/hierarchy", s.chunkHandler.GetChunkHierarchy).Methods("GET")
 	api.HandleFunc("/chunks/{id}/children", s.chunkHandler.GetChunkChildren).Methods("GET")
-	api.HandleFunc("/chunks/{id}/move", s.chunkHandler.MoveChunk).Methods("POST")
+	api.HandleFunc("/chunks/{id}/move", s.requirePermission(services.PermissionWrite, s.chunkHandler.MoveChunk)).Methods("POST")
 
-	// Batch chunk operations (only available with unified handlers)
+	// Batch and subtree chunk operations (only available with unified handlers)
 	if unifiedHandler, ok := s.chunkHandler.(*handlers.UnifiedChunkHandler); ok {
 		api.HandleFunc("/chunks/batch", unifiedHandler.BatchCreateChunks).Methods("POST")
 		api.HandleFunc("/chunks/batch", unifiedHandler.BatchUpdateChunks).Methods("PUT")
+		api.HandleFunc("/chunks/bulk-move", unifiedHandler.BulkMove).Methods("POST")
+		api.HandleFunc("/chunks/{id}", unifiedHandler.PatchChunk).Methods("PATCH")
+		api.HandleFunc("/chunks/{id}/metadata", unifiedHandler.PatchMetadata).Methods("PATCH")
+		api.HandleFunc("/chunks/{id}/move-subtree", unifiedHandler.MoveSubtree).Methods("POST")
+		api.HandleFunc("/chunks/{id}/copy", unifiedHandler.CopySubtree).Methods("POST")
+		api.HandleFunc("/chunks/{id}/delete-subtree", unifiedHandler.DeleteSubtree).Methods("POST")
+		api.HandleFunc("/chunks/{id}/merge", unifiedHandler.MergeChunks).Methods("POST")
 	}
 
 	// Legacy bulk update route and siblings route for backward compatibility
@@ -179,6 +613,143 @@ func (s *Server) setupRoutes() {
 		api.HandleFunc("/chunks/{id}/siblings", func(w http.ResponseWriter, r *http.Request) {
 			legacyWrapper.Handler.GetChunkSiblings(w, r)
 		}).Methods("GET")
+		api.HandleFunc("/chunks/{id}/children", func(w http.ResponseWriter, r *http.Request) {
+			legacyWrapper.Handler.InsertChunkAt(w, r)
+		}).Methods("POST")
+		api.HandleFunc("/chunks/{id}/children/order", func(w http.ResponseWriter, r *http.Request) {
+			legacyWrapper.Handler.ReorderChildren(w, r)
+		}).Methods("PUT")
+	}
+
+	// Reference graph routes
+	if s.backlinkHandler != nil {
+		api.HandleFunc("/chunks/{id}/backlinks", s.backlinkHandler.GetBacklinks).Methods("GET")
+		api.HandleFunc("/chunks/{id}/refs", s.backlinkHandler.GetOutgoingRefs).Methods("GET")
+		api.HandleFunc("/refs/broken", s.backlinkHandler.GetBrokenReferences).Methods("GET")
+		api.HandleFunc("/refs/rebuild", s.backlinkHandler.RebuildReferences).Methods("POST")
+	}
+
+	// Knowledge graph analytics routes
+	if s.graphAnalyticsHandler != nil {
+		api.HandleFunc("/graph/analytics", s.graphAnalyticsHandler.RunAnalytics).Methods("POST")
+		api.HandleFunc("/graph/entities/top", s.graphAnalyticsHandler.GetTopEntities).Methods("GET")
+		api.HandleFunc("/graph/communities", s.graphAnalyticsHandler.GetCommunities).Methods("GET")
+	}
+
+	if s.entityResolutionHandler != nil {
+		api.HandleFunc("/graph/entities/duplicates", s.entityResolutionHandler.FindDuplicates).Methods("GET")
+		api.HandleFunc("/graph/entities/merge", s.entityResolutionHandler.MergeEntities).Methods("POST")
+	}
+
+	if s.graphExportHandler != nil {
+		api.HandleFunc("/graph/export", s.graphExportHandler.ExportGraph).Methods("GET")
+	}
+
+	if s.graphEvidenceHandler != nil {
+		api.HandleFunc("/graph/edges/{id}/evidence", s.graphEvidenceHandler.GetEvidence).Methods("GET")
+	}
+
+	if s.graphTemporalHandler != nil {
+		api.HandleFunc("/graph/edges/{id}/validity", s.graphTemporalHandler.SetValidity).Methods("PUT")
+		api.HandleFunc("/graph/edges/{id}/supersede", s.graphTemporalHandler.Supersede).Methods("POST")
+		api.HandleFunc("/graph/conflicts", s.graphTemporalHandler.GetConflicts).Methods("GET")
+	}
+
+	if s.changeFeedHandler != nil {
+		api.HandleFunc("/changes/stream", s.changeFeedHandler.StreamChanges).Methods("GET")
+		api.HandleFunc("/changes/status", s.changeFeedHandler.GetStatus).Methods("GET")
+	}
+
+	// Live collaborative outline editing over WebSocket
+	if s.liveOutlineHandler != nil {
+		api.HandleFunc("/pages/{id}/live", s.liveOutlineHandler.Connect).Methods("GET")
+	}
+
+	// OPML exchange with other outliners
+	if s.outlineExchangeHandler != nil {
+		api.HandleFunc("/pages/{id}/opml", s.outlineExchangeHandler.ExportOPML).Methods("GET")
+		api.HandleFunc("/pages/import/opml", s.requirePermission(services.PermissionWrite, s.outlineExchangeHandler.ImportOPML)).Methods("POST")
+	}
+
+	// Page ACLs for shared workspaces; the services check who may manage each page
+	if s.pageACLHandler != nil {
+		api.HandleFunc("/pages/{id}/acl", s.pageACLHandler.GetACL).Methods("GET")
+		api.HandleFunc("/pages/{id}/acl", s.pageACLHandler.SetACL).Methods("PUT")
+		api.HandleFunc("/pages/{id}/acl", s.pageACLHandler.DeleteACL).Methods("DELETE")
+		api.HandleFunc("/chunks/{id}/permissions", s.pageACLHandler.EffectivePermission).Methods("GET")
+	}
+
+	// Editor locks on chunk subtrees; holders send the lock token in X-Chunk-Lock
+	if s.chunkLockHandler != nil {
+		api.HandleFunc("/chunks/{id}/lock", s.chunkLockHandler.GetLock).Methods("GET")
+		api.HandleFunc("/chunks/{id}/lock", s.requirePermission(services.PermissionWrite, s.chunkLockHandler.AcquireLock)).Methods("POST")
+		api.HandleFunc("/chunks/{id}/lock", s.requirePermission(services.PermissionWrite, s.chunkLockHandler.RefreshLock)).Methods("PUT")
+		api.HandleFunc("/chunks/{id}/lock", s.requirePermission(services.PermissionWrite, s.chunkLockHandler.ReleaseLock)).Methods("DELETE")
+	}
+
+	// Quick capture from external tools into the caller's inbox page
+	if s.captureHandler != nil {
+		api.HandleFunc("/capture", s.requirePermission(services.PermissionWrite, s.captureHandler.Capture)).Methods("POST")
+	}
+
+	// Daily note pages, created when first asked for
+	if s.journalHandler != nil {
+		api.HandleFunc("/journal", s.journalHandler.ListDays).Methods("GET")
+		api.HandleFunc("/journal/today", s.journalHandler.GetToday).Methods("GET")
+		api.HandleFunc("/journal/today/entries", s.requirePermission(services.PermissionWrite, s.journalHandler.AppendToToday)).Methods("POST")
+		api.HandleFunc("/journal/days/{date}", s.journalHandler.GetDay).Methods("GET")
+	}
+
+	// Tasks parsed from chunk contents
+	if s.taskHandler != nil {
+		api.HandleFunc("/tasks", s.taskHandler.ListTasks).Methods("GET")
+		api.HandleFunc("/tasks/reindex", s.requirePermission(services.PermissionAdmin, s.taskHandler.Reindex)).Methods("POST")
+	}
+
+	// Inbound email webhooks; served without API tokens, authMiddleware skips this path
+	if s.emailIngestHandler != nil {
+		api.HandleFunc("/email/inbound/sendgrid", s.emailIngestHandler.SendGridWebhook).Methods("POST")
+		api.HandleFunc("/email/inbound/mailgun", s.emailIngestHandler.MailgunWebhook).Methods("POST")
+	}
+
+	// Read-only GraphQL queries over chunks, tags, templates and graph nodes
+	if s.graphQLHandler != nil {
+		api.HandleFunc("/graphql", s.graphQLHandler.Query).Methods("GET", "POST")
+		api.HandleFunc("/graphql/schema", s.graphQLHandler.Schema).Methods("GET")
+	}
+
+	// Knowledge base statistics for dashboards; snapshots need admin permission
+	if s.statsHandler != nil {
+		api.HandleFunc("/stats", s.statsHandler.GetStats).Methods("GET")
+		api.HandleFunc("/stats/history", s.statsHandler.GetHistory).Methods("GET")
+		api.HandleFunc("/stats/snapshot", s.statsHandler.TakeSnapshot).Methods("POST")
+	}
+
+	// Imports from Notion and Logseq
+	if s.noteImportHandler != nil {
+		api.HandleFunc("/import/{format}", s.requirePermission(services.PermissionWrite, s.noteImportHandler.Import)).Methods("POST")
+	}
+
+	// Offline sync for mobile and desktop clients
+	if s.syncHandler != nil {
+		api.HandleFunc("/sync/changes", s.syncHandler.PullChanges).Methods("GET")
+		api.HandleFunc("/sync/push", s.syncHandler.PushOperations).Methods("POST")
+		api.HandleFunc("/sync/conflicts", s.syncHandler.ListConflicts).Methods("GET")
+		api.HandleFunc("/sync/conflicts/{id}/resolve", s.syncHandler.ResolveConflict).Methods("POST")
+	}
+
+	// Vector index management routes
+	if s.vectorIndexHandler != nil {
+		api.HandleFunc("/vector-indexes", s.vectorIndexHandler.ListIndexes).Methods("GET")
+		api.HandleFunc("/vector-indexes", s.vectorIndexHandler.CreateIndex).Methods("POST")
+		api.HandleFunc("/vector-indexes/rebuild", s.vectorIndexHandler.RebuildIndex).Methods("POST")
+		api.HandleFunc("/vector-indexes/suggestions", s.vectorIndexHandler.GetSuggestions).Methods("GET")
+	}
+
+	// Batch embedding with per-workspace token and cost accounting
+	if s.embeddingBatchHandler != nil {
+		api.HandleFunc("/embeddings/batch", s.requirePermission(services.PermissionWrite, s.embeddingBatchHandler.EmbedBatch)).Methods("POST")
+		api.HandleFunc("/embeddings/usage", s.embeddingBatchHandler.GetUsage).Methods("GET")
 	}
 
 	// Search routes
@@ -190,6 +761,51 @@ func (s *Server) setupRoutes() {
 	// api.HandleFunc("/search/chunks", s.searchHandler.SearchChunks).Methods("POST")
 	// api.HandleFunc("/search/hybrid", s.searchHandler.HybridSearch).Methods("POST")
 
+	// Cached semantic search with stale-while-revalidate
+	if s.optimizedSearchHandler != nil {
+		api.HandleFunc("/search/optimized", s.optimizedSearchHandler.Search).Methods("POST")
+	}
+
+	// Autocomplete and popular queries from the search query log
+	if s.querySuggestionHandler != nil {
+		api.HandleFunc("/search/suggestions", s.querySuggestionHandler.Suggest).Methods("GET")
+		api.HandleFunc("/search/popular", s.querySuggestionHandler.Popular).Methods("GET")
+	}
+
+	// Question answering over the knowledge base with chunk citations
+	if s.ragHandler != nil {
+		api.HandleFunc("/ask", s.ragHandler.Ask).Methods("POST")
+		api.HandleFunc("/context", s.ragHandler.AssembleContext).Methods("POST")
+	}
+
+	// Cached chunk summaries and rollup summaries of pages
+	if s.summaryHandler != nil {
+		api.HandleFunc("/chunks/{id}/summary", s.summaryHandler.GetSummary).Methods("GET")
+	}
+
+	// Chunks, pages and search results as they were at a past time
+	if s.chunkHistoryHandler != nil {
+		api.HandleFunc("/chunks/{id}/history", s.chunkHistoryHandler.GetHistory).Methods("GET")
+		api.HandleFunc("/history/chunks/{id}", s.chunkHistoryHandler.GetChunk).Methods("GET")
+		api.HandleFunc("/history/chunks/{id}/children", s.chunkHistoryHandler.GetChildren).Methods("GET")
+		api.HandleFunc("/history/pages/{id}", s.chunkHistoryHandler.GetPage).Methods("GET")
+		api.HandleFunc("/history/pages/{id}/diff", s.chunkHistoryHandler.DiffPage).Methods("GET")
+		api.HandleFunc("/history/search", s.chunkHistoryHandler.Search).Methods("GET")
+	}
+
+	if s.pageRenderHandler != nil {
+		api.HandleFunc("/pages/{id}/render", s.pageRenderHandler.RenderPage).Methods("GET")
+	}
+
+	if s.publicationHandler != nil {
+		api.HandleFunc("/pages/{id}/publish", s.requirePermission(services.PermissionWrite, s.publicationHandler.Publish)).Methods("POST")
+		api.HandleFunc("/pages/{id}/publish", s.publicationHandler.GetPublication).Methods("GET")
+		api.HandleFunc("/pages/{id}/publish", s.requirePermission(services.PermissionWrite, s.publicationHandler.Unpublish)).Methods("DELETE")
+		api.HandleFunc("/publications", s.publicationHandler.ListPublications).Methods("GET")
+		// Served without authentication; authMiddleware skips this path
+		api.HandleFunc("/public/pages/{slug}", s.publicationHandler.ServePublic).Methods("GET")
+	}
+
 	// New multimodal search endpoints
 	api.HandleFunc("/search/multimodal", s.searchHandler.MultimodalSearch).Methods("POST")
 	api.HandleFunc("/search/image-similarity", s.searchHandler.SearchByImage).Methods("POST")
@@ -199,7 +815,7 @@ func (s *Server) setupRoutes() {
 	api.HandleFunc("/search/similar/{chunk_id}", s.searchHandler.GetSimilarImages).Methods("GET")
 
 	// Media routes
-	api.HandleFunc("/media/upload", s.simpleMediaHandler.UploadImage).Methods("POST", "OPTIONS")
+	api.HandleFunc("/media/upload", s.requirePermission(services.PermissionWrite, s.simpleMediaHandler.UploadImage)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/media/library", s.simpleMediaHandler.GetImageLibrary).Methods("GET", "OPTIONS")
 
 	// AI routes
@@ -219,6 +835,14 @@ func (s *Server) setupMiddleware() {
 	s.router.Use(s.corsMiddleware)
 	s.router.Use(s.loggingMiddleware)
 	s.router.Use(s.contentTypeMiddleware)
+	s.router.Use(s.workspaceMiddleware)
+	s.router.Use(s.chunkLockMiddleware)
+	if s.config.Auth.Enabled {
+		s.router.Use(s.authMiddleware)
+	}
+	if s.config.Supabase.ForwardUserTokens {
+		s.router.Use(s.supabaseUserTokenMiddleware)
+	}
 	
 	// Add performance monitoring middleware if enabled
 	if s.config.Performance.MonitoringEnabled && s.services.MetricsService != nil {
@@ -230,28 +854,70 @@ func (s *Server) setupMiddleware() {
 func (s *Server) Start() error {
 	log.Printf("Starting server on port %s", s.config.Server.Port)
 	
+	if err := s.services.Start(context.Background()); err != nil {
+		return fmt.Errorf("failed to start services: %w", err)
+	}
+
 	// Start server in a goroutine
+	serverErr := make(chan error, 1)
 	go func() {
 		if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			serverErr <- err
 		}
 	}()
 
 	// Wait for interrupt signal to gracefully shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	select {
+	case <-quit:
+	case err := <-serverErr:
+		log.Printf("Server failed: %v", err)
+		if shutdownErr := s.Shutdown(); shutdownErr != nil {
+			log.Printf("Warning: %v", shutdownErr)
+		}
+		return fmt.Errorf("failed to serve: %w", err)
+	}
 
 	log.Println("Shutting down server...")
 	return s.Shutdown()
 }
 
-// Shutdown gracefully shuts down the server
+// ApplyConfig applies reloadable settings from a reloaded configuration
+func (s *Server) ApplyConfig(cfg *config.Config) {
+	s.services.ApplyConfig(cfg)
+}
+
+// Shutdown gracefully shuts down the server. Readiness fails first so load balancers
+// stop routing here, then the listener closes and in-flight requests drain until the
+// shutdown timeout, after which the remaining connections are closed. Background work
+// stops and caches and metrics are flushed within the same deadline.
 func (s *Server) Shutdown() error {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), s.config.Shutdown.Timeout)
 	defer cancel()
 
-	return s.httpServer.Shutdown(ctx)
+	if s.services.Probes != nil {
+		s.services.Probes.StartDraining()
+	}
+	if delay := s.config.Shutdown.DrainDelay; delay > 0 {
+		log.Printf("Draining for %s before closing the listener", delay)
+		time.Sleep(delay)
+	}
+
+	err := s.httpServer.Shutdown(ctx)
+	if err != nil {
+		log.Printf("Warning: in-flight requests did not finish before the shutdown timeout: %v", err)
+		s.httpServer.Close()
+		err = fmt.Errorf("failed to drain in-flight requests: %w", err)
+	}
+
+	if stopErr := s.services.Close(ctx); stopErr != nil {
+		log.Printf("Warning: %v", stopErr)
+		if err == nil {
+			err = stopErr
+		}
+	}
+	return err
 }
 
 // healthCheck handles health check requests
@@ -329,6 +995,15 @@ func (s *Server) metricsHandler(w http.ResponseWriter, r *http.Request) {
 		cacheStats := s.services.CacheService.GetStats()
 		metrics["cache"] = cacheStats
 	}
+	if s.services.EmbeddingSync != nil {
+		if coverage, err := s.services.EmbeddingSync.Coverage(r.Context()); err == nil {
+			metrics["embeddings"] = coverage
+		}
+	}
+	// Query latency percentiles per query type, since the last reset and in rolling windows
+	if s.services.QueryMonitor != nil {
+		metrics["queries"] = s.services.QueryMonitor.GetQueryStats()
+	}
 	
 	w.WriteHeader(http.StatusOK)
 	if err := json.NewEncoder(w).Encode(metrics); err != nil {
@@ -358,6 +1033,85 @@ func (s *Server) cacheStatsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// embeddingStatsHandler reports how many chunks have an embedding of their current contents
+func (s *Server) embeddingStatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.services.EmbeddingSync == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, `{"error":"embedding sync not enabled"}`)
+		return
+	}
+
+	coverage, err := s.services.EmbeddingSync.Coverage(r.Context())
+	if err != nil {
+		log.Printf("Failed to get embedding coverage: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, `{"error":"failed to get embedding coverage"}`)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(coverage); err != nil {
+		log.Printf("Failed to encode embedding coverage: %v", err)
+	}
+}
+
+// databaseHealthHandler reports live connection pool statistics and pool alerts
+func (s *Server) databaseHealthHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.services.DBHealth == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, `{"error":"database health service not available"}`)
+		return
+	}
+
+	health := s.services.DBHealth.CheckHealth(r.Context())
+
+	status := http.StatusOK
+	if health.Status == services.HealthStatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		log.Printf("Failed to encode database health: %v", err)
+	}
+}
+
+// piiComplianceHandler reports personal data findings recorded during ingestion,
+// optionally for one workspace and since a time: ?workspace=legal&since=720h
+func (s *Server) piiComplianceHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.services.PIICompliance == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, `{"error":"pii scanning not enabled"}`)
+		return
+	}
+
+	var since time.Time
+	if window := r.URL.Query().Get("since"); window != "" {
+		d, err := time.ParseDuration(window)
+		if err != nil || d <= 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":"since must be a positive duration such as 720h"}`)
+			return
+		}
+		since = time.Now().Add(-d)
+	}
+
+	report, err := s.services.PIICompliance.Report(r.Context(), r.URL.Query().Get("workspace"), since)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("Failed to encode pii compliance report: %v", err)
+	}
+}
+
 // cacheClearHandler handles cache clear requests
 func (s *Server) cacheClearHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
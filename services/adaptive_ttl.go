@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"semantic-text-processor/config"
+)
+
+const (
+	defaultAdaptiveTTLPatterns = 2000
+	defaultAdaptiveTTLRefresh  = 5 * time.Minute
+)
+
+// CacheTTLPolicy decides how long a cache entry is kept, given the TTL its writer asked for
+type CacheTTLPolicy interface {
+	TTL(key string, requested time.Duration) time.Duration
+}
+
+// AdaptiveTTLPolicy scales the TTLs of chunk and tag listing entries by how often their
+// reads appear in the hot data history, and spreads every TTL by a random jitter so that
+// entries written together do not expire together.
+//
+// A key read as often as the median hot pattern keeps the requested TTL; hotter keys are
+// kept proportionally longer, up to the maximum, and cooler ones shorter, down to the
+// minimum. Keys missing from the history are cached for the minimum. Until the history
+// has been loaded, and for other kinds of keys, only the jitter applies.
+type AdaptiveTTLPolicy struct {
+	history  HotPatternSource
+	limit    int
+	minTTL   time.Duration
+	maxTTL   time.Duration
+	jitter   float64
+	interval time.Duration
+	random   func() float64
+
+	mu      sync.RWMutex
+	weights map[string]float64
+	median  float64
+	loaded  bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewAdaptiveTTLPolicy creates a policy from the cache configuration. A nil history only
+// applies the jitter.
+func NewAdaptiveTTLPolicy(history HotPatternSource, cfg config.CacheConfig) *AdaptiveTTLPolicy {
+	limit := cfg.AdaptiveTTLPatterns
+	if limit <= 0 {
+		limit = defaultAdaptiveTTLPatterns
+	}
+	interval := cfg.AdaptiveTTLRefresh
+	if interval <= 0 {
+		interval = defaultAdaptiveTTLRefresh
+	}
+	return &AdaptiveTTLPolicy{
+		history:  history,
+		limit:    limit,
+		minTTL:   cfg.AdaptiveTTLMin,
+		maxTTL:   cfg.AdaptiveTTLMax,
+		jitter:   cfg.TTLJitter,
+		interval: interval,
+		random:   rand.Float64,
+	}
+}
+
+// hotPatternForCacheKey returns the hot data pattern counting the reads a cache entry
+// serves, or "" when reads of that kind are not counted
+func hotPatternForCacheKey(key string) string {
+	if id, ok := strings.CutPrefix(key, hotChunkPrefix); ok {
+		return hotChunkPrefix + id
+	}
+	// Rolled-up listings (chunks_by_tag:{id}:rollup:{depth}) are not counted
+	if id, ok := strings.CutPrefix(key, "chunks_by_tag:"); ok && !strings.Contains(id, ":") {
+		return hotTagPrefix + id
+	}
+	return ""
+}
+
+// TTL returns the adapted and jittered TTL of key
+func (p *AdaptiveTTLPolicy) TTL(key string, requested time.Duration) time.Duration {
+	if requested <= 0 {
+		return requested
+	}
+	ttl := requested
+	if pattern := hotPatternForCacheKey(key); pattern != "" {
+		ttl = p.adapt(pattern, requested)
+	}
+	if p.jitter > 0 {
+		ttl = time.Duration(float64(ttl) * (1 + p.jitter*(2*p.random()-1)))
+	}
+	return ttl
+}
+
+// adapt scales requested by the weight of pattern relative to the median weight. The
+// bounds never cut a requested TTL that already lies outside them the other way.
+func (p *AdaptiveTTLPolicy) adapt(pattern string, requested time.Duration) time.Duration {
+	p.mu.RLock()
+	weight, hot := p.weights[pattern]
+	median, loaded := p.median, p.loaded
+	p.mu.RUnlock()
+	if !loaded || median <= 0 {
+		return requested
+	}
+
+	lower, upper := p.minTTL, p.maxTTL
+	if lower <= 0 || lower > requested {
+		lower = requested
+	}
+	if upper < requested {
+		upper = requested
+	}
+	if !hot {
+		return lower
+	}
+	ttl := time.Duration(float64(requested) * weight / median)
+	if ttl < lower {
+		return lower
+	}
+	if ttl > upper {
+		return upper
+	}
+	return ttl
+}
+
+// Refresh loads the weights of the hottest patterns. The previous weights are kept when
+// the history cannot be read.
+func (p *AdaptiveTTLPolicy) Refresh(ctx context.Context) error {
+	if p.history == nil {
+		return nil
+	}
+	patterns, err := p.history.TopPatterns(ctx, p.limit)
+	if err != nil {
+		return err
+	}
+
+	weights := make(map[string]float64, len(patterns))
+	sorted := make([]float64, 0, len(patterns))
+	for _, pattern := range patterns {
+		weights[pattern.Pattern] = pattern.CacheWeight
+		sorted = append(sorted, pattern.CacheWeight)
+	}
+	sort.Float64s(sorted)
+	median := 0.0
+	if len(sorted) > 0 {
+		median = sorted[len(sorted)/2]
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.weights = weights
+	p.median = median
+	p.loaded = true
+	return nil
+}
+
+// Start loads the history and reloads it every interval until Stop is called
+func (p *AdaptiveTTLPolicy) Start(ctx context.Context) {
+	if p.cancel != nil || p.history == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			if err := p.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Warning: failed to refresh adaptive cache TTLs: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops reloading the history
+func (p *AdaptiveTTLPolicy) Stop() {
+	if p.cancel != nil {
+		p.cancel()
+		<-p.done
+		p.cancel = nil
+	}
+}
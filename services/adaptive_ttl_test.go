@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// failingHotPatterns is a read history that cannot be read
+type failingHotPatterns struct{}
+
+func (failingHotPatterns) TopPatterns(ctx context.Context, limit int) ([]models.HotDataPattern, error) {
+	return nil, errors.New("database unavailable")
+}
+
+func adaptiveTTLConfig(jitter float64) config.CacheConfig {
+	return config.CacheConfig{
+		TTLJitter:      jitter,
+		AdaptiveTTLMin: time.Minute,
+		AdaptiveTTLMax: time.Hour,
+	}
+}
+
+func TestAdaptiveTTLPolicy_ScalesByHotWeight(t *testing.T) {
+	history := staticHotPatterns{
+		{Pattern: "chunk:hot", CacheWeight: 400},
+		{Pattern: "chunk:warm", CacheWeight: 20},
+		{Pattern: "tag:tag-1", CacheWeight: 10},
+		{Pattern: "chunk:cool", CacheWeight: 5},
+		{Pattern: "chunk:cold", CacheWeight: 0.1},
+	}
+	policy := NewAdaptiveTTLPolicy(history, adaptiveTTLConfig(0))
+
+	requested := 5 * time.Minute
+	assert.Equal(t, requested, policy.TTL("chunk:hot", requested), "before the history loads TTLs are kept")
+
+	require.NoError(t, policy.Refresh(context.Background()))
+	assert.Equal(t, 10*time.Minute, policy.TTL("chunk:warm", requested), "twice the median weight")
+	assert.Equal(t, requested, policy.TTL("chunks_by_tag:tag-1", requested), "the median weight keeps the TTL")
+	assert.Equal(t, 150*time.Second, policy.TTL("chunk:cool", requested))
+	assert.Equal(t, time.Hour, policy.TTL("chunk:hot", requested), "capped at the maximum")
+	assert.Equal(t, time.Minute, policy.TTL("chunk:cold", requested), "floored at the minimum")
+	assert.Equal(t, time.Minute, policy.TTL("chunk:unread", requested), "keys missing from the history")
+
+	assert.Equal(t, requested, policy.TTL("chunks_by_tag:tag-1:rollup:3", requested), "uncounted reads keep their TTL")
+	assert.Equal(t, requested, policy.TTL("chunk_children:hot", requested))
+	assert.Equal(t, 30*time.Second, policy.TTL("chunk:unread", 30*time.Second), "short TTLs are not lengthened to the minimum")
+	assert.Equal(t, 2*time.Hour, policy.TTL("chunk:hot", 2*time.Hour), "long TTLs are not cut to the maximum")
+}
+
+func TestAdaptiveTTLPolicy_KeepsWeightsWhenRefreshFails(t *testing.T) {
+	policy := NewAdaptiveTTLPolicy(staticHotPatterns{
+		{Pattern: "chunk:a", CacheWeight: 1},
+		{Pattern: "chunk:b", CacheWeight: 4},
+	}, adaptiveTTLConfig(0))
+	require.NoError(t, policy.Refresh(context.Background()))
+
+	policy.history = failingHotPatterns{}
+	assert.Error(t, policy.Refresh(context.Background()))
+	assert.Equal(t, 5*time.Minute, policy.TTL("chunk:b", 5*time.Minute))
+	assert.Equal(t, time.Minute, policy.TTL("chunk:missing", 5*time.Minute))
+}
+
+func TestAdaptiveTTLPolicy_Jitter(t *testing.T) {
+	policy := NewAdaptiveTTLPolicy(nil, adaptiveTTLConfig(0.1))
+
+	policy.random = func() float64 { return 0 }
+	assert.Equal(t, 9*time.Minute, policy.TTL("search:x", 10*time.Minute))
+	policy.random = func() float64 { return 0.5 }
+	assert.Equal(t, 10*time.Minute, policy.TTL("search:x", 10*time.Minute))
+	policy.random = func() float64 { return 1 }
+	assert.Equal(t, 11*time.Minute, policy.TTL("chunk:x", 10*time.Minute), "without history chunks are only jittered")
+
+	policy.random = nil
+	assert.Equal(t, time.Duration(0), policy.TTL("chunk:x", 0), "entries without a TTL are left alone")
+}
+
+func TestInMemoryCache_AppliesTTLPolicy(t *testing.T) {
+	cache := NewInMemoryCache(10, time.Minute)
+	defer cache.Stop()
+	policy := NewAdaptiveTTLPolicy(staticHotPatterns{{Pattern: "chunk:hot", CacheWeight: 1}}, adaptiveTTLConfig(0))
+	require.NoError(t, policy.Refresh(context.Background()))
+	cache.SetTTLPolicy(policy)
+
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "chunk:cold", "value", 5*time.Minute))
+	typed := NewTypedCache[string](cache)
+	require.NoError(t, typed.Set(ctx, "chunk:hot", "value", 5*time.Minute))
+
+	expires := func(key string) time.Duration {
+		cache.mu.RLock()
+		defer cache.mu.RUnlock()
+		entry := cache.data[key]
+		return entry.ExpiresAt.Sub(entry.CreatedAt).Round(time.Second)
+	}
+	assert.Equal(t, time.Minute, expires("chunk:cold"))
+	assert.Equal(t, 5*time.Minute, expires("chunk:hot"))
+}
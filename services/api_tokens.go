@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidCredential is returned for unknown, revoked or expired API tokens and for
+// JWTs that fail verification
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// ErrInvalidAPITokenRequest is returned for token requests with an invalid name, role or
+// expiry
+var ErrInvalidAPITokenRequest = errors.New("invalid api token request")
+
+// ErrAPITokenNotFound is returned when revoking a token that does not exist in the
+// caller's workspace or is already revoked
+var ErrAPITokenNotFound = errors.New("api token not found")
+
+const (
+	apiTokenPrefix       = "ink_"
+	apiTokenPrefixLength = len(apiTokenPrefix) + 8
+	maxAPITokenNameLen   = 100
+
+	// tokenUsageResolution limits last_used_at updates to one per token and interval, so
+	// busy tokens do not write on every request
+	tokenUsageResolution = time.Minute
+)
+
+// APITokenService issues workspace-scoped API tokens and resolves request credentials
+// to the calling principal
+type APITokenService interface {
+	// CreateToken issues a token in workspace. The token is returned once and only its
+	// hash is stored.
+	CreateToken(ctx context.Context, workspace string, req *models.CreateAPITokenRequest) (*models.CreatedAPIToken, error)
+
+	// ListTokens returns the workspace's tokens, including revoked ones, newest first
+	ListTokens(ctx context.Context, workspace string) ([]models.APIToken, error)
+
+	// RevokeToken stops a token from authenticating
+	RevokeToken(ctx context.Context, workspace, tokenID string) error
+
+	// Authenticate resolves a bearer credential, an API token or a JWT, to its caller
+	Authenticate(ctx context.Context, credential string) (*Principal, error)
+}
+
+// apiTokenService implements APITokenService on the api_tokens table. JWTs are verified
+// with a shared HMAC secret and carry the role and workspace as claims.
+type apiTokenService struct {
+	db        *sql.DB
+	jwtSecret []byte
+	jwtIssuer string
+	monitor   QueryPerformanceMonitor
+}
+
+// NewAPITokenService creates a token service
+func NewAPITokenService(db *sql.DB, cfg config.AuthConfig, monitor QueryPerformanceMonitor) APITokenService {
+	var secret []byte
+	if cfg.JWTSecret != "" {
+		secret = []byte(cfg.JWTSecret)
+	}
+	return &apiTokenService{
+		db:        db,
+		jwtSecret: secret,
+		jwtIssuer: cfg.JWTIssuer,
+		monitor:   monitor,
+	}
+}
+
+// CreateToken issues a token in workspace
+func (s *apiTokenService) CreateToken(ctx context.Context, workspace string, req *models.CreateAPITokenRequest) (*models.CreatedAPIToken, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAPITokenNameLen {
+		return nil, fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidAPITokenRequest, maxAPITokenNameLen)
+	}
+	if !req.Role.Valid() {
+		return nil, fmt.Errorf("%w: role must be admin, editor or reader", ErrInvalidAPITokenRequest)
+	}
+	var expiresAt *time.Time
+	if req.ExpiresIn != "" {
+		d, err := time.ParseDuration(req.ExpiresIn)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%w: expires_in must be a positive duration such as 720h", ErrInvalidAPITokenRequest)
+		}
+		expires := time.Now().Add(d)
+		expiresAt = &expires
+	}
+
+	token, err := generateAPIToken()
+	if err != nil {
+		return nil, err
+	}
+
+	created := &models.CreatedAPIToken{
+		APIToken: models.APIToken{
+			Name:      name,
+			Workspace: workspace,
+			Role:      req.Role,
+			Prefix:    token[:apiTokenPrefixLength],
+			ExpiresAt: expiresAt,
+		},
+		Token: token,
+	}
+
+	start := time.Now()
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (name, workspace_id, role, token_hash, token_prefix, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`,
+		name, workspace, string(req.Role), hashAPIToken(token), created.Prefix, expiresAt,
+	).Scan(&created.ID, &created.CreatedAt)
+	s.monitor.RecordQuery("create_api_token", time.Since(start), 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create api token: %w", err)
+	}
+	return created, nil
+}
+
+// ListTokens returns the workspace's tokens, newest first
+func (s *apiTokenService) ListTokens(ctx context.Context, workspace string) ([]models.APIToken, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, workspace_id, role, token_prefix, created_at, expires_at, last_used_at, revoked_at
+		FROM api_tokens
+		WHERE workspace_id = $1
+		ORDER BY created_at DESC`, workspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.APIToken{}
+	for rows.Next() {
+		var token models.APIToken
+		var expiresAt, lastUsedAt, revokedAt sql.NullTime
+		if err := rows.Scan(&token.ID, &token.Name, &token.Workspace, &token.Role, &token.Prefix,
+			&token.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan api token: %w", err)
+		}
+		token.ExpiresAt = nullTimePtr(expiresAt)
+		token.LastUsedAt = nullTimePtr(lastUsedAt)
+		token.RevokedAt = nullTimePtr(revokedAt)
+		tokens = append(tokens, token)
+	}
+	s.monitor.RecordQuery("list_api_tokens", time.Since(start), len(tokens))
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeToken stops a token from authenticating
+func (s *apiTokenService) RevokeToken(ctx context.Context, workspace, tokenID string) error {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(tokenID); err != nil {
+		return ErrAPITokenNotFound
+	}
+
+	start := time.Now()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE api_tokens SET revoked_at = NOW()
+		WHERE id = $1 AND workspace_id = $2 AND revoked_at IS NULL`,
+		tokenID, workspace)
+	s.monitor.RecordQuery("revoke_api_token", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api token: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// Authenticate resolves a bearer credential to its caller
+func (s *apiTokenService) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	if strings.HasPrefix(credential, apiTokenPrefix) {
+		return s.authenticateToken(ctx, credential)
+	}
+	return s.authenticateJWT(credential)
+}
+
+// authenticateToken looks up an API token by its hash and records its use
+func (s *apiTokenService) authenticateToken(ctx context.Context, token string) (*Principal, error) {
+	var principal Principal
+	var lastUsedAt sql.NullTime
+
+	start := time.Now()
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name, workspace_id, role, last_used_at
+		FROM api_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > NOW())`,
+		hashAPIToken(token),
+	).Scan(&principal.TokenID, &principal.Subject, &principal.Workspace, &principal.Role, &lastUsedAt)
+	s.monitor.RecordQuery("authenticate_api_token", time.Since(start), 1)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidCredential
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate api token: %w", err)
+	}
+
+	if !lastUsedAt.Valid || time.Since(lastUsedAt.Time) >= tokenUsageResolution {
+		// Usage tracking is informational; a failed update does not fail the request
+		start := time.Now()
+		s.db.ExecContext(ctx, `UPDATE api_tokens SET last_used_at = NOW() WHERE id = $1`, principal.TokenID)
+		s.monitor.RecordQuery("touch_api_token", time.Since(start), 1)
+	}
+	return &principal, nil
+}
+
+// tokenClaims are the claims a JWT needs besides the registered ones
+type tokenClaims struct {
+	Role      models.Role `json:"role"`
+	Workspace string      `json:"workspace"`
+	Groups    []string    `json:"groups,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// authenticateJWT verifies an HMAC-signed JWT with an expiry and a known role
+func (s *apiTokenService) authenticateJWT(credential string) (*Principal, error) {
+	if s.jwtSecret == nil {
+		return nil, ErrInvalidCredential
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}),
+		jwt.WithExpirationRequired(),
+	}
+	if s.jwtIssuer != "" {
+		opts = append(opts, jwt.WithIssuer(s.jwtIssuer))
+	}
+
+	var claims tokenClaims
+	_, err := jwt.ParseWithClaims(credential, &claims, func(*jwt.Token) (interface{}, error) {
+		return s.jwtSecret, nil
+	}, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+	if !claims.Role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %q", ErrInvalidCredential, claims.Role)
+	}
+	workspace, err := NormalizeWorkspace(claims.Workspace)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredential, err)
+	}
+
+	return &Principal{
+		Subject:   claims.Subject,
+		Workspace: workspace,
+		Role:      claims.Role,
+		Groups:    claims.Groups,
+	}, nil
+}
+
+// generateAPIToken returns a new random token
+func generateAPIToken() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate api token: %w", err)
+	}
+	return apiTokenPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashAPIToken returns the stored form of a token. Tokens are random, so an unsalted
+// hash is enough to keep a database leak from exposing usable tokens.
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
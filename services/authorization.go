@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"semantic-text-processor/models"
+)
+
+// ErrPermissionDenied is returned when the caller's role does not allow an operation
+var ErrPermissionDenied = errors.New("permission denied")
+
+// Permission is a class of operations a role may be allowed to perform
+type Permission string
+
+const (
+	// PermissionRead covers reads and searches
+	PermissionRead Permission = "read"
+	// PermissionWrite covers creating, changing and deleting chunks, tags and templates
+	PermissionWrite Permission = "write"
+	// PermissionAdmin covers migrations, consistency repairs, rebuilds and token management
+	PermissionAdmin Permission = "admin"
+)
+
+// Allows reports whether role grants perm
+func Allows(role models.Role, perm Permission) bool {
+	switch role {
+	case models.RoleAdmin:
+		return true
+	case models.RoleEditor:
+		return perm == PermissionRead || perm == PermissionWrite
+	case models.RoleReader:
+		return perm == PermissionRead
+	}
+	return false
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject   string // token name or JWT subject
+	TokenID   string // set for API tokens
+	Workspace string
+	Role      models.Role
+	Groups    []string // JWT groups claim, matched against page ACLs
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated caller
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller set by WithPrincipal, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// Authorize checks that the caller in ctx may perform operations needing perm. Contexts
+// without a caller belong to the service itself, such as background jobs, command line
+// tools and requests when authentication is disabled, and are allowed everything.
+func Authorize(ctx context.Context, perm Permission) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok || Allows(principal.Role, perm) {
+		return nil
+	}
+	return fmt.Errorf("%w: role %s cannot perform %s operations", ErrPermissionDenied, principal.Role, perm)
+}
+
+// authorizingChunkService refuses writes from callers whose role only allows reading.
+// Reads pass through unchanged.
+type authorizingChunkService struct {
+	UnifiedChunkService
+}
+
+// NewAuthorizingChunkService wraps a chunk service with role checks on every write
+func NewAuthorizingChunkService(base UnifiedChunkService) UnifiedChunkService {
+	return &authorizingChunkService{UnifiedChunkService: base}
+}
+
+func (s *authorizingChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.CreateChunk(ctx, chunk)
+}
+
+func (s *authorizingChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.UpdateChunk(ctx, chunk)
+}
+
+func (s *authorizingChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+}
+
+func (s *authorizingChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.DeleteChunk(ctx, chunkID)
+}
+
+func (s *authorizingChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.BatchCreateChunks(ctx, chunks)
+}
+
+func (s *authorizingChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks)
+}
+
+func (s *authorizingChunkService) AddTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.AddTags(ctx, chunkID, tagChunkIDs)
+}
+
+func (s *authorizingChunkService) RemoveTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.RemoveTags(ctx, chunkID, tagChunkIDs)
+}
+
+func (s *authorizingChunkService) BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return 0, err
+	}
+	return s.UnifiedChunkService.BatchAddTags(ctx, chunkIDs, tagChunkIDs)
+}
+
+func (s *authorizingChunkService) SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.SetTagParent(ctx, tagChunkID, parentTagChunkID)
+}
+
+func (s *authorizingChunkService) RenameTag(ctx context.Context, tagChunkID, newName string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.RenameTag(ctx, tagChunkID, newName)
+}
+
+func (s *authorizingChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.MergeTags(ctx, sourceTagIDs, targetTagID)
+}
+
+func (s *authorizingChunkService) MoveChunk(ctx context.Context, chunkID, newParentID string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.MoveChunk(ctx, chunkID, newParentID)
+}
+
+func (s *authorizingChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.MoveSubtree(ctx, chunkID, newParentID)
+}
+
+func (s *authorizingChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.CopySubtree(ctx, chunkID, newParentID)
+}
+
+func (s *authorizingChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.BulkMove(ctx, moves)
+}
+
+func (s *authorizingChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+}
+
+// DeleteSubtree allows previews, which change nothing, to readers
+func (s *authorizingChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	if opts.Confirm {
+		if err := Authorize(ctx, PermissionWrite); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.DeleteSubtree(ctx, chunkID, opts)
+}
+
+// authorizingTemplateService refuses template writes from callers whose role only allows
+// reading
+type authorizingTemplateService struct {
+	TemplateService
+}
+
+// NewAuthorizingTemplateService wraps a template service with role checks on every write
+func NewAuthorizingTemplateService(base TemplateService) TemplateService {
+	return &authorizingTemplateService{TemplateService: base}
+}
+
+func (s *authorizingTemplateService) CreateTemplate(ctx context.Context, req *models.CreateTemplateRequest) (*models.TemplateWithInstances, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.TemplateService.CreateTemplate(ctx, req)
+}
+
+func (s *authorizingTemplateService) CreateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.TemplateService.CreateInstance(ctx, req)
+}
+
+func (s *authorizingTemplateService) UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	return s.TemplateService.UpdateSlotValue(ctx, instanceChunkID, slotName, value)
+}
+
+func (s *authorizingTemplateService) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	return s.TemplateService.UpdateTemplateSchema(ctx, templateChunkID, req)
+}
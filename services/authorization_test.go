@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// writeRecorder counts the writes that reach the wrapped chunk service
+type writeRecorder struct {
+	UnifiedChunkService
+	writes int
+}
+
+func (r *writeRecorder) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	r.writes++
+	return nil
+}
+
+func (r *writeRecorder) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	return &models.UnifiedChunkRecord{ChunkID: chunkID}, nil
+}
+
+func (r *writeRecorder) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	if opts.Confirm {
+		r.writes++
+	}
+	return &models.SubtreeDeleteResult{}, nil
+}
+
+func contextWithRole(role models.Role) context.Context {
+	return WithPrincipal(context.Background(), &Principal{Subject: "test", Workspace: DefaultWorkspace, Role: role})
+}
+
+func TestAuthorize_RolePermissions(t *testing.T) {
+	tests := []struct {
+		role    models.Role
+		allowed []Permission
+		denied  []Permission
+	}{
+		{models.RoleAdmin, []Permission{PermissionRead, PermissionWrite, PermissionAdmin}, nil},
+		{models.RoleEditor, []Permission{PermissionRead, PermissionWrite}, []Permission{PermissionAdmin}},
+		{models.RoleReader, []Permission{PermissionRead}, []Permission{PermissionWrite, PermissionAdmin}},
+		{models.Role("owner"), nil, []Permission{PermissionRead, PermissionWrite, PermissionAdmin}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			ctx := contextWithRole(tt.role)
+			for _, perm := range tt.allowed {
+				assert.NoError(t, Authorize(ctx, perm), perm)
+			}
+			for _, perm := range tt.denied {
+				assert.ErrorIs(t, Authorize(ctx, perm), ErrPermissionDenied, perm)
+			}
+		})
+	}
+
+	// The service's own calls carry no caller
+	assert.NoError(t, Authorize(context.Background(), PermissionAdmin))
+}
+
+func TestAuthorizingChunkService_RefusesReaderWrites(t *testing.T) {
+	base := &writeRecorder{}
+	service := NewAuthorizingChunkService(base)
+
+	reader := contextWithRole(models.RoleReader)
+	err := service.CreateChunk(reader, &models.UnifiedChunkRecord{Contents: "note"})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = service.DeleteSubtree(reader, "chunk-1", models.SubtreeDeleteOptions{Confirm: true})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Zero(t, base.writes)
+
+	// Reads and delete previews change nothing
+	chunk, err := service.GetChunk(reader, "chunk-1")
+	require.NoError(t, err)
+	assert.Equal(t, "chunk-1", chunk.ChunkID)
+	_, err = service.DeleteSubtree(reader, "chunk-1", models.SubtreeDeleteOptions{})
+	assert.NoError(t, err)
+
+	editor := contextWithRole(models.RoleEditor)
+	require.NoError(t, service.CreateChunk(editor, &models.UnifiedChunkRecord{Contents: "note"}))
+	assert.Equal(t, 1, base.writes)
+}
+
+func TestAdminOperations_RefuseEditors(t *testing.T) {
+	// The services have no database; the role check must fail before it is needed
+	editor := contextWithRole(models.RoleEditor)
+
+	_, err := NewChunkHierarchyService(nil, NewNoOpMonitor()).Rebuild(editor)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = NewDatabaseConsistencyChecker(nil, nil).RepairAllInconsistencies(editor)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	tokens := NewAPITokenService(nil, config.AuthConfig{}, NewNoOpMonitor())
+	_, err = tokens.CreateToken(editor, DefaultWorkspace, &models.CreateAPITokenRequest{Name: "ci", Role: models.RoleReader})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.ErrorIs(t, tokens.RevokeToken(editor, DefaultWorkspace, "6c1f0f1e-1b7a-4c55-9a43-0d7f3c2a9e10"), ErrPermissionDenied)
+}
+
+func TestAPITokenService_ValidatesRequests(t *testing.T) {
+	tokens := NewAPITokenService(nil, config.AuthConfig{}, NewNoOpMonitor())
+	admin := contextWithRole(models.RoleAdmin)
+
+	for _, req := range []models.CreateAPITokenRequest{
+		{Name: " ", Role: models.RoleReader},
+		{Name: "ci", Role: models.Role("owner")},
+		{Name: "ci", Role: models.RoleReader, ExpiresIn: "-1h"},
+		{Name: "ci", Role: models.RoleReader, ExpiresIn: "soon"},
+	} {
+		_, err := tokens.CreateToken(admin, DefaultWorkspace, &req)
+		assert.ErrorIs(t, err, ErrInvalidAPITokenRequest, req)
+	}
+
+	assert.ErrorIs(t, tokens.RevokeToken(admin, DefaultWorkspace, "not-a-uuid"), ErrAPITokenNotFound)
+}
+
+func TestGenerateAPIToken(t *testing.T) {
+	first, err := generateAPIToken()
+	require.NoError(t, err)
+	second, err := generateAPIToken()
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(first, apiTokenPrefix))
+	assert.Len(t, first, len(apiTokenPrefix)+43)
+	assert.NotEqual(t, first, second)
+	assert.Len(t, hashAPIToken(first), 64)
+	assert.NotEqual(t, hashAPIToken(first), hashAPIToken(second))
+}
+
+func TestAPITokenService_AuthenticateJWT(t *testing.T) {
+	secret := strings.Repeat("s", 32)
+	tokens := NewAPITokenService(nil, config.AuthConfig{JWTSecret: secret, JWTIssuer: "ink"}, NewNoOpMonitor())
+
+	sign := func(claims jwt.MapClaims, method jwt.SigningMethod, key interface{}) string {
+		signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	valid := jwt.MapClaims{
+		"sub":       "alice",
+		"iss":       "ink",
+		"role":      "editor",
+		"workspace": "legal",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	}
+
+	principal, err := tokens.Authenticate(context.Background(), sign(valid, jwt.SigningMethodHS256, []byte(secret)))
+	require.NoError(t, err)
+	assert.Equal(t, &Principal{Subject: "alice", Workspace: "legal", Role: models.RoleEditor}, principal)
+
+	invalid := map[string]string{}
+	withClaim := func(key string, value interface{}) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	invalid["expired"] = sign(withClaim("exp", time.Now().Add(-time.Minute).Unix()), jwt.SigningMethodHS256, []byte(secret))
+	invalid["no expiry"] = sign(withClaim("exp", nil), jwt.SigningMethodHS256, []byte(secret))
+	invalid["wrong issuer"] = sign(withClaim("iss", "other"), jwt.SigningMethodHS256, []byte(secret))
+	invalid["unknown role"] = sign(withClaim("role", "owner"), jwt.SigningMethodHS256, []byte(secret))
+	invalid["wrong secret"] = sign(valid, jwt.SigningMethodHS256, []byte(strings.Repeat("x", 32)))
+	invalid["unsigned"] = sign(valid, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType)
+	invalid["garbage"] = "not.a.jwt"
+
+	for name, credential := range invalid {
+		_, err := tokens.Authenticate(context.Background(), credential)
+		assert.ErrorIs(t, err, ErrInvalidCredential, name)
+	}
+
+	// Without a secret JWTs are not accepted at all
+	withoutSecret := NewAPITokenService(nil, config.AuthConfig{}, NewNoOpMonitor())
+	_, err = withoutSecret.Authenticate(context.Background(), sign(valid, jwt.SigningMethodHS256, []byte(secret)))
+	assert.ErrorIs(t, err, ErrInvalidCredential)
+}
@@ -0,0 +1,692 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"semantic-text-processor/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// BacklinkService resolves chunk Ref fields into a reference graph stored in chunk_refs
+type BacklinkService interface {
+	// SyncChunkRefs replaces the outgoing references of a chunk with the targets parsed from ref
+	SyncChunkRefs(ctx context.Context, chunkID string, ref *string) error
+	// GetBacklinks returns all chunks whose Ref points at chunkID
+	GetBacklinks(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error)
+	// GetBacklinkSources returns the IDs of the chunks referencing each of chunkIDs in one
+	// query, most recently updated first
+	GetBacklinkSources(ctx context.Context, chunkIDs []string) (map[string][]string, error)
+	// GetOutgoingRefs returns the resolved references held by chunkID
+	GetOutgoingRefs(ctx context.Context, chunkID string) ([]models.ChunkRefRelation, error)
+	// FindBrokenReferences returns references whose target chunk no longer exists
+	FindBrokenReferences(ctx context.Context, limit int) ([]models.BrokenReference, error)
+	// HandleChunkDeleted strips a deleted chunk from every Ref pointing at it
+	HandleChunkDeleted(ctx context.Context, chunkID string) (int, error)
+	// RedirectReferences rewrites every Ref pointing at fromChunkID to point at toChunkID
+	RedirectReferences(ctx context.Context, fromChunkID, toChunkID string) (int, error)
+	// RebuildRefs recomputes chunk_refs from the ref column of every chunk
+	RebuildRefs(ctx context.Context) (int, error)
+}
+
+// backlinkService implements BacklinkService on top of the chunk_refs auxiliary table
+type backlinkService struct {
+	db      *sql.DB
+	cache   CacheService
+	monitor QueryPerformanceMonitor
+}
+
+// NewBacklinkService creates a new backlink service
+func NewBacklinkService(db *sql.DB, cache CacheService, monitor QueryPerformanceMonitor) BacklinkService {
+	return &backlinkService{
+		db:      db,
+		cache:   cache,
+		monitor: monitor,
+	}
+}
+
+// ParseRefTargets extracts referenced chunk IDs from a Ref value.
+// A Ref may hold several references separated by commas or whitespace, optionally wrapped
+// as ((id)) or [[id]]. Values that are not chunk UUIDs are treated as external identifiers
+// and ignored. The result is de-duplicated and keeps the original order.
+func ParseRefTargets(ref string) []string {
+	fields := strings.FieldsFunc(ref, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	seen := make(map[string]bool)
+	targets := make([]string, 0, len(fields))
+	for _, field := range fields {
+		id, err := uuid.Parse(unwrapRefField(field))
+		if err != nil {
+			continue
+		}
+
+		normalized := id.String()
+		if !seen[normalized] {
+			seen[normalized] = true
+			targets = append(targets, normalized)
+		}
+	}
+
+	return targets
+}
+
+// unwrapRefField strips the (( )) or [[ ]] around a reference
+func unwrapRefField(field string) string {
+	field = strings.TrimPrefix(strings.TrimPrefix(field, "(("), "[[")
+	return strings.TrimSuffix(strings.TrimSuffix(field, "))"), "]]")
+}
+
+// rewriteRefTarget replaces oldID in a Ref value with newID, or removes it when newID is empty.
+// Returns nil when no references remain.
+func rewriteRefTarget(ref string, oldID, newID string) *string {
+	fields := strings.FieldsFunc(ref, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t'
+	})
+
+	seen := make(map[string]bool)
+	rewritten := make([]string, 0, len(fields))
+	for _, field := range fields {
+		target := unwrapRefField(field)
+		if id, err := uuid.Parse(target); err == nil && strings.EqualFold(id.String(), oldID) {
+			if newID == "" {
+				continue
+			}
+			field = strings.Replace(field, target, newID, 1)
+		}
+		if !seen[field] {
+			seen[field] = true
+			rewritten = append(rewritten, field)
+		}
+	}
+
+	if len(rewritten) == 0 {
+		return nil
+	}
+
+	result := strings.Join(rewritten, ", ")
+	return &result
+}
+
+// SyncChunkRefs replaces the outgoing references of a chunk
+func (s *backlinkService) SyncChunkRefs(ctx context.Context, chunkID string, ref *string) error {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("sync_chunk_refs", time.Since(start), 1)
+	}()
+
+	var targets []string
+	if ref != nil {
+		targets = ParseRefTargets(*ref)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Capture previous targets so their backlink caches can be invalidated
+	previous, err := s.queryTargets(ctx, tx, chunkID)
+	if err != nil {
+		return err
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM chunk_refs WHERE source_chunk_id = $1", chunkID); err != nil {
+		return fmt.Errorf("failed to clear chunk refs: %w", err)
+	}
+
+	for _, target := range targets {
+		if target == chunkID {
+			continue // self references carry no navigational value
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO chunk_refs (source_chunk_id, target_chunk_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			chunkID, target)
+		if err != nil {
+			return fmt.Errorf("failed to insert chunk ref: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.invalidateBacklinkCaches(ctx, append(previous, targets...))
+
+	return nil
+}
+
+// GetBacklinks returns all chunks whose Ref points at chunkID as stored, without page ACL
+// checks or decryption; NewChunkLoadingBacklinkService serves them to callers
+func (s *backlinkService) GetBacklinks(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	rowCount := 0
+	defer func() {
+		s.monitor.RecordQuery("get_backlinks", time.Since(start), rowCount)
+	}()
+
+	cacheKey := fmt.Sprintf("chunk_backlinks:%s", chunkID)
+	if s.cache != nil {
+		var cached []models.UnifiedChunkRecord
+		if err := s.cache.Get(ctx, cacheKey, &cached); err == nil {
+			rowCount = len(cached)
+			return cached, nil
+		}
+	}
+
+	query := `
+		SELECT ` + unifiedChunkColumns + `
+		FROM chunks c
+		JOIN chunk_refs cr ON c.chunk_id = cr.source_chunk_id
+		WHERE cr.target_chunk_id = $1
+		ORDER BY c.last_updated DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backlinks: %w", err)
+	}
+	defer rows.Close()
+
+	backlinks, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if backlinks == nil {
+		backlinks = []models.UnifiedChunkRecord{}
+	}
+	rowCount = len(backlinks)
+
+	if s.cache != nil {
+		s.cache.Set(ctx, cacheKey, backlinks, 5*time.Minute)
+	}
+
+	return backlinks, nil
+}
+
+// GetBacklinkSources returns the IDs of the chunks referencing each of chunkIDs
+func (s *backlinkService) GetBacklinkSources(ctx context.Context, chunkIDs []string) (map[string][]string, error) {
+	start := time.Now()
+	rowCount := 0
+	defer func() {
+		s.monitor.RecordQuery("get_backlink_sources", time.Since(start), rowCount)
+	}()
+
+	sources := make(map[string][]string)
+	chunkIDs = validChunkIDs(chunkIDs)
+	if len(chunkIDs) == 0 {
+		return sources, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT cr.target_chunk_id, cr.source_chunk_id
+		FROM chunk_refs cr
+		JOIN chunks c ON c.chunk_id = cr.source_chunk_id
+		WHERE cr.target_chunk_id = ANY($1)
+		ORDER BY c.last_updated DESC`, pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query backlink sources: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var target, source string
+		if err := rows.Scan(&target, &source); err != nil {
+			return nil, fmt.Errorf("failed to scan backlink source: %w", err)
+		}
+		sources[target] = append(sources[target], source)
+		rowCount++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating backlink sources: %w", err)
+	}
+	return sources, nil
+}
+
+// GetOutgoingRefs returns the resolved references held by chunkID
+func (s *backlinkService) GetOutgoingRefs(ctx context.Context, chunkID string) ([]models.ChunkRefRelation, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("get_outgoing_refs", time.Since(start), 0)
+	}()
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT source_chunk_id, target_chunk_id, created_at FROM chunk_refs WHERE source_chunk_id = $1 ORDER BY created_at ASC",
+		chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outgoing refs: %w", err)
+	}
+	defer rows.Close()
+
+	refs := []models.ChunkRefRelation{}
+	for rows.Next() {
+		var rel models.ChunkRefRelation
+		if err := rows.Scan(&rel.SourceChunkID, &rel.TargetChunkID, &rel.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk ref row: %w", err)
+		}
+		refs = append(refs, rel)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk ref rows: %w", err)
+	}
+
+	return refs, nil
+}
+
+// FindBrokenReferences returns references whose target chunk no longer exists
+func (s *backlinkService) FindBrokenReferences(ctx context.Context, limit int) ([]models.BrokenReference, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("find_broken_references", time.Since(start), 0)
+	}()
+
+	if limit <= 0 {
+		limit = 100
+	}
+
+	query := `
+		SELECT cr.source_chunk_id, src.contents, cr.target_chunk_id, cr.created_at
+		FROM chunk_refs cr
+		JOIN chunks src ON src.chunk_id = cr.source_chunk_id
+		LEFT JOIN chunks tgt ON tgt.chunk_id = cr.target_chunk_id
+		WHERE tgt.chunk_id IS NULL
+		ORDER BY cr.created_at DESC
+		LIMIT $1
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query broken references: %w", err)
+	}
+	defer rows.Close()
+
+	broken := []models.BrokenReference{}
+	for rows.Next() {
+		var ref models.BrokenReference
+		if err := rows.Scan(&ref.SourceChunkID, &ref.SourceContents, &ref.MissingChunkID, &ref.ReferencedSince); err != nil {
+			return nil, fmt.Errorf("failed to scan broken reference row: %w", err)
+		}
+		broken = append(broken, ref)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating broken reference rows: %w", err)
+	}
+
+	return broken, nil
+}
+
+// HandleChunkDeleted strips a deleted chunk from every Ref pointing at it
+func (s *backlinkService) HandleChunkDeleted(ctx context.Context, chunkID string) (int, error) {
+	return s.rewriteReferences(ctx, "handle_chunk_deleted", chunkID, "")
+}
+
+// RedirectReferences rewrites every Ref pointing at fromChunkID to point at toChunkID
+func (s *backlinkService) RedirectReferences(ctx context.Context, fromChunkID, toChunkID string) (int, error) {
+	if toChunkID == "" {
+		return 0, fmt.Errorf("target chunk ID is required")
+	}
+	if fromChunkID == toChunkID {
+		return 0, nil
+	}
+	return s.rewriteReferences(ctx, "redirect_references", fromChunkID, toChunkID)
+}
+
+// rewriteReferences rewrites the ref column and chunk_refs rows of every chunk referencing oldID.
+// An empty newID removes the reference instead of redirecting it.
+func (s *backlinkService) rewriteReferences(ctx context.Context, operation, oldID, newID string) (int, error) {
+	start := time.Now()
+	updated := 0
+	defer func() {
+		s.monitor.RecordQuery(operation, time.Since(start), updated)
+	}()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.chunk_id, COALESCE(c.ref, '')
+		FROM chunks c
+		JOIN chunk_refs cr ON c.chunk_id = cr.source_chunk_id
+		WHERE cr.target_chunk_id = $1
+		FOR UPDATE OF c`, oldID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query referencing chunks: %w", err)
+	}
+
+	refs := make(map[string]string)
+	for rows.Next() {
+		var sourceID, ref string
+		if err := rows.Scan(&sourceID, &ref); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan referencing chunk: %w", err)
+		}
+		refs[sourceID] = ref
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating referencing chunks: %w", err)
+	}
+
+	for sourceID, ref := range refs {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE chunks SET ref = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
+			rewriteRefTarget(ref, oldID, newID), sourceID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rewrite ref for chunk %s: %w", sourceID, err)
+		}
+
+		if newID != "" && newID != sourceID {
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO chunk_refs (source_chunk_id, target_chunk_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				sourceID, newID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to insert redirected ref: %w", err)
+			}
+		}
+		updated++
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM chunk_refs WHERE target_chunk_id = $1", oldID); err != nil {
+		return 0, fmt.Errorf("failed to remove old refs: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	affected := []string{oldID}
+	if newID != "" {
+		affected = append(affected, newID)
+	}
+	s.invalidateBacklinkCaches(ctx, affected)
+	if s.cache != nil {
+		for sourceID := range refs {
+			s.cache.Delete(ctx, fmt.Sprintf("chunk:%s", sourceID))
+		}
+	}
+
+	return updated, nil
+}
+
+// RebuildRefs recomputes chunk_refs from the ref column of every chunk
+func (s *backlinkService) RebuildRefs(ctx context.Context) (int, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	inserted := 0
+	defer func() {
+		s.monitor.RecordQuery("rebuild_refs", time.Since(start), inserted)
+	}()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT chunk_id, ref FROM chunks WHERE ref IS NOT NULL AND ref <> ''")
+	if err != nil {
+		return 0, fmt.Errorf("failed to query chunk refs: %w", err)
+	}
+
+	refs := make(map[string][]string)
+	for rows.Next() {
+		var chunkID, ref string
+		if err := rows.Scan(&chunkID, &ref); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan chunk ref: %w", err)
+		}
+		if targets := ParseRefTargets(ref); len(targets) > 0 {
+			refs[chunkID] = targets
+		}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating chunk refs: %w", err)
+	}
+
+	if _, err = tx.ExecContext(ctx, "DELETE FROM chunk_refs"); err != nil {
+		return 0, fmt.Errorf("failed to clear chunk refs: %w", err)
+	}
+
+	for chunkID, targets := range refs {
+		for _, target := range targets {
+			if target == chunkID {
+				continue
+			}
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO chunk_refs (source_chunk_id, target_chunk_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+				chunkID, target)
+			if err != nil {
+				return 0, fmt.Errorf("failed to insert chunk ref: %w", err)
+			}
+			inserted++
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if s.cache != nil {
+		s.cache.DeletePattern(ctx, "chunk_backlinks:*")
+	}
+
+	return inserted, nil
+}
+
+// queryTargets returns the current reference targets of a chunk within a transaction
+func (s *backlinkService) queryTargets(ctx context.Context, tx *sql.Tx, chunkID string) ([]string, error) {
+	var targets pq.StringArray
+	err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(array_agg(target_chunk_id::text), '{}') FROM chunk_refs WHERE source_chunk_id = $1",
+		chunkID).Scan(&targets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query existing refs: %w", err)
+	}
+	return []string(targets), nil
+}
+
+// invalidateBacklinkCaches drops cached backlink lists for the given targets
+func (s *backlinkService) invalidateBacklinkCaches(ctx context.Context, targetIDs []string) {
+	if s.cache == nil {
+		return
+	}
+	for _, targetID := range targetIDs {
+		s.cache.Delete(ctx, fmt.Sprintf("chunk_backlinks:%s", targetID))
+	}
+}
+
+// chunkLoadingBacklinkService loads backlinks through a chunk service, so that the page ACLs
+// and decryption of its decorators apply to them
+type chunkLoadingBacklinkService struct {
+	BacklinkService
+	chunks UnifiedChunkService
+}
+
+// NewChunkLoadingBacklinkService wraps a backlink service for callers: backlinks are read as
+// chunk IDs and loaded with chunks, which leaves out chunks the caller may not read.
+func NewChunkLoadingBacklinkService(base BacklinkService, chunks UnifiedChunkService) BacklinkService {
+	return &chunkLoadingBacklinkService{
+		BacklinkService: base,
+		chunks:          chunks,
+	}
+}
+
+// GetBacklinks returns the referencing chunks the caller may read, most recently updated first
+func (s *chunkLoadingBacklinkService) GetBacklinks(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	sources, err := s.BacklinkService.GetBacklinkSources(ctx, []string{chunkID})
+	if err != nil {
+		return nil, err
+	}
+	sourceIDs := sources[chunkID]
+	backlinks := make([]models.UnifiedChunkRecord, 0, len(sourceIDs))
+	if len(sourceIDs) == 0 {
+		return backlinks, nil
+	}
+
+	chunks, err := s.chunks.BatchGetChunks(ctx, sourceIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backlinks: %w", err)
+	}
+	for _, sourceID := range sourceIDs {
+		if chunk, ok := chunks[sourceID]; ok {
+			backlinks = append(backlinks, *chunk)
+		}
+	}
+	return backlinks, nil
+}
+
+// backlinkTrackingChunkService keeps chunk_refs in sync with chunk writes
+type backlinkTrackingChunkService struct {
+	UnifiedChunkService
+	backlinks BacklinkService
+}
+
+// NewBacklinkTrackingChunkService wraps a UnifiedChunkService so that creates, updates and
+// deletes maintain the reference graph. Reference bookkeeping failures are logged and do
+// not fail the underlying write; RebuildRefs can repair any drift.
+func NewBacklinkTrackingChunkService(base UnifiedChunkService, backlinks BacklinkService) UnifiedChunkService {
+	return &backlinkTrackingChunkService{
+		UnifiedChunkService: base,
+		backlinks:           backlinks,
+	}
+}
+
+// CreateChunk creates a chunk and records its references
+func (s *backlinkTrackingChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.CreateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	s.syncRefs(ctx, chunk)
+	return nil
+}
+
+// UpdateChunk updates a chunk and re-syncs its references
+func (s *backlinkTrackingChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.UpdateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	s.syncRefs(ctx, chunk)
+	return nil
+}
+
+// PatchChunk patches a chunk and re-syncs its references
+func (s *backlinkTrackingChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return nil, err
+	}
+	s.syncRefs(ctx, chunk)
+	return chunk, nil
+}
+
+// DeleteChunk deletes a chunk and strips it from chunks that referenced it
+func (s *backlinkTrackingChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
+	if err := s.UnifiedChunkService.DeleteChunk(ctx, chunkID); err != nil {
+		return err
+	}
+	if _, err := s.backlinks.HandleChunkDeleted(ctx, chunkID); err != nil {
+		log.Printf("Warning: failed to update references to deleted chunk %s: %v", chunkID, err)
+	}
+	return nil
+}
+
+// DeleteSubtree deletes a subtree and marks references to its chunks as broken
+func (s *backlinkTrackingChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	result, err := s.UnifiedChunkService.DeleteSubtree(ctx, chunkID, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, deletedID := range result.DeletedChunkIDs {
+		if _, err := s.backlinks.HandleChunkDeleted(ctx, deletedID); err != nil {
+			log.Printf("Warning: failed to update references to deleted chunk %s: %v", deletedID, err)
+		}
+	}
+	return result, nil
+}
+
+// BatchCreateChunks creates chunks and records their references
+func (s *backlinkTrackingChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchCreateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		s.syncRefs(ctx, &chunks[i])
+	}
+	return nil
+}
+
+// BatchUpdateChunks updates chunks and re-syncs their references
+func (s *backlinkTrackingChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		s.syncRefs(ctx, &chunks[i])
+	}
+	return nil
+}
+
+// MergeTags merges tags and redirects references from the merged tags to the target
+func (s *backlinkTrackingChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	result, err := s.UnifiedChunkService.MergeTags(ctx, sourceTagIDs, targetTagID)
+	if err != nil {
+		return nil, err
+	}
+	for _, sourceID := range result.MergedTagIDs {
+		if _, err := s.backlinks.RedirectReferences(ctx, sourceID, targetTagID); err != nil {
+			log.Printf("Warning: failed to redirect references from merged tag %s: %v", sourceID, err)
+		}
+	}
+	return result, nil
+}
+
+// MergeChunks merges two chunks and re-syncs the references of the target from its
+// merged ref. References to the source are redirected inside the merge itself.
+func (s *backlinkTrackingChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	result, err := s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if result.Chunk != nil {
+		s.syncRefs(ctx, result.Chunk)
+	}
+	return result, nil
+}
+
+// CopySubtree copies a subtree and records the references of every copy
+func (s *backlinkTrackingChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	result, err := s.UnifiedChunkService.CopySubtree(ctx, chunkID, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	for _, copyID := range result.IDMapping {
+		chunk, err := s.UnifiedChunkService.GetChunk(ctx, copyID)
+		if err != nil {
+			log.Printf("Warning: failed to load copied chunk %s for reference sync: %v", copyID, err)
+			continue
+		}
+		s.syncRefs(ctx, chunk)
+	}
+	return result, nil
+}
+
+func (s *backlinkTrackingChunkService) syncRefs(ctx context.Context, chunk *models.UnifiedChunkRecord) {
+	if err := s.backlinks.SyncChunkRefs(ctx, chunk.ChunkID, chunk.Ref); err != nil {
+		log.Printf("Warning: failed to sync references for chunk %s: %v", chunk.ChunkID, err)
+	}
+}
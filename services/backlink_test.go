@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func TestParseRefTargets(t *testing.T) {
+	idA := "3f2c1a9e-8b7d-4c6e-9a1b-2d3e4f5a6b7c"
+	idB := "7a6b5c4d-3e2f-4a1b-8c9d-0e1f2a3b4c5d"
+
+	tests := []struct {
+		name     string
+		ref      string
+		expected []string
+	}{
+		{name: "single id", ref: idA, expected: []string{idA}},
+		{name: "comma separated", ref: idA + ", " + idB, expected: []string{idA, idB}},
+		{name: "block ref syntax", ref: "((" + idA + ")) [[" + idB + "]]", expected: []string{idA, idB}},
+		{name: "duplicates removed", ref: idA + " " + idA, expected: []string{idA}},
+		{name: "uppercase normalized", ref: "3F2C1A9E-8B7D-4C6E-9A1B-2D3E4F5A6B7C", expected: []string{idA}},
+		{name: "external identifiers ignored", ref: "text-123, " + idB, expected: []string{idB}},
+		{name: "empty", ref: "", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseRefTargets(tt.ref))
+		})
+	}
+}
+
+func TestRewriteRefTarget(t *testing.T) {
+	idA := "3f2c1a9e-8b7d-4c6e-9a1b-2d3e4f5a6b7c"
+	idB := "7a6b5c4d-3e2f-4a1b-8c9d-0e1f2a3b4c5d"
+	idC := "0b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e"
+
+	t.Run("redirect keeps wrapping syntax", func(t *testing.T) {
+		result := rewriteRefTarget("(("+idA+"))", idA, idB)
+		require.NotNil(t, result)
+		assert.Equal(t, "(("+idB+"))", *result)
+	})
+
+	t.Run("remove one of several", func(t *testing.T) {
+		result := rewriteRefTarget(idA+", "+idC, idA, "")
+		require.NotNil(t, result)
+		assert.Equal(t, idC, *result)
+	})
+
+	t.Run("remove last reference", func(t *testing.T) {
+		assert.Nil(t, rewriteRefTarget(idA, idA, ""))
+	})
+
+	t.Run("redirect collapses duplicates", func(t *testing.T) {
+		result := rewriteRefTarget(idA+", "+idB, idA, idB)
+		require.NotNil(t, result)
+		assert.Equal(t, idB, *result)
+	})
+
+	t.Run("matches mixed-case references", func(t *testing.T) {
+		result := rewriteRefTarget("[["+strings.ToUpper(idA)+"]], "+idC, idA, idB)
+		require.NotNil(t, result)
+		assert.Equal(t, "[["+idB+"]], "+idC, *result)
+
+		assert.Nil(t, rewriteRefTarget("3F2C1A9E-8b7d-4C6E-9a1b-2D3E4F5A6B7C", idA, ""))
+	})
+
+	t.Run("ignores fields merely containing the ID", func(t *testing.T) {
+		result := rewriteRefTarget("x"+idA, idA, "")
+		require.NotNil(t, result)
+		assert.Equal(t, "x"+idA, *result)
+	})
+}
+
+// fixedBacklinkSources reports the same sources for every target
+type fixedBacklinkSources struct {
+	BacklinkService
+	sources []string
+}
+
+func (f *fixedBacklinkSources) GetBacklinkSources(ctx context.Context, chunkIDs []string) (map[string][]string, error) {
+	sources := make(map[string][]string, len(chunkIDs))
+	for _, id := range chunkIDs {
+		sources[id] = f.sources
+	}
+	return sources, nil
+}
+
+func TestChunkLoadingBacklinkService_GetBacklinks(t *testing.T) {
+	chunks, _, _ := newTestPageACLs()
+	base := &fixedBacklinkSources{sources: []string{"secret-block", "open-block", "gone", "loose"}}
+	service := NewChunkLoadingBacklinkService(base, chunks)
+
+	backlinks, err := service.GetBacklinks(contextWithSubject("erin", models.RoleReader), "target")
+	require.NoError(t, err)
+	require.Len(t, backlinks, 2, "chunks on restricted pages and missing chunks are left out")
+	assert.Equal(t, "open-block", backlinks[0].ChunkID)
+	assert.Equal(t, "loose", backlinks[1].ChunkID)
+
+	backlinks, err = service.GetBacklinks(contextWithSubject("alice", models.RoleReader), "target")
+	require.NoError(t, err)
+	assert.Len(t, backlinks, 3)
+
+	base.sources = nil
+	backlinks, err = service.GetBacklinks(context.Background(), "target")
+	require.NoError(t, err)
+	assert.NotNil(t, backlinks)
+	assert.Empty(t, backlinks)
+}
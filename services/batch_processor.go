@@ -2,8 +2,10 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"sync"
 	"sync/atomic"
@@ -49,6 +51,8 @@ type BatchJob struct {
 	pauseChan       chan struct{}
 	resumeChan      chan struct{}
 	isPaused        bool
+	doneFiles       map[string]bool // 已處理（成功或失敗）的檔案路徑
+	interrupted     bool            // 因關機而中斷，已寫入檢查點
 }
 
 // BatchProgress 批次處理進度
@@ -95,6 +99,7 @@ func (b *BatchProcessor) StartBatchProcess(ctx context.Context, req *models.Batc
 		pauseChan:      make(chan struct{}),
 		resumeChan:     make(chan struct{}),
 		isPaused:       false,
+		doneFiles:      make(map[string]bool),
 	}
 	
 	// 註冊批次任務
@@ -278,6 +283,103 @@ func (b *BatchProcessor) CleanupCompletedBatches(olderThan time.Duration) int {
 	return cleaned
 }
 
+// Checkpoint 中斷所有執行中與暫停的批次，並把尚未處理的檔案寫入 path，
+// 供下次啟動時以 ResumeFromCheckpoint 繼續。正在處理的檔案會在恢復時重新處理。
+func (b *BatchProcessor) Checkpoint(path string) (int, error) {
+	b.batchesMutex.RLock()
+	jobs := make([]*BatchJob, 0, len(b.activeBatches))
+	for _, job := range b.activeBatches {
+		jobs = append(jobs, job)
+	}
+	b.batchesMutex.RUnlock()
+
+	checkpoints := make([]models.BatchCheckpoint, 0, len(jobs))
+	now := time.Now()
+	for _, job := range jobs {
+		job.mutex.Lock()
+		switch job.Status.Status {
+		case "starting", "processing", "paused":
+		default:
+			job.mutex.Unlock()
+			continue
+		}
+
+		// 先標記並取消，之後完成的檔案不會再被記為已處理
+		job.interrupted = true
+		job.CancelFunc()
+
+		remaining := make([]string, 0, len(job.Request.Files))
+		for _, file := range job.Request.Files {
+			if !job.doneFiles[file] {
+				remaining = append(remaining, file)
+			}
+		}
+		request := *job.Request
+		request.Files = remaining
+		checkpoints = append(checkpoints, models.BatchCheckpoint{
+			BatchID:        job.ID,
+			Request:        request,
+			ProcessedFiles: job.Status.ProcessedFiles,
+			FailedFiles:    job.Status.FailedFiles,
+			StartedAt:      job.Status.StartedAt,
+			CheckpointedAt: now,
+		})
+		job.mutex.Unlock()
+	}
+
+	if len(checkpoints) == 0 {
+		return 0, nil
+	}
+
+	data, err := json.MarshalIndent(checkpoints, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode batch checkpoint: %w", err)
+	}
+	// 先寫入暫存檔再改名，避免關機中途留下不完整的檢查點
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o600); err != nil {
+		return 0, fmt.Errorf("failed to write batch checkpoint: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, fmt.Errorf("failed to write batch checkpoint: %w", err)
+	}
+	return len(checkpoints), nil
+}
+
+// ResumeFromCheckpoint 以新的批次 ID 重新開始檢查點中尚未處理的檔案，並刪除檢查點檔案。
+// 檔案不存在時不做任何事。
+func (b *BatchProcessor) ResumeFromCheckpoint(ctx context.Context, path string) ([]*BatchJob, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batch checkpoint: %w", err)
+	}
+
+	var checkpoints []models.BatchCheckpoint
+	if err := json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, fmt.Errorf("failed to decode batch checkpoint: %w", err)
+	}
+
+	jobs := make([]*BatchJob, 0, len(checkpoints))
+	for i := range checkpoints {
+		if len(checkpoints[i].Request.Files) == 0 {
+			continue
+		}
+		job, err := b.StartBatchProcess(ctx, &checkpoints[i].Request)
+		if err != nil {
+			return jobs, fmt.Errorf("failed to resume batch %s: %w", checkpoints[i].BatchID, err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return jobs, fmt.Errorf("failed to remove batch checkpoint: %w", err)
+	}
+	return jobs, nil
+}
+
 // 私有方法
 
 // processBatch 處理批次任務
@@ -371,6 +473,12 @@ func (b *BatchProcessor) processBatch(job *BatchJob) {
 
 			// 處理單個檔案
 			result, err := b.processSingleFile(job.Context, file, job.Request)
+			// 因取消而失敗的檔案不算已處理，恢復時會重新處理
+			if err == nil || job.Context.Err() == nil {
+				job.mutex.Lock()
+				job.doneFiles[file.Path] = true
+				job.mutex.Unlock()
+			}
 			if err != nil {
 				errorChan <- models.BatchError{
 					Filename:  file.Filename,
@@ -444,6 +552,9 @@ func (b *BatchProcessor) finalizeBatch(job *BatchJob, status string, err error)
 	job.mutex.Lock()
 	defer job.mutex.Unlock()
 	
+	if job.interrupted && status == string(models.BatchStatusCancelled) {
+		status = string(models.BatchStatusInterrupted)
+	}
 	job.Status.Status = status
 	completedAt := time.Now()
 	job.Status.CompletedAt = &completedAt
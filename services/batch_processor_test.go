@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingMediaProcessor processes files instantly except those it blocks until cancelled
+type blockingMediaProcessor struct {
+	MediaProcessor
+	block map[string]bool
+}
+
+func (p *blockingMediaProcessor) ProcessImage(ctx context.Context, req *models.ProcessImageRequest) (*models.ProcessImageResult, error) {
+	if p.block[req.OriginalFilename] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	return &models.ProcessImageResult{}, nil
+}
+
+func writeBatchFiles(t *testing.T, names ...string) []string {
+	dir := t.TempDir()
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("image"), 0o600))
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+func TestBatchProcessor_CheckpointAndResume(t *testing.T) {
+	files := writeBatchFiles(t, "a.png", "b.png", "c.png")
+	checkpointPath := filepath.Join(t.TempDir(), "batches.json")
+
+	processor := NewBatchProcessor(&blockingMediaProcessor{block: map[string]bool{"b.png": true}}, nil)
+	job, err := processor.StartBatchProcess(context.Background(), &models.BatchProcessRequest{
+		Files:       files,
+		Tags:        []string{"slides"},
+		Concurrency: 3,
+	})
+	require.NoError(t, err)
+
+	// a.png and c.png finish while b.png blocks
+	require.Eventually(t, func() bool {
+		status, err := processor.GetBatchStatus(job.ID)
+		return err == nil && status.ProcessedFiles == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	saved, err := processor.Checkpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved)
+
+	require.Eventually(t, func() bool {
+		status, err := processor.GetBatchStatus(job.ID)
+		return err == nil && status.Status == string(models.BatchStatusInterrupted)
+	}, 2*time.Second, 10*time.Millisecond)
+
+	data, err := os.ReadFile(checkpointPath)
+	require.NoError(t, err)
+	var checkpoints []models.BatchCheckpoint
+	require.NoError(t, json.Unmarshal(data, &checkpoints))
+	require.Len(t, checkpoints, 1)
+	assert.Equal(t, job.ID, checkpoints[0].BatchID)
+	assert.Equal(t, files[1:2], checkpoints[0].Request.Files)
+	assert.Equal(t, []string{"slides"}, checkpoints[0].Request.Tags)
+	assert.Equal(t, 2, checkpoints[0].ProcessedFiles)
+
+	// The next process resumes only the files that were not processed
+	restarted := NewBatchProcessor(&blockingMediaProcessor{}, nil)
+	jobs, err := restarted.ResumeFromCheckpoint(context.Background(), checkpointPath)
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+
+	result, err := restarted.WaitForCompletion(jobs[0].ID, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", result.Status.Status)
+	assert.Equal(t, 1, result.Status.TotalFiles)
+	assert.Equal(t, 1, result.Status.ProcessedFiles)
+
+	_, err = os.Stat(checkpointPath)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBatchProcessor_CheckpointSkipsFinishedBatches(t *testing.T) {
+	files := writeBatchFiles(t, "a.png")
+	checkpointPath := filepath.Join(t.TempDir(), "batches.json")
+
+	processor := NewBatchProcessor(&blockingMediaProcessor{}, nil)
+	job, err := processor.StartBatchProcess(context.Background(), &models.BatchProcessRequest{Files: files})
+	require.NoError(t, err)
+	_, err = processor.WaitForCompletion(job.ID, 2*time.Second)
+	require.NoError(t, err)
+
+	saved, err := processor.Checkpoint(checkpointPath)
+	require.NoError(t, err)
+	assert.Zero(t, saved)
+	_, err = os.Stat(checkpointPath)
+	assert.True(t, os.IsNotExist(err))
+
+	// Without a checkpoint there is nothing to resume
+	jobs, err := processor.ResumeFromCheckpoint(context.Background(), checkpointPath)
+	require.NoError(t, err)
+	assert.Empty(t, jobs)
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// BULK TAG ASSIGNMENT
+// ============================================================================
+//
+// Bulk assignment resolves a selector, a search, a tag filter or a list of chunk IDs, to
+// chunk IDs through the full chunk service stack, so searches see the same results as the
+// search API, and then tags the chunks in batches of one transaction each. A failed batch
+// leaves the batches before it applied.
+
+// ErrInvalidBulkTagRequest is returned for bulk tag requests without tags or with a
+// selector that does not pick exactly one kind of chunk set
+var ErrInvalidBulkTagRequest = errors.New("invalid bulk tag request")
+
+const (
+	bulkTagBatchSize = 500
+	maxBulkTagChunks = 50000
+)
+
+// BulkTagService adds tags to many chunks at once
+type BulkTagService interface {
+	// TagChunks adds tagIDs to every chunk selector picks. Progress is reported after each
+	// batch to the function set with WithBulkTagProgress.
+	TagChunks(ctx context.Context, selector *models.TagSelector, tagIDs []string) (*models.BulkTagResult, error)
+}
+
+// BulkTagProgressFunc receives the progress of a bulk tag assignment
+type BulkTagProgressFunc func(progress models.BulkTagProgress)
+
+type bulkTagProgressKey struct{}
+
+// WithBulkTagProgress returns a context whose bulk tag assignments report their progress
+// to fn after each batch
+func WithBulkTagProgress(ctx context.Context, fn BulkTagProgressFunc) context.Context {
+	return context.WithValue(ctx, bulkTagProgressKey{}, fn)
+}
+
+func bulkTagProgressFrom(ctx context.Context) BulkTagProgressFunc {
+	fn, _ := ctx.Value(bulkTagProgressKey{}).(BulkTagProgressFunc)
+	return fn
+}
+
+// bulkTagService implements BulkTagService on top of a chunk service
+type bulkTagService struct {
+	chunks    UnifiedChunkService
+	monitor   QueryPerformanceMonitor
+	batchSize int
+}
+
+// NewBulkTagService creates a bulk tag service that reads and writes through chunks
+func NewBulkTagService(chunks UnifiedChunkService, monitor QueryPerformanceMonitor) BulkTagService {
+	return &bulkTagService{chunks: chunks, monitor: monitor, batchSize: bulkTagBatchSize}
+}
+
+// TagChunks adds tagIDs to every chunk selector picks, one batch per transaction
+func (s *bulkTagService) TagChunks(ctx context.Context, selector *models.TagSelector, tagIDs []string) (*models.BulkTagResult, error) {
+	start := time.Now()
+	result := &models.BulkTagResult{TagIDs: uniqueNonEmpty(tagIDs)}
+	defer func() {
+		s.monitor.RecordQuery("bulk_tag_chunks", time.Since(start), result.Tagged)
+	}()
+
+	// Readers are refused before the selector runs what may be a large search
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	if len(result.TagIDs) == 0 {
+		return nil, fmt.Errorf("%w: at least one tag is required", ErrInvalidBulkTagRequest)
+	}
+
+	chunkIDs, err := s.selectChunks(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+	result.Selected = len(chunkIDs)
+
+	progress := bulkTagProgressFrom(ctx)
+	for offset := 0; offset < len(chunkIDs); offset += s.batchSize {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("bulk tagging stopped after %d of %d chunks: %w", offset, len(chunkIDs), err)
+		}
+
+		batch := chunkIDs[offset:min(offset+s.batchSize, len(chunkIDs))]
+		tagged, err := s.chunks.BatchAddTags(ctx, batch, result.TagIDs)
+		if err != nil {
+			return result, fmt.Errorf("bulk tagging failed after %d of %d chunks: %w", offset, len(chunkIDs), err)
+		}
+		result.Tagged += tagged
+		result.Batches++
+
+		if progress != nil {
+			progress(models.BulkTagProgress{
+				Processed: offset + len(batch),
+				Total:     len(chunkIDs),
+				Tagged:    result.Tagged,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// selectChunks resolves a selector to distinct chunk IDs
+func (s *bulkTagService) selectChunks(ctx context.Context, selector *models.TagSelector) ([]string, error) {
+	if selector == nil {
+		return nil, fmt.Errorf("%w: a selector is required", ErrInvalidBulkTagRequest)
+	}
+	kinds := 0
+	if selector.Query != nil {
+		kinds++
+	}
+	if len(selector.Tags) > 0 {
+		kinds++
+	}
+	if len(selector.ChunkIDs) > 0 {
+		kinds++
+	}
+	if kinds != 1 {
+		return nil, fmt.Errorf("%w: the selector must set exactly one of query, tags and chunk_ids", ErrInvalidBulkTagRequest)
+	}
+
+	var chunkIDs []string
+	switch {
+	case len(selector.ChunkIDs) > 0:
+		chunkIDs = uniqueNonEmpty(selector.ChunkIDs)
+
+	case len(selector.Tags) > 0:
+		logic := strings.ToUpper(selector.TagLogic)
+		if logic == "" {
+			logic = "OR"
+		}
+		if logic != "AND" && logic != "OR" {
+			return nil, fmt.Errorf("%w: tag_logic must be AND or OR", ErrInvalidBulkTagRequest)
+		}
+		chunks, err := s.chunks.GetChunksByTags(ctx, selector.Tags, logic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select chunks by tag: %w", err)
+		}
+		for _, chunk := range chunks {
+			chunkIDs = append(chunkIDs, chunk.ChunkID)
+		}
+		chunkIDs = uniqueNonEmpty(chunkIDs)
+
+	default:
+		var err error
+		if chunkIDs, err = s.searchChunkIDs(ctx, *selector.Query); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(chunkIDs) > maxBulkTagChunks {
+		return nil, fmt.Errorf("%w: the selector picks %d chunks, more than the limit of %d", ErrInvalidBulkTagRequest, len(chunkIDs), maxBulkTagChunks)
+	}
+	return chunkIDs, nil
+}
+
+// searchChunkIDs pages through a search. The query's Limit, when set, caps the chunks
+// returned; its Offset is where paging starts.
+func (s *bulkTagService) searchChunkIDs(ctx context.Context, query models.SearchQuery) ([]string, error) {
+	limit := query.Limit
+	if limit <= 0 {
+		// One more than the maximum, so too broad a search is reported instead of cut off
+		limit = maxBulkTagChunks + 1
+	}
+
+	seen := make(map[string]bool)
+	var chunkIDs []string
+	for len(chunkIDs) < limit {
+		query.Limit = min(s.batchSize, limit-len(chunkIDs))
+		page, err := s.chunks.SearchChunks(ctx, &query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to select chunks by search: %w", err)
+		}
+		for _, chunk := range page.Chunks {
+			if !seen[chunk.ChunkID] {
+				seen[chunk.ChunkID] = true
+				chunkIDs = append(chunkIDs, chunk.ChunkID)
+			}
+		}
+		if len(page.Chunks) < query.Limit {
+			break
+		}
+		query.Offset += len(page.Chunks)
+	}
+	return chunkIDs, nil
+}
+
+// uniqueNonEmpty returns ids without blanks and duplicates, in their first order
+func uniqueNonEmpty(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+// BatchAddTags adds tags to many chunks in one transaction. New tags are appended to each
+// chunk's tags array; chunks that do not exist are skipped.
+func (s *unifiedChunkService) BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error) {
+	start := time.Now()
+	var updated pq.StringArray
+	defer func() {
+		s.monitor.RecordQuery("batch_add_tags", time.Since(start), len(updated))
+	}()
+
+	chunkIDs = uniqueNonEmpty(chunkIDs)
+	tagChunkIDs = uniqueNonEmpty(tagChunkIDs)
+	if len(chunkIDs) == 0 || len(tagChunkIDs) == 0 {
+		return 0, nil
+	}
+
+	for _, tagID := range tagChunkIDs {
+		if err := s.validateTagChunk(ctx, tagID); err != nil {
+			return 0, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Only chunks missing one of the tags are rewritten, keeping their tag order
+	err = tx.QueryRowContext(ctx, `
+		WITH updated AS (
+			UPDATE chunks SET
+				tags = COALESCE(tags, '[]'::jsonb) || (
+					SELECT jsonb_agg(t) FROM unnest($2::text[]) AS t
+					WHERE NOT COALESCE(chunks.tags, '[]'::jsonb) ? t
+				),
+				last_updated = NOW(),
+				version = version + 1
+			WHERE chunk_id = ANY($1) AND NOT COALESCE(tags, '[]'::jsonb) ?& $2::text[]
+			RETURNING chunk_id
+		)
+		SELECT COALESCE(array_agg(chunk_id), '{}') FROM updated`,
+		pq.Array(chunkIDs), pq.Array(tagChunkIDs)).Scan(&updated)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add tags to chunks: %w", err)
+	}
+
+	// The auxiliary table is written for every existing chunk, so it also catches up with
+	// tags arrays that already held the tags
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunk_tags (source_chunk_id, tag_chunk_id)
+		SELECT c.chunk_id, t::uuid FROM chunks c CROSS JOIN unnest($2::text[]) AS t
+		WHERE c.chunk_id = ANY($1)
+		ON CONFLICT DO NOTHING`,
+		pq.Array(chunkIDs), pq.Array(tagChunkIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert tag relationships: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.markWrite()
+	for _, chunkID := range updated {
+		s.cache.Delete(ctx, fmt.Sprintf("chunk:%s", chunkID))
+		s.cache.Delete(ctx, fmt.Sprintf("chunk_tags:%s", chunkID))
+	}
+	s.cache.DeletePattern(ctx, "chunks_by_tag:*")
+	s.cache.DeletePattern(ctx, "chunks_by_tags:*")
+
+	return len(updated), nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+// bulkTagRecorder serves a fixed set of chunks to searches and tag filters and records
+// the batches it is asked to tag
+type bulkTagRecorder struct {
+	UnifiedChunkService
+	chunks   []string
+	searches []models.SearchQuery
+	batches  [][]string
+}
+
+func newBulkTagRecorder(n int) *bulkTagRecorder {
+	r := &bulkTagRecorder{}
+	for i := 0; i < n; i++ {
+		r.chunks = append(r.chunks, fmt.Sprintf("chunk-%d", i))
+	}
+	return r
+}
+
+func (r *bulkTagRecorder) records(ids []string) []models.UnifiedChunkRecord {
+	records := make([]models.UnifiedChunkRecord, len(ids))
+	for i, id := range ids {
+		records[i] = models.UnifiedChunkRecord{ChunkID: id}
+	}
+	return records
+}
+
+func (r *bulkTagRecorder) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	r.searches = append(r.searches, *query)
+	start := min(query.Offset, len(r.chunks))
+	end := min(start+query.Limit, len(r.chunks))
+	return &models.SearchResult{Chunks: r.records(r.chunks[start:end]), TotalCount: len(r.chunks)}, nil
+}
+
+func (r *bulkTagRecorder) GetChunksByTags(ctx context.Context, tagChunkIDs []string, matchType string) ([]models.UnifiedChunkRecord, error) {
+	return r.records(r.chunks), nil
+}
+
+func (r *bulkTagRecorder) BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error) {
+	r.batches = append(r.batches, chunkIDs)
+	return len(chunkIDs), nil
+}
+
+func TestBulkTagService_TagsSearchResultsInBatches(t *testing.T) {
+	chunks := newBulkTagRecorder(1200)
+	service := NewBulkTagService(chunks, NewNoOpMonitor())
+
+	var progress []models.BulkTagProgress
+	ctx := WithBulkTagProgress(context.Background(), func(p models.BulkTagProgress) {
+		progress = append(progress, p)
+	})
+
+	result, err := service.TagChunks(ctx, &models.TagSelector{Query: &models.SearchQuery{Content: "contract"}}, []string{"tag-1", "tag-1", ""})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"tag-1"}, result.TagIDs)
+	assert.Equal(t, 1200, result.Selected)
+	assert.Equal(t, 1200, result.Tagged)
+	assert.Equal(t, 3, result.Batches)
+	require.Len(t, chunks.batches, 3)
+	assert.Len(t, chunks.batches[0], bulkTagBatchSize)
+	assert.Len(t, chunks.batches[2], 200)
+
+	// The search is paged rather than asked for every result at once
+	require.Len(t, chunks.searches, 3)
+	assert.Equal(t, 1000, chunks.searches[2].Offset)
+
+	assert.Equal(t, []models.BulkTagProgress{
+		{Processed: 500, Total: 1200, Tagged: 500},
+		{Processed: 1000, Total: 1200, Tagged: 1000},
+		{Processed: 1200, Total: 1200, Tagged: 1200},
+	}, progress)
+}
+
+func TestBulkTagService_SearchLimitCapsSelection(t *testing.T) {
+	chunks := newBulkTagRecorder(1200)
+	service := NewBulkTagService(chunks, NewNoOpMonitor())
+
+	result, err := service.TagChunks(context.Background(), &models.TagSelector{Query: &models.SearchQuery{Content: "contract", Limit: 700}}, []string{"tag-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 700, result.Selected)
+	assert.Equal(t, 200, chunks.searches[1].Limit)
+}
+
+func TestBulkTagService_ChunkIDsAndTagFilter(t *testing.T) {
+	chunks := newBulkTagRecorder(3)
+	service := NewBulkTagService(chunks, NewNoOpMonitor())
+
+	result, err := service.TagChunks(context.Background(), &models.TagSelector{ChunkIDs: []string{"a", "b", "a"}}, []string{"tag-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Selected)
+	assert.Equal(t, [][]string{{"a", "b"}}, chunks.batches)
+
+	result, err = service.TagChunks(context.Background(), &models.TagSelector{Tags: []string{"tag-2"}, TagLogic: "and"}, []string{"tag-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Selected)
+}
+
+func TestBulkTagService_RejectsInvalidRequests(t *testing.T) {
+	chunks := newBulkTagRecorder(3)
+	service := NewBulkTagService(chunks, NewNoOpMonitor())
+	ctx := context.Background()
+
+	invalid := map[string]struct {
+		selector *models.TagSelector
+		tags     []string
+	}{
+		"no tags":          {&models.TagSelector{ChunkIDs: []string{"a"}}, []string{""}},
+		"no selector":      {nil, []string{"tag-1"}},
+		"empty selector":   {&models.TagSelector{}, []string{"tag-1"}},
+		"two selectors":    {&models.TagSelector{ChunkIDs: []string{"a"}, Tags: []string{"tag-2"}}, []string{"tag-1"}},
+		"unknown tag mode": {&models.TagSelector{Tags: []string{"tag-2"}, TagLogic: "XOR"}, []string{"tag-1"}},
+	}
+	for name, tc := range invalid {
+		_, err := service.TagChunks(ctx, tc.selector, tc.tags)
+		assert.ErrorIs(t, err, ErrInvalidBulkTagRequest, name)
+	}
+
+	_, err := service.TagChunks(contextWithRole(models.RoleReader), &models.TagSelector{ChunkIDs: []string{"a"}}, []string{"tag-1"})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Empty(t, chunks.batches)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 )
@@ -28,6 +29,19 @@ type CacheStats struct {
 	MaxSize     int     `json:"max_size"`
 	Evictions   int64   `json:"evictions"`
 	LastCleared time.Time `json:"last_cleared"`
+
+	// Bytes is the estimated size of the entries; MaxBytes is 0 without a byte limit
+	Bytes          int64  `json:"bytes"`
+	MaxBytes       int64  `json:"max_bytes"`
+	EvictionPolicy string `json:"eviction_policy,omitempty"`
+	// EvictedBytes is the estimated size of the evicted entries, and SizeEvictions the
+	// evictions made for the byte limit rather than the entry limit
+	EvictedBytes  int64 `json:"evicted_bytes"`
+	SizeEvictions int64 `json:"size_evictions"`
+	// Expirations counts entries dropped because their TTL passed
+	Expirations int64 `json:"expirations"`
+	// Rejected counts entries not cached because they alone exceed the byte limit
+	Rejected int64 `json:"rejected"`
 }
 
 // CacheEntry represents a cached item
@@ -35,26 +49,38 @@ type CacheEntry struct {
 	Value     []byte    `json:"value"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
+	Cost      int64     `json:"cost"`
 }
 
-// InMemoryCache implements CacheService using in-memory storage
+// InMemoryCache implements CacheService using in-memory storage. It holds at most maxSize
+// entries and, when a byte limit is set, at most maxBytes of estimated entry size, evicting
+// entries by its eviction policy to stay within both.
 type InMemoryCache struct {
 	mu       sync.RWMutex
 	data     map[string]*CacheEntry
 	maxSize  int
+	maxBytes int64
+	bytes    int64
+	policy   EvictionPolicy
+	evictor  cacheEvictor
 	stats    CacheStats
 	janitor  *time.Ticker
 	stopChan chan struct{}
+	codec    CacheCodec
+	ttls     CacheTTLPolicy
 }
 
-// NewInMemoryCache creates a new in-memory cache
+// NewInMemoryCache creates a new in-memory cache that evicts the least recently used entries
 func NewInMemoryCache(maxSize int, cleanupInterval time.Duration) *InMemoryCache {
 	cache := &InMemoryCache{
 		data:     make(map[string]*CacheEntry),
 		maxSize:  maxSize,
+		policy:   EvictionLRU,
+		evictor:  newLRUEvictor(),
 		stats:    CacheStats{MaxSize: maxSize, LastCleared: time.Now()},
 		janitor:  time.NewTicker(cleanupInterval),
 		stopChan: make(chan struct{}),
+		codec:    JSONCodec{},
 	}
 	
 	// Start cleanup goroutine
@@ -79,51 +105,188 @@ func (c *InMemoryCache) Get(ctx context.Context, key string, dest interface{}) e
 	if time.Now().After(entry.ExpiresAt) {
 		c.stats.Misses++
 		// Remove expired entry immediately
-		delete(c.data, key)
-		c.stats.Size = len(c.data)
+		c.expire(key)
 		c.updateHitRate()
 		return fmt.Errorf("cache miss: key %s expired", key)
 	}
 	
 	c.stats.Hits++
+	c.evictor.accessed(key)
 	c.updateHitRate()
 	
 	// Deserialize value
 	return json.Unmarshal(entry.Value, dest)
 }
 
-// GetDirect retrieves a value from cache without deserialization
+// GetDirect retrieves a value from cache without a destination type. Values come back as
+// decoded JSON (maps, slices and float64s), not as the type stored; use TypedCache to get
+// the stored type back.
 func (c *InMemoryCache) GetDirect(ctx context.Context, key string) (interface{}, bool) {
+	data, found := c.GetRaw(ctx, key)
+	if !found {
+		return nil, false
+	}
+	
+	// Deserialize and return value
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, false
+	}
+	
+	return value, true
+}
+
+// GetRaw retrieves the stored bytes of a value
+func (c *InMemoryCache) GetRaw(ctx context.Context, key string) ([]byte, bool) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	
+
 	entry, exists := c.data[key]
 	if !exists {
 		c.stats.Misses++
 		c.updateHitRate()
 		return nil, false
 	}
-	
+
 	// Check if expired
 	if time.Now().After(entry.ExpiresAt) {
 		c.stats.Misses++
 		// Remove expired entry immediately
-		delete(c.data, key)
-		c.stats.Size = len(c.data)
+		c.expire(key)
 		c.updateHitRate()
 		return nil, false
 	}
-	
+
 	c.stats.Hits++
+	c.evictor.accessed(key)
 	c.updateHitRate()
-	
-	// Deserialize and return value
-	var value interface{}
-	if err := json.Unmarshal(entry.Value, &value); err != nil {
-		return nil, false
+	return entry.Value, true
+}
+
+// SetRaw stores already encoded bytes
+func (c *InMemoryCache) SetRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.store(key, data, ttl)
+	return nil
+}
+
+// store adds or replaces an entry, first evicting entries until it fits within the entry
+// and byte limits. An entry larger than the byte limit is not stored, and any older
+// value of its key is removed. Callers hold the lock.
+func (c *InMemoryCache) store(key string, data []byte, ttl time.Duration) {
+	cost := cacheEntryCost(key, data)
+	previous, exists := c.data[key]
+	if c.maxBytes > 0 && cost > c.maxBytes {
+		if exists {
+			c.remove(key, false)
+		}
+		c.stats.Rejected++
+		return
 	}
-	
-	return value, true
+
+	if exists {
+		// The replaced value's bytes are freed before making room
+		c.bytes -= previous.Cost
+		c.evictor.accessed(key)
+	} else {
+		for len(c.data) >= c.maxSize {
+			if !c.evict(key, false) {
+				break
+			}
+		}
+	}
+	for c.maxBytes > 0 && c.bytes+cost > c.maxBytes {
+		if !c.evict(key, true) {
+			break
+		}
+	}
+
+	now := time.Now()
+	c.data[key] = &CacheEntry{
+		Value:     data,
+		ExpiresAt: now.Add(c.ttl(key, ttl)),
+		CreatedAt: now,
+		Cost:      cost,
+	}
+	c.bytes += cost
+	if !exists {
+		c.evictor.added(key)
+	}
+	c.stats.Size = len(c.data)
+}
+
+// evict removes the entry the eviction policy picks to make room for incoming, other than
+// incoming itself, and reports whether there was one. forSize counts it as an eviction
+// for the byte limit.
+func (c *InMemoryCache) evict(incoming string, forSize bool) bool {
+	victim, ok := c.evictor.victim(incoming)
+	if !ok || victim == incoming {
+		return false
+	}
+	entry, exists := c.data[victim]
+	if !exists {
+		// Keep a stray key from blocking eviction forever
+		c.evictor.removed(victim, false)
+		return true
+	}
+	c.remove(victim, true)
+	c.stats.Evictions++
+	c.stats.EvictedBytes += entry.Cost
+	if forSize {
+		c.stats.SizeEvictions++
+	}
+	return true
+}
+
+// remove deletes an entry; evicted tells the eviction policy whether it chose the entry.
+// Callers hold the lock.
+func (c *InMemoryCache) remove(key string, evicted bool) {
+	entry, exists := c.data[key]
+	if !exists {
+		return
+	}
+	delete(c.data, key)
+	c.bytes -= entry.Cost
+	c.evictor.removed(key, evicted)
+	c.stats.Size = len(c.data)
+}
+
+// expire removes an entry whose TTL passed. Callers hold the lock.
+func (c *InMemoryCache) expire(key string) {
+	c.remove(key, false)
+	c.stats.Expirations++
+}
+
+// Codec returns the codec typed caches use for this cache
+func (c *InMemoryCache) Codec() CacheCodec {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.codec
+}
+
+// SetCodec changes the codec typed caches use for new entries; entries written with the
+// previous codec read as misses
+func (c *InMemoryCache) SetCodec(codec CacheCodec) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.codec = codec
+}
+
+// SetTTLPolicy makes policy decide the TTL of new entries; nil keeps the TTLs writers ask for
+func (c *InMemoryCache) SetTTLPolicy(policy CacheTTLPolicy) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ttls = policy
+}
+
+// ttl returns how long to keep a new entry; callers hold the lock
+func (c *InMemoryCache) ttl(key string, requested time.Duration) time.Duration {
+	if c.ttls == nil {
+		return requested
+	}
+	return c.ttls.TTL(key, requested)
 }
 
 // Set stores a value in cache
@@ -137,19 +300,7 @@ func (c *InMemoryCache) Set(ctx context.Context, key string, value interface{},
 		return fmt.Errorf("failed to serialize cache value: %w", err)
 	}
 	
-	// Check if we need to evict entries
-	if len(c.data) >= c.maxSize {
-		c.evictOldest()
-	}
-	
-	entry := &CacheEntry{
-		Value:     data,
-		ExpiresAt: time.Now().Add(ttl),
-		CreatedAt: time.Now(),
-	}
-	
-	c.data[key] = entry
-	c.stats.Size = len(c.data)
+	c.store(key, data, ttl)
 	
 	return nil
 }
@@ -159,8 +310,7 @@ func (c *InMemoryCache) Delete(ctx context.Context, key string) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	delete(c.data, key)
-	c.stats.Size = len(c.data)
+	c.remove(key, false)
 	
 	return nil
 }
@@ -173,8 +323,7 @@ func (c *InMemoryCache) DeletePattern(ctx context.Context, pattern string) error
 	// Simple pattern matching - supports * wildcard at the end
 	if pattern == "*" {
 		// Delete all
-		c.data = make(map[string]*CacheEntry)
-		c.stats.Size = 0
+		c.reset()
 		return nil
 	}
 	
@@ -190,11 +339,11 @@ func (c *InMemoryCache) DeletePattern(ctx context.Context, pattern string) error
 		}
 		
 		for _, key := range keysToDelete {
-			delete(c.data, key)
+			c.remove(key, false)
 		}
 	} else {
 		// Exact match
-		delete(c.data, pattern)
+		c.remove(pattern, false)
 	}
 	
 	c.stats.Size = len(c.data)
@@ -206,13 +355,21 @@ func (c *InMemoryCache) Clear(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	
-	c.data = make(map[string]*CacheEntry)
-	c.stats.Size = 0
+	c.reset()
 	c.stats.LastCleared = time.Now()
 	
 	return nil
 }
 
+// reset drops every entry. Callers hold the lock.
+func (c *InMemoryCache) reset() {
+	c.data = make(map[string]*CacheEntry)
+	c.bytes = 0
+	// The policy was accepted when it was set
+	c.evictor, _ = newCacheEvictor(c.policy, c.maxSize)
+	c.stats.Size = 0
+}
+
 // GetStats returns cache statistics
 func (c *InMemoryCache) GetStats() CacheStats {
 	c.mu.RLock()
@@ -220,9 +377,72 @@ func (c *InMemoryCache) GetStats() CacheStats {
 	
 	stats := c.stats
 	stats.Size = len(c.data)
+	stats.Bytes = c.bytes
+	stats.MaxBytes = c.maxBytes
+	stats.EvictionPolicy = string(c.policy)
 	return stats
 }
 
+// SetMaxSize changes the entry limit, evicting entries when shrinking
+func (c *InMemoryCache) SetMaxSize(maxSize int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxSize = maxSize
+	c.stats.MaxSize = maxSize
+	c.evictor.setCapacity(maxSize)
+	for len(c.data) > c.maxSize {
+		if !c.evict("", false) {
+			break
+		}
+	}
+	c.stats.Size = len(c.data)
+}
+
+// SetMaxBytes changes the limit on the estimated size of the entries, evicting entries
+// when shrinking; 0 removes the limit
+func (c *InMemoryCache) SetMaxBytes(maxBytes int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.maxBytes = maxBytes
+	for c.maxBytes > 0 && c.bytes > c.maxBytes {
+		if !c.evict("", true) {
+			break
+		}
+	}
+}
+
+// SetEvictionPolicy changes how entries are picked for eviction. The entries kept are
+// handed to the new policy oldest first, without their access history.
+func (c *InMemoryCache) SetEvictionPolicy(policy EvictionPolicy) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if policy == c.policy {
+		return nil
+	}
+
+	evictor, err := newCacheEvictor(policy, c.maxSize)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(c.data))
+	for key := range c.data {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return c.data[keys[i]].CreatedAt.Before(c.data[keys[j]].CreatedAt)
+	})
+	for _, key := range keys {
+		evictor.added(key)
+	}
+	c.policy = policy
+	c.evictor = evictor
+	return nil
+}
+
 // Stop stops the cache cleanup goroutine
 func (c *InMemoryCache) Stop() {
 	close(c.stopChan)
@@ -249,30 +469,12 @@ func (c *InMemoryCache) removeExpired() {
 	now := time.Now()
 	for key, entry := range c.data {
 		if now.After(entry.ExpiresAt) {
-			delete(c.data, key)
+			c.expire(key)
 		}
 	}
 	c.stats.Size = len(c.data)
 }
 
-// evictOldest removes the oldest entry to make room for new ones
-func (c *InMemoryCache) evictOldest() {
-	var oldestKey string
-	var oldestTime time.Time
-	
-	for key, entry := range c.data {
-		if oldestKey == "" || entry.CreatedAt.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = entry.CreatedAt
-		}
-	}
-	
-	if oldestKey != "" {
-		delete(c.data, oldestKey)
-		c.stats.Evictions++
-	}
-}
-
 // updateHitRate calculates the current hit rate
 func (c *InMemoryCache) updateHitRate() {
 	total := c.stats.Hits + c.stats.Misses
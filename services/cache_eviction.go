@@ -0,0 +1,288 @@
+package services
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// EvictionPolicy names how InMemoryCache picks the entry to drop when it is full
+type EvictionPolicy string
+
+const (
+	// EvictionLRU drops the least recently used entry
+	EvictionLRU EvictionPolicy = "lru"
+	// EvictionLFU drops the least frequently used entry, the least recently used among equals
+	EvictionLFU EvictionPolicy = "lfu"
+	// EvictionARC balances recency and frequency with the Adaptive Replacement Cache
+	// algorithm, which also remembers recently evicted keys to tune the balance
+	EvictionARC EvictionPolicy = "arc"
+)
+
+// cacheEntryOverhead estimates the memory an entry takes besides its key and value: the
+// entry, its map slot and its eviction bookkeeping
+const cacheEntryOverhead = 128
+
+// cacheEntryCost estimates the bytes an entry takes
+func cacheEntryCost(key string, value []byte) int64 {
+	return int64(len(key) + len(value) + cacheEntryOverhead)
+}
+
+// cacheEvictor tracks the keys of a cache to choose eviction victims. The cache calls it
+// under its lock.
+type cacheEvictor interface {
+	// added records a new key
+	added(key string)
+	// accessed records a hit or an overwrite of a key
+	accessed(key string)
+	// removed forgets a key; evicted reports whether the evictor chose it
+	removed(key string, evicted bool)
+	// victim returns the key to evict to make room for incoming
+	victim(incoming string) (string, bool)
+	// setCapacity tells the evictor how many entries the cache holds at most
+	setCapacity(capacity int)
+}
+
+// newCacheEvictor returns the evictor of a policy; "" selects LRU
+func newCacheEvictor(policy EvictionPolicy, capacity int) (cacheEvictor, error) {
+	switch policy {
+	case "", EvictionLRU:
+		return newLRUEvictor(), nil
+	case EvictionLFU:
+		return newLFUEvictor(), nil
+	case EvictionARC:
+		return newARCEvictor(capacity), nil
+	default:
+		return nil, fmt.Errorf("unknown cache eviction policy: %s", policy)
+	}
+}
+
+// keyList is a recency list of keys, most recent first
+type keyList struct {
+	order    *list.List
+	elements map[string]*list.Element
+}
+
+func newKeyList() *keyList {
+	return &keyList{order: list.New(), elements: make(map[string]*list.Element)}
+}
+
+func (l *keyList) len() int { return len(l.elements) }
+
+func (l *keyList) contains(key string) bool {
+	_, ok := l.elements[key]
+	return ok
+}
+
+// touch makes key the most recent, adding it if needed
+func (l *keyList) touch(key string) {
+	if element, ok := l.elements[key]; ok {
+		l.order.MoveToFront(element)
+		return
+	}
+	l.elements[key] = l.order.PushFront(key)
+}
+
+func (l *keyList) remove(key string) bool {
+	element, ok := l.elements[key]
+	if ok {
+		l.order.Remove(element)
+		delete(l.elements, key)
+	}
+	return ok
+}
+
+// oldest returns the least recent key
+func (l *keyList) oldest() (string, bool) {
+	element := l.order.Back()
+	if element == nil {
+		return "", false
+	}
+	return element.Value.(string), true
+}
+
+// lruEvictor evicts the least recently used key
+type lruEvictor struct {
+	keys *keyList
+}
+
+func newLRUEvictor() *lruEvictor {
+	return &lruEvictor{keys: newKeyList()}
+}
+
+func (e *lruEvictor) added(key string)                 { e.keys.touch(key) }
+func (e *lruEvictor) accessed(key string)              { e.keys.touch(key) }
+func (e *lruEvictor) removed(key string, evicted bool) { e.keys.remove(key) }
+func (e *lruEvictor) setCapacity(capacity int)         {}
+
+func (e *lruEvictor) victim(incoming string) (string, bool) {
+	return e.keys.oldest()
+}
+
+// lfuEvictor evicts the least frequently used key in constant time, keeping one recency
+// list of keys per use count
+type lfuEvictor struct {
+	counts   map[string]int
+	buckets  map[int]*keyList
+	minCount int
+}
+
+func newLFUEvictor() *lfuEvictor {
+	return &lfuEvictor{counts: make(map[string]int), buckets: make(map[int]*keyList)}
+}
+
+func (e *lfuEvictor) bucket(count int) *keyList {
+	bucket, ok := e.buckets[count]
+	if !ok {
+		bucket = newKeyList()
+		e.buckets[count] = bucket
+	}
+	return bucket
+}
+
+// drop removes key from the bucket of count, deleting the bucket once it is empty
+func (e *lfuEvictor) drop(key string, count int) {
+	bucket := e.buckets[count]
+	bucket.remove(key)
+	if bucket.len() == 0 {
+		delete(e.buckets, count)
+		if e.minCount == count {
+			e.minCount++
+		}
+	}
+}
+
+func (e *lfuEvictor) added(key string) {
+	if _, ok := e.counts[key]; ok {
+		e.accessed(key)
+		return
+	}
+	e.counts[key] = 1
+	e.bucket(1).touch(key)
+	e.minCount = 1
+}
+
+func (e *lfuEvictor) accessed(key string) {
+	count, ok := e.counts[key]
+	if !ok {
+		e.added(key)
+		return
+	}
+	e.drop(key, count)
+	e.counts[key] = count + 1
+	e.bucket(count + 1).touch(key)
+}
+
+func (e *lfuEvictor) removed(key string, evicted bool) {
+	count, ok := e.counts[key]
+	if !ok {
+		return
+	}
+	delete(e.counts, key)
+	e.drop(key, count)
+	if len(e.counts) == 0 {
+		e.minCount = 0
+	}
+}
+
+func (e *lfuEvictor) setCapacity(capacity int) {}
+
+func (e *lfuEvictor) victim(incoming string) (string, bool) {
+	if len(e.counts) == 0 {
+		return "", false
+	}
+	// Removals outside eviction can leave minCount pointing at an emptied bucket
+	for e.buckets[e.minCount] == nil {
+		e.minCount++
+	}
+	return e.buckets[e.minCount].oldest()
+}
+
+// arcEvictor implements the Adaptive Replacement Cache policy of Megiddo and Modha. Keys
+// seen once are kept in recent, keys seen again in frequent; the ghost lists remember
+// keys recently evicted from each. A new key found in a ghost list shows that list was
+// evicted from too eagerly, and moves the target size of recent, which decides which list
+// the next victim comes from.
+type arcEvictor struct {
+	capacity                     int
+	target                       int
+	recent, frequent             *keyList
+	recentGhosts, frequentGhosts *keyList
+}
+
+func newARCEvictor(capacity int) *arcEvictor {
+	return &arcEvictor{
+		capacity:       capacity,
+		recent:         newKeyList(),
+		frequent:       newKeyList(),
+		recentGhosts:   newKeyList(),
+		frequentGhosts: newKeyList(),
+	}
+}
+
+func (e *arcEvictor) added(key string) {
+	switch {
+	case e.recent.contains(key) || e.frequent.contains(key):
+		e.accessed(key)
+	case e.recentGhosts.contains(key):
+		// Recent was too small: grow its target
+		e.target = min(e.capacity, e.target+max(e.frequentGhosts.len()/e.recentGhosts.len(), 1))
+		e.recentGhosts.remove(key)
+		e.frequent.touch(key)
+	case e.frequentGhosts.contains(key):
+		// Frequent was too small: shrink the target of recent
+		e.target = max(0, e.target-max(e.recentGhosts.len()/e.frequentGhosts.len(), 1))
+		e.frequentGhosts.remove(key)
+		e.frequent.touch(key)
+	default:
+		e.recent.touch(key)
+	}
+}
+
+func (e *arcEvictor) accessed(key string) {
+	if e.recent.remove(key) || e.frequent.contains(key) {
+		e.frequent.touch(key)
+		return
+	}
+	e.added(key)
+}
+
+func (e *arcEvictor) removed(key string, evicted bool) {
+	switch {
+	case e.recent.remove(key):
+		if evicted {
+			e.recentGhosts.touch(key)
+		}
+	case e.frequent.remove(key):
+		if evicted {
+			e.frequentGhosts.touch(key)
+		}
+	}
+	e.trimGhosts()
+}
+
+// trimGhosts keeps each ghost list within the capacity
+func (e *arcEvictor) trimGhosts() {
+	for _, ghosts := range []*keyList{e.recentGhosts, e.frequentGhosts} {
+		for ghosts.len() > e.capacity {
+			oldest, _ := ghosts.oldest()
+			ghosts.remove(oldest)
+		}
+	}
+}
+
+func (e *arcEvictor) setCapacity(capacity int) {
+	e.capacity = capacity
+	e.target = min(e.target, capacity)
+	e.trimGhosts()
+}
+
+func (e *arcEvictor) victim(incoming string) (string, bool) {
+	recent := e.recent.len()
+	if recent > 0 && (recent > e.target || (e.frequentGhosts.contains(incoming) && recent == e.target) || e.frequent.len() == 0) {
+		return e.recent.oldest()
+	}
+	if e.frequent.len() > 0 {
+		return e.frequent.oldest()
+	}
+	return e.recent.oldest()
+}
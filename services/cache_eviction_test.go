@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// cachedKeys returns which of keys the cache holds, without counting as accesses
+func cachedKeys(cache *InMemoryCache, keys ...string) []string {
+	cache.mu.RLock()
+	defer cache.mu.RUnlock()
+	var held []string
+	for _, key := range keys {
+		if _, ok := cache.data[key]; ok {
+			held = append(held, key)
+		}
+	}
+	return held
+}
+
+func newPolicyCache(t *testing.T, policy EvictionPolicy, maxSize int) *InMemoryCache {
+	cache := NewInMemoryCache(maxSize, time.Minute)
+	t.Cleanup(cache.Stop)
+	require.NoError(t, cache.SetEvictionPolicy(policy))
+	return cache
+}
+
+func TestInMemoryCache_LRUEviction(t *testing.T) {
+	cache := newPolicyCache(t, EvictionLRU, 3)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Set(ctx, key, key, time.Hour))
+	}
+	var value string
+	require.NoError(t, cache.Get(ctx, "a", &value))
+
+	require.NoError(t, cache.Set(ctx, "d", "d", time.Hour))
+	assert.Equal(t, []string{"a", "c", "d"}, cachedKeys(cache, "a", "b", "c", "d"))
+	assert.Equal(t, "lru", cache.GetStats().EvictionPolicy)
+}
+
+func TestInMemoryCache_LFUEviction(t *testing.T) {
+	cache := newPolicyCache(t, EvictionLFU, 3)
+	ctx := context.Background()
+	for _, key := range []string{"a", "b", "c"} {
+		require.NoError(t, cache.Set(ctx, key, key, time.Hour))
+	}
+	var value string
+	for _, key := range []string{"a", "a", "b", "c", "c"} {
+		require.NoError(t, cache.Get(ctx, key, &value))
+	}
+
+	require.NoError(t, cache.Set(ctx, "d", "d", time.Hour))
+	assert.Equal(t, []string{"a", "c", "d"}, cachedKeys(cache, "a", "b", "c", "d"), "b was used least")
+
+	// New entries are the least used until they are read again
+	require.NoError(t, cache.Set(ctx, "e", "e", time.Hour))
+	assert.Equal(t, []string{"a", "c", "e"}, cachedKeys(cache, "a", "c", "d", "e"))
+
+	// Deleting the least used entry leaves the next count to evict from
+	require.NoError(t, cache.Delete(ctx, "e"))
+	require.NoError(t, cache.Set(ctx, "f", "f", time.Hour))
+	require.NoError(t, cache.Set(ctx, "g", "g", time.Hour))
+	assert.Equal(t, []string{"a", "c", "g"}, cachedKeys(cache, "a", "c", "f", "g"))
+}
+
+func TestInMemoryCache_ARCResistsScans(t *testing.T) {
+	scan := func(cache *InMemoryCache) []string {
+		ctx := context.Background()
+		var value string
+		for _, key := range []string{"a", "b"} {
+			require.NoError(t, cache.Set(ctx, key, key, time.Hour))
+			require.NoError(t, cache.Get(ctx, key, &value))
+		}
+		// A scan reads many keys once
+		for i := 0; i < 10; i++ {
+			key := fmt.Sprintf("scan-%d", i)
+			require.NoError(t, cache.Set(ctx, key, key, time.Hour))
+		}
+		return cachedKeys(cache, "a", "b")
+	}
+
+	assert.Empty(t, scan(newPolicyCache(t, EvictionLRU, 4)), "a scan flushes an LRU cache")
+	arc := newPolicyCache(t, EvictionARC, 4)
+	assert.Equal(t, []string{"a", "b"}, scan(arc), "keys read twice outlive the scan")
+
+	// A key evicted from the recent list and set again grows the recent list's target
+	evictor := arc.evictor.(*arcEvictor)
+	require.True(t, evictor.recentGhosts.contains("scan-7"))
+	require.NoError(t, arc.Set(context.Background(), "scan-7", "again", time.Hour))
+	assert.Equal(t, 1, evictor.target)
+	assert.True(t, evictor.frequent.contains("scan-7"))
+	assert.LessOrEqual(t, evictor.recentGhosts.len(), 4)
+}
+
+func TestInMemoryCache_ByteLimit(t *testing.T) {
+	cache := newPolicyCache(t, EvictionLRU, 100)
+	ctx := context.Background()
+	// Each entry below costs its key, its JSON value and the entry overhead
+	entryCost := cacheEntryCost("key-0", []byte(`"`+strings.Repeat("x", 100)+`"`))
+	cache.SetMaxBytes(2 * entryCost)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, cache.Set(ctx, fmt.Sprintf("key-%d", i), strings.Repeat("x", 100), time.Hour))
+	}
+	assert.Equal(t, []string{"key-1", "key-2"}, cachedKeys(cache, "key-0", "key-1", "key-2"))
+	stats := cache.GetStats()
+	assert.Equal(t, 2*entryCost, stats.Bytes)
+	assert.Equal(t, 2*entryCost, stats.MaxBytes)
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, int64(1), stats.SizeEvictions)
+	assert.Equal(t, entryCost, stats.EvictedBytes)
+
+	// Overwriting a key frees its old value first
+	require.NoError(t, cache.Set(ctx, "key-1", strings.Repeat("y", 100), time.Hour))
+	assert.Equal(t, []string{"key-1", "key-2"}, cachedKeys(cache, "key-1", "key-2"))
+
+	// An entry larger than the limit is not cached, and drops the key's old value
+	require.NoError(t, cache.Set(ctx, "key-2", strings.Repeat("z", 1000), time.Hour))
+	assert.Equal(t, []string{"key-1"}, cachedKeys(cache, "key-1", "key-2"))
+	stats = cache.GetStats()
+	assert.Equal(t, int64(1), stats.Rejected)
+	assert.Equal(t, entryCost, stats.Bytes)
+
+	// Shrinking the limit evicts; clearing frees everything
+	require.NoError(t, cache.Set(ctx, "key-3", strings.Repeat("x", 100), time.Hour))
+	cache.SetMaxBytes(entryCost)
+	assert.Equal(t, []string{"key-3"}, cachedKeys(cache, "key-1", "key-3"))
+	require.NoError(t, cache.Clear(ctx))
+	assert.Equal(t, int64(0), cache.GetStats().Bytes)
+}
+
+func TestInMemoryCache_ExpirationsAndPolicyChanges(t *testing.T) {
+	cache := newPolicyCache(t, EvictionLFU, 2)
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, "short", "v", time.Millisecond))
+	require.NoError(t, cache.Set(ctx, "long", "v", time.Hour))
+	time.Sleep(5 * time.Millisecond)
+
+	var value string
+	assert.Error(t, cache.Get(ctx, "short", &value))
+	stats := cache.GetStats()
+	assert.Equal(t, int64(1), stats.Expirations)
+	assert.Equal(t, int64(0), stats.Evictions)
+	assert.Equal(t, cacheEntryCost("long", []byte(`"v"`)), stats.Bytes)
+
+	// Entries are handed to a new policy, which then evicts them
+	require.NoError(t, cache.SetEvictionPolicy(EvictionARC))
+	require.NoError(t, cache.Set(ctx, "a", "v", time.Hour))
+	require.NoError(t, cache.Set(ctx, "b", "v", time.Hour))
+	assert.Equal(t, []string{"a", "b"}, cachedKeys(cache, "long", "a", "b"))
+	assert.Equal(t, "arc", cache.GetStats().EvictionPolicy)
+
+	assert.Error(t, cache.SetEvictionPolicy("random"))
+	assert.Equal(t, "arc", cache.GetStats().EvictionPolicy)
+}
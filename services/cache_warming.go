@@ -0,0 +1,401 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// Hot data patterns name the read whose result is worth caching
+const (
+	hotChunkPrefix  = "chunk:"
+	hotTagPrefix    = "tag:"
+	hotSearchPrefix = "search:"
+)
+
+const (
+	defaultHotDataFlushInterval = time.Minute
+	defaultCacheWarmLimit       = 500
+	defaultCacheWarmConcurrency = 4
+	defaultCacheWarmTimeout     = 2 * time.Minute
+
+	// maxHotPatternLength keeps searches with very long queries out of the history
+	maxHotPatternLength = 1024
+	// maxPendingHotPatterns bounds the counts kept in memory between flushes
+	maxPendingHotPatterns = 10000
+	// hotPatternHalfLife is how long it takes an unread pattern to lose half its weight
+	hotPatternHalfLife = 24 * time.Hour
+	// hotPatternRetention is how long an unread pattern is kept
+	hotPatternRetention = 30 * 24 * time.Hour
+)
+
+// HotDataTracker counts reads of chunks, tag listings and searches and adds the counts to
+// the cache_hot_patterns table periodically, so that the history outlives the process
+type HotDataTracker struct {
+	db       *sql.DB
+	monitor  QueryPerformanceMonitor
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending map[string]int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewHotDataTracker creates a tracker that flushes its counts every interval
+func NewHotDataTracker(db *sql.DB, monitor QueryPerformanceMonitor, interval time.Duration) *HotDataTracker {
+	if interval <= 0 {
+		interval = defaultHotDataFlushInterval
+	}
+	return &HotDataTracker{
+		db:       db,
+		monitor:  monitor,
+		interval: interval,
+		pending:  make(map[string]int64),
+	}
+}
+
+// RecordAccess counts one read of pattern
+func (t *HotDataTracker) RecordAccess(pattern string) {
+	if pattern == "" || len(pattern) > maxHotPatternLength {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.pending[pattern]; !ok && len(t.pending) >= maxPendingHotPatterns {
+		return
+	}
+	t.pending[pattern]++
+}
+
+// Flush adds the counts recorded since the last flush to the history and prunes patterns
+// that have not been read for the retention period. Counts that fail to flush are kept
+// for the next attempt.
+func (t *HotDataTracker) Flush(ctx context.Context) error {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[string]int64)
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	patterns := make([]string, 0, len(pending))
+	counts := make([]int64, 0, len(pending))
+	for pattern, count := range pending {
+		patterns = append(patterns, pattern)
+		counts = append(counts, count)
+	}
+
+	start := time.Now()
+	_, err := t.db.ExecContext(ctx, `
+		INSERT INTO cache_hot_patterns (pattern, access_count, last_access)
+		SELECT pattern, access_count, NOW()
+		FROM unnest($1::text[], $2::bigint[]) AS hot(pattern, access_count)
+		ON CONFLICT (pattern) DO UPDATE SET
+			access_count = cache_hot_patterns.access_count + EXCLUDED.access_count,
+			last_access = EXCLUDED.last_access`,
+		pq.Array(patterns), pq.Array(counts))
+	t.monitor.RecordQuery("flush_hot_patterns", time.Since(start), len(patterns))
+	if err != nil {
+		t.restore(pending)
+		return fmt.Errorf("failed to flush hot data patterns: %w", err)
+	}
+
+	if _, err := t.db.ExecContext(ctx,
+		"DELETE FROM cache_hot_patterns WHERE last_access < $1",
+		time.Now().Add(-hotPatternRetention)); err != nil {
+		return fmt.Errorf("failed to prune hot data patterns: %w", err)
+	}
+	return nil
+}
+
+// restore merges counts that failed to flush back into the pending counts
+func (t *HotDataTracker) restore(counts map[string]int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for pattern, count := range counts {
+		if _, ok := t.pending[pattern]; !ok && len(t.pending) >= maxPendingHotPatterns {
+			continue
+		}
+		t.pending[pattern] += count
+	}
+}
+
+// TopPatterns returns up to limit patterns by weight: the access count halved for every
+// hotPatternHalfLife since the last access, so yesterday's burst does not outrank what
+// is read today
+func (t *HotDataTracker) TopPatterns(ctx context.Context, limit int) ([]models.HotDataPattern, error) {
+	if limit <= 0 {
+		limit = defaultCacheWarmLimit
+	}
+
+	start := time.Now()
+	rows, err := t.db.QueryContext(ctx, `
+		SELECT pattern, access_count, last_access,
+			access_count * power(0.5, EXTRACT(EPOCH FROM (NOW() - last_access)) / $1) AS cache_weight
+		FROM cache_hot_patterns
+		ORDER BY cache_weight DESC
+		LIMIT $2`,
+		hotPatternHalfLife.Seconds(), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hot data patterns: %w", err)
+	}
+	defer rows.Close()
+
+	var patterns []models.HotDataPattern
+	for rows.Next() {
+		var pattern models.HotDataPattern
+		if err := rows.Scan(&pattern.Pattern, &pattern.AccessCount, &pattern.LastAccess, &pattern.CacheWeight); err != nil {
+			return nil, fmt.Errorf("failed to scan hot data pattern: %w", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read hot data patterns: %w", err)
+	}
+	t.monitor.RecordQuery("get_hot_patterns", time.Since(start), len(patterns))
+	return patterns, nil
+}
+
+// Start flushes counts every interval until Stop is called
+func (t *HotDataTracker) Start(ctx context.Context) {
+	if t.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	t.cancel = cancel
+	t.done = make(chan struct{})
+	go func() {
+		defer close(t.done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := t.Flush(ctx); err != nil {
+					log.Printf("Warning: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic flush and flushes the remaining counts
+func (t *HotDataTracker) Stop() {
+	if t.cancel != nil {
+		t.cancel()
+		<-t.done
+		t.cancel = nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := t.Flush(ctx); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
+
+// searchPattern names a search by its query
+func searchPattern(query *models.SearchQuery) string {
+	encoded, err := json.Marshal(query)
+	if err != nil {
+		return ""
+	}
+	return hotSearchPrefix + string(encoded)
+}
+
+// accessTrackingChunkService records the reads a cache warmer can replay
+type accessTrackingChunkService struct {
+	UnifiedChunkService
+	tracker *HotDataTracker
+}
+
+// NewAccessTrackingChunkService wraps a UnifiedChunkService so that successful chunk reads,
+// tag listings and searches are counted as hot data patterns
+func NewAccessTrackingChunkService(base UnifiedChunkService, tracker *HotDataTracker) UnifiedChunkService {
+	return &accessTrackingChunkService{
+		UnifiedChunkService: base,
+		tracker:             tracker,
+	}
+}
+
+// GetChunk reads a chunk and counts the read
+func (s *accessTrackingChunkService) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+	if err == nil {
+		s.tracker.RecordAccess(hotChunkPrefix + chunkID)
+	}
+	return chunk, err
+}
+
+// GetChunksByTag lists a tag's chunks and counts the listing
+func (s *accessTrackingChunkService) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTag(ctx, tagChunkID)
+	if err == nil {
+		s.tracker.RecordAccess(hotTagPrefix + tagChunkID)
+	}
+	return chunks, err
+}
+
+// SearchChunks searches chunks and counts the search
+func (s *accessTrackingChunkService) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	result, err := s.UnifiedChunkService.SearchChunks(ctx, query)
+	if err == nil && query != nil {
+		s.tracker.RecordAccess(searchPattern(query))
+	}
+	return result, err
+}
+
+// HotPatternSource provides the read history a CacheWarmer replays
+type HotPatternSource interface {
+	TopPatterns(ctx context.Context, limit int) ([]models.HotDataPattern, error)
+}
+
+// CacheWarmer preloads the most read chunks, tag listings and searches on startup by
+// replaying them through the chunk service, whose read paths fill the caches
+type CacheWarmer struct {
+	chunks      UnifiedChunkService
+	history     HotPatternSource
+	limit       int
+	concurrency int
+	timeout     time.Duration
+	logger      Logger
+}
+
+// NewCacheWarmer creates a warmer that replays up to limit patterns, concurrency at a
+// time, for at most timeout. chunks should not count accesses, or warming would inflate
+// the history it reads.
+func NewCacheWarmer(chunks UnifiedChunkService, history HotPatternSource, limit, concurrency int, timeout time.Duration, logger Logger) *CacheWarmer {
+	if limit <= 0 {
+		limit = defaultCacheWarmLimit
+	}
+	if concurrency <= 0 {
+		concurrency = defaultCacheWarmConcurrency
+	}
+	if timeout <= 0 {
+		timeout = defaultCacheWarmTimeout
+	}
+	return &CacheWarmer{
+		chunks:      chunks,
+		history:     history,
+		limit:       limit,
+		concurrency: concurrency,
+		timeout:     timeout,
+		logger:      logger,
+	}
+}
+
+// Warm replays the hottest patterns, hottest first. Individual failures are counted
+// rather than returned; patterns not started before the timeout are skipped.
+func (w *CacheWarmer) Warm(ctx context.Context) (*models.CacheWarmResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.timeout)
+	defer cancel()
+
+	result := &models.CacheWarmResult{StartedAt: time.Now()}
+	patterns, err := w.history.TopPatterns(ctx, w.limit)
+	if err != nil {
+		return nil, err
+	}
+	result.Patterns = len(patterns)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, w.concurrency)
+
+	for _, pattern := range patterns {
+		if !acquireSlot(ctx, slots) {
+			mu.Lock()
+			result.Skipped++
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(pattern string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			warmed, err := w.warmPattern(ctx, pattern)
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err != nil:
+				result.Failed++
+			case warmed:
+				result.Warmed++
+			default:
+				result.Skipped++
+			}
+		}(pattern.Pattern)
+	}
+	wg.Wait()
+
+	result.Duration = time.Since(result.StartedAt)
+	return result, nil
+}
+
+// acquireSlot waits for a free slot and reports false once ctx is done
+func acquireSlot(ctx context.Context, slots chan struct{}) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	select {
+	case slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// WarmAsync warms the caches in the background and logs the outcome
+func (w *CacheWarmer) WarmAsync(ctx context.Context) {
+	go func() {
+		result, err := w.Warm(ctx)
+		if err != nil {
+			w.logger.Error("cache warming failed", err)
+			return
+		}
+		w.logger.Info("cache warming finished",
+			LogField{Key: "patterns", Value: result.Patterns},
+			LogField{Key: "warmed", Value: result.Warmed},
+			LogField{Key: "failed", Value: result.Failed},
+			LogField{Key: "skipped", Value: result.Skipped},
+			LogField{Key: "duration", Value: result.Duration.String()},
+		)
+	}()
+}
+
+// warmPattern replays one pattern; it reports false for patterns it does not recognise
+func (w *CacheWarmer) warmPattern(ctx context.Context, pattern string) (bool, error) {
+	switch {
+	case strings.HasPrefix(pattern, hotChunkPrefix):
+		_, err := w.chunks.GetChunk(ctx, strings.TrimPrefix(pattern, hotChunkPrefix))
+		return err == nil, err
+	case strings.HasPrefix(pattern, hotTagPrefix):
+		_, err := w.chunks.GetChunksByTag(ctx, strings.TrimPrefix(pattern, hotTagPrefix))
+		return err == nil, err
+	case strings.HasPrefix(pattern, hotSearchPrefix):
+		var query models.SearchQuery
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(pattern, hotSearchPrefix)), &query); err != nil {
+			return false, nil
+		}
+		_, err := w.chunks.SearchChunks(ctx, &query)
+		return err == nil, err
+	default:
+		return false, nil
+	}
+}
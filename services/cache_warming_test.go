@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+// staticHotPatterns serves a fixed read history
+type staticHotPatterns []models.HotDataPattern
+
+func (p staticHotPatterns) TopPatterns(ctx context.Context, limit int) ([]models.HotDataPattern, error) {
+	if len(p) > limit {
+		return p[:limit], nil
+	}
+	return p, nil
+}
+
+// replayRecorder records the reads a cache warmer replays
+type replayRecorder struct {
+	UnifiedChunkService
+	mu       sync.Mutex
+	chunks   []string
+	tags     []string
+	searches []models.SearchQuery
+}
+
+func (r *replayRecorder) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if chunkID == "missing" {
+		return nil, errors.New("chunk not found")
+	}
+	r.chunks = append(r.chunks, chunkID)
+	return &models.UnifiedChunkRecord{ChunkID: chunkID}, nil
+}
+
+func (r *replayRecorder) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tags = append(r.tags, tagChunkID)
+	return nil, nil
+}
+
+func (r *replayRecorder) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.searches = append(r.searches, *query)
+	return &models.SearchResult{}, nil
+}
+
+func TestAccessTrackingChunkService_RecordsSuccessfulReads(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewHotDataTracker(nil, NewNoOpMonitor(), time.Minute)
+	service := NewAccessTrackingChunkService(&replayRecorder{}, tracker)
+
+	_, err := service.GetChunk(ctx, "chunk-1")
+	require.NoError(t, err)
+	_, err = service.GetChunk(ctx, "chunk-1")
+	require.NoError(t, err)
+	_, err = service.GetChunk(ctx, "missing")
+	require.Error(t, err)
+	_, err = service.GetChunksByTag(ctx, "tag-1")
+	require.NoError(t, err)
+	query := &models.SearchQuery{Content: "golang", Limit: 10}
+	_, err = service.SearchChunks(ctx, query)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int64{
+		"chunk:chunk-1":      2,
+		"tag:tag-1":          1,
+		searchPattern(query): 1,
+	}, tracker.pending)
+}
+
+func TestHotDataTracker_BoundsPendingPatterns(t *testing.T) {
+	tracker := NewHotDataTracker(nil, NewNoOpMonitor(), time.Minute)
+	for i := 0; i < maxPendingHotPatterns; i++ {
+		tracker.RecordAccess(hotChunkPrefix + time.Duration(i).String())
+	}
+	tracker.RecordAccess("chunk:new")
+	tracker.RecordAccess("chunk:0s")
+
+	assert.Len(t, tracker.pending, maxPendingHotPatterns)
+	assert.NotContains(t, tracker.pending, "chunk:new")
+	assert.Equal(t, int64(2), tracker.pending["chunk:0s"], "known patterns keep counting")
+
+	tracker.RecordAccess("")
+	tracker.RecordAccess(hotSearchPrefix + string(make([]byte, maxHotPatternLength)))
+	assert.Len(t, tracker.pending, maxPendingHotPatterns)
+}
+
+func TestCacheWarmer_ReplaysHotPatterns(t *testing.T) {
+	recorder := &replayRecorder{}
+	history := staticHotPatterns{
+		{Pattern: "chunk:chunk-1", AccessCount: 40},
+		{Pattern: "tag:tag-1", AccessCount: 30},
+		{Pattern: `search:{"content":"golang","limit":10}`, AccessCount: 20},
+		{Pattern: "chunk:missing", AccessCount: 10},
+		{Pattern: "outline:chunk-1", AccessCount: 5},
+		{Pattern: "search:not json", AccessCount: 5},
+		{Pattern: "chunk:chunk-2", AccessCount: 1},
+	}
+	warmer := NewCacheWarmer(recorder, history, 6, 2, time.Minute, NewStructuredLogger(LogLevelError, nil))
+
+	result, err := warmer.Warm(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, result.Patterns)
+	assert.Equal(t, 3, result.Warmed)
+	assert.Equal(t, 1, result.Failed)
+	assert.Equal(t, 2, result.Skipped)
+	assert.Equal(t, []string{"chunk-1"}, recorder.chunks, "patterns past the limit are not replayed")
+	assert.Equal(t, []string{"tag-1"}, recorder.tags)
+	assert.Equal(t, []models.SearchQuery{{Content: "golang", Limit: 10}}, recorder.searches)
+}
+
+func TestCacheWarmer_SkipsPatternsAfterTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	warmer := NewCacheWarmer(&replayRecorder{}, staticHotPatterns{
+		{Pattern: "chunk:chunk-1"},
+		{Pattern: "chunk:chunk-2"},
+	}, 10, 1, time.Minute, NewStructuredLogger(LogLevelError, nil))
+
+	result, err := warmer.Warm(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Patterns)
+	assert.Equal(t, 2, result.Skipped)
+	assert.Zero(t, result.Warmed)
+}
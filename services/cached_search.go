@@ -275,6 +275,100 @@ func (s *CachedUnifiedChunkService) GetChunksByTags(ctx context.Context, tagChun
 	return result, err
 }
 
+// SetTagParent updates the tag hierarchy and invalidates tag caches
+func (s *CachedUnifiedChunkService) SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error {
+	err := s.base.SetTagParent(ctx, tagChunkID, parentTagChunkID)
+	if err != nil {
+		return err
+	}
+	
+	// Hierarchy changes affect every rollup query, so drop all cached entries
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return nil
+}
+
+// GetTagAncestors retrieves tag ancestors with caching
+func (s *CachedUnifiedChunkService) GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	cacheKey := s.cacheManager.GenerateCacheKey("tag_ancestors", tagChunkID, nil)
+	
+	var result []models.UnifiedChunkRecord
+	err := s.cacheManager.ExecuteWithCache(ctx, cacheKey, "get_tag_ancestors", func() (interface{}, error) {
+		return s.base.GetTagAncestors(ctx, tagChunkID)
+	}, &result)
+	
+	return result, err
+}
+
+// GetTagDescendants retrieves tag descendants with caching
+func (s *CachedUnifiedChunkService) GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	cacheKey := s.cacheManager.GenerateCacheKey("tag_descendants", tagChunkID, nil)
+	
+	var result []models.UnifiedChunkRecord
+	err := s.cacheManager.ExecuteWithCache(ctx, cacheKey, "get_tag_descendants", func() (interface{}, error) {
+		return s.base.GetTagDescendants(ctx, tagChunkID)
+	}, &result)
+	
+	return result, err
+}
+
+// GetChunksByTagWithOptions retrieves chunks by tag hierarchy with caching
+func (s *CachedUnifiedChunkService) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	if opts == nil || !opts.IncludeDescendants {
+		return s.GetChunksByTag(ctx, tagChunkID)
+	}
+	
+	params := map[string]interface{}{
+		"include_descendants": true,
+		"max_depth":           opts.MaxDepth,
+	}
+	cacheKey := s.cacheManager.GenerateCacheKey("chunks_by_tag", tagChunkID, params)
+	
+	var result []models.UnifiedChunkRecord
+	err := s.cacheManager.ExecuteWithCache(ctx, cacheKey, "get_chunks_by_tag_rollup", func() (interface{}, error) {
+		return s.base.GetChunksByTagWithOptions(ctx, tagChunkID, opts)
+	}, &result)
+	
+	return result, err
+}
+
+// GetTagRollup retrieves tag rollup counts with caching
+func (s *CachedUnifiedChunkService) GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error) {
+	cacheKey := s.cacheManager.GenerateCacheKey("tag_rollup", tagChunkID, nil)
+	
+	var result *models.TagRollup
+	err := s.cacheManager.ExecuteWithCache(ctx, cacheKey, "get_tag_rollup", func() (interface{}, error) {
+		return s.base.GetTagRollup(ctx, tagChunkID)
+	}, &result)
+	
+	return result, err
+}
+
+// RenameTag renames a tag and invalidates cached tag data
+func (s *CachedUnifiedChunkService) RenameTag(ctx context.Context, tagChunkID, newName string) error {
+	err := s.base.RenameTag(ctx, tagChunkID, newName)
+	if err != nil {
+		return err
+	}
+	
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return nil
+}
+
+// MergeTags merges tags and invalidates cached tag data
+func (s *CachedUnifiedChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	result, err := s.base.MergeTags(ctx, sourceTagIDs, targetTagID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Merges rewrite tags on arbitrary chunks, so drop all cached entries
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return result, nil
+}
+
 // GetChunkTags retrieves chunk tags with caching
 func (s *CachedUnifiedChunkService) GetChunkTags(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
 	cacheKey := s.cacheManager.GenerateCacheKey("chunk_tags", chunkID, nil)
@@ -287,6 +381,21 @@ func (s *CachedUnifiedChunkService) GetChunkTags(ctx context.Context, chunkID st
 	return result, err
 }
 
+// BatchGetChunks retrieves many chunks from the base service, which caches chunks one by one
+func (s *CachedUnifiedChunkService) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	return s.base.BatchGetChunks(ctx, chunkIDs)
+}
+
+// BatchGetChunkTags retrieves the tags of many chunks without caching
+func (s *CachedUnifiedChunkService) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	return s.base.BatchGetChunkTags(ctx, chunkIDs)
+}
+
+// BatchGetChildren retrieves the children of many chunks without caching
+func (s *CachedUnifiedChunkService) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	return s.base.BatchGetChildren(ctx, parentChunkIDs)
+}
+
 // GetChildren retrieves children with caching
 func (s *CachedUnifiedChunkService) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
 	cacheKey := s.cacheManager.GenerateCacheKey("chunk_children", parentChunkID, nil)
@@ -339,6 +448,7 @@ func (s *CachedUnifiedChunkService) SearchChunks(ctx context.Context, query *mod
 		"parent":      query.Parent,
 		"page":        query.Page,
 		"metadata":    query.Metadata,
+		"filter":      query.Filter,
 		"limit":       query.Limit,
 		"offset":      query.Offset,
 	}
@@ -402,6 +512,20 @@ func (s *CachedUnifiedChunkService) UpdateChunk(ctx context.Context, chunk *mode
 	return nil
 }
 
+// PatchChunk patches a chunk and invalidates related caches
+func (s *CachedUnifiedChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.base.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Invalidate related caches
+	patterns := s.getInvalidationPatterns(chunk.ChunkID, chunk.Tags, chunk.Parent)
+	s.cacheManager.InvalidateCachePatterns(ctx, patterns)
+	
+	return chunk, nil
+}
+
 // DeleteChunk deletes a chunk and invalidates related caches
 func (s *CachedUnifiedChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
 	err := s.base.DeleteChunk(ctx, chunkID)
@@ -458,6 +582,19 @@ func (s *CachedUnifiedChunkService) AddTags(ctx context.Context, chunkID string,
 	return nil
 }
 
+// BatchAddTags adds tags to many chunks and invalidates cached data
+func (s *CachedUnifiedChunkService) BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error) {
+	tagged, err := s.base.BatchAddTags(ctx, chunkIDs, tagChunkIDs)
+	if err != nil {
+		return 0, err
+	}
+	
+	// Entries for any of the chunks may be affected, so drop them all
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return tagged, nil
+}
+
 // RemoveTags removes tags and invalidates related caches
 func (s *CachedUnifiedChunkService) RemoveTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
 	err := s.base.RemoveTags(ctx, chunkID, tagChunkIDs)
@@ -486,6 +623,70 @@ func (s *CachedUnifiedChunkService) MoveChunk(ctx context.Context, chunkID, newP
 	return nil
 }
 
+// MoveSubtree moves a subtree and invalidates cached data
+func (s *CachedUnifiedChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	result, err := s.base.MoveSubtree(ctx, chunkID, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Descendants may change page as well as ancestors, so drop all cached entries
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return result, nil
+}
+
+// CopySubtree copies a subtree and invalidates cached data
+func (s *CachedUnifiedChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	result, err := s.base.CopySubtree(ctx, chunkID, newParentID)
+	if err != nil {
+		return nil, err
+	}
+	
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return result, nil
+}
+
+// DeleteSubtree deletes a subtree and invalidates cached data
+func (s *CachedUnifiedChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	result, err := s.base.DeleteSubtree(ctx, chunkID, opts)
+	if err != nil {
+		return nil, err
+	}
+	
+	if result.Deleted {
+		s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	}
+	
+	return result, nil
+}
+
+// MergeChunks merges one chunk into another and invalidates cached data
+func (s *CachedUnifiedChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	result, err := s.base.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Chunks that referenced the source now reference the target
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return result, nil
+}
+
+// BulkMove moves several subtrees and invalidates cached data
+func (s *CachedUnifiedChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	result, err := s.base.BulkMove(ctx, moves)
+	if err != nil {
+		return nil, err
+	}
+	
+	s.cacheManager.InvalidateCachePatterns(ctx, []string{"qcache:*"})
+	
+	return result, nil
+}
+
 // Helper methods for cache invalidation patterns
 
 func (s *CachedUnifiedChunkService) getInvalidationPatterns(chunkID string, tags []string, parent *string) []string {
@@ -32,6 +32,14 @@ func (m *MockUnifiedChunkService) UpdateChunk(ctx context.Context, chunk *models
 	return args.Error(0)
 }
 
+func (m *MockUnifiedChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, chunkID, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UnifiedChunkRecord), args.Error(1)
+}
+
 func (m *MockUnifiedChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
 	args := m.Called(ctx, chunkID)
 	return args.Error(0)
@@ -47,6 +55,11 @@ func (m *MockUnifiedChunkService) BatchUpdateChunks(ctx context.Context, chunks
 	return args.Error(0)
 }
 
+func (m *MockUnifiedChunkService) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, chunkIDs)
+	return args.Get(0).(map[string]*models.UnifiedChunkRecord), args.Error(1)
+}
+
 func (m *MockUnifiedChunkService) AddTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
 	args := m.Called(ctx, chunkID, tagChunkIDs)
 	return args.Error(0)
@@ -57,11 +70,21 @@ func (m *MockUnifiedChunkService) RemoveTags(ctx context.Context, chunkID string
 	return args.Error(0)
 }
 
+func (m *MockUnifiedChunkService) BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error) {
+	args := m.Called(ctx, chunkIDs, tagChunkIDs)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockUnifiedChunkService) GetChunkTags(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
 	args := m.Called(ctx, chunkID)
 	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
 }
 
+func (m *MockUnifiedChunkService) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, chunkIDs)
+	return args.Get(0).(map[string][]models.UnifiedChunkRecord), args.Error(1)
+}
+
 func (m *MockUnifiedChunkService) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
 	args := m.Called(ctx, tagChunkID)
 	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
@@ -77,6 +100,11 @@ func (m *MockUnifiedChunkService) GetChildren(ctx context.Context, parentChunkID
 	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
 }
 
+func (m *MockUnifiedChunkService) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, parentChunkIDs)
+	return args.Get(0).(map[string][]models.UnifiedChunkRecord), args.Error(1)
+}
+
 func (m *MockUnifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
 	args := m.Called(ctx, ancestorChunkID, maxDepth)
 	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
@@ -102,6 +130,87 @@ func (m *MockUnifiedChunkService) SearchByContent(ctx context.Context, content s
 	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
 }
 
+func (m *MockUnifiedChunkService) SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error {
+	args := m.Called(ctx, tagChunkID, parentTagChunkID)
+	return args.Error(0)
+}
+
+func (m *MockUnifiedChunkService) GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, tagChunkID)
+	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, tagChunkID)
+	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	args := m.Called(ctx, tagChunkID, opts)
+	return args.Get(0).([]models.UnifiedChunkRecord), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error) {
+	args := m.Called(ctx, tagChunkID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TagRollup), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) RenameTag(ctx context.Context, tagChunkID, newName string) error {
+	args := m.Called(ctx, tagChunkID, newName)
+	return args.Error(0)
+}
+
+func (m *MockUnifiedChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	args := m.Called(ctx, sourceTagIDs, targetTagID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TagMergeResult), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	args := m.Called(ctx, chunkID, newParentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubtreeMoveResult), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	args := m.Called(ctx, chunkID, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubtreeDeleteResult), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	args := m.Called(ctx, targetID, sourceID, strategy)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ChunkMergeResult), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	args := m.Called(ctx, chunkID, newParentID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubtreeCopyResult), args.Error(1)
+}
+
+func (m *MockUnifiedChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	args := m.Called(ctx, moves)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SubtreeMoveResult), args.Error(1)
+}
+
 func TestQueryCacheManager_GenerateCacheKey(t *testing.T) {
 	cache := NewInMemoryCache(100, time.Minute)
 	defer cache.Stop()
@@ -0,0 +1,537 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// QUICK CAPTURE
+// ============================================================================
+//
+// External tools such as browser extensions and phone shortcuts send notes, URLs and
+// emails to one endpoint. Each capture becomes a chunk on the caller's inbox page at once,
+// holding everything captured, so the caller gets its ID back without waiting. A worker
+// then splits the chunk into its first line and child chunks for the rest, and tags it
+// with the tags the capture named and the #tags in its text.
+//
+// Captures waiting for the worker are marked pending in their metadata, so captures the
+// queue had no room for, or that a restart interrupted, are picked up by a periodic sweep.
+
+// ErrInvalidCapture is returned for captures without content, with an unknown kind or a
+// URL that is not http(s), and for captures larger than the configured limit
+var ErrInvalidCapture = errors.New("invalid capture")
+
+const (
+	// captureSweepInterval is how often pending captures the queue missed are picked up
+	captureSweepInterval = time.Minute
+	// captureSweepBatch caps the captures one sweep queues
+	captureSweepBatch = 100
+	// maxCaptureTags caps the tags one capture names
+	maxCaptureTags = 50
+)
+
+// CaptureService files quick captures on per-user inbox pages
+type CaptureService interface {
+	// Capture creates a chunk holding the capture on the caller's inbox page, creating the
+	// page on first use, and queues it for chunking and tagging
+	Capture(ctx context.Context, req *models.CaptureRequest) (*models.CaptureResult, error)
+	// Start processes queued captures and sweeps for missed ones until Stop is called
+	Start(ctx context.Context)
+	// Stop stops processing; pending captures are picked up after the next start
+	Stop()
+}
+
+// captureService implements CaptureService
+type captureService struct {
+	db     *sql.DB
+	chunks UnifiedChunkService
+	// llm splits capture bodies; nil splits them at blank lines and list items
+	llm      LLMService
+	pageACLs PageACLService
+	config   config.CaptureConfig
+	monitor  QueryPerformanceMonitor
+
+	queue chan string
+
+	// inboxMu serializes inbox creation, so one instance creates one inbox per owner
+	inboxMu sync.Mutex
+	inboxes map[string]string
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCaptureService creates a capture service. llm may be nil; pageACLs, when set,
+// restricts each inbox to its owner.
+func NewCaptureService(db *sql.DB, chunks UnifiedChunkService, llm LLMService, pageACLs PageACLService, cfg config.CaptureConfig, monitor QueryPerformanceMonitor) CaptureService {
+	return &captureService{
+		db:       db,
+		chunks:   chunks,
+		llm:      llm,
+		pageACLs: pageACLs,
+		config:   cfg,
+		monitor:  monitor,
+		queue:    make(chan string, cfg.QueueSize),
+		inboxes:  make(map[string]string),
+	}
+}
+
+// Capture files a capture on the caller's inbox page
+func (s *captureService) Capture(ctx context.Context, req *models.CaptureRequest) (*models.CaptureResult, error) {
+	start := time.Now()
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	kind, contents, metadata, err := buildCapture(req, s.config.MaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := ""
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		owner = principal.Subject
+	}
+	inboxID, err := s.inbox(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	chunk := &models.UnifiedChunkRecord{
+		ChunkID:  uuid.New().String(),
+		Contents: contents,
+		Parent:   &inboxID,
+		Page:     &inboxID,
+		Metadata: metadata,
+	}
+	if err := s.chunks.CreateChunk(ctx, chunk); err != nil {
+		return nil, fmt.Errorf("failed to create capture chunk: %w", err)
+	}
+	s.monitor.RecordQuery("capture", time.Since(start), 1)
+	s.enqueue(chunk.ChunkID)
+
+	return &models.CaptureResult{
+		ChunkID:     chunk.ChunkID,
+		InboxPageID: inboxID,
+		Kind:        kind,
+		Status:      models.CaptureStatusPending,
+	}, nil
+}
+
+// buildCapture validates a capture and returns its kind, the contents of its chunk, the
+// first line followed by the body, and the chunk's metadata
+func buildCapture(req *models.CaptureRequest, maxBytes int) (models.CaptureKind, string, map[string]interface{}, error) {
+	kind := req.Kind
+	if kind == "" {
+		switch {
+		case req.Email != nil:
+			kind = models.CaptureEmail
+		case strings.TrimSpace(req.URL) != "":
+			kind = models.CaptureURL
+		default:
+			kind = models.CaptureText
+		}
+	}
+
+	metadata := map[string]interface{}{
+		models.CaptureStatusKey: models.CaptureStatusPending,
+		models.CaptureKindKey:   string(kind),
+	}
+	text := strings.TrimSpace(req.Text)
+	var contents string
+	switch kind {
+	case models.CaptureText:
+		if text == "" {
+			return "", "", nil, fmt.Errorf("%w: text is required", ErrInvalidCapture)
+		}
+		contents = text
+	case models.CaptureURL:
+		link := strings.TrimSpace(req.URL)
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "", "", nil, fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidCapture)
+		}
+		headline := link
+		if title := strings.Join(strings.Fields(req.Title), " "); title != "" {
+			headline = "[" + title + "](" + link + ")"
+		}
+		contents = joinCapture(headline, text)
+		metadata[models.SourceURLKey] = link
+	case models.CaptureEmail:
+		if req.Email == nil {
+			return "", "", nil, fmt.Errorf("%w: email is required", ErrInvalidCapture)
+		}
+		subject := strings.Join(strings.Fields(req.Email.Subject), " ")
+		body := strings.TrimSpace(req.Email.Body)
+		if subject == "" && body == "" {
+			return "", "", nil, fmt.Errorf("%w: email needs a subject or a body", ErrInvalidCapture)
+		}
+		headline := subject
+		if headline == "" {
+			headline = "(no subject)"
+		}
+		contents = joinCapture(headline, body)
+		metadata[models.EmailSubjectKey] = subject
+		if from := strings.TrimSpace(req.Email.From); from != "" {
+			metadata[models.EmailFromKey] = from
+		}
+	default:
+		return "", "", nil, fmt.Errorf("%w: unknown kind %q", ErrInvalidCapture, kind)
+	}
+	if len(contents) > maxBytes {
+		return "", "", nil, fmt.Errorf("%w: %d bytes exceed the limit of %d", ErrInvalidCapture, len(contents), maxBytes)
+	}
+
+	tags := normalizeCaptureTags(req.Tags)
+	if len(tags) > maxCaptureTags {
+		return "", "", nil, fmt.Errorf("%w: %d tags exceed the limit of %d", ErrInvalidCapture, len(tags), maxCaptureTags)
+	}
+	if len(tags) > 0 {
+		metadata[models.CaptureTagsKey] = tags
+	}
+	if source := strings.TrimSpace(req.Source); source != "" {
+		metadata[models.CaptureSourceKey] = source
+	}
+	return kind, contents, metadata, nil
+}
+
+func joinCapture(headline, body string) string {
+	if body == "" {
+		return headline
+	}
+	return headline + "\n\n" + body
+}
+
+// normalizeCaptureTags trims tag names and their leading #, dropping empty and repeated ones
+func normalizeCaptureTags(names []string) []string {
+	seen := make(map[string]bool, len(names))
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// inbox returns the inbox page of owner, creating it on first use. The gateway's own
+// captures, made without a caller, share the inbox of the empty owner.
+func (s *captureService) inbox(ctx context.Context, owner string) (string, error) {
+	s.inboxMu.Lock()
+	defer s.inboxMu.Unlock()
+
+	if id, ok := s.inboxes[owner]; ok {
+		if _, err := s.chunks.GetChunk(ctx, id); err == nil {
+			return id, nil
+		}
+		delete(s.inboxes, owner)
+	}
+
+	var id string
+	start := time.Now()
+	err := s.db.QueryRowContext(ctx, `
+		SELECT chunk_id FROM chunks
+		WHERE is_page = true AND metadata->>'`+models.InboxOwnerKey+`' = $1
+		ORDER BY created_time LIMIT 1`, owner).Scan(&id)
+	s.monitor.RecordQuery("find_inbox", time.Since(start), 1)
+	if err == nil {
+		s.inboxes[owner] = id
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("failed to find inbox: %w", err)
+	}
+
+	title := s.config.InboxTitle
+	if owner != "" {
+		title = fmt.Sprintf("%s (%s)", title, owner)
+	}
+	page := &models.UnifiedChunkRecord{
+		ChunkID:  uuid.New().String(),
+		Contents: title,
+		IsPage:   true,
+		Metadata: map[string]interface{}{models.InboxOwnerKey: owner},
+	}
+	if err := s.chunks.CreateChunk(ctx, page); err != nil {
+		return "", fmt.Errorf("failed to create inbox: %w", err)
+	}
+	if s.pageACLs != nil && owner != "" {
+		if _, err := s.pageACLs.SetACL(ctx, page.ChunkID, &models.PageACLRequest{Owner: owner}); err != nil {
+			log.Printf("Warning: failed to restrict inbox %s to %s: %v", page.ChunkID, owner, err)
+		}
+	}
+	s.inboxes[owner] = page.ChunkID
+	return page.ChunkID, nil
+}
+
+// enqueue queues a capture for processing, leaving it to the next sweep when the queue is full
+func (s *captureService) enqueue(chunkID string) {
+	select {
+	case s.queue <- chunkID:
+	default:
+	}
+}
+
+// Start runs the worker in the background
+func (s *captureService) Start(ctx context.Context) {
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+// Stop stops the worker and waits for the current capture
+func (s *captureService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+func (s *captureService) run(ctx context.Context) {
+	sweep := time.NewTicker(captureSweepInterval)
+	defer sweep.Stop()
+	s.sweep(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunkID := <-s.queue:
+			if err := s.process(ctx, chunkID); err != nil {
+				log.Printf("Warning: failed to process capture %s: %v", chunkID, err)
+			}
+		case <-sweep.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+// sweep queues pending captures that have waited longer than a sweep interval
+func (s *captureService) sweep(ctx context.Context) {
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT chunk_id FROM chunks
+		WHERE metadata->>'`+models.CaptureStatusKey+`' = $1 AND last_updated < $2
+		ORDER BY created_time LIMIT $3`,
+		models.CaptureStatusPending, time.Now().Add(-captureSweepInterval), captureSweepBatch)
+	if err != nil {
+		log.Printf("Warning: failed to find pending captures: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var chunkID string
+		if err := rows.Scan(&chunkID); err != nil {
+			log.Printf("Warning: failed to scan pending capture: %v", err)
+			return
+		}
+		s.enqueue(chunkID)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Warning: error iterating pending captures: %v", err)
+	}
+	s.monitor.RecordQuery("sweep_captures", time.Since(start), count)
+}
+
+// process splits a pending capture into its first line and child chunks for the rest, then
+// tags it. Children are removed again when the capture changed while it was split, and
+// the capture is split again from its new contents.
+func (s *captureService) process(ctx context.Context, chunkID string) error {
+	chunk, err := s.chunks.GetChunk(ctx, chunkID)
+	if err != nil {
+		// Deleted since it was captured
+		return nil
+	}
+	if status, _ := chunk.Metadata[models.CaptureStatusKey].(string); status != models.CaptureStatusPending {
+		return nil
+	}
+
+	headline, body := splitCapture(chunk.Contents)
+	var children []models.UnifiedChunkRecord
+	for _, piece := range s.splitBody(ctx, body) {
+		children = append(children, models.UnifiedChunkRecord{
+			ChunkID:  uuid.New().String(),
+			Contents: piece,
+			Parent:   &chunk.ChunkID,
+			Page:     chunk.Page,
+			Metadata: map[string]interface{}{},
+		})
+	}
+	if len(children) > 0 {
+		if err := s.chunks.BatchCreateChunks(ctx, children); err != nil {
+			s.markFailed(ctx, chunk, err)
+			return fmt.Errorf("failed to create capture chunks: %w", err)
+		}
+	}
+
+	tags := append(captureTagsOf(chunk), inlineTags(chunk.Contents)...)
+	processed := *chunk
+	processed.Contents = headline
+	processed.Metadata = make(map[string]interface{}, len(chunk.Metadata))
+	for key, value := range chunk.Metadata {
+		if key != models.CaptureTagsKey {
+			processed.Metadata[key] = value
+		}
+	}
+	processed.Metadata[models.CaptureStatusKey] = models.CaptureStatusProcessed
+	if err := s.chunks.UpdateChunk(ctx, &processed); err != nil {
+		for _, child := range children {
+			if err := s.chunks.DeleteChunk(ctx, child.ChunkID); err != nil {
+				log.Printf("Warning: failed to remove chunk %s of capture %s: %v", child.ChunkID, chunkID, err)
+			}
+		}
+		if errors.Is(err, ErrVersionConflict) {
+			s.enqueue(chunkID)
+			return nil
+		}
+		return fmt.Errorf("failed to update capture: %w", err)
+	}
+
+	if err := s.applyTags(ctx, chunkID, normalizeCaptureTags(tags)); err != nil {
+		return fmt.Errorf("capture processed but failed to tag it: %w", err)
+	}
+	return nil
+}
+
+// splitCapture separates the first line of a capture from the rest
+func splitCapture(contents string) (string, string) {
+	contents = strings.TrimSpace(contents)
+	headline, body, _ := strings.Cut(contents, "\n")
+	return strings.TrimSpace(headline), strings.TrimSpace(body)
+}
+
+// splitBody splits a capture body into chunks with the LLM, or at blank lines and list
+// items without one or when it fails
+func (s *captureService) splitBody(ctx context.Context, body string) []string {
+	if body == "" {
+		return nil
+	}
+	if s.llm != nil {
+		pieces, err := s.llm.ChunkText(ctx, body)
+		if err == nil && len(pieces) > 0 {
+			return nonEmptyPieces(pieces)
+		}
+		if err != nil {
+			log.Printf("Warning: LLM chunking of capture failed, splitting at paragraphs: %v", err)
+		}
+	}
+	pieces, _ := defaultChunkText(ctx, body)
+	return nonEmptyPieces(pieces)
+}
+
+func nonEmptyPieces(pieces []string) []string {
+	kept := make([]string, 0, len(pieces))
+	for _, piece := range pieces {
+		if piece = strings.TrimSpace(piece); piece != "" {
+			kept = append(kept, piece)
+		}
+	}
+	return kept
+}
+
+// captureTagsOf returns the tags a capture named, which JSON decoding turns into a
+// []interface{} once the chunk has been stored
+func captureTagsOf(chunk *models.UnifiedChunkRecord) []string {
+	switch tags := chunk.Metadata[models.CaptureTagsKey].(type) {
+	case []string:
+		return tags
+	case []interface{}:
+		names := make([]string, 0, len(tags))
+		for _, tag := range tags {
+			if name, ok := tag.(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+// inlineTags returns the #tags in text, in order of appearance
+func inlineTags(text string) []string {
+	var tags []string
+	for _, line := range strings.Split(text, "\n") {
+		_, found := extractInlineTags(line)
+		tags = append(tags, found...)
+	}
+	return tags
+}
+
+// applyTags tags a capture, creating the tags that do not exist yet
+func (s *captureService) applyTags(ctx context.Context, chunkID string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT chunk_id, contents FROM chunks WHERE is_tag = true AND contents = ANY($1)",
+		pq.Array(names))
+	if err != nil {
+		return fmt.Errorf("failed to look up tags: %w", err)
+	}
+	existing := make(map[string]string, len(names))
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan tag: %w", err)
+		}
+		existing[name] = id
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tags: %w", err)
+	}
+
+	tagIDs := make([]string, 0, len(names))
+	for _, name := range names {
+		id, ok := existing[name]
+		if !ok {
+			tag := &models.UnifiedChunkRecord{Contents: name, IsTag: true, Metadata: map[string]interface{}{}}
+			if err := s.chunks.CreateChunk(ctx, tag); err != nil {
+				return fmt.Errorf("failed to create tag %s: %w", name, err)
+			}
+			id = tag.ChunkID
+		}
+		tagIDs = append(tagIDs, id)
+	}
+	return s.chunks.AddTags(ctx, chunkID, tagIDs)
+}
+
+// markFailed records why a capture could not be processed; failed captures are not retried
+func (s *captureService) markFailed(ctx context.Context, chunk *models.UnifiedChunkRecord, cause error) {
+	_, err := s.chunks.PatchChunk(ctx, chunk.ChunkID, &models.ChunkPatch{
+		ExpectedVersion: chunk.Version,
+		Metadata: map[string]interface{}{
+			models.CaptureStatusKey: models.CaptureStatusFailed,
+			models.CaptureErrorKey:  cause.Error(),
+		},
+	})
+	if err != nil {
+		log.Printf("Warning: failed to mark capture %s failed: %v", chunk.ChunkID, err)
+	}
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// capturedChunks stores chunks in memory and records the writes that reached it
+type capturedChunks struct {
+	UnifiedChunkService
+	chunks    map[string]*models.UnifiedChunkRecord
+	created   []models.UnifiedChunkRecord
+	deleted   []string
+	createErr error
+	updateErr error
+	patches   []*models.ChunkPatch
+}
+
+func (c *capturedChunks) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, ok := c.chunks[chunkID]
+	if !ok {
+		return nil, errors.New("chunk not found")
+	}
+	return chunk, nil
+}
+
+func (c *capturedChunks) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if c.createErr != nil {
+		return c.createErr
+	}
+	c.created = append(c.created, chunks...)
+	return nil
+}
+
+func (c *capturedChunks) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if c.updateErr != nil {
+		return c.updateErr
+	}
+	c.chunks[chunk.ChunkID] = chunk
+	return nil
+}
+
+func (c *capturedChunks) DeleteChunk(ctx context.Context, chunkID string) error {
+	c.deleted = append(c.deleted, chunkID)
+	return nil
+}
+
+func (c *capturedChunks) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	c.patches = append(c.patches, patch)
+	return c.chunks[chunkID], nil
+}
+
+func newTestCaptureService(chunks *capturedChunks) *captureService {
+	cfg := config.CaptureConfig{InboxTitle: "Inbox", MaxBytes: 1 << 10, QueueSize: 10}
+	return NewCaptureService(nil, chunks, nil, nil, cfg, NewNoOpMonitor()).(*captureService)
+}
+
+func pendingCapture(contents string) *capturedChunks {
+	inbox := "inbox"
+	return &capturedChunks{chunks: map[string]*models.UnifiedChunkRecord{
+		"capture": {
+			ChunkID:  "capture",
+			Contents: contents,
+			Parent:   &inbox,
+			Page:     &inbox,
+			Version:  1,
+			Metadata: map[string]interface{}{
+				models.CaptureStatusKey: models.CaptureStatusPending,
+				models.CaptureKindKey:   string(models.CaptureText),
+			},
+		},
+	}}
+}
+
+func TestBuildCapture(t *testing.T) {
+	t.Run("text", func(t *testing.T) {
+		kind, contents, metadata, err := buildCapture(&models.CaptureRequest{
+			Text:   "  Call the bank  ",
+			Tags:   []string{"#errands", "errands", " ", "money"},
+			Source: "shortcut",
+		}, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, models.CaptureText, kind)
+		assert.Equal(t, "Call the bank", contents)
+		assert.Equal(t, models.CaptureStatusPending, metadata[models.CaptureStatusKey])
+		assert.Equal(t, []string{"errands", "money"}, metadata[models.CaptureTagsKey])
+		assert.Equal(t, "shortcut", metadata[models.CaptureSourceKey])
+	})
+
+	t.Run("url", func(t *testing.T) {
+		kind, contents, metadata, err := buildCapture(&models.CaptureRequest{
+			URL:   "https://example.com/a",
+			Title: "An  article",
+			Text:  "Read later",
+		}, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, models.CaptureURL, kind)
+		assert.Equal(t, "[An article](https://example.com/a)\n\nRead later", contents)
+		assert.Equal(t, "https://example.com/a", metadata[models.SourceURLKey])
+
+		_, contents, _, err = buildCapture(&models.CaptureRequest{URL: "http://example.com"}, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, "http://example.com", contents)
+	})
+
+	t.Run("email", func(t *testing.T) {
+		kind, contents, metadata, err := buildCapture(&models.CaptureRequest{
+			Email: &models.CapturedEmail{From: "bob@example.com", Body: "Minutes attached"},
+		}, 1<<10)
+		require.NoError(t, err)
+		assert.Equal(t, models.CaptureEmail, kind)
+		assert.Equal(t, "(no subject)\n\nMinutes attached", contents)
+		assert.Equal(t, "bob@example.com", metadata[models.EmailFromKey])
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var tags []string
+		for i := 0; i <= maxCaptureTags; i++ {
+			tags = append(tags, strings.Repeat("t", i+1))
+		}
+		invalid := map[string]*models.CaptureRequest{
+			"empty text":    {Text: "   "},
+			"relative url":  {URL: "example.com/a"},
+			"ftp url":       {URL: "ftp://example.com/a"},
+			"empty email":   {Email: &models.CapturedEmail{From: "bob@example.com"}},
+			"email kind":    {Kind: models.CaptureEmail, Text: "no email"},
+			"unknown kind":  {Kind: "voice", Text: "hello"},
+			"too large":     {Text: strings.Repeat("a", 1<<10+1)},
+			"too many tags": {Text: "hello", Tags: tags},
+		}
+		for name, req := range invalid {
+			_, _, _, err := buildCapture(req, 1<<10)
+			assert.ErrorIs(t, err, ErrInvalidCapture, name)
+		}
+	})
+}
+
+func TestSplitCapture(t *testing.T) {
+	headline, body := splitCapture("  Subject line\n\nFirst paragraph\n\nSecond  ")
+	assert.Equal(t, "Subject line", headline)
+	assert.Equal(t, "First paragraph\n\nSecond", body)
+
+	headline, body = splitCapture("Just one line")
+	assert.Equal(t, "Just one line", headline)
+	assert.Empty(t, body)
+}
+
+func TestCaptureTagsOf(t *testing.T) {
+	chunk := &models.UnifiedChunkRecord{Metadata: map[string]interface{}{
+		models.CaptureTagsKey: []interface{}{"reading", 3, "later"},
+	}}
+	assert.Equal(t, []string{"reading", "later"}, captureTagsOf(chunk), "stored tags decode as []interface{}")
+
+	chunk.Metadata[models.CaptureTagsKey] = []string{"reading"}
+	assert.Equal(t, []string{"reading"}, captureTagsOf(chunk))
+
+	delete(chunk.Metadata, models.CaptureTagsKey)
+	assert.Nil(t, captureTagsOf(chunk))
+}
+
+func TestCaptureService_Process(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("splits the body into children", func(t *testing.T) {
+		chunks := pendingCapture("Meeting notes\n\nAgreed on the budget\n\nNext review in May")
+		service := newTestCaptureService(chunks)
+
+		require.NoError(t, service.process(ctx, "capture"))
+		require.Len(t, chunks.created, 2)
+		assert.Equal(t, "Agreed on the budget", chunks.created[0].Contents)
+		assert.Equal(t, "Next review in May", chunks.created[1].Contents)
+		for _, child := range chunks.created {
+			assert.Equal(t, "capture", *child.Parent)
+			assert.Equal(t, "inbox", *child.Page)
+		}
+
+		processed := chunks.chunks["capture"]
+		assert.Equal(t, "Meeting notes", processed.Contents)
+		assert.Equal(t, models.CaptureStatusProcessed, processed.Metadata[models.CaptureStatusKey])
+
+		chunks.created = nil
+		require.NoError(t, service.process(ctx, "capture"))
+		assert.Empty(t, chunks.created, "processed captures are skipped")
+	})
+
+	t.Run("single line", func(t *testing.T) {
+		chunks := pendingCapture("Buy milk")
+		service := newTestCaptureService(chunks)
+
+		require.NoError(t, service.process(ctx, "capture"))
+		assert.Empty(t, chunks.created)
+		assert.Equal(t, models.CaptureStatusProcessed, chunks.chunks["capture"].Metadata[models.CaptureStatusKey])
+	})
+
+	t.Run("deleted capture", func(t *testing.T) {
+		service := newTestCaptureService(&capturedChunks{chunks: map[string]*models.UnifiedChunkRecord{}})
+		assert.NoError(t, service.process(ctx, "missing"))
+	})
+
+	t.Run("failed split", func(t *testing.T) {
+		chunks := pendingCapture("Title\n\nBody")
+		chunks.createErr = errors.New("database unavailable")
+		service := newTestCaptureService(chunks)
+
+		assert.Error(t, service.process(ctx, "capture"))
+		require.Len(t, chunks.patches, 1)
+		assert.Equal(t, int64(1), chunks.patches[0].ExpectedVersion)
+		assert.Equal(t, models.CaptureStatusFailed, chunks.patches[0].Metadata[models.CaptureStatusKey])
+	})
+
+	t.Run("edited while splitting", func(t *testing.T) {
+		chunks := pendingCapture("Title\n\nBody")
+		chunks.updateErr = ErrVersionConflict
+		service := newTestCaptureService(chunks)
+
+		require.NoError(t, service.process(ctx, "capture"))
+		require.Len(t, chunks.created, 1)
+		assert.Equal(t, []string{chunks.created[0].ChunkID}, chunks.deleted, "children are removed again")
+		select {
+		case chunkID := <-service.queue:
+			assert.Equal(t, "capture", chunkID, "the capture is split again")
+		default:
+			t.Fatal("capture was not queued again")
+		}
+	})
+}
+
+func TestCaptureService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	cfg := config.CaptureConfig{InboxTitle: "Capture test inbox", MaxBytes: 1 << 20, QueueSize: 10}
+	service := NewCaptureService(db, chunks, nil, nil, cfg, NewNoOpMonitor()).(*captureService)
+	ctx := contextWithSubject("capture-test-user", models.RoleEditor)
+
+	result, err := service.Capture(ctx, &models.CaptureRequest{Text: "Capture test\n\nSecond paragraph #capture-test"})
+	require.NoError(t, err)
+	defer chunks.DeleteChunk(context.Background(), result.InboxPageID)
+	assert.Equal(t, models.CaptureStatusPending, result.Status)
+
+	again, err := service.Capture(ctx, &models.CaptureRequest{Text: "Another capture"})
+	require.NoError(t, err)
+	assert.Equal(t, result.InboxPageID, again.InboxPageID, "captures share the caller's inbox")
+
+	require.NoError(t, service.process(context.Background(), result.ChunkID))
+	chunk, err := chunks.GetChunk(context.Background(), result.ChunkID)
+	require.NoError(t, err)
+	assert.Equal(t, "Capture test", chunk.Contents)
+	assert.Equal(t, models.CaptureStatusProcessed, chunk.Metadata[models.CaptureStatusKey])
+}
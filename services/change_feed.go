@@ -0,0 +1,354 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"semantic-text-processor/models"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ChangeNotificationSource delivers raw chunk change notifications.
+// database.NotificationListener implements it with PostgreSQL LISTEN/NOTIFY.
+type ChangeNotificationSource interface {
+	Channel() string
+	Connected() bool
+	Listen(ctx context.Context, handle func(payload string), connected func(reconnect bool)) error
+}
+
+// ChangeFeedService normalizes chunk table changes into typed events and fans them out to
+// in-process subscribers (cache invalidation) and streaming clients
+type ChangeFeedService interface {
+	// Start listens for notifications in the background until Stop is called
+	Start(ctx context.Context)
+	// Stop stops listening and closes every subscription
+	Stop()
+	// Subscribe registers a subscriber. Events that do not fit in its buffer are dropped
+	// and counted rather than slowing down other subscribers.
+	Subscribe(filter models.ChangeFeedFilter, buffer int) *ChangeSubscription
+	// Publish assigns the next sequence number to event and delivers it
+	Publish(event *models.ChangeEvent)
+	// EventsSince returns retained events after sequence that match filter. ok is false
+	// when some events after sequence are no longer retained.
+	EventsSince(sequence uint64, filter models.ChangeFeedFilter) (events []*models.ChangeEvent, ok bool)
+	GetStats() models.ChangeFeedStats
+}
+
+// ChangeSubscription receives change events until it is closed
+type ChangeSubscription struct {
+	// Events is closed when the subscription or the feed is closed
+	Events <-chan *models.ChangeEvent
+
+	id      uint64
+	events  chan *models.ChangeEvent
+	filter  models.ChangeFeedFilter
+	feed    *changeFeed
+	dropped atomic.Uint64
+}
+
+// Close unsubscribes and closes Events
+func (s *ChangeSubscription) Close() {
+	s.feed.unsubscribe(s.id)
+}
+
+// Dropped returns how many events were dropped because the subscriber fell behind
+func (s *ChangeSubscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// changeFeed implements ChangeFeedService
+type changeFeed struct {
+	source      ChangeNotificationSource
+	historySize int
+
+	mu          sync.Mutex
+	subscribers map[uint64]*ChangeSubscription
+	nextID      uint64
+	sequence    uint64
+	history     []*models.ChangeEvent
+	lastEventAt *time.Time
+	stopped     bool
+
+	published atomic.Uint64
+	dropped   atomic.Uint64
+	resyncs   atomic.Uint64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+const (
+	defaultChangeFeedHistory = 1000
+	defaultChangeFeedBuffer  = 256
+)
+
+// NewChangeFeedService creates a change feed over source, retaining the last historySize
+// events for replay. A nil source gives a feed that only carries published events.
+func NewChangeFeedService(source ChangeNotificationSource, historySize int) ChangeFeedService {
+	if historySize <= 0 {
+		historySize = defaultChangeFeedHistory
+	}
+	return &changeFeed{
+		source:      source,
+		historySize: historySize,
+		subscribers: make(map[uint64]*ChangeSubscription),
+	}
+}
+
+// Start begins listening in the background
+func (f *changeFeed) Start(ctx context.Context) {
+	if f.source == nil || f.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	f.cancel = cancel
+	f.done = make(chan struct{})
+	go func() {
+		defer close(f.done)
+		f.source.Listen(ctx, f.handleNotification, f.handleConnected)
+	}()
+}
+
+// Stop stops listening and closes all subscriptions
+func (f *changeFeed) Stop() {
+	if f.cancel != nil {
+		f.cancel()
+		<-f.done
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.stopped = true
+	for id, sub := range f.subscribers {
+		delete(f.subscribers, id)
+		close(sub.events)
+	}
+}
+
+// Subscribe registers a filtered subscriber
+func (f *changeFeed) Subscribe(filter models.ChangeFeedFilter, buffer int) *ChangeSubscription {
+	if buffer <= 0 {
+		buffer = defaultChangeFeedBuffer
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.nextID++
+	events := make(chan *models.ChangeEvent, buffer)
+	sub := &ChangeSubscription{Events: events, id: f.nextID, events: events, filter: filter, feed: f}
+	if f.stopped {
+		close(events)
+		return sub
+	}
+	f.subscribers[sub.id] = sub
+	return sub
+}
+
+func (f *changeFeed) unsubscribe(id uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if sub, ok := f.subscribers[id]; ok {
+		delete(f.subscribers, id)
+		close(sub.events)
+	}
+}
+
+// Publish sequences and delivers an event without blocking on slow subscribers
+func (f *changeFeed) Publish(event *models.ChangeEvent) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stopped {
+		return
+	}
+
+	f.sequence++
+	event.Sequence = f.sequence
+	f.history = append(f.history, event)
+	if len(f.history) > f.historySize {
+		f.history = f.history[len(f.history)-f.historySize:]
+	}
+	occurredAt := event.OccurredAt
+	f.lastEventAt = &occurredAt
+	f.published.Add(1)
+
+	for _, sub := range f.subscribers {
+		if !sub.filter.Matches(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+			f.dropped.Add(1)
+		}
+	}
+}
+
+// EventsSince returns retained events after sequence
+func (f *changeFeed) EventsSince(sequence uint64, filter models.ChangeFeedFilter) ([]*models.ChangeEvent, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// A sequence from the future means the client saw a previous process
+	if sequence > f.sequence {
+		return nil, false
+	}
+	complete := sequence == f.sequence || (len(f.history) > 0 && f.history[0].Sequence <= sequence+1)
+
+	var events []*models.ChangeEvent
+	for _, event := range f.history {
+		if event.Sequence > sequence && filter.Matches(event) {
+			events = append(events, event)
+		}
+	}
+	return events, complete
+}
+
+// GetStats returns change feed statistics
+func (f *changeFeed) GetStats() models.ChangeFeedStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stats := models.ChangeFeedStats{
+		Subscribers: len(f.subscribers),
+		Published:   f.published.Load(),
+		Dropped:     f.dropped.Load(),
+		Resyncs:     f.resyncs.Load(),
+		LastEventAt: f.lastEventAt,
+	}
+	if f.source != nil {
+		stats.Channel = f.source.Channel()
+		stats.Listening = f.source.Connected()
+	}
+	return stats
+}
+
+func (f *changeFeed) handleNotification(payload string) {
+	event, err := parseChangeNotification(payload)
+	if err != nil {
+		log.Printf("Warning: ignoring change notification: %v", err)
+		return
+	}
+	f.Publish(event)
+}
+
+// handleConnected announces a resync after a reconnect, since notifications sent while
+// the connection was down are lost
+func (f *changeFeed) handleConnected(reconnect bool) {
+	if reconnect {
+		f.resyncs.Add(1)
+		f.Publish(&models.ChangeEvent{Type: models.ChangeEventResync})
+	}
+}
+
+// changeNotification is the payload published by the notify_chunk_change trigger
+type changeNotification struct {
+	Op         string    `json:"op"`
+	Table      string    `json:"table"`
+	ChunkID    string    `json:"chunk_id"`
+	Page       *string   `json:"page"`
+	Parent     *string   `json:"parent"`
+	IsPage     bool      `json:"is_page"`
+	IsTag      bool      `json:"is_tag"`
+	IsTemplate bool      `json:"is_template"`
+	Version    int64     `json:"version"`
+	At         time.Time `json:"at"`
+}
+
+// parseChangeNotification converts a trigger payload into a change event
+func parseChangeNotification(payload string) (*models.ChangeEvent, error) {
+	var notification changeNotification
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+		return nil, fmt.Errorf("failed to parse change notification: %w", err)
+	}
+	if notification.ChunkID == "" {
+		return nil, fmt.Errorf("change notification has no chunk_id")
+	}
+
+	eventType := models.ChangeEventType(notification.Op)
+	switch eventType {
+	case models.ChangeEventInsert, models.ChangeEventUpdate, models.ChangeEventDelete:
+	default:
+		return nil, fmt.Errorf("unknown change operation %q", notification.Op)
+	}
+
+	return &models.ChangeEvent{
+		Type:       eventType,
+		Table:      notification.Table,
+		ChunkID:    notification.ChunkID,
+		Page:       notification.Page,
+		Parent:     notification.Parent,
+		IsPage:     notification.IsPage,
+		IsTag:      notification.IsTag,
+		IsTemplate: notification.IsTemplate,
+		Version:    notification.Version,
+		OccurredAt: notification.At,
+	}, nil
+}
+
+// ChangeFeedCacheInvalidator keeps caches consistent with changes made by other gateway
+// instances or directly in the database
+type ChangeFeedCacheInvalidator struct {
+	cache       CacheService
+	searchCache SearchCacheService
+	debounce    time.Duration
+}
+
+// NewChangeFeedCacheInvalidator creates an invalidator; either cache may be nil. Search
+// cache invalidations are coalesced over debounce since each one clears the whole cache.
+func NewChangeFeedCacheInvalidator(cache CacheService, searchCache SearchCacheService, debounce time.Duration) *ChangeFeedCacheInvalidator {
+	if debounce <= 0 {
+		debounce = time.Second
+	}
+	return &ChangeFeedCacheInvalidator{cache: cache, searchCache: searchCache, debounce: debounce}
+}
+
+// Run applies events from sub until it is closed or ctx is cancelled
+func (i *ChangeFeedCacheInvalidator) Run(ctx context.Context, sub *ChangeSubscription) {
+	defer sub.Close()
+
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			i.invalidate(ctx, event)
+			if flush == nil && i.searchCache != nil {
+				flush = time.After(i.debounce)
+			}
+		case <-flush:
+			flush = nil
+			if err := i.searchCache.InvalidateSearchCache(ctx, []string{"*"}); err != nil {
+				log.Printf("Warning: failed to invalidate search cache after changes: %v", err)
+			}
+		}
+	}
+}
+
+// invalidate drops the cached entries an event makes stale
+func (i *ChangeFeedCacheInvalidator) invalidate(ctx context.Context, event *models.ChangeEvent) {
+	if i.cache == nil {
+		return
+	}
+	if event.Type == models.ChangeEventResync {
+		i.cache.Clear(ctx)
+		return
+	}
+
+	for _, pattern := range chunkCacheInvalidationPatterns(event.ChunkID) {
+		i.cache.DeletePattern(ctx, pattern)
+	}
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNotificationSource replays payloads, simulating one dropped connection between batches
+type fakeNotificationSource struct {
+	batches [][]string
+}
+
+func (s *fakeNotificationSource) Channel() string { return "chunk_changes" }
+func (s *fakeNotificationSource) Connected() bool { return true }
+
+func (s *fakeNotificationSource) Listen(ctx context.Context, handle func(payload string), connected func(reconnect bool)) error {
+	for i, batch := range s.batches {
+		connected(i > 0)
+		for _, payload := range batch {
+			handle(payload)
+		}
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func receiveEvent(t *testing.T, sub *ChangeSubscription) *models.ChangeEvent {
+	t.Helper()
+	select {
+	case event := <-sub.Events:
+		require.NotNil(t, event)
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for change event")
+		return nil
+	}
+}
+
+func TestParseChangeNotification(t *testing.T) {
+	event, err := parseChangeNotification(`{"op":"update","table":"chunks","chunk_id":"c1","page":"p1","parent":null,"is_page":false,"is_tag":true,"is_template":null,"version":3,"at":"2024-01-15T10:30:00.123456+00:00"}`)
+	require.NoError(t, err)
+	assert.Equal(t, models.ChangeEventUpdate, event.Type)
+	assert.Equal(t, "c1", event.ChunkID)
+	require.NotNil(t, event.Page)
+	assert.Equal(t, "p1", *event.Page)
+	assert.Nil(t, event.Parent)
+	assert.True(t, event.IsTag)
+	assert.Equal(t, int64(3), event.Version)
+	assert.Equal(t, 2024, event.OccurredAt.Year())
+
+	_, err = parseChangeNotification(`{"op":"truncate","chunk_id":"c1"}`)
+	assert.Error(t, err)
+	_, err = parseChangeNotification(`{"op":"insert"}`)
+	assert.Error(t, err)
+	_, err = parseChangeNotification(`not json`)
+	assert.Error(t, err)
+}
+
+func TestChangeFeed_FanOut(t *testing.T) {
+	feed := NewChangeFeedService(nil, 3)
+	page := "p1"
+
+	all := feed.Subscribe(models.ChangeFeedFilter{}, 10)
+	deletes := feed.Subscribe(models.ChangeFeedFilter{Types: []models.ChangeEventType{models.ChangeEventDelete}}, 10)
+	onPage := feed.Subscribe(models.ChangeFeedFilter{PageID: page}, 1)
+
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventInsert, ChunkID: "c1", Page: &page})
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventDelete, ChunkID: "c2"})
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventUpdate, ChunkID: page})
+
+	assert.Equal(t, uint64(1), receiveEvent(t, all).Sequence)
+	assert.Equal(t, "c2", receiveEvent(t, deletes).ChunkID)
+	assert.Equal(t, "c1", receiveEvent(t, onPage).ChunkID)
+	assert.Equal(t, uint64(1), onPage.Dropped(), "the page chunk's own update overflowed the buffer")
+
+	stats := feed.GetStats()
+	assert.Equal(t, 3, stats.Subscribers)
+	assert.Equal(t, uint64(3), stats.Published)
+	assert.Equal(t, uint64(1), stats.Dropped)
+
+	deletes.Close()
+	_, open := <-deletes.Events
+	assert.False(t, open)
+	assert.Equal(t, 2, feed.GetStats().Subscribers)
+}
+
+func TestChangeFeed_EventsSince(t *testing.T) {
+	feed := NewChangeFeedService(nil, 2)
+	for _, id := range []string{"c1", "c2", "c3"} {
+		feed.Publish(&models.ChangeEvent{Type: models.ChangeEventUpdate, ChunkID: id})
+	}
+
+	events, complete := feed.EventsSince(1, models.ChangeFeedFilter{})
+	assert.True(t, complete)
+	require.Len(t, events, 2)
+	assert.Equal(t, "c2", events[0].ChunkID)
+
+	_, complete = feed.EventsSince(0, models.ChangeFeedFilter{})
+	assert.False(t, complete, "event 1 is no longer retained")
+
+	events, complete = feed.EventsSince(3, models.ChangeFeedFilter{})
+	assert.True(t, complete)
+	assert.Empty(t, events)
+
+	_, complete = feed.EventsSince(10, models.ChangeFeedFilter{})
+	assert.False(t, complete, "sequences from a previous process cannot be resumed")
+}
+
+func TestChangeFeed_ListenAndResync(t *testing.T) {
+	source := &fakeNotificationSource{batches: [][]string{
+		{`{"op":"insert","chunk_id":"c1"}`, `{"op":"bogus","chunk_id":"c2"}`},
+		{`{"op":"delete","chunk_id":"c3"}`},
+	}}
+	feed := NewChangeFeedService(source, 10)
+	sub := feed.Subscribe(models.ChangeFeedFilter{Types: []models.ChangeEventType{models.ChangeEventDelete}}, 10)
+
+	feed.Start(context.Background())
+
+	resync := receiveEvent(t, sub)
+	assert.Equal(t, models.ChangeEventResync, resync.Type, "resync events bypass filters")
+	assert.Equal(t, "c3", receiveEvent(t, sub).ChunkID)
+	assert.Equal(t, uint64(1), feed.GetStats().Resyncs)
+
+	feed.Stop()
+	_, open := <-sub.Events
+	assert.False(t, open)
+
+	late := feed.Subscribe(models.ChangeFeedFilter{}, 1)
+	_, open = <-late.Events
+	assert.False(t, open)
+}
+
+func TestChangeFeedCacheInvalidator(t *testing.T) {
+	ctx := context.Background()
+	cache := NewInMemoryCache(100, time.Minute)
+	require.NoError(t, cache.Set(ctx, "chunk:c1", "stale", time.Minute))
+	require.NoError(t, cache.Set(ctx, "chunk:c2", "fresh", time.Minute))
+
+	feed := NewChangeFeedService(nil, 10)
+	sub := feed.Subscribe(models.ChangeFeedFilter{}, 10)
+	done := make(chan struct{})
+	go func() {
+		NewChangeFeedCacheInvalidator(cache, nil, 0).Run(ctx, sub)
+		close(done)
+	}()
+
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventUpdate, ChunkID: "c1"})
+	assert.Eventually(t, func() bool {
+		_, found := cache.GetDirect(ctx, "chunk:c1")
+		return !found
+	}, time.Second, 5*time.Millisecond)
+	_, found := cache.GetDirect(ctx, "chunk:c2")
+	assert.True(t, found)
+
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventResync})
+	assert.Eventually(t, func() bool {
+		_, found := cache.GetDirect(ctx, "chunk:c2")
+		return !found
+	}, time.Second, 5*time.Millisecond)
+
+	feed.Stop()
+	<-done
+}
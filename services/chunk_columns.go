@@ -0,0 +1,298 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"semantic-text-processor/models"
+
+	"github.com/lib/pq"
+)
+
+// chunkColumn registers a column of the chunks table: which statements use it, how they
+// write it from a chunk record and where scans read it to. Adding a column to the table
+// means adding it here; the statements and scans below are built from this list.
+type chunkColumn struct {
+	name string
+	// listed columns are read by every chunk query; the others only by single-chunk reads
+	listed bool
+	// write returns the value inserts and updates store; nil for columns the database
+	// maintains, such as version
+	write func(chunk *models.UnifiedChunkRecord) (interface{}, error)
+	// immutable columns are written by inserts but never by updates
+	immutable bool
+	// target returns where a scan stores the column
+	target func(scan *chunkScan) interface{}
+}
+
+// chunkScan holds a chunk record being scanned, and the columns that need converting after
+// the scan
+type chunkScan struct {
+	chunk    models.UnifiedChunkRecord
+	tags     pq.StringArray
+	metadata []byte
+	lang     sql.NullString
+}
+
+// chunkColumnRegistry lists the columns of the chunks table in statement order
+var chunkColumnRegistry = []chunkColumn{
+	{
+		name: "chunk_id", listed: true, immutable: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.ChunkID, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.ChunkID },
+	},
+	{
+		name: "contents", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.Contents, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.Contents },
+	},
+	{
+		name: "parent", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.Parent, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.Parent },
+	},
+	{
+		name: "page", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.Page, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.Page },
+	},
+	{
+		name: "is_page", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.IsPage, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.IsPage },
+	},
+	{
+		name: "is_tag", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.IsTag, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.IsTag },
+	},
+	{
+		name: "is_template", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.IsTemplate, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.IsTemplate },
+	},
+	{
+		name: "is_slot", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.IsSlot, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.IsSlot },
+	},
+	{
+		name: "ref", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.Ref, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.Ref },
+	},
+	{
+		name: "tags", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return pq.Array(c.Tags), nil },
+		target: func(s *chunkScan) interface{} { return &s.tags },
+	},
+	{
+		name: "metadata", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return chunkMetadataValue(c.Metadata) },
+		target: func(s *chunkScan) interface{} { return &s.metadata },
+	},
+	{
+		name: "created_time", listed: true, immutable: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.CreatedTime, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.CreatedTime },
+	},
+	{
+		name: "last_updated", listed: true,
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.LastUpdated, nil },
+		target: func(s *chunkScan) interface{} { return &s.chunk.LastUpdated },
+	},
+	{
+		name: "version", listed: true,
+		target: func(s *chunkScan) interface{} { return &s.chunk.Version },
+	},
+	{
+		name:   "lang",
+		write:  func(c *models.UnifiedChunkRecord) (interface{}, error) { return c.Lang, nil },
+		target: func(s *chunkScan) interface{} { return &s.lang },
+	},
+}
+
+// chunkColumnsWhere returns the registered columns that match keep, in registry order
+func chunkColumnsWhere(keep func(column chunkColumn) bool) []chunkColumn {
+	var columns []chunkColumn
+	for _, column := range chunkColumnRegistry {
+		if keep(column) {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+var (
+	// chunkListColumns are read by queries returning many chunks; they include the version,
+	// so listed chunks can be written back with optimistic updates
+	chunkListColumns = chunkColumnsWhere(func(c chunkColumn) bool { return c.listed })
+	// chunkInsertColumns are written when a chunk is created
+	chunkInsertColumns = chunkColumnsWhere(func(c chunkColumn) bool { return c.write != nil })
+	// chunkUpdateColumns are written when a whole chunk is updated
+	chunkUpdateColumns = chunkColumnsWhere(func(c chunkColumn) bool { return c.write != nil && !c.immutable })
+)
+
+// unifiedChunkColumns is the column list shared by queries that alias chunks as "c"
+var unifiedChunkColumns = chunkColumnList("c", chunkListColumns)
+
+// chunkColumnList joins the names of columns, qualified with alias unless it is empty
+func chunkColumnList(alias string, columns []chunkColumn) string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+		if alias != "" {
+			names[i] = alias + "." + column.name
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// chunkInsertQuery inserts one chunk; chunkInsertArgs returns its arguments
+var chunkInsertQuery = buildChunkInsert()
+
+func buildChunkInsert() string {
+	placeholders := make([]string, len(chunkInsertColumns))
+	for i := range chunkInsertColumns {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+	}
+	return fmt.Sprintf("INSERT INTO chunks (%s) VALUES (%s)",
+		chunkColumnList("", chunkInsertColumns), strings.Join(placeholders, ", "))
+}
+
+// chunkInsertArgs returns the arguments of chunkInsertQuery for chunk
+func chunkInsertArgs(chunk *models.UnifiedChunkRecord) ([]interface{}, error) {
+	return chunkWriteArgs(chunk, chunkInsertColumns)
+}
+
+// chunkUpdateQuery replaces the mutable columns of a chunk if it is still at the expected
+// version, and moves it to the next version; chunkUpdateArgs returns its arguments
+var chunkUpdateQuery = buildChunkUpdate()
+
+func buildChunkUpdate() string {
+	sets := make([]string, 0, len(chunkUpdateColumns)+1)
+	for i, column := range chunkUpdateColumns {
+		sets = append(sets, fmt.Sprintf("%s = $%d", column.name, i+2))
+	}
+	sets = append(sets, "version = version + 1")
+	return fmt.Sprintf("UPDATE chunks SET %s WHERE chunk_id = $1 AND version = $%d",
+		strings.Join(sets, ", "), len(chunkUpdateColumns)+2)
+}
+
+// chunkUpdateArgs returns the arguments of chunkUpdateQuery: the chunk ID, the mutable
+// columns and the expected version
+func chunkUpdateArgs(chunk *models.UnifiedChunkRecord) ([]interface{}, error) {
+	values, err := chunkWriteArgs(chunk, chunkUpdateColumns)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(values)+2)
+	args = append(args, chunk.ChunkID)
+	args = append(args, values...)
+	return append(args, chunk.Version), nil
+}
+
+func chunkWriteArgs(chunk *models.UnifiedChunkRecord, columns []chunkColumn) ([]interface{}, error) {
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		value, err := column.write(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s of chunk %s: %w", column.name, chunk.ChunkID, err)
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+// chunkMetadataValue encodes metadata for the jsonb column; nil metadata is stored as the
+// column default, an empty object
+func chunkMetadataValue(metadata map[string]interface{}) (interface{}, error) {
+	if metadata == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanChunkColumns scans a row selecting columns into a chunk record, scanning any columns
+// selected before them into leading. Scan errors, including sql.ErrNoRows, are wrapped.
+func scanChunkColumns(row rowScanner, columns []chunkColumn, leading ...interface{}) (*models.UnifiedChunkRecord, error) {
+	var scan chunkScan
+	targets := make([]interface{}, 0, len(leading)+len(columns))
+	targets = append(targets, leading...)
+	for _, column := range columns {
+		targets = append(targets, column.target(&scan))
+	}
+	if err := row.Scan(targets...); err != nil {
+		return nil, fmt.Errorf("failed to scan chunk row: %w", err)
+	}
+
+	chunk := &scan.chunk
+	chunk.Tags = []string(scan.tags)
+	chunk.Lang = scan.lang.String
+	chunk.Metadata = make(map[string]interface{})
+	if len(scan.metadata) > 0 {
+		if err := json.Unmarshal(scan.metadata, &chunk.Metadata); err != nil {
+			log.Printf("Warning: failed to parse metadata for chunk %s: %v", chunk.ChunkID, err)
+			chunk.Metadata = make(map[string]interface{})
+		}
+	}
+	return chunk, nil
+}
+
+// scanUnifiedChunk scans the current row of a chunk query into a chunk record, scanning any
+// columns selected before unifiedChunkColumns into leading
+func scanUnifiedChunk(row rowScanner, leading ...interface{}) (*models.UnifiedChunkRecord, error) {
+	return scanChunkColumns(row, chunkListColumns, leading...)
+}
+
+// scanUnifiedChunks scans rows selected with unifiedChunkColumns into chunk records
+func scanUnifiedChunks(rows *sql.Rows) ([]models.UnifiedChunkRecord, error) {
+	var chunks []models.UnifiedChunkRecord
+	for rows.Next() {
+		chunk, err := scanUnifiedChunk(rows)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, *chunk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk rows: %w", err)
+	}
+
+	return chunks, nil
+}
+
+// scanKeyedUnifiedChunks scans rows selecting a key column followed by unifiedChunkColumns
+// into chunk records grouped by key, returning the number of rows
+func scanKeyedUnifiedChunks(rows *sql.Rows) (map[string][]models.UnifiedChunkRecord, int, error) {
+	chunks := make(map[string][]models.UnifiedChunkRecord)
+	count := 0
+	for rows.Next() {
+		var key string
+		chunk, err := scanUnifiedChunk(rows, &key)
+		if err != nil {
+			return nil, count, err
+		}
+		chunks[key] = append(chunks[key], *chunk)
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, count, fmt.Errorf("error iterating chunk rows: %w", err)
+	}
+
+	return chunks, count, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeChunkRow scans fixed values into a row's destinations
+type fakeChunkRow struct {
+	values []interface{}
+	err    error
+}
+
+func (r *fakeChunkRow) Scan(dest ...interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(dest) != len(r.values) {
+		return fmt.Errorf("expected %d destinations, got %d", len(r.values), len(dest))
+	}
+	for i, value := range r.values {
+		switch d := dest[i].(type) {
+		case *string:
+			*d = value.(string)
+		case **string:
+			*d = value.(*string)
+		case *bool:
+			*d = value.(bool)
+		case *int:
+			*d = value.(int)
+		case *int64:
+			*d = value.(int64)
+		case *time.Time:
+			*d = value.(time.Time)
+		case *[]byte:
+			*d = value.([]byte)
+		case sql.Scanner:
+			if err := d.Scan(value); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported destination %T", dest[i])
+		}
+	}
+	return nil
+}
+
+func TestChunkColumns_Statements(t *testing.T) {
+	assert.Equal(t,
+		"INSERT INTO chunks (chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot, ref, tags, metadata, created_time, last_updated, lang) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)",
+		chunkInsertQuery)
+
+	assert.Equal(t,
+		"UPDATE chunks SET contents = $2, parent = $3, page = $4, is_page = $5, is_tag = $6, is_template = $7, is_slot = $8, "+
+			"ref = $9, tags = $10, metadata = $11, last_updated = $12, lang = $13, version = version + 1 "+
+			"WHERE chunk_id = $1 AND version = $14",
+		chunkUpdateQuery)
+
+	assert.Equal(t,
+		"c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag, c.is_template, c.is_slot, c.ref, c.tags, c.metadata, c.created_time, c.last_updated, c.version",
+		unifiedChunkColumns)
+}
+
+func TestChunkColumns_WriteArgs(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	parent := "parent-1"
+	chunk := &models.UnifiedChunkRecord{
+		ChunkID:     "chunk-1",
+		Contents:    "hello",
+		Parent:      &parent,
+		IsTag:       true,
+		Tags:        []string{"tag-1"},
+		Metadata:    map[string]interface{}{"status": "done"},
+		CreatedTime: now,
+		LastUpdated: now,
+		Version:     4,
+		Lang:        "en",
+	}
+
+	args, err := chunkInsertArgs(chunk)
+	require.NoError(t, err)
+	require.Len(t, args, 14)
+	assert.Equal(t, "chunk-1", args[0])
+	assert.Equal(t, &parent, args[2])
+	assert.Equal(t, pq.Array([]string{"tag-1"}), args[9])
+	assert.Equal(t, `{"status":"done"}`, args[10], "metadata is stored as JSON")
+	assert.Equal(t, now, args[11])
+	assert.Equal(t, "en", args[13])
+
+	args, err = chunkUpdateArgs(chunk)
+	require.NoError(t, err)
+	require.Len(t, args, 14)
+	assert.Equal(t, "chunk-1", args[0])
+	assert.Equal(t, "hello", args[1])
+	assert.Equal(t, now, args[11], "created_time is never updated")
+	assert.Equal(t, "en", args[12])
+	assert.Equal(t, int64(4), args[13], "the expected version comes last")
+
+	chunk.Metadata = nil
+	args, err = chunkInsertArgs(chunk)
+	require.NoError(t, err)
+	assert.Equal(t, "{}", args[10])
+
+	chunk.Metadata = map[string]interface{}{"bad": make(chan int)}
+	_, err = chunkUpdateArgs(chunk)
+	assert.ErrorContains(t, err, "failed to encode metadata of chunk chunk-1")
+}
+
+func TestChunkColumns_Scan(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	listed := []interface{}{
+		"chunk-1", "hello", (*string)(nil), (*string)(nil),
+		false, false, true, false, (*string)(nil),
+		[]byte(`{"tag-1","tag-2"}`), []byte(`{"priority":2}`),
+		now, now, int64(7),
+	}
+
+	t.Run("list columns with leading columns", func(t *testing.T) {
+		var depth int
+		row := &fakeChunkRow{values: append([]interface{}{3}, listed...)}
+		chunk, err := scanUnifiedChunk(row, &depth)
+		require.NoError(t, err)
+		assert.Equal(t, 3, depth)
+		assert.Equal(t, "chunk-1", chunk.ChunkID)
+		assert.True(t, chunk.IsTemplate)
+		assert.Equal(t, []string{"tag-1", "tag-2"}, chunk.Tags)
+		assert.Equal(t, map[string]interface{}{"priority": float64(2)}, chunk.Metadata)
+		assert.Equal(t, int64(7), chunk.Version)
+	})
+
+	t.Run("every column", func(t *testing.T) {
+		row := &fakeChunkRow{values: append(append([]interface{}{}, listed...), "zh")}
+		chunk, err := scanChunkColumns(row, chunkColumnRegistry)
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), chunk.Version)
+		assert.Equal(t, "zh", chunk.Lang)
+	})
+
+	t.Run("unparsable metadata is dropped", func(t *testing.T) {
+		values := append([]interface{}{}, listed...)
+		values[10] = []byte("not json")
+		chunk, err := scanUnifiedChunk(&fakeChunkRow{values: values})
+		require.NoError(t, err)
+		assert.Empty(t, chunk.Metadata)
+		assert.NotNil(t, chunk.Metadata)
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		_, err := scanChunkColumns(&fakeChunkRow{err: sql.ErrNoRows}, chunkColumnRegistry)
+		assert.True(t, errors.Is(err, sql.ErrNoRows))
+	})
+}
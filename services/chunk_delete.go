@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// CASCADING SUBTREE DELETE
+// ============================================================================
+//
+// Deleting a single chunk leaves its children behind as roots. DeleteSubtree removes a chunk
+// together with every descendant instead, after reporting what else the delete touches:
+// chunks outside the subtree lose the tags defined inside it, template instances lose their
+// template, and graph nodes extracted from the subtree go with it. Unless the delete is
+// permanent, the removed rows are kept in chunk_trash.
+
+// ErrSubtreeChanged is matched by errors.Is when a confirmed delete finds a different
+// subtree than the preview it was based on
+var ErrSubtreeChanged = errors.New("subtree changed since preview")
+
+// DeleteSubtree reports the impact of deleting a chunk and its descendants and, when
+// opts.Confirm is set, performs the delete in one transaction
+func (s *unifiedChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	start := time.Now()
+	result := &models.SubtreeDeleteResult{}
+	defer func() {
+		s.monitor.RecordQuery("delete_subtree", time.Since(start), result.Impact.Chunks)
+	}()
+
+	if chunkID == "" {
+		return nil, fmt.Errorf("chunk ID is required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: !opts.Confirm})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	chunkIDs, err := subtreeChunkIDsTx(ctx, tx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunkIDs) == 0 {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+
+	if opts.Confirm {
+		// Lock the subtree so that no child is added under it while it is deleted
+		_, err = tx.ExecContext(ctx,
+			"SELECT chunk_id FROM chunks WHERE chunk_id = ANY($1::uuid[]) ORDER BY chunk_id FOR UPDATE",
+			pq.Array(chunkIDs))
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock subtree: %w", err)
+		}
+	}
+
+	impact, err := subtreeDeleteImpactTx(ctx, tx, chunkID, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	result.Impact = *impact
+
+	if !opts.Confirm {
+		return result, nil
+	}
+	if opts.ExpectedChunks > 0 && opts.ExpectedChunks != impact.Chunks {
+		return nil, fmt.Errorf("%w: subtree of %s has %d chunks, expected %d",
+			ErrSubtreeChanged, chunkID, impact.Chunks, opts.ExpectedChunks)
+	}
+
+	if !opts.Permanent {
+		result.TrashID = uuid.New().String()
+		if err = trashSubtreeTx(ctx, tx, result.TrashID, chunkID, chunkIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = deleteSubtreeTx(ctx, tx, chunkIDs, impact.GraphNodes > 0); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Deleted = true
+	result.DeletedChunkIDs = chunkIDs
+	s.invalidateSubtreeCaches(ctx)
+
+	return result, nil
+}
+
+// subtreeChunkIDsTx returns a chunk and all of its descendants, parents before children
+func subtreeChunkIDsTx(ctx context.Context, tx *sql.Tx, rootID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT chunk_id, 0 AS depth FROM chunks WHERE chunk_id = $1
+			UNION ALL
+			SELECT c.chunk_id, t.depth + 1 FROM chunks c JOIN tree t ON c.parent = t.chunk_id
+			WHERE t.depth < 100
+		)
+		SELECT chunk_id FROM tree ORDER BY depth, chunk_id`, rootID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtree: %w", err)
+	}
+	defer rows.Close()
+
+	var chunkIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan subtree chunk: %w", err)
+		}
+		chunkIDs = append(chunkIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subtree: %w", err)
+	}
+	return chunkIDs, nil
+}
+
+// subtreeDeleteImpactTx counts what outside the subtree depends on chunks inside it
+func subtreeDeleteImpactTx(ctx context.Context, tx *sql.Tx, rootID string, chunkIDs []string) (*models.SubtreeDeleteImpact, error) {
+	impact := &models.SubtreeDeleteImpact{
+		RootChunkID: rootID,
+		Chunks:      len(chunkIDs),
+		Descendants: len(chunkIDs) - 1,
+	}
+
+	err := tx.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(DISTINCT source_chunk_id) FROM chunk_tags
+			 WHERE tag_chunk_id = ANY($1::uuid[]) AND NOT source_chunk_id = ANY($1::uuid[])),
+			(SELECT COUNT(*) FROM chunks
+			 WHERE metadata->>'template_chunk_id' = ANY($1::text[]) AND NOT chunk_id = ANY($1::uuid[]))`,
+		pq.Array(chunkIDs)).Scan(&impact.TaggedChunks, &impact.TemplateInstances)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count subtree dependents: %w", err)
+	}
+
+	// The knowledge graph tables are optional on the unified schema
+	hasGraph, err := snapshotTableExists(ctx, tx, "graph_nodes")
+	if err != nil {
+		return nil, err
+	}
+	if hasGraph {
+		err = tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM graph_nodes WHERE chunk_id = ANY($1::uuid[])",
+			pq.Array(chunkIDs)).Scan(&impact.GraphNodes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count subtree graph nodes: %w", err)
+		}
+	}
+
+	return impact, nil
+}
+
+// trashSubtreeTx copies the subtree's rows into chunk_trash under one trash ID. The stored
+// row keeps the tags array, so tag relationships can be restored from it.
+func trashSubtreeTx(ctx context.Context, tx *sql.Tx, trashID, rootID string, chunkIDs []string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO chunk_trash (trash_id, chunk_id, root_chunk_id, record, position)
+		SELECT $1, c.chunk_id, $2, to_jsonb(c) - 'search_vector', ids.position
+		FROM unnest($3::uuid[]) WITH ORDINALITY AS ids(chunk_id, position)
+		JOIN chunks c ON c.chunk_id = ids.chunk_id`,
+		trashID, rootID, pq.Array(chunkIDs))
+	if err != nil {
+		return fmt.Errorf("failed to move subtree to trash: %w", err)
+	}
+	return nil
+}
+
+// deleteSubtreeTx removes the subtree and the links to it that foreign keys do not cover.
+// Hierarchy, tag and reference rows of the deleted chunks cascade.
+func deleteSubtreeTx(ctx context.Context, tx *sql.Tx, chunkIDs []string, hasGraphNodes bool) error {
+	// Keep the tags arrays of chunks outside the subtree in step with chunk_tags
+	_, err := tx.ExecContext(ctx, `
+		UPDATE chunks SET tags = tags - $1::text[], last_updated = NOW(), version = version + 1
+		WHERE chunk_id IN (
+			SELECT source_chunk_id FROM chunk_tags WHERE tag_chunk_id = ANY($1::uuid[])
+		) AND NOT chunk_id = ANY($1::uuid[])`,
+		pq.Array(chunkIDs))
+	if err != nil {
+		return fmt.Errorf("failed to remove subtree tags from other chunks: %w", err)
+	}
+
+	if hasGraphNodes {
+		_, err = tx.ExecContext(ctx, "DELETE FROM graph_nodes WHERE chunk_id = ANY($1::uuid[])", pq.Array(chunkIDs))
+		if err != nil {
+			return fmt.Errorf("failed to delete subtree graph nodes: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM chunks WHERE chunk_id = ANY($1::uuid[])", pq.Array(chunkIDs))
+	if err != nil {
+		return fmt.Errorf("failed to delete subtree: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteSubtree_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	migration, err := os.ReadFile("../database/chunk_trash_migration.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	service := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+
+	newChunk := func(contents string, parent *models.UnifiedChunkRecord) *models.UnifiedChunkRecord {
+		chunk := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: contents}
+		if parent != nil {
+			chunk.Parent = &parent.ChunkID
+		}
+		require.NoError(t, service.CreateChunk(ctx, chunk))
+		return chunk
+	}
+	root := newChunk("Root", nil)
+	child := newChunk("Child", root)
+	tag := newChunk("subtree-tag-"+uuid.New().String(), child)
+	grandchild := newChunk("Grandchild", child)
+	outside := newChunk("Outside", nil)
+	defer service.DeleteChunk(ctx, outside.ChunkID)
+	defer db.Exec("DELETE FROM chunk_trash WHERE root_chunk_id = $1", root.ChunkID)
+
+	_, err = db.Exec("UPDATE chunks SET is_tag = true WHERE chunk_id = $1", tag.ChunkID)
+	require.NoError(t, err)
+	require.NoError(t, service.AddTags(ctx, outside.ChunkID, []string{tag.ChunkID}))
+
+	// The preview changes nothing
+	preview, err := service.DeleteSubtree(ctx, root.ChunkID, models.SubtreeDeleteOptions{})
+	require.NoError(t, err)
+	assert.False(t, preview.Deleted)
+	assert.Equal(t, 4, preview.Impact.Chunks)
+	assert.Equal(t, 3, preview.Impact.Descendants)
+	assert.Equal(t, 1, preview.Impact.TaggedChunks)
+	_, err = service.GetChunk(ctx, grandchild.ChunkID)
+	require.NoError(t, err)
+
+	// A confirmation based on a stale preview is refused
+	_, err = service.DeleteSubtree(ctx, root.ChunkID, models.SubtreeDeleteOptions{Confirm: true, ExpectedChunks: 3})
+	assert.ErrorIs(t, err, ErrSubtreeChanged)
+
+	result, err := service.DeleteSubtree(ctx, root.ChunkID, models.SubtreeDeleteOptions{Confirm: true, ExpectedChunks: 4})
+	require.NoError(t, err)
+	assert.True(t, result.Deleted)
+	assert.Len(t, result.DeletedChunkIDs, 4)
+	require.NotEmpty(t, result.TrashID)
+
+	for _, chunk := range []*models.UnifiedChunkRecord{root, child, tag, grandchild} {
+		_, err = service.GetChunk(ctx, chunk.ChunkID)
+		assert.Error(t, err, "chunk %s is deleted", chunk.Contents)
+	}
+	tags, err := service.GetChunkTags(ctx, outside.ChunkID)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	var trashed int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM chunk_trash WHERE trash_id = $1", result.TrashID).Scan(&trashed))
+	assert.Equal(t, 4, trashed)
+}
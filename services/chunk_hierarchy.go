@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// HIERARCHY CLOSURE TABLE MAINTENANCE
+// ============================================================================
+//
+// chunk_hierarchy holds one row per (ancestor, descendant) pair, the chunk itself included
+// at depth 0. Managed Postgres services such as Supabase do not install the
+// sync_chunk_hierarchy trigger, so every write that adds chunks or changes a parent updates
+// the table itself, in the same transaction as the write.
+
+// hierarchyPathsCTE walks from every chunk of the subtree up to its root, yielding one
+// closure row per ancestor. It needs subtreeCTE ahead of it.
+const hierarchyPathsCTE = `
+	paths AS (
+		SELECT c.chunk_id AS ancestor_id, c.chunk_id AS descendant_id, c.parent AS next_id,
+			0 AS depth, ARRAY[c.chunk_id] AS path_ids
+		FROM chunks c JOIN subtree s ON c.chunk_id = s.chunk_id
+		UNION ALL
+		SELECT p.next_id, p.descendant_id, c.parent, p.depth + 1, ARRAY[p.next_id] || p.path_ids
+		FROM paths p JOIN chunks c ON c.chunk_id = p.next_id
+		WHERE p.depth < 100
+	)`
+
+// insertSubtreeHierarchyTx adds the closure rows of every chunk in the given subtrees.
+// Existing rows are overwritten, so it is safe next to the trigger where one is installed.
+func insertSubtreeHierarchyTx(ctx context.Context, tx *sql.Tx, rootIDs []string) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE`+subtreeCTE+`,`+hierarchyPathsCTE+`
+		INSERT INTO chunk_hierarchy (ancestor_id, descendant_id, depth, path_ids)
+		SELECT ancestor_id, descendant_id, depth, path_ids FROM paths
+		ON CONFLICT (ancestor_id, descendant_id) DO UPDATE SET depth = EXCLUDED.depth, path_ids = EXCLUDED.path_ids`,
+		pq.Array(rootIDs))
+	if err != nil {
+		return fmt.Errorf("failed to rebuild subtree hierarchy: %w", err)
+	}
+	return nil
+}
+
+// parentChangesTx reports whether setting newParent would move the chunk. A missing chunk
+// reports false and is left to the write to detect.
+func parentChangesTx(ctx context.Context, tx *sql.Tx, chunkID string, newParent *string) (bool, error) {
+	var current sql.NullString
+	err := tx.QueryRowContext(ctx, "SELECT parent FROM chunks WHERE chunk_id = $1", chunkID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get chunk parent: %w", err)
+	}
+
+	next := ""
+	if newParent != nil {
+		next = *newParent
+	}
+	return current.String != next, nil
+}
+
+// childChunkIDsTx returns the direct children of a chunk
+func childChunkIDsTx(ctx context.Context, tx *sql.Tx, parentID string) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT chunk_id FROM chunks WHERE parent = $1", parentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query child chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var children []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan child chunk: %w", err)
+		}
+		children = append(children, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read child chunks: %w", err)
+	}
+	return children, nil
+}
+
+// ChunkHierarchyService repairs the chunk_hierarchy closure table
+type ChunkHierarchyService interface {
+	// Rebuild recomputes the whole table from parent pointers, e.g. for data written
+	// before the service maintained it or while the trigger was missing
+	Rebuild(ctx context.Context) (*models.HierarchyRebuildResult, error)
+}
+
+// chunkHierarchyService implements ChunkHierarchyService on the chunks table
+type chunkHierarchyService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+}
+
+// NewChunkHierarchyService creates a closure table maintenance service
+func NewChunkHierarchyService(db *sql.DB, monitor QueryPerformanceMonitor) ChunkHierarchyService {
+	return &chunkHierarchyService{db: db, monitor: monitor}
+}
+
+// Rebuild replaces every closure row in one transaction, so readers never see a partly
+// rebuilt table
+func (s *chunkHierarchyService) Rebuild(ctx context.Context) (*models.HierarchyRebuildResult, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result := &models.HierarchyRebuildResult{}
+	defer func() {
+		s.monitor.RecordQuery("rebuild_chunk_hierarchy", time.Since(start), result.Chunks)
+	}()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Keep concurrent writers from adding rows that the rebuild would then duplicate
+	if _, err = tx.ExecContext(ctx, "LOCK TABLE chunk_hierarchy IN EXCLUSIVE MODE"); err != nil {
+		return nil, fmt.Errorf("failed to lock hierarchy table: %w", err)
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM chunk_hierarchy"); err != nil {
+		return nil, fmt.Errorf("failed to clear hierarchy table: %w", err)
+	}
+
+	insertResult, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE subtree AS (SELECT chunk_id FROM chunks),`+hierarchyPathsCTE+`
+		INSERT INTO chunk_hierarchy (ancestor_id, descendant_id, depth, path_ids)
+		SELECT ancestor_id, descendant_id, depth, path_ids FROM paths`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild hierarchy table: %w", err)
+	}
+	inserted, err := insertResult.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if err = tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks").Scan(&result.Chunks); err != nil {
+		return nil, fmt.Errorf("failed to count chunks: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Rows = int(inserted)
+	result.Duration = time.Since(start)
+	return result, nil
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hierarchyDepths returns the closure rows of a chunk as ancestor -> depth
+func hierarchyDepths(t *testing.T, db *sql.DB, descendantID string) map[string]int {
+	t.Helper()
+	rows, err := db.Query("SELECT ancestor_id, depth FROM chunk_hierarchy WHERE descendant_id = $1", descendantID)
+	require.NoError(t, err)
+	defer rows.Close()
+
+	depths := make(map[string]int)
+	for rows.Next() {
+		var ancestor string
+		var depth int
+		require.NoError(t, rows.Scan(&ancestor, &depth))
+		depths[ancestor] = depth
+	}
+	require.NoError(t, rows.Err())
+	return depths
+}
+
+func TestChunkHierarchyMaintenance_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	// The service must keep the table correct on its own, as on Supabase
+	_, err := db.Exec("DROP TRIGGER IF EXISTS trigger_sync_chunk_hierarchy ON chunks")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	service := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+
+	newChunk := func(contents string, parent *models.UnifiedChunkRecord) *models.UnifiedChunkRecord {
+		chunk := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: contents}
+		if parent != nil {
+			chunk.Parent = &parent.ChunkID
+		}
+		return chunk
+	}
+	root := newChunk("Root", nil)
+	other := newChunk("Other root", nil)
+	child := newChunk("Child", root)
+	grandchild := newChunk("Grandchild", child)
+	chunks := []*models.UnifiedChunkRecord{root, other, child, grandchild}
+	defer func() {
+		for i := len(chunks) - 1; i >= 0; i-- {
+			service.DeleteChunk(ctx, chunks[i].ChunkID)
+		}
+	}()
+
+	for _, chunk := range chunks[:3] {
+		require.NoError(t, service.CreateChunk(ctx, chunk))
+	}
+	require.NoError(t, service.BatchCreateChunks(ctx, []models.UnifiedChunkRecord{*grandchild}))
+	assert.Equal(t, map[string]int{grandchild.ChunkID: 0, child.ChunkID: 1, root.ChunkID: 2}, hierarchyDepths(t, db, grandchild.ChunkID))
+
+	// Moving a chunk carries its descendants to the new ancestors
+	require.NoError(t, service.MoveChunk(ctx, child.ChunkID, other.ChunkID))
+	assert.Equal(t, map[string]int{grandchild.ChunkID: 0, child.ChunkID: 1, other.ChunkID: 2}, hierarchyDepths(t, db, grandchild.ChunkID))
+	assert.Error(t, service.MoveChunk(ctx, other.ChunkID, grandchild.ChunkID), "a chunk cannot move under its descendant")
+
+	// Deleting a chunk leaves its children as roots
+	require.NoError(t, service.DeleteChunk(ctx, child.ChunkID))
+	chunks = []*models.UnifiedChunkRecord{root, other, grandchild}
+	assert.Equal(t, map[string]int{grandchild.ChunkID: 0}, hierarchyDepths(t, db, grandchild.ChunkID))
+
+	// A rebuild restores rows written behind the service's back
+	_, err = db.Exec("UPDATE chunks SET parent = $1 WHERE chunk_id = $2", root.ChunkID, grandchild.ChunkID)
+	require.NoError(t, err)
+	result, err := NewChunkHierarchyService(db, NewNoOpMonitor()).Rebuild(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, result.Rows, result.Chunks)
+	assert.Equal(t, map[string]int{grandchild.ChunkID: 0, root.ChunkID: 1}, hierarchyDepths(t, db, grandchild.ChunkID))
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidAsOf is returned for point-in-time reads at a time that is not in the past, and
+// for diffs whose range is empty
+var ErrInvalidAsOf = errors.New("invalid as-of time")
+
+// ErrHistoricalChunkNotFound is returned for chunks without a version at the time read, or
+// without any history
+var ErrHistoricalChunkNotFound = errors.New("chunk not found")
+
+const (
+	defaultChunkHistoryLimit = 50
+	maxChunkHistoryLimit     = 500
+	defaultAsOfSearchLimit   = 20
+	maxAsOfSearchLimit       = 100
+)
+
+// ChunkHistoryService reads the knowledge base as it was at a point in time, from the
+// versions chunk_history keeps of every chunk
+type ChunkHistoryService interface {
+	// AsOf returns a reader of the chunks as they were at at
+	AsOf(ctx context.Context, at time.Time) (ChunkSnapshotReader, error)
+
+	// History lists the versions of a chunk, newest first
+	History(ctx context.Context, chunkID string, limit int) ([]models.ChunkRevision, error)
+
+	// DiffPage compares the chunks of a page at from with those at to
+	DiffPage(ctx context.Context, pageID string, from, to time.Time) (*models.PageDiff, error)
+}
+
+// ChunkSnapshotReader reads chunks as they were at one point in time. Chunks that did not
+// exist then are not found, even if they exist now. Page ACLs are the current ones.
+type ChunkSnapshotReader interface {
+	// AsOf returns the time the reader reads at
+	AsOf() time.Time
+
+	// GetChunk returns the version of a chunk current at the reader's time
+	GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error)
+
+	// GetChildren returns the chunks whose parent was parentChunkID, oldest first
+	GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error)
+
+	// GetPage returns a page and the chunks that were on it
+	GetPage(ctx context.Context, pageID string) (*models.PageSnapshot, error)
+
+	// Search ranks the chunks whose contents then matched query with full-text search
+	Search(ctx context.Context, query string, limit int) ([]models.UnifiedChunkRecord, error)
+}
+
+// chunkHistoryService implements ChunkHistoryService on PostgreSQL
+type chunkHistoryService struct {
+	db      *sql.DB
+	acls    PageACLService
+	cipher  *ContentCipher
+	monitor QueryPerformanceMonitor
+}
+
+// NewChunkHistoryService creates a chunk history service; acls may be nil when page ACLs
+// are disabled and cipher when content encryption is
+func NewChunkHistoryService(db *sql.DB, acls PageACLService, cipher *ContentCipher, monitor QueryPerformanceMonitor) ChunkHistoryService {
+	return &chunkHistoryService{db: db, acls: acls, cipher: cipher, monitor: monitor}
+}
+
+// chunkHistoryFrom selects the versions of chunks as "h" with their records expanded as
+// "c", so unifiedChunkColumns applies to them
+const chunkHistoryFrom = `
+	FROM chunk_history h
+	CROSS JOIN LATERAL jsonb_populate_record(NULL::chunks, h.record) c`
+
+// chunkHistoryValidAt matches the versions current at the time in parameter $1
+const chunkHistoryValidAt = `h.valid_from <= $1 AND (h.valid_to IS NULL OR h.valid_to > $1)`
+
+// validateAsOf rejects times that are not in the past
+func validateAsOf(at time.Time) error {
+	if at.IsZero() {
+		return fmt.Errorf("%w: a time is required", ErrInvalidAsOf)
+	}
+	if at.After(time.Now()) {
+		return fmt.Errorf("%w: %s is in the future", ErrInvalidAsOf, at.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// AsOf returns a reader of the chunks as they were at at
+func (s *chunkHistoryService) AsOf(ctx context.Context, at time.Time) (ChunkSnapshotReader, error) {
+	if err := Authorize(ctx, PermissionRead); err != nil {
+		return nil, err
+	}
+	if err := validateAsOf(at); err != nil {
+		return nil, err
+	}
+	return &chunkSnapshotReader{service: s, at: at}, nil
+}
+
+// History lists the versions of a chunk, newest first
+func (s *chunkHistoryService) History(ctx context.Context, chunkID string, limit int) ([]models.ChunkRevision, error) {
+	start := time.Now()
+	var revisions []models.ChunkRevision
+	defer func() {
+		s.monitor.RecordQuery("chunk_history", time.Since(start), len(revisions))
+	}()
+
+	if err := Authorize(ctx, PermissionRead); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultChunkHistoryLimit
+	}
+	limit = min(limit, maxChunkHistoryLimit)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT h.valid_from, h.valid_to, h.version, `+unifiedChunkColumns+chunkHistoryFrom+`
+		WHERE h.chunk_id = $1
+		ORDER BY h.valid_from DESC
+		LIMIT $2`, chunkID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var revision models.ChunkRevision
+		var validTo sql.NullTime
+		var version int64
+		chunk, err := scanUnifiedChunk(rows, &revision.ValidFrom, &validTo, &version)
+		if err != nil {
+			return nil, err
+		}
+		chunk.Version = version
+		if err := s.decrypt(ctx, chunk); err != nil {
+			return nil, err
+		}
+		revision.Chunk = *chunk
+		if validTo.Valid {
+			revision.ValidTo = &validTo.Time
+		}
+		revisions = append(revisions, revision)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk history: %w", err)
+	}
+
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrHistoricalChunkNotFound, chunkID)
+	}
+	if err := s.checkRead(ctx, &revisions[0].Chunk); err != nil {
+		return nil, err
+	}
+	revisions[0].Deleted = revisions[0].ValidTo != nil
+	return revisions, nil
+}
+
+// DiffPage compares the chunks of a page at from with those at to
+func (s *chunkHistoryService) DiffPage(ctx context.Context, pageID string, from, to time.Time) (*models.PageDiff, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidAsOf)
+	}
+	before, err := s.pageAt(ctx, pageID, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.pageAt(ctx, pageID, to)
+	if err != nil {
+		return nil, err
+	}
+	if before.Page == nil && after.Page == nil {
+		return nil, fmt.Errorf("%w: page %s", ErrHistoricalChunkNotFound, pageID)
+	}
+
+	diff := diffPageSnapshots(before, after)
+	diff.PageID, diff.From, diff.To = pageID, from, to
+	return diff, nil
+}
+
+// pageAt reads a page at a time, returning an empty snapshot if it did not exist then
+func (s *chunkHistoryService) pageAt(ctx context.Context, pageID string, at time.Time) (*models.PageSnapshot, error) {
+	reader, err := s.AsOf(ctx, at)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := reader.GetPage(ctx, pageID)
+	if errors.Is(err, ErrHistoricalChunkNotFound) {
+		return &models.PageSnapshot{AsOf: at, Blocks: []models.UnifiedChunkRecord{}}, nil
+	}
+	return snapshot, err
+}
+
+// diffPageSnapshots lists the chunks added to, removed from and modified on a page, in the
+// order of the later snapshot followed by the removed chunks
+func diffPageSnapshots(before, after *models.PageSnapshot) *models.PageDiff {
+	diff := &models.PageDiff{Changes: []models.ChunkChange{}}
+	previous := make(map[string]*models.UnifiedChunkRecord)
+	for _, chunk := range pageSnapshotChunks(before) {
+		previous[chunk.ChunkID] = chunk
+	}
+
+	for _, chunk := range pageSnapshotChunks(after) {
+		old, existed := previous[chunk.ChunkID]
+		delete(previous, chunk.ChunkID)
+		switch {
+		case !existed:
+			diff.Changes = append(diff.Changes, models.ChunkChange{ChunkID: chunk.ChunkID, Change: models.ChunkChangeAdded, After: chunk})
+			diff.Added++
+		case !sameChunkState(old, chunk):
+			diff.Changes = append(diff.Changes, models.ChunkChange{ChunkID: chunk.ChunkID, Change: models.ChunkChangeModified, Before: old, After: chunk})
+			diff.Modified++
+		}
+	}
+
+	removed := make([]*models.UnifiedChunkRecord, 0, len(previous))
+	for _, chunk := range previous {
+		removed = append(removed, chunk)
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		if !removed[i].CreatedTime.Equal(removed[j].CreatedTime) {
+			return removed[i].CreatedTime.Before(removed[j].CreatedTime)
+		}
+		return removed[i].ChunkID < removed[j].ChunkID
+	})
+	for _, chunk := range removed {
+		diff.Changes = append(diff.Changes, models.ChunkChange{ChunkID: chunk.ChunkID, Change: models.ChunkChangeRemoved, Before: chunk})
+		diff.Removed++
+	}
+	return diff
+}
+
+// pageSnapshotChunks returns the page chunk of a snapshot, if any, followed by its blocks
+func pageSnapshotChunks(snapshot *models.PageSnapshot) []*models.UnifiedChunkRecord {
+	var chunks []*models.UnifiedChunkRecord
+	if snapshot.Page != nil {
+		chunks = append(chunks, snapshot.Page)
+	}
+	for i := range snapshot.Blocks {
+		chunks = append(chunks, &snapshot.Blocks[i])
+	}
+	return chunks
+}
+
+// sameChunkState reports whether two versions of a chunk hold the same data. Versions are
+// compared by content rather than number, as some writes do not bump the version.
+func sameChunkState(a, b *models.UnifiedChunkRecord) bool {
+	left, errA := json.Marshal(a)
+	right, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(left) == string(right)
+}
+
+// decrypt replaces encrypted contents read from history with their plaintext. Without a
+// cipher they are left encrypted.
+func (s *chunkHistoryService) decrypt(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if s.cipher == nil || !IsEncryptedContent(chunk.Contents) {
+		return nil
+	}
+	contents, err := s.cipher.Decrypt(ctx, chunk.Contents)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt chunk %s: %w", chunk.ChunkID, err)
+	}
+	chunk.Contents = contents
+	return nil
+}
+
+// checkRead applies the page ACLs, if enabled, to a chunk read
+func (s *chunkHistoryService) checkRead(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if s.acls == nil {
+		return nil
+	}
+	return s.acls.CheckChunk(ctx, chunk, PermissionRead)
+}
+
+// filterRead drops the chunks on pages the caller may not read
+func (s *chunkHistoryService) filterRead(ctx context.Context, chunks []models.UnifiedChunkRecord) ([]models.UnifiedChunkRecord, error) {
+	if s.acls == nil {
+		return chunks, nil
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+// chunkSnapshotReader reads chunk_history at one time
+type chunkSnapshotReader struct {
+	service *chunkHistoryService
+	at      time.Time
+}
+
+func (r *chunkSnapshotReader) AsOf() time.Time {
+	return r.at
+}
+
+// query selects the chunk versions current at the reader's time that match where, in
+// which the arguments after the time are $2 onwards
+func (r *chunkSnapshotReader) query(ctx context.Context, operation, where, orderBy string, args ...interface{}) ([]models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	var chunks []models.UnifiedChunkRecord
+	defer func() {
+		r.service.monitor.RecordQuery(operation, time.Since(start), len(chunks))
+	}()
+
+	rows, err := r.service.db.QueryContext(ctx, `
+		SELECT h.version, `+unifiedChunkColumns+chunkHistoryFrom+`
+		WHERE `+chunkHistoryValidAt+` AND (`+where+`)
+		ORDER BY `+orderBy, append([]interface{}{r.at}, args...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk history: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var version int64
+		chunk, err := scanUnifiedChunk(rows, &version)
+		if err != nil {
+			return nil, err
+		}
+		chunk.Version = version
+		if err := r.service.decrypt(ctx, chunk); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, *chunk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk history: %w", err)
+	}
+	return chunks, nil
+}
+
+func (r *chunkSnapshotReader) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunks, err := r.query(ctx, "get_chunk_as_of", "h.chunk_id = $2", "h.valid_from", chunkID)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("%w: %s at %s", ErrHistoricalChunkNotFound, chunkID, r.at.Format(time.RFC3339))
+	}
+	if err := r.service.checkRead(ctx, &chunks[0]); err != nil {
+		return nil, err
+	}
+	return &chunks[0], nil
+}
+
+func (r *chunkSnapshotReader) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
+	if _, err := r.GetChunk(ctx, parentChunkID); err != nil {
+		return nil, err
+	}
+	children, err := r.query(ctx, "get_children_as_of", "h.record->>'parent' = $2", "c.created_time, h.chunk_id", parentChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return r.service.filterRead(ctx, children)
+}
+
+func (r *chunkSnapshotReader) GetPage(ctx context.Context, pageID string) (*models.PageSnapshot, error) {
+	page, err := r.GetChunk(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := r.query(ctx, "get_page_as_of", "h.record->>'page' = $2 AND h.chunk_id::text <> $2", "c.created_time, h.chunk_id", pageID)
+	if err != nil {
+		return nil, err
+	}
+	if blocks == nil {
+		blocks = []models.UnifiedChunkRecord{}
+	}
+	return &models.PageSnapshot{AsOf: r.at, Page: page, Blocks: blocks}, nil
+}
+
+// Search builds the full-text vectors of the versions on the fly, with the configuration
+// of each version's language, as search_vector is not kept in the history
+func (r *chunkSnapshotReader) Search(ctx context.Context, query string, limit int) ([]models.UnifiedChunkRecord, error) {
+	if query == "" {
+		return nil, fmt.Errorf("%w: a search query is required", ErrInvalidAsOf)
+	}
+	if limit <= 0 {
+		limit = defaultAsOfSearchLimit
+	}
+	limit = min(limit, maxAsOfSearchLimit)
+
+	const vector = `to_tsvector(chunk_search_config(c.lang), COALESCE(c.contents, ''))`
+	chunks, err := r.query(ctx, "search_as_of",
+		vector+` @@ chunk_search_query(c.lang, $2)`,
+		`ts_rank(`+vector+`, chunk_search_query(c.lang, $2)) DESC, h.chunk_id LIMIT $3`,
+		query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.service.filterRead(ctx, chunks)
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffPageSnapshots(t *testing.T) {
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	page := models.UnifiedChunkRecord{ChunkID: "page", Contents: "Roadmap", IsPage: true, CreatedTime: at}
+	kept := models.UnifiedChunkRecord{ChunkID: "kept", Contents: "Unchanged", CreatedTime: at}
+	edited := models.UnifiedChunkRecord{ChunkID: "edited", Contents: "Draft", Version: 1, CreatedTime: at}
+	removed := models.UnifiedChunkRecord{ChunkID: "removed", Contents: "Old idea", CreatedTime: at}
+
+	revised := edited
+	revised.Contents, revised.Version = "Final", 2
+	added := models.UnifiedChunkRecord{ChunkID: "added", Contents: "New idea", CreatedTime: at.Add(time.Hour)}
+
+	diff := diffPageSnapshots(
+		&models.PageSnapshot{Page: &page, Blocks: []models.UnifiedChunkRecord{kept, edited, removed}},
+		&models.PageSnapshot{Page: &page, Blocks: []models.UnifiedChunkRecord{kept, revised, added}},
+	)
+	assert.Equal(t, 1, diff.Added)
+	assert.Equal(t, 1, diff.Removed)
+	assert.Equal(t, 1, diff.Modified)
+	require.Len(t, diff.Changes, 3)
+	assert.Equal(t, models.ChunkChange{ChunkID: "edited", Change: models.ChunkChangeModified, Before: &edited, After: &revised}, diff.Changes[0])
+	assert.Equal(t, "added", diff.Changes[1].ChunkID)
+	assert.Nil(t, diff.Changes[1].Before)
+	assert.Equal(t, "removed", diff.Changes[2].ChunkID)
+	assert.Nil(t, diff.Changes[2].After)
+
+	// A page that did not exist yet is added with all its blocks
+	diff = diffPageSnapshots(&models.PageSnapshot{}, &models.PageSnapshot{Page: &page, Blocks: []models.UnifiedChunkRecord{kept}})
+	assert.Equal(t, 2, diff.Added)
+}
+
+func TestChunkHistory_RejectsInvalidTimes(t *testing.T) {
+	service := NewChunkHistoryService(nil, nil, nil, NewNoOpMonitor())
+	ctx := context.Background()
+
+	_, err := service.AsOf(ctx, time.Time{})
+	assert.ErrorIs(t, err, ErrInvalidAsOf)
+	_, err = service.AsOf(ctx, time.Now().Add(time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidAsOf)
+
+	now := time.Now()
+	_, err = service.DiffPage(ctx, "page", now, now.Add(-time.Hour))
+	assert.ErrorIs(t, err, ErrInvalidAsOf)
+
+	reader, err := service.AsOf(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, now, reader.AsOf())
+	_, err = reader.Search(ctx, "", 10)
+	assert.ErrorIs(t, err, ErrInvalidAsOf)
+}
+
+func TestChunkHistory_DecryptsContents(t *testing.T) {
+	cipher := newTestContentCipher(t)
+	ctx := context.Background()
+	key, err := cipher.NewDataKey(ctx)
+	require.NoError(t, err)
+	encrypted, err := cipher.Encrypt(key, "salary review")
+	require.NoError(t, err)
+
+	service := NewChunkHistoryService(nil, nil, cipher, NewNoOpMonitor()).(*chunkHistoryService)
+	chunk := &models.UnifiedChunkRecord{ChunkID: "c1", Contents: encrypted}
+	require.NoError(t, service.decrypt(ctx, chunk))
+	assert.Equal(t, "salary review", chunk.Contents)
+
+	plain := &models.UnifiedChunkRecord{ChunkID: "c2", Contents: "open notes"}
+	require.NoError(t, service.decrypt(ctx, plain))
+	assert.Equal(t, "open notes", plain.Contents)
+
+	withoutCipher := NewChunkHistoryService(nil, nil, nil, NewNoOpMonitor()).(*chunkHistoryService)
+	chunk = &models.UnifiedChunkRecord{ChunkID: "c1", Contents: encrypted}
+	require.NoError(t, withoutCipher.decrypt(ctx, chunk))
+	assert.Equal(t, encrypted, chunk.Contents)
+}
+
+func TestChunkHistory_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	migration, err := os.ReadFile("../database/chunk_history_migration.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	history := NewChunkHistoryService(db, nil, nil, NewNoOpMonitor())
+
+	page := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "Time travel page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer db.Exec("DELETE FROM chunk_history WHERE chunk_id = $1 OR record->>'page' = $1", page.ChunkID)
+	defer chunks.DeleteChunk(ctx, page.ChunkID)
+	block := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "zeppelin draft", Parent: &page.ChunkID, Page: &page.ChunkID}
+	require.NoError(t, chunks.CreateChunk(ctx, block))
+	created := time.Now()
+	time.Sleep(10 * time.Millisecond)
+
+	stored, err := chunks.GetChunk(ctx, block.ChunkID)
+	require.NoError(t, err)
+	stored.Contents = "zeppelin final"
+	require.NoError(t, chunks.UpdateChunk(ctx, stored))
+	later := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "Added later", Parent: &page.ChunkID, Page: &page.ChunkID}
+	require.NoError(t, chunks.CreateChunk(ctx, later))
+	defer chunks.DeleteChunk(ctx, later.ChunkID)
+	time.Sleep(10 * time.Millisecond)
+	updated := time.Now()
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, chunks.DeleteChunk(ctx, block.ChunkID))
+
+	then, err := history.AsOf(ctx, created)
+	require.NoError(t, err)
+	old, err := then.GetChunk(ctx, block.ChunkID)
+	require.NoError(t, err)
+	assert.Equal(t, "zeppelin draft", old.Contents)
+	_, err = then.GetChunk(ctx, later.ChunkID)
+	assert.ErrorIs(t, err, ErrHistoricalChunkNotFound, "chunks created afterwards did not exist yet")
+
+	children, err := then.GetChildren(ctx, page.ChunkID)
+	require.NoError(t, err)
+	require.Len(t, children, 1)
+	results, err := then.Search(ctx, "zeppelin draft", 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, block.ChunkID, results[0].ChunkID)
+
+	revisions, err := history.History(ctx, block.ChunkID, 0)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.True(t, revisions[0].Deleted)
+	assert.Equal(t, "zeppelin final", revisions[0].Chunk.Contents)
+	assert.Greater(t, revisions[0].Chunk.Version, revisions[1].Chunk.Version)
+
+	diff, err := history.DiffPage(ctx, page.ChunkID, created, updated)
+	require.NoError(t, err)
+	assert.Equal(t, 1, diff.Added)
+	assert.Equal(t, 1, diff.Modified)
+	assert.Equal(t, 0, diff.Removed)
+
+	now, err := history.AsOf(ctx, time.Now())
+	require.NoError(t, err)
+	snapshot, err := now.GetPage(ctx, page.ChunkID)
+	require.NoError(t, err)
+	require.Len(t, snapshot.Blocks, 1, "the deleted block is gone")
+	assert.Equal(t, later.ChunkID, snapshot.Blocks[0].ChunkID)
+
+	// Marking a chunk sensitive redacts the plaintext of its earlier versions
+	migration, err = os.ReadFile("../database/chunk_history_encryption_migration.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	cipher := newTestContentCipher(t)
+	encrypting := NewEncryptingChunkService(chunks, cipher)
+	history = NewChunkHistoryService(db, nil, cipher, NewNoOpMonitor())
+	secret := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "launch codes", Parent: &page.ChunkID, Page: &page.ChunkID}
+	require.NoError(t, encrypting.CreateChunk(ctx, secret))
+	defer chunks.DeleteChunk(ctx, secret.ChunkID)
+	stored, err = encrypting.GetChunk(ctx, secret.ChunkID)
+	require.NoError(t, err)
+	stored.Metadata = map[string]interface{}{SensitiveMetadataKey: true}
+	require.NoError(t, encrypting.UpdateChunk(ctx, stored))
+
+	var plaintextVersions int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM chunk_history WHERE chunk_id = $1 AND record->>'contents' = 'launch codes'",
+		secret.ChunkID).Scan(&plaintextVersions))
+	assert.Zero(t, plaintextVersions)
+	revisions, err = history.History(ctx, secret.ChunkID, 0)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	for _, revision := range revisions {
+		assert.Equal(t, "launch codes", revision.Chunk.Contents)
+	}
+}
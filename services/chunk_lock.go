@@ -0,0 +1,533 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// CHUNK LOCKS
+// ============================================================================
+//
+// An editor session locks a chunk before restructuring it, so two editors do not move,
+// merge or delete chunks of the same subtree at once. A live lock covers the chunk and its
+// descendants: only a caller presenting the lock's token may move or delete chunks inside
+// the subtree, move chunks into it or merge chunks out of it. Edits to contents are not
+// restructuring and stay open to everyone with write access.
+//
+// Locks are rows of chunk_locks with an expiry, so an editor that disappears stops
+// blocking others once its lock expires. Acquires are serialized with an advisory lock and
+// refuse a chunk whose ancestors or descendants are already locked. Restructuring checks
+// query the table; lock status on reads comes from a snapshot reloaded every few seconds.
+
+// ErrChunkLocked is returned for restructuring a subtree another editor holds a lock on,
+// and for acquiring a lock overlapping a live one
+var ErrChunkLocked = errors.New("chunk is locked")
+
+// ErrChunkLockNotHeld is returned for refreshing or releasing a lock that expired, was
+// released or is held under another token
+var ErrChunkLockNotHeld = errors.New("chunk lock not held")
+
+// ErrInvalidChunkLock is returned for lock requests with a TTL outside the allowed range
+// or without a token
+var ErrInvalidChunkLock = errors.New("invalid chunk lock")
+
+// chunkLockRefreshInterval bounds how long locks taken through other instances take to
+// show in lock status; restructuring checks always see them
+const chunkLockRefreshInterval = 5 * time.Second
+
+// ChunkLockService manages editor locks on chunk subtrees
+type ChunkLockService interface {
+	// AcquireLock locks a chunk and its descendants for ttl, or the configured default when
+	// ttl is zero. The returned lock carries the token that refreshes and releases it.
+	AcquireLock(ctx context.Context, chunkID string, ttl time.Duration) (*models.ChunkLock, error)
+
+	// RefreshLock extends a live lock to expire ttl from now
+	RefreshLock(ctx context.Context, chunkID, token string, ttl time.Duration) (*models.ChunkLock, error)
+
+	// ReleaseLock removes a lock. Admins and the gateway may release a lock without its
+	// token, to free a subtree an editor abandoned.
+	ReleaseLock(ctx context.Context, chunkID, token string) error
+
+	// LockStatus returns the live lock on a chunk or its nearest locked ancestor, without
+	// its token, or nil when the chunk is not locked
+	LockStatus(ctx context.Context, chunkID string) (*models.ChunkLock, error)
+
+	// CheckRestructure returns ErrChunkLocked when a lock held under another token than the
+	// caller's covers or lies inside the subtree of any of chunkIDs, which are moved, merged
+	// away or deleted, or covers any of destinationIDs, which gain children
+	CheckRestructure(ctx context.Context, chunkIDs, destinationIDs []string) error
+}
+
+type chunkLockTokenKey struct{}
+
+// WithChunkLockToken returns a context carrying the lock token a request presents, which
+// lets it restructure the subtree that lock covers
+func WithChunkLockToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, chunkLockTokenKey{}, token)
+}
+
+func chunkLockTokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(chunkLockTokenKey{}).(string)
+	return token
+}
+
+// chunkLockService implements ChunkLockService with the chunk_locks table of
+// database/chunk_lock_migration.sql
+type chunkLockService struct {
+	db *sql.DB
+	// chunks reads chunks without lock status, to check locked chunks exist
+	chunks  UnifiedChunkService
+	config  config.ChunkLockConfig
+	monitor QueryPerformanceMonitor
+
+	mu       sync.RWMutex
+	locks    map[string]*models.ChunkLock
+	loadedAt time.Time
+}
+
+// NewChunkLockService creates a chunk lock service. chunks must not attach lock status itself.
+func NewChunkLockService(db *sql.DB, chunks UnifiedChunkService, cfg config.ChunkLockConfig, monitor QueryPerformanceMonitor) ChunkLockService {
+	return &chunkLockService{
+		db:      db,
+		chunks:  chunks,
+		config:  cfg,
+		monitor: monitor,
+		locks:   make(map[string]*models.ChunkLock),
+	}
+}
+
+const chunkLockColumns = `chunk_id, holder, token, acquired_at, expires_at`
+
+// lockTTL resolves the lifetime a request asks for
+func (s *chunkLockService) lockTTL(ttl time.Duration) (time.Duration, error) {
+	if ttl == 0 {
+		return s.config.DefaultTTL, nil
+	}
+	if ttl < time.Second || ttl > s.config.MaxTTL {
+		return 0, fmt.Errorf("%w: ttl %v must be between 1s and %v", ErrInvalidChunkLock, ttl, s.config.MaxTTL)
+	}
+	return ttl, nil
+}
+
+// AcquireLock locks a chunk and its descendants
+func (s *chunkLockService) AcquireLock(ctx context.Context, chunkID string, ttl time.Duration) (*models.ChunkLock, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	ttl, err := s.lockTTL(ttl)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.chunks.GetChunk(ctx, chunkID); err != nil {
+		return nil, err
+	}
+	holder := "gateway"
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		holder = principal.Subject
+	}
+
+	start := time.Now()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize acquires, so two of them cannot both find overlapping subtrees free
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('chunk_locks'))`); err != nil {
+		return nil, fmt.Errorf("failed to serialize chunk lock: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM chunk_locks WHERE expires_at <= NOW()`); err != nil {
+		return nil, fmt.Errorf("failed to delete expired chunk locks: %w", err)
+	}
+
+	conflict, err := scanChunkLock(tx.QueryRowContext(ctx, `
+		SELECT `+chunkLockColumns+` FROM chunk_locks l
+		WHERE l.chunk_id = $1 OR EXISTS (
+			SELECT 1 FROM chunk_hierarchy h
+			WHERE (h.ancestor_id = l.chunk_id AND h.descendant_id = $1)
+			   OR (h.ancestor_id = $1 AND h.descendant_id = l.chunk_id))
+		LIMIT 1`, chunkID))
+	if err == nil {
+		return nil, chunkLockConflict(conflict)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	lock, err := scanChunkLock(tx.QueryRowContext(ctx, `
+		INSERT INTO chunk_locks (chunk_id, holder, token, expires_at)
+		VALUES ($1, $2, $3, NOW() + make_interval(secs => $4))
+		RETURNING `+chunkLockColumns,
+		chunkID, holder, uuid.New().String(), ttl.Seconds()))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit chunk lock: %w", err)
+	}
+	s.monitor.RecordQuery("acquire_chunk_lock", time.Since(start), 1)
+	s.store(chunkID, lock)
+	return lock, nil
+}
+
+// RefreshLock extends a live lock
+func (s *chunkLockService) RefreshLock(ctx context.Context, chunkID, token string, ttl time.Duration) (*models.ChunkLock, error) {
+	if token == "" {
+		return nil, fmt.Errorf("%w: token is required", ErrInvalidChunkLock)
+	}
+	ttl, err := s.lockTTL(ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	lock, err := scanChunkLock(s.db.QueryRowContext(ctx, `
+		UPDATE chunk_locks SET expires_at = NOW() + make_interval(secs => $3)
+		WHERE chunk_id = $1 AND token = $2 AND expires_at > NOW()
+		RETURNING `+chunkLockColumns,
+		chunkID, token, ttl.Seconds()))
+	s.monitor.RecordQuery("refresh_chunk_lock", time.Since(start), 1)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: no live lock on chunk %s with this token", ErrChunkLockNotHeld, chunkID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.store(chunkID, lock)
+	return lock, nil
+}
+
+// ReleaseLock removes a lock
+func (s *chunkLockService) ReleaseLock(ctx context.Context, chunkID, token string) error {
+	principal, ok := PrincipalFromContext(ctx)
+	force := token == "" && (!ok || principal.Role == models.RoleAdmin)
+	if token == "" && !force {
+		return fmt.Errorf("%w: token is required", ErrInvalidChunkLock)
+	}
+
+	start := time.Now()
+	result, err := s.db.ExecContext(ctx, `DELETE FROM chunk_locks WHERE chunk_id = $1 AND (token = $2 OR $3)`,
+		chunkID, token, force)
+	s.monitor.RecordQuery("release_chunk_lock", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to release chunk lock: %w", err)
+	}
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%w: no lock on chunk %s with this token", ErrChunkLockNotHeld, chunkID)
+	}
+	s.store(chunkID, nil)
+	return nil
+}
+
+// LockStatus returns the live lock covering a chunk
+func (s *chunkLockService) LockStatus(ctx context.Context, chunkID string) (*models.ChunkLock, error) {
+	locks, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if lock, ok := locks[chunkID]; ok && !lock.Expired(now) {
+		return withoutToken(lock), nil
+	}
+	locked := make([]string, 0, len(locks))
+	for id, lock := range locks {
+		if !lock.Expired(now) {
+			locked = append(locked, id)
+		}
+	}
+	if len(locked) == 0 {
+		return nil, nil
+	}
+
+	var ancestorID string
+	start := time.Now()
+	err = s.db.QueryRowContext(ctx, `
+		SELECT ancestor_id FROM chunk_hierarchy
+		WHERE descendant_id = $1 AND ancestor_id = ANY($2)
+		ORDER BY depth LIMIT 1`,
+		chunkID, pq.Array(locked)).Scan(&ancestorID)
+	s.monitor.RecordQuery("chunk_lock_status", time.Since(start), 1)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up locked ancestors: %w", err)
+	}
+	return withoutToken(locks[ancestorID]), nil
+}
+
+// CheckRestructure refuses restructuring subtrees locked under another token
+func (s *chunkLockService) CheckRestructure(ctx context.Context, chunkIDs, destinationIDs []string) error {
+	chunkIDs, destinationIDs = nonEmptyIDs(chunkIDs), nonEmptyIDs(destinationIDs)
+	if len(chunkIDs) == 0 && len(destinationIDs) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	conflict, err := scanChunkLock(s.db.QueryRowContext(ctx, `
+		SELECT `+chunkLockColumns+` FROM chunk_locks l
+		WHERE l.expires_at > NOW() AND l.token <> $3 AND EXISTS (
+			SELECT 1 FROM chunk_hierarchy h
+			WHERE (h.ancestor_id = l.chunk_id AND (h.descendant_id = ANY($1) OR h.descendant_id = ANY($2)))
+			   OR (h.descendant_id = l.chunk_id AND h.ancestor_id = ANY($1)))
+		LIMIT 1`,
+		pq.Array(chunkIDs), pq.Array(destinationIDs), chunkLockTokenFromContext(ctx)))
+	s.monitor.RecordQuery("check_chunk_locks", time.Since(start), len(chunkIDs)+len(destinationIDs))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return chunkLockConflict(conflict)
+}
+
+// snapshot returns the locks known to be live, reloading them when they are older than
+// chunkLockRefreshInterval
+func (s *chunkLockService) snapshot(ctx context.Context) (map[string]*models.ChunkLock, error) {
+	s.mu.RLock()
+	locks, fresh := s.locks, time.Since(s.loadedAt) < chunkLockRefreshInterval
+	s.mu.RUnlock()
+	if fresh {
+		return locks, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < chunkLockRefreshInterval {
+		return s.locks, nil
+	}
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `SELECT `+chunkLockColumns+` FROM chunk_locks WHERE expires_at > NOW()`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk locks: %w", err)
+	}
+	defer rows.Close()
+
+	locks = make(map[string]*models.ChunkLock)
+	for rows.Next() {
+		lock, err := scanChunkLock(rows)
+		if err != nil {
+			return nil, err
+		}
+		locks[lock.ChunkID] = lock
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk locks: %w", err)
+	}
+	s.monitor.RecordQuery("load_chunk_locks", time.Since(start), len(locks))
+	s.locks, s.loadedAt = locks, time.Now()
+	return locks, nil
+}
+
+// store replaces the cached lock on a chunk, or removes it when lock is nil. The map is
+// copied so snapshots handed out earlier do not change under their readers.
+func (s *chunkLockService) store(chunkID string, lock *models.ChunkLock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	locks := make(map[string]*models.ChunkLock, len(s.locks)+1)
+	for id, existing := range s.locks {
+		locks[id] = existing
+	}
+	if lock == nil {
+		delete(locks, chunkID)
+	} else {
+		locks[chunkID] = lock
+	}
+	s.locks = locks
+}
+
+func scanChunkLock(row rowScanner) (*models.ChunkLock, error) {
+	var lock models.ChunkLock
+	if err := row.Scan(&lock.ChunkID, &lock.Holder, &lock.Token, &lock.AcquiredAt, &lock.ExpiresAt); err != nil {
+		return nil, fmt.Errorf("failed to scan chunk lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// withoutToken copies a lock for callers other than the one acquiring it
+func withoutToken(lock *models.ChunkLock) *models.ChunkLock {
+	public := *lock
+	public.Token = ""
+	return &public
+}
+
+func chunkLockConflict(lock *models.ChunkLock) error {
+	holder := lock.Holder
+	if holder == "" {
+		holder = "another editor"
+	}
+	return fmt.Errorf("%w: chunk %s is locked by %s until %s", ErrChunkLocked, lock.ChunkID, holder,
+		lock.ExpiresAt.UTC().Format(time.RFC3339))
+}
+
+func nonEmptyIDs(ids []string) []string {
+	kept := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id = strings.TrimSpace(id); id != "" {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// lockChunkService refuses restructuring subtrees other editors hold locks on, and
+// attaches the covering lock to single-chunk reads
+type lockChunkService struct {
+	UnifiedChunkService
+	locks ChunkLockService
+}
+
+// NewLockEnforcingChunkService wraps a chunk service with editor lock checks. It belongs
+// above the caching layers, so cached chunks are not served with stale lock status.
+func NewLockEnforcingChunkService(base UnifiedChunkService, locks ChunkLockService) UnifiedChunkService {
+	return &lockChunkService{UnifiedChunkService: base, locks: locks}
+}
+
+// GetChunk returns a copy of the chunk carrying the lock covering it. Failing to read
+// lock status serves the chunk without it rather than failing the read.
+func (s *lockChunkService) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	lock, err := s.locks.LockStatus(ctx, chunkID)
+	if err != nil {
+		log.Printf("Warning: failed to read lock status of chunk %s: %v", chunkID, err)
+		return chunk, nil
+	}
+	if lock == nil && chunk.Lock == nil {
+		return chunk, nil
+	}
+	locked := *chunk
+	locked.Lock = lock
+	return &locked, nil
+}
+
+func (s *lockChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	current, err := s.UnifiedChunkService.GetChunk(ctx, chunk.ChunkID)
+	if err == nil && !sameParent(current.Parent, chunk.Parent) {
+		if err := s.locks.CheckRestructure(ctx, []string{chunk.ChunkID}, parentIDs(chunk.Parent)); err != nil {
+			return err
+		}
+	}
+	return s.UnifiedChunkService.UpdateChunk(ctx, chunk)
+}
+
+func (s *lockChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	chunkIDs := make([]string, len(chunks))
+	for i := range chunks {
+		chunkIDs[i] = chunks[i].ChunkID
+	}
+	current, err := s.UnifiedChunkService.BatchGetChunks(ctx, chunkIDs)
+	if err != nil {
+		return err
+	}
+	var moved, destinations []string
+	for i := range chunks {
+		if existing, ok := current[chunks[i].ChunkID]; ok && !sameParent(existing.Parent, chunks[i].Parent) {
+			moved = append(moved, chunks[i].ChunkID)
+			destinations = append(destinations, parentIDs(chunks[i].Parent)...)
+		}
+	}
+	if err := s.locks.CheckRestructure(ctx, moved, destinations); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks)
+}
+
+func (s *lockChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	if patch.Parent != nil {
+		if err := s.locks.CheckRestructure(ctx, []string{chunkID}, []string{*patch.Parent}); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+}
+
+func (s *lockChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
+	if err := s.locks.CheckRestructure(ctx, []string{chunkID}, nil); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.DeleteChunk(ctx, chunkID)
+}
+
+func (s *lockChunkService) MoveChunk(ctx context.Context, chunkID, newParentID string) error {
+	if err := s.locks.CheckRestructure(ctx, []string{chunkID}, []string{newParentID}); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.MoveChunk(ctx, chunkID, newParentID)
+}
+
+func (s *lockChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	if err := s.locks.CheckRestructure(ctx, []string{chunkID}, []string{newParentID}); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.MoveSubtree(ctx, chunkID, newParentID)
+}
+
+// CopySubtree only adds chunks under the destination, so only the destination is checked
+func (s *lockChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	if err := s.locks.CheckRestructure(ctx, nil, []string{newParentID}); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.CopySubtree(ctx, chunkID, newParentID)
+}
+
+func (s *lockChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	chunkIDs := make([]string, len(moves))
+	destinations := make([]string, len(moves))
+	for i, move := range moves {
+		chunkIDs[i], destinations[i] = move.ChunkID, move.NewParentID
+	}
+	if err := s.locks.CheckRestructure(ctx, chunkIDs, destinations); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.BulkMove(ctx, moves)
+}
+
+// DeleteSubtree allows previews, which change nothing, of locked subtrees
+func (s *lockChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	if opts.Confirm {
+		if err := s.locks.CheckRestructure(ctx, []string{chunkID}, nil); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.DeleteSubtree(ctx, chunkID, opts)
+}
+
+// MergeChunks moves the source's children to the target and deletes the source
+func (s *lockChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	if err := s.locks.CheckRestructure(ctx, []string{sourceID}, []string{targetID}); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+}
+
+func sameParent(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func parentIDs(parent *string) []string {
+	if parent == nil {
+		return nil
+	}
+	return []string{*parent}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// fakeChunkLocks records restructuring checks and refuses them while err is set
+type fakeChunkLocks struct {
+	ChunkLockService
+	locks     map[string]*models.ChunkLock
+	statusErr error
+	err       error
+	checks    [][2][]string
+}
+
+func (l *fakeChunkLocks) LockStatus(ctx context.Context, chunkID string) (*models.ChunkLock, error) {
+	return l.locks[chunkID], l.statusErr
+}
+
+func (l *fakeChunkLocks) CheckRestructure(ctx context.Context, chunkIDs, destinationIDs []string) error {
+	l.checks = append(l.checks, [2][]string{chunkIDs, destinationIDs})
+	return l.err
+}
+
+// lockedChunks serves fixed chunks and records which writes reached it
+type lockedChunks struct {
+	UnifiedChunkService
+	chunks map[string]*models.UnifiedChunkRecord
+	calls  []string
+}
+
+func (c *lockedChunks) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, ok := c.chunks[chunkID]
+	if !ok {
+		return nil, errors.New("chunk not found")
+	}
+	return chunk, nil
+}
+
+func (c *lockedChunks) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	found := make(map[string]*models.UnifiedChunkRecord)
+	for _, id := range chunkIDs {
+		if chunk, ok := c.chunks[id]; ok {
+			found[id] = chunk
+		}
+	}
+	return found, nil
+}
+
+func (c *lockedChunks) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	c.calls = append(c.calls, "update")
+	return nil
+}
+
+func (c *lockedChunks) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	c.calls = append(c.calls, "batch_update")
+	return nil
+}
+
+func (c *lockedChunks) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	c.calls = append(c.calls, "move_subtree")
+	return &models.SubtreeMoveResult{}, nil
+}
+
+func (c *lockedChunks) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	c.calls = append(c.calls, "delete_subtree")
+	return &models.SubtreeDeleteResult{}, nil
+}
+
+func (c *lockedChunks) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	c.calls = append(c.calls, "merge")
+	return &models.ChunkMergeResult{}, nil
+}
+
+func newTestLockedChunks() *lockedChunks {
+	root := "root"
+	return &lockedChunks{chunks: map[string]*models.UnifiedChunkRecord{
+		"root":  {ChunkID: "root", Contents: "Outline"},
+		"block": {ChunkID: "block", Contents: "Item", Parent: &root},
+	}}
+}
+
+func TestLockChunkService_GetChunk(t *testing.T) {
+	chunks := newTestLockedChunks()
+	lock := &models.ChunkLock{ChunkID: "root", Holder: "alice", ExpiresAt: time.Now().Add(time.Minute)}
+	locks := &fakeChunkLocks{locks: map[string]*models.ChunkLock{"block": lock}}
+	service := NewLockEnforcingChunkService(chunks, locks)
+
+	chunk, err := service.GetChunk(context.Background(), "block")
+	require.NoError(t, err)
+	assert.Equal(t, lock, chunk.Lock)
+	assert.Nil(t, chunks.chunks["block"].Lock, "the base chunk, which may be cached, is not changed")
+
+	chunk, err = service.GetChunk(context.Background(), "root")
+	require.NoError(t, err)
+	assert.Nil(t, chunk.Lock)
+
+	locks.statusErr = errors.New("database unavailable")
+	chunk, err = service.GetChunk(context.Background(), "block")
+	require.NoError(t, err, "lock status failures do not fail reads")
+	assert.Nil(t, chunk.Lock)
+}
+
+func TestLockChunkService_Restructuring(t *testing.T) {
+	chunks := newTestLockedChunks()
+	locks := &fakeChunkLocks{}
+	service := NewLockEnforcingChunkService(chunks, locks)
+	ctx := context.Background()
+	root, other := "root", "other"
+
+	require.NoError(t, service.UpdateChunk(ctx, &models.UnifiedChunkRecord{ChunkID: "block", Contents: "Edited", Parent: &root}))
+	assert.Empty(t, locks.checks, "edits keeping the parent are not restructuring")
+
+	require.NoError(t, service.UpdateChunk(ctx, &models.UnifiedChunkRecord{ChunkID: "block", Parent: &other}))
+	require.NoError(t, service.BatchUpdateChunks(ctx, []models.UnifiedChunkRecord{
+		{ChunkID: "root"},
+		{ChunkID: "block"},
+	}))
+	_, err := service.MoveSubtree(ctx, "block", "other")
+	require.NoError(t, err)
+	_, err = service.DeleteSubtree(ctx, "root", models.SubtreeDeleteOptions{})
+	require.NoError(t, err)
+	_, err = service.DeleteSubtree(ctx, "root", models.SubtreeDeleteOptions{Confirm: true})
+	require.NoError(t, err)
+	_, err = service.MergeChunks(ctx, "root", "block", models.ChunkMergeStrategy{})
+	require.NoError(t, err)
+
+	assert.Equal(t, [][2][]string{
+		{{"block"}, {"other"}},
+		{{"block"}, nil},
+		{{"block"}, {"other"}},
+		{{"root"}, nil},
+		{{"block"}, {"root"}},
+	}, locks.checks, "delete previews are not checked")
+	assert.Equal(t, []string{"update", "update", "batch_update", "move_subtree", "delete_subtree", "delete_subtree", "merge"}, chunks.calls)
+
+	locks.err = ErrChunkLocked
+	chunks.calls = nil
+	_, err = service.MoveSubtree(ctx, "block", "other")
+	assert.ErrorIs(t, err, ErrChunkLocked)
+	err = service.UpdateChunk(ctx, &models.UnifiedChunkRecord{ChunkID: "block"})
+	assert.ErrorIs(t, err, ErrChunkLocked)
+	assert.Empty(t, chunks.calls)
+}
+
+func TestChunkLockService_WithoutDatabase(t *testing.T) {
+	cfg := config.ChunkLockConfig{Enabled: true, DefaultTTL: 2 * time.Minute, MaxTTL: 10 * time.Minute}
+	locks := NewChunkLockService(nil, newTestLockedChunks(), cfg, NewNoOpMonitor()).(*chunkLockService)
+
+	t.Run("ttl", func(t *testing.T) {
+		ttl, err := locks.lockTTL(0)
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Minute, ttl)
+		ttl, err = locks.lockTTL(5 * time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 5*time.Minute, ttl)
+		for _, invalid := range []time.Duration{-time.Second, 500 * time.Millisecond, 11 * time.Minute} {
+			_, err = locks.lockTTL(invalid)
+			assert.ErrorIs(t, err, ErrInvalidChunkLock, invalid.String())
+		}
+	})
+
+	t.Run("status from snapshot", func(t *testing.T) {
+		locks.locks = map[string]*models.ChunkLock{
+			"root":  {ChunkID: "root", Holder: "alice", Token: "secret", ExpiresAt: time.Now().Add(time.Minute)},
+			"stale": {ChunkID: "stale", Holder: "bob", Token: "old", ExpiresAt: time.Now().Add(-time.Second)},
+		}
+		locks.loadedAt = time.Now()
+
+		lock, err := locks.LockStatus(context.Background(), "root")
+		require.NoError(t, err)
+		assert.Equal(t, "alice", lock.Holder)
+		assert.Empty(t, lock.Token, "only the caller acquiring a lock sees its token")
+		assert.Equal(t, "secret", locks.locks["root"].Token)
+
+		delete(locks.locks, "root")
+		lock, err = locks.LockStatus(context.Background(), "stale")
+		require.NoError(t, err)
+		assert.Nil(t, lock, "expired locks are ignored without a query")
+	})
+
+	t.Run("token required", func(t *testing.T) {
+		_, err := locks.RefreshLock(context.Background(), "root", "", 0)
+		assert.ErrorIs(t, err, ErrInvalidChunkLock)
+		err = locks.ReleaseLock(contextWithSubject("bob", models.RoleEditor), "root", "")
+		assert.ErrorIs(t, err, ErrInvalidChunkLock)
+		assert.NoError(t, locks.CheckRestructure(context.Background(), []string{""}, nil), "nothing to check")
+	})
+
+	t.Run("readers may not lock", func(t *testing.T) {
+		_, err := locks.AcquireLock(contextWithSubject("carol", models.RoleReader), "root", 0)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+	})
+}
+
+func TestChunkLockService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	cfg := config.ChunkLockConfig{Enabled: true, DefaultTTL: time.Minute, MaxTTL: 10 * time.Minute}
+	locks := NewChunkLockService(db, chunks, cfg, NewNoOpMonitor())
+	service := NewLockEnforcingChunkService(chunks, locks)
+
+	root := &models.UnifiedChunkRecord{Contents: "Lock test root", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, root))
+	defer chunks.DeleteChunk(ctx, root.ChunkID)
+	block := &models.UnifiedChunkRecord{Contents: "Lock test block", Parent: &root.ChunkID}
+	require.NoError(t, chunks.CreateChunk(ctx, block))
+	other := &models.UnifiedChunkRecord{Contents: "Lock test destination", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, other))
+	defer chunks.DeleteChunk(ctx, other.ChunkID)
+
+	lock, err := locks.AcquireLock(contextWithSubject("alice", models.RoleEditor), root.ChunkID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", lock.Holder)
+	assert.NotEmpty(t, lock.Token)
+
+	_, err = locks.AcquireLock(contextWithSubject("bob", models.RoleEditor), block.ChunkID, 0)
+	assert.ErrorIs(t, err, ErrChunkLocked, "locks may not overlap")
+
+	fetched, err := service.GetChunk(ctx, block.ChunkID)
+	require.NoError(t, err)
+	require.NotNil(t, fetched.Lock)
+	assert.Equal(t, root.ChunkID, fetched.Lock.ChunkID)
+
+	_, err = service.MoveSubtree(ctx, block.ChunkID, other.ChunkID)
+	assert.ErrorIs(t, err, ErrChunkLocked)
+	_, err = service.MoveSubtree(WithChunkLockToken(ctx, lock.Token), block.ChunkID, other.ChunkID)
+	assert.NoError(t, err, "the holder may restructure")
+
+	_, err = locks.RefreshLock(ctx, root.ChunkID, "wrong", 0)
+	assert.ErrorIs(t, err, ErrChunkLockNotHeld)
+	require.NoError(t, locks.ReleaseLock(ctx, root.ChunkID, lock.Token))
+	assert.ErrorIs(t, locks.ReleaseLock(ctx, root.ChunkID, lock.Token), ErrChunkLockNotHeld)
+}
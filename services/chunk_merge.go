@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// CHUNK MERGE
+// ============================================================================
+//
+// MergeChunks folds a source chunk into a target chunk in one transaction: the contents
+// are combined by the strategy, the source's children move under the target, tags,
+// metadata and refs are unioned, references and graph nodes pointing at the source are
+// redirected to the target, and the source is moved to the trash or deleted.
+
+// ErrInvalidChunkMerge is matched by errors.Is when two chunks cannot be merged
+var ErrInvalidChunkMerge = errors.New("invalid chunk merge")
+
+// defaultMergeSeparator joins concatenated contents
+const defaultMergeSeparator = "\n\n"
+
+// mergeChunkRow is the state of a chunk taking part in a merge
+type mergeChunkRow struct {
+	contents   string
+	page       sql.NullString
+	isPage     bool
+	isTag      bool
+	isTemplate bool
+	isSlot     bool
+	ref        sql.NullString
+	tags       []string
+	metadata   map[string]interface{}
+}
+
+// MergeChunks merges sourceID into targetID and returns the merged target
+func (s *unifiedChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	start := time.Now()
+	result := &models.ChunkMergeResult{SourceChunkID: sourceID}
+	defer func() {
+		s.monitor.RecordQuery("merge_chunks", time.Since(start), result.ReparentedChunks+result.RedirectedRefs)
+	}()
+
+	if targetID == "" || sourceID == "" {
+		return nil, fmt.Errorf("%w: target and source chunk IDs are required", ErrInvalidChunkMerge)
+	}
+	if targetID == sourceID {
+		return nil, fmt.Errorf("%w: a chunk cannot be merged into itself", ErrInvalidChunkMerge)
+	}
+	switch strategy.Contents {
+	case "":
+		strategy.Contents = models.MergeContentsConcatenate
+	case models.MergeContentsConcatenate, models.MergeContentsReplace, models.MergeContentsKeepTarget:
+	default:
+		return nil, fmt.Errorf("%w: unknown contents strategy %q", ErrInvalidChunkMerge, strategy.Contents)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock both chunks in a stable order so concurrent merges serialize
+	_, err = tx.ExecContext(ctx,
+		"SELECT chunk_id FROM chunks WHERE chunk_id = ANY($1::uuid[]) ORDER BY chunk_id FOR UPDATE",
+		pq.Array([]string{targetID, sourceID}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock chunks: %w", err)
+	}
+
+	target, err := loadMergeChunkTx(ctx, tx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := loadMergeChunkTx(ctx, tx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	for id, row := range map[string]*mergeChunkRow{targetID: target, sourceID: source} {
+		switch {
+		case row.isTag:
+			return nil, fmt.Errorf("%w: chunk %s is a tag; merge tags with MergeTags", ErrInvalidChunkMerge, id)
+		case row.isTemplate || row.isSlot:
+			return nil, fmt.Errorf("%w: chunk %s is a template or slot", ErrInvalidChunkMerge, id)
+		}
+	}
+
+	var targetInSource bool
+	err = tx.QueryRowContext(ctx, `
+		WITH RECURSIVE`+subtreeCTE+`
+		SELECT EXISTS(SELECT 1 FROM subtree WHERE chunk_id = $2)`,
+		pq.Array([]string{sourceID}), targetID).Scan(&targetInSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chunk hierarchy: %w", err)
+	}
+	if targetInSource {
+		return nil, fmt.Errorf("%w: cannot merge a chunk into its own descendant", ErrInvalidChunkMerge)
+	}
+
+	contents, err := mergeContents(target.contents, source.contents, strategy)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := json.Marshal(unionStrings(target.tags, source.tags))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged tags: %w", err)
+	}
+	metadata := make(map[string]interface{}, len(target.metadata)+len(source.metadata))
+	for key, value := range source.metadata {
+		metadata[key] = value
+	}
+	for key, value := range target.metadata {
+		metadata[key] = value
+	}
+	// A sensitive source keeps its contents encrypted inside the target
+	if IsSensitive(source.metadata) {
+		metadata[SensitiveMetadataKey] = true
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged metadata: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE chunks SET contents = $2, lang = $3, tags = $4::jsonb, metadata = $5::jsonb, ref = $6,
+			last_updated = NOW(), version = version + 1
+		WHERE chunk_id = $1`,
+		targetID, contents, DetectLanguage(contents), string(tags), string(metadataJSON),
+		mergeRefs(target.ref, source.ref, targetID, sourceID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update merged chunk: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunk_tags (source_chunk_id, tag_chunk_id)
+		SELECT $1::uuid, tag_chunk_id FROM chunk_tags WHERE source_chunk_id = $2::uuid
+		ON CONFLICT DO NOTHING`,
+		targetID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge tag relationships: %w", err)
+	}
+
+	if result.ReparentedChunks, err = reparentMergedChildrenTx(ctx, tx, target, source, targetID, sourceID); err != nil {
+		return nil, err
+	}
+	if result.RedirectedRefs, err = redirectMergedRefsTx(ctx, tx, targetID, sourceID); err != nil {
+		return nil, err
+	}
+
+	hasGraph, err := snapshotTableExists(ctx, tx, "graph_nodes")
+	if err != nil {
+		return nil, err
+	}
+	if hasGraph {
+		moved, err := tx.ExecContext(ctx, "UPDATE graph_nodes SET chunk_id = $1 WHERE chunk_id = $2", targetID, sourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move graph nodes: %w", err)
+		}
+		if n, err := moved.RowsAffected(); err == nil {
+			result.GraphNodes = int(n)
+		}
+	}
+
+	if !strategy.Permanent {
+		result.TrashID = uuid.New().String()
+		if err = trashSubtreeTx(ctx, tx, result.TrashID, sourceID, []string{sourceID}); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM chunks WHERE chunk_id = $1", sourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete merged chunk: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.invalidateSubtreeCaches(ctx)
+	s.cache.DeletePattern(ctx, "chunk_tags:*")
+	s.cache.Delete(ctx, fmt.Sprintf("chunk_backlinks:%s", targetID))
+	s.cache.Delete(ctx, fmt.Sprintf("chunk_backlinks:%s", sourceID))
+
+	if result.Chunk, err = s.GetChunk(ctx, targetID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadMergeChunkTx reads the fields of a chunk a merge reconciles
+func loadMergeChunkTx(ctx context.Context, tx *sql.Tx, chunkID string) (*mergeChunkRow, error) {
+	var row mergeChunkRow
+	var tags, metadata []byte
+	err := tx.QueryRowContext(ctx, `
+		SELECT contents, page::text, COALESCE(is_page, false), COALESCE(is_tag, false),
+			COALESCE(is_template, false), COALESCE(is_slot, false), ref, tags, metadata
+		FROM chunks WHERE chunk_id = $1`, chunkID).Scan(
+		&row.contents, &row.page, &row.isPage, &row.isTag, &row.isTemplate, &row.isSlot,
+		&row.ref, &tags, &metadata)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk %s: %w", chunkID, err)
+	}
+	if len(tags) > 0 {
+		if err := json.Unmarshal(tags, &row.tags); err != nil {
+			return nil, fmt.Errorf("failed to parse tags of chunk %s: %w", chunkID, err)
+		}
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &row.metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata of chunk %s: %w", chunkID, err)
+		}
+	}
+	return &row, nil
+}
+
+// mergeContents combines the contents of two chunks by the strategy. Encrypted contents
+// are opaque here, so they can only be kept or moved whole.
+func mergeContents(target, source string, strategy models.ChunkMergeStrategy) (string, error) {
+	if strategy.ResolvedContents != nil {
+		return *strategy.ResolvedContents, nil
+	}
+	switch strategy.Contents {
+	case models.MergeContentsReplace:
+		return source, nil
+	case models.MergeContentsKeepTarget:
+		return target, nil
+	}
+
+	if IsEncryptedContent(target) || IsEncryptedContent(source) {
+		return "", fmt.Errorf("%w: encrypted contents cannot be concatenated without the content cipher", ErrInvalidChunkMerge)
+	}
+	separator := strategy.Separator
+	if separator == "" {
+		separator = defaultMergeSeparator
+	}
+	switch {
+	case strings.TrimSpace(source) == "":
+		return target, nil
+	case strings.TrimSpace(target) == "":
+		return source, nil
+	}
+	return target + separator + source, nil
+}
+
+// unionStrings returns a followed by the values of b not in a
+func unionStrings(a, b []string) []string {
+	return uniqueNonEmpty(append(append([]string{}, a...), b...))
+}
+
+// mergeRefs combines the ref values of a merged pair, dropping references between the two
+func mergeRefs(targetRef, sourceRef sql.NullString, targetID, sourceID string) *string {
+	var fields []string
+	for _, ref := range []struct {
+		value sql.NullString
+		self  string
+		other string
+	}{{targetRef, targetID, sourceID}, {sourceRef, sourceID, targetID}} {
+		if !ref.value.Valid {
+			continue
+		}
+		rewritten := rewriteRefTarget(ref.value.String, ref.other, "")
+		if rewritten != nil {
+			rewritten = rewriteRefTarget(*rewritten, ref.self, "")
+		}
+		if rewritten != nil {
+			fields = append(fields, strings.Split(*rewritten, ", ")...)
+		}
+	}
+
+	// Both refs may name the same third chunk
+	fields = uniqueNonEmpty(fields)
+	if len(fields) == 0 {
+		return nil
+	}
+	merged := strings.Join(fields, ", ")
+	return &merged
+}
+
+// reparentMergedChildrenTx moves the source's children under the target. When the source
+// is a page, chunks on it move onto the target's page.
+func reparentMergedChildrenTx(ctx context.Context, tx *sql.Tx, target, source *mergeChunkRow, targetID, sourceID string) (int, error) {
+	var children pq.StringArray
+	err := tx.QueryRowContext(ctx, `
+		WITH moved AS (
+			UPDATE chunks SET parent = $1, last_updated = NOW(), version = version + 1 WHERE parent = $2 RETURNING chunk_id
+		)
+		SELECT COALESCE(array_agg(chunk_id), '{}') FROM moved`,
+		targetID, sourceID).Scan(&children)
+	if err != nil {
+		return 0, fmt.Errorf("failed to move children of merged chunk: %w", err)
+	}
+	if len(children) == 0 {
+		return 0, nil
+	}
+
+	if source.isPage {
+		newPage := target.page
+		if target.isPage {
+			newPage = sql.NullString{String: targetID, Valid: true}
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE chunks SET page = $1::uuid, last_updated = NOW(), version = version + 1 WHERE page = $2",
+			newPage, sourceID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to move chunks onto the merged page: %w", err)
+		}
+	} else {
+		for _, childID := range children {
+			if err = reassignSubtreePageTx(ctx, tx, childID, targetID); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if _, err = rebuildSubtreeHierarchyTx(ctx, tx, children); err != nil {
+		return 0, err
+	}
+	return len(children), nil
+}
+
+// redirectMergedRefsTx points references to the source at the target and gives the target
+// the source's outgoing references. It returns the number of chunks whose ref was rewritten.
+func redirectMergedRefsTx(ctx context.Context, tx *sql.Tx, targetID, sourceID string) (int, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT c.chunk_id, COALESCE(c.ref, '')
+		FROM chunks c
+		JOIN chunk_refs cr ON c.chunk_id = cr.source_chunk_id
+		WHERE cr.target_chunk_id = $1::uuid AND c.chunk_id NOT IN ($1::uuid, $2::uuid)
+		FOR UPDATE OF c`, sourceID, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query referencing chunks: %w", err)
+	}
+	refs := make(map[string]string)
+	for rows.Next() {
+		var chunkID, ref string
+		if err := rows.Scan(&chunkID, &ref); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan referencing chunk: %w", err)
+		}
+		refs[chunkID] = ref
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating referencing chunks: %w", err)
+	}
+
+	for chunkID, ref := range refs {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE chunks SET ref = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
+			rewriteRefTarget(ref, sourceID, targetID), chunkID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to rewrite ref for chunk %s: %w", chunkID, err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunk_refs (source_chunk_id, target_chunk_id)
+		SELECT source_chunk_id, $1::uuid FROM chunk_refs
+		WHERE target_chunk_id = $2::uuid AND source_chunk_id NOT IN ($1::uuid, $2::uuid)
+		UNION
+		SELECT $1::uuid, target_chunk_id FROM chunk_refs
+		WHERE source_chunk_id = $2::uuid AND target_chunk_id NOT IN ($1::uuid, $2::uuid)
+		ON CONFLICT DO NOTHING`,
+		targetID, sourceID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to redirect references: %w", err)
+	}
+	_, err = tx.ExecContext(ctx,
+		"DELETE FROM chunk_refs WHERE target_chunk_id = $1 OR (source_chunk_id = $2 AND target_chunk_id = $1)",
+		sourceID, targetID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove references to merged chunk: %w", err)
+	}
+
+	return len(refs), nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func TestMergeContents_Strategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		source   string
+		strategy models.ChunkMergeStrategy
+		want     string
+	}{
+		{"concatenate", "first", "second", models.ChunkMergeStrategy{Contents: models.MergeContentsConcatenate}, "first\n\nsecond"},
+		{"separator", "first", "second", models.ChunkMergeStrategy{Contents: models.MergeContentsConcatenate, Separator: " / "}, "first / second"},
+		{"empty source", "first", "  ", models.ChunkMergeStrategy{Contents: models.MergeContentsConcatenate}, "first"},
+		{"empty target", "", "second", models.ChunkMergeStrategy{Contents: models.MergeContentsConcatenate}, "second"},
+		{"replace", "first", "second", models.ChunkMergeStrategy{Contents: models.MergeContentsReplace}, "second"},
+		{"keep target", "first", "second", models.ChunkMergeStrategy{Contents: models.MergeContentsKeepTarget}, "first"},
+		{"encrypted replace", "first", "enc:v1:abc", models.ChunkMergeStrategy{Contents: models.MergeContentsReplace}, "enc:v1:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeContents(tt.target, tt.source, tt.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	resolved := "resolved"
+	got, err := mergeContents("first", "second", models.ChunkMergeStrategy{Contents: models.MergeContentsConcatenate, ResolvedContents: &resolved})
+	require.NoError(t, err)
+	assert.Equal(t, resolved, got)
+
+	_, err = mergeContents("first", "enc:v1:abc", models.ChunkMergeStrategy{Contents: models.MergeContentsConcatenate})
+	assert.ErrorIs(t, err, ErrInvalidChunkMerge)
+}
+
+func TestMergeRefs_DropsReferencesBetweenMergedChunks(t *testing.T) {
+	target := "9f0d5a8e-1c2b-4e3f-8a7b-6c5d4e3f2a10"
+	source := "1a2b3c4d-5e6f-4a8b-9c0d-1e2f3a4b5c6d"
+	other := "7e8f9a0b-1c2d-4e4f-8a6b-7c8d9e0f1a2b"
+
+	ref := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+
+	merged := mergeRefs(ref(source+", "+other), ref(target+"; "+other), target, source)
+	require.NotNil(t, merged)
+	assert.Equal(t, other, *merged)
+
+	assert.Nil(t, mergeRefs(ref(source), sql.NullString{}, target, source))
+	assert.Equal(t, []string{"a", "b", "c"}, unionStrings([]string{"a", "b"}, []string{"c", "a"}))
+}
+
+func TestMergeChunks_RejectsInvalidRequests(t *testing.T) {
+	// The service has no database; validation must fail before it is needed
+	service := &unifiedChunkService{monitor: NewNoOpMonitor()}
+	ctx := context.Background()
+
+	_, err := service.MergeChunks(ctx, "", "chunk-2", models.ChunkMergeStrategy{})
+	assert.ErrorIs(t, err, ErrInvalidChunkMerge)
+	_, err = service.MergeChunks(ctx, "chunk-1", "chunk-1", models.ChunkMergeStrategy{})
+	assert.ErrorIs(t, err, ErrInvalidChunkMerge)
+	_, err = service.MergeChunks(ctx, "chunk-1", "chunk-2", models.ChunkMergeStrategy{Contents: "interleave"})
+	assert.ErrorIs(t, err, ErrInvalidChunkMerge)
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"semantic-text-processor/models"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// SUBTREE MOVE AND COPY OPERATIONS IMPLEMENTATION
+// ============================================================================
+
+// subtreeCTE selects the given root chunks and all of their descendants by walking parent
+// pointers. It uses parent pointers rather than chunk_hierarchy so that it sees moves made
+// earlier in the same transaction.
+const subtreeCTE = `
+	subtree AS (
+		SELECT chunk_id FROM chunks WHERE chunk_id = ANY($1::uuid[])
+		UNION
+		SELECT c.chunk_id FROM chunks c JOIN subtree s ON c.parent = s.chunk_id
+	)`
+
+// MoveSubtree moves a chunk under a new parent together with all of its descendants.
+// The internal structure of the subtree is preserved; descendants that shared the moved
+// chunk's page are reassigned to the destination page.
+func (s *unifiedChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	return s.moveSubtrees(ctx, "move_subtree", []models.ChunkMove{{ChunkID: chunkID, NewParentID: newParentID}})
+}
+
+// BulkMove applies several moves atomically. Moves are applied in order, so a later move may
+// place a chunk under a parent that an earlier move relocated. The hierarchy table is rebuilt
+// once for all affected subtrees before the transaction commits.
+func (s *unifiedChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	return s.moveSubtrees(ctx, "bulk_move", moves)
+}
+
+// moveSubtrees applies moves in a single transaction and records them under the given operation
+func (s *unifiedChunkService) moveSubtrees(ctx context.Context, operation string, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	start := time.Now()
+	result := &models.SubtreeMoveResult{}
+	defer func() {
+		s.monitor.RecordQuery(operation, time.Since(start), result.AffectedChunks)
+	}()
+
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("at least one move is required")
+	}
+
+	chunkIDs := make([]string, 0, len(moves))
+	seen := make(map[string]bool, len(moves))
+	for _, move := range moves {
+		if move.ChunkID == "" {
+			return nil, fmt.Errorf("chunk ID is required for every move")
+		}
+		if seen[move.ChunkID] {
+			return nil, fmt.Errorf("chunk %s appears in more than one move", move.ChunkID)
+		}
+		seen[move.ChunkID] = true
+		chunkIDs = append(chunkIDs, move.ChunkID)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Lock the moved chunks in a stable order so concurrent reorganizations serialize
+	_, err = tx.ExecContext(ctx,
+		"SELECT chunk_id FROM chunks WHERE chunk_id = ANY($1::uuid[]) ORDER BY chunk_id FOR UPDATE",
+		pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock chunks: %w", err)
+	}
+
+	for _, move := range moves {
+		if err = validateMoveTx(ctx, tx, move.ChunkID, move.NewParentID); err != nil {
+			return nil, err
+		}
+
+		var parentPtr *string
+		if move.NewParentID != "" {
+			parentPtr = &move.NewParentID
+		}
+		_, err = tx.ExecContext(ctx,
+			"UPDATE chunks SET parent = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
+			parentPtr, move.ChunkID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update chunk parent: %w", err)
+		}
+
+		if err = reassignSubtreePageTx(ctx, tx, move.ChunkID, move.NewParentID); err != nil {
+			return nil, err
+		}
+	}
+
+	affected, err := rebuildSubtreeHierarchyTx(ctx, tx, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.MovedChunkIDs = chunkIDs
+	result.AffectedChunks = affected
+	s.invalidateSubtreeCaches(ctx)
+
+	return result, nil
+}
+
+// subtreeRow is a chunk of a subtree being copied
+type subtreeRow struct {
+	chunkID string
+	parent  sql.NullString
+	page    sql.NullString
+	isPage  bool
+	isTag   bool
+	ref     sql.NullString
+}
+
+// CopySubtree deep copies a chunk and all of its descendants under a new parent. Every copy
+// gets a new ID; parents, pages and Ref values pointing inside the subtree are remapped to
+// the copies, while tags and references to chunks outside the subtree are kept as-is.
+func (s *unifiedChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	start := time.Now()
+	result := &models.SubtreeCopyResult{SourceChunkID: chunkID}
+	defer func() {
+		s.monitor.RecordQuery("copy_subtree", time.Since(start), result.CopiedChunks)
+	}()
+
+	if chunkID == "" {
+		return nil, fmt.Errorf("chunk ID is required")
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Copying a subtree into itself is allowed: the source is read before any copy is inserted
+	targetPage := sql.NullString{}
+	if newParentID != "" {
+		err = tx.QueryRowContext(ctx,
+			"SELECT CASE WHEN is_page THEN chunk_id::text ELSE page::text END FROM chunks WHERE chunk_id = $1",
+			newParentID).Scan(&targetPage)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("new parent chunk not found: %s", newParentID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to validate new parent chunk: %w", err)
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT chunk_id, 0 AS depth FROM chunks WHERE chunk_id = $1
+			UNION ALL
+			SELECT c.chunk_id, t.depth + 1 FROM chunks c JOIN tree t ON c.parent = t.chunk_id
+			WHERE t.depth < 100
+		)
+		SELECT c.chunk_id, c.parent, c.page, COALESCE(c.is_page, false), COALESCE(c.is_tag, false), c.ref
+		FROM tree t JOIN chunks c ON c.chunk_id = t.chunk_id
+		ORDER BY t.depth, c.created_time`, chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtree: %w", err)
+	}
+	var subtree []subtreeRow
+	for rows.Next() {
+		var row subtreeRow
+		if err := rows.Scan(&row.chunkID, &row.parent, &row.page, &row.isPage, &row.isTag, &row.ref); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan subtree chunk: %w", err)
+		}
+		subtree = append(subtree, row)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subtree: %w", err)
+	}
+	if len(subtree) == 0 {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+
+	mapping := make(map[string]string, len(subtree))
+	for _, row := range subtree {
+		if row.isTag {
+			return nil, fmt.Errorf("cannot copy subtree containing tag chunk %s: tag names must stay unique", row.chunkID)
+		}
+		mapping[row.chunkID] = uuid.New().String()
+	}
+
+	root := subtree[0]
+	for _, row := range subtree {
+		newID := mapping[row.chunkID]
+
+		var parent *string
+		if row.chunkID == root.chunkID {
+			if newParentID != "" {
+				parent = &newParentID
+			}
+		} else if mapped, ok := mapping[row.parent.String]; ok {
+			parent = &mapped
+		}
+
+		page := copiedPage(row, root, mapping, targetPage, newParentID != "")
+		ref := copiedRef(row.ref, mapping)
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chunks (
+				chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
+				ref, tags, metadata, created_time, last_updated, lang
+			)
+			SELECT $2, contents, $3, $4, is_page, is_tag, is_template, is_slot,
+				$5, tags, metadata, NOW(), NOW(), lang
+			FROM chunks WHERE chunk_id = $1`,
+			row.chunkID, newID, parent, page, ref)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy chunk %s: %w", row.chunkID, err)
+		}
+
+		// Keep the auxiliary tag table in step even without triggers
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO chunk_tags (source_chunk_id, tag_chunk_id)
+			SELECT $2::uuid, tag_chunk_id FROM chunk_tags WHERE source_chunk_id = $1
+			ON CONFLICT DO NOTHING`,
+			row.chunkID, newID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to copy tags of chunk %s: %w", row.chunkID, err)
+		}
+	}
+
+	if _, err = rebuildSubtreeHierarchyTx(ctx, tx, []string{mapping[root.chunkID]}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.RootChunkID = mapping[root.chunkID]
+	result.IDMapping = mapping
+	result.CopiedChunks = len(mapping)
+	s.invalidateSubtreeCaches(ctx)
+
+	return result, nil
+}
+
+// copiedPage returns the page of a copied chunk. Pages inside the subtree map to their copies;
+// chunks that shared the root's page follow the root to the destination page.
+func copiedPage(row, root subtreeRow, mapping map[string]string, targetPage sql.NullString, hasParent bool) *string {
+	if row.page.Valid {
+		if mapped, ok := mapping[row.page.String]; ok {
+			return &mapped
+		}
+	}
+	if hasParent && !root.isPage && row.page == root.page && targetPage.Valid {
+		page := targetPage.String
+		return &page
+	}
+	if row.page.Valid {
+		page := row.page.String
+		return &page
+	}
+	return nil
+}
+
+// copiedRef remaps references to chunks inside the copied subtree
+func copiedRef(ref sql.NullString, mapping map[string]string) *string {
+	if !ref.Valid {
+		return nil
+	}
+	value := &ref.String
+	for _, target := range ParseRefTargets(ref.String) {
+		if mapped, ok := mapping[target]; ok {
+			value = rewriteRefTarget(*value, target, mapped)
+		}
+	}
+	return value
+}
+
+// validateMoveTx checks that a chunk and its new parent exist and that the move would not
+// place the chunk inside its own subtree
+func validateMoveTx(ctx context.Context, tx *sql.Tx, chunkID, newParentID string) error {
+	var exists bool
+	err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", chunkID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to validate chunk: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("chunk not found: %s", chunkID)
+	}
+
+	if newParentID == "" {
+		return nil
+	}
+	if newParentID == chunkID {
+		return fmt.Errorf("cannot move chunk %s under itself: circular reference detected", chunkID)
+	}
+
+	// Walk up from the new parent; meeting the moved chunk means the parent is its descendant
+	var parentExists, isDescendant bool
+	err = tx.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT chunk_id, parent, 0 AS depth FROM chunks WHERE chunk_id = $2
+			UNION ALL
+			SELECT c.chunk_id, c.parent, a.depth + 1 FROM chunks c JOIN ancestors a ON c.chunk_id = a.parent
+			WHERE a.depth < 100
+		)
+		SELECT EXISTS(SELECT 1 FROM ancestors), EXISTS(SELECT 1 FROM ancestors WHERE chunk_id = $1)`,
+		chunkID, newParentID).Scan(&parentExists, &isDescendant)
+	if err != nil {
+		return fmt.Errorf("failed to check for circular reference: %w", err)
+	}
+	if !parentExists {
+		return fmt.Errorf("new parent chunk not found: %s", newParentID)
+	}
+	if isDescendant {
+		return fmt.Errorf("cannot move chunk %s to its own descendant: circular reference detected", chunkID)
+	}
+
+	return nil
+}
+
+// reassignSubtreePageTx moves the chunks of a subtree that belonged to the root's old page onto
+// the page of the new parent. Page chunks keep their own descendants, so nested pages and the
+// chunks under them are left alone.
+func reassignSubtreePageTx(ctx context.Context, tx *sql.Tx, rootID, newParentID string) error {
+	if newParentID == "" {
+		return nil
+	}
+
+	var rootIsPage bool
+	var oldPage, newPage sql.NullString
+	err := tx.QueryRowContext(ctx, `
+		SELECT COALESCE(r.is_page, false), r.page::text,
+			(SELECT CASE WHEN p.is_page THEN p.chunk_id::text ELSE p.page::text END FROM chunks p WHERE p.chunk_id = $2)
+		FROM chunks r WHERE r.chunk_id = $1`,
+		rootID, newParentID).Scan(&rootIsPage, &oldPage, &newPage)
+	if err != nil {
+		return fmt.Errorf("failed to resolve destination page: %w", err)
+	}
+	if rootIsPage || !newPage.Valid || oldPage == newPage {
+		return nil
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH RECURSIVE`+subtreeCTE+`
+		UPDATE chunks SET page = $3::uuid, last_updated = NOW(), version = version + 1
+		WHERE chunk_id IN (SELECT chunk_id FROM subtree) AND page IS NOT DISTINCT FROM $2::uuid`,
+		pq.Array([]string{rootID}), oldPage, newPage.String)
+	if err != nil {
+		return fmt.Errorf("failed to reassign subtree page: %w", err)
+	}
+
+	return nil
+}
+
+// rebuildSubtreeHierarchyTx recomputes chunk_hierarchy rows for every chunk in the given
+// subtrees and returns the number of chunks covered
+func rebuildSubtreeHierarchyTx(ctx context.Context, tx *sql.Tx, rootIDs []string) (int, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE`+subtreeCTE+`
+		SELECT COUNT(*) FROM subtree`,
+		pq.Array(rootIDs)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count subtree chunks: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		WITH RECURSIVE`+subtreeCTE+`
+		DELETE FROM chunk_hierarchy WHERE descendant_id IN (SELECT chunk_id FROM subtree)`,
+		pq.Array(rootIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear subtree hierarchy: %w", err)
+	}
+
+	if err = insertSubtreeHierarchyTx(ctx, tx, rootIDs); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// invalidateSubtreeCaches drops caches affected by moving or copying whole subtrees.
+// Descendants may change page, so per-chunk entries are cleared wholesale.
+func (s *unifiedChunkService) invalidateSubtreeCaches(ctx context.Context) {
+	s.markWrite()
+
+	patterns := []string{
+		"chunk:*",
+		"chunk_children:*",
+		"chunk_descendants:*",
+		"chunk_ancestors:*",
+		"chunks_by_tag:*",
+		"chunks_by_tags:*",
+	}
+
+	for _, pattern := range patterns {
+		s.cache.DeletePattern(ctx, pattern)
+	}
+}
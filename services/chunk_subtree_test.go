@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	subtreePageA   = "11111111-1111-1111-1111-111111111111"
+	subtreePageB   = "22222222-2222-2222-2222-222222222222"
+	subtreeRootID  = "33333333-3333-3333-3333-333333333333"
+	subtreeChildID = "44444444-4444-4444-4444-444444444444"
+	subtreeOutside = "55555555-5555-5555-5555-555555555555"
+)
+
+func validNullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: true}
+}
+
+func TestCopiedPage(t *testing.T) {
+	root := subtreeRow{chunkID: subtreeRootID, page: validNullString(subtreePageA)}
+	mapping := map[string]string{subtreeRootID: "root-copy", subtreeChildID: "child-copy"}
+
+	t.Run("chunks on the root page follow the copy to the destination page", func(t *testing.T) {
+		row := subtreeRow{chunkID: subtreeChildID, page: validNullString(subtreePageA)}
+		page := copiedPage(row, root, mapping, validNullString(subtreePageB), true)
+		require.NotNil(t, page)
+		assert.Equal(t, subtreePageB, *page)
+	})
+
+	t.Run("pages inside the subtree map to their copies", func(t *testing.T) {
+		row := subtreeRow{chunkID: "grandchild", page: validNullString(subtreeChildID)}
+		page := copiedPage(row, root, mapping, validNullString(subtreePageB), true)
+		require.NotNil(t, page)
+		assert.Equal(t, "child-copy", *page)
+	})
+
+	t.Run("top level copy keeps the original page", func(t *testing.T) {
+		row := subtreeRow{chunkID: subtreeChildID, page: validNullString(subtreePageA)}
+		page := copiedPage(row, root, mapping, sql.NullString{}, false)
+		require.NotNil(t, page)
+		assert.Equal(t, subtreePageA, *page)
+	})
+
+	t.Run("chunks without a page stay without one", func(t *testing.T) {
+		pageless := subtreeRow{chunkID: subtreeRootID}
+		assert.Nil(t, copiedPage(pageless, pageless, mapping, sql.NullString{}, false))
+	})
+}
+
+func TestCopiedRef(t *testing.T) {
+	mapping := map[string]string{
+		subtreeChildID: "66666666-6666-6666-6666-666666666666",
+	}
+
+	assert.Nil(t, copiedRef(sql.NullString{}, mapping))
+
+	ref := copiedRef(validNullString("(("+subtreeChildID+")), [["+subtreeOutside+"]]"), mapping)
+	require.NotNil(t, ref)
+	assert.Equal(t, "((66666666-6666-6666-6666-666666666666)), [["+subtreeOutside+"]]", *ref)
+
+	unchanged := copiedRef(validNullString(subtreeOutside), mapping)
+	require.NotNil(t, unchanged)
+	assert.Equal(t, subtreeOutside, *unchanged)
+}
+
+func TestBulkMove_ValidatesMoves(t *testing.T) {
+	svc := NewUnifiedChunkService(nil, nil, NewNoOpMonitor())
+	ctx := context.Background()
+
+	_, err := svc.BulkMove(ctx, nil)
+	assert.Error(t, err)
+
+	_, err = svc.BulkMove(ctx, []models.ChunkMove{{NewParentID: subtreePageA}})
+	assert.Error(t, err)
+
+	_, err = svc.BulkMove(ctx, []models.ChunkMove{
+		{ChunkID: subtreeRootID, NewParentID: subtreePageA},
+		{ChunkID: subtreeRootID, NewParentID: subtreePageB},
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "more than one move")
+}
@@ -0,0 +1,805 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// OFFLINE SYNC
+// ============================================================================
+//
+// Offline clients pull the chunks changed after their cursor, a position in
+// chunk_sync_log, and push the operations they made while offline. A pushed update is
+// merged field by field: fields nobody else changed since the client's base version are
+// written as sent; for fields also changed on the server the later write wins, by the
+// client's timestamp against the server's change time, and the losing value is kept as a
+// conflict for manual resolution. Writes go through the chunk service, so hierarchy,
+// caches and encryption stay consistent.
+
+// ErrSyncConflictNotFound is matched by errors.Is when a conflict does not exist
+var ErrSyncConflictNotFound = errors.New("sync conflict not found")
+
+// ErrSyncConflictResolved is matched by errors.Is when a conflict was already resolved
+var ErrSyncConflictResolved = errors.New("sync conflict already resolved")
+
+// ErrInvalidSyncResolution is matched by errors.Is when a resolution cannot apply to a conflict
+var ErrInvalidSyncResolution = errors.New("invalid sync conflict resolution")
+
+// ChunkSyncService serves change logs to offline clients and merges their edits
+type ChunkSyncService interface {
+	// Pull returns the latest change of each chunk changed after cursor, oldest first
+	Pull(ctx context.Context, cursor int64, limit int) (*models.SyncPullResult, error)
+	// Push applies a client's operations in order and reports each outcome
+	Push(ctx context.Context, req *models.SyncPushRequest) (*models.SyncPushResult, error)
+	// ListConflicts returns conflicts with status ("open", "resolved" or "" for both),
+	// optionally for one chunk, newest first
+	ListConflicts(ctx context.Context, chunkID, status string, limit int) ([]models.SyncConflict, error)
+	// ResolveConflict settles a conflict, writing the chosen value when it is not the
+	// one already kept
+	ResolveConflict(ctx context.Context, conflictID string, resolution *models.SyncConflictResolution) (*models.SyncConflict, error)
+}
+
+// chunkSyncService implements ChunkSyncService
+type chunkSyncService struct {
+	db      *sql.DB
+	chunks  UnifiedChunkService
+	monitor QueryPerformanceMonitor
+	// settleDelay holds back log entries this young from pulls. Sequence numbers are taken
+	// when a row is written but become visible at commit, so a younger entry may still be
+	// followed by a lower sequence number from a transaction that has not committed yet.
+	settleDelay time.Duration
+}
+
+const (
+	defaultSyncPullLimit = 500
+	maxSyncPullLimit     = 5000
+	maxSyncMergeAttempts = 3
+	defaultSyncSettle    = 2 * time.Second
+)
+
+// syncFields are the chunk fields clients may write, in the order they are merged
+var syncFields = []string{"contents", "parent", "page", "is_page", "is_tag", "is_template", "is_slot", "ref", "tags", "metadata"}
+
+// NewChunkSyncService creates a sync service that writes through chunks
+func NewChunkSyncService(db *sql.DB, chunks UnifiedChunkService, monitor QueryPerformanceMonitor) ChunkSyncService {
+	return &chunkSyncService{db: db, chunks: chunks, monitor: monitor, settleDelay: defaultSyncSettle}
+}
+
+// Pull coalesces the log after cursor into one change per chunk with its current state
+func (s *chunkSyncService) Pull(ctx context.Context, cursor int64, limit int) (*models.SyncPullResult, error) {
+	start := time.Now()
+	result := &models.SyncPullResult{Changes: []models.SyncChange{}, Cursor: cursor}
+	defer func() {
+		s.monitor.RecordQuery("sync_pull", time.Since(start), len(result.Changes))
+	}()
+
+	if limit <= 0 {
+		limit = defaultSyncPullLimit
+	}
+	limit = min(limit, maxSyncPullLimit)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT seq, chunk_id, op, version, fields, changed_at
+		FROM chunk_sync_log
+		WHERE seq > $1 AND changed_at < clock_timestamp() - make_interval(secs => $2)
+		ORDER BY seq
+		LIMIT $3`,
+		cursor, s.settleDelay.Seconds(), limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync log: %w", err)
+	}
+	defer rows.Close()
+
+	byChunk := make(map[string]*models.SyncChange)
+	var order []string
+	entries := 0
+	for rows.Next() {
+		entries++
+		if entries > limit {
+			result.HasMore = true
+			break
+		}
+
+		var seq, version int64
+		var chunkID, op string
+		var fields pq.StringArray
+		var changedAt time.Time
+		if err := rows.Scan(&seq, &chunkID, &op, &version, &fields, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sync log entry: %w", err)
+		}
+
+		change, ok := byChunk[chunkID]
+		if !ok {
+			change = &models.SyncChange{ChunkID: chunkID}
+			byChunk[chunkID] = change
+		} else {
+			order = removeString(order, chunkID)
+		}
+		order = append(order, chunkID)
+
+		change.Sequence = seq
+		change.Version = version
+		change.ChangedAt = changedAt
+		change.Deleted = op == "delete"
+		change.Fields = mergeFieldNames(change.Fields, fields)
+		result.Cursor = seq
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync log: %w", err)
+	}
+	rows.Close()
+
+	changes := make([]*models.SyncChange, len(order))
+	for i, chunkID := range order {
+		changes[i] = byChunk[chunkID]
+	}
+	if result.Changes, err = s.attachChunks(ctx, changes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// attachChunks adds the current state of each changed chunk. Changes to chunks the caller
+// may not read are left out, so they do not stop the pull.
+func (s *chunkSyncService) attachChunks(ctx context.Context, changes []*models.SyncChange) ([]models.SyncChange, error) {
+	attached := make([]models.SyncChange, 0, len(changes))
+	for _, change := range changes {
+		if !change.Deleted {
+			chunk, err := s.chunks.GetChunk(ctx, change.ChunkID)
+			if errors.Is(err, ErrPermissionDenied) {
+				continue
+			}
+			if err != nil {
+				exists, existsErr := s.chunkExists(ctx, change.ChunkID)
+				if existsErr != nil || exists {
+					return nil, fmt.Errorf("failed to get changed chunk %s: %w", change.ChunkID, err)
+				}
+				// Deleted after the pulled entries; its delete entry follows
+				change.Deleted = true
+			}
+			change.Chunk = chunk
+		}
+		attached = append(attached, *change)
+	}
+	return attached, nil
+}
+
+// Push applies operations in order. An operation that fails does not stop the others;
+// its result says why.
+func (s *chunkSyncService) Push(ctx context.Context, req *models.SyncPushRequest) (*models.SyncPushResult, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("sync_push", time.Since(start), len(req.Operations))
+	}()
+
+	if req.ClientID == "" {
+		return nil, fmt.Errorf("client_id is required")
+	}
+
+	result := &models.SyncPushResult{Results: make([]models.SyncOperationResult, 0, len(req.Operations))}
+	for i := range req.Operations {
+		opResult, err := s.pushOperation(ctx, req.ClientID, &req.Operations[i])
+		if err != nil {
+			return nil, err
+		}
+		result.Results = append(result.Results, *opResult)
+	}
+
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM chunk_sync_log").Scan(&result.Cursor); err != nil {
+		return nil, fmt.Errorf("failed to get sync cursor: %w", err)
+	}
+	return result, nil
+}
+
+// pushOperation applies one operation once. Only errors reaching the sync tables are
+// returned; failures of the operation itself are reported in its result.
+func (s *chunkSyncService) pushOperation(ctx context.Context, clientID string, op *models.SyncOperation) (*models.SyncOperationResult, error) {
+	result := &models.SyncOperationResult{OpID: op.OpID, ChunkID: op.ChunkID}
+	reject := func(err error) (*models.SyncOperationResult, error) {
+		result.Status = models.SyncStatusRejected
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if err := validateSyncOperation(op); err != nil {
+		return reject(err)
+	}
+
+	var previous []byte
+	err := s.db.QueryRowContext(ctx,
+		"SELECT result FROM chunk_sync_ops WHERE client_id = $1 AND op_id = $2",
+		clientID, op.OpID).Scan(&previous)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(previous, result); err != nil {
+			return nil, fmt.Errorf("failed to decode applied operation %s: %w", op.OpID, err)
+		}
+		result.Status = models.SyncStatusDuplicate
+		return result, nil
+	case err != sql.ErrNoRows:
+		return nil, fmt.Errorf("failed to check applied operations: %w", err)
+	}
+
+	if op.Timestamp.IsZero() {
+		op.Timestamp = time.Now()
+	}
+
+	var applyErr error
+	switch op.Op {
+	case models.SyncOperationCreate:
+		applyErr = s.pushCreate(ctx, op, result)
+	case models.SyncOperationUpdate:
+		applyErr = s.pushUpdate(ctx, clientID, op, result)
+	case models.SyncOperationDelete:
+		applyErr = s.pushDelete(ctx, clientID, op, result)
+	}
+	if applyErr != nil {
+		// Rejected operations are not recorded, so that a retry can still apply them
+		return reject(applyErr)
+	}
+
+	recorded, err := json.Marshal(result)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode operation result: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO chunk_sync_ops (client_id, op_id, chunk_id, result) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (client_id, op_id) DO NOTHING`,
+		clientID, op.OpID, op.ChunkID, recorded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record applied operation: %w", err)
+	}
+	return result, nil
+}
+
+func validateSyncOperation(op *models.SyncOperation) error {
+	if op.OpID == "" {
+		return fmt.Errorf("op_id is required")
+	}
+	if _, err := uuid.Parse(op.ChunkID); err != nil {
+		return fmt.Errorf("chunk_id must be a UUID")
+	}
+	switch op.Op {
+	case models.SyncOperationCreate, models.SyncOperationUpdate:
+		if len(op.Fields) == 0 {
+			return fmt.Errorf("%s needs fields", op.Op)
+		}
+	case models.SyncOperationDelete:
+	default:
+		return fmt.Errorf("unknown operation %q", op.Op)
+	}
+	for field := range op.Fields {
+		if !isSyncField(field) {
+			return fmt.Errorf("unknown field %q", field)
+		}
+	}
+	return nil
+}
+
+// pushCreate creates the chunk with the client's ID
+func (s *chunkSyncService) pushCreate(ctx context.Context, op *models.SyncOperation, result *models.SyncOperationResult) error {
+	exists, err := s.chunkExists(ctx, op.ChunkID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("chunk %s already exists", op.ChunkID)
+	}
+
+	patch := &models.ChunkPatch{}
+	for field, raw := range op.Fields {
+		if err := setSyncPatchField(patch, field, raw); err != nil {
+			return err
+		}
+	}
+	chunk := chunkFromSyncPatch(op.ChunkID, patch)
+	if err := s.chunks.CreateChunk(ctx, chunk); err != nil {
+		return err
+	}
+
+	result.Status = models.SyncStatusApplied
+	result.Version = chunk.Version
+	return nil
+}
+
+// syncFieldConflict is a conflict found while merging, stored once the merge is written
+type syncFieldConflict struct {
+	field     string
+	winner    string
+	kept      json.RawMessage
+	discarded json.RawMessage
+}
+
+// pushUpdate merges the operation's fields with the chunk's current state, retrying when
+// another writer gets in between
+func (s *chunkSyncService) pushUpdate(ctx context.Context, clientID string, op *models.SyncOperation, result *models.SyncOperationResult) error {
+	for attempt := 0; attempt < maxSyncMergeAttempts; attempt++ {
+		current, err := s.chunks.GetChunk(ctx, op.ChunkID)
+		if err != nil {
+			exists, existsErr := s.chunkExists(ctx, op.ChunkID)
+			if existsErr != nil || exists {
+				return err
+			}
+			// Edited offline, deleted on the server: the delete stands
+			fields, _ := json.Marshal(op.Fields)
+			return s.recordOperationConflict(ctx, clientID, op, result, nil, fields)
+		}
+
+		changedSince, err := s.fieldsChangedSince(ctx, op.ChunkID, op.BaseVersion)
+		if err != nil {
+			return err
+		}
+
+		patch := &models.ChunkPatch{ExpectedVersion: current.Version}
+		var conflicts []syncFieldConflict
+		fields := 0
+		for _, field := range syncFields {
+			raw, ok := op.Fields[field]
+			if !ok {
+				continue
+			}
+			serverValue, err := syncFieldValue(current, field)
+			if err != nil {
+				return err
+			}
+			if sameSyncValue(serverValue, raw) {
+				continue
+			}
+
+			serverChangedAt, concurrent := changedSince[field]
+			switch {
+			case !concurrent:
+			case op.Timestamp.After(serverChangedAt):
+				conflicts = append(conflicts, syncFieldConflict{field: field, winner: "client", kept: raw, discarded: serverValue})
+			default:
+				conflicts = append(conflicts, syncFieldConflict{field: field, winner: "server", kept: serverValue, discarded: raw})
+				continue
+			}
+			if err := setSyncPatchField(patch, field, raw); err != nil {
+				return err
+			}
+			fields++
+		}
+
+		result.Version = current.Version
+		if fields > 0 {
+			updated, err := s.chunks.PatchChunk(ctx, op.ChunkID, patch)
+			if errors.Is(err, ErrVersionConflict) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			result.Version = updated.Version
+		}
+
+		for _, conflict := range conflicts {
+			conflictID, err := s.insertConflict(ctx, clientID, op, conflict)
+			if err != nil {
+				return err
+			}
+			result.ConflictIDs = append(result.ConflictIDs, conflictID)
+		}
+
+		switch {
+		case len(conflicts) > 0:
+			result.Status = models.SyncStatusConflict
+		case len(changedSince) > 0:
+			result.Status = models.SyncStatusMerged
+		default:
+			result.Status = models.SyncStatusApplied
+		}
+		return nil
+	}
+	return fmt.Errorf("chunk %s kept changing while merging; retry the push", op.ChunkID)
+}
+
+// pushDelete deletes the chunk and its descendants unless the server changed it since the
+// client's base version, in which case the server's edits stand
+func (s *chunkSyncService) pushDelete(ctx context.Context, clientID string, op *models.SyncOperation, result *models.SyncOperationResult) error {
+	current, err := s.chunks.GetChunk(ctx, op.ChunkID)
+	if err != nil {
+		exists, existsErr := s.chunkExists(ctx, op.ChunkID)
+		if existsErr != nil || exists {
+			return err
+		}
+		result.Status = models.SyncStatusApplied
+		return nil
+	}
+
+	if op.BaseVersion > 0 && current.Version > op.BaseVersion {
+		changedSince, err := s.fieldsChangedSince(ctx, op.ChunkID, op.BaseVersion)
+		if err != nil {
+			return err
+		}
+		if len(changedSince) > 0 {
+			kept, err := json.Marshal(current)
+			if err != nil {
+				return fmt.Errorf("failed to encode chunk: %w", err)
+			}
+			result.Version = current.Version
+			return s.recordOperationConflict(ctx, clientID, op, result, kept, nil)
+		}
+	}
+
+	if _, err := s.chunks.DeleteSubtree(ctx, op.ChunkID, models.SubtreeDeleteOptions{Confirm: true}); err != nil {
+		return err
+	}
+	result.Status = models.SyncStatusApplied
+	return nil
+}
+
+// recordOperationConflict keeps the server's state against a whole client operation
+func (s *chunkSyncService) recordOperationConflict(ctx context.Context, clientID string, op *models.SyncOperation, result *models.SyncOperationResult, kept, discarded json.RawMessage) error {
+	conflictID, err := s.insertConflict(ctx, clientID, op, syncFieldConflict{field: "*", winner: "server", kept: kept, discarded: discarded})
+	if err != nil {
+		return err
+	}
+	result.Status = models.SyncStatusConflict
+	result.ConflictIDs = append(result.ConflictIDs, conflictID)
+	return nil
+}
+
+func (s *chunkSyncService) insertConflict(ctx context.Context, clientID string, op *models.SyncOperation, conflict syncFieldConflict) (string, error) {
+	conflictID := uuid.New().String()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO chunk_sync_conflicts
+			(conflict_id, chunk_id, field, winner, kept_value, discarded_value, base_version, client_id, op_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`,
+		conflictID, op.ChunkID, conflict.field, conflict.winner,
+		nullableJSON(conflict.kept), nullableJSON(conflict.discarded), op.BaseVersion, clientID, op.OpID)
+	if err != nil {
+		return "", fmt.Errorf("failed to record sync conflict: %w", err)
+	}
+	return conflictID, nil
+}
+
+// fieldsChangedSince returns each field written after version with its latest change time
+func (s *chunkSyncService) fieldsChangedSince(ctx context.Context, chunkID string, version int64) (map[string]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT field, MAX(changed_at)
+		FROM chunk_sync_log, unnest(fields) AS field
+		WHERE chunk_id = $1 AND version > $2
+		GROUP BY field`,
+		chunkID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query concurrent changes: %w", err)
+	}
+	defer rows.Close()
+
+	changed := make(map[string]time.Time)
+	for rows.Next() {
+		var field string
+		var changedAt time.Time
+		if err := rows.Scan(&field, &changedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan concurrent change: %w", err)
+		}
+		changed[field] = changedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating concurrent changes: %w", err)
+	}
+	return changed, nil
+}
+
+func (s *chunkSyncService) chunkExists(ctx context.Context, chunkID string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM chunks WHERE chunk_id = $1)", chunkID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check chunk: %w", err)
+	}
+	return exists, nil
+}
+
+// ListConflicts returns recorded conflicts
+func (s *chunkSyncService) ListConflicts(ctx context.Context, chunkID, status string, limit int) ([]models.SyncConflict, error) {
+	if limit <= 0 {
+		limit = defaultSyncPullLimit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+syncConflictColumns+`
+		FROM chunk_sync_conflicts
+		WHERE ($1 = '' OR chunk_id::text = $1) AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC
+		LIMIT $3`,
+		chunkID, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query sync conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	conflicts := []models.SyncConflict{}
+	for rows.Next() {
+		conflict, err := scanSyncConflict(rows)
+		if err != nil {
+			return nil, err
+		}
+		conflicts = append(conflicts, *conflict)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sync conflicts: %w", err)
+	}
+	return conflicts, nil
+}
+
+// ResolveConflict applies the chosen value and marks the conflict resolved
+func (s *chunkSyncService) ResolveConflict(ctx context.Context, conflictID string, resolution *models.SyncConflictResolution) (*models.SyncConflict, error) {
+	row := s.db.QueryRowContext(ctx, "SELECT "+syncConflictColumns+" FROM chunk_sync_conflicts WHERE conflict_id = $1", conflictID)
+	conflict, err := scanSyncConflict(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrSyncConflictNotFound, conflictID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if conflict.Status == "resolved" {
+		return nil, fmt.Errorf("%w: %s", ErrSyncConflictResolved, conflictID)
+	}
+
+	var value json.RawMessage
+	switch resolution.Use {
+	case "kept":
+	case "discarded":
+		value = conflict.DiscardedValue
+	case "value":
+		if len(resolution.Value) == 0 {
+			return nil, fmt.Errorf("%w: value is required", ErrInvalidSyncResolution)
+		}
+		value = resolution.Value
+	default:
+		return nil, fmt.Errorf("%w: use must be kept, discarded or value", ErrInvalidSyncResolution)
+	}
+
+	if value != nil {
+		if conflict.Field == "*" {
+			return nil, fmt.Errorf("%w: a conflict over a whole operation can only keep the server state", ErrInvalidSyncResolution)
+		}
+		if err := s.writeResolvedValue(ctx, conflict.ChunkID, conflict.Field, value); err != nil {
+			return nil, err
+		}
+	}
+
+	row = s.db.QueryRowContext(ctx, `
+		UPDATE chunk_sync_conflicts SET status = 'resolved', resolution = $2, resolved_at = NOW()
+		WHERE conflict_id = $1 AND status = 'open'
+		RETURNING `+syncConflictColumns,
+		conflictID, resolution.Use)
+	conflict, err = scanSyncConflict(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrSyncConflictResolved, conflictID)
+	}
+	return conflict, err
+}
+
+// writeResolvedValue writes one field at the chunk's current version
+func (s *chunkSyncService) writeResolvedValue(ctx context.Context, chunkID, field string, value json.RawMessage) error {
+	for attempt := 0; attempt < maxSyncMergeAttempts; attempt++ {
+		current, err := s.chunks.GetChunk(ctx, chunkID)
+		if err != nil {
+			return err
+		}
+		patch := &models.ChunkPatch{ExpectedVersion: current.Version}
+		if err := setSyncPatchField(patch, field, value); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidSyncResolution, err)
+		}
+		_, err = s.chunks.PatchChunk(ctx, chunkID, patch)
+		if !errors.Is(err, ErrVersionConflict) {
+			return err
+		}
+	}
+	return fmt.Errorf("chunk %s kept changing while resolving; retry", chunkID)
+}
+
+const syncConflictColumns = `conflict_id, chunk_id, field, winner, kept_value, discarded_value, base_version,
+	client_id, op_id, status, COALESCE(resolution, ''), created_at, resolved_at`
+
+type syncConflictScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSyncConflict(row syncConflictScanner) (*models.SyncConflict, error) {
+	var conflict models.SyncConflict
+	var kept, discarded []byte
+	err := row.Scan(&conflict.ConflictID, &conflict.ChunkID, &conflict.Field, &conflict.Winner,
+		&kept, &discarded, &conflict.BaseVersion, &conflict.ClientID, &conflict.OpID,
+		&conflict.Status, &conflict.Resolution, &conflict.CreatedAt, &conflict.ResolvedAt)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan sync conflict: %w", err)
+	}
+	conflict.KeptValue = kept
+	conflict.DiscardedValue = discarded
+	return &conflict, nil
+}
+
+func isSyncField(field string) bool {
+	for _, f := range syncFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// syncFieldValue returns a chunk field as JSON
+func syncFieldValue(chunk *models.UnifiedChunkRecord, field string) (json.RawMessage, error) {
+	var value interface{}
+	switch field {
+	case "contents":
+		value = chunk.Contents
+	case "parent":
+		value = chunk.Parent
+	case "page":
+		value = chunk.Page
+	case "is_page":
+		value = chunk.IsPage
+	case "is_tag":
+		value = chunk.IsTag
+	case "is_template":
+		value = chunk.IsTemplate
+	case "is_slot":
+		value = chunk.IsSlot
+	case "ref":
+		value = chunk.Ref
+	case "tags":
+		value = chunk.Tags
+	case "metadata":
+		value = chunk.Metadata
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", field, err)
+	}
+	return data, nil
+}
+
+// setSyncPatchField sets one field of patch from JSON. null clears parent, page and ref.
+func setSyncPatchField(patch *models.ChunkPatch, field string, raw json.RawMessage) error {
+	decode := func(target interface{}) error {
+		if err := json.Unmarshal(raw, target); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", field, err)
+		}
+		return nil
+	}
+	id := func() (*string, error) {
+		var value *string
+		if err := decode(&value); err != nil {
+			return nil, err
+		}
+		if value == nil {
+			value = new(string)
+		}
+		return value, nil
+	}
+	flag := func() (*bool, error) {
+		var value bool
+		return &value, decode(&value)
+	}
+
+	var err error
+	switch field {
+	case "contents":
+		var contents string
+		err = decode(&contents)
+		patch.Contents = &contents
+	case "parent":
+		patch.Parent, err = id()
+	case "page":
+		patch.Page, err = id()
+	case "ref":
+		patch.Ref, err = id()
+	case "is_page":
+		patch.IsPage, err = flag()
+	case "is_tag":
+		patch.IsTag, err = flag()
+	case "is_template":
+		patch.IsTemplate, err = flag()
+	case "is_slot":
+		patch.IsSlot, err = flag()
+	case "tags":
+		var tags []string
+		err = decode(&tags)
+		patch.Tags = &tags
+	case "metadata":
+		err = decode(&patch.Metadata)
+	default:
+		err = fmt.Errorf("unknown field %q", field)
+	}
+	return err
+}
+
+// chunkFromSyncPatch builds a new chunk from the fields of a create
+func chunkFromSyncPatch(chunkID string, patch *models.ChunkPatch) *models.UnifiedChunkRecord {
+	chunk := &models.UnifiedChunkRecord{ChunkID: chunkID, Metadata: patch.Metadata}
+	optionalID := func(id *string) *string {
+		if id == nil || *id == "" {
+			return nil
+		}
+		return id
+	}
+	flag := func(b *bool) bool {
+		return b != nil && *b
+	}
+	if patch.Contents != nil {
+		chunk.Contents = *patch.Contents
+	}
+	chunk.Parent = optionalID(patch.Parent)
+	chunk.Page = optionalID(patch.Page)
+	chunk.Ref = optionalID(patch.Ref)
+	chunk.IsPage = flag(patch.IsPage)
+	chunk.IsTag = flag(patch.IsTag)
+	chunk.IsTemplate = flag(patch.IsTemplate)
+	chunk.IsSlot = flag(patch.IsSlot)
+	if patch.Tags != nil {
+		chunk.Tags = *patch.Tags
+	}
+	return chunk
+}
+
+// sameSyncValue reports whether two JSON values are equal, so that both sides making the
+// same change is not a conflict
+func sameSyncValue(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	// An empty list or object and null mean the same for tags and metadata
+	empty := func(v interface{}) bool {
+		switch v := v.(type) {
+		case nil:
+			return true
+		case []interface{}:
+			return len(v) == 0
+		case map[string]interface{}:
+			return len(v) == 0
+		}
+		return false
+	}
+	if empty(va) && empty(vb) {
+		return true
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func mergeFieldNames(a []string, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	merged := make([]string, 0, len(a)+len(b))
+	for _, field := range append(append([]string{}, a...), b...) {
+		if !seen[field] {
+			seen[field] = true
+			merged = append(merged, field)
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+func removeString(values []string, value string) []string {
+	for i, v := range values {
+		if v == value {
+			return append(values[:i], values[i+1:]...)
+		}
+	}
+	return values
+}
+
+func nullableJSON(value json.RawMessage) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return []byte(value)
+}
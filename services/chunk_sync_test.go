@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunkSync_FieldHelpers(t *testing.T) {
+	t.Run("equal values are not conflicts", func(t *testing.T) {
+		assert.True(t, sameSyncValue(json.RawMessage(`{"a":1,"b":[1,2]}`), json.RawMessage(`{"b":[1,2],"a":1}`)))
+		assert.True(t, sameSyncValue(json.RawMessage(`null`), json.RawMessage(`[]`)))
+		assert.False(t, sameSyncValue(json.RawMessage(`"a"`), json.RawMessage(`"b"`)))
+	})
+
+	t.Run("null clears references", func(t *testing.T) {
+		patch := &models.ChunkPatch{}
+		require.NoError(t, setSyncPatchField(patch, "parent", json.RawMessage(`null`)))
+		require.NoError(t, setSyncPatchField(patch, "is_page", json.RawMessage(`true`)))
+		require.NoError(t, setSyncPatchField(patch, "tags", json.RawMessage(`["x"]`)))
+		assert.Equal(t, "", *patch.Parent)
+		assert.True(t, *patch.IsPage)
+		assert.Equal(t, []string{"x"}, *patch.Tags)
+		assert.Error(t, setSyncPatchField(patch, "contents", json.RawMessage(`12`)))
+	})
+
+	t.Run("operations are validated", func(t *testing.T) {
+		chunkID := uuid.New().String()
+		assert.NoError(t, validateSyncOperation(&models.SyncOperation{OpID: "1", Op: models.SyncOperationDelete, ChunkID: chunkID}))
+		assert.Error(t, validateSyncOperation(&models.SyncOperation{Op: models.SyncOperationDelete, ChunkID: chunkID}))
+		assert.Error(t, validateSyncOperation(&models.SyncOperation{OpID: "1", Op: models.SyncOperationDelete, ChunkID: "c1"}))
+		assert.Error(t, validateSyncOperation(&models.SyncOperation{OpID: "1", Op: models.SyncOperationUpdate, ChunkID: chunkID}))
+		assert.Error(t, validateSyncOperation(&models.SyncOperation{OpID: "1", Op: models.SyncOperationUpdate, ChunkID: chunkID,
+			Fields: map[string]json.RawMessage{"version": json.RawMessage(`3`)}}))
+	})
+}
+
+func TestChunkSync_AttachChunksSkipsUnreadable(t *testing.T) {
+	chunks, _, _ := newTestPageACLs()
+	service := &chunkSyncService{chunks: chunks, monitor: NewNoOpMonitor()}
+
+	changes, err := service.attachChunks(contextWithSubject("erin", models.RoleReader), []*models.SyncChange{
+		{ChunkID: "secret-block", Sequence: 1},
+		{ChunkID: "open-block", Sequence: 2},
+		{ChunkID: "removed", Sequence: 3, Deleted: true},
+	})
+	require.NoError(t, err)
+	require.Len(t, changes, 2, "a change the caller may not read does not fail the pull")
+	assert.Equal(t, "open-block", changes[0].ChunkID)
+	assert.Equal(t, "Be kind", changes[0].Chunk.Contents)
+	assert.True(t, changes[1].Deleted)
+}
+
+func TestChunkSync_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	for _, file := range []string{"../database/chunk_trash_migration.sql", "../database/chunk_sync_migration.sql"} {
+		migration, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = db.Exec(string(migration))
+		require.NoError(t, err)
+	}
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	sync := NewChunkSyncService(db, chunks, NewNoOpMonitor()).(*chunkSyncService)
+	sync.settleDelay = 0
+	clientID := "test-client-" + uuid.New().String()
+	defer db.Exec("DELETE FROM chunk_sync_ops WHERE client_id = $1", clientID)
+	defer db.Exec("DELETE FROM chunk_sync_conflicts WHERE client_id = $1", clientID)
+
+	var cursor int64
+	require.NoError(t, db.QueryRow("SELECT COALESCE(MAX(seq), 0) FROM chunk_sync_log").Scan(&cursor))
+
+	chunk := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "Original", Tags: []string{}}
+	require.NoError(t, chunks.CreateChunk(ctx, chunk))
+	defer chunks.DeleteChunk(ctx, chunk.ChunkID)
+	base := chunk.Version
+
+	pulled, err := sync.Pull(ctx, cursor, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, pulled.Changes)
+	last := pulled.Changes[len(pulled.Changes)-1]
+	assert.Equal(t, chunk.ChunkID, last.ChunkID)
+	assert.Equal(t, "Original", last.Chunk.Contents)
+	cursor = pulled.Cursor
+
+	// The server edits contents after the client went offline
+	serverEdit := "Edited on the server"
+	_, err = chunks.PatchChunk(ctx, chunk.ChunkID, &models.ChunkPatch{ExpectedVersion: base, Contents: &serverEdit})
+	require.NoError(t, err)
+
+	t.Run("concurrent edits to different fields merge", func(t *testing.T) {
+		result, err := sync.Push(ctx, &models.SyncPushRequest{ClientID: clientID, Operations: []models.SyncOperation{{
+			OpID: "op-1", Op: models.SyncOperationUpdate, ChunkID: chunk.ChunkID, BaseVersion: base,
+			Timestamp: time.Now().Add(-time.Hour), Fields: map[string]json.RawMessage{"is_slot": json.RawMessage(`true`)},
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusMerged, result.Results[0].Status)
+
+		stored, err := chunks.GetChunk(ctx, chunk.ChunkID)
+		require.NoError(t, err)
+		assert.Equal(t, serverEdit, stored.Contents)
+		assert.True(t, stored.IsSlot)
+	})
+
+	t.Run("the later write to the same field wins", func(t *testing.T) {
+		result, err := sync.Push(ctx, &models.SyncPushRequest{ClientID: clientID, Operations: []models.SyncOperation{
+			{OpID: "op-2", Op: models.SyncOperationUpdate, ChunkID: chunk.ChunkID, BaseVersion: base,
+				Timestamp: time.Now().Add(-time.Hour), Fields: map[string]json.RawMessage{"contents": json.RawMessage(`"Old offline edit"`)}},
+			{OpID: "op-3", Op: models.SyncOperationUpdate, ChunkID: chunk.ChunkID, BaseVersion: base,
+				Timestamp: time.Now().Add(time.Minute), Fields: map[string]json.RawMessage{"contents": json.RawMessage(`"New offline edit"`)}},
+		}})
+		require.NoError(t, err)
+		require.Len(t, result.Results, 2)
+		for _, r := range result.Results {
+			assert.Equal(t, models.SyncStatusConflict, r.Status)
+			require.Len(t, r.ConflictIDs, 1)
+		}
+
+		stored, err := chunks.GetChunk(ctx, chunk.ChunkID)
+		require.NoError(t, err)
+		assert.Equal(t, "New offline edit", stored.Contents)
+
+		conflicts, err := sync.ListConflicts(ctx, chunk.ChunkID, "open", 0)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 2)
+		byOp := map[string]models.SyncConflict{}
+		for _, c := range conflicts {
+			byOp[c.OpID] = c
+		}
+		assert.Equal(t, "server", byOp["op-2"].Winner)
+		assert.JSONEq(t, `"Old offline edit"`, string(byOp["op-2"].DiscardedValue))
+		assert.Equal(t, "client", byOp["op-3"].Winner)
+
+		// Restoring the losing value writes it and closes the conflict
+		resolved, err := sync.ResolveConflict(ctx, byOp["op-2"].ConflictID, &models.SyncConflictResolution{Use: "discarded"})
+		require.NoError(t, err)
+		assert.Equal(t, "resolved", resolved.Status)
+		stored, err = chunks.GetChunk(ctx, chunk.ChunkID)
+		require.NoError(t, err)
+		assert.Equal(t, "Old offline edit", stored.Contents)
+
+		_, err = sync.ResolveConflict(ctx, byOp["op-2"].ConflictID, &models.SyncConflictResolution{Use: "kept"})
+		assert.ErrorIs(t, err, ErrSyncConflictResolved)
+	})
+
+	t.Run("retried operations are applied once", func(t *testing.T) {
+		result, err := sync.Push(ctx, &models.SyncPushRequest{ClientID: clientID, Operations: []models.SyncOperation{{
+			OpID: "op-1", Op: models.SyncOperationUpdate, ChunkID: chunk.ChunkID, BaseVersion: base,
+			Fields: map[string]json.RawMessage{"is_slot": json.RawMessage(`true`)},
+		}}})
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusDuplicate, result.Results[0].Status)
+	})
+
+	t.Run("create and delete", func(t *testing.T) {
+		childID := uuid.New().String()
+		result, err := sync.Push(ctx, &models.SyncPushRequest{ClientID: clientID, Operations: []models.SyncOperation{
+			{OpID: "op-4", Op: models.SyncOperationCreate, ChunkID: childID,
+				Fields: map[string]json.RawMessage{"contents": json.RawMessage(`"Offline child"`), "parent": json.RawMessage(`"` + chunk.ChunkID + `"`)}},
+			{OpID: "op-5", Op: models.SyncOperationCreate, ChunkID: childID,
+				Fields: map[string]json.RawMessage{"contents": json.RawMessage(`"Again"`)}},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusApplied, result.Results[0].Status)
+		assert.Equal(t, models.SyncStatusRejected, result.Results[1].Status)
+
+		// Deleting from a stale base version loses to the server's edits
+		result, err = sync.Push(ctx, &models.SyncPushRequest{ClientID: clientID, Operations: []models.SyncOperation{
+			{OpID: "op-6", Op: models.SyncOperationDelete, ChunkID: chunk.ChunkID, BaseVersion: base},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusConflict, result.Results[0].Status)
+
+		stored, err := chunks.GetChunk(ctx, chunk.ChunkID)
+		require.NoError(t, err)
+		result, err = sync.Push(ctx, &models.SyncPushRequest{ClientID: clientID, Operations: []models.SyncOperation{
+			{OpID: "op-7", Op: models.SyncOperationDelete, ChunkID: chunk.ChunkID, BaseVersion: stored.Version},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, models.SyncStatusApplied, result.Results[0].Status)
+		defer db.Exec("DELETE FROM chunk_trash WHERE root_chunk_id = $1", chunk.ChunkID)
+
+		pulled, err := sync.Pull(ctx, cursor, 0)
+		require.NoError(t, err)
+		deleted := map[string]bool{}
+		for _, change := range pulled.Changes {
+			deleted[change.ChunkID] = change.Deleted
+		}
+		assert.True(t, deleted[chunk.ChunkID])
+		assert.True(t, deleted[childID])
+	})
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"semantic-text-processor/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrVersionConflict is matched by errors.Is when an update names a chunk version that is no longer current
+var ErrVersionConflict = errors.New("chunk version conflict")
+
+// VersionConflictError reports the version a caller expected and the version actually stored
+type VersionConflictError struct {
+	ChunkID         string
+	ExpectedVersion int64
+	CurrentVersion  int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("chunk %s was modified concurrently: expected version %d, current version %d",
+		e.ChunkID, e.ExpectedVersion, e.CurrentVersion)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrVersionConflict
+}
+
+// versionQuerier is satisfied by *sql.DB and *sql.Tx
+type versionQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// requireExpectedVersion rejects updates that do not name the version they were based on
+func requireExpectedVersion(chunkID string, version int64) error {
+	if version <= 0 {
+		return fmt.Errorf("expected version is required to update chunk %s", chunkID)
+	}
+	return nil
+}
+
+// explainVersionMismatch is called after a versioned UPDATE matched no rows and tells a
+// missing chunk apart from a stale version
+func explainVersionMismatch(ctx context.Context, q versionQuerier, chunkID string, expected int64) error {
+	var current int64
+	err := q.QueryRowContext(ctx, `SELECT version FROM chunks WHERE chunk_id = $1`, chunkID).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get chunk version: %w", err)
+	}
+	return &VersionConflictError{ChunkID: chunkID, ExpectedVersion: expected, CurrentVersion: current}
+}
+
+// PatchChunk applies the non-nil fields of patch if the chunk is still at patch.ExpectedVersion
+// and returns the updated chunk with its new version
+func (s *unifiedChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("patch_chunk", time.Since(start), 1)
+	}()
+
+	if patch == nil {
+		return nil, fmt.Errorf("patch is required")
+	}
+	if err := requireExpectedVersion(chunkID, patch.ExpectedVersion); err != nil {
+		return nil, err
+	}
+
+	args := []interface{}{chunkID, patch.ExpectedVersion}
+	var sets []string
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if patch.Contents != nil {
+		set("contents", *patch.Contents)
+		set("lang", DetectLanguage(*patch.Contents))
+	}
+	if patch.Parent != nil {
+		set("parent", nullableID(*patch.Parent))
+	}
+	if patch.Page != nil {
+		set("page", nullableID(*patch.Page))
+	}
+	if patch.IsPage != nil {
+		set("is_page", *patch.IsPage)
+	}
+	if patch.IsTag != nil {
+		set("is_tag", *patch.IsTag)
+	}
+	if patch.IsTemplate != nil {
+		set("is_template", *patch.IsTemplate)
+	}
+	if patch.IsSlot != nil {
+		set("is_slot", *patch.IsSlot)
+	}
+	if patch.Ref != nil {
+		set("ref", nullableID(*patch.Ref))
+	}
+	if patch.Tags != nil {
+		set("tags", pq.Array(*patch.Tags))
+	}
+	if len(patch.Metadata) > 0 || len(patch.MetadataOps) > 0 {
+		metadata := "COALESCE(metadata, '{}'::jsonb)"
+		if len(patch.Metadata) > 0 {
+			metadataJSON, err := json.Marshal(patch.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+			}
+			args = append(args, metadataJSON)
+			metadata += fmt.Sprintf(" || $%d::jsonb", len(args))
+		}
+		// Operations are applied by jsonb_patch_metadata, see database/metadata_patch_migration.sql
+		if len(patch.MetadataOps) > 0 {
+			if err := validateMetadataOps(patch.MetadataOps); err != nil {
+				return nil, err
+			}
+			opsJSON, err := json.Marshal(patch.MetadataOps)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal metadata operations: %w", err)
+			}
+			args = append(args, opsJSON)
+			metadata = fmt.Sprintf("jsonb_patch_metadata(%s, $%d::jsonb)", metadata, len(args))
+		}
+		sets = append(sets, "metadata = "+metadata)
+	}
+	if len(sets) == 0 {
+		return nil, fmt.Errorf("patch for chunk %s has no fields to update", chunkID)
+	}
+
+	args = append(args, time.Now())
+	sets = append(sets, fmt.Sprintf("last_updated = $%d", len(args)), "version = version + 1")
+
+	query := fmt.Sprintf(`
+		UPDATE chunks c SET %s
+		WHERE c.chunk_id = $1 AND c.version = $2
+		RETURNING %s`, strings.Join(sets, ", "), chunkColumnList("c", chunkListColumns))
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	moved := false
+	if patch.Parent != nil {
+		if moved, err = parentChangesTx(ctx, tx, chunkID, nullableParent(*patch.Parent)); err != nil {
+			return nil, err
+		}
+	}
+
+	chunk, err := patchChunkTx(ctx, tx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if chunk == nil {
+		return nil, explainVersionMismatch(ctx, tx, chunkID, patch.ExpectedVersion)
+	}
+
+	if moved {
+		if _, err = rebuildSubtreeHierarchyTx(ctx, tx, []string{chunkID}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.invalidateChunkCaches(ctx, chunkID)
+
+	return chunk, nil
+}
+
+// patchChunkTx runs a patch query and returns the patched chunk, or nil when the version
+// did not match. The rows are closed before returning so the transaction can continue.
+func patchChunkTx(ctx context.Context, tx *sql.Tx, query string, args []interface{}) (*models.UnifiedChunkRecord, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to patch chunk: %w", err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to patch chunk: %w", err)
+		}
+		return nil, nil
+	}
+	return scanChunkColumns(rows, chunkListColumns)
+}
+
+// nullableParent maps an empty parent to no parent
+func nullableParent(id string) *string {
+	if id == "" {
+		return nil
+	}
+	return &id
+}
+
+// nullableID maps an empty reference to NULL
+func nullableID(id string) interface{} {
+	if id == "" {
+		return nil
+	}
+	return id
+}
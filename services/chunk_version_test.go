@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionConflictError(t *testing.T) {
+	var err error = &VersionConflictError{ChunkID: "c1", ExpectedVersion: 3, CurrentVersion: 5}
+	wrapped := errors.Join(errors.New("batch failed"), err)
+
+	assert.ErrorIs(t, wrapped, ErrVersionConflict)
+
+	var conflict *VersionConflictError
+	require.ErrorAs(t, wrapped, &conflict)
+	assert.Equal(t, int64(5), conflict.CurrentVersion)
+	assert.Contains(t, err.Error(), "expected version 3, current version 5")
+}
+
+func TestUnifiedChunkService_RequiresExpectedVersion(t *testing.T) {
+	ctx := context.Background()
+	// No database: each call must be rejected before issuing a query
+	service := NewUnifiedChunkService(nil, nil, NewNoOpMonitor())
+
+	err := service.UpdateChunk(ctx, &models.UnifiedChunkRecord{ChunkID: "c1", Contents: "stale write"})
+	assert.ErrorContains(t, err, "expected version is required")
+
+	err = service.BatchUpdateChunks(ctx, []models.UnifiedChunkRecord{
+		{ChunkID: "c1", Version: 2},
+		{ChunkID: "c2"},
+	})
+	assert.ErrorContains(t, err, "chunk c2")
+
+	contents := "patched"
+	_, err = service.PatchChunk(ctx, "c1", &models.ChunkPatch{Contents: &contents})
+	assert.ErrorContains(t, err, "expected version is required")
+
+	_, err = service.PatchChunk(ctx, "c1", &models.ChunkPatch{ExpectedVersion: 1})
+	assert.ErrorContains(t, err, "no fields to update")
+
+	_, err = service.PatchChunk(ctx, "c1", &models.ChunkPatch{ExpectedVersion: 1, MetadataOps: []models.MetadataOp{
+		{Op: models.MetadataOpMerge, Path: []string{"review"}, Value: "approved"},
+	}})
+	assert.ErrorIs(t, err, ErrInvalidMetadataPatch)
+}
+
+func TestValidateMetadataOps(t *testing.T) {
+	assert.NoError(t, validateMetadataOps([]models.MetadataOp{
+		{Op: models.MetadataOpSet, Path: []string{"a", "b"}, Value: nil},
+		{Op: models.MetadataOpDelete, Path: []string{"c"}},
+		{Op: models.MetadataOpMerge, Path: []string{"d"}, Value: map[string]interface{}{"e": 1.0}},
+	}))
+	assert.ErrorIs(t, validateMetadataOps([]models.MetadataOp{{Op: models.MetadataOpSet}}), ErrInvalidMetadataPatch)
+	assert.ErrorIs(t, validateMetadataOps([]models.MetadataOp{{Op: models.MetadataOpSet, Path: []string{"a", ""}}}), ErrInvalidMetadataPatch)
+	assert.ErrorIs(t, validateMetadataOps([]models.MetadataOp{{Op: "replace", Path: []string{"a"}}}), ErrInvalidMetadataPatch)
+}
+
+func TestPatchedMetadata(t *testing.T) {
+	current := map[string]interface{}{
+		"status": "draft",
+		"review": map[string]interface{}{"round": 1.0, "notes": "tbd"},
+		"title":  "Plan",
+	}
+	patch := &models.ChunkPatch{
+		Metadata: map[string]interface{}{"owner": "ana"},
+		MetadataOps: []models.MetadataOp{
+			{Op: models.MetadataOpSet, Path: []string{"status"}, Value: "done"},
+			{Op: models.MetadataOpSet, Path: []string{"title", "en"}, Value: "Plan"},
+			{Op: models.MetadataOpMerge, Path: []string{"review"}, Value: map[string]interface{}{"round": 2.0}},
+			{Op: models.MetadataOpDelete, Path: []string{"review", "notes"}},
+			{Op: models.MetadataOpDelete, Path: []string{"missing", "key"}},
+		},
+	}
+
+	assert.Equal(t, map[string]interface{}{
+		"status": "done",
+		"owner":  "ana",
+		"title":  map[string]interface{}{"en": "Plan"},
+		"review": map[string]interface{}{"round": 2.0},
+	}, patchedMetadata(current, patch))
+	assert.Equal(t, "tbd", current["review"].(map[string]interface{})["notes"], "the current metadata is left alone")
+
+	assert.True(t, metadataKeyChanged(patch, "review"))
+	assert.True(t, metadataKeyChanged(patch, "owner"))
+	assert.False(t, metadataKeyChanged(patch, "sensitive"))
+}
+
+func TestPatchChunk_MetadataOps_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	var hasFunction bool
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT to_regproc('public.jsonb_patch_metadata') IS NOT NULL").Scan(&hasFunction))
+	if !hasFunction {
+		t.Skip("apply database/metadata_patch_migration.sql first")
+	}
+
+	service := NewUnifiedChunkService(db, NewInMemoryCache(100, time.Minute), NewNoOpMonitor())
+	chunk := &models.UnifiedChunkRecord{Contents: "metadata patch test", Metadata: map[string]interface{}{
+		"review": map[string]interface{}{"round": 1.0, "notes": "tbd"},
+		"title":  "Plan",
+	}}
+	require.NoError(t, service.CreateChunk(ctx, chunk))
+	defer service.DeleteChunk(ctx, chunk.ChunkID)
+	stored, err := service.GetChunk(ctx, chunk.ChunkID)
+	require.NoError(t, err)
+
+	patch := &models.ChunkPatch{ExpectedVersion: stored.Version, MetadataOps: []models.MetadataOp{
+		{Op: models.MetadataOpSet, Path: []string{"title", "en"}, Value: "Plan"},
+		{Op: models.MetadataOpMerge, Path: []string{"review"}, Value: map[string]interface{}{"round": 2.0}},
+		{Op: models.MetadataOpDelete, Path: []string{"review", "notes"}},
+	}}
+	patched, err := service.PatchChunk(ctx, chunk.ChunkID, patch)
+	require.NoError(t, err)
+	assert.Equal(t, patchedMetadata(stored.Metadata, patch), patched.Metadata, "the database and Go agree")
+	assert.Equal(t, stored.Version+1, patched.Version)
+
+	_, err = service.PatchChunk(ctx, chunk.ChunkID, patch)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	reread, err := service.GetChunk(ctx, chunk.ChunkID)
+	require.NoError(t, err)
+	assert.Equal(t, patched.Metadata, reread.Metadata, "the cached chunk was invalidated")
+}
@@ -0,0 +1,265 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"semantic-text-processor/config"
+	"semantic-text-processor/errors"
+	"semantic-text-processor/models"
+	"strings"
+)
+
+// Supported completion providers
+const (
+	CompletionProviderGateway   = "gateway"
+	CompletionProviderOpenAI    = "openai"
+	CompletionProviderAnthropic = "anthropic"
+)
+
+const (
+	defaultOpenAIEndpoint    = "https://api.openai.com/v1/chat/completions"
+	defaultAnthropicEndpoint = "https://api.anthropic.com/v1/messages"
+	anthropicAPIVersion      = "2023-06-01"
+)
+
+// CompletionProvider generates text from a prompt using an LLM
+type CompletionProvider interface {
+	Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error)
+	Name() string
+}
+
+// CompletionRequest is a provider-neutral completion request
+type CompletionRequest struct {
+	System      string
+	Prompt      string
+	MaxTokens   int
+	Temperature float64
+}
+
+// CompletionResponse is a provider-neutral completion result
+type CompletionResponse struct {
+	Text  string
+	Model string
+	Usage *models.TokenUsage
+}
+
+// NewCompletionProvider creates the completion provider selected in configuration
+func NewCompletionProvider(cfg *config.RAGConfig) (CompletionProvider, error) {
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	switch strings.ToLower(cfg.Provider) {
+	case "", CompletionProviderGateway:
+		if cfg.Endpoint == "" {
+			return nil, fmt.Errorf("gateway completion provider requires an endpoint")
+		}
+		return &gatewayCompletionProvider{cfg: cfg, client: client}, nil
+	case CompletionProviderOpenAI:
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("openai completion provider requires a model")
+		}
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = defaultOpenAIEndpoint
+		}
+		return &openAICompletionProvider{cfg: cfg, endpoint: endpoint, client: client}, nil
+	case CompletionProviderAnthropic:
+		if cfg.Model == "" {
+			return nil, fmt.Errorf("anthropic completion provider requires a model")
+		}
+		endpoint := cfg.Endpoint
+		if endpoint == "" {
+			endpoint = defaultAnthropicEndpoint
+		}
+		return &anthropicCompletionProvider{cfg: cfg, endpoint: endpoint, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported completion provider: %s", cfg.Provider)
+	}
+}
+
+// gatewayCompletionProvider uses the same LLM gateway contract as LLMClient
+type gatewayCompletionProvider struct {
+	cfg    *config.RAGConfig
+	client *http.Client
+}
+
+func (p *gatewayCompletionProvider) Name() string { return CompletionProviderGateway }
+
+func (p *gatewayCompletionProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	body := LLMRequest{
+		Text:      req.Prompt,
+		Operation: "generate_answer",
+		Options: map[string]interface{}{
+			"system":      req.System,
+			"max_tokens":  req.MaxTokens,
+			"temperature": req.Temperature,
+		},
+	}
+	if p.cfg.Model != "" {
+		body.Options["model"] = p.cfg.Model
+	}
+
+	var response LLMResponse
+	headers := map[string]string{"Authorization": "Bearer " + p.cfg.APIKey}
+	if err := postCompletion(ctx, p.client, p.cfg.Endpoint, headers, body, &response); err != nil {
+		return nil, err
+	}
+	if !response.Success {
+		return nil, errors.NewExternalServiceError(errors.ErrCodeLLMServiceFailed, "LLM API returned error: "+response.Error, nil)
+	}
+
+	return &CompletionResponse{Text: strings.Join(response.Data, "\n"), Model: p.cfg.Model}, nil
+}
+
+// openAICompletionProvider calls an OpenAI-compatible chat completions API
+type openAICompletionProvider struct {
+	cfg      *config.RAGConfig
+	endpoint string
+	client   *http.Client
+}
+
+func (p *openAICompletionProvider) Name() string { return CompletionProviderOpenAI }
+
+func (p *openAICompletionProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	messages := []map[string]string{}
+	if req.System != "" {
+		messages = append(messages, map[string]string{"role": "system", "content": req.System})
+	}
+	messages = append(messages, map[string]string{"role": "user", "content": req.Prompt})
+
+	body := map[string]interface{}{
+		"model":       p.cfg.Model,
+		"messages":    messages,
+		"max_tokens":  req.MaxTokens,
+		"temperature": req.Temperature,
+	}
+
+	var response struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{"Authorization": "Bearer " + p.cfg.APIKey}
+	if err := postCompletion(ctx, p.client, p.endpoint, headers, body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Choices) == 0 {
+		return nil, errors.NewExternalServiceError(errors.ErrCodeLLMServiceFailed, "LLM API returned no choices", nil)
+	}
+
+	return &CompletionResponse{
+		Text:  response.Choices[0].Message.Content,
+		Model: response.Model,
+		Usage: &models.TokenUsage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+		},
+	}, nil
+}
+
+// anthropicCompletionProvider calls the Anthropic Messages API
+type anthropicCompletionProvider struct {
+	cfg      *config.RAGConfig
+	endpoint string
+	client   *http.Client
+}
+
+func (p *anthropicCompletionProvider) Name() string { return CompletionProviderAnthropic }
+
+func (p *anthropicCompletionProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	body := map[string]interface{}{
+		"model":       p.cfg.Model,
+		"max_tokens":  req.MaxTokens,
+		"temperature": req.Temperature,
+		"messages": []map[string]string{
+			{"role": "user", "content": req.Prompt},
+		},
+	}
+	if req.System != "" {
+		body["system"] = req.System
+	}
+
+	var response struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	headers := map[string]string{
+		"x-api-key":         p.cfg.APIKey,
+		"anthropic-version": anthropicAPIVersion,
+	}
+	if err := postCompletion(ctx, p.client, p.endpoint, headers, body, &response); err != nil {
+		return nil, err
+	}
+
+	var text strings.Builder
+	for _, block := range response.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+
+	return &CompletionResponse{
+		Text:  text.String(),
+		Model: response.Model,
+		Usage: &models.TokenUsage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// postCompletion posts a JSON request with retries and decodes the JSON response
+func postCompletion(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body interface{}, response interface{}) error {
+	requestBody, err := json.Marshal(body)
+	if err != nil {
+		return errors.NewInternalError(errors.ErrCodeSerializationError, "Failed to marshal completion request", err)
+	}
+
+	retryer := errors.NewRetryer(errors.ExternalServiceRetryConfig())
+	return retryer.Execute(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(requestBody))
+		if err != nil {
+			return errors.NewInternalError(errors.ErrCodeProcessingError, "Failed to create HTTP request", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return errors.NewNetworkError(errors.ErrCodeNetworkConnection, "Completion API request failed", err)
+		}
+		defer resp.Body.Close()
+
+		data, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return errors.NewNetworkError(errors.ErrCodeNetworkConnection, "Failed to read completion API response", err)
+		}
+		if resp.StatusCode >= 400 {
+			return llmHTTPError(resp.StatusCode, string(data))
+		}
+
+		if err := json.Unmarshal(data, response); err != nil {
+			return errors.NewInternalError(errors.ErrCodeSerializationError, "Failed to unmarshal completion API response", err)
+		}
+		return nil
+	})
+}
@@ -210,6 +210,9 @@ func (cc *DatabaseConsistencyChecker) CheckTagConsistency(ctx context.Context) (
 
 // RepairTagConsistency repairs tag consistency for a specific chunk
 func (cc *DatabaseConsistencyChecker) RepairTagConsistency(ctx context.Context, chunkID string) error {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return err
+	}
 	tx, err := cc.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -254,6 +257,9 @@ func (cc *DatabaseConsistencyChecker) RepairTagConsistency(ctx context.Context,
 
 // RepairAllTagConsistencies repairs all tag consistency issues
 func (cc *DatabaseConsistencyChecker) RepairAllTagConsistencies(ctx context.Context) (int, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return 0, err
+	}
 	errors, err := cc.CheckTagConsistency(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to check tag consistency: %w", err)
@@ -365,6 +371,9 @@ func (cc *DatabaseConsistencyChecker) CheckHierarchyConsistency(ctx context.Cont
 
 // RepairHierarchyConsistency repairs hierarchy consistency for a specific chunk
 func (cc *DatabaseConsistencyChecker) RepairHierarchyConsistency(ctx context.Context, chunkID string) error {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return err
+	}
 	tx, err := cc.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
@@ -410,6 +419,9 @@ func (cc *DatabaseConsistencyChecker) RepairHierarchyConsistency(ctx context.Con
 
 // RepairAllHierarchyConsistencies repairs all hierarchy consistency issues
 func (cc *DatabaseConsistencyChecker) RepairAllHierarchyConsistencies(ctx context.Context) (int, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return 0, err
+	}
 	errors, err := cc.CheckHierarchyConsistency(ctx)
 	if err != nil {
 		return 0, fmt.Errorf("failed to check hierarchy consistency: %w", err)
@@ -487,6 +499,9 @@ func (cc *DatabaseConsistencyChecker) CheckSearchCacheConsistency(ctx context.Co
 
 // CleanupExpiredSearchCache removes expired search cache entries
 func (cc *DatabaseConsistencyChecker) CleanupExpiredSearchCache(ctx context.Context) (int, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return 0, err
+	}
 	result, err := cc.db.ExecContext(ctx, "DELETE FROM chunk_search_cache WHERE expires_at < NOW()")
 	if err != nil {
 		return 0, fmt.Errorf("failed to cleanup expired search cache: %w", err)
@@ -569,6 +584,9 @@ func (cc *DatabaseConsistencyChecker) CheckAllConsistency(ctx context.Context) (
 
 // RepairAllInconsistencies attempts to repair all found inconsistencies
 func (cc *DatabaseConsistencyChecker) RepairAllInconsistencies(ctx context.Context) (*RepairReport, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
 	start := time.Now()
 	repairedByType := make(map[string]int)
 	var failedRepairs []ConsistencyError
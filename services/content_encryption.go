@@ -0,0 +1,533 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"semantic-text-processor/models"
+)
+
+// SensitiveMetadataKey flags a chunk whose contents are encrypted at rest when set to
+// true in its metadata
+const SensitiveMetadataKey = "sensitive"
+
+// encryptedContentPrefix marks stored contents as an encrypted envelope. The database
+// keeps contents with this prefix out of the full-text index.
+const encryptedContentPrefix = "enc:v1:"
+
+// maxCachedDataKeys bounds the unwrapped data keys kept in memory
+const maxCachedDataKeys = 1024
+
+// contentEnvelope is the stored form of encrypted contents: the data key wrapped by the
+// master key next to the AES-GCM sealed contents
+type contentEnvelope struct {
+	KeyID      string `json:"k"`
+	Wrapped    []byte `json:"w"`
+	Ciphertext []byte `json:"c"`
+}
+
+// IsSensitive reports whether chunk metadata flags the contents for encryption
+func IsSensitive(metadata map[string]interface{}) bool {
+	sensitive, _ := metadata[SensitiveMetadataKey].(bool)
+	return sensitive
+}
+
+// IsEncryptedContent reports whether stored contents are an encrypted envelope
+func IsEncryptedContent(contents string) bool {
+	return strings.HasPrefix(contents, encryptedContentPrefix)
+}
+
+// ContentCipher encrypts chunk contents with envelope keys from a ContentKeyProvider.
+// Unwrapped data keys are cached so reading many chunks written together unwraps
+// their key once.
+type ContentCipher struct {
+	provider ContentKeyProvider
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+// NewContentCipher creates a content cipher
+func NewContentCipher(provider ContentKeyProvider) *ContentCipher {
+	return &ContentCipher{
+		provider: provider,
+		keys:     make(map[string][]byte),
+	}
+}
+
+// NewDataKey generates a data key to encrypt one or more contents with
+func (c *ContentCipher) NewDataKey(ctx context.Context) (*DataKey, error) {
+	key, err := c.provider.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.cacheKey(key.KeyID, key.Wrapped, key.Plaintext)
+	return key, nil
+}
+
+// Encrypt seals plaintext under key and returns the stored form
+func (c *ContentCipher) Encrypt(key *DataKey, plaintext string) (string, error) {
+	aead, err := newGCM(key.Plaintext)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := gcmSeal(aead, []byte(plaintext), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt contents: %w", err)
+	}
+
+	envelope, err := json.Marshal(contentEnvelope{KeyID: key.KeyID, Wrapped: key.Wrapped, Ciphertext: sealed})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode encrypted contents: %w", err)
+	}
+	return encryptedContentPrefix + base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// Decrypt returns the plaintext of stored contents; contents that are not encrypted are
+// returned unchanged
+func (c *ContentCipher) Decrypt(ctx context.Context, contents string) (string, error) {
+	if !IsEncryptedContent(contents) {
+		return contents, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(contents, encryptedContentPrefix))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted contents: %w", err)
+	}
+	var envelope contentEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", fmt.Errorf("failed to decode encrypted contents: %w", err)
+	}
+
+	dataKey, err := c.dataKey(ctx, envelope.KeyID, envelope.Wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := gcmOpen(aead, envelope.Ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt contents: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// dataKey unwraps a data key, using the cache when it was seen before
+func (c *ContentCipher) dataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	cacheKey := keyID + ":" + base64.StdEncoding.EncodeToString(wrapped)
+	c.mu.Lock()
+	plaintext, ok := c.keys[cacheKey]
+	c.mu.Unlock()
+	if ok {
+		return plaintext, nil
+	}
+
+	plaintext, err := c.provider.DecryptDataKey(ctx, keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	c.cacheKey(keyID, wrapped, plaintext)
+	return plaintext, nil
+}
+
+func (c *ContentCipher) cacheKey(keyID string, wrapped, plaintext []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Dropping everything keeps the bound without tracking recency; keys are
+	// unwrapped again on demand
+	if len(c.keys) >= maxCachedDataKeys {
+		c.keys = make(map[string][]byte)
+	}
+	c.keys[keyID+":"+base64.StdEncoding.EncodeToString(wrapped)] = plaintext
+}
+
+// encryptingChunkService encrypts the contents of sensitive chunks before they reach
+// the underlying service and decrypts them again on reads. Encrypted contents are not
+// full-text indexed, so sensitive chunks are only found by metadata, tag and hierarchy
+// filters.
+type encryptingChunkService struct {
+	UnifiedChunkService
+	cipher *ContentCipher
+}
+
+// NewEncryptingChunkService wraps a chunk service with content encryption at rest
+func NewEncryptingChunkService(base UnifiedChunkService, cipher *ContentCipher) UnifiedChunkService {
+	return &encryptingChunkService{
+		UnifiedChunkService: base,
+		cipher:              cipher,
+	}
+}
+
+// CreateChunk encrypts a sensitive chunk's contents for storage
+func (s *encryptingChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	restore, err := s.encryptChunks(ctx, []*models.UnifiedChunkRecord{chunk})
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return s.UnifiedChunkService.CreateChunk(ctx, chunk)
+}
+
+// UpdateChunk encrypts a sensitive chunk's contents for storage
+func (s *encryptingChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	restore, err := s.encryptChunks(ctx, []*models.UnifiedChunkRecord{chunk})
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return s.UnifiedChunkService.UpdateChunk(ctx, chunk)
+}
+
+// BatchCreateChunks encrypts sensitive chunks with one data key for the batch
+func (s *encryptingChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	restore, err := s.encryptChunks(ctx, chunkPointers(chunks))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return s.UnifiedChunkService.BatchCreateChunks(ctx, chunks)
+}
+
+// BatchUpdateChunks encrypts sensitive chunks with one data key for the batch
+func (s *encryptingChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	restore, err := s.encryptChunks(ctx, chunkPointers(chunks))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	return s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks)
+}
+
+// PatchChunk re-encrypts the contents when they change on a sensitive chunk, and
+// encrypts or decrypts them when the patch sets or clears the sensitive flag
+func (s *encryptingChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	if patch == nil {
+		return s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	}
+	flagChanged := metadataKeyChanged(patch, SensitiveMetadataKey)
+	if patch.Contents != nil || flagChanged {
+		current, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+		if err != nil {
+			return nil, err
+		}
+
+		sensitive := IsSensitive(current.Metadata)
+		if flagChanged {
+			sensitive = IsSensitive(patchedMetadata(current.Metadata, patch))
+		}
+		if patch.Contents != nil || sensitive != IsEncryptedContent(current.Contents) {
+			contents, err := s.cipher.Decrypt(ctx, current.Contents)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decrypt chunk %s: %w", chunkID, err)
+			}
+			if patch.Contents != nil {
+				contents = *patch.Contents
+			}
+			if sensitive {
+				key, err := s.cipher.NewDataKey(ctx)
+				if err != nil {
+					return nil, fmt.Errorf("failed to encrypt chunk %s: %w", chunkID, err)
+				}
+				if contents, err = s.cipher.Encrypt(key, contents); err != nil {
+					return nil, fmt.Errorf("failed to encrypt chunk %s: %w", chunkID, err)
+				}
+			}
+
+			encrypted := *patch
+			encrypted.Contents = &contents
+			patch = &encrypted
+		}
+	}
+
+	chunk, err := s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return chunk, err
+	}
+	return s.decryptChunk(ctx, chunk)
+}
+
+// MergeChunks merges the plaintext of the two chunks, so sensitive contents can be
+// concatenated, and encrypts the result when either chunk is sensitive
+func (s *encryptingChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	target, err := s.UnifiedChunkService.GetChunk(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.UnifiedChunkService.GetChunk(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	sensitive := IsSensitive(target.Metadata) || IsSensitive(source.Metadata)
+	if strategy.ResolvedContents == nil && (sensitive || IsEncryptedContent(target.Contents) || IsEncryptedContent(source.Contents)) {
+		if target, err = s.decryptChunk(ctx, target); err != nil {
+			return nil, err
+		}
+		if source, err = s.decryptChunk(ctx, source); err != nil {
+			return nil, err
+		}
+		contents, err := mergeContents(target.Contents, source.Contents, strategy)
+		if err != nil {
+			return nil, err
+		}
+		if sensitive {
+			key, err := s.cipher.NewDataKey(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encrypt chunk %s: %w", targetID, err)
+			}
+			if contents, err = s.cipher.Encrypt(key, contents); err != nil {
+				return nil, fmt.Errorf("failed to encrypt chunk %s: %w", targetID, err)
+			}
+		}
+		strategy.ResolvedContents = &contents
+	}
+
+	result, err := s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	merged := *result
+	if merged.Chunk, err = s.decryptChunk(ctx, result.Chunk); err != nil {
+		return nil, err
+	}
+	return &merged, nil
+}
+
+// GetChunk decrypts the chunk's contents
+func (s *encryptingChunkService) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunk(ctx, chunk)
+}
+
+func (s *encryptingChunkService) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.BatchGetChunks(ctx, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := make(map[string]*models.UnifiedChunkRecord, len(chunks))
+	for chunkID, chunk := range chunks {
+		if decrypted[chunkID], err = s.decryptChunk(ctx, chunk); err != nil {
+			return nil, err
+		}
+	}
+	return decrypted, nil
+}
+
+func (s *encryptingChunkService) GetChunkTags(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunkTags(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.BatchGetChunkTags(ctx, chunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunkLists(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTag(ctx, tagChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetChunksByTags(ctx context.Context, tagChunkIDs []string, matchType string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTags(ctx, tagChunkIDs, matchType)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetTagAncestors(ctx, tagChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetTagDescendants(ctx, tagChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTagWithOptions(ctx, tagChunkID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChildren(ctx, parentChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.BatchGetChildren(ctx, parentChunkIDs)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunkLists(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetDescendants(ctx, ancestorChunkID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+func (s *encryptingChunkService) GetAncestors(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetAncestors(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+// SearchChunks decrypts matched chunks. Encrypted contents are never matched by a
+// content query, only by the other filters.
+func (s *encryptingChunkService) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	result, err := s.UnifiedChunkService.SearchChunks(ctx, query)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	chunks, err := s.decryptChunks(ctx, result.Chunks)
+	if err != nil {
+		return nil, err
+	}
+	decrypted := *result
+	decrypted.Chunks = chunks
+	return &decrypted, nil
+}
+
+func (s *encryptingChunkService) SearchByContent(ctx context.Context, content string, filters map[string]interface{}) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.SearchByContent(ctx, content, filters)
+	if err != nil {
+		return nil, err
+	}
+	return s.decryptChunks(ctx, chunks)
+}
+
+// encryptChunks replaces the contents of sensitive chunks with their encrypted form,
+// using one data key for the call. The returned function puts the plaintext back so
+// callers keep seeing what they wrote.
+func (s *encryptingChunkService) encryptChunks(ctx context.Context, chunks []*models.UnifiedChunkRecord) (func(), error) {
+	var key *DataKey
+	plaintexts := make(map[*models.UnifiedChunkRecord]string)
+	restore := func() {
+		for chunk, plaintext := range plaintexts {
+			chunk.Contents = plaintext
+		}
+	}
+
+	for _, chunk := range chunks {
+		if chunk == nil || !IsSensitive(chunk.Metadata) || IsEncryptedContent(chunk.Contents) {
+			continue
+		}
+		if key == nil {
+			var err error
+			if key, err = s.cipher.NewDataKey(ctx); err != nil {
+				restore()
+				return nil, fmt.Errorf("failed to encrypt chunk contents: %w", err)
+			}
+		}
+
+		encrypted, err := s.cipher.Encrypt(key, chunk.Contents)
+		if err != nil {
+			restore()
+			return nil, fmt.Errorf("failed to encrypt chunk %s: %w", chunk.ChunkID, err)
+		}
+		plaintexts[chunk] = chunk.Contents
+		chunk.Contents = encrypted
+	}
+
+	return restore, nil
+}
+
+// decryptChunk returns a copy of chunk with plaintext contents. Chunks from the
+// underlying service may be shared with its cache, so they are never modified.
+func (s *encryptingChunkService) decryptChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) (*models.UnifiedChunkRecord, error) {
+	if chunk == nil || !IsEncryptedContent(chunk.Contents) {
+		return chunk, nil
+	}
+	contents, err := s.cipher.Decrypt(ctx, chunk.Contents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt chunk %s: %w", chunk.ChunkID, err)
+	}
+	decrypted := *chunk
+	decrypted.Contents = contents
+	return &decrypted, nil
+}
+
+// decryptChunks decrypts a list read from the underlying service, copying it when any
+// contents are encrypted
+func (s *encryptingChunkService) decryptChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) ([]models.UnifiedChunkRecord, error) {
+	var decrypted []models.UnifiedChunkRecord
+	for i := range chunks {
+		if !IsEncryptedContent(chunks[i].Contents) {
+			continue
+		}
+		if decrypted == nil {
+			decrypted = append([]models.UnifiedChunkRecord(nil), chunks...)
+		}
+		contents, err := s.cipher.Decrypt(ctx, chunks[i].Contents)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt chunk %s: %w", chunks[i].ChunkID, err)
+		}
+		decrypted[i].Contents = contents
+	}
+
+	if decrypted == nil {
+		return chunks, nil
+	}
+	return decrypted, nil
+}
+
+// decryptChunkLists decrypts lists keyed by chunk ID, as returned by batch reads. The map
+// is copied; each list is copied when any of its contents are encrypted.
+func (s *encryptingChunkService) decryptChunkLists(ctx context.Context, lists map[string][]models.UnifiedChunkRecord) (map[string][]models.UnifiedChunkRecord, error) {
+	decrypted := make(map[string][]models.UnifiedChunkRecord, len(lists))
+	for key, chunks := range lists {
+		var err error
+		if decrypted[key], err = s.decryptChunks(ctx, chunks); err != nil {
+			return nil, err
+		}
+	}
+	return decrypted, nil
+}
+
+// chunkPointers addresses the records of a batch so they can be encrypted in place
+func chunkPointers(chunks []models.UnifiedChunkRecord) []*models.UnifiedChunkRecord {
+	pointers := make([]*models.UnifiedChunkRecord, len(chunks))
+	for i := range chunks {
+		pointers[i] = &chunks[i]
+	}
+	return pointers
+}
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryChunkService stores chunks as written, so tests see what reaches the database
+type memoryChunkService struct {
+	UnifiedChunkService
+	chunks map[string]models.UnifiedChunkRecord
+}
+
+func newMemoryChunkService() *memoryChunkService {
+	return &memoryChunkService{chunks: make(map[string]models.UnifiedChunkRecord)}
+}
+
+func (m *memoryChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	chunk.Lang = DetectLanguage(chunk.Contents)
+	m.chunks[chunk.ChunkID] = *chunk
+	return nil
+}
+
+func (m *memoryChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for i := range chunks {
+		if err := m.CreateChunk(ctx, &chunks[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryChunkService) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk := m.chunks[chunkID]
+	return &chunk, nil
+}
+
+func (m *memoryChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk := m.chunks[chunkID]
+	if patch.Contents != nil {
+		chunk.Contents = *patch.Contents
+	}
+	for key, value := range patch.Metadata {
+		chunk.Metadata[key] = value
+	}
+	m.chunks[chunkID] = chunk
+	return &chunk, nil
+}
+
+func (m *memoryChunkService) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
+	var children []models.UnifiedChunkRecord
+	for _, chunk := range m.chunks {
+		if chunk.Parent != nil && *chunk.Parent == parentChunkID {
+			children = append(children, chunk)
+		}
+	}
+	return children, nil
+}
+
+func newTestContentCipher(t *testing.T) *ContentCipher {
+	provider, err := NewLocalKeyProvider("test-key", []byte(strings.Repeat("k", 32)))
+	require.NoError(t, err)
+	return NewContentCipher(provider)
+}
+
+func TestContentEncryption_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := newMemoryChunkService()
+	service := NewEncryptingChunkService(store, newTestContentCipher(t))
+
+	parent := "page"
+	secret := &models.UnifiedChunkRecord{ChunkID: "secret", Contents: "salary review notes", Parent: &parent,
+		Metadata: map[string]interface{}{SensitiveMetadataKey: true}}
+	require.NoError(t, service.CreateChunk(ctx, secret))
+	assert.Equal(t, "salary review notes", secret.Contents, "callers keep the plaintext")
+	assert.Equal(t, LanguageUnknown, secret.Lang)
+
+	stored := store.chunks["secret"].Contents
+	assert.True(t, IsEncryptedContent(stored))
+	assert.NotContains(t, stored, "salary")
+
+	plain := &models.UnifiedChunkRecord{ChunkID: "plain", Contents: "public notes", Parent: &parent,
+		Metadata: map[string]interface{}{}}
+	require.NoError(t, service.CreateChunk(ctx, plain))
+	assert.Equal(t, "public notes", store.chunks["plain"].Contents)
+
+	got, err := service.GetChunk(ctx, "secret")
+	require.NoError(t, err)
+	assert.Equal(t, "salary review notes", got.Contents)
+	assert.Equal(t, stored, store.chunks["secret"].Contents, "reads do not modify stored chunks")
+
+	children, err := service.GetChildren(ctx, parent)
+	require.NoError(t, err)
+	contents := make([]string, len(children))
+	for i, child := range children {
+		contents[i] = child.Contents
+	}
+	assert.ElementsMatch(t, []string{"salary review notes", "public notes"}, contents)
+
+	// Patched contents of a sensitive chunk are encrypted again
+	updated := "promotion approved"
+	got, err = service.PatchChunk(ctx, "secret", &models.ChunkPatch{Contents: &updated})
+	require.NoError(t, err)
+	assert.Equal(t, "promotion approved", got.Contents)
+	assert.True(t, IsEncryptedContent(store.chunks["secret"].Contents))
+
+	// Clearing the flag stores the contents in plaintext again
+	_, err = service.PatchChunk(ctx, "secret", &models.ChunkPatch{Metadata: map[string]interface{}{SensitiveMetadataKey: false}})
+	require.NoError(t, err)
+	assert.Equal(t, "promotion approved", store.chunks["secret"].Contents)
+
+	// Setting it encrypts the existing contents
+	_, err = service.PatchChunk(ctx, "plain", &models.ChunkPatch{Metadata: map[string]interface{}{SensitiveMetadataKey: true}})
+	require.NoError(t, err)
+	assert.True(t, IsEncryptedContent(store.chunks["plain"].Contents))
+}
+
+func TestContentEncryption_BatchSharesDataKey(t *testing.T) {
+	store := newMemoryChunkService()
+	service := NewEncryptingChunkService(store, newTestContentCipher(t))
+
+	sensitive := map[string]interface{}{SensitiveMetadataKey: true}
+	chunks := []models.UnifiedChunkRecord{
+		{ChunkID: "a", Contents: "first secret", Metadata: sensitive},
+		{ChunkID: "b", Contents: "open", Metadata: map[string]interface{}{}},
+		{ChunkID: "c", Contents: "second secret", Metadata: sensitive},
+	}
+	require.NoError(t, service.BatchCreateChunks(context.Background(), chunks))
+	assert.Equal(t, "first secret", chunks[0].Contents)
+	assert.Equal(t, "open", store.chunks["b"].Contents)
+
+	envelope := func(id string) contentEnvelope {
+		t.Helper()
+		var e contentEnvelope
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(store.chunks[id].Contents, encryptedContentPrefix))
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &e))
+		return e
+	}
+	assert.Equal(t, envelope("a").Wrapped, envelope("c").Wrapped)
+	assert.NotEqual(t, envelope("a").Ciphertext, envelope("c").Ciphertext)
+}
+
+func TestLocalKeyProvider_RejectsOtherMasterKey(t *testing.T) {
+	ctx := context.Background()
+	writer := newTestContentCipher(t)
+	key, err := writer.NewDataKey(ctx)
+	require.NoError(t, err)
+	stored, err := writer.Encrypt(key, "secret")
+	require.NoError(t, err)
+
+	other, err := NewLocalKeyProvider("test-key", []byte(strings.Repeat("x", 32)))
+	require.NoError(t, err)
+	_, err = NewContentCipher(other).Decrypt(ctx, stored)
+	assert.Error(t, err)
+
+	_, err = NewLocalKeyProvider("test-key", []byte("short"))
+	assert.ErrorIs(t, err, ErrInvalidEncryptionConfig)
+}
+
+func TestKMSKeyProvider(t *testing.T) {
+	dataKey := []byte(strings.Repeat("d", 32))
+	var targets []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targets = append(targets, r.Header.Get("X-Amz-Target"))
+		assert.Equal(t, "application/x-amz-json-1.1", r.Header.Get("Content-Type"))
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKID/")
+		assert.Contains(t, r.Header.Get("Authorization"), "/us-west-2/kms/aws4_request")
+		assert.Contains(t, r.Header.Get("Authorization"), "x-amz-target")
+
+		var req map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.GenerateDataKey":
+			assert.Equal(t, "alias/notes", req["KeyId"])
+			assert.Equal(t, "AES_256", req["KeySpec"])
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"KeyId": "arn:aws:kms:us-west-2:1:key/k", "CiphertextBlob": []byte("wrapped"), "Plaintext": dataKey,
+			})
+		case "TrentService.Decrypt":
+			assert.Equal(t, "d3JhcHBlZA==", req["CiphertextBlob"])
+			json.NewEncoder(w).Encode(map[string]interface{}{"Plaintext": dataKey})
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"UnknownOperationException"}`))
+		}
+	}))
+	defer server.Close()
+
+	provider, err := NewKMSKeyProvider(config.KMSConfig{
+		KeyID: "alias/notes", Region: "us-west-2", Endpoint: server.URL,
+		AccessKeyID: "AKID", SecretAccessKey: "secret",
+	})
+	require.NoError(t, err)
+
+	key, err := provider.GenerateDataKey(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, dataKey, key.Plaintext)
+	assert.Equal(t, []byte("wrapped"), key.Wrapped)
+
+	// A fresh cipher has to ask KMS to unwrap the key
+	stored, err := NewContentCipher(provider).Encrypt(key, "quarterly numbers")
+	require.NoError(t, err)
+	plaintext, err := NewContentCipher(provider).Decrypt(context.Background(), stored)
+	require.NoError(t, err)
+	assert.Equal(t, "quarterly numbers", plaintext)
+	assert.Equal(t, []string{"TrentService.GenerateDataKey", "TrentService.Decrypt"}, targets)
+}
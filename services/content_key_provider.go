@@ -0,0 +1,303 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+)
+
+// ErrInvalidEncryptionConfig is returned when the configured key provider cannot be built
+var ErrInvalidEncryptionConfig = errors.New("invalid encryption configuration")
+
+const dataKeySize = 32
+
+// DataKey is a data encryption key in plaintext and wrapped by the master key
+type DataKey struct {
+	KeyID     string
+	Plaintext []byte
+	Wrapped   []byte
+}
+
+// ContentKeyProvider issues data keys for envelope encryption and unwraps them again.
+// Only wrapped keys are stored next to encrypted content.
+type ContentKeyProvider interface {
+	// GenerateDataKey returns a new random 256-bit data key
+	GenerateDataKey(ctx context.Context) (*DataKey, error)
+
+	// DecryptDataKey unwraps a data key wrapped under the master key keyID
+	DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// NewContentKeyProvider creates the key provider selected by cfg.Provider
+func NewContentKeyProvider(cfg config.EncryptionConfig) (ContentKeyProvider, error) {
+	switch cfg.Provider {
+	case "", "local":
+		masterKey, err := base64.StdEncoding.DecodeString(cfg.MasterKey)
+		if err != nil {
+			return nil, fmt.Errorf("%w: master key is not valid base64", ErrInvalidEncryptionConfig)
+		}
+		return NewLocalKeyProvider(cfg.KeyID, masterKey)
+	case "aws-kms":
+		return NewKMSKeyProvider(cfg.KMS)
+	default:
+		return nil, fmt.Errorf("%w: unsupported provider %q", ErrInvalidEncryptionConfig, cfg.Provider)
+	}
+}
+
+// localKeyProvider wraps data keys with AES-256-GCM under a master key held in memory
+type localKeyProvider struct {
+	keyID  string
+	master cipher.AEAD
+}
+
+// NewLocalKeyProvider creates a key provider from a 32-byte master key
+func NewLocalKeyProvider(keyID string, masterKey []byte) (ContentKeyProvider, error) {
+	if keyID == "" {
+		return nil, fmt.Errorf("%w: key ID is required", ErrInvalidEncryptionConfig)
+	}
+	if len(masterKey) != dataKeySize {
+		return nil, fmt.Errorf("%w: master key must be %d bytes, got %d", ErrInvalidEncryptionConfig, dataKeySize, len(masterKey))
+	}
+	aead, err := newGCM(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return &localKeyProvider{keyID: keyID, master: aead}, nil
+}
+
+// GenerateDataKey creates a random data key and seals it with the master key
+func (p *localKeyProvider) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	plaintext := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, plaintext); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := gcmSeal(p.master, plaintext, []byte(p.keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return &DataKey{KeyID: p.keyID, Plaintext: plaintext, Wrapped: wrapped}, nil
+}
+
+// DecryptDataKey opens a data key sealed by GenerateDataKey
+func (p *localKeyProvider) DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != p.keyID {
+		return nil, fmt.Errorf("data key was wrapped with unknown master key %q", keyID)
+	}
+	plaintext, err := gcmOpen(p.master, wrapped, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return plaintext, nil
+}
+
+// kmsKeyProvider wraps data keys with AWS KMS through its JSON API, signed with Signature V4
+type kmsKeyProvider struct {
+	cfg        config.KMSConfig
+	endpoint   *url.URL
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewKMSKeyProvider creates a key provider backed by an AWS KMS key
+func NewKMSKeyProvider(cfg config.KMSConfig) (ContentKeyProvider, error) {
+	if cfg.KeyID == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%w: kms requires a key ID and credentials", ErrInvalidEncryptionConfig)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://kms.%s.amazonaws.com", cfg.Region)
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: invalid kms endpoint %q", ErrInvalidEncryptionConfig, cfg.Endpoint)
+	}
+
+	return &kmsKeyProvider{
+		cfg:      cfg,
+		endpoint: endpoint,
+		httpClient: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// GenerateDataKey asks KMS for a new AES-256 data key
+func (p *kmsKeyProvider) GenerateDataKey(ctx context.Context) (*DataKey, error) {
+	var resp struct {
+		KeyID          string `json:"KeyId"`
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+		Plaintext      []byte `json:"Plaintext"`
+	}
+	err := p.call(ctx, "GenerateDataKey", map[string]interface{}{
+		"KeyId":   p.cfg.KeyID,
+		"KeySpec": "AES_256",
+	}, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	if len(resp.Plaintext) != dataKeySize {
+		return nil, fmt.Errorf("failed to generate data key: kms returned a %d-byte key", len(resp.Plaintext))
+	}
+	return &DataKey{KeyID: resp.KeyID, Plaintext: resp.Plaintext, Wrapped: resp.CiphertextBlob}, nil
+}
+
+// DecryptDataKey asks KMS to unwrap a data key
+func (p *kmsKeyProvider) DecryptDataKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	request := map[string]interface{}{"CiphertextBlob": wrapped}
+	if keyID != "" {
+		request["KeyId"] = keyID
+	}
+	if err := p.call(ctx, "Decrypt", request, &resp); err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+	}
+	return resp.Plaintext, nil
+}
+
+// call invokes a KMS action. []byte fields travel base64-encoded, as KMS expects.
+func (p *kmsKeyProvider) call(ctx context.Context, action string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kms request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint.String()+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create kms request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+action)
+	if p.cfg.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", p.cfg.SessionToken)
+	}
+	payloadHash := sha256.Sum256(body)
+	p.signRequest(req, hex.EncodeToString(payloadHash[:]), p.now())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("kms request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var kmsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &kmsErr) == nil && kmsErr.Type != "" {
+			return fmt.Errorf("kms %s failed with status %d: %s %s", action, resp.StatusCode, kmsErr.Type, kmsErr.Message)
+		}
+		return fmt.Errorf("kms %s failed with status %d: %s", action, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return fmt.Errorf("failed to decode kms response: %w", err)
+	}
+	return nil
+}
+
+// signRequest signs a KMS request with Signature V4 over host, content type and x-amz-* headers
+func (p *kmsKeyProvider) signRequest(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	names := []string{"content-type", "host", "x-amz-date"}
+	values := map[string]string{
+		"content-type": req.Header.Get("Content-Type"),
+		"host":         req.URL.Host,
+		"x-amz-date":   amzDate,
+	}
+	if token := req.Header.Get("X-Amz-Security-Token"); token != "" {
+		names = append(names, "x-amz-security-token")
+		values["x-amz-security-token"] = token
+	}
+	names = append(names, "x-amz-target")
+	values["x-amz-target"] = req.Header.Get("X-Amz-Target")
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := fmt.Sprintf("%s/%s/kms/aws4_request", t.Format("20060102"), p.cfg.Region)
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+p.cfg.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, p.cfg.Region)
+	key = hmacSHA256(key, "kms")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, p.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// newGCM creates an AES-GCM cipher for a 256-bit key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// gcmSeal encrypts plaintext under a random nonce, returning nonce followed by ciphertext
+func gcmSeal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// gcmOpen decrypts the output of gcmSeal
+func gcmOpen(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
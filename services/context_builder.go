@@ -0,0 +1,247 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"semantic-text-processor/models"
+)
+
+// Tokenizer counts the tokens text takes in a model's context window
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a counting function to Tokenizer
+type TokenizerFunc func(text string) int
+
+// CountTokens calls f
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// EstimatingTokenizer approximates token counts without a model vocabulary: CJK characters
+// count as one token each and other text as one token per four characters
+var EstimatingTokenizer Tokenizer = TokenizerFunc(estimateTokens)
+
+// Context block orders
+const (
+	ContextOrderRelevance = "relevance"
+	ContextOrderDocument  = "document"
+)
+
+// ContextBuilder packs ranked retrieval results into a token budget. Results whose content
+// another result already contains, duplicates and chunks nested in a retrieved parent, are
+// folded into that result's block, and every block keeps the chunk it came from for
+// attribution.
+type ContextBuilder struct {
+	tokenizer Tokenizer
+	maxTokens int
+	order     string
+}
+
+// NewContextBuilder creates a builder for a budget of maxTokens. A nil tokenizer uses
+// EstimatingTokenizer.
+func NewContextBuilder(tokenizer Tokenizer, maxTokens int) *ContextBuilder {
+	if tokenizer == nil {
+		tokenizer = EstimatingTokenizer
+	}
+	return &ContextBuilder{tokenizer: tokenizer, maxTokens: maxTokens, order: ContextOrderRelevance}
+}
+
+// WithOrder sets the order of the emitted blocks, ContextOrderRelevance or ContextOrderDocument
+func (b *ContextBuilder) WithOrder(order string) *ContextBuilder {
+	if order == ContextOrderDocument {
+		b.order = order
+	} else {
+		b.order = ContextOrderRelevance
+	}
+	return b
+}
+
+// contextCandidate is a result that survived deduplication
+type contextCandidate struct {
+	result     models.SimilarityResult
+	content    string
+	normalized string
+	covers     []string
+}
+
+// Build deduplicates results, given best first, and greedily packs them into the budget.
+// A top result larger than the whole budget is truncated rather than dropped.
+func (b *ContextBuilder) Build(results []models.SimilarityResult) *models.AssembledContext {
+	assembled := &models.AssembledContext{Blocks: []models.ContextBlock{}, MaxTokens: b.maxTokens}
+	candidates := b.deduplicate(results, assembled)
+
+	remaining := b.maxTokens
+	for _, candidate := range candidates {
+		if remaining <= 0 {
+			assembled.Omitted = append(assembled.Omitted, candidate.result.Chunk.ID)
+			continue
+		}
+
+		content := candidate.content
+		tokens := b.tokenizer.CountTokens(content)
+		truncated := false
+		if tokens > remaining {
+			if len(assembled.Blocks) > 0 {
+				assembled.Omitted = append(assembled.Omitted, candidate.result.Chunk.ID)
+				continue
+			}
+			content = b.truncate(content, remaining)
+			tokens = b.tokenizer.CountTokens(content)
+			truncated = true
+		}
+
+		remaining -= tokens
+		assembled.TotalTokens += tokens
+		chunk := candidate.result.Chunk
+		assembled.Blocks = append(assembled.Blocks, models.ContextBlock{
+			ChunkID:       chunk.ID,
+			TextID:        chunk.TextID,
+			ParentChunkID: chunk.ParentChunkID,
+			Content:       content,
+			Tokens:        tokens,
+			Similarity:    candidate.result.Similarity,
+			Truncated:     truncated,
+			Covers:        candidate.covers,
+		})
+	}
+
+	if b.order == ContextOrderDocument {
+		sortBlocksByDocument(assembled.Blocks, results)
+	}
+	for i := range assembled.Blocks {
+		assembled.Blocks[i].Index = i + 1
+	}
+	return assembled
+}
+
+// deduplicate drops empty and repeated results and folds related chunks whose content the
+// other contains into one candidate, at the better rank
+func (b *ContextBuilder) deduplicate(results []models.SimilarityResult, assembled *models.AssembledContext) []*contextCandidate {
+	parents := make(map[string]string, len(results))
+	for _, result := range results {
+		if result.Chunk.ParentChunkID != nil {
+			parents[result.Chunk.ID] = *result.Chunk.ParentChunkID
+		}
+	}
+	related := func(a, b string) bool {
+		return isContextAncestor(parents, a, b) || isContextAncestor(parents, b, a)
+	}
+
+	var candidates []*contextCandidate
+	byID := make(map[string]*contextCandidate)
+	// byContent also keeps the contents of folded chunks, which a block still includes
+	byContent := make(map[string]*contextCandidate)
+	for _, result := range results {
+		content := strings.TrimSpace(result.Chunk.Content)
+		if content == "" {
+			continue
+		}
+		if byID[result.Chunk.ID] != nil {
+			continue
+		}
+		normalized := strings.Join(strings.Fields(content), " ")
+		if existing := byContent[normalized]; existing != nil {
+			existing.covers = append(existing.covers, result.Chunk.ID)
+			byID[result.Chunk.ID] = existing
+			assembled.Deduplicated++
+			continue
+		}
+
+		folded := false
+		for _, existing := range candidates {
+			switch {
+			case related(existing.result.Chunk.ID, result.Chunk.ID) && strings.Contains(existing.normalized, normalized):
+				existing.covers = append(existing.covers, result.Chunk.ID)
+				folded = true
+			case related(existing.result.Chunk.ID, result.Chunk.ID) && strings.Contains(normalized, existing.normalized):
+				// The lower-ranked chunk contains the better one: keep the rank, take the content
+				existing.covers = append(existing.covers, existing.result.Chunk.ID)
+				existing.result = models.SimilarityResult{Chunk: result.Chunk, Similarity: existing.result.Similarity}
+				existing.content, existing.normalized = content, normalized
+				folded = true
+			}
+			if folded {
+				byID[result.Chunk.ID] = existing
+				byContent[normalized] = existing
+				assembled.Deduplicated++
+				break
+			}
+		}
+		if folded {
+			continue
+		}
+
+		candidate := &contextCandidate{result: result, content: content, normalized: normalized}
+		candidates = append(candidates, candidate)
+		byID[result.Chunk.ID] = candidate
+		byContent[normalized] = candidate
+	}
+	return candidates
+}
+
+// isContextAncestor reports whether ancestor is reachable from chunkID through the parents
+// of retrieved chunks
+func isContextAncestor(parents map[string]string, ancestor, chunkID string) bool {
+	seen := make(map[string]bool)
+	for current, ok := parents[chunkID]; ok && !seen[current]; current, ok = parents[current] {
+		if current == ancestor {
+			return true
+		}
+		seen[current] = true
+	}
+	return false
+}
+
+// truncate cuts text to the largest prefix that fits budget tokens
+func (b *ContextBuilder) truncate(text string, budget int) string {
+	if budget <= 0 {
+		return ""
+	}
+	runes := []rune(text)
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if b.tokenizer.CountTokens(string(runes[:mid])) <= budget {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return string(runes[:low])
+}
+
+// sortBlocksByDocument groups blocks by text in order of each text's best block and orders
+// blocks within a text by their sequence number
+func sortBlocksByDocument(blocks []models.ContextBlock, results []models.SimilarityResult) {
+	sequence := make(map[string]int, len(results))
+	for _, result := range results {
+		if result.Chunk.SequenceNumber != nil {
+			sequence[result.Chunk.ID] = *result.Chunk.SequenceNumber
+		}
+	}
+	textRank := make(map[string]int)
+	for i, block := range blocks {
+		if _, ok := textRank[block.TextID]; !ok {
+			textRank[block.TextID] = i
+		}
+	}
+	sort.SliceStable(blocks, func(i, j int) bool {
+		if blocks[i].TextID != blocks[j].TextID {
+			return textRank[blocks[i].TextID] < textRank[blocks[j].TextID]
+		}
+		return sequence[blocks[i].ChunkID] < sequence[blocks[j].ChunkID]
+	})
+}
+
+// RenderContext lays out blocks as numbered sources for a prompt
+func RenderContext(assembled *models.AssembledContext) string {
+	var rendered strings.Builder
+	for _, block := range assembled.Blocks {
+		rendered.WriteString(fmt.Sprintf("[%d] %s\n\n", block.Index, block.Content))
+	}
+	return rendered.String()
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func childResult(id, parent, textID, content string, sequence int, score float64) models.SimilarityResult {
+	result := similarity(id, content, score)
+	if parent != "" {
+		result.Chunk.ParentChunkID = &parent
+	}
+	result.Chunk.TextID = textID
+	result.Chunk.SequenceNumber = &sequence
+	return result
+}
+
+func TestContextBuilder_Deduplicates(t *testing.T) {
+	results := []models.SimilarityResult{
+		childResult("child", "parent", "t1", "Go has goroutines.", 2, 0.9),
+		childResult("parent", "", "t1", "Concurrency in Go. Go has goroutines. Channels connect them.", 1, 0.8),
+		childResult("copy", "", "t2", "Go  has goroutines.", 1, 0.7),
+		childResult("sibling", "parent", "t1", "Channels connect them.", 3, 0.6),
+		childResult("other", "", "t2", "Unrelated note.", 2, 0.5),
+	}
+
+	assembled := NewContextBuilder(nil, 1000).Build(results)
+
+	require.Len(t, assembled.Blocks, 2)
+	// The parent containing the top child takes the child's rank
+	parent := assembled.Blocks[0]
+	assert.Equal(t, "parent", parent.ChunkID)
+	assert.Equal(t, 0.9, parent.Similarity)
+	assert.ElementsMatch(t, []string{"child", "copy", "sibling"}, parent.Covers)
+	assert.Equal(t, "other", assembled.Blocks[1].ChunkID)
+	assert.Equal(t, 2, assembled.Blocks[1].Index)
+	assert.Equal(t, 3, assembled.Deduplicated)
+	assert.Equal(t, parent.Tokens+assembled.Blocks[1].Tokens, assembled.TotalTokens)
+}
+
+func TestContextBuilder_Budget(t *testing.T) {
+	words := TokenizerFunc(func(text string) int { return len(strings.Fields(text)) })
+	results := []models.SimilarityResult{
+		similarity("a", "one two three", 0.9),
+		similarity("b", "four five six seven", 0.8),
+		similarity("c", "eight", 0.7),
+	}
+
+	t.Run("uses the pluggable tokenizer", func(t *testing.T) {
+		assembled := NewContextBuilder(words, 5).Build(results)
+		require.Len(t, assembled.Blocks, 2)
+		assert.Equal(t, "a", assembled.Blocks[0].ChunkID)
+		assert.Equal(t, "c", assembled.Blocks[1].ChunkID)
+		assert.Equal(t, 4, assembled.TotalTokens)
+		assert.Equal(t, []string{"b"}, assembled.Omitted)
+	})
+
+	t.Run("truncates an oversized top result", func(t *testing.T) {
+		assembled := NewContextBuilder(words, 2).Build(results[1:2])
+		require.Len(t, assembled.Blocks, 1)
+		assert.True(t, assembled.Blocks[0].Truncated)
+		assert.LessOrEqual(t, assembled.Blocks[0].Tokens, 2)
+	})
+}
+
+func TestContextBuilder_DocumentOrder(t *testing.T) {
+	results := []models.SimilarityResult{
+		childResult("t2-b", "", "t2", "Second text, later block", 5, 0.9),
+		childResult("t1-a", "", "t1", "First text", 1, 0.8),
+		childResult("t2-a", "", "t2", "Second text, early block", 1, 0.7),
+	}
+
+	assembled := NewContextBuilder(nil, 1000).WithOrder(ContextOrderDocument).Build(results)
+
+	var order []string
+	for _, block := range assembled.Blocks {
+		order = append(order, block.ChunkID)
+	}
+	assert.Equal(t, []string{"t2-a", "t2-b", "t1-a"}, order)
+	assert.Equal(t, 1, assembled.Blocks[0].Index)
+	assert.Contains(t, RenderContext(assembled), "[3] First text")
+}
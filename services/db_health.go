@@ -0,0 +1,314 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"semantic-text-processor/models"
+	"sync"
+	"time"
+)
+
+// Connection pool alert types raised through the performance monitor
+const (
+	AlertPoolExhausted = "connection_pool_exhausted"
+	AlertPoolSaturated = "connection_pool_saturated"
+	AlertPoolWaits     = "connection_pool_waits"
+)
+
+const maxDatabaseHealthAlerts = 20
+
+// DBHealthConfig holds connection pool diagnostics settings
+type DBHealthConfig struct {
+	// WarnUtilization is the fraction of MaxOpenConns in use that raises a warning
+	WarnUtilization float64
+	// CheckInterval controls how often the pool is sampled for warnings
+	CheckInterval time.Duration
+	// PingTimeout bounds the connectivity check made by CheckHealth
+	PingTimeout time.Duration
+}
+
+// DatabaseHealth reports live connectivity and connection pool diagnostics
+type DatabaseHealth struct {
+	Status      HealthStatus         `json:"status"`
+	Message     string               `json:"message,omitempty"`
+	Stats       models.DatabaseStats `json:"stats"`
+	Utilization float64              `json:"utilization"`
+	PingLatency time.Duration        `json:"ping_latency"`
+	Warnings    []string             `json:"warnings,omitempty"`
+	Alerts      []AlertRecord        `json:"alerts,omitempty"`
+	CheckedAt   time.Time            `json:"checked_at"`
+}
+
+// DBHealthService reports the state of the database/sql pool services query through.
+// While started it samples the pool periodically and raises exhaustion, saturation
+// and wait alerts on the performance monitor.
+type DBHealthService interface {
+	// Stats returns current pool and query statistics
+	Stats() models.DatabaseStats
+
+	// CheckHealth pings the database and reports pool statistics and warnings
+	CheckHealth(ctx context.Context) DatabaseHealth
+
+	Start(ctx context.Context)
+	Stop()
+}
+
+// dbHealthService implements DBHealthService over a *sql.DB
+type dbHealthService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+	cfg     DBHealthConfig
+
+	mu sync.Mutex
+	// last is the sample the previous pool check saw, so waits are reported once
+	last models.DatabaseStats
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewDBHealthService creates a pool diagnostics service. Query counts come from monitor;
+// alerts are raised on it when it is an EnhancedPerformanceMonitor and logged otherwise.
+func NewDBHealthService(db *sql.DB, monitor QueryPerformanceMonitor, cfg DBHealthConfig) DBHealthService {
+	if monitor == nil {
+		monitor = NewNoOpMonitor()
+	}
+	if cfg.WarnUtilization <= 0 || cfg.WarnUtilization > 1 {
+		cfg.WarnUtilization = 0.9
+	}
+	if cfg.CheckInterval <= 0 {
+		cfg.CheckInterval = 30 * time.Second
+	}
+	if cfg.PingTimeout <= 0 {
+		cfg.PingTimeout = 2 * time.Second
+	}
+
+	return &dbHealthService{
+		db:      db,
+		monitor: monitor,
+		cfg:     cfg,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Stats reads the live pool statistics
+func (s *dbHealthService) Stats() models.DatabaseStats {
+	pool := s.db.Stats()
+	queries := s.monitor.GetQueryStats()
+
+	return models.DatabaseStats{
+		ActiveConnections:    pool.InUse,
+		IdleConnections:      pool.Idle,
+		TotalConnections:     pool.OpenConnections,
+		MaxConnections:       pool.MaxOpenConnections,
+		QueryCount:           queries.TotalQueries,
+		SlowQueryCount:       queries.SlowQueries,
+		AverageQueryDuration: queries.AverageTime,
+		WaitCount:            pool.WaitCount,
+		WaitDuration:         pool.WaitDuration,
+		MaxIdleClosed:        pool.MaxIdleClosed,
+		MaxLifetimeClosed:    pool.MaxLifetimeClosed,
+	}
+}
+
+// CheckHealth pings the database and evaluates the pool against the warning thresholds.
+// Waits are counted since the last periodic check.
+func (s *dbHealthService) CheckHealth(ctx context.Context) DatabaseHealth {
+	pingCtx, cancel := context.WithTimeout(ctx, s.cfg.PingTimeout)
+	defer cancel()
+
+	start := time.Now()
+	pingErr := s.db.PingContext(pingCtx)
+	latency := time.Since(start)
+
+	stats := s.Stats()
+	s.mu.Lock()
+	previous := s.last
+	s.mu.Unlock()
+
+	health := DatabaseHealth{
+		Status:      HealthStatusHealthy,
+		Stats:       stats,
+		Utilization: poolUtilization(stats),
+		PingLatency: latency,
+		CheckedAt:   time.Now(),
+	}
+	for _, warning := range checkPool(stats, previous, s.cfg.WarnUtilization) {
+		health.Warnings = append(health.Warnings, warning.message)
+	}
+	if enhanced, ok := s.monitor.(EnhancedPerformanceMonitor); ok {
+		health.Alerts = poolAlerts(enhanced.GetAlerts(0), maxDatabaseHealthAlerts)
+	}
+
+	switch {
+	case pingErr != nil:
+		health.Status = HealthStatusUnhealthy
+		health.Message = fmt.Sprintf("database ping failed: %v", pingErr)
+	case len(health.Warnings) > 0:
+		health.Status = HealthStatusDegraded
+		health.Message = health.Warnings[0]
+	default:
+		health.Message = "Database connection pool healthy"
+	}
+
+	return health
+}
+
+// Start samples the pool every CheckInterval until Stop is called or ctx is done
+func (s *dbHealthService) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.cfg.CheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.report(s.Stats())
+			}
+		}
+	}()
+}
+
+// Stop ends periodic pool checks
+func (s *dbHealthService) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+}
+
+// report raises an alert for every pool warning in the sample and remembers it as the
+// baseline for the next check
+func (s *dbHealthService) report(current models.DatabaseStats) {
+	s.mu.Lock()
+	previous := s.last
+	s.last = current
+	s.mu.Unlock()
+
+	enhanced, _ := s.monitor.(EnhancedPerformanceMonitor)
+	for _, warning := range checkPool(current, previous, s.cfg.WarnUtilization) {
+		if enhanced == nil {
+			log.Printf("DATABASE POOL [%s]: %s - %s", warning.severity, warning.alertType, warning.message)
+			continue
+		}
+		enhanced.RecordAlert(warning.alertType, warning.message, warning.severity, map[string]interface{}{
+			"active_connections": current.ActiveConnections,
+			"idle_connections":   current.IdleConnections,
+			"max_connections":    current.MaxConnections,
+			"wait_count":         current.WaitCount - previous.WaitCount,
+			"wait_duration":      (current.WaitDuration - previous.WaitDuration).String(),
+		})
+	}
+}
+
+// poolWarning is a pool condition worth alerting on
+type poolWarning struct {
+	alertType string
+	severity  string
+	message   string
+}
+
+// checkPool compares a pool sample with the warning thresholds. Waits are counted since
+// the previous sample, since database/sql only reports cumulative totals.
+func checkPool(current, previous models.DatabaseStats, warnUtilization float64) []poolWarning {
+	var warnings []poolWarning
+
+	if current.MaxConnections > 0 {
+		if current.ActiveConnections >= current.MaxConnections {
+			warnings = append(warnings, poolWarning{
+				alertType: AlertPoolExhausted,
+				severity:  "critical",
+				message:   fmt.Sprintf("Connection pool exhausted: all %d connections in use", current.MaxConnections),
+			})
+		} else if utilization := poolUtilization(current); utilization >= warnUtilization {
+			warnings = append(warnings, poolWarning{
+				alertType: AlertPoolSaturated,
+				severity:  "warning",
+				message: fmt.Sprintf("Connection pool %.0f%% utilized: %d of %d connections in use",
+					utilization*100, current.ActiveConnections, current.MaxConnections),
+			})
+		}
+	}
+
+	// Cumulative counters restart when the handle is reopened
+	waits := current.WaitCount - previous.WaitCount
+	waited := current.WaitDuration - previous.WaitDuration
+	if waits < 0 {
+		waits, waited = current.WaitCount, current.WaitDuration
+	}
+	if waits > 0 {
+		warnings = append(warnings, poolWarning{
+			alertType: AlertPoolWaits,
+			severity:  "warning",
+			message:   fmt.Sprintf("%d queries waited %v in total for a free connection", waits, waited),
+		})
+	}
+
+	return warnings
+}
+
+// poolUtilization is the fraction of the connection limit in use, 0 when unlimited
+func poolUtilization(stats models.DatabaseStats) float64 {
+	if stats.MaxConnections <= 0 {
+		return 0
+	}
+	return float64(stats.ActiveConnections) / float64(stats.MaxConnections)
+}
+
+// poolAlerts keeps the most recent connection pool alerts
+func poolAlerts(alerts []AlertRecord, limit int) []AlertRecord {
+	var pool []AlertRecord
+	for _, alert := range alerts {
+		switch alert.Type {
+		case AlertPoolExhausted, AlertPoolSaturated, AlertPoolWaits:
+			pool = append(pool, alert)
+		}
+	}
+	if len(pool) > limit {
+		pool = pool[len(pool)-limit:]
+	}
+	return pool
+}
+
+// DBPoolHealthChecker reports connection pool diagnostics as a system health component
+type DBPoolHealthChecker struct {
+	name    string
+	service DBHealthService
+}
+
+// NewDBPoolHealthChecker creates a connection pool health checker
+func NewDBPoolHealthChecker(name string, service DBHealthService) *DBPoolHealthChecker {
+	return &DBPoolHealthChecker{
+		name:    name,
+		service: service,
+	}
+}
+
+// Name returns the checker name
+func (c *DBPoolHealthChecker) Name() string {
+	return c.name
+}
+
+// Check performs the connection pool health check
+func (c *DBPoolHealthChecker) Check(ctx context.Context) ComponentHealth {
+	start := time.Now()
+	health := c.service.CheckHealth(ctx)
+
+	return ComponentHealth{
+		Name:    c.name,
+		Status:  health.Status,
+		Message: health.Message,
+		Details: map[string]interface{}{
+			"active_connections": health.Stats.ActiveConnections,
+			"idle_connections":   health.Stats.IdleConnections,
+			"max_connections":    health.Stats.MaxConnections,
+			"utilization":        health.Utilization,
+			"wait_count":         health.Stats.WaitCount,
+			"ping_latency":       health.PingLatency.String(),
+		},
+		Timestamp: time.Now(),
+		Duration:  time.Since(start),
+	}
+}
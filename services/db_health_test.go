@@ -0,0 +1,65 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"semantic-text-processor/database"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPool(t *testing.T) {
+	alertTypes := func(warnings []poolWarning) []string {
+		types := []string{}
+		for _, warning := range warnings {
+			types = append(types, warning.alertType)
+		}
+		return types
+	}
+
+	idle := models.DatabaseStats{ActiveConnections: 2, MaxConnections: 10}
+	assert.Empty(t, alertTypes(checkPool(idle, models.DatabaseStats{}, 0.9)))
+
+	busy := models.DatabaseStats{ActiveConnections: 9, MaxConnections: 10}
+	assert.Equal(t, []string{AlertPoolSaturated}, alertTypes(checkPool(busy, models.DatabaseStats{}, 0.9)))
+
+	exhausted := models.DatabaseStats{ActiveConnections: 10, MaxConnections: 10, WaitCount: 7, WaitDuration: time.Second}
+	previous := models.DatabaseStats{WaitCount: 4, WaitDuration: 400 * time.Millisecond}
+	warnings := checkPool(exhausted, previous, 0.9)
+	assert.Equal(t, []string{AlertPoolExhausted, AlertPoolWaits}, alertTypes(warnings))
+	assert.Contains(t, warnings[1].message, "3 queries waited 600ms")
+
+	assert.Equal(t, []string{AlertPoolExhausted}, alertTypes(checkPool(exhausted, exhausted, 0.9)), "waits already reported are not repeated")
+
+	unlimited := models.DatabaseStats{ActiveConnections: 500}
+	assert.Empty(t, alertTypes(checkPool(unlimited, models.DatabaseStats{}, 0.9)))
+}
+
+func TestDBHealthService_StatsAndAlerts(t *testing.T) {
+	// sql.Open does not connect, so pool statistics are available without a database
+	db, err := sql.Open("postgres", "host=localhost dbname=unused")
+	require.NoError(t, err)
+	defer db.Close()
+	database.ConfigurePool(db, database.SQLPoolConfig{MaxOpenConns: 8, MaxIdleConns: 2})
+
+	monitor := NewInMemoryPerformanceMonitor(time.Second, 10)
+	defer monitor.Stop()
+	monitor.RecordQuery("get_chunk", 10*time.Millisecond, 1)
+
+	service := NewDBHealthService(db, monitor, DBHealthConfig{WarnUtilization: 0.75}).(*dbHealthService)
+	stats := service.Stats()
+	assert.Equal(t, 8, stats.MaxConnections)
+	assert.Equal(t, int64(1), stats.QueryCount)
+
+	service.report(models.DatabaseStats{ActiveConnections: 8, MaxConnections: 8, WaitCount: 2, WaitDuration: time.Second})
+	alerts := poolAlerts(monitor.GetAlerts(0), maxDatabaseHealthAlerts)
+	require.Len(t, alerts, 2)
+	assert.Equal(t, AlertPoolExhausted, alerts[0].Type)
+	assert.Equal(t, "critical", alerts[0].Severity)
+	assert.Equal(t, AlertPoolWaits, alerts[1].Type)
+	assert.Equal(t, int64(2), alerts[1].Details["wait_count"])
+}
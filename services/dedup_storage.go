@@ -0,0 +1,430 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/lib/pq"
+)
+
+// dedupBlobPrefix 去重後的內容存放於 blobs/<雜湊前兩碼>/<sha256>
+const dedupBlobPrefix = "blobs/"
+
+// defaultDedupTopDuplicates 去重報告預設列出的重複 blob 數
+const defaultDedupTopDuplicates = 10
+
+// DedupStorage 以內容雜湊定址的 StorageService：相同內容只儲存一次，物件鍵值成為
+// 指向 blob 的參照並計數，沒有參照的 blob 由垃圾回收刪除。
+// 啟用前寫入的物件沒有對應紀錄，讀取與刪除時直接轉給底層儲存。
+type DedupStorage struct {
+	db          *sql.DB
+	storage     StorageService
+	maxSize     int64
+	gracePeriod time.Duration
+	monitor     QueryPerformanceMonitor
+}
+
+// NewDedupStorage 建立去重儲存，參照記錄於 attachment_blobs 與 attachment_objects
+// （database/attachment_dedup_migration.sql）
+func NewDedupStorage(db *sql.DB, storage StorageService, cfg *config.StorageConfig, monitor QueryPerformanceMonitor) *DedupStorage {
+	return &DedupStorage{
+		db:          db,
+		storage:     storage,
+		maxSize:     cfg.MaxObjectSize,
+		gracePeriod: cfg.DedupGracePeriod,
+		monitor:     monitor,
+	}
+}
+
+// dedupBlobKey 回傳內容雜湊對應的 blob 鍵值
+func dedupBlobKey(hash string) string {
+	return dedupBlobPrefix + hash[:2] + "/" + hash
+}
+
+// Put 上傳物件；內容已存在時只新增參照，不重複上傳
+func (s *DedupStorage) Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("dedup_storage_put", time.Since(start), 1)
+	}()
+
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(key, dedupBlobPrefix) {
+		return nil, fmt.Errorf("object key must not start with %q: %s", dedupBlobPrefix, key)
+	}
+	data, contentType, err := readObjectBody(key, r, opts, s.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var previous string
+	err = tx.QueryRowContext(ctx, "SELECT hash FROM attachment_objects WHERE object_key = $1 FOR UPDATE", key).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to look up object: %w", err)
+	}
+	if previous != "" && (opts == nil || !opts.Overwrite) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectExists, key)
+	}
+
+	url, err := s.ensureBlob(ctx, tx, hash, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	var createdAt time.Time
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO attachment_objects (object_key, hash, content_type) VALUES ($1, $2, $3)
+		ON CONFLICT (object_key) DO UPDATE SET hash = EXCLUDED.hash, content_type = EXCLUDED.content_type, created_at = NOW()
+		RETURNING created_at`, key, hash, contentType).Scan(&createdAt); err != nil {
+		return nil, fmt.Errorf("failed to record object: %w", err)
+	}
+	if previous != hash {
+		if err := adjustBlobReferences(ctx, tx, hash, 1); err != nil {
+			return nil, err
+		}
+		if previous != "" {
+			if err := adjustBlobReferences(ctx, tx, previous, -1); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit object: %w", err)
+	}
+	return &models.StorageObject{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		ETag:         hash,
+		URL:          url,
+		StorageType:  s.storage.Backend(),
+		LastModified: createdAt,
+	}, nil
+}
+
+// ensureBlob 確保內容已上傳並回傳其公開 URL。同時上傳相同內容時兩者寫入同一個
+// blob 鍵值，內容一致，因此不需額外鎖定。
+func (s *DedupStorage) ensureBlob(ctx context.Context, tx *sql.Tx, hash string, data []byte, contentType string) (string, error) {
+	var url string
+	err := tx.QueryRowContext(ctx, "SELECT url FROM attachment_blobs WHERE hash = $1 FOR UPDATE", hash).Scan(&url)
+	if err == nil {
+		return url, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to look up blob: %w", err)
+	}
+
+	blobKey := dedupBlobKey(hash)
+	object, err := s.storage.Put(ctx, blobKey, bytes.NewReader(data), &models.PutObjectOptions{
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Overwrite:   true,
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO attachment_blobs (hash, storage_key, size, content_type, url) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (hash) DO NOTHING`,
+		hash, blobKey, len(data), contentType, object.URL); err != nil {
+		return "", fmt.Errorf("failed to record blob: %w", err)
+	}
+	return object.URL, nil
+}
+
+// adjustBlobReferences 增減 blob 的參照數，歸零時記錄時間供垃圾回收判斷
+func adjustBlobReferences(ctx context.Context, tx *sql.Tx, hash string, delta int) error {
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE attachment_blobs
+		SET ref_count = ref_count + $2,
+		    unreferenced_at = CASE WHEN ref_count + $2 = 0 THEN NOW() ELSE NULL END
+		WHERE hash = $1`, hash, delta); err != nil {
+		return fmt.Errorf("failed to update blob references: %w", err)
+	}
+	return nil
+}
+
+// resolve 回傳物件鍵值所參照的 blob；沒有紀錄時回傳 nil
+func (s *DedupStorage) resolve(ctx context.Context, key string) (*models.StorageObject, string, error) {
+	var object models.StorageObject
+	var blobKey string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT o.hash, o.content_type, o.created_at, b.storage_key, b.size, b.url
+		FROM attachment_objects o JOIN attachment_blobs b ON b.hash = o.hash
+		WHERE o.object_key = $1`, key).
+		Scan(&object.ETag, &object.ContentType, &object.LastModified, &blobKey, &object.Size, &object.URL)
+	if err == sql.ErrNoRows {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to look up object: %w", err)
+	}
+	object.Key = key
+	object.StorageType = s.storage.Backend()
+	return &object, blobKey, nil
+}
+
+// Get 下載物件
+func (s *DedupStorage) Get(ctx context.Context, key string) (io.ReadCloser, *models.StorageObject, error) {
+	object, blobKey, err := s.resolve(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if object == nil {
+		return s.storage.Get(ctx, key)
+	}
+	body, _, err := s.storage.Get(ctx, blobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, object, nil
+}
+
+// SignedURL 產生有時效的下載 URL
+func (s *DedupStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	object, blobKey, err := s.resolve(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	if object == nil {
+		return s.storage.SignedURL(ctx, key, expiry)
+	}
+	return s.storage.SignedURL(ctx, blobKey, expiry)
+}
+
+// Delete 刪除物件鍵值並減少 blob 的參照數；blob 本身由垃圾回收刪除
+func (s *DedupStorage) Delete(ctx context.Context, key string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var hash string
+	err = tx.QueryRowContext(ctx, "DELETE FROM attachment_objects WHERE object_key = $1 RETURNING hash", key).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return s.storage.Delete(ctx, key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+	if err := adjustBlobReferences(ctx, tx, hash, -1); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit object deletion: %w", err)
+	}
+	return nil
+}
+
+// List 列出指定前綴下的物件，包含啟用去重前寫入的物件，不含 blob 本身
+func (s *DedupStorage) List(ctx context.Context, prefix string, limit int) ([]models.StorageObject, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT o.object_key, o.hash, o.content_type, o.created_at, b.size, b.url
+		FROM attachment_objects o JOIN attachment_blobs b ON b.hash = o.hash
+		WHERE starts_with(o.object_key, $1)
+		ORDER BY o.object_key
+		LIMIT $2`, prefix, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	defer rows.Close()
+
+	objects := []models.StorageObject{}
+	listed := make(map[string]bool)
+	for rows.Next() {
+		object := models.StorageObject{StorageType: s.storage.Backend()}
+		if err := rows.Scan(&object.Key, &object.ETag, &object.ContentType, &object.LastModified, &object.Size, &object.URL); err != nil {
+			return nil, fmt.Errorf("failed to scan object: %w", err)
+		}
+		objects = append(objects, object)
+		listed[object.Key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating objects: %w", err)
+	}
+	if len(objects) >= limit {
+		return objects, nil
+	}
+
+	stored, err := s.storage.List(ctx, prefix, limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, object := range stored {
+		if len(objects) >= limit {
+			break
+		}
+		if !listed[object.Key] && !strings.HasPrefix(object.Key, dedupBlobPrefix) {
+			objects = append(objects, object)
+		}
+	}
+	return objects, nil
+}
+
+// Backend 回傳底層儲存後端類型
+func (s *DedupStorage) Backend() models.StorageType {
+	return s.storage.Backend()
+}
+
+// CollectGarbage 刪除參照數歸零超過保留期限的 blob。dryRun 只統計不刪除。
+// 每個 blob 在鎖定其紀錄的交易中刪除，期間重新被參照的 blob 會保留。
+func (s *DedupStorage) CollectGarbage(ctx context.Context, dryRun bool) (*models.AttachmentGCResult, error) {
+	start := time.Now()
+	result := &models.AttachmentGCResult{DryRun: dryRun}
+	defer func() {
+		result.Duration = time.Since(start)
+		s.monitor.RecordQuery("dedup_storage_gc", result.Duration, int(result.DeletedBlobs))
+	}()
+
+	cutoff := time.Now().Add(-s.gracePeriod)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT hash, size FROM attachment_blobs
+		WHERE ref_count = 0 AND unreferenced_at <= $1
+		ORDER BY unreferenced_at`, cutoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find unreferenced blobs: %w", err)
+	}
+	type candidate struct {
+		hash string
+		size int64
+	}
+	var candidates []candidate
+	for rows.Next() {
+		var c candidate
+		if err := rows.Scan(&c.hash, &c.size); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan blob: %w", err)
+		}
+		candidates = append(candidates, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blobs: %w", err)
+	}
+
+	for _, c := range candidates {
+		if dryRun {
+			result.DeletedBlobs++
+			result.DeletedBytes += c.size
+			continue
+		}
+		deleted, err := s.deleteBlob(ctx, c.hash, cutoff)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			result.Failed++
+			continue
+		}
+		if deleted {
+			result.DeletedBlobs++
+			result.DeletedBytes += c.size
+		}
+	}
+	return result, nil
+}
+
+// deleteBlob 在仍無參照時刪除 blob 的紀錄與內容；儲存刪除失敗時保留紀錄以便重試
+func (s *DedupStorage) deleteBlob(ctx context.Context, hash string, cutoff time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var blobKey string
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM attachment_blobs
+		WHERE hash = $1 AND ref_count = 0 AND unreferenced_at <= $2
+		RETURNING storage_key`, hash, cutoff).Scan(&blobKey)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to delete blob record: %w", err)
+	}
+	if err := s.storage.Delete(ctx, blobKey); err != nil && !errors.Is(err, ErrObjectNotFound) {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit blob deletion: %w", err)
+	}
+	return true, nil
+}
+
+// Report 統計去重節省的容量，並列出節省最多的 top 個重複 blob
+func (s *DedupStorage) Report(ctx context.Context, top int) (*models.AttachmentDedupReport, error) {
+	if top <= 0 {
+		top = defaultDedupTopDuplicates
+	}
+
+	report := &models.AttachmentDedupReport{TopDuplicates: []models.DuplicatedAttachment{}}
+	var referencedBytes int64
+	if err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(ref_count), 0),
+		       COUNT(*),
+		       COALESCE(SUM(size * ref_count), 0),
+		       COALESCE(SUM(size), 0),
+		       COALESCE(SUM(size) FILTER (WHERE ref_count > 0), 0),
+		       COUNT(*) FILTER (WHERE ref_count = 0),
+		       COALESCE(SUM(size) FILTER (WHERE ref_count = 0), 0)
+		FROM attachment_blobs`).Scan(
+		&report.Objects, &report.Blobs, &report.LogicalBytes, &report.StoredBytes,
+		&referencedBytes, &report.UnreferencedBlobs, &report.UnreferencedBytes); err != nil {
+		return nil, fmt.Errorf("failed to summarize blobs: %w", err)
+	}
+	report.SavedBytes = report.LogicalBytes - referencedBytes
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT b.hash, b.size, b.content_type, b.ref_count,
+		       ARRAY(SELECT o.object_key FROM attachment_objects o WHERE o.hash = b.hash ORDER BY o.object_key LIMIT 3)
+		FROM attachment_blobs b
+		WHERE b.ref_count > 1
+		ORDER BY b.size * (b.ref_count - 1) DESC, b.hash
+		LIMIT $1`, top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicated blobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var duplicate models.DuplicatedAttachment
+		var keys pq.StringArray
+		if err := rows.Scan(&duplicate.Hash, &duplicate.Size, &duplicate.ContentType, &duplicate.References, &keys); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicated blob: %w", err)
+		}
+		duplicate.SampleKeys = keys
+		duplicate.SavedBytes = duplicate.Size * int64(duplicate.References-1)
+		report.TopDuplicates = append(report.TopDuplicates, duplicate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating duplicated blobs: %w", err)
+	}
+	return report, nil
+}
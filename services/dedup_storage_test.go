@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// memoryObjectStorage keeps objects in a map and counts uploads
+type memoryObjectStorage struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	puts    int
+}
+
+func newMemoryObjectStorage() *memoryObjectStorage {
+	return &memoryObjectStorage{objects: make(map[string][]byte)}
+}
+
+func (m *memoryObjectStorage) Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error) {
+	data, contentType, err := readObjectBody(key, r, opts, 0)
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; ok && (opts == nil || !opts.Overwrite) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectExists, key)
+	}
+	m.objects[key] = data
+	m.puts++
+	return &models.StorageObject{Key: key, Size: int64(len(data)), ContentType: contentType, URL: "memory://" + key}, nil
+}
+
+func (m *memoryObjectStorage) Get(ctx context.Context, key string) (io.ReadCloser, *models.StorageObject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), &models.StorageObject{Key: key, Size: int64(len(data))}, nil
+}
+
+func (m *memoryObjectStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return "memory://" + key + "?signed", nil
+}
+
+func (m *memoryObjectStorage) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.objects[key]; !ok {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+	}
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memoryObjectStorage) List(ctx context.Context, prefix string, limit int) ([]models.StorageObject, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var objects []models.StorageObject
+	for key, data := range m.objects {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, models.StorageObject{Key: key, Size: int64(len(data))})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (m *memoryObjectStorage) Backend() models.StorageType {
+	return models.StorageTypeS3
+}
+
+func TestDedupBlobKey(t *testing.T) {
+	hash := "ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12cd34ef56ab12"
+	assert.Equal(t, "blobs/ab/"+hash, dedupBlobKey(hash))
+}
+
+func TestDedupStorage_RejectsBlobKeys(t *testing.T) {
+	storage := NewDedupStorage(nil, newMemoryObjectStorage(), &config.StorageConfig{}, NewNoOpMonitor())
+	_, err := storage.Put(context.Background(), "blobs/ab/file.pdf", strings.NewReader("data"), nil)
+	assert.Error(t, err)
+	_, err = storage.Put(context.Background(), "../file.pdf", strings.NewReader("data"), nil)
+	assert.Error(t, err)
+}
+
+func TestDedupStorage_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	inner := newMemoryObjectStorage()
+	storage := NewDedupStorage(db, inner, &config.StorageConfig{}, NewNoOpMonitor())
+
+	prefix := "dedup-test/" + uuid.NewString() + "/"
+	content := "same pdf " + prefix
+	first, err := storage.Put(ctx, prefix+"a.pdf", strings.NewReader(content), &models.PutObjectOptions{ContentType: "application/pdf"})
+	require.NoError(t, err)
+	second, err := storage.Put(ctx, prefix+"b.pdf", strings.NewReader(content), &models.PutObjectOptions{ContentType: "application/pdf"})
+	require.NoError(t, err)
+	assert.Equal(t, first.ETag, second.ETag)
+	assert.Equal(t, 1, inner.puts, "identical content is uploaded once")
+
+	_, err = storage.Put(ctx, prefix+"a.pdf", strings.NewReader("other"), nil)
+	assert.ErrorIs(t, err, ErrObjectExists)
+
+	body, object, err := storage.Get(ctx, prefix+"b.pdf")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	assert.Equal(t, content, string(data))
+	assert.Equal(t, prefix+"b.pdf", object.Key)
+
+	listed, err := storage.List(ctx, prefix, 10)
+	require.NoError(t, err)
+	assert.Len(t, listed, 2)
+
+	report, err := storage.Report(ctx, 100)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, report.SavedBytes, int64(len(content)))
+
+	// The blob outlives its first reference and is collected after the last
+	require.NoError(t, storage.Delete(ctx, prefix+"a.pdf"))
+	result, err := storage.CollectGarbage(ctx, false)
+	require.NoError(t, err)
+	assert.Contains(t, inner.objects, dedupBlobKey(first.ETag))
+
+	require.NoError(t, storage.Delete(ctx, prefix+"b.pdf"))
+	result, err = storage.CollectGarbage(ctx, true)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, result.DeletedBlobs, int64(1))
+	assert.Contains(t, inner.objects, dedupBlobKey(first.ETag), "dry run keeps blobs")
+
+	_, err = storage.CollectGarbage(ctx, false)
+	require.NoError(t, err)
+	assert.NotContains(t, inner.objects, dedupBlobKey(first.ETag))
+
+	_, _, err = storage.Get(ctx, prefix+"b.pdf")
+	assert.ErrorIs(t, err, ErrObjectNotFound)
+}
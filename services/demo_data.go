@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"semantic-text-processor/models"
+)
+
+// demoNode is a chunk of the demo knowledge base with its children
+type demoNode struct {
+	contents string
+	tags     []string // contents of the tags to attach
+	children []demoNode
+}
+
+// demoPages is a small knowledge base covering pages, nested blocks and tags
+var demoPages = []demoNode{
+	{contents: "Welcome to Ink Gateway", tags: []string{"getting-started"}, children: []demoNode{
+		{contents: "Every page, block, tag and template is a chunk; blocks nest under their parents."},
+		{contents: "Search", children: []demoNode{
+			{contents: "Full-text search matches words in any language.", tags: []string{"search"}},
+			{contents: "Semantic search finds chunks with similar meaning once embeddings are configured.", tags: []string{"search"}},
+		}},
+		{contents: "Tags such as #getting-started group chunks across pages."},
+	}},
+	{contents: "Project notes", tags: []string{"project"}, children: []demoNode{
+		{contents: "Goals", children: []demoNode{
+			{contents: "Import existing notes from Notion or Logseq."},
+			{contents: "Build a knowledge graph from the notes.", tags: []string{"project", "search"}},
+		}},
+		{contents: "Open questions", children: []demoNode{
+			{contents: "Which embedding model fits our languages best?"},
+		}},
+	}},
+}
+
+// demoTemplate is a template with its slots
+var demoTemplate = demoNode{contents: "Meeting notes", children: []demoNode{
+	{contents: "Date"},
+	{contents: "Attendees"},
+	{contents: "Decisions"},
+}}
+
+// SeedDemoData creates a small demo knowledge base: tags, two pages of nested blocks and a
+// meeting template. It returns the number of chunks created.
+func SeedDemoData(ctx context.Context, chunks UnifiedChunkService) (int, error) {
+	created := 0
+	tagIDs := make(map[string]string)
+	for _, name := range []string{"getting-started", "search", "project"} {
+		tag := &models.UnifiedChunkRecord{Contents: name, IsTag: true, Metadata: map[string]interface{}{"demo": true}}
+		if err := chunks.CreateChunk(ctx, tag); err != nil {
+			return created, fmt.Errorf("failed to create demo tag %s: %w", name, err)
+		}
+		tagIDs[name] = tag.ChunkID
+		created++
+	}
+
+	var create func(node demoNode, parent, page *string, isTemplate bool) error
+	create = func(node demoNode, parent, page *string, isTemplate bool) error {
+		chunk := &models.UnifiedChunkRecord{
+			Contents: node.contents,
+			Parent:   parent,
+			Page:     page,
+			IsPage:   parent == nil && !isTemplate,
+			Metadata: map[string]interface{}{"demo": true},
+		}
+		if isTemplate {
+			chunk.IsTemplate = parent == nil
+			chunk.IsSlot = parent != nil
+		}
+		if err := chunks.CreateChunk(ctx, chunk); err != nil {
+			return fmt.Errorf("failed to create demo chunk %q: %w", node.contents, err)
+		}
+		created++
+
+		if len(node.tags) > 0 {
+			ids := make([]string, len(node.tags))
+			for i, name := range node.tags {
+				ids[i] = tagIDs[name]
+			}
+			if err := chunks.AddTags(ctx, chunk.ChunkID, ids); err != nil {
+				return fmt.Errorf("failed to tag demo chunk %q: %w", node.contents, err)
+			}
+		}
+
+		if page == nil && !isTemplate {
+			page = &chunk.ChunkID
+		}
+		for _, child := range node.children {
+			if err := create(child, &chunk.ChunkID, page, isTemplate); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, page := range demoPages {
+		if err := create(page, nil, nil, false); err != nil {
+			return created, err
+		}
+	}
+	if err := create(demoTemplate, nil, nil, true); err != nil {
+		return created, err
+	}
+	return created, nil
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// EMAIL-TO-NOTE
+// ============================================================================
+//
+// Emails forwarded by SendGrid or Mailgun inbound webhooks, or fetched from an IMAP mailbox,
+// become chunks on one configured page. The subject is the message's chunk; its body, split
+// at paragraphs, and its attachments are children of it. The first message of a thread sits
+// directly on the page, and replies, recognized by their In-Reply-To and References headers,
+// become children of that first message, so a thread reads as one outline.
+//
+// Every ingested message is recorded in email_messages by Message-ID (see
+// database/email_messages_migration.sql), so webhook retries and messages fetched again
+// after a failed poll are not ingested twice.
+
+const (
+	// EmailWebhookPath is where inbound email webhooks are received, followed by the
+	// provider. Webhooks authenticate with their own secret instead of an API token.
+	EmailWebhookPath = "/api/v1/email/inbound/"
+
+	// emailAttachmentPrefix is the object storage prefix of email attachments
+	emailAttachmentPrefix = "email-attachments"
+)
+
+// EmailIngestService turns inbound emails into chunks
+type EmailIngestService interface {
+	// Ingest files an email on the configured page, or under its thread's first message
+	Ingest(ctx context.Context, email *models.InboundEmail) (*models.EmailIngestResult, error)
+	// Poll ingests the unread messages of the IMAP mailbox and marks them read
+	Poll(ctx context.Context) (int, error)
+	// Start polls the IMAP mailbox at the configured interval until Stop is called; without
+	// a mailbox it does nothing
+	Start(ctx context.Context)
+	// Stop stops polling
+	Stop()
+}
+
+// emailIngestService implements EmailIngestService
+type emailIngestService struct {
+	db      *sql.DB
+	chunks  UnifiedChunkService
+	storage StorageService
+	config  config.EmailIngestConfig
+	monitor QueryPerformanceMonitor
+
+	// dial connects to the IMAP server; replaced in tests
+	dial func(ctx context.Context) (*imapClient, error)
+
+	// ingestMu serializes ingestion, so concurrent deliveries of one message are ingested once
+	ingestMu sync.Mutex
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewEmailIngestService creates an email ingestion service. Without storage, attachments
+// other than text files are skipped with a warning.
+func NewEmailIngestService(db *sql.DB, chunks UnifiedChunkService, storage StorageService, cfg config.EmailIngestConfig, monitor QueryPerformanceMonitor) EmailIngestService {
+	service := &emailIngestService{
+		db:      db,
+		chunks:  chunks,
+		storage: storage,
+		config:  cfg,
+		monitor: monitor,
+	}
+	service.dial = func(ctx context.Context) (*imapClient, error) {
+		return dialIMAP(ctx, cfg.IMAPAddr, cfg.MaxBytes)
+	}
+	return service
+}
+
+// Ingest files an email, skipping messages already ingested
+func (s *emailIngestService) Ingest(ctx context.Context, email *models.InboundEmail) (*models.EmailIngestResult, error) {
+	start := time.Now()
+	if email.MessageID == "" {
+		email.MessageID = syntheticMessageID(email)
+	}
+
+	s.ingestMu.Lock()
+	defer s.ingestMu.Unlock()
+
+	result := &models.EmailIngestResult{MessageID: email.MessageID}
+	err := s.db.QueryRowContext(ctx,
+		"SELECT chunk_id, thread_id FROM email_messages WHERE message_id = $1",
+		email.MessageID).Scan(&result.ChunkID, &result.ThreadID)
+	if err == nil {
+		result.Duplicate = true
+		return result, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to look up message: %w", err)
+	}
+
+	threadID, err := s.findThread(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	pageID := s.config.PageID
+	message := &models.UnifiedChunkRecord{
+		ChunkID:  uuid.New().String(),
+		Contents: emailHeadline(email),
+		Parent:   &pageID,
+		Page:     &pageID,
+		Metadata: emailMetadata(email),
+	}
+	if threadID != "" {
+		message.Parent = &threadID
+	} else {
+		threadID = message.ChunkID
+	}
+	message.Metadata[models.EmailThreadKey] = threadID
+
+	children := s.emailChildren(ctx, email, message, result)
+	if err := s.chunks.CreateChunk(ctx, message); err != nil {
+		return nil, fmt.Errorf("failed to create email chunk: %w", err)
+	}
+	if len(children) > 0 {
+		if err := s.chunks.BatchCreateChunks(ctx, children); err != nil {
+			s.discard(ctx, message.ChunkID)
+			return nil, fmt.Errorf("failed to create email body chunks: %w", err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO email_messages (message_id, chunk_id, thread_id) VALUES ($1, $2, $3)",
+		email.MessageID, message.ChunkID, threadID); err != nil {
+		s.discard(ctx, message.ChunkID)
+		return nil, fmt.Errorf("failed to record message: %w", err)
+	}
+	s.monitor.RecordQuery("ingest_email", time.Since(start), 1+len(children))
+
+	result.ChunkID = message.ChunkID
+	result.ThreadID = threadID
+	result.Chunks = 1 + len(children)
+	return result, nil
+}
+
+// findThread returns the chunk of the first message of the thread an email replies to, or
+// an empty ID for emails starting a thread. The nearest ingested message it names decides.
+func (s *emailIngestService) findThread(ctx context.Context, email *models.InboundEmail) (string, error) {
+	var candidates []string
+	candidates = append(candidates, email.InReplyTo...)
+	for i := len(email.References) - 1; i >= 0; i-- {
+		candidates = append(candidates, email.References[i])
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT message_id, thread_id FROM email_messages WHERE message_id = ANY($1)",
+		pq.Array(candidates))
+	if err != nil {
+		return "", fmt.Errorf("failed to look up thread: %w", err)
+	}
+	defer rows.Close()
+
+	threads := make(map[string]string)
+	for rows.Next() {
+		var messageID, threadID string
+		if err := rows.Scan(&messageID, &threadID); err != nil {
+			return "", fmt.Errorf("failed to scan thread: %w", err)
+		}
+		threads[messageID] = threadID
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("error iterating threads: %w", err)
+	}
+	for _, candidate := range candidates {
+		if threadID, ok := threads[candidate]; ok {
+			return threadID, nil
+		}
+	}
+	return "", nil
+}
+
+// emailChildren builds the chunks of an email's body and attachments
+func (s *emailIngestService) emailChildren(ctx context.Context, email *models.InboundEmail, message *models.UnifiedChunkRecord, result *models.EmailIngestResult) []models.UnifiedChunkRecord {
+	body := strings.TrimSpace(email.Text)
+	if body == "" && email.HTML != "" {
+		body = htmlToText(email.HTML)
+	}
+	if len(email.InReplyTo) > 0 || len(email.References) > 0 {
+		body = stripQuotedReply(body)
+	}
+
+	var children []models.UnifiedChunkRecord
+	child := func(contents string, metadata map[string]interface{}) {
+		children = append(children, models.UnifiedChunkRecord{
+			ChunkID:  uuid.New().String(),
+			Contents: contents,
+			Parent:   &message.ChunkID,
+			Page:     message.Page,
+			Metadata: metadata,
+		})
+	}
+	if body != "" {
+		pieces, _ := defaultChunkText(ctx, body)
+		for _, piece := range nonEmptyPieces(pieces) {
+			child(piece, map[string]interface{}{})
+		}
+	}
+
+	for _, attachment := range email.Attachments {
+		metadata := map[string]interface{}{
+			models.AttachmentNameKey: attachment.Filename,
+			models.AttachmentTypeKey: attachment.ContentType,
+			models.AttachmentSizeKey: len(attachment.Data),
+		}
+		if isTextAttachment(attachment) {
+			if text := strings.TrimSpace(string(attachment.Data)); text != "" {
+				child(text, metadata)
+				result.Attachments++
+			}
+			continue
+		}
+		if s.storage == nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("object storage is not configured; attachment %s was not stored", attachment.Filename))
+			continue
+		}
+		object, err := s.storeAttachment(ctx, attachment)
+		if err != nil {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("attachment %s was not stored: %v", attachment.Filename, err))
+			continue
+		}
+		ref := object.URL
+		if ref == "" {
+			ref = object.Key
+		}
+		metadata[models.AttachmentKeyKey] = object.Key
+		link := "[" + attachment.Filename + "](" + ref + ")"
+		if strings.HasPrefix(attachment.ContentType, "image/") {
+			link = "!" + link
+		}
+		child(link, metadata)
+		result.Attachments++
+	}
+	return children
+}
+
+// storeAttachment uploads an attachment under its content hash, so a file sent repeatedly
+// is stored once
+func (s *emailIngestService) storeAttachment(ctx context.Context, attachment models.EmailAttachment) (*models.StorageObject, error) {
+	sum := sha256.Sum256(attachment.Data)
+	hash := hex.EncodeToString(sum[:])
+	ext := strings.ToLower(path.Ext(attachment.Filename))
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(ext)
+	}
+	key := fmt.Sprintf("%s/%s/%s%s", emailAttachmentPrefix, hash[:2], hash, ext)
+	return s.storage.Put(ctx, key, bytes.NewReader(attachment.Data), &models.PutObjectOptions{
+		ContentType: contentType,
+		Size:        int64(len(attachment.Data)),
+		Overwrite:   true,
+	})
+}
+
+// discard removes a partly ingested email, so a retry starts over
+func (s *emailIngestService) discard(ctx context.Context, chunkID string) {
+	if _, err := s.chunks.DeleteSubtree(ctx, chunkID, models.SubtreeDeleteOptions{Confirm: true}); err != nil {
+		log.Printf("Warning: failed to remove partly ingested email %s: %v", chunkID, err)
+	}
+}
+
+// isTextAttachment reports whether an attachment is plain text or Markdown, kept as the
+// contents of its chunk instead of being uploaded
+func isTextAttachment(attachment models.EmailAttachment) bool {
+	switch attachment.ContentType {
+	case "text/plain", "text/markdown", "text/x-markdown":
+		return true
+	}
+	return false
+}
+
+func emailHeadline(email *models.InboundEmail) string {
+	if subject := strings.TrimSpace(email.Subject); subject != "" {
+		return subject
+	}
+	return "(no subject)"
+}
+
+func emailMetadata(email *models.InboundEmail) map[string]interface{} {
+	metadata := map[string]interface{}{
+		models.EmailMessageIDKey: email.MessageID,
+		models.EmailSubjectKey:   email.Subject,
+	}
+	if email.From != "" {
+		metadata[models.EmailFromKey] = email.From
+	}
+	if email.To != "" {
+		metadata[models.EmailToKey] = email.To
+	}
+	if !email.Date.IsZero() {
+		metadata[models.EmailDateKey] = email.Date.UTC().Format(time.RFC3339)
+	}
+	return metadata
+}
+
+// syntheticMessageID identifies a message sent without a Message-ID by its sender, date,
+// subject and body, so its redeliveries are still recognized
+func syntheticMessageID(email *models.InboundEmail) string {
+	hash := sha256.New()
+	for _, part := range []string{email.From, email.Date.UTC().Format(time.RFC3339), email.Subject, email.Text, email.HTML} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil)) + "@ink-gateway.invalid"
+}
+
+// Poll ingests the unread messages of the IMAP mailbox. Messages that fail to ingest stay
+// unread and are tried again by the next poll; messages that cannot be parsed are marked
+// read so they do not block the mailbox.
+func (s *emailIngestService) Poll(ctx context.Context) (int, error) {
+	if s.config.IMAPAddr == "" {
+		return 0, nil
+	}
+	client, err := s.dial(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		client.setDeadline(deadline)
+	}
+
+	if err := client.Login(s.config.IMAPUsername, s.config.IMAPPassword); err != nil {
+		return 0, err
+	}
+	if err := client.Select(s.config.IMAPMailbox); err != nil {
+		return 0, err
+	}
+	uids, err := client.SearchUnseen()
+	if err != nil {
+		return 0, err
+	}
+
+	ingested := 0
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			return ingested, ctx.Err()
+		}
+		raw, err := client.Fetch(uid)
+		if err != nil {
+			return ingested, err
+		}
+		email, err := ParseEmailMessage(bytes.NewReader(raw))
+		if err != nil {
+			log.Printf("Warning: skipping unreadable message %d in %s: %v", uid, s.config.IMAPMailbox, err)
+		} else if result, err := s.Ingest(ctx, email); err != nil {
+			log.Printf("Warning: failed to ingest message %d in %s: %v", uid, s.config.IMAPMailbox, err)
+			continue
+		} else {
+			for _, warning := range result.Warnings {
+				log.Printf("Warning: message %s: %s", result.MessageID, warning)
+			}
+			if !result.Duplicate {
+				ingested++
+			}
+		}
+		if err := client.MarkSeen(uid); err != nil {
+			return ingested, err
+		}
+	}
+	return ingested, nil
+}
+
+// Start polls the mailbox in the background
+func (s *emailIngestService) Start(ctx context.Context) {
+	if s.cancel != nil || s.config.IMAPAddr == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+// Stop stops polling and waits for the current poll
+func (s *emailIngestService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+func (s *emailIngestService) run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.IMAPPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Each poll must finish within the interval, so a stalled server cannot stop polling
+		pollCtx, cancel := context.WithTimeout(ctx, s.config.IMAPPollInterval)
+		if count, err := s.Poll(pollCtx); err != nil && ctx.Err() == nil {
+			log.Printf("Warning: failed to poll mailbox %s: %v", s.config.IMAPMailbox, err)
+		} else if count > 0 {
+			log.Printf("Ingested %d emails from %s", count, s.config.IMAPMailbox)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
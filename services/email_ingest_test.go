@@ -0,0 +1,309 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"mime/multipart"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+const testEmailMessage = "From: =?UTF-8?Q?Zo=C3=AB?= <zoe@example.com>\r\n" +
+	"To: notes@example.com\r\n" +
+	"Subject: =?UTF-8?B?UmU6IEJ1ZGdldCDigJMgUTM=?=\r\n" +
+	"Date: Fri, 16 Oct 2026 09:00:00 +0200\r\n" +
+	"Message-ID: <reply-1@example.com>\r\n" +
+	"In-Reply-To: <first@example.com>\r\n" +
+	"References: <first@example.com>\r\n" +
+	"  <second@example.com>\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/mixed; boundary=outer\r\n" +
+	"\r\n" +
+	"--outer\r\n" +
+	"Content-Type: multipart/alternative; boundary=inner\r\n" +
+	"\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"Agreed =E2=80=93 let's ship it.\r\n" +
+	"\r\n" +
+	"On Thu, Oct 15, 2026 at 5:00 PM Bob wrote:\r\n" +
+	"> What about the budget?\r\n" +
+	"--inner\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>Agreed &ndash; let's ship it.</p>\r\n" +
+	"--inner--\r\n" +
+	"--outer\r\n" +
+	"Content-Type: image/png; name=chart.png\r\n" +
+	"Content-Disposition: attachment; filename=chart.png\r\n" +
+	"Content-Transfer-Encoding: base64\r\n" +
+	"\r\n" +
+	"iVBORw0KGgo=\r\n" +
+	"--outer--\r\n"
+
+func TestParseEmailMessage(t *testing.T) {
+	email, err := ParseEmailMessage(strings.NewReader(testEmailMessage))
+	require.NoError(t, err)
+
+	assert.Equal(t, "reply-1@example.com", email.MessageID)
+	assert.Equal(t, []string{"first@example.com"}, email.InReplyTo)
+	assert.Equal(t, []string{"first@example.com", "second@example.com"}, email.References)
+	assert.Equal(t, "Zoë <zoe@example.com>", email.From)
+	assert.Equal(t, "Re: Budget – Q3", email.Subject)
+	assert.Equal(t, time.Date(2026, 10, 16, 7, 0, 0, 0, time.UTC), email.Date.UTC())
+	assert.True(t, strings.HasPrefix(email.Text, "Agreed – let's ship it."))
+	assert.Contains(t, email.HTML, "<p>Agreed")
+
+	require.Len(t, email.Attachments, 1)
+	assert.Equal(t, "chart.png", email.Attachments[0].Filename)
+	assert.Equal(t, "image/png", email.Attachments[0].ContentType)
+	assert.Equal(t, []byte("\x89PNG\r\n\x1a\n"), email.Attachments[0].Data)
+
+	_, err = ParseEmailMessage(strings.NewReader("not a message"))
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+}
+
+// testForm encodes fields and files as a multipart form and reads it back
+func testForm(t *testing.T, fields map[string]string, files map[string]string) *multipart.Form {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	for name, contents := range files {
+		part, err := writer.CreateFormFile(name, name+".txt")
+		require.NoError(t, err)
+		part.Write([]byte(contents))
+	}
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	return form
+}
+
+func TestParseSendGridForm(t *testing.T) {
+	form := testForm(t, map[string]string{
+		"headers":         "Message-ID: <sg-1@example.com>\nIn-Reply-To: <first@example.com>\nDate: Fri, 16 Oct 2026 09:00:00 +0000\n",
+		"from":            "Bob <bob@example.com>",
+		"subject":         "Minutes",
+		"text":            "See attached",
+		"attachment-info": `{"attachment1": {"filename": "minutes.md", "type": "text/markdown"}}`,
+	}, map[string]string{"attachment1": "# Minutes"})
+
+	email, err := ParseSendGridForm(form)
+	require.NoError(t, err)
+	assert.Equal(t, "sg-1@example.com", email.MessageID)
+	assert.Equal(t, []string{"first@example.com"}, email.InReplyTo)
+	assert.Equal(t, "Bob <bob@example.com>", email.From)
+	assert.Equal(t, "Minutes", email.Subject)
+	assert.Equal(t, "See attached", email.Text)
+	require.Len(t, email.Attachments, 1)
+	assert.Equal(t, "minutes.md", email.Attachments[0].Filename)
+	assert.Equal(t, "text/markdown", email.Attachments[0].ContentType)
+	assert.Equal(t, "# Minutes", string(email.Attachments[0].Data))
+
+	raw, err := ParseSendGridForm(testForm(t, map[string]string{"email": testEmailMessage}, nil))
+	require.NoError(t, err)
+	assert.Equal(t, "reply-1@example.com", raw.MessageID, "raw mode posts the whole message")
+}
+
+func TestParseMailgunForm(t *testing.T) {
+	form := testForm(t, map[string]string{
+		"Message-Id":      "<mg-1@example.com>",
+		"References":      "<first@example.com> <second@example.com>",
+		"sender":          "bob@example.com",
+		"recipient":       "notes@example.com",
+		"subject":         "Follow-up",
+		"body-plain":      "Done.",
+		"message-headers": `[["Date", "Fri, 16 Oct 2026 09:00:00 +0000"], ["X-Mailer", "test"]]`,
+	}, map[string]string{"attachment-1": "notes", "attachment-2": "more notes"})
+
+	email, err := ParseMailgunForm(form)
+	require.NoError(t, err)
+	assert.Equal(t, "mg-1@example.com", email.MessageID)
+	assert.Equal(t, []string{"first@example.com", "second@example.com"}, email.References)
+	assert.Equal(t, "bob@example.com", email.From, "the sender is used without a from field")
+	assert.Equal(t, "notes@example.com", email.To)
+	assert.Equal(t, time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC), email.Date.UTC())
+	require.Len(t, email.Attachments, 2)
+	assert.Equal(t, "more notes", string(email.Attachments[1].Data))
+
+	_, err = ParseMailgunForm(testForm(t, map[string]string{"message-headers": "not json"}, nil))
+	assert.ErrorIs(t, err, ErrInvalidEmail)
+}
+
+func TestVerifyMailgunSignature(t *testing.T) {
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write([]byte("1760600000" + "token"))
+	signature := hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, VerifyMailgunSignature("key", "1760600000", "token", signature))
+	assert.False(t, VerifyMailgunSignature("other", "1760600000", "token", signature))
+	assert.False(t, VerifyMailgunSignature("key", "1760600001", "token", signature))
+	assert.False(t, VerifyMailgunSignature("", "1760600000", "token", signature))
+}
+
+func TestEmailBodyHelpers(t *testing.T) {
+	assert.Equal(t, "Hello & welcome\n\nSecond paragraph\nLine two",
+		htmlToText("<html><head><style>p {}</style></head><body><p>Hello &amp; <b>welcome</b></p><p></p><p>Second paragraph<br>Line two</p></body></html>"))
+
+	assert.Equal(t, "Sounds good.\n\nThanks", stripQuotedReply("Sounds good.\r\n> quoted\r\n\r\nThanks\r\nOn Mon, Bob <bob@example.com> wrote:\r\nOld text"))
+	assert.Equal(t, "Fine", stripQuotedReply("Fine\n-----Original Message-----\nFrom: Bob"))
+
+	first := syntheticMessageID(&models.InboundEmail{From: "a@example.com", Subject: "Hi"})
+	assert.Equal(t, first, syntheticMessageID(&models.InboundEmail{From: "a@example.com", Subject: "Hi"}))
+	assert.NotEqual(t, first, syntheticMessageID(&models.InboundEmail{From: "a@example.com", Subject: "Hello"}))
+}
+
+// memoryStorage keeps uploaded objects in memory
+type memoryStorage struct {
+	StorageService
+	objects map[string][]byte
+}
+
+func (s *memoryStorage) Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s.objects[key] = data
+	return &models.StorageObject{Key: key, URL: "https://files.example.com/" + key, ContentType: opts.ContentType}, nil
+}
+
+func TestEmailIngestService_Children(t *testing.T) {
+	storage := &memoryStorage{objects: map[string][]byte{}}
+	service := NewEmailIngestService(nil, nil, storage, config.EmailIngestConfig{PageID: "page"}, NewNoOpMonitor()).(*emailIngestService)
+	page := "page"
+	message := &models.UnifiedChunkRecord{ChunkID: "message", Page: &page}
+
+	email, err := ParseEmailMessage(strings.NewReader(testEmailMessage))
+	require.NoError(t, err)
+	email.Attachments = append(email.Attachments, models.EmailAttachment{Filename: "notes.txt", ContentType: "text/plain", Data: []byte("Plain notes")})
+
+	result := &models.EmailIngestResult{}
+	children := service.emailChildren(context.Background(), email, message, result)
+	require.Len(t, children, 3)
+	assert.Equal(t, "Agreed – let's ship it.", children[0].Contents, "replies drop the quoted message")
+	assert.True(t, strings.HasPrefix(children[1].Contents, "![chart.png](https://files.example.com/email-attachments/"))
+	assert.Equal(t, "Plain notes", children[2].Contents)
+	assert.Equal(t, "notes.txt", children[2].Metadata[models.AttachmentNameKey])
+	for _, child := range children {
+		assert.Equal(t, "message", *child.Parent)
+		assert.Equal(t, "page", *child.Page)
+	}
+	assert.Equal(t, 2, result.Attachments)
+	assert.Len(t, storage.objects, 1)
+
+	service.storage = nil
+	result = &models.EmailIngestResult{}
+	children = service.emailChildren(context.Background(), &models.InboundEmail{
+		HTML:        "<p>Only HTML</p>",
+		Attachments: []models.EmailAttachment{{Filename: "chart.png", ContentType: "image/png", Data: []byte{1}}},
+	}, message, result)
+	require.Len(t, children, 1)
+	assert.Equal(t, "Only HTML", children[0].Contents)
+	assert.Len(t, result.Warnings, 1, "attachments are skipped without storage")
+}
+
+// scriptedIMAPServer answers the client's commands with canned responses, checking that
+// the commands arrive in order
+func scriptedIMAPServer(t *testing.T, conn net.Conn, script []struct{ command, response string }) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	io.WriteString(conn, "* OK IMAP4rev1 ready\r\n")
+	for _, step := range script {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		tag, command, _ := strings.Cut(strings.TrimRight(line, "\r\n"), " ")
+		assert.Equal(t, step.command, command)
+		io.WriteString(conn, strings.ReplaceAll(step.response, "TAG", tag))
+	}
+}
+
+func TestIMAPClient(t *testing.T) {
+	message := "Subject: Hi\r\nMessage-ID: <imap-1@example.com>\r\n\r\nHello\r\n"
+	server, clientConn := net.Pipe()
+	go scriptedIMAPServer(t, server, []struct{ command, response string }{
+		{`LOGIN "notes" "p\"ss"`, "TAG OK LOGIN completed\r\n"},
+		{`SELECT "INBOX"`, "* 2 EXISTS\r\nTAG OK [READ-WRITE] SELECT completed\r\n"},
+		{"UID SEARCH UNSEEN", "* SEARCH 7 9\r\nTAG OK SEARCH completed\r\n"},
+		{"UID FETCH 7 (BODY.PEEK[])", "* 1 FETCH (UID 7 BODY[] {" + strconv.Itoa(len(message)) + "}\r\n" + message + ")\r\nTAG OK FETCH completed\r\n"},
+		{`UID STORE 7 +FLAGS.SILENT (\Seen)`, "TAG OK STORE completed\r\n"},
+		{"UID FETCH 9 (BODY.PEEK[])", "TAG NO message expunged\r\n"},
+		{"LOGOUT", "* BYE\r\nTAG OK LOGOUT completed\r\n"},
+	})
+
+	client, err := newIMAPClient(clientConn, 1<<20)
+	require.NoError(t, err)
+	require.NoError(t, client.Login("notes", `p"ss`))
+	require.NoError(t, client.Select("INBOX"))
+	uids, err := client.SearchUnseen()
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{7, 9}, uids)
+
+	raw, err := client.Fetch(7)
+	require.NoError(t, err)
+	assert.Equal(t, message, string(raw))
+	require.NoError(t, client.MarkSeen(7))
+
+	_, err = client.Fetch(9)
+	assert.ErrorContains(t, err, "message expunged")
+	client.Close()
+}
+
+func TestEmailIngestService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	page := &models.UnifiedChunkRecord{Contents: "Email test page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer chunks.DeleteSubtree(ctx, page.ChunkID, models.SubtreeDeleteOptions{Confirm: true})
+
+	service := NewEmailIngestService(db, chunks, nil, config.EmailIngestConfig{PageID: page.ChunkID, MaxBytes: 1 << 20}, NewNoOpMonitor())
+	suffix := time.Now().Format("150405.000000")
+
+	first, err := service.Ingest(ctx, &models.InboundEmail{MessageID: "first-" + suffix, Subject: "Budget", Text: "What about Q3?"})
+	require.NoError(t, err)
+	assert.Equal(t, first.ChunkID, first.ThreadID)
+	assert.Equal(t, 2, first.Chunks)
+
+	reply, err := service.Ingest(ctx, &models.InboundEmail{
+		MessageID: "reply-" + suffix,
+		InReplyTo: []string{"first-" + suffix},
+		Subject:   "Re: Budget",
+		Text:      "Approved.\n> What about Q3?",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, first.ChunkID, reply.ThreadID)
+
+	chunk, err := chunks.GetChunk(ctx, reply.ChunkID)
+	require.NoError(t, err)
+	require.NotNil(t, chunk.Parent)
+	assert.Equal(t, first.ChunkID, *chunk.Parent, "replies are filed under the thread's first message")
+
+	again, err := service.Ingest(ctx, &models.InboundEmail{MessageID: "reply-" + suffix, Subject: "Re: Budget"})
+	require.NoError(t, err)
+	assert.True(t, again.Duplicate)
+	assert.Equal(t, reply.ChunkID, again.ChunkID)
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidEmail is returned for messages and webhook payloads that cannot be parsed
+var ErrInvalidEmail = errors.New("invalid email")
+
+// maxEmailParts caps the MIME parts read from one message, so deeply nested or crafted
+// messages cannot exhaust the parser
+const maxEmailParts = 100
+
+var (
+	messageIDPattern    = regexp.MustCompile(`<([^<>\s]+)>`)
+	htmlDropPattern     = regexp.MustCompile(`(?is)<(script|style|head)[^>]*>.*?</(script|style|head)>`)
+	htmlBreakPattern    = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|tr|h[1-6]|blockquote)>`)
+	htmlTagPattern      = regexp.MustCompile(`(?s)<[^>]*>`)
+	blankLinesPattern   = regexp.MustCompile(`\n{3,}`)
+	replyHeaderPattern  = regexp.MustCompile(`^On .+ wrote:$`)
+	forwardMarkerPrefix = "-----Original Message-----"
+)
+
+// ParseEmailMessage parses an RFC 5322 message, as fetched over IMAP or posted raw by a
+// webhook, taking the first plain text and HTML bodies and every attached file
+func ParseEmailMessage(r io.Reader) (*models.InboundEmail, error) {
+	msg, err := mail.ReadMessage(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	email := emailFromHeader(msg.Header)
+
+	parts := 0
+	if err := readEmailPart(email, msg.Header, msg.Body, &parts); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmail, err)
+	}
+	return email, nil
+}
+
+// emailFromHeader reads the addressing and threading headers of a message
+func emailFromHeader(header mail.Header) *models.InboundEmail {
+	email := &models.InboundEmail{
+		MessageID:  firstMessageID(header.Get("Message-Id")),
+		InReplyTo:  parseMessageIDs(header.Get("In-Reply-To")),
+		References: parseMessageIDs(header.Get("References")),
+		From:       decodeEmailHeader(header.Get("From")),
+		To:         decodeEmailHeader(header.Get("To")),
+		Subject:    decodeEmailHeader(header.Get("Subject")),
+	}
+	if date, err := header.Date(); err == nil {
+		email.Date = date
+	}
+	return email
+}
+
+// partHeader is the header of a message or MIME part
+type partHeader interface {
+	Get(key string) string
+}
+
+// readEmailPart walks a MIME part, descending into multiparts and keeping the first text
+// and HTML bodies and the attached files
+func readEmailPart(email *models.InboundEmail, header partHeader, body io.Reader, parts *int) error {
+	*parts++
+	if *parts > maxEmailParts {
+		return fmt.Errorf("more than %d MIME parts", maxEmailParts)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextRawPart()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if err := readEmailPart(email, part.Header, part, parts); err != nil {
+				return err
+			}
+		}
+	}
+
+	data, err := io.ReadAll(decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body))
+	if err != nil {
+		return err
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	filename := dispositionParams["filename"]
+	if filename == "" {
+		filename = params["name"]
+	}
+	if disposition == "attachment" || (filename != "" && !strings.HasPrefix(mediaType, "text/")) {
+		if filename == "" {
+			filename = "attachment"
+		}
+		email.Attachments = append(email.Attachments, models.EmailAttachment{
+			Filename:    decodeEmailHeader(filename),
+			ContentType: mediaType,
+			Data:        data,
+		})
+		return nil
+	}
+
+	switch mediaType {
+	case "text/plain":
+		if email.Text == "" {
+			email.Text = decodeCharset(params["charset"], data)
+		}
+	case "text/html":
+		if email.HTML == "" {
+			email.HTML = decodeCharset(params["charset"], data)
+		}
+	}
+	return nil
+}
+
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// decodeCharset converts a body to UTF-8. Only UTF-8, ASCII and Latin-1 are converted;
+// bodies in other charsets are kept when they happen to be valid UTF-8.
+func decodeCharset(charset string, data []byte) string {
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "iso-8859-1", "latin1", "windows-1252":
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return strings.ToValidUTF8(string(data), "�")
+}
+
+// emailWordDecoder decodes RFC 2047 encoded words in the charsets decodeCharset handles
+var emailWordDecoder = &mime.WordDecoder{
+	CharsetReader: func(charset string, input io.Reader) (io.Reader, error) {
+		data, err := io.ReadAll(input)
+		if err != nil {
+			return nil, err
+		}
+		return strings.NewReader(decodeCharset(charset, data)), nil
+	},
+}
+
+func decodeEmailHeader(value string) string {
+	decoded, err := emailWordDecoder.DecodeHeader(value)
+	if err != nil {
+		decoded = value
+	}
+	return strings.Join(strings.Fields(decoded), " ")
+}
+
+// parseMessageIDs returns the message IDs in a References or In-Reply-To header without
+// their angle brackets
+func parseMessageIDs(value string) []string {
+	var ids []string
+	for _, match := range messageIDPattern.FindAllStringSubmatch(value, -1) {
+		ids = append(ids, match[1])
+	}
+	if len(ids) == 0 {
+		if id := strings.Trim(strings.TrimSpace(value), "<>"); id != "" && !strings.ContainsAny(id, " \t") {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func firstMessageID(value string) string {
+	if ids := parseMessageIDs(value); len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// ParseSendGridForm reads a SendGrid Inbound Parse webhook, in either its parsed or its raw
+// MIME format
+func ParseSendGridForm(form *multipart.Form) (*models.InboundEmail, error) {
+	if raw := formValue(form, "email"); raw != "" {
+		return ParseEmailMessage(strings.NewReader(raw))
+	}
+
+	email := &models.InboundEmail{}
+	if headers := formValue(form, "headers"); headers != "" {
+		msg, err := mail.ReadMessage(strings.NewReader(strings.TrimRight(headers, "\r\n") + "\r\n\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("%w: headers: %v", ErrInvalidEmail, err)
+		}
+		email = emailFromHeader(msg.Header)
+	}
+	if from := formValue(form, "from"); from != "" {
+		email.From = decodeEmailHeader(from)
+	}
+	if to := formValue(form, "to"); to != "" {
+		email.To = decodeEmailHeader(to)
+	}
+	if subject := formValue(form, "subject"); subject != "" {
+		email.Subject = decodeEmailHeader(subject)
+	}
+	email.Text = formValue(form, "text")
+	email.HTML = formValue(form, "html")
+
+	// attachment-info maps the file fields to the attachments' names and types
+	var info map[string]struct {
+		Filename string `json:"filename"`
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+	}
+	if raw := formValue(form, "attachment-info"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &info); err != nil {
+			return nil, fmt.Errorf("%w: attachment-info: %v", ErrInvalidEmail, err)
+		}
+	}
+	attachments, err := formAttachments(form, "attachment")
+	if err != nil {
+		return nil, err
+	}
+	for i, attachment := range attachments {
+		field := fmt.Sprintf("attachment%d", i+1)
+		if details, ok := info[field]; ok {
+			if details.Filename != "" {
+				attachment.Filename = details.Filename
+			} else if details.Name != "" {
+				attachment.Filename = details.Name
+			}
+			if details.Type != "" {
+				attachment.ContentType = details.Type
+			}
+		}
+		email.Attachments = append(email.Attachments, attachment)
+	}
+	return email, nil
+}
+
+// ParseMailgunForm reads a Mailgun inbound route webhook, posted to a URL either parsed or,
+// for routes ending in "mime", as the raw message
+func ParseMailgunForm(form *multipart.Form) (*models.InboundEmail, error) {
+	if raw := formValue(form, "body-mime"); raw != "" {
+		return ParseEmailMessage(strings.NewReader(raw))
+	}
+
+	email := &models.InboundEmail{
+		MessageID:  firstMessageID(formValue(form, "Message-Id")),
+		InReplyTo:  parseMessageIDs(formValue(form, "In-Reply-To")),
+		References: parseMessageIDs(formValue(form, "References")),
+		From:       decodeEmailHeader(formValue(form, "from")),
+		To:         decodeEmailHeader(formValue(form, "recipient")),
+		Subject:    decodeEmailHeader(formValue(form, "subject")),
+		Text:       formValue(form, "body-plain"),
+		HTML:       formValue(form, "body-html"),
+	}
+	// message-headers holds every header as [name, value] pairs, including the Date
+	var headers [][2]string
+	if raw := formValue(form, "message-headers"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &headers); err != nil {
+			return nil, fmt.Errorf("%w: message-headers: %v", ErrInvalidEmail, err)
+		}
+	}
+	for _, header := range headers {
+		if strings.EqualFold(header[0], "Date") {
+			if date, err := mail.ParseDate(header[1]); err == nil {
+				email.Date = date
+			}
+		}
+	}
+	if email.From == "" {
+		email.From = formValue(form, "sender")
+	}
+
+	attachments, err := formAttachments(form, "attachment-")
+	if err != nil {
+		return nil, err
+	}
+	email.Attachments = attachments
+	return email, nil
+}
+
+// VerifyMailgunSignature checks the signature Mailgun adds to webhooks: the hex HMAC-SHA256
+// of the timestamp followed by the token, keyed with the account's signing key
+func VerifyMailgunSignature(signingKey, timestamp, token, signature string) bool {
+	if signingKey == "" || timestamp == "" || token == "" {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(timestamp + token))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
+
+func formValue(form *multipart.Form, key string) string {
+	if values := form.Value[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// formAttachments reads the files posted as prefix1, prefix2, ... in order
+func formAttachments(form *multipart.Form, prefix string) ([]models.EmailAttachment, error) {
+	var attachments []models.EmailAttachment
+	for i := 1; ; i++ {
+		files := form.File[fmt.Sprintf("%s%d", prefix, i)]
+		if len(files) == 0 {
+			return attachments, nil
+		}
+		file, err := files[0].Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open attachment %d: %w", i, err)
+		}
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read attachment %d: %w", i, err)
+		}
+		contentType := files[0].Header.Get("Content-Type")
+		if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+			contentType = mediaType
+		}
+		attachments = append(attachments, models.EmailAttachment{
+			Filename:    files[0].Filename,
+			ContentType: contentType,
+			Data:        data,
+		})
+	}
+}
+
+// htmlToText reduces an HTML body to its text, one line per paragraph, for emails without
+// a plain text body
+func htmlToText(body string) string {
+	body = htmlDropPattern.ReplaceAllString(body, "")
+	body = htmlBreakPattern.ReplaceAllString(body, "\n")
+	body = html.UnescapeString(htmlTagPattern.ReplaceAllString(body, ""))
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(body)+1)
+	for scanner.Scan() {
+		lines = append(lines, strings.Join(strings.Fields(scanner.Text()), " "))
+	}
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// stripQuotedReply drops the quoted message a reply repeats below its own text: lines
+// starting with ">", and everything after an "On ... wrote:" line or a forwarded message marker
+func stripQuotedReply(body string) string {
+	var kept bytes.Buffer
+	for _, line := range strings.Split(strings.ReplaceAll(body, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if replyHeaderPattern.MatchString(trimmed) || strings.HasPrefix(trimmed, forwardMarkerPrefix) {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		kept.WriteString(line)
+		kept.WriteByte('\n')
+	}
+	return strings.TrimSpace(kept.String())
+}
@@ -16,6 +16,7 @@ import (
 type embeddingService struct {
 	apiKey     string
 	endpoint   string
+	dimensions int // truncate embeddings to this many dimensions; 0 keeps them whole
 	httpClient *http.Client
 }
 
@@ -24,6 +25,7 @@ func NewEmbeddingService(cfg *config.EmbeddingConfig) EmbeddingService {
 	return &embeddingService{
 		apiKey:   cfg.APIKey,
 		endpoint: cfg.Endpoint,
+		dimensions: cfg.Dimensions,
 		httpClient: &http.Client{
 			Timeout: cfg.Timeout,
 		},
@@ -78,8 +80,15 @@ func (s *embeddingService) GenerateEmbedding(ctx context.Context, text string) (
 
 // GenerateBatchEmbeddings generates vector embeddings for multiple texts
 func (s *embeddingService) GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float64, error) {
+	embeddings, _, err := s.GenerateBatchEmbeddingsWithUsage(ctx, texts)
+	return embeddings, err
+}
+
+// GenerateBatchEmbeddingsWithUsage generates vector embeddings for multiple texts and
+// returns the prompt tokens the provider billed for them
+func (s *embeddingService) GenerateBatchEmbeddingsWithUsage(ctx context.Context, texts []string) ([][]float64, int, error) {
 	if len(texts) == 0 {
-		return [][]float64{}, nil
+		return [][]float64{}, 0, nil
 	}
 	
 	// Prepare request
@@ -95,25 +104,25 @@ func (s *embeddingService) GenerateBatchEmbeddings(ctx context.Context, texts []
 	})
 	
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		return nil, 0, fmt.Errorf("failed to generate embeddings: %w", err)
 	}
 	
 	// Extract embeddings in correct order
 	embeddings := make([][]float64, len(texts))
 	for _, data := range response.Data {
 		if data.Index < len(embeddings) {
-			embeddings[data.Index] = data.Embedding
+			embeddings[data.Index] = truncateEmbedding(data.Embedding, s.dimensions)
 		}
 	}
 	
 	// Validate all embeddings were returned
 	for i, embedding := range embeddings {
 		if embedding == nil {
-			return nil, fmt.Errorf("missing embedding for text at index %d", i)
+			return nil, 0, fmt.Errorf("missing embedding for text at index %d", i)
 		}
 	}
 	
-	return embeddings, nil
+	return embeddings, response.Usage.PromptTokens, nil
 }
 
 // makeRequest performs HTTP request to embedding API
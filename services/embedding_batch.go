@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// maxEmbeddingBatchItems bounds the chunks and texts of one batch request
+const maxEmbeddingBatchItems = 2000
+
+// defaultEmbeddingUsageDays is the period of usage reports without a day count
+const defaultEmbeddingUsageDays = 30
+
+// ErrInvalidEmbeddingBatch is returned for batch requests that cannot be processed
+var ErrInvalidEmbeddingBatch = errors.New("invalid embedding batch")
+
+// EmbeddingWriter stores embeddings of chunks
+type EmbeddingWriter interface {
+	InsertEmbeddings(ctx context.Context, embeddings []models.EmbeddingRecord) error
+}
+
+// EmbeddingBatchService embeds many chunks or texts in as few provider requests as
+// possible and accounts for the tokens used per workspace
+type EmbeddingBatchService interface {
+	// Embed embeds the request's chunks, storing their embeddings, and texts, returning
+	// their vectors. Chunks and texts that fail are reported in the response; the others
+	// are still embedded.
+	Embed(ctx context.Context, req *models.EmbeddingBatchRequest) (*models.EmbeddingBatchResponse, error)
+
+	// Usage reports the caller's workspace usage over the last days
+	Usage(ctx context.Context, days int) (*models.EmbeddingUsageReport, error)
+}
+
+// embeddingBatchService packs texts into provider requests by estimated tokens and records
+// daily usage in embedding_usage
+type embeddingBatchService struct {
+	db         *sql.DB
+	chunks     UnifiedChunkService
+	embeddings EmbeddingService
+	writer     EmbeddingWriter
+	config     *config.EmbeddingConfig
+	tokenizer  Tokenizer
+	monitor    QueryPerformanceMonitor
+}
+
+// NewEmbeddingBatchService creates a batch embedding service storing chunk embeddings
+// through writer
+func NewEmbeddingBatchService(db *sql.DB, chunks UnifiedChunkService, embeddings EmbeddingService, writer EmbeddingWriter, cfg *config.EmbeddingConfig, monitor QueryPerformanceMonitor) EmbeddingBatchService {
+	return &embeddingBatchService{
+		db:         db,
+		chunks:     chunks,
+		embeddings: embeddings,
+		writer:     writer,
+		config:     cfg,
+		tokenizer:  EstimatingTokenizer,
+		monitor:    monitor,
+	}
+}
+
+// embeddingBatchItem is a chunk or text waiting for its embedding
+type embeddingBatchItem struct {
+	index   int
+	chunkID string
+	text    string
+	tokens  int
+}
+
+// Embed embeds the chunks and texts of a request batch by batch
+func (s *embeddingBatchService) Embed(ctx context.Context, req *models.EmbeddingBatchRequest) (*models.EmbeddingBatchResponse, error) {
+	start := time.Now()
+	total := len(req.ChunkIDs) + len(req.Texts)
+	if total == 0 {
+		return nil, fmt.Errorf("%w: chunk_ids or texts are required", ErrInvalidEmbeddingBatch)
+	}
+	if total > maxEmbeddingBatchItems {
+		return nil, fmt.Errorf("%w: at most %d chunks and texts per batch", ErrInvalidEmbeddingBatch, maxEmbeddingBatchItems)
+	}
+
+	response := &models.EmbeddingBatchResponse{
+		Results: make([]models.EmbeddingBatchResult, total),
+		Model:   s.config.Model,
+	}
+	fail := func(index int, message string) {
+		response.Results[index].Status = models.EmbeddingBatchStatusFailed
+		response.Results[index].Error = message
+	}
+
+	items, err := s.collectItems(ctx, req, response.Results, fail)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, batch := range s.packBatches(items) {
+		if err := ctx.Err(); err != nil {
+			for _, item := range batch {
+				fail(item.index, err.Error())
+			}
+			continue
+		}
+
+		texts := make([]string, len(batch))
+		for i, item := range batch {
+			texts[i] = item.text
+		}
+		vectors, tokens, estimated, err := s.embed(ctx, batch, texts)
+		if err != nil {
+			for _, item := range batch {
+				fail(item.index, err.Error())
+			}
+			continue
+		}
+		response.Requests++
+		response.Tokens += tokens
+		response.EstimatedTokens += estimated
+
+		var records []models.EmbeddingRecord
+		var stored []int
+		for i, item := range batch {
+			result := &response.Results[item.index]
+			result.Status = models.EmbeddingBatchStatusOK
+			if item.chunkID == "" || req.ReturnVectors {
+				result.Vector = vectors[i]
+			}
+			if item.chunkID != "" {
+				records = append(records, models.EmbeddingRecord{ChunkID: item.chunkID, Vector: vectors[i], CreatedAt: time.Now()})
+				stored = append(stored, item.index)
+			}
+		}
+		if len(records) > 0 {
+			if err := s.writer.InsertEmbeddings(ctx, records); err != nil {
+				for _, index := range stored {
+					fail(index, fmt.Sprintf("failed to store embedding: %v", err))
+				}
+				continue
+			}
+			for _, index := range stored {
+				response.Results[index].Stored = true
+			}
+		}
+	}
+
+	for _, result := range response.Results {
+		if result.Status == models.EmbeddingBatchStatusOK {
+			response.Succeeded++
+		} else {
+			response.Failed++
+		}
+	}
+	response.EstimatedCost = embeddingCost(response.Tokens, s.config.CostPerMillionTokens)
+	if response.Requests > 0 {
+		s.recordUsage(ctx, response)
+	}
+
+	response.Duration = time.Since(start)
+	s.monitor.RecordQuery("embedding_batch", response.Duration, response.Succeeded)
+	return response, nil
+}
+
+// collectItems resolves chunk IDs to their contents and fails chunks that cannot be
+// embedded: missing, empty or sensitive ones
+func (s *embeddingBatchService) collectItems(ctx context.Context, req *models.EmbeddingBatchRequest, results []models.EmbeddingBatchResult, fail func(int, string)) ([]embeddingBatchItem, error) {
+	items := make([]embeddingBatchItem, 0, len(results))
+
+	if len(req.ChunkIDs) > 0 {
+		chunks, err := s.chunks.BatchGetChunks(ctx, req.ChunkIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load chunks: %w", err)
+		}
+		for i, chunkID := range req.ChunkIDs {
+			results[i].Index = i
+			results[i].ChunkID = chunkID
+			chunk, ok := chunks[chunkID]
+			switch {
+			case !ok:
+				fail(i, "chunk not found")
+			case !embeddable(chunk):
+				fail(i, "chunk is empty or sensitive and is not embedded")
+			default:
+				items = append(items, embeddingBatchItem{index: i, chunkID: chunkID, text: chunk.Contents})
+			}
+		}
+	}
+
+	for i, text := range req.Texts {
+		index := len(req.ChunkIDs) + i
+		results[index].Index = index
+		if text == "" {
+			fail(index, "text is empty")
+			continue
+		}
+		items = append(items, embeddingBatchItem{index: index, text: text})
+	}
+
+	for i := range items {
+		items[i].tokens = s.tokenizer.CountTokens(items[i].text)
+	}
+	return items, nil
+}
+
+// packBatches groups items in order into provider requests of at most BatchMaxTexts texts
+// and BatchMaxTokens estimated tokens. A text larger than the token limit is sent alone.
+func (s *embeddingBatchService) packBatches(items []embeddingBatchItem) [][]embeddingBatchItem {
+	var batches [][]embeddingBatchItem
+	var batch []embeddingBatchItem
+	tokens := 0
+	for _, item := range items {
+		if len(batch) > 0 && (len(batch) == s.config.BatchMaxTexts || tokens+item.tokens > s.config.BatchMaxTokens) {
+			batches = append(batches, batch)
+			batch, tokens = nil, 0
+		}
+		batch = append(batch, item)
+		tokens += item.tokens
+	}
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// embed generates the embeddings of one batch and returns the tokens used, of which
+// estimated were not reported by the provider
+func (s *embeddingBatchService) embed(ctx context.Context, batch []embeddingBatchItem, texts []string) ([][]float64, int, int, error) {
+	var vectors [][]float64
+	tokens := 0
+	var err error
+	if reporter, ok := s.embeddings.(EmbeddingUsageReporter); ok {
+		vectors, tokens, err = reporter.GenerateBatchEmbeddingsWithUsage(ctx, texts)
+	} else {
+		vectors, err = s.embeddings.GenerateBatchEmbeddings(ctx, texts)
+	}
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if len(vectors) != len(texts) {
+		return nil, 0, 0, fmt.Errorf("embedding provider returned %d vectors for %d texts", len(vectors), len(texts))
+	}
+	if tokens > 0 {
+		return vectors, tokens, 0, nil
+	}
+	for _, item := range batch {
+		tokens += item.tokens
+	}
+	return vectors, tokens, tokens, nil
+}
+
+// embeddingCost estimates the cost of tokens at a price per million tokens
+func embeddingCost(tokens int, costPerMillion float64) float64 {
+	return float64(tokens) * costPerMillion / 1e6
+}
+
+// recordUsage adds a batch to the workspace's daily usage. Embeddings are already made,
+// so accounting failures are only logged.
+func (s *embeddingBatchService) recordUsage(ctx context.Context, response *models.EmbeddingBatchResponse) {
+	workspace := WorkspaceFromContext(ctx)
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO embedding_usage (workspace_id, model, requests, texts, tokens, estimated_tokens, cost)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (workspace_id, usage_date, model) DO UPDATE SET
+			requests = embedding_usage.requests + EXCLUDED.requests,
+			texts = embedding_usage.texts + EXCLUDED.texts,
+			tokens = embedding_usage.tokens + EXCLUDED.tokens,
+			estimated_tokens = embedding_usage.estimated_tokens + EXCLUDED.estimated_tokens,
+			cost = embedding_usage.cost + EXCLUDED.cost`,
+		workspace, response.Model, response.Requests, response.Succeeded,
+		response.Tokens, response.EstimatedTokens, response.EstimatedCost); err != nil {
+		log.Printf("Warning: failed to record embedding usage of workspace %s: %v", workspace, err)
+	}
+}
+
+// Usage reports the caller's workspace usage per day and model
+func (s *embeddingBatchService) Usage(ctx context.Context, days int) (*models.EmbeddingUsageReport, error) {
+	if days <= 0 {
+		days = defaultEmbeddingUsageDays
+	}
+	if days > 366 {
+		return nil, fmt.Errorf("%w: days must be at most 366", ErrInvalidEmbeddingBatch)
+	}
+
+	now := time.Now().UTC()
+	report := &models.EmbeddingUsageReport{
+		Workspace: WorkspaceFromContext(ctx),
+		Since:     time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days),
+		Days:      []models.EmbeddingUsageDay{},
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT usage_date, model, requests, texts, tokens, estimated_tokens, cost::float8
+		FROM embedding_usage
+		WHERE workspace_id = $1 AND usage_date >= $2::date
+		ORDER BY usage_date DESC, model`, report.Workspace, report.Since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query embedding usage: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day models.EmbeddingUsageDay
+		if err := rows.Scan(&day.Date, &day.Model, &day.Requests, &day.Texts, &day.Tokens, &day.EstimatedTokens, &day.EstimatedCost); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding usage: %w", err)
+		}
+		report.Days = append(report.Days, day)
+		report.Requests += day.Requests
+		report.Texts += day.Texts
+		report.Tokens += day.Tokens
+		report.EstimatedCost += day.EstimatedCost
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating embedding usage: %w", err)
+	}
+	return report, nil
+}
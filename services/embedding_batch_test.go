@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// batchChunks serves chunks from a map
+type batchChunks struct {
+	UnifiedChunkService
+	chunks map[string]*models.UnifiedChunkRecord
+}
+
+func (c *batchChunks) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	found := make(map[string]*models.UnifiedChunkRecord)
+	for _, id := range chunkIDs {
+		if chunk, ok := c.chunks[id]; ok {
+			found[id] = chunk
+		}
+	}
+	return found, nil
+}
+
+// recordingEmbeddingWriter keeps inserted embeddings, failing for chunks in fail
+type recordingEmbeddingWriter struct {
+	inserted []models.EmbeddingRecord
+	fail     map[string]bool
+}
+
+func (w *recordingEmbeddingWriter) InsertEmbeddings(ctx context.Context, embeddings []models.EmbeddingRecord) error {
+	for _, embedding := range embeddings {
+		if w.fail[embedding.ChunkID] {
+			return errors.New("insert rejected")
+		}
+	}
+	w.inserted = append(w.inserted, embeddings...)
+	return nil
+}
+
+// reportingEmbeddingService bills one token per text and counts requests
+type reportingEmbeddingService struct {
+	*TestEmbeddingService
+	requests [][]string
+}
+
+func (s *reportingEmbeddingService) GenerateBatchEmbeddingsWithUsage(ctx context.Context, texts []string) ([][]float64, int, error) {
+	s.requests = append(s.requests, texts)
+	vectors, err := s.GenerateBatchEmbeddings(ctx, texts)
+	return vectors, len(texts), err
+}
+
+// unreachableDB returns a handle whose queries fail quickly, for services that only log
+// write failures
+func unreachableDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("postgres", "host=127.0.0.1 port=1 dbname=unused sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testEmbeddingBatchConfig() *config.EmbeddingConfig {
+	return &config.EmbeddingConfig{
+		Model:                "test-embedding",
+		BatchMaxTokens:       100,
+		BatchMaxTexts:        3,
+		CostPerMillionTokens: 0.02,
+	}
+}
+
+func TestEmbeddingBatchService_PackBatches(t *testing.T) {
+	service := NewEmbeddingBatchService(nil, nil, nil, nil, testEmbeddingBatchConfig(), NewNoOpMonitor()).(*embeddingBatchService)
+
+	items := []embeddingBatchItem{
+		{index: 0, tokens: 40}, {index: 1, tokens: 40}, {index: 2, tokens: 40},
+		{index: 3, tokens: 500},
+		{index: 4, tokens: 1}, {index: 5, tokens: 1}, {index: 6, tokens: 1}, {index: 7, tokens: 1},
+	}
+	var sizes []int
+	for _, batch := range service.packBatches(items) {
+		sizes = append(sizes, len(batch))
+	}
+	assert.Equal(t, []int{2, 1, 1, 3, 1}, sizes)
+}
+
+func TestEmbeddingBatchService_Embed(t *testing.T) {
+	chunks := &batchChunks{chunks: map[string]*models.UnifiedChunkRecord{
+		"a":         {ChunkID: "a", Contents: "first chunk"},
+		"b":         {ChunkID: "b", Contents: "second chunk"},
+		"empty":     {ChunkID: "empty", Contents: "  "},
+		"sensitive": {ChunkID: "sensitive", Contents: "secret", Metadata: map[string]interface{}{"sensitive": true}},
+		"rejected":  {ChunkID: "rejected", Contents: "stored elsewhere"},
+	}}
+	embeddings := &reportingEmbeddingService{TestEmbeddingService: NewTestEmbeddingService()}
+	writer := &recordingEmbeddingWriter{fail: map[string]bool{"rejected": true}}
+	cfg := testEmbeddingBatchConfig()
+	cfg.BatchMaxTexts = 2
+	service := NewEmbeddingBatchService(unreachableDB(t), chunks, embeddings, writer, cfg, NewNoOpMonitor())
+
+	response, err := service.Embed(context.Background(), &models.EmbeddingBatchRequest{
+		ChunkIDs: []string{"a", "missing", "empty", "sensitive", "b", "rejected"},
+		Texts:    []string{"raw text", ""},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 8)
+
+	status := make([]string, len(response.Results))
+	for i, result := range response.Results {
+		assert.Equal(t, i, result.Index)
+		status[i] = result.Status
+	}
+	assert.Equal(t, []string{"ok", "failed", "failed", "failed", "ok", "failed", "ok", "failed"}, status)
+	assert.Equal(t, 3, response.Succeeded)
+	assert.Equal(t, 5, response.Failed)
+
+	assert.True(t, response.Results[0].Stored)
+	assert.Nil(t, response.Results[0].Vector, "chunk vectors are only returned on request")
+	assert.NotEmpty(t, response.Results[6].Vector)
+	assert.False(t, response.Results[6].Stored)
+	assert.Equal(t, "chunk not found", response.Results[1].Error)
+	assert.True(t, strings.HasPrefix(response.Results[5].Error, "failed to store embedding"))
+
+	// a and b, then rejected and the raw text: a failed insert only fails the chunks of its batch
+	assert.Equal(t, 2, response.Requests)
+	assert.Len(t, embeddings.requests, 2)
+	assert.Equal(t, 4, response.Tokens)
+	assert.Zero(t, response.EstimatedTokens)
+	assert.InDelta(t, 4*0.02/1e6, response.EstimatedCost, 1e-12)
+
+	var stored []string
+	for _, record := range writer.inserted {
+		stored = append(stored, record.ChunkID)
+	}
+	assert.Equal(t, []string{"a", "b"}, stored)
+}
+
+func TestEmbeddingBatchService_EstimatesTokensAndReportsProviderFailures(t *testing.T) {
+	chunks := &batchChunks{chunks: map[string]*models.UnifiedChunkRecord{}}
+	embeddings := NewTestEmbeddingService()
+	service := NewEmbeddingBatchService(unreachableDB(t), chunks, embeddings, &recordingEmbeddingWriter{}, testEmbeddingBatchConfig(), NewNoOpMonitor())
+
+	response, err := service.Embed(context.Background(), &models.EmbeddingBatchRequest{Texts: []string{"one text", "another text"}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Succeeded)
+	assert.Positive(t, response.Tokens)
+	assert.Equal(t, response.Tokens, response.EstimatedTokens)
+
+	embeddings.SetShouldFail(true)
+	response, err = service.Embed(context.Background(), &models.EmbeddingBatchRequest{Texts: []string{"one text"}})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Failed)
+	assert.Zero(t, response.Requests)
+	assert.Contains(t, response.Results[0].Error, "mock embedding service error")
+
+	_, err = service.Embed(context.Background(), &models.EmbeddingBatchRequest{})
+	assert.True(t, errors.Is(err, ErrInvalidEmbeddingBatch))
+}
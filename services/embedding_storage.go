@@ -0,0 +1,730 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// EmbeddingStore writes and searches text embeddings in the configured storage format and
+// re-encodes existing embeddings when the format or dimensionality changes. Image vectors
+// always stay float32 in chunks.vector.
+type EmbeddingStore struct {
+	db         *sql.DB
+	format     string
+	dimensions int
+	indexes    VectorIndexManager
+	monitor    QueryPerformanceMonitor
+}
+
+const (
+	// int8SearchOversample is how many sign-bit candidates are re-scored per requested result
+	int8SearchOversample    = 10
+	minInt8SearchCandidates = 100
+	// maxInt8SearchCandidates is the largest hnsw.ef_search pgvector accepts
+	maxInt8SearchCandidates  = 1000
+	defaultReencodeBatchSize = 500
+)
+
+// embeddingColumns lists the chunks columns each storage format writes; the first one is
+// NULL when a chunk has no embedding in that format
+var embeddingColumns = map[string][]string{
+	models.EmbeddingStorageVector:  {"vector"},
+	models.EmbeddingStorageHalfvec: {"vector_half"},
+	models.EmbeddingStorageInt8:    {"vector_int8", "vector_int8_scale", "vector_bits"},
+}
+
+// NewEmbeddingStore creates an embedding store for the configured format. indexes may be
+// nil; searches then run with the server's default vector search settings.
+func NewEmbeddingStore(db *sql.DB, cfg *config.EmbeddingConfig, indexes VectorIndexManager, monitor QueryPerformanceMonitor) *EmbeddingStore {
+	format := cfg.Storage
+	if format == "" {
+		format = models.EmbeddingStorageVector
+	}
+	return &EmbeddingStore{
+		db:         db,
+		format:     format,
+		dimensions: cfg.Dimensions,
+		indexes:    indexes,
+		monitor:    monitor,
+	}
+}
+
+// Format returns the storage format of text embeddings
+func (s *EmbeddingStore) Format() string {
+	return s.format
+}
+
+// Quantized reports whether text embeddings are stored outside the float32 vector column,
+// which the Supabase match_chunks function searches
+func (s *EmbeddingStore) Quantized() bool {
+	return s.format != models.EmbeddingStorageVector
+}
+
+// prepare truncates an embedding to the configured dimensionality
+func (s *EmbeddingStore) prepare(vector []float64) ([]float64, error) {
+	if len(vector) == 0 {
+		return nil, fmt.Errorf("embedding is empty")
+	}
+	if s.dimensions > 0 && len(vector) < s.dimensions {
+		return nil, fmt.Errorf("embedding has %d dimensions, fewer than the configured %d", len(vector), s.dimensions)
+	}
+	return truncateEmbedding(vector, s.dimensions), nil
+}
+
+// presentColumn is NULL exactly when a chunk has no embedding in the configured format
+func (s *EmbeddingStore) presentColumn() string {
+	return embeddingColumns[s.format][0]
+}
+
+// clearAssignments is the SET list removing a chunk's text embedding in every column the
+// configured format may have left a value in
+func (s *EmbeddingStore) clearAssignments() string {
+	columns := embeddingColumns[s.format]
+	if s.Quantized() {
+		columns = append([]string{"vector"}, columns...)
+	}
+	assignments := make([]string, len(columns))
+	for i, column := range columns {
+		assignments[i] = column + " = NULL"
+	}
+	return strings.Join(assignments, ", ")
+}
+
+// assignments is the SET list storing a prepared vector in the configured format, with
+// placeholders numbered from argIndex. clearFloat also removes the float32 copy that
+// quantized formats replace.
+func (s *EmbeddingStore) assignments(vector []float64, argIndex int, clearFloat bool) (string, []interface{}) {
+	var set []string
+	var args []interface{}
+	switch s.format {
+	case models.EmbeddingStorageHalfvec:
+		set = append(set, fmt.Sprintf("vector_half = $%d::halfvec", argIndex))
+		args = append(args, formatVectorLiteral(vector))
+	case models.EmbeddingStorageInt8:
+		codes, scale := quantizeInt8(vector)
+		set = append(set,
+			fmt.Sprintf("vector_int8 = $%d", argIndex),
+			fmt.Sprintf("vector_int8_scale = $%d", argIndex+1),
+			fmt.Sprintf("vector_bits = $%d::bit(%d)", argIndex+2, len(vector)))
+		args = append(args, codes, scale, embeddingBits(vector))
+	default:
+		return fmt.Sprintf("vector = $%d::vector", argIndex), []interface{}{formatVectorLiteral(vector)}
+	}
+	if clearFloat {
+		set = append(set, "vector = NULL")
+	}
+	return strings.Join(set, ", "), args
+}
+
+// Store writes the text embedding of a chunk's contents, replacing any earlier one
+func (s *EmbeddingStore) Store(ctx context.Context, chunkID, contents, model string, vector []float64) error {
+	prepared, err := s.prepare(vector)
+	if err != nil {
+		return err
+	}
+	set, args := s.assignments(prepared, 2, true)
+	next := 2 + len(args)
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE chunks SET %s, vector_type = $%d, vector_model = $%d, vector_content_hash = $%d
+		WHERE chunk_id = $1`, set, next, next+1, next+2),
+		append(append([]interface{}{chunkID}, args...),
+			string(models.VectorTypeText), model, contentHash(contents))...)
+	if err != nil {
+		return fmt.Errorf("failed to store embedding: %w", err)
+	}
+	return nil
+}
+
+// similarityColumns are the chunk columns returned by similarity searches
+const similarityColumns = "chunk_id, contents, parent, is_template, is_slot, metadata, created_time, last_updated"
+
+// SearchSimilar finds the text chunks closest to the query vector by cosine similarity.
+// int8 embeddings are searched through their sign bits and the candidates re-scored.
+func (s *EmbeddingStore) SearchSimilar(ctx context.Context, queryVector []float64, limit int) ([]models.SimilarityResult, error) {
+	return s.SearchSimilarFiltered(ctx, queryVector, limit, models.SimilarityFilter{})
+}
+
+// SearchSimilarFiltered finds the text chunks matching filter closest to the query vector,
+// filtering in the same query that orders by distance
+func (s *EmbeddingStore) SearchSimilarFiltered(ctx context.Context, queryVector []float64, limit int, filter models.SimilarityFilter) ([]models.SimilarityResult, error) {
+	if limit <= 0 {
+		limit = 10
+	}
+	query, err := s.prepare(queryVector)
+	if err != nil {
+		return nil, err
+	}
+	condition, filterArgs := similarityFilterCondition(filter, 3)
+
+	start := time.Now()
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.indexes != nil {
+		if err := s.indexes.ConfigureSearch(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+
+	var results []models.SimilarityResult
+	if s.format == models.EmbeddingStorageInt8 {
+		results, err = s.searchInt8(ctx, tx, query, limit, condition, filterArgs)
+	} else {
+		results, err = s.searchFloat(ctx, tx, query, limit, condition, filterArgs)
+	}
+	s.monitor.RecordQuery("embedding_search_"+s.format, time.Since(start), len(results))
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// similarityFilterCondition compiles filter into conditions to AND onto a chunks query,
+// with placeholders from argIndex
+func similarityFilterCondition(filter models.SimilarityFilter, argIndex int) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", argIndex+len(args)-1)
+	}
+	if len(filter.TagIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("tags ?& %s::text[]", arg(pq.Array(filter.TagIDs))))
+	}
+	if filter.PageID != "" {
+		page := arg(filter.PageID)
+		conditions = append(conditions, fmt.Sprintf("(page = %s::uuid OR chunk_id = %s::uuid)", page, page))
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_time >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_time < "+arg(*filter.CreatedBefore))
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " AND " + strings.Join(conditions, " AND "), args
+}
+
+// searchFloat orders by cosine distance on the vector or halfvec column
+func (s *EmbeddingStore) searchFloat(ctx context.Context, tx *sql.Tx, query []float64, limit int, condition string, filterArgs []interface{}) ([]models.SimilarityResult, error) {
+	column, cast, where := "vector", "vector", "vector_type = 'text' AND vector IS NOT NULL"
+	if s.format == models.EmbeddingStorageHalfvec {
+		column, cast, where = "vector_half", "halfvec", "vector_half IS NOT NULL"
+	}
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s, 1 - (%s <=> $1::%s)
+		FROM chunks
+		WHERE %s%s
+		ORDER BY %s <=> $1::%s
+		LIMIT $2`, similarityColumns, column, cast, where, condition, column, cast),
+		append([]interface{}{formatVectorLiteral(query), limit}, filterArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.SimilarityResult
+	for rows.Next() {
+		var result models.SimilarityResult
+		if err := scanSimilarityChunk(rows, &result.Chunk, &result.Similarity); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read similar chunks: %w", err)
+	}
+	return results, nil
+}
+
+// searchInt8 fetches candidates by Hamming distance of the sign bits, then ranks them by the
+// cosine similarity of the int8 codes
+func (s *EmbeddingStore) searchInt8(ctx context.Context, tx *sql.Tx, query []float64, limit int, condition string, filterArgs []interface{}) ([]models.SimilarityResult, error) {
+	candidates := limit * int8SearchOversample
+	if candidates < minInt8SearchCandidates {
+		candidates = minInt8SearchCandidates
+	}
+	if candidates > maxInt8SearchCandidates {
+		candidates = maxInt8SearchCandidates
+	}
+	// An HNSW scan returns at most ef_search rows
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('hnsw.ef_search', $1, true)", strconv.Itoa(candidates)); err != nil {
+		return nil, fmt.Errorf("failed to apply vector search setting: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s, vector_int8
+		FROM chunks
+		WHERE vector_bits IS NOT NULL%s
+		ORDER BY vector_bits <~> $1::bit(%d)
+		LIMIT $2`, similarityColumns, condition, len(query)),
+		append([]interface{}{embeddingBits(query), candidates}, filterArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.SimilarityResult
+	for rows.Next() {
+		var result models.SimilarityResult
+		var codes []byte
+		if err := scanSimilarityChunk(rows, &result.Chunk, &codes); err != nil {
+			return nil, err
+		}
+		result.Similarity = int8Similarity(query, codes)
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read similar chunks: %w", err)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Similarity > results[j].Similarity })
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// scanSimilarityChunk reads similarityColumns followed by extra
+func scanSimilarityChunk(rows *sql.Rows, chunk *models.ChunkRecord, extra ...interface{}) error {
+	var parent sql.NullString
+	var metadata []byte
+	dest := append([]interface{}{&chunk.ID, &chunk.Content, &parent, &chunk.IsTemplate, &chunk.IsSlot,
+		&metadata, &chunk.CreatedAt, &chunk.UpdatedAt}, extra...)
+	if err := rows.Scan(dest...); err != nil {
+		return fmt.Errorf("failed to scan similar chunk: %w", err)
+	}
+	if parent.Valid {
+		chunk.ParentChunkID = &parent.String
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &chunk.Metadata); err != nil {
+			return fmt.Errorf("failed to parse chunk metadata: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetChunkVectors returns the text embeddings of the chunks in the configured format, by
+// chunk ID. Chunks without one are left out.
+func (s *EmbeddingStore) GetChunkVectors(ctx context.Context, chunkIDs []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return vectors, nil
+	}
+	columns := embeddingColumns[s.format][0] + "::text"
+	if s.format == models.EmbeddingStorageInt8 {
+		columns = "vector_int8, vector_int8_scale"
+	}
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT chunk_id, %s FROM chunks
+		WHERE chunk_id = ANY($1::uuid[]) AND %s IS NOT NULL`, columns, s.presentColumn()),
+		pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chunk embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var chunkID string
+		var vector []float64
+		if s.format == models.EmbeddingStorageInt8 {
+			var codes []byte
+			var scale float64
+			if err := rows.Scan(&chunkID, &codes, &scale); err != nil {
+				return nil, fmt.Errorf("failed to scan embedding: %w", err)
+			}
+			vector = dequantizeInt8(codes, scale)
+		} else {
+			var text string
+			if err := rows.Scan(&chunkID, &text); err != nil {
+				return nil, fmt.Errorf("failed to scan embedding: %w", err)
+			}
+			if err := json.Unmarshal([]byte(text), &vector); err != nil {
+				return nil, fmt.Errorf("failed to parse embedding of chunk %s: %w", chunkID, err)
+			}
+		}
+		vectors[chunkID] = vector
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chunk embeddings: %w", err)
+	}
+	s.monitor.RecordQuery("embedding_vectors_"+s.format, time.Since(start), len(vectors))
+	return vectors, nil
+}
+
+// Reencode writes every text embedding in the configured format and dimensionality, reading
+// it from whichever format it is stored in, then creates the format's vector index. Unless
+// keepSource is set the other formats' copies are cleared; VACUUM chunks afterwards to
+// reclaim their space.
+func (s *EmbeddingStore) Reencode(ctx context.Context, batchSize int, keepSource bool) (*models.EmbeddingReencodeResult, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	if batchSize <= 0 {
+		batchSize = defaultReencodeBatchSize
+	}
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("reencode_embeddings", time.Since(start), 1)
+	}()
+
+	// Which formats exist, and the column types they were created with
+	types := make(map[string]string)
+	for format, columns := range embeddingColumns {
+		column := columns[len(columns)-1]
+		var columnType sql.NullString
+		err := s.db.QueryRowContext(ctx, `
+			SELECT format_type(atttypid, atttypmod) FROM pg_attribute
+			WHERE attrelid = 'chunks'::regclass AND attname = $1 AND NOT attisdropped`, column).Scan(&columnType)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to inspect chunks.%s: %w", column, err)
+		}
+		if columnType.Valid {
+			types[format] = columnType.String
+		}
+	}
+	if _, ok := types[models.EmbeddingStorageVector]; !ok {
+		return nil, fmt.Errorf("chunks has no vector column; run the multimodal embeddings migration first")
+	}
+
+	dimensions := s.dimensions
+	if existing := types[s.format]; dimensions == 0 && strings.HasSuffix(existing, ")") {
+		// The configured format's columns decide, as in ensureColumns
+		dimensions, _ = strconv.Atoi(existing[strings.Index(existing, "(")+1 : len(existing)-1])
+	}
+	if dimensions == 0 {
+		detected, err := s.detectDimensions(ctx, types)
+		if err != nil {
+			return nil, err
+		}
+		if detected == 0 {
+			return nil, fmt.Errorf("no text embeddings to re-encode; set EMBEDDING_DIMENSIONS to size the %s columns", s.format)
+		}
+		dimensions = detected
+	}
+	result := &models.EmbeddingReencodeResult{Format: s.format, Dimensions: dimensions}
+
+	if err := s.ensureColumns(ctx, types, dimensions); err != nil {
+		return nil, err
+	}
+	types[s.format] = "" // written below, never a source
+
+	for {
+		count, err := s.reencodeBatch(ctx, types, batchSize, !keepSource)
+		if err != nil {
+			return result, err
+		}
+		result.Reencoded += count
+		if count < int64(batchSize) {
+			break
+		}
+	}
+
+	if !keepSource {
+		for format := range types {
+			if format == s.format {
+				continue
+			}
+			set := make([]string, len(embeddingColumns[format]))
+			for i, column := range embeddingColumns[format] {
+				set[i] = column + " = NULL"
+			}
+			res, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+				UPDATE chunks SET %s
+				WHERE %s IS NOT NULL AND COALESCE(vector_type, 'text') = 'text' AND %s IS NOT NULL`,
+				strings.Join(set, ", "), embeddingColumns[format][0], s.presentColumn()))
+			if err != nil {
+				return result, fmt.Errorf("failed to clear %s embeddings: %w", format, err)
+			}
+			cleared, _ := res.RowsAffected()
+			result.Cleared += cleared
+		}
+	}
+
+	if s.Quantized() && s.indexes != nil {
+		spec := s.indexes.DefaultSpec("")
+		spec.Table = "chunks"
+		spec.Column = embeddingColumns[s.format][len(embeddingColumns[s.format])-1]
+		spec.OperatorClass = "halfvec_cosine_ops"
+		if s.format == models.EmbeddingStorageInt8 {
+			spec.OperatorClass = "bit_hamming_ops"
+		}
+		spec.Name = fmt.Sprintf("idx_chunks_%s_%s", spec.Column, spec.Method)
+		if err := s.indexes.CreateIndex(ctx, spec); err != nil {
+			return result, err
+		}
+		result.IndexName = spec.Name
+	}
+
+	result.Duration = time.Since(start)
+	return result, nil
+}
+
+// detectDimensions reads the dimensionality of a stored text embedding
+func (s *EmbeddingStore) detectDimensions(ctx context.Context, types map[string]string) (int, error) {
+	queries := map[string]string{
+		models.EmbeddingStorageVector:  "SELECT vector_dims(vector) FROM chunks WHERE vector IS NOT NULL AND COALESCE(vector_type, 'text') = 'text' LIMIT 1",
+		models.EmbeddingStorageHalfvec: "SELECT vector_dims(vector_half) FROM chunks WHERE vector_half IS NOT NULL LIMIT 1",
+		models.EmbeddingStorageInt8:    "SELECT length(vector_int8) FROM chunks WHERE vector_int8 IS NOT NULL LIMIT 1",
+	}
+	for format := range types {
+		var dimensions int
+		err := s.db.QueryRowContext(ctx, queries[format]).Scan(&dimensions)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s embedding dimensions: %w", format, err)
+		}
+		return dimensions, nil
+	}
+	return 0, nil
+}
+
+// ensureColumns adds the configured format's columns, or checks the dimensions of existing ones
+func (s *EmbeddingStore) ensureColumns(ctx context.Context, types map[string]string, dimensions int) error {
+	base := map[string]string{
+		models.EmbeddingStorageVector:  "vector",
+		models.EmbeddingStorageHalfvec: "halfvec",
+		models.EmbeddingStorageInt8:    "bit",
+	}[s.format]
+	want := fmt.Sprintf("%s(%d)", base, dimensions)
+
+	if existing, ok := types[s.format]; ok {
+		if existing != want && existing != base {
+			columns := embeddingColumns[s.format]
+			return fmt.Errorf("chunks.%s is %s but the configured dimensionality needs %s; re-embed or drop the column to change it",
+				columns[len(columns)-1], existing, want)
+		}
+		return nil
+	}
+
+	var ddl string
+	switch s.format {
+	case models.EmbeddingStorageHalfvec:
+		ddl = fmt.Sprintf("ALTER TABLE chunks ADD COLUMN IF NOT EXISTS vector_half halfvec(%d)", dimensions)
+	case models.EmbeddingStorageInt8:
+		ddl = fmt.Sprintf(`ALTER TABLE chunks ADD COLUMN IF NOT EXISTS vector_int8 bytea,
+			ADD COLUMN IF NOT EXISTS vector_int8_scale real,
+			ADD COLUMN IF NOT EXISTS vector_bits bit(%d)`, dimensions)
+	default:
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, ddl); err != nil {
+		return fmt.Errorf("failed to add %s embedding columns: %w", s.format, err)
+	}
+	return nil
+}
+
+// reencodeBatch converts up to batchSize text embeddings that are missing in the configured
+// format and reports how many it wrote
+func (s *EmbeddingStore) reencodeBatch(ctx context.Context, types map[string]string, batchSize int, clearFloat bool) (int64, error) {
+	var selects, present []string
+	var sources []string
+	for _, format := range []string{models.EmbeddingStorageVector, models.EmbeddingStorageHalfvec, models.EmbeddingStorageInt8} {
+		if _, ok := types[format]; !ok || format == s.format {
+			continue
+		}
+		sources = append(sources, format)
+		present = append(present, embeddingColumns[format][0]+" IS NOT NULL")
+		switch format {
+		case models.EmbeddingStorageInt8:
+			selects = append(selects, "vector_int8, vector_int8_scale")
+		default:
+			selects = append(selects, embeddingColumns[format][0]+"::text")
+		}
+	}
+	if len(sources) == 0 {
+		return 0, nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT chunk_id, %s FROM chunks
+		WHERE %s IS NULL AND COALESCE(vector_type, 'text') = 'text' AND (%s)
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`,
+		strings.Join(selects, ", "), s.presentColumn(), strings.Join(present, " OR ")), batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read embeddings to re-encode: %w", err)
+	}
+
+	vectors := make(map[string][]float64)
+	for rows.Next() {
+		var chunkID string
+		dest := []interface{}{&chunkID}
+		texts := make(map[string]*sql.NullString)
+		var codes []byte
+		var scale sql.NullFloat64
+		for _, format := range sources {
+			if format == models.EmbeddingStorageInt8 {
+				dest = append(dest, &codes, &scale)
+				continue
+			}
+			texts[format] = &sql.NullString{}
+			dest = append(dest, texts[format])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+
+		var vector []float64
+		for _, format := range sources {
+			if text := texts[format]; text != nil && text.Valid {
+				if err := json.Unmarshal([]byte(text.String), &vector); err != nil {
+					rows.Close()
+					return 0, fmt.Errorf("failed to parse %s embedding of chunk %s: %w", format, chunkID, err)
+				}
+				break
+			}
+			if format == models.EmbeddingStorageInt8 && codes != nil {
+				vector = dequantizeInt8(codes, scale.Float64)
+				break
+			}
+		}
+		vectors[chunkID] = vector
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return 0, fmt.Errorf("failed to read embeddings to re-encode: %w", err)
+	}
+	rows.Close()
+
+	for chunkID, vector := range vectors {
+		prepared, err := s.prepare(vector)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encode chunk %s: %w", chunkID, err)
+		}
+		set, args := s.assignments(prepared, 2, clearFloat)
+		if _, err := tx.ExecContext(ctx, "UPDATE chunks SET "+set+" WHERE chunk_id = $1",
+			append([]interface{}{chunkID}, args...)...); err != nil {
+			return 0, fmt.Errorf("failed to store re-encoded embedding of chunk %s: %w", chunkID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit re-encoded embeddings: %w", err)
+	}
+	return int64(len(vectors)), nil
+}
+
+// truncateEmbedding keeps the first dimensions components and rescales them to unit length,
+// which is how Matryoshka-trained models such as text-embedding-3 shorten embeddings
+func truncateEmbedding(vector []float64, dimensions int) []float64 {
+	if dimensions <= 0 || len(vector) <= dimensions {
+		return vector
+	}
+	truncated := make([]float64, dimensions)
+	copy(truncated, vector)
+	var norm float64
+	for _, x := range truncated {
+		norm += x * x
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range truncated {
+			truncated[i] /= norm
+		}
+	}
+	return truncated
+}
+
+// quantizeInt8 maps each component to a signed byte with one scale per vector, so x is
+// approximately int8(code) * scale
+func quantizeInt8(vector []float64) ([]byte, float64) {
+	var maxAbs float64
+	for _, x := range vector {
+		maxAbs = math.Max(maxAbs, math.Abs(x))
+	}
+	codes := make([]byte, len(vector))
+	if maxAbs == 0 {
+		return codes, 0
+	}
+	scale := maxAbs / 127
+	for i, x := range vector {
+		codes[i] = byte(int8(math.Round(x / scale)))
+	}
+	return codes, scale
+}
+
+// dequantizeInt8 reverses quantizeInt8
+func dequantizeInt8(codes []byte, scale float64) []float64 {
+	vector := make([]float64, len(codes))
+	for i, code := range codes {
+		vector[i] = float64(int8(code)) * scale
+	}
+	return vector
+}
+
+// int8Similarity is the cosine similarity between a query and int8 codes; the scale
+// cancels out
+func int8Similarity(query []float64, codes []byte) float64 {
+	if len(query) != len(codes) {
+		return 0
+	}
+	var dot, queryNorm, codeNorm float64
+	for i, code := range codes {
+		c := float64(int8(code))
+		dot += query[i] * c
+		queryNorm += query[i] * query[i]
+		codeNorm += c * c
+	}
+	if queryNorm == 0 || codeNorm == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(queryNorm*codeNorm)
+}
+
+// embeddingBits is the bit string of the vector's signs, indexed for Hamming distance
+func embeddingBits(vector []float64) string {
+	var b strings.Builder
+	b.Grow(len(vector))
+	for _, x := range vector {
+		if x > 0 {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+// formatVectorLiteral writes a vector in pgvector's text format with float32 precision,
+// which is all the vector and halfvec types store
+func formatVectorLiteral(vector []float64) string {
+	buf := make([]byte, 0, len(vector)*10+2)
+	buf = append(buf, '[')
+	for i, x := range vector {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, x, 'g', -1, 32)
+	}
+	return string(append(buf, ']'))
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+func TestEmbeddingEncoding(t *testing.T) {
+	truncated := truncateEmbedding([]float64{3, 4, 12}, 2)
+	assert.InDeltaSlice(t, []float64{0.6, 0.8}, truncated, 1e-9)
+	assert.Equal(t, []float64{3, 4}, truncateEmbedding([]float64{3, 4}, 0))
+
+	vector := []float64{0.5, -0.25, 0, 0.125}
+	codes, scale := quantizeInt8(vector)
+	assert.Equal(t, []byte{127, 0xc0, 0, 32}, codes) // -64 is 0xc0
+	assert.InDelta(t, 0.5/127, scale, 1e-12)
+	assert.InDeltaSlice(t, vector, dequantizeInt8(codes, scale), scale/2)
+	assert.InDelta(t, 1.0, int8Similarity(vector, codes), 1e-3)
+	assert.Equal(t, 0.0, int8Similarity(vector, codes[:2]), "dimension mismatch")
+
+	zero, zeroScale := quantizeInt8([]float64{0, 0})
+	assert.Equal(t, []byte{0, 0}, zero)
+	assert.Equal(t, 0.0, zeroScale)
+
+	assert.Equal(t, "1001", embeddingBits(vector))
+	assert.Equal(t, "[0.5,-0.25,0,0.1]", formatVectorLiteral([]float64{0.5, -0.25, 0, 0.1}))
+	assert.Equal(t, "[1e-07]", formatVectorLiteral([]float64{1e-7}))
+	assert.False(t, math.IsNaN(int8Similarity([]float64{0, 0}, []byte{1, 1})))
+}
+
+func TestEmbeddingStoreSQL(t *testing.T) {
+	vectorStore := NewEmbeddingStore(nil, &config.EmbeddingConfig{}, nil, NewNoOpMonitor())
+	assert.False(t, vectorStore.Quantized())
+	set, args := vectorStore.assignments([]float64{1, 0}, 3, true)
+	assert.Equal(t, "vector = $3::vector", set)
+	assert.Equal(t, []interface{}{"[1,0]"}, args)
+	assert.Equal(t, "vector = NULL", vectorStore.clearAssignments())
+	assert.Equal(t, "vector", vectorStore.presentColumn())
+
+	half := NewEmbeddingStore(nil, &config.EmbeddingConfig{Storage: models.EmbeddingStorageHalfvec}, nil, NewNoOpMonitor())
+	set, args = half.assignments([]float64{1, 0}, 3, true)
+	assert.Equal(t, "vector_half = $3::halfvec, vector = NULL", set)
+	assert.Equal(t, []interface{}{"[1,0]"}, args)
+	set, _ = half.assignments([]float64{1, 0}, 3, false)
+	assert.Equal(t, "vector_half = $3::halfvec", set, "--keep-source leaves the float copy")
+
+	int8Store := NewEmbeddingStore(nil, &config.EmbeddingConfig{Storage: models.EmbeddingStorageInt8, Dimensions: 2}, nil, NewNoOpMonitor())
+	prepared, err := int8Store.prepare([]float64{3, -4, 100})
+	require.NoError(t, err)
+	assert.InDeltaSlice(t, []float64{0.6, -0.8}, prepared, 1e-9)
+	set, args = int8Store.assignments(prepared, 2, true)
+	assert.Equal(t, "vector_int8 = $2, vector_int8_scale = $3, vector_bits = $4::bit(2), vector = NULL", set)
+	require.Len(t, args, 3)
+	assert.Equal(t, "10", args[2])
+	assert.Equal(t, "vector = NULL, vector_int8 = NULL, vector_int8_scale = NULL, vector_bits = NULL", int8Store.clearAssignments())
+	assert.Equal(t, "vector_int8", int8Store.presentColumn())
+
+	_, err = int8Store.prepare([]float64{1})
+	assert.Error(t, err, "too few dimensions")
+	_, err = int8Store.prepare(nil)
+	assert.Error(t, err)
+
+	condition, filterArgs := similarityFilterCondition(models.SimilarityFilter{}, 3)
+	assert.Empty(t, condition)
+	assert.Empty(t, filterArgs)
+
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	condition, filterArgs = similarityFilterCondition(models.SimilarityFilter{
+		TagIDs: []string{"tag-a"}, PageID: "page", CreatedAfter: &after,
+	}, 3)
+	assert.Equal(t, " AND tags ?& $3::text[] AND (page = $4::uuid OR chunk_id = $4::uuid) AND created_time >= $5", condition)
+	require.Len(t, filterArgs, 3)
+	assert.Equal(t, "page", filterArgs[1])
+	assert.Equal(t, after, filterArgs[2])
+}
+
+func TestEmbeddingStore_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	var hasVectors bool
+	require.NoError(t, db.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM pg_attribute WHERE attrelid = 'chunks'::regclass AND attname = 'vector_bits' AND NOT attisdropped)`).
+		Scan(&hasVectors))
+	if !hasVectors {
+		t.Skip("run ink-gateway reencode-embeddings with EMBEDDING_STORAGE=int8 first")
+	}
+
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	store := NewEmbeddingStore(db, &config.EmbeddingConfig{Storage: models.EmbeddingStorageInt8}, nil, NewNoOpMonitor())
+	sync := NewEmbeddingSyncService(db, chunks, NewTestEmbeddingService(), store, NewNoOpMonitor(), "", 10, 100, 0).(*embeddingSyncService)
+
+	chunk := &models.UnifiedChunkRecord{Contents: "quantized embedding test"}
+	require.NoError(t, chunks.CreateChunk(ctx, chunk))
+	defer chunks.DeleteChunk(ctx, chunk.ChunkID)
+	stored, err := chunks.GetChunk(ctx, chunk.ChunkID)
+	require.NoError(t, err)
+
+	var dimensions int
+	require.NoError(t, db.QueryRowContext(ctx, "SELECT atttypmod FROM pg_attribute WHERE attrelid = 'chunks'::regclass AND attname = 'vector_bits'").Scan(&dimensions))
+	vector := make([]float64, dimensions)
+	for i := range vector {
+		vector[i] = math.Sin(float64(i + 1))
+	}
+	require.NoError(t, sync.storeEmbedding(ctx, stored, vector))
+
+	results, err := store.SearchSimilar(ctx, vector, 5)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	assert.Equal(t, chunk.ChunkID, results[0].Chunk.ID)
+	assert.InDelta(t, 1.0, results[0].Similarity, 0.01)
+}
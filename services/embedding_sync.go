@@ -0,0 +1,480 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// EmbeddingSyncService keeps text embeddings in step with chunk contents. Each embedding
+// records the hash of the contents it was computed from; writes whose contents no longer
+// match are queued and re-embedded in batches in the background.
+type EmbeddingSyncService interface {
+	// TrackChange queues a written chunk for re-embedding when its contents differ from
+	// those its embedding was computed from
+	TrackChange(ctx context.Context, chunk *models.UnifiedChunkRecord) error
+	// Enqueue queues chunks for re-embedding and returns how many were accepted
+	Enqueue(chunkIDs ...string) int
+	// Backfill queues up to limit chunks whose embedding is missing or stale
+	Backfill(ctx context.Context, limit int) (int, error)
+	// Coverage reports embedded, stale and missing chunk counts and queue progress
+	Coverage(ctx context.Context) (*models.EmbeddingCoverage, error)
+	// Start processes the queue and backfills periodically until Stop is called
+	Start(ctx context.Context)
+	// Stop stops background processing; queued chunks are left to the next backfill
+	Stop()
+}
+
+// embeddingSyncService implements EmbeddingSyncService
+type embeddingSyncService struct {
+	db         *sql.DB
+	chunks     UnifiedChunkService
+	embeddings EmbeddingService
+	monitor    QueryPerformanceMonitor
+	store      *EmbeddingStore
+	model      string
+	batchSize  int
+	interval   time.Duration
+
+	queue chan string
+
+	mu          sync.Mutex
+	pending     map[string]bool
+	lastBatchAt *time.Time
+	lastError   string
+
+	processed atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+const (
+	defaultEmbeddingSyncBatch    = 32
+	defaultEmbeddingSyncQueue    = 10000
+	defaultEmbeddingSyncInterval = 5 * time.Minute
+	// embeddingSyncFlushDelay bounds how long a partial batch waits for more chunks
+	embeddingSyncFlushDelay = 500 * time.Millisecond
+)
+
+// NewEmbeddingSyncService creates an embedding sync service. chunks is used to read
+// contents, so it must decrypt sensitive chunks; those are never embedded. A nil store
+// writes float32 vectors.
+func NewEmbeddingSyncService(db *sql.DB, chunks UnifiedChunkService, embeddings EmbeddingService, store *EmbeddingStore, monitor QueryPerformanceMonitor, model string, batchSize, queueSize int, interval time.Duration) EmbeddingSyncService {
+	if store == nil {
+		store = NewEmbeddingStore(db, &config.EmbeddingConfig{}, nil, monitor)
+	}
+	if batchSize <= 0 {
+		batchSize = defaultEmbeddingSyncBatch
+	}
+	if queueSize <= 0 {
+		queueSize = defaultEmbeddingSyncQueue
+	}
+	if interval <= 0 {
+		interval = defaultEmbeddingSyncInterval
+	}
+	if model == "" {
+		model = string(models.VectorModelTextEmbedding3Small)
+	}
+	return &embeddingSyncService{
+		db:         db,
+		chunks:     chunks,
+		embeddings: embeddings,
+		store:      store,
+		monitor:    monitor,
+		model:      model,
+		batchSize:  batchSize,
+		interval:   interval,
+		queue:      make(chan string, queueSize),
+		pending:    make(map[string]bool),
+	}
+}
+
+// contentHash identifies the contents an embedding was computed from
+func contentHash(contents string) string {
+	sum := sha256.Sum256([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+// embeddable reports whether a chunk's contents should have a text embedding
+func embeddable(chunk *models.UnifiedChunkRecord) bool {
+	if strings.TrimSpace(chunk.Contents) == "" || IsSensitive(chunk.Metadata) {
+		return false
+	}
+	return chunk.VectorType == nil || *chunk.VectorType == string(models.VectorTypeText)
+}
+
+// TrackChange compares the chunk's contents with its embedding's hash
+func (s *embeddingSyncService) TrackChange(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if IsSensitive(chunk.Metadata) {
+		// The vector of a chunk that became sensitive would leak its contents
+		_, err := s.db.ExecContext(ctx, fmt.Sprintf(`
+			UPDATE chunks SET %s, vector_content_hash = NULL
+			WHERE chunk_id = $1 AND %s IS NOT NULL AND COALESCE(vector_type, 'text') = 'text'`,
+			s.store.clearAssignments(), s.store.presentColumn()),
+			chunk.ChunkID)
+		if err != nil {
+			return fmt.Errorf("failed to clear embedding of sensitive chunk: %w", err)
+		}
+		return nil
+	}
+	if !embeddable(chunk) {
+		return nil
+	}
+
+	var stored sql.NullString
+	err := s.db.QueryRowContext(ctx,
+		"SELECT vector_content_hash FROM chunks WHERE chunk_id = $1 AND COALESCE(vector_type, 'text') = 'text'",
+		chunk.ChunkID).Scan(&stored)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get embedding hash: %w", err)
+	}
+
+	hash := contentHash(chunk.Contents)
+	if stored.Valid && stored.String == hash {
+		return nil
+	}
+	if stored.Valid {
+		// Mark the embedding stale so that it is backfilled even if the queue is lost
+		_, err := s.db.ExecContext(ctx,
+			"UPDATE chunks SET vector_content_hash = NULL WHERE chunk_id = $1 AND vector_content_hash = $2",
+			chunk.ChunkID, stored.String)
+		if err != nil {
+			return fmt.Errorf("failed to mark embedding stale: %w", err)
+		}
+	}
+	s.Enqueue(chunk.ChunkID)
+	return nil
+}
+
+// Enqueue adds chunks not already waiting. When the queue is full they are counted as
+// dropped and picked up by the next backfill.
+func (s *embeddingSyncService) Enqueue(chunkIDs ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accepted := 0
+	for _, chunkID := range chunkIDs {
+		if s.pending[chunkID] {
+			continue
+		}
+		select {
+		case s.queue <- chunkID:
+			s.pending[chunkID] = true
+			accepted++
+		default:
+			s.dropped.Add(1)
+		}
+	}
+	return accepted
+}
+
+// Backfill queues the chunks that waited longest for an embedding
+func (s *embeddingSyncService) Backfill(ctx context.Context, limit int) (int, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return 0, err
+	}
+	if limit <= 0 {
+		limit = s.batchSize
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT chunk_id FROM chunks
+		WHERE vector_content_hash IS NULL AND COALESCE(vector_type, 'text') = 'text'
+		  AND contents <> '' AND NOT COALESCE(metadata @> '{"sensitive": true}'::jsonb, false)
+		ORDER BY last_updated
+		LIMIT $1`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query chunks without embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	var chunkIDs []string
+	for rows.Next() {
+		var chunkID string
+		if err := rows.Scan(&chunkID); err != nil {
+			return 0, fmt.Errorf("failed to scan chunk ID: %w", err)
+		}
+		chunkIDs = append(chunkIDs, chunkID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error iterating chunks without embeddings: %w", err)
+	}
+	return s.Enqueue(chunkIDs...), nil
+}
+
+// Coverage counts text chunks by embedding state
+func (s *embeddingSyncService) Coverage(ctx context.Context) (*models.EmbeddingCoverage, error) {
+	start := time.Now()
+	coverage := &models.EmbeddingCoverage{}
+	column := s.store.presentColumn()
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE %[1]s IS NOT NULL AND vector_content_hash IS NOT NULL),
+		       COUNT(*) FILTER (WHERE %[1]s IS NOT NULL AND vector_content_hash IS NULL),
+		       COUNT(*) FILTER (WHERE %[1]s IS NULL)
+		FROM chunks
+		WHERE COALESCE(vector_type, 'text') = 'text' AND contents <> ''
+		  AND NOT COALESCE(metadata @> '{"sensitive": true}'::jsonb, false)`, column)).
+		Scan(&coverage.TotalChunks, &coverage.EmbeddedChunks, &coverage.StaleChunks, &coverage.MissingChunks)
+	s.monitor.RecordQuery("embedding_coverage", time.Since(start), 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count embeddings: %w", err)
+	}
+
+	coverage.Coverage = 1
+	if coverage.TotalChunks > 0 {
+		coverage.Coverage = float64(coverage.EmbeddedChunks) / float64(coverage.TotalChunks)
+	}
+
+	s.mu.Lock()
+	coverage.Queued = len(s.pending)
+	coverage.LastBatchAt = s.lastBatchAt
+	coverage.LastError = s.lastError
+	s.mu.Unlock()
+	coverage.Processed = s.processed.Load()
+	coverage.Failed = s.failed.Load()
+	coverage.Dropped = s.dropped.Load()
+	return coverage, nil
+}
+
+// Start runs the worker in the background
+func (s *embeddingSyncService) Start(ctx context.Context) {
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+// Stop stops the worker and waits for the current batch
+func (s *embeddingSyncService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+// run collects queued chunks into batches and backfills on every interval
+func (s *embeddingSyncService) run(ctx context.Context) {
+	backfill := time.NewTicker(s.interval)
+	defer backfill.Stop()
+	if _, err := s.Backfill(ctx, s.batchSize*10); err != nil {
+		log.Printf("Warning: embedding backfill failed: %v", err)
+	}
+
+	batch := make([]string, 0, s.batchSize)
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunkID := <-s.queue:
+			batch = append(batch, chunkID)
+			if len(batch) < s.batchSize {
+				if flush == nil {
+					flush = time.After(embeddingSyncFlushDelay)
+				}
+				continue
+			}
+		case <-flush:
+		case <-backfill.C:
+			if _, err := s.Backfill(ctx, s.batchSize*10); err != nil {
+				log.Printf("Warning: embedding backfill failed: %v", err)
+			}
+			continue
+		}
+
+		s.processBatch(ctx, batch)
+		batch = batch[:0]
+		flush = nil
+	}
+}
+
+// processBatch embeds the current contents of the chunks in one request
+func (s *embeddingSyncService) processBatch(ctx context.Context, chunkIDs []string) {
+	start := time.Now()
+	s.mu.Lock()
+	for _, chunkID := range chunkIDs {
+		// Writes from here on queue the chunk again
+		delete(s.pending, chunkID)
+	}
+	s.mu.Unlock()
+
+	var chunks []*models.UnifiedChunkRecord
+	var texts []string
+	for _, chunkID := range chunkIDs {
+		chunk, err := s.chunks.GetChunk(ctx, chunkID)
+		if err != nil {
+			// Deleted since it was queued
+			continue
+		}
+		if !embeddable(chunk) {
+			continue
+		}
+		chunks = append(chunks, chunk)
+		texts = append(texts, chunk.Contents)
+	}
+	if len(texts) == 0 {
+		return
+	}
+
+	vectors, err := s.embeddings.GenerateBatchEmbeddings(ctx, texts)
+	if err == nil && len(vectors) != len(texts) {
+		err = fmt.Errorf("got %d embeddings for %d chunks", len(vectors), len(texts))
+	}
+	if err != nil {
+		s.failed.Add(int64(len(texts)))
+		s.recordBatch(err)
+		log.Printf("Warning: failed to re-embed %d chunks: %v", len(texts), err)
+		return
+	}
+
+	for i, chunk := range chunks {
+		if err := s.storeEmbedding(ctx, chunk, vectors[i]); err != nil {
+			s.failed.Add(1)
+			s.recordBatch(err)
+			log.Printf("Warning: failed to store embedding for chunk %s: %v", chunk.ChunkID, err)
+			continue
+		}
+		s.processed.Add(1)
+	}
+	s.recordBatch(nil)
+	s.monitor.RecordQuery("embedding_sync_batch", time.Since(start), len(chunks))
+}
+
+// storeEmbedding writes the vector unless the chunk changed after it was read; that write
+// queued the chunk again
+func (s *embeddingSyncService) storeEmbedding(ctx context.Context, chunk *models.UnifiedChunkRecord, vector []float64) error {
+	prepared, err := s.store.prepare(vector)
+	if err != nil {
+		return err
+	}
+	set, args := s.store.assignments(prepared, 3, true)
+	next := 3 + len(args)
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE chunks SET %s, vector_type = $%d, vector_model = $%d, vector_content_hash = $%d
+		WHERE chunk_id = $1 AND version = $2`, set, next, next+1, next+2),
+		append(append([]interface{}{chunk.ChunkID, chunk.Version}, args...),
+			string(models.VectorTypeText), s.model, contentHash(chunk.Contents))...)
+	if err != nil {
+		return fmt.Errorf("failed to update embedding: %w", err)
+	}
+	return nil
+}
+
+func (s *embeddingSyncService) recordBatch(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.lastBatchAt = &now
+	if err != nil {
+		s.lastError = err.Error()
+	}
+}
+
+// embeddingTrackingChunkService queues chunks for re-embedding when writes change contents
+type embeddingTrackingChunkService struct {
+	UnifiedChunkService
+	embeddingSync EmbeddingSyncService
+}
+
+// NewEmbeddingTrackingChunkService wraps a UnifiedChunkService so that writes changing a
+// chunk's contents queue it for re-embedding. Tracking failures are logged and do not fail
+// the write; the periodic backfill catches up.
+func NewEmbeddingTrackingChunkService(base UnifiedChunkService, embeddingSync EmbeddingSyncService) UnifiedChunkService {
+	return &embeddingTrackingChunkService{
+		UnifiedChunkService: base,
+		embeddingSync:       embeddingSync,
+	}
+}
+
+// CreateChunk creates a chunk and queues its first embedding
+func (s *embeddingTrackingChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.CreateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	s.track(ctx, chunk)
+	return nil
+}
+
+// UpdateChunk updates a chunk and queues it if its contents drifted from its embedding
+func (s *embeddingTrackingChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.UpdateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	s.track(ctx, chunk)
+	return nil
+}
+
+// PatchChunk patches a chunk and queues it if its contents drifted from its embedding
+func (s *embeddingTrackingChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return nil, err
+	}
+	flagChanged := metadataKeyChanged(patch, SensitiveMetadataKey)
+	if patch.Contents != nil || flagChanged {
+		s.track(ctx, chunk)
+	}
+	return chunk, nil
+}
+
+// MergeChunks merges two chunks and queues the target, whose contents usually changed
+func (s *embeddingTrackingChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	result, err := s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if result.Chunk != nil {
+		s.track(ctx, result.Chunk)
+	}
+	return result, nil
+}
+
+// BatchCreateChunks creates chunks and queues their first embeddings
+func (s *embeddingTrackingChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchCreateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		s.track(ctx, &chunks[i])
+	}
+	return nil
+}
+
+// BatchUpdateChunks updates chunks and queues those whose contents drifted
+func (s *embeddingTrackingChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		s.track(ctx, &chunks[i])
+	}
+	return nil
+}
+
+func (s *embeddingTrackingChunkService) track(ctx context.Context, chunk *models.UnifiedChunkRecord) {
+	if err := s.embeddingSync.TrackChange(ctx, chunk); err != nil {
+		log.Printf("Warning: failed to track embedding drift for chunk %s: %v", chunk.ChunkID, err)
+	}
+}
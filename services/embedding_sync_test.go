@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddingSync_Queue(t *testing.T) {
+	sync := NewEmbeddingSyncService(nil, nil, NewTestEmbeddingService(), nil, NewNoOpMonitor(), "", 2, 2, time.Minute).(*embeddingSyncService)
+
+	assert.Equal(t, 2, sync.Enqueue("a", "b", "a"))
+	assert.Equal(t, 0, sync.Enqueue("a"), "queued chunks are not queued twice")
+	assert.Equal(t, 0, sync.Enqueue("c"), "a full queue drops")
+	assert.Equal(t, int64(1), sync.dropped.Load())
+
+	image := string(models.VectorTypeImage)
+	assert.True(t, embeddable(&models.UnifiedChunkRecord{Contents: "text"}))
+	assert.False(t, embeddable(&models.UnifiedChunkRecord{Contents: "  "}))
+	assert.False(t, embeddable(&models.UnifiedChunkRecord{Contents: "photo", VectorType: &image}))
+	assert.False(t, embeddable(&models.UnifiedChunkRecord{Contents: "secret", Metadata: map[string]interface{}{SensitiveMetadataKey: true}}))
+	assert.NotEqual(t, contentHash("a"), contentHash("b"))
+}
+
+func TestEmbeddingSync_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	for _, file := range []string{"../database/multimodal_embeddings_migration.sql", "../database/embedding_sync_migration.sql"} {
+		migration, err := os.ReadFile(file)
+		require.NoError(t, err)
+		_, err = db.Exec(string(migration))
+		require.NoError(t, err)
+	}
+
+	ctx := context.Background()
+	base := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	sync := NewEmbeddingSyncService(db, base, NewTestEmbeddingService(), nil, NewNoOpMonitor(), "", 10, 100, time.Minute).(*embeddingSyncService)
+	chunks := NewEmbeddingTrackingChunkService(base, sync)
+
+	chunk := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "First draft"}
+	require.NoError(t, chunks.CreateChunk(ctx, chunk))
+	defer base.DeleteChunk(ctx, chunk.ChunkID)
+
+	drain := func() []string {
+		var queued []string
+		for len(sync.queue) > 0 {
+			queued = append(queued, <-sync.queue)
+		}
+		return queued
+	}
+	storedHash := func() *string {
+		var hash *string
+		require.NoError(t, db.QueryRow("SELECT vector_content_hash FROM chunks WHERE chunk_id = $1", chunk.ChunkID).Scan(&hash))
+		return hash
+	}
+
+	queued := drain()
+	require.Equal(t, []string{chunk.ChunkID}, queued)
+	sync.processBatch(ctx, queued)
+	require.NotNil(t, storedHash())
+	assert.Equal(t, contentHash("First draft"), *storedHash())
+
+	// Metadata-only writes keep the embedding
+	current, err := base.GetChunk(ctx, chunk.ChunkID)
+	require.NoError(t, err)
+	_, err = chunks.PatchChunk(ctx, chunk.ChunkID, &models.ChunkPatch{ExpectedVersion: current.Version, Metadata: map[string]interface{}{"color": "red"}})
+	require.NoError(t, err)
+	assert.Empty(t, drain())
+
+	// Changed contents mark the embedding stale and queue the chunk
+	contents := "Second draft"
+	_, err = chunks.PatchChunk(ctx, chunk.ChunkID, &models.ChunkPatch{ExpectedVersion: current.Version + 1, Contents: &contents})
+	require.NoError(t, err)
+	assert.Nil(t, storedHash())
+	queued = drain()
+	assert.Equal(t, []string{chunk.ChunkID}, queued)
+
+	coverage, err := sync.Coverage(ctx)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, coverage.StaleChunks, int64(1))
+
+	sync.processBatch(ctx, queued)
+	require.NotNil(t, storedHash())
+	assert.Equal(t, contentHash(contents), *storedHash())
+	assert.Equal(t, int64(2), sync.processed.Load())
+}
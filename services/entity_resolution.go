@@ -0,0 +1,507 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+var (
+	// ErrInvalidEntityMerge is returned for merge requests that name no nodes, repeat the
+	// target as a source or use invalid IDs
+	ErrInvalidEntityMerge = errors.New("invalid entity merge")
+	// ErrGraphNodeNotFound is returned when a node to merge does not exist
+	ErrGraphNodeNotFound = errors.New("graph node not found")
+)
+
+const (
+	defaultEntityMinSimilarity = 0.9
+	defaultEntityCandidates    = 50
+	// maxEmbeddedEntityNames bounds the pairwise comparison of name embeddings; entity
+	// types with more distinct names are matched by normalized name only
+	maxEmbeddedEntityNames = 2000
+	entityEmbeddingBatch   = 100
+)
+
+// EntityResolutionService finds graph nodes that refer to the same entity and merges them
+type EntityResolutionService interface {
+	// FindCandidates groups nodes by normalized entity name and, unless skipped, by the
+	// similarity of their name embeddings, most confident first
+	FindCandidates(ctx context.Context, opts models.EntityResolutionOptions) ([]models.EntityMergeCandidate, error)
+	// MergeNodes moves the edges of the source nodes onto the target, records the sources
+	// in the target's properties and deletes them
+	MergeNodes(ctx context.Context, req *models.EntityMergeRequest) (*models.EntityMergeResult, error)
+}
+
+// entityResolutionService implements EntityResolutionService on PostgreSQL
+type entityResolutionService struct {
+	db         *sql.DB
+	embeddings EmbeddingService
+	monitor    QueryPerformanceMonitor
+}
+
+// NewEntityResolutionService creates a new entity resolution service. Without an
+// embedding service, candidates are matched by normalized name only.
+func NewEntityResolutionService(db *sql.DB, embeddings EmbeddingService, monitor QueryPerformanceMonitor) EntityResolutionService {
+	return &entityResolutionService{db: db, embeddings: embeddings, monitor: monitor}
+}
+
+// entityNode is a graph node considered for resolution
+type entityNode struct {
+	models.EntityCandidateNode
+	createdAt time.Time
+}
+
+// FindCandidates loads the graph nodes and clusters them into merge candidates
+func (s *entityResolutionService) FindCandidates(ctx context.Context, opts models.EntityResolutionOptions) ([]models.EntityMergeCandidate, error) {
+	start := time.Now()
+	if opts.MinSimilarity <= 0 || opts.MinSimilarity > 1 {
+		opts.MinSimilarity = defaultEntityMinSimilarity
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = defaultEntityCandidates
+	}
+
+	nodes, err := s.loadNodes(ctx, opts.EntityType)
+	if err != nil {
+		return nil, err
+	}
+
+	var embed func(ctx context.Context, names []string) ([][]float64, error)
+	if !opts.SkipEmbeddings && s.embeddings != nil {
+		embed = s.embedNames
+	}
+	candidates, err := clusterEntities(ctx, nodes, opts.MinSimilarity, embed)
+	if err != nil {
+		return nil, err
+	}
+	if len(candidates) > opts.Limit {
+		candidates = candidates[:opts.Limit]
+	}
+
+	s.monitor.RecordQuery("find_entity_candidates", time.Since(start), len(candidates))
+	return candidates, nil
+}
+
+// loadNodes reads graph nodes with their edge counts
+func (s *entityResolutionService) loadNodes(ctx context.Context, entityType string) ([]entityNode, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT n.id, n.chunk_id, n.entity_name, n.entity_type, n.created_at,
+			(SELECT COUNT(*) FROM graph_edges e WHERE e.source_node_id = n.id OR e.target_node_id = n.id)
+		FROM graph_nodes n
+		WHERE $1 = '' OR n.entity_type = $1
+		ORDER BY n.created_at, n.id`, entityType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []entityNode
+	for rows.Next() {
+		var node entityNode
+		if err := rows.Scan(&node.ID, &node.ChunkID, &node.EntityName, &node.EntityType, &node.createdAt, &node.Degree); err != nil {
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load graph nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+// embedNames embeds entity names in batches
+func (s *entityResolutionService) embedNames(ctx context.Context, names []string) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(names))
+	for i := 0; i < len(names); i += entityEmbeddingBatch {
+		end := i + entityEmbeddingBatch
+		if end > len(names) {
+			end = len(names)
+		}
+		batch, err := s.embeddings.GenerateBatchEmbeddings(ctx, names[i:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed entity names: %w", err)
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// entityNameGroup is the nodes of one entity type sharing a normalized name
+type entityNameGroup struct {
+	entityType string
+	nodes      []entityNode
+}
+
+// clusterEntities groups nodes by normalized name within each entity type and, when embed
+// is set, joins groups whose names are at least minSimilarity alike
+func clusterEntities(ctx context.Context, nodes []entityNode, minSimilarity float64,
+	embed func(ctx context.Context, names []string) ([][]float64, error)) ([]models.EntityMergeCandidate, error) {
+	var groups []*entityNameGroup
+	byKey := make(map[string]*entityNameGroup)
+	for _, node := range nodes {
+		normalized := models.NormalizeEntityName(node.EntityName)
+		if normalized == "" {
+			continue
+		}
+		key := node.EntityType + "\x00" + normalized
+		group, ok := byKey[key]
+		if !ok {
+			group = &entityNameGroup{entityType: node.EntityType}
+			byKey[key] = group
+			groups = append(groups, group)
+		}
+		group.nodes = append(group.nodes, node)
+	}
+
+	// Union-find over the name groups; linkSimilarity keeps the weakest link that joined
+	// each cluster
+	parent := make([]int, len(groups))
+	linkSimilarity := make([]float64, len(groups))
+	for i := range parent {
+		parent[i] = i
+		linkSimilarity[i] = 1
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	if embed != nil {
+		byType := make(map[string][]int)
+		var types []string
+		for i, group := range groups {
+			if _, ok := byType[group.entityType]; !ok {
+				types = append(types, group.entityType)
+			}
+			byType[group.entityType] = append(byType[group.entityType], i)
+		}
+		for _, entityType := range types {
+			members := byType[entityType]
+			if len(members) < 2 || len(members) > maxEmbeddedEntityNames {
+				continue
+			}
+			names := make([]string, len(members))
+			for i, member := range members {
+				names[i] = groups[member].nodes[0].EntityName
+			}
+			vectors, err := embed(ctx, names)
+			if err != nil {
+				return nil, err
+			}
+			if len(vectors) != len(names) {
+				return nil, fmt.Errorf("failed to embed entity names: got %d embeddings for %d names", len(vectors), len(names))
+			}
+			for i := range members {
+				for j := i + 1; j < len(members); j++ {
+					similarity := vectorCosineSimilarity(vectors[i], vectors[j])
+					if similarity < minSimilarity {
+						continue
+					}
+					a, b := find(members[i]), find(members[j])
+					if a == b {
+						continue
+					}
+					parent[b] = a
+					linkSimilarity[a] = math.Min(similarity, math.Min(linkSimilarity[a], linkSimilarity[b]))
+				}
+			}
+		}
+	}
+
+	clusters := make(map[int][]int)
+	var roots []int
+	for i := range groups {
+		root := find(i)
+		if _, ok := clusters[root]; !ok {
+			roots = append(roots, root)
+		}
+		clusters[root] = append(clusters[root], i)
+	}
+
+	var candidates []models.EntityMergeCandidate
+	for _, root := range roots {
+		members := clusters[root]
+		var nodes []entityNode
+		for _, member := range members {
+			nodes = append(nodes, groups[member].nodes...)
+		}
+		if len(nodes) < 2 {
+			continue
+		}
+		candidates = append(candidates, newEntityMergeCandidate(nodes, len(members) > 1, linkSimilarity[root]))
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Confidence != candidates[j].Confidence {
+			return candidates[i].Confidence > candidates[j].Confidence
+		}
+		return len(candidates[i].Nodes) > len(candidates[j].Nodes)
+	})
+	return candidates, nil
+}
+
+// newEntityMergeCandidate describes a cluster, suggesting the best-connected, oldest node
+// as the target
+func newEntityMergeCandidate(nodes []entityNode, byEmbedding bool, similarity float64) models.EntityMergeCandidate {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].Degree != nodes[j].Degree {
+			return nodes[i].Degree > nodes[j].Degree
+		}
+		return nodes[i].createdAt.Before(nodes[j].createdAt)
+	})
+
+	candidate := models.EntityMergeCandidate{
+		TargetNodeID: nodes[0].ID,
+		EntityType:   nodes[0].EntityType,
+		Confidence:   1,
+		Reason:       models.EntityMatchNormalizedName,
+	}
+	seen := make(map[string]bool)
+	for _, node := range nodes {
+		candidate.Nodes = append(candidate.Nodes, node.EntityCandidateNode)
+		if !seen[node.EntityName] {
+			seen[node.EntityName] = true
+			candidate.Names = append(candidate.Names, node.EntityName)
+		}
+		if !strings.EqualFold(node.EntityName, nodes[0].EntityName) {
+			candidate.Confidence = 0.95
+		}
+	}
+	if byEmbedding {
+		candidate.Confidence = similarity
+		candidate.Reason = models.EntityMatchEmbedding
+	}
+	return candidate
+}
+
+// vectorCosineSimilarity compares two embeddings; mismatched or zero vectors score 0
+func vectorCosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// MergeNodes merges the source nodes into the target in one transaction. Edges between
+// merged nodes are removed rather than kept as self-loops; every other edge keeps its
+// original endpoints in its properties.
+func (s *entityResolutionService) MergeNodes(ctx context.Context, req *models.EntityMergeRequest) (*models.EntityMergeResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	sources, err := validateEntityMerge(req)
+	if err != nil {
+		return nil, err
+	}
+	allIDs := append([]string{req.TargetNodeID}, sources...)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, chunk_id, entity_name, entity_type, COALESCE(properties, '{}'::jsonb)
+		FROM graph_nodes WHERE id = ANY($1::uuid[]) ORDER BY id FOR UPDATE`,
+		pq.Array(allIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock graph nodes: %w", err)
+	}
+	locked := make(map[string]*models.GraphNode, len(allIDs))
+	for rows.Next() {
+		var node models.GraphNode
+		var properties []byte
+		if err := rows.Scan(&node.ID, &node.ChunkID, &node.EntityName, &node.EntityType, &properties); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		if err := json.Unmarshal(properties, &node.Properties); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to decode properties of node %s: %w", node.ID, err)
+		}
+		locked[node.ID] = &node
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock graph nodes: %w", err)
+	}
+	for _, id := range allIDs {
+		if locked[id] == nil {
+			return nil, fmt.Errorf("%w: %s", ErrGraphNodeNotFound, id)
+		}
+	}
+
+	result := &models.EntityMergeResult{
+		TargetNodeID:  req.TargetNodeID,
+		MergedNodeIDs: sources,
+		MergedAt:      time.Now().UTC(),
+	}
+
+	removed, err := tx.ExecContext(ctx, `
+		DELETE FROM graph_edges
+		WHERE source_node_id = ANY($1::uuid[]) AND target_node_id = ANY($1::uuid[])
+			AND (source_node_id = ANY($2::uuid[]) OR target_node_id = ANY($2::uuid[]))`,
+		pq.Array(allIDs), pq.Array(sources))
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove edges between merged nodes: %w", err)
+	}
+	if n, err := removed.RowsAffected(); err == nil {
+		result.RemovedEdges = int(n)
+	}
+
+	// Original endpoints from an earlier merge are kept: existing properties win
+	rewritten, err := tx.ExecContext(ctx, `
+		UPDATE graph_edges SET
+			source_node_id = CASE WHEN source_node_id = ANY($2::uuid[]) THEN $1::uuid ELSE source_node_id END,
+			target_node_id = CASE WHEN target_node_id = ANY($2::uuid[]) THEN $1::uuid ELSE target_node_id END,
+			properties = jsonb_strip_nulls(jsonb_build_object(
+				'`+models.EdgeOriginalSourceProperty+`', CASE WHEN source_node_id = ANY($2::uuid[]) THEN source_node_id::text END,
+				'`+models.EdgeOriginalTargetProperty+`', CASE WHEN target_node_id = ANY($2::uuid[]) THEN target_node_id::text END
+			)) || COALESCE(properties, '{}'::jsonb)
+		WHERE source_node_id = ANY($2::uuid[]) OR target_node_id = ANY($2::uuid[])`,
+		req.TargetNodeID, pq.Array(sources))
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite edges of merged nodes: %w", err)
+	}
+	if n, err := rewritten.RowsAffected(); err == nil {
+		result.RewrittenEdges = int(n)
+	}
+
+	mergeSources := make([]*models.GraphNode, len(sources))
+	for i, id := range sources {
+		mergeSources[i] = locked[id]
+	}
+	properties, err := json.Marshal(mergedEntityProperties(locked[req.TargetNodeID], mergeSources, result.MergedAt))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode merged node properties: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE graph_nodes SET properties = $2::jsonb WHERE id = $1",
+		req.TargetNodeID, string(properties)); err != nil {
+		return nil, fmt.Errorf("failed to update merged node: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM graph_nodes WHERE id = ANY($1::uuid[])", pq.Array(sources)); err != nil {
+		return nil, fmt.Errorf("failed to delete merged nodes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.monitor.RecordQuery("merge_entities", time.Since(start), len(sources))
+	return result, nil
+}
+
+// validateEntityMerge checks a merge request and returns its distinct source IDs
+func validateEntityMerge(req *models.EntityMergeRequest) ([]string, error) {
+	if req == nil || req.TargetNodeID == "" {
+		return nil, fmt.Errorf("%w: target_node_id is required", ErrInvalidEntityMerge)
+	}
+	if _, err := uuid.Parse(req.TargetNodeID); err != nil {
+		return nil, fmt.Errorf("%w: invalid node ID %q", ErrInvalidEntityMerge, req.TargetNodeID)
+	}
+
+	seen := make(map[string]bool, len(req.SourceNodeIDs))
+	var sources []string
+	for _, id := range req.SourceNodeIDs {
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("%w: invalid node ID %q", ErrInvalidEntityMerge, id)
+		}
+		if id == req.TargetNodeID {
+			return nil, fmt.Errorf("%w: node %s cannot be merged into itself", ErrInvalidEntityMerge, id)
+		}
+		if !seen[id] {
+			seen[id] = true
+			sources = append(sources, id)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("%w: source_node_ids is required", ErrInvalidEntityMerge)
+	}
+	return sources, nil
+}
+
+// mergedEntityProperties adds the sources to the target's merge provenance and aliases.
+// Provenance and aliases the sources gathered in earlier merges carry over.
+func mergedEntityProperties(target *models.GraphNode, sources []*models.GraphNode, mergedAt time.Time) map[string]interface{} {
+	properties := make(map[string]interface{}, len(target.Properties)+2)
+	for key, value := range target.Properties {
+		properties[key] = value
+	}
+
+	provenance := propertyList(target.Properties[models.EntityMergedFromProperty])
+	aliases := propertyList(target.Properties[models.EntityAliasesProperty])
+	known := map[string]bool{target.EntityName: true}
+	for _, alias := range aliases {
+		if name, ok := alias.(string); ok {
+			known[name] = true
+		}
+	}
+	addAlias := func(name interface{}) {
+		if name, ok := name.(string); ok && name != "" && !known[name] {
+			known[name] = true
+			aliases = append(aliases, name)
+		}
+	}
+
+	for _, source := range sources {
+		sourceProperties := make(map[string]interface{}, len(source.Properties))
+		for key, value := range source.Properties {
+			if key != models.EntityMergedFromProperty && key != models.EntityAliasesProperty {
+				sourceProperties[key] = value
+			}
+		}
+		provenance = append(provenance, map[string]interface{}{
+			"node_id":     source.ID,
+			"entity_name": source.EntityName,
+			"entity_type": source.EntityType,
+			"chunk_id":    source.ChunkID,
+			"properties":  sourceProperties,
+			"merged_at":   mergedAt,
+		})
+		provenance = append(provenance, propertyList(source.Properties[models.EntityMergedFromProperty])...)
+
+		addAlias(source.EntityName)
+		for _, alias := range propertyList(source.Properties[models.EntityAliasesProperty]) {
+			addAlias(alias)
+		}
+	}
+
+	properties[models.EntityMergedFromProperty] = provenance
+	if len(aliases) > 0 {
+		properties[models.EntityAliasesProperty] = aliases
+	}
+	return properties
+}
+
+// propertyList reads a JSON array property, treating anything else as empty
+func propertyList(value interface{}) []interface{} {
+	list, _ := value.([]interface{})
+	return append([]interface{}(nil), list...)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func TestNormalizeEntityName(t *testing.T) {
+	cases := map[string]string{
+		"The Acme Corp.":       "acme corp",
+		"  acme   corp ":       "acme corp",
+		"O'Brien":              "obrien",
+		"New-York":             "new york",
+		"Théâtre du Châtelet":  "théâtre du châtelet",
+		"the":                  "the",
+		"...":                  "",
+		"Theodore Roosevelt":   "theodore roosevelt",
+		"台北 101":               "台北 101",
+		"GPT_4 / Turbo Model!": "gpt 4 turbo model",
+	}
+	for name, want := range cases {
+		assert.Equal(t, want, models.NormalizeEntityName(name), name)
+	}
+}
+
+func entityTestNode(id, name, entityType string, degree int, age time.Duration) entityNode {
+	return entityNode{
+		EntityCandidateNode: models.EntityCandidateNode{ID: id, EntityName: name, EntityType: entityType, Degree: degree},
+		createdAt:           time.Now().Add(-age),
+	}
+}
+
+func TestClusterEntities_ByNormalizedName(t *testing.T) {
+	candidates, err := clusterEntities(context.Background(), []entityNode{
+		entityTestNode("1", "Acme Corp", "organization", 1, 3*time.Hour),
+		entityTestNode("2", "acme corp", "organization", 4, time.Hour),
+		entityTestNode("3", "The Acme Corp.", "organization", 4, 2*time.Hour),
+		entityTestNode("4", "Acme Corp", "product", 1, time.Hour),
+		entityTestNode("5", "Paris", "location", 2, time.Hour),
+		entityTestNode("6", "Paris", "location", 0, time.Hour),
+	}, 0.9, nil)
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+
+	// Identical names rank above names that only match once normalized
+	assert.Equal(t, 1.0, candidates[0].Confidence)
+	assert.Equal(t, "5", candidates[0].TargetNodeID)
+	assert.Len(t, candidates[0].Nodes, 2)
+
+	// Types never mix; the best-connected node is the target, the older one on a tie
+	assert.Equal(t, 0.95, candidates[1].Confidence)
+	assert.Equal(t, models.EntityMatchNormalizedName, candidates[1].Reason)
+	assert.Equal(t, "3", candidates[1].TargetNodeID)
+	assert.Len(t, candidates[1].Nodes, 3)
+	assert.ElementsMatch(t, []string{"Acme Corp", "acme corp", "The Acme Corp."}, candidates[1].Names)
+}
+
+func TestClusterEntities_ByEmbedding(t *testing.T) {
+	vectors := map[string][]float64{
+		"IBM":                             {1, 0, 0},
+		"International Business Machines": {0.95, 0.31, 0},
+		"Big Blue":                        {0.8, 0.6, 0},
+		"Apple":                           {0, 0, 1},
+	}
+	embed := func(ctx context.Context, names []string) ([][]float64, error) {
+		out := make([][]float64, len(names))
+		for i, name := range names {
+			out[i] = vectors[name]
+		}
+		return out, nil
+	}
+
+	candidates, err := clusterEntities(context.Background(), []entityNode{
+		entityTestNode("1", "IBM", "organization", 5, time.Hour),
+		entityTestNode("2", "International Business Machines", "organization", 1, time.Hour),
+		entityTestNode("3", "Big Blue", "organization", 1, time.Hour),
+		entityTestNode("4", "Apple", "organization", 3, time.Hour),
+		entityTestNode("5", "Big Blue", "person", 1, time.Hour),
+	}, 0.9, embed)
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+
+	candidate := candidates[0]
+	assert.Equal(t, models.EntityMatchEmbedding, candidate.Reason)
+	assert.Equal(t, "1", candidate.TargetNodeID)
+	assert.Len(t, candidate.Nodes, 3, "Big Blue joins through its similarity to International Business Machines")
+	assert.GreaterOrEqual(t, candidate.Confidence, 0.9)
+	assert.Less(t, candidate.Confidence, 1.0)
+}
+
+func TestMergedEntityProperties_KeepsProvenance(t *testing.T) {
+	mergedAt := time.Now().UTC()
+	target := &models.GraphNode{ID: "t", EntityName: "IBM", Properties: map[string]interface{}{
+		"pagerank":                   0.4,
+		models.EntityAliasesProperty: []interface{}{"I.B.M."},
+	}}
+	earlier := map[string]interface{}{"node_id": "old", "entity_name": "Big Blue"}
+	source := &models.GraphNode{ID: "s", ChunkID: "c", EntityName: "International Business Machines", EntityType: "organization",
+		Properties: map[string]interface{}{
+			"confidence":                    0.8,
+			models.EntityAliasesProperty:    []interface{}{"Big Blue", "IBM"},
+			models.EntityMergedFromProperty: []interface{}{earlier},
+		}}
+
+	properties := mergedEntityProperties(target, []*models.GraphNode{source}, mergedAt)
+	assert.Equal(t, 0.4, properties["pagerank"])
+	assert.Equal(t, []interface{}{"I.B.M.", "International Business Machines", "Big Blue"}, properties[models.EntityAliasesProperty])
+
+	provenance := properties[models.EntityMergedFromProperty].([]interface{})
+	require.Len(t, provenance, 2)
+	entry := provenance[0].(map[string]interface{})
+	assert.Equal(t, "s", entry["node_id"])
+	assert.Equal(t, "c", entry["chunk_id"])
+	assert.Equal(t, map[string]interface{}{"confidence": 0.8}, entry["properties"])
+	assert.Equal(t, earlier, provenance[1])
+
+	// The target's own properties are not modified
+	assert.NotContains(t, target.Properties, models.EntityMergedFromProperty)
+}
+
+func TestEntityResolutionService_MergeValidation(t *testing.T) {
+	service := NewEntityResolutionService(nil, nil, NewNoOpMonitor())
+	target := "9f0d5a8e-1c2b-4e3f-8a7b-6c5d4e3f2a10"
+
+	_, err := service.MergeNodes(contextWithRole(models.RoleReader), &models.EntityMergeRequest{TargetNodeID: target})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	ctx := contextWithRole(models.RoleEditor)
+	invalid := map[string]*models.EntityMergeRequest{
+		"no request":     nil,
+		"no sources":     {TargetNodeID: target},
+		"self merge":     {TargetNodeID: target, SourceNodeIDs: []string{target}},
+		"invalid source": {TargetNodeID: target, SourceNodeIDs: []string{"node-1"}},
+		"invalid target": {TargetNodeID: "node-1", SourceNodeIDs: []string{target}},
+	}
+	for name, req := range invalid {
+		_, err := service.MergeNodes(ctx, req)
+		assert.ErrorIs(t, err, ErrInvalidEntityMerge, name)
+	}
+}
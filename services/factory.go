@@ -2,10 +2,17 @@ package services
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"semantic-text-processor/clients"
 	"semantic-text-processor/config"
 	"semantic-text-processor/database"
+	"semantic-text-processor/models"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // ServiceContainer holds all service instances
@@ -18,9 +25,56 @@ type ServiceContainer struct {
 	TemplateService    TemplateService
 	TagService         TagService
 	UnifiedChunkService UnifiedChunkService
+	BacklinkService    BacklinkService
+	VectorIndexManager VectorIndexManager
+	StorageService     StorageService
+	DedupStorage       *DedupStorage
+	SearchCache        SearchCacheService
+	OptimizedSearch    *OptimizedSearchService
+	QuerySuggestions   QuerySuggestionService
+	PIICompliance      PIIComplianceService
+	RAGService         *RAGService
+	Summarization      SummarizationService
+	SnapshotService    SnapshotService
+	GraphAnalytics     GraphAnalyticsService
+	ChunkHierarchy     ChunkHierarchyService
+	GraphExport        GraphExportService
+	GraphEvidence      GraphEvidenceService
+	GraphTemporal      GraphTemporalService
+	GraphConflicts     *ConflictDetector
+	EntityResolution   EntityResolutionService
+	ChangeFeed         ChangeFeedService
+	LiveOutline        LiveOutlineService
+	ChunkSync          ChunkSyncService
+	BulkTags           BulkTagService
+	OutlineExchange    OutlineExchangeService
+	NoteImport         NoteImportService
+	PageACLs           PageACLService
+	ChunkLocks         ChunkLockService
+	Capture            CaptureService
+	EmailIngest        EmailIngestService
+	Journal            JournalService
+	Tasks              TaskService
+	ChunkHistory       ChunkHistoryService
+	PageRender         PageRenderService
+	Publishing         PublishingService
+	GraphQL            GraphQLService
+	Stats              StatsService
+	EmbeddingSync      EmbeddingSyncService
+	EmbeddingStore     *EmbeddingStore
+	EmbeddingBatch     EmbeddingBatchService
+	GraphSync          GraphSyncService
+	HotData            *HotDataTracker
+	Retention          RetentionService
+	Maintenance        *MaintenanceDaemon
+	APITokens          APITokenService
+	ImageSimilarity    *ImageSimilaritySearch
+	SlideRecommendation *SlideImageRecommendationService
 
 	// Database
 	PostgresService *database.PostgresService
+	ReplicaRouter   *database.ReplicaRouter
+	DBHealth        DBHealthService
 
 	// Clients (deprecated - use PostgresService)
 	SupabaseClient SupabaseClient
@@ -28,8 +82,15 @@ type ServiceContainer struct {
 	// Performance and monitoring
 	CacheService   CacheService
 	MetricsService MetricsService
+	QueryMonitor   QueryPerformanceMonitor
 	Logger         Logger
 	HealthService  HealthService
+	Probes         *ProbeService
+
+	lifecycle *Lifecycle
+	writePool *pgxpool.Pool // the pgx pool opened for DB_WRITE_DSN, if any
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // ServiceFactory creates and configures all services
@@ -55,10 +116,24 @@ func (f *ServiceFactory) CreateServices() (*ServiceContainer, error) {
 	var metricsService MetricsService
 	
 	if f.config.Cache.Enabled {
-		cacheService = NewInMemoryCache(
+		codec, err := CacheCodecByName(f.config.Cache.Codec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+		inMemoryCache := NewInMemoryCache(
 			f.config.Cache.MaxSize,
 			f.config.Cache.CleanupInterval,
 		)
+		inMemoryCache.SetCodec(codec)
+		if err := inMemoryCache.SetEvictionPolicy(EvictionPolicy(f.config.Cache.EvictionPolicy)); err != nil {
+			return nil, fmt.Errorf("failed to create cache: %w", err)
+		}
+		inMemoryCache.SetMaxBytes(f.config.Cache.MaxBytes)
+		// Jittered TTLs keep entries written together from expiring together
+		if f.config.Cache.TTLJitter > 0 {
+			inMemoryCache.SetTTLPolicy(NewAdaptiveTTLPolicy(nil, f.config.Cache))
+		}
+		cacheService = inMemoryCache
 	}
 	
 	if f.config.Performance.MetricsEnabled {
@@ -78,6 +153,10 @@ func (f *ServiceFactory) CreateServices() (*ServiceContainer, error) {
 		SSLMode:  f.config.Database.SSLMode,
 		MaxConns: int32(f.config.Database.MaxConns),
 		MinConns: int32(f.config.Database.MinConns),
+
+		MaxConnLife: f.config.Database.ConnMaxLifetime,
+
+		StatementCacheCapacity: f.config.Database.StatementCacheCapacity,
 	}
 
 	postgresService, err := database.NewPostgresService(pgConfig)
@@ -87,6 +166,12 @@ func (f *ServiceFactory) CreateServices() (*ServiceContainer, error) {
 
 	// Create Supabase client (deprecated)
 	supabaseClient := clients.NewSupabaseClient(&f.config.Supabase)
+	if weighted, ok := supabaseClient.(interface{ SetEdgeDecay(models.EdgeDecay) }); ok {
+		weighted.SetEdgeDecay(models.EdgeDecay{
+			HalfLife: f.config.Graph.EdgeDecayHalfLife,
+			Floor:    f.config.Graph.EdgeDecayFloor,
+		})
+	}
 	
 	// Wrap with caching if enabled
 	var wrappedSupabaseClient SupabaseClient = supabaseClient
@@ -108,17 +193,406 @@ func (f *ServiceFactory) CreateServices() (*ServiceContainer, error) {
 	// Create core services with dependencies
 	textProcessor := NewTextProcessor(llmService, embeddingService)
 	searchService := NewSearchService(wrappedSupabaseClient, embeddingService)
-	templateService := NewTemplateService(wrappedSupabaseClient)
 	tagService := NewTagService(wrappedSupabaseClient)
 
 	// Create unified chunk service with PostgreSQL
-	// Use no-op monitor for now
+	// Track query performance and raise alerts when monitoring is enabled
 	monitor := NewNoOpMonitor()
-	stdlibDB, err := postgresService.StdlibDB()
+	if f.config.Performance.MonitoringEnabled {
+		performanceMonitor := NewInMemoryPerformanceMonitor(f.config.Performance.SlowQueryThreshold, 100)
+		alertConfig := DefaultAlertConfig()
+		alertConfig.SlowQueryThreshold = f.config.Performance.SlowQueryThreshold
+		performanceMonitor.SetAlertThresholds(alertConfig)
+		monitor = performanceMonitor
+	}
+	var stdlibDB *sql.DB
+	if f.config.Database.WriteDSN != "" {
+		stdlibDB, err = database.OpenStdlibDB(f.config.Database.WriteDSN, f.config.Database.StatementCacheCapacity)
+	} else {
+		stdlibDB, err = postgresService.StdlibDB()
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get stdlib DB: %w", err)
 	}
-	unifiedChunkService := NewUnifiedChunkService(stdlibDB, cacheService, monitor)
+	poolConfig := database.SQLPoolConfig{
+		MaxOpenConns:    f.config.Database.MaxOpenConns,
+		MaxIdleConns:    f.config.Database.MaxIdleConns,
+		ConnMaxLifetime: f.config.Database.ConnMaxLifetime,
+	}
+	database.ConfigurePool(stdlibDB, poolConfig)
+
+	// Query template instances by slot value through the slot value index when the
+	// template tables share this database
+	templateService := NewAuthorizingTemplateService(NewTemplateServiceWithIndex(
+		wrappedSupabaseClient, NewPostgresTemplateInstanceIndex(stdlibDB, monitor),
+	))
+
+	// Explain slow statements on the primary, which has the same data and indexes as replicas
+	if performanceMonitor, ok := monitor.(*InMemoryPerformanceMonitor); ok && f.config.Performance.ExplainSlowQueries {
+		performanceMonitor.SetQueryPlanCapturer(NewQueryPlanCapturer(
+			stdlibDB, f.config.Performance.ExplainTimeout, f.config.Performance.ExplainCooldown,
+		))
+	}
+
+	// Route query-heavy reads to replicas when read DSNs are configured
+	replicaDBs := make([]*sql.DB, 0, len(f.config.Database.ReadDSNs))
+	for _, dsn := range f.config.Database.ReadDSNs {
+		replicaDB, err := database.OpenStdlibDB(dsn, f.config.Database.StatementCacheCapacity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open read replica: %w", err)
+		}
+		database.ConfigurePool(replicaDB, poolConfig)
+		replicaDBs = append(replicaDBs, replicaDB)
+	}
+	replicaRouter := database.NewReplicaRouter(stdlibDB, replicaDBs, database.ReplicaConfig{
+		MaxLag:           f.config.Database.ReplicaMaxLag,
+		LagCheckInterval: f.config.Database.ReplicaLagCheckInterval,
+	})
+
+	// Background workers are registered here and run from ServiceContainer.Start, so
+	// building the services does not start goroutines
+	lifecycle := NewLifecycle()
+	lifecycle.Register("replica_lag_checks", ServiceFuncs{
+		StartFunc: func(ctx context.Context) error {
+			replicaRouter.Start(ctx)
+			return nil
+		},
+	})
+
+	var unifiedChunkService UnifiedChunkService
+	var writePool *pgxpool.Pool
+	if f.config.Database.UnifiedChunkDriver == "pgx" {
+		// The native pool must reach the same primary as the database/sql handle
+		pool := postgresService.Pool()
+		if f.config.Database.WriteDSN != "" {
+			if writePool, err = database.OpenPool(context.Background(), f.config.Database.WriteDSN, f.config.Database.StatementCacheCapacity); err != nil {
+				return nil, fmt.Errorf("failed to open pgx pool: %w", err)
+			}
+			pool = writePool
+		}
+		unifiedChunkService = NewPgxUnifiedChunkService(pool, replicaRouter, cacheService, monitor)
+	} else {
+		unifiedChunkService = NewReplicaAwareUnifiedChunkService(replicaRouter, cacheService, monitor)
+	}
+
+	// Cache search results in PostgreSQL, serving stale entries while they refresh
+	var searchCache SearchCacheService = &NoOpSearchCacheService{}
+	if f.config.SearchCache.Enabled {
+		searchCacheConfig := DefaultSearchCacheConfig()
+		searchCacheConfig.DefaultTTL = f.config.SearchCache.DefaultTTL
+		searchCacheConfig.StaleWhileRevalidate = f.config.SearchCache.StaleWhileRevalidate
+		searchCacheConfig.MaxCacheEntries = f.config.SearchCache.MaxEntries
+		searchCacheConfig.CleanupInterval = f.config.SearchCache.CleanupInterval
+		searchCache = NewDatabaseSearchCache(stdlibDB, searchCacheConfig, monitor)
+		unifiedChunkService = NewSearchCacheEnhancedUnifiedChunkService(unifiedChunkService, searchCache, stdlibDB, monitor)
+	}
+
+	// Encrypt the contents of chunks flagged sensitive before they are stored or cached
+	var contentCipher *ContentCipher
+	if f.config.Encryption.Enabled {
+		keyProvider, err := NewContentKeyProvider(f.config.Encryption)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create content key provider: %w", err)
+		}
+		contentCipher = NewContentCipher(keyProvider)
+		unifiedChunkService = NewEncryptingChunkService(unifiedChunkService, contentCipher)
+	}
+
+	// Detect personal data in chunk contents and redact, flag or reject it per workspace
+	var piiCompliance PIIComplianceService
+	if f.config.PII.Enabled {
+		piiScanner, err := NewPIIScanner(f.config.PII)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pii scanner: %w", err)
+		}
+		unifiedChunkService = NewPIIScanningChunkService(unifiedChunkService, piiScanner)
+		piiCompliance = NewPIIComplianceService(stdlibDB, monitor)
+	}
+
+	// Keep the chunk reference graph in sync with chunk writes
+	backlinkService := NewBacklinkService(stdlibDB, cacheService, monitor)
+	unifiedChunkService = NewBacklinkTrackingChunkService(unifiedChunkService, backlinkService)
+
+	// Manage pgvector indexes with configured build and search parameters
+	vectorIndexManager := NewVectorIndexManager(stdlibDB, &f.config.VectorIndex, monitor)
+
+	// Store text embeddings as float32, float16 or int8 vectors; match_chunks only
+	// searches float32 ones
+	embeddingStore := NewEmbeddingStore(stdlibDB, &f.config.Embedding, vectorIndexManager, monitor)
+	if embeddingStore.Quantized() {
+		searchService = NewSearchServiceWithSimilarity(wrappedSupabaseClient, embeddingService, embeddingStore)
+	}
+
+	// Re-embed chunks in the background when their contents change
+	var embeddingSync EmbeddingSyncService
+	if f.config.Embedding.SyncEnabled {
+		embeddingSync = NewEmbeddingSyncService(
+			stdlibDB, unifiedChunkService, embeddingService, embeddingStore, monitor,
+			f.config.Embedding.Model,
+			f.config.Embedding.SyncBatchSize,
+			f.config.Embedding.SyncQueueSize,
+			f.config.Embedding.SyncInterval,
+		)
+		unifiedChunkService = NewEmbeddingTrackingChunkService(unifiedChunkService, embeddingSync)
+		lifecycle.Register("embedding_sync", WorkerService(embeddingSync), "replica_lag_checks")
+	}
+
+	// Re-extract the graph nodes of chunks whose contents change and drop those of deleted
+	// chunks, so the knowledge graph follows the text
+	var graphSync GraphSyncService
+	if f.config.Graph.SyncEnabled {
+		graphSync = NewGraphSyncService(
+			stdlibDB, wrappedSupabaseClient, unifiedChunkService, textProcessor, monitor,
+			f.config.Graph.SyncQueueSize,
+		)
+		unifiedChunkService = NewGraphTrackingChunkService(unifiedChunkService, graphSync)
+		lifecycle.Register("graph_sync", WorkerService(graphSync), "replica_lag_checks")
+	}
+
+	// Count hot reads and, on startup, replay the hottest ones so their results are cached
+	// before traffic arrives. Warming reads below the tracker so it does not count itself.
+	var hotData *HotDataTracker
+	if f.config.Cache.WarmingEnabled {
+		hotData = NewHotDataTracker(stdlibDB, monitor, f.config.Cache.HotDataFlushInterval)
+		warmer := NewCacheWarmer(
+			unifiedChunkService, hotData,
+			f.config.Cache.WarmLimit,
+			f.config.Cache.WarmConcurrency,
+			f.config.Cache.WarmTimeout,
+			logger,
+		)
+		unifiedChunkService = NewAccessTrackingChunkService(unifiedChunkService, hotData)
+		// Stopping flushes the read counts of this run for the next startup's cache warming
+		lifecycle.Register("hot_data", WorkerService(hotData))
+		lifecycle.Register("cache_warming", ServiceFuncs{
+			StartFunc: func(ctx context.Context) error {
+				warmer.WarmAsync(ctx)
+				return nil
+			},
+		}, "hot_data", "replica_lag_checks")
+
+		// Keep hot chunks and tag listings cached longer and rarely read ones shorter
+		if inMemoryCache, ok := cacheService.(*InMemoryCache); ok && f.config.Cache.AdaptiveTTL {
+			ttlPolicy := NewAdaptiveTTLPolicy(hotData, f.config.Cache)
+			inMemoryCache.SetTTLPolicy(ttlPolicy)
+			lifecycle.Register("adaptive_ttl", WorkerService(ttlPolicy), "replica_lag_checks")
+		}
+	}
+
+	// Restrict pages with ACLs to their owner and the users and groups they are shared
+	// with. Checks run above the caches, so cached chunks and searches are filtered per caller.
+	var pageACLs PageACLService
+	if f.config.Auth.PageACLsEnabled {
+		pageACLs = NewPageACLService(stdlibDB, unifiedChunkService, monitor)
+		unifiedChunkService = NewACLEnforcingChunkService(unifiedChunkService, pageACLs)
+	}
+
+	// Keep editors from restructuring subtrees another editor has locked. Lock status is
+	// attached above the caches, so cached chunks are not served with stale locks.
+	var chunkLocks ChunkLockService
+	if f.config.Locks.Enabled {
+		chunkLocks = NewChunkLockService(stdlibDB, unifiedChunkService, f.config.Locks, monitor)
+		unifiedChunkService = NewLockEnforcingChunkService(unifiedChunkService, chunkLocks)
+	}
+
+	// Refuse writes from callers whose role only allows reading. Admin-only operations
+	// such as repairs and rebuilds check the caller's role themselves.
+	unifiedChunkService = NewAuthorizingChunkService(unifiedChunkService)
+
+	// Index the TODO/DONE tasks written in chunks and post reminders when they fall due.
+	// Recurring tasks are rescheduled through the stack below, as their writer.
+	var tasks TaskService
+	if f.config.Tasks.Enabled {
+		tasks, err = NewTaskService(stdlibDB, unifiedChunkService, pageACLs, f.config.Tasks, monitor)
+		if err != nil {
+			return nil, err
+		}
+		unifiedChunkService = NewTaskTrackingChunkService(unifiedChunkService, tasks)
+		lifecycle.Register("task_reminders", WorkerService(tasks))
+	}
+	apiTokens := NewAPITokenService(stdlibDB, f.config.Auth, monitor)
+
+	// Read chunks, pages and search results as they were at a past time
+	chunkHistory := NewChunkHistoryService(stdlibDB, pageACLs, contentCipher, monitor)
+
+	// Render pages with their transclusions for read-only publishing
+	pageRender := NewPageRenderService(stdlibDB, unifiedChunkService, cacheService, &f.config.Render, monitor)
+
+	// Serve published pages without authentication; needs a signing secret
+	var publishing PublishingService
+	if f.config.Publish.SigningSecret != "" {
+		publishing = NewPublishingService(stdlibDB, unifiedChunkService, pageACLs, pageRender, f.config.Publish, monitor)
+	}
+
+	// Back up and restore the knowledge base as JSONL archives
+	snapshotService := NewSnapshotService(stdlibDB, monitor)
+
+	// Repair the hierarchy closure table after writes made outside the service
+	chunkHierarchy := NewChunkHierarchyService(stdlibDB, monitor)
+
+	// Score knowledge graph entities by PageRank, betweenness and community
+	graphAnalytics := NewGraphAnalyticsService(stdlibDB, monitor)
+
+	// Export subgraphs as GraphML, GEXF or Cytoscape JSON for external visualization
+	graphExport := NewGraphExportService(stdlibDB, monitor)
+
+	// Show the text behind each relationship, from the spans recorded at extraction
+	graphEvidence := NewGraphEvidenceService(stdlibDB, pageACLs, monitor)
+
+	// Supersede relationships without deleting them and flag entities holding an exclusive
+	// relationship toward two targets at once
+	graphTemporal := NewGraphTemporalService(stdlibDB, monitor)
+	graphConflicts := NewConflictDetector(stdlibDB, f.config.Graph.ExclusiveRelationships, monitor)
+
+	// Propose and merge graph nodes that name the same entity
+	entityResolution := NewEntityResolutionService(stdlibDB, embeddingService, monitor)
+
+	// Stream chunk changes from LISTEN/NOTIFY and keep this instance's caches in sync with
+	// writes made elsewhere
+	var changeFeed ChangeFeedService
+	if f.config.ChangeFeed.Enabled {
+		listenDSN := f.config.Database.WriteDSN
+		if listenDSN == "" {
+			listenDSN = pgConfig.BuildDirectConnectionString()
+		}
+		changeFeed = NewChangeFeedService(
+			database.NewNotificationListener(listenDSN, f.config.ChangeFeed.Channel),
+			f.config.ChangeFeed.HistorySize,
+		)
+		lifecycle.Register("change_feed", WorkerService(changeFeed))
+
+		invalidator := NewChangeFeedCacheInvalidator(cacheService, searchCache, time.Second)
+		lifecycle.Register("change_feed_invalidation", ServiceFuncs{
+			StartFunc: func(ctx context.Context) error {
+				go invalidator.Run(ctx, changeFeed.Subscribe(models.ChangeFeedFilter{}, f.config.ChangeFeed.SubscriberBuffer))
+				return nil
+			},
+		}, "change_feed")
+	}
+
+	// Live outline sessions broadcast their own operations and, with the change feed,
+	// changes made elsewhere
+	liveOutline := NewLiveOutlineService(unifiedChunkService, f.config.ChangeFeed.SubscriberBuffer)
+	if changeFeed != nil {
+		lifecycle.Register("live_outline", ServiceFuncs{
+			StartFunc: func(ctx context.Context) error {
+				go liveOutline.Run(ctx, changeFeed.Subscribe(models.ChangeFeedFilter{}, f.config.ChangeFeed.SubscriberBuffer))
+				return nil
+			},
+		}, "change_feed")
+	}
+
+	// Offline clients sync through the chunk service so hierarchy and caches stay consistent
+	chunkSync := NewChunkSyncService(stdlibDB, unifiedChunkService, monitor)
+
+	// Bulk tagging selects through the full stack, so searches match the search API
+	bulkTags := NewBulkTagService(unifiedChunkService, monitor)
+
+	// File quick captures on per-user inbox pages, splitting and tagging them in the
+	// background. Without an LLM key captures are split at paragraphs.
+	var captureLLM LLMService
+	if f.config.LLM.APIKey != "" {
+		captureLLM = llmService
+	}
+	capture := NewCaptureService(stdlibDB, unifiedChunkService, captureLLM, pageACLs, f.config.Capture, monitor)
+	lifecycle.Register("capture", WorkerService(capture))
+
+	// One journal page per caller and day, created when first asked for
+	journal, err := NewJournalService(stdlibDB, unifiedChunkService, pageACLs, f.config.Journal, monitor)
+	if err != nil {
+		return nil, err
+	}
+
+	// Move pages to and from other outliners as OPML
+	outlineExchange := NewOutlineExchangeService(stdlibDB, unifiedChunkService, monitor)
+
+	// Embed chunks and texts on request in token-sized provider batches, accounting for
+	// the tokens each workspace uses
+	embeddingBatch := NewEmbeddingBatchService(stdlibDB, unifiedChunkService, embeddingService, wrappedSupabaseClient, &f.config.Embedding, monitor)
+
+	// Retention rules archive or trash stale chunks and purge old trash; the maintenance
+	// daemon applies them on a schedule
+	retention := NewRetentionService(stdlibDB, unifiedChunkService, cacheService, searchCache, monitor)
+
+	// Dashboard statistics, served from a daily snapshot the maintenance daemon takes
+	stats := NewStatsService(stdlibDB, pageACLs, monitor)
+
+	// Object storage for media and exports is optional; only Supabase and S3 providers have one
+	var storageService StorageService
+	switch models.StorageType(f.config.Storage.Provider) {
+	case models.StorageTypeSupabase, models.StorageTypeS3:
+		storageService, err = NewStorageServiceFromConfig(&f.config.Storage)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create storage service: %w", err)
+		}
+	}
+	// Store identical attachments once, keyed by content hash
+	var dedupStorage *DedupStorage
+	if f.config.Storage.Dedup && storageService != nil {
+		dedupStorage = NewDedupStorage(stdlibDB, storageService, &f.config.Storage, monitor)
+		storageService = dedupStorage
+	}
+
+	var maintenance *MaintenanceDaemon
+	if f.config.Maintenance.Enabled {
+		maintenance = NewMaintenanceDaemon(f.config.Maintenance.Interval, monitor)
+		maintenance.Register("retention", retention.RunAll)
+		maintenance.Register("chunk_stats_snapshot", stats.SnapshotIfDue)
+		// Reweight graph edges by how often and how recently their relationships were seen
+		edgeWeighter := NewGraphEdgeWeighter(stdlibDB, monitor)
+		maintenance.Register("graph_edge_weights", func(ctx context.Context) error {
+			_, err := edgeWeighter.Refresh(ctx)
+			return err
+		})
+		if dedupStorage != nil {
+			// Delete attachment blobs no object has referenced for the grace period
+			maintenance.Register("attachment_gc", func(ctx context.Context) error {
+				_, err := dedupStorage.CollectGarbage(ctx, false)
+				return err
+			})
+		}
+		maintenanceDependencies := []string{"replica_lag_checks"}
+		if graphSync != nil {
+			// Remove graph nodes of chunks deleted outside the service
+			maintenance.Register("graph_orphans", func(ctx context.Context) error {
+				_, err := graphSync.CleanupOrphans(ctx)
+				return err
+			})
+			maintenanceDependencies = append(maintenanceDependencies, "graph_sync")
+		}
+		// Let a maintenance run in progress stop before the workers it may enqueue for
+		lifecycle.Register("maintenance", WorkerService(maintenance), maintenanceDependencies...)
+	}
+
+	// Image similarity uses perceptual hashes always and CLIP vectors when an endpoint is configured
+	var imageEmbeddingService ImageEmbeddingService
+	if f.config.ImageSimilarity.CLIPEndpoint != "" {
+		imageEmbeddingService = NewCLIPEmbeddingService(f.config.ImageSimilarity.CLIPEndpoint)
+	}
+	imageSimilarity := NewImageSimilaritySearch(imageEmbeddingService, unifiedChunkService, nil, cacheService)
+	imageSimilarity.EnableCache(cacheService != nil)
+	imageSimilarity.SetIndexStore(NewPostgresImageIndexStore(stdlibDB))
+	imageSimilarity.SetTuning(imageSimilarityTuning(&f.config.ImageSimilarity))
+
+	// Slide outlines rank chunks by topic or page and suggest images through multimodal search
+	multimodalSearch := NewMultimodalSearchService(unifiedChunkService, imageEmbeddingService, nil, searchCache, monitor)
+	slideConfig := DefaultSlideRecommendationConfig()
+	slideConfig.EnableCaching = cacheService != nil
+	slideRecommendation := NewSlideImageRecommendationService(multimodalSearch, nil, cacheService, slideConfig)
+	slideRecommendation.SetChunkService(unifiedChunkService)
+
+	// Import Notion and Logseq exports, uploading their embedded files when storage exists
+	noteImport := NewNoteImportService(stdlibDB, unifiedChunkService, templateService, storageService, monitor)
+
+	// File inbound emails on a page, from webhooks and, with a mailbox, IMAP polling
+	var emailIngest EmailIngestService
+	if f.config.EmailIngest.PageID != "" {
+		emailIngest = NewEmailIngestService(stdlibDB, unifiedChunkService, storageService, f.config.EmailIngest, monitor)
+		lifecycle.Register("email_ingest", WorkerService(emailIngest))
+	}
+
+	// GraphQL queries, read through the wrapped chunk service with per-query batching
+	graphQL := NewGraphQLService(stdlibDB, unifiedChunkService, backlinkService, templateService, monitor)
 	
 	// TODO: Implement NewCachedSearchService when needed
 	// Wrap search service with caching and monitoring
@@ -136,17 +610,73 @@ func (f *ServiceFactory) CreateServices() (*ServiceContainer, error) {
 		monitor := NewPerformanceMonitor(metricsService)
 		searchService = NewMonitoredSearchService(searchService, monitor)
 	}
+	optimizedSearch := NewOptimizedSearchService(searchService, searchCache, f.config.SearchCache.DefaultTTL)
+	optimizedSearch.SetFacetSource(NewSQLSearchFacetSource(stdlibDB))
+	optimizedSearch.SetVectorSource(embeddingStore)
+	if pageACLs != nil {
+		optimizedSearch.SetAccessFilter(pageACLs)
+	}
+
+	// Log searched queries for autocomplete and typo-tolerant suggestions
+	var querySuggestions QuerySuggestionService
+	if f.config.Suggestions.Enabled {
+		querySuggestions = NewQuerySuggestionService(stdlibDB, f.config.Suggestions.MinSimilarity, f.config.Suggestions.MaxQueryLength, monitor)
+		optimizedSearch.SetQueryRecorder(querySuggestions)
+	}
+
+	// Question answering is only available when a completion provider is configured
+	var ragService *RAGService
+	var summarization SummarizationService
+	completionProvider, err := NewCompletionProvider(&f.config.RAG)
+	if err != nil {
+		logger.Warn("question answering disabled", LogField{Key: "reason", Value: err.Error()})
+	} else {
+		ragService = NewRAGService(searchService, completionProvider, &f.config.RAG)
+		ragService.SetHierarchySource(unifiedChunkService)
+		if pageACLs != nil {
+			ragService.SetAccessFilter(pageACLs)
+		}
+		// Chunk and page summaries share the completion provider
+		summarization = NewSummarizationService(stdlibDB, unifiedChunkService, completionProvider, &f.config.Summary, monitor)
+	}
+
+	// Optimized search can re-rank with a cross-encoder or the completion provider
+	if reranker := NewRerankService(&f.config.Rerank, completionProvider); reranker.Available() {
+		optimizedSearch.SetReranker(reranker)
+	}
 	
+	// Sample the primary pool for exhaustion while the service runs
+	dbHealth := NewDBHealthService(stdlibDB, monitor, DBHealthConfig{
+		WarnUtilization: f.config.Database.PoolWarnUtilization,
+		CheckInterval:   f.config.Database.PoolCheckInterval,
+	})
+	lifecycle.Register("db_health", WorkerService(dbHealth))
+
 	// Register health checkers
 	if wrappedSupabaseClient != nil {
 		healthService.RegisterChecker(NewDatabaseHealthChecker("database", wrappedSupabaseClient))
 	}
+	healthService.RegisterChecker(NewDBPoolHealthChecker("database_pool", dbHealth))
 	if cacheService != nil {
 		healthService.RegisterChecker(NewCacheHealthChecker("cache", cacheService))
 	}
 	if metricsService != nil {
 		healthService.RegisterChecker(NewMetricsHealthChecker("metrics", metricsService))
 	}
+
+	// Answer liveness, readiness and startup probes. Traffic needs PostgreSQL and Supabase;
+	// an unreachable cache or embedding provider only degrades the service.
+	probes := NewProbeService("1.0.0", f.config.Probes.Timeout)
+	probes.AddCheck(NewDBPoolHealthChecker("postgres", dbHealth), true)
+	if wrappedSupabaseClient != nil {
+		probes.AddCheck(NewDatabaseHealthChecker("supabase", wrappedSupabaseClient), true)
+	}
+	if cacheService != nil {
+		probes.AddCheck(NewCacheHealthChecker("cache", cacheService), false)
+	}
+	if f.config.Embedding.Endpoint != "" {
+		probes.AddCheck(NewEmbeddingHealthChecker("embedding", embeddingService, f.config.Probes.EmbeddingCache), false)
+	}
 	
 	container := &ServiceContainer{
 		TextProcessor:       textProcessor,
@@ -156,12 +686,63 @@ func (f *ServiceFactory) CreateServices() (*ServiceContainer, error) {
 		TemplateService:     templateService,
 		TagService:          tagService,
 		UnifiedChunkService: unifiedChunkService,
+		BacklinkService:     NewChunkLoadingBacklinkService(backlinkService, unifiedChunkService),
+		VectorIndexManager:  vectorIndexManager,
+		StorageService:      storageService,
+		DedupStorage:        dedupStorage,
+		SearchCache:         searchCache,
+		OptimizedSearch:     optimizedSearch,
+		QuerySuggestions:    querySuggestions,
+		RAGService:          ragService,
+		Summarization:       summarization,
+		SnapshotService:     snapshotService,
+		GraphAnalytics:      graphAnalytics,
+		ChunkHierarchy:      chunkHierarchy,
+		GraphExport:         graphExport,
+		GraphEvidence:       graphEvidence,
+		GraphTemporal:       graphTemporal,
+		GraphConflicts:      graphConflicts,
+		EntityResolution:    entityResolution,
+		ChangeFeed:          changeFeed,
+		LiveOutline:         liveOutline,
+		ChunkSync:           chunkSync,
+		BulkTags:            bulkTags,
+		OutlineExchange:     outlineExchange,
+		NoteImport:          noteImport,
+		PageACLs:            pageACLs,
+		ChunkLocks:          chunkLocks,
+		Capture:             capture,
+		EmailIngest:         emailIngest,
+		Journal:             journal,
+		Tasks:               tasks,
+		ChunkHistory:        chunkHistory,
+		PageRender:          pageRender,
+		Publishing:          publishing,
+		GraphQL:             graphQL,
+		Stats:               stats,
+		EmbeddingSync:       embeddingSync,
+		EmbeddingStore:      embeddingStore,
+		EmbeddingBatch:      embeddingBatch,
+		GraphSync:           graphSync,
+		HotData:             hotData,
+		Retention:           retention,
+		Maintenance:         maintenance,
+		APITokens:           apiTokens,
+		ImageSimilarity:     imageSimilarity,
+		SlideRecommendation: slideRecommendation,
 		PostgresService:     postgresService,
+		ReplicaRouter:       replicaRouter,
+		DBHealth:            dbHealth,
+		PIICompliance:       piiCompliance,
 		SupabaseClient:      wrappedSupabaseClient,
 		CacheService:        cacheService,
 		MetricsService:      metricsService,
+		QueryMonitor:        monitor,
 		Logger:              logger,
 		HealthService:       healthService,
+		Probes:              probes,
+		lifecycle:           lifecycle,
+		writePool:           writePool,
 	}
 	
 	return container, nil
@@ -178,4 +759,116 @@ func (c *ServiceContainer) HealthCheck() error {
 	// For now, we only check the critical Supabase connection
 	
 	return nil
-}
\ No newline at end of file
+}
+
+// Start starts the background workers in dependency order: the embedding and graph sync
+// workers, the maintenance daemon, the change feed, hot read tracking and cache warming,
+// and pool and replica lag checks. Services can be used before Start, but writes are
+// only queued for the sync workers until they run.
+func (c *ServiceContainer) Start(ctx context.Context) error {
+	if c.lifecycle == nil {
+		return nil
+	}
+	return c.lifecycle.Start(ctx)
+}
+
+// Close stops the background workers in the reverse of their start order and releases
+// connections. Workers finish the item they are on, hot read counts are flushed and a
+// final metrics snapshot is logged before the database pools close. If ctx expires first
+// the pools are left open for the work still running and the error is returned. Later
+// calls return the first call's result.
+func (c *ServiceContainer) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.close(ctx)
+	})
+	return c.closeErr
+}
+
+func (c *ServiceContainer) close(ctx context.Context) error {
+	if c.lifecycle != nil {
+		if err := c.lifecycle.Stop(ctx); err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("failed to stop background services: %w", err)
+			}
+			// A service that failed to stop on its own is not using the pools any more
+			if c.Logger != nil {
+				c.Logger.Warn("background service failed to stop", LogField{Key: "error", Value: err.Error()})
+			}
+		}
+	}
+
+	if c.MetricsService != nil && c.Logger != nil {
+		c.Logger.Info("final metrics", LogField{Key: "metrics", Value: c.MetricsService.GetMetrics()})
+	}
+	if stopper, ok := c.QueryMonitor.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
+	if stopper, ok := c.CacheService.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
+	if stopper, ok := c.SearchCache.(interface{ Stop() }); ok {
+		stopper.Stop()
+	}
+
+	var errs []error
+	if c.ReplicaRouter != nil {
+		if err := c.ReplicaRouter.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close read replicas: %w", err))
+		}
+		if primary := c.ReplicaRouter.Primary(); primary != nil {
+			if err := primary.Close(); err != nil {
+				errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+			}
+		}
+	}
+	if c.writePool != nil {
+		c.writePool.Close()
+	}
+	if c.PostgresService != nil {
+		c.PostgresService.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyConfig applies reloadable tunables from a reloaded configuration to running
+// services. Settings such as connection strings and providers require a restart.
+func (c *ServiceContainer) ApplyConfig(cfg *config.Config) {
+	if cache, ok := c.CacheService.(*InMemoryCache); ok {
+		cache.SetMaxSize(cfg.Cache.MaxSize)
+		cache.SetMaxBytes(cfg.Cache.MaxBytes)
+		if err := cache.SetEvictionPolicy(EvictionPolicy(cfg.Cache.EvictionPolicy)); err != nil && c.Logger != nil {
+			c.Logger.Warn("failed to change cache eviction policy", LogField{Key: "error", Value: err.Error()})
+		}
+	}
+	if searchCache, ok := c.SearchCache.(interface {
+		SetTTLs(defaultTTL, staleWhileRevalidate time.Duration)
+	}); ok {
+		searchCache.SetTTLs(cfg.SearchCache.DefaultTTL, cfg.SearchCache.StaleWhileRevalidate)
+	}
+	if c.OptimizedSearch != nil {
+		c.OptimizedSearch.SetTTL(cfg.SearchCache.DefaultTTL)
+	}
+	if c.RAGService != nil {
+		ragConfig := cfg.RAG
+		c.RAGService.SetConfig(&ragConfig)
+	}
+	if c.ImageSimilarity != nil {
+		c.ImageSimilarity.SetTuning(imageSimilarityTuning(&cfg.ImageSimilarity))
+	}
+
+	if c.Logger != nil {
+		c.Logger.Info("applied reloaded configuration",
+			LogField{Key: "cache_max_size", Value: cfg.Cache.MaxSize},
+			LogField{Key: "search_cache_ttl", Value: cfg.SearchCache.DefaultTTL.String()},
+		)
+	}
+}
+
+// imageSimilarityTuning converts image similarity configuration into match thresholds
+func imageSimilarityTuning(cfg *config.ImageSimilarityConfig) ImageSimilarityTuning {
+	return ImageSimilarityTuning{
+		MaxHashDistance:    cfg.MaxHashDistance,
+		EmbeddingThreshold: cfg.EmbeddingThreshold,
+		HashWeight:         cfg.HashWeight,
+	}
+}
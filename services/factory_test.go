@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stoppableCache records whether its janitor was stopped
+type stoppableCache struct {
+	CacheService
+	stopped int
+}
+
+func (c *stoppableCache) Stop() {
+	c.stopped++
+}
+
+func TestServiceContainer_StartAndClose(t *testing.T) {
+	worker := &slowWorker{}
+	cache := &stoppableCache{}
+	lifecycle := NewLifecycle()
+	lifecycle.Register("graph_sync", WorkerService(worker))
+	container := &ServiceContainer{CacheService: cache, lifecycle: lifecycle}
+
+	require.NoError(t, container.Start(context.Background()))
+	require.NoError(t, container.Close(context.Background()))
+	assert.Equal(t, 1, worker.stopped)
+	assert.Equal(t, 1, cache.stopped)
+
+	// Later calls do not stop anything twice
+	require.NoError(t, container.Close(context.Background()))
+	assert.Equal(t, 1, worker.stopped)
+	assert.Equal(t, 1, cache.stopped)
+}
+
+func TestServiceContainer_CloseDeadline(t *testing.T) {
+	cache := &stoppableCache{}
+	lifecycle := NewLifecycle()
+	lifecycle.Register("graph_sync", WorkerService(&slowWorker{delay: 200 * time.Millisecond}))
+	container := &ServiceContainer{CacheService: cache, lifecycle: lifecycle}
+	require.NoError(t, container.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := container.Close(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	// Resources the worker may still use are left alone
+	assert.Zero(t, cache.stopped)
+}
@@ -0,0 +1,371 @@
+package services
+
+import (
+	"math"
+	"semantic-text-processor/models"
+	"sort"
+)
+
+// analyticsGraph is an in-memory, index-addressed copy of graph_nodes/graph_edges used by
+// the analytics algorithms. Edges keep their direction for PageRank; betweenness and
+// community detection use the undirected view.
+type analyticsGraph struct {
+	ids        []string
+	index      map[string]int
+	out        [][]weightedArc
+	outWeight  []float64
+	undirected []map[int]float64
+	edgeCount  int
+}
+
+type weightedArc struct {
+	to     int
+	weight float64
+}
+
+// newAnalyticsGraph builds the graph, skipping edges whose endpoints are unknown.
+// An edge's "weight" property is used when positive, otherwise it counts as 1.
+func newAnalyticsGraph(nodeIDs []string, edges []models.GraphEdge) *analyticsGraph {
+	g := &analyticsGraph{
+		ids:        nodeIDs,
+		index:      make(map[string]int, len(nodeIDs)),
+		out:        make([][]weightedArc, len(nodeIDs)),
+		outWeight:  make([]float64, len(nodeIDs)),
+		undirected: make([]map[int]float64, len(nodeIDs)),
+	}
+	for i, id := range nodeIDs {
+		g.index[id] = i
+		g.undirected[i] = make(map[int]float64)
+	}
+
+	for _, edge := range edges {
+		source, ok := g.index[edge.SourceNodeID]
+		if !ok {
+			continue
+		}
+		target, ok := g.index[edge.TargetNodeID]
+		if !ok {
+			continue
+		}
+
+		weight := 1.0
+		if w, ok := edge.Properties["weight"].(float64); ok && w > 0 {
+			weight = w
+		}
+
+		g.out[source] = append(g.out[source], weightedArc{to: target, weight: weight})
+		g.outWeight[source] += weight
+		if source == target {
+			// A self loop adds its weight to both ends of the node's degree
+			g.undirected[source][source] += 2 * weight
+		} else {
+			g.undirected[source][target] += weight
+			g.undirected[target][source] += weight
+		}
+		g.edgeCount++
+	}
+
+	return g
+}
+
+// pageRank runs weighted power iteration. Rank held by nodes without outgoing edges is
+// redistributed uniformly so scores always sum to 1.
+func (g *analyticsGraph) pageRank(damping float64, maxIterations int, tolerance float64) ([]float64, int, bool) {
+	n := len(g.ids)
+	if n == 0 {
+		return nil, 0, true
+	}
+
+	rank := make([]float64, n)
+	for i := range rank {
+		rank[i] = 1 / float64(n)
+	}
+	next := make([]float64, n)
+
+	for iteration := 1; iteration <= maxIterations; iteration++ {
+		var dangling float64
+		for u := 0; u < n; u++ {
+			if g.outWeight[u] == 0 {
+				dangling += rank[u]
+			}
+		}
+
+		base := (1-damping)/float64(n) + damping*dangling/float64(n)
+		for i := range next {
+			next[i] = base
+		}
+		for u := 0; u < n; u++ {
+			if g.outWeight[u] == 0 {
+				continue
+			}
+			share := damping * rank[u] / g.outWeight[u]
+			for _, arc := range g.out[u] {
+				next[arc.to] += share * arc.weight
+			}
+		}
+
+		var delta float64
+		for i := range rank {
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < tolerance {
+			return rank, iteration, true
+		}
+	}
+
+	return rank, maxIterations, false
+}
+
+// betweenness computes normalized betweenness centrality on the undirected, unweighted
+// view with Brandes' algorithm. With 0 < samples < n, only evenly spaced source nodes are
+// expanded and the result is scaled up, trading accuracy for O(samples·E) time.
+func (g *analyticsGraph) betweenness(samples int) []float64 {
+	n := len(g.ids)
+	centrality := make([]float64, n)
+	if n < 3 {
+		return centrality
+	}
+
+	sources := make([]int, 0, n)
+	if samples > 0 && samples < n {
+		for i := 0; i < samples; i++ {
+			sources = append(sources, i*n/samples)
+		}
+	} else {
+		for i := 0; i < n; i++ {
+			sources = append(sources, i)
+		}
+	}
+
+	neighbors := make([][]int, n)
+	for u := 0; u < n; u++ {
+		for v := range g.undirected[u] {
+			if v != u {
+				neighbors[u] = append(neighbors[u], v)
+			}
+		}
+		sort.Ints(neighbors[u])
+	}
+
+	sigma := make([]float64, n)
+	distance := make([]int, n)
+	delta := make([]float64, n)
+	predecessors := make([][]int, n)
+	for _, s := range sources {
+		for i := 0; i < n; i++ {
+			sigma[i], distance[i], delta[i] = 0, -1, 0
+			predecessors[i] = predecessors[i][:0]
+		}
+		sigma[s], distance[s] = 1, 0
+
+		stack := make([]int, 0, n)
+		queue := []int{s}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range neighbors[v] {
+				if distance[w] < 0 {
+					distance[w] = distance[v] + 1
+					queue = append(queue, w)
+				}
+				if distance[w] == distance[v]+1 {
+					sigma[w] += sigma[v]
+					predecessors[w] = append(predecessors[w], v)
+				}
+			}
+		}
+
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range predecessors[w] {
+				delta[v] += sigma[v] / sigma[w] * (1 + delta[w])
+			}
+			if w != s {
+				centrality[w] += delta[w]
+			}
+		}
+	}
+
+	// Every undirected pair is counted from both ends; normalizing by (n-1)(n-2) folds that
+	// in and maps scores to [0, 1]
+	scale := float64(n) / float64(len(sources)) / float64((n-1)*(n-2))
+	for i := range centrality {
+		centrality[i] *= scale
+	}
+	return centrality
+}
+
+// louvainCommunities detects communities by greedy modularity optimization: nodes move to
+// the neighboring community with the best modularity gain, then communities are collapsed
+// into single nodes and the process repeats until nothing moves. Community IDs are ordered
+// by size, largest first. It returns each node's community and the final modularity.
+func (g *analyticsGraph) louvainCommunities(resolution float64) ([]int, float64) {
+	n := len(g.ids)
+	membership := make([]int, n)
+	for i := range membership {
+		membership[i] = i
+	}
+	if n == 0 {
+		return membership, 0
+	}
+
+	adjacency := g.undirected
+	for {
+		community, moved := louvainLocalMoves(adjacency, resolution)
+		if !moved {
+			break
+		}
+
+		// Collapse communities into nodes of the next level
+		renumbered, count := compactCommunities(community)
+		for i := range membership {
+			membership[i] = renumbered[membership[i]]
+		}
+
+		aggregated := make([]map[int]float64, count)
+		for c := range aggregated {
+			aggregated[c] = make(map[int]float64)
+		}
+		for u, row := range adjacency {
+			for v, weight := range row {
+				aggregated[renumbered[u]][renumbered[v]] += weight
+			}
+		}
+		adjacency = aggregated
+	}
+
+	membership = orderCommunitiesBySize(membership)
+	return membership, g.modularity(membership, resolution)
+}
+
+// louvainLocalMoves runs the first Louvain phase on one level of the graph. Self loops in
+// adjacency carry twice the internal weight so that a node's degree is its row sum.
+func louvainLocalMoves(adjacency []map[int]float64, resolution float64) ([]int, bool) {
+	n := len(adjacency)
+	community := make([]int, n)
+	degree := make([]float64, n)
+	total := make([]float64, n)
+	var totalWeight float64
+	for u, row := range adjacency {
+		community[u] = u
+		for _, weight := range row {
+			degree[u] += weight
+		}
+		total[u] = degree[u]
+		totalWeight += degree[u]
+	}
+	if totalWeight == 0 {
+		return community, false
+	}
+
+	moved := false
+	for {
+		improved := false
+		for u := 0; u < n; u++ {
+			if degree[u] == 0 {
+				continue
+			}
+			current := community[u]
+			total[current] -= degree[u]
+
+			links := make(map[int]float64)
+			for v, weight := range adjacency[u] {
+				if v != u {
+					links[community[v]] += weight
+				}
+			}
+			candidates := make([]int, 0, len(links))
+			for c := range links {
+				candidates = append(candidates, c)
+			}
+			sort.Ints(candidates)
+
+			best := current
+			bestGain := links[current] - resolution*total[current]*degree[u]/totalWeight
+			for _, c := range candidates {
+				gain := links[c] - resolution*total[c]*degree[u]/totalWeight
+				if gain > bestGain+1e-12 {
+					best, bestGain = c, gain
+				}
+			}
+
+			total[best] += degree[u]
+			if best != current {
+				community[u] = best
+				improved = true
+				moved = true
+			}
+		}
+		if !improved {
+			return community, moved
+		}
+	}
+}
+
+// modularity scores a partition of the undirected view
+func (g *analyticsGraph) modularity(membership []int, resolution float64) float64 {
+	var totalWeight float64
+	internal := make(map[int]float64)
+	total := make(map[int]float64)
+	for u, row := range g.undirected {
+		for v, weight := range row {
+			totalWeight += weight
+			total[membership[u]] += weight
+			if membership[u] == membership[v] {
+				internal[membership[u]] += weight
+			}
+		}
+	}
+	if totalWeight == 0 {
+		return 0
+	}
+
+	var q float64
+	for c, sum := range total {
+		q += internal[c]/totalWeight - resolution*(sum/totalWeight)*(sum/totalWeight)
+	}
+	return q
+}
+
+// compactCommunities renumbers community labels to 0..count-1 in order of first appearance
+func compactCommunities(community []int) ([]int, int) {
+	ids := make(map[int]int)
+	renumbered := make([]int, len(community))
+	for u, c := range community {
+		id, ok := ids[c]
+		if !ok {
+			id = len(ids)
+			ids[c] = id
+		}
+		renumbered[u] = id
+	}
+	return renumbered, len(ids)
+}
+
+// orderCommunitiesBySize relabels communities so 0 is the largest, breaking ties by the
+// lowest member index
+func orderCommunitiesBySize(membership []int) []int {
+	compact, count := compactCommunities(membership)
+	sizes := make([]int, count)
+	for _, c := range compact {
+		sizes[c]++
+	}
+
+	order := make([]int, count)
+	for i := range order {
+		order[i] = i
+	}
+	// compactCommunities numbers by first appearance, so a stable sort keeps lower member indexes first
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+
+	rank := make([]int, count)
+	for position, c := range order {
+		rank[c] = position
+	}
+	for u := range compact {
+		compact[u] = rank[compact[u]]
+	}
+	return compact
+}
@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"semantic-text-processor/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrGraphAnalyticsRunning is returned when an analytics run is requested while another is in progress
+var ErrGraphAnalyticsRunning = errors.New("graph analytics run already in progress")
+
+// GraphAnalyticsService computes PageRank, betweenness centrality and Louvain communities
+// over graph_nodes/graph_edges and stores the scores as node properties
+type GraphAnalyticsService interface {
+	// RunAnalytics recomputes the requested metrics for the whole graph
+	RunAnalytics(ctx context.Context, opts models.GraphAnalyticsOptions) (*models.GraphAnalyticsResult, error)
+	// TopEntities returns the highest-scoring nodes for pagerank or betweenness, optionally of one entity type
+	TopEntities(ctx context.Context, metric, entityType string, limit int) ([]models.EntityScore, error)
+	// GetCommunities returns detected communities with at least minSize members, largest first,
+	// each listing up to memberLimit members by PageRank
+	GetCommunities(ctx context.Context, minSize, memberLimit int) ([]models.GraphCommunity, error)
+}
+
+// graphAnalyticsService implements GraphAnalyticsService on PostgreSQL
+type graphAnalyticsService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+	running sync.Mutex
+}
+
+// NewGraphAnalyticsService creates a new graph analytics service
+func NewGraphAnalyticsService(db *sql.DB, monitor QueryPerformanceMonitor) GraphAnalyticsService {
+	return &graphAnalyticsService{db: db, monitor: monitor}
+}
+
+var allGraphAnalytics = []string{models.GraphMetricPageRank, models.GraphMetricBetweenness, models.GraphMetricCommunity}
+
+// RunAnalytics loads the graph, runs the selected algorithms and merges the scores into
+// each node's properties in one transaction
+func (s *graphAnalyticsService) RunAnalytics(ctx context.Context, opts models.GraphAnalyticsOptions) (*models.GraphAnalyticsResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	if !s.running.TryLock() {
+		return nil, ErrGraphAnalyticsRunning
+	}
+	defer s.running.Unlock()
+
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("graph_analytics", time.Since(start), 1)
+	}()
+
+	algorithms, err := selectGraphAnalytics(opts.Algorithms)
+	if err != nil {
+		return nil, err
+	}
+	applyGraphAnalyticsDefaults(&opts)
+
+	graph, err := s.loadGraph(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.GraphAnalyticsResult{
+		NodeCount:  len(graph.ids),
+		EdgeCount:  graph.edgeCount,
+		Algorithms: algorithms,
+		ComputedAt: time.Now().UTC(),
+	}
+
+	properties := make([]map[string]interface{}, len(graph.ids))
+	for i := range properties {
+		properties[i] = map[string]interface{}{"analytics_computed_at": result.ComputedAt.Format(time.RFC3339)}
+	}
+
+	for _, algorithm := range algorithms {
+		switch algorithm {
+		case models.GraphMetricPageRank:
+			ranks, iterations, converged := graph.pageRank(opts.Damping, opts.MaxIterations, opts.Tolerance)
+			result.PageRankIterations, result.PageRankConverged = iterations, converged
+			if !converged {
+				log.Printf("Warning: PageRank did not converge within %d iterations", iterations)
+			}
+			for i, rank := range ranks {
+				properties[i][models.GraphMetricPageRank] = rank
+			}
+		case models.GraphMetricBetweenness:
+			for i, score := range graph.betweenness(opts.BetweennessSamples) {
+				properties[i][models.GraphMetricBetweenness] = score
+			}
+		case models.GraphMetricCommunity:
+			membership, modularity := graph.louvainCommunities(opts.Resolution)
+			result.Modularity = modularity
+			for i, community := range membership {
+				properties[i][models.GraphMetricCommunity] = community
+				if community+1 > result.CommunityCount {
+					result.CommunityCount = community + 1
+				}
+			}
+		}
+	}
+
+	updated, err := s.storeScores(ctx, graph.ids, properties)
+	if err != nil {
+		return nil, err
+	}
+	result.UpdatedNodes = updated
+	result.Duration = time.Since(start)
+
+	return result, nil
+}
+
+// TopEntities ranks nodes by a stored metric
+func (s *graphAnalyticsService) TopEntities(ctx context.Context, metric, entityType string, limit int) ([]models.EntityScore, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("graph_top_entities", time.Since(start), 1)
+	}()
+
+	if metric != models.GraphMetricPageRank && metric != models.GraphMetricBetweenness {
+		return nil, fmt.Errorf("unsupported metric %q: use %s or %s", metric, models.GraphMetricPageRank, models.GraphMetricBetweenness)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+
+	query := `
+		SELECT id, chunk_id, entity_name, entity_type, properties, created_at,
+			   (properties->>$1)::float8 AS score
+		FROM graph_nodes
+		WHERE properties ? $1`
+	args := []interface{}{metric}
+	if entityType != "" {
+		args = append(args, entityType)
+		query += fmt.Sprintf(" AND entity_type = $%d", len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY score DESC, entity_name LIMIT $%d", len(args))
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top entities: %w", err)
+	}
+	defer rows.Close()
+
+	var entities []models.EntityScore
+	for rows.Next() {
+		var entity models.EntityScore
+		node, err := scanGraphNode(rows, &entity.Score)
+		if err != nil {
+			return nil, err
+		}
+		entity.Node = *node
+		entity.Rank = len(entities) + 1
+		entities = append(entities, entity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph nodes: %w", err)
+	}
+
+	return entities, nil
+}
+
+// GetCommunities groups nodes by their stored community
+func (s *graphAnalyticsService) GetCommunities(ctx context.Context, minSize, memberLimit int) ([]models.GraphCommunity, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("graph_communities", time.Since(start), 1)
+	}()
+
+	if minSize <= 0 {
+		minSize = 2
+	}
+	if memberLimit <= 0 {
+		memberLimit = 10
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, chunk_id, entity_name, entity_type, properties, created_at,
+			   COALESCE((properties->>'pagerank')::float8, 0) AS score
+		FROM graph_nodes
+		WHERE properties ? 'community'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query communities: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[int]*models.GraphCommunity)
+	for rows.Next() {
+		var score float64
+		node, err := scanGraphNode(rows, &score)
+		if err != nil {
+			return nil, err
+		}
+		id, ok := node.Properties[models.GraphMetricCommunity].(float64)
+		if !ok {
+			continue
+		}
+
+		community, exists := byID[int(id)]
+		if !exists {
+			community = &models.GraphCommunity{ID: int(id)}
+			byID[int(id)] = community
+		}
+		community.Members = append(community.Members, models.EntityScore{Node: *node, Score: score})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph nodes: %w", err)
+	}
+
+	communities := make([]models.GraphCommunity, 0, len(byID))
+	for _, community := range byID {
+		community.Size = len(community.Members)
+		if community.Size < minSize {
+			continue
+		}
+
+		sort.Slice(community.Members, func(i, j int) bool {
+			a, b := community.Members[i], community.Members[j]
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return a.Node.EntityName < b.Node.EntityName
+		})
+		if len(community.Members) > memberLimit {
+			community.Members = community.Members[:memberLimit]
+		}
+		for i := range community.Members {
+			community.Members[i].Rank = i + 1
+		}
+		community.Label = community.Members[0].Node.EntityName
+		communities = append(communities, *community)
+	}
+
+	sort.Slice(communities, func(i, j int) bool {
+		if communities[i].Size != communities[j].Size {
+			return communities[i].Size > communities[j].Size
+		}
+		return communities[i].ID < communities[j].ID
+	})
+
+	return communities, nil
+}
+
+// loadGraph reads node IDs and edges into memory
+func (s *graphAnalyticsService) loadGraph(ctx context.Context) (*analyticsGraph, error) {
+	nodeRows, err := s.db.QueryContext(ctx, `SELECT id FROM graph_nodes ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph nodes: %w", err)
+	}
+	defer nodeRows.Close()
+
+	var nodeIDs []string
+	for nodeRows.Next() {
+		var id string
+		if err := nodeRows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		nodeIDs = append(nodeIDs, id)
+	}
+	if err := nodeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph nodes: %w", err)
+	}
+
+	edgeRows, err := s.db.QueryContext(ctx, `SELECT source_node_id, target_node_id, relationship_type, properties FROM graph_edges`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph edges: %w", err)
+	}
+	defer edgeRows.Close()
+
+	var edges []models.GraphEdge
+	for edgeRows.Next() {
+		var edge models.GraphEdge
+		var propertiesBytes []byte
+		if err := edgeRows.Scan(&edge.SourceNodeID, &edge.TargetNodeID, &edge.RelationshipType, &propertiesBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan graph edge: %w", err)
+		}
+		if len(propertiesBytes) > 0 {
+			if err := json.Unmarshal(propertiesBytes, &edge.Properties); err != nil {
+				log.Printf("Warning: failed to parse properties for edge %s -> %s: %v", edge.SourceNodeID, edge.TargetNodeID, err)
+			}
+		}
+		edges = append(edges, edge)
+	}
+	if err := edgeRows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph edges: %w", err)
+	}
+
+	return newAnalyticsGraph(nodeIDs, edges), nil
+}
+
+// storeScores merges computed scores into node properties, keeping unrelated keys
+func (s *graphAnalyticsService) storeScores(ctx context.Context, nodeIDs []string, properties []map[string]interface{}) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		UPDATE graph_nodes
+		SET properties = COALESCE(properties, '{}'::jsonb) || $2::jsonb
+		WHERE id = $1`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i, id := range nodeIDs {
+		scores, err := json.Marshal(properties[i])
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal scores for node %s: %w", id, err)
+		}
+		if _, err := stmt.ExecContext(ctx, id, scores); err != nil {
+			return 0, fmt.Errorf("failed to store scores for node %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return len(nodeIDs), nil
+}
+
+// scanGraphNode scans id, chunk_id, entity_name, entity_type, properties, created_at and a score column
+func scanGraphNode(rows *sql.Rows, score *float64) (*models.GraphNode, error) {
+	var node models.GraphNode
+	var propertiesBytes []byte
+	if err := rows.Scan(&node.ID, &node.ChunkID, &node.EntityName, &node.EntityType, &propertiesBytes, &node.CreatedAt, score); err != nil {
+		return nil, fmt.Errorf("failed to scan graph node: %w", err)
+	}
+
+	node.Properties = make(map[string]interface{})
+	if len(propertiesBytes) > 0 {
+		if err := json.Unmarshal(propertiesBytes, &node.Properties); err != nil {
+			log.Printf("Warning: failed to parse properties for graph node %s: %v", node.ID, err)
+		}
+	}
+	return &node, nil
+}
+
+// selectGraphAnalytics validates the requested algorithms, defaulting to all of them
+func selectGraphAnalytics(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return allGraphAnalytics, nil
+	}
+
+	selected := make([]string, 0, len(requested))
+	for _, algorithm := range allGraphAnalytics {
+		for _, name := range requested {
+			if name == algorithm {
+				selected = append(selected, algorithm)
+				break
+			}
+		}
+	}
+	if len(selected) != len(requested) {
+		return nil, fmt.Errorf("unsupported analytics algorithm in %v: use %v", requested, allGraphAnalytics)
+	}
+	return selected, nil
+}
+
+func applyGraphAnalyticsDefaults(opts *models.GraphAnalyticsOptions) {
+	if opts.Damping <= 0 || opts.Damping >= 1 {
+		opts.Damping = 0.85
+	}
+	if opts.MaxIterations <= 0 {
+		opts.MaxIterations = 100
+	}
+	if opts.Tolerance <= 0 {
+		opts.Tolerance = 1e-6
+	}
+	if opts.Resolution <= 0 {
+		opts.Resolution = 1
+	}
+}
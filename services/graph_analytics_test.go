@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// twoCliquesGraph builds two 4-node cliques a0..a3 and b0..b3 joined through a single bridge node
+func twoCliquesGraph() *analyticsGraph {
+	var ids []string
+	var edges []models.GraphEdge
+	for _, prefix := range []string{"a", "b"} {
+		for i := 0; i < 4; i++ {
+			ids = append(ids, fmt.Sprintf("%s%d", prefix, i))
+			for j := 0; j < i; j++ {
+				edges = append(edges, models.GraphEdge{
+					SourceNodeID: fmt.Sprintf("%s%d", prefix, i),
+					TargetNodeID: fmt.Sprintf("%s%d", prefix, j),
+				})
+			}
+		}
+	}
+	ids = append(ids, "bridge")
+	edges = append(edges,
+		models.GraphEdge{SourceNodeID: "a0", TargetNodeID: "bridge"},
+		models.GraphEdge{SourceNodeID: "bridge", TargetNodeID: "b0"},
+		models.GraphEdge{SourceNodeID: "a1", TargetNodeID: "missing"},
+	)
+	return newAnalyticsGraph(ids, edges)
+}
+
+func TestAnalyticsGraph_PageRank(t *testing.T) {
+	// Every spoke points at the hub, which points back at one of them
+	ids := []string{"hub", "s1", "s2", "s3", "isolated"}
+	graph := newAnalyticsGraph(ids, []models.GraphEdge{
+		{SourceNodeID: "s1", TargetNodeID: "hub"},
+		{SourceNodeID: "s2", TargetNodeID: "hub"},
+		{SourceNodeID: "s3", TargetNodeID: "hub", Properties: map[string]interface{}{"weight": 3.0}},
+		{SourceNodeID: "hub", TargetNodeID: "s1"},
+	})
+
+	ranks, iterations, converged := graph.pageRank(0.85, 500, 1e-9)
+	require.True(t, converged)
+	assert.Greater(t, iterations, 1)
+
+	var sum float64
+	for _, rank := range ranks {
+		sum += rank
+	}
+	assert.InDelta(t, 1.0, sum, 1e-9, "dangling rank is redistributed")
+	assert.Greater(t, ranks[0], ranks[1])
+	assert.Greater(t, ranks[1], ranks[2], "the hub's only out-link favours s1")
+	assert.InDelta(t, ranks[2], ranks[3], 1e-9)
+}
+
+func TestAnalyticsGraph_Betweenness(t *testing.T) {
+	graph := twoCliquesGraph()
+	assert.Equal(t, 14, graph.edgeCount, "edges to unknown nodes are skipped")
+
+	exact := graph.betweenness(0)
+	bridge := graph.index["bridge"]
+	for i, score := range exact {
+		assert.GreaterOrEqual(t, score, 0.0)
+		assert.LessOrEqual(t, score, 1.0)
+		if i != bridge && i != graph.index["a0"] && i != graph.index["b0"] {
+			assert.InDelta(t, 0, score, 1e-9, "clique members are never on a shortest path")
+		}
+	}
+	assert.Greater(t, exact[bridge], exact[graph.index["a0"]], "the bridge joins all 16 cross-clique pairs")
+	assert.Greater(t, exact[graph.index["a0"]], exact[graph.index["a1"]])
+
+	// Sampling every node is the exact computation
+	assert.InDeltaSlice(t, exact, graph.betweenness(len(exact)), 1e-9)
+	sampled := graph.betweenness(3)
+	assert.Greater(t, sampled[bridge], 0.0)
+}
+
+func TestAnalyticsGraph_LouvainCommunities(t *testing.T) {
+	graph := twoCliquesGraph()
+	membership, modularity := graph.louvainCommunities(1)
+
+	a, b := membership[graph.index["a1"]], membership[graph.index["b1"]]
+	assert.NotEqual(t, a, b)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, a, membership[graph.index[fmt.Sprintf("a%d", i)]])
+		assert.Equal(t, b, membership[graph.index[fmt.Sprintf("b%d", i)]])
+	}
+	assert.Contains(t, []int{a, b}, membership[graph.index["bridge"]])
+	assert.Contains(t, []int{0, 1}, a, "communities are numbered from the largest")
+	assert.Greater(t, modularity, 0.3)
+
+	empty := newAnalyticsGraph([]string{"x", "y"}, nil)
+	membership, modularity = empty.louvainCommunities(1)
+	assert.Equal(t, []int{0, 1}, membership)
+	assert.Zero(t, modularity)
+}
+
+func TestSelectGraphAnalytics(t *testing.T) {
+	selected, err := selectGraphAnalytics(nil)
+	require.NoError(t, err)
+	assert.Len(t, selected, 3)
+
+	selected, err = selectGraphAnalytics([]string{"community", "pagerank"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"pagerank", "community"}, selected)
+
+	_, err = selectGraphAnalytics([]string{"closeness"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GraphEdgeWeighter recomputes graph edge weights from co-occurrence frequency. Extraction
+// creates nodes per chunk, so a relationship seen in many chunks is many edges between
+// nodes with the same entity names; each of those edges gets the relationship's
+// occurrence count, its newest sighting and a weight of confidence * (1 + ln(count)).
+// Traversals decay the weight by the time since the last sighting.
+type GraphEdgeWeighter struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+}
+
+// NewGraphEdgeWeighter creates an edge weighter
+func NewGraphEdgeWeighter(db *sql.DB, monitor QueryPerformanceMonitor) *GraphEdgeWeighter {
+	return &GraphEdgeWeighter{db: db, monitor: monitor}
+}
+
+// Refresh updates the weights that changed and returns how many edges were updated.
+// Co-occurrence is symmetric, so its entity names are compared as an unordered pair.
+func (w *GraphEdgeWeighter) Refresh(ctx context.Context) (int, error) {
+	start := time.Now()
+	result, err := w.db.ExecContext(ctx, `
+		WITH sightings AS (
+			SELECT e.id, e.relationship_type, e.created_at,
+				CASE WHEN e.relationship_type = 'co_occurs_with'
+					THEN LEAST(lower(s.entity_name), lower(t.entity_name)) ELSE lower(s.entity_name) END AS first_entity,
+				CASE WHEN e.relationship_type = 'co_occurs_with'
+					THEN GREATEST(lower(s.entity_name), lower(t.entity_name)) ELSE lower(t.entity_name) END AS second_entity,
+				COALESCE((e.properties->>'confidence')::double precision, 1) AS confidence
+			FROM graph_edges e
+			JOIN graph_nodes s ON s.id = e.source_node_id
+			JOIN graph_nodes t ON t.id = e.target_node_id
+		),
+		relationships AS (
+			SELECT first_entity, second_entity, relationship_type,
+				COUNT(*) AS occurrences, MAX(created_at) AS last_seen
+			FROM sightings
+			GROUP BY first_entity, second_entity, relationship_type
+		),
+		weights AS (
+			SELECT s.id, r.occurrences, r.last_seen,
+				s.confidence * (1 + ln(r.occurrences)) AS weight
+			FROM sightings s
+			JOIN relationships r USING (first_entity, second_entity, relationship_type)
+		)
+		UPDATE graph_edges e SET
+			occurrence_count = w.occurrences,
+			last_seen_at = w.last_seen,
+			weight = w.weight
+		FROM weights w
+		WHERE e.id = w.id
+			AND (e.occurrence_count <> w.occurrences
+				OR e.last_seen_at IS DISTINCT FROM w.last_seen
+				OR e.weight <> w.weight)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh graph edge weights: %w", err)
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to refresh graph edge weights: %w", err)
+	}
+	w.monitor.RecordQuery("refresh_graph_edge_weights", time.Since(start), int(updated))
+	return int(updated), nil
+}
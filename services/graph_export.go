@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"semantic-text-processor/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GraphExportService writes the knowledge graph, or the subgraph around an entity or page,
+// in formats understood by external visualization tools
+type GraphExportService interface {
+	// ExportGraph writes the subgraph selected by filter to w in the given format
+	ExportGraph(ctx context.Context, w io.Writer, format models.GraphExportFormat, filter models.GraphExportFilter) (*models.GraphExportStats, error)
+}
+
+// graphExportService implements GraphExportService on PostgreSQL
+type graphExportService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+}
+
+// NewGraphExportService creates a new graph export service
+func NewGraphExportService(db *sql.DB, monitor QueryPerformanceMonitor) GraphExportService {
+	return &graphExportService{db: db, monitor: monitor}
+}
+
+const defaultGraphExportMaxNodes = 5000
+
+// ParseGraphExportFormat validates a format name
+func ParseGraphExportFormat(name string) (models.GraphExportFormat, error) {
+	switch format := models.GraphExportFormat(strings.ToLower(name)); format {
+	case models.GraphExportGraphML, models.GraphExportGEXF, models.GraphExportCytoscape:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported graph export format %q: use graphml, gexf or cytoscape", name)
+	}
+}
+
+// ExportGraph extracts the subgraph and encodes it
+func (s *graphExportService) ExportGraph(ctx context.Context, w io.Writer, format models.GraphExportFormat, filter models.GraphExportFilter) (*models.GraphExportStats, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("graph_export", time.Since(start), 1)
+	}()
+
+	if _, err := ParseGraphExportFormat(string(format)); err != nil {
+		return nil, err
+	}
+
+	graph, truncated, err := s.extractSubgraph(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encodeGraph(w, format, graph); err != nil {
+		return nil, err
+	}
+
+	return &models.GraphExportStats{
+		Format:    format,
+		NodeCount: len(graph.Nodes),
+		EdgeCount: len(graph.Edges),
+		Truncated: truncated,
+	}, nil
+}
+
+// extractSubgraph expands breadth-first from the seed nodes up to filter.Depth edges away
+func (s *graphExportService) extractSubgraph(ctx context.Context, filter models.GraphExportFilter) (*models.GraphResult, bool, error) {
+	maxNodes := filter.MaxNodes
+	if maxNodes <= 0 {
+		maxNodes = defaultGraphExportMaxNodes
+	}
+
+	seeds, err := s.seedNodeIDs(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+	if seeds == nil {
+		return s.wholeGraph(ctx, filter, maxNodes)
+	}
+	if len(seeds) == 0 {
+		return &models.GraphResult{Nodes: []models.GraphNode{}, Edges: []models.GraphEdge{}}, false, nil
+	}
+
+	depth := filter.Depth
+	if depth <= 0 {
+		depth = 1
+	}
+
+	included := make(map[string]bool)
+	seedSet := make(map[string]bool)
+	for _, id := range seeds {
+		included[id] = true
+		seedSet[id] = true
+	}
+	truncated := len(seeds) > maxNodes
+	if truncated {
+		seeds = seeds[:maxNodes]
+	}
+
+	frontier := seeds
+	for level := 0; level < depth && len(frontier) > 0 && !truncated; level++ {
+		edges, err := s.queryEdges(ctx, `(source_node_id = ANY($1) OR target_node_id = ANY($1))`, frontier, filter)
+		if err != nil {
+			return nil, false, err
+		}
+
+		var next []string
+		for _, edge := range edges {
+			for _, id := range []string{edge.SourceNodeID, edge.TargetNodeID} {
+				if included[id] {
+					continue
+				}
+				if len(included) >= maxNodes {
+					truncated = true
+					break
+				}
+				included[id] = true
+				next = append(next, id)
+			}
+		}
+		frontier = next
+	}
+
+	ids := make([]string, 0, len(included))
+	for id := range included {
+		ids = append(ids, id)
+	}
+
+	nodes, err := s.queryNodes(ctx, `id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, false, err
+	}
+	nodes = filterNodesByType(nodes, filter.EntityTypes, seedSet)
+	nodes = filterNodesByConfidence(nodes, filter.MinConfidence, seedSet)
+
+	edges, err := s.queryEdges(ctx, `(source_node_id = ANY($1) AND target_node_id = ANY($1))`, nodeIDs(nodes), filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &models.GraphResult{Nodes: nodes, Edges: edges}, truncated, nil
+}
+
+// wholeGraph exports every node up to maxNodes along with the edges between them
+func (s *graphExportService) wholeGraph(ctx context.Context, filter models.GraphExportFilter, maxNodes int) (*models.GraphResult, bool, error) {
+	nodes, err := s.queryNodes(ctx, `TRUE ORDER BY created_at, id LIMIT $1`, maxNodes+1)
+	if err != nil {
+		return nil, false, err
+	}
+	truncated := len(nodes) > maxNodes
+	if truncated {
+		nodes = nodes[:maxNodes]
+	}
+	nodes = filterNodesByType(nodes, filter.EntityTypes, nil)
+	nodes = filterNodesByConfidence(nodes, filter.MinConfidence, nil)
+
+	edges, err := s.queryEdges(ctx, `(source_node_id = ANY($1) AND target_node_id = ANY($1))`, nodeIDs(nodes), filter)
+	if err != nil {
+		return nil, false, err
+	}
+	return &models.GraphResult{Nodes: nodes, Edges: edges}, truncated, nil
+}
+
+// seedNodeIDs resolves the filter's seeds; nil means no seed was given
+func (s *graphExportService) seedNodeIDs(ctx context.Context, filter models.GraphExportFilter) ([]string, error) {
+	var conditions []string
+	var args []interface{}
+	if filter.NodeID != "" {
+		args = append(args, filter.NodeID)
+		conditions = append(conditions, fmt.Sprintf("id = $%d", len(args)))
+	}
+	if filter.EntityName != "" {
+		args = append(args, filter.EntityName)
+		conditions = append(conditions, fmt.Sprintf("LOWER(entity_name) = LOWER($%d)", len(args)))
+	}
+	if filter.PageID != "" {
+		args = append(args, filter.PageID)
+		conditions = append(conditions, fmt.Sprintf(
+			"chunk_id IN (SELECT chunk_id FROM chunks WHERE chunk_id = $%d OR page = $%d)", len(args), len(args)))
+	}
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM graph_nodes WHERE `+strings.Join(conditions, " OR ")+` ORDER BY id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find seed nodes: %w", err)
+	}
+	defer rows.Close()
+
+	seeds := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan seed node: %w", err)
+		}
+		seeds = append(seeds, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating seed nodes: %w", err)
+	}
+	return seeds, nil
+}
+
+// queryNodes loads graph nodes matching a WHERE clause
+func (s *graphExportService) queryNodes(ctx context.Context, where string, args ...interface{}) ([]models.GraphNode, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, chunk_id, entity_name, entity_type, properties, created_at,
+			source_start, source_end, confidence
+		FROM graph_nodes
+		WHERE `+where, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := []models.GraphNode{}
+	for rows.Next() {
+		var node models.GraphNode
+		var propertiesBytes []byte
+		if err := rows.Scan(&node.ID, &node.ChunkID, &node.EntityName, &node.EntityType, &propertiesBytes, &node.CreatedAt,
+			&node.SourceStart, &node.SourceEnd, &node.Confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		node.Properties = parseGraphProperties(propertiesBytes, node.ID)
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph nodes: %w", err)
+	}
+	return nodes, nil
+}
+
+// queryEdges loads edges touching nodeIDs as described by condition, optionally only of
+// the filter's types and confidence and holding at its as-of time
+func (s *graphExportService) queryEdges(ctx context.Context, condition string, nodeIDs []string, filter models.GraphExportFilter) ([]models.GraphEdge, error) {
+	query := `
+		SELECT id, source_node_id, target_node_id, relationship_type, properties, created_at,
+			source_chunk_id, source_start, source_end, confidence, valid_from, valid_to
+		FROM graph_edges
+		WHERE ` + condition
+	args := []interface{}{pq.Array(nodeIDs)}
+	if len(filter.RelationshipTypes) > 0 {
+		args = append(args, pq.Array(filter.RelationshipTypes))
+		query += fmt.Sprintf(" AND relationship_type = ANY($%d)", len(args))
+	}
+	if filter.MinConfidence > 0 {
+		args = append(args, filter.MinConfidence)
+		query += fmt.Sprintf(" AND COALESCE(confidence, 1) >= $%d", len(args))
+	}
+	if filter.AsOf != nil {
+		args = append(args, *filter.AsOf)
+		query += fmt.Sprintf(" AND (valid_from IS NULL OR valid_from <= $%[1]d) AND (valid_to IS NULL OR valid_to > $%[1]d)", len(args))
+	}
+	query += " ORDER BY created_at, id"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph edges: %w", err)
+	}
+	defer rows.Close()
+
+	edges := []models.GraphEdge{}
+	for rows.Next() {
+		var edge models.GraphEdge
+		var propertiesBytes []byte
+		if err := rows.Scan(&edge.ID, &edge.SourceNodeID, &edge.TargetNodeID, &edge.RelationshipType, &propertiesBytes, &edge.CreatedAt,
+			&edge.SourceChunkID, &edge.SourceStart, &edge.SourceEnd, &edge.Confidence, &edge.ValidFrom, &edge.ValidTo); err != nil {
+			return nil, fmt.Errorf("failed to scan graph edge: %w", err)
+		}
+		edge.Properties = parseGraphProperties(propertiesBytes, edge.ID)
+		edges = append(edges, edge)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph edges: %w", err)
+	}
+	return edges, nil
+}
+
+// filterNodesByType keeps nodes of the given types plus any kept IDs
+func filterNodesByType(nodes []models.GraphNode, entityTypes []string, keep map[string]bool) []models.GraphNode {
+	if len(entityTypes) == 0 {
+		return nodes
+	}
+	allowed := make(map[string]bool, len(entityTypes))
+	for _, entityType := range entityTypes {
+		allowed[entityType] = true
+	}
+
+	filtered := nodes[:0]
+	for _, node := range nodes {
+		if allowed[node.EntityType] || keep[node.ID] {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+// filterNodesByConfidence keeps nodes extracted with at least minConfidence plus any kept IDs
+func filterNodesByConfidence(nodes []models.GraphNode, minConfidence float64, keep map[string]bool) []models.GraphNode {
+	if minConfidence <= 0 {
+		return nodes
+	}
+	filtered := nodes[:0]
+	for _, node := range nodes {
+		if node.ExtractionConfidence() >= minConfidence || keep[node.ID] {
+			filtered = append(filtered, node)
+		}
+	}
+	return filtered
+}
+
+func nodeIDs(nodes []models.GraphNode) []string {
+	ids := make([]string, len(nodes))
+	for i, node := range nodes {
+		ids[i] = node.ID
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func parseGraphProperties(data []byte, id string) map[string]interface{} {
+	properties := make(map[string]interface{})
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &properties); err != nil {
+			log.Printf("Warning: failed to parse graph properties for %s: %v", id, err)
+		}
+	}
+	return properties
+}
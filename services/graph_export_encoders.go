@@ -0,0 +1,395 @@
+package services
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"semantic-text-processor/models"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// encodeGraph writes graph in the given interchange format
+func encodeGraph(w io.Writer, format models.GraphExportFormat, graph *models.GraphResult) error {
+	switch format {
+	case models.GraphExportGraphML:
+		return encodeGraphML(w, graph)
+	case models.GraphExportGEXF:
+		return encodeGEXF(w, graph)
+	case models.GraphExportCytoscape:
+		return encodeCytoscape(w, graph)
+	default:
+		return fmt.Errorf("unsupported graph export format %q", format)
+	}
+}
+
+// graphAttribute is a node or edge property declared in the export schema
+type graphAttribute struct {
+	name string
+	kind string // "string", "double" or "boolean"
+}
+
+// collectGraphAttributes declares every property found in properties, sorted by name. A
+// property is typed double or boolean only if every value has that type. Names in reserved
+// are left out because the exporter writes them itself.
+func collectGraphAttributes(properties []map[string]interface{}, reserved ...string) []graphAttribute {
+	skip := make(map[string]bool, len(reserved))
+	for _, name := range reserved {
+		skip[name] = true
+	}
+
+	kinds := make(map[string]string)
+	for _, props := range properties {
+		for name, value := range props {
+			if skip[name] || value == nil {
+				continue
+			}
+			kind := graphValueKind(value)
+			if existing, ok := kinds[name]; ok && existing != kind {
+				kind = "string"
+			}
+			kinds[name] = kind
+		}
+	}
+
+	attributes := make([]graphAttribute, 0, len(kinds))
+	for name, kind := range kinds {
+		attributes = append(attributes, graphAttribute{name: name, kind: kind})
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].name < attributes[j].name })
+	return attributes
+}
+
+func graphValueKind(value interface{}) string {
+	switch value.(type) {
+	case float64, float32, int, int64:
+		return "double"
+	case bool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
+
+// formatGraphValue renders a property value as text; nested values become JSON
+func formatGraphValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+func nodeProperties(nodes []models.GraphNode) []map[string]interface{} {
+	properties := make([]map[string]interface{}, len(nodes))
+	for i, node := range nodes {
+		properties[i] = node.Properties
+	}
+	return properties
+}
+
+func edgeProperties(edges []models.GraphEdge) []map[string]interface{} {
+	properties := make([]map[string]interface{}, len(edges))
+	for i, edge := range edges {
+		properties[i] = edge.Properties
+	}
+	return properties
+}
+
+// ============================================================================
+// GRAPHML
+// ============================================================================
+
+type graphMLDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+type graphMLKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphMLGraph struct {
+	ID          string           `xml:"id,attr"`
+	EdgeDefault string           `xml:"edgedefault,attr"`
+	Nodes       []graphMLElement `xml:"node"`
+	Edges       []graphMLElement `xml:"edge"`
+}
+
+type graphMLElement struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr,omitempty"`
+	Target string        `xml:"target,attr,omitempty"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+func encodeGraphML(w io.Writer, graph *models.GraphResult) error {
+	nodeAttributes := collectGraphAttributes(nodeProperties(graph.Nodes), "label", "entity_type", "chunk_id")
+	edgeAttributes := collectGraphAttributes(edgeProperties(graph.Edges), "relationship_type")
+
+	doc := graphMLDocument{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []graphMLKey{
+			{ID: "label", For: "node", Name: "label", Type: "string"},
+			{ID: "entity_type", For: "node", Name: "entity_type", Type: "string"},
+			{ID: "chunk_id", For: "node", Name: "chunk_id", Type: "string"},
+			{ID: "relationship_type", For: "edge", Name: "relationship_type", Type: "string"},
+		},
+		Graph: graphMLGraph{ID: "G", EdgeDefault: "directed"},
+	}
+	for i, attribute := range nodeAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: fmt.Sprintf("n%d", i), For: "node", Name: attribute.name, Type: attribute.kind})
+	}
+	for i, attribute := range edgeAttributes {
+		doc.Keys = append(doc.Keys, graphMLKey{ID: fmt.Sprintf("e%d", i), For: "edge", Name: attribute.name, Type: attribute.kind})
+	}
+
+	for _, node := range graph.Nodes {
+		element := graphMLElement{ID: node.ID, Data: []graphMLData{
+			{Key: "label", Value: node.EntityName},
+			{Key: "entity_type", Value: node.EntityType},
+			{Key: "chunk_id", Value: node.ChunkID},
+		}}
+		for i, attribute := range nodeAttributes {
+			if value, ok := node.Properties[attribute.name]; ok && value != nil {
+				element.Data = append(element.Data, graphMLData{Key: fmt.Sprintf("n%d", i), Value: formatGraphValue(value)})
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, element)
+	}
+
+	for _, edge := range graph.Edges {
+		element := graphMLElement{ID: edge.ID, Source: edge.SourceNodeID, Target: edge.TargetNodeID, Data: []graphMLData{
+			{Key: "relationship_type", Value: edge.RelationshipType},
+		}}
+		for i, attribute := range edgeAttributes {
+			if value, ok := edge.Properties[attribute.name]; ok && value != nil {
+				element.Data = append(element.Data, graphMLData{Key: fmt.Sprintf("e%d", i), Value: formatGraphValue(value)})
+			}
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, element)
+	}
+
+	return writeXMLDocument(w, doc)
+}
+
+// ============================================================================
+// GEXF
+// ============================================================================
+
+type gexfDocument struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfMeta struct {
+	LastModified string `xml:"lastmodifieddate,attr"`
+	Creator      string `xml:"creator"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Mode            string           `xml:"mode,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfElement    `xml:"nodes>node"`
+	Edges           []gexfElement    `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfElement struct {
+	ID        string         `xml:"id,attr"`
+	Source    string         `xml:"source,attr,omitempty"`
+	Target    string         `xml:"target,attr,omitempty"`
+	Label     string         `xml:"label,attr,omitempty"`
+	Weight    string         `xml:"weight,attr,omitempty"`
+	AttValues []gexfAttValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func encodeGEXF(w io.Writer, graph *models.GraphResult) error {
+	nodeAttributes := append([]graphAttribute{{name: "entity_type", kind: "string"}, {name: "chunk_id", kind: "string"}},
+		collectGraphAttributes(nodeProperties(graph.Nodes), "entity_type", "chunk_id")...)
+	edgeAttributes := append([]graphAttribute{{name: "relationship_type", kind: "string"}},
+		collectGraphAttributes(edgeProperties(graph.Edges), "relationship_type")...)
+
+	doc := gexfDocument{
+		Xmlns:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Meta:    gexfMeta{LastModified: time.Now().UTC().Format("2006-01-02"), Creator: "ink-gateway"},
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Mode:            "static",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: gexfAttributeList(nodeAttributes)},
+				{Class: "edge", Attributes: gexfAttributeList(edgeAttributes)},
+			},
+		},
+	}
+
+	for _, node := range graph.Nodes {
+		values := map[string]interface{}{"entity_type": node.EntityType, "chunk_id": node.ChunkID}
+		for name, value := range node.Properties {
+			if name != "entity_type" && name != "chunk_id" {
+				values[name] = value
+			}
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, gexfElement{
+			ID:        node.ID,
+			Label:     node.EntityName,
+			AttValues: gexfValues(nodeAttributes, values),
+		})
+	}
+
+	for _, edge := range graph.Edges {
+		values := map[string]interface{}{"relationship_type": edge.RelationshipType}
+		for name, value := range edge.Properties {
+			if name != "relationship_type" {
+				values[name] = value
+			}
+		}
+		element := gexfElement{
+			ID:        edge.ID,
+			Source:    edge.SourceNodeID,
+			Target:    edge.TargetNodeID,
+			Label:     edge.RelationshipType,
+			AttValues: gexfValues(edgeAttributes, values),
+		}
+		if weight, ok := edge.Properties["weight"].(float64); ok {
+			element.Weight = formatGraphValue(weight)
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, element)
+	}
+
+	return writeXMLDocument(w, doc)
+}
+
+func gexfAttributeList(attributes []graphAttribute) []gexfAttribute {
+	list := make([]gexfAttribute, len(attributes))
+	for i, attribute := range attributes {
+		list[i] = gexfAttribute{ID: strconv.Itoa(i), Title: attribute.name, Type: attribute.kind}
+	}
+	return list
+}
+
+func gexfValues(attributes []graphAttribute, values map[string]interface{}) []gexfAttValue {
+	var attValues []gexfAttValue
+	for i, attribute := range attributes {
+		if value, ok := values[attribute.name]; ok && value != nil {
+			attValues = append(attValues, gexfAttValue{For: strconv.Itoa(i), Value: formatGraphValue(value)})
+		}
+	}
+	return attValues
+}
+
+func writeXMLDocument(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write graph export: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode graph export: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write graph export: %w", err)
+	}
+	return nil
+}
+
+// ============================================================================
+// CYTOSCAPE JSON
+// ============================================================================
+
+// encodeCytoscape writes the Cytoscape.js elements JSON that Cytoscape desktop also imports.
+// Properties are flattened into each element's data; names clashing with the element's own
+// fields are prefixed with "property_".
+func encodeCytoscape(w io.Writer, graph *models.GraphResult) error {
+	nodes := make([]map[string]interface{}, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		data := map[string]interface{}{
+			"id":          node.ID,
+			"name":        node.EntityName,
+			"entity_type": node.EntityType,
+			"chunk_id":    node.ChunkID,
+		}
+		mergeCytoscapeData(data, node.Properties)
+		nodes = append(nodes, map[string]interface{}{"data": data})
+	}
+
+	edges := make([]map[string]interface{}, 0, len(graph.Edges))
+	for _, edge := range graph.Edges {
+		data := map[string]interface{}{
+			"id":          edge.ID,
+			"source":      edge.SourceNodeID,
+			"target":      edge.TargetNodeID,
+			"interaction": edge.RelationshipType,
+		}
+		mergeCytoscapeData(data, edge.Properties)
+		edges = append(edges, map[string]interface{}{"data": data})
+	}
+
+	doc := map[string]interface{}{
+		"format_version":             "1.0",
+		"generated_by":               "ink-gateway",
+		"target_cytoscapejs_version": "~3",
+		"data":                       map[string]interface{}{"name": "ink-gateway knowledge graph"},
+		"elements": map[string]interface{}{
+			"nodes": nodes,
+			"edges": edges,
+		},
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode graph export: %w", err)
+	}
+	return nil
+}
+
+func mergeCytoscapeData(data map[string]interface{}, properties map[string]interface{}) {
+	for name, value := range properties {
+		if _, reserved := data[name]; reserved {
+			name = "property_" + name
+		}
+		data[name] = value
+	}
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportTestGraph() *models.GraphResult {
+	return &models.GraphResult{
+		Nodes: []models.GraphNode{
+			{ID: "n1", ChunkID: "c1", EntityName: "Go", EntityType: "language",
+				Properties: map[string]interface{}{"pagerank": 0.4, "stable": true, "aliases": []interface{}{"golang"}}},
+			{ID: "n2", ChunkID: "c2", EntityName: "Gopher & Co", EntityType: "mascot",
+				Properties: map[string]interface{}{"pagerank": "high", "id": "external-7"}},
+		},
+		Edges: []models.GraphEdge{
+			{ID: "e1", SourceNodeID: "n2", TargetNodeID: "n1", RelationshipType: "represents",
+				Properties: map[string]interface{}{"weight": 2.5}},
+		},
+	}
+}
+
+func TestParseGraphExportFormat(t *testing.T) {
+	format, err := ParseGraphExportFormat("GraphML")
+	require.NoError(t, err)
+	assert.Equal(t, models.GraphExportGraphML, format)
+
+	_, err = ParseGraphExportFormat("dot")
+	assert.Error(t, err)
+}
+
+func TestGraphExport_CollectAttributes(t *testing.T) {
+	graph := exportTestGraph()
+	attributes := collectGraphAttributes(nodeProperties(graph.Nodes), "id")
+
+	assert.Equal(t, []graphAttribute{
+		{name: "aliases", kind: "string"},
+		{name: "pagerank", kind: "string"},
+		{name: "stable", kind: "boolean"},
+	}, attributes, "mixed types fall back to string and reserved names are skipped")
+	assert.Equal(t, `["golang"]`, formatGraphValue([]interface{}{"golang"}))
+	assert.Equal(t, "0.4", formatGraphValue(0.4))
+}
+
+func TestGraphExport_GraphML(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, encodeGraph(&buf, models.GraphExportGraphML, exportTestGraph()))
+
+	var doc graphMLDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "directed", doc.Graph.EdgeDefault)
+	require.Len(t, doc.Graph.Nodes, 2)
+	require.Len(t, doc.Graph.Edges, 1)
+
+	keyNames := make(map[string]string)
+	for _, key := range doc.Keys {
+		keyNames[key.ID] = key.Name
+	}
+	values := make(map[string]string)
+	for _, data := range doc.Graph.Nodes[1].Data {
+		values[keyNames[data.Key]] = data.Value
+	}
+	assert.Equal(t, "Gopher & Co", values["label"])
+	assert.Equal(t, "mascot", values["entity_type"])
+	assert.Equal(t, "external-7", values["id"])
+
+	edge := doc.Graph.Edges[0]
+	assert.Equal(t, "n2", edge.Source)
+	assert.Equal(t, "n1", edge.Target)
+	assert.Contains(t, edge.Data, graphMLData{Key: "relationship_type", Value: "represents"})
+}
+
+func TestGraphExport_GEXF(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, encodeGraph(&buf, models.GraphExportGEXF, exportTestGraph()))
+
+	var doc gexfDocument
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "1.3", doc.Version)
+	assert.Equal(t, "ink-gateway", doc.Meta.Creator)
+	require.Len(t, doc.Graph.Attributes, 2)
+	require.Len(t, doc.Graph.Nodes, 2)
+	assert.Equal(t, "Go", doc.Graph.Nodes[0].Label)
+	assert.Contains(t, doc.Graph.Nodes[0].AttValues, gexfAttValue{For: "0", Value: "language"})
+
+	require.Len(t, doc.Graph.Edges, 1)
+	assert.Equal(t, "represents", doc.Graph.Edges[0].Label)
+	assert.Equal(t, "2.5", doc.Graph.Edges[0].Weight)
+}
+
+func TestGraphExport_Cytoscape(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, encodeGraph(&buf, models.GraphExportCytoscape, exportTestGraph()))
+
+	var doc struct {
+		Elements struct {
+			Nodes []struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"nodes"`
+			Edges []struct {
+				Data map[string]interface{} `json:"data"`
+			} `json:"edges"`
+		} `json:"elements"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Elements.Nodes, 2)
+
+	node := doc.Elements.Nodes[1].Data
+	assert.Equal(t, "n2", node["id"])
+	assert.Equal(t, "external-7", node["property_id"], "colliding properties are prefixed")
+	assert.Equal(t, "Gopher & Co", node["name"])
+
+	require.Len(t, doc.Elements.Edges, 1)
+	edge := doc.Elements.Edges[0].Data
+	assert.Equal(t, "represents", edge["interaction"])
+	assert.Equal(t, 2.5, edge["weight"])
+}
+
+func TestGraphExport_UnsupportedFormat(t *testing.T) {
+	var buf bytes.Buffer
+	assert.Error(t, encodeGraph(&buf, "dot", exportTestGraph()))
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"semantic-text-processor/models"
+)
+
+// ErrGraphEdgeNotFound is returned for evidence of an edge that does not exist
+var ErrGraphEdgeNotFound = errors.New("graph edge not found")
+
+const (
+	// maxEvidenceSnippets bounds the snippets returned for one relationship
+	maxEvidenceSnippets = 50
+	// maxSentenceContext is how far a span is widened, in characters, looking for the
+	// ends of its sentences
+	maxSentenceContext = 200
+)
+
+// GraphEvidenceService returns the text the knowledge graph was extracted from
+type GraphEvidenceService interface {
+	// GetEvidence returns a snippet for every sighting of the edge's relationship: the edge
+	// itself and the edges of the same type between nodes with the same entity names
+	GetEvidence(ctx context.Context, edgeID string) (*models.GraphEvidence, error)
+}
+
+// graphEvidenceService implements GraphEvidenceService on PostgreSQL
+type graphEvidenceService struct {
+	db       *sql.DB
+	pageACLs PageACLService
+	monitor  QueryPerformanceMonitor
+}
+
+// NewGraphEvidenceService creates a graph evidence service. pageACLs, when set, leaves out
+// snippets from pages the caller may not read.
+func NewGraphEvidenceService(db *sql.DB, pageACLs PageACLService, monitor QueryPerformanceMonitor) GraphEvidenceService {
+	return &graphEvidenceService{db: db, pageACLs: pageACLs, monitor: monitor}
+}
+
+// GetEvidence reads the sightings from the chunks' current contents. Spans that no longer
+// fit the contents, or no longer cover the entity, are found again by name; sensitive
+// chunks are left out.
+func (s *graphEvidenceService) GetEvidence(ctx context.Context, edgeID string) (*models.GraphEvidence, error) {
+	start := time.Now()
+
+	evidence := &models.GraphEvidence{EdgeID: edgeID, Snippets: []models.EvidenceSnippet{}}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT e.relationship_type, s.entity_name, t.entity_name
+		FROM graph_edges e
+		JOIN graph_nodes s ON s.id = e.source_node_id
+		JOIN graph_nodes t ON t.id = e.target_node_id
+		WHERE e.id = $1`, edgeID).Scan(&evidence.RelationshipType, &evidence.SourceEntity, &evidence.TargetEntity)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrGraphEdgeNotFound, edgeID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph edge: %w", err)
+	}
+
+	// Co-occurrence is symmetric, so its sightings may name the entities either way round
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT e.id, c.chunk_id, c.contents, c.metadata, e.source_start, e.source_end,
+			COALESCE(e.confidence, (e.properties->>'confidence')::double precision, 1) AS confidence
+		FROM graph_edges e
+		JOIN graph_nodes s ON s.id = e.source_node_id
+		JOIN graph_nodes t ON t.id = e.target_node_id
+		JOIN chunks c ON c.chunk_id = e.source_chunk_id
+		WHERE e.relationship_type = $1
+			AND ((lower(s.entity_name) = lower($2) AND lower(t.entity_name) = lower($3))
+				OR ($1 = 'co_occurs_with' AND lower(s.entity_name) = lower($3) AND lower(t.entity_name) = lower($2)))
+		ORDER BY e.id = $4 DESC, confidence DESC, e.created_at DESC, e.id
+		LIMIT $5`,
+		evidence.RelationshipType, evidence.SourceEntity, evidence.TargetEntity, edgeID, maxEvidenceSnippets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph evidence: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var snippet models.EvidenceSnippet
+		var contents string
+		var metadata []byte
+		var spanStart, spanEnd sql.NullInt64
+		if err := rows.Scan(&snippet.EdgeID, &snippet.ChunkID, &contents, &metadata, &spanStart, &spanEnd, &snippet.Confidence); err != nil {
+			return nil, fmt.Errorf("failed to scan graph evidence: %w", err)
+		}
+		if IsEncryptedContent(contents) || IsSensitive(parseGraphProperties(metadata, snippet.ChunkID)) {
+			continue
+		}
+
+		text := []rune(contents)
+		snippet.Start, snippet.End = -1, -1
+		if spanStart.Valid && spanEnd.Valid {
+			snippet.Start, snippet.End = int(spanStart.Int64), int(spanEnd.Int64)
+		}
+		if !spanMentions(text, snippet.Start, snippet.End, evidence.TargetEntity) {
+			var found bool
+			if snippet.Start, snippet.End, found = evidenceSpan(text, evidence); !found {
+				continue
+			}
+		}
+		snippet.Text = string(text[snippet.Start:snippet.End])
+		evidence.Snippets = append(evidence.Snippets, snippet)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph evidence: %w", err)
+	}
+
+	if s.pageACLs != nil && len(evidence.Snippets) > 0 {
+		if evidence.Snippets, err = s.filterReadable(ctx, evidence.Snippets); err != nil {
+			return nil, err
+		}
+	}
+
+	s.monitor.RecordQuery("graph_evidence", time.Since(start), len(evidence.Snippets))
+	return evidence, nil
+}
+
+// filterReadable drops the snippets from pages the caller may not read
+func (s *graphEvidenceService) filterReadable(ctx context.Context, snippets []models.EvidenceSnippet) ([]models.EvidenceSnippet, error) {
+	chunkIDs := make([]string, len(snippets))
+	for i, snippet := range snippets {
+		chunkIDs[i] = snippet.ChunkID
+	}
+	readable, err := s.pageACLs.FilterChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter graph evidence: %w", err)
+	}
+	allowed := make(map[string]bool, len(readable))
+	for _, chunkID := range readable {
+		allowed[chunkID] = true
+	}
+	filtered := make([]models.EvidenceSnippet, 0, len(readable))
+	for _, snippet := range snippets {
+		if allowed[snippet.ChunkID] {
+			filtered = append(filtered, snippet)
+		}
+	}
+	return filtered, nil
+}
+
+// evidenceSpan finds a relationship's text by entity name: the sentences mentioning both
+// entities, or for hierarchy edges the child's mention
+func evidenceSpan(text []rune, evidence *models.GraphEvidence) (int, int, bool) {
+	targetStart := indexFold(text, []rune(evidence.TargetEntity), 0)
+	if targetStart < 0 {
+		return 0, 0, false
+	}
+	targetEnd := targetStart + len([]rune(evidence.TargetEntity))
+	if evidence.RelationshipType != "co_occurs_with" {
+		start, end := sentenceSpan(text, targetStart, targetEnd)
+		return start, end, true
+	}
+	sourceStart := indexFold(text, []rune(evidence.SourceEntity), 0)
+	if sourceStart < 0 {
+		return 0, 0, false
+	}
+	sourceEnd := sourceStart + len([]rune(evidence.SourceEntity))
+	start, end := sentenceSpan(text, min(sourceStart, targetStart), max(sourceEnd, targetEnd))
+	return start, end, true
+}
+
+// spanMentions reports whether [start, end) lies within text and still mentions name
+func spanMentions(text []rune, start, end int, name string) bool {
+	if start < 0 || end > len(text) || start >= end {
+		return false
+	}
+	return indexFold(text[start:end], []rune(name), 0) >= 0
+}
+
+// locateMentions gives each node the span of its first mention in contents, unless it
+// already has a span that fits, and its confidence from its properties when extraction
+// reported none
+func locateMentions(contents string, nodes []models.GraphNode) {
+	text := []rune(contents)
+	for i := range nodes {
+		node := &nodes[i]
+		if node.Confidence == nil {
+			if confidence, ok := node.Properties["confidence"].(float64); ok {
+				node.Confidence = &confidence
+			}
+		}
+		if node.SourceStart != nil && node.SourceEnd != nil &&
+			*node.SourceStart >= 0 && *node.SourceStart < *node.SourceEnd && *node.SourceEnd <= len(text) {
+			continue
+		}
+		node.SourceStart, node.SourceEnd = nil, nil
+		name := []rune(strings.TrimSpace(node.EntityName))
+		if len(name) == 0 {
+			continue
+		}
+		if start := indexFold(text, name, 0); start >= 0 {
+			end := start + len(name)
+			node.SourceStart, node.SourceEnd = &start, &end
+		}
+	}
+}
+
+// cooccurrenceSpan covers the sentences from the first of two mentions to the last; nil
+// when either mention was not found
+func cooccurrenceSpan(text []rune, a, b models.GraphNode) (*int, *int) {
+	if a.SourceStart == nil || a.SourceEnd == nil || b.SourceStart == nil || b.SourceEnd == nil {
+		return nil, nil
+	}
+	if *a.SourceEnd > len(text) || *b.SourceEnd > len(text) {
+		return nil, nil
+	}
+	start, end := sentenceSpan(text, min(*a.SourceStart, *b.SourceStart), max(*a.SourceEnd, *b.SourceEnd))
+	return &start, &end
+}
+
+// sentenceSpan widens [start, end) to the sentences around it, at most maxSentenceContext
+// characters each way, without surrounding whitespace
+func sentenceSpan(text []rune, start, end int) (int, int) {
+	for limit := max(start-maxSentenceContext, 0); start > limit && !isSentenceEnd(text[start-1]); start-- {
+	}
+	for limit := min(end+maxSentenceContext, len(text)); end < limit; end++ {
+		if isSentenceEnd(text[end]) {
+			if text[end] != '\n' {
+				end++
+			}
+			break
+		}
+	}
+	for start < end && unicode.IsSpace(text[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(text[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '\n', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// indexFold is the index of the first case-insensitive match of needle in text at or after
+// from, or -1
+func indexFold(text, needle []rune, from int) int {
+	if len(needle) == 0 {
+		return -1
+	}
+	for i := from; i+len(needle) <= len(text); i++ {
+		match := true
+		for j, r := range needle {
+			if unicode.ToLower(text[i+j]) != unicode.ToLower(r) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
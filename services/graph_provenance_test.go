@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func spanText(contents string, start, end *int) string {
+	if start == nil || end == nil {
+		return ""
+	}
+	return string([]rune(contents)[*start:*end])
+}
+
+func TestLocateMentions(t *testing.T) {
+	contents := "我在台北工作。Alice met BOB in Taipei."
+	reportedStart, reportedEnd := 24, 30
+	badStart, badEnd := 30, 99
+	nodes := []models.GraphNode{
+		{EntityName: "台北"},
+		{EntityName: "bob", Properties: map[string]interface{}{"confidence": 0.9}},
+		{EntityName: "Taipei", SourceStart: &reportedStart, SourceEnd: &reportedEnd},
+		{EntityName: "Alice", SourceStart: &badStart, SourceEnd: &badEnd},
+		{EntityName: "Carol"},
+	}
+
+	locateMentions(contents, nodes)
+
+	assert.Equal(t, "台北", spanText(contents, nodes[0].SourceStart, nodes[0].SourceEnd))
+	assert.Equal(t, 2, *nodes[0].SourceStart, "offsets count characters, not bytes")
+	assert.Nil(t, nodes[0].Confidence)
+
+	assert.Equal(t, "BOB", spanText(contents, nodes[1].SourceStart, nodes[1].SourceEnd))
+	require.NotNil(t, nodes[1].Confidence)
+	assert.Equal(t, 0.9, *nodes[1].Confidence)
+
+	// A span reported by extraction is kept when it fits the contents
+	assert.Equal(t, "Taipei", spanText(contents, nodes[2].SourceStart, nodes[2].SourceEnd))
+	assert.Equal(t, "Alice", spanText(contents, nodes[3].SourceStart, nodes[3].SourceEnd))
+	assert.Nil(t, nodes[4].SourceStart)
+	assert.Nil(t, nodes[4].SourceEnd)
+}
+
+func TestSentenceSpan(t *testing.T) {
+	text := []rune("First sentence. Alice met Bob here! Then Carol left.\nNext line")
+
+	start, end := sentenceSpan(text, 16, 21)
+	assert.Equal(t, "Alice met Bob here!", string(text[start:end]))
+
+	start, end = sentenceSpan(text, 16, 46)
+	assert.Equal(t, "Alice met Bob here! Then Carol left.", string(text[start:end]))
+
+	start, end = sentenceSpan(text, 53, 57)
+	assert.Equal(t, "Next line", string(text[start:end]))
+}
+
+func TestExtractKnowledge_Provenance(t *testing.T) {
+	mockLLM := NewMockLLMService()
+	mockLLM.ExtractEntitiesFunc = func(ctx context.Context, text string) ([]models.GraphNode, error) {
+		switch text {
+		case "Alice met Bob. Much later, Carol arrived.":
+			return []models.GraphNode{
+				{EntityName: "Alice", EntityType: "PERSON"},
+				{EntityName: "Bob", EntityType: "PERSON"},
+			}, nil
+		case "Carol arrived.":
+			return []models.GraphNode{{EntityName: "Carol", EntityType: "PERSON"}}, nil
+		}
+		return nil, nil
+	}
+	processor := NewTextProcessor(mockLLM, nil)
+
+	parentID := "parent"
+	chunks := []models.ChunkRecord{
+		{ID: parentID, Content: "Alice met Bob. Much later, Carol arrived."},
+		{ID: "child", Content: "Carol arrived.", ParentChunkID: &parentID},
+	}
+	result, err := processor.ExtractKnowledge(context.Background(), chunks)
+	require.NoError(t, err)
+	require.Len(t, result.Nodes, 3)
+
+	contents := map[string]string{"parent": chunks[0].Content, "child": chunks[1].Content}
+	var cooccurrence, hierarchy int
+	for _, edge := range result.Edges {
+		require.NotNil(t, edge.SourceChunkID)
+		require.NotNil(t, edge.Confidence)
+		text := spanText(contents[*edge.SourceChunkID], edge.SourceStart, edge.SourceEnd)
+		switch edge.RelationshipType {
+		case "co_occurs_with":
+			cooccurrence++
+			assert.Equal(t, "parent", *edge.SourceChunkID)
+			assert.Equal(t, "Alice met Bob.", text)
+			assert.Equal(t, 0.7, edge.ExtractionConfidence())
+		case "contains":
+			hierarchy++
+			assert.Equal(t, "child", *edge.SourceChunkID)
+			assert.Equal(t, "Carol", text)
+			assert.Equal(t, 0.8, edge.ExtractionConfidence())
+		}
+	}
+	assert.Equal(t, 1, cooccurrence)
+	assert.Equal(t, 2, hierarchy)
+}
+
+func TestEvidenceSpan(t *testing.T) {
+	text := []rune("Intro. Bob and Alice wrote the paper. Outro.")
+
+	start, end, found := evidenceSpan(text, &models.GraphEvidence{
+		RelationshipType: "co_occurs_with", SourceEntity: "alice", TargetEntity: "Bob",
+	})
+	require.True(t, found)
+	assert.Equal(t, "Bob and Alice wrote the paper.", string(text[start:end]))
+
+	_, _, found = evidenceSpan(text, &models.GraphEvidence{
+		RelationshipType: "co_occurs_with", SourceEntity: "Alice", TargetEntity: "Carol",
+	})
+	assert.False(t, found)
+
+	assert.True(t, spanMentions(text, 7, 10, "bob"))
+	assert.False(t, spanMentions(text, 7, 10, "Alice"), "a span that no longer covers the entity is stale")
+	assert.False(t, spanMentions(text, 40, 60, "Outro"))
+}
+
+func TestExtractionConfidence(t *testing.T) {
+	stored := 0.4
+	assert.Equal(t, 0.4, models.GraphEdge{Confidence: &stored, Properties: map[string]interface{}{"confidence": 0.9}}.ExtractionConfidence())
+	assert.Equal(t, 0.9, models.GraphEdge{Properties: map[string]interface{}{"confidence": 0.9}}.ExtractionConfidence())
+	assert.Equal(t, 1.0, models.GraphNode{}.ExtractionConfidence())
+}
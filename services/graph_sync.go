@@ -0,0 +1,538 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// graphContentHashProperty records on each graph node the hash of the chunk contents it
+// was extracted from
+const graphContentHashProperty = "content_hash"
+
+const defaultGraphSyncQueue = 1000
+
+// GraphSyncService keeps knowledge graph nodes in step with the chunks they were extracted
+// from. Edits to a chunk with graph nodes queue it for re-extraction in the background;
+// deleting a chunk removes its nodes and their edges at once.
+type GraphSyncService interface {
+	// TrackChange queues a written chunk for re-extraction when its graph nodes were
+	// extracted from other contents. The nodes of a chunk that became sensitive are
+	// removed at once.
+	TrackChange(ctx context.Context, chunk *models.UnifiedChunkRecord) error
+	// TrackDelete removes the graph nodes of deleted chunks and the edges between them
+	TrackDelete(ctx context.Context, chunkIDs ...string) error
+	// Enqueue queues chunks for re-extraction and returns how many were accepted
+	Enqueue(chunkIDs ...string) int
+	// Resync re-extracts a chunk's entities and reconciles its graph nodes with them
+	Resync(ctx context.Context, chunkID string) (*models.GraphSyncResult, error)
+	// CleanupOrphans removes graph nodes whose chunk no longer exists and returns how many
+	CleanupOrphans(ctx context.Context) (int, error)
+	// Start processes the queue until Stop is called
+	Start(ctx context.Context)
+	// Stop stops background processing; queued chunks are dropped
+	Stop()
+}
+
+// graphSyncService implements GraphSyncService
+type graphSyncService struct {
+	db        *sql.DB
+	supabase  SupabaseClient
+	chunks    UnifiedChunkService
+	processor TextProcessor
+	monitor   QueryPerformanceMonitor
+
+	queue   chan string
+	mu      sync.Mutex
+	pending map[string]bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewGraphSyncService creates a graph sync service. chunks is used to read contents, so it
+// must decrypt sensitive chunks; those are never extracted.
+func NewGraphSyncService(db *sql.DB, supabase SupabaseClient, chunks UnifiedChunkService, processor TextProcessor, monitor QueryPerformanceMonitor, queueSize int) GraphSyncService {
+	if queueSize <= 0 {
+		queueSize = defaultGraphSyncQueue
+	}
+	return &graphSyncService{
+		db:        db,
+		supabase:  supabase,
+		chunks:    chunks,
+		processor: processor,
+		monitor:   monitor,
+		queue:     make(chan string, queueSize),
+		pending:   make(map[string]bool),
+	}
+}
+
+// TrackChange compares the hash each of the chunk's graph nodes was extracted from with
+// its contents. Chunks without graph nodes are not part of the graph and are left alone.
+func (s *graphSyncService) TrackChange(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if IsSensitive(chunk.Metadata) {
+		// Entity names would leak the contents of a sensitive chunk
+		return s.TrackDelete(ctx, chunk.ChunkID)
+	}
+
+	nodes, err := s.supabase.GetNodesByChunk(ctx, chunk.ChunkID)
+	if err != nil {
+		return fmt.Errorf("failed to get graph nodes of chunk: %w", err)
+	}
+	hash := contentHash(chunk.Contents)
+	for _, node := range nodes {
+		if node.Properties[graphContentHashProperty] != hash {
+			s.Enqueue(chunk.ChunkID)
+			return nil
+		}
+	}
+	return nil
+}
+
+// TrackDelete removes the nodes in one transaction
+func (s *graphSyncService) TrackDelete(ctx context.Context, chunkIDs ...string) error {
+	if len(chunkIDs) == 0 {
+		return nil
+	}
+	start := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	nodes, _, err := removeChunkGraphTx(ctx, tx, "chunk_id = ANY($1::uuid[])", pq.Array(chunkIDs))
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.monitor.RecordQuery("graph_sync_delete", time.Since(start), nodes)
+	return nil
+}
+
+// removeChunkGraphTx deletes the graph nodes matching condition and every edge touching
+// them, returning how many nodes and edges were removed
+func removeChunkGraphTx(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) (int, int, error) {
+	edges, err := tx.ExecContext(ctx, `
+		DELETE FROM graph_edges
+		WHERE source_node_id IN (SELECT id FROM graph_nodes WHERE `+condition+`)
+			OR target_node_id IN (SELECT id FROM graph_nodes WHERE `+condition+`)`, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete graph edges: %w", err)
+	}
+	nodes, err := tx.ExecContext(ctx, "DELETE FROM graph_nodes WHERE "+condition, args...)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to delete graph nodes: %w", err)
+	}
+
+	removedEdges, _ := edges.RowsAffected()
+	removedNodes, _ := nodes.RowsAffected()
+	return int(removedNodes), int(removedEdges), nil
+}
+
+// Enqueue adds chunks not already waiting. When the queue is full they are dropped; their
+// next edit queues them again.
+func (s *graphSyncService) Enqueue(chunkIDs ...string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accepted := 0
+	for _, chunkID := range chunkIDs {
+		if s.pending[chunkID] {
+			continue
+		}
+		select {
+		case s.queue <- chunkID:
+			s.pending[chunkID] = true
+			accepted++
+		default:
+			log.Printf("Warning: graph sync queue is full; dropping chunk %s", chunkID)
+		}
+	}
+	return accepted
+}
+
+// Resync extracts the chunk's current contents. A chunk that was deleted, became empty or
+// sensitive, or no longer mentions any entity loses all its nodes.
+func (s *graphSyncService) Resync(ctx context.Context, chunkID string) (*models.GraphSyncResult, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("graph_sync", time.Since(start), 1)
+	}()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", chunkID).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check chunk: %w", err)
+	}
+
+	var graph models.GraphResult
+	var hash string
+	if exists {
+		chunk, err := s.chunks.GetChunk(ctx, chunkID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chunk: %w", err)
+		}
+		hash = contentHash(chunk.Contents)
+		if !IsSensitive(chunk.Metadata) && strings.TrimSpace(chunk.Contents) != "" {
+			extracted, err := s.processor.ExtractKnowledge(ctx, []models.ChunkRecord{{ID: chunkID, Content: chunk.Contents}})
+			if err != nil {
+				return nil, err
+			}
+			graph = *extracted
+		}
+	}
+
+	return s.reconcile(ctx, chunkID, hash, graph)
+}
+
+// reconcile matches the extracted entities to the chunk's nodes by entity type and
+// normalized name. Matched nodes keep their ID, edges and properties, so analytics
+// scores and merge provenance survive edits; unmatched nodes are removed with their
+// edges and new entities are added with the extracted edges between them.
+func (s *graphSyncService) reconcile(ctx context.Context, chunkID, hash string, graph models.GraphResult) (*models.GraphSyncResult, error) {
+	result := &models.GraphSyncResult{ChunkID: chunkID}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, entity_name, entity_type FROM graph_nodes
+		WHERE chunk_id = $1 ORDER BY created_at, id FOR UPDATE`, chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock graph nodes: %w", err)
+	}
+	existing := make(map[string]string) // node key -> first node with that key
+	var current []models.GraphNode
+	for rows.Next() {
+		var node models.GraphNode
+		if err := rows.Scan(&node.ID, &node.EntityName, &node.EntityType); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		if _, ok := existing[graphNodeKey(node)]; !ok {
+			existing[graphNodeKey(node)] = node.ID
+		}
+		current = append(current, node)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to lock graph nodes: %w", err)
+	}
+
+	// Map every extracted node to the node that will represent it
+	nodeIDs := make(map[string]string, len(graph.Nodes))
+	matched := make(map[string]models.GraphNode) // node key -> extracted node
+	added := make(map[string]string)
+	var inserts []models.GraphNode
+	for _, node := range graph.Nodes {
+		key := graphNodeKey(node)
+		if id, ok := existing[key]; ok {
+			nodeIDs[node.ID] = id
+			if _, ok := matched[key]; !ok {
+				matched[key] = node
+			}
+		} else if id, ok := added[key]; ok {
+			nodeIDs[node.ID] = id
+		} else {
+			added[key] = node.ID
+			nodeIDs[node.ID] = node.ID
+			inserts = append(inserts, node)
+		}
+	}
+
+	// Kept nodes take the span and confidence of their entity in the new contents
+	var kept, removed []string
+	var keptStarts, keptEnds []sql.NullInt64
+	var keptConfidences []sql.NullFloat64
+	for _, node := range current {
+		if extracted, ok := matched[graphNodeKey(node)]; ok {
+			kept = append(kept, node.ID)
+			keptStarts = append(keptStarts, nullInt(extracted.SourceStart))
+			keptEnds = append(keptEnds, nullInt(extracted.SourceEnd))
+			keptConfidences = append(keptConfidences, nullFloat(extracted.Confidence))
+		} else {
+			removed = append(removed, node.ID)
+		}
+	}
+	result.KeptNodes = len(kept)
+
+	if len(removed) > 0 {
+		result.RemovedNodes, result.RemovedEdges, err = removeChunkGraphTx(ctx, tx, "id = ANY($1::uuid[])", pq.Array(removed))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(kept) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			UPDATE graph_nodes g
+			SET properties = COALESCE(g.properties, '{}'::jsonb) || jsonb_build_object('`+graphContentHashProperty+`', $2::text),
+				source_start = k.source_start,
+				source_end = k.source_end,
+				confidence = COALESCE(k.confidence, g.confidence)
+			FROM unnest($1::uuid[], $3::integer[], $4::integer[], $5::double precision[])
+				AS k(id, source_start, source_end, confidence)
+			WHERE g.id = k.id`,
+			pq.Array(kept), hash, pq.Array(keptStarts), pq.Array(keptEnds), pq.Array(keptConfidences))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update graph nodes: %w", err)
+		}
+	}
+
+	for _, node := range inserts {
+		properties := make(map[string]interface{}, len(node.Properties)+1)
+		for key, value := range node.Properties {
+			properties[key] = value
+		}
+		properties[graphContentHashProperty] = hash
+		propertiesJSON, err := json.Marshal(properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode graph node properties: %w", err)
+		}
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO graph_nodes (id, chunk_id, entity_name, entity_type, properties, source_start, source_end, confidence)
+			VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7, $8)`,
+			node.ID, chunkID, node.EntityName, node.EntityType, string(propertiesJSON),
+			nullInt(node.SourceStart), nullInt(node.SourceEnd), nullFloat(node.Confidence))
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert graph node: %w", err)
+		}
+	}
+	result.AddedNodes = len(inserts)
+
+	for _, edge := range graph.Edges {
+		source, target := nodeIDs[edge.SourceNodeID], nodeIDs[edge.TargetNodeID]
+		if source == "" || target == "" || source == target {
+			continue
+		}
+		propertiesJSON, err := json.Marshal(edge.Properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode graph edge properties: %w", err)
+		}
+		// Skip relationships the kept nodes already have; co-occurrence has no direction
+		inserted, err := tx.ExecContext(ctx, `
+			INSERT INTO graph_edges (id, source_node_id, target_node_id, relationship_type, properties,
+				source_chunk_id, source_start, source_end, confidence)
+			SELECT $1, $2::uuid, $3::uuid, $4::text, $5::jsonb, $6::uuid, $7::integer, $8::integer, $9::double precision
+			WHERE NOT EXISTS (
+				SELECT 1 FROM graph_edges
+				WHERE relationship_type = $4::text
+					AND ((source_node_id = $2::uuid AND target_node_id = $3::uuid)
+						OR ($4::text = 'co_occurs_with' AND source_node_id = $3::uuid AND target_node_id = $2::uuid))
+			)`,
+			edge.ID, source, target, edge.RelationshipType, string(propertiesJSON),
+			nullString(edge.SourceChunkID), nullInt(edge.SourceStart), nullInt(edge.SourceEnd), nullFloat(edge.Confidence))
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert graph edge: %w", err)
+		}
+		if n, err := inserted.RowsAffected(); err == nil {
+			result.AddedEdges += int(n)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return result, nil
+}
+
+func nullInt(value *int) sql.NullInt64 {
+	if value == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: int64(*value), Valid: true}
+}
+
+func nullFloat(value *float64) sql.NullFloat64 {
+	if value == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *value, Valid: true}
+}
+
+func nullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
+
+// graphNodeKey identifies an entity within a chunk
+func graphNodeKey(node models.GraphNode) string {
+	return node.EntityType + "\x00" + models.NormalizeEntityName(node.EntityName)
+}
+
+// CleanupOrphans catches nodes left behind by deletes made outside the service
+func (s *graphSyncService) CleanupOrphans(ctx context.Context) (int, error) {
+	start := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	nodes, _, err := removeChunkGraphTx(ctx, tx,
+		"NOT EXISTS (SELECT 1 FROM chunks c WHERE c.chunk_id = graph_nodes.chunk_id)")
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.monitor.RecordQuery("graph_sync_orphans", time.Since(start), nodes)
+	return nodes, nil
+}
+
+// Start runs the worker in the background
+func (s *graphSyncService) Start(ctx context.Context) {
+	if s.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+// Stop stops the worker and waits for the current chunk
+func (s *graphSyncService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+// run re-extracts queued chunks one at a time, each with its own LLM request
+func (s *graphSyncService) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case chunkID := <-s.queue:
+			s.mu.Lock()
+			// Writes from here on queue the chunk again
+			delete(s.pending, chunkID)
+			s.mu.Unlock()
+
+			if _, err := s.Resync(ctx, chunkID); err != nil {
+				log.Printf("Warning: failed to sync graph nodes of chunk %s: %v", chunkID, err)
+			}
+		}
+	}
+}
+
+// graphTrackingChunkService keeps graph nodes in step with chunk writes
+type graphTrackingChunkService struct {
+	UnifiedChunkService
+	graphSync GraphSyncService
+}
+
+// NewGraphTrackingChunkService wraps a UnifiedChunkService so that edits queue chunks
+// with graph nodes for re-extraction and deletes remove their nodes. Tracking failures
+// are logged and do not fail the write; orphaned nodes are removed by CleanupOrphans.
+func NewGraphTrackingChunkService(base UnifiedChunkService, graphSync GraphSyncService) UnifiedChunkService {
+	return &graphTrackingChunkService{
+		UnifiedChunkService: base,
+		graphSync:           graphSync,
+	}
+}
+
+// UpdateChunk updates a chunk and queues it if its contents drifted from its graph nodes
+func (s *graphTrackingChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.UpdateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	s.track(ctx, chunk)
+	return nil
+}
+
+// PatchChunk patches a chunk and queues it if its contents drifted from its graph nodes
+func (s *graphTrackingChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return nil, err
+	}
+	flagChanged := metadataKeyChanged(patch, SensitiveMetadataKey)
+	if patch.Contents != nil || flagChanged {
+		s.track(ctx, chunk)
+	}
+	return chunk, nil
+}
+
+// BatchUpdateChunks updates chunks and queues those whose contents drifted
+func (s *graphTrackingChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		s.track(ctx, &chunks[i])
+	}
+	return nil
+}
+
+// MergeChunks merges two chunks and queues the target, which now holds the source's
+// graph nodes and contents
+func (s *graphTrackingChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	result, err := s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if result.Chunk != nil {
+		s.track(ctx, result.Chunk)
+	}
+	return result, nil
+}
+
+// DeleteChunk deletes a chunk and its graph nodes
+func (s *graphTrackingChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
+	if err := s.UnifiedChunkService.DeleteChunk(ctx, chunkID); err != nil {
+		return err
+	}
+	s.untrack(ctx, chunkID)
+	return nil
+}
+
+// DeleteSubtree deletes a subtree and the graph nodes of its chunks
+func (s *graphTrackingChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	result, err := s.UnifiedChunkService.DeleteSubtree(ctx, chunkID, opts)
+	if err != nil {
+		return nil, err
+	}
+	if result.Deleted {
+		s.untrack(ctx, result.DeletedChunkIDs...)
+	}
+	return result, nil
+}
+
+func (s *graphTrackingChunkService) track(ctx context.Context, chunk *models.UnifiedChunkRecord) {
+	if err := s.graphSync.TrackChange(ctx, chunk); err != nil {
+		log.Printf("Warning: failed to track graph drift for chunk %s: %v", chunk.ChunkID, err)
+	}
+}
+
+func (s *graphTrackingChunkService) untrack(ctx context.Context, chunkIDs ...string) {
+	if err := s.graphSync.TrackDelete(ctx, chunkIDs...); err != nil {
+		log.Printf("Warning: failed to remove graph nodes of %d deleted chunks: %v", len(chunkIDs), err)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+// graphNodesClient serves fixed graph nodes per chunk
+type graphNodesClient struct {
+	MockSupabaseClient
+	nodes map[string][]models.GraphNode
+}
+
+func (c *graphNodesClient) GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error) {
+	return c.nodes[chunkID], nil
+}
+
+func graphNodeFrom(contents string) models.GraphNode {
+	return models.GraphNode{Properties: map[string]interface{}{graphContentHashProperty: contentHash(contents)}}
+}
+
+func TestGraphSync_TrackChange(t *testing.T) {
+	client := &graphNodesClient{nodes: map[string][]models.GraphNode{
+		"edited":    {graphNodeFrom("old text"), graphNodeFrom("new text")},
+		"unchanged": {graphNodeFrom("same text")},
+		"legacy":    {{EntityName: "extracted before hashes were recorded"}},
+	}}
+	sync := NewGraphSyncService(nil, client, nil, nil, NewNoOpMonitor(), 10).(*graphSyncService)
+	ctx := context.Background()
+
+	for chunkID, contents := range map[string]string{
+		"edited":    "new text",
+		"unchanged": "same text",
+		"legacy":    "text",
+		"no-nodes":  "text",
+	} {
+		require.NoError(t, sync.TrackChange(ctx, &models.UnifiedChunkRecord{ChunkID: chunkID, Contents: contents}))
+	}
+
+	var queued []string
+	for len(sync.queue) > 0 {
+		queued = append(queued, <-sync.queue)
+	}
+	assert.ElementsMatch(t, []string{"edited", "legacy"}, queued)
+}
+
+func TestGraphSync_Queue(t *testing.T) {
+	sync := NewGraphSyncService(nil, nil, nil, nil, NewNoOpMonitor(), 2).(*graphSyncService)
+
+	assert.Equal(t, 2, sync.Enqueue("a", "b", "a"))
+	assert.Equal(t, 0, sync.Enqueue("a"), "queued chunks are not queued twice")
+	assert.Equal(t, 0, sync.Enqueue("c"), "a full queue drops")
+
+	// Entities match across case and punctuation, but not across types
+	assert.Equal(t, graphNodeKey(models.GraphNode{EntityName: "The Acme Corp.", EntityType: "ORG"}),
+		graphNodeKey(models.GraphNode{EntityName: "acme corp", EntityType: "ORG"}))
+	assert.NotEqual(t, graphNodeKey(models.GraphNode{EntityName: "Acme", EntityType: "ORG"}),
+		graphNodeKey(models.GraphNode{EntityName: "Acme", EntityType: "PRODUCT"}))
+}
+
+// graphSyncRecorder records the chunks reported to graph sync
+type graphSyncRecorder struct {
+	GraphSyncService
+	changed []string
+	deleted []string
+}
+
+func (r *graphSyncRecorder) TrackChange(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	r.changed = append(r.changed, chunk.ChunkID)
+	return nil
+}
+
+func (r *graphSyncRecorder) TrackDelete(ctx context.Context, chunkIDs ...string) error {
+	r.deleted = append(r.deleted, chunkIDs...)
+	return nil
+}
+
+// subtreeDeleter deletes subtrees of two chunks when confirmed
+type subtreeDeleter struct {
+	writeRecorder
+}
+
+func (d *subtreeDeleter) DeleteChunk(ctx context.Context, chunkID string) error {
+	return nil
+}
+
+func (d *subtreeDeleter) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	return &models.UnifiedChunkRecord{ChunkID: chunkID}, nil
+}
+
+func (d *subtreeDeleter) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	if !opts.Confirm {
+		return &models.SubtreeDeleteResult{}, nil
+	}
+	return &models.SubtreeDeleteResult{Deleted: true, DeletedChunkIDs: []string{chunkID, chunkID + "-child"}}, nil
+}
+
+func TestGraphTrackingChunkService_ReportsWrites(t *testing.T) {
+	recorder := &graphSyncRecorder{}
+	chunks := NewGraphTrackingChunkService(&subtreeDeleter{}, recorder)
+	ctx := context.Background()
+
+	contents := "edited"
+	_, err := chunks.PatchChunk(ctx, "patched", &models.ChunkPatch{Contents: &contents})
+	require.NoError(t, err)
+	_, err = chunks.PatchChunk(ctx, "retagged", &models.ChunkPatch{Metadata: map[string]interface{}{"status": "done"}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"patched"}, recorder.changed, "metadata patches keep the graph")
+
+	require.NoError(t, chunks.DeleteChunk(ctx, "deleted"))
+	_, err = chunks.DeleteSubtree(ctx, "preview", models.SubtreeDeleteOptions{})
+	require.NoError(t, err)
+	_, err = chunks.DeleteSubtree(ctx, "root", models.SubtreeDeleteOptions{Confirm: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deleted", "root", "root-child"}, recorder.deleted)
+}
@@ -0,0 +1,348 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidEdgeValidity is returned for validity intervals that end before they start and
+// for supersessions the edge's interval does not allow
+var ErrInvalidEdgeValidity = errors.New("invalid edge validity")
+
+const (
+	defaultGraphConflicts = 100
+	// maxConflictPairs bounds the contradicting edge pairs read for one detection
+	maxConflictPairs = 10000
+)
+
+// DefaultExclusiveRelationships are the relationship types an entity holds toward one
+// target at a time, checked for conflicts unless configured otherwise
+var DefaultExclusiveRelationships = []string{
+	"works_at", "lives_in", "located_in", "headquartered_in", "married_to", "reports_to",
+}
+
+// GraphTemporalService records when knowledge graph relationships held
+type GraphTemporalService interface {
+	// SetValidity sets the interval an edge's relationship held
+	SetValidity(ctx context.Context, edgeID string, req *models.EdgeValidityRequest) (*models.GraphEdge, error)
+	// Supersede ends a relationship at a point in time and starts one of the same type
+	// from the same source toward another target
+	Supersede(ctx context.Context, edgeID string, req *models.EdgeSupersedeRequest) (*models.EdgeSupersedeResult, error)
+}
+
+// graphTemporalService implements GraphTemporalService on PostgreSQL
+type graphTemporalService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+}
+
+// NewGraphTemporalService creates a graph temporal service
+func NewGraphTemporalService(db *sql.DB, monitor QueryPerformanceMonitor) GraphTemporalService {
+	return &graphTemporalService{db: db, monitor: monitor}
+}
+
+// SetValidity replaces both ends of the interval
+func (s *graphTemporalService) SetValidity(ctx context.Context, edgeID string, req *models.EdgeValidityRequest) (*models.GraphEdge, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	if req.ValidFrom != nil && req.ValidTo != nil && !req.ValidFrom.Before(*req.ValidTo) {
+		return nil, fmt.Errorf("%w: valid_to must be after valid_from", ErrInvalidEdgeValidity)
+	}
+	start := time.Now()
+
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE graph_edges SET valid_from = $2, valid_to = $3 WHERE id = $1`,
+		edgeID, req.ValidFrom, req.ValidTo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set edge validity: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrGraphEdgeNotFound, edgeID)
+	}
+
+	edge, err := loadGraphEdge(ctx, s.db, edgeID)
+	if err != nil {
+		return nil, err
+	}
+	s.monitor.RecordQuery("graph_edge_validity", time.Since(start), 1)
+	return edge, nil
+}
+
+// Supersede closes every edge stating the relationship between the same entity names that
+// held at the time, so sightings from other chunks end too, and adds the replacement in
+// one transaction
+func (s *graphTemporalService) Supersede(ctx context.Context, edgeID string, req *models.EdgeSupersedeRequest) (*models.EdgeSupersedeResult, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	if req.TargetNodeID == "" {
+		return nil, fmt.Errorf("%w: target_node_id is required", ErrInvalidEdgeValidity)
+	}
+	at := time.Now().UTC()
+	if req.At != nil {
+		at = req.At.UTC()
+	}
+	start := time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var edge models.GraphEdge
+	var sourceName, targetName string
+	err = tx.QueryRowContext(ctx, `
+		SELECT e.source_node_id, e.target_node_id, e.relationship_type, e.valid_from, e.valid_to,
+			s.entity_name, t.entity_name
+		FROM graph_edges e
+		JOIN graph_nodes s ON s.id = e.source_node_id
+		JOIN graph_nodes t ON t.id = e.target_node_id
+		WHERE e.id = $1
+		FOR UPDATE OF e`, edgeID).Scan(
+		&edge.SourceNodeID, &edge.TargetNodeID, &edge.RelationshipType, &edge.ValidFrom, &edge.ValidTo,
+		&sourceName, &targetName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrGraphEdgeNotFound, edgeID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph edge: %w", err)
+	}
+	if !edge.ValidAt(at) {
+		return nil, fmt.Errorf("%w: the edge does not hold at %s", ErrInvalidEdgeValidity, at.Format(time.RFC3339))
+	}
+
+	var newTargetName string
+	err = tx.QueryRowContext(ctx, `SELECT entity_name FROM graph_nodes WHERE id = $1`, req.TargetNodeID).Scan(&newTargetName)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrGraphNodeNotFound, req.TargetNodeID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph node: %w", err)
+	}
+	if strings.EqualFold(newTargetName, targetName) {
+		return nil, fmt.Errorf("%w: the new target is the current one", ErrInvalidEdgeValidity)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE graph_edges e SET valid_to = $4
+		FROM graph_nodes s, graph_nodes t
+		WHERE s.id = e.source_node_id AND t.id = e.target_node_id
+			AND e.relationship_type = $1
+			AND lower(s.entity_name) = lower($2) AND lower(t.entity_name) = lower($3)
+			AND (e.valid_from IS NULL OR e.valid_from <= $4)
+			AND (e.valid_to IS NULL OR e.valid_to > $4)
+		RETURNING e.id`,
+		edge.RelationshipType, sourceName, targetName, at)
+	if err != nil {
+		return nil, fmt.Errorf("failed to close superseded edges: %w", err)
+	}
+	result := &models.EdgeSupersedeResult{ClosedEdgeIDs: []string{}}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan superseded edge: %w", err)
+		}
+		result.ClosedEdgeIDs = append(result.ClosedEdgeIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to close superseded edges: %w", err)
+	}
+	sort.Strings(result.ClosedEdgeIDs)
+
+	properties := make(map[string]interface{}, len(req.Properties)+2)
+	for key, value := range req.Properties {
+		properties[key] = value
+	}
+	properties["supersedes"] = edgeID
+	properties["source"] = "manual"
+	propertiesJSON, err := json.Marshal(properties)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode graph edge properties: %w", err)
+	}
+	confidence := 1.0
+	result.Replacement = models.GraphEdge{
+		ID:               uuid.New().String(),
+		SourceNodeID:     edge.SourceNodeID,
+		TargetNodeID:     req.TargetNodeID,
+		RelationshipType: edge.RelationshipType,
+		Properties:       properties,
+		Confidence:       &confidence,
+		ValidFrom:        &at,
+	}
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO graph_edges (id, source_node_id, target_node_id, relationship_type, properties, confidence, valid_from)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6, $7)
+		RETURNING created_at`,
+		result.Replacement.ID, edge.SourceNodeID, req.TargetNodeID, edge.RelationshipType,
+		string(propertiesJSON), confidence, at).Scan(&result.Replacement.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert replacement edge: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	s.monitor.RecordQuery("graph_edge_supersede", time.Since(start), len(result.ClosedEdgeIDs)+1)
+	return result, nil
+}
+
+// loadGraphEdge reads one edge with its provenance and validity
+func loadGraphEdge(ctx context.Context, db *sql.DB, edgeID string) (*models.GraphEdge, error) {
+	var edge models.GraphEdge
+	var propertiesBytes []byte
+	err := db.QueryRowContext(ctx, `
+		SELECT id, source_node_id, target_node_id, relationship_type, properties, created_at,
+			source_chunk_id, source_start, source_end, confidence, valid_from, valid_to
+		FROM graph_edges WHERE id = $1`, edgeID).Scan(
+		&edge.ID, &edge.SourceNodeID, &edge.TargetNodeID, &edge.RelationshipType, &propertiesBytes, &edge.CreatedAt,
+		&edge.SourceChunkID, &edge.SourceStart, &edge.SourceEnd, &edge.Confidence, &edge.ValidFrom, &edge.ValidTo)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("%w: %s", ErrGraphEdgeNotFound, edgeID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get graph edge: %w", err)
+	}
+	edge.Properties = parseGraphProperties(propertiesBytes, edge.ID)
+	return &edge, nil
+}
+
+// ConflictDetector flags contradictory concurrent edges: an entity holding an exclusive
+// relationship, such as works_at, toward two differently named targets over overlapping
+// intervals. Extraction creates nodes per chunk, so entities are compared by name.
+type ConflictDetector struct {
+	db                *sql.DB
+	relationshipTypes []string
+	monitor           QueryPerformanceMonitor
+}
+
+// NewConflictDetector creates a conflict detector for the given exclusive relationship
+// types, or DefaultExclusiveRelationships when there are none
+func NewConflictDetector(db *sql.DB, relationshipTypes []string, monitor QueryPerformanceMonitor) *ConflictDetector {
+	if len(relationshipTypes) == 0 {
+		relationshipTypes = DefaultExclusiveRelationships
+	}
+	return &ConflictDetector{db: db, relationshipTypes: relationshipTypes, monitor: monitor}
+}
+
+// conflictKey identifies a conflict between two targets of one source's relationship
+type conflictKey struct {
+	relationshipType, source, first, second string
+}
+
+// Detect returns the conflicts, each listing the edges stating either side, ordered by
+// relationship type and source entity
+func (d *ConflictDetector) Detect(ctx context.Context, opts models.GraphConflictOptions) ([]models.GraphEdgeConflict, error) {
+	start := time.Now()
+	relationshipTypes := opts.RelationshipTypes
+	if len(relationshipTypes) == 0 {
+		relationshipTypes = d.relationshipTypes
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultGraphConflicts
+	}
+	var asOf interface{}
+	if opts.AsOf != nil {
+		asOf = *opts.AsOf
+	}
+
+	// Each pair is read once, with the targets in name order
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT a.id, b.id, a.relationship_type, sa.entity_name, ta.entity_name, tb.entity_name,
+			GREATEST(a.valid_from, b.valid_from), LEAST(a.valid_to, b.valid_to)
+		FROM graph_edges a
+		JOIN graph_nodes sa ON sa.id = a.source_node_id
+		JOIN graph_nodes ta ON ta.id = a.target_node_id
+		JOIN graph_edges b ON b.relationship_type = a.relationship_type
+		JOIN graph_nodes sb ON sb.id = b.source_node_id AND lower(sb.entity_name) = lower(sa.entity_name)
+		JOIN graph_nodes tb ON tb.id = b.target_node_id AND lower(tb.entity_name) > lower(ta.entity_name)
+		WHERE a.relationship_type = ANY($1)
+			AND COALESCE(a.valid_from, '-infinity'::timestamptz) < COALESCE(b.valid_to, 'infinity'::timestamptz)
+			AND COALESCE(b.valid_from, '-infinity'::timestamptz) < COALESCE(a.valid_to, 'infinity'::timestamptz)
+			AND ($2::timestamptz IS NULL OR (
+				COALESCE(a.valid_from, '-infinity'::timestamptz) <= $2 AND COALESCE(a.valid_to, 'infinity'::timestamptz) > $2
+				AND COALESCE(b.valid_from, '-infinity'::timestamptz) <= $2 AND COALESCE(b.valid_to, 'infinity'::timestamptz) > $2))
+		ORDER BY a.relationship_type, lower(sa.entity_name), lower(ta.entity_name), lower(tb.entity_name), a.id, b.id
+		LIMIT $3`,
+		pq.Array(relationshipTypes), asOf, maxConflictPairs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect graph conflicts: %w", err)
+	}
+	defer rows.Close()
+
+	var conflicts []models.GraphEdgeConflict
+	index := make(map[conflictKey]int)
+	type statementEdge struct {
+		conflict, side int
+		edgeID         string
+	}
+	seen := make(map[statementEdge]bool)
+	for rows.Next() {
+		var firstEdge, secondEdge, relationshipType, source, firstTarget, secondTarget string
+		var overlapFrom, overlapTo *time.Time
+		if err := rows.Scan(&firstEdge, &secondEdge, &relationshipType, &source, &firstTarget, &secondTarget, &overlapFrom, &overlapTo); err != nil {
+			return nil, fmt.Errorf("failed to scan graph conflict: %w", err)
+		}
+
+		key := conflictKey{relationshipType, strings.ToLower(source), strings.ToLower(firstTarget), strings.ToLower(secondTarget)}
+		i, ok := index[key]
+		if !ok {
+			if len(conflicts) == limit {
+				continue
+			}
+			i = len(conflicts)
+			index[key] = i
+			conflicts = append(conflicts, models.GraphEdgeConflict{
+				RelationshipType: relationshipType,
+				SourceEntity:     source,
+				Statements: [2]models.ConflictingStatement{
+					{TargetEntity: firstTarget, EdgeIDs: []string{}},
+					{TargetEntity: secondTarget, EdgeIDs: []string{}},
+				},
+				OverlapFrom: overlapFrom,
+				OverlapTo:   overlapTo,
+			})
+		} else {
+			// The conflict spans the overlaps of all its edge pairs
+			conflict := &conflicts[i]
+			if conflict.OverlapFrom != nil && (overlapFrom == nil || overlapFrom.Before(*conflict.OverlapFrom)) {
+				conflict.OverlapFrom = overlapFrom
+			}
+			if conflict.OverlapTo != nil && (overlapTo == nil || overlapTo.After(*conflict.OverlapTo)) {
+				conflict.OverlapTo = overlapTo
+			}
+		}
+
+		conflict := &conflicts[i]
+		for side, edgeID := range []string{firstEdge, secondEdge} {
+			if key := (statementEdge{i, side, edgeID}); !seen[key] {
+				seen[key] = true
+				conflict.Statements[side].EdgeIDs = append(conflict.Statements[side].EdgeIDs, edgeID)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph conflicts: %w", err)
+	}
+	if conflicts == nil {
+		conflicts = []models.GraphEdgeConflict{}
+	}
+
+	d.monitor.RecordQuery("graph_conflicts", time.Since(start), len(conflicts))
+	return conflicts, nil
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"semantic-text-processor/models"
+)
+
+func TestGraphEdgeValidAt(t *testing.T) {
+	from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	edge := models.GraphEdge{ValidFrom: &from, ValidTo: &to}
+
+	assert.False(t, edge.ValidAt(from.Add(-time.Second)))
+	assert.True(t, edge.ValidAt(from), "valid_from is inclusive")
+	assert.True(t, edge.ValidAt(to.Add(-time.Second)))
+	assert.False(t, edge.ValidAt(to), "valid_to is exclusive")
+	assert.True(t, models.GraphEdge{}.ValidAt(from), "edges without bounds always hold")
+	assert.True(t, models.GraphEdge{ValidFrom: &from}.ValidAt(to.AddDate(100, 0, 0)))
+}
+
+func TestNewConflictDetector_Defaults(t *testing.T) {
+	assert.Equal(t, DefaultExclusiveRelationships, NewConflictDetector(nil, nil, nil).relationshipTypes)
+	assert.Equal(t, []string{"ceo_of"}, NewConflictDetector(nil, []string{"ceo_of"}, nil).relationshipTypes)
+}
+
+func TestSetValidity_RejectsEmptyInterval(t *testing.T) {
+	service := NewGraphTemporalService(nil, nil)
+	at := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.SetValidity(context.Background(), "edge", &models.EdgeValidityRequest{ValidFrom: &at, ValidTo: &at})
+	assert.True(t, errors.Is(err, ErrInvalidEdgeValidity))
+}
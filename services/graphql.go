@@ -0,0 +1,601 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// GraphQLService answers read-only GraphQL queries over chunks, tags, templates and graph
+// nodes. Nested fields such as children, tags and backlinks are loaded level by level, so a
+// query costs one batched read per field and level rather than one per chunk.
+type GraphQLService interface {
+	// Execute runs a query. Errors are reported in the response rather than returned.
+	Execute(ctx context.Context, req *models.GraphQLRequest) *models.GraphQLResponse
+	// Schema returns the schema in the GraphQL schema definition language
+	Schema() string
+}
+
+// maxGraphQLNodes bounds the graph nodes returned by the graphNodes query
+const maxGraphQLNodes = 500
+
+// graphQLService implements GraphQLService on the chunk, backlink and template services.
+// Reads go through the wrapped chunk service, so decryption and page ACLs apply as for REST
+// reads.
+type graphQLService struct {
+	db        *sql.DB // optional; graph nodes are only available with a database
+	chunks    UnifiedChunkService
+	backlinks BacklinkService // optional
+	templates TemplateService // optional
+	monitor   QueryPerformanceMonitor
+
+	schemaOnce sync.Once
+	schema     *gqlSchema
+	schemaErr  error
+}
+
+// NewGraphQLService creates a GraphQL service
+func NewGraphQLService(db *sql.DB, chunks UnifiedChunkService, backlinks BacklinkService, templates TemplateService, monitor QueryPerformanceMonitor) GraphQLService {
+	return &graphQLService{db: db, chunks: chunks, backlinks: backlinks, templates: templates, monitor: monitor}
+}
+
+// graphQLRequest holds the loaders of one query, which batch and cache reads for the
+// duration of the query only
+type graphQLRequest struct {
+	chunks     *gqlLoader // chunk ID -> *models.UnifiedChunkRecord
+	children   *gqlLoader // parent chunk ID -> []*models.UnifiedChunkRecord
+	tags       *gqlLoader // chunk ID -> []*models.UnifiedChunkRecord
+	tagged     *gqlLoader // tag chunk ID -> []*models.UnifiedChunkRecord
+	backlinks  *gqlLoader // chunk ID -> []string of referencing chunk IDs
+	graphNodes *gqlLoader // chunk ID -> []*models.GraphNode
+}
+
+func (s *graphQLService) Execute(ctx context.Context, req *models.GraphQLRequest) *models.GraphQLResponse {
+	start := time.Now()
+	response := &models.GraphQLResponse{}
+	defer func() {
+		s.monitor.RecordQuery("graphql_query", time.Since(start), len(response.Errors))
+	}()
+
+	schema, err := s.getSchema()
+	if err != nil {
+		response.Errors = []models.GraphQLError{{Message: err.Error()}}
+		return response
+	}
+	doc, err := parseGraphQLDocument(req.Query)
+	if err != nil {
+		graphQLErr := models.GraphQLError{Message: err.Error()}
+		if syntaxErr, ok := err.(*gqlSyntaxError); ok {
+			graphQLErr.Locations = []models.GraphQLLocation{{Line: syntaxErr.line, Column: syntaxErr.column}}
+		}
+		response.Errors = []models.GraphQLError{graphQLErr}
+		return response
+	}
+
+	data, errs := executeGraphQL(ctx, schema, doc, req.OperationName, req.Variables, s.newRequest(ctx))
+	if data != nil {
+		response.Data = data
+	}
+	response.Errors = errs
+	return response
+}
+
+func (s *graphQLService) Schema() string {
+	schema, err := s.getSchema()
+	if err != nil {
+		return ""
+	}
+	return schema.SDL()
+}
+
+func (s *graphQLService) getSchema() (*gqlSchema, error) {
+	s.schemaOnce.Do(func() {
+		s.schema, s.schemaErr = s.buildSchema()
+	})
+	return s.schema, s.schemaErr
+}
+
+// newRequest creates the loaders of one query
+func (s *graphQLService) newRequest(ctx context.Context) *graphQLRequest {
+	r := &graphQLRequest{}
+	r.chunks = newGraphQLLoader(ctx, func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		chunks, err := s.chunks.BatchGetChunks(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(chunks))
+		for id, chunk := range chunks {
+			values[id] = chunk
+		}
+		return values, nil
+	})
+	r.children = newGraphQLLoader(ctx, func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		lists, err := s.chunks.BatchGetChildren(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		return r.chunkLists(ids, lists), nil
+	})
+	r.tags = newGraphQLLoader(ctx, func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		lists, err := s.chunks.BatchGetChunkTags(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		return r.chunkLists(ids, lists), nil
+	})
+	// The chunk service has no batch read of tagged chunks; tags are read one by one but
+	// only once per query
+	r.tagged = newGraphQLLoader(ctx, func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		lists := make(map[string][]models.UnifiedChunkRecord, len(ids))
+		for _, id := range ids {
+			chunks, err := s.chunks.GetChunksByTag(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			lists[id] = chunks
+		}
+		return r.chunkLists(ids, lists), nil
+	})
+	r.backlinks = newGraphQLLoader(ctx, func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		if s.backlinks == nil {
+			return nil, fmt.Errorf("backlinks are not available")
+		}
+		sources, err := s.backlinks.GetBacklinkSources(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(ids))
+		for _, id := range ids {
+			values[id] = sources[id]
+		}
+		return values, nil
+	})
+	r.graphNodes = newGraphQLLoader(ctx, func(ctx context.Context, ids []string) (map[string]interface{}, error) {
+		nodes, err := s.queryGraphNodes(ctx, "chunk_id = ANY($1)", 0, pq.Array(validChunkIDs(ids)))
+		if err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, len(ids))
+		for _, id := range ids {
+			values[id] = []*models.GraphNode{}
+		}
+		for _, node := range nodes {
+			list, _ := values[node.ChunkID].([]*models.GraphNode)
+			values[node.ChunkID] = append(list, node)
+		}
+		return values, nil
+	})
+	return r
+}
+
+// chunkLists converts batch results to loader values, priming the chunk loader with every
+// chunk listed
+func (r *graphQLRequest) chunkLists(ids []string, lists map[string][]models.UnifiedChunkRecord) map[string]interface{} {
+	values := make(map[string]interface{}, len(ids))
+	for _, id := range ids {
+		chunks := make([]*models.UnifiedChunkRecord, len(lists[id]))
+		for i := range lists[id] {
+			chunks[i] = &lists[id][i]
+			r.chunks.prime(chunks[i].ChunkID, chunks[i])
+		}
+		values[id] = chunks
+	}
+	return values
+}
+
+// queryGraphNodes loads graph nodes matching a WHERE clause, up to limit when positive
+func (s *graphQLService) queryGraphNodes(ctx context.Context, where string, limit int, args ...interface{}) ([]*models.GraphNode, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("graph nodes are not available without a database")
+	}
+	start := time.Now()
+	query := `
+		SELECT id, chunk_id, entity_name, entity_type, properties, created_at
+		FROM graph_nodes
+		WHERE ` + where + `
+		ORDER BY entity_name, id`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load graph nodes: %w", err)
+	}
+	defer rows.Close()
+
+	nodes := []*models.GraphNode{}
+	for rows.Next() {
+		var node models.GraphNode
+		var propertiesBytes []byte
+		if err := rows.Scan(&node.ID, &node.ChunkID, &node.EntityName, &node.EntityType, &propertiesBytes, &node.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan graph node: %w", err)
+		}
+		node.Properties = parseGraphProperties(propertiesBytes, node.ID)
+		nodes = append(nodes, &node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating graph nodes: %w", err)
+	}
+	s.monitor.RecordQuery("graphql_graph_nodes", time.Since(start), len(nodes))
+	return nodes, nil
+}
+
+// readableGraphNodes keeps the nodes whose chunk the caller may read
+func (r *graphQLRequest) readableGraphNodes(nodes []*models.GraphNode) gqlThunk {
+	chunks := make([]gqlThunk, len(nodes))
+	for i, node := range nodes {
+		chunks[i] = r.chunks.load(node.ChunkID)
+	}
+	return func() (interface{}, error) {
+		readable := make([]*models.GraphNode, 0, len(nodes))
+		for i, node := range nodes {
+			chunk, err := chunks[i]()
+			if err != nil {
+				return nil, err
+			}
+			if chunk != nil {
+				readable = append(readable, node)
+			}
+		}
+		return readable, nil
+	}
+}
+
+// graphQLTemplate is the source of Template fields
+type graphQLTemplate struct {
+	template *models.TemplateWithInstances
+}
+
+func (s *graphQLService) buildSchema() (*gqlSchema, error) {
+	query := &gqlObjectType{name: "Query", fields: map[string]*gqlFieldDef{
+		"chunk": {
+			typ: "Chunk", args: []string{"id: ID!"},
+			description: "A chunk by ID, null when it does not exist or is not readable",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.chunks.load(p.args["id"].(string)), nil
+			},
+		},
+		"chunks": {
+			typ: "[Chunk!]!", args: []string{"ids: [ID!]!"},
+			description: "Chunks by ID, leaving out those that do not exist or are not readable",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.chunks.loadMany(stringArgs(p.args["ids"])), nil
+			},
+		},
+		"search": {
+			typ: "SearchResult!",
+			args: []string{"content: String", "tags: [ID!]", "tagLogic: String = \"OR\"", "isPage: Boolean", "isTag: Boolean",
+				"isTemplate: Boolean", "parent: ID", "page: ID", "filter: String", "limit: Int = 20", "offset: Int = 0"},
+			description: "Chunks matching content, tags, flags and a filter expression",
+			resolve:     s.resolveSearch,
+		},
+		"tag": {
+			typ: "Tag", args: []string{"id: ID!"},
+			description: "A tag by chunk ID, null when the chunk is not a tag",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				chunk := p.request.chunks.load(p.args["id"].(string))
+				return gqlThunk(func() (interface{}, error) {
+					value, err := chunk()
+					if tag, ok := value.(*models.UnifiedChunkRecord); ok && tag != nil && !tag.IsTag {
+						return nil, err
+					}
+					return value, err
+				}), nil
+			},
+		},
+		"template": {
+			typ: "Template", args: []string{"name: String!"},
+			description: "A template by name",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				if s.templates == nil {
+					return nil, fmt.Errorf("templates are not available")
+				}
+				template, err := s.templates.GetTemplate(p.ctx, TemplateContent(p.args["name"].(string)))
+				if err != nil || template == nil || template.Template == nil {
+					return nil, err
+				}
+				return &graphQLTemplate{template: template}, nil
+			},
+		},
+		"templates": {
+			typ:         "[Template!]!",
+			description: "All templates",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				if s.templates == nil {
+					return nil, fmt.Errorf("templates are not available")
+				}
+				all, err := s.templates.GetAllTemplates(p.ctx)
+				if err != nil {
+					return nil, err
+				}
+				templates := make([]*graphQLTemplate, 0, len(all))
+				for i := range all {
+					if all[i].Template != nil {
+						templates = append(templates, &graphQLTemplate{template: &all[i]})
+					}
+				}
+				return templates, nil
+			},
+		},
+		"graphNodes": {
+			typ:         "[GraphNode!]!",
+			args:        []string{"chunkIds: [ID!]", "entityType: String", "name: String", "limit: Int = 50"},
+			description: "Graph nodes of chunks, or matching an entity type and a name substring",
+			resolve:     s.resolveGraphNodes,
+		},
+	}}
+
+	chunk := &gqlObjectType{name: "Chunk", description: "A block, page, tag, template or slot", fields: map[string]*gqlFieldDef{
+		"id":          {typ: "ID!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.ChunkID })},
+		"contents":    {typ: "String!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Contents })},
+		"isPage":      {typ: "Boolean!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.IsPage })},
+		"isTag":       {typ: "Boolean!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.IsTag })},
+		"isTemplate":  {typ: "Boolean!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.IsTemplate })},
+		"isSlot":      {typ: "Boolean!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.IsSlot })},
+		"parentId":    {typ: "ID", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Parent })},
+		"pageId":      {typ: "ID", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Page })},
+		"ref":         {typ: "String", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Ref })},
+		"metadata":    {typ: "JSON", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Metadata })},
+		"version":     {typ: "Int!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Version })},
+		"createdTime": {typ: "DateTime!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.CreatedTime })},
+		"lastUpdated": {typ: "DateTime!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.LastUpdated })},
+		"parent": {
+			typ: "Chunk", description: "The parent chunk",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.loadOptionalChunk(p.source.(*models.UnifiedChunkRecord).Parent), nil
+			},
+		},
+		"page": {
+			typ: "Chunk", description: "The page the chunk is on",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.loadOptionalChunk(p.source.(*models.UnifiedChunkRecord).Page), nil
+			},
+		},
+		"children": {
+			typ: "[Chunk!]!", description: "Direct children, oldest first",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.children.load(p.source.(*models.UnifiedChunkRecord).ChunkID), nil
+			},
+		},
+		"tags": {
+			typ: "[Tag!]!", description: "Tags of the chunk by name",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.tags.load(p.source.(*models.UnifiedChunkRecord).ChunkID), nil
+			},
+		},
+		"backlinks": {
+			typ: "[Chunk!]!", description: "Chunks referencing this chunk, most recently updated first",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				sources := p.request.backlinks.load(p.source.(*models.UnifiedChunkRecord).ChunkID)
+				return gqlThunk(func() (interface{}, error) {
+					ids, err := sources()
+					if err != nil {
+						return nil, err
+					}
+					sourceIDs, _ := ids.([]string)
+					return p.request.chunks.loadMany(sourceIDs), nil
+				}), nil
+			},
+		},
+		"references": {
+			typ: "[Chunk!]!", description: "Chunks this chunk references in its ref",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				ref := p.source.(*models.UnifiedChunkRecord).Ref
+				if ref == nil {
+					return []*models.UnifiedChunkRecord{}, nil
+				}
+				return p.request.chunks.loadMany(ParseRefTargets(*ref)), nil
+			},
+		},
+		"graphNodes": {
+			typ: "[GraphNode!]!", description: "Entities extracted from the chunk",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				return p.request.graphNodes.load(p.source.(*models.UnifiedChunkRecord).ChunkID), nil
+			},
+		},
+	}}
+
+	tag := &gqlObjectType{name: "Tag", description: "A chunk used as a tag", fields: map[string]*gqlFieldDef{
+		"id":    {typ: "ID!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.ChunkID })},
+		"name":  {typ: "String!", resolve: chunkField(func(c *models.UnifiedChunkRecord) interface{} { return c.Contents })},
+		"chunk": {typ: "Chunk!", description: "The tag's own chunk", resolve: func(p gqlResolveParams) (interface{}, error) { return p.source, nil }},
+		"chunks": {
+			typ: "[Chunk!]!", args: []string{"limit: Int"}, description: "Chunks with the tag",
+			resolve: func(p gqlResolveParams) (interface{}, error) {
+				tagged := p.request.tagged.load(p.source.(*models.UnifiedChunkRecord).ChunkID)
+				return gqlThunk(func() (interface{}, error) {
+					value, err := tagged()
+					chunks, _ := value.([]*models.UnifiedChunkRecord)
+					if limit, ok := p.args["limit"].(int); ok && limit >= 0 && limit < len(chunks) {
+						chunks = chunks[:limit]
+					}
+					return chunks, err
+				}), nil
+			},
+		},
+	}}
+
+	template := &gqlObjectType{name: "Template", description: "A template with its slots and instances", fields: map[string]*gqlFieldDef{
+		"id": {typ: "ID!", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return p.source.(*graphQLTemplate).template.Template.ID, nil
+		}},
+		"name": {typ: "String!", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return TemplateName(p.source.(*graphQLTemplate).template.Template), nil
+		}},
+		"slots": {typ: "[String!]!", description: "Slot names in slot order", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return TemplateSlotNames(p.source.(*graphQLTemplate).template), nil
+		}},
+		"chunk": {typ: "Chunk", description: "The template's own chunk", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return p.request.chunks.load(p.source.(*graphQLTemplate).template.Template.ID), nil
+		}},
+		"instances": {typ: "[Chunk!]!", description: "Instance chunks of the template", resolve: func(p gqlResolveParams) (interface{}, error) {
+			instances := p.source.(*graphQLTemplate).template.Instances
+			ids := make([]string, 0, len(instances))
+			for _, instance := range instances {
+				if instance.Instance != nil {
+					ids = append(ids, instance.Instance.ID)
+				}
+			}
+			return p.request.chunks.loadMany(ids), nil
+		}},
+	}}
+
+	graphNode := &gqlObjectType{name: "GraphNode", description: "An entity of the knowledge graph", fields: map[string]*gqlFieldDef{
+		"id":         {typ: "ID!", resolve: graphNodeField(func(n *models.GraphNode) interface{} { return n.ID })},
+		"chunkId":    {typ: "ID!", resolve: graphNodeField(func(n *models.GraphNode) interface{} { return n.ChunkID })},
+		"entityName": {typ: "String!", resolve: graphNodeField(func(n *models.GraphNode) interface{} { return n.EntityName })},
+		"entityType": {typ: "String!", resolve: graphNodeField(func(n *models.GraphNode) interface{} { return n.EntityType })},
+		"properties": {typ: "JSON", resolve: graphNodeField(func(n *models.GraphNode) interface{} { return n.Properties })},
+		"createdAt":  {typ: "DateTime!", resolve: graphNodeField(func(n *models.GraphNode) interface{} { return n.CreatedAt })},
+		"chunk": {typ: "Chunk", description: "The chunk the entity was extracted from", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return p.request.chunks.load(p.source.(*models.GraphNode).ChunkID), nil
+		}},
+	}}
+
+	searchResult := &gqlObjectType{name: "SearchResult", fields: map[string]*gqlFieldDef{
+		"totalCount": {typ: "Int!", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return p.source.(*models.SearchResult).TotalCount, nil
+		}},
+		"hasMore": {typ: "Boolean!", resolve: func(p gqlResolveParams) (interface{}, error) {
+			return p.source.(*models.SearchResult).HasMore, nil
+		}},
+		"chunks": {typ: "[Chunk!]!", resolve: func(p gqlResolveParams) (interface{}, error) {
+			result := p.source.(*models.SearchResult)
+			chunks := make([]*models.UnifiedChunkRecord, len(result.Chunks))
+			for i := range result.Chunks {
+				chunks[i] = &result.Chunks[i]
+				p.request.chunks.prime(chunks[i].ChunkID, chunks[i])
+			}
+			return chunks, nil
+		}},
+	}}
+
+	return newGraphQLSchema(query, chunk, tag, template, graphNode, searchResult)
+}
+
+func (s *graphQLService) resolveSearch(p gqlResolveParams) (interface{}, error) {
+	query := &models.SearchQuery{
+		Tags:     stringArgs(p.args["tags"]),
+		TagLogic: strings.ToUpper(p.args["tagLogic"].(string)),
+		Limit:    p.args["limit"].(int),
+		Offset:   p.args["offset"].(int),
+	}
+	if query.TagLogic != "AND" && query.TagLogic != "OR" {
+		return nil, fmt.Errorf("tagLogic must be AND or OR")
+	}
+	if query.Limit < 1 || query.Limit > 100 {
+		return nil, fmt.Errorf("limit must be between 1 and 100")
+	}
+	if query.Offset < 0 {
+		return nil, fmt.Errorf("offset must not be negative")
+	}
+	query.Content, _ = p.args["content"].(string)
+	query.IsPage = boolArg(p.args["isPage"])
+	query.IsTag = boolArg(p.args["isTag"])
+	query.IsTemplate = boolArg(p.args["isTemplate"])
+	if parent, ok := p.args["parent"].(string); ok {
+		query.Parent = &parent
+	}
+	if page, ok := p.args["page"].(string); ok {
+		query.Page = &page
+	}
+	if filter, ok := p.args["filter"].(string); ok {
+		expr, err := ParseFilterExpression(filter)
+		if err != nil {
+			return nil, err
+		}
+		query.Filter = expr
+	}
+	return s.chunks.SearchChunks(p.ctx, query)
+}
+
+func (s *graphQLService) resolveGraphNodes(p gqlResolveParams) (interface{}, error) {
+	limit := p.args["limit"].(int)
+	if limit < 1 || limit > maxGraphQLNodes {
+		return nil, fmt.Errorf("limit must be between 1 and %d", maxGraphQLNodes)
+	}
+
+	if chunkIDs, ok := p.args["chunkIds"]; ok {
+		nodes := p.request.graphNodes.loadMany(stringArgs(chunkIDs))
+		return gqlThunk(func() (interface{}, error) {
+			lists, err := nodes()
+			if err != nil {
+				return nil, err
+			}
+			var all []*models.GraphNode
+			for _, list := range lists.([]interface{}) {
+				all = append(all, list.([]*models.GraphNode)...)
+			}
+			if len(all) > limit {
+				all = all[:limit]
+			}
+			return p.request.readableGraphNodes(all), nil
+		}), nil
+	}
+
+	conditions := []string{"TRUE"}
+	var args []interface{}
+	if entityType, ok := p.args["entityType"].(string); ok {
+		args = append(args, entityType)
+		conditions = append(conditions, fmt.Sprintf("entity_type = $%d", len(args)))
+	}
+	if name, ok := p.args["name"].(string); ok {
+		args = append(args, "%"+escapeLikePattern(name)+"%")
+		conditions = append(conditions, fmt.Sprintf("entity_name ILIKE $%d", len(args)))
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("graphNodes needs chunkIds, entityType or name")
+	}
+	nodes, err := s.queryGraphNodes(p.ctx, strings.Join(conditions, " AND "), limit, args...)
+	if err != nil {
+		return nil, err
+	}
+	return p.request.readableGraphNodes(nodes), nil
+}
+
+// loadOptionalChunk loads the chunk id points at, if any
+func (r *graphQLRequest) loadOptionalChunk(id *string) interface{} {
+	if id == nil || *id == "" {
+		return nil
+	}
+	return r.chunks.load(*id)
+}
+
+func chunkField(get func(*models.UnifiedChunkRecord) interface{}) gqlResolver {
+	return func(p gqlResolveParams) (interface{}, error) {
+		return get(p.source.(*models.UnifiedChunkRecord)), nil
+	}
+}
+
+func graphNodeField(get func(*models.GraphNode) interface{}) gqlResolver {
+	return func(p gqlResolveParams) (interface{}, error) {
+		return get(p.source.(*models.GraphNode)), nil
+	}
+}
+
+// stringArgs converts a coerced list argument of IDs or strings
+func stringArgs(value interface{}) []string {
+	items, _ := value.([]interface{})
+	strs := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+func boolArg(value interface{}) *bool {
+	if b, ok := value.(bool); ok {
+		return &b
+	}
+	return nil
+}
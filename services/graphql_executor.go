@@ -0,0 +1,788 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// This file executes parsed documents against a schema of object types defined in Go.
+// Resolvers may return a gqlThunk instead of a value; the executor resolves all fields of
+// one level of the response before calling any thunk, so that loaders batch the keys of
+// the whole level into one fetch.
+
+// maxGraphQLDepth bounds how deeply selections may nest
+const maxGraphQLDepth = 12
+
+// gqlThunk is a deferred resolver result
+type gqlThunk func() (interface{}, error)
+
+// gqlResolveParams are passed to field resolvers
+type gqlResolveParams struct {
+	ctx     context.Context
+	source  interface{}
+	args    map[string]interface{}
+	request *graphQLRequest
+}
+
+// gqlResolver resolves a field to a value or a gqlThunk
+type gqlResolver func(p gqlResolveParams) (interface{}, error)
+
+// gqlFieldDef defines a field of an object type. Types and arguments are written in
+// GraphQL notation, e.g. "[Chunk!]!" or "limit: Int = 20".
+type gqlFieldDef struct {
+	typ         string
+	args        []string
+	description string
+	resolve     gqlResolver
+
+	typeRef *gqlTypeRef
+	argDefs []gqlArgumentDef
+}
+
+// gqlArgumentDef is a parsed argument definition
+type gqlArgumentDef struct {
+	name         string
+	typ          *gqlTypeRef
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+// gqlObjectType is an object type of the schema
+type gqlObjectType struct {
+	name        string
+	description string
+	fields      map[string]*gqlFieldDef
+	order       []string
+}
+
+// gqlSchema holds the object types of a schema, starting at the query type
+type gqlSchema struct {
+	query *gqlObjectType
+	types map[string]*gqlObjectType
+}
+
+// gqlScalars are the scalar types known to the executor
+var gqlScalars = map[string]string{
+	"ID":       "",
+	"String":   "",
+	"Int":      "",
+	"Float":    "",
+	"Boolean":  "",
+	"JSON":     "Arbitrary JSON value",
+	"DateTime": "RFC 3339 timestamp",
+}
+
+// newGraphQLSchema checks and indexes object types. Every field type and argument type must
+// name a scalar or one of the types.
+func newGraphQLSchema(query *gqlObjectType, types ...*gqlObjectType) (*gqlSchema, error) {
+	schema := &gqlSchema{query: query, types: make(map[string]*gqlObjectType)}
+	for _, object := range append([]*gqlObjectType{query}, types...) {
+		schema.types[object.name] = object
+	}
+
+	for _, object := range schema.types {
+		object.order = object.order[:0]
+		for name, field := range object.fields {
+			object.order = append(object.order, name)
+			typ, err := parseGraphQLType(field.typ)
+			if err != nil {
+				return nil, fmt.Errorf("invalid type of %s.%s: %w", object.name, name, err)
+			}
+			if !schema.knownType(typ) {
+				return nil, fmt.Errorf("unknown type %s of %s.%s", typ, object.name, name)
+			}
+			field.typeRef = typ
+
+			field.argDefs = field.argDefs[:0]
+			for _, arg := range field.args {
+				def, err := parseGraphQLArgumentDef(arg)
+				if err != nil {
+					return nil, fmt.Errorf("invalid argument of %s.%s: %w", object.name, name, err)
+				}
+				if !isScalarInput(def.typ) {
+					return nil, fmt.Errorf("argument %s of %s.%s must be a scalar or a list of scalars", def.name, object.name, name)
+				}
+				field.argDefs = append(field.argDefs, def)
+			}
+		}
+		sort.Strings(object.order)
+	}
+	return schema, nil
+}
+
+func parseGraphQLArgumentDef(s string) (gqlArgumentDef, error) {
+	var def gqlArgumentDef
+	p, err := newGraphQLParser(s)
+	if err != nil {
+		return def, err
+	}
+	if def.name, err = p.name(); err != nil {
+		return def, err
+	}
+	if err := p.expect(":"); err != nil {
+		return def, err
+	}
+	if def.typ, err = p.parseType(); err != nil {
+		return def, err
+	}
+	if p.peek("=") {
+		if err := p.advance(); err != nil {
+			return def, err
+		}
+		if def.defaultValue, err = p.parseValue(true); err != nil {
+			return def, err
+		}
+		def.hasDefault = true
+	}
+	if p.tok.kind != gqlTokenEOF {
+		return def, p.unexpected()
+	}
+	return def, nil
+}
+
+func isBuiltinScalar(name string) bool {
+	_, ok := gqlScalars[name]
+	return ok
+}
+
+// isScalarInput reports whether typ is a scalar or a list of scalars, the only argument
+// types the executor coerces
+func isScalarInput(typ *gqlTypeRef) bool {
+	if typ.list != nil {
+		return typ.list.list == nil && isScalarInput(typ.list)
+	}
+	return isBuiltinScalar(typ.name)
+}
+
+func (s *gqlSchema) knownType(typ *gqlTypeRef) bool {
+	if typ.list != nil {
+		return s.knownType(typ.list)
+	}
+	_, object := s.types[typ.name]
+	return object || isBuiltinScalar(typ.name)
+}
+
+// SDL describes the schema in the GraphQL schema definition language
+func (s *gqlSchema) SDL() string {
+	var b strings.Builder
+	scalars := make([]string, 0, len(gqlScalars))
+	for name, description := range gqlScalars {
+		if description != "" {
+			scalars = append(scalars, name)
+		}
+	}
+	sort.Strings(scalars)
+	for _, name := range scalars {
+		fmt.Fprintf(&b, "\"%s\"\nscalar %s\n\n", gqlScalars[name], name)
+	}
+
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		if name != s.query.name {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range append([]string{s.query.name}, names...) {
+		object := s.types[name]
+		if object.description != "" {
+			fmt.Fprintf(&b, "\"%s\"\n", object.description)
+		}
+		fmt.Fprintf(&b, "type %s {\n", object.name)
+		for _, fieldName := range object.order {
+			field := object.fields[fieldName]
+			if field.description != "" {
+				fmt.Fprintf(&b, "  \"%s\"\n", field.description)
+			}
+			b.WriteString("  " + fieldName)
+			if len(field.args) > 0 {
+				b.WriteString("(" + strings.Join(field.args, ", ") + ")")
+			}
+			fmt.Fprintf(&b, ": %s\n", field.typ)
+		}
+		b.WriteString("}\n\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// gqlResponseMap is a response object, which keeps fields in selection order
+type gqlResponseMap struct {
+	keys   []string
+	values []interface{}
+}
+
+// MarshalJSON writes the fields in selection order
+func (m *gqlResponseMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		encodedKey, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Get returns the value of a field, for tests and callers inspecting results
+func (m *gqlResponseMap) Get(key string) interface{} {
+	for i, k := range m.keys {
+		if k == key {
+			return m.values[i]
+		}
+	}
+	return nil
+}
+
+// gqlExecution is the state of executing one operation
+type gqlExecution struct {
+	ctx       context.Context
+	schema    *gqlSchema
+	fragments map[string]*gqlFragment
+	variables map[string]interface{}
+	request   *graphQLRequest
+	errors    []models.GraphQLError
+	pending   []func()
+}
+
+// gqlCollectedField is a response key with the field selections merged into it
+type gqlCollectedField struct {
+	key   string
+	nodes []*gqlField
+}
+
+// executeGraphQL runs the named operation of a document, or its only operation
+func executeGraphQL(ctx context.Context, schema *gqlSchema, doc *gqlDocument, operationName string, variables map[string]interface{}, request *graphQLRequest) (*gqlResponseMap, []models.GraphQLError) {
+	operation, err := selectOperation(doc, operationName)
+	if err != nil {
+		return nil, []models.GraphQLError{{Message: err.Error()}}
+	}
+	if operation.kind != "query" {
+		return nil, []models.GraphQLError{{Message: fmt.Sprintf("%s operations are not supported", operation.kind)}}
+	}
+	coerced, err := coerceVariables(operation, variables)
+	if err != nil {
+		return nil, []models.GraphQLError{{Message: err.Error()}}
+	}
+
+	e := &gqlExecution{
+		ctx:       ctx,
+		schema:    schema,
+		fragments: doc.fragments,
+		variables: coerced,
+		request:   request,
+	}
+	data := e.executeSelections(schema.query, nil, operation.selections, nil)
+	for len(e.pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			e.errors = append(e.errors, models.GraphQLError{Message: err.Error()})
+			break
+		}
+		level := e.pending
+		e.pending = nil
+		for _, complete := range level {
+			complete()
+		}
+	}
+	return data, e.errors
+}
+
+func selectOperation(doc *gqlDocument, name string) (*gqlOperation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, fmt.Errorf("operationName is required for documents with several operations")
+		}
+		return doc.operations[0], nil
+	}
+	for _, operation := range doc.operations {
+		if operation.name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func coerceVariables(operation *gqlOperation, values map[string]interface{}) (map[string]interface{}, error) {
+	coerced := make(map[string]interface{}, len(operation.variables))
+	for _, definition := range operation.variables {
+		value, provided := values[definition.name]
+		if !provided {
+			if definition.hasDefault {
+				coerced[definition.name] = definition.defaultValue
+				continue
+			}
+			if definition.typ.nonNull {
+				return nil, fmt.Errorf("variable $%s of type %s was not provided", definition.name, definition.typ)
+			}
+			continue
+		}
+		input, err := coerceInput(definition.typ, value, nil)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %w", definition.name, err)
+		}
+		coerced[definition.name] = input
+	}
+	return coerced, nil
+}
+
+// coerceInput converts an argument or variable value to the Go value of typ: string, int,
+// float64, bool or a slice of those. Variable references are replaced by their values.
+func coerceInput(typ *gqlTypeRef, value interface{}, variables map[string]interface{}) (interface{}, error) {
+	if name, ok := value.(gqlVariable); ok {
+		value = variables[string(name)]
+	}
+	if value == nil {
+		if typ.nonNull {
+			return nil, fmt.Errorf("expected a non-null %s", typ)
+		}
+		return nil, nil
+	}
+
+	if typ.list != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			items = []interface{}{value}
+		}
+		list := make([]interface{}, len(items))
+		for i, item := range items {
+			var err error
+			if list[i], err = coerceInput(typ.list, item, variables); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+
+	invalid := fmt.Errorf("expected %s, got %v", typ.name, value)
+	switch typ.name {
+	case "ID", "String":
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case int64:
+			if typ.name == "ID" {
+				return fmt.Sprint(v), nil
+			}
+		case float64:
+			if typ.name == "ID" && v == math.Trunc(v) {
+				return fmt.Sprint(int64(v)), nil
+			}
+		}
+	case "Int":
+		switch v := value.(type) {
+		case int64:
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int(v), nil
+			}
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt32 && v <= math.MaxInt32 {
+				return int(v), nil
+			}
+		}
+	case "Float":
+		switch v := value.(type) {
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		}
+	case "Boolean":
+		if v, ok := value.(bool); ok {
+			return v, nil
+		}
+	case "JSON":
+		return value, nil
+	}
+	return nil, invalid
+}
+
+// executeSelections resolves the fields selected on an object
+func (e *gqlExecution) executeSelections(object *gqlObjectType, source interface{}, selections []gqlSelection, path []interface{}) *gqlResponseMap {
+	fields := e.collectFields(object, selections, nil, map[string]bool{})
+	result := &gqlResponseMap{keys: make([]string, len(fields)), values: make([]interface{}, len(fields))}
+	for i, field := range fields {
+		i := i
+		result.keys[i] = field.key
+		e.resolveField(object, source, field, appendPath(path, field.key), func(value interface{}) {
+			result.values[i] = value
+		})
+	}
+	return result
+}
+
+// collectFields flattens fragments and applies @skip and @include, merging selections of
+// the same response key
+func (e *gqlExecution) collectFields(object *gqlObjectType, selections []gqlSelection, fields []gqlCollectedField, visited map[string]bool) []gqlCollectedField {
+	for _, selection := range selections {
+		if !e.included(selection.directives) {
+			continue
+		}
+		switch {
+		case selection.field != nil:
+			key := selection.field.responseKey()
+			merged := false
+			for i := range fields {
+				if fields[i].key == key {
+					fields[i].nodes = append(fields[i].nodes, selection.field)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				fields = append(fields, gqlCollectedField{key: key, nodes: []*gqlField{selection.field}})
+			}
+		case selection.spread != "":
+			fragment, ok := e.fragments[selection.spread]
+			if visited[selection.spread] || !ok {
+				if !ok {
+					e.errors = append(e.errors, models.GraphQLError{Message: fmt.Sprintf("unknown fragment %q", selection.spread)})
+				}
+				continue
+			}
+			visited[selection.spread] = true
+			if fragment.typeCondition == object.name {
+				fields = e.collectFields(object, fragment.selections, fields, visited)
+			}
+		case selection.inline != nil:
+			if selection.inline.typeCondition == "" || selection.inline.typeCondition == object.name {
+				fields = e.collectFields(object, selection.inline.selections, fields, visited)
+			}
+		}
+	}
+	return fields
+}
+
+// included evaluates @skip(if:) and @include(if:)
+func (e *gqlExecution) included(directives []gqlDirective) bool {
+	for _, directive := range directives {
+		condition, err := coerceInput(&gqlTypeRef{name: "Boolean", nonNull: true}, directive.arguments["if"], e.variables)
+		if err != nil {
+			continue
+		}
+		if (directive.name == "skip" && condition.(bool)) || (directive.name == "include" && !condition.(bool)) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *gqlExecution) resolveField(object *gqlObjectType, source interface{}, field gqlCollectedField, path []interface{}, set func(interface{})) {
+	node := field.nodes[0]
+	if node.name == "__typename" {
+		set(object.name)
+		return
+	}
+
+	def, ok := object.fields[node.name]
+	if !ok {
+		e.fail(fmt.Errorf("cannot query field %q on type %q", node.name, object.name), node, path)
+		set(nil)
+		return
+	}
+	if fieldDepth(path) > maxGraphQLDepth {
+		e.fail(fmt.Errorf("query exceeds the maximum depth of %d", maxGraphQLDepth), node, path)
+		set(nil)
+		return
+	}
+
+	args, err := e.coerceArguments(def, node)
+	if err != nil {
+		e.fail(err, node, path)
+		set(nil)
+		return
+	}
+
+	value, err := def.resolve(gqlResolveParams{ctx: e.ctx, source: source, args: args, request: e.request})
+	e.await(value, err, func(value interface{}, err error) {
+		e.complete(def.typeRef, field, value, err, path, set)
+	})
+}
+
+// await passes a resolved value to then, first deferring it to the next level while it is a
+// thunk. Thunks may produce further thunks, e.g. IDs loaded by one loader that are then
+// loaded by another.
+func (e *gqlExecution) await(value interface{}, err error, then func(interface{}, error)) {
+	thunk, ok := value.(gqlThunk)
+	if !ok || err != nil {
+		then(value, err)
+		return
+	}
+	e.pending = append(e.pending, func() {
+		value, err := thunk()
+		e.await(value, err, then)
+	})
+}
+
+func (e *gqlExecution) coerceArguments(def *gqlFieldDef, node *gqlField) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(def.argDefs))
+	for name := range node.arguments {
+		known := false
+		for _, argDef := range def.argDefs {
+			known = known || argDef.name == name
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown argument %q on field %q", name, node.name)
+		}
+	}
+	for _, argDef := range def.argDefs {
+		value, provided := node.arguments[argDef.name]
+		if name, ok := value.(gqlVariable); ok {
+			_, provided = e.variables[string(name)]
+		}
+		if !provided && argDef.hasDefault {
+			value = argDef.defaultValue
+		}
+		coerced, err := coerceInput(argDef.typ, value, e.variables)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q of field %q: %w", argDef.name, node.name, err)
+		}
+		if coerced != nil {
+			args[argDef.name] = coerced
+		}
+	}
+	return args, nil
+}
+
+func (e *gqlExecution) complete(typ *gqlTypeRef, field gqlCollectedField, value interface{}, err error, path []interface{}, set func(interface{})) {
+	if err != nil {
+		e.fail(err, field.nodes[0], path)
+		set(nil)
+		return
+	}
+	set(e.completeValue(typ, field, value, path))
+}
+
+// completeValue serializes a resolved value according to its type, resolving the
+// selections of objects
+func (e *gqlExecution) completeValue(typ *gqlTypeRef, field gqlCollectedField, value interface{}, path []interface{}) interface{} {
+	if isNilValue(value) {
+		if typ.nonNull {
+			e.fail(fmt.Errorf("cannot return null for non-nullable field %s", typ), field.nodes[0], path)
+		}
+		return nil
+	}
+
+	if typ.list != nil {
+		items := reflect.ValueOf(value)
+		if items.Kind() != reflect.Slice {
+			e.fail(fmt.Errorf("expected a list for %s", typ), field.nodes[0], path)
+			return nil
+		}
+		list := make([]interface{}, items.Len())
+		for i := range list {
+			list[i] = e.completeValue(typ.list, field, items.Index(i).Interface(), appendPath(path, i))
+		}
+		return list
+	}
+
+	if object, ok := e.schema.types[typ.name]; ok {
+		var selections []gqlSelection
+		for _, node := range field.nodes {
+			selections = append(selections, node.selections...)
+		}
+		if len(selections) == 0 {
+			e.fail(fmt.Errorf("field of type %s must have a selection of subfields", typ.name), field.nodes[0], path)
+			return nil
+		}
+		return e.executeSelections(object, value, selections, path)
+	}
+
+	if len(field.nodes[0].selections) > 0 {
+		e.fail(fmt.Errorf("field of scalar type %s cannot have a selection of subfields", typ.name), field.nodes[0], path)
+		return nil
+	}
+	serialized, err := serializeScalar(typ.name, value)
+	if err != nil {
+		e.fail(err, field.nodes[0], path)
+		return nil
+	}
+	return serialized
+}
+
+func serializeScalar(name string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case *string:
+		value = *v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	case *time.Time:
+		return v.UTC().Format(time.RFC3339Nano), nil
+	}
+
+	switch name {
+	case "ID", "String", "DateTime":
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+	case "Int":
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return v, nil
+		}
+	case "Float":
+		switch v := value.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		}
+	case "Boolean":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	case "JSON":
+		return value, nil
+	}
+	return nil, fmt.Errorf("cannot serialize %T as %s", value, name)
+}
+
+func isNilValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func (e *gqlExecution) fail(err error, node *gqlField, path []interface{}) {
+	e.errors = append(e.errors, models.GraphQLError{
+		Message:   err.Error(),
+		Locations: []models.GraphQLLocation{{Line: node.line, Column: node.column}},
+		Path:      path,
+	})
+}
+
+// fieldDepth counts the fields of a response path, leaving out list indexes
+func fieldDepth(path []interface{}) int {
+	depth := 0
+	for _, key := range path {
+		if _, ok := key.(string); ok {
+			depth++
+		}
+	}
+	return depth
+}
+
+// appendPath returns path extended by key without sharing its backing array
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	extended := make([]interface{}, len(path)+1)
+	copy(extended, path)
+	extended[len(path)] = key
+	return extended
+}
+
+// gqlLoader batches loads of keys requested while resolving one level of a response into a
+// single fetch, and caches results for the rest of the request. It is used by one
+// execution at a time and is not safe for concurrent use.
+type gqlLoader struct {
+	ctx     context.Context
+	fetch   func(ctx context.Context, keys []string) (map[string]interface{}, error)
+	queued  []string
+	waiting map[string]bool
+	results map[string]interface{}
+	errors  map[string]error
+	batches int
+}
+
+func newGraphQLLoader(ctx context.Context, fetch func(ctx context.Context, keys []string) (map[string]interface{}, error)) *gqlLoader {
+	return &gqlLoader{
+		ctx:     ctx,
+		fetch:   fetch,
+		waiting: make(map[string]bool),
+		results: make(map[string]interface{}),
+		errors:  make(map[string]error),
+	}
+}
+
+// load queues key and returns a thunk producing its value, which is nil for keys the
+// fetch did not return
+func (l *gqlLoader) load(key string) gqlThunk {
+	_, done := l.results[key]
+	if !done && l.errors[key] == nil && !l.waiting[key] {
+		l.waiting[key] = true
+		l.queued = append(l.queued, key)
+	}
+	return func() (interface{}, error) {
+		if l.waiting[key] {
+			l.dispatch()
+		}
+		if err := l.errors[key]; err != nil {
+			return nil, err
+		}
+		return l.results[key], nil
+	}
+}
+
+// loadMany queues keys and returns a thunk producing the values found, in key order
+func (l *gqlLoader) loadMany(keys []string) gqlThunk {
+	thunks := make([]gqlThunk, len(keys))
+	for i, key := range keys {
+		thunks[i] = l.load(key)
+	}
+	return func() (interface{}, error) {
+		values := make([]interface{}, 0, len(keys))
+		for _, thunk := range thunks {
+			value, err := thunk()
+			if err != nil {
+				return nil, err
+			}
+			if !isNilValue(value) {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+}
+
+// prime caches the value of a key loaded by other means, such as a chunk listed among the
+// children of another, unless the key was loaded or queued already
+func (l *gqlLoader) prime(key string, value interface{}) {
+	if _, done := l.results[key]; !done && !l.waiting[key] {
+		l.results[key] = value
+	}
+}
+
+// dispatch fetches every queued key in one batch
+func (l *gqlLoader) dispatch() {
+	keys := l.queued
+	l.queued = nil
+	if len(keys) == 0 {
+		return
+	}
+	l.batches++
+	values, err := l.fetch(l.ctx, keys)
+	for _, key := range keys {
+		delete(l.waiting, key)
+		if err != nil {
+			l.errors[key] = err
+			continue
+		}
+		l.results[key] = values[key]
+	}
+}
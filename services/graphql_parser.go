@@ -0,0 +1,768 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// This file parses GraphQL executable documents: operations with variables, selection
+// sets with aliases, arguments and directives, and named and inline fragments. Schema
+// definitions are not accepted, since the schema is defined in Go.
+
+// maxGraphQLDocumentSize bounds the size of a query document
+const maxGraphQLDocumentSize = 64 * 1024
+
+// gqlDocument is a parsed query document
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+// gqlOperation is a query, mutation or subscription of a document
+type gqlOperation struct {
+	kind       string
+	name       string
+	variables  []gqlVariableDefinition
+	selections []gqlSelection
+}
+
+// gqlVariableDefinition declares a variable of an operation
+type gqlVariableDefinition struct {
+	name         string
+	typ          *gqlTypeRef
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+// gqlFragment is a named fragment
+type gqlFragment struct {
+	name          string
+	typeCondition string
+	selections    []gqlSelection
+}
+
+// gqlSelection is one of a field, a fragment spread or an inline fragment
+type gqlSelection struct {
+	field      *gqlField
+	spread     string
+	inline     *gqlFragment
+	directives []gqlDirective
+}
+
+// gqlField is a field selection
+type gqlField struct {
+	alias      string
+	name       string
+	arguments  map[string]interface{}
+	selections []gqlSelection
+	line       int
+	column     int
+}
+
+// responseKey is the key of the field in the response
+func (f *gqlField) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+// gqlDirective is a directive such as @include(if: $flag)
+type gqlDirective struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+// gqlVariable is a reference to a variable in an argument value
+type gqlVariable string
+
+// gqlEnumValue is an enum literal in an argument value
+type gqlEnumValue string
+
+// gqlTypeRef is a type reference such as [ID!]!
+type gqlTypeRef struct {
+	name    string
+	list    *gqlTypeRef
+	nonNull bool
+}
+
+func (t *gqlTypeRef) String() string {
+	s := t.name
+	if t.list != nil {
+		s = "[" + t.list.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// parseGraphQLType parses a type reference written in GraphQL notation
+func parseGraphQLType(s string) (*gqlTypeRef, error) {
+	p, err := newGraphQLParser(s)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != gqlTokenEOF {
+		return nil, p.unexpected()
+	}
+	return typ, nil
+}
+
+// gqlSyntaxError reports an error in a query document
+type gqlSyntaxError struct {
+	message string
+	line    int
+	column  int
+}
+
+func (e *gqlSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at %d:%d: %s", e.line, e.column, e.message)
+}
+
+type gqlTokenKind int
+
+const (
+	gqlTokenEOF gqlTokenKind = iota
+	gqlTokenPunctuator
+	gqlTokenName
+	gqlTokenInt
+	gqlTokenFloat
+	gqlTokenString
+)
+
+type gqlToken struct {
+	kind   gqlTokenKind
+	value  string
+	line   int
+	column int
+}
+
+// gqlLexer splits a document into tokens, skipping whitespace, commas and comments
+type gqlLexer struct {
+	src       string
+	pos       int
+	line      int
+	lineStart int
+}
+
+func (l *gqlLexer) errorf(format string, args ...interface{}) error {
+	return &gqlSyntaxError{message: fmt.Sprintf(format, args...), line: l.line, column: l.pos - l.lineStart + 1}
+}
+
+func (l *gqlLexer) next() (gqlToken, error) {
+	l.skipIgnored()
+	tok := gqlToken{line: l.line, column: l.pos - l.lineStart + 1}
+	if l.pos >= len(l.src) {
+		return tok, nil
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$()&:=@[]{}|", c) >= 0:
+		l.pos++
+		tok.kind, tok.value = gqlTokenPunctuator, string(c)
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return tok, l.errorf("unexpected %q", c)
+		}
+		l.pos += 3
+		tok.kind, tok.value = gqlTokenPunctuator, "..."
+	case c == '_' || isGraphQLLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isGraphQLLetter(l.src[l.pos]) || isGraphQLDigit(l.src[l.pos])) {
+			l.pos++
+		}
+		tok.kind, tok.value = gqlTokenName, l.src[start:l.pos]
+	case c == '-' || isGraphQLDigit(c):
+		return l.number(tok)
+	case c == '"':
+		value, err := l.string()
+		if err != nil {
+			return tok, err
+		}
+		tok.kind, tok.value = gqlTokenString, value
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return tok, l.errorf("unexpected character %q", r)
+	}
+	return tok, nil
+}
+
+func (l *gqlLexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', ',', '\r':
+			l.pos++
+		case '\n':
+			l.pos++
+			l.line++
+			l.lineStart = l.pos
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+				l.pos += len("\ufeff")
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (l *gqlLexer) number(tok gqlToken) (gqlToken, error) {
+	start := l.pos
+	if l.src[l.pos] == '-' {
+		l.pos++
+	}
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.src) && isGraphQLDigit(l.src[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+	if digits() == 0 {
+		return tok, l.errorf("invalid number")
+	}
+	tok.kind = gqlTokenInt
+	if l.pos < len(l.src) && l.src[l.pos] == '.' {
+		l.pos++
+		if digits() == 0 {
+			return tok, l.errorf("invalid number")
+		}
+		tok.kind = gqlTokenFloat
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		l.pos++
+		if l.pos < len(l.src) && (l.src[l.pos] == '+' || l.src[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return tok, l.errorf("invalid number")
+		}
+		tok.kind = gqlTokenFloat
+	}
+	tok.value = l.src[start:l.pos]
+	return tok, nil
+}
+
+// string reads a quoted string or a block string
+func (l *gqlLexer) string() (string, error) {
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		end := strings.Index(l.src[l.pos+3:], `"""`)
+		if end < 0 {
+			return "", l.errorf("unterminated block string")
+		}
+		raw := l.src[l.pos+3 : l.pos+3+end]
+		for _, c := range raw {
+			if c == '\n' {
+				l.line++
+			}
+		}
+		l.pos += end + 6
+		if i := strings.LastIndexByte(l.src[:l.pos], '\n'); i >= 0 {
+			l.lineStart = i + 1
+		}
+		return strings.TrimSpace(raw), nil
+	}
+
+	l.pos++
+	var b strings.Builder
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return b.String(), nil
+		case c == '\n':
+			return "", l.errorf("unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.src) {
+				return "", l.errorf("unterminated string")
+			}
+			esc := l.src[l.pos+1]
+			l.pos += 2
+			switch esc {
+			case '"', '\\', '/':
+				b.WriteByte(esc)
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.src) {
+					return "", l.errorf("invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return "", l.errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return "", l.errorf("invalid escape \\%c", esc)
+			}
+		default:
+			b.WriteByte(c)
+			l.pos++
+		}
+	}
+	return "", l.errorf("unterminated string")
+}
+
+func isGraphQLLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isGraphQLDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// gqlParser is a recursive descent parser over gqlLexer with one token of lookahead
+type gqlParser struct {
+	lexer gqlLexer
+	tok   gqlToken
+}
+
+func newGraphQLParser(src string) (*gqlParser, error) {
+	p := &gqlParser{lexer: gqlLexer{src: src, line: 1}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// parseGraphQLDocument parses a query document
+func parseGraphQLDocument(src string) (*gqlDocument, error) {
+	if len(src) > maxGraphQLDocumentSize {
+		return nil, fmt.Errorf("query document exceeds %d bytes", maxGraphQLDocumentSize)
+	}
+	p, err := newGraphQLParser(src)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &gqlDocument{fragments: make(map[string]*gqlFragment)}
+	for p.tok.kind != gqlTokenEOF {
+		switch {
+		case p.peek("{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &gqlOperation{kind: "query", selections: selections})
+		case p.tok.kind == gqlTokenName && p.tok.value == "fragment":
+			fragment, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := doc.fragments[fragment.name]; exists {
+				return nil, fmt.Errorf("fragment %q is defined more than once", fragment.name)
+			}
+			doc.fragments[fragment.name] = fragment
+		case p.tok.kind == gqlTokenName && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			operation, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, operation)
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document contains no operation")
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *gqlParser) peek(punctuator string) bool {
+	return p.tok.kind == gqlTokenPunctuator && p.tok.value == punctuator
+}
+
+func (p *gqlParser) unexpected() error {
+	if p.tok.kind == gqlTokenEOF {
+		return &gqlSyntaxError{message: "unexpected end of document", line: p.tok.line, column: p.tok.column}
+	}
+	return &gqlSyntaxError{message: fmt.Sprintf("unexpected %q", p.tok.value), line: p.tok.line, column: p.tok.column}
+}
+
+func (p *gqlParser) expect(punctuator string) error {
+	if p.tok.kind == gqlTokenEOF {
+		return p.unexpected()
+	}
+	if !p.peek(punctuator) {
+		return &gqlSyntaxError{message: fmt.Sprintf("expected %q", punctuator), line: p.tok.line, column: p.tok.column}
+	}
+	return p.advance()
+}
+
+func (p *gqlParser) name() (string, error) {
+	if p.tok.kind == gqlTokenEOF {
+		return "", p.unexpected()
+	}
+	if p.tok.kind != gqlTokenName {
+		return "", &gqlSyntaxError{message: "expected a name", line: p.tok.line, column: p.tok.column}
+	}
+	name := p.tok.value
+	return name, p.advance()
+}
+
+func (p *gqlParser) parseOperation() (*gqlOperation, error) {
+	operation := &gqlOperation{kind: p.tok.value}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == gqlTokenName {
+		operation.name = p.tok.value
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek("(") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		for !p.peek(")") {
+			definition, err := p.parseVariableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			operation.variables = append(operation.variables, definition)
+		}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	operation.selections = selections
+	return operation, nil
+}
+
+func (p *gqlParser) parseVariableDefinition() (gqlVariableDefinition, error) {
+	var definition gqlVariableDefinition
+	if err := p.expect("$"); err != nil {
+		return definition, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return definition, err
+	}
+	definition.name = name
+	if err := p.expect(":"); err != nil {
+		return definition, err
+	}
+	if definition.typ, err = p.parseType(); err != nil {
+		return definition, err
+	}
+	if p.peek("=") {
+		if err := p.advance(); err != nil {
+			return definition, err
+		}
+		if definition.defaultValue, err = p.parseValue(true); err != nil {
+			return definition, err
+		}
+		definition.hasDefault = true
+	}
+	_, err = p.parseDirectives()
+	return definition, err
+}
+
+func (p *gqlParser) parseType() (*gqlTypeRef, error) {
+	typ := &gqlTypeRef{}
+	if p.peek("[") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		elem, err := p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		typ.list = elem
+	} else {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		typ.name = name
+	}
+	if p.peek("!") {
+		typ.nonNull = true
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	return typ, nil
+}
+
+func (p *gqlParser) parseFragment() (*gqlFragment, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if name == "on" {
+		return nil, &gqlSyntaxError{message: `fragments cannot be named "on"`, line: p.tok.line, column: p.tok.column}
+	}
+	if p.tok.kind != gqlTokenName || p.tok.value != "on" {
+		return nil, &gqlSyntaxError{message: `expected "on"`, line: p.tok.line, column: p.tok.column}
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	typeCondition, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &gqlFragment{name: name, typeCondition: typeCondition, selections: selections}, nil
+}
+
+func (p *gqlParser) parseSelectionSet() ([]gqlSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []gqlSelection
+	for !p.peek("}") {
+		selection, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, &gqlSyntaxError{message: "empty selection set", line: p.tok.line, column: p.tok.column}
+	}
+	return selections, p.advance()
+}
+
+func (p *gqlParser) parseSelection() (gqlSelection, error) {
+	var selection gqlSelection
+	if p.peek("...") {
+		if err := p.advance(); err != nil {
+			return selection, err
+		}
+		if p.tok.kind == gqlTokenName && p.tok.value != "on" {
+			selection.spread = p.tok.value
+			if err := p.advance(); err != nil {
+				return selection, err
+			}
+			directives, err := p.parseDirectives()
+			selection.directives = directives
+			return selection, err
+		}
+
+		inline := &gqlFragment{}
+		if p.tok.kind == gqlTokenName {
+			if err := p.advance(); err != nil {
+				return selection, err
+			}
+			typeCondition, err := p.name()
+			if err != nil {
+				return selection, err
+			}
+			inline.typeCondition = typeCondition
+		}
+		directives, err := p.parseDirectives()
+		if err != nil {
+			return selection, err
+		}
+		if inline.selections, err = p.parseSelectionSet(); err != nil {
+			return selection, err
+		}
+		selection.inline, selection.directives = inline, directives
+		return selection, nil
+	}
+
+	field := &gqlField{line: p.tok.line, column: p.tok.column}
+	name, err := p.name()
+	if err != nil {
+		return selection, err
+	}
+	if p.peek(":") {
+		if err := p.advance(); err != nil {
+			return selection, err
+		}
+		field.alias = name
+		if name, err = p.name(); err != nil {
+			return selection, err
+		}
+	}
+	field.name = name
+
+	if field.arguments, err = p.parseArguments(false); err != nil {
+		return selection, err
+	}
+	if selection.directives, err = p.parseDirectives(); err != nil {
+		return selection, err
+	}
+	if p.peek("{") {
+		if field.selections, err = p.parseSelectionSet(); err != nil {
+			return selection, err
+		}
+	}
+	selection.field = field
+	return selection, nil
+}
+
+func (p *gqlParser) parseArguments(constant bool) (map[string]interface{}, error) {
+	if !p.peek("(") {
+		return nil, nil
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	arguments := make(map[string]interface{})
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arguments[name], err = p.parseValue(constant); err != nil {
+			return nil, err
+		}
+	}
+	return arguments, p.advance()
+}
+
+func (p *gqlParser) parseDirectives() ([]gqlDirective, error) {
+	var directives []gqlDirective
+	for p.peek("@") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		arguments, err := p.parseArguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, gqlDirective{name: name, arguments: arguments})
+	}
+	return directives, nil
+}
+
+// parseValue parses an argument value. Constant values, such as variable defaults, may
+// not reference variables.
+func (p *gqlParser) parseValue(constant bool) (interface{}, error) {
+	tok := p.tok
+	switch tok.kind {
+	case gqlTokenInt:
+		n, err := strconv.ParseInt(tok.value, 10, 64)
+		if err != nil {
+			return nil, &gqlSyntaxError{message: "integer out of range", line: tok.line, column: tok.column}
+		}
+		return n, p.advance()
+	case gqlTokenFloat:
+		f, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			return nil, &gqlSyntaxError{message: "invalid float", line: tok.line, column: tok.column}
+		}
+		return f, p.advance()
+	case gqlTokenString:
+		return tok.value, p.advance()
+	case gqlTokenName:
+		var value interface{}
+		switch tok.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = gqlEnumValue(tok.value)
+		}
+		return value, p.advance()
+	}
+
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return gqlVariable(name), err
+	case p.peek("["):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		list := []interface{}{}
+		for !p.peek("]") {
+			value, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, p.advance()
+	case p.peek("{"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		object := make(map[string]interface{})
+		for !p.peek("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.advance()
+	}
+	return nil, p.unexpected()
+}
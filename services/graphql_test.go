@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+// graphQLChunks serves a small outline and counts the reads made through it
+type graphQLChunks struct {
+	UnifiedChunkService
+	chunks   map[string]*models.UnifiedChunkRecord
+	children map[string][]string
+	tags     map[string][]string
+	calls    map[string]int
+}
+
+func (c *graphQLChunks) records(ids []string) []models.UnifiedChunkRecord {
+	records := make([]models.UnifiedChunkRecord, len(ids))
+	for i, id := range ids {
+		records[i] = *c.chunks[id]
+	}
+	return records
+}
+
+func (c *graphQLChunks) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	c.calls["chunks"]++
+	found := make(map[string]*models.UnifiedChunkRecord)
+	for _, id := range chunkIDs {
+		if chunk, ok := c.chunks[id]; ok {
+			found[id] = chunk
+		}
+	}
+	return found, nil
+}
+
+func (c *graphQLChunks) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	c.calls["children"]++
+	lists := make(map[string][]models.UnifiedChunkRecord)
+	for _, id := range parentChunkIDs {
+		lists[id] = c.records(c.children[id])
+	}
+	return lists, nil
+}
+
+func (c *graphQLChunks) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	c.calls["tags"]++
+	lists := make(map[string][]models.UnifiedChunkRecord)
+	for _, id := range chunkIDs {
+		lists[id] = c.records(c.tags[id])
+	}
+	return lists, nil
+}
+
+func (c *graphQLChunks) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	c.calls["tagged"]++
+	var tagged []string
+	for id, tags := range c.tags {
+		for _, tag := range tags {
+			if tag == tagChunkID {
+				tagged = append(tagged, id)
+			}
+		}
+	}
+	return c.records(tagged), nil
+}
+
+// secondBlock is the ID of the block "b1" references, a UUID as refs hold chunk UUIDs
+const secondBlock = "00000000-0000-4000-8000-0000000000b2"
+
+// graphQLBacklinks reports "b1" as referencing secondBlock
+type graphQLBacklinks struct {
+	BacklinkService
+	calls int
+}
+
+func (b *graphQLBacklinks) GetBacklinkSources(ctx context.Context, chunkIDs []string) (map[string][]string, error) {
+	b.calls++
+	return map[string][]string{secondBlock: {"b1"}}, nil
+}
+
+func newTestGraphQL() (GraphQLService, *graphQLChunks, *graphQLBacklinks) {
+	page := "page"
+	ref := "((" + secondBlock + "))"
+	chunks := &graphQLChunks{
+		chunks: map[string]*models.UnifiedChunkRecord{
+			"page":      {ChunkID: "page", Contents: "Project", IsPage: true, CreatedTime: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)},
+			"b1":        {ChunkID: "b1", Contents: "First", Parent: &page, Page: &page, Ref: &ref},
+			secondBlock: {ChunkID: secondBlock, Contents: "Second", Parent: &page, Page: &page},
+			"urgent":    {ChunkID: "urgent", Contents: "urgent", IsTag: true},
+		},
+		children: map[string][]string{"page": {"b1", secondBlock}},
+		tags:     map[string][]string{"b1": {"urgent"}, secondBlock: {"urgent"}},
+		calls:    map[string]int{},
+	}
+	backlinks := &graphQLBacklinks{}
+	return NewGraphQLService(nil, chunks, backlinks, nil, NewNoOpMonitor()), chunks, backlinks
+}
+
+func executeTestGraphQL(t *testing.T, service GraphQLService, query string, variables map[string]interface{}) (map[string]interface{}, []models.GraphQLError) {
+	response := service.Execute(context.Background(), &models.GraphQLRequest{Query: query, Variables: variables})
+	var data map[string]interface{}
+	if response.Data != nil {
+		encoded, err := json.Marshal(response.Data)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(encoded, &data))
+	}
+	return data, response.Errors
+}
+
+func TestGraphQLService_NestedFieldsAreBatched(t *testing.T) {
+	service, chunks, backlinks := newTestGraphQL()
+
+	data, errs := executeTestGraphQL(t, service, `
+		query Outline($id: ID!) {
+			chunk(id: $id) {
+				contents
+				createdTime
+				children {
+					id
+					contents
+					tags { name }
+					backlinks { id }
+					references { id }
+					page { contents }
+				}
+			}
+		}`, map[string]interface{}{"id": "page"})
+	require.Empty(t, errs)
+
+	page := data["chunk"].(map[string]interface{})
+	assert.Equal(t, "Project", page["contents"])
+	assert.Equal(t, "2026-01-02T03:04:05Z", page["createdTime"])
+	children := page["children"].([]interface{})
+	require.Len(t, children, 2)
+	first, second := children[0].(map[string]interface{}), children[1].(map[string]interface{})
+	assert.Equal(t, "First", first["contents"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "urgent"}}, first["tags"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": secondBlock}}, first["references"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": "b1"}}, second["backlinks"])
+	assert.Equal(t, map[string]interface{}{"contents": "Project"}, second["page"])
+
+	// One read per field and level, however many children there are; chunks listed as
+	// children are not read again
+	assert.Equal(t, map[string]int{"chunks": 1, "children": 1, "tags": 1}, chunks.calls)
+	assert.Equal(t, 1, backlinks.calls)
+}
+
+func TestGraphQLService_AliasesFragmentsAndDirectives(t *testing.T) {
+	service, chunks, _ := newTestGraphQL()
+
+	data, errs := executeTestGraphQL(t, service, `
+		query ($withTags: Boolean = false) {
+			a: chunk(id: "b1") { ...Summary }
+			b: chunk(id: "`+secondBlock+`") { ...Summary tags @include(if: $withTags) { name } }
+			missing: chunk(id: "nope") { id }
+			tag(id: "urgent") { name chunks(limit: 1) { __typename } }
+			notATag: tag(id: "b1") { name }
+		}
+		fragment Summary on Chunk { id ... on Chunk { text: contents } }`, nil)
+	require.Empty(t, errs)
+
+	assert.Equal(t, map[string]interface{}{"id": "b1", "text": "First"}, data["a"])
+	assert.Equal(t, map[string]interface{}{"id": secondBlock, "text": "Second"}, data["b"])
+	assert.Nil(t, data["missing"])
+	assert.Nil(t, data["notATag"])
+	assert.Equal(t, map[string]interface{}{
+		"name":   "urgent",
+		"chunks": []interface{}{map[string]interface{}{"__typename": "Chunk"}},
+	}, data["tag"])
+	assert.Equal(t, 1, chunks.calls["chunks"], "all root chunks are loaded in one batch")
+
+	encoded, err := json.Marshal(service.Execute(context.Background(), &models.GraphQLRequest{Query: `{ chunk(id: "b1") { isTag id } }`}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"data":{"chunk":{"isTag":false,"id":"b1"}}}`, string(encoded))
+	assert.Contains(t, string(encoded), `{"isTag":false,"id":"b1"}`, "fields keep selection order")
+}
+
+func TestGraphQLService_Errors(t *testing.T) {
+	service, _, _ := newTestGraphQL()
+
+	data, errs := executeTestGraphQL(t, service, `{ chunk(id: "b1") { id bogus } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, `cannot query field "bogus" on type "Chunk"`)
+	assert.Equal(t, []interface{}{"chunk", "bogus"}, errs[0].Path)
+	assert.Equal(t, []models.GraphQLLocation{{Line: 1, Column: 24}}, errs[0].Locations)
+	assert.Equal(t, map[string]interface{}{"id": "b1", "bogus": nil}, data["chunk"])
+
+	data, errs = executeTestGraphQL(t, service, `{ chunk(id: "b1") { id }`, nil)
+	require.Len(t, errs, 1)
+	assert.Nil(t, data)
+	assert.Contains(t, errs[0].Message, "unexpected end of document")
+
+	_, errs = executeTestGraphQL(t, service, `mutation { chunk(id: "b1") { id } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "mutation operations are not supported")
+
+	_, errs = executeTestGraphQL(t, service, `query ($id: ID!) { chunk(id: $id) { id } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "variable $id of type ID! was not provided")
+
+	_, errs = executeTestGraphQL(t, service, `{ search(limit: 1000) { totalCount } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "limit must be between 1 and 100")
+
+	_, errs = executeTestGraphQL(t, service, `{ templates { name } }`, nil)
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Message, "templates are not available")
+}
+
+func TestGraphQLService_Schema(t *testing.T) {
+	service, _, _ := newTestGraphQL()
+	schema := service.Schema()
+	assert.Contains(t, schema, "type Query {")
+	assert.Contains(t, schema, `  search(content: String, tags: [ID!], tagLogic: String = "OR"`)
+	assert.Contains(t, schema, "  backlinks: [Chunk!]!")
+	assert.Contains(t, schema, "scalar DateTime")
+}
+
+func TestUnifiedChunkService_BatchReads_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+
+	page := &models.UnifiedChunkRecord{Contents: "Batch read page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer chunks.DeleteChunk(ctx, page.ChunkID)
+	tag := &models.UnifiedChunkRecord{Contents: "batch-read-tag", IsTag: true}
+	require.NoError(t, chunks.CreateChunk(ctx, tag))
+	defer chunks.DeleteChunk(ctx, tag.ChunkID)
+	block := &models.UnifiedChunkRecord{Contents: "Batch read block", Parent: &page.ChunkID, Page: &page.ChunkID}
+	require.NoError(t, chunks.CreateChunk(ctx, block))
+	defer chunks.DeleteChunk(ctx, block.ChunkID)
+	require.NoError(t, chunks.AddTags(ctx, block.ChunkID, []string{tag.ChunkID}))
+
+	found, err := chunks.BatchGetChunks(ctx, []string{page.ChunkID, block.ChunkID, "not-a-uuid", uuid.New().String()})
+	require.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, "Batch read block", found[block.ChunkID].Contents)
+
+	children, err := chunks.BatchGetChildren(ctx, []string{page.ChunkID, block.ChunkID})
+	require.NoError(t, err)
+	require.Len(t, children[page.ChunkID], 1)
+	assert.Equal(t, block.ChunkID, children[page.ChunkID][0].ChunkID)
+	assert.Empty(t, children[block.ChunkID])
+
+	tags, err := chunks.BatchGetChunkTags(ctx, []string{block.ChunkID, page.ChunkID})
+	require.NoError(t, err)
+	require.Len(t, tags[block.ChunkID], 1)
+	assert.Equal(t, "batch-read-tag", tags[block.ChunkID][0].Contents)
+	assert.Empty(t, tags[page.ChunkID])
+}
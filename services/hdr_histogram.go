@@ -0,0 +1,212 @@
+package services
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// hdrHistogram is a high dynamic range histogram of latencies in microseconds. Values below
+// hdrSubBuckets are counted exactly; larger ones fall in buckets whose width doubles with
+// each power of two, split into hdrSubBuckets/2 sub-buckets, so every value is kept to
+// within 1% (two significant digits) from a microsecond to an hour. Only buckets that were
+// hit are stored.
+type hdrHistogram struct {
+	counts map[int]int64
+	total  int64
+	max    int64
+}
+
+const (
+	hdrSubBucketBits = 8
+	hdrSubBuckets    = 1 << hdrSubBucketBits
+	hdrHalfBuckets   = hdrSubBuckets / 2
+	// hdrMaxValue caps recorded latencies at an hour
+	hdrMaxValue = int64(time.Hour / time.Microsecond)
+)
+
+// LatencyPercentiles summarizes a latency distribution
+type LatencyPercentiles struct {
+	Count int64         `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+func newHDRHistogram() *hdrHistogram {
+	return &hdrHistogram{counts: make(map[int]int64)}
+}
+
+// hdrBucket returns the bucket of a value
+func hdrBucket(value int64) int {
+	if value < hdrSubBuckets {
+		return int(value)
+	}
+	shift := bits.Len64(uint64(value)) - hdrSubBucketBits
+	return hdrSubBuckets + (shift-1)*hdrHalfBuckets + int(value>>uint(shift)) - hdrHalfBuckets
+}
+
+// hdrBucketValue returns the highest value of a bucket, which quantiles report
+func hdrBucketValue(bucket int) int64 {
+	if bucket < hdrSubBuckets {
+		return int64(bucket)
+	}
+	shift := (bucket-hdrSubBuckets)/hdrHalfBuckets + 1
+	sub := int64((bucket-hdrSubBuckets)%hdrHalfBuckets + hdrHalfBuckets)
+	return (sub+1)<<uint(shift) - 1
+}
+
+// record counts one latency; negative ones count as zero and those over an hour as an hour
+func (h *hdrHistogram) record(d time.Duration) {
+	value := int64(d / time.Microsecond)
+	if value < 0 {
+		value = 0
+	}
+	if value > hdrMaxValue {
+		value = hdrMaxValue
+	}
+	h.counts[hdrBucket(value)]++
+	h.total++
+	if value > h.max {
+		h.max = value
+	}
+}
+
+// merge adds the counts of other
+func (h *hdrHistogram) merge(other *hdrHistogram) {
+	for bucket, count := range other.counts {
+		h.counts[bucket] += count
+	}
+	h.total += other.total
+	if other.max > h.max {
+		h.max = other.max
+	}
+}
+
+// quantile returns the latency below which a fraction q of the recorded ones fall
+func (h *hdrHistogram) quantile(q float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	buckets := make([]int, 0, len(h.counts))
+	for bucket := range h.counts {
+		buckets = append(buckets, bucket)
+	}
+	sort.Ints(buckets)
+
+	rank := int64(math.Ceil(q * float64(h.total)))
+	if rank < 1 {
+		rank = 1
+	}
+	var seen int64
+	for _, bucket := range buckets {
+		seen += h.counts[bucket]
+		if seen >= rank {
+			// The bucket's highest value, but never above the largest latency seen
+			value := hdrBucketValue(bucket)
+			if value > h.max {
+				value = h.max
+			}
+			return time.Duration(value) * time.Microsecond
+		}
+	}
+	return time.Duration(h.max) * time.Microsecond
+}
+
+// percentiles summarizes the histogram
+func (h *hdrHistogram) percentiles() LatencyPercentiles {
+	return LatencyPercentiles{
+		Count: h.total,
+		P50:   h.quantile(0.50),
+		P95:   h.quantile(0.95),
+		P99:   h.quantile(0.99),
+		Max:   time.Duration(h.max) * time.Microsecond,
+	}
+}
+
+// latencyWindowSpans are the rolling windows reported, by name
+var latencyWindowSpans = []struct {
+	name string
+	span time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+}
+
+const (
+	fineLatencySlot   = 5 * time.Second
+	coarseLatencySlot = time.Minute
+)
+
+// latencySlot holds the latencies recorded during one slot of time
+type latencySlot struct {
+	start time.Time
+	hist  *hdrHistogram
+}
+
+// latencyWindows keeps recent latencies in time slots: five-second slots for the last
+// minute and one-minute slots for the last hour. A window merges the slots that started
+// within it, so it covers its span to within one slot.
+type latencyWindows struct {
+	fine   [int(time.Minute / fineLatencySlot)]latencySlot
+	coarse [int(time.Hour / coarseLatencySlot)]latencySlot
+}
+
+func (w *latencyWindows) record(now time.Time, d time.Duration) {
+	recordInSlot(w.fine[:], fineLatencySlot, now, d)
+	recordInSlot(w.coarse[:], coarseLatencySlot, now, d)
+}
+
+// recordInSlot records d in the ring slot of now, clearing the slot if it held an older period
+func recordInSlot(slots []latencySlot, width time.Duration, now time.Time, d time.Duration) {
+	start := now.Truncate(width)
+	slot := &slots[int(start.UnixNano()/int64(width))%len(slots)]
+	if slot.hist == nil || !slot.start.Equal(start) {
+		slot.start = start
+		slot.hist = newHDRHistogram()
+	}
+	slot.hist.record(d)
+}
+
+// window merges the latencies recorded within span before now
+func (w *latencyWindows) window(now time.Time, span time.Duration) *hdrHistogram {
+	slots := w.coarse[:]
+	if span <= time.Minute {
+		slots = w.fine[:]
+	}
+	merged := newHDRHistogram()
+	for i := range slots {
+		if slots[i].hist != nil && slots[i].start.After(now.Add(-span)) && !slots[i].start.After(now) {
+			merged.merge(slots[i].hist)
+		}
+	}
+	return merged
+}
+
+// summary returns the percentiles of every window
+func (w *latencyWindows) summary(now time.Time) map[string]LatencyPercentiles {
+	windows := make(map[string]LatencyPercentiles, len(latencyWindowSpans))
+	for _, window := range latencyWindowSpans {
+		windows[window.name] = w.window(now, window.span).percentiles()
+	}
+	return windows
+}
+
+// queryLatencies tracks the latencies of one query type, since the last reset and in
+// rolling windows
+type queryLatencies struct {
+	lifetime *hdrHistogram
+	windows  latencyWindows
+}
+
+func newQueryLatencies() *queryLatencies {
+	return &queryLatencies{lifetime: newHDRHistogram()}
+}
+
+func (l *queryLatencies) record(now time.Time, d time.Duration) {
+	l.lifetime.record(d)
+	l.windows.record(now, d)
+}
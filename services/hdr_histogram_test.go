@@ -0,0 +1,47 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHDRBuckets(t *testing.T) {
+	// Buckets are contiguous and every value maps into a bucket within 1% of it
+	previous := -1
+	for _, value := range []int64{0, 1, 255, 256, 257, 511, 512, 1000, 65535, 65536, 1e6, hdrMaxValue} {
+		bucket := hdrBucket(value)
+		assert.GreaterOrEqual(t, bucket, previous, value)
+		previous = bucket
+		highest := hdrBucketValue(bucket)
+		assert.GreaterOrEqual(t, highest, value)
+		assert.LessOrEqual(t, float64(highest-value), float64(value)/100+1, value)
+		assert.Equal(t, bucket, hdrBucket(highest), value)
+	}
+	for bucket := 0; bucket < hdrBucket(hdrMaxValue); bucket++ {
+		assert.Equal(t, bucket+1, hdrBucket(hdrBucketValue(bucket)+1))
+	}
+}
+
+func TestHDRHistogram_Quantiles(t *testing.T) {
+	h := newHDRHistogram()
+	assert.Equal(t, LatencyPercentiles{}, h.percentiles())
+
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	percentiles := h.percentiles()
+	assert.Equal(t, int64(1000), percentiles.Count)
+	assert.InEpsilon(t, 500*time.Millisecond, percentiles.P50, 0.01)
+	assert.InEpsilon(t, 950*time.Millisecond, percentiles.P95, 0.01)
+	assert.InEpsilon(t, 990*time.Millisecond, percentiles.P99, 0.01)
+	assert.Equal(t, time.Second, percentiles.Max)
+	assert.Equal(t, time.Second, h.quantile(1), "quantiles never exceed the largest latency")
+
+	other := newHDRHistogram()
+	other.record(2 * time.Hour)
+	h.merge(other)
+	assert.Equal(t, int64(1001), h.total)
+	assert.Equal(t, time.Hour, h.percentiles().Max, "latencies are capped at an hour")
+}
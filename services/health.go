@@ -84,7 +84,7 @@ func (h *DefaultHealthService) CheckHealth(ctx context.Context) SystemHealth {
 	
 	// Check each component
 	for name, checker := range h.checkers {
-		componentHealth := h.checkComponentWithTimeout(ctx, checker, 5*time.Second)
+		componentHealth := checkComponentWithTimeout(ctx, checker, 5*time.Second)
 		components[name] = componentHealth
 		
 		// Determine overall status
@@ -122,7 +122,7 @@ func (h *DefaultHealthService) CheckComponent(ctx context.Context, name string)
 		return ComponentHealth{}, fmt.Errorf("component %s not found", name)
 	}
 	
-	return h.checkComponentWithTimeout(ctx, checker, 5*time.Second), nil
+	return checkComponentWithTimeout(ctx, checker, 5*time.Second), nil
 }
 
 // GetSystemInfo returns general system information
@@ -135,8 +135,9 @@ func (h *DefaultHealthService) GetSystemInfo() map[string]interface{} {
 	}
 }
 
-// checkComponentWithTimeout checks a component with a timeout
-func (h *DefaultHealthService) checkComponentWithTimeout(ctx context.Context, checker HealthChecker, timeout time.Duration) ComponentHealth {
+// checkComponentWithTimeout checks a component with a timeout, reporting a checker that
+// panics or overruns as unhealthy
+func checkComponentWithTimeout(ctx context.Context, checker HealthChecker, timeout time.Duration) ComponentHealth {
 	start := time.Now()
 	
 	// Create context with timeout
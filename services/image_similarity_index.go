@@ -0,0 +1,643 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// maxFetchedImageSize 索引時下載圖片的大小上限（50MB）
+const maxFetchedImageSize = 50 * 1024 * 1024
+
+// ImageIndexStore 以圖搜圖索引的儲存介面：圖片 chunk 的感知雜湊與 CLIP 向量
+type ImageIndexStore interface {
+	// ListImages 列出所有圖片 chunk 及已建立的索引資料
+	ListImages(ctx context.Context) ([]ImageIndexEntry, error)
+	// GetImage 取得單一圖片 chunk 的索引資料
+	GetImage(ctx context.Context, chunkID string) (*ImageIndexEntry, error)
+	// SaveImageIndex 儲存感知雜湊，vector 非空時一併儲存圖片向量
+	SaveImageIndex(ctx context.Context, chunkID string, hashes *PerceptualHashes, vector []float64) error
+}
+
+// ImageIndexEntry 圖片 chunk 的索引資料
+type ImageIndexEntry struct {
+	ChunkID     string
+	ImageURL    string
+	Description string
+	Tags        []string
+	Metadata    map[string]interface{}
+	CreatedAt   time.Time
+	Hashes      *PerceptualHashes // 尚未建立索引時為 nil
+	Vector      []float64         // 尚無圖片向量時為空
+}
+
+// ImageSimilarityTuning 相似度判定閾值
+type ImageSimilarityTuning struct {
+	// MaxHashDistance pHash 漢明距離不超過此值即視為相似（0~64）
+	MaxHashDistance int `json:"max_hash_distance"`
+	// EmbeddingThreshold CLIP 餘弦相似度達到此值即視為相似（0~1）
+	EmbeddingThreshold float64 `json:"embedding_threshold"`
+	// HashWeight 兩種訊號都可用時，雜湊相似度在綜合分數中的權重（0~1）
+	HashWeight float64 `json:"hash_weight"`
+}
+
+// DefaultImageSimilarityTuning 預設相似度閾值
+func DefaultImageSimilarityTuning() ImageSimilarityTuning {
+	return ImageSimilarityTuning{
+		MaxHashDistance:    10,
+		EmbeddingThreshold: 0.85,
+		HashWeight:         0.5,
+	}
+}
+
+// FindSimilarImagesRequest 相似圖片查詢：以既有圖片 chunk 或原始圖片內容查詢
+type FindSimilarImagesRequest struct {
+	ChunkID string `json:"chunk_id,omitempty"`
+	Data    []byte `json:"data,omitempty"`
+	Limit   int    `json:"limit,omitempty"`
+	// MinScore 綜合分數下限
+	MinScore float64 `json:"min_score,omitempty"`
+	// 以下欄位覆寫服務預設閾值
+	MaxHashDistance    *int     `json:"max_hash_distance,omitempty"`
+	EmbeddingThreshold *float64 `json:"embedding_threshold,omitempty"`
+	HashWeight         *float64 `json:"hash_weight,omitempty"`
+}
+
+// ImageIndexOptions 批次索引選項
+type ImageIndexOptions struct {
+	// Reindex 重新計算已有索引的圖片
+	Reindex bool `json:"reindex"`
+	// Concurrency 同時下載與計算雜湊的圖片數
+	Concurrency int `json:"concurrency"`
+	// BatchSize 每次向 CLIP 服務請求的圖片數
+	BatchSize int `json:"batch_size"`
+}
+
+// ImageIndexResult 批次索引結果
+type ImageIndexResult struct {
+	Total             int               `json:"total"`
+	Indexed           int               `json:"indexed"`
+	Skipped           int               `json:"skipped"`
+	Failed            int               `json:"failed"`
+	EmbeddingFailures int               `json:"embedding_failures"`
+	Errors            []ImageIndexError `json:"errors,omitempty"`
+	Duration          time.Duration     `json:"duration"`
+}
+
+// ImageIndexError 單張圖片的索引錯誤
+type ImageIndexError struct {
+	ChunkID string `json:"chunk_id"`
+	Error   string `json:"error"`
+}
+
+// imageFeatures 比對用的圖片特徵
+type imageFeatures struct {
+	hashes *PerceptualHashes
+	vector []float64
+}
+
+// SetIndexStore 設定索引儲存，FindSimilarImages 與 IndexImages 需要此設定
+func (i *ImageSimilaritySearch) SetIndexStore(store ImageIndexStore) {
+	i.indexStore = store
+}
+
+// SetTuning 設定相似度閾值，超出範圍的值會被忽略
+func (i *ImageSimilaritySearch) SetTuning(tuning ImageSimilarityTuning) {
+	i.tuningMu.Lock()
+	defer i.tuningMu.Unlock()
+	if tuning.MaxHashDistance >= 0 && tuning.MaxHashDistance <= 64 {
+		i.tuning.MaxHashDistance = tuning.MaxHashDistance
+	}
+	if tuning.EmbeddingThreshold >= 0 && tuning.EmbeddingThreshold <= 1 {
+		i.tuning.EmbeddingThreshold = tuning.EmbeddingThreshold
+	}
+	if tuning.HashWeight >= 0 && tuning.HashWeight <= 1 {
+		i.tuning.HashWeight = tuning.HashWeight
+	}
+}
+
+// Tuning 取得目前的相似度閾值
+func (i *ImageSimilaritySearch) Tuning() ImageSimilarityTuning {
+	i.tuningMu.RLock()
+	defer i.tuningMu.RUnlock()
+	return i.tuning
+}
+
+// FindSimilarImages 結合感知雜湊與 CLIP 向量尋找相似圖片
+//
+// 雜湊找出近似重複（縮放、壓縮、裁切邊緣），向量找出語意相近的圖片；
+// 符合任一閾值的圖片會以綜合分數排序回傳。
+func (i *ImageSimilaritySearch) FindSimilarImages(ctx context.Context, req *FindSimilarImagesRequest) (*ImageSearchResponse, error) {
+	startTime := time.Now()
+
+	if i.indexStore == nil {
+		return nil, fmt.Errorf("image index store is not configured")
+	}
+	if req == nil || (req.ChunkID == "" && len(req.Data) == 0) {
+		return nil, fmt.Errorf("either chunk_id or image data is required")
+	}
+
+	tuning := i.resolveTuning(req)
+	limit := req.Limit
+	if limit <= 0 {
+		limit = i.maxResults
+	}
+
+	query, err := i.queryFeatures(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := i.indexStore.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexed images: %w", err)
+	}
+
+	results := make([]ImageSearchResult, 0)
+	for _, entry := range entries {
+		if entry.ChunkID == req.ChunkID {
+			continue
+		}
+
+		result, ok := i.scoreImageMatch(query, imageFeatures{hashes: entry.Hashes, vector: entry.Vector}, tuning)
+		if !ok || result.Similarity < req.MinScore {
+			continue
+		}
+
+		result.ChunkID = entry.ChunkID
+		result.ImageURL = entry.ImageURL
+		result.Description = entry.Description
+		result.Tags = entry.Tags
+		result.Metadata = entry.Metadata
+		result.CreatedAt = entry.CreatedAt
+		results = append(results, result)
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].Similarity > results[b].Similarity
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	response := &ImageSearchResponse{
+		Results:     results,
+		TotalCount:  len(results),
+		SearchTime:  time.Since(startTime),
+		QueryType:   "chunk",
+		QuerySource: req.ChunkID,
+	}
+	if req.ChunkID == "" {
+		response.QueryType = "upload"
+		response.QuerySource = fmt.Sprintf("%d bytes", len(req.Data))
+	}
+	return response, nil
+}
+
+// IndexImages 為既有圖片批次計算感知雜湊並補齊缺少的 CLIP 向量
+func (i *ImageSimilaritySearch) IndexImages(ctx context.Context, opts *ImageIndexOptions) (*ImageIndexResult, error) {
+	startTime := time.Now()
+
+	if i.indexStore == nil {
+		return nil, fmt.Errorf("image index store is not configured")
+	}
+	if opts == nil {
+		opts = &ImageIndexOptions{}
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 4
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 16
+	}
+
+	entries, err := i.indexStore.ListImages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	result := &ImageIndexResult{Total: len(entries)}
+	var pending []ImageIndexEntry
+	for _, entry := range entries {
+		needsVector := len(entry.Vector) == 0 && i.embeddingService != nil
+		if opts.Reindex || entry.Hashes == nil || needsVector {
+			pending = append(pending, entry)
+		} else {
+			result.Skipped++
+		}
+	}
+
+	var mu sync.Mutex
+	recordError := func(chunkID string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		result.Failed++
+		result.Errors = append(result.Errors, ImageIndexError{ChunkID: chunkID, Error: err.Error()})
+	}
+
+	for start := 0; start < len(pending); start += batchSize {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		batch := pending[start:min(start+batchSize, len(pending))]
+
+		// 一次向 CLIP 服務請求整批圖片向量，失敗時仍保存雜湊
+		vectors, embeddingFailures := i.embedBatch(ctx, batch, opts.Reindex)
+		result.EmbeddingFailures += embeddingFailures
+
+		semaphore := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for idx, entry := range batch {
+			vector := vectors[idx]
+
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func(entry ImageIndexEntry, vector []float64) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+
+				if err := i.indexImage(ctx, entry, vector, opts.Reindex); err != nil {
+					recordError(entry.ChunkID, err)
+					return
+				}
+				mu.Lock()
+				result.Indexed++
+				mu.Unlock()
+			}(entry, vector)
+		}
+		wg.Wait()
+	}
+
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// indexImage 計算並儲存單張圖片的索引
+func (i *ImageSimilaritySearch) indexImage(ctx context.Context, entry ImageIndexEntry, vector []float64, reindex bool) error {
+	hashes := entry.Hashes
+	if hashes == nil || reindex {
+		if entry.ImageURL == "" {
+			return fmt.Errorf("no image URL found for chunk %s", entry.ChunkID)
+		}
+		data, err := i.fetchImage(ctx, entry.ImageURL)
+		if err != nil {
+			return fmt.Errorf("failed to fetch image: %w", err)
+		}
+		hashes, err = ComputePerceptualHashes(data)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := i.indexStore.SaveImageIndex(ctx, entry.ChunkID, hashes, vector); err != nil {
+		return fmt.Errorf("failed to save image index: %w", err)
+	}
+
+	// chunk metadata 已變更，清除 chunk 快取
+	if i.cacheService != nil {
+		i.cacheService.Delete(ctx, fmt.Sprintf("chunk:%s", entry.ChunkID))
+	}
+	return nil
+}
+
+// embedBatch 為批次中缺少向量的圖片產生 CLIP 向量，回傳與 batch 對應的向量（不需更新者為 nil）及失敗數
+func (i *ImageSimilaritySearch) embedBatch(ctx context.Context, batch []ImageIndexEntry, reindex bool) ([][]float64, int) {
+	vectors := make([][]float64, len(batch))
+	if i.embeddingService == nil {
+		return vectors, 0
+	}
+
+	var urls []string
+	var positions []int
+	for idx, entry := range batch {
+		if (reindex || len(entry.Vector) == 0) && entry.ImageURL != "" {
+			urls = append(urls, entry.ImageURL)
+			positions = append(positions, idx)
+		}
+	}
+
+	if len(urls) == 0 {
+		return vectors, 0
+	}
+
+	embeddings, err := i.embeddingService.GenerateBatchEmbeddings(ctx, urls)
+	if err != nil || len(embeddings) != len(urls) {
+		return vectors, len(urls)
+	}
+	for idx, position := range positions {
+		vectors[position] = embeddings[idx]
+	}
+	return vectors, 0
+}
+
+// resolveTuning 以請求中的覆寫值調整預設閾值
+func (i *ImageSimilaritySearch) resolveTuning(req *FindSimilarImagesRequest) ImageSimilarityTuning {
+	tuning := i.Tuning()
+	if req.MaxHashDistance != nil {
+		tuning.MaxHashDistance = *req.MaxHashDistance
+	}
+	if req.EmbeddingThreshold != nil {
+		tuning.EmbeddingThreshold = *req.EmbeddingThreshold
+	}
+	if req.HashWeight != nil {
+		tuning.HashWeight = *req.HashWeight
+	}
+	return tuning
+}
+
+// queryFeatures 取得查詢圖片的雜湊與向量
+func (i *ImageSimilaritySearch) queryFeatures(ctx context.Context, req *FindSimilarImagesRequest) (*imageFeatures, error) {
+	if req.ChunkID == "" {
+		hashes, err := ComputePerceptualHashes(req.Data)
+		if err != nil {
+			return nil, err
+		}
+		// 向量化失敗時僅以雜湊比對
+		vector, _ := i.generateEmbedding(ctx, imageDataURL(req.Data))
+		return &imageFeatures{hashes: hashes, vector: vector}, nil
+	}
+
+	entry, err := i.indexStore.GetImage(ctx, req.ChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image chunk: %w", err)
+	}
+
+	features := &imageFeatures{hashes: entry.Hashes, vector: entry.Vector}
+	if features.hashes == nil && entry.ImageURL != "" {
+		if data, err := i.fetchImage(ctx, entry.ImageURL); err == nil {
+			features.hashes, _ = ComputePerceptualHashes(data)
+		}
+	}
+	if len(features.vector) == 0 && entry.ImageURL != "" {
+		features.vector, _ = i.generateEmbedding(ctx, entry.ImageURL)
+	}
+
+	if features.hashes == nil && len(features.vector) == 0 {
+		return nil, fmt.Errorf("chunk %s has no perceptual hash or image vector; run image indexing first", req.ChunkID)
+	}
+	return features, nil
+}
+
+// scoreImageMatch 判斷候選圖片是否相似並計算綜合分數
+func (i *ImageSimilaritySearch) scoreImageMatch(query *imageFeatures, candidate imageFeatures, tuning ImageSimilarityTuning) (ImageSearchResult, bool) {
+	var result ImageSearchResult
+
+	hasHash := query.hashes != nil && candidate.hashes != nil
+	hasVector := len(query.vector) > 0 && len(query.vector) == len(candidate.vector)
+
+	matched := false
+	if hasHash {
+		distance := HammingDistance(query.hashes.PHash, candidate.hashes.PHash)
+		result.HashDistance = &distance
+		result.HashSimilarity = query.hashes.Similarity(candidate.hashes)
+		matched = distance <= tuning.MaxHashDistance
+	}
+	if hasVector {
+		result.EmbeddingSimilarity = i.calculateCosineSimilarity(query.vector, candidate.vector)
+		matched = matched || result.EmbeddingSimilarity >= tuning.EmbeddingThreshold
+	}
+	if !matched {
+		return result, false
+	}
+
+	switch {
+	case hasHash && hasVector:
+		result.Similarity = tuning.HashWeight*result.HashSimilarity + (1-tuning.HashWeight)*result.EmbeddingSimilarity
+	case hasHash:
+		result.Similarity = result.HashSimilarity
+	default:
+		result.Similarity = result.EmbeddingSimilarity
+	}
+	return result, true
+}
+
+// generateEmbedding 產生圖片向量，未設定向量化服務時回傳錯誤
+func (i *ImageSimilaritySearch) generateEmbedding(ctx context.Context, imageURL string) ([]float64, error) {
+	if i.embeddingService == nil {
+		return nil, fmt.Errorf("image embedding service is not configured")
+	}
+	return i.embeddingService.GenerateEmbedding(ctx, imageURL)
+}
+
+// imageDataURL 將原始圖片內容編碼為 data URL，供 CLIP 服務直接讀取
+func imageDataURL(data []byte) string {
+	return "data:" + http.DetectContentType(data) + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// fetchImageOverHTTP 下載圖片內容
+func fetchImageOverHTTP(client *http.Client) func(ctx context.Context, imageURL string) ([]byte, error) {
+	return func(ctx context.Context, imageURL string) ([]byte, error) {
+		if !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") {
+			return nil, fmt.Errorf("unsupported image URL: %s", imageURL)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imageURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download image: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to download image: status %d", resp.StatusCode)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(resp.Body, maxFetchedImageSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image: %w", err)
+		}
+		if len(data) > maxFetchedImageSize {
+			return nil, fmt.Errorf("image exceeds %d bytes", maxFetchedImageSize)
+		}
+		return data, nil
+	}
+}
+
+// ============================================================================
+// POSTGRES INDEX STORE
+// ============================================================================
+
+// postgresImageIndexStore 將感知雜湊存於 chunks.metadata，圖片向量存於 chunks.vector
+type postgresImageIndexStore struct {
+	db *sql.DB
+
+	mu            sync.Mutex
+	vectorChecked bool
+	hasVector     bool
+}
+
+// NewPostgresImageIndexStore 建立 PostgreSQL 圖片索引儲存
+func NewPostgresImageIndexStore(db *sql.DB) ImageIndexStore {
+	return &postgresImageIndexStore{db: db}
+}
+
+// vectorColumn 檢查是否已套用多模態向量 migration
+func (s *postgresImageIndexStore) vectorColumn(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.vectorChecked {
+		hasVector, err := snapshotColumnExists(ctx, s.db, "chunks", "vector")
+		if err != nil {
+			return false, err
+		}
+		s.hasVector, s.vectorChecked = hasVector, true
+	}
+	return s.hasVector, nil
+}
+
+func (s *postgresImageIndexStore) selectQuery(ctx context.Context, where string) (string, error) {
+	hasVector, err := s.vectorColumn(ctx)
+	if err != nil {
+		return "", err
+	}
+	vectorExpr := "NULL::text"
+	if hasVector {
+		vectorExpr = "CASE WHEN vector_type = 'image' THEN vector::text END"
+	}
+	return fmt.Sprintf(`
+		SELECT chunk_id, contents, tags, metadata, created_time, %s
+		FROM chunks
+		WHERE metadata->>'media_type' = 'image'%s
+		ORDER BY created_time`, vectorExpr, where), nil
+}
+
+func (s *postgresImageIndexStore) ListImages(ctx context.Context) ([]ImageIndexEntry, error) {
+	query, err := s.selectQuery(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query image chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ImageIndexEntry
+	for rows.Next() {
+		entry, err := scanImageIndexEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate image chunks: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *postgresImageIndexStore) GetImage(ctx context.Context, chunkID string) (*ImageIndexEntry, error) {
+	query, err := s.selectQuery(ctx, " AND chunk_id = $1")
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := scanImageIndexEntry(s.db.QueryRowContext(ctx, query, chunkID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("image chunk not found: %s", chunkID)
+	}
+	return entry, err
+}
+
+func (s *postgresImageIndexStore) SaveImageIndex(ctx context.Context, chunkID string, hashes *PerceptualHashes, vector []float64) error {
+	hashJSON, err := json.Marshal(hashes.ToMetadata())
+	if err != nil {
+		return fmt.Errorf("failed to serialize perceptual hash: %w", err)
+	}
+
+	query := `
+		UPDATE chunks SET
+			metadata = jsonb_set(COALESCE(metadata, '{}'::jsonb), '{perceptual_hash}', $2::jsonb),
+			last_updated = NOW(), version = version + 1
+		WHERE chunk_id = $1`
+	args := []interface{}{chunkID, string(hashJSON)}
+
+	if len(vector) > 0 {
+		hasVector, err := s.vectorColumn(ctx)
+		if err != nil {
+			return err
+		}
+		if hasVector {
+			vectorJSON, err := json.Marshal(vector)
+			if err != nil {
+				return fmt.Errorf("failed to serialize vector: %w", err)
+			}
+			// pgvector 接受 JSON 陣列格式的文字輸入
+			query = `
+				UPDATE chunks SET
+					metadata = jsonb_set(COALESCE(metadata, '{}'::jsonb), '{perceptual_hash}', $2::jsonb),
+					vector = $3::vector, vector_type = $4, vector_model = $5,
+					last_updated = NOW(), version = version + 1
+				WHERE chunk_id = $1`
+			args = append(args, string(vectorJSON), string(models.VectorTypeImage), string(models.VectorModelCLIPViTB32))
+		}
+	}
+
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update image index: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	return nil
+}
+
+// scanImageIndexEntry 讀取一筆圖片索引資料
+func scanImageIndexEntry(row interface{ Scan(...interface{}) error }) (*ImageIndexEntry, error) {
+	var entry ImageIndexEntry
+	var tags pq.StringArray
+	var metadataBytes []byte
+	var vectorText sql.NullString
+
+	if err := row.Scan(&entry.ChunkID, &entry.Description, &tags, &metadataBytes, &entry.CreatedAt, &vectorText); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan image chunk: %w", err)
+	}
+
+	entry.Tags = []string(tags)
+	entry.Metadata = make(map[string]interface{})
+	if len(metadataBytes) > 0 {
+		if err := json.Unmarshal(metadataBytes, &entry.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse metadata for chunk %s: %w", entry.ChunkID, err)
+		}
+	}
+
+	record := models.UnifiedChunkRecord{Metadata: entry.Metadata}
+	entry.ImageURL = record.GetImageURL()
+	if hashes, ok := ParsePerceptualHashes(entry.Metadata); ok {
+		entry.Hashes = hashes
+	}
+
+	// pgvector 的文字格式 "[0.1,0.2]" 即 JSON 陣列
+	if vectorText.Valid {
+		if err := json.Unmarshal([]byte(vectorText.String), &entry.Vector); err != nil {
+			return nil, fmt.Errorf("failed to parse vector for chunk %s: %w", entry.ChunkID, err)
+		}
+	}
+	return &entry, nil
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryImageIndexStore keeps image index entries in memory for tests
+type memoryImageIndexStore struct {
+	mu      sync.Mutex
+	entries []ImageIndexEntry
+}
+
+func (s *memoryImageIndexStore) ListImages(ctx context.Context) ([]ImageIndexEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]ImageIndexEntry(nil), s.entries...), nil
+}
+
+func (s *memoryImageIndexStore) GetImage(ctx context.Context, chunkID string) (*ImageIndexEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, entry := range s.entries {
+		if entry.ChunkID == chunkID {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("image chunk not found: %s", chunkID)
+}
+
+func (s *memoryImageIndexStore) SaveImageIndex(ctx context.Context, chunkID string, hashes *PerceptualHashes, vector []float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for idx := range s.entries {
+		if s.entries[idx].ChunkID == chunkID {
+			s.entries[idx].Hashes = hashes
+			if len(vector) > 0 {
+				s.entries[idx].Vector = vector
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("chunk not found: %s", chunkID)
+}
+
+// sceneImage draws a horizontal gradient with a bright disc and a dark rectangle,
+// proportionally scaled to the given size
+func sceneImage(width, height int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			value := 40 + 120*fx
+			if (fx-0.3)*(fx-0.3)+(fy-0.35)*(fy-0.35) < 0.04 {
+				value = 230
+			}
+			if fx > 0.55 && fx < 0.9 && fy > 0.6 && fy < 0.85 {
+				value = 15
+			}
+			img.SetGray(x, y, color.Gray{Y: uint8(value)})
+		}
+	}
+	return img
+}
+
+// checkerboardImage draws an 8x8 checkerboard
+func checkerboardImage(size int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	cell := size / 8
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if (x/cell+y/cell)%2 == 0 {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestPerceptualHashes(t *testing.T) {
+	original, err := ComputePerceptualHashes(encodePNG(t, sceneImage(400, 300)))
+	require.NoError(t, err)
+	resized, err := ComputePerceptualHashes(encodePNG(t, sceneImage(97, 73)))
+	require.NoError(t, err)
+	different, err := ComputePerceptualHashes(encodePNG(t, checkerboardImage(256)))
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, HammingDistance(original.PHash, resized.PHash), 8, "resizing keeps the pHash")
+	assert.LessOrEqual(t, HammingDistance(original.DHash, resized.DHash), 8, "resizing keeps the dHash")
+	assert.Greater(t, HammingDistance(original.PHash, different.PHash), 20)
+	assert.Greater(t, original.Similarity(resized), original.Similarity(different))
+
+	parsed, ok := ParsePerceptualHashes(map[string]interface{}{perceptualHashMetadataKey: original.ToMetadata()})
+	require.True(t, ok)
+	assert.Equal(t, original, parsed)
+
+	_, err = ComputePerceptualHashes([]byte("not an image"))
+	assert.Error(t, err)
+}
+
+func TestImageSimilaritySearch_FindSimilarImages(t *testing.T) {
+	ctx := context.Background()
+	hash := func(img image.Image) *PerceptualHashes {
+		hashes, err := HashImage(img)
+		require.NoError(t, err)
+		return hashes
+	}
+
+	store := &memoryImageIndexStore{entries: []ImageIndexEntry{
+		{ChunkID: "original", Hashes: hash(sceneImage(400, 300)), Vector: []float64{1, 0, 0}},
+		{ChunkID: "thumbnail", Hashes: hash(sceneImage(100, 75)), Vector: []float64{0.9, 0.1, 0}},
+		{ChunkID: "same-subject", Hashes: hash(checkerboardImage(256)), Vector: []float64{0.95, 0.05, 0}},
+		{ChunkID: "unrelated", Hashes: hash(checkerboardImage(128)), Vector: []float64{0, 0, 1}},
+	}}
+
+	search := NewImageSimilaritySearch(nil, nil, nil, nil)
+	search.SetIndexStore(store)
+
+	response, err := search.FindSimilarImages(ctx, &FindSimilarImagesRequest{ChunkID: "original"})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, "thumbnail", response.Results[0].ChunkID, "hash and embedding matches rank first")
+	assert.Equal(t, "same-subject", response.Results[1].ChunkID, "embedding-only matches are included")
+	assert.NotNil(t, response.Results[0].HashDistance)
+
+	// Tightening the embedding threshold leaves only the near-duplicate
+	threshold := 0.999
+	response, err = search.FindSimilarImages(ctx, &FindSimilarImagesRequest{ChunkID: "original", EmbeddingThreshold: &threshold})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, "thumbnail", response.Results[0].ChunkID)
+
+	// Raw bytes without an embedding service fall back to hash matching
+	response, err = search.FindSimilarImages(ctx, &FindSimilarImagesRequest{Data: encodePNG(t, sceneImage(160, 120))})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, "upload", response.QueryType)
+	assert.ElementsMatch(t, []string{"original", "thumbnail"}, []string{response.Results[0].ChunkID, response.Results[1].ChunkID})
+
+	_, err = search.FindSimilarImages(ctx, &FindSimilarImagesRequest{})
+	assert.Error(t, err)
+}
+
+func TestImageSimilaritySearch_IndexImages(t *testing.T) {
+	images := map[string][]byte{
+		"http://images/a.png": encodePNG(t, sceneImage(160, 120)),
+		"http://images/b.png": encodePNG(t, checkerboardImage(128)),
+	}
+	store := &memoryImageIndexStore{entries: []ImageIndexEntry{
+		{ChunkID: "a", ImageURL: "http://images/a.png"},
+		{ChunkID: "b", ImageURL: "http://images/b.png"},
+		{ChunkID: "missing", ImageURL: "http://images/missing.png"},
+	}}
+
+	embedding := NewMockImageEmbeddingService()
+	search := NewImageSimilaritySearch(embedding, nil, nil, nil)
+	search.SetIndexStore(store)
+	search.fetchImage = func(ctx context.Context, imageURL string) ([]byte, error) {
+		if data, ok := images[imageURL]; ok {
+			return data, nil
+		}
+		return nil, fmt.Errorf("status 404")
+	}
+
+	result, err := search.IndexImages(context.Background(), &ImageIndexOptions{BatchSize: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, 2, result.Indexed)
+	assert.Equal(t, 1, result.Failed)
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "missing", result.Errors[0].ChunkID)
+
+	indexed, err := store.GetImage(context.Background(), "a")
+	require.NoError(t, err)
+	assert.NotNil(t, indexed.Hashes)
+	assert.Len(t, indexed.Vector, 512)
+
+	// A second run only retries the image that failed
+	result, err = search.IndexImages(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Skipped)
+	assert.Equal(t, 1, result.Failed)
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net/http"
 	"sort"
+	"sync"
 	"time"
 
 	"semantic-text-processor/models"
@@ -21,6 +23,12 @@ type ImageSimilaritySearch struct {
 	maxResults         int
 	cacheEnabled       bool
 	cacheTTL           time.Duration
+
+	// 感知雜湊與向量索引（FindSimilarImages、IndexImages 使用）
+	indexStore ImageIndexStore
+	fetchImage func(ctx context.Context, imageURL string) ([]byte, error)
+	tuningMu   sync.RWMutex
+	tuning     ImageSimilarityTuning
 }
 
 // NewImageSimilaritySearch 建立新的以圖搜圖服務
@@ -39,6 +47,8 @@ func NewImageSimilaritySearch(
 		maxResults:          50,
 		cacheEnabled:        true,
 		cacheTTL:            30 * time.Minute,
+		fetchImage:          fetchImageOverHTTP(&http.Client{Timeout: 30 * time.Second}),
+		tuning:              DefaultImageSimilarityTuning(),
 	}
 }
 
@@ -60,7 +70,7 @@ func (i *ImageSimilaritySearch) SearchByImageURL(ctx context.Context, imageURL s
 	}
 	
 	// 生成查詢向量
-	queryVector, err := i.embeddingService.GenerateEmbedding(ctx, imageURL)
+	queryVector, err := i.generateEmbedding(ctx, imageURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding for image URL: %w", err)
 	}
@@ -86,6 +96,10 @@ func (i *ImageSimilaritySearch) SearchByImageURL(ctx context.Context, imageURL s
 // SearchByImageFile 使用上傳的圖片檔案搜尋相似圖片
 func (i *ImageSimilaritySearch) SearchByImageFile(ctx context.Context, imageFile io.Reader, filename string, options *ImageSearchOptions) (*ImageSearchResponse, error) {
 	startTime := time.Now()
+
+	if i.mediaProcessor == nil {
+		return nil, fmt.Errorf("media processor is not configured; use FindSimilarImages for raw image data")
+	}
 	
 	// 暫時處理圖片檔案
 	tempResult, err := i.processTemporaryImage(ctx, imageFile, filename)
@@ -95,7 +109,7 @@ func (i *ImageSimilaritySearch) SearchByImageFile(ctx context.Context, imageFile
 	defer i.cleanupTemporaryImage(ctx, tempResult.URL)
 	
 	// 生成查詢向量
-	queryVector, err := i.embeddingService.GenerateEmbedding(ctx, tempResult.URL)
+	queryVector, err := i.generateEmbedding(ctx, tempResult.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate embedding for uploaded image: %w", err)
 	}
@@ -150,7 +164,7 @@ func (i *ImageSimilaritySearch) SearchByChunkID(ctx context.Context, chunkID str
 			return nil, fmt.Errorf("no image URL found for chunk %s", chunkID)
 		}
 		
-		queryVector, err = i.embeddingService.GenerateEmbedding(ctx, imageURL)
+		queryVector, err = i.generateEmbedding(ctx, imageURL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to generate embedding for chunk image: %w", err)
 		}
@@ -331,6 +345,11 @@ type ImageSearchResult struct {
 	Tags        []string          `json:"tags"`
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 	CreatedAt   time.Time         `json:"created_at"`
+
+	// FindSimilarImages 的個別訊號分數
+	HashDistance        *int    `json:"hash_distance,omitempty"`
+	HashSimilarity      float64 `json:"hash_similarity,omitempty"`
+	EmbeddingSimilarity float64 `json:"embedding_similarity,omitempty"`
 }
 
 // DuplicateSearchOptions 重複圖片搜尋選項
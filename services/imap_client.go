@@ -0,0 +1,198 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapClient speaks the few IMAP4rev1 commands email ingestion needs: logging in, selecting
+// a mailbox, searching it for unread messages, fetching them and marking them read. It runs
+// one command at a time.
+type imapClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	tag    int
+	// maxLiteral caps the size of one message read from the server
+	maxLiteral int
+}
+
+// imapResponse is an untagged response line with the literals it carried
+type imapResponse struct {
+	line     string
+	literals [][]byte
+}
+
+var imapLiteralPattern = regexp.MustCompile(`\{(\d+)\}$`)
+
+// dialIMAP connects to an IMAPS server and reads its greeting
+func dialIMAP(ctx context.Context, addr string, maxLiteral int) (*imapClient, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid IMAP address %s: %w", addr, err)
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IMAP server: %w", err)
+	}
+	client, err := newIMAPClient(conn, maxLiteral)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
+// newIMAPClient reads the greeting of a server on an open connection
+func newIMAPClient(conn net.Conn, maxLiteral int) (*imapClient, error) {
+	client := &imapClient{conn: conn, reader: bufio.NewReader(conn), maxLiteral: maxLiteral}
+	greeting, err := client.readLine()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read IMAP greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return nil, fmt.Errorf("IMAP server refused the connection: %s", greeting)
+	}
+	return client, nil
+}
+
+// Login authenticates with a username and password
+func (c *imapClient) Login(username, password string) error {
+	_, err := c.command("LOGIN " + imapQuote(username) + " " + imapQuote(password))
+	if err != nil {
+		return fmt.Errorf("IMAP login failed: %w", err)
+	}
+	return nil
+}
+
+// Select opens a mailbox for reading and writing
+func (c *imapClient) Select(mailbox string) error {
+	_, err := c.command("SELECT " + imapQuote(mailbox))
+	if err != nil {
+		return fmt.Errorf("failed to select mailbox %s: %w", mailbox, err)
+	}
+	return nil
+}
+
+// SearchUnseen returns the UIDs of the unread messages in the selected mailbox
+func (c *imapClient) SearchUnseen() ([]uint32, error) {
+	responses, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, fmt.Errorf("failed to search mailbox: %w", err)
+	}
+	var uids []uint32
+	for _, response := range responses {
+		fields, ok := strings.CutPrefix(response.line, "* SEARCH")
+		if !ok {
+			continue
+		}
+		for _, field := range strings.Fields(fields) {
+			uid, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid UID %q in search response", field)
+			}
+			uids = append(uids, uint32(uid))
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns a message without marking it read
+func (c *imapClient) Fetch(uid uint32) ([]byte, error) {
+	responses, err := c.command(fmt.Sprintf("UID FETCH %d (BODY.PEEK[])", uid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch message %d: %w", uid, err)
+	}
+	for _, response := range responses {
+		if strings.Contains(response.line, "FETCH") && len(response.literals) > 0 {
+			return response.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("message %d not found", uid)
+}
+
+// MarkSeen marks a message read, so it is not fetched again
+func (c *imapClient) MarkSeen(uid uint32) error {
+	if _, err := c.command(fmt.Sprintf(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)); err != nil {
+		return fmt.Errorf("failed to mark message %d read: %w", uid, err)
+	}
+	return nil
+}
+
+// Close logs out and closes the connection
+func (c *imapClient) Close() error {
+	c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+// command sends a command and returns its untagged responses once the server completes it
+func (c *imapClient) command(command string) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%d", c.tag)
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, command); err != nil {
+		return nil, err
+	}
+
+	var responses []imapResponse
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if status, ok := strings.CutPrefix(line, tag+" "); ok {
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("%s", status)
+			}
+			return responses, nil
+		}
+
+		response := imapResponse{line: line}
+		// A line ending in {n} continues after n bytes of literal data
+		for {
+			match := imapLiteralPattern.FindStringSubmatch(line)
+			if match == nil {
+				break
+			}
+			size, err := strconv.Atoi(match[1])
+			if err != nil || size > c.maxLiteral {
+				return nil, fmt.Errorf("literal of %s bytes exceeds the limit of %d", match[1], c.maxLiteral)
+			}
+			literal := make([]byte, size)
+			if _, err := io.ReadFull(c.reader, literal); err != nil {
+				return nil, err
+			}
+			response.literals = append(response.literals, literal)
+			if line, err = c.readLine(); err != nil {
+				return nil, err
+			}
+			response.line += line
+		}
+		responses = append(responses, response)
+	}
+}
+
+func (c *imapClient) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// setDeadline bounds the remaining exchange with the server
+func (c *imapClient) setDeadline(deadline time.Time) {
+	c.conn.SetDeadline(deadline)
+}
+
+// imapQuote quotes a string argument
+func imapQuote(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value) + `"`
+}
@@ -29,7 +29,7 @@ func TestStorageServiceIntegration(t *testing.T) {
 	}
 	
 	// 建立儲存服務
-	storageService, err := NewStorageService(cfg)
+	storageService, err := NewMediaStorageService(cfg)
 	if err != nil {
 		t.Fatalf("Failed to create storage service: %v", err)
 	}
@@ -26,6 +26,7 @@ type SupabaseClient interface {
 	InsertChunk(ctx context.Context, chunk *models.ChunkRecord) error
 	InsertChunks(ctx context.Context, chunks []models.ChunkRecord) error
 	GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error)
+	GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error)
 	GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error)
 	UpdateChunk(ctx context.Context, chunk *models.ChunkRecord) error
 	DeleteChunk(ctx context.Context, id string) error
@@ -40,6 +41,7 @@ type SupabaseClient interface {
 	CreateTemplateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error)
 	GetTemplateInstances(ctx context.Context, templateChunkID string) ([]models.TemplateInstance, error)
 	UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error
+	UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error)
 
 	// Tag operations
 	AddTag(ctx context.Context, chunkID string, tagContent string) error
@@ -52,6 +54,8 @@ type SupabaseClient interface {
 	GetChildrenChunks(ctx context.Context, parentChunkID string) ([]models.ChunkRecord, error)
 	GetSiblingChunks(ctx context.Context, chunkID string) ([]models.ChunkRecord, error)
 	MoveChunk(ctx context.Context, req *models.MoveChunkRequest) error
+	InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error
+	ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error
 	BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error
 
 	// Search operations
@@ -65,11 +69,14 @@ type SupabaseClient interface {
 	// Graph operations
 	InsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error
 	InsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error
+	UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error
+	UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error
 	SearchGraph(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error)
 	GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error)
 	GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error)
 	FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error)
 	GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error)
+	GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error)
 	GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error)
 
 	// Health check
@@ -88,6 +95,12 @@ type EmbeddingService interface {
 	GenerateBatchEmbeddings(ctx context.Context, texts []string) ([][]float64, error)
 }
 
+// EmbeddingUsageReporter is implemented by embedding services that report the prompt
+// tokens the provider billed for a batch
+type EmbeddingUsageReporter interface {
+	GenerateBatchEmbeddingsWithUsage(ctx context.Context, texts []string) ([][]float64, int, error)
+}
+
 // TemplateService handles template operations
 type TemplateService interface {
 	CreateTemplate(ctx context.Context, req *models.CreateTemplateRequest) (*models.TemplateWithInstances, error)
@@ -95,6 +108,10 @@ type TemplateService interface {
 	GetAllTemplates(ctx context.Context) ([]models.TemplateWithInstances, error)
 	CreateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error)
 	UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error
+	UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error)
+	RenderInstance(ctx context.Context, instanceChunkID, format string) (*models.RenderedInstance, error)
+	QueryInstances(ctx context.Context, templateChunkID string, req *models.QueryInstancesRequest) (*models.InstanceQueryResult, error)
+	TableView(ctx context.Context, templateChunkID string, req *models.TableViewRequest) (*models.TableView, error)
 }
 
 // TagService handles tag operations
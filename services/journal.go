@@ -0,0 +1,279 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// JOURNAL
+// ============================================================================
+//
+// Every caller has one journal page per day, titled with the configured format and marked
+// with its date and owner in metadata. Pages are created the first time a day is asked
+// for, under a PostgreSQL advisory lock, so instances racing to create the same day make
+// one page. Days start at midnight in the configured time zone.
+
+var (
+	// ErrInvalidJournalDate is returned for dates that are not YYYY-MM-DD and for ranges
+	// that are reversed or too long
+	ErrInvalidJournalDate = errors.New("invalid journal date")
+	// ErrJournalDayNotFound is returned when a day has no page and the caller may not create one
+	ErrJournalDayNotFound = errors.New("journal day not found")
+	// ErrInvalidJournalEntry is returned for entries without contents
+	ErrInvalidJournalEntry = errors.New("invalid journal entry")
+)
+
+// maxJournalRangeDays caps the days one calendar query covers
+const maxJournalRangeDays = 366
+
+// JournalService manages daily note pages
+type JournalService interface {
+	// Today returns today's page, creating it on first use when the caller may write
+	Today(ctx context.Context) (*models.JournalDay, error)
+	// GetDay returns the page of a YYYY-MM-DD date, creating it when create is set
+	GetDay(ctx context.Context, date string, create bool) (*models.JournalDay, error)
+	// AppendToToday adds an entry at the end of today's page
+	AppendToToday(ctx context.Context, req *models.JournalAppendRequest) (*models.UnifiedChunkRecord, error)
+	// Entries lists the days between two dates that have pages
+	Entries(ctx context.Context, query *models.JournalRangeQuery) (*models.JournalRange, error)
+}
+
+// journalService implements JournalService
+type journalService struct {
+	db       *sql.DB
+	chunks   UnifiedChunkService
+	pageACLs PageACLService
+	config   config.JournalConfig
+	location *time.Location
+	monitor  QueryPerformanceMonitor
+	now      func() time.Time
+}
+
+// NewJournalService creates a journal service. pageACLs, when set, restricts each journal
+// page to its owner.
+func NewJournalService(db *sql.DB, chunks UnifiedChunkService, pageACLs PageACLService, cfg config.JournalConfig, monitor QueryPerformanceMonitor) (JournalService, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load journal time zone: %w", err)
+	}
+	return &journalService{
+		db:       db,
+		chunks:   chunks,
+		pageACLs: pageACLs,
+		config:   cfg,
+		location: location,
+		monitor:  monitor,
+		now:      time.Now,
+	}, nil
+}
+
+// today returns today's date in the journal's time zone
+func (s *journalService) today() string {
+	return s.now().In(s.location).Format(models.JournalDateLayout)
+}
+
+// Today returns today's page; readers get ErrJournalDayNotFound until a writer creates it
+func (s *journalService) Today(ctx context.Context) (*models.JournalDay, error) {
+	return s.GetDay(ctx, s.today(), Authorize(ctx, PermissionWrite) == nil)
+}
+
+// GetDay returns the page of a date
+func (s *journalService) GetDay(ctx context.Context, date string, create bool) (*models.JournalDay, error) {
+	day, err := s.parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	owner := journalOwner(ctx)
+	found, err := s.findDay(ctx, owner, date)
+	if err != nil || found != nil {
+		return found, err
+	}
+	if !create {
+		return nil, fmt.Errorf("%w: %s", ErrJournalDayNotFound, date)
+	}
+	return s.createDay(ctx, owner, date, day)
+}
+
+// AppendToToday adds an entry to today's page
+func (s *journalService) AppendToToday(ctx context.Context, req *models.JournalAppendRequest) (*models.UnifiedChunkRecord, error) {
+	contents := strings.TrimSpace(req.Contents)
+	if contents == "" {
+		return nil, fmt.Errorf("%w: contents is required", ErrInvalidJournalEntry)
+	}
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	day, err := s.Today(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := make(map[string]interface{}, len(req.Metadata))
+	for key, value := range req.Metadata {
+		metadata[key] = value
+	}
+	entry := &models.UnifiedChunkRecord{
+		ChunkID:  uuid.New().String(),
+		Contents: contents,
+		Parent:   &day.PageID,
+		Page:     &day.PageID,
+		Metadata: metadata,
+	}
+	if err := s.chunks.CreateChunk(ctx, entry); err != nil {
+		return nil, fmt.Errorf("failed to append journal entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Entries lists the days of a range that have pages, oldest first
+func (s *journalService) Entries(ctx context.Context, query *models.JournalRangeQuery) (*models.JournalRange, error) {
+	from, err := s.parseDate(query.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.parseDate(query.To)
+	if err != nil {
+		return nil, err
+	}
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: %s is before %s", ErrInvalidJournalDate, query.To, query.From)
+	}
+	if to.After(from.AddDate(0, 0, maxJournalRangeDays-1)) {
+		return nil, fmt.Errorf("%w: ranges may cover at most %d days", ErrInvalidJournalDate, maxJournalRangeDays)
+	}
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.chunk_id, p.contents, p.metadata->>'`+models.JournalDateKey+`',
+			(SELECT COUNT(*) FROM chunks c WHERE c.parent = p.chunk_id)
+		FROM chunks p
+		WHERE p.is_page = true AND p.metadata ? '`+models.JournalDateKey+`'
+			AND p.metadata->>'`+models.JournalOwnerKey+`' = $1
+			AND p.metadata->>'`+models.JournalDateKey+`' BETWEEN $2 AND $3
+		ORDER BY p.metadata->>'`+models.JournalDateKey+`', p.created_time`,
+		journalOwner(ctx), query.From, query.To)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query journal days: %w", err)
+	}
+	defer rows.Close()
+
+	result := &models.JournalRange{From: query.From, To: query.To, Days: []models.JournalDay{}}
+	for rows.Next() {
+		var day models.JournalDay
+		if err := rows.Scan(&day.PageID, &day.Title, &day.Date, &day.EntryCount); err != nil {
+			return nil, fmt.Errorf("failed to scan journal day: %w", err)
+		}
+		// Keep the first page of a date should one have been created outside the service
+		if n := len(result.Days); n > 0 && result.Days[n-1].Date == day.Date {
+			continue
+		}
+		result.Days = append(result.Days, day)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating journal days: %w", err)
+	}
+	s.monitor.RecordQuery("journal_entries", time.Since(start), len(result.Days))
+
+	if query.IncludeEntries && len(result.Days) > 0 {
+		pageIDs := make([]string, len(result.Days))
+		for i, day := range result.Days {
+			pageIDs[i] = day.PageID
+		}
+		children, err := s.chunks.BatchGetChildren(ctx, pageIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get journal entries: %w", err)
+		}
+		for i := range result.Days {
+			result.Days[i].Entries = children[result.Days[i].PageID]
+		}
+	}
+	return result, nil
+}
+
+// findDay returns the page of a date, or nil when it has none
+func (s *journalService) findDay(ctx context.Context, owner, date string) (*models.JournalDay, error) {
+	start := time.Now()
+	day := &models.JournalDay{Date: date}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT p.chunk_id, p.contents, (SELECT COUNT(*) FROM chunks c WHERE c.parent = p.chunk_id)
+		FROM chunks p
+		WHERE p.is_page = true AND p.metadata ? '`+models.JournalDateKey+`'
+			AND p.metadata->>'`+models.JournalOwnerKey+`' = $1
+			AND p.metadata->>'`+models.JournalDateKey+`' = $2
+		ORDER BY p.created_time LIMIT 1`, owner, date).Scan(&day.PageID, &day.Title, &day.EntryCount)
+	s.monitor.RecordQuery("find_journal_day", time.Since(start), 1)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find journal day: %w", err)
+	}
+	return day, nil
+}
+
+// createDay creates the page of a date. The advisory lock is held until the page is
+// committed, so a racing instance waits and then finds it.
+func (s *journalService) createDay(ctx context.Context, owner, date string, day time.Time) (*models.JournalDay, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock(hashtext($1))", "journal:"+owner+":"+date); err != nil {
+		return nil, fmt.Errorf("failed to lock journal day: %w", err)
+	}
+	if found, err := s.findDay(ctx, owner, date); err != nil || found != nil {
+		return found, err
+	}
+
+	page := &models.UnifiedChunkRecord{
+		ChunkID:  uuid.New().String(),
+		Contents: day.Format(s.config.TitleFormat),
+		IsPage:   true,
+		Metadata: map[string]interface{}{
+			models.JournalDateKey:  date,
+			models.JournalOwnerKey: owner,
+		},
+	}
+	if err := s.chunks.CreateChunk(ctx, page); err != nil {
+		return nil, fmt.Errorf("failed to create journal page: %w", err)
+	}
+	if s.pageACLs != nil && owner != "" {
+		if _, err := s.pageACLs.SetACL(ctx, page.ChunkID, &models.PageACLRequest{Owner: owner}); err != nil {
+			log.Printf("Warning: failed to restrict journal page %s to %s: %v", page.ChunkID, owner, err)
+		}
+	}
+	return &models.JournalDay{Date: date, PageID: page.ChunkID, Title: page.Contents, Created: true}, nil
+}
+
+// parseDate checks a YYYY-MM-DD date, returning midnight of it in the journal's time zone
+func (s *journalService) parseDate(date string) (time.Time, error) {
+	day, err := time.ParseInLocation(models.JournalDateLayout, date, s.location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %q is not YYYY-MM-DD", ErrInvalidJournalDate, date)
+	}
+	return day, nil
+}
+
+// journalOwner is the caller whose journal a request reads. Requests without a caller, made
+// by the gateway itself, share the journal of the empty owner.
+func journalOwner(ctx context.Context) string {
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		return principal.Subject
+	}
+	return ""
+}
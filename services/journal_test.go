@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+func TestJournalService_WithoutDatabase(t *testing.T) {
+	service, err := NewJournalService(nil, nil, nil, config.JournalConfig{TitleFormat: "Jan 2, 2006", Timezone: "Asia/Taipei"}, NewNoOpMonitor())
+	require.NoError(t, err)
+	journal := service.(*journalService)
+
+	t.Run("today follows the time zone", func(t *testing.T) {
+		journal.now = func() time.Time { return time.Date(2026, 10, 16, 17, 30, 0, 0, time.UTC) }
+		assert.Equal(t, "2026-10-17", journal.today(), "01:30 the next day in Taipei")
+	})
+
+	t.Run("dates", func(t *testing.T) {
+		day, err := journal.parseDate("2026-10-16")
+		require.NoError(t, err)
+		assert.Equal(t, "Oct 16, 2026", day.Format(journal.config.TitleFormat))
+		for _, invalid := range []string{"", "16/10/2026", "2026-13-01", "today"} {
+			_, err := journal.GetDay(context.Background(), invalid, false)
+			assert.ErrorIs(t, err, ErrInvalidJournalDate, invalid)
+		}
+	})
+
+	t.Run("ranges", func(t *testing.T) {
+		invalid := []models.JournalRangeQuery{
+			{From: "2026-10-16"},
+			{From: "2026-10-16", To: "2026-10-01"},
+			{From: "2025-01-01", To: "2026-01-02"},
+		}
+		for _, query := range invalid {
+			_, err := journal.Entries(context.Background(), &query)
+			assert.ErrorIs(t, err, ErrInvalidJournalDate, query.From+".."+query.To)
+		}
+	})
+
+	t.Run("entries need contents", func(t *testing.T) {
+		_, err := journal.AppendToToday(context.Background(), &models.JournalAppendRequest{Contents: "  "})
+		assert.ErrorIs(t, err, ErrInvalidJournalEntry)
+		_, err = journal.AppendToToday(contextWithSubject("carol", models.RoleReader), &models.JournalAppendRequest{Contents: "Note"})
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+	})
+
+	_, err = NewJournalService(nil, nil, nil, config.JournalConfig{TitleFormat: "2006-01-02", Timezone: "Mars/Olympus"}, NewNoOpMonitor())
+	assert.Error(t, err)
+}
+
+func TestJournalService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	service, err := NewJournalService(db, chunks, nil, config.JournalConfig{TitleFormat: "Jan 2, 2006", Timezone: "UTC"}, NewNoOpMonitor())
+	require.NoError(t, err)
+	journal := service.(*journalService)
+	journal.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	owner := "journal-test-" + time.Now().Format("150405.000000")
+	ctx := contextWithSubject(owner, models.RoleEditor)
+
+	today, err := journal.Today(ctx)
+	require.NoError(t, err)
+	defer chunks.DeleteSubtree(context.Background(), today.PageID, models.SubtreeDeleteOptions{Confirm: true})
+	assert.True(t, today.Created)
+	assert.Equal(t, "Oct 16, 2026", today.Title)
+
+	entry, err := journal.AppendToToday(ctx, &models.JournalAppendRequest{Contents: "Standup notes"})
+	require.NoError(t, err)
+	assert.Equal(t, today.PageID, *entry.Parent)
+
+	again, err := journal.Today(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, today.PageID, again.PageID, "one page per day")
+	assert.False(t, again.Created)
+	assert.Equal(t, 1, again.EntryCount)
+
+	_, err = journal.GetDay(ctx, "2026-10-15", false)
+	assert.ErrorIs(t, err, ErrJournalDayNotFound)
+
+	days, err := journal.Entries(ctx, &models.JournalRangeQuery{From: "2026-10-10", To: "2026-10-16", IncludeEntries: true})
+	require.NoError(t, err)
+	require.Len(t, days.Days, 1)
+	assert.Equal(t, "2026-10-16", days.Days[0].Date)
+	require.Len(t, days.Days[0].Entries, 1)
+	assert.Equal(t, "Standup notes", days.Days[0].Entries[0].Contents)
+
+	others, err := journal.Entries(contextWithSubject(owner+"-other", models.RoleEditor), &models.JournalRangeQuery{From: "2026-10-16", To: "2026-10-16"})
+	require.NoError(t, err)
+	assert.Empty(t, others.Days, "journals are per caller")
+}
@@ -0,0 +1,53 @@
+package services
+
+import "unicode"
+
+// Content languages detected at ingestion and stored in chunks.lang
+const (
+	LanguageChinese = "zh"
+	LanguageEnglish = "en"
+	LanguageUnknown = "und"
+)
+
+// hanLetterWeight is how many Latin letters one Han character counts as when
+// comparing scripts, since a single character carries about a short word of meaning
+const hanLetterWeight = 3
+
+// DetectLanguage classifies text as Chinese, English or unknown by script. Mixed text
+// is Chinese when its Han characters outweigh its Latin letters. The database applies
+// the same rule in chunk_detect_lang for rows written without a language. Encrypted
+// contents have no language.
+func DetectLanguage(text string) string {
+	if IsEncryptedContent(text) {
+		return LanguageUnknown
+	}
+
+	var han, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			latin++
+		}
+	}
+
+	switch {
+	case han == 0 && latin == 0:
+		return LanguageUnknown
+	case han*hanLetterWeight >= latin:
+		return LanguageChinese
+	default:
+		return LanguageEnglish
+	}
+}
+
+// SearchLanguage returns the language a full-text query is parsed in: the requested
+// language when one is given, otherwise the language detected from the query
+func SearchLanguage(query, requested string) string {
+	switch requested {
+	case LanguageChinese, LanguageEnglish, LanguageUnknown:
+		return requested
+	}
+	return DetectLanguage(query)
+}
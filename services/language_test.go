@@ -0,0 +1,34 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"english", "Full-text search works across languages", LanguageEnglish},
+		{"chinese", "全文檢索支援多種語言", LanguageChinese},
+		{"chinese with english terms", "使用 PostgreSQL 建立全文索引", LanguageChinese},
+		{"english with a chinese name", "Meeting notes with 王小明 about the quarterly roadmap", LanguageEnglish},
+		{"digits and punctuation", "2024-01-15 #42", LanguageUnknown},
+		{"empty", "", LanguageUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DetectLanguage(tt.text))
+		})
+	}
+}
+
+func TestSearchLanguage(t *testing.T) {
+	assert.Equal(t, LanguageChinese, SearchLanguage("資料庫", ""))
+	assert.Equal(t, LanguageEnglish, SearchLanguage("資料庫", LanguageEnglish), "a requested language wins")
+	assert.Equal(t, LanguageChinese, SearchLanguage("資料庫", "klingon"), "unsupported languages are detected instead")
+}
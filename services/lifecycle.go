@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidServiceDependency is returned when a service depends on one that is not
+// registered or the dependencies form a cycle
+var ErrInvalidServiceDependency = errors.New("invalid service dependency")
+
+// Service is a component with background work. Start launches the work and returns once
+// it is running; the context it is given stays valid until the service is stopped. Stop
+// stops the work and waits for it until ctx expires.
+type Service interface {
+	Start(ctx context.Context) error
+	Stop(ctx context.Context) error
+}
+
+// ServiceFuncs adapts a pair of functions to Service. Either may be nil.
+type ServiceFuncs struct {
+	StartFunc func(ctx context.Context) error
+	StopFunc  func(ctx context.Context) error
+}
+
+// Start calls StartFunc
+func (s ServiceFuncs) Start(ctx context.Context) error {
+	if s.StartFunc == nil {
+		return nil
+	}
+	return s.StartFunc(ctx)
+}
+
+// Stop calls StopFunc
+func (s ServiceFuncs) Stop(ctx context.Context) error {
+	if s.StopFunc == nil {
+		return nil
+	}
+	return s.StopFunc(ctx)
+}
+
+// backgroundWorker is a worker that runs a goroutine until Stop, which blocks until the
+// goroutine has finished its current item
+type backgroundWorker interface {
+	Start(ctx context.Context)
+	Stop()
+}
+
+// WorkerService adapts a background worker to Service. Stop returns when ctx expires
+// even if the worker is still finishing.
+func WorkerService(worker backgroundWorker) Service {
+	return ServiceFuncs{
+		StartFunc: func(ctx context.Context) error {
+			worker.Start(ctx)
+			return nil
+		},
+		StopFunc: func(ctx context.Context) error {
+			stopped := make(chan struct{})
+			go func() {
+				defer close(stopped)
+				worker.Stop()
+			}()
+			select {
+			case <-stopped:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	}
+}
+
+type lifecycleEntry struct {
+	name      string
+	service   Service
+	dependsOn []string
+}
+
+// Lifecycle starts services after the services they depend on and stops them in the
+// reverse order, so no service outlives one it uses
+type Lifecycle struct {
+	mu      sync.Mutex
+	entries []lifecycleEntry
+	started []lifecycleEntry
+	cancel  context.CancelFunc
+}
+
+// NewLifecycle creates an empty lifecycle
+func NewLifecycle() *Lifecycle {
+	return &Lifecycle{}
+}
+
+// Register adds a service that starts after the named services
+func (l *Lifecycle) Register(name string, service Service, dependsOn ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, lifecycleEntry{name: name, service: service, dependsOn: dependsOn})
+}
+
+// Order returns the service names in start order: dependencies first, otherwise in
+// registration order
+func (l *Lifecycle) Order() ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entries, err := l.sorted()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.name
+	}
+	return names, nil
+}
+
+// sorted orders the entries depth first; must be called with the lock held
+func (l *Lifecycle) sorted() ([]lifecycleEntry, error) {
+	byName := make(map[string]lifecycleEntry, len(l.entries))
+	for _, entry := range l.entries {
+		byName[entry.name] = entry
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(l.entries))
+	ordered := make([]lifecycleEntry, 0, len(l.entries))
+	var visit func(entry lifecycleEntry) error
+	visit = func(entry lifecycleEntry) error {
+		switch state[entry.name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: cycle through %s", ErrInvalidServiceDependency, entry.name)
+		}
+		state[entry.name] = visiting
+		for _, dependency := range entry.dependsOn {
+			dep, ok := byName[dependency]
+			if !ok {
+				return fmt.Errorf("%w: %s depends on unknown service %s", ErrInvalidServiceDependency, entry.name, dependency)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[entry.name] = visited
+		ordered = append(ordered, entry)
+		return nil
+	}
+
+	for _, entry := range l.entries {
+		if err := visit(entry); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// Start starts the services in dependency order. Services run on a context that keeps
+// ctx's values but is cancelled only by Stop. If a service fails to start, the ones
+// already started are stopped again.
+func (l *Lifecycle) Start(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cancel != nil {
+		return nil
+	}
+
+	entries, err := l.sorted()
+	if err != nil {
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	l.cancel = cancel
+	for _, entry := range entries {
+		if err := entry.service.Start(runCtx); err != nil {
+			if stopErr := l.stopStarted(ctx); stopErr != nil {
+				err = errors.Join(err, stopErr)
+			}
+			return fmt.Errorf("failed to start %s: %w", entry.name, err)
+		}
+		l.started = append(l.started, entry)
+	}
+	return nil
+}
+
+// Stop stops the started services in reverse start order. Once ctx expires, the services
+// not yet stopped are left to the cancellation of their context.
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.stopStarted(ctx)
+}
+
+// stopStarted must be called with the lock held
+func (l *Lifecycle) stopStarted(ctx context.Context) error {
+	if l.cancel == nil {
+		return nil
+	}
+	defer func() {
+		l.cancel()
+		l.cancel = nil
+		l.started = nil
+	}()
+
+	var errs []error
+	for i := len(l.started) - 1; i >= 0; i-- {
+		entry := l.started[i]
+		if ctx.Err() != nil {
+			return errors.Join(append(errs, fmt.Errorf("failed to stop %s: %w", entry.name, ctx.Err()))...)
+		}
+		if err := entry.service.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", entry.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingService appends its start and stop events to a shared log
+func recordingService(name string, events *[]string, startErr error) Service {
+	return ServiceFuncs{
+		StartFunc: func(ctx context.Context) error {
+			if startErr != nil {
+				return startErr
+			}
+			*events = append(*events, "start "+name)
+			return nil
+		},
+		StopFunc: func(ctx context.Context) error {
+			*events = append(*events, "stop "+name)
+			return nil
+		},
+	}
+}
+
+func TestLifecycle_StartsDependenciesFirst(t *testing.T) {
+	var events []string
+	lifecycle := NewLifecycle()
+	lifecycle.Register("maintenance", recordingService("maintenance", &events, nil), "database", "graph_sync")
+	lifecycle.Register("database", recordingService("database", &events, nil))
+	lifecycle.Register("graph_sync", recordingService("graph_sync", &events, nil), "database")
+
+	order, err := lifecycle.Order()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"database", "graph_sync", "maintenance"}, order)
+
+	ctx := context.Background()
+	require.NoError(t, lifecycle.Start(ctx))
+	require.NoError(t, lifecycle.Stop(ctx))
+	assert.Equal(t, []string{
+		"start database", "start graph_sync", "start maintenance",
+		"stop maintenance", "stop graph_sync", "stop database",
+	}, events)
+
+	// Stopping again does nothing
+	require.NoError(t, lifecycle.Stop(ctx))
+	assert.Len(t, events, 6)
+}
+
+func TestLifecycle_InvalidDependencies(t *testing.T) {
+	unknown := NewLifecycle()
+	unknown.Register("maintenance", ServiceFuncs{}, "graph_sync")
+	err := unknown.Start(context.Background())
+	assert.ErrorIs(t, err, ErrInvalidServiceDependency)
+
+	cycle := NewLifecycle()
+	cycle.Register("a", ServiceFuncs{}, "b")
+	cycle.Register("b", ServiceFuncs{}, "a")
+	_, err = cycle.Order()
+	assert.ErrorIs(t, err, ErrInvalidServiceDependency)
+}
+
+func TestLifecycle_StartFailureStopsStartedServices(t *testing.T) {
+	var events []string
+	lifecycle := NewLifecycle()
+	lifecycle.Register("database", recordingService("database", &events, nil))
+	lifecycle.Register("cache", recordingService("cache", &events, nil))
+	lifecycle.Register("graph_sync", recordingService("graph_sync", &events, errors.New("no llm")), "database")
+
+	err := lifecycle.Start(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to start graph_sync")
+	assert.Equal(t, []string{"start database", "start cache", "stop cache", "stop database"}, events)
+}
+
+func TestLifecycle_ServicesRunUntilStopped(t *testing.T) {
+	var runCtx context.Context
+	lifecycle := NewLifecycle()
+	lifecycle.Register("worker", ServiceFuncs{
+		StartFunc: func(ctx context.Context) error {
+			runCtx = ctx
+			return nil
+		},
+	})
+
+	startCtx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, lifecycle.Start(startCtx))
+	cancel()
+	assert.NoError(t, runCtx.Err(), "cancelling the start context does not stop services")
+
+	require.NoError(t, lifecycle.Stop(context.Background()))
+	assert.Error(t, runCtx.Err())
+}
+
+// slowWorker takes delay to stop, like a worker finishing an LLM request
+type slowWorker struct {
+	delay   time.Duration
+	stopped int
+}
+
+func (w *slowWorker) Start(ctx context.Context) {}
+
+func (w *slowWorker) Stop() {
+	time.Sleep(w.delay)
+	w.stopped++
+}
+
+func TestLifecycle_StopDeadline(t *testing.T) {
+	var events []string
+	lifecycle := NewLifecycle()
+	lifecycle.Register("database", recordingService("database", &events, nil))
+	lifecycle.Register("graph_sync", WorkerService(&slowWorker{delay: 200 * time.Millisecond}), "database")
+	require.NoError(t, lifecycle.Start(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := lifecycle.Stop(ctx)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"start database"}, events, "services the worker uses are left running")
+}
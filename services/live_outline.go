@@ -0,0 +1,589 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// LIVE OUTLINE EDITING
+// ============================================================================
+//
+// Clients editing the same page join its room. Each client applies its own operations
+// optimistically and sends them here; the room applies them one at a time, acknowledges
+// or rejects them to the sender and broadcasts the resulting change to everyone else.
+// Changes made outside the room arrive through the change feed and are broadcast too,
+// except those the room already announced, which it recognizes by chunk version.
+
+// LiveOutlineService coordinates live editing sessions on pages
+type LiveOutlineService interface {
+	// Join adds a participant to a page. The session's first message is a welcome listing
+	// the other participants.
+	Join(ctx context.Context, pageID, name string) (*LiveSession, error)
+	// Leave removes a participant and closes its Messages
+	Leave(session *LiveSession)
+	// Apply applies an operation and delivers an ack or reject to the session
+	Apply(ctx context.Context, session *LiveSession, op *models.LiveOperation)
+	// UpdatePresence records where a participant is and relays it to the others
+	UpdatePresence(session *LiveSession, presence models.LivePresence)
+	// Run relays changes from the change feed to the rooms until sub is closed or ctx is
+	// cancelled
+	Run(ctx context.Context, sub *ChangeSubscription)
+}
+
+// LiveSession is one participant's connection to a page
+type LiveSession struct {
+	ID     string
+	PageID string
+	Name   string
+	// Messages is closed when the session leaves
+	Messages <-chan *models.LiveServerMessage
+
+	messages chan *models.LiveServerMessage
+	dropped  atomic.Uint64
+}
+
+// Dropped returns how many messages were dropped because the participant fell behind
+func (s *LiveSession) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// deliver queues a message without blocking the room on a slow participant
+func (s *LiveSession) deliver(msg *models.LiveServerMessage) {
+	select {
+	case s.messages <- msg:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+// liveChunkState is what a room knows about one chunk on its page
+type liveChunkState struct {
+	parent  string
+	version int64
+}
+
+// liveRoom holds the participants of one page
+type liveRoom struct {
+	pageID string
+
+	// opMu applies one change at a time, so that sequence order is the database order
+	opMu sync.Mutex
+
+	mu       sync.Mutex
+	sessions map[string]*LiveSession
+	presence map[string]models.LivePresence
+	chunks   map[string]liveChunkState
+	sequence uint64
+}
+
+// liveOutline implements LiveOutlineService
+type liveOutline struct {
+	chunks UnifiedChunkService
+	buffer int
+
+	mu    sync.Mutex
+	rooms map[string]*liveRoom
+}
+
+const defaultLiveSessionBuffer = 256
+
+// NewLiveOutlineService creates a live outline service over chunks. buffer is the number
+// of messages queued per participant.
+func NewLiveOutlineService(chunks UnifiedChunkService, buffer int) LiveOutlineService {
+	if buffer <= 0 {
+		buffer = defaultLiveSessionBuffer
+	}
+	return &liveOutline{
+		chunks: chunks,
+		buffer: buffer,
+		rooms:  make(map[string]*liveRoom),
+	}
+}
+
+// Join adds a participant, loading the page into a new room when it is the first one
+func (l *liveOutline) Join(ctx context.Context, pageID, name string) (*LiveSession, error) {
+	room, err := l.room(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make(chan *models.LiveServerMessage, l.buffer)
+	session := &LiveSession{ID: uuid.New().String(), PageID: pageID, Name: name, Messages: messages, messages: messages}
+	presence := models.LivePresence{ConnectionID: session.ID, Name: name, UpdatedAt: time.Now()}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Another participant may have opened the room while the page was loading
+	if existing, ok := l.rooms[pageID]; ok {
+		room = existing
+	} else {
+		l.rooms[pageID] = room
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+
+	welcome := &models.LiveServerMessage{
+		Type:         models.LiveMessageWelcome,
+		Sequence:     room.sequence,
+		ConnectionID: session.ID,
+		Participants: []models.LivePresence{},
+	}
+	for _, other := range room.presence {
+		welcome.Participants = append(welcome.Participants, other)
+	}
+	session.deliver(welcome)
+
+	room.sessions[session.ID] = session
+	room.presence[session.ID] = presence
+	room.broadcast(session.ID, &models.LiveServerMessage{Type: models.LiveMessagePresence, Presence: &presence})
+	return session, nil
+}
+
+// room returns the room of a page, or a new one loaded from the page's current chunks
+// that Join registers
+func (l *liveOutline) room(ctx context.Context, pageID string) (*liveRoom, error) {
+	l.mu.Lock()
+	room, ok := l.rooms[pageID]
+	l.mu.Unlock()
+	if ok {
+		return room, nil
+	}
+
+	page, err := l.chunks.GetChunk(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page: %w", err)
+	}
+	if !page.IsPage {
+		return nil, fmt.Errorf("chunk %s is not a page", pageID)
+	}
+	descendants, err := l.chunks.GetDescendants(ctx, pageID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+
+	room = &liveRoom{
+		pageID:   pageID,
+		sessions: make(map[string]*LiveSession),
+		presence: make(map[string]models.LivePresence),
+		chunks:   make(map[string]liveChunkState, len(descendants)),
+	}
+	for _, chunk := range descendants {
+		room.chunks[chunk.ChunkID] = liveChunkState{parent: stringValue(chunk.Parent), version: chunk.Version}
+	}
+	return room, nil
+}
+
+// Leave removes a participant and drops the room once it is empty
+func (l *liveOutline) Leave(session *LiveSession) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	room, ok := l.rooms[session.PageID]
+	if !ok {
+		return
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if _, ok := room.sessions[session.ID]; !ok {
+		return
+	}
+	delete(room.sessions, session.ID)
+	delete(room.presence, session.ID)
+	close(session.messages)
+	room.broadcast("", &models.LiveServerMessage{Type: models.LiveMessageLeave, ConnectionID: session.ID})
+
+	if len(room.sessions) == 0 {
+		delete(l.rooms, session.PageID)
+	}
+}
+
+// UpdatePresence relays a participant's cursor. A cursor in a chunk that is not on the
+// page is cleared.
+func (l *liveOutline) UpdatePresence(session *LiveSession, presence models.LivePresence) {
+	room := l.existingRoom(session.PageID)
+	if room == nil {
+		return
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	if _, ok := room.sessions[session.ID]; !ok {
+		return
+	}
+	presence.ConnectionID = session.ID
+	if presence.Name == "" {
+		presence.Name = session.Name
+	}
+	if !room.containsLocked(presence.ChunkID) {
+		presence.ChunkID, presence.Offset = "", 0
+	}
+	presence.UpdatedAt = time.Now()
+	room.presence[session.ID] = presence
+	room.broadcast(session.ID, &models.LiveServerMessage{Type: models.LiveMessagePresence, Presence: &presence})
+}
+
+func (l *liveOutline) existingRoom(pageID string) *liveRoom {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rooms[pageID]
+}
+
+// liveOpError is an operation failure reported to the sender with a code
+type liveOpError struct {
+	code    string
+	message string
+	current *models.UnifiedChunkRecord
+}
+
+func (e *liveOpError) Error() string {
+	return e.message
+}
+
+func invalidLiveOp(format string, args ...interface{}) *liveOpError {
+	return &liveOpError{code: models.LiveErrorInvalid, message: fmt.Sprintf(format, args...)}
+}
+
+// Apply applies op in the session's room. On success the sender gets an ack carrying the
+// stored chunk and everyone else an event; on failure only the sender hears of it.
+func (l *liveOutline) Apply(ctx context.Context, session *LiveSession, op *models.LiveOperation) {
+	room := l.existingRoom(session.PageID)
+	if room == nil {
+		return
+	}
+	if op == nil || op.OpID == "" {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		if _, ok := room.sessions[session.ID]; ok {
+			session.deliver(&models.LiveServerMessage{Type: models.LiveMessageReject, Code: models.LiveErrorInvalid, Error: "operation needs an op_id"})
+		}
+		return
+	}
+
+	room.opMu.Lock()
+	defer room.opMu.Unlock()
+
+	change, err := l.applyOperation(ctx, room, op)
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	// The session may have left while the operation ran
+	if _, ok := room.sessions[session.ID]; !ok {
+		return
+	}
+
+	if err != nil {
+		reject := &models.LiveServerMessage{Type: models.LiveMessageReject, OpID: op.OpID, ChunkID: op.ChunkID, Code: models.LiveErrorFailed, Error: err.Error()}
+		var opErr *liveOpError
+		if errors.As(err, &opErr) {
+			reject.Code = opErr.code
+			reject.Chunk = opErr.current
+		}
+		session.deliver(reject)
+		return
+	}
+
+	change.Origin = session.ID
+	change.OpID = op.OpID
+	room.publishLocked(session.ID, change)
+
+	ack := *change
+	ack.Type = models.LiveMessageAck
+	session.deliver(&ack)
+}
+
+// applyOperation performs one operation and returns the change to broadcast
+func (l *liveOutline) applyOperation(ctx context.Context, room *liveRoom, op *models.LiveOperation) (*models.LiveServerMessage, error) {
+	switch op.Op {
+	case models.LiveOperationCreate:
+		return l.applyCreate(ctx, room, op)
+	case models.LiveOperationUpdate, models.LiveOperationMove, models.LiveOperationDelete:
+	default:
+		return nil, invalidLiveOp("unknown operation %q", op.Op)
+	}
+
+	if op.ChunkID == "" {
+		return nil, invalidLiveOp("%s needs a chunk_id", op.Op)
+	}
+	if op.ChunkID == room.pageID {
+		return nil, invalidLiveOp("the page itself cannot be changed in a live session")
+	}
+	if !room.contains(op.ChunkID) {
+		return nil, &liveOpError{code: models.LiveErrorNotFound, message: fmt.Sprintf("chunk %s is not on page %s", op.ChunkID, room.pageID)}
+	}
+
+	switch op.Op {
+	case models.LiveOperationUpdate:
+		return l.applyUpdate(ctx, room, op)
+	case models.LiveOperationMove:
+		return l.applyMove(ctx, room, op)
+	default:
+		return l.applyDelete(ctx, room, op)
+	}
+}
+
+func (l *liveOutline) applyCreate(ctx context.Context, room *liveRoom, op *models.LiveOperation) (*models.LiveServerMessage, error) {
+	parent := room.pageID
+	if op.Parent != nil && *op.Parent != "" {
+		parent = *op.Parent
+	}
+	if !room.contains(parent) {
+		return nil, invalidLiveOp("parent %s is not on page %s", parent, room.pageID)
+	}
+	if op.ChunkID != "" {
+		// Clients choose IDs so that they can refer to the chunk before the ack arrives
+		if _, err := uuid.Parse(op.ChunkID); err != nil {
+			return nil, invalidLiveOp("chunk_id must be a UUID")
+		}
+		if room.contains(op.ChunkID) {
+			return nil, invalidLiveOp("chunk %s already exists", op.ChunkID)
+		}
+	}
+
+	pageID := room.pageID
+	chunk := &models.UnifiedChunkRecord{ChunkID: op.ChunkID, Parent: &parent, Page: &pageID, Metadata: op.Metadata}
+	if op.Contents != nil {
+		chunk.Contents = *op.Contents
+	}
+	if err := l.chunks.CreateChunk(ctx, chunk); err != nil {
+		return nil, err
+	}
+	return l.changedChunk(ctx, room, models.LiveOperationCreate, chunk.ChunkID)
+}
+
+func (l *liveOutline) applyUpdate(ctx context.Context, room *liveRoom, op *models.LiveOperation) (*models.LiveServerMessage, error) {
+	if op.Contents == nil && op.Metadata == nil {
+		return nil, invalidLiveOp("update needs contents or metadata")
+	}
+	if op.ExpectedVersion <= 0 {
+		return nil, invalidLiveOp("update needs the expected_version it was based on")
+	}
+	_, err := l.chunks.PatchChunk(ctx, op.ChunkID, &models.ChunkPatch{
+		ExpectedVersion: op.ExpectedVersion,
+		Contents:        op.Contents,
+		Metadata:        op.Metadata,
+	})
+	if err != nil {
+		return nil, l.explainLiveFailure(ctx, op.ChunkID, err)
+	}
+	return l.changedChunk(ctx, room, models.LiveOperationUpdate, op.ChunkID)
+}
+
+func (l *liveOutline) applyMove(ctx context.Context, room *liveRoom, op *models.LiveOperation) (*models.LiveServerMessage, error) {
+	if op.Parent == nil || *op.Parent == "" {
+		return nil, invalidLiveOp("move needs a parent")
+	}
+	if !room.contains(*op.Parent) {
+		return nil, invalidLiveOp("parent %s is not on page %s", *op.Parent, room.pageID)
+	}
+	if err := l.checkExpectedVersion(ctx, op); err != nil {
+		return nil, err
+	}
+	if err := l.chunks.MoveChunk(ctx, op.ChunkID, *op.Parent); err != nil {
+		return nil, err
+	}
+	return l.changedChunk(ctx, room, models.LiveOperationMove, op.ChunkID)
+}
+
+func (l *liveOutline) applyDelete(ctx context.Context, room *liveRoom, op *models.LiveOperation) (*models.LiveServerMessage, error) {
+	if err := l.checkExpectedVersion(ctx, op); err != nil {
+		return nil, err
+	}
+	// An outliner deletes a block together with its children
+	result, err := l.chunks.DeleteSubtree(ctx, op.ChunkID, models.SubtreeDeleteOptions{Confirm: true})
+	if err != nil {
+		return nil, err
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	for _, id := range result.DeletedChunkIDs {
+		delete(room.chunks, id)
+	}
+	return &models.LiveServerMessage{Event: models.LiveOperationDelete, ChunkID: op.ChunkID, ChunkIDs: result.DeletedChunkIDs}, nil
+}
+
+// checkExpectedVersion rejects a move or delete based on a stale chunk, when the client
+// named the version it saw
+func (l *liveOutline) checkExpectedVersion(ctx context.Context, op *models.LiveOperation) error {
+	if op.ExpectedVersion <= 0 {
+		return nil
+	}
+	current, err := l.chunks.GetChunk(ctx, op.ChunkID)
+	if err != nil {
+		return &liveOpError{code: models.LiveErrorNotFound, message: err.Error()}
+	}
+	if current.Version != op.ExpectedVersion {
+		conflict := &VersionConflictError{ChunkID: op.ChunkID, ExpectedVersion: op.ExpectedVersion, CurrentVersion: current.Version}
+		return &liveOpError{code: models.LiveErrorConflict, message: conflict.Error(), current: current}
+	}
+	return nil
+}
+
+// explainLiveFailure attaches the stored chunk to version conflicts, so that the client
+// can rebase its local edit
+func (l *liveOutline) explainLiveFailure(ctx context.Context, chunkID string, err error) error {
+	if !errors.Is(err, ErrVersionConflict) {
+		return err
+	}
+	opErr := &liveOpError{code: models.LiveErrorConflict, message: err.Error()}
+	if current, getErr := l.chunks.GetChunk(ctx, chunkID); getErr == nil {
+		opErr.current = current
+	}
+	return opErr
+}
+
+// changedChunk reads back a chunk after a change, records it in the room and returns the
+// change to broadcast
+func (l *liveOutline) changedChunk(ctx context.Context, room *liveRoom, event models.LiveOperationType, chunkID string) (*models.LiveServerMessage, error) {
+	chunk, err := l.chunks.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read back chunk: %w", err)
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.chunks[chunk.ChunkID] = liveChunkState{parent: stringValue(chunk.Parent), version: chunk.Version}
+	return &models.LiveServerMessage{Event: event, ChunkID: chunk.ChunkID, Chunk: chunk}, nil
+}
+
+// Run relays change feed events to the rooms of the pages they touch
+func (l *liveOutline) Run(ctx context.Context, sub *ChangeSubscription) {
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			l.relay(ctx, event)
+		}
+	}
+}
+
+// relay turns one change feed event into room events
+func (l *liveOutline) relay(ctx context.Context, event *models.ChangeEvent) {
+	l.mu.Lock()
+	rooms := make([]*liveRoom, 0, len(l.rooms))
+	for _, room := range l.rooms {
+		rooms = append(rooms, room)
+	}
+	l.mu.Unlock()
+
+	for _, room := range rooms {
+		if event.Type == models.ChangeEventResync {
+			room.mu.Lock()
+			room.broadcast("", &models.LiveServerMessage{Type: models.LiveMessageResync})
+			room.mu.Unlock()
+			continue
+		}
+		if event.ChunkID == room.pageID {
+			continue
+		}
+		onPage := event.Page != nil && *event.Page == room.pageID
+		if !onPage && !room.contains(event.ChunkID) {
+			continue
+		}
+		if err := l.relayToRoom(ctx, room, event, onPage); err != nil {
+			log.Printf("Warning: failed to relay change of chunk %s to live page %s: %v", event.ChunkID, room.pageID, err)
+		}
+	}
+}
+
+func (l *liveOutline) relayToRoom(ctx context.Context, room *liveRoom, event *models.ChangeEvent, onPage bool) error {
+	room.opMu.Lock()
+	defer room.opMu.Unlock()
+
+	room.mu.Lock()
+	known, isKnown := room.chunks[event.ChunkID]
+	room.mu.Unlock()
+
+	// A chunk deleted or moved to another page leaves this one
+	if event.Type == models.ChangeEventDelete || !onPage {
+		if !isKnown {
+			return nil
+		}
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		delete(room.chunks, event.ChunkID)
+		room.publishLocked("", &models.LiveServerMessage{Event: models.LiveOperationDelete, ChunkID: event.ChunkID, ChunkIDs: []string{event.ChunkID}})
+		return nil
+	}
+
+	// The room announced this version itself
+	if isKnown && event.Version > 0 && event.Version <= known.version {
+		return nil
+	}
+
+	chunk, err := l.chunks.GetChunk(ctx, event.ChunkID)
+	if err != nil {
+		// Deleted since; its delete event follows
+		return nil
+	}
+
+	change := models.LiveOperationUpdate
+	switch {
+	case !isKnown:
+		change = models.LiveOperationCreate
+	case stringValue(chunk.Parent) != known.parent:
+		change = models.LiveOperationMove
+	}
+
+	room.mu.Lock()
+	defer room.mu.Unlock()
+	room.chunks[chunk.ChunkID] = liveChunkState{parent: stringValue(chunk.Parent), version: chunk.Version}
+	room.publishLocked("", &models.LiveServerMessage{Event: change, ChunkID: chunk.ChunkID, Chunk: chunk})
+	return nil
+}
+
+// contains reports whether a chunk is the page or on it
+func (r *liveRoom) contains(chunkID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.containsLocked(chunkID)
+}
+
+func (r *liveRoom) containsLocked(chunkID string) bool {
+	if chunkID == r.pageID {
+		return true
+	}
+	_, ok := r.chunks[chunkID]
+	return ok
+}
+
+// publishLocked sequences a change and sends it to every participant but except; r.mu must be held
+func (r *liveRoom) publishLocked(except string, change *models.LiveServerMessage) {
+	r.sequence++
+	change.Type = models.LiveMessageEvent
+	change.Sequence = r.sequence
+	r.broadcast(except, change)
+}
+
+// broadcast sends msg to every participant but except; r.mu must be held
+func (r *liveRoom) broadcast(except string, msg *models.LiveServerMessage) {
+	for id, session := range r.sessions {
+		if id != except {
+			session.deliver(msg)
+		}
+	}
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// versionedChunkStore keeps chunks with versions, as the database does
+type versionedChunkStore struct {
+	UnifiedChunkService
+	mu     sync.Mutex
+	chunks map[string]models.UnifiedChunkRecord
+}
+
+func (s *versionedChunkStore) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if chunk.ChunkID == "" {
+		chunk.ChunkID = uuid.New().String()
+	}
+	chunk.Version = 1
+	s.chunks[chunk.ChunkID] = *chunk
+	return nil
+}
+
+func (s *versionedChunkStore) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunk, ok := s.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	return &chunk, nil
+}
+
+func (s *versionedChunkStore) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var descendants []models.UnifiedChunkRecord
+	for _, chunk := range s.chunks {
+		if chunk.Page != nil && *chunk.Page == ancestorChunkID && chunk.ChunkID != ancestorChunkID {
+			descendants = append(descendants, chunk)
+		}
+	}
+	return descendants, nil
+}
+
+func (s *versionedChunkStore) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunk := s.chunks[chunkID]
+	if chunk.Version != patch.ExpectedVersion {
+		return nil, &VersionConflictError{ChunkID: chunkID, ExpectedVersion: patch.ExpectedVersion, CurrentVersion: chunk.Version}
+	}
+	if patch.Contents != nil {
+		chunk.Contents = *patch.Contents
+	}
+	chunk.Version++
+	s.chunks[chunkID] = chunk
+	return &chunk, nil
+}
+
+func (s *versionedChunkStore) MoveChunk(ctx context.Context, chunkID, newParentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunk := s.chunks[chunkID]
+	chunk.Parent = &newParentID
+	chunk.Version++
+	s.chunks[chunkID] = chunk
+	return nil
+}
+
+func (s *versionedChunkStore) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleted := []string{chunkID}
+	for i := 0; i < len(deleted); i++ {
+		for id, chunk := range s.chunks {
+			if chunk.Parent != nil && *chunk.Parent == deleted[i] {
+				deleted = append(deleted, id)
+			}
+		}
+	}
+	for _, id := range deleted {
+		delete(s.chunks, id)
+	}
+	return &models.SubtreeDeleteResult{Deleted: true, DeletedChunkIDs: deleted}, nil
+}
+
+// nextLiveMessage waits for the next message of a session
+func nextLiveMessage(t *testing.T, session *LiveSession) *models.LiveServerMessage {
+	t.Helper()
+	select {
+	case msg := <-session.Messages:
+		require.NotNil(t, msg, "session closed")
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+		return nil
+	}
+}
+
+func assertNoLiveMessage(t *testing.T, session *LiveSession) {
+	t.Helper()
+	select {
+	case msg := <-session.Messages:
+		t.Fatalf("unexpected message %+v", msg)
+	default:
+	}
+}
+
+func TestLiveOutline_OperationsAndPresence(t *testing.T) {
+	ctx := context.Background()
+	pageID := uuid.New().String()
+	blockID := uuid.New().String()
+	store := &versionedChunkStore{chunks: map[string]models.UnifiedChunkRecord{
+		pageID:  {ChunkID: pageID, Contents: "Page", IsPage: true, Version: 1},
+		blockID: {ChunkID: blockID, Contents: "Block", Parent: &pageID, Page: &pageID, Version: 1},
+	}}
+	live := NewLiveOutlineService(store, 16)
+
+	alice, err := live.Join(ctx, pageID, "alice")
+	require.NoError(t, err)
+	welcome := nextLiveMessage(t, alice)
+	assert.Equal(t, models.LiveMessageWelcome, welcome.Type)
+	assert.Equal(t, alice.ID, welcome.ConnectionID)
+	assert.Empty(t, welcome.Participants)
+
+	bob, err := live.Join(ctx, pageID, "bob")
+	require.NoError(t, err)
+	welcome = nextLiveMessage(t, bob)
+	require.Len(t, welcome.Participants, 1)
+	assert.Equal(t, "alice", welcome.Participants[0].Name)
+	joined := nextLiveMessage(t, alice)
+	assert.Equal(t, models.LiveMessagePresence, joined.Type)
+	assert.Equal(t, "bob", joined.Presence.Name)
+
+	_, err = live.Join(ctx, blockID, "carol")
+	assert.Error(t, err, "only pages can be joined")
+
+	t.Run("presence is relayed to the others", func(t *testing.T) {
+		live.UpdatePresence(bob, models.LivePresence{ChunkID: blockID, Offset: 3})
+		msg := nextLiveMessage(t, alice)
+		assert.Equal(t, bob.ID, msg.Presence.ConnectionID)
+		assert.Equal(t, blockID, msg.Presence.ChunkID)
+		assertNoLiveMessage(t, bob)
+	})
+
+	newID := uuid.New().String()
+	t.Run("create is acknowledged and broadcast", func(t *testing.T) {
+		contents := "New block"
+		live.Apply(ctx, alice, &models.LiveOperation{OpID: "op-1", Op: models.LiveOperationCreate, ChunkID: newID, Parent: &blockID, Contents: &contents})
+
+		ack := nextLiveMessage(t, alice)
+		assert.Equal(t, models.LiveMessageAck, ack.Type)
+		assert.Equal(t, "op-1", ack.OpID)
+		assert.Equal(t, int64(1), ack.Chunk.Version)
+		assert.Equal(t, pageID, *ack.Chunk.Page)
+
+		event := nextLiveMessage(t, bob)
+		assert.Equal(t, models.LiveMessageEvent, event.Type)
+		assert.Equal(t, models.LiveOperationCreate, event.Event)
+		assert.Equal(t, alice.ID, event.Origin)
+		assert.Equal(t, ack.Sequence, event.Sequence)
+	})
+
+	t.Run("a stale update is rejected with the stored chunk", func(t *testing.T) {
+		first, second := "Edited by bob", "Edited by alice"
+		live.Apply(ctx, bob, &models.LiveOperation{OpID: "op-2", Op: models.LiveOperationUpdate, ChunkID: newID, Contents: &first, ExpectedVersion: 1})
+		assert.Equal(t, models.LiveMessageAck, nextLiveMessage(t, bob).Type)
+		assert.Equal(t, models.LiveOperationUpdate, nextLiveMessage(t, alice).Event)
+
+		live.Apply(ctx, alice, &models.LiveOperation{OpID: "op-3", Op: models.LiveOperationUpdate, ChunkID: newID, Contents: &second, ExpectedVersion: 1})
+		reject := nextLiveMessage(t, alice)
+		assert.Equal(t, models.LiveMessageReject, reject.Type)
+		assert.Equal(t, models.LiveErrorConflict, reject.Code)
+		assert.Equal(t, first, reject.Chunk.Contents)
+		assertNoLiveMessage(t, bob)
+	})
+
+	t.Run("operations outside the page are rejected", func(t *testing.T) {
+		elsewhere := uuid.New().String()
+		live.Apply(ctx, alice, &models.LiveOperation{OpID: "op-4", Op: models.LiveOperationMove, ChunkID: newID, Parent: &elsewhere})
+		assert.Equal(t, models.LiveErrorInvalid, nextLiveMessage(t, alice).Code)
+		live.Apply(ctx, alice, &models.LiveOperation{OpID: "op-5", Op: models.LiveOperationDelete, ChunkID: elsewhere})
+		assert.Equal(t, models.LiveErrorNotFound, nextLiveMessage(t, alice).Code)
+	})
+
+	t.Run("move and delete", func(t *testing.T) {
+		live.Apply(ctx, alice, &models.LiveOperation{OpID: "op-6", Op: models.LiveOperationMove, ChunkID: newID, Parent: &pageID})
+		assert.Equal(t, models.LiveMessageAck, nextLiveMessage(t, alice).Type)
+		assert.Equal(t, models.LiveOperationMove, nextLiveMessage(t, bob).Event)
+
+		live.Apply(ctx, bob, &models.LiveOperation{OpID: "op-7", Op: models.LiveOperationDelete, ChunkID: blockID, ExpectedVersion: 1})
+		assert.Equal(t, models.LiveMessageAck, nextLiveMessage(t, bob).Type)
+		event := nextLiveMessage(t, alice)
+		assert.Equal(t, models.LiveOperationDelete, event.Event)
+		assert.Equal(t, []string{blockID}, event.ChunkIDs)
+	})
+
+	live.Leave(bob)
+	_, open := <-bob.Messages
+	assert.False(t, open)
+	assert.Equal(t, models.LiveMessageLeave, nextLiveMessage(t, alice).Type)
+}
+
+func TestLiveOutline_RelaysChangeFeed(t *testing.T) {
+	ctx := context.Background()
+	pageID := uuid.New().String()
+	blockID := uuid.New().String()
+	store := &versionedChunkStore{chunks: map[string]models.UnifiedChunkRecord{
+		pageID:  {ChunkID: pageID, Contents: "Page", IsPage: true, Version: 1},
+		blockID: {ChunkID: blockID, Contents: "Block", Parent: &pageID, Page: &pageID, Version: 1},
+	}}
+	live := NewLiveOutlineService(store, 16)
+	feed := NewChangeFeedService(nil, 10)
+	defer feed.Stop()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go live.Run(runCtx, feed.Subscribe(models.ChangeFeedFilter{}, 10))
+
+	session, err := live.Join(ctx, pageID, "")
+	require.NoError(t, err)
+	nextLiveMessage(t, session)
+
+	// A change the room already announced is not repeated
+	contents := "Edited"
+	other, err := live.Join(ctx, pageID, "")
+	require.NoError(t, err)
+	nextLiveMessage(t, other)
+	nextLiveMessage(t, session)
+	live.Apply(ctx, other, &models.LiveOperation{OpID: "op-1", Op: models.LiveOperationUpdate, ChunkID: blockID, Contents: &contents, ExpectedVersion: 1})
+	nextLiveMessage(t, other)
+	nextLiveMessage(t, session)
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventUpdate, ChunkID: blockID, Page: &pageID, Version: 2})
+
+	// A change made elsewhere is broadcast with the stored chunk
+	_, err = store.PatchChunk(ctx, blockID, &models.ChunkPatch{ExpectedVersion: 2, Contents: &contents})
+	require.NoError(t, err)
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventUpdate, ChunkID: blockID, Page: &pageID, Version: 3})
+	event := nextLiveMessage(t, session)
+	assert.Equal(t, models.LiveOperationUpdate, event.Event)
+	assert.Empty(t, event.Origin)
+	assert.Equal(t, int64(3), event.Chunk.Version)
+
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventDelete, ChunkID: blockID, Page: &pageID, Version: 3})
+	assert.Equal(t, models.LiveOperationDelete, nextLiveMessage(t, session).Event)
+
+	feed.Publish(&models.ChangeEvent{Type: models.ChangeEventResync})
+	assert.Equal(t, models.LiveMessageResync, nextLiveMessage(t, session).Type)
+}
@@ -68,6 +68,10 @@ type EntityExtraction struct {
 	Name       string                 `json:"name"`
 	Type       string                 `json:"type"`
 	Properties map[string]interface{} `json:"properties,omitempty"`
+	// Start and End are the character offsets of the mention, when the LLM reports them
+	Start      *int                   `json:"start,omitempty"`
+	End        *int                   `json:"end,omitempty"`
+	Confidence *float64               `json:"confidence,omitempty"`
 }
 
 // ChunkText implements LLMService.ChunkText
@@ -149,10 +153,13 @@ func (c *LLMClient) ExtractEntities(ctx context.Context, text string) ([]models.
 	nodes := make([]models.GraphNode, len(response.Data))
 	for i, entity := range response.Data {
 		nodes[i] = models.GraphNode{
-			EntityName: entity.Name,
-			EntityType: entity.Type,
-			Properties: entity.Properties,
-			CreatedAt:  time.Now(),
+			EntityName:  entity.Name,
+			EntityType:  entity.Type,
+			Properties:  entity.Properties,
+			CreatedAt:   time.Now(),
+			SourceStart: entity.Start,
+			SourceEnd:   entity.End,
+			Confidence:  entity.Confidence,
 		}
 	}
 
@@ -236,6 +243,11 @@ func (c *LLMClient) makeHTTPRequest(ctx context.Context, request LLMRequest, res
 
 // handleHTTPError converts HTTP errors to appropriate AppErrors
 func (c *LLMClient) handleHTTPError(statusCode int, body string) error {
+	return llmHTTPError(statusCode, body)
+}
+
+// llmHTTPError maps an LLM API HTTP status to an AppError
+func llmHTTPError(statusCode int, body string) error {
 	switch {
 	case statusCode == 401:
 		return errors.NewAuthError(
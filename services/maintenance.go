@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultMaintenanceInterval = time.Hour
+
+// MaintenanceJob is a task the maintenance daemon runs on every tick
+type MaintenanceJob struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+// MaintenanceDaemon runs registered housekeeping jobs, such as retention rules, one after
+// another every interval. A failing job is logged and does not stop the others.
+type MaintenanceDaemon struct {
+	interval time.Duration
+	monitor  QueryPerformanceMonitor
+
+	mu   sync.Mutex
+	jobs []MaintenanceJob
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewMaintenanceDaemon creates a daemon that runs its jobs every interval
+func NewMaintenanceDaemon(interval time.Duration, monitor QueryPerformanceMonitor) *MaintenanceDaemon {
+	if interval <= 0 {
+		interval = defaultMaintenanceInterval
+	}
+	return &MaintenanceDaemon{interval: interval, monitor: monitor}
+}
+
+// Register adds a job to every following run
+func (d *MaintenanceDaemon) Register(name string, run func(ctx context.Context) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.jobs = append(d.jobs, MaintenanceJob{Name: name, Run: run})
+}
+
+// RunOnce runs every registered job and returns how many failed
+func (d *MaintenanceDaemon) RunOnce(ctx context.Context) int {
+	d.mu.Lock()
+	jobs := append([]MaintenanceJob(nil), d.jobs...)
+	d.mu.Unlock()
+
+	failed := 0
+	for _, job := range jobs {
+		if ctx.Err() != nil {
+			break
+		}
+		start := time.Now()
+		err := job.Run(ctx)
+		d.monitor.RecordQuery("maintenance_"+job.Name, time.Since(start), 1)
+		if err != nil {
+			failed++
+			log.Printf("Warning: maintenance job %s failed: %v", job.Name, err)
+		}
+	}
+	return failed
+}
+
+// Start runs the jobs every interval until Stop is called
+func (d *MaintenanceDaemon) Start(ctx context.Context) {
+	if d.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	d.cancel = cancel
+	d.done = make(chan struct{})
+	go func() {
+		defer close(d.done)
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				d.RunOnce(ctx)
+			}
+		}
+	}()
+}
+
+// Stop stops the daemon, cancelling a run in progress, and waits for it to exit
+func (d *MaintenanceDaemon) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+		<-d.done
+		d.cancel = nil
+	}
+}
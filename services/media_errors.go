@@ -18,6 +18,9 @@ var (
 	ErrBatchProcessingFailed = errors.New("batch processing failed")
 	ErrStorageAdapterNotFound = errors.New("storage adapter not found")
 	ErrInvalidStorageConfig  = errors.New("invalid storage configuration")
+	ErrObjectNotFound        = errors.New("storage object not found")
+	ErrObjectTooLarge        = errors.New("storage object too large")
+	ErrObjectExists          = errors.New("storage object already exists")
 )
 
 // MediaProcessingError 媒體處理錯誤
@@ -208,6 +211,8 @@ func IsPermanentError(err error) bool {
 		return true
 	case errors.Is(err, ErrInvalidStorageConfig):
 		return true
+	case errors.Is(err, ErrObjectTooLarge):
+		return true
 	default:
 		return false
 	}
@@ -15,7 +15,7 @@ import (
 
 // mediaProcessor MediaProcessor 核心服務實作
 type mediaProcessor struct {
-	storageService    *StorageService
+	storageService    StorageService
 	visionService     VisionAIService
 	embeddingService  ImageEmbeddingService
 	hashService       *HashService
@@ -25,7 +25,7 @@ type mediaProcessor struct {
 
 // NewMediaProcessor 建立新的 MediaProcessor 服務
 func NewMediaProcessor(
-	storageService *StorageService,
+	storageService StorageService,
 	visionService VisionAIService,
 	embeddingService ImageEmbeddingService,
 	chunkService UnifiedChunkService,
@@ -67,13 +67,13 @@ func (m *mediaProcessor) ProcessImage(ctx context.Context, req *models.ProcessIm
 	metadata.Hash = hash
 	metadata.Size = size
 	
-	// 4. 驗證檔案
-	if err := m.storageService.ValidateFile(metadata); err != nil {
-		return nil, fmt.Errorf("file validation failed: %w", err)
+	// 4. 驗證檔案（大小限制由儲存服務檢查）
+	if !models.IsImageFile(metadata.OriginalFilename) {
+		return nil, fmt.Errorf("file validation failed: %w", ErrUnsupportedImageFormat)
 	}
 	
 	// 5. 上傳到儲存服務
-	storageResult, err := m.storageService.Upload(ctx, hashReader, metadata)
+	storageResult, err := m.uploadImage(ctx, hashReader, metadata)
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload image: %w", err)
 	}
@@ -277,6 +277,46 @@ func (m *mediaProcessor) buildResultFromExistingChunk(chunk *models.UnifiedChunk
 	}, nil
 }
 
+// imageSignedURLExpiry 私有 bucket 圖片簽章 URL 的有效期限
+const imageSignedURLExpiry = 24 * time.Hour
+
+// uploadImage 以內容雜湊為鍵值上傳圖片，相同內容會寫入同一物件
+func (m *mediaProcessor) uploadImage(ctx context.Context, file io.Reader, metadata *models.MediaMetadata) (*models.StorageResult, error) {
+	if m.storageService == nil {
+		return nil, ErrStorageNotAvailable
+	}
+	if len(metadata.Hash) != 64 {
+		return nil, fmt.Errorf("invalid file hash length: expected 64, got %d", len(metadata.Hash))
+	}
+	
+	key := fmt.Sprintf("images/%s/%s%s", metadata.Hash[:2], metadata.Hash,
+		strings.ToLower(filepath.Ext(metadata.OriginalFilename)))
+	object, err := m.storageService.Put(ctx, key, file, &models.PutObjectOptions{
+		ContentType: metadata.ContentType,
+		Size:        metadata.Size,
+		Overwrite:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	// 私有 bucket 沒有公開 URL，改用簽章 URL 供 AI 分析與預覽
+	url := object.URL
+	if url == "" {
+		url, err = m.storageService.SignedURL(ctx, key, imageSignedURLExpiry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create signed URL: %w", err)
+		}
+	}
+	
+	return &models.StorageResult{
+		StorageID:   object.Key,
+		URL:         url,
+		StorageType: object.StorageType,
+		UploadedAt:  object.LastModified,
+	}, nil
+}
+
 // createImageChunk 建立圖片 chunk
 func (m *mediaProcessor) createImageChunk(ctx context.Context, req *models.ProcessImageRequest, storageResult *models.StorageResult, metadata *models.MediaMetadata) (*models.UnifiedChunkRecord, error) {
 	// 建立圖片 metadata
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidMetadataPatch is returned for metadata operations that cannot be applied
+var ErrInvalidMetadataPatch = errors.New("invalid metadata patch")
+
+// validateMetadataOps checks that every operation is known, has a path and carries the
+// value it needs
+func validateMetadataOps(ops []models.MetadataOp) error {
+	for i, op := range ops {
+		if len(op.Path) == 0 {
+			return fmt.Errorf("%w: operation %d has no path", ErrInvalidMetadataPatch, i)
+		}
+		for _, key := range op.Path {
+			if key == "" {
+				return fmt.Errorf("%w: operation %d has an empty path key", ErrInvalidMetadataPatch, i)
+			}
+		}
+		switch op.Op {
+		case models.MetadataOpSet, models.MetadataOpDelete:
+		case models.MetadataOpMerge:
+			if _, ok := op.Value.(map[string]interface{}); !ok {
+				return fmt.Errorf("%w: merge operation %d needs an object value", ErrInvalidMetadataPatch, i)
+			}
+		default:
+			return fmt.Errorf("%w: unknown operation %q", ErrInvalidMetadataPatch, op.Op)
+		}
+	}
+	return nil
+}
+
+// metadataKeyChanged reports whether patch may change the top-level metadata key
+func metadataKeyChanged(patch *models.ChunkPatch, key string) bool {
+	if _, ok := patch.Metadata[key]; ok {
+		return true
+	}
+	for _, op := range patch.MetadataOps {
+		if len(op.Path) > 0 && op.Path[0] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// patchedMetadata returns the metadata patch leaves behind on a chunk holding metadata,
+// matching jsonb_patch_metadata (database/metadata_patch_migration.sql). metadata is not
+// modified, and invalid operations are skipped.
+func patchedMetadata(metadata map[string]interface{}, patch *models.ChunkPatch) map[string]interface{} {
+	patched := copyMetadataObject(metadata)
+	for key, value := range patch.Metadata {
+		patched[key] = value
+	}
+	for _, op := range patch.MetadataOps {
+		if len(op.Path) == 0 {
+			continue
+		}
+		parent := patched
+		for _, key := range op.Path[:len(op.Path)-1] {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok && op.Op == models.MetadataOpDelete {
+				parent = nil
+				break
+			}
+			child = copyMetadataObject(child)
+			parent[key] = child
+			parent = child
+		}
+		if parent == nil {
+			continue
+		}
+
+		last := op.Path[len(op.Path)-1]
+		switch op.Op {
+		case models.MetadataOpDelete:
+			delete(parent, last)
+		case models.MetadataOpMerge:
+			merged, _ := parent[last].(map[string]interface{})
+			merged = copyMetadataObject(merged)
+			values, _ := op.Value.(map[string]interface{})
+			for key, value := range values {
+				merged[key] = value
+			}
+			parent[last] = merged
+		default:
+			parent[last] = op.Value
+		}
+	}
+	return patched
+}
+
+// copyMetadataObject copies the top level of a metadata object; nil gives an empty one
+func copyMetadataObject(object map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"semantic-text-processor/models"
+)
+
+const (
+	// defaultMMRLambda weighs relevance and diversity equally when a request sets no lambda
+	defaultMMRLambda = 0.5
+	// mmrCandidateFactor is how many vector results are diversified per requested result
+	mmrCandidateFactor = 4
+)
+
+// ErrInvalidMMRLambda is returned for an MMR lambda outside [0, 1]
+var ErrInvalidMMRLambda = errors.New("mmr_lambda must be between 0 and 1")
+
+// SearchVectorSource loads the stored embeddings of search results, which MMR compares
+// to tell near-duplicates apart
+type SearchVectorSource interface {
+	GetChunkVectors(ctx context.Context, chunkIDs []string) (map[string][]float64, error)
+}
+
+// resolveMMRLambda returns the lambda an MMR request diversifies with
+func resolveMMRLambda(req *models.OptimizedSearchRequest) (float64, error) {
+	if req.MMRLambda == nil {
+		return defaultMMRLambda, nil
+	}
+	if *req.MMRLambda < 0 || *req.MMRLambda > 1 {
+		return 0, fmt.Errorf("%w: got %v", ErrInvalidMMRLambda, *req.MMRLambda)
+	}
+	return *req.MMRLambda, nil
+}
+
+// diversifyMMR picks limit results by maximal marginal relevance: each pick maximizes
+// lambda*relevance - (1-lambda)*(highest similarity to an earlier pick). Relevance is
+// scaled to [0, 1] over the candidates, so re-ranking scores weigh the same as vector
+// similarities. Results are compared by their vectors, or by the words they share when
+// either has none.
+func diversifyMMR(results []models.OptimizedSearchResult, vectors map[string][]float64, lambda float64, limit int) []models.OptimizedSearchResult {
+	if limit <= 0 || len(results) <= 1 {
+		return results
+	}
+
+	minRelevance, maxRelevance := results[0].Relevance, results[0].Relevance
+	for _, result := range results {
+		if result.Relevance < minRelevance {
+			minRelevance = result.Relevance
+		}
+		if result.Relevance > maxRelevance {
+			maxRelevance = result.Relevance
+		}
+	}
+	relevance := make([]float64, len(results))
+	words := make([]map[string]bool, len(results))
+	for i, result := range results {
+		relevance[i] = 1
+		if maxRelevance > minRelevance {
+			relevance[i] = (result.Relevance - minRelevance) / (maxRelevance - minRelevance)
+		}
+	}
+	similarity := func(i, j int) float64 {
+		a, b := vectors[results[i].ChunkID], vectors[results[j].ChunkID]
+		if len(a) > 0 && len(b) > 0 {
+			return vectorCosineSimilarity(a, b)
+		}
+		if words[i] == nil {
+			words[i] = contentWords(results[i].Content)
+		}
+		if words[j] == nil {
+			words[j] = contentWords(results[j].Content)
+		}
+		return wordOverlap(words[i], words[j])
+	}
+
+	// redundancy[i] is candidate i's highest similarity to the results picked so far
+	redundancy := make([]float64, len(results))
+	picked := make([]bool, len(results))
+	selected := make([]models.OptimizedSearchResult, 0, limit)
+	for len(selected) < limit && len(selected) < len(results) {
+		best, bestScore := -1, 0.0
+		for i := range results {
+			if picked[i] {
+				continue
+			}
+			score := lambda*relevance[i] - (1-lambda)*redundancy[i]
+			if best < 0 || score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		picked[best] = true
+		selected = append(selected, results[best])
+		for i := range results {
+			if !picked[i] {
+				if sim := similarity(i, best); len(selected) == 1 || sim > redundancy[i] {
+					redundancy[i] = sim
+				}
+			}
+		}
+	}
+	return selected
+}
+
+// contentWords is the set of lower-cased words in content
+func contentWords(content string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(content), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
+
+// wordOverlap is the Jaccard similarity of two word sets
+func wordOverlap(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
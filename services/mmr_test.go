@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+type staticVectorSource map[string][]float64
+
+func (s staticVectorSource) GetChunkVectors(ctx context.Context, chunkIDs []string) (map[string][]float64, error) {
+	vectors := make(map[string][]float64)
+	for _, id := range chunkIDs {
+		if vector, ok := s[id]; ok {
+			vectors[id] = vector
+		}
+	}
+	return vectors, nil
+}
+
+func mmrTestResults() []models.SimilarityResult {
+	return []models.SimilarityResult{
+		similarity("c1", "Jaguars are big cats of the Americas", 0.95),
+		similarity("c2", "Jaguars are big cats of the Americas!", 0.94),
+		similarity("c3", "Jaguar is a British car brand", 0.80),
+		similarity("c4", "Leopards and jaguars look alike", 0.70),
+	}
+}
+
+func TestOptimizedSearch_MMR(t *testing.T) {
+	service := NewOptimizedSearchService(&staticSearchService{results: mmrTestResults()}, nil, time.Minute)
+	service.SetVectorSource(staticVectorSource{
+		"c1": {1, 0, 0},
+		"c2": {0.99, 0.1, 0},
+		"c3": {0, 1, 0},
+		"c4": {0.6, 0, 0.8},
+	})
+
+	plain, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c1", "c2"}, resultIDs(plain.Results))
+
+	diversified, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", Limit: 2, MMR: true})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c1", "c3"}, resultIDs(diversified.Results), "the near-duplicate of c1 is skipped")
+	assert.Equal(t, 0.8, diversified.Results[1].Relevance, "scores are kept")
+	assert.Contains(t, diversified.Metadata.ProcessingSteps, "mmr")
+	assert.NotEqual(t, plain.Metadata.QueryHash, diversified.Metadata.QueryHash, "diversified results are cached separately")
+
+	relevanceOnly := 1.0
+	response, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", Limit: 2, MMR: true, MMRLambda: &relevanceOnly})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c1", "c2"}, resultIDs(response.Results))
+
+	invalid := 1.5
+	_, err = service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", MMR: true, MMRLambda: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidMMRLambda)
+}
+
+func TestDiversifyMMR_WithoutVectors(t *testing.T) {
+	var results []models.OptimizedSearchResult
+	for _, result := range mmrTestResults() {
+		results = append(results, models.OptimizedSearchResult{ChunkID: result.Chunk.ID, Content: result.Chunk.Content, Relevance: result.Similarity})
+	}
+
+	// Without embeddings results are compared by the words they share
+	diversified := diversifyMMR(results, nil, 0.5, 2)
+	assert.Equal(t, []string{"c1", "c3"}, resultIDs(diversified))
+
+	assert.ElementsMatch(t, []string{"c1", "c2", "c3", "c4"}, resultIDs(diversifyMMR(results, nil, 0.5, 10)), "every candidate is returned once")
+	assert.Equal(t, 0.5, wordOverlap(contentWords("a b c"), contentWords("B, C d!")))
+}
+
+func resultIDs(results []models.OptimizedSearchResult) []string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ChunkID
+	}
+	return ids
+}
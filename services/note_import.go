@@ -0,0 +1,655 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// NOTE IMPORT
+// ============================================================================
+//
+// Importers read the exports of other note-taking apps into pages and blocks. An adapter
+// per app parses the files into pages whose blocks keep the app's link syntax. The import
+// then assigns chunk IDs, uploads the embedded files the links point at to object storage,
+// has the adapter rewrite the links against the new IDs and creates everything in one
+// batch. Links to pages and blocks become Refs of the linking chunk. Page properties fill a
+// template instance when the page names a template with matching slots and are kept in
+// metadata otherwise.
+//
+// Every page and block also gets an external ID from the export, recorded against its chunk
+// under the import's source. An upsert import of the same source reuses those chunks: it
+// updates the ones whose content changed, skips the rest and optionally deletes the ones
+// no longer in the export. Chunks edited since their import are conflicts and left alone.
+
+// ErrInvalidNoteImport is returned for unknown formats and exports without pages or over
+// the import limits
+var ErrInvalidNoteImport = errors.New("invalid note import")
+
+const (
+	// maxNoteImportChunks caps the pages and blocks of one import, created in one transaction
+	maxNoteImportChunks = 50000
+	// maxNoteImportFileSize caps one page file
+	maxNoteImportFileSize = 16 << 20
+	// maxNoteImportWarnings caps the warnings reported for one import
+	maxNoteImportWarnings = 100
+	// noteImportAssetPrefix is where embedded files are stored, by content hash
+	noteImportAssetPrefix = "imports/assets"
+)
+
+// markdownLink matches [label](target) and ![alt](target)
+var markdownLink = regexp.MustCompile(`(!?)\[([^\]]*)\]\(([^)\s]+)\)`)
+
+// NoteImportService imports the exports of other note-taking apps
+type NoteImportService interface {
+	// Import reads the export in fsys and creates its pages and blocks
+	Import(ctx context.Context, format models.NoteImportFormat, fsys fs.FS) (*models.NoteImportResult, error)
+
+	// ImportWithOptions reads the export in fsys and merges it as opts asks
+	ImportWithOptions(ctx context.Context, format models.NoteImportFormat, fsys fs.FS, opts models.NoteImportOptions) (*models.NoteImportResult, error)
+}
+
+// noteImportService implements NoteImportService on top of the chunk, template and
+// storage services
+type noteImportService struct {
+	db        *sql.DB
+	chunks    UnifiedChunkService
+	templates TemplateService
+	storage   StorageService
+	mappings  importMappingStore
+	monitor   QueryPerformanceMonitor
+}
+
+// NewNoteImportService creates a note import service. Without storage, embedded files are
+// reported as warnings and their links kept; without templates, properties go to metadata.
+// Without db, imports are not recorded and upsert imports are unavailable.
+func NewNoteImportService(db *sql.DB, chunks UnifiedChunkService, templates TemplateService, storage StorageService, monitor QueryPerformanceMonitor) NoteImportService {
+	service := &noteImportService{db: db, chunks: chunks, templates: templates, storage: storage, monitor: monitor}
+	if db != nil {
+		service.mappings = sqlImportMappings{db: db}
+	}
+	return service
+}
+
+// ParseNoteImportFormat validates an import format name
+func ParseNoteImportFormat(name string) (models.NoteImportFormat, error) {
+	switch format := models.NoteImportFormat(strings.ToLower(name)); format {
+	case models.NoteImportNotion, models.NoteImportLogseq:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported format %q: use notion or logseq", ErrInvalidNoteImport, name)
+	}
+}
+
+// importedPage is a page read from an export
+type importedPage struct {
+	// key is how links in the export name the page
+	key string
+	// externalID identifies the page across exports of the same source; it defaults to path
+	externalID string
+	// aliases are further keys for the page
+	aliases []string
+	// path is the page's file in the export
+	path       string
+	title      string
+	properties map[string]string
+	// template names the template the properties fill, if any
+	template string
+	// metadata is set by the adapter and kept on the page chunk
+	metadata map[string]interface{}
+	blocks   []*importedBlock
+	id       string
+}
+
+// importedBlock is a block of an imported page. Its text keeps the export's link syntax.
+type importedBlock struct {
+	text       string
+	properties map[string]string
+	// sourceID is the export's ID for the block, named by block references
+	sourceID string
+	// externalID identifies the block across exports: its sourceID or its position
+	externalID string
+	children   []*importedBlock
+	id         string
+}
+
+// noteImportAdapter reads the export of one app
+type noteImportAdapter interface {
+	// readPages parses every page of the export
+	readPages(fsys fs.FS) ([]*importedPage, error)
+	// rewriteLinks resolves the links in text, a block of page, through links and returns
+	// the text with links to imported chunks and stored files
+	rewriteLinks(page *importedPage, text string, links *importLinks) string
+}
+
+func noteImportAdapterFor(format models.NoteImportFormat) (noteImportAdapter, error) {
+	switch format {
+	case models.NoteImportNotion:
+		return notionImporter{}, nil
+	case models.NoteImportLogseq:
+		return logseqImporter{}, nil
+	default:
+		_, err := ParseNoteImportFormat(string(format))
+		return nil, err
+	}
+}
+
+// importLinks resolves the links of an import. While collecting, it records the page
+// titles and files that links ask for, so they can be looked up and uploaded at once;
+// afterwards it resolves links and gathers the references of the block being rewritten.
+type importLinks struct {
+	collecting bool
+	pages      map[string]*importedPage
+	// blocks maps export block IDs to chunk IDs
+	blocks map[string]string
+	// existing maps lowercase titles to pages already in the knowledge base
+	existing map[string]string
+	// assets maps export paths to stored references and keys
+	assets    map[string]string
+	assetKeys map[string]string
+
+	wantTitles map[string]bool
+	wantAssets []string
+
+	refs        []string
+	blockAssets []string
+	unresolved  int
+}
+
+func newImportLinks(pages []*importedPage) *importLinks {
+	links := &importLinks{
+		pages:      make(map[string]*importedPage),
+		blocks:     make(map[string]string),
+		existing:   make(map[string]string),
+		assets:     make(map[string]string),
+		assetKeys:  make(map[string]string),
+		wantTitles: make(map[string]bool),
+	}
+	for _, page := range pages {
+		for _, alias := range page.aliases {
+			links.pages[alias] = page
+		}
+	}
+	// Keys win over aliases of other pages
+	for _, page := range pages {
+		links.pages[page.key] = page
+	}
+	return links
+}
+
+// page resolves a link to the imported page with key or, failing that, to an existing page
+// titled title, and returns the title to show
+func (l *importLinks) page(key, title string) (string, bool) {
+	if target, ok := l.pages[key]; ok {
+		l.addRef(target.id)
+		return target.title, true
+	}
+	lower := strings.ToLower(strings.TrimSpace(title))
+	if l.collecting {
+		if lower != "" {
+			l.wantTitles[lower] = true
+		}
+		return title, false
+	}
+	if id, ok := l.existing[lower]; ok {
+		l.addRef(id)
+		return title, true
+	}
+	l.unresolved++
+	return title, false
+}
+
+// block resolves a block reference to the chunk ID of the imported block
+func (l *importLinks) block(sourceID string) (string, bool) {
+	if id, ok := l.blocks[sourceID]; ok {
+		l.addRef(id)
+		return id, true
+	}
+	if !l.collecting {
+		l.unresolved++
+	}
+	return "", false
+}
+
+// asset resolves an embedded file to its stored reference
+func (l *importLinks) asset(filePath string) (string, bool) {
+	if l.collecting {
+		if _, ok := l.assets[filePath]; !ok {
+			l.assets[filePath] = ""
+			l.wantAssets = append(l.wantAssets, filePath)
+		}
+		return "", false
+	}
+	ref := l.assets[filePath]
+	if ref == "" {
+		return "", false
+	}
+	l.blockAssets = appendUnique(l.blockAssets, l.assetKeys[filePath])
+	return ref, true
+}
+
+func (l *importLinks) addRef(id string) {
+	if !l.collecting {
+		l.refs = appendUnique(l.refs, id)
+	}
+}
+
+// reset starts the references of the next block
+func (l *importLinks) reset() {
+	l.refs = nil
+	l.blockAssets = nil
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, existing := range values {
+		if existing == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
+
+// Import creates the pages and blocks of an export as new chunks
+func (s *noteImportService) Import(ctx context.Context, format models.NoteImportFormat, fsys fs.FS) (*models.NoteImportResult, error) {
+	return s.ImportWithOptions(ctx, format, fsys, models.NoteImportOptions{})
+}
+
+// ImportWithOptions parses the export, assigns IDs, uploads embedded files and writes the
+// chunks
+func (s *noteImportService) ImportWithOptions(ctx context.Context, format models.NoteImportFormat, fsys fs.FS, opts models.NoteImportOptions) (*models.NoteImportResult, error) {
+	start := time.Now()
+	opts, err := normalizeNoteImportOptions(format, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := &models.NoteImportResult{Format: format, Mode: opts.Mode, Source: opts.Source, PageIDs: []string{}, Warnings: []string{}}
+	defer func() {
+		s.monitor.RecordQuery("note_import", time.Since(start), len(result.PageIDs)+result.Chunks)
+	}()
+
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	adapter, err := noteImportAdapterFor(format)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := adapter.readPages(fsys)
+	if err != nil {
+		return nil, err
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: no pages found in the %s export", ErrInvalidNoteImport, format)
+	}
+	sort.Slice(pages, func(i, j int) bool { return pages[i].path < pages[j].path })
+	assignImportExternalIDs(pages)
+
+	plan, err := s.loadImportPlan(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	links := newImportLinks(pages)
+	if count := assignImportIDs(pages, links, plan); count > maxNoteImportChunks {
+		return nil, fmt.Errorf("%w: %d pages and blocks exceed the limit of %d", ErrInvalidNoteImport, count, maxNoteImportChunks)
+	}
+
+	// The first pass only collects what the links name
+	links.collecting = true
+	walkImportedBlocks(pages, func(page *importedPage, block *importedBlock, parentID string) {
+		adapter.rewriteLinks(page, block.text, links)
+	})
+	links.collecting = false
+
+	if links.existing, err = findPagesByTitle(ctx, s.db, links.wantTitles); err != nil {
+		return nil, err
+	}
+	if err := s.uploadAssets(ctx, fsys, links, result); err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*models.TemplateWithInstances)
+	for _, page := range pages {
+		result.PageIDs = append(result.PageIDs, page.id)
+		hash := importedPageHash(page)
+		change := plan.classify(page.externalID, hash)
+		if change == importUnchanged || change == importConflict {
+			plan.stage(change, page.externalID, hash, models.UnifiedChunkRecord{ChunkID: page.id})
+			continue
+		}
+
+		metadata := map[string]interface{}{
+			"import_source": string(format),
+			"import_path":   page.path,
+		}
+		for key, value := range page.metadata {
+			metadata[key] = value
+		}
+		properties := page.properties
+		if instanceID, remaining := s.fillTemplate(ctx, page, templates, plan.templateInstance(page.id), result); instanceID != "" {
+			metadata["template_instance_id"] = instanceID
+			properties = remaining
+			result.TemplateInstances++
+		}
+		if len(properties) > 0 {
+			metadata["properties"] = properties
+		}
+
+		plan.stage(change, page.externalID, hash, models.UnifiedChunkRecord{
+			ChunkID:  page.id,
+			Contents: page.title,
+			IsPage:   true,
+			Metadata: metadata,
+		})
+	}
+
+	walkImportedBlocks(pages, func(page *importedPage, block *importedBlock, parentID string) {
+		links.reset()
+		contents := adapter.rewriteLinks(page, block.text, links)
+
+		parent, pageID := parentID, page.id
+		record := models.UnifiedChunkRecord{
+			ChunkID:  block.id,
+			Contents: contents,
+			Parent:   &parent,
+			Page:     &pageID,
+			Metadata: map[string]interface{}{},
+		}
+		if len(links.refs) > 0 {
+			ref := strings.Join(links.refs, ", ")
+			record.Ref = &ref
+			result.Links += len(links.refs)
+		}
+		if len(block.properties) > 0 {
+			record.Metadata["properties"] = block.properties
+		}
+		if len(links.blockAssets) > 0 {
+			record.Metadata["assets"] = links.blockAssets
+		}
+		hash := importedBlockHash(&record)
+		change := plan.classify(block.externalID, hash)
+		if change == importCreate {
+			result.Chunks++
+		}
+		plan.stage(change, block.externalID, hash, record)
+	})
+	result.UnresolvedLinks = links.unresolved
+
+	if err := s.applyImportPlan(ctx, plan, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// assignImportIDs gives every page and block a chunk ID, the chunk it was imported into
+// before if any, and returns how many there are
+func assignImportIDs(pages []*importedPage, links *importLinks, plan *importPlan) int {
+	count := 0
+	for _, page := range pages {
+		page.id = plan.chunkID(page.externalID)
+		count++
+	}
+	walkImportedBlocks(pages, func(page *importedPage, block *importedBlock, parentID string) {
+		block.id = plan.chunkID(block.externalID)
+		if block.sourceID != "" {
+			links.blocks[block.sourceID] = block.id
+		}
+		count++
+	})
+	return count
+}
+
+// walkImportedBlocks visits the blocks of every page, parents before children and
+// siblings in order, with the chunk ID of their parent
+func walkImportedBlocks(pages []*importedPage, visit func(page *importedPage, block *importedBlock, parentID string)) {
+	var walk func(page *importedPage, blocks []*importedBlock, parentID string)
+	walk = func(page *importedPage, blocks []*importedBlock, parentID string) {
+		for _, block := range blocks {
+			visit(page, block, parentID)
+			walk(page, block.children, block.id)
+		}
+	}
+	for _, page := range pages {
+		walk(page, page.blocks, page.id)
+	}
+}
+
+// findPagesByTitle maps lowercase titles of existing pages to their IDs, the oldest page
+// first
+func findPagesByTitle(ctx context.Context, db *sql.DB, titles map[string]bool) (map[string]string, error) {
+	ids := make(map[string]string, len(titles))
+	if len(titles) == 0 {
+		return ids, nil
+	}
+	names := make([]string, 0, len(titles))
+	for title := range titles {
+		names = append(names, title)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT chunk_id, lower(contents) FROM chunks
+		WHERE is_page = true AND lower(contents) = ANY($1)
+		ORDER BY created_time`,
+		pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up linked pages: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return nil, fmt.Errorf("failed to scan linked page: %w", err)
+		}
+		if _, ok := ids[title]; !ok {
+			ids[title] = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating linked pages: %w", err)
+	}
+	return ids, nil
+}
+
+// uploadAssets stores the files links point at. Files that are missing or fail to upload
+// are reported as warnings and their links kept.
+func (s *noteImportService) uploadAssets(ctx context.Context, fsys fs.FS, links *importLinks, result *models.NoteImportResult) error {
+	if len(links.wantAssets) == 0 {
+		return nil
+	}
+	if s.storage == nil {
+		addImportWarning(result, fmt.Sprintf("object storage is not configured; %d embedded files were not uploaded", len(links.wantAssets)))
+		return nil
+	}
+
+	for _, filePath := range links.wantAssets {
+		object, err := s.uploadAsset(ctx, fsys, filePath)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			addImportWarning(result, fmt.Sprintf("embedded file %s was not uploaded: %v", filePath, err))
+			continue
+		}
+		ref := object.URL
+		if ref == "" {
+			ref = object.Key
+		}
+		links.assets[filePath] = ref
+		links.assetKeys[filePath] = object.Key
+		result.Assets++
+	}
+	return nil
+}
+
+// uploadAsset stores a file under its content hash, so the same file is stored once
+func (s *noteImportService) uploadAsset(ctx context.Context, fsys fs.FS, filePath string) (*models.StorageObject, error) {
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	file, err = fsys.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(path.Ext(filePath))
+	key := fmt.Sprintf("%s/%s/%s%s", noteImportAssetPrefix, sum[:2], sum, ext)
+	return s.storage.Put(ctx, key, file, &models.PutObjectOptions{
+		ContentType: mime.TypeByExtension(ext),
+		Size:        size,
+		Overwrite:   true,
+	})
+}
+
+// fillTemplate creates an instance of the page's template from the properties matching its
+// slots, or updates the slots of instanceID, the instance of an earlier import, and returns
+// the instance ID with the properties left over. Pages without a matching template return
+// no instance.
+func (s *noteImportService) fillTemplate(ctx context.Context, page *importedPage, cache map[string]*models.TemplateWithInstances, instanceID string, result *models.NoteImportResult) (string, map[string]string) {
+	if s.templates == nil || page.template == "" || len(page.properties) == 0 {
+		return "", page.properties
+	}
+
+	template, ok := cache[page.template]
+	if !ok {
+		// Pages naming a template that does not exist keep their properties in metadata
+		template, _ = s.templates.GetTemplate(ctx, page.template+"#template")
+		cache[page.template] = template
+	}
+	if template == nil || template.Template == nil {
+		return "", page.properties
+	}
+
+	remaining := make(map[string]string, len(page.properties))
+	for key, value := range page.properties {
+		remaining[key] = value
+	}
+	values := make(map[string]string)
+	var slots []string
+	for _, slotName := range TemplateSlotNames(template) {
+		for key, value := range remaining {
+			if strings.EqualFold(key, slotName) {
+				values[slotName] = value
+				slots = append(slots, slotName)
+				delete(remaining, key)
+				break
+			}
+		}
+	}
+	if len(values) == 0 {
+		return "", page.properties
+	}
+
+	if instanceID != "" {
+		for _, slotName := range slots {
+			if err := s.templates.UpdateSlotValue(ctx, instanceID, slotName, values[slotName]); err != nil {
+				addImportWarning(result, fmt.Sprintf("properties of %s kept as metadata: %v", page.path, err))
+				return "", page.properties
+			}
+		}
+		return instanceID, remaining
+	}
+
+	instance, err := s.templates.CreateInstance(ctx, &models.CreateInstanceRequest{
+		TemplateChunkID: template.Template.ID,
+		InstanceName:    page.title,
+		SlotValues:      values,
+	})
+	if err != nil {
+		addImportWarning(result, fmt.Sprintf("properties of %s kept as metadata: %v", page.path, err))
+		return "", page.properties
+	}
+	return instance.Instance.ID, remaining
+}
+
+func addImportWarning(result *models.NoteImportResult, warning string) {
+	if len(result.Warnings) < maxNoteImportWarnings {
+		result.Warnings = append(result.Warnings, warning)
+	}
+}
+
+// readImportFile reads a page file of an export
+func readImportFile(fsys fs.FS, filePath string) (string, error) {
+	file, err := fsys.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxNoteImportFileSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	if len(data) > maxNoteImportFileSize {
+		return "", fmt.Errorf("%w: %s is larger than %d bytes", ErrInvalidNoteImport, filePath, maxNoteImportFileSize)
+	}
+	return strings.ReplaceAll(string(data), "\r\n", "\n"), nil
+}
+
+// localImportPath resolves a link target relative to the page file to a path in the
+// export, rejecting URLs and paths that leave the export
+func localImportPath(pagePath, target string) (string, bool) {
+	if target == "" || strings.Contains(target, "://") || strings.HasPrefix(target, "mailto:") ||
+		strings.HasPrefix(target, "#") || strings.HasPrefix(target, "/") {
+		return "", false
+	}
+	filePath := path.Join(path.Dir(pagePath), target)
+	if !fs.ValidPath(filePath) {
+		return "", false
+	}
+	return filePath, true
+}
+
+// unescapeImportPath decodes a percent-encoded link target, keeping targets that are not
+// valid encodings as they are
+func unescapeImportPath(target string) string {
+	if decoded, err := url.PathUnescape(target); err == nil {
+		return decoded
+	}
+	return target
+}
+
+// rewriteAssetLinks points markdown links to local files at their stored copies. Links to
+// pages and databases are left to the adapter.
+func rewriteAssetLinks(page *importedPage, text string, links *importLinks) string {
+	return markdownLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLink.FindStringSubmatch(match)
+		filePath, ok := localImportPath(page.path, unescapeImportPath(parts[3]))
+		if !ok {
+			return match
+		}
+		if ext := strings.ToLower(path.Ext(filePath)); ext == ".md" || ext == ".csv" {
+			return match
+		}
+		if ref, ok := links.asset(filePath); ok {
+			return parts[1] + "[" + parts[2] + "](" + ref + ")"
+		}
+		return match
+	})
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Logseq graphs keep one markdown file per page in pages/ and per day in journals/, with
+// embedded files in assets/. Blocks are "- " bullets nested by indentation; "key:: value"
+// lines after a bullet are block properties, and before the first bullet page properties.
+// Pages are linked as [[Title]], #tag or #[[Title]], and blocks by ((uuid)) of their id
+// property.
+
+var (
+	logseqPropertyLine = regexp.MustCompile(`^([A-Za-z0-9_\-]+)::\s?(.*)$`)
+	logseqPageLink     = regexp.MustCompile(`#?\[\[([^\[\]]+)\]\]`)
+	logseqTagLink      = regexp.MustCompile(`(?:^|\s)#([^\s#\[\]\(\),.;:!?"']+)`)
+	logseqBlockRef     = regexp.MustCompile(`\(\(([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\)\)`)
+	logseqJournalName  = regexp.MustCompile(`^(\d{4})_(\d{2})_(\d{2})$`)
+)
+
+// logseqHiddenProperties are block properties Logseq keeps for itself
+var logseqHiddenProperties = map[string]bool{"id": true, "collapsed": true}
+
+// logseqImporter reads Logseq graphs
+type logseqImporter struct{}
+
+// readPages reads the pages and journals folders of the graph, which may sit below the root
+// of fsys as in a zipped graph
+func (logseqImporter) readPages(fsys fs.FS) ([]*importedPage, error) {
+	root, err := findLogseqRoot(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var pages []*importedPage
+	for _, folder := range []string{"pages", "journals"} {
+		dir := path.Join(root, folder)
+		entries, err := fs.ReadDir(fsys, dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.EqualFold(path.Ext(entry.Name()), ".md") {
+				continue
+			}
+			filePath := path.Join(dir, entry.Name())
+			content, err := readImportFile(fsys, filePath)
+			if err != nil {
+				return nil, err
+			}
+			page := parseLogseqPage(filePath, folder == "journals", content)
+			// Relative to the graph, so the folder the graph is exported to does not matter
+			page.externalID = path.Join(folder, entry.Name())
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+// findLogseqRoot returns the shallowest folder holding pages/ or journals/
+func findLogseqRoot(fsys fs.FS) (string, error) {
+	root := ""
+	found := false
+	err := fs.WalkDir(fsys, ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.IsDir() {
+			return err
+		}
+		if name := entry.Name(); name == "pages" || name == "journals" {
+			parent := path.Dir(p)
+			if !found || strings.Count(parent, "/") < strings.Count(root, "/") {
+				root, found = parent, true
+			}
+			return fs.SkipDir
+		}
+		// Logseq's own folder holds backups of pages, not pages
+		if entry.Name() == "logseq" || entry.Name() == "node_modules" || (strings.HasPrefix(entry.Name(), ".") && p != ".") {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to read the Logseq graph: %w", err)
+	}
+	if !found {
+		return "", fmt.Errorf("%w: no pages or journals folder found in the Logseq graph", ErrInvalidNoteImport)
+	}
+	return root, nil
+}
+
+// parseLogseqPage parses a page or journal file
+func parseLogseqPage(filePath string, journal bool, content string) *importedPage {
+	name := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))
+	page := &importedPage{
+		path:       filePath,
+		title:      logseqTitleFromFileName(name),
+		properties: make(map[string]string),
+		metadata:   make(map[string]interface{}),
+	}
+	if match := logseqJournalName.FindStringSubmatch(name); journal && match != nil {
+		if day, err := time.Parse("2006_01_02", name); err == nil {
+			page.title = logseqJournalTitle(day)
+			page.metadata["journal_date"] = day.Format("2006-01-02")
+		}
+	}
+
+	page.blocks = parseLogseqBlocks(content, page.properties)
+	if title := page.properties["title"]; title != "" {
+		page.title = title
+		delete(page.properties, "title")
+	}
+	for _, alias := range strings.Split(page.properties["alias"], ",") {
+		alias = strings.Trim(strings.TrimSpace(alias), "[]")
+		if alias != "" {
+			page.aliases = append(page.aliases, strings.ToLower(alias))
+		}
+	}
+	page.template = page.properties["type"]
+	page.key = strings.ToLower(page.title)
+	return page
+}
+
+// logseqTitleFromFileName decodes the file name escapes of page titles: "___" and %2F for
+// namespace slashes and percent-encoding for other reserved characters
+func logseqTitleFromFileName(name string) string {
+	name = strings.ReplaceAll(name, "___", "/")
+	return unescapeImportPath(name)
+}
+
+// logseqJournalTitle formats a day the way Logseq titles journals by default, "Jan 2nd, 2024"
+func logseqJournalTitle(day time.Time) string {
+	suffix := "th"
+	switch d := day.Day(); {
+	case d%10 == 1 && d != 11:
+		suffix = "st"
+	case d%10 == 2 && d != 12:
+		suffix = "nd"
+	case d%10 == 3 && d != 13:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%s %d%s, %d", day.Format("Jan"), day.Day(), suffix, day.Year())
+}
+
+// logseqBlockState is a block being parsed with the indentation of its bullet
+type logseqBlockState struct {
+	block  *importedBlock
+	indent int
+	lines  []string
+	// inProperties is set until the first line after the bullet that is not a property
+	inProperties bool
+	inFence      bool
+}
+
+// addProperty stores line as a block property if it is one
+func (s *logseqBlockState) addProperty(line string) bool {
+	match := logseqPropertyLine.FindStringSubmatch(line)
+	if match == nil {
+		return false
+	}
+	key := strings.ToLower(match[1])
+	value := strings.TrimSpace(match[2])
+	switch {
+	case key == "id":
+		s.block.sourceID = strings.ToLower(value)
+	case !logseqHiddenProperties[key]:
+		s.block.properties[key] = value
+	}
+	return true
+}
+
+// parseLogseqBlocks parses the bullets of a page, storing properties before the first
+// bullet in pageProperties
+func parseLogseqBlocks(content string, pageProperties map[string]string) []*importedBlock {
+	var roots []*importedBlock
+	var stack []*logseqBlockState
+	var loose []string
+
+	finish := func(state *logseqBlockState) {
+		state.block.text = strings.TrimRight(strings.Join(state.lines, "\n"), " \t\n")
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		indent := logseqIndent(line)
+		trimmed := strings.TrimSpace(line)
+		var current *logseqBlockState
+		if len(stack) > 0 {
+			current = stack[len(stack)-1]
+		}
+
+		isBullet := trimmed == "-" || strings.HasPrefix(trimmed, "- ")
+		if isBullet && (current == nil || !current.inFence) {
+			for len(stack) > 0 && stack[len(stack)-1].indent >= indent {
+				finish(stack[len(stack)-1])
+				stack = stack[:len(stack)-1]
+			}
+			state := &logseqBlockState{
+				block:        &importedBlock{properties: make(map[string]string)},
+				indent:       indent,
+				inProperties: true,
+			}
+			first := strings.TrimSpace(strings.TrimPrefix(trimmed, "-"))
+			if !state.addProperty(first) {
+				state.lines = []string{first}
+				state.inFence = strings.HasPrefix(first, "```")
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1].block
+				parent.children = append(parent.children, state.block)
+			} else {
+				roots = append(roots, state.block)
+			}
+			stack = append(stack, state)
+			continue
+		}
+
+		if current == nil {
+			// Before the first bullet: page properties, or text of a page without bullets
+			if match := logseqPropertyLine.FindStringSubmatch(trimmed); match != nil && len(loose) == 0 {
+				pageProperties[strings.ToLower(match[1])] = strings.TrimSpace(match[2])
+			} else if trimmed != "" || len(loose) > 0 {
+				loose = append(loose, line)
+			}
+			continue
+		}
+
+		if current.inProperties && !current.inFence {
+			if current.addProperty(trimmed) {
+				continue
+			}
+			current.inProperties = false
+		}
+		if strings.HasPrefix(trimmed, "```") {
+			current.inFence = !current.inFence
+		}
+		// Continuation lines are indented past the bullet's "- "
+		current.lines = append(current.lines, trimIndent(line, current.indent+2))
+	}
+	for _, state := range stack {
+		finish(state)
+	}
+
+	if text := strings.TrimSpace(strings.Join(loose, "\n")); text != "" {
+		roots = append([]*importedBlock{{text: text}}, roots...)
+	}
+
+	// Older graphs keep page properties in a first bullet of only properties
+	if len(roots) > 0 && roots[0].text == "" && len(roots[0].children) == 0 && len(roots[0].properties) > 0 &&
+		len(pageProperties) == 0 {
+		for key, value := range roots[0].properties {
+			pageProperties[key] = value
+		}
+		roots = roots[1:]
+	}
+	return roots
+}
+
+// logseqIndent measures leading whitespace, counting a tab as four spaces
+func logseqIndent(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 4
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// trimIndent removes up to width columns of leading whitespace
+func trimIndent(line string, width int) string {
+	removed := 0
+	for i, r := range line {
+		if removed >= width || (r != ' ' && r != '\t') {
+			return line[i:]
+		}
+		if r == '\t' {
+			removed += 4
+		} else {
+			removed++
+		}
+	}
+	return ""
+}
+
+// rewriteLinks keeps page links and tags as written, since they name pages by title, and
+// records them as references. Block references point at the new chunk IDs.
+func (logseqImporter) rewriteLinks(page *importedPage, text string, links *importLinks) string {
+	text = logseqBlockRef.ReplaceAllStringFunc(text, func(match string) string {
+		sourceID := strings.ToLower(logseqBlockRef.FindStringSubmatch(match)[1])
+		if id, ok := links.block(sourceID); ok {
+			return "((" + id + "))"
+		}
+		return match
+	})
+	for _, match := range logseqPageLink.FindAllStringSubmatch(text, -1) {
+		links.page(strings.ToLower(match[1]), match[1])
+	}
+	for _, match := range logseqTagLink.FindAllStringSubmatch(text, -1) {
+		links.page(strings.ToLower(match[1]), match[1])
+	}
+	return rewriteAssetLinks(page, text, links)
+}
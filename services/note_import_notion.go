@@ -0,0 +1,243 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Notion's "Markdown & CSV" export writes each page as "Title <32 hex ID>.md" and its
+// subpages and files in a folder of the same name. A database is a CSV file next to the
+// folder of its row pages; each row page lists the database's properties as "Key: Value"
+// lines under its "# Title". Links name other pages by relative, percent-encoded path.
+
+var (
+	notionIDSuffix    = regexp.MustCompile(` [0-9a-f]{32}$`)
+	notionListItem    = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	notionProperty    = regexp.MustCompile(`^([^:]{1,100}): (.*)$`)
+	notionFenceMarker = "```"
+)
+
+// notionImporter reads Notion exports
+type notionImporter struct{}
+
+// readPages reads every markdown file of the export
+func (notionImporter) readPages(fsys fs.FS) ([]*importedPage, error) {
+	columns := make(map[string]map[string]bool)
+	var pages []*importedPage
+	err := fs.WalkDir(fsys, ".", func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if strings.HasPrefix(entry.Name(), "__MACOSX") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !strings.EqualFold(path.Ext(p), ".md") {
+			return nil
+		}
+
+		content, err := readImportFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		dir := path.Dir(p)
+		if _, ok := columns[dir]; !ok {
+			columns[dir] = readNotionColumns(fsys, dir)
+		}
+		pages = append(pages, parseNotionPage(p, content, columns[dir]))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Notion export: %w", err)
+	}
+	return pages, nil
+}
+
+// readNotionColumns returns the property names of the database whose rows are in dir, or
+// nil when dir is not a database
+func readNotionColumns(fsys fs.FS, dir string) map[string]bool {
+	if dir == "." {
+		return nil
+	}
+	file, err := fsys.Open(dir + ".csv")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	header, err := csv.NewReader(file).Read()
+	if err != nil {
+		return nil
+	}
+	columns := make(map[string]bool, len(header))
+	for _, name := range header {
+		// Notion starts the file with a byte order mark
+		columns[strings.TrimPrefix(strings.TrimSpace(name), "\ufeff")] = true
+	}
+	return columns
+}
+
+// notionName strips the ID Notion appends to file and folder names
+func notionName(name string) string {
+	return notionIDSuffix.ReplaceAllString(name, "")
+}
+
+// parseNotionPage parses a page file. Rows of a database take its property lines and fill
+// the template named like the database.
+func parseNotionPage(filePath, content string, columns map[string]bool) *importedPage {
+	page := &importedPage{
+		key:        filePath,
+		path:       filePath,
+		title:      notionName(strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))),
+		properties: make(map[string]string),
+	}
+	// The ID Notion appends stays the same when the page is renamed or moved
+	if id := notionIDSuffix.FindString(strings.TrimSuffix(path.Base(filePath), path.Ext(filePath))); id != "" {
+		page.externalID = "notion:" + strings.TrimSpace(id)
+	}
+
+	lines := strings.Split(content, "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		page.title = strings.TrimSpace(strings.TrimPrefix(lines[0], "# "))
+		lines = lines[1:]
+	}
+
+	if len(columns) > 0 {
+		page.template = notionName(path.Base(path.Dir(filePath)))
+		lines = parseNotionProperties(lines, columns, page.properties)
+	}
+	page.blocks = parseMarkdownBlocks(lines)
+	return page
+}
+
+// parseNotionProperties takes the "Key: Value" lines of database columns from the top of
+// lines and returns the rest
+func parseNotionProperties(lines []string, columns map[string]bool, properties map[string]string) []string {
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	for ; i < len(lines); i++ {
+		match := notionProperty.FindStringSubmatch(lines[i])
+		if match == nil || !columns[match[1]] {
+			break
+		}
+		properties[match[1]] = strings.TrimSpace(match[2])
+	}
+	if len(properties) == 0 {
+		return lines
+	}
+	return lines[i:]
+}
+
+// parseMarkdownBlocks splits markdown into blocks: paragraphs, headings and fenced code
+// become top-level blocks, and list items nest by indentation
+func parseMarkdownBlocks(lines []string) []*importedBlock {
+	type listLevel struct {
+		indent int
+		block  *importedBlock
+	}
+	var roots []*importedBlock
+	var list []listLevel
+	var paragraph []string
+	inFence := false
+
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(paragraph, "\n")); text != "" {
+			roots = append(roots, &importedBlock{text: text})
+		}
+		paragraph = nil
+	}
+
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if inFence {
+			paragraph = append(paragraph, line)
+			if strings.HasPrefix(trimmed, notionFenceMarker) {
+				inFence = false
+				flush()
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, notionFenceMarker) {
+			flush()
+			list = nil
+			inFence = true
+			paragraph = append(paragraph, line)
+			continue
+		}
+		if trimmed == "" {
+			// Blank lines end paragraphs but not lists
+			flush()
+			continue
+		}
+
+		if match := notionListItem.FindStringSubmatch(line); match != nil {
+			flush()
+			indent := logseqIndent(match[1])
+			text := match[3]
+			if marker := match[2]; marker != "-" && marker != "*" && marker != "+" {
+				// Numbered items keep their number
+				text = marker + " " + text
+			}
+			block := &importedBlock{text: text}
+			for len(list) > 0 && list[len(list)-1].indent >= indent {
+				list = list[:len(list)-1]
+			}
+			if len(list) > 0 {
+				parent := list[len(list)-1].block
+				parent.children = append(parent.children, block)
+			} else {
+				roots = append(roots, block)
+			}
+			list = append(list, listLevel{indent: indent, block: block})
+			continue
+		}
+
+		if len(list) > 0 && logseqIndent(line) > list[len(list)-1].indent {
+			// An indented line continues the list item above
+			item := list[len(list)-1].block
+			item.text += "\n" + trimmed
+			continue
+		}
+		list = nil
+
+		if strings.HasPrefix(trimmed, "#") {
+			flush()
+			roots = append(roots, &importedBlock{text: trimmed})
+			continue
+		}
+		paragraph = append(paragraph, line)
+	}
+	flush()
+	return roots
+}
+
+// rewriteLinks turns links to exported pages into [[Title]] references and points links to
+// exported files at their stored copies
+func (notionImporter) rewriteLinks(page *importedPage, text string, links *importLinks) string {
+	text = markdownLink.ReplaceAllStringFunc(text, func(match string) string {
+		parts := markdownLink.FindStringSubmatch(match)
+		if parts[1] == "!" {
+			return match
+		}
+		filePath, ok := localImportPath(page.path, unescapeImportPath(parts[3]))
+		if !ok || !strings.EqualFold(path.Ext(filePath), ".md") {
+			return match
+		}
+		if title, ok := links.page(filePath, parts[2]); ok {
+			return "[[" + title + "]]"
+		}
+		return match
+	})
+	return rewriteAssetLinks(page, text, links)
+}
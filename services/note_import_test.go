@@ -0,0 +1,452 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+const logseqProjectPage = `type:: project
+status:: active
+
+- Kickoff with [[Alice]] #planning
+  id:: 6571f3a2-1b2c-4d5e-8f90-a1b2c3d4e5f6
+  owner:: alice
+	- Agenda
+	  spans two lines
+	- ![diagram](../assets/diagram.png)
+- ` + "```go" + `
+  - not a bullet
+  ` + "```" + `
+`
+
+const logseqJournal = `- Followed up on ((6571f3a2-1b2c-4d5e-8f90-a1b2c3d4e5f6))
+`
+
+func logseqGraph() fstest.MapFS {
+	return fstest.MapFS{
+		"graph/pages/Project Apollo.md":     {Data: []byte(logseqProjectPage)},
+		"graph/pages/Alice.md":              {Data: []byte("alias:: Al\n\n- Engineer\n")},
+		"graph/pages/planning.md":           {Data: []byte("- Plans\n")},
+		"graph/journals/2024_01_02.md":      {Data: []byte(logseqJournal)},
+		"graph/assets/diagram.png":          {Data: []byte("png bytes")},
+		"graph/logseq/bak/pages/Old.md":     {Data: []byte("- backup\n")},
+		"graph/logseq/config.edn":           {Data: []byte("{}")},
+		"graph/pages/notes/ignored-dir.txt": {Data: []byte("not a page")},
+	}
+}
+
+func TestParseLogseqPage(t *testing.T) {
+	page := parseLogseqPage("pages/Project Apollo.md", false, logseqProjectPage)
+
+	assert.Equal(t, "Project Apollo", page.title)
+	assert.Equal(t, "project apollo", page.key)
+	assert.Equal(t, "project", page.template)
+	assert.Equal(t, map[string]string{"type": "project", "status": "active"}, page.properties)
+
+	require.Len(t, page.blocks, 2)
+	kickoff := page.blocks[0]
+	assert.Equal(t, "Kickoff with [[Alice]] #planning", kickoff.text)
+	assert.Equal(t, "6571f3a2-1b2c-4d5e-8f90-a1b2c3d4e5f6", kickoff.sourceID)
+	assert.Equal(t, map[string]string{"owner": "alice"}, kickoff.properties)
+	require.Len(t, kickoff.children, 2)
+	assert.Equal(t, "Agenda\nspans two lines", kickoff.children[0].text)
+	assert.Equal(t, "![diagram](../assets/diagram.png)", kickoff.children[1].text)
+	assert.Equal(t, "```go\n- not a bullet\n```", page.blocks[1].text, "bullets in code fences are code")
+}
+
+func TestParseLogseqPage_TitlesAndOldStyleProperties(t *testing.T) {
+	journal := parseLogseqPage("journals/2024_01_02.md", true, logseqJournal)
+	assert.Equal(t, "Jan 2nd, 2024", journal.title)
+	assert.Equal(t, "2024-01-02", journal.metadata["journal_date"])
+
+	namespaced := parseLogseqPage("pages/work___q1%3F.md", false, "- title:: Work/Q1 plan\n  alias:: [[Q1]], quarter one\n- Goals\n")
+	assert.Equal(t, "Work/Q1 plan", namespaced.title, "a title property wins over the file name")
+	assert.Equal(t, []string{"q1", "quarter one"}, namespaced.aliases)
+	require.Len(t, namespaced.blocks, 1)
+	assert.Equal(t, "Goals", namespaced.blocks[0].text)
+
+	assert.Equal(t, "work/q1?", parseLogseqPage("pages/work___q1%3F.md", false, "").title)
+}
+
+func TestParseNotionPage(t *testing.T) {
+	content := "# Launch plan\n\nStatus: Done\nOwner: Bob\n\nIntro paragraph\nstill intro\n\n## Steps\n\n1. Draft\n   - Outline\n     more outline\n2. Review\n\n```\n- code\n```\n"
+	page := parseNotionPage("Tasks abc/Launch plan 0123456789abcdef0123456789abcdef.md", content,
+		map[string]bool{"Name": true, "Status": true, "Owner": true})
+
+	assert.Equal(t, "Launch plan", page.title)
+	assert.Equal(t, "Tasks abc", page.template)
+	assert.Equal(t, map[string]string{"Status": "Done", "Owner": "Bob"}, page.properties)
+
+	texts := make([]string, len(page.blocks))
+	for i, block := range page.blocks {
+		texts[i] = block.text
+	}
+	assert.Equal(t, []string{"Intro paragraph\nstill intro", "## Steps", "1. Draft", "2. Review", "```\n- code\n```"}, texts)
+	require.Len(t, page.blocks[2].children, 1)
+	assert.Equal(t, "Outline\nmore outline", page.blocks[2].children[0].text)
+
+	plain := parseNotionPage("Notes 0123456789abcdef0123456789abcdef.md", "Status: not a property\n", nil)
+	assert.Equal(t, "Notes", plain.title, "the file name names pages without a heading")
+	assert.Empty(t, plain.properties)
+	require.Len(t, plain.blocks, 1)
+}
+
+func TestLocalImportPath(t *testing.T) {
+	path, ok := localImportPath("Home/Page.md", "Sub Page.md")
+	assert.True(t, ok)
+	assert.Equal(t, "Home/Sub Page.md", path)
+
+	for _, target := range []string{"https://example.com/a.png", "mailto:a@b.c", "#heading", "/etc/passwd", "../../outside.png"} {
+		_, ok := localImportPath("Home/Page.md", target)
+		assert.False(t, ok, target)
+	}
+}
+
+// importChunks captures the chunks an import creates and keeps them versioned in stored
+type importChunks struct {
+	UnifiedChunkService
+	created []models.UnifiedChunkRecord
+	stored  map[string]*models.UnifiedChunkRecord
+	deleted []string
+}
+
+func (c *importChunks) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	c.created = append(c.created, chunks...)
+	if c.stored == nil {
+		c.stored = make(map[string]*models.UnifiedChunkRecord)
+	}
+	for _, chunk := range chunks {
+		chunk.Version = 1
+		c.stored[chunk.ChunkID] = &chunk
+	}
+	return nil
+}
+
+func (c *importChunks) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	found := make(map[string]*models.UnifiedChunkRecord)
+	for _, id := range chunkIDs {
+		if chunk, ok := c.stored[id]; ok {
+			copied := *chunk
+			found[id] = &copied
+		}
+	}
+	return found, nil
+}
+
+func (c *importChunks) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for _, chunk := range chunks {
+		if stored := c.stored[chunk.ChunkID]; stored == nil || stored.Version != chunk.Version {
+			return ErrVersionConflict
+		}
+	}
+	for _, chunk := range chunks {
+		chunk.Version++
+		c.stored[chunk.ChunkID] = &chunk
+	}
+	return nil
+}
+
+func (c *importChunks) DeleteChunk(ctx context.Context, chunkID string) error {
+	delete(c.stored, chunkID)
+	c.deleted = append(c.deleted, chunkID)
+	return nil
+}
+
+func (c *importChunks) storedByContents(contents string) *models.UnifiedChunkRecord {
+	for _, chunk := range c.stored {
+		if chunk.Contents == contents {
+			return chunk
+		}
+	}
+	return nil
+}
+
+// memoryImportMappings keeps import mappings per source
+type memoryImportMappings map[string]map[string]importMapping
+
+func (m memoryImportMappings) load(ctx context.Context, source string) (map[string]importMapping, error) {
+	loaded := make(map[string]importMapping)
+	for externalID, mapping := range m[source] {
+		loaded[externalID] = mapping
+	}
+	return loaded, nil
+}
+
+func (m memoryImportMappings) save(ctx context.Context, source string, mappings map[string]importMapping) error {
+	if m[source] == nil {
+		m[source] = make(map[string]importMapping)
+	}
+	for externalID, mapping := range mappings {
+		m[source][externalID] = mapping
+	}
+	return nil
+}
+
+func (c *importChunks) byContents(contents string) *models.UnifiedChunkRecord {
+	for i := range c.created {
+		if c.created[i].Contents == contents {
+			return &c.created[i]
+		}
+	}
+	return nil
+}
+
+// importStorage keeps uploaded objects in memory and fails keys containing "broken"
+type importStorage struct {
+	StorageService
+	objects map[string][]byte
+}
+
+func (s *importStorage) Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.Contains(string(data), "broken") {
+		return nil, errors.New("upload failed")
+	}
+	s.objects[key] = data
+	return &models.StorageObject{Key: key, URL: "https://files.example.com/" + key}, nil
+}
+
+// importTemplates serves one template and records the instances created from it
+type importTemplates struct {
+	TemplateService
+	template     *models.TemplateWithInstances
+	instances    []*models.CreateInstanceRequest
+	updatedSlots []string
+}
+
+func (s *importTemplates) GetTemplate(ctx context.Context, templateContent string) (*models.TemplateWithInstances, error) {
+	if templateContent != s.template.Template.Content {
+		return nil, fmt.Errorf("template not found: %s", templateContent)
+	}
+	return s.template, nil
+}
+
+func (s *importTemplates) CreateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error) {
+	s.instances = append(s.instances, req)
+	return &models.TemplateInstance{Instance: &models.ChunkRecord{ID: fmt.Sprintf("instance-%d", len(s.instances))}}, nil
+}
+
+func (s *importTemplates) UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error {
+	s.updatedSlots = append(s.updatedSlots, instanceChunkID+"/"+slotName+"="+value)
+	return nil
+}
+
+func newTestNoteImport() (*noteImportService, *importChunks, *importStorage, *importTemplates) {
+	chunks := &importChunks{}
+	storage := &importStorage{objects: make(map[string][]byte)}
+	templates := &importTemplates{template: &models.TemplateWithInstances{
+		Template: &models.ChunkRecord{ID: "template-1", Content: "project#template"},
+		Slots:    []models.ChunkRecord{{Content: "#status"}},
+	}}
+	service := NewNoteImportService(nil, chunks, templates, storage, NewNoOpMonitor()).(*noteImportService)
+	return service, chunks, storage, templates
+}
+
+func TestNoteImport_Logseq(t *testing.T) {
+	service, chunks, storage, templates := newTestNoteImport()
+
+	result, err := service.Import(context.Background(), models.NoteImportLogseq, logseqGraph())
+	require.NoError(t, err)
+
+	assert.Len(t, result.PageIDs, 4, "backups in the logseq folder are not pages")
+	assert.Equal(t, 7, result.Chunks)
+	assert.Equal(t, 1, result.Assets)
+	assert.Equal(t, 1, result.TemplateInstances)
+	assert.Equal(t, 0, result.UnresolvedLinks)
+	assert.Empty(t, result.Warnings)
+
+	project := chunks.byContents("Project Apollo")
+	require.NotNil(t, project)
+	assert.True(t, project.IsPage)
+	assert.Equal(t, "instance-1", project.Metadata["template_instance_id"])
+	assert.Equal(t, map[string]string{"type": "project"}, project.Metadata["properties"], "slot values leave the metadata")
+	require.Len(t, templates.instances, 1)
+	assert.Equal(t, map[string]string{"status": "active"}, templates.instances[0].SlotValues)
+
+	alice := chunks.byContents("Alice")
+	planning := chunks.byContents("planning")
+	kickoff := chunks.byContents("Kickoff with [[Alice]] #planning")
+	require.NotNil(t, kickoff)
+	assert.Equal(t, project.ChunkID, *kickoff.Parent)
+	require.NotNil(t, kickoff.Ref)
+	assert.Equal(t, alice.ChunkID+", "+planning.ChunkID, *kickoff.Ref)
+
+	followUp := chunks.byContents(fmt.Sprintf("Followed up on ((%s))", kickoff.ChunkID))
+	require.NotNil(t, followUp, "block references point at the new chunk")
+	assert.Equal(t, kickoff.ChunkID, *followUp.Ref)
+	assert.Equal(t, "Jan 2nd, 2024", chunks.created[0].Contents, "pages are ordered by path")
+
+	require.Len(t, storage.objects, 1)
+	for key := range storage.objects {
+		assert.True(t, strings.HasPrefix(key, noteImportAssetPrefix+"/"))
+		assert.True(t, strings.HasSuffix(key, ".png"))
+		embed := chunks.byContents("![diagram](https://files.example.com/" + key + ")")
+		require.NotNil(t, embed)
+		assert.Equal(t, []string{key}, embed.Metadata["assets"])
+	}
+}
+
+func TestNoteImport_Notion(t *testing.T) {
+	service, chunks, storage, templates := newTestNoteImport()
+	home := "Home 11111111111111111111111111111111"
+	fsys := fstest.MapFS{
+		home + ".md": {Data: []byte("# Home\n\nSee [Roadmap](Home%2011111111111111111111111111111111/Roadmap%2022222222222222222222222222222222.md) and [Tasks](Home%2011111111111111111111111111111111/Tasks%2033333333333333333333333333333333.csv)\n\n![Logo](Home%2011111111111111111111111111111111/logo.svg)\n![Broken](Home%2011111111111111111111111111111111/broken.png)\n")},
+		home + "/Roadmap 22222222222222222222222222222222.md":                                     {Data: []byte("# Roadmap\n\n- Back to [Home](../Home%2011111111111111111111111111111111.md)\n")},
+		home + "/Tasks 33333333333333333333333333333333.csv":                                      {Data: []byte("\ufeffName,Status\nShip,Done\n")},
+		home + "/Tasks 33333333333333333333333333333333/Ship 44444444444444444444444444444444.md": {Data: []byte("# Ship\n\nStatus: Done\n\nShip it.\n")},
+		home + "/logo.svg":   {Data: []byte("<svg/>")},
+		home + "/broken.png": {Data: []byte("broken")},
+	}
+
+	result, err := service.Import(context.Background(), models.NoteImportNotion, fsys)
+	require.NoError(t, err)
+
+	assert.Len(t, result.PageIDs, 3)
+	assert.Equal(t, 1, result.Assets)
+	require.Len(t, result.Warnings, 1)
+	assert.Contains(t, result.Warnings[0], "broken.png")
+	assert.Equal(t, 0, result.TemplateInstances, "Tasks has no template, so properties stay in metadata")
+	assert.Empty(t, templates.instances)
+
+	roadmap := chunks.byContents("Roadmap")
+	homePage := chunks.byContents("Home")
+	require.NotNil(t, roadmap)
+	require.NotNil(t, homePage)
+
+	see := chunks.byContents("See [[Roadmap]] and [Tasks](Home%2011111111111111111111111111111111/Tasks%2033333333333333333333333333333333.csv)")
+	require.NotNil(t, see, "page links become [[Title]] and database links are kept")
+	assert.Equal(t, roadmap.ChunkID, *see.Ref)
+
+	require.Len(t, storage.objects, 1)
+	for key := range storage.objects {
+		images := chunks.byContents("![Logo](https://files.example.com/" + key + ")\n![Broken](Home%2011111111111111111111111111111111/broken.png)")
+		require.NotNil(t, images, "files that failed to upload keep their link")
+		assert.Equal(t, []string{key}, images.Metadata["assets"])
+	}
+
+	back := chunks.byContents("Back to [[Home]]")
+	require.NotNil(t, back)
+	assert.Equal(t, homePage.ChunkID, *back.Ref)
+
+	ship := chunks.byContents("Ship")
+	require.NotNil(t, ship)
+	assert.Equal(t, map[string]string{"Status": "Done"}, ship.Metadata["properties"])
+}
+
+func TestNoteImport_WithoutStorage(t *testing.T) {
+	chunks := &importChunks{}
+	service := NewNoteImportService(nil, chunks, nil, nil, NewNoOpMonitor())
+
+	result, err := service.Import(context.Background(), models.NoteImportLogseq, logseqGraph())
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Assets)
+	require.Len(t, result.Warnings, 1)
+	assert.NotNil(t, chunks.byContents("![diagram](../assets/diagram.png)"))
+	assert.Equal(t, map[string]string{"type": "project", "status": "active"}, chunks.byContents("Project Apollo").Metadata["properties"])
+}
+
+func TestNoteImport_Errors(t *testing.T) {
+	service, chunks, _, _ := newTestNoteImport()
+
+	_, err := ParseNoteImportFormat("evernote")
+	assert.ErrorIs(t, err, ErrInvalidNoteImport)
+
+	_, err = service.Import(context.Background(), models.NoteImportLogseq, fstest.MapFS{"readme.md": {Data: []byte("hi")}})
+	assert.ErrorIs(t, err, ErrInvalidNoteImport)
+
+	_, err = service.Import(context.Background(), models.NoteImportNotion, fstest.MapFS{"data.csv": {Data: []byte("a,b")}})
+	assert.ErrorIs(t, err, ErrInvalidNoteImport)
+
+	_, err = service.Import(contextWithRole(models.RoleReader), models.NoteImportLogseq, logseqGraph())
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Empty(t, chunks.created)
+}
+
+func TestNoteImport_Upsert(t *testing.T) {
+	service, chunks, _, templates := newTestNoteImport()
+	service.mappings = memoryImportMappings{}
+	ctx := context.Background()
+	upsert := models.NoteImportOptions{Mode: models.NoteImportUpsert}
+
+	result, err := service.ImportWithOptions(ctx, models.NoteImportLogseq, logseqGraph(), upsert)
+	require.NoError(t, err)
+	assert.Equal(t, "logseq", result.Source)
+	require.NotNil(t, result.Diff)
+	assert.Equal(t, 11, result.Diff.Created)
+	assert.Equal(t, 7, result.Chunks)
+
+	// Importing the same export again changes nothing
+	again, err := service.ImportWithOptions(ctx, models.NoteImportLogseq, logseqGraph(), upsert)
+	require.NoError(t, err)
+	assert.Equal(t, result.PageIDs, again.PageIDs)
+	assert.Equal(t, models.NoteImportDiff{Unchanged: 11, Conflicts: []models.NoteImportConflict{}}, *again.Diff)
+	assert.Len(t, chunks.created, 11)
+	assert.Len(t, templates.instances, 1)
+
+	// Alice's block is edited in the export, the journal removed, and planning edited on
+	// both sides
+	engineer := chunks.storedByContents("Engineer")
+	plans := chunks.storedByContents("Plans")
+	require.NotNil(t, engineer)
+	require.NotNil(t, plans)
+	plans.Version++
+	plans.Metadata["reviewed"] = true
+	graph := logseqGraph()
+	graph["graph/pages/Alice.md"] = &fstest.MapFile{Data: []byte("alias:: Al\n\n- Staff engineer\n")}
+	graph["graph/pages/planning.md"] = &fstest.MapFile{Data: []byte("- Plans for Q2\n")}
+	delete(graph, "graph/journals/2024_01_02.md")
+
+	changed, err := service.ImportWithOptions(ctx, models.NoteImportLogseq, graph, upsert)
+	require.NoError(t, err)
+	diff := changed.Diff
+	assert.Equal(t, 1, diff.Updated)
+	assert.Equal(t, 2, diff.Missing, "removed pages and blocks are kept unless asked")
+	assert.Equal(t, 1, diff.Conflicted)
+	assert.Equal(t, 7, diff.Unchanged)
+	assert.Equal(t, []models.NoteImportConflict{{ExternalID: "page:pages/planning.md#0", ChunkID: plans.ChunkID, Change: "update"}}, diff.Conflicts)
+	assert.Equal(t, "Staff engineer", chunks.stored[engineer.ChunkID].Contents, "updates keep the chunk ID")
+	assert.Equal(t, "Plans", chunks.stored[plans.ChunkID].Contents, "local edits are not overwritten")
+
+	// The project's status moves on, which updates its template instance
+	graph["graph/pages/Project Apollo.md"] = &fstest.MapFile{Data: []byte(strings.Replace(logseqProjectPage, "status:: active", "status:: done", 1))}
+	upsert.DeleteMissing = true
+	upsert.Overwrite = true
+	merged, err := service.ImportWithOptions(ctx, models.NoteImportLogseq, graph, upsert)
+	require.NoError(t, err)
+	assert.Equal(t, 2, merged.Diff.Updated)
+	assert.Equal(t, 2, merged.Diff.Deleted)
+	assert.Equal(t, 7, merged.Diff.Unchanged)
+	assert.Equal(t, []string{"instance-1/status=done"}, templates.updatedSlots)
+	assert.Len(t, templates.instances, 1)
+	assert.Equal(t, "instance-1", chunks.storedByContents("Project Apollo").Metadata["template_instance_id"])
+	assert.Len(t, chunks.deleted, 2)
+	assert.Equal(t, "Plans for Q2", chunks.stored[plans.ChunkID].Contents)
+	assert.Equal(t, true, chunks.stored[plans.ChunkID].Metadata["reviewed"], "metadata the import does not manage is kept")
+	assert.Len(t, chunks.created, 11)
+}
+
+func TestNoteImport_UpsertErrors(t *testing.T) {
+	service, _, _, _ := newTestNoteImport()
+
+	_, err := ParseNoteImportMode("replace")
+	assert.ErrorIs(t, err, ErrInvalidNoteImport)
+
+	_, err = service.ImportWithOptions(context.Background(), models.NoteImportLogseq, logseqGraph(), models.NoteImportOptions{Mode: models.NoteImportUpsert})
+	assert.ErrorIs(t, err, ErrInvalidNoteImport, "upserts need the mappings of earlier imports")
+
+	_, err = service.ImportWithOptions(context.Background(), models.NoteImportLogseq, logseqGraph(), models.NoteImportOptions{DeleteMissing: true})
+	assert.ErrorIs(t, err, ErrInvalidNoteImport)
+}
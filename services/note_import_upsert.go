@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// noteImportMetadataKeys are the metadata keys imports write. Updating a chunk replaces
+// them and keeps any other keys added since the import.
+var noteImportMetadataKeys = map[string]bool{
+	"import_source":        true,
+	"import_path":          true,
+	"journal_date":         true,
+	"template_instance_id": true,
+	"properties":           true,
+	"assets":               true,
+}
+
+// ParseNoteImportMode validates an import mode name; empty means create
+func ParseNoteImportMode(name string) (models.NoteImportMode, error) {
+	switch mode := models.NoteImportMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "", models.NoteImportCreate:
+		return models.NoteImportCreate, nil
+	case models.NoteImportUpsert:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported mode %q: use create or upsert", ErrInvalidNoteImport, name)
+	}
+}
+
+// normalizeNoteImportOptions validates opts and fills in the default mode and source
+func normalizeNoteImportOptions(format models.NoteImportFormat, opts models.NoteImportOptions) (models.NoteImportOptions, error) {
+	mode, err := ParseNoteImportMode(string(opts.Mode))
+	if err != nil {
+		return opts, err
+	}
+	opts.Mode = mode
+	opts.Source = strings.TrimSpace(opts.Source)
+	if opts.Source == "" {
+		opts.Source = string(format)
+	}
+	if opts.Mode == models.NoteImportCreate && (opts.DeleteMissing || opts.Overwrite) {
+		return opts, fmt.Errorf("%w: delete_missing and overwrite need mode upsert", ErrInvalidNoteImport)
+	}
+	return opts, nil
+}
+
+// assignImportExternalIDs names every page and block the same way in each export of a
+// source. Blocks with an ID of their own use it; the others are named by their position on
+// the page, so inserting a block renames the siblings after it.
+func assignImportExternalIDs(pages []*importedPage) {
+	var walk func(blocks []*importedBlock, prefix string)
+	walk = func(blocks []*importedBlock, prefix string) {
+		for i, block := range blocks {
+			position := prefix + strconv.Itoa(i)
+			block.externalID = position
+			if block.sourceID != "" {
+				block.externalID = "block:" + block.sourceID
+			}
+			walk(block.children, position+".")
+		}
+	}
+	for _, page := range pages {
+		if page.externalID == "" {
+			page.externalID = page.path
+		}
+		page.externalID = "page:" + page.externalID
+		walk(page.blocks, page.externalID+"#")
+	}
+}
+
+// importedPageHash hashes what an import writes to a page chunk, before templates are
+// filled, so an unchanged page neither updates its chunk nor its template instance
+func importedPageHash(page *importedPage) string {
+	return importHash(struct {
+		Title      string                 `json:"title"`
+		Path       string                 `json:"path"`
+		Template   string                 `json:"template"`
+		Properties map[string]string      `json:"properties"`
+		Metadata   map[string]interface{} `json:"metadata"`
+	}{page.title, page.path, page.template, page.properties, page.metadata})
+}
+
+// importedBlockHash hashes what an import writes to a block chunk
+func importedBlockHash(record *models.UnifiedChunkRecord) string {
+	return importHash(struct {
+		Contents string                 `json:"contents"`
+		Parent   *string                `json:"parent"`
+		Page     *string                `json:"page"`
+		Ref      *string                `json:"ref"`
+		Metadata map[string]interface{} `json:"metadata"`
+	}{record.Contents, record.Parent, record.Page, record.Ref, record.Metadata})
+}
+
+func importHash(value interface{}) string {
+	// Maps marshal with sorted keys, so equal content always hashes the same
+	data, _ := json.Marshal(value)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// importMapping is the chunk an external ID was last imported into
+type importMapping struct {
+	chunkID string
+	hash    string
+	// version is the chunk version the import wrote; a newer one means a local edit
+	version int64
+}
+
+// importMappingStore keeps the chunks each source's external IDs were imported into
+type importMappingStore interface {
+	load(ctx context.Context, source string) (map[string]importMapping, error)
+	save(ctx context.Context, source string, mappings map[string]importMapping) error
+}
+
+// sqlImportMappings stores mappings in import_external_ids
+type sqlImportMappings struct {
+	db *sql.DB
+}
+
+func (m sqlImportMappings) load(ctx context.Context, source string) (map[string]importMapping, error) {
+	rows, err := m.db.QueryContext(ctx, `
+		SELECT external_id, chunk_id, content_hash, chunk_version
+		FROM import_external_ids WHERE source = $1`, source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load imported external IDs: %w", err)
+	}
+	defer rows.Close()
+
+	mappings := make(map[string]importMapping)
+	for rows.Next() {
+		var externalID string
+		var mapping importMapping
+		if err := rows.Scan(&externalID, &mapping.chunkID, &mapping.hash, &mapping.version); err != nil {
+			return nil, fmt.Errorf("failed to scan imported external ID: %w", err)
+		}
+		mappings[externalID] = mapping
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating imported external IDs: %w", err)
+	}
+	return mappings, nil
+}
+
+func (m sqlImportMappings) save(ctx context.Context, source string, mappings map[string]importMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	externalIDs := make([]string, 0, len(mappings))
+	chunkIDs := make([]string, 0, len(mappings))
+	hashes := make([]string, 0, len(mappings))
+	versions := make([]int64, 0, len(mappings))
+	for externalID, mapping := range mappings {
+		externalIDs = append(externalIDs, externalID)
+		chunkIDs = append(chunkIDs, mapping.chunkID)
+		hashes = append(hashes, mapping.hash)
+		versions = append(versions, mapping.version)
+	}
+
+	if _, err := m.db.ExecContext(ctx, `
+		INSERT INTO import_external_ids (source, external_id, chunk_id, content_hash, chunk_version)
+		SELECT $1, t.external_id, t.chunk_id::uuid, t.content_hash, t.chunk_version
+		FROM unnest($2::text[], $3::text[], $4::text[], $5::bigint[])
+			AS t(external_id, chunk_id, content_hash, chunk_version)
+		ON CONFLICT (source, external_id) DO UPDATE SET
+			chunk_id = EXCLUDED.chunk_id,
+			content_hash = EXCLUDED.content_hash,
+			chunk_version = EXCLUDED.chunk_version,
+			imported_at = NOW()`,
+		source, pq.Array(externalIDs), pq.Array(chunkIDs), pq.Array(hashes), pq.Array(versions)); err != nil {
+		return fmt.Errorf("failed to record imported external IDs: %w", err)
+	}
+	return nil
+}
+
+// importChange is what an import does with one page or block
+type importChange int
+
+const (
+	importCreate importChange = iota
+	importUpdate
+	importUnchanged
+	importConflict
+)
+
+// importPlan sorts the pages and blocks of an export into chunks to create, update and
+// delete, against the chunks earlier imports of the source created
+type importPlan struct {
+	opts models.NoteImportOptions
+	// existing are the mappings of earlier imports, and current their chunks as stored now
+	existing map[string]importMapping
+	current  map[string]*models.UnifiedChunkRecord
+	seen     map[string]bool
+
+	creates []models.UnifiedChunkRecord
+	updates []models.UnifiedChunkRecord
+	deletes []string
+	// saved are the mappings to record once the chunks are written
+	saved map[string]importMapping
+	diff  models.NoteImportDiff
+}
+
+// loadImportPlan reads what earlier imports of the source created. Create imports start
+// from nothing.
+func (s *noteImportService) loadImportPlan(ctx context.Context, opts models.NoteImportOptions) (*importPlan, error) {
+	plan := &importPlan{
+		opts:     opts,
+		existing: map[string]importMapping{},
+		current:  map[string]*models.UnifiedChunkRecord{},
+		seen:     map[string]bool{},
+		saved:    map[string]importMapping{},
+		diff:     models.NoteImportDiff{Conflicts: []models.NoteImportConflict{}},
+	}
+	if opts.Mode != models.NoteImportUpsert {
+		return plan, nil
+	}
+	if s.mappings == nil {
+		return nil, fmt.Errorf("%w: upsert imports need a database", ErrInvalidNoteImport)
+	}
+
+	existing, err := s.mappings.load(ctx, opts.Source)
+	if err != nil {
+		return nil, err
+	}
+	plan.existing = existing
+	if len(existing) == 0 {
+		return plan, nil
+	}
+
+	chunkIDs := make([]string, 0, len(existing))
+	for _, mapping := range existing {
+		chunkIDs = append(chunkIDs, mapping.chunkID)
+	}
+	current, err := s.chunks.BatchGetChunks(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load imported chunks: %w", err)
+	}
+	plan.current = current
+	return plan, nil
+}
+
+// chunkID returns the chunk an external ID was imported into, or a new ID
+func (p *importPlan) chunkID(externalID string) string {
+	p.seen[externalID] = true
+	if mapping, ok := p.existing[externalID]; ok && p.current[mapping.chunkID] != nil {
+		return mapping.chunkID
+	}
+	return uuid.New().String()
+}
+
+// classify decides what to do with the page or block of an external ID whose content
+// hashes to hash
+func (p *importPlan) classify(externalID, hash string) importChange {
+	mapping, ok := p.existing[externalID]
+	if !ok || p.current[mapping.chunkID] == nil {
+		return importCreate
+	}
+	if mapping.hash == hash {
+		return importUnchanged
+	}
+	if p.current[mapping.chunkID].Version != mapping.version && !p.opts.Overwrite {
+		return importConflict
+	}
+	return importUpdate
+}
+
+// templateInstance returns the template instance an earlier import filled for a page
+func (p *importPlan) templateInstance(chunkID string) string {
+	if current := p.current[chunkID]; current != nil {
+		instanceID, _ := current.Metadata["template_instance_id"].(string)
+		return instanceID
+	}
+	return ""
+}
+
+// stage records the change of one page or block. Only created and updated chunks need
+// their record.
+func (p *importPlan) stage(change importChange, externalID, hash string, record models.UnifiedChunkRecord) {
+	switch change {
+	case importCreate:
+		p.creates = append(p.creates, record)
+		p.saved[externalID] = importMapping{chunkID: record.ChunkID, hash: hash, version: 1}
+		p.diff.Created++
+	case importUpdate:
+		current := p.current[record.ChunkID]
+		updated := mergeImportedChunk(current, &record)
+		p.updates = append(p.updates, updated)
+		// The update only applies to the version read, so it writes the next one
+		p.saved[externalID] = importMapping{chunkID: record.ChunkID, hash: hash, version: current.Version + 1}
+		p.diff.Updated++
+	case importUnchanged:
+		p.diff.Unchanged++
+	case importConflict:
+		p.conflict(externalID, record.ChunkID, "update")
+	}
+}
+
+func (p *importPlan) conflict(externalID, chunkID, change string) {
+	p.diff.Conflicted++
+	if len(p.diff.Conflicts) < maxNoteImportWarnings {
+		p.diff.Conflicts = append(p.diff.Conflicts, models.NoteImportConflict{ExternalID: externalID, ChunkID: chunkID, Change: change})
+	}
+}
+
+// planMissing handles the chunks of earlier imports that are no longer in the export
+func (p *importPlan) planMissing() {
+	var missing []string
+	for externalID := range p.existing {
+		if !p.seen[externalID] {
+			missing = append(missing, externalID)
+		}
+	}
+	sort.Strings(missing)
+
+	for _, externalID := range missing {
+		mapping := p.existing[externalID]
+		current := p.current[mapping.chunkID]
+		switch {
+		case current == nil:
+			// Deleted since the import
+		case !p.opts.DeleteMissing:
+			p.diff.Missing++
+		case current.Version != mapping.version && !p.opts.Overwrite:
+			p.conflict(externalID, mapping.chunkID, "delete")
+		default:
+			p.deletes = append(p.deletes, mapping.chunkID)
+			p.diff.Deleted++
+		}
+	}
+}
+
+// mergeImportedChunk applies an imported record to the stored chunk, keeping its tags,
+// flags and the metadata added since the import
+func mergeImportedChunk(current, imported *models.UnifiedChunkRecord) models.UnifiedChunkRecord {
+	merged := *current
+	merged.Contents = imported.Contents
+	merged.Parent = imported.Parent
+	merged.Page = imported.Page
+	merged.Ref = imported.Ref
+	merged.IsPage = imported.IsPage
+	merged.Metadata = make(map[string]interface{}, len(current.Metadata)+len(imported.Metadata))
+	for key, value := range current.Metadata {
+		if !noteImportMetadataKeys[key] {
+			merged.Metadata[key] = value
+		}
+	}
+	for key, value := range imported.Metadata {
+		merged.Metadata[key] = value
+	}
+	return merged
+}
+
+// applyImportPlan writes the planned chunks, parents before children, and records their
+// external IDs. Create imports that cannot record them still succeed with a warning.
+func (s *noteImportService) applyImportPlan(ctx context.Context, plan *importPlan, result *models.NoteImportResult) error {
+	upsert := plan.opts.Mode == models.NoteImportUpsert
+	if upsert {
+		plan.planMissing()
+	}
+
+	if err := s.chunks.BatchCreateChunks(ctx, plan.creates); err != nil {
+		return fmt.Errorf("failed to create imported chunks: %w", err)
+	}
+	if len(plan.updates) > 0 {
+		if err := s.chunks.BatchUpdateChunks(ctx, plan.updates); err != nil {
+			return fmt.Errorf("failed to update imported chunks: %w", err)
+		}
+	}
+	for _, chunkID := range plan.deletes {
+		if err := s.chunks.DeleteChunk(ctx, chunkID); err != nil {
+			return fmt.Errorf("failed to delete chunk %s removed from the export: %w", chunkID, err)
+		}
+	}
+
+	if upsert {
+		result.Diff = &plan.diff
+	}
+	if s.mappings == nil {
+		return nil
+	}
+	if err := s.mappings.save(ctx, plan.opts.Source, plan.saved); err != nil {
+		if upsert {
+			return err
+		}
+		addImportWarning(result, fmt.Sprintf("the import cannot be merged by a later upsert: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// StorageService 物件儲存服務介面，供媒體處理與匯出功能存放二進位檔案
+type StorageService interface {
+	// Put 上傳物件，未指定 Content-Type 時自動偵測
+	Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error)
+	// Get 下載物件，呼叫者負責關閉回傳的 ReadCloser
+	Get(ctx context.Context, key string) (io.ReadCloser, *models.StorageObject, error)
+	// SignedURL 產生有時效的下載 URL
+	SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// Delete 刪除物件
+	Delete(ctx context.Context, key string) error
+	// List 列出指定前綴下的物件
+	List(ctx context.Context, prefix string, limit int) ([]models.StorageObject, error)
+	// Backend 回傳儲存後端類型
+	Backend() models.StorageType
+}
+
+const (
+	// defaultMaxObjectSize 未設定大小限制時的預設上限（50MB）
+	defaultMaxObjectSize int64 = 50 * 1024 * 1024
+	// defaultListLimit List 未指定數量時的預設上限
+	defaultListLimit = 1000
+	// contentSniffLength http.DetectContentType 所需的最大位元組數
+	contentSniffLength = 512
+)
+
+// NewStorageServiceFromConfig 依照設定建立物件儲存服務
+func NewStorageServiceFromConfig(cfg *config.StorageConfig) (StorageService, error) {
+	switch models.StorageType(cfg.Provider) {
+	case models.StorageTypeSupabase:
+		return NewSupabaseObjectStorage(cfg.Supabase, cfg.MaxObjectSize)
+	case models.StorageTypeS3:
+		return NewS3ObjectStorage(cfg.S3, cfg.MaxObjectSize)
+	default:
+		return nil, fmt.Errorf("%w: storage provider %q has no object storage backend", ErrInvalidStorageConfig, cfg.Provider)
+	}
+}
+
+// DetectContentType 偵測物件的 Content-Type，優先使用副檔名，無法判斷時分析檔案開頭內容
+func DetectContentType(key string, head []byte) string {
+	if ext := strings.ToLower(path.Ext(key)); ext != "" {
+		if models.IsImageFile(key) {
+			return models.GetImageContentType(key)
+		}
+		if contentType := mime.TypeByExtension(ext); contentType != "" {
+			return contentType
+		}
+	}
+
+	if len(head) == 0 {
+		return "application/octet-stream"
+	}
+	return http.DetectContentType(head)
+}
+
+// validateObjectKey 檢查物件鍵值是否合法
+func validateObjectKey(key string) error {
+	if key == "" {
+		return fmt.Errorf("object key is required")
+	}
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("object key must not start with '/': %s", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid object key: %s", key)
+		}
+	}
+	for _, r := range key {
+		if r < 0x20 || r == 0x7f {
+			return fmt.Errorf("object key contains control characters: %q", key)
+		}
+	}
+	return nil
+}
+
+// readObjectBody 讀取上傳內容並套用大小限制，回傳內容與 Content-Type
+func readObjectBody(key string, r io.Reader, opts *models.PutObjectOptions, maxSize int64) ([]byte, string, error) {
+	if maxSize <= 0 {
+		maxSize = defaultMaxObjectSize
+	}
+	if opts != nil && opts.Size > maxSize {
+		return nil, "", fmt.Errorf("%w: %d bytes exceeds limit of %d bytes", ErrObjectTooLarge, opts.Size, maxSize)
+	}
+
+	// 多讀一個位元組以判斷是否超過上限
+	var buf bytes.Buffer
+	n, err := io.Copy(&buf, io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read object body: %w", err)
+	}
+	if n > maxSize {
+		return nil, "", fmt.Errorf("%w: exceeds limit of %d bytes", ErrObjectTooLarge, maxSize)
+	}
+
+	data := buf.Bytes()
+	contentType := ""
+	if opts != nil {
+		contentType = opts.ContentType
+	}
+	if contentType == "" {
+		head := data
+		if len(head) > contentSniffLength {
+			head = head[:contentSniffLength]
+		}
+		contentType = DetectContentType(key, head)
+	}
+
+	return data, contentType, nil
+}
+
+// objectFromResponse 從 HTTP 回應標頭建立物件資訊
+func objectFromResponse(key string, storageType models.StorageType, resp *http.Response) *models.StorageObject {
+	object := &models.StorageObject{
+		Key:         key,
+		Size:        resp.ContentLength,
+		ContentType: resp.Header.Get("Content-Type"),
+		ETag:        strings.Trim(resp.Header.Get("ETag"), `"`),
+		StorageType: storageType,
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		object.LastModified = lastModified
+	}
+	return object
+}
+
+// readStorageError 讀取錯誤回應內容（截斷過長內容）
+func readStorageError(resp *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return strings.TrimSpace(string(body))
+}
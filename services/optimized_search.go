@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// defaultOptimizedSearchLimit matches the semantic search default
+const defaultOptimizedSearchLimit = 10
+
+// OptimizedSearchService runs semantic search through the PostgreSQL search cache
+type OptimizedSearchService struct {
+	search      SearchService
+	searchCache SearchCacheService
+	facets      SearchFacetSource
+	queryLog    SearchQueryRecorder
+	access      SearchAccessFilter
+	reranker    *RerankService
+	vectors     SearchVectorSource
+	ttl         atomic.Int64 // time.Duration
+}
+
+// SearchAccessFilter drops the results the caller may not read
+type SearchAccessFilter interface {
+	FilterChunkIDs(ctx context.Context, chunkIDs []string) ([]string, error)
+}
+
+// NewOptimizedSearchService creates a cached semantic search service
+func NewOptimizedSearchService(search SearchService, searchCache SearchCacheService, ttl time.Duration) *OptimizedSearchService {
+	if searchCache == nil {
+		searchCache = &NoOpSearchCacheService{}
+	}
+	service := &OptimizedSearchService{
+		search:      search,
+		searchCache: searchCache,
+	}
+	service.SetTTL(ttl)
+	return service
+}
+
+// SetTTL updates how long newly cached search results stay fresh
+func (s *OptimizedSearchService) SetTTL(ttl time.Duration) {
+	s.ttl.Store(int64(ttl))
+}
+
+// SetFacetSource enables facet computation for requests that ask for facets
+func (s *OptimizedSearchService) SetFacetSource(source SearchFacetSource) {
+	s.facets = source
+}
+
+// SetQueryRecorder logs every searched query for autocomplete and suggestions
+func (s *OptimizedSearchService) SetQueryRecorder(recorder SearchQueryRecorder) {
+	s.queryLog = recorder
+}
+
+// SetAccessFilter filters results per caller. Results are cached unfiltered, so callers
+// share cache entries.
+func (s *OptimizedSearchService) SetAccessFilter(filter SearchAccessFilter) {
+	s.access = filter
+}
+
+// SetReranker enables re-ranking for requests that set Rerank
+func (s *OptimizedSearchService) SetReranker(reranker *RerankService) {
+	s.reranker = reranker
+}
+
+// SetVectorSource lets MMR compare results by their embeddings rather than their words
+func (s *OptimizedSearchService) SetVectorSource(source SearchVectorSource) {
+	s.vectors = source
+}
+
+// Search performs a semantic search, serving cached results when UseCache is set.
+// Stale cache entries are returned immediately and refreshed in the background.
+func (s *OptimizedSearchService) Search(ctx context.Context, req *models.OptimizedSearchRequest) (*models.OptimizedSearchResponse, error) {
+	start := time.Now()
+	if req.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if req.Limit <= 0 {
+		req.Limit = defaultOptimizedSearchLimit
+	}
+	if err := ValidateSearchFacets(req.Facets); err != nil {
+		return nil, err
+	}
+	if len(req.Facets) > 0 && s.facets == nil {
+		return nil, fmt.Errorf("search facets are not available")
+	}
+	rerankModel, err := s.resolveRerankModel(req)
+	if err != nil {
+		return nil, err
+	}
+	var mmrLambda float64
+	if req.MMR {
+		if mmrLambda, err = resolveMMRLambda(req); err != nil {
+			return nil, err
+		}
+	}
+
+	queryParams := map[string]interface{}{
+		"type":             "semantic",
+		"query":            req.Query,
+		"limit":            req.Limit,
+		"min_similarity":   req.MinSimilarity,
+		"filters":          req.Filters,
+		"include_metadata": req.IncludeMetadata,
+	}
+	// Re-ranked results are cached separately from plain ones, per scorer
+	if req.Rerank {
+		queryParams["rerank_model"] = rerankModel
+		queryParams["rerank_candidates"] = s.reranker.Candidates(req.Limit)
+	}
+	if req.MMR {
+		queryParams["mmr_lambda"] = mmrLambda
+	}
+
+	compute := func(ctx context.Context) ([]string, json.RawMessage, error) {
+		results, err := s.runSearch(ctx, req, rerankModel, mmrLambda)
+		if err != nil {
+			return nil, nil, err
+		}
+		chunkIDs := make([]string, len(results))
+		for i, result := range results {
+			chunkIDs[i] = result.ChunkID
+		}
+		payload, err := json.Marshal(results)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to serialize search results: %w", err)
+		}
+		return chunkIDs, payload, nil
+	}
+
+	var (
+		entry    *models.SearchCacheEntry
+		cacheHit bool
+	)
+	steps := []string{"normalize_query"}
+	if req.UseCache {
+		entry, cacheHit, err = s.searchCache.GetOrComputeSearch(ctx, queryParams, time.Duration(s.ttl.Load()), compute)
+		steps = append(steps, "search_cache_lookup")
+	} else {
+		entry = &models.SearchCacheEntry{SearchHash: SearchCacheKey(queryParams)}
+		entry.ChunkIDs, entry.Payload, err = compute(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to perform optimized search: %w", err)
+	}
+
+	var results []models.OptimizedSearchResult
+	if len(entry.Payload) > 0 {
+		if err := json.Unmarshal(entry.Payload, &results); err != nil {
+			return nil, fmt.Errorf("failed to deserialize cached search results: %w", err)
+		}
+	}
+	if results == nil {
+		results = []models.OptimizedSearchResult{}
+	}
+
+	optimizations := []string{}
+	if cacheHit {
+		optimizations = append(optimizations, "search_cache_hit")
+		if entry.Stale {
+			optimizations = append(optimizations, "stale_while_revalidate")
+		}
+	} else {
+		steps = append(steps, "semantic_search")
+		if req.Rerank {
+			steps = append(steps, "rerank")
+		}
+		if req.MMR {
+			steps = append(steps, "mmr")
+		}
+	}
+	if s.access != nil && len(results) > 0 {
+		if results, err = s.filterResults(ctx, results); err != nil {
+			return nil, err
+		}
+		steps = append(steps, "access_filter")
+	}
+
+	cacheOperations := 0
+	if req.UseCache {
+		cacheOperations = 1
+	}
+
+	if s.queryLog != nil {
+		s.recordQuery(req.Workspace, req.Query, len(results))
+	}
+
+	// Facets are counted over the results rather than cached with them, so they reflect
+	// tag and page changes made since the results were cached
+	var facets map[string][]models.FacetBucket
+	databaseQueries := 0
+	if len(req.Facets) > 0 {
+		chunkIDs := make([]string, len(results))
+		for i, result := range results {
+			chunkIDs[i] = result.ChunkID
+		}
+		attributes, err := s.facets.GetFacetAttributes(ctx, chunkIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute search facets: %w", err)
+		}
+		facets = computeSearchFacets(results, attributes, req.Facets, req.FacetLimit)
+		databaseQueries++
+		steps = append(steps, "facet_aggregation")
+	}
+
+	return &models.OptimizedSearchResponse{
+		Results:       results,
+		TotalCount:    len(results),
+		Duration:      time.Since(start),
+		CacheHit:      cacheHit,
+		Optimizations: optimizations,
+		Metadata: models.SearchMetadata{
+			QueryHash:       entry.SearchHash,
+			DatabaseQueries: databaseQueries,
+			CacheOperations: cacheOperations,
+			ProcessingSteps: steps,
+		},
+		Facets: facets,
+	}, nil
+}
+
+// filterResults keeps the results the access filter allows, in order
+func (s *OptimizedSearchService) filterResults(ctx context.Context, results []models.OptimizedSearchResult) ([]models.OptimizedSearchResult, error) {
+	chunkIDs := make([]string, len(results))
+	for i, result := range results {
+		chunkIDs[i] = result.ChunkID
+	}
+	allowed, err := s.access.FilterChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter search results: %w", err)
+	}
+	if len(allowed) == len(results) {
+		return results, nil
+	}
+
+	keep := make(map[string]bool, len(allowed))
+	for _, id := range allowed {
+		keep[id] = true
+	}
+	filtered := make([]models.OptimizedSearchResult, 0, len(allowed))
+	for _, result := range results {
+		if keep[result.ChunkID] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
+// recordQuery logs the query in the background so logging never slows the search down
+func (s *OptimizedSearchService) recordQuery(workspace, query string, resultCount int) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.queryLog.RecordQuery(ctx, workspace, query, resultCount); err != nil {
+			log.Printf("Warning: failed to log search query: %v", err)
+		}
+	}()
+}
+
+// resolveRerankModel returns the scorer a re-ranked request uses, or "" without re-ranking
+func (s *OptimizedSearchService) resolveRerankModel(req *models.OptimizedSearchRequest) (string, error) {
+	if !req.Rerank {
+		return "", nil
+	}
+	if s.reranker == nil {
+		return "", fmt.Errorf("%w: no scorer is configured", ErrRerankUnavailable)
+	}
+	return s.reranker.ResolveModel(req.RerankModel)
+}
+
+// runSearch executes the underlying semantic search and converts its results. With
+// re-ranking or MMR, more candidates are retrieved: re-ranking orders them by score and
+// MMR then picks a relevant but varied set of them.
+func (s *OptimizedSearchService) runSearch(ctx context.Context, req *models.OptimizedSearchRequest, rerankModel string, mmrLambda float64) ([]models.OptimizedSearchResult, error) {
+	limit := req.Limit
+	if req.Rerank {
+		limit = s.reranker.Candidates(req.Limit)
+	}
+	if req.MMR && limit < req.Limit*mmrCandidateFactor {
+		limit = req.Limit * mmrCandidateFactor
+	}
+	resp, err := s.search.SemanticSearchWithFilters(ctx, &models.SemanticSearchRequest{
+		Query:           req.Query,
+		Limit:           limit,
+		MinSimilarity:   req.MinSimilarity,
+		Filters:         req.Filters,
+		IncludeMetadata: req.IncludeMetadata,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.OptimizedSearchResult, 0, len(resp.Results))
+	for _, result := range resp.Results {
+		optimized := models.OptimizedSearchResult{
+			ChunkID:    result.Chunk.ID,
+			Content:    result.Chunk.Content,
+			Similarity: result.Similarity,
+			Relevance:  result.Similarity,
+		}
+		if req.IncludeMetadata {
+			optimized.Metadata = result.Chunk.Metadata
+		}
+		results = append(results, optimized)
+	}
+
+	if req.Rerank {
+		if results, err = s.reranker.Rerank(ctx, rerankModel, req.Query, results); err != nil {
+			return nil, err
+		}
+	}
+	if req.MMR {
+		var vectors map[string][]float64
+		if s.vectors != nil && len(results) > 1 {
+			chunkIDs := make([]string, len(results))
+			for i, result := range results {
+				chunkIDs[i] = result.ChunkID
+			}
+			if vectors, err = s.vectors.GetChunkVectors(ctx, chunkIDs); err != nil {
+				return nil, fmt.Errorf("failed to load result embeddings: %w", err)
+			}
+		}
+		results = diversifyMMR(results, vectors, mmrLambda, req.Limit)
+	}
+	if len(results) > req.Limit {
+		results = results[:req.Limit]
+	}
+	return results, nil
+}
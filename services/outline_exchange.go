@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// OUTLINE EXCHANGE
+// ============================================================================
+//
+// Pages travel to and from other outliners as OPML, the format Workflowy and Dynalist
+// export. Each outline item is a chunk: the first line of its contents is the item text
+// and the remaining lines are its note. Siblings keep their order through creation times.
+// Inline #tags in item text become tag chunks on import and are written back after the
+// text on export.
+
+// ErrInvalidOPML is returned for documents that are not OPML or exceed the import limits
+var ErrInvalidOPML = errors.New("invalid OPML document")
+
+// maxOutlineImportItems caps the items of one import, which is created in one transaction
+const maxOutlineImportItems = 10000
+
+// defaultOutlineTitle names imported pages when neither the request nor the document does
+const defaultOutlineTitle = "Imported outline"
+
+// completedMetadataKey marks chunks of items checked off in the source outliner
+const completedMetadataKey = "completed"
+
+// inlineTagPattern matches #tags that start the text or follow whitespace
+var inlineTagPattern = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_](?:[\p{L}\p{N}_/-]*[\p{L}\p{N}_])?)`)
+
+// inlineTagName matches tag names that can be written inline
+var inlineTagName = regexp.MustCompile(`^[\p{L}\p{N}_](?:[\p{L}\p{N}_/-]*[\p{L}\p{N}_])?$`)
+
+// OutlineExchangeService converts pages to and from the formats of other outliners
+type OutlineExchangeService interface {
+	// ExportOPML writes the page and its blocks to w as an OPML document
+	ExportOPML(ctx context.Context, w io.Writer, pageID string) (*models.OutlineExportStats, error)
+	// ImportOPML creates the outline read from r as a new page or under opts.ParentID
+	ImportOPML(ctx context.Context, r io.Reader, opts models.OutlineImportOptions) (*models.OutlineImportResult, error)
+}
+
+// outlineExchangeService implements OutlineExchangeService on top of a chunk service
+type outlineExchangeService struct {
+	db      *sql.DB
+	chunks  UnifiedChunkService
+	monitor QueryPerformanceMonitor
+}
+
+// NewOutlineExchangeService creates an outline exchange service that writes through
+// chunks and looks up tags by name in db
+func NewOutlineExchangeService(db *sql.DB, chunks UnifiedChunkService, monitor QueryPerformanceMonitor) OutlineExchangeService {
+	return &outlineExchangeService{db: db, chunks: chunks, monitor: monitor}
+}
+
+// opmlDocument is the part of OPML 2.0 that outliners exchange
+type opmlDocument struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// opmlOutline is one item. _note and _complete are the Workflowy and Dynalist extensions
+// for notes and checked-off items.
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Note     string        `xml:"_note,attr,omitempty"`
+	Complete string        `xml:"_complete,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// outlineNode is an item of an imported outline
+type outlineNode struct {
+	text     string
+	note     string
+	complete bool
+	children []outlineNode
+}
+
+// ExportOPML writes the page's blocks as nested outlines in sibling order
+func (s *outlineExchangeService) ExportOPML(ctx context.Context, w io.Writer, pageID string) (*models.OutlineExportStats, error) {
+	start := time.Now()
+	stats := &models.OutlineExportStats{}
+	defer func() {
+		s.monitor.RecordQuery("outline_export_opml", time.Since(start), stats.Chunks)
+	}()
+
+	page, err := s.chunks.GetChunk(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page: %w", err)
+	}
+	if !page.IsPage {
+		return nil, fmt.Errorf("chunk %s is not a page", pageID)
+	}
+	descendants, err := s.chunks.GetDescendants(ctx, pageID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+
+	tagNames, err := s.tagNames(ctx, descendants)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := buildOPMLDocument(page, descendants, tagNames)
+	doc.Head.DateCreated = time.Now().UTC().Format(time.RFC1123Z)
+	if err := encodeOPML(w, doc); err != nil {
+		return nil, err
+	}
+
+	stats.Title = doc.Head.Title
+	stats.Chunks = len(descendants)
+	return stats, nil
+}
+
+// tagNames loads the names of the tags on chunks. Tags that no longer exist are left out.
+func (s *outlineExchangeService) tagNames(ctx context.Context, chunks []models.UnifiedChunkRecord) (map[string]string, error) {
+	names := make(map[string]string)
+	for _, chunk := range chunks {
+		for _, tagID := range chunk.Tags {
+			if _, ok := names[tagID]; ok {
+				continue
+			}
+			tag, err := s.chunks.GetChunk(ctx, tagID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, ctx.Err()
+				}
+				names[tagID] = ""
+				continue
+			}
+			names[tagID] = tag.Contents
+		}
+	}
+	return names, nil
+}
+
+// buildOPMLDocument nests descendants under the page. Children keep the order of
+// descendants, which lists siblings by creation time.
+func buildOPMLDocument(page *models.UnifiedChunkRecord, descendants []models.UnifiedChunkRecord, tagNames map[string]string) *opmlDocument {
+	children := make(map[string][]*models.UnifiedChunkRecord)
+	for i := range descendants {
+		parent := stringValue(descendants[i].Parent)
+		children[parent] = append(children[parent], &descendants[i])
+	}
+
+	var outlines func(parentID string) []opmlOutline
+	outlines = func(parentID string) []opmlOutline {
+		var items []opmlOutline
+		for _, chunk := range children[parentID] {
+			item := opmlOutlineFor(chunk, tagNames)
+			item.Outlines = outlines(chunk.ChunkID)
+			items = append(items, item)
+		}
+		return items
+	}
+
+	title, _, _ := strings.Cut(page.Contents, "\n")
+	return &opmlDocument{
+		Version: "2.0",
+		Head:    opmlHead{Title: title},
+		Body:    opmlBody{Outlines: outlines(page.ChunkID)},
+	}
+}
+
+// opmlOutlineFor writes a chunk as an outline item without its children. Tags whose
+// names cannot be written inline are left out.
+func opmlOutlineFor(chunk *models.UnifiedChunkRecord, tagNames map[string]string) opmlOutline {
+	text, note, _ := strings.Cut(chunk.Contents, "\n")
+	for _, tagID := range chunk.Tags {
+		if name := tagNames[tagID]; inlineTagName.MatchString(name) {
+			text = strings.TrimSpace(text + " #" + name)
+		}
+	}
+
+	item := opmlOutline{Text: text, Note: note}
+	if completed, _ := chunk.Metadata[completedMetadataKey].(bool); completed {
+		item.Complete = "true"
+	}
+	return item
+}
+
+// encodeOPML writes doc as an indented XML document
+func encodeOPML(w io.Writer, doc *opmlDocument) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write OPML: %w", err)
+	}
+	return nil
+}
+
+// parseOPML reads the title and items of an OPML document
+func parseOPML(r io.Reader) (string, []outlineNode, error) {
+	var doc opmlDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return "", nil, fmt.Errorf("%w: %w", ErrInvalidOPML, err)
+	}
+
+	count := 0
+	var convert func(outlines []opmlOutline) []outlineNode
+	convert = func(outlines []opmlOutline) []outlineNode {
+		nodes := make([]outlineNode, 0, len(outlines))
+		for _, outline := range outlines {
+			count++
+			nodes = append(nodes, outlineNode{
+				text:     outline.Text,
+				note:     outline.Note,
+				complete: outline.Complete == "true",
+				children: convert(outline.Outlines),
+			})
+		}
+		return nodes
+	}
+	nodes := convert(doc.Body.Outlines)
+
+	if count == 0 {
+		return "", nil, fmt.Errorf("%w: the body has no outlines", ErrInvalidOPML)
+	}
+	if count > maxOutlineImportItems {
+		return "", nil, fmt.Errorf("%w: %d outlines exceed the limit of %d", ErrInvalidOPML, count, maxOutlineImportItems)
+	}
+	return strings.TrimSpace(doc.Head.Title), nodes, nil
+}
+
+// extractInlineTags removes the #tags from text and returns them in order of appearance
+func extractInlineTags(text string) (string, []string) {
+	matches := inlineTagPattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return strings.TrimSpace(text), nil
+	}
+
+	tags := make([]string, 0, len(matches))
+	seen := make(map[string]bool, len(matches))
+	for _, match := range matches {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			tags = append(tags, match[1])
+		}
+	}
+	stripped := inlineTagPattern.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(stripped), " "), tags
+}
+
+// outlinePlan is an imported outline as chunk records
+type outlinePlan struct {
+	// chunks lists every parent before its children and siblings in document order
+	chunks []models.UnifiedChunkRecord
+	roots  []string
+	// tagged maps tag names to the chunks carrying them
+	tagged map[string][]string
+}
+
+// planOutline turns nodes into chunks under parentID on pageID
+func planOutline(nodes []outlineNode, parentID, pageID string) *outlinePlan {
+	plan := &outlinePlan{tagged: make(map[string][]string)}
+
+	var add func(nodes []outlineNode, parentID string) []string
+	add = func(nodes []outlineNode, parentID string) []string {
+		ids := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			text, tags := extractInlineTags(node.text)
+			contents := text
+			if node.note != "" {
+				contents += "\n" + node.note
+			}
+
+			chunk := models.UnifiedChunkRecord{
+				ChunkID:  uuid.New().String(),
+				Contents: contents,
+				Metadata: map[string]interface{}{},
+			}
+			if parentID != "" {
+				parent := parentID
+				chunk.Parent = &parent
+			}
+			if pageID != "" {
+				page := pageID
+				chunk.Page = &page
+			}
+			if node.complete {
+				chunk.Metadata[completedMetadataKey] = true
+			}
+			plan.chunks = append(plan.chunks, chunk)
+			for _, tag := range tags {
+				plan.tagged[tag] = append(plan.tagged[tag], chunk.ChunkID)
+			}
+
+			ids = append(ids, chunk.ChunkID)
+			add(node.children, chunk.ChunkID)
+		}
+		return ids
+	}
+	plan.roots = add(nodes, parentID)
+	return plan
+}
+
+// ImportOPML creates the page or finds the parent, creates missing tag chunks and the
+// outline in one batch, and then applies the tags
+func (s *outlineExchangeService) ImportOPML(ctx context.Context, r io.Reader, opts models.OutlineImportOptions) (*models.OutlineImportResult, error) {
+	start := time.Now()
+	result := &models.OutlineImportResult{RootIDs: []string{}, Tags: []string{}, CreatedTags: []string{}}
+	defer func() {
+		s.monitor.RecordQuery("outline_import_opml", time.Since(start), result.Chunks)
+	}()
+
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, err
+	}
+	title, nodes, err := parseOPML(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.UnifiedChunkRecord
+	parentID := opts.ParentID
+	if parentID != "" {
+		parent, err := s.chunks.GetChunk(ctx, parentID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent chunk: %w", err)
+		}
+		if parent.IsPage {
+			result.PageID = parent.ChunkID
+		} else {
+			result.PageID = stringValue(parent.Page)
+		}
+	} else {
+		if opts.Title != "" {
+			title = opts.Title
+		}
+		if title == "" {
+			title = defaultOutlineTitle
+		}
+		page := models.UnifiedChunkRecord{
+			ChunkID:  uuid.New().String(),
+			Contents: title,
+			IsPage:   true,
+			Metadata: map[string]interface{}{},
+		}
+		records = append(records, page)
+		parentID = page.ChunkID
+		result.PageID = page.ChunkID
+	}
+
+	plan := planOutline(nodes, parentID, result.PageID)
+	result.RootIDs = plan.roots
+	result.Chunks = len(plan.chunks)
+
+	tagNames := make([]string, 0, len(plan.tagged))
+	for name := range plan.tagged {
+		tagNames = append(tagNames, name)
+	}
+	sort.Strings(tagNames)
+	tagIDs, err := s.findTags(ctx, tagNames)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range tagNames {
+		if _, ok := tagIDs[name]; ok {
+			continue
+		}
+		tag := models.UnifiedChunkRecord{
+			ChunkID:  uuid.New().String(),
+			Contents: name,
+			IsTag:    true,
+			Metadata: map[string]interface{}{},
+		}
+		records = append(records, tag)
+		tagIDs[name] = tag.ChunkID
+		result.CreatedTags = append(result.CreatedTags, name)
+	}
+
+	records = append(records, plan.chunks...)
+	if err := s.chunks.BatchCreateChunks(ctx, records); err != nil {
+		return nil, fmt.Errorf("failed to create outline chunks: %w", err)
+	}
+
+	for _, name := range tagNames {
+		if _, err := s.chunks.BatchAddTags(ctx, plan.tagged[name], []string{tagIDs[name]}); err != nil {
+			return result, fmt.Errorf("outline imported but failed to apply tag %s: %w", name, err)
+		}
+		result.Tags = append(result.Tags, name)
+	}
+
+	return result, nil
+}
+
+// findTags maps the names of existing tag chunks to their IDs
+func (s *outlineExchangeService) findTags(ctx context.Context, names []string) (map[string]string, error) {
+	ids := make(map[string]string, len(names))
+	if len(names) == 0 {
+		return ids, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT chunk_id, contents FROM chunks WHERE is_tag = true AND contents = ANY($1)",
+		pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		ids[name] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tags: %w", err)
+	}
+	return ids, nil
+}
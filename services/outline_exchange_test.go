@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+const workflowyExport = `<?xml version="1.0"?>
+<opml version="2.0">
+  <head><title>Groceries</title></head>
+  <body>
+    <outline text="Buy milk #errand" _note="Oat, not dairy&#10;Two cartons" _complete="true" />
+    <outline text="#home Fix the #home sink">
+      <outline text="Call #plumber-joe" />
+      <outline text="Buy parts" />
+    </outline>
+  </body>
+</opml>`
+
+func TestExtractInlineTags(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+		tags     []string
+	}{
+		{"Buy milk #errand", "Buy milk", []string{"errand"}},
+		{"#home Fix the #home sink", "Fix the sink", []string{"home"}},
+		{"Read #books/sci-fi tonight", "Read tonight", []string{"books/sci-fi"}},
+		{"Issue#42 and C# stay", "Issue#42 and C# stay", nil},
+		{"#待辦 整理筆記", "整理筆記", []string{"待辦"}},
+	}
+	for _, tt := range tests {
+		text, tags := extractInlineTags(tt.text)
+		assert.Equal(t, tt.expected, text, tt.text)
+		assert.Equal(t, tt.tags, tags, tt.text)
+	}
+}
+
+func TestParseOPML(t *testing.T) {
+	title, nodes, err := parseOPML(strings.NewReader(workflowyExport))
+	require.NoError(t, err)
+	assert.Equal(t, "Groceries", title)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "Oat, not dairy\nTwo cartons", nodes[0].note)
+	assert.True(t, nodes[0].complete)
+	require.Len(t, nodes[1].children, 2)
+	assert.Equal(t, "Buy parts", nodes[1].children[1].text)
+
+	_, _, err = parseOPML(strings.NewReader(`<opml version="2.0"><body>`))
+	assert.True(t, errors.Is(err, ErrInvalidOPML))
+	_, _, err = parseOPML(strings.NewReader(`<opml version="2.0"><head/><body/></opml>`))
+	assert.True(t, errors.Is(err, ErrInvalidOPML), "documents without outlines are refused")
+
+	var huge strings.Builder
+	huge.WriteString(`<opml version="2.0"><body>`)
+	for i := 0; i <= maxOutlineImportItems; i++ {
+		huge.WriteString(`<outline text="x"/>`)
+	}
+	huge.WriteString(`</body></opml>`)
+	_, _, err = parseOPML(strings.NewReader(huge.String()))
+	assert.True(t, errors.Is(err, ErrInvalidOPML))
+}
+
+func TestPlanOutline(t *testing.T) {
+	_, nodes, err := parseOPML(strings.NewReader(workflowyExport))
+	require.NoError(t, err)
+
+	plan := planOutline(nodes, "page-1", "page-1")
+	require.Len(t, plan.chunks, 4)
+	require.Len(t, plan.roots, 2)
+
+	// Parents come before their children and siblings keep document order
+	milk, sink, call, parts := plan.chunks[0], plan.chunks[1], plan.chunks[2], plan.chunks[3]
+	assert.Equal(t, []string{milk.ChunkID, sink.ChunkID}, plan.roots)
+	assert.Equal(t, "Buy milk\nOat, not dairy\nTwo cartons", milk.Contents)
+	assert.Equal(t, true, milk.Metadata[completedMetadataKey])
+	assert.Equal(t, "page-1", *milk.Parent)
+	assert.Equal(t, "page-1", *call.Page)
+	assert.Equal(t, sink.ChunkID, *call.Parent)
+	assert.Equal(t, sink.ChunkID, *parts.Parent)
+	assert.Equal(t, "Call", call.Contents)
+
+	assert.Equal(t, map[string][]string{
+		"errand":      {milk.ChunkID},
+		"home":        {sink.ChunkID},
+		"plumber-joe": {call.ChunkID},
+	}, plan.tagged)
+}
+
+// outlineChunks serves a page and its descendants from memory
+type outlineChunks struct {
+	UnifiedChunkService
+	chunks map[string]*models.UnifiedChunkRecord
+	order  []string
+}
+
+func newOutlineChunks() *outlineChunks {
+	return &outlineChunks{chunks: make(map[string]*models.UnifiedChunkRecord)}
+}
+
+func (c *outlineChunks) add(chunk models.UnifiedChunkRecord) {
+	c.chunks[chunk.ChunkID] = &chunk
+	if !chunk.IsPage && !chunk.IsTag {
+		c.order = append(c.order, chunk.ChunkID)
+	}
+}
+
+func (c *outlineChunks) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, ok := c.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	return chunk, nil
+}
+
+func (c *outlineChunks) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
+	descendants := make([]models.UnifiedChunkRecord, 0, len(c.order))
+	for _, id := range c.order {
+		descendants = append(descendants, *c.chunks[id])
+	}
+	return descendants, nil
+}
+
+func TestOutlineExchange_ExportOPML(t *testing.T) {
+	_, nodes, err := parseOPML(strings.NewReader(workflowyExport))
+	require.NoError(t, err)
+
+	chunks := newOutlineChunks()
+	chunks.add(models.UnifiedChunkRecord{ChunkID: "page-1", Contents: "Groceries", IsPage: true})
+	tagIDs := map[string]string{"errand": "tag-1", "home": "tag-2", "plumber-joe": "tag-3"}
+	for name, id := range tagIDs {
+		chunks.add(models.UnifiedChunkRecord{ChunkID: id, Contents: name, IsTag: true})
+	}
+	chunks.add(models.UnifiedChunkRecord{ChunkID: "tag-4", Contents: "two words", IsTag: true})
+
+	plan := planOutline(nodes, "page-1", "page-1")
+	for _, chunk := range plan.chunks {
+		for name, ids := range plan.tagged {
+			if ids[0] == chunk.ChunkID {
+				chunk.Tags = append(chunk.Tags, tagIDs[name])
+			}
+		}
+		if chunk.Contents == "Buy parts" {
+			chunk.Tags = []string{"tag-4", "deleted-tag"}
+		}
+		chunks.add(chunk)
+	}
+
+	service := NewOutlineExchangeService(nil, chunks, NewNoOpMonitor())
+	var buf bytes.Buffer
+	stats, err := service.ExportOPML(context.Background(), &buf, "page-1")
+	require.NoError(t, err)
+	assert.Equal(t, &models.OutlineExportStats{Title: "Groceries", Chunks: 4}, stats)
+
+	// The export reads back as the imported outline, with tags moved after the text
+	title, exported, err := parseOPML(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "Groceries", title)
+	require.Len(t, exported, 2)
+	assert.Equal(t, "Buy milk #errand", exported[0].text)
+	assert.Equal(t, nodes[0].note, exported[0].note)
+	assert.True(t, exported[0].complete)
+	assert.Equal(t, "Fix the sink #home", exported[1].text)
+	require.Len(t, exported[1].children, 2)
+	assert.Equal(t, "Call #plumber-joe", exported[1].children[0].text)
+	assert.Equal(t, "Buy parts", exported[1].children[1].text, "tags that cannot be inline are left out")
+
+	_, err = service.ExportOPML(context.Background(), &buf, plan.chunks[0].ChunkID)
+	assert.Error(t, err, "only pages are exported")
+}
+
+func TestOutlineExchange_ImportRequiresWrite(t *testing.T) {
+	service := NewOutlineExchangeService(nil, newOutlineChunks(), NewNoOpMonitor())
+	_, err := service.ImportOPML(contextWithRole(models.RoleReader), strings.NewReader(workflowyExport), models.OutlineImportOptions{})
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+}
+
+func TestOutlineExchange_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	service := NewOutlineExchangeService(db, chunks, NewNoOpMonitor())
+
+	result, err := service.ImportOPML(ctx, strings.NewReader(workflowyExport), models.OutlineImportOptions{})
+	require.NoError(t, err)
+	defer chunks.DeleteSubtree(ctx, result.PageID, models.SubtreeDeleteOptions{})
+	for _, name := range result.CreatedTags {
+		defer db.Exec("DELETE FROM chunks WHERE is_tag = true AND contents = $1", name)
+	}
+	assert.Equal(t, 4, result.Chunks)
+	assert.Equal(t, []string{"errand", "home", "plumber-joe"}, result.Tags)
+
+	var buf bytes.Buffer
+	_, err = service.ExportOPML(ctx, &buf, result.PageID)
+	require.NoError(t, err)
+	_, exported, err := parseOPML(&buf)
+	require.NoError(t, err)
+	require.Len(t, exported, 2)
+	assert.Equal(t, "Buy milk #errand", exported[0].text)
+	assert.Equal(t, []string{"Call #plumber-joe", "Buy parts"}, []string{exported[1].children[0].text, exported[1].children[1].text})
+}
@@ -0,0 +1,999 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// PAGE ACCESS CONTROL
+// ============================================================================
+//
+// A page may carry an ACL naming its owner, the users and groups it is shared with, and
+// whether every workspace member may read it. The ACL covers the page and its blocks.
+// Pages without one stay open to the workspace as callers' roles allow, and admins and
+// the gateway itself may access every page. Shared callers write as their role allows;
+// public readers only read.
+//
+// Restricted pages are expected to be few, so all ACLs are kept in memory and reloaded
+// periodically; checks and result filtering then need no query. Filtering runs above the
+// chunk and search caches, so cached results are shared between callers and filtered per
+// caller.
+
+// ErrPageACLNotFound is returned for pages without an ACL
+var ErrPageACLNotFound = errors.New("page ACL not found")
+
+// ErrInvalidPageACL is returned for ACLs on chunks that are not pages, without an owner or
+// with too many entries
+var ErrInvalidPageACL = errors.New("invalid page ACL")
+
+const (
+	// pageACLRefreshInterval bounds how long ACL changes made by other instances take to
+	// apply; changes made through this instance apply at once
+	pageACLRefreshInterval = 30 * time.Second
+	// maxPageACLEntries caps the users, and the groups, one page is shared with
+	maxPageACLEntries = 1000
+)
+
+// PageACLService manages page ACLs and enforces them on chunks
+type PageACLService interface {
+	// GetACL returns a page's ACL to callers who may read the page
+	GetACL(ctx context.Context, pageID string) (*models.PageACL, error)
+
+	// SetACL creates or replaces a page's ACL. The caller must be able to manage the page:
+	// be its owner or an admin, or an editor while the page is unrestricted.
+	SetACL(ctx context.Context, pageID string, req *models.PageACLRequest) (*models.PageACL, error)
+
+	// DeleteACL removes a page's ACL, opening the page to the workspace
+	DeleteACL(ctx context.Context, pageID string) error
+
+	// EffectivePermission resolves what the caller may do with a chunk
+	EffectivePermission(ctx context.Context, chunkID string) (*models.EffectivePermission, error)
+
+	// CheckChunk returns ErrPermissionDenied unless the caller may perform perm on chunk
+	CheckChunk(ctx context.Context, chunk *models.UnifiedChunkRecord, perm Permission) error
+
+	// FilterChunks drops the chunks on pages the caller may not read
+	FilterChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) ([]models.UnifiedChunkRecord, error)
+
+	// FilterChunkIDs drops the IDs of chunks on pages the caller may not read, keeping order
+	FilterChunkIDs(ctx context.Context, chunkIDs []string) ([]string, error)
+}
+
+// pageACLService implements PageACLService with the page_acls table of
+// database/page_acl_migration.sql
+type pageACLService struct {
+	db *sql.DB
+	// chunks reads chunks without ACL checks, to find the page a chunk is on
+	chunks  UnifiedChunkService
+	monitor QueryPerformanceMonitor
+
+	mu       sync.RWMutex
+	acls     map[string]*models.PageACL
+	loadedAt time.Time
+}
+
+// NewPageACLService creates a page ACL service. chunks must not enforce ACLs itself.
+func NewPageACLService(db *sql.DB, chunks UnifiedChunkService, monitor QueryPerformanceMonitor) PageACLService {
+	return &pageACLService{
+		db:      db,
+		chunks:  chunks,
+		monitor: monitor,
+		acls:    make(map[string]*models.PageACL),
+	}
+}
+
+const pageACLColumns = `page_chunk_id, owner, shared_users, shared_groups, public_read, updated_by, created_at, updated_at`
+
+// pageOf returns the page whose ACL covers chunk, or "" for chunks outside pages
+func pageOf(chunk *models.UnifiedChunkRecord) string {
+	if chunk.IsPage {
+		return chunk.ChunkID
+	}
+	if chunk.Page != nil {
+		return *chunk.Page
+	}
+	return ""
+}
+
+// exemptFromPageACLs reports whether the caller in ctx may access every page: the gateway
+// itself and admins
+func exemptFromPageACLs(ctx context.Context) bool {
+	principal, ok := PrincipalFromContext(ctx)
+	return !ok || principal.Role == models.RoleAdmin
+}
+
+// resolvePageAccess decides what principal may do with a page protected by acl, which is
+// nil for unrestricted pages
+func resolvePageAccess(principal *Principal, acl *models.PageACL) models.EffectivePermission {
+	if principal == nil {
+		return models.EffectivePermission{Restricted: acl != nil, Read: true, Write: true, Manage: true, Source: models.PageAccessService}
+	}
+	access := models.EffectivePermission{
+		Subject:    principal.Subject,
+		Groups:     principal.Groups,
+		Restricted: acl != nil,
+	}
+	canWrite := Allows(principal.Role, PermissionWrite)
+
+	switch {
+	case principal.Role == models.RoleAdmin:
+		access.Read, access.Write, access.Manage = true, true, true
+		access.Source = models.PageAccessAdmin
+	case acl == nil:
+		access.Read, access.Write, access.Manage = Allows(principal.Role, PermissionRead), canWrite, canWrite
+		access.Source = models.PageAccessUnrestricted
+	case principal.Subject != "" && principal.Subject == acl.Owner:
+		access.Read, access.Write, access.Manage = true, canWrite, true
+		access.Source = models.PageAccessOwner
+	case principal.Subject != "" && hasString(acl.SharedUsers, principal.Subject):
+		access.Read, access.Write = true, canWrite
+		access.Source = models.PageAccessSharedUser
+	default:
+		for _, group := range principal.Groups {
+			if hasString(acl.SharedGroups, group) {
+				access.Read, access.Write = true, canWrite
+				access.Source = models.PageAccessSharedGroup
+				access.Group = group
+				return access
+			}
+		}
+		access.Read = acl.PublicRead
+		access.Source = models.PageAccessDenied
+		if acl.PublicRead {
+			access.Source = models.PageAccessPublicRead
+		}
+	}
+	return access
+}
+
+func hasString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot returns the ACLs of all restricted pages, reloading them when they are older
+// than pageACLRefreshInterval. Failing to load them fails the check rather than opening
+// restricted pages.
+func (s *pageACLService) snapshot(ctx context.Context) (map[string]*models.PageACL, error) {
+	s.mu.RLock()
+	acls, fresh := s.acls, time.Since(s.loadedAt) < pageACLRefreshInterval
+	s.mu.RUnlock()
+	if fresh {
+		return acls, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if time.Since(s.loadedAt) < pageACLRefreshInterval {
+		return s.acls, nil
+	}
+	loaded, err := s.queryACLs(ctx, "load_page_acls", "SELECT "+pageACLColumns+" FROM page_acls")
+	if err != nil {
+		return nil, err
+	}
+	acls = make(map[string]*models.PageACL, len(loaded))
+	for i := range loaded {
+		acls[loaded[i].PageID] = &loaded[i]
+	}
+	s.acls, s.loadedAt = acls, time.Now()
+	return acls, nil
+}
+
+// store replaces the cached ACL of a page, or removes it when acl is nil. The map is
+// copied so snapshots handed out earlier do not change under their readers.
+func (s *pageACLService) store(pageID string, acl *models.PageACL) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acls := make(map[string]*models.PageACL, len(s.acls)+1)
+	for id, existing := range s.acls {
+		acls[id] = existing
+	}
+	if acl == nil {
+		delete(acls, pageID)
+	} else {
+		acls[pageID] = acl
+	}
+	s.acls = acls
+}
+
+// pageAccess resolves the caller's access to a page
+func (s *pageACLService) pageAccess(ctx context.Context, pageID string) (models.EffectivePermission, *models.PageACL, error) {
+	principal, _ := PrincipalFromContext(ctx)
+	acls, err := s.snapshot(ctx)
+	if err != nil {
+		return models.EffectivePermission{}, nil, err
+	}
+	acl := acls[pageID]
+	access := resolvePageAccess(principal, acl)
+	access.PageID = pageID
+	return access, acl, nil
+}
+
+// loadPage returns the page chunk pageID names
+func (s *pageACLService) loadPage(ctx context.Context, pageID string) (*models.UnifiedChunkRecord, error) {
+	page, err := s.chunks.GetChunk(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if !page.IsPage {
+		return nil, fmt.Errorf("%w: chunk %s is not a page", ErrInvalidPageACL, pageID)
+	}
+	return page, nil
+}
+
+// GetACL returns a page's ACL to callers who may read the page
+func (s *pageACLService) GetACL(ctx context.Context, pageID string) (*models.PageACL, error) {
+	access, acl, err := s.pageAccess(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Read {
+		return nil, pageAccessDenied(access, PermissionRead)
+	}
+	if acl == nil {
+		if _, err := s.loadPage(ctx, pageID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: page %s is not restricted", ErrPageACLNotFound, pageID)
+	}
+	return acl, nil
+}
+
+// SetACL creates or replaces a page's ACL
+func (s *pageACLService) SetACL(ctx context.Context, pageID string, req *models.PageACLRequest) (*models.PageACL, error) {
+	if _, err := s.loadPage(ctx, pageID); err != nil {
+		return nil, err
+	}
+	access, _, err := s.pageAccess(ctx, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Manage {
+		return nil, pageAccessDenied(access, PermissionAdmin)
+	}
+
+	owner := strings.TrimSpace(req.Owner)
+	updatedBy := ""
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		updatedBy = principal.Subject
+		if owner == "" {
+			owner = principal.Subject
+		}
+	}
+	if owner == "" {
+		return nil, fmt.Errorf("%w: owner is required", ErrInvalidPageACL)
+	}
+	users, err := normalizePageACLEntries("shared_users", req.SharedUsers)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := normalizePageACLEntries("shared_groups", req.SharedGroups)
+	if err != nil {
+		return nil, err
+	}
+
+	acls, err := s.queryACLs(ctx, "set_page_acl", `
+		INSERT INTO page_acls (page_chunk_id, owner, shared_users, shared_groups, public_read, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (page_chunk_id) DO UPDATE SET
+			owner = EXCLUDED.owner,
+			shared_users = EXCLUDED.shared_users,
+			shared_groups = EXCLUDED.shared_groups,
+			public_read = EXCLUDED.public_read,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = NOW()
+		RETURNING `+pageACLColumns,
+		pageID, owner, pq.Array(users), pq.Array(groups), req.PublicRead, updatedBy)
+	if err != nil {
+		return nil, err
+	}
+	s.store(pageID, &acls[0])
+	return &acls[0], nil
+}
+
+// DeleteACL removes a page's ACL
+func (s *pageACLService) DeleteACL(ctx context.Context, pageID string) error {
+	access, acl, err := s.pageAccess(ctx, pageID)
+	if err != nil {
+		return err
+	}
+	if acl != nil && !access.Manage {
+		return pageAccessDenied(access, PermissionAdmin)
+	}
+
+	start := time.Now()
+	result, err := s.db.ExecContext(ctx, `DELETE FROM page_acls WHERE page_chunk_id = $1`, pageID)
+	s.monitor.RecordQuery("delete_page_acl", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to delete page ACL: %w", err)
+	}
+	s.store(pageID, nil)
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return fmt.Errorf("%w: page %s is not restricted", ErrPageACLNotFound, pageID)
+	}
+	return nil
+}
+
+// EffectivePermission resolves what the caller may do with a chunk
+func (s *pageACLService) EffectivePermission(ctx context.Context, chunkID string) (*models.EffectivePermission, error) {
+	chunk, err := s.chunks.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	access, _, err := s.pageAccess(ctx, pageOf(chunk))
+	if err != nil {
+		return nil, err
+	}
+	access.ChunkID = chunkID
+	return &access, nil
+}
+
+// CheckChunk returns ErrPermissionDenied unless the caller may perform perm on chunk.
+// Role checks are left to the authorizing services; only restricted pages are checked.
+func (s *pageACLService) CheckChunk(ctx context.Context, chunk *models.UnifiedChunkRecord, perm Permission) error {
+	pageID := pageOf(chunk)
+	if pageID == "" || exemptFromPageACLs(ctx) {
+		return nil
+	}
+	access, acl, err := s.pageAccess(ctx, pageID)
+	if err != nil || acl == nil {
+		return err
+	}
+	if (perm == PermissionRead && !access.Read) || (perm != PermissionRead && !access.Write) {
+		return pageAccessDenied(access, perm)
+	}
+	return nil
+}
+
+// FilterChunks drops the chunks on pages the caller may not read
+func (s *pageACLService) FilterChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) ([]models.UnifiedChunkRecord, error) {
+	if len(chunks) == 0 || exemptFromPageACLs(ctx) {
+		return chunks, nil
+	}
+	readable, err := s.readablePages(ctx)
+	if err != nil || readable == nil {
+		return chunks, err
+	}
+
+	filtered := make([]models.UnifiedChunkRecord, 0, len(chunks))
+	for i := range chunks {
+		if readable(pageOf(&chunks[i])) {
+			filtered = append(filtered, chunks[i])
+		}
+	}
+	return filtered, nil
+}
+
+// FilterChunkIDs drops the IDs of chunks on pages the caller may not read. IDs of chunks
+// that no longer exist are kept.
+func (s *pageACLService) FilterChunkIDs(ctx context.Context, chunkIDs []string) ([]string, error) {
+	if len(chunkIDs) == 0 || exemptFromPageACLs(ctx) {
+		return chunkIDs, nil
+	}
+	readable, err := s.readablePages(ctx)
+	if err != nil || readable == nil {
+		return chunkIDs, err
+	}
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT chunk_id, CASE WHEN is_page THEN chunk_id ELSE page END
+		FROM chunks WHERE chunk_id = ANY($1)`,
+		pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up chunk pages: %w", err)
+	}
+	defer rows.Close()
+
+	hidden := make(map[string]bool)
+	for rows.Next() {
+		var chunkID string
+		var pageID sql.NullString
+		if err := rows.Scan(&chunkID, &pageID); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk page: %w", err)
+		}
+		if !readable(pageID.String) {
+			hidden[chunkID] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk pages: %w", err)
+	}
+	s.monitor.RecordQuery("filter_chunk_ids_by_acl", time.Since(start), len(chunkIDs))
+
+	filtered := make([]string, 0, len(chunkIDs))
+	for _, id := range chunkIDs {
+		if !hidden[id] {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered, nil
+}
+
+// readablePages returns a predicate telling which pages the caller may read, or nil when
+// no page is restricted
+func (s *pageACLService) readablePages(ctx context.Context) (func(pageID string) bool, error) {
+	acls, err := s.snapshot(ctx)
+	if err != nil || len(acls) == 0 {
+		return nil, err
+	}
+	principal, _ := PrincipalFromContext(ctx)
+	decided := make(map[string]bool)
+	return func(pageID string) bool {
+		acl, ok := acls[pageID]
+		if !ok {
+			return true
+		}
+		readable, ok := decided[pageID]
+		if !ok {
+			readable = resolvePageAccess(principal, acl).Read
+			decided[pageID] = readable
+		}
+		return readable
+	}, nil
+}
+
+func pageAccessDenied(access models.EffectivePermission, perm Permission) error {
+	subject := access.Subject
+	if subject == "" {
+		subject = "anonymous caller"
+	}
+	return fmt.Errorf("%w: page %s does not allow %s operations by %s", ErrPermissionDenied, access.PageID, perm, subject)
+}
+
+// normalizePageACLEntries trims, deduplicates and sorts the users or groups of an ACL
+func normalizePageACLEntries(field string, entries []string) ([]string, error) {
+	seen := make(map[string]bool, len(entries))
+	normalized := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || seen[entry] {
+			continue
+		}
+		seen[entry] = true
+		normalized = append(normalized, entry)
+	}
+	if len(normalized) > maxPageACLEntries {
+		return nil, fmt.Errorf("%w: %s has %d entries, more than the limit of %d", ErrInvalidPageACL, field, len(normalized), maxPageACLEntries)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func (s *pageACLService) queryACLs(ctx context.Context, operation, query string, args ...interface{}) ([]models.PageACL, error) {
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query page ACLs: %w", err)
+	}
+	defer rows.Close()
+
+	acls := []models.PageACL{}
+	for rows.Next() {
+		var acl models.PageACL
+		var users, groups pq.StringArray
+		if err := rows.Scan(&acl.PageID, &acl.Owner, &users, &groups, &acl.PublicRead, &acl.UpdatedBy,
+			&acl.CreatedAt, &acl.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan page ACL: %w", err)
+		}
+		acl.SharedUsers, acl.SharedGroups = []string(users), []string(groups)
+		acls = append(acls, acl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating page ACLs: %w", err)
+	}
+	s.monitor.RecordQuery(operation, time.Since(start), len(acls))
+	return acls, nil
+}
+
+// aclChunkService enforces page ACLs on chunk reads and writes: chunks on pages the caller
+// may not read are refused or left out of listings and searches, and writes to them need
+// write access
+type aclChunkService struct {
+	UnifiedChunkService
+	acls PageACLService
+}
+
+// NewACLEnforcingChunkService wraps a chunk service with page ACL checks. It belongs above
+// the caching layers, so cached chunks and results are checked per caller.
+func NewACLEnforcingChunkService(base UnifiedChunkService, acls PageACLService) UnifiedChunkService {
+	return &aclChunkService{UnifiedChunkService: base, acls: acls}
+}
+
+func (s *aclChunkService) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.acls.CheckChunk(ctx, chunk, PermissionRead); err != nil {
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// checkWrite checks write access to the page an existing chunk is on
+func (s *aclChunkService) checkWrite(ctx context.Context, chunkID string) error {
+	if exemptFromPageACLs(ctx) {
+		return nil
+	}
+	chunk, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+	if err != nil {
+		return err
+	}
+	return s.acls.CheckChunk(ctx, chunk, PermissionWrite)
+}
+
+func (s *aclChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.acls.CheckChunk(ctx, chunk, PermissionWrite); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.CreateChunk(ctx, chunk)
+}
+
+func (s *aclChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for i := range chunks {
+		if err := s.acls.CheckChunk(ctx, &chunks[i], PermissionWrite); err != nil {
+			return err
+		}
+	}
+	return s.UnifiedChunkService.BatchCreateChunks(ctx, chunks)
+}
+
+// checkUpdate checks write access to the page a chunk is on and, when the update gives it
+// another parent or page, to the page it lands on
+func (s *aclChunkService) checkUpdate(ctx context.Context, chunkID string, parent, page *string) error {
+	if exemptFromPageACLs(ctx) {
+		return nil
+	}
+	current, err := s.UnifiedChunkService.GetChunk(ctx, chunkID)
+	if err != nil {
+		return err
+	}
+	if err := s.acls.CheckChunk(ctx, current, PermissionWrite); err != nil {
+		return err
+	}
+	if parent != nil && *parent != "" && !sameChunkRef(current.Parent, *parent) {
+		if err := s.checkWrite(ctx, *parent); err != nil {
+			return err
+		}
+	}
+	if page != nil && *page != "" && !sameChunkRef(current.Page, *page) {
+		return s.acls.CheckChunk(ctx, &models.UnifiedChunkRecord{Page: page}, PermissionWrite)
+	}
+	return nil
+}
+
+// sameChunkRef reports whether an optional chunk reference already points at chunkID
+func sameChunkRef(ref *string, chunkID string) bool {
+	return ref != nil && *ref == chunkID
+}
+
+func (s *aclChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.checkUpdate(ctx, chunk.ChunkID, chunk.Parent, chunk.Page); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.UpdateChunk(ctx, chunk)
+}
+
+// BatchUpdateChunks refuses the whole batch when any chunk in it may not be written
+func (s *aclChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for i := range chunks {
+		if err := s.checkUpdate(ctx, chunks[i].ChunkID, chunks[i].Parent, chunks[i].Page); err != nil {
+			return err
+		}
+	}
+	return s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks)
+}
+
+func (s *aclChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	var parent, page *string
+	if patch != nil {
+		parent, page = patch.Parent, patch.Page
+	}
+	if err := s.checkUpdate(ctx, chunkID, parent, page); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+}
+
+func (s *aclChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
+	if err := s.checkWrite(ctx, chunkID); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.DeleteChunk(ctx, chunkID)
+}
+
+// BatchGetChunks leaves out chunks on pages the caller may not read
+func (s *aclChunkService) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.BatchGetChunks(ctx, chunkIDs)
+	if err != nil || exemptFromPageACLs(ctx) {
+		return chunks, err
+	}
+	readable := make(map[string]*models.UnifiedChunkRecord, len(chunks))
+	for chunkID, chunk := range chunks {
+		err := s.acls.CheckChunk(ctx, chunk, PermissionRead)
+		if err == nil {
+			readable[chunkID] = chunk
+		} else if !errors.Is(err, ErrPermissionDenied) {
+			return nil, err
+		}
+	}
+	return readable, nil
+}
+
+func (s *aclChunkService) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTag(ctx, tagChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+func (s *aclChunkService) GetChunksByTags(ctx context.Context, tagChunkIDs []string, matchType string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTags(ctx, tagChunkIDs, matchType)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+func (s *aclChunkService) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChunksByTagWithOptions(ctx, tagChunkID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+func (s *aclChunkService) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetChildren(ctx, parentChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+func (s *aclChunkService) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	lists, err := s.UnifiedChunkService.BatchGetChildren(ctx, parentChunkIDs)
+	if err != nil || exemptFromPageACLs(ctx) {
+		return lists, err
+	}
+	filtered := make(map[string][]models.UnifiedChunkRecord, len(lists))
+	for parentID, chunks := range lists {
+		if filtered[parentID], err = s.acls.FilterChunks(ctx, chunks); err != nil {
+			return nil, err
+		}
+	}
+	return filtered, nil
+}
+
+func (s *aclChunkService) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.GetDescendants(ctx, ancestorChunkID, maxDepth)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+// GetAncestors needs read access to the chunk and leaves out ancestors on pages the caller
+// may not read
+func (s *aclChunkService) GetAncestors(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	if _, err := s.GetChunk(ctx, chunkID); err != nil {
+		return nil, err
+	}
+	chunks, err := s.UnifiedChunkService.GetAncestors(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+// checkMove checks write access to the page a chunk leaves and the page of its new parent.
+// Moves to the top level only need the first.
+func (s *aclChunkService) checkMove(ctx context.Context, chunkID, newParentID string) error {
+	if err := s.checkWrite(ctx, chunkID); err != nil {
+		return err
+	}
+	if newParentID == "" {
+		return nil
+	}
+	return s.checkWrite(ctx, newParentID)
+}
+
+func (s *aclChunkService) MoveChunk(ctx context.Context, chunkID, newParentID string) error {
+	if err := s.checkMove(ctx, chunkID, newParentID); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.MoveChunk(ctx, chunkID, newParentID)
+}
+
+func (s *aclChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	if err := s.checkMove(ctx, chunkID, newParentID); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.MoveSubtree(ctx, chunkID, newParentID)
+}
+
+func (s *aclChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	for _, move := range moves {
+		if err := s.checkMove(ctx, move.ChunkID, move.NewParentID); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.BulkMove(ctx, moves)
+}
+
+// CopySubtree needs read access to the copied chunk and write access to the page of the
+// new parent
+func (s *aclChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	if _, err := s.GetChunk(ctx, chunkID); err != nil {
+		return nil, err
+	}
+	if newParentID != "" {
+		if err := s.checkWrite(ctx, newParentID); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.CopySubtree(ctx, chunkID, newParentID)
+}
+
+func (s *aclChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	if err := s.checkWrite(ctx, chunkID); err != nil {
+		return nil, err
+	}
+	return s.UnifiedChunkService.DeleteSubtree(ctx, chunkID, opts)
+}
+
+func (s *aclChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	for _, chunkID := range []string{targetID, sourceID} {
+		if err := s.checkWrite(ctx, chunkID); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+}
+
+func (s *aclChunkService) AddTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
+	if err := s.checkWrite(ctx, chunkID); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.AddTags(ctx, chunkID, tagChunkIDs)
+}
+
+func (s *aclChunkService) RemoveTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
+	if err := s.checkWrite(ctx, chunkID); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.RemoveTags(ctx, chunkID, tagChunkIDs)
+}
+
+func (s *aclChunkService) BatchAddTags(ctx context.Context, chunkIDs, tagChunkIDs []string) (int, error) {
+	for _, chunkID := range chunkIDs {
+		if err := s.checkWrite(ctx, chunkID); err != nil {
+			return 0, err
+		}
+	}
+	return s.UnifiedChunkService.BatchAddTags(ctx, chunkIDs, tagChunkIDs)
+}
+
+// checkTaggedWrite checks write access to the pages of a tag chunk and of every chunk
+// carrying the tag, since renaming or merging the tag changes them all
+func (s *aclChunkService) checkTaggedWrite(ctx context.Context, tagChunkID string) error {
+	if exemptFromPageACLs(ctx) {
+		return nil
+	}
+	if err := s.checkWrite(ctx, tagChunkID); err != nil {
+		return err
+	}
+	tagged, err := s.UnifiedChunkService.GetChunksByTag(ctx, tagChunkID)
+	if err != nil {
+		return err
+	}
+	for i := range tagged {
+		if err := s.acls.CheckChunk(ctx, &tagged[i], PermissionWrite); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetTagParent needs write access to everything the moved tag covers and to the new parent tag
+func (s *aclChunkService) SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error {
+	if err := s.checkTaggedWrite(ctx, tagChunkID); err != nil {
+		return err
+	}
+	if parentTagChunkID != "" {
+		if err := s.checkWrite(ctx, parentTagChunkID); err != nil {
+			return err
+		}
+	}
+	return s.UnifiedChunkService.SetTagParent(ctx, tagChunkID, parentTagChunkID)
+}
+
+func (s *aclChunkService) GetChunkTags(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	tags, err := s.UnifiedChunkService.GetChunkTags(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, tags)
+}
+
+func (s *aclChunkService) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	lists, err := s.UnifiedChunkService.BatchGetChunkTags(ctx, chunkIDs)
+	if err != nil || exemptFromPageACLs(ctx) {
+		return lists, err
+	}
+	filtered := make(map[string][]models.UnifiedChunkRecord, len(lists))
+	for chunkID, tags := range lists {
+		if filtered[chunkID], err = s.acls.FilterChunks(ctx, tags); err != nil {
+			return nil, err
+		}
+	}
+	return filtered, nil
+}
+
+func (s *aclChunkService) GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	tags, err := s.UnifiedChunkService.GetTagAncestors(ctx, tagChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, tags)
+}
+
+func (s *aclChunkService) GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	tags, err := s.UnifiedChunkService.GetTagDescendants(ctx, tagChunkID)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, tags)
+}
+
+// GetTagRollup needs read access to the tag, leaves out descendant tags the caller may not
+// read and, when tagged chunks are hidden, counts only the chunks the caller may read
+func (s *aclChunkService) GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error) {
+	if _, err := s.GetChunk(ctx, tagChunkID); err != nil {
+		return nil, err
+	}
+	rollup, err := s.UnifiedChunkService.GetTagRollup(ctx, tagChunkID)
+	if err != nil || rollup == nil || exemptFromPageACLs(ctx) {
+		return rollup, err
+	}
+
+	tagged, err := s.UnifiedChunkService.GetChunksByTagWithOptions(ctx, tagChunkID, &models.TagQueryOptions{IncludeDescendants: true})
+	if err != nil {
+		return nil, err
+	}
+	readable, err := s.acls.FilterChunks(ctx, tagged)
+	if err != nil {
+		return nil, err
+	}
+	// Hidden chunks or hidden descendant tags make the stored counts too high
+	recount := len(readable) < len(tagged)
+
+	var prune func(node models.TagRollup) (models.TagRollup, error)
+	prune = func(node models.TagRollup) (models.TagRollup, error) {
+		children := node.Children
+		node.Children = nil
+		for _, child := range children {
+			if _, err := s.GetChunk(ctx, child.TagChunkID); err != nil {
+				if errors.Is(err, ErrPermissionDenied) {
+					recount = true
+					continue
+				}
+				return node, err
+			}
+			pruned, err := prune(child)
+			if err != nil {
+				return node, err
+			}
+			node.Children = append(node.Children, pruned)
+		}
+		if recount {
+			node.DirectCount, node.RollupCount = countTagged(readable, node)
+		}
+		return node, nil
+	}
+	pruned, err := prune(*rollup)
+	if err != nil {
+		return nil, err
+	}
+	return &pruned, nil
+}
+
+// countTagged counts the chunks tagged with a rollup node's tag, and those tagged with it or
+// any tag below it
+func countTagged(chunks []models.UnifiedChunkRecord, node models.TagRollup) (direct, rollup int) {
+	covered := map[string]bool{}
+	var collect func(n models.TagRollup)
+	collect = func(n models.TagRollup) {
+		covered[n.TagChunkID] = true
+		for _, child := range n.Children {
+			collect(child)
+		}
+	}
+	collect(node)
+
+	for i := range chunks {
+		if hasString(chunks[i].Tags, node.TagChunkID) {
+			direct++
+		}
+		for _, tag := range chunks[i].Tags {
+			if covered[tag] {
+				rollup++
+				break
+			}
+		}
+	}
+	return direct, rollup
+}
+
+func (s *aclChunkService) RenameTag(ctx context.Context, tagChunkID, newName string) error {
+	if err := s.checkTaggedWrite(ctx, tagChunkID); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.RenameTag(ctx, tagChunkID, newName)
+}
+
+// MergeTags needs write access to every chunk retagged by the merge and to the target tag
+func (s *aclChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	for _, tagChunkID := range sourceTagIDs {
+		if tagChunkID == "" || tagChunkID == targetTagID {
+			continue
+		}
+		if err := s.checkTaggedWrite(ctx, tagChunkID); err != nil {
+			return nil, err
+		}
+	}
+	if targetTagID != "" {
+		if err := s.checkWrite(ctx, targetTagID); err != nil {
+			return nil, err
+		}
+	}
+	return s.UnifiedChunkService.MergeTags(ctx, sourceTagIDs, targetTagID)
+}
+
+func (s *aclChunkService) SearchByContent(ctx context.Context, content string, filters map[string]interface{}) ([]models.UnifiedChunkRecord, error) {
+	chunks, err := s.UnifiedChunkService.SearchByContent(ctx, content, filters)
+	if err != nil {
+		return nil, err
+	}
+	return s.acls.FilterChunks(ctx, chunks)
+}
+
+// SearchChunks leaves out results on pages the caller may not read. The total count is
+// reduced by the results left out of this page of results.
+func (s *aclChunkService) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	result, err := s.UnifiedChunkService.SearchChunks(ctx, query)
+	if err != nil || result == nil {
+		return result, err
+	}
+	chunks, err := s.acls.FilterChunks(ctx, result.Chunks)
+	if err != nil {
+		return nil, err
+	}
+	if hidden := len(result.Chunks) - len(chunks); hidden > 0 {
+		filtered := *result
+		filtered.Chunks = chunks
+		filtered.TotalCount -= hidden
+		if filtered.TotalCount < len(chunks) {
+			filtered.TotalCount = len(chunks)
+		}
+		return &filtered, nil
+	}
+	return result, nil
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func TestResolvePageAccess(t *testing.T) {
+	acl := &models.PageACL{
+		PageID:       "page-1",
+		Owner:        "alice",
+		SharedUsers:  []string{"bob"},
+		SharedGroups: []string{"finance"},
+	}
+	public := *acl
+	public.PublicRead = true
+
+	tests := []struct {
+		name      string
+		principal *Principal
+		acl       *models.PageACL
+		want      models.EffectivePermission
+	}{
+		{"service", nil, acl, models.EffectivePermission{Read: true, Write: true, Manage: true, Source: models.PageAccessService}},
+		{"admin", &Principal{Subject: "root", Role: models.RoleAdmin}, acl,
+			models.EffectivePermission{Read: true, Write: true, Manage: true, Source: models.PageAccessAdmin}},
+		{"unrestricted reader", &Principal{Subject: "carol", Role: models.RoleReader}, nil,
+			models.EffectivePermission{Read: true, Source: models.PageAccessUnrestricted}},
+		{"unrestricted editor", &Principal{Subject: "carol", Role: models.RoleEditor}, nil,
+			models.EffectivePermission{Read: true, Write: true, Manage: true, Source: models.PageAccessUnrestricted}},
+		{"owner", &Principal{Subject: "alice", Role: models.RoleReader}, acl,
+			models.EffectivePermission{Read: true, Manage: true, Source: models.PageAccessOwner}},
+		{"shared user", &Principal{Subject: "bob", Role: models.RoleEditor}, acl,
+			models.EffectivePermission{Read: true, Write: true, Source: models.PageAccessSharedUser}},
+		{"shared group", &Principal{Subject: "dave", Role: models.RoleReader, Groups: []string{"sales", "finance"}}, acl,
+			models.EffectivePermission{Read: true, Source: models.PageAccessSharedGroup, Group: "finance"}},
+		{"public read", &Principal{Subject: "erin", Role: models.RoleEditor}, &public,
+			models.EffectivePermission{Read: true, Source: models.PageAccessPublicRead}},
+		{"denied", &Principal{Subject: "erin", Role: models.RoleEditor}, acl,
+			models.EffectivePermission{Source: models.PageAccessDenied}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resolvePageAccess(tt.principal, tt.acl)
+			got.Subject, got.Groups = "", nil
+			tt.want.Restricted = tt.acl != nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// aclChunks serves fixed chunks without access checks
+type aclChunks struct {
+	UnifiedChunkService
+	chunks  map[string]*models.UnifiedChunkRecord
+	updated []string
+}
+
+// all returns the chunks in the fixture, each tagged with "tag"
+
+func (c *aclChunks) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, ok := c.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	return chunk, nil
+}
+
+func (c *aclChunks) all() []models.UnifiedChunkRecord {
+	ids := []string{"secret", "secret-block", "open", "open-block", "loose"}
+	chunks := make([]models.UnifiedChunkRecord, len(ids))
+	for i, id := range ids {
+		chunks[i] = *c.chunks[id]
+		chunks[i].Tags = []string{"tag"}
+	}
+	return chunks
+}
+
+func (c *aclChunks) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	chunks := make(map[string]*models.UnifiedChunkRecord, len(chunkIDs))
+	for _, id := range chunkIDs {
+		if chunk, ok := c.chunks[id]; ok {
+			chunks[id] = chunk
+		}
+	}
+	return chunks, nil
+}
+
+func (c *aclChunks) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
+	return c.all(), nil
+}
+
+func (c *aclChunks) GetAncestors(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	return c.all(), nil
+}
+
+func (c *aclChunks) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	return c.all(), nil
+}
+
+func (c *aclChunks) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	return c.all(), nil
+}
+
+func (c *aclChunks) GetChunkTags(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	return c.all(), nil
+}
+
+// GetTagRollup reports "tag" with the restricted tag "secret-tag" below it
+func (c *aclChunks) GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error) {
+	return &models.TagRollup{TagChunkID: "tag", DirectCount: 5, RollupCount: 6, Children: []models.TagRollup{
+		{TagChunkID: "secret-tag", Depth: 1, DirectCount: 1, RollupCount: 1},
+	}}, nil
+}
+
+func (c *aclChunks) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
+	return &models.SearchResult{Chunks: c.all(), TotalCount: 12, CacheHit: true}, nil
+}
+
+func (c *aclChunks) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	c.updated = append(c.updated, chunkID)
+	return c.chunks[chunkID], nil
+}
+
+func (c *aclChunks) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	c.updated = append(c.updated, chunk.ChunkID)
+	return nil
+}
+
+func (c *aclChunks) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for _, chunk := range chunks {
+		c.updated = append(c.updated, chunk.ChunkID)
+	}
+	return nil
+}
+
+// newTestPageACLs returns a chunk service enforcing an ACL on the page "secret", shared
+// with bob and the finance group, with the ACLs already loaded
+func newTestPageACLs() (UnifiedChunkService, *pageACLService, *aclChunks) {
+	secret, open := "secret", "open"
+	chunks := &aclChunks{chunks: map[string]*models.UnifiedChunkRecord{
+		"secret":       {ChunkID: "secret", Contents: "Salaries", IsPage: true},
+		"secret-block": {ChunkID: "secret-block", Contents: "Alice: 100", Page: &secret},
+		"open":         {ChunkID: "open", Contents: "Handbook", IsPage: true},
+		"open-block":   {ChunkID: "open-block", Contents: "Be kind", Page: &open},
+		"loose":        {ChunkID: "loose", Contents: "Not on a page"},
+		"tag":          {ChunkID: "tag", Contents: "payroll", IsTag: true},
+		"secret-tag":   {ChunkID: "secret-tag", Contents: "bonus", IsTag: true, Page: &secret},
+	}}
+	acls := NewPageACLService(nil, chunks, NewNoOpMonitor()).(*pageACLService)
+	acls.acls["secret"] = &models.PageACL{PageID: "secret", Owner: "alice", SharedUsers: []string{"bob"}, SharedGroups: []string{"finance"}}
+	acls.loadedAt = time.Now()
+	return NewACLEnforcingChunkService(chunks, acls), acls, chunks
+}
+
+func contextWithSubject(subject string, role models.Role, groups ...string) context.Context {
+	return WithPrincipal(context.Background(), &Principal{Subject: subject, Workspace: DefaultWorkspace, Role: role, Groups: groups})
+}
+
+func TestACLChunkService_GetChunk(t *testing.T) {
+	service, _, _ := newTestPageACLs()
+
+	_, err := service.GetChunk(contextWithSubject("erin", models.RoleEditor), "secret-block")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		contextWithSubject("root", models.RoleAdmin),
+		contextWithSubject("alice", models.RoleReader),
+		contextWithSubject("bob", models.RoleReader),
+		contextWithSubject("dave", models.RoleReader, "finance"),
+	} {
+		chunk, err := service.GetChunk(ctx, "secret-block")
+		require.NoError(t, err)
+		assert.Equal(t, "Alice: 100", chunk.Contents)
+	}
+
+	chunk, err := service.GetChunk(contextWithSubject("erin", models.RoleReader), "open-block")
+	require.NoError(t, err)
+	assert.Equal(t, "Be kind", chunk.Contents)
+}
+
+func TestACLChunkService_Writes(t *testing.T) {
+	service, _, chunks := newTestPageACLs()
+
+	_, err := service.PatchChunk(contextWithSubject("dave", models.RoleReader, "finance"), "secret-block", &models.ChunkPatch{})
+	assert.ErrorIs(t, err, ErrPermissionDenied, "shared readers may not write")
+	_, err = service.PatchChunk(contextWithSubject("erin", models.RoleEditor), "secret-block", &models.ChunkPatch{})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = service.PatchChunk(contextWithSubject("bob", models.RoleEditor), "secret-block", &models.ChunkPatch{})
+	assert.NoError(t, err)
+
+	secret := "secret"
+	err = service.CreateChunk(contextWithSubject("erin", models.RoleEditor), &models.UnifiedChunkRecord{Contents: "sneaky", Page: &secret})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Equal(t, []string{"secret-block"}, chunks.updated)
+}
+
+func TestACLChunkService_UpdatesCheckDestination(t *testing.T) {
+	service, _, chunks := newTestPageACLs()
+	ctx := contextWithSubject("erin", models.RoleEditor)
+	secret, secretBlock := "secret", "secret-block"
+
+	_, err := service.PatchChunk(ctx, "open-block", &models.ChunkPatch{Parent: &secretBlock})
+	assert.ErrorIs(t, err, ErrPermissionDenied, "moving under a block on a restricted page")
+	_, err = service.PatchChunk(ctx, "open-block", &models.ChunkPatch{Page: &secret})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	err = service.UpdateChunk(ctx, &models.UnifiedChunkRecord{ChunkID: "loose", Contents: "moved", Page: &secret})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	err = service.BatchUpdateChunks(ctx, []models.UnifiedChunkRecord{
+		{ChunkID: "open-block", Contents: "Be kinder"},
+		{ChunkID: "secret-block", Contents: "Alice: 0", Page: &secret},
+	})
+	assert.ErrorIs(t, err, ErrPermissionDenied, "one denied chunk fails the whole batch")
+	assert.Empty(t, chunks.updated)
+
+	contents := "Be kinder"
+	_, err = service.PatchChunk(ctx, "open-block", &models.ChunkPatch{Contents: &contents})
+	require.NoError(t, err)
+	_, err = service.PatchChunk(contextWithSubject("bob", models.RoleEditor), "open-block", &models.ChunkPatch{Page: &secret})
+	require.NoError(t, err)
+	err = service.UpdateChunk(ctx, &models.UnifiedChunkRecord{ChunkID: "secret-block", Page: &secret})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Equal(t, []string{"open-block", "open-block"}, chunks.updated)
+}
+
+func TestACLChunkService_TreeAndTagWritesDenied(t *testing.T) {
+	service, _, _ := newTestPageACLs()
+	ctx := contextWithSubject("erin", models.RoleEditor)
+
+	calls := map[string]func() error{
+		"get ancestors": func() error {
+			_, err := service.GetAncestors(ctx, "secret-block")
+			return err
+		},
+		"move chunk out": func() error { return service.MoveChunk(ctx, "secret-block", "open") },
+		"move chunk in":  func() error { return service.MoveChunk(ctx, "open-block", "secret") },
+		"move subtree in": func() error {
+			_, err := service.MoveSubtree(ctx, "open-block", "secret")
+			return err
+		},
+		"bulk move": func() error {
+			_, err := service.BulkMove(ctx, []models.ChunkMove{{ChunkID: "loose", NewParentID: "open"}, {ChunkID: "secret-block"}})
+			return err
+		},
+		"copy subtree out": func() error {
+			_, err := service.CopySubtree(ctx, "secret-block", "open")
+			return err
+		},
+		"copy subtree in": func() error {
+			_, err := service.CopySubtree(ctx, "open-block", "secret")
+			return err
+		},
+		"delete subtree": func() error {
+			_, err := service.DeleteSubtree(ctx, "secret", models.SubtreeDeleteOptions{Confirm: true})
+			return err
+		},
+		"merge into": func() error {
+			_, err := service.MergeChunks(ctx, "secret-block", "open-block", models.ChunkMergeStrategy{})
+			return err
+		},
+		"merge from": func() error {
+			_, err := service.MergeChunks(ctx, "open-block", "secret-block", models.ChunkMergeStrategy{})
+			return err
+		},
+		"add tags":    func() error { return service.AddTags(ctx, "secret-block", []string{"tag"}) },
+		"remove tags": func() error { return service.RemoveTags(ctx, "secret-block", []string{"tag"}) },
+		"batch add tags": func() error {
+			_, err := service.BatchAddTags(ctx, []string{"open-block", "secret-block"}, []string{"tag"})
+			return err
+		},
+		"rename tag":     func() error { return service.RenameTag(ctx, "tag", "salary") },
+		"set tag parent": func() error { return service.SetTagParent(ctx, "tag", "") },
+		"merge tags": func() error {
+			_, err := service.MergeTags(ctx, []string{"tag"}, "loose")
+			return err
+		},
+	}
+	for name, call := range calls {
+		t.Run(name, func(t *testing.T) {
+			assert.ErrorIs(t, call(), ErrPermissionDenied)
+		})
+	}
+
+	ancestors, err := service.GetAncestors(contextWithSubject("erin", models.RoleReader), "open-block")
+	require.NoError(t, err)
+	assert.Len(t, ancestors, 3)
+}
+
+func TestACLChunkService_FiltersListingsAndSearches(t *testing.T) {
+	service, _, _ := newTestPageACLs()
+	ctx := contextWithSubject("erin", models.RoleReader)
+
+	children, err := service.GetChildren(ctx, "any")
+	require.NoError(t, err)
+	assert.Len(t, children, 3)
+	for _, chunk := range children {
+		assert.NotEqual(t, "secret", pageOf(&chunk))
+	}
+
+	result, err := service.SearchChunks(ctx, &models.SearchQuery{})
+	require.NoError(t, err)
+	assert.Len(t, result.Chunks, 3)
+	assert.Equal(t, 10, result.TotalCount)
+	assert.True(t, result.CacheHit)
+
+	result, err = service.SearchChunks(contextWithSubject("alice", models.RoleReader), &models.SearchQuery{})
+	require.NoError(t, err)
+	assert.Len(t, result.Chunks, 5)
+	assert.Equal(t, 12, result.TotalCount)
+
+	tags, err := service.GetChunkTags(ctx, "any")
+	require.NoError(t, err)
+	assert.Len(t, tags, 3)
+}
+
+func TestACLChunkService_GetTagRollup(t *testing.T) {
+	service, _, _ := newTestPageACLs()
+
+	rollup, err := service.GetTagRollup(contextWithSubject("erin", models.RoleReader), "tag")
+	require.NoError(t, err)
+	assert.Empty(t, rollup.Children, "restricted descendant tags are left out")
+	assert.Equal(t, 3, rollup.DirectCount)
+	assert.Equal(t, 3, rollup.RollupCount)
+
+	rollup, err = service.GetTagRollup(contextWithSubject("alice", models.RoleReader), "tag")
+	require.NoError(t, err)
+	assert.Len(t, rollup.Children, 1)
+	assert.Equal(t, 5, rollup.DirectCount)
+	assert.Equal(t, 6, rollup.RollupCount, "stored counts are kept when nothing is hidden")
+
+	_, err = service.GetTagRollup(contextWithSubject("erin", models.RoleReader), "secret-tag")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func TestPageACLService_EffectivePermission(t *testing.T) {
+	_, acls, _ := newTestPageACLs()
+
+	permission, err := acls.EffectivePermission(contextWithSubject("dave", models.RoleEditor, "finance"), "secret-block")
+	require.NoError(t, err)
+	assert.Equal(t, "secret-block", permission.ChunkID)
+	assert.Equal(t, "secret", permission.PageID)
+	assert.True(t, permission.Restricted)
+	assert.True(t, permission.Read)
+	assert.True(t, permission.Write)
+	assert.False(t, permission.Manage)
+	assert.Equal(t, models.PageAccessSharedGroup, permission.Source)
+
+	permission, err = acls.EffectivePermission(contextWithSubject("erin", models.RoleEditor), "loose")
+	require.NoError(t, err)
+	assert.Empty(t, permission.PageID)
+	assert.False(t, permission.Restricted)
+	assert.True(t, permission.Write)
+
+	_, err = acls.GetACL(contextWithSubject("erin", models.RoleEditor), "secret")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = acls.SetACL(contextWithSubject("bob", models.RoleEditor), "secret", &models.PageACLRequest{PublicRead: true})
+	assert.ErrorIs(t, err, ErrPermissionDenied, "only owners and admins manage restricted pages")
+	_, err = acls.SetACL(context.Background(), "open-block", &models.PageACLRequest{Owner: "alice"})
+	assert.ErrorIs(t, err, ErrInvalidPageACL)
+}
+
+func TestNormalizePageACLEntries(t *testing.T) {
+	entries, err := normalizePageACLEntries("shared_users", []string{" bob ", "alice", "", "bob"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, entries)
+
+	many := make([]string, maxPageACLEntries+1)
+	for i := range many {
+		many[i] = fmt.Sprintf("user-%d", i)
+	}
+	_, err = normalizePageACLEntries("shared_users", many)
+	assert.ErrorIs(t, err, ErrInvalidPageACL)
+}
+
+func TestPageACLService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	acls := NewPageACLService(db, chunks, NewNoOpMonitor())
+	service := NewACLEnforcingChunkService(chunks, acls)
+
+	page := &models.UnifiedChunkRecord{Contents: "ACL test page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer chunks.DeleteChunk(ctx, page.ChunkID)
+
+	acl, err := acls.SetACL(contextWithSubject("alice", models.RoleEditor), page.ChunkID, &models.PageACLRequest{SharedGroups: []string{"finance", "finance"}})
+	require.NoError(t, err)
+	assert.Equal(t, "alice", acl.Owner)
+	assert.Equal(t, []string{"finance"}, acl.SharedGroups)
+
+	_, err = service.GetChunk(contextWithSubject("erin", models.RoleReader), page.ChunkID)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = service.GetChunk(contextWithSubject("dave", models.RoleReader, "finance"), page.ChunkID)
+	assert.NoError(t, err)
+
+	missing := uuid.New().String()
+	visible, err := acls.FilterChunkIDs(contextWithSubject("erin", models.RoleReader), []string{page.ChunkID, missing})
+	require.NoError(t, err)
+	assert.Equal(t, []string{missing}, visible)
+
+	require.NoError(t, acls.DeleteACL(contextWithSubject("alice", models.RoleReader), page.ChunkID))
+	_, err = service.GetChunk(contextWithSubject("erin", models.RoleReader), page.ChunkID)
+	assert.NoError(t, err)
+}
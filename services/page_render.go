@@ -0,0 +1,525 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidRenderRequest is returned for unknown formats and for chunks that are not pages
+var ErrInvalidRenderRequest = errors.New("invalid render request")
+
+const (
+	// maxRenderTransclusions caps the referenced and embedded chunks loaded for one page
+	maxRenderTransclusions = 500
+	// renderedPagePath is where page links in rendered pages point
+	renderedPagePath = "/api/v1/pages/%s/render"
+)
+
+// PageRenderService renders pages for reading, with the chunks they reference shown inline
+type PageRenderService interface {
+	// RenderPage renders a page and its blocks in format. ((uuid)) references show the
+	// referenced block's text and {{embed ((uuid))}} lines the block with its children.
+	RenderPage(ctx context.Context, pageID string, format models.RenderFormat) (*models.RenderedPage, error)
+
+	// RenderPublished renders a page like RenderPage, showing nothing but the page and its
+	// descendants: references to other chunks stay unresolved and page links are text
+	RenderPublished(ctx context.Context, pageID string, format models.RenderFormat) (*models.RenderedPage, error)
+}
+
+// ParseRenderFormat parses a render format; empty means HTML
+func ParseRenderFormat(value string) (models.RenderFormat, error) {
+	switch format := models.RenderFormat(strings.ToLower(value)); format {
+	case "":
+		return models.RenderHTML, nil
+	case models.RenderHTML, models.RenderMarkdown:
+		return format, nil
+	default:
+		return "", fmt.Errorf("%w: unknown format %q", ErrInvalidRenderRequest, value)
+	}
+}
+
+// pageRenderService implements PageRenderService on the chunk service
+type pageRenderService struct {
+	db      *sql.DB
+	chunks  UnifiedChunkService
+	cache   CacheService
+	config  *config.RenderConfig
+	monitor QueryPerformanceMonitor
+}
+
+// NewPageRenderService creates a page render service. Without db, page links are rendered
+// as text; without cache, every request renders the page.
+func NewPageRenderService(db *sql.DB, chunks UnifiedChunkService, cache CacheService, cfg *config.RenderConfig, monitor QueryPerformanceMonitor) PageRenderService {
+	return &pageRenderService{db: db, chunks: chunks, cache: cache, config: cfg, monitor: monitor}
+}
+
+// cachedPageRender is a rendered page as cached, with the chunks it transcludes so a hit
+// can be checked against their current versions
+type cachedPageRender struct {
+	Page        models.RenderedPage `json:"page"`
+	Transcluded []string            `json:"transcluded"`
+	Embedded    []string            `json:"embedded"`
+}
+
+func (s *pageRenderService) RenderPage(ctx context.Context, pageID string, format models.RenderFormat) (*models.RenderedPage, error) {
+	return s.render(ctx, pageID, format, false)
+}
+
+func (s *pageRenderService) RenderPublished(ctx context.Context, pageID string, format models.RenderFormat) (*models.RenderedPage, error) {
+	return s.render(ctx, pageID, format, true)
+}
+
+// render renders a page; scoped renderings only show the page's own chunks
+func (s *pageRenderService) render(ctx context.Context, pageID string, format models.RenderFormat, scoped bool) (*models.RenderedPage, error) {
+	if err := Authorize(ctx, PermissionRead); err != nil {
+		return nil, err
+	}
+	if format != models.RenderHTML && format != models.RenderMarkdown {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidRenderRequest, format)
+	}
+	start := time.Now()
+
+	page, err := s.chunks.GetChunk(ctx, pageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page: %w", err)
+	}
+	if !page.IsPage {
+		return nil, fmt.Errorf("%w: chunk %s is not a page", ErrInvalidRenderRequest, pageID)
+	}
+	blocks, err := s.chunks.GetDescendants(ctx, pageID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page blocks: %w", err)
+	}
+
+	cacheKey := fmt.Sprintf("page_render:%s:%s", pageID, format)
+	if scoped {
+		cacheKey += ":published"
+	}
+	caching := s.cache != nil && s.config.CacheTTL > 0
+	if caching {
+		var cached cachedPageRender
+		if err := s.cache.Get(ctx, cacheKey, &cached); err == nil {
+			// The page is only served from the cache while nothing it shows has changed
+			if etag, err := s.currentETag(ctx, page, blocks, cacheKey, &cached); err == nil && etag == cached.Page.ETag {
+				rendered := cached.Page
+				rendered.Cached = true
+				s.monitor.RecordQuery("render_page_cached", time.Since(start), rendered.Blocks)
+				return &rendered, nil
+			}
+		}
+	}
+
+	r := newPageRenderer(page, blocks, s.config.MaxTransclusionDepth)
+	db := s.db
+	if scoped {
+		r.scope = make(map[string]bool, len(blocks))
+		for _, block := range blocks {
+			r.scope[block.ChunkID] = true
+		}
+		db = nil
+	}
+	if err := r.load(ctx, s.chunks, db); err != nil {
+		return nil, err
+	}
+	rendered := &models.RenderedPage{
+		PageID:     pageID,
+		Title:      page.Contents,
+		Format:     format,
+		Blocks:     len(blocks),
+		RenderedAt: time.Now(),
+	}
+	if format == models.RenderMarkdown {
+		rendered.Content = r.markdown()
+	} else {
+		rendered.Content = r.html()
+	}
+	rendered.Transclusions, rendered.Unresolved = r.transclusions, r.unresolved
+
+	entry := cachedPageRender{Transcluded: r.transcludedIDs(), Embedded: r.embeddedIDs()}
+	rendered.ETag = renderETag(page, blocks, cacheKey, entry.Transcluded, r.chunks, entry.Embedded, r.embedded)
+	if caching {
+		entry.Page = *rendered
+		s.cache.Set(ctx, cacheKey, entry, s.config.CacheTTL)
+	}
+
+	s.monitor.RecordQuery("render_page", time.Since(start), rendered.Blocks)
+	return rendered, nil
+}
+
+// currentETag computes the ETag a cached rendering would have now
+func (s *pageRenderService) currentETag(ctx context.Context, page *models.UnifiedChunkRecord, blocks []models.UnifiedChunkRecord, variant string, cached *cachedPageRender) (string, error) {
+	transcluded := make(map[string]*models.UnifiedChunkRecord, len(cached.Transcluded))
+	if len(cached.Transcluded) > 0 {
+		found, err := s.chunks.BatchGetChunks(ctx, cached.Transcluded)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range cached.Transcluded {
+			transcluded[id] = found[id]
+		}
+	}
+	embedded := make(map[string][]models.UnifiedChunkRecord, len(cached.Embedded))
+	for _, id := range cached.Embedded {
+		descendants, err := s.chunks.GetDescendants(ctx, id, 0)
+		if err != nil {
+			return "", err
+		}
+		embedded[id] = descendants
+	}
+	return renderETag(page, blocks, variant, cached.Transcluded, transcluded, cached.Embedded, embedded), nil
+}
+
+// renderETag hashes the versions of every chunk a rendering shows, and variant, which tells
+// formats and scopes apart. It is weak because the titles of linked pages are not part of it.
+func renderETag(
+	page *models.UnifiedChunkRecord,
+	blocks []models.UnifiedChunkRecord,
+	variant string,
+	transcludedIDs []string,
+	transcluded map[string]*models.UnifiedChunkRecord,
+	embeddedIDs []string,
+	embedded map[string][]models.UnifiedChunkRecord,
+) string {
+	hash := sha256.New()
+	write := func(chunk *models.UnifiedChunkRecord) {
+		fmt.Fprintf(hash, "%s@%d\n", chunk.ChunkID, chunk.LastUpdated.UnixNano())
+	}
+	fmt.Fprintf(hash, "%s\n", variant)
+	write(page)
+	for i := range blocks {
+		write(&blocks[i])
+	}
+	for _, id := range transcludedIDs {
+		if chunk := transcluded[id]; chunk != nil {
+			write(chunk)
+		} else {
+			fmt.Fprintf(hash, "%s@missing\n", id)
+		}
+	}
+	for _, id := range embeddedIDs {
+		fmt.Fprintf(hash, "embed %s\n", id)
+		for i := range embedded[id] {
+			write(&embedded[id][i])
+		}
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// pageRenderer renders one page. load fetches the chunks the page transcludes, then html
+// or markdown walk the hierarchy, resolving references as they meet them.
+type pageRenderer struct {
+	page     *models.UnifiedChunkRecord
+	maxDepth int
+
+	// children lists the children of the page, its blocks and embedded blocks
+	children map[string][]*models.UnifiedChunkRecord
+	placed   map[string]bool
+	// chunks holds the referenced and embedded chunks, nil for those not found
+	chunks map[string]*models.UnifiedChunkRecord
+	// embedded holds the descendants of embedded chunks
+	embedded map[string][]models.UnifiedChunkRecord
+	// pages maps lowercase titles of linked pages to their IDs
+	pages map[string]string
+	// scope, when set, holds the only chunks references may show
+	scope map[string]bool
+
+	// active holds the blocks being rendered, so a block that transcludes itself is caught
+	active        map[string]bool
+	depth         int
+	transclusions int
+	unresolved    int
+}
+
+func newPageRenderer(page *models.UnifiedChunkRecord, blocks []models.UnifiedChunkRecord, maxDepth int) *pageRenderer {
+	r := &pageRenderer{
+		page:     page,
+		maxDepth: maxDepth,
+		children: make(map[string][]*models.UnifiedChunkRecord),
+		placed:   make(map[string]bool),
+		chunks:   make(map[string]*models.UnifiedChunkRecord),
+		embedded: make(map[string][]models.UnifiedChunkRecord),
+		pages:    make(map[string]string),
+		active:   make(map[string]bool),
+	}
+	r.addChildren(blocks)
+	return r
+}
+
+func (r *pageRenderer) addChildren(chunks []models.UnifiedChunkRecord) {
+	for i := range chunks {
+		// Embedded blocks may be on the page already
+		if chunks[i].Parent != nil && !r.placed[chunks[i].ChunkID] {
+			r.placed[chunks[i].ChunkID] = true
+			r.children[*chunks[i].Parent] = append(r.children[*chunks[i].Parent], &chunks[i])
+		}
+	}
+}
+
+// load fetches the chunks the page references and embeds, level by level down to maxDepth,
+// and the pages it links to
+func (r *pageRenderer) load(ctx context.Context, chunks UnifiedChunkService, db *sql.DB) error {
+	titles := make(map[string]bool)
+	var refs, embeds []string
+	scan := func(contents string) {
+		for _, match := range logseqBlockRef.FindAllStringSubmatch(contents, -1) {
+			refs = append(refs, strings.ToLower(match[1]))
+		}
+		for _, line := range strings.Split(contents, "\n") {
+			if match := markdownEmbed.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+				embeds = append(embeds, strings.ToLower(match[1]))
+			}
+		}
+		for _, match := range logseqPageLink.FindAllStringSubmatch(contents, -1) {
+			titles[strings.ToLower(strings.TrimSpace(match[1]))] = true
+		}
+		for _, match := range logseqTagLink.FindAllStringSubmatch(contents, -1) {
+			titles[strings.ToLower(match[1])] = true
+		}
+	}
+	for _, blocks := range r.children {
+		for _, block := range blocks {
+			scan(block.Contents)
+		}
+	}
+
+	for level := 0; level < r.maxDepth && len(refs) > 0; level++ {
+		var ids []string
+		for _, id := range refs {
+			if r.scope != nil && !r.scope[id] {
+				continue
+			}
+			if _, ok := r.chunks[id]; !ok && len(r.chunks) < maxRenderTransclusions {
+				r.chunks[id] = nil
+				ids = append(ids, id)
+			}
+		}
+		levelEmbeds := embeds
+		refs, embeds = nil, nil
+
+		if len(ids) > 0 {
+			found, err := chunks.BatchGetChunks(ctx, ids)
+			if err != nil {
+				return fmt.Errorf("failed to get transcluded chunks: %w", err)
+			}
+			for _, id := range ids {
+				if chunk := found[id]; chunk != nil {
+					r.chunks[id] = chunk
+					scan(chunk.Contents)
+				}
+			}
+		}
+		for _, id := range levelEmbeds {
+			if _, ok := r.embedded[id]; ok || r.chunks[id] == nil {
+				continue
+			}
+			descendants, err := chunks.GetDescendants(ctx, id, 0)
+			if err != nil {
+				return fmt.Errorf("failed to get embedded blocks: %w", err)
+			}
+			r.embedded[id] = descendants
+			r.addChildren(descendants)
+			for i := range descendants {
+				scan(descendants[i].Contents)
+			}
+		}
+	}
+
+	if db != nil && len(titles) > 0 {
+		pages, err := findPagesByTitle(ctx, db, titles)
+		if err != nil {
+			return err
+		}
+		r.pages = pages
+	}
+	return nil
+}
+
+// transcludedIDs returns the IDs of the chunks looked up for references and embeds, sorted
+func (r *pageRenderer) transcludedIDs() []string {
+	ids := make([]string, 0, len(r.chunks))
+	for id := range r.chunks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// embeddedIDs returns the IDs of the embedded chunks whose descendants were loaded, sorted
+func (r *pageRenderer) embeddedIDs() []string {
+	ids := make([]string, 0, len(r.embedded))
+	for id := range r.embedded {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// resolve returns the chunk a reference at the current depth shows, counting the reference.
+// It fails for chunks that were not found, are too deep or are already being rendered.
+func (r *pageRenderer) resolve(chunkID string, embed bool) (*models.UnifiedChunkRecord, bool) {
+	chunk := r.chunks[chunkID]
+	_, loaded := r.embedded[chunkID]
+	if chunk == nil || r.active[chunkID] || r.depth >= r.maxDepth || embed && !loaded {
+		r.unresolved++
+		return nil, false
+	}
+	r.transclusions++
+	return chunk, true
+}
+
+// enter marks a chunk as being transcluded until leave
+func (r *pageRenderer) enter(chunkID string) {
+	r.active[chunkID] = true
+	r.depth++
+}
+
+func (r *pageRenderer) leave(chunkID string) {
+	delete(r.active, chunkID)
+	r.depth--
+}
+
+// firstLine returns the first line of a chunk's contents, which is what references show
+func firstLine(contents string) string {
+	line, _, _ := strings.Cut(contents, "\n")
+	return strings.TrimSpace(line)
+}
+
+// html renders the page as an article with its blocks as nested lists
+func (r *pageRenderer) html() string {
+	md := &markdownHTML{pageHref: r.pageHref}
+	md.blockRef = func(chunkID string) string {
+		chunk, ok := r.resolve(chunkID, false)
+		if !ok {
+			return fmt.Sprintf(`<span class="block-ref unresolved" data-chunk-id="%s">((%s))</span>`, chunkID, chunkID)
+		}
+		r.enter(chunkID)
+		defer r.leave(chunkID)
+		return fmt.Sprintf(`<span class="block-ref" data-chunk-id="%s">%s</span>`, chunkID, md.inline(firstLine(chunk.Contents)))
+	}
+	md.embed = func(chunkID string) string {
+		chunk, ok := r.resolve(chunkID, true)
+		if !ok {
+			return fmt.Sprintf(`<div class="embed unresolved" data-chunk-id="%s">((%s))</div>`+"\n", chunkID, chunkID)
+		}
+		r.enter(chunkID)
+		defer r.leave(chunkID)
+		return fmt.Sprintf(`<div class="embed" data-chunk-id="%s">`+"\n%s%s</div>\n", chunkID, md.blocks(chunk.Contents), r.htmlBlocks(md, chunkID, false))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<article class="page" data-page-id="%s">`+"\n", html.EscapeString(r.page.ChunkID))
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(r.page.Contents))
+	b.WriteString(r.htmlBlocks(md, r.page.ChunkID, true))
+	b.WriteString("</article>\n")
+	return b.String()
+}
+
+// htmlBlocks renders the children of parentID; only the page's own blocks get anchors
+func (r *pageRenderer) htmlBlocks(md *markdownHTML, parentID string, anchors bool) string {
+	children := r.children[parentID]
+	if len(children) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString(`<ul class="blocks">` + "\n")
+	for _, child := range children {
+		if anchors {
+			fmt.Fprintf(&b, `<li class="block" id="block-%s">`+"\n", html.EscapeString(child.ChunkID))
+		} else {
+			b.WriteString(`<li class="block">` + "\n")
+		}
+		r.active[child.ChunkID] = true
+		b.WriteString(md.blocks(child.Contents))
+		b.WriteString(r.htmlBlocks(md, child.ChunkID, anchors))
+		delete(r.active, child.ChunkID)
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
+
+// pageHref links a page title to the rendering of the page
+func (r *pageRenderer) pageHref(title string) (string, bool) {
+	id, ok := r.pages[strings.ToLower(strings.TrimSpace(title))]
+	if !ok {
+		return "", false
+	}
+	return fmt.Sprintf(renderedPagePath, url.PathEscape(id)), true
+}
+
+// markdown renders the page as a title and nested bullets, with references replaced by
+// the text they show and embeds by the embedded blocks
+func (r *pageRenderer) markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.page.Contents)
+	r.markdownBlocks(&b, r.page.ChunkID, "")
+	return b.String()
+}
+
+func (r *pageRenderer) markdownBlocks(b *strings.Builder, parentID, indent string) {
+	for _, child := range r.children[parentID] {
+		r.active[child.ChunkID] = true
+		r.markdownBlock(b, child, indent)
+		delete(r.active, child.ChunkID)
+	}
+}
+
+// markdownBlock writes a block as a bullet followed by its children
+func (r *pageRenderer) markdownBlock(b *strings.Builder, block *models.UnifiedChunkRecord, indent string) {
+	for i, line := range strings.Split(r.markdownContents(block.Contents), "\n") {
+		if i == 0 {
+			fmt.Fprintf(b, "%s- %s\n", indent, line)
+		} else {
+			fmt.Fprintf(b, "%s  %s\n", indent, line)
+		}
+	}
+	r.markdownBlocks(b, block.ChunkID, indent+"  ")
+}
+
+// markdownContents resolves the references and embeds in a block's contents
+func (r *pageRenderer) markdownContents(contents string) string {
+	lines := strings.Split(contents, "\n")
+	for i, line := range lines {
+		if match := markdownEmbed.FindStringSubmatch(strings.TrimSpace(line)); match != nil {
+			lines[i] = r.markdownEmbed(strings.ToLower(match[1]), line)
+			continue
+		}
+		lines[i] = logseqBlockRef.ReplaceAllStringFunc(line, func(ref string) string {
+			chunkID := strings.ToLower(ref[2 : len(ref)-2])
+			chunk, ok := r.resolve(chunkID, false)
+			if !ok {
+				return ref
+			}
+			r.enter(chunkID)
+			defer r.leave(chunkID)
+			return r.markdownContents(firstLine(chunk.Contents))
+		})
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownEmbed renders an embedded block and its children as bullets, or returns line as
+// it is if the block cannot be shown
+func (r *pageRenderer) markdownEmbed(chunkID, line string) string {
+	chunk, ok := r.resolve(chunkID, true)
+	if !ok {
+		return line
+	}
+	r.enter(chunkID)
+	defer r.leave(chunkID)
+	var b strings.Builder
+	r.markdownBlock(&b, chunk, "")
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownHTML(t *testing.T) {
+	md := &markdownHTML{
+		pageHref: func(title string) (string, bool) { return "/pages/" + title, title == "Known" },
+		blockRef: func(chunkID string) string { return "[ref " + chunkID + "]" },
+		embed:    func(chunkID string) string { return "[embed " + chunkID + "]\n" },
+	}
+
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{"escapes text", `5 < 6 & "quotes"`, "<p>5 &lt; 6 &amp; &#34;quotes&#34;</p>\n"},
+		{"escapes raw html", `<script>alert(1)</script>`, "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"drops unsafe links", `[click](javascript:steal)`, "<p>click</p>\n"},
+		{"drops unsafe images", `![pixel](data:image/png;base64,AAAA)`, "<p>pixel</p>\n"},
+		{"escapes link targets", `[x](/a"onmouseover="b)`, "<p><a href=\"/a&#34;onmouseover=&#34;b\" rel=\"nofollow noopener\">x</a></p>\n"},
+		{"links", `see [docs](https://example.com/docs) or https://example.com.`,
+			"<p>see <a href=\"https://example.com/docs\" rel=\"nofollow noopener\">docs</a> or <a href=\"https://example.com\" rel=\"nofollow noopener\">https://example.com</a>.</p>\n"},
+		{"emphasis", `**bold** *em* ~~gone~~ snake_case_name`, "<p><strong>bold</strong> <em>em</em> <del>gone</del> snake_case_name</p>\n"},
+		{"code spans are literal", "`**not bold** <b>`", "<p><code>**not bold** &lt;b&gt;</code></p>\n"},
+		{"page links and tags", `[[Known]] [[Unknown]] #Known`,
+			"<p><a class=\"page-ref\" href=\"/pages/Known\">Known</a> <span class=\"page-ref\">Unknown</span> <a class=\"tag\" href=\"/pages/Known\">#Known</a></p>\n"},
+		{"block refs", `as ((6f1c2a34-5b6d-4e7f-8a9b-0c1d2e3f4a5b)) says`, "<p>as [ref 6f1c2a34-5b6d-4e7f-8a9b-0c1d2e3f4a5b] says</p>\n"},
+		{"embeds", `{{embed ((6F1C2A34-5B6D-4E7F-8A9B-0C1D2E3F4A5B))}}`, "[embed 6f1c2a34-5b6d-4e7f-8a9b-0c1d2e3f4a5b]\n"},
+		{"headings and rules", "## Plan\n---", "<h2>Plan</h2>\n<hr>\n"},
+		{"line breaks", "one\ntwo", "<p>one<br>\ntwo</p>\n"},
+		{"fenced code", "```go\nif a < b {}\n```", "<pre><code class=\"language-go\">if a &lt; b {}</code></pre>\n"},
+		{"quotes", "> quoted *text*", "<blockquote>\n<p>quoted <em>text</em></p>\n</blockquote>\n"},
+		{"nested lists", "- one\n  - two\n- three\n1. first",
+			"<ul>\n<li>one\n<ul>\n<li>two</li>\n</ul>\n</li>\n<li>three</li>\n</ul>\n<ol>\n<li>first</li>\n</ol>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, md.blocks(tt.markdown))
+		})
+	}
+}
+
+// renderChunks serves chunks from memory, with descendants in the order they were added
+type renderChunks struct {
+	UnifiedChunkService
+	chunks map[string]*models.UnifiedChunkRecord
+	order  []string
+}
+
+func newRenderChunks(chunks ...models.UnifiedChunkRecord) *renderChunks {
+	c := &renderChunks{chunks: make(map[string]*models.UnifiedChunkRecord)}
+	for _, chunk := range chunks {
+		chunk := chunk
+		c.chunks[chunk.ChunkID] = &chunk
+		c.order = append(c.order, chunk.ChunkID)
+	}
+	return c
+}
+
+func (c *renderChunks) GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	chunk, ok := c.chunks[chunkID]
+	if !ok {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
+	}
+	copied := *chunk
+	return &copied, nil
+}
+
+func (c *renderChunks) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	found := make(map[string]*models.UnifiedChunkRecord)
+	for _, id := range chunkIDs {
+		if chunk, ok := c.chunks[id]; ok {
+			copied := *chunk
+			found[id] = &copied
+		}
+	}
+	return found, nil
+}
+
+func (c *renderChunks) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
+	under := map[string]bool{ancestorChunkID: true}
+	var descendants []models.UnifiedChunkRecord
+	for _, id := range c.order {
+		chunk := c.chunks[id]
+		if chunk.Parent != nil && under[*chunk.Parent] {
+			under[id] = true
+			descendants = append(descendants, *chunk)
+		}
+	}
+	return descendants, nil
+}
+
+const (
+	renderPageID  = "0e5f4b1a-0000-4000-8000-000000000001"
+	renderIntro   = "0e5f4b1a-0000-4000-8000-000000000002"
+	renderDetail  = "0e5f4b1a-0000-4000-8000-000000000003"
+	renderOther   = "0e5f4b1a-0000-4000-8000-000000000004"
+	renderQuote   = "0e5f4b1a-0000-4000-8000-000000000005"
+	renderSteps   = "0e5f4b1a-0000-4000-8000-000000000006"
+	renderStep    = "0e5f4b1a-0000-4000-8000-000000000007"
+	renderMissing = "0e5f4b1a-0000-4000-8000-0000000000ff"
+)
+
+func newRenderFixture() *renderChunks {
+	page, other := renderPageID, renderOther
+	intro, steps := renderIntro, renderSteps
+	at := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	return newRenderChunks(
+		models.UnifiedChunkRecord{ChunkID: renderPageID, Contents: "Launch <plan>", IsPage: true, LastUpdated: at},
+		models.UnifiedChunkRecord{ChunkID: renderIntro, Contents: "As ((" + renderQuote + ")) said", Parent: &page, Page: &page, LastUpdated: at},
+		models.UnifiedChunkRecord{ChunkID: renderDetail, Contents: "{{embed ((" + renderSteps + "))}}\nSee ((" + renderMissing + ")) and ((" + renderDetail + "))", Parent: &intro, Page: &page, LastUpdated: at},
+		models.UnifiedChunkRecord{ChunkID: renderOther, Contents: "Sources", IsPage: true, LastUpdated: at},
+		models.UnifiedChunkRecord{ChunkID: renderQuote, Contents: "**ship** it\nsecond line", Parent: &other, Page: &other, LastUpdated: at},
+		models.UnifiedChunkRecord{ChunkID: renderSteps, Contents: "Steps", Parent: &other, Page: &other, LastUpdated: at},
+		models.UnifiedChunkRecord{ChunkID: renderStep, Contents: "Test <it>", Parent: &steps, Page: &other, LastUpdated: at},
+	)
+}
+
+func TestPageRender_HTML(t *testing.T) {
+	service := NewPageRenderService(nil, newRenderFixture(), nil, &config.RenderConfig{MaxTransclusionDepth: 3}, NewNoOpMonitor())
+
+	page, err := service.RenderPage(context.Background(), renderPageID, models.RenderHTML)
+	require.NoError(t, err)
+	assert.Equal(t, "Launch <plan>", page.Title)
+	assert.Equal(t, 2, page.Blocks)
+	assert.Equal(t, 2, page.Transclusions, "the quote and the embedded steps")
+	assert.Equal(t, 2, page.Unresolved, "the missing block and the block referencing itself")
+	assert.False(t, page.Cached)
+	assert.NotEmpty(t, page.ETag)
+
+	assert.Equal(t, `<article class="page" data-page-id="`+renderPageID+`">
+<h1>Launch &lt;plan&gt;</h1>
+<ul class="blocks">
+<li class="block" id="block-`+renderIntro+`">
+<p>As <span class="block-ref" data-chunk-id="`+renderQuote+`"><strong>ship</strong> it</span> said</p>
+<ul class="blocks">
+<li class="block" id="block-`+renderDetail+`">
+<div class="embed" data-chunk-id="`+renderSteps+`">
+<p>Steps</p>
+<ul class="blocks">
+<li class="block">
+<p>Test &lt;it&gt;</p>
+</li>
+</ul>
+</div>
+<p>See <span class="block-ref unresolved" data-chunk-id="`+renderMissing+`">((`+renderMissing+`))</span> and <span class="block-ref unresolved" data-chunk-id="`+renderDetail+`">((`+renderDetail+`))</span></p>
+</li>
+</ul>
+</li>
+</ul>
+</article>
+`, page.Content)
+}
+
+func TestPageRender_Markdown(t *testing.T) {
+	service := NewPageRenderService(nil, newRenderFixture(), nil, &config.RenderConfig{MaxTransclusionDepth: 3}, NewNoOpMonitor())
+
+	page, err := service.RenderPage(context.Background(), renderPageID, models.RenderMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, "# Launch <plan>\n\n"+
+		"- As **ship** it said\n"+
+		"  - - Steps\n"+
+		"      - Test <it>\n"+
+		"    See (("+renderMissing+")) and (("+renderDetail+"))\n", page.Content)
+	assert.Equal(t, 2, page.Transclusions)
+	assert.Equal(t, 2, page.Unresolved)
+}
+
+func TestPageRender_TransclusionDepth(t *testing.T) {
+	// With no transclusion allowed, references are left as they are
+	service := NewPageRenderService(nil, newRenderFixture(), nil, &config.RenderConfig{}, NewNoOpMonitor())
+	page, err := service.RenderPage(context.Background(), renderPageID, models.RenderMarkdown)
+	require.NoError(t, err)
+	assert.Contains(t, page.Content, "- As (("+renderQuote+")) said\n")
+	assert.Equal(t, 0, page.Transclusions)
+	assert.Equal(t, 4, page.Unresolved)
+}
+
+func TestPageRender_Cache(t *testing.T) {
+	ctx := context.Background()
+	chunks := newRenderFixture()
+	service := NewPageRenderService(nil, chunks, NewInMemoryCache(100, time.Minute), &config.RenderConfig{CacheTTL: time.Hour, MaxTransclusionDepth: 3}, NewNoOpMonitor())
+
+	first, err := service.RenderPage(ctx, renderPageID, models.RenderHTML)
+	require.NoError(t, err)
+	second, err := service.RenderPage(ctx, renderPageID, models.RenderHTML)
+	require.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Equal(t, first.Content, second.Content)
+	assert.Equal(t, first.ETag, second.ETag)
+
+	markdown, err := service.RenderPage(ctx, renderPageID, models.RenderMarkdown)
+	require.NoError(t, err)
+	assert.False(t, markdown.Cached, "formats are cached separately")
+	assert.NotEqual(t, first.ETag, markdown.ETag)
+
+	// Editing a block on another page that the page embeds invalidates the rendering
+	chunks.chunks[renderStep].Contents = "Test everything"
+	chunks.chunks[renderStep].LastUpdated = time.Now()
+	third, err := service.RenderPage(ctx, renderPageID, models.RenderHTML)
+	require.NoError(t, err)
+	assert.False(t, third.Cached)
+	assert.NotEqual(t, first.ETag, third.ETag)
+	assert.Contains(t, third.Content, "<p>Test everything</p>")
+}
+
+func TestPageRender_Errors(t *testing.T) {
+	ctx := context.Background()
+	service := NewPageRenderService(nil, newRenderFixture(), nil, &config.RenderConfig{}, NewNoOpMonitor())
+
+	_, err := service.RenderPage(ctx, renderIntro, models.RenderHTML)
+	assert.ErrorIs(t, err, ErrInvalidRenderRequest)
+	_, err = service.RenderPage(ctx, renderPageID, "pdf")
+	assert.ErrorIs(t, err, ErrInvalidRenderRequest)
+	_, err = ParseRenderFormat("pdf")
+	assert.ErrorIs(t, err, ErrInvalidRenderRequest)
+	format, err := ParseRenderFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, models.RenderHTML, format)
+
+	_, err = service.RenderPage(contextWithRole(models.RoleReader), renderPageID, models.RenderHTML)
+	assert.NoError(t, err, "readers can render pages")
+}
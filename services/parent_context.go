@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"semantic-text-processor/models"
+)
+
+// ErrParentContextUnavailable is returned when parent context is requested without a
+// chunk hierarchy to read parents from
+var ErrParentContextUnavailable = errors.New("parent context retrieval is not available")
+
+// ChunkHierarchySource reads the parents and children of retrieved chunks
+type ChunkHierarchySource interface {
+	GetAncestors(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error)
+	BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error)
+}
+
+// SetHierarchySource enables parent context retrieval for requests that set ParentContext
+func (s *RAGService) SetHierarchySource(source ChunkHierarchySource) {
+	s.hierarchy = source
+}
+
+// parentSections expands results to their sections with the configured hierarchy
+func (s *RAGService) parentSections(ctx context.Context, results []models.SimilarityResult) ([]models.SimilarityResult, map[string][]models.ContextChildHit, error) {
+	if s.hierarchy == nil {
+		return nil, nil, ErrParentContextUnavailable
+	}
+	return expandToParents(ctx, s.hierarchy, results)
+}
+
+// expandToParents replaces each result with the section it belongs to: its parent chunk
+// followed by the parent's children. Results sharing a parent become one result at the
+// rank of the best of them, and the results each section holds are returned by the
+// section's chunk ID. Results whose parent is a page, or that have none, are kept as they
+// are, since a whole page is rarely a useful context block.
+func expandToParents(ctx context.Context, hierarchy ChunkHierarchySource, results []models.SimilarityResult) ([]models.SimilarityResult, map[string][]models.ContextChildHit, error) {
+	parents := make(map[string]models.UnifiedChunkRecord)
+	var order []string
+	sections := make(map[string]models.SimilarityResult)
+	hits := make(map[string][]models.ContextChildHit)
+	for _, result := range results {
+		ancestors, err := hierarchy.GetAncestors(ctx, result.Chunk.ID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get ancestors of chunk %s: %w", result.Chunk.ID, err)
+		}
+		if len(ancestors) == 0 || ancestors[len(ancestors)-1].IsPage {
+			if _, ok := sections[result.Chunk.ID]; !ok {
+				sections[result.Chunk.ID] = result
+				order = append(order, result.Chunk.ID)
+			}
+			continue
+		}
+
+		parent := ancestors[len(ancestors)-1]
+		section, retrieved := sections[parent.ChunkID]
+		if !retrieved {
+			section.Similarity = result.Similarity
+			order = append(order, parent.ChunkID)
+		}
+		if _, expanded := parents[parent.ChunkID]; !expanded {
+			if retrieved {
+				// The parent was retrieved itself, so it is one of its section's hits
+				hits[parent.ChunkID] = append(hits[parent.ChunkID], models.ContextChildHit{
+					ChunkID:    parent.ChunkID,
+					Similarity: section.Similarity,
+				})
+			}
+			parents[parent.ChunkID] = parent
+			section.Chunk = models.ChunkRecord{
+				ID:            parent.ChunkID,
+				TextID:        result.Chunk.TextID,
+				ParentChunkID: parent.Parent,
+				Metadata:      parent.Metadata,
+				CreatedAt:     parent.CreatedTime,
+				UpdatedAt:     parent.LastUpdated,
+			}
+			sections[parent.ChunkID] = section
+		}
+		hits[parent.ChunkID] = append(hits[parent.ChunkID], models.ContextChildHit{
+			ChunkID:    result.Chunk.ID,
+			Similarity: result.Similarity,
+		})
+	}
+
+	if len(parents) > 0 {
+		parentIDs := make([]string, 0, len(parents))
+		for id := range parents {
+			parentIDs = append(parentIDs, id)
+		}
+		children, err := hierarchy.BatchGetChildren(ctx, parentIDs)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get parent sections: %w", err)
+		}
+		for id, parent := range parents {
+			section := sections[id]
+			section.Chunk.Content = sectionContent(parent, children[id])
+			sections[id] = section
+		}
+	}
+
+	expanded := make([]models.SimilarityResult, len(order))
+	for i, id := range order {
+		expanded[i] = sections[id]
+	}
+	return expanded, hits, nil
+}
+
+// sectionContent is a parent chunk's contents followed by its children's, one per line
+func sectionContent(parent models.UnifiedChunkRecord, children []models.UnifiedChunkRecord) string {
+	lines := []string{parent.Contents}
+	for _, child := range children {
+		lines = append(lines, child.Contents)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// annotateChildHits lists on each block the retrieved chunks it holds, including those of
+// the sections folded into it
+func annotateChildHits(assembled *models.AssembledContext, hits map[string][]models.ContextChildHit) {
+	for i := range assembled.Blocks {
+		block := &assembled.Blocks[i]
+		block.ChildHits = append(block.ChildHits, hits[block.ChunkID]...)
+		for _, covered := range block.Covers {
+			block.ChildHits = append(block.ChildHits, hits[covered]...)
+		}
+	}
+}
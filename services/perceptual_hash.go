@@ -0,0 +1,180 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// PerceptualHashes 圖片的感知雜湊值（各 64 位元）
+type PerceptualHashes struct {
+	PHash uint64 // DCT 低頻雜湊，對縮放、壓縮與輕微色調變化穩定
+	DHash uint64 // 相鄰像素梯度雜湊，計算快速且對亮度變化穩定
+}
+
+// ComputePerceptualHashes 解碼圖片並計算 pHash 與 dHash
+func ComputePerceptualHashes(data []byte) (*PerceptualHashes, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	return HashImage(img)
+}
+
+// HashImage 計算已解碼圖片的 pHash 與 dHash
+func HashImage(img image.Image) (*PerceptualHashes, error) {
+	bounds := img.Bounds()
+	if bounds.Dx() == 0 || bounds.Dy() == 0 {
+		return nil, fmt.Errorf("image has no pixels")
+	}
+
+	gray := grayscalePixels(img)
+	return &PerceptualHashes{
+		PHash: computePHash(gray, bounds.Dx(), bounds.Dy()),
+		DHash: computeDHash(gray, bounds.Dx(), bounds.Dy()),
+	}, nil
+}
+
+// HammingDistance 計算兩個雜湊值不同的位元數
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Distance 回傳與另一組雜湊的距離（pHash 與 dHash 的平均漢明距離）
+func (h *PerceptualHashes) Distance(other *PerceptualHashes) float64 {
+	return float64(HammingDistance(h.PHash, other.PHash)+HammingDistance(h.DHash, other.DHash)) / 2
+}
+
+// Similarity 將雜湊距離換算為 0~1 的相似度
+func (h *PerceptualHashes) Similarity(other *PerceptualHashes) float64 {
+	return 1 - h.Distance(other)/64
+}
+
+// ToMetadata 轉換為可存入 chunk metadata 的格式
+func (h *PerceptualHashes) ToMetadata() map[string]interface{} {
+	return map[string]interface{}{
+		"phash": formatHash(h.PHash),
+		"dhash": formatHash(h.DHash),
+	}
+}
+
+// ParsePerceptualHashes 從 chunk metadata 的 perceptual_hash 欄位讀取雜湊值
+func ParsePerceptualHashes(metadata map[string]interface{}) (*PerceptualHashes, bool) {
+	raw, ok := metadata[perceptualHashMetadataKey].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	phashText, _ := raw["phash"].(string)
+	dhashText, _ := raw["dhash"].(string)
+	phash, err := strconv.ParseUint(phashText, 16, 64)
+	if err != nil {
+		return nil, false
+	}
+	dhash, err := strconv.ParseUint(dhashText, 16, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &PerceptualHashes{PHash: phash, DHash: dhash}, true
+}
+
+// perceptualHashMetadataKey chunk metadata 中存放感知雜湊的欄位
+const perceptualHashMetadataKey = "perceptual_hash"
+
+func formatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// grayscalePixels 將圖片轉為亮度值陣列（列優先）
+func grayscalePixels(img image.Image) []float64 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	gray := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// ITU-R BT.601 亮度
+			gray[y*width+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+		}
+	}
+	return gray
+}
+
+// resizeGray 以區域平均將亮度陣列縮放到指定大小
+func resizeGray(gray []float64, width, height, targetWidth, targetHeight int) []float64 {
+	resized := make([]float64, targetWidth*targetHeight)
+	for ty := 0; ty < targetHeight; ty++ {
+		y0 := ty * height / targetHeight
+		y1 := max((ty+1)*height/targetHeight, y0+1)
+		for tx := 0; tx < targetWidth; tx++ {
+			x0 := tx * width / targetWidth
+			x1 := max((tx+1)*width/targetWidth, x0+1)
+
+			var sum float64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					sum += gray[y*width+x]
+				}
+			}
+			resized[ty*targetWidth+tx] = sum / float64((y1-y0)*(x1-x0))
+		}
+	}
+	return resized
+}
+
+// computeDHash 縮放為 9x8 後比較水平相鄰像素
+func computeDHash(gray []float64, width, height int) uint64 {
+	small := resizeGray(gray, width, height, 9, 8)
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if small[y*9+x] > small[y*9+x+1] {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// computePHash 縮放為 32x32，取 DCT 左上 8x8 低頻係數並以中位數二值化
+func computePHash(gray []float64, width, height int) uint64 {
+	const size, lowFreq = 32, 8
+	small := resizeGray(gray, width, height, size, size)
+
+	coefficients := make([]float64, 0, lowFreq*lowFreq)
+	for v := 0; v < lowFreq; v++ {
+		for u := 0; u < lowFreq; u++ {
+			coefficients = append(coefficients, dctCoefficient(small, size, u, v))
+		}
+	}
+
+	// 直流分量只反映平均亮度，不參與中位數計算
+	sorted := append([]float64(nil), coefficients[1:]...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	var hash uint64
+	for _, coefficient := range coefficients {
+		hash <<= 1
+		if coefficient > median {
+			hash |= 1
+		}
+	}
+	return hash
+}
+
+// dctCoefficient 計算二維 DCT-II 的 (u, v) 係數
+func dctCoefficient(pixels []float64, size, u, v int) float64 {
+	var sum float64
+	for y := 0; y < size; y++ {
+		cosY := math.Cos(float64((2*y+1)*v) * math.Pi / float64(2*size))
+		for x := 0; x < size; x++ {
+			sum += pixels[y*size+x] * math.Cos(float64((2*x+1)*u)*math.Pi/float64(2*size)) * cosY
+		}
+	}
+	return sum
+}
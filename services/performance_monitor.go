@@ -6,6 +6,8 @@ import (
 	"log"
 	"sync"
 	"time"
+
+	"semantic-text-processor/models"
 )
 
 // QueryPerformanceMonitor provides query performance monitoring (legacy interface)
@@ -22,6 +24,7 @@ type EnhancedPerformanceMonitor interface {
 	
 	// Alert functionality
 	SetAlertThresholds(config *AlertConfig)
+	RecordAlert(alertType, message, severity string, details map[string]interface{})
 	GetAlerts(limit int) []AlertRecord
 	ClearAlerts()
 	
@@ -61,18 +64,26 @@ type AlertRecord struct {
 type PerformanceHealthStatus struct {
 	IsHealthy           bool          `json:"is_healthy"`
 	AverageResponseTime time.Duration `json:"average_response_time"`
+	// P99ResponseTime is the 99th percentile of the last five minutes
+	P99ResponseTime     time.Duration `json:"p99_response_time"`
 	SlowQueryRate       float64       `json:"slow_query_rate"`
 	ErrorRate           float64       `json:"error_rate"`
 	QueriesPerSecond    float64       `json:"queries_per_second"`
 	LastCheck           time.Time     `json:"last_check"`
 }
 
-// QueryStatistics holds performance statistics
+// QueryStatistics holds performance statistics. Percentiles cover every query since the
+// last reset; Windows holds them for the last minute, five minutes and hour ("1m", "5m",
+// "1h").
 type QueryStatistics struct {
 	TotalQueries    int64         `json:"total_queries"`
 	AverageTime     time.Duration `json:"average_time"`
+	P50             time.Duration `json:"p50"`
+	P95             time.Duration `json:"p95"`
+	P99             time.Duration `json:"p99"`
 	SlowQueries     int64         `json:"slow_queries"`
 	QueryTypes      map[string]QueryTypeStats `json:"query_types"`
+	Windows         map[string]LatencyPercentiles `json:"windows,omitempty"`
 	LastReset       time.Time     `json:"last_reset"`
 }
 
@@ -83,15 +94,21 @@ type QueryTypeStats struct {
 	AverageTime time.Duration `json:"average_time"`
 	MinTime     time.Duration `json:"min_time"`
 	MaxTime     time.Duration `json:"max_time"`
+	P50         time.Duration `json:"p50"`
+	P95         time.Duration `json:"p95"`
+	P99         time.Duration `json:"p99"`
 	TotalRows   int64         `json:"total_rows"`
+	// Windows holds the percentiles of the last minute, five minutes and hour
+	Windows map[string]LatencyPercentiles `json:"windows,omitempty"`
 }
 
 // SlowQueryRecord represents a slow query record
 type SlowQueryRecord struct {
 	Query     string                 `json:"query"`
 	Duration  time.Duration          `json:"duration"`
-	Params    map[string]interface{} `json:"params"`
+	Params    map[string]interface{} `json:"params"` // sanitized, see SanitizeQueryParams
 	Timestamp time.Time              `json:"timestamp"`
+	Plan      *models.QueryPlan      `json:"plan,omitempty"` // attached once captured
 }
 
 // InMemoryPerformanceMonitor implements EnhancedPerformanceMonitor using in-memory storage
@@ -110,6 +127,11 @@ type InMemoryPerformanceMonitor struct {
 	ctx            context.Context
 	cancel         context.CancelFunc
 	stopped        bool
+	planCapturer   *QueryPlanCapturer
+	// latencies holds the latency histograms of each query type, overall those of all queries
+	latencies      map[string]*queryLatencies
+	overall        *queryLatencies
+	now            func() time.Time
 }
 
 // NewInMemoryPerformanceMonitor creates a new in-memory performance monitor
@@ -131,6 +153,9 @@ func NewInMemoryPerformanceMonitor(slowThreshold time.Duration, maxSlowQueries i
 		startTime:      time.Now(),
 		ctx:            ctx,
 		cancel:         cancel,
+		latencies:      make(map[string]*queryLatencies),
+		overall:        newQueryLatencies(),
+		now:            time.Now,
 	}
 	
 	// Start background monitoring
@@ -181,6 +206,15 @@ func (m *InMemoryPerformanceMonitor) RecordQuery(queryType string, duration time
 
 	m.stats.QueryTypes[queryType] = typeStats
 
+	latencies, exists := m.latencies[queryType]
+	if !exists {
+		latencies = newQueryLatencies()
+		m.latencies[queryType] = latencies
+	}
+	now := m.now()
+	latencies.record(now, duration)
+	m.overall.record(now, duration)
+
 	// Calculate overall average
 	totalTime := time.Duration(0)
 	for _, stats := range m.stats.QueryTypes {
@@ -226,7 +260,21 @@ func (m *InMemoryPerformanceMonitor) checkQueryAlerts(queryType string, duration
 	}
 }
 
-// RecordSlowQuery records a slow query with details
+// SetQueryPlanCapturer makes the monitor explain slow SQL statements and attach their plans
+// to the slow query records
+func (m *InMemoryPerformanceMonitor) SetQueryPlanCapturer(capturer *QueryPlanCapturer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.planCapturer = capturer
+}
+
+// SlowQueryThreshold returns the duration from which queries count as slow
+func (m *InMemoryPerformanceMonitor) SlowQueryThreshold() time.Duration {
+	return m.slowThreshold
+}
+
+// RecordSlowQuery records a slow query with details. Params are sanitized before they are
+// stored; for SQL statements, params named $1, $2, ... are bound to explain the statement.
 func (m *InMemoryPerformanceMonitor) RecordSlowQuery(query string, duration time.Duration, params map[string]interface{}) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -234,10 +282,19 @@ func (m *InMemoryPerformanceMonitor) RecordSlowQuery(query string, duration time
 	record := SlowQueryRecord{
 		Query:     query,
 		Duration:  duration,
-		Params:    params,
+		Params:    SanitizeQueryParams(params),
 		Timestamp: time.Now(),
 	}
 
+	if m.planCapturer != nil && isSQLStatement(query) {
+		if args := statementArgs(params); args != nil || len(params) == 0 {
+			timestamp := record.Timestamp
+			m.planCapturer.CaptureAsync(query, args, func(plan *models.QueryPlan) {
+				m.attachPlan(query, timestamp, plan)
+			})
+		}
+	}
+
 	// Add to slow queries list
 	m.slowQueries = append(m.slowQueries, record)
 
@@ -254,26 +311,50 @@ func (m *InMemoryPerformanceMonitor) RecordSlowQuery(query string, duration time
 		"warning", map[string]interface{}{
 			"query": query,
 			"duration": duration.String(),
-			"params": params,
+			"params": record.Params,
 			"timestamp": record.Timestamp,
 		})
 }
 
+// attachPlan adds a captured plan to the slow query record it was captured for
+func (m *InMemoryPerformanceMonitor) attachPlan(query string, timestamp time.Time, plan *models.QueryPlan) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i := len(m.slowQueries) - 1; i >= 0; i-- {
+		if m.slowQueries[i].Query == query && m.slowQueries[i].Timestamp.Equal(timestamp) {
+			m.slowQueries[i].Plan = plan
+			return
+		}
+	}
+}
+
 // GetQueryStats returns current query statistics
 func (m *InMemoryPerformanceMonitor) GetQueryStats() QueryStatistics {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	// Create a deep copy to avoid race conditions
+	now := m.now()
+	overall := m.overall.lifetime.percentiles()
 	stats := QueryStatistics{
 		TotalQueries: m.stats.TotalQueries,
 		AverageTime:  m.stats.AverageTime,
+		P50:          overall.P50,
+		P95:          overall.P95,
+		P99:          overall.P99,
 		SlowQueries:  m.stats.SlowQueries,
 		QueryTypes:   make(map[string]QueryTypeStats),
+		Windows:      m.overall.windows.summary(now),
 		LastReset:    m.stats.LastReset,
 	}
 
 	for k, v := range m.stats.QueryTypes {
+		if latencies, ok := m.latencies[k]; ok {
+			percentiles := latencies.lifetime.percentiles()
+			v.P50, v.P95, v.P99 = percentiles.P50, percentiles.P95, percentiles.P99
+			v.Windows = latencies.windows.summary(now)
+		}
 		stats.QueryTypes[k] = v
 	}
 
@@ -329,6 +410,14 @@ func (m *InMemoryPerformanceMonitor) GetAlerts(limit int) []AlertRecord {
 	return result
 }
 
+// RecordAlert raises an alert detected outside query recording, such as connection
+// pool exhaustion. Alerts of the same type are subject to the alert cooldown.
+func (m *InMemoryPerformanceMonitor) RecordAlert(alertType, message, severity string, details map[string]interface{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.addAlert(alertType, message, severity, details)
+}
+
 // ClearAlerts removes all alerts
 func (m *InMemoryPerformanceMonitor) ClearAlerts() {
 	m.mu.Lock()
@@ -347,7 +436,7 @@ func (m *InMemoryPerformanceMonitor) GetPerformanceHealth() PerformanceHealthSta
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	now := time.Now()
+	now := m.now()
 	uptime := now.Sub(m.startTime)
 	
 	// Calculate queries per second
@@ -386,6 +475,7 @@ func (m *InMemoryPerformanceMonitor) GetPerformanceHealth() PerformanceHealthSta
 	return PerformanceHealthStatus{
 		IsHealthy:           isHealthy,
 		AverageResponseTime: m.stats.AverageTime,
+		P99ResponseTime:     m.overall.windows.window(now, 5*time.Minute).quantile(0.99),
 		SlowQueryRate:       slowQueryRate,
 		ErrorRate:           errorRate,
 		QueriesPerSecond:    qps,
@@ -428,6 +518,8 @@ func (m *InMemoryPerformanceMonitor) Reset() {
 	m.alerts = make([]AlertRecord, 0)
 	m.errorCount = 0
 	m.lastAlertTime = make(map[string]time.Time)
+	m.latencies = make(map[string]*queryLatencies)
+	m.overall = newQueryLatencies()
 }
 
 // Stop gracefully shuts down the performance monitor
@@ -523,7 +615,7 @@ func (m *InMemoryPerformanceMonitor) performHealthCheck() {
 
 // getHealthStatusLocked returns health status (must be called with lock held)
 func (m *InMemoryPerformanceMonitor) getHealthStatusLocked() PerformanceHealthStatus {
-	now := time.Now()
+	now := m.now()
 	uptime := now.Sub(m.startTime)
 	
 	var qps float64
@@ -558,6 +650,7 @@ func (m *InMemoryPerformanceMonitor) getHealthStatusLocked() PerformanceHealthSt
 	return PerformanceHealthStatus{
 		IsHealthy:           isHealthy,
 		AverageResponseTime: m.stats.AverageTime,
+		P99ResponseTime:     m.overall.windows.window(now, 5*time.Minute).quantile(0.99),
 		SlowQueryRate:       slowQueryRate,
 		ErrorRate:           errorRate,
 		QueriesPerSecond:    qps,
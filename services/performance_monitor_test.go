@@ -40,6 +40,50 @@ func TestInMemoryPerformanceMonitor_RecordQuery(t *testing.T) {
 	assert.Equal(t, 25*time.Millisecond, insertStats.MaxTime)
 }
 
+func TestInMemoryPerformanceMonitor_Percentiles(t *testing.T) {
+	monitor := NewInMemoryPerformanceMonitor(time.Second, 10)
+	defer monitor.Stop()
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	monitor.now = func() time.Time { return now }
+
+	// An hour ago, a burst of slow queries
+	now = now.Add(-50 * time.Minute)
+	for i := 0; i < 100; i++ {
+		monitor.RecordQuery("search", 800*time.Millisecond, 1)
+	}
+	// Recently, 98 fast queries and two slow ones the average hides
+	now = now.Add(50 * time.Minute)
+	for i := 0; i < 98; i++ {
+		monitor.RecordQuery("search", 10*time.Millisecond, 1)
+	}
+	monitor.RecordQuery("search", 500*time.Millisecond, 1)
+	monitor.RecordQuery("search", 500*time.Millisecond, 1)
+
+	stats := monitor.GetQueryStats()
+	search := stats.QueryTypes["search"]
+	assert.Equal(t, int64(200), search.Count)
+	assert.InEpsilon(t, 800*time.Millisecond, search.P99, 0.01)
+	assert.Equal(t, stats.P99, search.P99)
+
+	recent := search.Windows["1m"]
+	assert.Equal(t, int64(100), recent.Count)
+	assert.InEpsilon(t, 10*time.Millisecond, recent.P50, 0.01)
+	assert.InEpsilon(t, 500*time.Millisecond, recent.P99, 0.01)
+	assert.Equal(t, 500*time.Millisecond, recent.Max)
+	assert.Equal(t, int64(100), search.Windows["5m"].Count)
+	assert.Equal(t, int64(200), search.Windows["1h"].Count)
+
+	// Windows roll forward
+	now = now.Add(2 * time.Minute)
+	stats = monitor.GetQueryStats()
+	assert.Equal(t, int64(0), stats.QueryTypes["search"].Windows["1m"].Count)
+	assert.Equal(t, int64(100), stats.Windows["5m"].Count)
+	assert.InEpsilon(t, 500*time.Millisecond, monitor.GetPerformanceHealth().P99ResponseTime, 0.01)
+
+	monitor.Reset()
+	assert.Equal(t, time.Duration(0), monitor.GetQueryStats().P99)
+}
+
 func TestInMemoryPerformanceMonitor_RecordSlowQuery(t *testing.T) {
 	monitor := NewInMemoryPerformanceMonitor(100*time.Millisecond, 3)
 
@@ -3,6 +3,9 @@ package services
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/rand"
+	"sort"
 	"testing"
 	"time"
 
@@ -219,6 +222,119 @@ func BenchmarkInMemoryMetrics_SetGauge(b *testing.B) {
 	}
 }
 
+// BenchmarkEmbeddingQuantization_Recall reports recall@10 of the halfvec and int8 storage
+// formats against exact float search, on clustered 512-dimensional embeddings
+func BenchmarkEmbeddingQuantization_Recall(b *testing.B) {
+	const (
+		dimensions = 512
+		corpusSize = 5000
+		k          = 10
+	)
+	rng := rand.New(rand.NewSource(42))
+	centers := make([][]float64, 50)
+	for i := range centers {
+		centers[i] = randomUnitVector(rng, dimensions, nil, 1)
+	}
+	corpus := make([][]float64, corpusSize)
+	halves := make([][]float64, corpusSize)
+	codes := make([][]byte, corpusSize)
+	bits := make([]string, corpusSize)
+	for i := range corpus {
+		corpus[i] = randomUnitVector(rng, dimensions, centers[i%len(centers)], 0.6)
+		halves[i] = make([]float64, dimensions)
+		for j, x := range corpus[i] {
+			halves[i][j] = roundToFloat16(x)
+		}
+		codes[i], _ = quantizeInt8(corpus[i])
+		bits[i] = embeddingBits(corpus[i])
+	}
+
+	cosine := func(a, b []float64) float64 {
+		var dot, na, nb float64
+		for i := range a {
+			dot += a[i] * b[i]
+			na += a[i] * a[i]
+			nb += b[i] * b[i]
+		}
+		return dot / math.Sqrt(na*nb)
+	}
+	topK := func(candidates []int, score func(i int) float64, n int) []int {
+		sort.SliceStable(candidates, func(a, b int) bool { return score(candidates[a]) > score(candidates[b]) })
+		if len(candidates) > n {
+			candidates = candidates[:n]
+		}
+		return candidates
+	}
+	all := func() []int {
+		ids := make([]int, corpusSize)
+		for i := range ids {
+			ids[i] = i
+		}
+		return ids
+	}
+	recall := func(exact, found []int) float64 {
+		hits := 0
+		for _, id := range found {
+			for _, want := range exact {
+				if id == want {
+					hits++
+					break
+				}
+			}
+		}
+		return float64(hits) / float64(len(exact))
+	}
+
+	var halfRecall, int8Recall float64
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		query := randomUnitVector(rng, dimensions, centers[n%len(centers)], 0.6)
+		exact := topK(all(), func(i int) float64 { return cosine(query, corpus[i]) }, k)
+
+		halfRecall += recall(exact, topK(all(), func(i int) float64 { return cosine(query, halves[i]) }, k))
+
+		// Hamming candidates from the bit index, re-scored with the int8 codes
+		queryBits := embeddingBits(query)
+		hamming := make([]float64, corpusSize)
+		for i := range bits {
+			for j := range queryBits {
+				if bits[i][j] != queryBits[j] {
+					hamming[i]--
+				}
+			}
+		}
+		candidates := topK(all(), func(i int) float64 { return hamming[i] }, k*int8SearchOversample)
+		int8Recall += recall(exact, topK(candidates, func(i int) float64 { return int8Similarity(query, codes[i]) }, k))
+	}
+	b.ReportMetric(halfRecall/float64(b.N), "halfvec-recall@10")
+	b.ReportMetric(int8Recall/float64(b.N), "int8-recall@10")
+}
+
+// randomUnitVector returns a unit vector around center, or uniformly random without one
+func randomUnitVector(rng *rand.Rand, dimensions int, center []float64, spread float64) []float64 {
+	vector := make([]float64, dimensions)
+	var norm float64
+	for i := range vector {
+		vector[i] = rng.NormFloat64() * spread / math.Sqrt(float64(dimensions))
+		if center != nil {
+			vector[i] += center[i]
+		}
+		norm += vector[i] * vector[i]
+	}
+	for i := range vector {
+		vector[i] /= math.Sqrt(norm)
+	}
+	return vector
+}
+
+// roundToFloat16 rounds to the 10-bit mantissa of a halfvec component; embedding
+// components are far from float16's range limits
+func roundToFloat16(x float64) float64 {
+	bits := math.Float32bits(float32(x))
+	bits = (bits + 0x1000) &^ 0x1fff
+	return float64(math.Float32frombits(bits))
+}
+
 // Benchmark functions removed to avoid duplication with cache_test.go
 
 // Load test for concurrent operations
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// piiScanningChunkService scans chunk contents for personal data on every write and
+// applies the policy of the workspace the request belongs to. Findings are recorded in
+// the chunk's metadata under models.PIIMetadataKey.
+type piiScanningChunkService struct {
+	UnifiedChunkService
+	scanner *PIIScanner
+}
+
+// NewPIIScanningChunkService wraps a chunk service with personal data scanning
+func NewPIIScanningChunkService(base UnifiedChunkService, scanner *PIIScanner) UnifiedChunkService {
+	return &piiScanningChunkService{
+		UnifiedChunkService: base,
+		scanner:             scanner,
+	}
+}
+
+// CreateChunk scans the chunk's contents before creating it
+func (s *piiScanningChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.scanChunk(ctx, chunk); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.CreateChunk(ctx, chunk)
+}
+
+// UpdateChunk scans the chunk's contents before updating it
+func (s *piiScanningChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.scanChunk(ctx, chunk); err != nil {
+		return err
+	}
+	return s.UnifiedChunkService.UpdateChunk(ctx, chunk)
+}
+
+// BatchCreateChunks scans every chunk; one rejected chunk rejects the batch
+func (s *piiScanningChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for i := range chunks {
+		if err := s.scanChunk(ctx, &chunks[i]); err != nil {
+			return fmt.Errorf("chunk %d: %w", i, err)
+		}
+	}
+	return s.UnifiedChunkService.BatchCreateChunks(ctx, chunks)
+}
+
+// BatchUpdateChunks scans every chunk; one rejected chunk rejects the batch
+func (s *piiScanningChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	for i := range chunks {
+		if err := s.scanChunk(ctx, &chunks[i]); err != nil {
+			return fmt.Errorf("chunk %d: %w", i, err)
+		}
+	}
+	return s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks)
+}
+
+// PatchChunk scans patched contents. A clean scan clears findings recorded for the
+// previous contents.
+func (s *piiScanningChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	if patch == nil || patch.Contents == nil {
+		return s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	}
+
+	result, err := s.scanner.Apply(WorkspaceFromContext(ctx), *patch.Contents)
+	if err != nil {
+		return nil, err
+	}
+
+	scanned := *patch
+	scanned.Contents = &result.Text
+	scanned.Metadata = make(map[string]interface{}, len(patch.Metadata)+1)
+	for key, value := range patch.Metadata {
+		scanned.Metadata[key] = value
+	}
+	if len(result.Findings) > 0 {
+		scanned.Metadata[models.PIIMetadataKey] = piiScanMetadata(WorkspaceFromContext(ctx), result)
+	} else if current, err := s.UnifiedChunkService.GetChunk(ctx, chunkID); err == nil && current.Metadata[models.PIIMetadataKey] != nil {
+		// Metadata patches are merged, so a JSON null is what removes the old findings
+		scanned.Metadata[models.PIIMetadataKey] = nil
+	}
+
+	return s.UnifiedChunkService.PatchChunk(ctx, chunkID, &scanned)
+}
+
+// scanChunk applies the workspace's policy to the chunk's contents and records the
+// findings in its metadata, replacing findings recorded for earlier contents
+func (s *piiScanningChunkService) scanChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	workspace := WorkspaceFromContext(ctx)
+	result, err := s.scanner.Apply(workspace, chunk.Contents)
+	if err != nil {
+		return err
+	}
+
+	chunk.Contents = result.Text
+	if len(result.Findings) == 0 {
+		delete(chunk.Metadata, models.PIIMetadataKey)
+		return nil
+	}
+	if chunk.Metadata == nil {
+		chunk.Metadata = make(map[string]interface{})
+	}
+	chunk.Metadata[models.PIIMetadataKey] = piiScanMetadata(workspace, result)
+	return nil
+}
+
+// piiScanMetadata converts a scan result to the JSON object stored in chunk metadata
+func piiScanMetadata(workspace string, result *PIIScanResult) map[string]interface{} {
+	record := models.PIIScanRecord{
+		Policy:    result.Policy,
+		Workspace: workspace,
+		Findings:  result.Findings,
+		Counts:    make(map[models.PIIType]int),
+		ScannedAt: time.Now().UTC(),
+	}
+	for _, finding := range result.Findings {
+		record.Counts[finding.Type]++
+	}
+
+	// Stored as a plain object so it reads back the same from the database and caches
+	data, _ := json.Marshal(record)
+	var metadata map[string]interface{}
+	json.Unmarshal(data, &metadata)
+	return metadata
+}
+
+// PIIComplianceService reports the personal data findings recorded in chunk metadata
+type PIIComplianceService interface {
+	// Report counts findings by type and policy. An empty workspace covers all
+	// workspaces; a zero since covers all scans.
+	Report(ctx context.Context, workspace string, since time.Time) (*models.PIIComplianceReport, error)
+}
+
+// piiComplianceService implements PIIComplianceService on the chunks table
+type piiComplianceService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+}
+
+// NewPIIComplianceService creates a compliance report service
+func NewPIIComplianceService(db *sql.DB, monitor QueryPerformanceMonitor) PIIComplianceService {
+	return &piiComplianceService{db: db, monitor: monitor}
+}
+
+// Report aggregates the findings recorded in chunk metadata
+func (s *piiComplianceService) Report(ctx context.Context, workspace string, since time.Time) (*models.PIIComplianceReport, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("pii_compliance_report", time.Since(start), 1)
+	}()
+
+	report := &models.PIIComplianceReport{
+		Workspace:   workspace,
+		Entries:     []models.PIIComplianceEntry{},
+		GeneratedAt: time.Now(),
+	}
+	if !since.IsZero() {
+		report.Since = &since
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT f->>'type', c.metadata->'pii'->>'policy', COUNT(*), COUNT(DISTINCT c.chunk_id)
+		FROM chunks c, jsonb_array_elements(c.metadata->'pii'->'findings') AS f
+		WHERE jsonb_typeof(c.metadata->'pii') = 'object'
+		  AND ($1 = '' OR c.metadata->'pii'->>'workspace' = $1)
+		  AND (c.metadata->'pii'->>'scanned_at')::timestamptz >= $2
+		GROUP BY 1, 2
+		ORDER BY 3 DESC, 1, 2`,
+		workspace, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pii findings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.PIIComplianceEntry
+		if err := rows.Scan(&entry.Type, &entry.Policy, &entry.Findings, &entry.Chunks); err != nil {
+			return nil, fmt.Errorf("failed to scan pii findings: %w", err)
+		}
+		report.Entries = append(report.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read pii findings: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM chunks
+		WHERE jsonb_typeof(metadata->'pii') = 'object'
+		  AND ($1 = '' OR metadata->'pii'->>'workspace' = $1)
+		  AND (metadata->'pii'->>'scanned_at')::timestamptz >= $2`,
+		workspace, since).Scan(&report.FlaggedChunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count flagged chunks: %w", err)
+	}
+
+	return report, nil
+}
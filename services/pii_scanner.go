@@ -0,0 +1,306 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ErrPIIRejected is returned when the workspace's policy refuses contents containing
+// personal data
+var ErrPIIRejected = errors.New("contents contain personal data")
+
+var (
+	emailPattern      = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)
+	creditCardPattern = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+	ssnPattern        = regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`)
+	taiwanIDPattern   = regexp.MustCompile(`\b[A-Z][12]\d{8}\b`)
+	phonePattern      = regexp.MustCompile(`\+?\(?\d[\d ().\-]{7,20}\d`)
+)
+
+// piiDetector finds one type of personal data
+type piiDetector struct {
+	piiType models.PIIType
+	find    func(text string) [][]int
+}
+
+var piiDetectors = map[models.PIIType]piiDetector{
+	models.PIITypeCreditCard: {models.PIITypeCreditCard, findCreditCards},
+	models.PIITypeIDNumber:   {models.PIITypeIDNumber, findIDNumbers},
+	models.PIITypeEmail:      {models.PIITypeEmail, func(text string) [][]int { return emailPattern.FindAllStringIndex(text, -1) }},
+	models.PIITypePhone:      {models.PIITypePhone, findPhoneNumbers},
+}
+
+// piiDetectionOrder runs the most specific detectors first; a span claimed by one is
+// not reported again by a later one, so card and ID numbers are not also phones
+var piiDetectionOrder = []models.PIIType{
+	models.PIITypeCreditCard,
+	models.PIITypeIDNumber,
+	models.PIITypeEmail,
+	models.PIITypePhone,
+}
+
+// PIIScanResult is the outcome of applying a workspace's policy to contents
+type PIIScanResult struct {
+	Policy models.PIIPolicy
+	// Text is the contents to store: redacted under the redact policy, unchanged otherwise
+	Text     string
+	Findings []models.PIIFinding
+}
+
+// PIIScanner detects emails, phone numbers, ID numbers and credit card numbers in text
+// and applies a redact, flag or reject policy chosen per workspace
+type PIIScanner struct {
+	detectors         []piiDetector
+	defaultPolicy     models.PIIPolicy
+	workspacePolicies map[string]models.PIIPolicy
+}
+
+// NewPIIScanner creates a scanner from configuration
+func NewPIIScanner(cfg config.PIIConfig) (*PIIScanner, error) {
+	scanner := &PIIScanner{
+		defaultPolicy:     models.PIIPolicy(cfg.DefaultPolicy),
+		workspacePolicies: make(map[string]models.PIIPolicy),
+	}
+	if scanner.defaultPolicy == "" {
+		scanner.defaultPolicy = models.PIIPolicyFlag
+	}
+	if !validPIIPolicy(scanner.defaultPolicy) {
+		return nil, fmt.Errorf("unsupported pii policy %q", cfg.DefaultPolicy)
+	}
+
+	for _, entry := range cfg.WorkspacePolicies {
+		workspace, policy, ok := strings.Cut(entry, ":")
+		workspace, policy = strings.TrimSpace(workspace), strings.TrimSpace(policy)
+		if !ok || workspace == "" || !validPIIPolicy(models.PIIPolicy(policy)) {
+			return nil, fmt.Errorf("invalid pii workspace policy %q", entry)
+		}
+		scanner.workspacePolicies[workspace] = models.PIIPolicy(policy)
+	}
+
+	enabled := make(map[models.PIIType]bool)
+	for _, name := range cfg.Detectors {
+		if _, ok := piiDetectors[models.PIIType(name)]; !ok {
+			return nil, fmt.Errorf("unsupported pii detector %q", name)
+		}
+		enabled[models.PIIType(name)] = true
+	}
+	for _, piiType := range piiDetectionOrder {
+		if len(enabled) == 0 || enabled[piiType] {
+			scanner.detectors = append(scanner.detectors, piiDetectors[piiType])
+		}
+	}
+
+	return scanner, nil
+}
+
+func validPIIPolicy(policy models.PIIPolicy) bool {
+	switch policy {
+	case models.PIIPolicyRedact, models.PIIPolicyFlag, models.PIIPolicyReject:
+		return true
+	}
+	return false
+}
+
+// Policy returns the policy applied to a workspace's contents
+func (s *PIIScanner) Policy(workspace string) models.PIIPolicy {
+	if policy, ok := s.workspacePolicies[workspace]; ok {
+		return policy
+	}
+	return s.defaultPolicy
+}
+
+// Scan returns the personal data found in text, ordered by position
+func (s *PIIScanner) Scan(text string) []models.PIIFinding {
+	var findings []models.PIIFinding
+	for _, detector := range s.detectors {
+		for _, span := range detector.find(text) {
+			if overlapsFinding(findings, span[0], span[1]) {
+				continue
+			}
+			findings = append(findings, models.PIIFinding{
+				Type:   detector.piiType,
+				Start:  span[0],
+				End:    span[1],
+				Masked: maskPII(detector.piiType, text[span[0]:span[1]]),
+			})
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Start < findings[j].Start })
+	return findings
+}
+
+// Apply scans text under the workspace's policy. Rejected contents return an error
+// wrapping ErrPIIRejected; redacted contents have every finding replaced by a
+// placeholder, with the findings' offsets pointing at the placeholders.
+func (s *PIIScanner) Apply(workspace, text string) (*PIIScanResult, error) {
+	policy := s.Policy(workspace)
+	findings := s.Scan(text)
+	result := &PIIScanResult{Policy: policy, Text: text, Findings: findings}
+	if len(findings) == 0 {
+		return result, nil
+	}
+
+	switch policy {
+	case models.PIIPolicyReject:
+		return nil, fmt.Errorf("%w: %s", ErrPIIRejected, strings.Join(piiTypeNames(findings), ", "))
+	case models.PIIPolicyRedact:
+		var redacted strings.Builder
+		last := 0
+		for i, finding := range findings {
+			redacted.WriteString(text[last:finding.Start])
+			placeholder := "[REDACTED:" + strings.ToUpper(string(finding.Type)) + "]"
+			result.Findings[i].Start = redacted.Len()
+			redacted.WriteString(placeholder)
+			result.Findings[i].End = redacted.Len()
+			last = finding.End
+		}
+		redacted.WriteString(text[last:])
+		result.Text = redacted.String()
+	}
+
+	return result, nil
+}
+
+// piiTypeNames lists the distinct types of findings in order of first appearance
+func piiTypeNames(findings []models.PIIFinding) []string {
+	seen := make(map[models.PIIType]bool)
+	var names []string
+	for _, finding := range findings {
+		if !seen[finding.Type] {
+			seen[finding.Type] = true
+			names = append(names, string(finding.Type))
+		}
+	}
+	return names
+}
+
+func overlapsFinding(findings []models.PIIFinding, start, end int) bool {
+	for _, finding := range findings {
+		if start < finding.End && finding.Start < end {
+			return true
+		}
+	}
+	return false
+}
+
+// findCreditCards matches 13-19 digit numbers that pass the Luhn check
+func findCreditCards(text string) [][]int {
+	var spans [][]int
+	for _, span := range creditCardPattern.FindAllStringIndex(text, -1) {
+		digits := digitsOf(text[span[0]:span[1]])
+		if len(digits) >= 13 && len(digits) <= 19 && luhnValid(digits) {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// findIDNumbers matches US social security numbers and Taiwan national ID numbers
+func findIDNumbers(text string) [][]int {
+	var spans [][]int
+	for _, span := range ssnPattern.FindAllStringIndex(text, -1) {
+		// Area numbers 000, 666 and 900-999 are never issued
+		area := text[span[0] : span[0]+3]
+		if area != "000" && area != "666" && area[0] != '9' && text[span[0]+4:span[0]+6] != "00" && text[span[1]-4:span[1]] != "0000" {
+			spans = append(spans, span)
+		}
+	}
+	for _, span := range taiwanIDPattern.FindAllStringIndex(text, -1) {
+		if taiwanIDValid(text[span[0]:span[1]]) {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// findPhoneNumbers matches runs of 9-15 digits with phone punctuation. Eight digits or
+// fewer are left alone so dates such as 2024-01-15 are not reported.
+func findPhoneNumbers(text string) [][]int {
+	var spans [][]int
+	for _, span := range phonePattern.FindAllStringIndex(text, -1) {
+		// Do not start or end inside a longer word or number
+		if span[0] > 0 && isWordByte(text[span[0]-1]) || span[1] < len(text) && isWordByte(text[span[1]]) {
+			continue
+		}
+		digits := digitsOf(text[span[0]:span[1]])
+		if len(digits) >= 9 && len(digits) <= 15 {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+func digitsOf(s string) string {
+	var digits strings.Builder
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	return digits.String()
+}
+
+// luhnValid checks the card number checksum
+func luhnValid(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// taiwanIDLetterCodes maps the first letter of a Taiwan national ID to its region code
+var taiwanIDLetterCodes = map[byte]int{
+	'A': 10, 'B': 11, 'C': 12, 'D': 13, 'E': 14, 'F': 15, 'G': 16, 'H': 17, 'I': 34,
+	'J': 18, 'K': 19, 'L': 20, 'M': 21, 'N': 22, 'O': 35, 'P': 23, 'Q': 24, 'R': 25,
+	'S': 26, 'T': 27, 'U': 28, 'V': 29, 'W': 32, 'X': 30, 'Y': 31, 'Z': 33,
+}
+
+// taiwanIDValid checks the Taiwan national ID checksum
+func taiwanIDValid(id string) bool {
+	code, ok := taiwanIDLetterCodes[id[0]]
+	if !ok {
+		return false
+	}
+	sum := code/10 + (code%10)*9
+	for i := 1; i < 9; i++ {
+		sum += int(id[i]-'0') * (9 - i)
+	}
+	sum += int(id[9] - '0')
+	return sum%10 == 0
+}
+
+// maskPII hides a matched value, keeping only enough to recognize it
+func maskPII(piiType models.PIIType, value string) string {
+	switch piiType {
+	case models.PIITypeEmail:
+		local, domain, _ := strings.Cut(value, "@")
+		return local[:1] + "***@" + domain
+	default:
+		digits := digitsOf(value)
+		if len(digits) <= 4 {
+			return strings.Repeat("*", len(digits))
+		}
+		return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+	}
+}
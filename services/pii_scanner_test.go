@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func findingTypes(findings []models.PIIFinding) []models.PIIType {
+	types := make([]models.PIIType, len(findings))
+	for i, finding := range findings {
+		types[i] = finding.Type
+	}
+	return types
+}
+
+func TestPIIScanner_Scan(t *testing.T) {
+	scanner, err := NewPIIScanner(config.PIIConfig{})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		text string
+		want []models.PIIType
+	}{
+		{"email", "write to jane.doe@example.co.uk today", []models.PIIType{models.PIITypeEmail}},
+		{"phone", "call +1 (555) 123-4567 or 0912-345-678", []models.PIIType{models.PIITypePhone, models.PIITypePhone}},
+		{"credit card", "card 4111 1111 1111 1111 on file", []models.PIIType{models.PIITypeCreditCard}},
+		{"card failing luhn", "ref 4111 1111 1111 1112", nil},
+		{"ssn", "SSN 123-45-6789", []models.PIIType{models.PIITypeIDNumber}},
+		{"taiwan id", "身分證 A123456789", []models.PIIType{models.PIITypeIDNumber}},
+		{"invalid taiwan id", "code A123456788", nil},
+		{"dates are not phones", "met on 2024-01-15 and 2024-02-01", nil},
+		{"mixed in order", "jane@example.com, 555-123-4567", []models.PIIType{models.PIITypeEmail, models.PIITypePhone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := scanner.Scan(tt.text)
+			if tt.want == nil {
+				assert.Empty(t, findings)
+				return
+			}
+			assert.Equal(t, tt.want, findingTypes(findings))
+		})
+	}
+
+	findings := scanner.Scan("card 4111111111111111")
+	require.Len(t, findings, 1)
+	assert.Equal(t, "************1111", findings[0].Masked)
+	assert.Equal(t, "4111111111111111", "card 4111111111111111"[findings[0].Start:findings[0].End])
+}
+
+func TestPIIScanner_Policies(t *testing.T) {
+	scanner, err := NewPIIScanner(config.PIIConfig{
+		DefaultPolicy:     "flag",
+		WorkspacePolicies: []string{"legal:reject", "support: redact"},
+		Detectors:         []string{"email", "phone"},
+	})
+	require.NoError(t, err)
+	text := "contact jane@example.com or 555-123-4567, card 4111 1111 1111 1111"
+
+	flagged, err := scanner.Apply("default", text)
+	require.NoError(t, err)
+	assert.Equal(t, models.PIIPolicyFlag, flagged.Policy)
+	assert.Equal(t, text, flagged.Text)
+	assert.Equal(t, []models.PIIType{models.PIITypeEmail, models.PIITypePhone}, findingTypes(flagged.Findings), "credit card detector is disabled")
+
+	redacted, err := scanner.Apply("support", text)
+	require.NoError(t, err)
+	assert.Equal(t, "contact [REDACTED:EMAIL] or [REDACTED:PHONE], card 4111 1111 1111 1111", redacted.Text)
+	for _, finding := range redacted.Findings {
+		assert.Contains(t, redacted.Text[finding.Start:finding.End], "[REDACTED:")
+	}
+
+	_, err = scanner.Apply("legal", text)
+	assert.ErrorIs(t, err, ErrPIIRejected)
+	clean, err := scanner.Apply("legal", "nothing personal")
+	require.NoError(t, err)
+	assert.Empty(t, clean.Findings)
+
+	_, err = NewPIIScanner(config.PIIConfig{WorkspacePolicies: []string{"legal:shred"}})
+	assert.Error(t, err)
+	_, err = NewPIIScanner(config.PIIConfig{Detectors: []string{"passport"}})
+	assert.Error(t, err)
+}
+
+func TestPIIScanningChunkService(t *testing.T) {
+	scanner, err := NewPIIScanner(config.PIIConfig{DefaultPolicy: "flag", WorkspacePolicies: []string{"support:redact", "legal:reject"}})
+	require.NoError(t, err)
+	store := newMemoryChunkService()
+	service := NewPIIScanningChunkService(store, scanner)
+
+	flagged := &models.UnifiedChunkRecord{ChunkID: "a", Contents: "email jane@example.com", Metadata: map[string]interface{}{}}
+	require.NoError(t, service.CreateChunk(context.Background(), flagged))
+	assert.Equal(t, "email jane@example.com", store.chunks["a"].Contents)
+	record := store.chunks["a"].Metadata[models.PIIMetadataKey].(map[string]interface{})
+	assert.Equal(t, "flag", record["policy"])
+	assert.Equal(t, DefaultWorkspace, record["workspace"])
+	assert.Equal(t, map[string]interface{}{"email": float64(1)}, record["counts"])
+	assert.NotContains(t, record["findings"].([]interface{})[0].(map[string]interface{})["masked"], "jane")
+
+	support := WithWorkspace(context.Background(), "support")
+	redacted := &models.UnifiedChunkRecord{ChunkID: "b", Contents: "call 555-123-4567"}
+	require.NoError(t, service.CreateChunk(support, redacted))
+	assert.Equal(t, "call [REDACTED:PHONE]", store.chunks["b"].Contents)
+
+	legal := WithWorkspace(context.Background(), "legal")
+	err = service.BatchCreateChunks(legal, []models.UnifiedChunkRecord{
+		{ChunkID: "c", Contents: "fine"},
+		{ChunkID: "d", Contents: "SSN 123-45-6789"},
+	})
+	assert.ErrorIs(t, err, ErrPIIRejected)
+	assert.NotContains(t, store.chunks, "c", "a rejected batch writes nothing")
+
+	// Patching in clean contents clears the recorded findings
+	clean := "no personal data"
+	_, err = service.PatchChunk(context.Background(), "a", &models.ChunkPatch{Contents: &clean})
+	require.NoError(t, err)
+	pii, recorded := store.chunks["a"].Metadata[models.PIIMetadataKey]
+	assert.True(t, recorded)
+	assert.Nil(t, pii)
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultProbeTimeout        = 2 * time.Second
+	defaultEmbeddingProbeCache = 5 * time.Minute
+)
+
+// ProbeCheck is the result of checking one dependency for a probe
+type ProbeCheck struct {
+	Status    HealthStatus           `json:"status"`
+	Critical  bool                   `json:"critical"`
+	LatencyMS float64                `json:"latency_ms"`
+	Message   string                 `json:"message,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// ProbeReport answers a liveness, readiness or startup probe. OK decides the HTTP status:
+// only critical dependencies can fail a probe; the others degrade it.
+type ProbeReport struct {
+	Probe     string                `json:"probe"`
+	OK        bool                  `json:"ok"`
+	Status    HealthStatus          `json:"status"`
+	Timestamp time.Time             `json:"timestamp"`
+	Uptime    string                `json:"uptime"`
+	Version   string                `json:"version,omitempty"`
+	StartedAt *time.Time            `json:"started_at,omitempty"`
+	Draining  bool                  `json:"draining,omitempty"`
+	Checks    map[string]ProbeCheck `json:"checks,omitempty"`
+}
+
+// probeCheck is a dependency checked by readiness and, if critical, startup probes
+type probeCheck struct {
+	checker  HealthChecker
+	critical bool
+}
+
+// ProbeService answers Kubernetes-style probes. Liveness checks only that the process
+// responds; readiness checks every dependency concurrently; startup checks the critical
+// dependencies until they have all been reachable once and then always succeeds.
+// Once the process starts shutting down, readiness fails so no new traffic is routed here.
+type ProbeService struct {
+	version   string
+	startTime time.Time
+	timeout   time.Duration
+	checks    []probeCheck
+	draining  atomic.Bool
+
+	mu        sync.Mutex
+	startedAt *time.Time
+}
+
+// NewProbeService creates a probe service. timeout bounds each dependency check.
+func NewProbeService(version string, timeout time.Duration) *ProbeService {
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	return &ProbeService{
+		version:   version,
+		startTime: time.Now(),
+		timeout:   timeout,
+	}
+}
+
+// AddCheck adds a dependency; a critical dependency that is unhealthy fails readiness
+// and startup
+func (p *ProbeService) AddCheck(checker HealthChecker, critical bool) {
+	p.checks = append(p.checks, probeCheck{checker: checker, critical: critical})
+}
+
+// Liveness reports that the process is serving requests
+func (p *ProbeService) Liveness() ProbeReport {
+	return p.report("liveness", true, HealthStatusHealthy, nil)
+}
+
+// StartDraining makes readiness fail for the rest of the process's life
+func (p *ProbeService) StartDraining() {
+	p.draining.Store(true)
+}
+
+// Draining reports whether the process is shutting down
+func (p *ProbeService) Draining() bool {
+	return p.draining.Load()
+}
+
+// Readiness checks every dependency, failing without checking them while draining
+func (p *ProbeService) Readiness(ctx context.Context) ProbeReport {
+	if p.Draining() {
+		report := p.report("readiness", false, HealthStatusUnhealthy, nil)
+		report.Draining = true
+		return report
+	}
+
+	checks, status, ok := p.run(ctx, false)
+	if ok {
+		p.markStarted()
+	}
+	return p.report("readiness", ok, status, checks)
+}
+
+// Startup checks the critical dependencies until they have all been reachable
+func (p *ProbeService) Startup(ctx context.Context) ProbeReport {
+	p.mu.Lock()
+	started := p.startedAt != nil
+	p.mu.Unlock()
+	if started {
+		return p.report("startup", true, HealthStatusHealthy, nil)
+	}
+
+	checks, status, ok := p.run(ctx, true)
+	if ok {
+		p.markStarted()
+	}
+	return p.report("startup", ok, status, checks)
+}
+
+func (p *ProbeService) markStarted() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.startedAt == nil {
+		now := time.Now()
+		p.startedAt = &now
+	}
+}
+
+// run checks the dependencies concurrently. The probe fails if a critical dependency is
+// unhealthy and is degraded if any other check is not healthy.
+func (p *ProbeService) run(ctx context.Context, criticalOnly bool) (map[string]ProbeCheck, HealthStatus, bool) {
+	results := make(map[string]ProbeCheck, len(p.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range p.checks {
+		if criticalOnly && !check.critical {
+			continue
+		}
+		wg.Add(1)
+		go func(check probeCheck) {
+			defer wg.Done()
+			health := checkComponentWithTimeout(ctx, check.checker, p.timeout)
+			mu.Lock()
+			results[check.checker.Name()] = ProbeCheck{
+				Status:    health.Status,
+				Critical:  check.critical,
+				LatencyMS: float64(health.Duration.Microseconds()) / 1000,
+				Message:   health.Message,
+				Details:   health.Details,
+			}
+			mu.Unlock()
+		}(check)
+	}
+	wg.Wait()
+
+	status := HealthStatusHealthy
+	ok := true
+	for _, result := range results {
+		if result.Status == HealthStatusHealthy {
+			continue
+		}
+		if result.Critical && result.Status == HealthStatusUnhealthy {
+			ok = false
+		}
+		status = HealthStatusDegraded
+	}
+	if !ok {
+		status = HealthStatusUnhealthy
+	}
+	return results, status, ok
+}
+
+func (p *ProbeService) report(probe string, ok bool, status HealthStatus, checks map[string]ProbeCheck) ProbeReport {
+	p.mu.Lock()
+	startedAt := p.startedAt
+	p.mu.Unlock()
+	return ProbeReport{
+		Probe:     probe,
+		OK:        ok,
+		Status:    status,
+		Timestamp: time.Now(),
+		Uptime:    time.Since(p.startTime).Round(time.Second).String(),
+		Version:   p.version,
+		StartedAt: startedAt,
+		Checks:    checks,
+	}
+}
+
+// EmbeddingHealthChecker checks the embedding provider by embedding a short text. Every
+// check is a billed request, so a successful result is reused for a while instead of
+// calling the provider on each probe; failures are checked again every time.
+type EmbeddingHealthChecker struct {
+	name       string
+	embeddings EmbeddingService
+	cacheFor   time.Duration
+
+	mu        sync.Mutex
+	last      ComponentHealth
+	checkedAt time.Time
+}
+
+// NewEmbeddingHealthChecker creates an embedding provider health checker that reuses
+// successful results for cacheFor
+func NewEmbeddingHealthChecker(name string, embeddings EmbeddingService, cacheFor time.Duration) *EmbeddingHealthChecker {
+	if cacheFor <= 0 {
+		cacheFor = defaultEmbeddingProbeCache
+	}
+	return &EmbeddingHealthChecker{
+		name:       name,
+		embeddings: embeddings,
+		cacheFor:   cacheFor,
+	}
+}
+
+// Name returns the checker name
+func (e *EmbeddingHealthChecker) Name() string {
+	return e.name
+}
+
+// Check embeds a probe text unless a recent result can be reused
+func (e *EmbeddingHealthChecker) Check(ctx context.Context) ComponentHealth {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.last.Status == HealthStatusHealthy && time.Since(e.checkedAt) < e.cacheFor {
+		health := e.last
+		health.Details = map[string]interface{}{
+			"cached":     true,
+			"checked_at": e.checkedAt.Format(time.RFC3339),
+		}
+		return health
+	}
+
+	start := time.Now()
+	health := ComponentHealth{Name: e.name}
+	vector, err := e.embeddings.GenerateEmbedding(ctx, "health check")
+	switch {
+	case err != nil:
+		health.Status = HealthStatusUnhealthy
+		health.Message = err.Error()
+	case len(vector) == 0:
+		health.Status = HealthStatusUnhealthy
+		health.Message = "Embedding provider returned an empty vector"
+	default:
+		health.Status = HealthStatusHealthy
+		health.Message = "Embedding provider reachable"
+		health.Details = map[string]interface{}{"dimensions": len(vector)}
+	}
+	health.Timestamp = time.Now()
+	health.Duration = time.Since(start)
+
+	e.last = health
+	e.checkedAt = health.Timestamp
+	return health
+}
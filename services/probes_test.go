@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubChecker reports a fixed status and counts its checks
+type stubChecker struct {
+	name   string
+	status HealthStatus
+	delay  time.Duration
+	checks int
+}
+
+func (c *stubChecker) Name() string { return c.name }
+
+func (c *stubChecker) Check(ctx context.Context) ComponentHealth {
+	c.checks++
+	if c.delay > 0 {
+		select {
+		case <-time.After(c.delay):
+		case <-ctx.Done():
+		}
+	}
+	return ComponentHealth{Name: c.name, Status: c.status}
+}
+
+func TestProbeService_Readiness(t *testing.T) {
+	database := &stubChecker{name: "postgres", status: HealthStatusHealthy}
+	cache := &stubChecker{name: "cache", status: HealthStatusUnhealthy}
+	probes := NewProbeService("test", time.Second)
+	probes.AddCheck(database, true)
+	probes.AddCheck(cache, false)
+	ctx := context.Background()
+
+	report := probes.Readiness(ctx)
+	assert.True(t, report.OK, "optional dependencies only degrade readiness")
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+	require.Len(t, report.Checks, 2)
+	assert.True(t, report.Checks["postgres"].Critical)
+	assert.Equal(t, HealthStatusUnhealthy, report.Checks["cache"].Status)
+
+	database.status = HealthStatusUnhealthy
+	report = probes.Readiness(ctx)
+	assert.False(t, report.OK)
+	assert.Equal(t, HealthStatusUnhealthy, report.Status)
+
+	live := probes.Liveness()
+	assert.True(t, live.OK)
+	assert.Empty(t, live.Checks, "liveness does not check dependencies")
+}
+
+func TestProbeService_StartupLatches(t *testing.T) {
+	database := &stubChecker{name: "postgres", status: HealthStatusUnhealthy}
+	embedding := &stubChecker{name: "embedding", status: HealthStatusUnhealthy}
+	probes := NewProbeService("test", time.Second)
+	probes.AddCheck(database, true)
+	probes.AddCheck(embedding, false)
+	ctx := context.Background()
+
+	report := probes.Startup(ctx)
+	assert.False(t, report.OK)
+	assert.Nil(t, report.StartedAt)
+	assert.NotContains(t, report.Checks, "embedding", "startup waits only for critical dependencies")
+
+	database.status = HealthStatusHealthy
+	report = probes.Startup(ctx)
+	assert.True(t, report.OK)
+	require.NotNil(t, report.StartedAt)
+
+	database.status = HealthStatusUnhealthy
+	checks := database.checks
+	report = probes.Startup(ctx)
+	assert.True(t, report.OK, "startup stays complete once reached")
+	assert.Equal(t, checks, database.checks)
+}
+
+func TestProbeService_TimesOutSlowChecks(t *testing.T) {
+	probes := NewProbeService("test", 20*time.Millisecond)
+	probes.AddCheck(&stubChecker{name: "supabase", status: HealthStatusHealthy, delay: time.Second}, true)
+
+	start := time.Now()
+	report := probes.Readiness(context.Background())
+	assert.False(t, report.OK)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, "Health check timed out", report.Checks["supabase"].Message)
+}
+
+// countingEmbeddings counts embedding requests and fails while err is set
+type countingEmbeddings struct {
+	EmbeddingService
+	calls int
+	err   error
+}
+
+func (e *countingEmbeddings) GenerateEmbedding(ctx context.Context, text string) ([]float64, error) {
+	e.calls++
+	if e.err != nil {
+		return nil, e.err
+	}
+	return []float64{0.1, 0.2, 0.3}, nil
+}
+
+func TestEmbeddingHealthChecker_ReusesSuccess(t *testing.T) {
+	embeddings := &countingEmbeddings{err: errors.New("provider down")}
+	checker := NewEmbeddingHealthChecker("embedding", embeddings, time.Hour)
+	ctx := context.Background()
+
+	assert.Equal(t, HealthStatusUnhealthy, checker.Check(ctx).Status)
+	assert.Equal(t, HealthStatusUnhealthy, checker.Check(ctx).Status)
+	assert.Equal(t, 2, embeddings.calls, "failures are checked again")
+
+	embeddings.err = nil
+	health := checker.Check(ctx)
+	assert.Equal(t, HealthStatusHealthy, health.Status)
+	assert.Equal(t, 3, health.Details["dimensions"])
+
+	health = checker.Check(ctx)
+	assert.Equal(t, HealthStatusHealthy, health.Status)
+	assert.Equal(t, true, health.Details["cached"])
+	assert.Equal(t, 3, embeddings.calls)
+}
+
+func TestProbeService_Draining(t *testing.T) {
+	database := &stubChecker{name: "postgres", status: HealthStatusHealthy}
+	probes := NewProbeService("test", time.Second)
+	probes.AddCheck(database, true)
+	ctx := context.Background()
+
+	require.True(t, probes.Readiness(ctx).OK)
+	probes.StartDraining()
+
+	report := probes.Readiness(ctx)
+	assert.False(t, report.OK)
+	assert.True(t, report.Draining)
+	assert.Empty(t, report.Checks)
+	assert.Equal(t, 1, database.checks, "dependencies are not checked while draining")
+
+	// The process is still alive and has started
+	assert.True(t, probes.Liveness().OK)
+	assert.True(t, probes.Startup(ctx).OK)
+}
@@ -0,0 +1,306 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ErrPublicationNotFound is returned for pages that are not published and for public URLs
+// that are forged, revoked or unknown
+var ErrPublicationNotFound = errors.New("publication not found")
+
+// ErrInvalidPublication is returned when publishing a chunk that is not a page
+var ErrInvalidPublication = errors.New("invalid publication")
+
+const (
+	// PublicPagePath is where published pages are served, followed by their slug
+	PublicPagePath = "/api/v1/public/pages/"
+
+	publicationTokenBytes     = 16
+	publicationSignatureBytes = 16
+)
+
+// PublishingService publishes pages for reading without authentication. A published page
+// is served with its descendants only, at a URL whose slug is signed, until it is revoked.
+type PublishingService interface {
+	// Publish publishes a page, or returns its live publication; created reports which
+	Publish(ctx context.Context, pageID string) (publication *models.PagePublication, created bool, err error)
+
+	// Unpublish revokes a page's live publication, so its URL stops working
+	Unpublish(ctx context.Context, pageID string) error
+
+	// GetPublication returns a page's live publication with its view count
+	GetPublication(ctx context.Context, pageID string) (*models.PagePublication, error)
+
+	// ListPublications lists the workspace's publications, newest first
+	ListPublications(ctx context.Context, includeRevoked bool) ([]models.PagePublication, error)
+
+	// RenderPublished renders the page a public slug points at and counts the view
+	RenderPublished(ctx context.Context, slug string, format models.RenderFormat) (*models.RenderedPage, error)
+}
+
+// publishingService implements PublishingService on the page_publications table
+type publishingService struct {
+	db       *sql.DB
+	chunks   UnifiedChunkService
+	acls     PageACLService
+	renderer PageRenderService
+	secret   []byte
+	baseURL  string
+	monitor  QueryPerformanceMonitor
+}
+
+// NewPublishingService creates a publishing service; acls may be nil when page ACLs are
+// disabled
+func NewPublishingService(db *sql.DB, chunks UnifiedChunkService, acls PageACLService, renderer PageRenderService, cfg config.PublishConfig, monitor QueryPerformanceMonitor) PublishingService {
+	return &publishingService{
+		db:       db,
+		chunks:   chunks,
+		acls:     acls,
+		renderer: renderer,
+		secret:   []byte(cfg.SigningSecret),
+		baseURL:  cfg.BaseURL,
+		monitor:  monitor,
+	}
+}
+
+// sign returns the slug of a publication token: the token and its truncated HMAC
+func (s *publishingService) sign(token string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(token))
+	return token + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:publicationSignatureBytes])
+}
+
+// verify returns the token of a slug whose signature is valid
+func (s *publishingService) verify(slug string) (string, bool) {
+	token, _, ok := strings.Cut(slug, ".")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, hmac.Equal([]byte(s.sign(token)), []byte(slug))
+}
+
+func newPublicationToken() (string, error) {
+	buf := make([]byte, publicationTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate publication token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (s *publishingService) Publish(ctx context.Context, pageID string) (*models.PagePublication, bool, error) {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return nil, false, err
+	}
+	page, err := s.chunks.GetChunk(ctx, pageID)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get page: %w", err)
+	}
+	if !page.IsPage {
+		return nil, false, fmt.Errorf("%w: chunk %s is not a page", ErrInvalidPublication, pageID)
+	}
+	// Publishing shows a restricted page to everyone, so it takes the right to change it
+	if s.acls != nil {
+		if err := s.acls.CheckChunk(ctx, page, PermissionWrite); err != nil {
+			return nil, false, err
+		}
+	}
+
+	token, err := newPublicationToken()
+	if err != nil {
+		return nil, false, err
+	}
+	publishedBy := ""
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		publishedBy = principal.Subject
+	}
+
+	// A live publication is kept; the insert only happens without one
+	start := time.Now()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO page_publications (token, page_chunk_id, workspace_id, published_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (page_chunk_id) WHERE revoked_at IS NULL DO NOTHING`,
+		token, pageID, WorkspaceFromContext(ctx), publishedBy)
+	s.monitor.RecordQuery("publish_page", time.Since(start), 1)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to publish page: %w", err)
+	}
+
+	publication, err := s.GetPublication(ctx, pageID)
+	if err != nil {
+		return nil, false, err
+	}
+	return publication, publication.Slug == s.sign(token), nil
+}
+
+func (s *publishingService) Unpublish(ctx context.Context, pageID string) error {
+	if err := Authorize(ctx, PermissionWrite); err != nil {
+		return err
+	}
+	if s.acls != nil {
+		page, err := s.chunks.GetChunk(ctx, pageID)
+		if err != nil {
+			return fmt.Errorf("failed to get page: %w", err)
+		}
+		if err := s.acls.CheckChunk(ctx, page, PermissionWrite); err != nil {
+			return err
+		}
+	}
+
+	start := time.Now()
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE page_publications SET revoked_at = NOW()
+		WHERE page_chunk_id = $1 AND revoked_at IS NULL`,
+		pageID)
+	s.monitor.RecordQuery("unpublish_page", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to unpublish page: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrPublicationNotFound
+	}
+	return nil
+}
+
+func (s *publishingService) GetPublication(ctx context.Context, pageID string) (*models.PagePublication, error) {
+	if err := Authorize(ctx, PermissionRead); err != nil {
+		return nil, err
+	}
+	// The chunk service refuses pages the caller may not read
+	if s.acls != nil {
+		if _, err := s.chunks.GetChunk(ctx, pageID); err != nil {
+			return nil, fmt.Errorf("failed to get page: %w", err)
+		}
+	}
+	publications, err := s.queryPublications(ctx, "get_publication",
+		`WHERE page_chunk_id = $1 AND revoked_at IS NULL`, pageID)
+	if err != nil {
+		return nil, err
+	}
+	if len(publications) == 0 {
+		return nil, ErrPublicationNotFound
+	}
+	return &publications[0], nil
+}
+
+func (s *publishingService) ListPublications(ctx context.Context, includeRevoked bool) ([]models.PagePublication, error) {
+	if err := Authorize(ctx, PermissionRead); err != nil {
+		return nil, err
+	}
+	where := `WHERE workspace_id = $1`
+	if !includeRevoked {
+		where += ` AND revoked_at IS NULL`
+	}
+	publications, err := s.queryPublications(ctx, "list_publications", where+` ORDER BY published_at DESC`, WorkspaceFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if s.acls == nil || len(publications) == 0 {
+		return publications, nil
+	}
+
+	// Callers only see the publications of pages they may read
+	pageIDs := make([]string, len(publications))
+	for i, publication := range publications {
+		pageIDs[i] = publication.PageID
+	}
+	readable, err := s.acls.FilterChunkIDs(ctx, pageIDs)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(readable))
+	for _, id := range readable {
+		allowed[id] = true
+	}
+	visible := publications[:0]
+	for _, publication := range publications {
+		if allowed[publication.PageID] {
+			visible = append(visible, publication)
+		}
+	}
+	return visible, nil
+}
+
+func (s *publishingService) RenderPublished(ctx context.Context, slug string, format models.RenderFormat) (*models.RenderedPage, error) {
+	token, ok := s.verify(slug)
+	if !ok {
+		return nil, ErrPublicationNotFound
+	}
+	publications, err := s.queryPublications(ctx, "get_public_page",
+		`WHERE token = $1 AND revoked_at IS NULL`, token)
+	if err != nil {
+		return nil, err
+	}
+	if len(publications) == 0 {
+		return nil, ErrPublicationNotFound
+	}
+	publication := publications[0]
+
+	// Public readers have no principal of their own; the page is rendered as the gateway,
+	// which the publisher allowed, and the rendering shows nothing outside the page
+	renderCtx := WithPrincipal(WithWorkspace(ctx, publication.Workspace), nil)
+	page, err := s.renderer.RenderPublished(renderCtx, publication.PageID, format)
+	if err != nil {
+		return nil, err
+	}
+
+	// View counting is informational; a failed update does not fail the request
+	start := time.Now()
+	s.db.ExecContext(ctx, `
+		UPDATE page_publications SET view_count = view_count + 1, last_viewed_at = NOW()
+		WHERE token = $1`,
+		token)
+	s.monitor.RecordQuery("count_public_view", time.Since(start), 1)
+	return page, nil
+}
+
+// queryPublications runs a SELECT of publications with the given WHERE and ORDER BY clauses
+func (s *publishingService) queryPublications(ctx context.Context, operation, clauses string, args ...interface{}) ([]models.PagePublication, error) {
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT token, page_chunk_id, workspace_id, published_by, published_at,
+			revoked_at, view_count, last_viewed_at
+		FROM page_publications `+clauses,
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query publications: %w", err)
+	}
+	defer rows.Close()
+
+	var publications []models.PagePublication
+	for rows.Next() {
+		var publication models.PagePublication
+		var token string
+		var revokedAt, lastViewedAt sql.NullTime
+		if err := rows.Scan(&token, &publication.PageID, &publication.Workspace, &publication.PublishedBy,
+			&publication.PublishedAt, &revokedAt, &publication.ViewCount, &lastViewedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan publication: %w", err)
+		}
+		if revokedAt.Valid {
+			publication.RevokedAt = &revokedAt.Time
+		}
+		if lastViewedAt.Valid {
+			publication.LastViewedAt = &lastViewedAt.Time
+		}
+		publication.Slug = s.sign(token)
+		publication.URL = s.baseURL + PublicPagePath + publication.Slug
+		publications = append(publications, publication)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating publications: %w", err)
+	}
+	s.monitor.RecordQuery(operation, time.Since(start), len(publications))
+	return publications, nil
+}
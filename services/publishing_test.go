@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPublishSecret = "0123456789abcdef0123456789abcdef"
+
+func TestPublishing_SignedSlugs(t *testing.T) {
+	service := NewPublishingService(nil, nil, nil, nil, config.PublishConfig{SigningSecret: testPublishSecret}, NewNoOpMonitor()).(*publishingService)
+
+	token, err := newPublicationToken()
+	require.NoError(t, err)
+	slug := service.sign(token)
+	verified, ok := service.verify(slug)
+	require.True(t, ok)
+	assert.Equal(t, token, verified)
+
+	other, err := newPublicationToken()
+	require.NoError(t, err)
+	for _, forged := range []string{token, other + slug[len(token):], slug + "x", "." + slug[len(token)+1:], ""} {
+		_, ok := service.verify(forged)
+		assert.False(t, ok, forged)
+	}
+	rotated := NewPublishingService(nil, nil, nil, nil, config.PublishConfig{SigningSecret: testPublishSecret + "!"}, NewNoOpMonitor()).(*publishingService)
+	_, ok = rotated.verify(slug)
+	assert.False(t, ok, "changing the secret invalidates slugs")
+
+	// Forged slugs are refused before any query
+	_, err = service.RenderPublished(context.Background(), other+".forged", models.RenderHTML)
+	assert.ErrorIs(t, err, ErrPublicationNotFound)
+}
+
+func TestPageRender_PublishedShowsOnlyThePage(t *testing.T) {
+	service := NewPageRenderService(nil, newRenderFixture(), nil, &config.RenderConfig{MaxTransclusionDepth: 3}, NewNoOpMonitor())
+
+	page, err := service.RenderPublished(context.Background(), renderPageID, models.RenderMarkdown)
+	require.NoError(t, err)
+	assert.Equal(t, "# Launch <plan>\n\n"+
+		"- As (("+renderQuote+")) said\n"+
+		"  - {{embed (("+renderSteps+"))}}\n"+
+		"    See (("+renderMissing+")) and (("+renderDetail+"))\n", page.Content)
+	assert.Equal(t, 0, page.Transclusions)
+	assert.Equal(t, 4, page.Unresolved)
+}
+
+func TestPublishing_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	migration, err := os.ReadFile("../database/page_publication_migration.sql")
+	require.NoError(t, err)
+	_, err = db.Exec(string(migration))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	renderer := NewPageRenderService(db, chunks, nil, &config.RenderConfig{MaxTransclusionDepth: 3}, NewNoOpMonitor())
+	publishing := NewPublishingService(db, chunks, nil, renderer, config.PublishConfig{SigningSecret: testPublishSecret, BaseURL: "https://notes.example.com"}, NewNoOpMonitor())
+
+	page := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "Published page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer chunks.DeleteChunk(ctx, page.ChunkID)
+	block := &models.UnifiedChunkRecord{ChunkID: uuid.New().String(), Contents: "Hello *world*", Parent: &page.ChunkID, Page: &page.ChunkID}
+	require.NoError(t, chunks.CreateChunk(ctx, block))
+	defer chunks.DeleteChunk(ctx, block.ChunkID)
+
+	publication, created, err := publishing.Publish(ctx, page.ChunkID)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "https://notes.example.com"+PublicPagePath+publication.Slug, publication.URL)
+	again, created, err := publishing.Publish(ctx, page.ChunkID)
+	require.NoError(t, err)
+	assert.False(t, created, "a live publication is reused")
+	assert.Equal(t, publication.Slug, again.Slug)
+
+	rendered, err := publishing.RenderPublished(contextWithRole(models.RoleReader), publication.Slug, models.RenderHTML)
+	require.NoError(t, err)
+	assert.Contains(t, rendered.Content, "Hello <em>world</em>")
+	current, err := publishing.GetPublication(ctx, page.ChunkID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), current.ViewCount)
+	assert.NotNil(t, current.LastViewedAt)
+
+	require.NoError(t, publishing.Unpublish(ctx, page.ChunkID))
+	_, err = publishing.RenderPublished(ctx, publication.Slug, models.RenderHTML)
+	assert.ErrorIs(t, err, ErrPublicationNotFound, "revoked slugs stop working")
+	assert.ErrorIs(t, publishing.Unpublish(ctx, page.ChunkID), ErrPublicationNotFound)
+
+	republished, created, err := publishing.Publish(ctx, page.ChunkID)
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.NotEqual(t, publication.Slug, republished.Slug)
+	all, err := publishing.ListPublications(ctx, true)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(all), 2)
+
+	_, _, err = publishing.Publish(ctx, block.ChunkID)
+	assert.ErrorIs(t, err, ErrInvalidPublication)
+	_, _, err = publishing.Publish(contextWithRole(models.RoleReader), page.ChunkID)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
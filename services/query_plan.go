@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// QueryPlanCapturer runs EXPLAIN for slow statements so that their plans can be inspected
+// after the fact. Read-only statements are explained with ANALYZE and BUFFERS inside a
+// read-only transaction that is rolled back; statements that write are only explained, so
+// capture never repeats a write.
+type QueryPlanCapturer struct {
+	db       *sql.DB
+	timeout  time.Duration
+	cooldown time.Duration
+
+	mu       sync.Mutex
+	plans    map[string]*models.QueryPlan
+	inflight chan struct{}
+}
+
+// maxCachedPlans bounds the plans kept for reuse within the cooldown
+const maxCachedPlans = 256
+
+// NewQueryPlanCapturer creates a capturer. Each EXPLAIN runs for at most timeout, and a
+// statement is explained again only after cooldown.
+func NewQueryPlanCapturer(db *sql.DB, timeout, cooldown time.Duration) *QueryPlanCapturer {
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &QueryPlanCapturer{
+		db:       db,
+		timeout:  timeout,
+		cooldown: cooldown,
+		plans:    make(map[string]*models.QueryPlan),
+		inflight: make(chan struct{}, 1),
+	}
+}
+
+// Capture explains query with args and summarises the plan
+func (c *QueryPlanCapturer) Capture(ctx context.Context, query string, args []interface{}) (*models.QueryPlan, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	tx, err := c.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin explain transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// EXPLAIN ANALYZE executes the statement, so bound it like the capture itself
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", c.timeout.Milliseconds())); err != nil {
+		return nil, fmt.Errorf("failed to set explain timeout: %w", err)
+	}
+
+	analyze := isReadOnlyStatement(query)
+	explain := "EXPLAIN (FORMAT JSON) "
+	if analyze {
+		explain = "EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) "
+	}
+
+	var raw []byte
+	if err := tx.QueryRowContext(ctx, explain+query, args...).Scan(&raw); err != nil {
+		return nil, fmt.Errorf("failed to explain statement: %w", err)
+	}
+
+	plan, err := parseQueryPlan(raw)
+	if err != nil {
+		return nil, err
+	}
+	plan.Analyzed = analyze
+	return plan, nil
+}
+
+// CaptureAsync explains a statement in the background and passes the plan to done. A plan
+// captured within the cooldown is reused, and statements arriving while another capture
+// runs are skipped, so capture adds at most one extra query at a time.
+func (c *QueryPlanCapturer) CaptureAsync(query string, args []interface{}, done func(*models.QueryPlan)) {
+	key := normalizeStatement(query)
+
+	c.mu.Lock()
+	if plan := c.plans[key]; plan != nil && time.Since(plan.CapturedAt) < c.cooldown {
+		c.mu.Unlock()
+		go done(plan)
+		return
+	}
+	c.mu.Unlock()
+
+	select {
+	case c.inflight <- struct{}{}:
+	default:
+		return
+	}
+
+	go func() {
+		defer func() { <-c.inflight }()
+
+		plan, err := c.Capture(context.Background(), query, args)
+		if err != nil {
+			log.Printf("Warning: failed to capture plan for slow query: %v", err)
+			return
+		}
+		c.remember(key, plan)
+		done(plan)
+	}()
+}
+
+// remember caches a plan for reuse within the cooldown
+func (c *QueryPlanCapturer) remember(key string, plan *models.QueryPlan) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.plans) >= maxCachedPlans {
+		for cached, p := range c.plans {
+			if time.Since(p.CapturedAt) >= c.cooldown {
+				delete(c.plans, cached)
+			}
+		}
+		if len(c.plans) >= maxCachedPlans {
+			return
+		}
+	}
+	c.plans[key] = plan
+}
+
+// explainOutput is one element of EXPLAIN (FORMAT JSON) output
+type explainOutput struct {
+	Plan          explainNode `json:"Plan"`
+	PlanningTime  float64     `json:"Planning Time"`
+	ExecutionTime float64     `json:"Execution Time"`
+}
+
+// explainNode is a plan node; only the fields the summary uses are decoded
+type explainNode struct {
+	NodeType         string        `json:"Node Type"`
+	RelationName     string        `json:"Relation Name"`
+	Filter           string        `json:"Filter"`
+	TotalCost        float64       `json:"Total Cost"`
+	PlanRows         int64         `json:"Plan Rows"`
+	ActualRows       int64         `json:"Actual Rows"`
+	ActualLoops      int64         `json:"Actual Loops"`
+	RowsRemoved      int64         `json:"Rows Removed by Filter"`
+	SharedReadBlocks int64         `json:"Shared Read Blocks"`
+	Plans            []explainNode `json:"Plans"`
+}
+
+// parseQueryPlan summarises EXPLAIN (FORMAT JSON) output
+func parseQueryPlan(raw []byte) (*models.QueryPlan, error) {
+	var outputs []explainOutput
+	if err := json.Unmarshal(raw, &outputs); err != nil {
+		return nil, fmt.Errorf("failed to parse query plan: %w", err)
+	}
+	if len(outputs) == 0 {
+		return nil, fmt.Errorf("failed to parse query plan: empty output")
+	}
+
+	output := outputs[0]
+	plan := &models.QueryPlan{
+		Plan:             json.RawMessage(raw),
+		TotalCost:        output.Plan.TotalCost,
+		PlanningTime:     millisecondsToDuration(output.PlanningTime),
+		ExecutionTime:    millisecondsToDuration(output.ExecutionTime),
+		SharedBlocksRead: output.Plan.SharedReadBlocks,
+		CapturedAt:       time.Now(),
+	}
+	collectSeqScans(&output.Plan, plan)
+	return plan, nil
+}
+
+// collectSeqScans appends every sequential scan below node to the plan summary
+func collectSeqScans(node *explainNode, plan *models.QueryPlan) {
+	if node.NodeType == "Seq Scan" || node.NodeType == "Parallel Seq Scan" {
+		loops := node.ActualLoops
+		if loops < 1 {
+			loops = 1
+		}
+		plan.SeqScans = append(plan.SeqScans, models.PlanSeqScan{
+			Table:       node.RelationName,
+			Filter:      node.Filter,
+			Columns:     filterColumns(node.Filter),
+			PlanRows:    node.PlanRows,
+			ActualRows:  node.ActualRows * loops,
+			RowsRemoved: node.RowsRemoved * loops,
+		})
+	}
+	for i := range node.Plans {
+		collectSeqScans(&node.Plans[i], plan)
+	}
+}
+
+// filterColumnPattern matches a column compared by a plan filter, e.g. tag_chunk_id in
+// "(tag_chunk_id = $1)" or metadata in "((metadata ->> 'status'::text) = 'done'::text)"
+var filterColumnPattern = regexp.MustCompile(`\(+([a-z_][a-z0-9_]*)\s*(?:=|<>|<=|>=|<|>|~~|->>|->|@>|IS\b)`)
+
+// filterColumns returns the distinct columns a filter compares
+func filterColumns(filter string) []string {
+	seen := make(map[string]bool)
+	var columns []string
+	for _, match := range filterColumnPattern.FindAllStringSubmatch(filter, -1) {
+		if column := match[1]; !seen[column] {
+			seen[column] = true
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// writeStatementPattern matches statements or clauses that modify data or take row locks
+var writeStatementPattern = regexp.MustCompile(`(?i)\b(INSERT|UPDATE|DELETE|MERGE|TRUNCATE|SHARE)\b`)
+
+// isReadOnlyStatement reports whether EXPLAIN ANALYZE may safely execute query
+func isReadOnlyStatement(query string) bool {
+	trimmed := strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(trimmed, "SELECT") && !strings.HasPrefix(trimmed, "WITH") {
+		return false
+	}
+	return !writeStatementPattern.MatchString(query)
+}
+
+// isSQLStatement reports whether a slow query record holds a statement that can be explained
+func isSQLStatement(query string) bool {
+	trimmed := strings.ToUpper(strings.TrimSpace(query))
+	for _, verb := range []string{"SELECT", "WITH", "INSERT", "UPDATE", "DELETE"} {
+		if strings.HasPrefix(trimmed, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeStatement collapses whitespace so that the same statement maps to one plan
+func normalizeStatement(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// millisecondsToDuration converts EXPLAIN's fractional milliseconds
+func millisecondsToDuration(ms float64) time.Duration {
+	return time.Duration(ms * float64(time.Millisecond))
+}
+
+// slowQueryThresholder is implemented by monitors that record slow statements
+type slowQueryThresholder interface {
+	SlowQueryThreshold() time.Duration
+}
+
+// RecordStatement reports a SQL statement to monitor when it ran longer than the monitor's
+// slow query threshold. Its arguments are recorded as the parameters $1, $2, ..., which a
+// monitor with a QueryPlanCapturer binds to explain the statement.
+func RecordStatement(monitor QueryPerformanceMonitor, query string, duration time.Duration, args ...interface{}) {
+	thresholder, ok := monitor.(slowQueryThresholder)
+	if !ok || duration < thresholder.SlowQueryThreshold() {
+		return
+	}
+
+	params := make(map[string]interface{}, len(args))
+	for i, arg := range args {
+		params["$"+strconv.Itoa(i+1)] = arg
+	}
+	monitor.RecordSlowQuery(normalizeStatement(query), duration, params)
+}
+
+// statementArgs returns the $n parameters of a slow query record in order, or nil when
+// they are not contiguous from $1
+func statementArgs(params map[string]interface{}) []interface{} {
+	var positions []int
+	for key := range params {
+		if n, err := strconv.Atoi(strings.TrimPrefix(key, "$")); err == nil && strings.HasPrefix(key, "$") {
+			positions = append(positions, n)
+		}
+	}
+	sort.Ints(positions)
+
+	args := make([]interface{}, 0, len(positions))
+	for i, n := range positions {
+		if n != i+1 {
+			return nil
+		}
+		args = append(args, params["$"+strconv.Itoa(n)])
+	}
+	return args
+}
+
+// sensitiveParamPattern matches parameter names whose values must not be stored
+var sensitiveParamPattern = regexp.MustCompile(`(?i)(password|secret|token|api_?key|credential|email|phone)`)
+
+// maxParamLength bounds stored string parameters
+const maxParamLength = 200
+
+// SanitizeQueryParams returns a copy of params safe to keep in slow query records and
+// alerts: values of sensitive-sounding names are redacted, vectors are reduced to their
+// dimension and long strings are truncated
+func SanitizeQueryParams(params map[string]interface{}) map[string]interface{} {
+	if params == nil {
+		return nil
+	}
+	sanitized := make(map[string]interface{}, len(params))
+	for key, value := range params {
+		if sensitiveParamPattern.MatchString(key) {
+			sanitized[key] = "[REDACTED]"
+			continue
+		}
+		sanitized[key] = sanitizeParamValue(value)
+	}
+	return sanitized
+}
+
+// sanitizeParamValue reduces one parameter value to a loggable form
+func sanitizeParamValue(value interface{}) interface{} {
+	if valuer, ok := value.(driver.Valuer); ok {
+		if v, err := valuer.Value(); err == nil {
+			value = v
+		}
+	}
+
+	switch v := value.(type) {
+	case []float32:
+		return fmt.Sprintf("<vector dim=%d>", len(v))
+	case []float64:
+		return fmt.Sprintf("<vector dim=%d>", len(v))
+	case []byte:
+		return sanitizeParamValue(string(v))
+	case string:
+		if strings.HasPrefix(v, "[") && strings.Count(v, ",") >= 32 {
+			return fmt.Sprintf("<vector dim=%d>", strings.Count(v, ",")+1)
+		}
+		if runes := []rune(v); len(runes) > maxParamLength {
+			return string(runes[:maxParamLength]) + "..."
+		}
+		return v
+	default:
+		return v
+	}
+}
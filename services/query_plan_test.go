@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sampleExplainJSON = `[{
+  "Plan": {
+    "Node Type": "Hash Join", "Total Cost": 1843.5, "Plan Rows": 12, "Actual Rows": 9,
+    "Actual Loops": 1, "Shared Read Blocks": 40,
+    "Plans": [
+      {"Node Type": "Seq Scan", "Relation Name": "chunk_tags", "Filter": "(tag_chunk_id = $1)",
+       "Plan Rows": 12, "Actual Rows": 9, "Actual Loops": 1, "Rows Removed by Filter": 48210},
+      {"Node Type": "Index Scan", "Relation Name": "chunks", "Plan Rows": 1, "Actual Rows": 1, "Actual Loops": 9}
+    ]
+  },
+  "Planning Time": 0.25,
+  "Execution Time": 812.5
+}]`
+
+func TestParseQueryPlan(t *testing.T) {
+	plan, err := parseQueryPlan([]byte(sampleExplainJSON))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1843.5, plan.TotalCost)
+	assert.Equal(t, 812500*time.Microsecond, plan.ExecutionTime)
+	assert.Equal(t, int64(40), plan.SharedBlocksRead)
+	require.Len(t, plan.SeqScans, 1, "index scans are not reported")
+	assert.Equal(t, "chunk_tags", plan.SeqScans[0].Table)
+	assert.Equal(t, []string{"tag_chunk_id"}, plan.SeqScans[0].Columns)
+	assert.Equal(t, int64(48210), plan.SeqScans[0].RowsRemoved)
+
+	_, err = parseQueryPlan([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func TestFilterColumns(t *testing.T) {
+	assert.Equal(t, []string{"contents", "is_tag"}, filterColumns("((contents ~~ '%go%'::text) AND (is_tag = true) AND (is_tag IS NOT NULL))"))
+	assert.Equal(t, []string{"metadata"}, filterColumns("((metadata ->> 'status'::text) = 'done'::text)"))
+	assert.Equal(t, []string{"parent", "page"}, filterColumns("((parent = $1) OR (page IS NULL))"))
+	assert.Empty(t, filterColumns(""))
+}
+
+func TestIsReadOnlyStatement(t *testing.T) {
+	assert.True(t, isReadOnlyStatement("SELECT * FROM chunks WHERE chunk_id = $1"))
+	assert.True(t, isReadOnlyStatement("  with t AS (SELECT 1) SELECT * FROM t"))
+	assert.True(t, isReadOnlyStatement("SELECT last_updated FROM chunks"), "column names containing keywords are fine")
+	assert.False(t, isReadOnlyStatement("UPDATE chunks SET contents = $1"))
+	assert.False(t, isReadOnlyStatement("WITH moved AS (DELETE FROM chunks RETURNING *) SELECT * FROM moved"))
+	assert.False(t, isReadOnlyStatement("SELECT * FROM chunks FOR UPDATE"))
+	assert.False(t, isReadOnlyStatement("SELECT * FROM chunks FOR SHARE"))
+
+	assert.True(t, isSQLStatement("insert into chunks values ($1)"))
+	assert.False(t, isSQLStatement("semantic_search"))
+}
+
+func TestSanitizeQueryParams(t *testing.T) {
+	long := make([]rune, maxParamLength+10)
+	for i := range long {
+		long[i] = '字'
+	}
+
+	sanitized := SanitizeQueryParams(map[string]interface{}{
+		"$1":        "chunk-1",
+		"api_key":   "sk-123",
+		"userEmail": "a@example.com",
+		"embedding": make([]float64, 768),
+		"contents":  string(long),
+		"tags":      pq.Array([]string{"a", "b"}),
+	})
+
+	assert.Equal(t, "chunk-1", sanitized["$1"])
+	assert.Equal(t, "[REDACTED]", sanitized["api_key"])
+	assert.Equal(t, "[REDACTED]", sanitized["userEmail"])
+	assert.Equal(t, "<vector dim=768>", sanitized["embedding"])
+	assert.Equal(t, string(long[:maxParamLength])+"...", sanitized["contents"])
+	assert.Equal(t, `{"a","b"}`, sanitized["tags"])
+	assert.Nil(t, SanitizeQueryParams(nil))
+}
+
+func TestRecordStatement(t *testing.T) {
+	monitor := NewInMemoryPerformanceMonitor(100*time.Millisecond, 10)
+	defer monitor.Stop()
+
+	RecordStatement(monitor, "SELECT * FROM chunks WHERE chunk_id = $1", 10*time.Millisecond, "fast")
+	assert.Empty(t, monitor.GetSlowQueries(0), "statements under the threshold are not recorded")
+
+	RecordStatement(monitor, "SELECT *\n\tFROM chunks WHERE chunk_id = $1 AND page = $2", 200*time.Millisecond, "slow", "page-1")
+	records := monitor.GetSlowQueries(0)
+	require.Len(t, records, 1)
+	assert.Equal(t, "SELECT * FROM chunks WHERE chunk_id = $1 AND page = $2", records[0].Query)
+	assert.Equal(t, []interface{}{"slow", "page-1"}, statementArgs(records[0].Params))
+
+	// Monitors without a threshold never record statements
+	RecordStatement(NewNoOpMonitor(), "SELECT 1", time.Hour)
+
+	assert.Nil(t, statementArgs(map[string]interface{}{"$2": "gap"}))
+	assert.Equal(t, []interface{}{}, statementArgs(map[string]interface{}{"query": "not a parameter"}))
+}
@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"semantic-text-processor/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// DefaultWorkspace is used when a request names no workspace
+const DefaultWorkspace = "default"
+
+const (
+	defaultSuggestionLimit = 10
+	maxSuggestionLimit     = 50
+	maxWorkspaceIDLength   = 64
+)
+
+// SearchQueryRecorder records the queries users search for
+type SearchQueryRecorder interface {
+	RecordQuery(ctx context.Context, workspace, query string, resultCount int) error
+}
+
+// QuerySuggestionService keeps a per-workspace log of search queries and suggests past
+// queries from it. Queries that returned nothing the last time they ran are never
+// suggested.
+type QuerySuggestionService interface {
+	SearchQueryRecorder
+
+	// Suggest completes input from past queries starting with it, topped up with past
+	// queries spelled similarly when there are too few completions
+	Suggest(ctx context.Context, workspace, input string, limit int) ([]models.QuerySuggestion, error)
+
+	// SuggestSimilar returns past queries spelled similarly to query, excluding query
+	// itself; this is what FullTextResponse.Suggestions carries
+	SuggestSimilar(ctx context.Context, workspace, query string, limit int) ([]models.QuerySuggestion, error)
+
+	// PopularQueries returns the most frequent queries searched since the given time
+	PopularQueries(ctx context.Context, workspace string, since time.Time, limit int) ([]models.QuerySuggestion, error)
+}
+
+// querySuggestionService implements QuerySuggestionService on the search_query_log table
+type querySuggestionService struct {
+	db             *sql.DB
+	minSimilarity  float64
+	maxQueryLength int
+	monitor        QueryPerformanceMonitor
+}
+
+// NewQuerySuggestionService creates a suggestion service. minSimilarity is the pg_trgm
+// similarity (0-1) a past query needs to be suggested for a misspelled input; queries
+// longer than maxQueryLength characters are not logged.
+func NewQuerySuggestionService(db *sql.DB, minSimilarity float64, maxQueryLength int, monitor QueryPerformanceMonitor) QuerySuggestionService {
+	return &querySuggestionService{
+		db:             db,
+		minSimilarity:  minSimilarity,
+		maxQueryLength: maxQueryLength,
+		monitor:        monitor,
+	}
+}
+
+// NormalizeWorkspace returns the workspace a request belongs to
+func NormalizeWorkspace(workspace string) (string, error) {
+	workspace = strings.TrimSpace(workspace)
+	if workspace == "" {
+		return DefaultWorkspace, nil
+	}
+	if len(workspace) > maxWorkspaceIDLength {
+		return "", fmt.Errorf("workspace must be at most %d characters", maxWorkspaceIDLength)
+	}
+	return workspace, nil
+}
+
+type workspaceKey struct{}
+
+// WithWorkspace returns a context carrying the workspace a request belongs to
+func WithWorkspace(ctx context.Context, workspace string) context.Context {
+	return context.WithValue(ctx, workspaceKey{}, workspace)
+}
+
+// WorkspaceFromContext returns the workspace set by WithWorkspace, or DefaultWorkspace
+func WorkspaceFromContext(ctx context.Context) string {
+	if workspace, ok := ctx.Value(workspaceKey{}).(string); ok && workspace != "" {
+		return workspace
+	}
+	return DefaultWorkspace
+}
+
+// normalizeQuery lowercases a query and collapses its whitespace, so queries differing
+// only in case or spacing are counted together
+func normalizeQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// escapeLikePattern escapes LIKE wildcards so input matches literally
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func clampSuggestionLimit(limit int) int {
+	if limit <= 0 {
+		return defaultSuggestionLimit
+	}
+	return min(limit, maxSuggestionLimit)
+}
+
+// RecordQuery counts one search for query in workspace
+func (s *querySuggestionService) RecordQuery(ctx context.Context, workspace, query string, resultCount int) error {
+	normalized := normalizeQuery(query)
+	if normalized == "" || utf8.RuneCountInString(normalized) > s.maxQueryLength {
+		return nil
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO search_query_log (workspace_id, normalized_query, display_query, last_result_count)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (workspace_id, normalized_query) DO UPDATE
+		SET frequency = search_query_log.frequency + 1,
+			display_query = EXCLUDED.display_query,
+			last_result_count = EXCLUDED.last_result_count,
+			last_searched_at = NOW()`,
+		workspace, normalized, strings.Join(strings.Fields(query), " "), resultCount)
+	s.monitor.RecordQuery("record_search_query", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to record search query: %w", err)
+	}
+	return nil
+}
+
+// Suggest merges prefix completions with similar queries
+func (s *querySuggestionService) Suggest(ctx context.Context, workspace, input string, limit int) ([]models.QuerySuggestion, error) {
+	limit = clampSuggestionLimit(limit)
+	normalized := normalizeQuery(input)
+	if normalized == "" {
+		return s.PopularQueries(ctx, workspace, time.Time{}, limit)
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("suggest_search_queries", time.Since(start), 1)
+	}()
+
+	completions, err := s.querySuggestions(ctx, models.QuerySuggestionPrefix, `
+		SELECT display_query, frequency, last_result_count, 0::float8
+		FROM search_query_log
+		WHERE workspace_id = $1 AND normalized_query LIKE $2 ESCAPE '\' AND last_result_count > 0
+		ORDER BY frequency DESC, last_searched_at DESC
+		LIMIT $3`,
+		workspace, escapeLikePattern(normalized)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete search query: %w", err)
+	}
+	if len(completions) >= limit {
+		return completions, nil
+	}
+
+	similar, err := s.similarQueries(ctx, workspace, normalized, limit)
+	if err != nil {
+		return nil, err
+	}
+	return mergeSuggestions(limit, completions, similar), nil
+}
+
+// SuggestSimilar returns similarly spelled past queries
+func (s *querySuggestionService) SuggestSimilar(ctx context.Context, workspace, query string, limit int) ([]models.QuerySuggestion, error) {
+	normalized := normalizeQuery(query)
+	if normalized == "" {
+		return []models.QuerySuggestion{}, nil
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("suggest_similar_queries", time.Since(start), 1)
+	}()
+
+	return s.similarQueries(ctx, workspace, normalized, clampSuggestionLimit(limit))
+}
+
+// similarQueries finds past queries by trigram similarity. The threshold is set for the
+// transaction only, so the % operator can use the trigram index without changing the
+// setting for other users of the pooled connection.
+func (s *querySuggestionService) similarQueries(ctx context.Context, workspace, normalized string, limit int) ([]models.QuerySuggestion, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin suggestion transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	threshold := strconv.FormatFloat(s.minSimilarity, 'f', -1, 64)
+	if _, err := tx.ExecContext(ctx, "SELECT set_config('pg_trgm.similarity_threshold', $1, true)", threshold); err != nil {
+		return nil, fmt.Errorf("failed to set similarity threshold: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT display_query, frequency, last_result_count, similarity(normalized_query, $2) AS score
+		FROM search_query_log
+		WHERE workspace_id = $1 AND normalized_query % $2 AND normalized_query <> $2 AND last_result_count > 0
+		ORDER BY score DESC, frequency DESC
+		LIMIT $3`,
+		workspace, normalized, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find similar queries: %w", err)
+	}
+	return scanSuggestions(rows, models.QuerySuggestionSimilar)
+}
+
+// PopularQueries returns the most frequent queries
+func (s *querySuggestionService) PopularQueries(ctx context.Context, workspace string, since time.Time, limit int) ([]models.QuerySuggestion, error) {
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("popular_search_queries", time.Since(start), 1)
+	}()
+
+	suggestions, err := s.querySuggestions(ctx, models.QuerySuggestionPopular, `
+		SELECT display_query, frequency, last_result_count, 0::float8
+		FROM search_query_log
+		WHERE workspace_id = $1 AND last_searched_at >= $2 AND last_result_count > 0
+		ORDER BY frequency DESC, last_searched_at DESC
+		LIMIT $3`,
+		workspace, since, clampSuggestionLimit(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get popular queries: %w", err)
+	}
+	return suggestions, nil
+}
+
+func (s *querySuggestionService) querySuggestions(ctx context.Context, source models.QuerySuggestionSource, query string, args ...interface{}) ([]models.QuerySuggestion, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanSuggestions(rows, source)
+}
+
+// scanSuggestions reads display_query, frequency, last_result_count, similarity rows
+func scanSuggestions(rows *sql.Rows, source models.QuerySuggestionSource) ([]models.QuerySuggestion, error) {
+	defer rows.Close()
+
+	suggestions := []models.QuerySuggestion{}
+	for rows.Next() {
+		suggestion := models.QuerySuggestion{Source: source}
+		if err := rows.Scan(&suggestion.Query, &suggestion.Frequency, &suggestion.ResultCount, &suggestion.Similarity); err != nil {
+			return nil, fmt.Errorf("failed to scan query suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read query suggestions: %w", err)
+	}
+	return suggestions, nil
+}
+
+// mergeSuggestions concatenates suggestion lists in priority order, dropping queries
+// already suggested
+func mergeSuggestions(limit int, lists ...[]models.QuerySuggestion) []models.QuerySuggestion {
+	seen := make(map[string]bool)
+	merged := []models.QuerySuggestion{}
+	for _, list := range lists {
+		for _, suggestion := range list {
+			if len(merged) >= limit {
+				return merged
+			}
+			key := normalizeQuery(suggestion.Query)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, suggestion)
+		}
+	}
+	return merged
+}
+
+// SuggestionQueries returns the query text of suggestions, as carried by
+// FullTextResponse.Suggestions
+func SuggestionQueries(suggestions []models.QuerySuggestion) []string {
+	queries := make([]string, len(suggestions))
+	for i, suggestion := range suggestions {
+		queries[i] = suggestion.Query
+	}
+	return queries
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedQuery is one call to fakeQueryRecorder
+type recordedQuery struct {
+	workspace   string
+	query       string
+	resultCount int
+}
+
+type fakeQueryRecorder struct {
+	mu      sync.Mutex
+	queries []recordedQuery
+}
+
+func (r *fakeQueryRecorder) RecordQuery(ctx context.Context, workspace, query string, resultCount int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queries = append(r.queries, recordedQuery{workspace, query, resultCount})
+	return nil
+}
+
+func (r *fakeQueryRecorder) recorded() []recordedQuery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedQuery(nil), r.queries...)
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	assert.Equal(t, "machine learning", normalizeQuery("  Machine\tLEARNING \n"))
+	assert.Equal(t, "", normalizeQuery("   "))
+	assert.Equal(t, `100\% a\_b c\\d`, escapeLikePattern(`100% a_b c\d`))
+
+	workspace, err := NormalizeWorkspace(" ")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultWorkspace, workspace)
+	workspace, err = NormalizeWorkspace(" team-a ")
+	require.NoError(t, err)
+	assert.Equal(t, "team-a", workspace)
+	_, err = NormalizeWorkspace(strings.Repeat("w", maxWorkspaceIDLength+1))
+	assert.Error(t, err)
+
+	assert.Equal(t, defaultSuggestionLimit, clampSuggestionLimit(0))
+	assert.Equal(t, maxSuggestionLimit, clampSuggestionLimit(1000))
+}
+
+func TestMergeSuggestions(t *testing.T) {
+	prefix := []models.QuerySuggestion{
+		{Query: "Machine learning", Source: models.QuerySuggestionPrefix, Frequency: 9},
+		{Query: "machine vision", Source: models.QuerySuggestionPrefix, Frequency: 3},
+	}
+	similar := []models.QuerySuggestion{
+		{Query: "machine  LEARNING", Source: models.QuerySuggestionSimilar, Similarity: 0.8},
+		{Query: "machining", Source: models.QuerySuggestionSimilar, Similarity: 0.5},
+		{Query: "marching", Source: models.QuerySuggestionSimilar, Similarity: 0.4},
+	}
+
+	merged := mergeSuggestions(3, prefix, similar)
+	require.Len(t, merged, 3)
+	assert.Equal(t, []string{"Machine learning", "machine vision", "machining"}, SuggestionQueries(merged))
+	assert.Equal(t, models.QuerySuggestionSimilar, merged[2].Source)
+
+	assert.Empty(t, mergeSuggestions(5))
+}
+
+func TestOptimizedSearch_RecordsQueries(t *testing.T) {
+	search := &staticSearchService{results: []models.SimilarityResult{
+		{Chunk: models.ChunkRecord{ID: "c1"}, Similarity: 0.9},
+		{Chunk: models.ChunkRecord{ID: "c2"}, Similarity: 0.8},
+	}}
+	recorder := &fakeQueryRecorder{}
+	service := NewOptimizedSearchService(search, nil, time.Minute)
+	service.SetQueryRecorder(recorder)
+
+	_, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "Graph Theory", Workspace: "team-a"})
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return len(recorder.recorded()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, recordedQuery{workspace: "team-a", query: "Graph Theory", resultCount: 2}, recorder.recorded()[0])
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+const (
+	// ragPromptOverheadTokens reserves room for instructions around the context
+	ragPromptOverheadTokens = 150
+	// ragSnippetLength is the maximum rune length of citation snippets
+	ragSnippetLength = 200
+	// ragNoContextAnswer is returned when retrieval finds nothing to answer from
+	ragNoContextAnswer = "I could not find anything in the knowledge base to answer this question."
+)
+
+const ragSystemPrompt = `You answer questions using only the numbered sources provided.
+Cite the sources supporting every sentence with their numbers in square brackets, for example [1] or [2, 3].
+If the sources do not contain the answer, say so instead of guessing.`
+
+// citationMarkerPattern matches citation markers such as [1] or [2, 3]
+var citationMarkerPattern = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// spaceBeforePunctuationPattern matches whitespace left behind by removed citation markers
+var spaceBeforePunctuationPattern = regexp.MustCompile(`\s+([.,;:!?。，！？])`)
+
+// RAGService answers questions from retrieved chunks with per-sentence citations
+type RAGService struct {
+	search    SearchService
+	provider  CompletionProvider
+	hierarchy ChunkHierarchySource
+	access    SearchAccessFilter
+	config    atomic.Pointer[config.RAGConfig]
+	tokenizer atomic.Pointer[Tokenizer]
+}
+
+// NewRAGService creates a retrieval-augmented answering service
+func NewRAGService(search SearchService, provider CompletionProvider, cfg *config.RAGConfig) *RAGService {
+	service := &RAGService{
+		search:   search,
+		provider: provider,
+	}
+	service.config.Store(cfg)
+	return service
+}
+
+// SetConfig replaces the retrieval and generation tunables used by later requests
+func (s *RAGService) SetConfig(cfg *config.RAGConfig) {
+	s.config.Store(cfg)
+}
+
+// SetAccessFilter leaves retrieved chunks the caller may not read out of contexts and answers
+func (s *RAGService) SetAccessFilter(filter SearchAccessFilter) {
+	s.access = filter
+}
+
+// SetTokenizer replaces the token counter used to fit context into budgets, for example
+// with the completion model's own tokenizer. nil restores EstimatingTokenizer.
+func (s *RAGService) SetTokenizer(tokenizer Tokenizer) {
+	if tokenizer == nil {
+		s.tokenizer.Store(nil)
+		return
+	}
+	s.tokenizer.Store(&tokenizer)
+}
+
+func (s *RAGService) currentTokenizer() Tokenizer {
+	if tokenizer := s.tokenizer.Load(); tokenizer != nil {
+		return *tokenizer
+	}
+	return EstimatingTokenizer
+}
+
+// RetrieveContext retrieves chunks for a query with hybrid search and packs them into
+// req.MaxTokens, or the configured context budget, without generating an answer. With
+// ParentContext the sections holding the chunks are packed instead.
+func (s *RAGService) RetrieveContext(ctx context.Context, req *models.ContextRequest) (*models.AssembledContext, error) {
+	cfg := s.config.Load()
+
+	query := strings.TrimSpace(req.Query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = cfg.MaxContextTokens
+	}
+
+	results, err := s.retrieve(ctx, cfg, query, req.Limit, req.SemanticWeight)
+	if err != nil {
+		return nil, err
+	}
+	var hits map[string][]models.ContextChildHit
+	if req.ParentContext {
+		if results, hits, err = s.parentSections(ctx, results); err != nil {
+			return nil, err
+		}
+	}
+	assembled := NewContextBuilder(s.currentTokenizer(), maxTokens).WithOrder(req.Order).Build(results)
+	annotateChildHits(assembled, hits)
+	return assembled, nil
+}
+
+// retrieve runs hybrid search with request overrides of the configured defaults
+func (s *RAGService) retrieve(ctx context.Context, cfg *config.RAGConfig, query string, limit int, semanticWeight *float64) ([]models.SimilarityResult, error) {
+	if limit <= 0 {
+		limit = cfg.RetrievalLimit
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	weight := cfg.SemanticWeight
+	if semanticWeight != nil {
+		weight = *semanticWeight
+	}
+
+	results, err := s.search.HybridSearch(ctx, query, limit, weight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve context: %w", err)
+	}
+	if s.access == nil || len(results) == 0 {
+		return results, nil
+	}
+	return s.filterResults(ctx, results)
+}
+
+// filterResults keeps the retrieved chunks the access filter allows, in order
+func (s *RAGService) filterResults(ctx context.Context, results []models.SimilarityResult) ([]models.SimilarityResult, error) {
+	chunkIDs := make([]string, len(results))
+	for i, result := range results {
+		chunkIDs[i] = result.Chunk.ID
+	}
+	allowed, err := s.access.FilterChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter retrieved context: %w", err)
+	}
+	if len(allowed) == len(results) {
+		return results, nil
+	}
+
+	keep := make(map[string]bool, len(allowed))
+	for _, id := range allowed {
+		keep[id] = true
+	}
+	filtered := make([]models.SimilarityResult, 0, len(allowed))
+	for _, result := range results {
+		if keep[result.Chunk.ID] {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered, nil
+}
+
+// ragSource is a retrieved chunk selected for the context window
+type ragSource struct {
+	citation models.AnswerCitation
+	content  string
+}
+
+// Ask retrieves relevant chunks with hybrid search and synthesizes a cited answer
+func (s *RAGService) Ask(ctx context.Context, req *models.AskRequest) (*models.AskResponse, error) {
+	start := time.Now()
+	cfg := s.config.Load()
+
+	question := strings.TrimSpace(req.Question)
+	if question == "" {
+		return nil, fmt.Errorf("question is required")
+	}
+
+	results, err := s.retrieve(ctx, cfg, question, req.Limit, req.SemanticWeight)
+	if err != nil {
+		return nil, err
+	}
+	if req.ParentContext {
+		if results, _, err = s.parentSections(ctx, results); err != nil {
+			return nil, err
+		}
+	}
+
+	tokenizer := s.currentTokenizer()
+	budget := cfg.MaxContextTokens - tokenizer.CountTokens(question) - ragPromptOverheadTokens
+	sources := ragSourcesFromContext(NewContextBuilder(tokenizer, budget).Build(results))
+
+	response := &models.AskResponse{
+		Question:  question,
+		Sentences: []models.AnswerSentence{},
+		Citations: []models.AnswerCitation{},
+		Sources:   make([]models.AnswerCitation, len(sources)),
+	}
+	for i, source := range sources {
+		response.Sources[i] = source.citation
+	}
+
+	if len(sources) == 0 {
+		response.Answer = ragNoContextAnswer
+		response.Sentences = append(response.Sentences, models.AnswerSentence{Text: ragNoContextAnswer, Citations: []models.AnswerCitation{}})
+		response.Duration = time.Since(start)
+		return response, nil
+	}
+
+	maxTokens := req.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = cfg.MaxAnswerTokens
+	}
+
+	completion, err := s.provider.Complete(ctx, &CompletionRequest{
+		System:      ragSystemPrompt,
+		Prompt:      buildRAGPrompt(question, sources),
+		MaxTokens:   maxTokens,
+		Temperature: cfg.Temperature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+
+	response.Answer = strings.TrimSpace(completion.Text)
+	response.Sentences, response.Citations = attachCitations(response.Answer, sources)
+	response.Model = completion.Model
+	response.Usage = completion.Usage
+	response.Duration = time.Since(start)
+	return response, nil
+}
+
+// selectContextSources packs the most relevant chunks into the token budget with the
+// estimating tokenizer
+func selectContextSources(results []models.SimilarityResult, budget int) []ragSource {
+	return ragSourcesFromContext(NewContextBuilder(nil, budget).Build(results))
+}
+
+// ragSourcesFromContext numbers context blocks as citable sources
+func ragSourcesFromContext(assembled *models.AssembledContext) []ragSource {
+	sources := make([]ragSource, 0, len(assembled.Blocks))
+	for _, block := range assembled.Blocks {
+		sources = append(sources, ragSource{
+			citation: models.AnswerCitation{
+				Index:      block.Index,
+				ChunkID:    block.ChunkID,
+				Snippet:    ragSnippet(block.Content, ragSnippetLength),
+				Similarity: block.Similarity,
+			},
+			content: block.Content,
+		})
+	}
+	return sources
+}
+
+// buildRAGPrompt lays out the numbered sources followed by the question
+func buildRAGPrompt(question string, sources []ragSource) string {
+	var prompt strings.Builder
+	prompt.WriteString("Sources:\n\n")
+	for _, source := range sources {
+		prompt.WriteString(fmt.Sprintf("[%d] %s\n\n", source.citation.Index, source.content))
+	}
+	prompt.WriteString("Question: ")
+	prompt.WriteString(question)
+	prompt.WriteString("\n\nAnswer with citations:")
+	return prompt.String()
+}
+
+// attachCitations splits an answer into sentences and resolves their citation markers.
+// It also returns the distinct cited sources in order of first use.
+func attachCitations(answer string, sources []ragSource) ([]models.AnswerSentence, []models.AnswerCitation) {
+	byIndex := make(map[int]models.AnswerCitation, len(sources))
+	for _, source := range sources {
+		byIndex[source.citation.Index] = source.citation
+	}
+
+	sentences := []models.AnswerSentence{}
+	citations := []models.AnswerCitation{}
+	cited := make(map[int]bool)
+
+	for _, sentence := range splitSentences(answer) {
+		answerSentence := models.AnswerSentence{Citations: []models.AnswerCitation{}}
+		sentenceCited := make(map[int]bool)
+
+		for _, match := range citationMarkerPattern.FindAllStringSubmatch(sentence, -1) {
+			for _, part := range strings.Split(match[1], ",") {
+				index, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					continue
+				}
+				citation, ok := byIndex[index]
+				if !ok || sentenceCited[index] {
+					continue
+				}
+				sentenceCited[index] = true
+				answerSentence.Citations = append(answerSentence.Citations, citation)
+				if !cited[index] {
+					cited[index] = true
+					citations = append(citations, citation)
+				}
+			}
+		}
+
+		text := strings.Join(strings.Fields(citationMarkerPattern.ReplaceAllString(sentence, "")), " ")
+		answerSentence.Text = spaceBeforePunctuationPattern.ReplaceAllString(text, "$1")
+		if answerSentence.Text != "" {
+			sentences = append(sentences, answerSentence)
+		}
+	}
+
+	return sentences, citations
+}
+
+// splitSentences splits text on sentence terminators and newlines, keeping
+// citation markers that follow a terminator with the preceding sentence
+func splitSentences(text string) []string {
+	var sentences []string
+	var current strings.Builder
+
+	flush := func() {
+		if sentence := strings.TrimSpace(current.String()); sentence != "" {
+			sentences = append(sentences, sentence)
+		}
+		current.Reset()
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' {
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+
+		if !isSentenceTerminator(r) {
+			continue
+		}
+		// Decimal points and similar are not sentence ends
+		if r == '.' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != '[' {
+			continue
+		}
+
+		// Absorb trailing citation markers such as "... end. [1][2]"
+		j := i + 1
+		for {
+			k := j
+			for k < len(runes) && runes[k] == ' ' {
+				k++
+			}
+			marker := citationMarkerPattern.FindStringIndex(string(runes[k:]))
+			if marker == nil || marker[0] != 0 {
+				break
+			}
+			markerRunes := utf8.RuneCountInString(string(runes[k:])[:marker[1]])
+			current.WriteString(string(runes[j : k+markerRunes]))
+			j = k + markerRunes
+		}
+		i = j - 1
+		flush()
+	}
+	flush()
+
+	return sentences
+}
+
+func isSentenceTerminator(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// estimateTokens approximates the token count of text: CJK characters count as
+// one token each and other text as one token per four characters
+func estimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r) {
+			cjk++
+		} else {
+			other++
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// ragSnippet returns a whitespace-collapsed prefix of text of at most maxRunes runes
+func ragSnippet(text string, maxRunes int) string {
+	collapsed := strings.Join(strings.Fields(text), " ")
+	runes := []rune(collapsed)
+	if len(runes) <= maxRunes {
+		return collapsed
+	}
+	return strings.TrimSpace(string(runes[:maxRunes])) + "…"
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCompletionProvider returns a canned answer and records the request
+type fakeCompletionProvider struct {
+	answer  string
+	request *CompletionRequest
+}
+
+func (f *fakeCompletionProvider) Name() string { return "fake" }
+
+func (f *fakeCompletionProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	f.request = req
+	return &CompletionResponse{Text: f.answer, Model: "fake-model"}, nil
+}
+
+func testRAGConfig() *config.RAGConfig {
+	return &config.RAGConfig{
+		RetrievalLimit:   10,
+		SemanticWeight:   0.7,
+		MaxContextTokens: 1000,
+		MaxAnswerTokens:  256,
+	}
+}
+
+func similarity(id, content string, score float64) models.SimilarityResult {
+	return models.SimilarityResult{Chunk: models.ChunkRecord{ID: id, Content: content}, Similarity: score}
+}
+
+func TestSplitSentences(t *testing.T) {
+	sentences := splitSentences("Go was released in 2009. [1] It costs 3.5 dollars! Really? [2][3]\n新的一行。好的")
+	assert.Equal(t, []string{
+		"Go was released in 2009. [1]",
+		"It costs 3.5 dollars!",
+		"Really? [2][3]",
+		"新的一行。",
+		"好的",
+	}, sentences)
+}
+
+func TestAttachCitations(t *testing.T) {
+	sources := selectContextSources([]models.SimilarityResult{
+		similarity("chunk-a", "Alpha content", 0.9),
+		similarity("chunk-b", "Beta content", 0.8),
+	}, 100)
+
+	sentences, citations := attachCitations("Alpha is first [1]. Both matter [2, 1]. Unknown source [7].", sources)
+
+	require.Len(t, sentences, 3)
+	assert.Equal(t, "Alpha is first.", sentences[0].Text)
+	require.Len(t, sentences[0].Citations, 1)
+	assert.Equal(t, "chunk-a", sentences[0].Citations[0].ChunkID)
+	assert.Equal(t, "Both matter.", sentences[1].Text)
+	require.Len(t, sentences[1].Citations, 2)
+	assert.Equal(t, "chunk-b", sentences[1].Citations[0].ChunkID)
+	assert.Empty(t, sentences[2].Citations)
+
+	require.Len(t, citations, 2)
+	assert.Equal(t, "chunk-a", citations[0].ChunkID)
+	assert.Equal(t, "chunk-b", citations[1].ChunkID)
+}
+
+func TestSelectContextSources(t *testing.T) {
+	long := strings.Repeat("word ", 100) // ~125 tokens
+
+	t.Run("respects the token budget", func(t *testing.T) {
+		sources := selectContextSources([]models.SimilarityResult{
+			similarity("a", "short one", 0.9),
+			similarity("b", long, 0.8),
+			similarity("a", "short one", 0.7),
+			similarity("c", "short two", 0.6),
+		}, 20)
+
+		require.Len(t, sources, 2)
+		assert.Equal(t, "a", sources[0].citation.ChunkID)
+		assert.Equal(t, "c", sources[1].citation.ChunkID)
+		assert.Equal(t, 2, sources[1].citation.Index)
+	})
+
+	t.Run("truncates an oversized top result", func(t *testing.T) {
+		sources := selectContextSources([]models.SimilarityResult{similarity("b", long, 0.8)}, 10)
+
+		require.Len(t, sources, 1)
+		assert.LessOrEqual(t, estimateTokens(sources[0].content), 10)
+	})
+}
+
+func TestRAGService_Ask(t *testing.T) {
+	t.Run("answers with citations", func(t *testing.T) {
+		search := new(MockSearchService)
+		search.On("HybridSearch", mock.Anything, "What is Go?", 10, 0.7).Return([]SimilarityResult{
+			similarity("chunk-go", "Go is a programming language.", 0.95),
+		}, nil)
+		provider := &fakeCompletionProvider{answer: "Go is a programming language [1]."}
+
+		response, err := NewRAGService(search, provider, testRAGConfig()).Ask(context.Background(), &models.AskRequest{Question: " What is Go? "})
+
+		require.NoError(t, err)
+		assert.Equal(t, "Go is a programming language [1].", response.Answer)
+		require.Len(t, response.Sentences, 1)
+		assert.Equal(t, "chunk-go", response.Sentences[0].Citations[0].ChunkID)
+		assert.Equal(t, "fake-model", response.Model)
+		assert.Contains(t, provider.request.Prompt, "[1] Go is a programming language.")
+		assert.Equal(t, 256, provider.request.MaxTokens)
+		search.AssertExpectations(t)
+	})
+
+	t.Run("skips the LLM without context", func(t *testing.T) {
+		search := new(MockSearchService)
+		search.On("HybridSearch", mock.Anything, "Unknown?", 5, 0.2).Return([]SimilarityResult{}, nil)
+		provider := &fakeCompletionProvider{}
+		weight := 0.2
+
+		response, err := NewRAGService(search, provider, testRAGConfig()).Ask(context.Background(), &models.AskRequest{Question: "Unknown?", Limit: 5, SemanticWeight: &weight})
+
+		require.NoError(t, err)
+		assert.Equal(t, ragNoContextAnswer, response.Answer)
+		assert.Nil(t, provider.request)
+	})
+
+	t.Run("leaves out chunks the caller may not read", func(t *testing.T) {
+		search := new(MockSearchService)
+		search.On("HybridSearch", mock.Anything, "Salaries?", 10, 0.7).Return([]SimilarityResult{
+			similarity("chunk-secret", "Alice earns 100.", 0.95),
+			similarity("chunk-open", "Salaries are reviewed yearly.", 0.8),
+		}, nil)
+		provider := &fakeCompletionProvider{answer: "Salaries are reviewed yearly [1]."}
+		service := NewRAGService(search, provider, testRAGConfig())
+		service.SetAccessFilter(&fixedAccessFilter{readable: map[string]bool{"chunk-open": true}})
+
+		response, err := service.Ask(context.Background(), &models.AskRequest{Question: "Salaries?"})
+
+		require.NoError(t, err)
+		require.Len(t, response.Sources, 1)
+		assert.Equal(t, "chunk-open", response.Sources[0].ChunkID)
+		assert.NotContains(t, provider.request.Prompt, "Alice")
+	})
+
+	t.Run("rejects an empty question", func(t *testing.T) {
+		_, err := NewRAGService(new(MockSearchService), &fakeCompletionProvider{}, testRAGConfig()).Ask(context.Background(), &models.AskRequest{})
+		assert.Error(t, err)
+	})
+}
+
+func TestCompletionProvider_OpenAI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "test-model", body["model"])
+		assert.Len(t, body["messages"], 2)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"model":"test-model","choices":[{"message":{"content":"Hello [1]."}}],"usage":{"prompt_tokens":12,"completion_tokens":3}}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewCompletionProvider(&config.RAGConfig{
+		Provider: CompletionProviderOpenAI,
+		Endpoint: server.URL,
+		APIKey:   "secret",
+		Model:    "test-model",
+		Timeout:  5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	response, err := provider.Complete(context.Background(), &CompletionRequest{System: "system", Prompt: "prompt", MaxTokens: 10})
+	require.NoError(t, err)
+	assert.Equal(t, "Hello [1].", response.Text)
+	assert.Equal(t, 12, response.Usage.PromptTokens)
+
+	_, err = NewCompletionProvider(&config.RAGConfig{Provider: "unknown"})
+	assert.Error(t, err)
+}
+
+// staticHierarchy serves ancestors and children from parent links
+type staticHierarchy map[string]models.UnifiedChunkRecord
+
+func (h staticHierarchy) GetAncestors(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error) {
+	var ancestors []models.UnifiedChunkRecord
+	for chunk := h[chunkID]; chunk.Parent != nil; chunk = h[*chunk.Parent] {
+		ancestors = append([]models.UnifiedChunkRecord{h[*chunk.Parent]}, ancestors...)
+	}
+	return ancestors, nil
+}
+
+func (h staticHierarchy) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	children := make(map[string][]models.UnifiedChunkRecord)
+	for _, id := range []string{"intro", "goroutines", "channels", "history"} {
+		if chunk := h[id]; chunk.Parent != nil {
+			children[*chunk.Parent] = append(children[*chunk.Parent], chunk)
+		}
+	}
+	return children, nil
+}
+
+func TestRAGService_RetrieveContext_ParentContext(t *testing.T) {
+	page, section := "page", "concurrency"
+	hierarchy := staticHierarchy{
+		"page":        {ChunkID: "page", Contents: "Go", IsPage: true},
+		"concurrency": {ChunkID: "concurrency", Contents: "## Concurrency", Parent: &page},
+		"goroutines":  {ChunkID: "goroutines", Contents: "Goroutines are lightweight threads.", Parent: &section},
+		"channels":    {ChunkID: "channels", Contents: "Channels connect goroutines.", Parent: &section},
+		"history":     {ChunkID: "history", Contents: "Go was released in 2009.", Parent: &page},
+	}
+	search := new(MockSearchService)
+	search.On("HybridSearch", mock.Anything, "goroutines", 10, 0.7).Return([]SimilarityResult{
+		similarity("concurrency", "## Concurrency", 0.95),
+		similarity("goroutines", "Goroutines are lightweight threads.", 0.9),
+		similarity("history", "Go was released in 2009.", 0.6),
+		similarity("channels", "Channels connect goroutines.", 0.5),
+	}, nil)
+	service := NewRAGService(search, &fakeCompletionProvider{}, testRAGConfig())
+
+	_, err := service.RetrieveContext(context.Background(), &models.ContextRequest{Query: "goroutines", ParentContext: true})
+	assert.ErrorIs(t, err, ErrParentContextUnavailable)
+
+	service.SetHierarchySource(hierarchy)
+	assembled, err := service.RetrieveContext(context.Background(), &models.ContextRequest{Query: "goroutines", ParentContext: true})
+	require.NoError(t, err)
+	require.Len(t, assembled.Blocks, 2, "chunks in the same section share a block")
+
+	assert.Equal(t, "concurrency", assembled.Blocks[0].ChunkID)
+	assert.Equal(t, "## Concurrency\nGoroutines are lightweight threads.\nChannels connect goroutines.", assembled.Blocks[0].Content)
+	assert.Equal(t, 0.95, assembled.Blocks[0].Similarity)
+	assert.Equal(t, []models.ContextChildHit{
+		{ChunkID: "concurrency", Similarity: 0.95},
+		{ChunkID: "goroutines", Similarity: 0.9},
+		{ChunkID: "channels", Similarity: 0.5},
+	}, assembled.Blocks[0].ChildHits, "a retrieved parent is one of its section's hits")
+
+	assert.Equal(t, "history", assembled.Blocks[1].ChunkID, "chunks directly on a page are not expanded to the page")
+	assert.Empty(t, assembled.Blocks[1].ChildHits)
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	markdownHeading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)(?:\s+#+)?\s*$`)
+	markdownRule     = regexp.MustCompile(`^(?:-{3,}|\*{3,}|_{3,})$`)
+	markdownFence    = regexp.MustCompile("^(`{3,}|~{3,})\\s*([\\w+#.-]*)")
+	markdownListItem = regexp.MustCompile(`^([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	markdownEmbed    = regexp.MustCompile(`^\{\{embed\s+\(\(([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\)\)\s*\}\}$`)
+	markdownImage    = regexp.MustCompile(`^!\[([^\]]*)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	markdownInline   = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+	markdownPageLink = regexp.MustCompile(`^\[\[([^\[\]]+)\]\]`)
+	markdownTagLink  = regexp.MustCompile(`^#(?:\[\[([^\[\]]+)\]\]|([^\s#\[\](),.;:!?"'<>]+))`)
+	markdownBlockRef = regexp.MustCompile(`^\(\(([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\)\)`)
+	markdownAutolink = regexp.MustCompile(`^https?://[^\s<>"]+`)
+)
+
+// markdownHTML renders the Markdown of chunk contents as HTML. Text is always escaped and
+// only links with safe schemes are kept, so the output can be embedded as is. Page links,
+// block references and embeds are rendered by the callbacks.
+type markdownHTML struct {
+	// pageHref returns the link of the page titled title, if there is one
+	pageHref func(title string) (string, bool)
+	// blockRef returns the HTML of a ((uuid)) block reference
+	blockRef func(chunkID string) string
+	// embed returns the HTML of an {{embed ((uuid))}} line
+	embed func(chunkID string) string
+}
+
+// blocks renders paragraphs, headings, fenced code, quotes, rules, lists and embeds
+func (m *markdownHTML) blocks(text string) string {
+	var b strings.Builder
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = expandLeadingTabs(line)
+	}
+
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) == 0 {
+			return
+		}
+		b.WriteString("<p>")
+		for i, line := range paragraph {
+			if i > 0 {
+				b.WriteString("<br>\n")
+			}
+			b.WriteString(m.inline(line))
+		}
+		b.WriteString("</p>\n")
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		if match := markdownFence.FindStringSubmatch(trimmed); match != nil {
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), match[1]); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code")
+			if match[2] != "" {
+				fmt.Fprintf(&b, ` class="language-%s"`, html.EscapeString(match[2]))
+			}
+			fmt.Fprintf(&b, ">%s</code></pre>\n", html.EscapeString(strings.Join(code, "\n")))
+			continue
+		}
+		if match := markdownEmbed.FindStringSubmatch(trimmed); match != nil {
+			flush()
+			b.WriteString(m.embed(strings.ToLower(match[1])))
+			continue
+		}
+		if match := markdownHeading.FindStringSubmatch(trimmed); match != nil {
+			flush()
+			level := len(match[1])
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, m.inline(match[2]), level)
+			continue
+		}
+		if markdownRule.MatchString(strings.ReplaceAll(trimmed, " ", "")) {
+			flush()
+			b.WriteString("<hr>\n")
+			continue
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			flush()
+			var quote []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				line := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quote = append(quote, strings.TrimPrefix(line, " "))
+			}
+			i--
+			fmt.Fprintf(&b, "<blockquote>\n%s</blockquote>\n", m.blocks(strings.Join(quote, "\n")))
+			continue
+		}
+		if markdownListItem.MatchString(trimmed) && len(paragraph) == 0 {
+			i = m.list(&b, lines, i) - 1
+			continue
+		}
+		paragraph = append(paragraph, trimmed)
+	}
+	flush()
+	return b.String()
+}
+
+// list renders the list starting at lines[start] and returns the index of the first line
+// after it. Lines indented deeper than an item belong to it.
+func (m *markdownHTML) list(b *strings.Builder, lines []string, start int) int {
+	indent := leadingSpaces(lines[start])
+	first := markdownListItem.FindStringSubmatch(strings.TrimSpace(lines[start]))
+	ordered := unicode.IsDigit(rune(first[1][0]))
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+	}
+
+	fmt.Fprintf(b, "<%s>\n", tag)
+	i := start
+	for i < len(lines) {
+		match := markdownListItem.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if match == nil || leadingSpaces(lines[i]) != indent || unicode.IsDigit(rune(match[1][0])) != ordered {
+			break
+		}
+
+		var nested []string
+		for i++; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "" {
+				// A blank line only continues the item if indented lines follow it
+				if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && leadingSpaces(lines[i+1]) > indent {
+					nested = append(nested, "")
+					continue
+				}
+				break
+			}
+			if leadingSpaces(lines[i]) <= indent {
+				break
+			}
+			nested = append(nested, lines[i])
+		}
+
+		b.WriteString("<li>")
+		b.WriteString(m.inline(match[2]))
+		if len(nested) > 0 {
+			b.WriteString("\n")
+			b.WriteString(m.blocks(dedentLines(nested)))
+		}
+		b.WriteString("</li>\n")
+	}
+	fmt.Fprintf(b, "</%s>\n", tag)
+	return i
+}
+
+// inline renders code spans, emphasis, links, images, page links, tags, block references
+// and bare URLs within one line
+func (m *markdownHTML) inline(text string) string {
+	var b strings.Builder
+	plain := 0
+	for i := 0; i < len(text); {
+		wordStart := i == 0 || !isWordByte(text[i-1])
+		rendered, n := m.inlineToken(text[i:], wordStart)
+		if n == 0 {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		b.WriteString(html.EscapeString(text[plain:i]))
+		b.WriteString(rendered)
+		i += n
+		plain = i
+	}
+	b.WriteString(html.EscapeString(text[plain:]))
+	return b.String()
+}
+
+// inlineToken renders the inline element at the start of rest and returns its length, or
+// 0 if rest does not start with one
+func (m *markdownHTML) inlineToken(rest string, wordStart bool) (string, int) {
+	switch rest[0] {
+	case '`':
+		if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+			return "<code>" + html.EscapeString(rest[1:1+end]) + "</code>", end + 2
+		}
+	case '!':
+		if match := markdownImage.FindStringSubmatch(rest); match != nil {
+			if href, ok := safeLinkURL(match[2]); ok {
+				return fmt.Sprintf(`<img src="%s" alt="%s">`, html.EscapeString(href), html.EscapeString(match[1])), len(match[0])
+			}
+			return html.EscapeString(match[1]), len(match[0])
+		}
+	case '[':
+		if match := markdownPageLink.FindStringSubmatch(rest); match != nil {
+			return m.pageLink(match[1], "page-ref", match[1]), len(match[0])
+		}
+		if match := markdownInline.FindStringSubmatch(rest); match != nil {
+			if href, ok := safeLinkURL(match[2]); ok {
+				return fmt.Sprintf(`<a href="%s" rel="nofollow noopener">%s</a>`, html.EscapeString(href), m.inline(match[1])), len(match[0])
+			}
+			return m.inline(match[1]), len(match[0])
+		}
+	case '(':
+		if match := markdownBlockRef.FindStringSubmatch(rest); match != nil {
+			return m.blockRef(strings.ToLower(match[1])), len(match[0])
+		}
+	case '#':
+		if match := markdownTagLink.FindStringSubmatch(rest); match != nil && wordStart {
+			title := match[1] + match[2]
+			return m.pageLink(title, "tag", "#"+title), len(match[0])
+		}
+	case 'h':
+		if match := markdownAutolink.FindString(rest); match != "" && wordStart {
+			match = strings.TrimRight(match, ".,;:!?)'")
+			if href, ok := safeLinkURL(match); ok {
+				return fmt.Sprintf(`<a href="%s" rel="nofollow noopener">%s</a>`, html.EscapeString(href), html.EscapeString(match)), len(match)
+			}
+		}
+	case '*', '_', '~':
+		return m.emphasis(rest, wordStart)
+	}
+	return "", 0
+}
+
+// emphasis renders **strong**, __strong__, *em*, _em_ and ~~del~~. Underscores only
+// open emphasis at the start of a word, so snake_case names stay as they are.
+func (m *markdownHTML) emphasis(rest string, wordStart bool) (string, int) {
+	if rest[0] == '_' && !wordStart {
+		return "", 0
+	}
+	for _, style := range []struct{ delim, tag string }{
+		{"**", "strong"}, {"__", "strong"}, {"~~", "del"}, {"*", "em"}, {"_", "em"},
+	} {
+		if !strings.HasPrefix(rest, style.delim) {
+			continue
+		}
+		inner := rest[len(style.delim):]
+		end := strings.Index(inner, style.delim)
+		if end <= 0 || inner[0] == ' ' || inner[end-1] == ' ' {
+			continue
+		}
+		return fmt.Sprintf("<%s>%s</%s>", style.tag, m.inline(inner[:end]), style.tag), end + 2*len(style.delim)
+	}
+	return "", 0
+}
+
+// pageLink renders a link to the page titled title, or the text alone if there is no such page
+func (m *markdownHTML) pageLink(title, class, text string) string {
+	if href, ok := m.pageHref(title); ok {
+		return fmt.Sprintf(`<a class="%s" href="%s">%s</a>`, class, html.EscapeString(href), html.EscapeString(text))
+	}
+	return fmt.Sprintf(`<span class="%s">%s</span>`, class, html.EscapeString(text))
+}
+
+// safeLinkURL accepts relative URLs and http, https and mailto URLs
+func safeLinkURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if strings.IndexFunc(raw, unicode.IsControl) >= 0 {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return raw, true
+	}
+	return "", false
+}
+
+// expandLeadingTabs replaces the tabs indenting a line with four spaces each
+func expandLeadingTabs(line string) string {
+	tabs := len(line) - len(strings.TrimLeft(line, "\t"))
+	return strings.Repeat("    ", tabs) + line[tabs:]
+}
+
+func leadingSpaces(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// dedentLines removes the indentation the non-blank lines have in common
+func dedentLines(lines []string) string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" && (common < 0 || leadingSpaces(line) < common) {
+			common = leadingSpaces(line)
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= common && common > 0 {
+			line = line[common:]
+		}
+		out[i] = line
+	}
+	return strings.Join(out, "\n")
+}
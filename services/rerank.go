@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// RerankModelLLM selects the LLM relevance scorer instead of a cross-encoder model
+const RerankModelLLM = "llm"
+
+const (
+	// maxRerankDocumentChars caps the whitespace-collapsed text of each candidate sent to a scorer
+	maxRerankDocumentChars = 2000
+	// llmRerankMaxScore is the top of the scale the LLM rates candidates on
+	llmRerankMaxScore = 10
+)
+
+// ErrRerankUnavailable is returned when a search asks for a scorer that is not configured
+var ErrRerankUnavailable = errors.New("re-ranking is not available")
+
+// Reranker scores how relevant each document is to a query, in document order.
+// Higher scores are more relevant.
+type Reranker interface {
+	Rerank(ctx context.Context, query string, documents []string) ([]float64, error)
+}
+
+// RerankService selects the scorer a search asked for and orders candidates by its scores
+type RerankService struct {
+	crossEncoder *crossEncoderReranker
+	llm          Reranker
+	model        string
+	candidates   int
+}
+
+// NewRerankService creates a re-ranking service from configuration. completion may be nil,
+// in which case only the cross-encoder is available.
+func NewRerankService(cfg *config.RerankConfig, completion CompletionProvider) *RerankService {
+	service := &RerankService{model: cfg.Model, candidates: cfg.Candidates}
+	if cfg.Endpoint != "" {
+		service.crossEncoder = &crossEncoderReranker{
+			endpoint: cfg.Endpoint,
+			apiKey:   cfg.APIKey,
+			client:   &http.Client{Timeout: cfg.Timeout},
+		}
+	}
+	if completion != nil {
+		service.llm = &llmReranker{completion: completion}
+	}
+	return service
+}
+
+// Available reports whether any scorer is configured
+func (s *RerankService) Available() bool {
+	return s.crossEncoder != nil || s.llm != nil
+}
+
+// Candidates returns how many vector results to score for a search returning limit results
+func (s *RerankService) Candidates(limit int) int {
+	if s.candidates > limit {
+		return s.candidates
+	}
+	return limit
+}
+
+// ResolveModel returns the model a request is scored with: the requested one, the
+// configured default, or the LLM scorer when no cross-encoder is configured
+func (s *RerankService) ResolveModel(model string) (string, error) {
+	if model == "" {
+		model = s.model
+	}
+	if model == "" && s.crossEncoder == nil {
+		model = RerankModelLLM
+	}
+
+	if model == RerankModelLLM {
+		if s.llm == nil {
+			return "", fmt.Errorf("%w: no completion provider is configured for the llm scorer", ErrRerankUnavailable)
+		}
+		return model, nil
+	}
+	if s.crossEncoder == nil {
+		return "", fmt.Errorf("%w: no cross-encoder endpoint is configured", ErrRerankUnavailable)
+	}
+	return model, nil
+}
+
+// Rerank scores results with the model from ResolveModel, sets their relevance to the
+// score and returns them most relevant first. Equal scores keep the retrieval order.
+func (s *RerankService) Rerank(ctx context.Context, model, query string, results []models.OptimizedSearchResult) ([]models.OptimizedSearchResult, error) {
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	var scorer Reranker = s.llm
+	if model != RerankModelLLM {
+		scorer = s.crossEncoder.withModel(model)
+	}
+
+	documents := make([]string, len(results))
+	for i, result := range results {
+		documents[i] = ragSnippet(result.Content, maxRerankDocumentChars)
+	}
+	scores, err := scorer.Rerank(ctx, query, documents)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-rank search results: %w", err)
+	}
+	if len(scores) != len(results) {
+		return nil, fmt.Errorf("failed to re-rank search results: got %d scores for %d results", len(scores), len(results))
+	}
+
+	reranked := make([]models.OptimizedSearchResult, len(results))
+	for i, result := range results {
+		result.Relevance = scores[i]
+		reranked[i] = result
+	}
+	sort.SliceStable(reranked, func(i, j int) bool {
+		return reranked[i].Relevance > reranked[j].Relevance
+	})
+	return reranked, nil
+}
+
+// crossEncoderReranker calls a Cohere or Jina style rerank API, which scores query and
+// document pairs with a cross-encoder model
+type crossEncoderReranker struct {
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+}
+
+func (r *crossEncoderReranker) withModel(model string) *crossEncoderReranker {
+	scoped := *r
+	scoped.model = model
+	return &scoped
+}
+
+func (r *crossEncoderReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	body := map[string]interface{}{
+		"query":     query,
+		"documents": documents,
+		"top_n":     len(documents),
+	}
+	if r.model != "" {
+		body["model"] = r.model
+	}
+
+	var response struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	headers := map[string]string{}
+	if r.apiKey != "" {
+		headers["Authorization"] = "Bearer " + r.apiKey
+	}
+	if err := postCompletion(ctx, r.client, r.endpoint, headers, body, &response); err != nil {
+		return nil, err
+	}
+
+	// Documents the API leaves out rank last
+	scores := make([]float64, len(documents))
+	for i := range scores {
+		scores[i] = -1
+	}
+	for _, result := range response.Results {
+		if result.Index < 0 || result.Index >= len(documents) {
+			return nil, fmt.Errorf("rerank API returned an unknown document index %d", result.Index)
+		}
+		scores[result.Index] = result.RelevanceScore
+	}
+	return scores, nil
+}
+
+// llmReranker asks the completion provider to rate every candidate in a single prompt
+type llmReranker struct {
+	completion CompletionProvider
+}
+
+const llmRerankSystemPrompt = "You rate how well passages answer a search query. Rate every passage from 0 " +
+	"(irrelevant) to 10 (answers the query directly). Reply with only a JSON array of the ratings, " +
+	"one number per passage, in passage order."
+
+func (r *llmReranker) Rerank(ctx context.Context, query string, documents []string) ([]float64, error) {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Query: %s\n\n", query)
+	for i, document := range documents {
+		fmt.Fprintf(&prompt, "[%d] %s\n\n", i+1, document)
+	}
+	fmt.Fprintf(&prompt, "Ratings for the %d passages:", len(documents))
+
+	response, err := r.completion.Complete(ctx, &CompletionRequest{
+		System:    llmRerankSystemPrompt,
+		Prompt:    prompt.String(),
+		MaxTokens: 16 + 6*len(documents),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	scores, err := parseLLMRatings(response.Text, len(documents))
+	if err != nil {
+		return nil, err
+	}
+	for i := range scores {
+		scores[i] /= llmRerankMaxScore
+	}
+	return scores, nil
+}
+
+// parseLLMRatings reads the JSON array of ratings from a completion, ignoring any text
+// around it, and clamps each rating to the rating scale
+func parseLLMRatings(text string, count int) ([]float64, error) {
+	start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("LLM scorer returned no ratings: %q", ragSnippet(text, 200))
+	}
+
+	var ratings []float64
+	if err := json.Unmarshal([]byte(text[start:end+1]), &ratings); err != nil {
+		return nil, fmt.Errorf("LLM scorer returned malformed ratings: %w", err)
+	}
+	if len(ratings) != count {
+		return nil, fmt.Errorf("LLM scorer returned %d ratings for %d passages", len(ratings), count)
+	}
+	for i, rating := range ratings {
+		ratings[i] = math.Max(0, math.Min(rating, llmRerankMaxScore))
+	}
+	return ratings, nil
+}
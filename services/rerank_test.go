@@ -0,0 +1,94 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+func rerankTestResults() []models.SimilarityResult {
+	return []models.SimilarityResult{
+		similarity("c1", "Jaguar is a car brand", 0.9),
+		similarity("c2", "The jaguar is a big cat of the Americas", 0.8),
+		similarity("c3", "Leopards   and jaguars\nlook alike", 0.7),
+	}
+}
+
+func TestRerankService_CrossEncoder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+
+		var body struct {
+			Model     string   `json:"model"`
+			Query     string   `json:"query"`
+			Documents []string `json:"documents"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "rerank-v3", body.Model)
+		assert.Equal(t, "jaguar animal", body.Query)
+		assert.Equal(t, "Leopards and jaguars look alike", body.Documents[2])
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"results":[{"index":1,"relevance_score":0.97},{"index":2,"relevance_score":0.41},{"index":0,"relevance_score":0.02}]}`))
+	}))
+	defer server.Close()
+
+	reranker := NewRerankService(&config.RerankConfig{
+		Endpoint:   server.URL,
+		APIKey:     "secret",
+		Model:      "rerank-v3",
+		Candidates: 30,
+		Timeout:    5 * time.Second,
+	}, nil)
+	service := NewOptimizedSearchService(&staticSearchService{results: rerankTestResults()}, nil, time.Minute)
+	service.SetReranker(reranker)
+
+	response, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar animal", Limit: 2, Rerank: true})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, "c2", response.Results[0].ChunkID)
+	assert.Equal(t, 0.97, response.Results[0].Relevance)
+	assert.Equal(t, 0.8, response.Results[0].Similarity, "the vector similarity is kept")
+	assert.Equal(t, "c3", response.Results[1].ChunkID)
+	assert.Contains(t, response.Metadata.ProcessingSteps, "rerank")
+
+	assert.Equal(t, 30, reranker.Candidates(10))
+	assert.Equal(t, 40, reranker.Candidates(40))
+
+	_, err = service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", Rerank: true, RerankModel: RerankModelLLM})
+	assert.ErrorIs(t, err, ErrRerankUnavailable, "the llm scorer needs a completion provider")
+}
+
+func TestRerankService_LLM(t *testing.T) {
+	completion := &fakeCompletionProvider{answer: "Ratings: [1, 9.5, 14]"}
+	service := NewOptimizedSearchService(&staticSearchService{results: rerankTestResults()}, nil, time.Minute)
+	service.SetReranker(NewRerankService(&config.RerankConfig{Candidates: 10}, completion))
+
+	response, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar animal", Rerank: true})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, []string{"c3", "c2", "c1"}, []string{response.Results[0].ChunkID, response.Results[1].ChunkID, response.Results[2].ChunkID})
+	assert.Equal(t, 1.0, response.Results[0].Relevance, "ratings are clamped to the scale")
+	assert.Equal(t, 0.95, response.Results[1].Relevance)
+	assert.Contains(t, completion.request.Prompt, "[2] The jaguar is a big cat of the Americas")
+
+	_, err = service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", Rerank: true, RerankModel: "rerank-v3"})
+	assert.ErrorIs(t, err, ErrRerankUnavailable, "cross-encoder models need an endpoint")
+
+	completion.answer = "[3, 4]"
+	_, err = service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar", Rerank: true})
+	assert.ErrorContains(t, err, "2 ratings for 3 passages")
+
+	response, err = service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "jaguar"})
+	require.NoError(t, err)
+	assert.Equal(t, "c1", response.Results[0].ChunkID, "results are only re-ranked on request")
+}
@@ -0,0 +1,537 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// RETENTION POLICIES
+// ============================================================================
+//
+// Retention rules belong to a workspace and are applied by the maintenance daemon on every
+// run, or on demand by an admin. A rule archives chunks not updated for its age, moves
+// stale subtrees to the trash, or purges trash entries older than its age. Tags,
+// templates and slots are structure other chunks depend on and are never selected.
+
+// ErrInvalidRetentionRule is returned for rules with an unknown action, a non-positive
+// age or a scope their action does not support
+var ErrInvalidRetentionRule = errors.New("invalid retention rule")
+
+// ErrRetentionRuleNotFound is returned for rules that do not exist in the caller's workspace
+var ErrRetentionRuleNotFound = errors.New("retention rule not found")
+
+const (
+	maxRetentionRuleNameLen = 100
+	// retentionBatchSize caps the chunks, or trash entries, one rule handles per run, so a
+	// first run over a large backlog does not hold the database for long
+	retentionBatchSize = 500
+	// retentionSampleSize is the number of matched chunk IDs listed in a report
+	retentionSampleSize = 20
+)
+
+// RetentionService manages per-workspace retention rules and applies them
+type RetentionService interface {
+	// ListRules returns the workspace's rules, oldest first
+	ListRules(ctx context.Context, workspace string) ([]models.RetentionRule, error)
+
+	// CreateRule adds a rule to workspace
+	CreateRule(ctx context.Context, workspace string, req *models.RetentionRuleRequest) (*models.RetentionRule, error)
+
+	// UpdateRule replaces a rule's settings
+	UpdateRule(ctx context.Context, workspace, ruleID string, req *models.RetentionRuleRequest) (*models.RetentionRule, error)
+
+	// DeleteRule removes a rule
+	DeleteRule(ctx context.Context, workspace, ruleID string) error
+
+	// Preview reports what the workspace's enabled rules would affect without changing
+	// anything
+	Preview(ctx context.Context, workspace string) (*models.RetentionReport, error)
+
+	// Run applies the workspace's enabled rules
+	Run(ctx context.Context, workspace string) (*models.RetentionReport, error)
+
+	// RunAll applies the enabled rules of every workspace; it is the maintenance daemon's job
+	RunAll(ctx context.Context) error
+}
+
+// retentionService implements RetentionService on the retention_rules table. Subtrees are
+// trashed through the chunk service so deletes behave as they do through the API;
+// archiving updates chunk metadata in bulk and clears the caches itself.
+type retentionService struct {
+	db          *sql.DB
+	chunks      UnifiedChunkService
+	cache       CacheService
+	searchCache SearchCacheService
+	monitor     QueryPerformanceMonitor
+}
+
+// NewRetentionService creates a retention service; either cache may be nil
+func NewRetentionService(db *sql.DB, chunks UnifiedChunkService, cache CacheService, searchCache SearchCacheService, monitor QueryPerformanceMonitor) RetentionService {
+	return &retentionService{
+		db:          db,
+		chunks:      chunks,
+		cache:       cache,
+		searchCache: searchCache,
+		monitor:     monitor,
+	}
+}
+
+const retentionRuleColumns = `id, workspace_id, name, action, max_age_days, tag_chunk_id, page_chunk_id, enabled,
+	created_at, updated_at, last_run_at, last_run_affected, last_run_error`
+
+// ListRules returns the workspace's rules, oldest first
+func (s *retentionService) ListRules(ctx context.Context, workspace string) ([]models.RetentionRule, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+	return s.listRules(ctx, "list_retention_rules",
+		"SELECT "+retentionRuleColumns+" FROM retention_rules WHERE workspace_id = $1 ORDER BY created_at",
+		workspace)
+}
+
+// CreateRule adds a rule to workspace
+func (s *retentionService) CreateRule(ctx context.Context, workspace string, req *models.RetentionRuleRequest) (*models.RetentionRule, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+	name, err := validateRetentionRule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.listRules(ctx, "create_retention_rule", `
+		INSERT INTO retention_rules (workspace_id, name, action, max_age_days, tag_chunk_id, page_chunk_id, enabled)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+retentionRuleColumns,
+		workspace, name, string(req.Action), req.MaxAgeDays, req.TagID, req.PageID, req.Enabled == nil || *req.Enabled)
+	if err != nil {
+		return nil, retentionScopeError(err)
+	}
+	return &rules[0], nil
+}
+
+// UpdateRule replaces a rule's settings
+func (s *retentionService) UpdateRule(ctx context.Context, workspace, ruleID string, req *models.RetentionRuleRequest) (*models.RetentionRule, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(ruleID); err != nil {
+		return nil, ErrRetentionRuleNotFound
+	}
+	name, err := validateRetentionRule(req)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.listRules(ctx, "update_retention_rule", `
+		UPDATE retention_rules SET name = $3, action = $4, max_age_days = $5, tag_chunk_id = $6,
+			page_chunk_id = $7, enabled = $8, updated_at = NOW()
+		WHERE id = $1 AND workspace_id = $2
+		RETURNING `+retentionRuleColumns,
+		ruleID, workspace, name, string(req.Action), req.MaxAgeDays, req.TagID, req.PageID, req.Enabled == nil || *req.Enabled)
+	if err != nil {
+		return nil, retentionScopeError(err)
+	}
+	if len(rules) == 0 {
+		return nil, ErrRetentionRuleNotFound
+	}
+	return &rules[0], nil
+}
+
+// DeleteRule removes a rule
+func (s *retentionService) DeleteRule(ctx context.Context, workspace, ruleID string) error {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(ruleID); err != nil {
+		return ErrRetentionRuleNotFound
+	}
+
+	start := time.Now()
+	result, err := s.db.ExecContext(ctx,
+		"DELETE FROM retention_rules WHERE id = $1 AND workspace_id = $2", ruleID, workspace)
+	s.monitor.RecordQuery("delete_retention_rule", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention rule: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return ErrRetentionRuleNotFound
+	}
+	return nil
+}
+
+// Preview reports what the workspace's enabled rules would affect
+func (s *retentionService) Preview(ctx context.Context, workspace string) (*models.RetentionReport, error) {
+	return s.apply(ctx, workspace, true)
+}
+
+// Run applies the workspace's enabled rules
+func (s *retentionService) Run(ctx context.Context, workspace string) (*models.RetentionReport, error) {
+	return s.apply(ctx, workspace, false)
+}
+
+// RunAll applies the enabled rules of every workspace. A failing rule does not stop the
+// others; its error is recorded on the rule and returned with the rest.
+func (s *retentionService) RunAll(ctx context.Context) error {
+	rules, err := s.listRules(ctx, "list_retention_rules",
+		"SELECT "+retentionRuleColumns+" FROM retention_rules WHERE enabled ORDER BY workspace_id, created_at")
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, rule := range rules {
+		report := s.applyRule(ctx, rule, false)
+		if report.Error != "" {
+			errs = append(errs, fmt.Errorf("retention rule %s in workspace %s: %s", rule.ID, rule.Workspace, report.Error))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// apply previews or runs the enabled rules of a workspace
+func (s *retentionService) apply(ctx context.Context, workspace string, dryRun bool) (*models.RetentionReport, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	workspace, err := NormalizeWorkspace(workspace)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := s.listRules(ctx, "list_retention_rules",
+		"SELECT "+retentionRuleColumns+" FROM retention_rules WHERE workspace_id = $1 AND enabled ORDER BY created_at",
+		workspace)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.RetentionReport{
+		Workspace:   workspace,
+		DryRun:      dryRun,
+		GeneratedAt: time.Now(),
+		Rules:       []models.RetentionRuleReport{},
+	}
+	for _, rule := range rules {
+		report.Rules = append(report.Rules, s.applyRule(ctx, rule, dryRun))
+	}
+	return report, nil
+}
+
+// applyRule reports what one rule matches and, unless dryRun, applies it and records the
+// outcome on the rule
+func (s *retentionService) applyRule(ctx context.Context, rule models.RetentionRule, dryRun bool) models.RetentionRuleReport {
+	report := models.RetentionRuleReport{
+		Rule:   rule,
+		Cutoff: time.Now().AddDate(0, 0, -rule.MaxAgeDays),
+	}
+
+	start := time.Now()
+	err := s.match(ctx, &report)
+	if err == nil && !dryRun && report.Matched > 0 {
+		switch rule.Action {
+		case models.RetentionArchive:
+			report.Affected, err = s.archive(ctx, rule, report.Cutoff)
+		case models.RetentionDelete:
+			report.Affected, err = s.trash(ctx, rule, report.Cutoff)
+		case models.RetentionPurgeTrash:
+			report.Affected, err = s.purgeTrash(ctx, report.Cutoff)
+		}
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+	s.monitor.RecordQuery("retention_"+string(rule.Action), time.Since(start), report.Affected)
+
+	if !dryRun {
+		var lastError *string
+		if report.Error != "" {
+			lastError = &report.Error
+		}
+		// The rule's run history is informational; failing to record it does not fail the run
+		s.db.ExecContext(ctx, `
+			UPDATE retention_rules SET last_run_at = NOW(), last_run_affected = $2, last_run_error = $3
+			WHERE id = $1`,
+			rule.ID, report.Affected, lastError)
+	}
+	return report
+}
+
+// staleChunksCondition selects the chunks a chunk rule applies to. $1 is the cutoff, $2
+// the tag and $3 the page the rule is narrowed to, either NULL. Delete rules only select
+// chunks whose whole subtree is stale and free of tags, templates and slots.
+func staleChunksCondition(action models.RetentionAction) string {
+	condition := `c.last_updated < $1
+		AND NOT COALESCE(c.is_tag, FALSE) AND NOT COALESCE(c.is_template, FALSE) AND NOT COALESCE(c.is_slot, FALSE)
+		AND ($2::uuid IS NULL OR EXISTS (
+			SELECT 1 FROM chunk_tags ct WHERE ct.source_chunk_id = c.chunk_id AND ct.tag_chunk_id = $2::uuid))
+		AND ($3::uuid IS NULL OR c.chunk_id = $3::uuid OR c.page = $3::uuid)`
+	switch action {
+	case models.RetentionArchive:
+		condition += `
+		AND COALESCE(c.metadata->>'` + models.ArchivedMetadataKey + `', '') <> 'true'`
+	case models.RetentionDelete:
+		condition += `
+		AND NOT EXISTS (
+			SELECT 1 FROM chunk_hierarchy h JOIN chunks d ON d.chunk_id = h.descendant_id
+			WHERE h.ancestor_id = c.chunk_id AND h.depth > 0
+				AND (d.last_updated >= $1 OR d.is_tag OR d.is_template OR d.is_slot))`
+	}
+	return condition
+}
+
+// match counts what a rule selects and samples the oldest of it
+func (s *retentionService) match(ctx context.Context, report *models.RetentionRuleReport) error {
+	rule := report.Rule
+	var countQuery, sampleQuery string
+	args := []interface{}{report.Cutoff}
+	if rule.Action == models.RetentionPurgeTrash {
+		countQuery = "SELECT COUNT(*) FROM chunk_trash WHERE deleted_at < $1"
+		sampleQuery = "SELECT chunk_id FROM chunk_trash WHERE deleted_at < $1 ORDER BY deleted_at, position LIMIT $2"
+	} else {
+		condition := staleChunksCondition(rule.Action)
+		countQuery = "SELECT COUNT(*) FROM chunks c WHERE " + condition
+		sampleQuery = "SELECT c.chunk_id FROM chunks c WHERE " + condition + " ORDER BY c.last_updated LIMIT $4"
+		args = append(args, rule.TagID, rule.PageID)
+	}
+
+	if err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&report.Matched); err != nil {
+		return fmt.Errorf("failed to count retention candidates: %w", err)
+	}
+	if report.Matched == 0 {
+		return nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, sampleQuery, append(args, retentionSampleSize)...)
+	if err != nil {
+		return fmt.Errorf("failed to sample retention candidates: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var chunkID string
+		if err := rows.Scan(&chunkID); err != nil {
+			return fmt.Errorf("failed to scan retention candidate: %w", err)
+		}
+		report.Sample = append(report.Sample, chunkID)
+	}
+	return rows.Err()
+}
+
+// archive marks up to retentionBatchSize stale chunks archived, oldest first. The update
+// counts as a change, so archived chunks are only selected by a later delete rule once
+// they have been left alone for that rule's age.
+func (s *retentionService) archive(ctx context.Context, rule models.RetentionRule, cutoff time.Time) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		WITH stale AS (
+			SELECT c.chunk_id FROM chunks c WHERE `+staleChunksCondition(rule.Action)+`
+			ORDER BY c.last_updated
+			LIMIT $4
+		)
+		UPDATE chunks SET
+			metadata = COALESCE(chunks.metadata, '{}'::jsonb) || jsonb_build_object(
+				'`+models.ArchivedMetadataKey+`', TRUE,
+				'`+models.ArchivedAtMetadataKey+`', NOW(),
+				'`+models.ArchivedRuleMetadataKey+`', $5::text),
+			version = chunks.version + 1,
+			last_updated = NOW()
+		FROM stale
+		WHERE chunks.chunk_id = stale.chunk_id
+		RETURNING chunks.chunk_id`,
+		cutoff, rule.TagID, rule.PageID, retentionBatchSize, rule.ID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive stale chunks: %w", err)
+	}
+	defer rows.Close()
+
+	var archived []string
+	for rows.Next() {
+		var chunkID string
+		if err := rows.Scan(&chunkID); err != nil {
+			return 0, fmt.Errorf("failed to scan archived chunk: %w", err)
+		}
+		archived = append(archived, chunkID)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to archive stale chunks: %w", err)
+	}
+
+	s.invalidateArchived(ctx, archived)
+	return len(archived), nil
+}
+
+// invalidateArchived drops cached copies of chunks whose metadata changed
+func (s *retentionService) invalidateArchived(ctx context.Context, chunkIDs []string) {
+	if len(chunkIDs) == 0 {
+		return
+	}
+	if s.cache != nil {
+		for _, chunkID := range chunkIDs {
+			s.cache.Delete(ctx, "chunk:"+chunkID)
+		}
+		for _, pattern := range []string{"chunk_children:*", "chunk_descendants:*", "chunks_by_tag:*", "chunks_by_tags:*", "qcache:*"} {
+			s.cache.DeletePattern(ctx, pattern)
+		}
+	}
+	if s.searchCache != nil {
+		s.searchCache.InvalidateSearchCache(ctx, []string{"*"})
+	}
+}
+
+// trash moves up to retentionBatchSize stale subtrees to the trash, oldest first. Each
+// subtree is deleted on its own, so a subtree that was changed or removed since it was
+// selected is skipped rather than failing the rest.
+func (s *retentionService) trash(ctx context.Context, rule models.RetentionRule, cutoff time.Time) (int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		"SELECT c.chunk_id FROM chunks c WHERE "+staleChunksCondition(rule.Action)+" ORDER BY c.last_updated LIMIT $4",
+		cutoff, rule.TagID, rule.PageID, retentionBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select stale subtrees: %w", err)
+	}
+	var roots []string
+	for rows.Next() {
+		var chunkID string
+		if err := rows.Scan(&chunkID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan stale subtree: %w", err)
+		}
+		roots = append(roots, chunkID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to select stale subtrees: %w", err)
+	}
+
+	trashed := 0
+	for _, rootID := range roots {
+		// An earlier subtree of this batch may have contained this one
+		result, err := s.chunks.DeleteSubtree(ctx, rootID, models.SubtreeDeleteOptions{Confirm: true})
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				continue
+			}
+			return trashed, err
+		}
+		trashed += len(result.DeletedChunkIDs)
+	}
+	return trashed, nil
+}
+
+// purgeTrash permanently removes up to retentionBatchSize trashed deletes older than
+// cutoff. Deletes are purged whole, so none is left partly restorable.
+func (s *retentionService) purgeTrash(ctx context.Context, cutoff time.Time) (int, error) {
+	result, err := s.db.ExecContext(ctx, `
+		DELETE FROM chunk_trash WHERE trash_id IN (
+			SELECT trash_id FROM chunk_trash
+			WHERE deleted_at < $1
+			GROUP BY trash_id
+			ORDER BY MIN(deleted_at)
+			LIMIT $2
+		)`,
+		cutoff, retentionBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	return int(n), nil
+}
+
+// listRules runs a query returning retention rule rows
+func (s *retentionService) listRules(ctx context.Context, operation, query string, args ...interface{}) ([]models.RetentionRule, error) {
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.RetentionRule{}
+	for rows.Next() {
+		var rule models.RetentionRule
+		var tagID, pageID, lastError sql.NullString
+		var lastRunAt sql.NullTime
+		if err := rows.Scan(&rule.ID, &rule.Workspace, &rule.Name, &rule.Action, &rule.MaxAgeDays, &tagID, &pageID,
+			&rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt, &lastRunAt, &rule.LastRunAffected, &lastError); err != nil {
+			return nil, fmt.Errorf("failed to scan retention rule: %w", err)
+		}
+		if tagID.Valid {
+			rule.TagID = &tagID.String
+		}
+		if pageID.Valid {
+			rule.PageID = &pageID.String
+		}
+		rule.LastRunAt = nullTimePtr(lastRunAt)
+		rule.LastRunError = lastError.String
+		rules = append(rules, rule)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read retention rules: %w", err)
+	}
+	s.monitor.RecordQuery(operation, time.Since(start), len(rules))
+	return rules, nil
+}
+
+// validateRetentionRule checks a rule request and returns its trimmed name
+func validateRetentionRule(req *models.RetentionRuleRequest) (string, error) {
+	if req == nil {
+		return "", fmt.Errorf("%w: rule is required", ErrInvalidRetentionRule)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxRetentionRuleNameLen {
+		return "", fmt.Errorf("%w: name must be 1 to %d characters", ErrInvalidRetentionRule, maxRetentionRuleNameLen)
+	}
+	if !req.Action.Valid() {
+		return "", fmt.Errorf("%w: action must be archive, delete or purge_trash", ErrInvalidRetentionRule)
+	}
+	if req.MaxAgeDays <= 0 {
+		return "", fmt.Errorf("%w: max_age_days must be positive", ErrInvalidRetentionRule)
+	}
+	for field, id := range map[string]*string{"tag_id": req.TagID, "page_id": req.PageID} {
+		if id == nil {
+			continue
+		}
+		if req.Action == models.RetentionPurgeTrash {
+			return "", fmt.Errorf("%w: %s does not apply to purge_trash rules", ErrInvalidRetentionRule, field)
+		}
+		if _, err := uuid.Parse(*id); err != nil {
+			return "", fmt.Errorf("%w: %s must be a chunk ID", ErrInvalidRetentionRule, field)
+		}
+	}
+	return name, nil
+}
+
+// retentionScopeError reports a tag or page that does not exist as an invalid rule
+func retentionScopeError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return fmt.Errorf("%w: tag or page chunk not found", ErrInvalidRetentionRule)
+	}
+	return err
+}
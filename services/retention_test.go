@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func TestValidateRetentionRule(t *testing.T) {
+	chunkID := "9f0d5a8e-1c2b-4e3f-8a7b-6c5d4e3f2a10"
+	notAnID := "page-1"
+
+	name, err := validateRetentionRule(&models.RetentionRuleRequest{Name: "  old notes ", Action: models.RetentionArchive, MaxAgeDays: 730, TagID: &chunkID})
+	require.NoError(t, err)
+	assert.Equal(t, "old notes", name)
+
+	_, err = validateRetentionRule(&models.RetentionRuleRequest{Name: "trash", Action: models.RetentionPurgeTrash, MaxAgeDays: 30})
+	require.NoError(t, err)
+
+	invalid := map[string]*models.RetentionRuleRequest{
+		"no request":         nil,
+		"no name":            {Action: models.RetentionArchive, MaxAgeDays: 1},
+		"unknown action":     {Name: "r", Action: "shred", MaxAgeDays: 1},
+		"no age":             {Name: "r", Action: models.RetentionDelete},
+		"negative age":       {Name: "r", Action: models.RetentionDelete, MaxAgeDays: -5},
+		"scoped trash purge": {Name: "r", Action: models.RetentionPurgeTrash, MaxAgeDays: 30, PageID: &chunkID},
+		"invalid page":       {Name: "r", Action: models.RetentionArchive, MaxAgeDays: 30, PageID: &notAnID},
+	}
+	for name, req := range invalid {
+		_, err := validateRetentionRule(req)
+		assert.ErrorIs(t, err, ErrInvalidRetentionRule, name)
+	}
+}
+
+func TestStaleChunksCondition_ByAction(t *testing.T) {
+	archive := staleChunksCondition(models.RetentionArchive)
+	assert.Contains(t, archive, "metadata->>'archived'")
+	assert.NotContains(t, archive, "chunk_hierarchy")
+
+	// Deletes only take subtrees that are stale throughout
+	remove := staleChunksCondition(models.RetentionDelete)
+	assert.Contains(t, remove, "chunk_hierarchy")
+	assert.NotContains(t, remove, "metadata->>'archived'")
+}
+
+func TestRetentionService_RequiresAdmin(t *testing.T) {
+	// The service has no database; the role check must fail before it is needed
+	service := NewRetentionService(nil, nil, nil, nil, NewNoOpMonitor())
+	ctx := contextWithRole(models.RoleEditor)
+	req := &models.RetentionRuleRequest{Name: "r", Action: models.RetentionArchive, MaxAgeDays: 30}
+
+	_, err := service.ListRules(ctx, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = service.CreateRule(ctx, "", req)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = service.UpdateRule(ctx, "", "rule-1", req)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.ErrorIs(t, service.DeleteRule(ctx, "", "rule-1"), ErrPermissionDenied)
+	_, err = service.Preview(ctx, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = service.Run(ctx, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = service.UpdateRule(contextWithRole(models.RoleAdmin), "", "rule-1", req)
+	assert.ErrorIs(t, err, ErrRetentionRuleNotFound)
+}
+
+func TestMaintenanceDaemon_RunsEveryJob(t *testing.T) {
+	daemon := NewMaintenanceDaemon(10*time.Millisecond, NewNoOpMonitor())
+
+	var ran []string
+	daemon.Register("failing", func(ctx context.Context) error {
+		ran = append(ran, "failing")
+		return errors.New("boom")
+	})
+	daemon.Register("retention", func(ctx context.Context) error {
+		ran = append(ran, "retention")
+		return nil
+	})
+
+	assert.Equal(t, 1, daemon.RunOnce(context.Background()))
+	assert.Equal(t, []string{"failing", "retention"}, ran)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, 0, daemon.RunOnce(ctx))
+	assert.Len(t, ran, 2)
+}
+
+func TestMaintenanceDaemon_StartStop(t *testing.T) {
+	daemon := NewMaintenanceDaemon(5*time.Millisecond, NewNoOpMonitor())
+	runs := make(chan struct{}, 10)
+	daemon.Register("tick", func(ctx context.Context) error {
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+
+	daemon.Start(context.Background())
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("maintenance job did not run")
+	}
+	daemon.Stop()
+	daemon.Stop()
+}
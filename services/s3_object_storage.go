@@ -0,0 +1,436 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+const (
+	s3SigningAlgorithm = "AWS4-HMAC-SHA256"
+	s3UnsignedPayload  = "UNSIGNED-PAYLOAD"
+	s3MaxPresignExpiry = 7 * 24 * time.Hour
+)
+
+// s3ObjectStorage S3 相容物件儲存實作（AWS S3、MinIO、Cloudflare R2 等），使用 Signature V4 簽章
+type s3ObjectStorage struct {
+	endpoint   *url.URL
+	cfg        config.S3StorageConfig
+	maxSize    int64
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewS3ObjectStorage 建立 S3 相容物件儲存服務
+func NewS3ObjectStorage(cfg config.S3StorageConfig, maxSize int64) (StorageService, error) {
+	if cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("%w: s3 storage requires bucket and credentials", ErrInvalidStorageConfig)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region)
+	}
+
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: invalid s3 endpoint %q", ErrInvalidStorageConfig, cfg.Endpoint)
+	}
+	if maxSize <= 0 {
+		maxSize = defaultMaxObjectSize
+	}
+
+	return &s3ObjectStorage{
+		endpoint: endpoint,
+		cfg:      cfg,
+		maxSize:  maxSize,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+		now: time.Now,
+	}, nil
+}
+
+// Put 上傳物件。未允許覆蓋時使用 If-None-Match 條件寫入
+func (s *s3ObjectStorage) Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+
+	data, contentType, err := readObjectBody(key, r, opts, s.maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, nil, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(data))
+	req.Header.Set("Content-Type", contentType)
+	if opts == nil || !opts.Overwrite {
+		req.Header.Set("If-None-Match", "*")
+	}
+	payloadHash := sha256.Sum256(data)
+	s.signRequest(req, hex.EncodeToString(payloadHash[:]), s.now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, NewStorageError(string(models.StorageTypeS3), "put", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusPreconditionFailed {
+		return nil, fmt.Errorf("%w: %s", ErrObjectExists, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewStorageError(string(models.StorageTypeS3), "put", key,
+			fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	return &models.StorageObject{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		ETag:         strings.Trim(resp.Header.Get("ETag"), `"`),
+		URL:          s.publicURL(key),
+		StorageType:  models.StorageTypeS3,
+		LastModified: s.now(),
+	}, nil
+}
+
+// Get 下載物件
+func (s *s3ObjectStorage) Get(ctx context.Context, key string) (io.ReadCloser, *models.StorageObject, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.signRequest(req, s3UnsignedPayload, s.now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, NewStorageError(string(models.StorageTypeS3), "get", key, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+		}
+		return nil, nil, NewStorageError(string(models.StorageTypeS3), "get", key,
+			fmt.Errorf("download failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	object := objectFromResponse(key, models.StorageTypeS3, resp)
+	object.URL = s.publicURL(key)
+
+	return resp.Body, object, nil
+}
+
+// SignedURL 產生預先簽章的下載 URL（S3 上限為 7 天）
+func (s *s3ObjectStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := validateObjectKey(key); err != nil {
+		return "", err
+	}
+	if expiry < time.Second || expiry > s3MaxPresignExpiry {
+		return "", fmt.Errorf("signed URL expiry must be between 1s and %s", s3MaxPresignExpiry)
+	}
+
+	return s.presign(http.MethodGet, key, expiry, s.now()), nil
+}
+
+// Delete 刪除物件
+func (s *s3ObjectStorage) Delete(ctx context.Context, key string) error {
+	if err := validateObjectKey(key); err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	s.signRequest(req, s3UnsignedPayload, s.now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return NewStorageError(string(models.StorageTypeS3), "delete", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return NewStorageError(string(models.StorageTypeS3), "delete", key,
+			fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	return nil
+}
+
+// s3ListResult ListObjectsV2 回應
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+		Size         int64     `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+// List 列出指定前綴下的物件（ListObjectsV2，自動處理分頁）
+func (s *s3ObjectStorage) List(ctx context.Context, prefix string, limit int) ([]models.StorageObject, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	var objects []models.StorageObject
+	continuationToken := ""
+	for len(objects) < limit {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("max-keys", strconv.Itoa(limit-len(objects)))
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		req, err := s.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.signRequest(req, s3UnsignedPayload, s.now())
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return nil, NewStorageError(string(models.StorageTypeS3), "list", prefix, err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			message := readStorageError(resp)
+			resp.Body.Close()
+			return nil, NewStorageError(string(models.StorageTypeS3), "list", prefix,
+				fmt.Errorf("list failed with status %d: %s", resp.StatusCode, message))
+		}
+
+		var result s3ListResult
+		err = xml.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode list response: %w", err)
+		}
+
+		for _, item := range result.Contents {
+			objects = append(objects, models.StorageObject{
+				Key:          item.Key,
+				Size:         item.Size,
+				ContentType:  DetectContentType(item.Key, nil),
+				ETag:         strings.Trim(item.ETag, `"`),
+				URL:          s.publicURL(item.Key),
+				StorageType:  models.StorageTypeS3,
+				LastModified: item.LastModified,
+			})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+
+	if len(objects) > limit {
+		objects = objects[:limit]
+	}
+	return objects, nil
+}
+
+// Backend 回傳儲存後端類型
+func (s *s3ObjectStorage) Backend() models.StorageType {
+	return models.StorageTypeS3
+}
+
+// publicURL 設定公開網址時回傳物件的公開 URL
+func (s *s3ObjectStorage) publicURL(key string) string {
+	if s.cfg.PublicBaseURL == "" {
+		return ""
+	}
+	return strings.TrimRight(s.cfg.PublicBaseURL, "/") + "/" + s3URIEncode(key, false)
+}
+
+// objectLocation 回傳物件的主機與已編碼路徑（path-style 或 virtual-hosted-style）
+func (s *s3ObjectStorage) objectLocation(key string) (host, escapedPath string) {
+	basePath := strings.TrimRight(s.endpoint.EscapedPath(), "/")
+	if s.cfg.ForcePathStyle {
+		return s.endpoint.Host, basePath + "/" + s3URIEncode(s.cfg.Bucket, true) + "/" + s3URIEncode(key, false)
+	}
+	return s.cfg.Bucket + "." + s.endpoint.Host, basePath + "/" + s3URIEncode(key, false)
+}
+
+// newRequest 建立尚未簽章的請求
+func (s *s3ObjectStorage) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	host, escapedPath := s.objectLocation(key)
+	rawURL := fmt.Sprintf("%s://%s%s", s.endpoint.Scheme, host, escapedPath)
+	if len(query) > 0 {
+		rawURL += "?" + s3CanonicalQuery(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 request: %w", err)
+	}
+	return req, nil
+}
+
+// signRequest 以 Authorization 標頭簽署請求
+func (s *s3ObjectStorage) signRequest(req *http.Request, payloadHash string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	// 簽署 host、content-type 與所有 x-amz-* 標頭
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if lower == "content-type" || strings.HasPrefix(lower, "x-amz-") || lower == "if-none-match" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.credentialScope(t)
+	signature := s.signature(t, scope, amzDate, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3SigningAlgorithm, s.cfg.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// presign 產生以查詢參數簽章的 URL
+func (s *s3ObjectStorage) presign(method, key string, expiry time.Duration, t time.Time) string {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	scope := s.credentialScope(t)
+	host, escapedPath := s.objectLocation(key)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", s3SigningAlgorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.FormatInt(int64(expiry/time.Second), 10))
+	query.Set("X-Amz-SignedHeaders", "host")
+	canonicalQuery := s3CanonicalQuery(query)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		escapedPath,
+		canonicalQuery,
+		"host:" + host + "\n",
+		"host",
+		s3UnsignedPayload,
+	}, "\n")
+
+	signature := s.signature(t, scope, amzDate, canonicalRequest)
+	return fmt.Sprintf("%s://%s%s?%s&X-Amz-Signature=%s", s.endpoint.Scheme, host, escapedPath, canonicalQuery, signature)
+}
+
+// credentialScope 回傳簽章範圍 date/region/s3/aws4_request
+func (s *s3ObjectStorage) credentialScope(t time.Time) string {
+	return fmt.Sprintf("%s/%s/s3/aws4_request", t.Format("20060102"), s.cfg.Region)
+}
+
+// signature 計算 Signature V4 簽章
+func (s *s3ObjectStorage) signature(t time.Time, scope, amzDate, canonicalRequest string) string {
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3SigningAlgorithm,
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// hmacSHA256 計算 HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3CanonicalQuery 依鍵值排序並以 RFC 3986 編碼查詢參數
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, s3URIEncode(key, true)+"="+s3URIEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3URIEncode 依 Signature V4 規則編碼，僅保留未保留字元
+func s3URIEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/google/uuid"
+
 	"semantic-text-processor/clients"
 	"semantic-text-processor/models"
 )
@@ -12,13 +14,33 @@ import (
 type searchService struct {
 	supabaseClient   clients.SupabaseClient
 	embeddingService EmbeddingService
+	similar          SimilaritySearcher
+}
+
+// SimilaritySearcher finds the text chunks closest to a query vector
+type SimilaritySearcher interface {
+	SearchSimilar(ctx context.Context, queryVector []float64, limit int) ([]models.SimilarityResult, error)
+}
+
+// FilteredSimilaritySearcher is a SimilaritySearcher that applies a filter inside its vector
+// query. Semantic searches filtering by tags, page or creation time need one.
+type FilteredSimilaritySearcher interface {
+	SearchSimilarFiltered(ctx context.Context, queryVector []float64, limit int, filter models.SimilarityFilter) ([]models.SimilarityResult, error)
 }
 
 // NewSearchService creates a new search service instance
 func NewSearchService(supabaseClient clients.SupabaseClient, embeddingService EmbeddingService) SearchService {
+	return NewSearchServiceWithSimilarity(supabaseClient, embeddingService, supabaseClient)
+}
+
+// NewSearchServiceWithSimilarity creates a search service whose vector searches go to
+// similar instead of the Supabase match_chunks function, e.g. an EmbeddingStore for
+// quantized embeddings
+func NewSearchServiceWithSimilarity(supabaseClient clients.SupabaseClient, embeddingService EmbeddingService, similar SimilaritySearcher) SearchService {
 	return &searchService{
 		supabaseClient:   supabaseClient,
 		embeddingService: embeddingService,
+		similar:          similar,
 	}
 }
 
@@ -41,7 +63,7 @@ func (s *searchService) SemanticSearch(ctx context.Context, query string, limit
 	}
 	
 	// Perform similarity search
-	results, err := s.supabaseClient.SearchSimilar(ctx, queryEmbedding, limit)
+	results, err := s.similar.SearchSimilar(ctx, queryEmbedding, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to perform similarity search: %w", err)
 	}
@@ -95,11 +117,32 @@ func (s *searchService) SemanticSearchWithFilters(ctx context.Context, req *mode
 	}, nil
 }
 
-// searchSimilarWithFilters performs the actual similarity search with filters
+// searchSimilarWithFilters performs the actual similarity search with filters. Tag, page
+// and creation time filters run inside the vector query; the others are applied to its
+// results.
 func (s *searchService) searchSimilarWithFilters(ctx context.Context, queryVector []float64, req *models.SemanticSearchRequest) ([]models.SimilarityResult, error) {
-	// For now, use the basic search and apply filters post-search
-	// In a production system, you'd want to push filters to the database level
-	results, err := s.supabaseClient.SearchSimilar(ctx, queryVector, req.Limit*2) // Get more results to account for filtering
+	filter, err := similarityFilterFromMap(req.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	for key := range req.Filters {
+		if !similarityFilterKeys[key] {
+			limit = req.Limit * 2 // Get more results to account for filtering afterwards
+			break
+		}
+	}
+	var results []models.SimilarityResult
+	if filter.IsZero() {
+		results, err = s.similar.SearchSimilar(ctx, queryVector, limit)
+	} else {
+		filtered, ok := s.similar.(FilteredSimilaritySearcher)
+		if !ok {
+			return nil, fmt.Errorf("%w: the similarity search backend cannot filter by tags, page or creation time", ErrInvalidFilter)
+		}
+		results, err = filtered.SearchSimilarFiltered(ctx, queryVector, limit, filter)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -157,6 +200,58 @@ func (s *searchService) matchesFilters(chunk *models.ChunkRecord, filters map[st
 	return true
 }
 
+// similarityFilterKeys are the filters applied inside the vector query
+var similarityFilterKeys = map[string]bool{"tags": true, "page_id": true, "created_after": true, "created_before": true}
+
+// similarityFilterFromMap reads the filters applied inside the vector query: tags (a tag
+// chunk ID or a list of them, all required), page_id, and created_after and
+// created_before (RFC 3339 timestamps or dates)
+func similarityFilterFromMap(filters map[string]interface{}) (models.SimilarityFilter, error) {
+	var filter models.SimilarityFilter
+	if value, ok := filters["tags"]; ok {
+		var list []interface{}
+		switch v := value.(type) {
+		case string:
+			list = []interface{}{v}
+		case []string:
+			for _, id := range v {
+				list = append(list, id)
+			}
+		case []interface{}:
+			list = v
+		default:
+			return filter, fmt.Errorf("%w: tags takes a tag chunk ID or a list of them", ErrInvalidFilter)
+		}
+		ids, err := filterStrings("tags", list)
+		if err != nil {
+			return filter, err
+		}
+		filter.TagIDs = ids
+	}
+	if value, ok := filters["page_id"]; ok {
+		id, _ := value.(string)
+		if _, err := uuid.Parse(id); err != nil {
+			return filter, fmt.Errorf("%w: page_id takes a chunk UUID", ErrInvalidFilter)
+		}
+		filter.PageID = id
+	}
+	if value, ok := filters["created_after"]; ok {
+		t, err := parseFilterTime("created_after", value)
+		if err != nil {
+			return filter, err
+		}
+		filter.CreatedAfter = &t
+	}
+	if value, ok := filters["created_before"]; ok {
+		t, err := parseFilterTime("created_before", value)
+		if err != nil {
+			return filter, err
+		}
+		filter.CreatedBefore = &t
+	}
+	return filter, nil
+}
+
 // GraphSearch performs graph-based search (placeholder implementation)
 func (s *searchService) GraphSearch(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) {
 	// This will be implemented in task 5.2
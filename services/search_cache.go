@@ -2,14 +2,13 @@ package services
 
 import (
 	"context"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"semantic-text-processor/models"
+	"sync"
 	"time"
 
-	"github.com/lib/pq"
 )
 
 // SearchCacheService provides database-backed search result caching
@@ -24,6 +23,9 @@ type SearchCacheService interface {
 	GetCacheStats(ctx context.Context) (*SearchCacheStats, error)
 	GetOptimizationSuggestions(ctx context.Context) ([]OptimizationSuggestion, error)
 	UpdateHitCount(ctx context.Context, searchHash string) error
+
+	// GetOrComputeSearch serves cached results, refreshing stale entries in the background
+	GetOrComputeSearch(ctx context.Context, queryParams map[string]interface{}, ttl time.Duration, compute SearchComputeFunc) (*models.SearchCacheEntry, bool, error)
 }
 
 // SearchCacheStats provides comprehensive cache performance metrics
@@ -69,6 +71,15 @@ type DatabaseSearchCache struct {
 	db      *sql.DB
 	config  *SearchCacheConfig
 	monitor QueryPerformanceMonitor
+
+	// refreshing tracks search hashes with an in-flight background refresh
+	refreshing sync.Map
+
+	// ttlMu guards DefaultTTL and StaleWhileRevalidate, which can change on config reload
+	ttlMu sync.RWMutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
 }
 
 // SearchCacheConfig holds configuration for database search cache
@@ -79,6 +90,11 @@ type SearchCacheConfig struct {
 	HitCountThreshold   int           `json:"hit_count_threshold"`
 	OptimizationEnabled bool          `json:"optimization_enabled"`
 	StatsEnabled        bool          `json:"stats_enabled"`
+
+	// StaleWhileRevalidate is how long past expiry an entry may still be served
+	// while a background refresh recomputes it
+	StaleWhileRevalidate time.Duration `json:"stale_while_revalidate"`
+	RefreshTimeout       time.Duration `json:"refresh_timeout"`
 }
 
 // DefaultSearchCacheConfig returns default search cache configuration
@@ -90,6 +106,9 @@ func DefaultSearchCacheConfig() *SearchCacheConfig {
 		HitCountThreshold:   5,
 		OptimizationEnabled: true,
 		StatsEnabled:        true,
+
+		StaleWhileRevalidate: 5 * time.Minute,
+		RefreshTimeout:       30 * time.Second,
 	}
 }
 
@@ -103,6 +122,7 @@ func NewDatabaseSearchCache(db *sql.DB, config *SearchCacheConfig, monitor Query
 		db:      db,
 		config:  config,
 		monitor: monitor,
+		stop:    make(chan struct{}),
 	}
 	
 	// Start background cleanup if enabled
@@ -115,128 +135,25 @@ func NewDatabaseSearchCache(db *sql.DB, config *SearchCacheConfig, monitor Query
 
 // generateSearchHash creates a deterministic hash for search parameters
 func (dsc *DatabaseSearchCache) generateSearchHash(queryParams map[string]interface{}) string {
-	// Normalize and sort parameters for consistent hashing
-	normalized := make(map[string]interface{})
-	for k, v := range queryParams {
-		if v != nil {
-			normalized[k] = v
-		}
-	}
-	
-	jsonBytes, err := json.Marshal(normalized)
-	if err != nil {
-		// Fallback to timestamp-based hash if JSON marshaling fails
-		return fmt.Sprintf("fallback_%d", time.Now().UnixNano())
-	}
-	
-	hash := sha256.Sum256(jsonBytes)
-	return fmt.Sprintf("%x", hash[:16]) // Use first 16 bytes for shorter hash
+	return SearchCacheKey(queryParams)
 }
 
 // GetCachedSearch retrieves a cached search result
 func (dsc *DatabaseSearchCache) GetCachedSearch(ctx context.Context, queryParams map[string]interface{}) (*models.SearchCacheEntry, error) {
-	start := time.Now()
 	searchHash := dsc.generateSearchHash(queryParams)
 	
-	query := `
-		SELECT search_hash, query_params, chunk_ids, result_count, created_at, expires_at, hit_count
-		FROM chunk_search_cache 
-		WHERE search_hash = $1 AND expires_at > NOW()
-	`
-	
-	var entry models.SearchCacheEntry
-	var queryParamsJSON []byte
-	var chunkIDsArray pq.StringArray
-	
-	err := dsc.db.QueryRowContext(ctx, query, searchHash).Scan(
-		&entry.SearchHash,
-		&queryParamsJSON,
-		&chunkIDsArray,
-		&entry.ResultCount,
-		&entry.CreatedAt,
-		&entry.ExpiresAt,
-		&entry.HitCount,
-	)
-	
-	duration := time.Since(start)
-	
-	if err != nil {
-		if err == sql.ErrNoRows {
-			dsc.monitor.RecordQuery("search_cache_miss", duration, 0)
-			return nil, nil // Cache miss, not an error
-		}
-		dsc.monitor.RecordQuery("search_cache_error", duration, 0)
-		return nil, fmt.Errorf("failed to get cached search: %w", err)
-	}
-	
-	// Deserialize query parameters
-	if err := json.Unmarshal(queryParamsJSON, &entry.QueryParams); err != nil {
-		return nil, fmt.Errorf("failed to deserialize query params: %w", err)
+	entry, err := dsc.lookupEntry(ctx, searchHash, 0)
+	if err != nil || entry == nil {
+		return nil, err
 	}
 	
-	entry.ChunkIDs = []string(chunkIDsArray)
-	
-	// Update hit count asynchronously
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
-		dsc.UpdateHitCount(ctx, searchHash)
-	}()
-	
-	dsc.monitor.RecordQuery("search_cache_hit", duration, entry.ResultCount)
-	return &entry, nil
+	dsc.touchEntry(searchHash)
+	return entry, nil
 }
 
 // SetCachedSearch stores a search result in cache
 func (dsc *DatabaseSearchCache) SetCachedSearch(ctx context.Context, queryParams map[string]interface{}, chunkIDs []string, ttl time.Duration) error {
-	start := time.Now()
-	searchHash := dsc.generateSearchHash(queryParams)
-	
-	// Serialize query parameters
-	queryParamsJSON, err := json.Marshal(queryParams)
-	if err != nil {
-		return fmt.Errorf("failed to serialize query params: %w", err)
-	}
-	
-	expiresAt := time.Now().Add(ttl)
-	
-	query := `
-		INSERT INTO chunk_search_cache (search_hash, query_params, chunk_ids, result_count, expires_at, hit_count)
-		VALUES ($1, $2, $3, $4, $5, 0)
-		ON CONFLICT (search_hash) 
-		DO UPDATE SET 
-			query_params = EXCLUDED.query_params,
-			chunk_ids = EXCLUDED.chunk_ids,
-			result_count = EXCLUDED.result_count,
-			expires_at = EXCLUDED.expires_at,
-			created_at = NOW()
-	`
-	
-	_, err = dsc.db.ExecContext(ctx, query, 
-		searchHash, 
-		queryParamsJSON, 
-		pq.Array(chunkIDs), 
-		len(chunkIDs), 
-		expiresAt,
-	)
-	
-	duration := time.Since(start)
-	
-	if err != nil {
-		dsc.monitor.RecordQuery("search_cache_set_error", duration, 0)
-		return fmt.Errorf("failed to set cached search: %w", err)
-	}
-	
-	dsc.monitor.RecordQuery("search_cache_set", duration, len(chunkIDs))
-	
-	// Check if we need to cleanup old entries
-	go func() {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-		dsc.cleanupIfNeeded(ctx)
-	}()
-	
-	return nil
+	return dsc.storeEntry(ctx, queryParams, chunkIDs, nil, ttl)
 }
 
 // UpdateHitCount increments the hit count for a cached entry
@@ -285,8 +202,10 @@ func (dsc *DatabaseSearchCache) InvalidateSearchCache(ctx context.Context, patte
 func (dsc *DatabaseSearchCache) CleanupExpiredEntries(ctx context.Context) (int, error) {
 	start := time.Now()
 	
-	query := "DELETE FROM chunk_search_cache WHERE expires_at < NOW()"
-	result, err := dsc.db.ExecContext(ctx, query)
+	// Keep expired entries that can still be served stale
+	query := "DELETE FROM chunk_search_cache WHERE expires_at < NOW() - ($1 * INTERVAL '1 second')"
+	_, staleWindow := dsc.ttls()
+	result, err := dsc.db.ExecContext(ctx, query, staleWindow.Seconds())
 	if err != nil {
 		dsc.monitor.RecordQuery("search_cache_cleanup_error", time.Since(start), 0)
 		return 0, fmt.Errorf("failed to cleanup expired entries: %w", err)
@@ -517,9 +436,23 @@ func (dsc *DatabaseSearchCache) startCleanupRoutine() {
 	ticker := time.NewTicker(dsc.config.CleanupInterval)
 	defer ticker.Stop()
 	
-	for range ticker.C {
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		dsc.CleanupExpiredEntries(ctx)
-		cancel()
+	for {
+		select {
+		case <-dsc.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			dsc.CleanupExpiredEntries(ctx)
+			cancel()
+		}
 	}
+}
+
+// Stop stops the background cleanup
+func (dsc *DatabaseSearchCache) Stop() {
+	dsc.stopOnce.Do(func() {
+		if dsc.stop != nil {
+			close(dsc.stop)
+		}
+	})
 }
\ No newline at end of file
@@ -174,6 +174,20 @@ func (n *NoOpSearchCacheService) UpdateHitCount(ctx context.Context, searchHash
 	return nil // No-op
 }
 
+func (n *NoOpSearchCacheService) GetOrComputeSearch(ctx context.Context, queryParams map[string]interface{}, ttl time.Duration, compute SearchComputeFunc) (*models.SearchCacheEntry, bool, error) {
+	chunkIDs, payload, err := compute(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	return &models.SearchCacheEntry{
+		SearchHash:  SearchCacheKey(queryParams),
+		QueryParams: queryParams,
+		ChunkIDs:    chunkIDs,
+		ResultCount: len(chunkIDs),
+		Payload:     payload,
+	}, false, nil // Always computed
+}
+
 // NoOpQueryPerformanceMonitor provides a no-op implementation for when monitoring is disabled
 type NoOpQueryPerformanceMonitor struct{}
 
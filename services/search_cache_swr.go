@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/lib/pq"
+)
+
+// SearchComputeFunc runs the underlying search on a cache miss or refresh.
+// The payload is stored alongside the chunk IDs so callers can rebuild
+// richer responses (scores, totals) without recomputing them.
+type SearchComputeFunc func(ctx context.Context) (chunkIDs []string, payload json.RawMessage, err error)
+
+// normalizedTextParams are free-text parameters compared case and whitespace insensitively
+var normalizedTextParams = map[string]bool{
+	"content": true,
+	"query":   true,
+}
+
+// SearchCacheKey returns the cache key for normalized search parameters
+func SearchCacheKey(queryParams map[string]interface{}) string {
+	jsonBytes, err := json.Marshal(normalizeSearchParams(queryParams))
+	if err != nil {
+		// Fallback to timestamp-based hash if JSON marshaling fails
+		return fmt.Sprintf("fallback_%d", time.Now().UnixNano())
+	}
+
+	hash := sha256.Sum256(jsonBytes)
+	return fmt.Sprintf("%x", hash[:16]) // Use first 16 bytes for shorter hash
+}
+
+// normalizeSearchParams drops empty values, normalizes free text and sorts
+// string lists so equivalent searches share a cache entry
+func normalizeSearchParams(queryParams map[string]interface{}) map[string]interface{} {
+	normalized := make(map[string]interface{}, len(queryParams))
+	for k, v := range queryParams {
+		switch value := v.(type) {
+		case nil:
+			continue
+		case string:
+			value = strings.Join(strings.Fields(value), " ")
+			if normalizedTextParams[k] {
+				value = strings.ToLower(value)
+			}
+			if value == "" {
+				continue
+			}
+			normalized[k] = value
+		case []string:
+			if len(value) == 0 {
+				continue
+			}
+			sorted := append([]string(nil), value...)
+			sort.Strings(sorted)
+			normalized[k] = sorted
+		case map[string]interface{}:
+			if len(value) == 0 {
+				continue
+			}
+			normalized[k] = normalizeSearchParams(value)
+		default:
+			normalized[k] = v
+		}
+	}
+	return normalized
+}
+
+// GetOrComputeSearch returns a fresh cache entry, serves a stale entry while
+// refreshing it in the background, or computes and stores the result on a miss.
+// Cache failures never fail the search; the result is computed directly instead.
+func (dsc *DatabaseSearchCache) GetOrComputeSearch(ctx context.Context, queryParams map[string]interface{}, ttl time.Duration, compute SearchComputeFunc) (*models.SearchCacheEntry, bool, error) {
+	defaultTTL, staleWindow := dsc.ttls()
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	searchHash := dsc.generateSearchHash(queryParams)
+
+	entry, err := dsc.lookupEntry(ctx, searchHash, staleWindow)
+	if err == nil && entry != nil {
+		dsc.touchEntry(searchHash)
+		if entry.Stale {
+			dsc.refreshInBackground(searchHash, queryParams, ttl, compute)
+		}
+		return entry, true, nil
+	}
+
+	chunkIDs, payload, err := compute(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	entry = &models.SearchCacheEntry{
+		SearchHash:  searchHash,
+		QueryParams: queryParams,
+		ChunkIDs:    chunkIDs,
+		ResultCount: len(chunkIDs),
+		Payload:     payload,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(ttl),
+	}
+
+	go func() {
+		storeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		dsc.storeEntry(storeCtx, queryParams, chunkIDs, payload, ttl)
+	}()
+
+	return entry, false, nil
+}
+
+// refreshInBackground recomputes a stale entry, at most once per hash at a time
+func (dsc *DatabaseSearchCache) refreshInBackground(searchHash string, queryParams map[string]interface{}, ttl time.Duration, compute SearchComputeFunc) {
+	if _, inFlight := dsc.refreshing.LoadOrStore(searchHash, struct{}{}); inFlight {
+		return
+	}
+
+	go func() {
+		defer dsc.refreshing.Delete(searchHash)
+
+		timeout := dsc.config.RefreshTimeout
+		if timeout <= 0 {
+			timeout = 30 * time.Second
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		chunkIDs, payload, err := compute(ctx)
+		if err != nil {
+			dsc.monitor.RecordQuery("search_cache_refresh_error", time.Since(start), 0)
+			return
+		}
+		if err := dsc.storeEntry(ctx, queryParams, chunkIDs, payload, ttl); err != nil {
+			return
+		}
+		dsc.monitor.RecordQuery("search_cache_refresh", time.Since(start), len(chunkIDs))
+	}()
+}
+
+// lookupEntry loads an entry that is fresh or expired by at most staleWindow
+func (dsc *DatabaseSearchCache) lookupEntry(ctx context.Context, searchHash string, staleWindow time.Duration) (*models.SearchCacheEntry, error) {
+	start := time.Now()
+
+	query := `
+		SELECT search_hash, query_params, chunk_ids, result_count, payload, created_at, expires_at, hit_count,
+			expires_at <= NOW() AS stale
+		FROM chunk_search_cache
+		WHERE search_hash = $1 AND expires_at > NOW() - ($2 * INTERVAL '1 second')
+	`
+
+	var entry models.SearchCacheEntry
+	var queryParamsJSON, payload []byte
+	var chunkIDsArray pq.StringArray
+
+	err := dsc.db.QueryRowContext(ctx, query, searchHash, staleWindow.Seconds()).Scan(
+		&entry.SearchHash,
+		&queryParamsJSON,
+		&chunkIDsArray,
+		&entry.ResultCount,
+		&payload,
+		&entry.CreatedAt,
+		&entry.ExpiresAt,
+		&entry.HitCount,
+		&entry.Stale,
+	)
+
+	duration := time.Since(start)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			dsc.monitor.RecordQuery("search_cache_miss", duration, 0)
+			return nil, nil // Cache miss, not an error
+		}
+		dsc.monitor.RecordQuery("search_cache_error", duration, 0)
+		return nil, fmt.Errorf("failed to get cached search: %w", err)
+	}
+
+	if err := json.Unmarshal(queryParamsJSON, &entry.QueryParams); err != nil {
+		return nil, fmt.Errorf("failed to deserialize query params: %w", err)
+	}
+
+	entry.ChunkIDs = []string(chunkIDsArray)
+	if len(payload) > 0 {
+		entry.Payload = json.RawMessage(payload)
+	}
+
+	if entry.Stale {
+		dsc.monitor.RecordQuery("search_cache_stale_hit", duration, entry.ResultCount)
+	} else {
+		dsc.monitor.RecordQuery("search_cache_hit", duration, entry.ResultCount)
+	}
+	return &entry, nil
+}
+
+// storeEntry upserts a cache entry with an optional result payload
+func (dsc *DatabaseSearchCache) storeEntry(ctx context.Context, queryParams map[string]interface{}, chunkIDs []string, payload json.RawMessage, ttl time.Duration) error {
+	start := time.Now()
+	searchHash := dsc.generateSearchHash(queryParams)
+
+	queryParamsJSON, err := json.Marshal(queryParams)
+	if err != nil {
+		return fmt.Errorf("failed to serialize query params: %w", err)
+	}
+
+	var payloadArg interface{}
+	if len(payload) > 0 {
+		payloadArg = []byte(payload)
+	}
+
+	query := `
+		INSERT INTO chunk_search_cache (search_hash, query_params, chunk_ids, result_count, payload, expires_at, hit_count)
+		VALUES ($1, $2, $3, $4, $5, $6, 0)
+		ON CONFLICT (search_hash)
+		DO UPDATE SET
+			query_params = EXCLUDED.query_params,
+			chunk_ids = EXCLUDED.chunk_ids,
+			result_count = EXCLUDED.result_count,
+			payload = EXCLUDED.payload,
+			expires_at = EXCLUDED.expires_at,
+			created_at = NOW()
+	`
+
+	_, err = dsc.db.ExecContext(ctx, query,
+		searchHash,
+		queryParamsJSON,
+		pq.Array(chunkIDs),
+		len(chunkIDs),
+		payloadArg,
+		time.Now().Add(ttl),
+	)
+
+	duration := time.Since(start)
+
+	if err != nil {
+		dsc.monitor.RecordQuery("search_cache_set_error", duration, 0)
+		return fmt.Errorf("failed to set cached search: %w", err)
+	}
+
+	dsc.monitor.RecordQuery("search_cache_set", duration, len(chunkIDs))
+
+	// Check if we need to cleanup old entries
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		dsc.cleanupIfNeeded(ctx)
+	}()
+
+	return nil
+}
+
+// touchEntry increments the hit count asynchronously
+func (dsc *DatabaseSearchCache) touchEntry(searchHash string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		dsc.UpdateHitCount(ctx, searchHash)
+	}()
+}
+
+// SetTTLs updates the default TTL and stale-while-revalidate window
+func (dsc *DatabaseSearchCache) SetTTLs(defaultTTL, staleWhileRevalidate time.Duration) {
+	dsc.ttlMu.Lock()
+	defer dsc.ttlMu.Unlock()
+	dsc.config.DefaultTTL = defaultTTL
+	dsc.config.StaleWhileRevalidate = staleWhileRevalidate
+}
+
+// ttls returns the default TTL and stale-while-revalidate window
+func (dsc *DatabaseSearchCache) ttls() (time.Duration, time.Duration) {
+	dsc.ttlMu.RLock()
+	defer dsc.ttlMu.RUnlock()
+	return dsc.config.DefaultTTL, dsc.config.StaleWhileRevalidate
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchCacheKey_Normalization(t *testing.T) {
+	base := SearchCacheKey(map[string]interface{}{
+		"content": "machine learning",
+		"tags":    []string{"ai", "ml"},
+		"limit":   10,
+	})
+
+	equivalent := SearchCacheKey(map[string]interface{}{
+		"content": "  Machine   LEARNING ",
+		"tags":    []string{"ml", "ai"},
+		"limit":   10,
+		"parent":  nil,
+		"page":    "",
+		"filters": map[string]interface{}{},
+	})
+	assert.Equal(t, base, equivalent)
+
+	assert.NotEqual(t, base, SearchCacheKey(map[string]interface{}{
+		"content": "machine learning",
+		"tags":    []string{"ai", "ml"},
+		"limit":   20,
+	}))
+
+	// Only free-text parameters are case-insensitive
+	assert.NotEqual(t,
+		SearchCacheKey(map[string]interface{}{"page": "Page-A"}),
+		SearchCacheKey(map[string]interface{}{"page": "page-a"}))
+}
+
+func TestNoOpSearchCacheService_GetOrComputeSearch(t *testing.T) {
+	cache := &NoOpSearchCacheService{}
+	params := map[string]interface{}{"query": "test"}
+
+	entry, cacheHit, err := cache.GetOrComputeSearch(context.Background(), params, time.Minute, func(ctx context.Context) ([]string, json.RawMessage, error) {
+		return []string{"a", "b"}, json.RawMessage(`{"total_count":2}`), nil
+	})
+	require.NoError(t, err)
+	assert.False(t, cacheHit)
+	assert.Equal(t, []string{"a", "b"}, entry.ChunkIDs)
+	assert.Equal(t, 2, entry.ResultCount)
+	assert.Equal(t, SearchCacheKey(params), entry.SearchHash)
+
+	_, _, err = cache.GetOrComputeSearch(context.Background(), params, time.Minute, func(ctx context.Context) ([]string, json.RawMessage, error) {
+		return nil, nil, errors.New("search failed")
+	})
+	assert.Error(t, err)
+}
+
+func TestDatabaseSearchCache_RefreshInBackgroundDeduplicates(t *testing.T) {
+	cache := &DatabaseSearchCache{config: DefaultSearchCacheConfig(), monitor: NewNoOpMonitor()}
+
+	var calls int32
+	release := make(chan struct{})
+	done := make(chan struct{})
+	compute := func(ctx context.Context) ([]string, json.RawMessage, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		defer close(done)
+		return nil, nil, errors.New("refresh failed")
+	}
+
+	cache.refreshInBackground("hash", nil, time.Minute, compute)
+	cache.refreshInBackground("hash", nil, time.Minute, compute)
+	close(release)
+	<-done
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Eventually(t, func() bool {
+		_, inFlight := cache.refreshing.Load("hash")
+		return !inFlight
+	}, time.Second, 10*time.Millisecond)
+}
+
+// staleSearchCache serves a fixed stale entry without calling compute
+type staleSearchCache struct {
+	NoOpSearchCacheService
+	entry *models.SearchCacheEntry
+}
+
+func (c *staleSearchCache) GetOrComputeSearch(ctx context.Context, queryParams map[string]interface{}, ttl time.Duration, compute SearchComputeFunc) (*models.SearchCacheEntry, bool, error) {
+	return c.entry, true, nil
+}
+
+func TestOptimizedSearchService_Search(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("stale cache hit is served without searching", func(t *testing.T) {
+		payload, err := json.Marshal([]models.OptimizedSearchResult{{ChunkID: "chunk-1", Similarity: 0.9}})
+		require.NoError(t, err)
+
+		mockSearch := new(MockSearchService)
+		svc := NewOptimizedSearchService(mockSearch, &staleSearchCache{entry: &models.SearchCacheEntry{
+			SearchHash: "abc",
+			ChunkIDs:   []string{"chunk-1"},
+			Payload:    payload,
+			Stale:      true,
+		}}, time.Minute)
+
+		resp, err := svc.Search(ctx, &models.OptimizedSearchRequest{Query: "test", UseCache: true})
+		require.NoError(t, err)
+		assert.True(t, resp.CacheHit)
+		assert.Equal(t, "abc", resp.Metadata.QueryHash)
+		assert.Contains(t, resp.Optimizations, "stale_while_revalidate")
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "chunk-1", resp.Results[0].ChunkID)
+		mockSearch.AssertNotCalled(t, "SemanticSearchWithFilters", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cache bypass runs the semantic search", func(t *testing.T) {
+		mockSearch := new(MockSearchService)
+		mockSearch.On("SemanticSearchWithFilters", ctx, mock.Anything).Return(&SemanticSearchResponse{
+			Results: []SimilarityResult{{Chunk: ChunkRecord{ID: "chunk-2", Content: "hello"}, Similarity: 0.8}},
+		}, nil).Once()
+
+		svc := NewOptimizedSearchService(mockSearch, nil, time.Minute)
+		resp, err := svc.Search(ctx, &models.OptimizedSearchRequest{Query: "hello"})
+		require.NoError(t, err)
+		assert.False(t, resp.CacheHit)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, "chunk-2", resp.Results[0].ChunkID)
+		assert.Equal(t, 0.8, resp.Results[0].Relevance)
+		mockSearch.AssertExpectations(t)
+	})
+
+	t.Run("empty query is rejected", func(t *testing.T) {
+		svc := NewOptimizedSearchService(new(MockSearchService), nil, time.Minute)
+		_, err := svc.Search(ctx, &models.OptimizedSearchRequest{})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"semantic-text-processor/models"
+	"sort"
+	"strconv"
+
+	"github.com/lib/pq"
+)
+
+const defaultSearchFacetLimit = 10
+
+// SearchFacetSource loads the chunk attributes that search facets are counted over
+type SearchFacetSource interface {
+	GetFacetAttributes(ctx context.Context, chunkIDs []string) (map[string]*models.ChunkFacetAttributes, error)
+}
+
+// sqlSearchFacetSource reads facet attributes from the chunks and chunk_tags tables
+type sqlSearchFacetSource struct {
+	db *sql.DB
+}
+
+// NewSQLSearchFacetSource creates a facet source backed by PostgreSQL
+func NewSQLSearchFacetSource(db *sql.DB) SearchFacetSource {
+	return &sqlSearchFacetSource{db: db}
+}
+
+// GetFacetAttributes loads the page, template flag, creation time and tags of every
+// chunk in one query. Page chunks count as their own page.
+func (s *sqlSearchFacetSource) GetFacetAttributes(ctx context.Context, chunkIDs []string) (map[string]*models.ChunkFacetAttributes, error) {
+	attributes := make(map[string]*models.ChunkFacetAttributes, len(chunkIDs))
+	if len(chunkIDs) == 0 {
+		return attributes, nil
+	}
+
+	query := `
+		SELECT c.chunk_id, COALESCE(c.page, CASE WHEN c.is_page THEN c.chunk_id END),
+			   COALESCE(p.contents, CASE WHEN c.is_page THEN c.contents END, ''),
+			   c.is_template, c.created_time,
+			   COALESCE(array_agg(t.chunk_id::text ORDER BY t.contents) FILTER (WHERE t.chunk_id IS NOT NULL), '{}'),
+			   COALESCE(array_agg(t.contents ORDER BY t.contents) FILTER (WHERE t.chunk_id IS NOT NULL), '{}')
+		FROM chunks c
+		LEFT JOIN chunks p ON p.chunk_id = c.page
+		LEFT JOIN chunk_tags ct ON ct.source_chunk_id = c.chunk_id
+		LEFT JOIN chunks t ON t.chunk_id = ct.tag_chunk_id
+		WHERE c.chunk_id = ANY($1)
+		GROUP BY c.chunk_id, c.page, p.contents, c.is_page, c.contents, c.is_template, c.created_time
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query facet attributes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var attrs models.ChunkFacetAttributes
+		var pageID sql.NullString
+		var tagIDs, tagNames pq.StringArray
+		if err := rows.Scan(&attrs.ChunkID, &pageID, &attrs.PageTitle, &attrs.IsTemplate, &attrs.CreatedTime, &tagIDs, &tagNames); err != nil {
+			return nil, fmt.Errorf("failed to scan facet attributes: %w", err)
+		}
+		if pageID.Valid {
+			attrs.PageID = &pageID.String
+		}
+		attrs.Tags = make(map[string]string, len(tagIDs))
+		for i, tagID := range tagIDs {
+			attrs.Tags[tagID] = tagNames[i]
+		}
+		attributes[attrs.ChunkID] = &attrs
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read facet attributes: %w", err)
+	}
+
+	return attributes, nil
+}
+
+// ValidateSearchFacets rejects facet names that cannot be computed
+func ValidateSearchFacets(facets []string) error {
+	for _, facet := range facets {
+		switch facet {
+		case models.SearchFacetTag, models.SearchFacetPage, models.SearchFacetIsTemplate, models.SearchFacetCreatedMonth:
+		default:
+			return fmt.Errorf("unsupported search facet %q: use tag, page, is_template or created_month", facet)
+		}
+	}
+	return nil
+}
+
+// computeSearchFacets counts results per facet value, keeping the limit largest buckets of
+// each facet. Results without attributes, such as chunks deleted since they were cached,
+// are not counted.
+func computeSearchFacets(results []models.OptimizedSearchResult, attributes map[string]*models.ChunkFacetAttributes, facets []string, limit int) map[string][]models.FacetBucket {
+	if limit <= 0 {
+		limit = defaultSearchFacetLimit
+	}
+
+	computed := make(map[string][]models.FacetBucket, len(facets))
+	for _, facet := range facets {
+		counts := make(map[string]*models.FacetBucket)
+		count := func(value, label string) {
+			bucket, ok := counts[value]
+			if !ok {
+				bucket = &models.FacetBucket{Value: value, Label: label}
+				counts[value] = bucket
+			}
+			bucket.Count++
+		}
+
+		for _, result := range results {
+			attrs, ok := attributes[result.ChunkID]
+			if !ok {
+				continue
+			}
+			switch facet {
+			case models.SearchFacetTag:
+				for tagID, tagName := range attrs.Tags {
+					count(tagID, tagName)
+				}
+			case models.SearchFacetPage:
+				if attrs.PageID != nil {
+					count(*attrs.PageID, attrs.PageTitle)
+				}
+			case models.SearchFacetIsTemplate:
+				count(strconv.FormatBool(attrs.IsTemplate), "")
+			case models.SearchFacetCreatedMonth:
+				count(attrs.CreatedTime.UTC().Format("2006-01"), "")
+			}
+		}
+
+		buckets := make([]models.FacetBucket, 0, len(counts))
+		for _, bucket := range counts {
+			buckets = append(buckets, *bucket)
+		}
+		sort.Slice(buckets, func(i, j int) bool {
+			if buckets[i].Count != buckets[j].Count {
+				return buckets[i].Count > buckets[j].Count
+			}
+			return buckets[i].Value < buckets[j].Value
+		})
+		if len(buckets) > limit {
+			buckets = buckets[:limit]
+		}
+		computed[facet] = buckets
+	}
+
+	return computed
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSearchService returns the same semantic search results for every query
+type staticSearchService struct {
+	results []models.SimilarityResult
+}
+
+func (s *staticSearchService) SemanticSearch(ctx context.Context, query string, limit int) ([]models.SimilarityResult, error) {
+	return s.results, nil
+}
+
+func (s *staticSearchService) SemanticSearchWithFilters(ctx context.Context, req *models.SemanticSearchRequest) (*models.SemanticSearchResponse, error) {
+	return &models.SemanticSearchResponse{Results: s.results, TotalCount: len(s.results), Query: req.Query, Limit: req.Limit}, nil
+}
+
+func (s *staticSearchService) HybridSearch(ctx context.Context, query string, limit int, semanticWeight float64) ([]models.SimilarityResult, error) {
+	return s.results, nil
+}
+
+func (s *staticSearchService) GraphSearch(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) {
+	return &models.GraphResult{}, nil
+}
+
+func (s *staticSearchService) SearchByTag(ctx context.Context, tagContent string) ([]models.ChunkWithTags, error) {
+	return nil, nil
+}
+
+func (s *staticSearchService) SearchChunks(ctx context.Context, query string, filters map[string]interface{}) ([]models.ChunkRecord, error) {
+	return nil, nil
+}
+
+// fakeFacetSource serves fixed attributes and counts lookups
+type fakeFacetSource struct {
+	attributes map[string]*models.ChunkFacetAttributes
+	calls      int
+}
+
+func (s *fakeFacetSource) GetFacetAttributes(ctx context.Context, chunkIDs []string) (map[string]*models.ChunkFacetAttributes, error) {
+	s.calls++
+	return s.attributes, nil
+}
+
+func TestOptimizedSearch_Facets(t *testing.T) {
+	page := "page-1"
+	january := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	february := time.Date(2024, 2, 3, 0, 0, 0, 0, time.UTC)
+
+	search := &staticSearchService{results: []models.SimilarityResult{
+		{Chunk: models.ChunkRecord{ID: "c1"}, Similarity: 0.9},
+		{Chunk: models.ChunkRecord{ID: "c2"}, Similarity: 0.8},
+		{Chunk: models.ChunkRecord{ID: "c3"}, Similarity: 0.7},
+		{Chunk: models.ChunkRecord{ID: "deleted"}, Similarity: 0.6},
+	}}
+	source := &fakeFacetSource{attributes: map[string]*models.ChunkFacetAttributes{
+		"c1": {ChunkID: "c1", PageID: &page, PageTitle: "Reading", CreatedTime: january, Tags: map[string]string{"t1": "#go", "t2": "#db"}},
+		"c2": {ChunkID: "c2", PageID: &page, PageTitle: "Reading", CreatedTime: january, Tags: map[string]string{"t1": "#go"}},
+		"c3": {ChunkID: "c3", IsTemplate: true, CreatedTime: february},
+	}}
+
+	service := NewOptimizedSearchService(search, nil, time.Minute)
+	service.SetFacetSource(source)
+
+	resp, err := service.Search(context.Background(), &models.OptimizedSearchRequest{
+		Query:  "go",
+		Facets: []string{models.SearchFacetTag, models.SearchFacetPage, models.SearchFacetIsTemplate, models.SearchFacetCreatedMonth},
+	})
+	require.NoError(t, err)
+	assert.Len(t, resp.Results, 4)
+	assert.Equal(t, 1, source.calls)
+	assert.Equal(t, 1, resp.Metadata.DatabaseQueries)
+
+	assert.Equal(t, []models.FacetBucket{{Value: "t1", Label: "#go", Count: 2}, {Value: "t2", Label: "#db", Count: 1}}, resp.Facets[models.SearchFacetTag])
+	assert.Equal(t, []models.FacetBucket{{Value: page, Label: "Reading", Count: 2}}, resp.Facets[models.SearchFacetPage])
+	assert.Equal(t, []models.FacetBucket{{Value: "false", Count: 2}, {Value: "true", Count: 1}}, resp.Facets[models.SearchFacetIsTemplate])
+	assert.Equal(t, []models.FacetBucket{{Value: "2024-01", Count: 2}, {Value: "2024-02", Count: 1}}, resp.Facets[models.SearchFacetCreatedMonth])
+
+	limited, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "go", Facets: []string{models.SearchFacetTag}, FacetLimit: 1})
+	require.NoError(t, err)
+	assert.Len(t, limited.Facets[models.SearchFacetTag], 1)
+	assert.NotContains(t, limited.Facets, models.SearchFacetPage)
+
+	plain, err := service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "go"})
+	require.NoError(t, err)
+	assert.Nil(t, plain.Facets)
+	assert.Equal(t, 2, source.calls, "facets are only computed when requested")
+
+	_, err = service.Search(context.Background(), &models.OptimizedSearchRequest{Query: "go", Facets: []string{"author"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,708 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+// Limits on filter expressions, which are compiled into the WHERE clause of every search
+const (
+	maxFilterExpressionLength = 4096
+	maxFilterConditions       = 32
+	maxFilterDepth            = 8
+	maxFilterListSize         = 100
+	maxFilterPathDepth        = 8
+)
+
+// ErrInvalidFilter is returned for filter expressions that cannot be compiled
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// filterPathSegment matches one segment of a metadata path
+var filterPathSegment = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// filterTimeLayouts are the formats accepted for created_time and last_updated values
+var filterTimeLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"}
+
+// textFilterOps maps the comparison operators of the text syntax to filter operators
+var textFilterOps = map[string]string{
+	"=":  models.FilterOpEq,
+	"!=": models.FilterOpNe,
+	">":  models.FilterOpGt,
+	">=": models.FilterOpGte,
+	"<":  models.FilterOpLt,
+	"<=": models.FilterOpLte,
+}
+
+// comparisonSQL maps ordering operators to SQL
+var comparisonSQL = map[string]string{
+	models.FilterOpEq:  "=",
+	models.FilterOpNe:  "<>",
+	models.FilterOpGt:  ">",
+	models.FilterOpGte: ">=",
+	models.FilterOpLt:  "<",
+	models.FilterOpLte: "<=",
+}
+
+// ParseFilterExpression parses the text form of a filter expression, for example
+//
+//	metadata.source = "email" AND (metadata.score > 0.5 OR tags IN ["uuid-1", "uuid-2"])
+//
+// Conditions compare a field with a JSON literal using =, !=, >, >=, <, <=, IN, NOT IN,
+// ALL and CONTAINS, or test it with EXISTS, and combine with AND, OR, NOT and parentheses.
+// Keywords are case-insensitive.
+func ParseFilterExpression(text string) (*models.FilterExpression, error) {
+	if len(text) > maxFilterExpressionLength {
+		return nil, fmt.Errorf("%w: expression is longer than %d bytes", ErrInvalidFilter, maxFilterExpressionLength)
+	}
+	tokens, err := lexFilterExpression(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("%w: expression is empty", ErrInvalidFilter)
+	}
+
+	parser := &filterParser{tokens: tokens}
+	expr, err := parser.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if parser.pos < len(tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, tokens[parser.pos].text)
+	}
+	if err := ValidateFilterExpression(expr); err != nil {
+		return nil, err
+	}
+	return expr, nil
+}
+
+// ValidateFilterExpression checks that a filter expression compiles. A nil expression is valid.
+func ValidateFilterExpression(expr *models.FilterExpression) error {
+	if expr == nil {
+		return nil
+	}
+	_, _, err := compileFilterExpression(expr, 1)
+	return err
+}
+
+type filterTokenKind int
+
+const (
+	filterTokenWord filterTokenKind = iota
+	filterTokenOp
+	filterTokenLiteral
+	filterTokenPunct
+)
+
+type filterToken struct {
+	kind filterTokenKind
+	text string
+}
+
+// lexFilterExpression splits the text syntax into words, operators, JSON literals and
+// punctuation
+func lexFilterExpression(text string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']' || c == ',':
+			tokens = append(tokens, filterToken{filterTokenPunct, string(c)})
+			i++
+		case c == '=' || c == '!' || c == '<' || c == '>':
+			op := string(c)
+			if i+1 < len(text) && text[i+1] == '=' {
+				op += "="
+			}
+			if _, ok := textFilterOps[op]; !ok {
+				return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, op)
+			}
+			tokens = append(tokens, filterToken{filterTokenOp, op})
+			i += len(op)
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(text) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+			}
+			tokens = append(tokens, filterToken{filterTokenLiteral, text[i : end+1]})
+			i = end + 1
+		case c == '-' || c == '+' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			tokens = append(tokens, filterToken{filterTokenLiteral, strings.TrimPrefix(text[i:end], "+")})
+			i = end
+		case c == '_' || c < unicode.MaxASCII && unicode.IsLetter(rune(c)):
+			end := i + 1
+			for end < len(text) && (text[end] == '_' || text[end] == '.' || text[end] == '-' ||
+				text[end] < unicode.MaxASCII && (unicode.IsLetter(rune(text[end])) || unicode.IsDigit(rune(text[end])))) {
+				end++
+			}
+			tokens = append(tokens, filterToken{filterTokenWord, text[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrInvalidFilter, c)
+		}
+	}
+	return tokens, nil
+}
+
+// filterParser is a recursive-descent parser over the text syntax tokens
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() *filterToken {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+// keyword consumes the next token if it is the given keyword
+func (p *filterParser) keyword(word string) bool {
+	if t := p.peek(); t != nil && t.kind == filterTokenWord && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// punct consumes the next token if it is the given punctuation
+func (p *filterParser) punct(s string) bool {
+	if t := p.peek(); t != nil && t.kind == filterTokenPunct && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr(depth int) (*models.FilterExpression, error) {
+	first, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	terms := []models.FilterExpression{*first}
+	for p.keyword("OR") {
+		next, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, *next)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &models.FilterExpression{Or: terms}, nil
+}
+
+func (p *filterParser) parseAnd(depth int) (*models.FilterExpression, error) {
+	first, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	terms := []models.FilterExpression{*first}
+	for p.keyword("AND") {
+		next, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, *next)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &models.FilterExpression{And: terms}, nil
+}
+
+func (p *filterParser) parseUnary(depth int) (*models.FilterExpression, error) {
+	if depth >= maxFilterDepth {
+		return nil, fmt.Errorf("%w: expression nests deeper than %d levels", ErrInvalidFilter, maxFilterDepth)
+	}
+	if p.keyword("NOT") {
+		inner, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &models.FilterExpression{Not: inner}, nil
+	}
+	if p.punct("(") {
+		inner, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if !p.punct(")") {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidFilter)
+		}
+		return inner, nil
+	}
+	return p.parseCondition()
+}
+
+func (p *filterParser) parseCondition() (*models.FilterExpression, error) {
+	field := p.peek()
+	if field == nil || field.kind != filterTokenWord {
+		return nil, p.unexpected("a field name")
+	}
+	p.pos++
+	condition := &models.FilterExpression{Field: field.text}
+
+	switch t := p.peek(); {
+	case t == nil:
+		return nil, p.unexpected("an operator")
+	case t.kind == filterTokenOp:
+		p.pos++
+		condition.Op = textFilterOps[t.text]
+	case p.keyword("EXISTS"):
+		condition.Op = models.FilterOpExists
+		return condition, nil
+	case p.keyword("IN"):
+		condition.Op = models.FilterOpIn
+	case p.keyword("ALL"):
+		condition.Op = models.FilterOpAll
+	case p.keyword("CONTAINS"):
+		condition.Op = models.FilterOpContains
+	case p.keyword("NOT"):
+		if !p.keyword("IN") {
+			return nil, p.unexpected("IN")
+		}
+		condition.Op = models.FilterOpNotIn
+	default:
+		return nil, p.unexpected("an operator")
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	condition.Value = value
+	return condition, nil
+}
+
+// parseValue parses a JSON scalar or a bracketed list of them
+func (p *filterParser) parseValue() (interface{}, error) {
+	if p.punct("[") {
+		list := []interface{}{}
+		if p.punct("]") {
+			return list, nil
+		}
+		for {
+			value, err := p.parseScalar()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+			if p.punct("]") {
+				return list, nil
+			}
+			if !p.punct(",") {
+				return nil, p.unexpected(`"," or "]"`)
+			}
+		}
+	}
+	return p.parseScalar()
+}
+
+func (p *filterParser) parseScalar() (interface{}, error) {
+	t := p.peek()
+	if t == nil {
+		return nil, p.unexpected("a value")
+	}
+	switch {
+	case t.kind == filterTokenLiteral:
+		p.pos++
+		var value interface{}
+		if err := json.Unmarshal([]byte(t.text), &value); err != nil {
+			return nil, fmt.Errorf("%w: malformed value %s", ErrInvalidFilter, t.text)
+		}
+		return value, nil
+	case p.keyword("true"):
+		return true, nil
+	case p.keyword("false"):
+		return false, nil
+	case p.keyword("null"):
+		return nil, nil
+	}
+	return nil, p.unexpected("a value")
+}
+
+func (p *filterParser) unexpected(want string) error {
+	if t := p.peek(); t != nil {
+		return fmt.Errorf("%w: expected %s, found %q", ErrInvalidFilter, want, t.text)
+	}
+	return fmt.Errorf("%w: expected %s at end of expression", ErrInvalidFilter, want)
+}
+
+// filterCompiler compiles a filter expression into a parameterized SQL condition on the
+// chunks table
+type filterCompiler struct {
+	args       []interface{}
+	next       int
+	conditions int
+}
+
+// compileFilterExpression compiles expr into a SQL condition whose placeholders start at
+// argIndex, returning the condition and its arguments. Metadata equality, membership and
+// existence tests, and tag tests, use the jsonb containment and key operators so that the
+// GIN indexes on metadata and tags serve them.
+func compileFilterExpression(expr *models.FilterExpression, argIndex int) (string, []interface{}, error) {
+	c := &filterCompiler{next: argIndex}
+	sql, err := c.compile(expr, 0)
+	if err != nil {
+		return "", nil, err
+	}
+	return sql, c.args, nil
+}
+
+func (c *filterCompiler) arg(value interface{}) string {
+	c.args = append(c.args, value)
+	c.next++
+	return fmt.Sprintf("$%d", c.next-1)
+}
+
+func (c *filterCompiler) compile(expr *models.FilterExpression, depth int) (string, error) {
+	if depth > maxFilterDepth {
+		return "", fmt.Errorf("%w: expression nests deeper than %d levels", ErrInvalidFilter, maxFilterDepth)
+	}
+
+	parts := 0
+	if expr.Field != "" || expr.Op != "" {
+		parts++
+	}
+	if len(expr.And) > 0 {
+		parts++
+	}
+	if len(expr.Or) > 0 {
+		parts++
+	}
+	if expr.Not != nil {
+		parts++
+	}
+	if parts != 1 {
+		return "", fmt.Errorf("%w: each expression needs exactly one of a condition, and, or, not", ErrInvalidFilter)
+	}
+
+	switch {
+	case len(expr.And) > 0:
+		return c.compileGroup(expr.And, " AND ", depth)
+	case len(expr.Or) > 0:
+		return c.compileGroup(expr.Or, " OR ", depth)
+	case expr.Not != nil:
+		inner, err := c.compile(expr.Not, depth+1)
+		if err != nil {
+			return "", err
+		}
+		return notCondition(inner), nil
+	}
+
+	c.conditions++
+	if c.conditions > maxFilterConditions {
+		return "", fmt.Errorf("%w: more than %d conditions", ErrInvalidFilter, maxFilterConditions)
+	}
+	return c.compileCondition(expr)
+}
+
+func (c *filterCompiler) compileGroup(terms []models.FilterExpression, separator string, depth int) (string, error) {
+	compiled := make([]string, len(terms))
+	for i := range terms {
+		sql, err := c.compile(&terms[i], depth+1)
+		if err != nil {
+			return "", err
+		}
+		compiled[i] = sql
+	}
+	return "(" + strings.Join(compiled, separator) + ")", nil
+}
+
+// notCondition negates a condition, treating NULL (a missing value) as false
+func notCondition(sql string) string {
+	return "NOT COALESCE(" + sql + ", false)"
+}
+
+func (c *filterCompiler) compileCondition(expr *models.FilterExpression) (string, error) {
+	field, value := expr.Field, filterNumber(expr.Value)
+	switch {
+	case strings.HasPrefix(field, "metadata."):
+		path := strings.Split(strings.TrimPrefix(field, "metadata."), ".")
+		if len(path) > maxFilterPathDepth {
+			return "", fmt.Errorf("%w: metadata path %s is deeper than %d keys", ErrInvalidFilter, field, maxFilterPathDepth)
+		}
+		for _, segment := range path {
+			if !filterPathSegment.MatchString(segment) {
+				return "", fmt.Errorf("%w: metadata path %s may only hold letters, digits, _ and -", ErrInvalidFilter, field)
+			}
+		}
+		return c.compileMetadata(field, path, expr.Op, value)
+	case field == "created_time" || field == "last_updated":
+		return c.compileTime(field, expr.Op, value)
+	case field == "tags":
+		return c.compileTags(expr.Op, value)
+	case field == "is_page" || field == "is_tag" || field == "is_template" || field == "is_slot":
+		flag, ok := value.(bool)
+		if !ok {
+			return "", fmt.Errorf("%w: %s takes true or false", ErrInvalidFilter, field)
+		}
+		switch expr.Op {
+		case models.FilterOpEq:
+			return fmt.Sprintf("%s = %s", field, c.arg(flag)), nil
+		case models.FilterOpNe:
+			return fmt.Sprintf("%s IS DISTINCT FROM %s", field, c.arg(flag)), nil
+		}
+	case field == "page" || field == "parent":
+		return c.compileChunkReference(field, expr.Op, value)
+	default:
+		return "", fmt.Errorf("%w: unknown field %q; use metadata.<path>, created_time, last_updated, tags, page, parent or an is_ flag", ErrInvalidFilter, field)
+	}
+	return "", unsupportedFilterOp(field, expr.Op)
+}
+
+func unsupportedFilterOp(field, op string) error {
+	return fmt.Errorf("%w: operator %q is not supported on %s", ErrInvalidFilter, op, field)
+}
+
+// compileMetadata compiles a condition on a metadata path
+func (c *filterCompiler) compileMetadata(field string, path []string, op string, value interface{}) (string, error) {
+	switch op {
+	case models.FilterOpEq, models.FilterOpNe:
+		if !isFilterScalar(value) {
+			return "", fmt.Errorf("%w: %s %s takes a string, number, boolean or null", ErrInvalidFilter, field, op)
+		}
+		sql, err := c.containment(path, value)
+		if err != nil || op == models.FilterOpEq {
+			return sql, err
+		}
+		return notCondition(sql), nil
+
+	case models.FilterOpIn, models.FilterOpNotIn:
+		list, err := filterList(field, op, value)
+		if err != nil {
+			return "", err
+		}
+		alternatives := make([]string, len(list))
+		for i, item := range list {
+			item = filterNumber(item)
+			if !isFilterScalar(item) {
+				return "", fmt.Errorf("%w: %s %s takes a list of strings, numbers, booleans or nulls", ErrInvalidFilter, field, op)
+			}
+			if alternatives[i], err = c.containment(path, item); err != nil {
+				return "", err
+			}
+		}
+		sql := "(" + strings.Join(alternatives, " OR ") + ")"
+		if len(list) == 0 {
+			sql = "false"
+		}
+		if op == models.FilterOpNotIn {
+			return notCondition(sql), nil
+		}
+		return sql, nil
+
+	case models.FilterOpContains:
+		if !isFilterScalar(value) {
+			return "", fmt.Errorf("%w: %s contains takes a string, number, boolean or null", ErrInvalidFilter, field)
+		}
+		return c.containment(path, []interface{}{value})
+
+	case models.FilterOpExists:
+		if len(path) == 1 {
+			return fmt.Sprintf("metadata ? %s", c.arg(path[0])), nil
+		}
+		return fmt.Sprintf("(metadata ? %s AND metadata #> %s::text[] IS NOT NULL)", c.arg(path[0]), c.arg(pq.Array(path))), nil
+
+	case models.FilterOpGt, models.FilterOpGte, models.FilterOpLt, models.FilterOpLte:
+		// Values of another JSON type than the operand never match. The key test lets the
+		// GIN index narrow the rows before the path is read.
+		var jsonType, cast string
+		switch value.(type) {
+		case float64:
+			jsonType, cast = "number", "::numeric"
+		case string:
+			jsonType = "string"
+		default:
+			return "", fmt.Errorf("%w: %s %s takes a number or string", ErrInvalidFilter, field, op)
+		}
+		pathArg := c.arg(pq.Array(path))
+		return fmt.Sprintf("(metadata ? %s AND CASE WHEN jsonb_typeof(metadata #> %s::text[]) = '%s' THEN (metadata #>> %s::text[])%s %s %s END)",
+			c.arg(path[0]), pathArg, jsonType, pathArg, cast, comparisonSQL[op], c.arg(value)), nil
+	}
+	return "", unsupportedFilterOp(field, op)
+}
+
+// containment tests that metadata holds value at path with the @> operator
+func (c *filterCompiler) containment(path []string, value interface{}) (string, error) {
+	for i := len(path) - 1; i >= 0; i-- {
+		value = map[string]interface{}{path[i]: value}
+	}
+	document, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+	return fmt.Sprintf("metadata @> %s::jsonb", c.arg(string(document))), nil
+}
+
+// compileTime compiles a comparison of created_time or last_updated with a timestamp
+func (c *filterCompiler) compileTime(field, op string, value interface{}) (string, error) {
+	operator, ok := comparisonSQL[op]
+	if !ok {
+		return "", unsupportedFilterOp(field, op)
+	}
+	t, err := parseFilterTime(field, value)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s %s", field, operator, c.arg(t)), nil
+}
+
+// parseFilterTime parses a filter's timestamp value in one of filterTimeLayouts
+func parseFilterTime(field string, value interface{}) (time.Time, error) {
+	text, _ := value.(string)
+	for _, layout := range filterTimeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%w: %s takes an RFC 3339 timestamp or a date, got %v", ErrInvalidFilter, field, value)
+}
+
+// compileTags compiles a test of the tag chunk IDs in the tags column
+func (c *filterCompiler) compileTags(op string, value interface{}) (string, error) {
+	if op == models.FilterOpEq || op == models.FilterOpContains {
+		id, ok := value.(string)
+		if !ok {
+			return "", fmt.Errorf("%w: tags %s takes a tag chunk ID", ErrInvalidFilter, op)
+		}
+		return fmt.Sprintf("tags ? %s", c.arg(id)), nil
+	}
+
+	list, err := filterList("tags", op, value)
+	if err != nil {
+		return "", err
+	}
+	ids, err := filterStrings("tags", list)
+	if err != nil {
+		return "", err
+	}
+	switch op {
+	case models.FilterOpIn:
+		return fmt.Sprintf("tags ?| %s::text[]", c.arg(pq.Array(ids))), nil
+	case models.FilterOpAll:
+		return fmt.Sprintf("tags ?& %s::text[]", c.arg(pq.Array(ids))), nil
+	case models.FilterOpNotIn:
+		return notCondition(fmt.Sprintf("tags ?| %s::text[]", c.arg(pq.Array(ids)))), nil
+	}
+	return "", unsupportedFilterOp("tags", op)
+}
+
+// compileChunkReference compiles a test of the page or parent chunk ID
+func (c *filterCompiler) compileChunkReference(field, op string, value interface{}) (string, error) {
+	switch op {
+	case models.FilterOpExists:
+		return field + " IS NOT NULL", nil
+	case models.FilterOpEq, models.FilterOpNe:
+		id, ok := value.(string)
+		if _, err := uuid.Parse(id); !ok || err != nil {
+			return "", fmt.Errorf("%w: %s takes a chunk UUID", ErrInvalidFilter, field)
+		}
+		if op == models.FilterOpEq {
+			return fmt.Sprintf("%s = %s::uuid", field, c.arg(id)), nil
+		}
+		return fmt.Sprintf("%s IS DISTINCT FROM %s::uuid", field, c.arg(id)), nil
+	case models.FilterOpIn, models.FilterOpNotIn:
+		list, err := filterList(field, op, value)
+		if err != nil {
+			return "", err
+		}
+		ids, err := filterStrings(field, list)
+		if err != nil {
+			return "", err
+		}
+		for _, id := range ids {
+			if _, err := uuid.Parse(id); err != nil {
+				return "", fmt.Errorf("%w: %s takes chunk UUIDs", ErrInvalidFilter, field)
+			}
+		}
+		sql := fmt.Sprintf("%s = ANY(%s::uuid[])", field, c.arg(pq.Array(ids)))
+		if op == models.FilterOpNotIn {
+			return notCondition(sql), nil
+		}
+		return sql, nil
+	}
+	return "", unsupportedFilterOp(field, op)
+}
+
+// filterList returns the list value of a membership condition
+func filterList(field, op string, value interface{}) ([]interface{}, error) {
+	if values, ok := value.([]string); ok {
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+		value = list
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s takes a list", ErrInvalidFilter, field, op)
+	}
+	if len(list) > maxFilterListSize {
+		return nil, fmt.Errorf("%w: %s %s lists more than %d values", ErrInvalidFilter, field, op, maxFilterListSize)
+	}
+	return list, nil
+}
+
+func filterStrings(field string, list []interface{}) ([]string, error) {
+	values := make([]string, len(list))
+	for i, item := range list {
+		value, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s takes a list of strings", ErrInvalidFilter, field)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// isFilterScalar reports whether value is a JSON scalar as decoded by encoding/json
+func isFilterScalar(value interface{}) bool {
+	switch value.(type) {
+	case nil, string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// filterNumber converts the integer types a filter built in Go may hold to float64, the
+// type JSON numbers decode to
+func filterNumber(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case json.Number:
+		if f, err := strconv.ParseFloat(string(v), 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
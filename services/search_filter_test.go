@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func TestParseFilterExpression(t *testing.T) {
+	expr, err := ParseFilterExpression(`metadata.source = "email" and (metadata.score >= 0.5 OR NOT tags in ["t1", "t2"]) AND created_time > "2024-01-01"`)
+	require.NoError(t, err)
+	assert.Equal(t, &models.FilterExpression{And: []models.FilterExpression{
+		{Field: "metadata.source", Op: models.FilterOpEq, Value: "email"},
+		{Or: []models.FilterExpression{
+			{Field: "metadata.score", Op: models.FilterOpGte, Value: 0.5},
+			{Not: &models.FilterExpression{Field: "tags", Op: models.FilterOpIn, Value: []interface{}{"t1", "t2"}}},
+		}},
+		{Field: "created_time", Op: models.FilterOpGt, Value: "2024-01-01"},
+	}}, expr)
+
+	expr, err = ParseFilterExpression(`metadata.author.name EXISTS`)
+	require.NoError(t, err)
+	assert.Equal(t, &models.FilterExpression{Field: "metadata.author.name", Op: models.FilterOpExists}, expr)
+
+	expr, err = ParseFilterExpression(`metadata.status NOT IN ["done", null] OR metadata.labels CONTAINS "urgent" OR is_page != true`)
+	require.NoError(t, err)
+	require.Len(t, expr.Or, 3)
+	assert.Equal(t, models.FilterExpression{Field: "metadata.status", Op: models.FilterOpNotIn, Value: []interface{}{"done", nil}}, expr.Or[0])
+	assert.Equal(t, models.FilterExpression{Field: "is_page", Op: models.FilterOpNe, Value: true}, expr.Or[2])
+
+	for _, invalid := range []string{
+		``,
+		`metadata.source`,
+		`metadata.source = email`,
+		`metadata.source == "email"`,
+		`(metadata.a = 1`,
+		`metadata.a = 1 metadata.b = 2`,
+		`metadata.a = "unterminated`,
+		`metadata.bad'key = 1`,
+		`contents = "x"`,
+		`metadata.a > true`,
+		`metadata.a IN "x"`,
+		`created_time > "yesterday"`,
+		`tags ALL [1]`,
+		`page = "not-a-uuid"`,
+		`is_page > true`,
+	} {
+		_, err := ParseFilterExpression(invalid)
+		assert.ErrorIs(t, err, ErrInvalidFilter, invalid)
+	}
+}
+
+func TestCompileFilterExpression(t *testing.T) {
+	expr, err := ParseFilterExpression(`metadata.source = "email" AND metadata.stats.score > 0.5 AND tags ALL ["t1", "t2"] AND created_time <= "2024-02-01T10:00:00Z"`)
+	require.NoError(t, err)
+
+	sql, args, err := compileFilterExpression(expr, 3)
+	require.NoError(t, err)
+	assert.Equal(t, "(metadata @> $3::jsonb"+
+		" AND (metadata ? $5 AND CASE WHEN jsonb_typeof(metadata #> $4::text[]) = 'number' THEN (metadata #>> $4::text[])::numeric > $6 END)"+
+		" AND tags ?& $7::text[]"+
+		" AND created_time <= $8)", sql)
+	assert.Equal(t, []interface{}{
+		`{"source":"email"}`,
+		pq.Array([]string{"stats", "score"}), "stats", 0.5,
+		pq.Array([]string{"t1", "t2"}),
+		time.Date(2024, 2, 1, 10, 0, 0, 0, time.UTC),
+	}, args)
+
+	sql, args, err = compileFilterExpression(&models.FilterExpression{Field: "metadata.priority", Op: models.FilterOpNotIn, Value: []interface{}{1, "high"}}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "NOT COALESCE((metadata @> $1::jsonb OR metadata @> $2::jsonb), false)", sql)
+	assert.Equal(t, []interface{}{`{"priority":1}`, `{"priority":"high"}`}, args)
+
+	sql, _, err = compileFilterExpression(&models.FilterExpression{Field: "metadata.labels", Op: models.FilterOpContains, Value: "urgent"}, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "metadata @> $1::jsonb", sql)
+
+	tooMany := &models.FilterExpression{}
+	for i := 0; i <= maxFilterConditions; i++ {
+		tooMany.Or = append(tooMany.Or, models.FilterExpression{Field: "metadata.n", Op: models.FilterOpEq, Value: i})
+	}
+	_, _, err = compileFilterExpression(tooMany, 1)
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+
+	_, _, err = compileFilterExpression(&models.FilterExpression{Field: "metadata.a", Op: models.FilterOpEq, Value: 1, Not: &models.FilterExpression{}}, 1)
+	assert.ErrorIs(t, err, ErrInvalidFilter, "a node is either a condition or a group")
+}
+
+func TestFilterExpression_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+
+	tag := &models.UnifiedChunkRecord{Contents: "filter-test-tag", IsTag: true}
+	require.NoError(t, chunks.CreateChunk(ctx, tag))
+	defer chunks.DeleteChunk(ctx, tag.ChunkID)
+	email := &models.UnifiedChunkRecord{Contents: "From the inbox", Metadata: map[string]interface{}{
+		"source": "email", "score": 0.9, "labels": []interface{}{"urgent"}, "author": map[string]interface{}{"name": "Ann"},
+	}}
+	require.NoError(t, chunks.CreateChunk(ctx, email))
+	defer chunks.DeleteChunk(ctx, email.ChunkID)
+	require.NoError(t, chunks.AddTags(ctx, email.ChunkID, []string{tag.ChunkID}))
+	web := &models.UnifiedChunkRecord{Contents: "From the web", Metadata: map[string]interface{}{"source": "web", "score": "high"}}
+	require.NoError(t, chunks.CreateChunk(ctx, web))
+	defer chunks.DeleteChunk(ctx, web.ChunkID)
+
+	match := func(filter string) []string {
+		expr, err := ParseFilterExpression(filter)
+		require.NoError(t, err)
+		sql, args, err := compileFilterExpression(expr, 2)
+		require.NoError(t, err)
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT chunk_id FROM chunks WHERE chunk_id = ANY($1) AND %s ORDER BY contents", sql),
+			append([]interface{}{pq.Array([]string{email.ChunkID, web.ChunkID})}, args...)...)
+		require.NoError(t, err)
+		defer rows.Close()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	assert.Equal(t, []string{email.ChunkID}, match(`metadata.source = "email"`))
+	assert.Equal(t, []string{email.ChunkID}, match(`metadata.score > 0.5`), "string scores never compare with numbers")
+	assert.Equal(t, []string{web.ChunkID}, match(`metadata.source != "email"`))
+	assert.Equal(t, []string{email.ChunkID, web.ChunkID}, match(`metadata.source IN ["email", "web"]`))
+	assert.Equal(t, []string{email.ChunkID}, match(`metadata.labels CONTAINS "urgent" AND metadata.author.name EXISTS`))
+	assert.Equal(t, []string{email.ChunkID}, match(fmt.Sprintf(`tags IN [%q]`, tag.ChunkID)))
+	assert.Equal(t, []string{web.ChunkID}, match(fmt.Sprintf(`NOT tags ALL [%q]`, tag.ChunkID)))
+	assert.Equal(t, []string{email.ChunkID, web.ChunkID}, match(`created_time > "2000-01-01" AND is_page = false`))
+}
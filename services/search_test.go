@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"testing"
+	"time"
 
 	"semantic-text-processor/models"
 
@@ -55,6 +56,7 @@ func (m *MockSupabaseClient) DeleteText(ctx context.Context, id string) error {
 func (m *MockSupabaseClient) InsertChunk(ctx context.Context, chunk *models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClient) InsertChunks(ctx context.Context, chunks []models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClient) GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error) { return nil, nil }
+func (m *MockSupabaseClient) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClient) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClient) UpdateChunk(ctx context.Context, chunk *models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClient) DeleteChunk(ctx context.Context, id string) error { return nil }
@@ -65,6 +67,7 @@ func (m *MockSupabaseClient) GetAllTemplates(ctx context.Context) ([]models.Temp
 func (m *MockSupabaseClient) CreateTemplateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error) { return nil, nil }
 func (m *MockSupabaseClient) GetTemplateInstances(ctx context.Context, templateChunkID string) ([]models.TemplateInstance, error) { return nil, nil }
 func (m *MockSupabaseClient) UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error { return nil }
+func (m *MockSupabaseClient) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) { return nil, nil }
 func (m *MockSupabaseClient) AddTag(ctx context.Context, chunkID string, tagContent string) error { return nil }
 func (m *MockSupabaseClient) RemoveTag(ctx context.Context, chunkID string, tagChunkID string) error { return nil }
 func (m *MockSupabaseClient) GetChunkTags(ctx context.Context, chunkID string) ([]models.ChunkRecord, error) { return nil, nil }
@@ -73,14 +76,19 @@ func (m *MockSupabaseClient) GetChunkHierarchy(ctx context.Context, rootChunkID
 func (m *MockSupabaseClient) GetChildrenChunks(ctx context.Context, parentChunkID string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClient) GetSiblingChunks(ctx context.Context, chunkID string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClient) MoveChunk(ctx context.Context, req *models.MoveChunkRequest) error { return nil }
+func (m *MockSupabaseClient) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error { return nil }
+func (m *MockSupabaseClient) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error { return nil }
 func (m *MockSupabaseClient) BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error { return nil }
 func (m *MockSupabaseClient) InsertEmbeddings(ctx context.Context, embeddings []models.EmbeddingRecord) error { return nil }
 func (m *MockSupabaseClient) InsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error { return nil }
 func (m *MockSupabaseClient) InsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error { return nil }
+func (m *MockSupabaseClient) UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error { return nil }
+func (m *MockSupabaseClient) UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error { return nil }
 func (m *MockSupabaseClient) GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClient) GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClient) FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClient) GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error) { return nil, nil }
+func (m *MockSupabaseClient) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClient) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) { return nil, nil }
 func (m *MockSupabaseClient) HealthCheck(ctx context.Context) error { return nil }
 
@@ -317,6 +325,72 @@ func TestSearchService_SemanticSearchWithFilters(t *testing.T) {
 	})
 }
 
+// filteringSearcher records the filters pushed into its vector query
+type filteringSearcher struct {
+	MockSupabaseClient
+	filter models.SimilarityFilter
+	limit  int
+}
+
+func (s *filteringSearcher) SearchSimilarFiltered(ctx context.Context, queryVector []float64, limit int, filter models.SimilarityFilter) ([]models.SimilarityResult, error) {
+	s.filter, s.limit = filter, limit
+	return []models.SimilarityResult{
+		{Chunk: models.ChunkRecord{ID: "chunk1", TextID: "text1"}, Similarity: 0.9},
+		{Chunk: models.ChunkRecord{ID: "chunk2", TextID: "text2"}, Similarity: 0.8},
+	}, nil
+}
+
+func TestSearchService_SemanticSearchWithFilters_PushedIntoQuery(t *testing.T) {
+	searcher := &filteringSearcher{}
+	searchService := NewSearchServiceWithSimilarity(&MockSupabaseClient{}, NewTestEmbeddingService(), searcher)
+	ctx := context.Background()
+	pageID := "7d0e6b1c-3c7e-4f8a-9a51-2b8f0c6d4e11"
+
+	response, err := searchService.SemanticSearchWithFilters(ctx, &models.SemanticSearchRequest{
+		Query: "test query",
+		Limit: 5,
+		Filters: map[string]interface{}{
+			"tags":           []interface{}{"tag-a", "tag-b"},
+			"page_id":        pageID,
+			"created_after":  "2024-01-01",
+			"created_before": "2024-02-01T00:00:00Z",
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, response.Results, 2)
+	assert.Equal(t, 5, searcher.limit, "filters inside the query need no extra candidates")
+	assert.Equal(t, []string{"tag-a", "tag-b"}, searcher.filter.TagIDs)
+	assert.Equal(t, pageID, searcher.filter.PageID)
+	require.NotNil(t, searcher.filter.CreatedAfter)
+	assert.Equal(t, "2024-01-01T00:00:00Z", searcher.filter.CreatedAfter.Format(time.RFC3339))
+	require.NotNil(t, searcher.filter.CreatedBefore)
+
+	// Other filters are still applied to the results
+	response, err = searchService.SemanticSearchWithFilters(ctx, &models.SemanticSearchRequest{
+		Query:   "test query",
+		Limit:   5,
+		Filters: map[string]interface{}{"tags": "tag-a", "text_id": "text1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, "chunk1", response.Results[0].Chunk.ID)
+	assert.Equal(t, 10, searcher.limit)
+
+	for _, filters := range []map[string]interface{}{
+		{"page_id": "not-a-uuid"},
+		{"created_after": "last tuesday"},
+		{"tags": 42},
+	} {
+		_, err := searchService.SemanticSearchWithFilters(ctx, &models.SemanticSearchRequest{Query: "q", Filters: filters})
+		assert.ErrorIs(t, err, ErrInvalidFilter, "%v", filters)
+	}
+
+	// A searcher that cannot filter in its query is not silently filtered afterwards
+	plain := NewSearchService(&MockSupabaseClient{}, NewTestEmbeddingService())
+	_, err = plain.SemanticSearchWithFilters(ctx, &models.SemanticSearchRequest{Query: "q", Filters: map[string]interface{}{"tags": "tag-a"}})
+	assert.ErrorIs(t, err, ErrInvalidFilter)
+}
+
 func TestSearchService_HybridSearch(t *testing.T) {
 	mockSupabase := &MockSupabaseClient{
 		searchSimilarFunc: func(ctx context.Context, queryVector []float64, limit int) ([]models.SimilarityResult, error) {
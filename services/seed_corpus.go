@@ -0,0 +1,627 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidSeedOptions is returned for seed options out of range
+var ErrInvalidSeedOptions = errors.New("invalid seed options")
+
+// Seed corpus defaults
+const (
+	defaultSeedPages         = 100
+	defaultSeedBlocksPerPage = 20
+	defaultSeedMaxDepth      = 4
+	defaultSeedTags          = 30
+	defaultSeedTemplates     = 3
+	defaultSeedRefRate       = 0.1
+	// defaultSeedDimensions matches the chunks.vector column
+	defaultSeedDimensions = 512
+	seedBatchSize         = 500
+	// seedTemplateEvery makes every fifth page an instance of a template
+	seedTemplateEvery = 5
+	seedBlockTagRate  = 0.1
+)
+
+// seedTopics are the subjects pages are written about; each language has titles and terms
+// for every topic in this order
+var seedTopics = []string{"engineering", "research", "reading", "travel", "cooking", "projects"}
+
+// seedTemplate is a template definition with the topic its instances are about
+type seedTemplate struct {
+	name  string
+	topic int
+	slots []string
+}
+
+// seedLanguage is the vocabulary notes in one language are written with
+type seedLanguage struct {
+	titles [][]string // per topic
+	terms  [][]string // per topic
+	// sentences take two terms as %[1]s and %[2]s
+	sentences []string
+	// link introduces a reference such as [[Page]] or ((block))
+	link      string
+	templates []seedTemplate
+}
+
+var seedLanguages = map[string]seedLanguage{
+	"en": {
+		titles: [][]string{
+			{"Service architecture", "Incident review", "API design"},
+			{"Literature review", "Experiment log", "Research questions"},
+			{"Reading notes", "Book summary", "Quotes"},
+			{"Trip plan", "Travel journal", "Packing list"},
+			{"Recipes", "Meal plan", "Kitchen notes"},
+			{"Weekly review", "Roadmap", "Meeting notes"},
+		},
+		terms: [][]string{
+			{"database migration", "connection pool", "cache invalidation", "rate limiter", "deployment pipeline", "query planner"},
+			{"embedding model", "evaluation dataset", "baseline", "ablation study", "retrieval accuracy", "sample size"},
+			{"chapter", "main argument", "author", "example", "counterpoint", "key idea"},
+			{"train ticket", "hotel", "museum", "local market", "itinerary", "budget"},
+			{"sourdough", "miso soup", "spice mix", "oven temperature", "grocery list", "fermentation"},
+			{"milestone", "deadline", "stakeholder", "action item", "risk", "retrospective"},
+		},
+		sentences: []string{
+			"The %[1]s depends on the %[2]s, so we should look at both together.",
+			"Open question: how does the %[1]s affect the %[2]s?",
+			"Today I learned more about the %[1]s.",
+			"Next step: compare the %[1]s with the %[2]s and write down the result.",
+			"The %[1]s worked better than expected.",
+			"Note to self: revisit the %[1]s next week.",
+		},
+		link: "See %s.",
+		templates: []seedTemplate{
+			{name: "Meeting notes", topic: 5, slots: []string{"Date", "Attendees", "Decisions"}},
+			{name: "Experiment", topic: 1, slots: []string{"Hypothesis", "Setup", "Result"}},
+		},
+	},
+	"zh": {
+		titles: [][]string{
+			{"服務架構", "事故檢討", "API 設計"},
+			{"文獻回顧", "實驗紀錄", "研究問題"},
+			{"讀書筆記", "書摘", "摘錄"},
+			{"旅行計畫", "旅遊日誌", "行李清單"},
+			{"食譜", "菜單規劃", "廚房筆記"},
+			{"每週回顧", "路線圖", "會議紀錄"},
+		},
+		terms: [][]string{
+			{"資料庫遷移", "連線池", "快取失效", "限流器", "部署流程", "查詢計畫"},
+			{"嵌入模型", "評估資料集", "基準線", "消融實驗", "檢索準確率", "樣本數"},
+			{"章節", "核心論點", "作者", "例子", "反論", "關鍵概念"},
+			{"火車票", "旅館", "博物館", "在地市場", "行程", "預算"},
+			{"酸種麵包", "味噌湯", "香料", "烤箱溫度", "採買清單", "發酵"},
+			{"里程碑", "截止日", "利害關係人", "待辦事項", "風險", "回顧會議"},
+		},
+		sentences: []string{
+			"%[1]s和%[2]s互相影響，應該一起看。",
+			"待釐清：%[1]s會怎麼影響%[2]s？",
+			"今天多了解了%[1]s。",
+			"下一步：比較%[1]s與%[2]s，並記錄結果。",
+			"%[1]s的效果比預期好。",
+			"提醒自己：下週再看一次%[1]s。",
+		},
+		link: "參見 %s。",
+		templates: []seedTemplate{
+			{name: "讀書摘要", topic: 2, slots: []string{"書名", "作者", "重點"}},
+			{name: "會議紀錄", topic: 5, slots: []string{"日期", "與會者", "決議"}},
+		},
+	},
+	"ja": {
+		titles: [][]string{
+			{"サービス構成", "障害振り返り", "API 設計"},
+			{"文献レビュー", "実験ログ", "研究課題"},
+			{"読書メモ", "本の要約", "引用"},
+			{"旅行計画", "旅日記", "持ち物リスト"},
+			{"レシピ", "献立", "台所メモ"},
+			{"週次レビュー", "ロードマップ", "議事録"},
+		},
+		terms: [][]string{
+			{"データベース移行", "コネクションプール", "キャッシュ無効化", "レートリミッター", "デプロイパイプライン", "クエリプラン"},
+			{"埋め込みモデル", "評価データセット", "ベースライン", "アブレーション", "検索精度", "サンプルサイズ"},
+			{"章", "主張", "著者", "具体例", "反論", "重要な考え"},
+			{"切符", "ホテル", "美術館", "市場", "旅程", "予算"},
+			{"サワードウ", "味噌汁", "スパイス", "オーブンの温度", "買い物リスト", "発酵"},
+			{"マイルストーン", "締め切り", "関係者", "アクション項目", "リスク", "振り返り"},
+		},
+		sentences: []string{
+			"%[1]sは%[2]sに左右されるので、一緒に確認する。",
+			"未解決：%[1]sは%[2]sにどう影響するか？",
+			"今日は%[1]sについて学んだ。",
+			"次は%[1]sと%[2]sを比較して結果を書く。",
+			"%[1]sは予想よりうまくいった。",
+			"来週もう一度%[1]sを見直す。",
+		},
+		link: "%s を参照。",
+		templates: []seedTemplate{
+			{name: "実験記録", topic: 1, slots: []string{"仮説", "手順", "結果"}},
+		},
+	},
+	"es": {
+		titles: [][]string{
+			{"Arquitectura del servicio", "Revisión de incidentes", "Diseño de la API"},
+			{"Revisión de literatura", "Registro de experimentos", "Preguntas de investigación"},
+			{"Notas de lectura", "Resumen del libro", "Citas"},
+			{"Plan de viaje", "Diario de viaje", "Lista de equipaje"},
+			{"Recetas", "Plan de comidas", "Notas de cocina"},
+			{"Revisión semanal", "Hoja de ruta", "Notas de reunión"},
+		},
+		terms: [][]string{
+			{"migración de base de datos", "pool de conexiones", "invalidación de caché", "limitador de tasa", "pipeline de despliegue", "plan de consulta"},
+			{"modelo de embeddings", "conjunto de evaluación", "línea base", "estudio de ablación", "precisión de recuperación", "tamaño de muestra"},
+			{"capítulo", "argumento principal", "autora", "ejemplo", "contraargumento", "idea clave"},
+			{"billete de tren", "hotel", "museo", "mercado local", "itinerario", "presupuesto"},
+			{"masa madre", "sopa de miso", "mezcla de especias", "temperatura del horno", "lista de compras", "fermentación"},
+			{"hito", "fecha límite", "parte interesada", "tarea pendiente", "riesgo", "retrospectiva"},
+		},
+		sentences: []string{
+			"El %[1]s depende del %[2]s, así que conviene revisarlos juntos.",
+			"Pregunta abierta: ¿cómo afecta el %[1]s al %[2]s?",
+			"Hoy aprendí más sobre el %[1]s.",
+			"Siguiente paso: comparar el %[1]s con el %[2]s y anotar el resultado.",
+			"El %[1]s funcionó mejor de lo esperado.",
+			"Nota: revisar el %[1]s la próxima semana.",
+		},
+		link: "Ver %s.",
+		templates: []seedTemplate{
+			{name: "Plan de viaje", topic: 3, slots: []string{"Destino", "Fechas", "Presupuesto"}},
+		},
+	},
+}
+
+// SeedLanguages lists the language codes seed corpora can be written in
+func SeedLanguages() []string {
+	return []string{"en", "zh", "ja", "es"}
+}
+
+// normalizeSeedOptions fills in defaults and rejects options out of range
+func normalizeSeedOptions(opts models.SeedCorpusOptions) (models.SeedCorpusOptions, error) {
+	if opts.Pages == 0 {
+		opts.Pages = defaultSeedPages
+	}
+	if opts.BlocksPerPage == 0 {
+		opts.BlocksPerPage = defaultSeedBlocksPerPage
+	}
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = defaultSeedMaxDepth
+	}
+	if len(opts.Languages) == 0 {
+		opts.Languages = SeedLanguages()
+	}
+	if opts.Embeddings == "" {
+		opts.Embeddings = models.SeedEmbeddingsNone
+	}
+	if opts.Dimensions == 0 {
+		opts.Dimensions = defaultSeedDimensions
+	}
+
+	switch {
+	case opts.Pages < 0 || opts.BlocksPerPage < 0 || opts.MaxDepth < 0 || opts.Tags < 0 || opts.Templates < 0:
+		return opts, fmt.Errorf("%w: counts cannot be negative", ErrInvalidSeedOptions)
+	case opts.RefRate < 0 || opts.RefRate > 1:
+		return opts, fmt.Errorf("%w: ref rate must be between 0 and 1", ErrInvalidSeedOptions)
+	case opts.Dimensions < 0:
+		return opts, fmt.Errorf("%w: dimensions cannot be negative", ErrInvalidSeedOptions)
+	}
+	for _, code := range opts.Languages {
+		if _, ok := seedLanguages[code]; !ok {
+			return opts, fmt.Errorf("%w: unsupported language %q, use one of %v", ErrInvalidSeedOptions, code, SeedLanguages())
+		}
+	}
+	switch opts.Embeddings {
+	case models.SeedEmbeddingsNone, models.SeedEmbeddingsSynthetic, models.SeedEmbeddingsService:
+	default:
+		return opts, fmt.Errorf("%w: embeddings must be %s, %s or %s", ErrInvalidSeedOptions,
+			models.SeedEmbeddingsNone, models.SeedEmbeddingsSynthetic, models.SeedEmbeddingsService)
+	}
+	return opts, nil
+}
+
+// DefaultSeedCorpusOptions returns the options of a corpus of about two thousand chunks
+func DefaultSeedCorpusOptions() models.SeedCorpusOptions {
+	return models.SeedCorpusOptions{
+		Pages:         defaultSeedPages,
+		BlocksPerPage: defaultSeedBlocksPerPage,
+		MaxDepth:      defaultSeedMaxDepth,
+		Languages:     SeedLanguages(),
+		Tags:          defaultSeedTags,
+		Templates:     defaultSeedTemplates,
+		RefRate:       defaultSeedRefRate,
+		Embeddings:    models.SeedEmbeddingsNone,
+		Dimensions:    defaultSeedDimensions,
+		Seed:          1,
+	}
+}
+
+// seedVectorKey is what a chunk's synthetic embedding clusters around; page is -1 for
+// tags and templates
+type seedVectorKey struct {
+	topic int
+	page  int
+}
+
+// seedCorpus is a generated knowledge base in creation order: parents before children and
+// siblings in order
+type seedCorpus struct {
+	records []models.UnifiedChunkRecord
+	keys    []seedVectorKey
+	tags    map[string][]string // tag chunk IDs by tagged chunk ID
+	result  models.SeedCorpusResult
+}
+
+func (c *seedCorpus) add(record models.UnifiedChunkRecord, topic, page int) {
+	c.records = append(c.records, record)
+	c.keys = append(c.keys, seedVectorKey{topic: topic, page: page})
+}
+
+func (c *seedCorpus) tag(chunkID string, tagIDs []string) {
+	if len(tagIDs) == 0 {
+		return
+	}
+	c.tags[chunkID] = tagIDs
+	c.result.Tagged++
+}
+
+// seedTemplateChunk is a template created in the corpus
+type seedTemplateChunk struct {
+	seedTemplate
+	id   string
+	lang string
+}
+
+// generateSeedCorpus builds a knowledge base from normalized options. IDs come from the
+// seeded random source too, so the same options give the same corpus.
+func generateSeedCorpus(opts models.SeedCorpusOptions) *seedCorpus {
+	rng := rand.New(rand.NewSource(opts.Seed))
+	corpus := &seedCorpus{
+		tags:   make(map[string][]string),
+		result: models.SeedCorpusResult{Languages: make(map[string]int)},
+	}
+	newID := func() string {
+		return uuid.Must(uuid.NewRandomFromReader(rng)).String()
+	}
+	metadata := func(topic int) map[string]interface{} {
+		return map[string]interface{}{"seed": true, "topic": seedTopics[topic]}
+	}
+
+	// Tags cycle through the topics, then the languages, then the terms
+	tagsByTopic := make([][]string, len(seedTopics))
+	for i := 0; i < opts.Tags; i++ {
+		topic := i % len(seedTopics)
+		lang := seedLanguages[opts.Languages[(i/len(seedTopics))%len(opts.Languages)]]
+		round := i / (len(seedTopics) * len(opts.Languages))
+		terms := lang.terms[topic]
+		name := terms[round%len(terms)]
+		if round >= len(terms) {
+			name = fmt.Sprintf("%s %d", name, round/len(terms)+1)
+		}
+		id := newID()
+		corpus.add(models.UnifiedChunkRecord{ChunkID: id, Contents: name, IsTag: true, Metadata: metadata(topic)}, topic, -1)
+		tagsByTopic[topic] = append(tagsByTopic[topic], id)
+		corpus.result.Tags++
+	}
+	pickTags := func(topic, count int) []string {
+		candidates := tagsByTopic[topic]
+		if len(candidates) == 0 {
+			return nil
+		}
+		var picked []string
+		for _, i := range rng.Perm(len(candidates)) {
+			if len(picked) == count {
+				break
+			}
+			picked = append(picked, candidates[i])
+		}
+		return picked
+	}
+
+	var available []seedTemplateChunk
+	for _, code := range opts.Languages {
+		for _, template := range seedLanguages[code].templates {
+			available = append(available, seedTemplateChunk{seedTemplate: template, lang: code})
+		}
+	}
+	templates := make([]seedTemplateChunk, 0, opts.Templates)
+	for i := 0; i < opts.Templates; i++ {
+		template := available[i%len(available)]
+		if i >= len(available) {
+			template.name = fmt.Sprintf("%s %d", template.name, i/len(available)+1)
+		}
+		template.id = newID()
+		corpus.add(models.UnifiedChunkRecord{
+			ChunkID:    template.id,
+			Contents:   template.name,
+			IsTemplate: true,
+			Metadata:   metadata(template.topic),
+		}, template.topic, -1)
+		for _, slot := range template.slots {
+			parent := template.id
+			corpus.add(models.UnifiedChunkRecord{
+				ChunkID:  newID(),
+				Contents: slot,
+				Parent:   &parent,
+				IsSlot:   true,
+				Metadata: metadata(template.topic),
+			}, template.topic, -1)
+			corpus.result.Blocks++
+		}
+		templates = append(templates, template)
+		corpus.result.Templates++
+	}
+
+	type seedPage struct{ id, title string }
+	var pages []seedPage
+	var blocks []string
+	sentence := func(lang seedLanguage, topic int) string {
+		terms := lang.terms[topic]
+		first := rng.Intn(len(terms))
+		second := (first + 1 + rng.Intn(len(terms)-1)) % len(terms)
+		return fmt.Sprintf(lang.sentences[rng.Intn(len(lang.sentences))], terms[first], terms[second])
+	}
+	// reference links the contents to an earlier page or block
+	reference := func(lang seedLanguage, contents string) (string, *string) {
+		if len(pages)+len(blocks) == 0 || rng.Float64() >= opts.RefRate {
+			return contents, nil
+		}
+		var target, link string
+		if len(blocks) == 0 || (len(pages) > 0 && rng.Intn(2) == 0) {
+			page := pages[rng.Intn(len(pages))]
+			target, link = page.id, "[["+page.title+"]]"
+		} else {
+			target = blocks[rng.Intn(len(blocks))]
+			link = "((" + target + "))"
+		}
+		corpus.result.Refs++
+		return contents + " " + fmt.Sprintf(lang.link, link), &target
+	}
+
+	for p := 0; p < opts.Pages; p++ {
+		code := opts.Languages[rng.Intn(len(opts.Languages))]
+		topic := rng.Intn(len(seedTopics))
+		var template *seedTemplateChunk
+		if len(templates) > 0 && p%seedTemplateEvery == seedTemplateEvery-1 {
+			template = &templates[(p/seedTemplateEvery)%len(templates)]
+			code, topic = template.lang, template.topic
+		}
+		lang := seedLanguages[code]
+
+		pageID := newID()
+		pageMetadata := metadata(topic)
+		var title string
+		if template != nil {
+			title = fmt.Sprintf("%s %d", template.name, p+1)
+			pageMetadata["template_instance_of"] = template.id
+			corpus.result.TemplateInstances++
+		} else {
+			titles := lang.titles[topic]
+			title = fmt.Sprintf("%s %d", titles[rng.Intn(len(titles))], p+1)
+		}
+		corpus.add(models.UnifiedChunkRecord{ChunkID: pageID, Contents: title, IsPage: true, Metadata: pageMetadata}, topic, p)
+		corpus.tag(pageID, pickTags(topic, 1+rng.Intn(2)))
+		corpus.result.Pages++
+		corpus.result.Languages[code]++
+
+		addBlock := func(parent, contents string, blockMetadata map[string]interface{}) string {
+			id := newID()
+			page := pageID
+			contents, ref := reference(lang, contents)
+			corpus.add(models.UnifiedChunkRecord{
+				ChunkID:  id,
+				Contents: contents,
+				Parent:   &parent,
+				Page:     &page,
+				Ref:      ref,
+				Metadata: blockMetadata,
+			}, topic, p)
+			if rng.Float64() < seedBlockTagRate {
+				corpus.tag(id, pickTags(topic, 1))
+			}
+			blocks = append(blocks, id)
+			corpus.result.Blocks++
+			return id
+		}
+
+		if template != nil {
+			for _, slot := range template.slots {
+				blockMetadata := metadata(topic)
+				blockMetadata["slot"] = slot
+				addBlock(pageID, slot+": "+sentence(lang, topic), blockMetadata)
+			}
+			corpus.result.MaxDepth = max(corpus.result.MaxDepth, 1)
+		} else {
+			// stack holds the path to the previous block; stack[d] is at depth d
+			stack := []string{pageID}
+			count := opts.BlocksPerPage/2 + rng.Intn(opts.BlocksPerPage+1)
+			for b := 0; b < count; b++ {
+				last := len(stack) - 1
+				depth := last
+				switch r := rng.Float64(); {
+				case r < 0.35 && last < opts.MaxDepth:
+					depth = last + 1
+				case r >= 0.8 && last > 1:
+					depth = 1 + rng.Intn(last-1)
+				}
+				depth = max(depth, 1)
+				id := addBlock(stack[depth-1], sentence(lang, topic), metadata(topic))
+				stack = append(stack[:depth], id)
+				corpus.result.MaxDepth = max(corpus.result.MaxDepth, depth)
+			}
+		}
+		pages = append(pages, seedPage{id: pageID, title: title})
+	}
+	return corpus
+}
+
+// syntheticEmbedder generates unit vectors around a center per topic, pulled toward a
+// center per page, so blocks of one page are most similar to each other and then to pages
+// on the same topic in any language
+type syntheticEmbedder struct {
+	rng        *rand.Rand
+	dimensions int
+	topics     [][]float64
+	pages      map[int][]float64
+}
+
+func newSyntheticEmbedder(seed int64, dimensions int) *syntheticEmbedder {
+	e := &syntheticEmbedder{
+		rng:        rand.New(rand.NewSource(seed)),
+		dimensions: dimensions,
+		pages:      make(map[int][]float64),
+	}
+	for range seedTopics {
+		e.topics = append(e.topics, e.random())
+	}
+	return e
+}
+
+func (e *syntheticEmbedder) random() []float64 {
+	vector := make([]float64, e.dimensions)
+	for i := range vector {
+		vector[i] = e.rng.NormFloat64()
+	}
+	return normalizeSeedVector(vector)
+}
+
+func (e *syntheticEmbedder) vector(key seedVectorKey) []float64 {
+	vector := make([]float64, e.dimensions)
+	copy(vector, e.topics[key.topic])
+	if key.page >= 0 {
+		page, ok := e.pages[key.page]
+		if !ok {
+			page = e.random()
+			e.pages[key.page] = page
+		}
+		for i := range vector {
+			vector[i] += 0.6 * page[i]
+		}
+	}
+	noise := e.random()
+	for i := range vector {
+		vector[i] += 0.4 * noise[i]
+	}
+	return normalizeSeedVector(vector)
+}
+
+func normalizeSeedVector(vector []float64) []float64 {
+	var norm float64
+	for _, v := range vector {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
+
+// CorpusSeeder writes generated knowledge bases of nested multi-language pages, tags,
+// templates and cross-references for demos and benchmarks
+type CorpusSeeder struct {
+	chunks     UnifiedChunkService
+	store      *EmbeddingStore
+	embeddings EmbeddingService
+	model      string
+}
+
+// NewCorpusSeeder creates a seeder. Chunks are written through chunks, so hierarchy, tag
+// and reference tables are filled as for any write. store and embeddings are only needed
+// for seeding embeddings; model is recorded with them.
+func NewCorpusSeeder(chunks UnifiedChunkService, store *EmbeddingStore, embeddings EmbeddingService, model string) *CorpusSeeder {
+	return &CorpusSeeder{chunks: chunks, store: store, embeddings: embeddings, model: model}
+}
+
+// Seed generates a knowledge base and writes it in batches
+func (s *CorpusSeeder) Seed(ctx context.Context, opts models.SeedCorpusOptions) (*models.SeedCorpusResult, error) {
+	start := time.Now()
+	opts, err := normalizeSeedOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Embeddings != models.SeedEmbeddingsNone && s.store == nil {
+		return nil, fmt.Errorf("%w: embeddings cannot be seeded without an embedding store", ErrInvalidSeedOptions)
+	}
+	if opts.Embeddings == models.SeedEmbeddingsService && s.embeddings == nil {
+		return nil, fmt.Errorf("%w: no embedding service is configured", ErrInvalidSeedOptions)
+	}
+
+	corpus := generateSeedCorpus(opts)
+	for i := 0; i < len(corpus.records); i += seedBatchSize {
+		end := min(i+seedBatchSize, len(corpus.records))
+		if err := s.chunks.BatchCreateChunks(ctx, corpus.records[i:end]); err != nil {
+			return nil, fmt.Errorf("failed to create seed chunks: %w", err)
+		}
+	}
+	for _, record := range corpus.records {
+		if tagIDs, ok := corpus.tags[record.ChunkID]; ok {
+			if err := s.chunks.AddTags(ctx, record.ChunkID, tagIDs); err != nil {
+				return nil, fmt.Errorf("failed to tag seed chunk %s: %w", record.ChunkID, err)
+			}
+		}
+	}
+
+	switch opts.Embeddings {
+	case models.SeedEmbeddingsSynthetic:
+		embedder := newSyntheticEmbedder(opts.Seed, opts.Dimensions)
+		model := fmt.Sprintf("synthetic-%d", opts.Dimensions)
+		for i := range corpus.records {
+			record := &corpus.records[i]
+			if !embeddable(record) {
+				continue
+			}
+			if err := s.store.Store(ctx, record.ChunkID, record.Contents, model, embedder.vector(corpus.keys[i])); err != nil {
+				return nil, err
+			}
+			corpus.result.Embedded++
+		}
+	case models.SeedEmbeddingsService:
+		var batch []*models.UnifiedChunkRecord
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			texts := make([]string, len(batch))
+			for i, record := range batch {
+				texts[i] = record.Contents
+			}
+			vectors, err := s.embeddings.GenerateBatchEmbeddings(ctx, texts)
+			if err != nil {
+				return fmt.Errorf("failed to embed seed chunks: %w", err)
+			}
+			for i, record := range batch {
+				if err := s.store.Store(ctx, record.ChunkID, record.Contents, s.model, vectors[i]); err != nil {
+					return err
+				}
+				corpus.result.Embedded++
+			}
+			batch = batch[:0]
+			return nil
+		}
+		for i := range corpus.records {
+			if !embeddable(&corpus.records[i]) {
+				continue
+			}
+			batch = append(batch, &corpus.records[i])
+			if len(batch) == seedBatchSize {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	corpus.result.Duration = time.Since(start)
+	return &corpus.result, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seedOptions(t *testing.T, modify func(*models.SeedCorpusOptions)) models.SeedCorpusOptions {
+	opts := DefaultSeedCorpusOptions()
+	opts.Pages = 40
+	opts.BlocksPerPage = 10
+	if modify != nil {
+		modify(&opts)
+	}
+	opts, err := normalizeSeedOptions(opts)
+	require.NoError(t, err)
+	return opts
+}
+
+func TestGenerateSeedCorpus_Hierarchy(t *testing.T) {
+	opts := seedOptions(t, nil)
+	corpus := generateSeedCorpus(opts)
+
+	depths := make(map[string]int)
+	pages := make(map[string]bool)
+	for _, record := range corpus.records {
+		if record.Parent == nil {
+			depths[record.ChunkID] = 0
+			pages[record.ChunkID] = record.IsPage
+			continue
+		}
+		parentDepth, ok := depths[*record.Parent]
+		require.True(t, ok, "parent of %q is created after it", record.Contents)
+		depths[record.ChunkID] = parentDepth + 1
+		assert.LessOrEqual(t, depths[record.ChunkID], opts.MaxDepth)
+
+		if record.IsSlot {
+			assert.Nil(t, record.Page)
+			continue
+		}
+		require.NotNil(t, record.Page)
+		assert.True(t, pages[*record.Page])
+	}
+
+	result := corpus.result
+	assert.Equal(t, opts.Pages, result.Pages)
+	assert.Equal(t, opts.Tags, result.Tags)
+	assert.Equal(t, opts.Templates, result.Templates)
+	assert.Equal(t, opts.Pages/seedTemplateEvery, result.TemplateInstances)
+	assert.Len(t, corpus.records, result.Pages+result.Blocks+result.Tags+result.Templates)
+	assert.Greater(t, result.MaxDepth, 1)
+	assert.Greater(t, result.Tagged, result.Pages/2)
+	assert.Greater(t, result.Refs, 0)
+	assert.Greater(t, len(result.Languages), 1)
+}
+
+func TestGenerateSeedCorpus_RefsPointBackwards(t *testing.T) {
+	corpus := generateSeedCorpus(seedOptions(t, func(opts *models.SeedCorpusOptions) { opts.RefRate = 0.5 }))
+
+	seen := make(map[string]bool)
+	refs := 0
+	for _, record := range corpus.records {
+		if record.Ref != nil {
+			assert.True(t, seen[*record.Ref], "%q references a later chunk", record.Contents)
+			assert.Regexp(t, `\[\[.+\]\]|\(\(`+*record.Ref+`\)\)`, record.Contents)
+			refs++
+		}
+		seen[record.ChunkID] = true
+	}
+	assert.Equal(t, corpus.result.Refs, refs)
+}
+
+func TestGenerateSeedCorpus_Deterministic(t *testing.T) {
+	opts := seedOptions(t, nil)
+	first, second := generateSeedCorpus(opts), generateSeedCorpus(opts)
+	require.Equal(t, len(first.records), len(second.records))
+	for i := range first.records {
+		assert.Equal(t, first.records[i].ChunkID, second.records[i].ChunkID)
+		assert.Equal(t, first.records[i].Contents, second.records[i].Contents)
+	}
+
+	opts.Seed++
+	assert.NotEqual(t, first.records[0].ChunkID, generateSeedCorpus(opts).records[0].ChunkID)
+}
+
+func TestGenerateSeedCorpus_TemplateInstances(t *testing.T) {
+	corpus := generateSeedCorpus(seedOptions(t, func(opts *models.SeedCorpusOptions) {
+		opts.Languages = []string{"zh"}
+		opts.Templates = 1
+	}))
+
+	var templateID string
+	slots := 0
+	for _, record := range corpus.records {
+		if record.IsTemplate {
+			templateID = record.ChunkID
+			assert.Equal(t, "讀書摘要", record.Contents)
+		}
+		if record.IsSlot {
+			slots++
+		}
+	}
+	require.NotEmpty(t, templateID)
+	assert.Equal(t, 3, slots)
+
+	instances := 0
+	for _, record := range corpus.records {
+		if record.IsPage && record.Metadata["template_instance_of"] == templateID {
+			instances++
+		}
+		if _, ok := record.Metadata["slot"]; ok {
+			assert.Contains(t, []string{"書名", "作者", "重點"}, record.Metadata["slot"])
+		}
+	}
+	assert.Equal(t, corpus.result.TemplateInstances, instances)
+	assert.Equal(t, map[string]int{"zh": 40}, corpus.result.Languages)
+}
+
+func TestNormalizeSeedOptions(t *testing.T) {
+	opts, err := normalizeSeedOptions(models.SeedCorpusOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultSeedPages, opts.Pages)
+	assert.Equal(t, SeedLanguages(), opts.Languages)
+	assert.Equal(t, models.SeedEmbeddingsNone, opts.Embeddings)
+	assert.Zero(t, opts.Tags)
+
+	for _, invalid := range []models.SeedCorpusOptions{
+		{Pages: -1},
+		{RefRate: 1.5},
+		{Languages: []string{"fr"}},
+		{Embeddings: "random"},
+	} {
+		_, err := normalizeSeedOptions(invalid)
+		assert.True(t, errors.Is(err, ErrInvalidSeedOptions), "%+v", invalid)
+	}
+}
+
+func TestSyntheticEmbedder_Clusters(t *testing.T) {
+	embedder := newSyntheticEmbedder(1, 64)
+	samePage := vectorCosineSimilarity(embedder.vector(seedVectorKey{topic: 0, page: 1}), embedder.vector(seedVectorKey{topic: 0, page: 1}))
+	sameTopic := vectorCosineSimilarity(embedder.vector(seedVectorKey{topic: 0, page: 1}), embedder.vector(seedVectorKey{topic: 0, page: 2}))
+	otherTopic := vectorCosineSimilarity(embedder.vector(seedVectorKey{topic: 0, page: 1}), embedder.vector(seedVectorKey{topic: 1, page: 3}))
+
+	assert.Greater(t, samePage, sameTopic)
+	assert.Greater(t, sameTopic, otherTopic)
+}
+
+// seedChunks records batches and tags written by a seeder
+type seedChunks struct {
+	UnifiedChunkService
+	batches [][]models.UnifiedChunkRecord
+	tags    map[string][]string
+}
+
+func (c *seedChunks) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	c.batches = append(c.batches, chunks)
+	return nil
+}
+
+func (c *seedChunks) AddTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
+	c.tags[chunkID] = tagChunkIDs
+	return nil
+}
+
+func TestCorpusSeeder_Seed(t *testing.T) {
+	chunks := &seedChunks{tags: make(map[string][]string)}
+	seeder := NewCorpusSeeder(chunks, nil, nil, "")
+
+	opts := DefaultSeedCorpusOptions()
+	result, err := seeder.Seed(context.Background(), opts)
+	require.NoError(t, err)
+
+	created := 0
+	for _, batch := range chunks.batches {
+		assert.LessOrEqual(t, len(batch), seedBatchSize)
+		created += len(batch)
+	}
+	assert.Greater(t, len(chunks.batches), 1)
+	assert.Equal(t, result.Pages+result.Blocks+result.Tags+result.Templates, created)
+	assert.Len(t, chunks.tags, result.Tagged)
+	assert.Zero(t, result.Embedded)
+
+	opts.Embeddings = models.SeedEmbeddingsSynthetic
+	_, err = seeder.Seed(context.Background(), opts)
+	assert.True(t, errors.Is(err, ErrInvalidSeedOptions))
+}
@@ -16,6 +16,7 @@ type SlideImageRecommendationService struct {
 	nlpService       NLPService
 	cacheService     CacheService
 	config           *SlideRecommendationConfig
+	chunkService     UnifiedChunkService // 投影片大綱使用，未設定時無法產生大綱
 }
 
 // NewSlideImageRecommendationService 建立新的 Slide 圖片推薦服務
@@ -0,0 +1,536 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// SlideOutlineRequest 投影片大綱請求：以主題搜尋或以頁面 chunk 為來源
+type SlideOutlineRequest struct {
+	Topic           string `json:"topic,omitempty"`
+	PageChunkID     string `json:"page_chunk_id,omitempty"`
+	TemplateChunkID string `json:"template_chunk_id,omitempty"` // 投影片模板，未指定時使用標題加要點的預設版型
+	MaxSlides       int    `json:"max_slides,omitempty"`
+	PointsPerSlide  int    `json:"points_per_slide,omitempty"` // 模板 metadata 的 max_points 優先
+	MaxChunks       int    `json:"max_chunks,omitempty"`       // 主題搜尋最多取用的 chunk 數
+	IncludeImages   bool   `json:"include_images,omitempty"`
+}
+
+// SlideOutline 投影片大綱
+type SlideOutline struct {
+	Title       string         `json:"title"`
+	Source      string         `json:"source"` // "topic" 或 "page"
+	Template    string         `json:"template"`
+	Slots       []string       `json:"slots"`
+	Slides      []OutlineSlide `json:"slides"`
+	TotalChunks int            `json:"total_chunks"`
+	Truncated   bool           `json:"truncated"`
+	GeneratedIn time.Duration  `json:"generated_in"`
+}
+
+// OutlineSlide 大綱中的單張投影片
+type OutlineSlide struct {
+	Index  int                       `json:"index"`
+	Title  string                    `json:"title"`
+	Points []SlidePoint              `json:"points"`
+	Fields map[string]string         `json:"fields"` // 模板插槽名稱對應的內容
+	Score  float64                   `json:"score"`
+	Image  *SlideImageRecommendation `json:"image,omitempty"`
+}
+
+// SlidePoint 投影片要點
+type SlidePoint struct {
+	ChunkID string  `json:"chunk_id"`
+	Text    string  `json:"text"`
+	Score   float64 `json:"score"`
+}
+
+// slideSection 分頁前的段落：同一標題下依序排列的要點
+type slideSection struct {
+	title  string
+	points []SlidePoint
+	order  int
+}
+
+const (
+	defaultOutlineMaxSlides      = 10
+	defaultOutlinePointsPerSlide = 5
+	defaultOutlineMaxChunks      = 40
+)
+
+// SetChunkService 設定大綱使用的 chunk 服務
+func (s *SlideImageRecommendationService) SetChunkService(chunkService UnifiedChunkService) {
+	s.chunkService = chunkService
+}
+
+// GenerateSlideOutline 依主題或頁面挑選相關 chunk，按模板分組成投影片並回傳有序大綱
+func (s *SlideImageRecommendationService) GenerateSlideOutline(ctx context.Context, req *SlideOutlineRequest) (*SlideOutline, error) {
+	startTime := time.Now()
+
+	if s.chunkService == nil {
+		return nil, fmt.Errorf("chunk service is not configured")
+	}
+	if req == nil || (strings.TrimSpace(req.Topic) == "" && req.PageChunkID == "") {
+		return nil, fmt.Errorf("either topic or page_chunk_id is required")
+	}
+
+	maxSlides := req.MaxSlides
+	if maxSlides <= 0 {
+		maxSlides = defaultOutlineMaxSlides
+	}
+
+	layout, err := s.loadSlideLayout(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	outline := &SlideOutline{
+		Template: layout.name,
+		Slots:    layout.slots,
+	}
+
+	var sections []slideSection
+	if req.PageChunkID != "" {
+		outline.Source = "page"
+		outline.Title, sections, outline.TotalChunks, err = s.pageSections(ctx, req.PageChunkID, req.Topic)
+	} else {
+		outline.Source = "topic"
+		outline.Title = strings.TrimSpace(req.Topic)
+		sections, outline.TotalChunks, err = s.topicSections(ctx, outline.Title, req.MaxChunks)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slides := paginateSections(sections, layout.pointsPerSlide)
+	if len(slides) > maxSlides {
+		slides = selectTopSlides(slides, maxSlides)
+		outline.Truncated = true
+	}
+
+	for i := range slides {
+		slides[i].Index = i + 1
+		slides[i].Fields = layout.fill(&slides[i])
+		if req.IncludeImages && s.multimodalSearch != nil {
+			slides[i].Image = s.recommendSlideImage(ctx, &slides[i])
+			if slides[i].Image != nil {
+				if slot := layout.slotFor(slotRoleImage); slot != "" {
+					slides[i].Fields[slot] = slides[i].Image.ImageURL
+				}
+			}
+		}
+	}
+
+	outline.Slides = slides
+	outline.GeneratedIn = time.Since(startTime)
+	return outline, nil
+}
+
+// pageSections 以頁面的第一層區塊為段落標題，其下內容依文件順序成為要點
+func (s *SlideImageRecommendationService) pageSections(ctx context.Context, pageID, topic string) (string, []slideSection, int, error) {
+	page, err := s.chunkService.GetChunk(ctx, pageID)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("failed to get page: %w", err)
+	}
+	descendants, err := s.chunkService.GetDescendants(ctx, pageID, 0)
+	if err != nil {
+		return "", nil, 0, fmt.Errorf("failed to get page content: %w", err)
+	}
+
+	children := make(map[string][]*models.UnifiedChunkRecord)
+	for i := range descendants {
+		chunk := &descendants[i]
+		if chunk.Parent != nil && !chunk.IsSlot && !chunk.IsTemplate {
+			children[*chunk.Parent] = append(children[*chunk.Parent], chunk)
+		}
+	}
+
+	pageTitle := outlineChunkTitle(page)
+	keywords := outlineKeywords(topic)
+
+	var sections []slideSection
+	var overview []SlidePoint
+	for _, top := range children[pageID] {
+		if len(children[top.ChunkID]) == 0 {
+			// 沒有子區塊的第一層區塊彙整為概覽投影片
+			overview = append(overview, SlidePoint{ChunkID: top.ChunkID, Text: outlineChunkTitle(top), Score: pointScore(top, 0, keywords)})
+			continue
+		}
+
+		section := slideSection{title: outlineChunkTitle(top), order: len(sections)}
+		var walk func(parentID string, depth int)
+		walk = func(parentID string, depth int) {
+			for _, chunk := range children[parentID] {
+				section.points = append(section.points, SlidePoint{
+					ChunkID: chunk.ChunkID,
+					Text:    outlineChunkTitle(chunk),
+					Score:   pointScore(chunk, depth, keywords),
+				})
+				walk(chunk.ChunkID, depth+1)
+			}
+		}
+		walk(top.ChunkID, 0)
+		sections = append(sections, section)
+	}
+
+	if len(overview) > 0 {
+		sections = append([]slideSection{{title: pageTitle, points: overview}}, sections...)
+	}
+	if len(keywords) > 0 {
+		sections = filterRelevantPoints(sections)
+	}
+	return pageTitle, sections, len(descendants), nil
+}
+
+// topicSections 搜尋主題相關 chunk，依所屬頁面分段並以相關度排序
+func (s *SlideImageRecommendationService) topicSections(ctx context.Context, topic string, maxChunks int) ([]slideSection, int, error) {
+	if maxChunks <= 0 {
+		maxChunks = defaultOutlineMaxChunks
+	}
+	keywords := outlineKeywords(topic)
+
+	// 完整主題先搜尋，結果不足時再以個別關鍵字補充
+	queries := append([]string{topic}, keywords...)
+	candidates := make(map[string]*models.UnifiedChunkRecord)
+	var order []string
+	for _, query := range queries {
+		if len(candidates) >= maxChunks*2 {
+			break
+		}
+		isFalse := false
+		result, err := s.chunkService.SearchChunks(ctx, &models.SearchQuery{
+			Content:    query,
+			IsTemplate: &isFalse,
+			IsSlot:     &isFalse,
+			Limit:      maxChunks * 2,
+		})
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to search chunks: %w", err)
+		}
+		for i := range result.Chunks {
+			chunk := &result.Chunks[i]
+			if _, seen := candidates[chunk.ChunkID]; !seen && !chunk.IsTag {
+				candidates[chunk.ChunkID] = chunk
+				order = append(order, chunk.ChunkID)
+			}
+		}
+	}
+
+	ranked := make([]SlidePoint, 0, len(order))
+	chunkPage := make(map[string]string)
+	for _, chunkID := range order {
+		chunk := candidates[chunkID]
+		score := keywordCoverage(chunk.Contents, chunk.Tags, keywords)
+		if score == 0 {
+			continue
+		}
+		ranked = append(ranked, SlidePoint{ChunkID: chunk.ChunkID, Text: outlineChunkTitle(chunk), Score: score})
+		switch {
+		case chunk.IsPage:
+			chunkPage[chunk.ChunkID] = chunk.ChunkID
+		case chunk.Page != nil:
+			chunkPage[chunk.ChunkID] = *chunk.Page
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].Score > ranked[j].Score })
+	if len(ranked) > maxChunks {
+		ranked = ranked[:maxChunks]
+	}
+
+	// 依所屬頁面分段，段落順序取決於最相關的要點
+	sectionIndex := make(map[string]int)
+	var sections []slideSection
+	for _, point := range ranked {
+		pageID := chunkPage[point.ChunkID]
+		idx, ok := sectionIndex[pageID]
+		if !ok {
+			idx = len(sections)
+			sectionIndex[pageID] = idx
+			sections = append(sections, slideSection{title: s.sectionTitle(ctx, pageID, topic, candidates), order: idx})
+		}
+		if pageID == point.ChunkID {
+			// 頁面本身作為段落標題，不重複列為要點
+			continue
+		}
+		sections[idx].points = append(sections[idx].points, point)
+	}
+
+	nonEmpty := sections[:0]
+	for _, section := range sections {
+		if len(section.points) > 0 {
+			nonEmpty = append(nonEmpty, section)
+		}
+	}
+	return nonEmpty, len(candidates), nil
+}
+
+// sectionTitle 取頁面標題作為段落標題，無所屬頁面時使用主題
+func (s *SlideImageRecommendationService) sectionTitle(ctx context.Context, pageID, topic string, known map[string]*models.UnifiedChunkRecord) string {
+	if pageID == "" {
+		return topic
+	}
+	if page, ok := known[pageID]; ok {
+		return outlineChunkTitle(page)
+	}
+	if page, err := s.chunkService.GetChunk(ctx, pageID); err == nil {
+		return outlineChunkTitle(page)
+	}
+	return topic
+}
+
+// recommendSlideImage 為投影片挑選最相關的一張圖片，失敗時略過
+func (s *SlideImageRecommendationService) recommendSlideImage(ctx context.Context, slide *OutlineSlide) *SlideImageRecommendation {
+	texts := make([]string, len(slide.Points))
+	for i, point := range slide.Points {
+		texts[i] = point.Text
+	}
+	response, err := s.RecommendImagesForSlide(ctx, &SlideImageRecommendationRequest{
+		SlideTitle:     slide.Title,
+		SlideContent:   strings.Join(texts, "\n"),
+		MaxSuggestions: 1,
+		MinRelevance:   s.config.MinRelevanceScore,
+	})
+	if err != nil || len(response.Recommendations) == 0 {
+		return nil
+	}
+	return &response.Recommendations[0]
+}
+
+// paginateSections 將段落切成每張最多 pointsPerSlide 個要點的投影片
+func paginateSections(sections []slideSection, pointsPerSlide int) []OutlineSlide {
+	var slides []OutlineSlide
+	for _, section := range sections {
+		pages := (len(section.points) + pointsPerSlide - 1) / pointsPerSlide
+		for page := 0; page < pages; page++ {
+			points := section.points[page*pointsPerSlide : min((page+1)*pointsPerSlide, len(section.points))]
+			title := section.title
+			if pages > 1 {
+				title = fmt.Sprintf("%s (%d/%d)", section.title, page+1, pages)
+			}
+
+			var total float64
+			for _, point := range points {
+				total += point.Score
+			}
+			slides = append(slides, OutlineSlide{
+				Title:  title,
+				Points: append([]SlidePoint(nil), points...),
+				Score:  total / float64(len(points)),
+			})
+		}
+	}
+	return slides
+}
+
+// selectTopSlides 保留分數最高的投影片並維持原本順序
+func selectTopSlides(slides []OutlineSlide, limit int) []OutlineSlide {
+	indexes := make([]int, len(slides))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool { return slides[indexes[a]].Score > slides[indexes[b]].Score })
+	indexes = indexes[:limit]
+	sort.Ints(indexes)
+
+	selected := make([]OutlineSlide, len(indexes))
+	for i, idx := range indexes {
+		selected[i] = slides[idx]
+	}
+	return selected
+}
+
+// filterRelevantPoints 指定主題時移除與主題無關的要點與空段落
+func filterRelevantPoints(sections []slideSection) []slideSection {
+	var filtered []slideSection
+	for _, section := range sections {
+		var points []SlidePoint
+		for _, point := range section.points {
+			if point.Score > 0 {
+				points = append(points, point)
+			}
+		}
+		if len(points) > 0 {
+			section.points = points
+			filtered = append(filtered, section)
+		}
+	}
+	return filtered
+}
+
+// pointScore 頁面要點的重要度：層級越淺越重要，指定主題時以關鍵字覆蓋率為準
+func pointScore(chunk *models.UnifiedChunkRecord, depth int, keywords []string) float64 {
+	if len(keywords) > 0 {
+		return keywordCoverage(chunk.Contents, chunk.Tags, keywords)
+	}
+	return 1 / float64(depth+1)
+}
+
+// keywordCoverage 計算內容與標籤涵蓋的關鍵字比例
+func keywordCoverage(contents string, tags []string, keywords []string) float64 {
+	if len(keywords) == 0 {
+		return 0
+	}
+	text := strings.ToLower(contents + " " + strings.Join(tags, " "))
+	var matched int
+	for _, keyword := range keywords {
+		if strings.Contains(text, keyword) {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(keywords))
+}
+
+// outlineKeywords 將主題拆成小寫關鍵字，略過停用詞
+func outlineKeywords(topic string) []string {
+	stopWords := map[string]bool{
+		"the": true, "and": true, "for": true, "with": true, "of": true, "to": true, "in": true, "a": true, "an": true,
+		"的": true, "和": true, "與": true,
+	}
+	seen := make(map[string]bool)
+	var keywords []string
+	for _, word := range strings.Fields(strings.ToLower(topic)) {
+		word = strings.Trim(word, ".,;:!?\"'()[]")
+		if word == "" || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		keywords = append(keywords, word)
+	}
+	return keywords
+}
+
+// outlineChunkTitle 取區塊內容第一行
+func outlineChunkTitle(chunk *models.UnifiedChunkRecord) string {
+	title := strings.TrimSpace(chunk.Contents)
+	if i := strings.IndexByte(title, '\n'); i >= 0 {
+		title = strings.TrimSpace(title[:i])
+	}
+	if title == "" {
+		return chunk.ChunkID
+	}
+	return title
+}
+
+// ============================================================================
+// SLIDE TEMPLATES
+// ============================================================================
+
+// 投影片插槽角色
+const (
+	slotRoleTitle  = "title"
+	slotRolePoints = "points"
+	slotRoleNotes  = "notes"
+	slotRoleImage  = "image"
+)
+
+// slotRoleNames 插槽名稱對應的角色
+var slotRoleNames = map[string]string{
+	"title": slotRoleTitle, "heading": slotRoleTitle, "標題": slotRoleTitle,
+	"points": slotRolePoints, "bullets": slotRolePoints, "body": slotRolePoints, "content": slotRolePoints,
+	"內容": slotRolePoints, "要點": slotRolePoints,
+	"notes": slotRoleNotes, "sources": slotRoleNotes, "備註": slotRoleNotes, "來源": slotRoleNotes,
+	"image": slotRoleImage, "picture": slotRoleImage, "圖片": slotRoleImage,
+}
+
+// slideLayout 投影片版型：插槽與每張投影片的要點容量
+type slideLayout struct {
+	name           string
+	slots          []string
+	roles          map[string]string // 插槽名稱 -> 角色
+	pointsPerSlide int
+}
+
+// loadSlideLayout 讀取投影片模板，未指定時使用預設版型
+func (s *SlideImageRecommendationService) loadSlideLayout(ctx context.Context, req *SlideOutlineRequest) (*slideLayout, error) {
+	layout := &slideLayout{
+		name:           "default",
+		slots:          []string{slotRoleTitle, slotRolePoints},
+		roles:          map[string]string{slotRoleTitle: slotRoleTitle, slotRolePoints: slotRolePoints},
+		pointsPerSlide: req.PointsPerSlide,
+	}
+	if layout.pointsPerSlide <= 0 {
+		layout.pointsPerSlide = defaultOutlinePointsPerSlide
+	}
+	if req.TemplateChunkID == "" {
+		return layout, nil
+	}
+
+	template, err := s.chunkService.GetChunk(ctx, req.TemplateChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get slide template: %w", err)
+	}
+	if !template.IsTemplate {
+		return nil, fmt.Errorf("chunk %s is not a template", req.TemplateChunkID)
+	}
+	children, err := s.chunkService.GetChildren(ctx, template.ChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template slots: %w", err)
+	}
+
+	layout.name = outlineChunkTitle(template)
+	layout.slots = nil
+	layout.roles = make(map[string]string)
+	for i := range children {
+		if !children[i].IsSlot {
+			continue
+		}
+		name := outlineSlotName(&children[i])
+		layout.slots = append(layout.slots, name)
+		if role, ok := slotRoleNames[strings.ToLower(name)]; ok {
+			layout.roles[name] = role
+		}
+	}
+	if maxPoints, ok := template.Metadata["max_points"].(float64); ok && maxPoints > 0 {
+		layout.pointsPerSlide = int(maxPoints)
+	}
+	return layout, nil
+}
+
+// slotFor 回傳擔任指定角色的第一個插槽
+func (l *slideLayout) slotFor(role string) string {
+	for _, slot := range l.slots {
+		if l.roles[slot] == role {
+			return slot
+		}
+	}
+	return ""
+}
+
+// fill 依插槽角色填入投影片內容，無法對應的插槽留空
+func (l *slideLayout) fill(slide *OutlineSlide) map[string]string {
+	fields := make(map[string]string, len(l.slots))
+	for _, slot := range l.slots {
+		switch l.roles[slot] {
+		case slotRoleTitle:
+			fields[slot] = slide.Title
+		case slotRolePoints:
+			lines := make([]string, len(slide.Points))
+			for i, point := range slide.Points {
+				lines[i] = "- " + point.Text
+			}
+			fields[slot] = strings.Join(lines, "\n")
+		case slotRoleNotes:
+			ids := make([]string, len(slide.Points))
+			for i, point := range slide.Points {
+				ids[i] = point.ChunkID
+			}
+			fields[slot] = "Sources: " + strings.Join(ids, ", ")
+		default:
+			fields[slot] = ""
+		}
+	}
+	return fields
+}
+
+// outlineSlotName 取插槽名稱，未設定時使用區塊內容
+func outlineSlotName(chunk *models.UnifiedChunkRecord) string {
+	if name, ok := chunk.Metadata["slot_name"].(string); ok && name != "" {
+		return name
+	}
+	return outlineChunkTitle(chunk)
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func outlineChunk(id, parent, contents string) models.UnifiedChunkRecord {
+	page := "page"
+	chunk := models.UnifiedChunkRecord{ChunkID: id, Contents: contents, Page: &page}
+	if parent != "" {
+		chunk.Parent = &parent
+	}
+	return chunk
+}
+
+func TestSlideOutline_FromPage(t *testing.T) {
+	ctx := context.Background()
+	chunkService := new(MockUnifiedChunkService)
+
+	page := outlineChunk("page", "", "Go Concurrency")
+	page.IsPage = true
+	chunkService.On("GetChunk", ctx, "page").Return(&page, nil)
+
+	// 依層級排列的子孫區塊：兩個段落與一個概覽區塊
+	descendants := []models.UnifiedChunkRecord{
+		outlineChunk("intro", "page", "Why concurrency matters"),
+		outlineChunk("goroutines", "page", "Goroutines"),
+		outlineChunk("channels", "page", "Channels"),
+	}
+	for i := 1; i <= 3; i++ {
+		descendants = append(descendants, outlineChunk(fmt.Sprintf("g%d", i), "goroutines", fmt.Sprintf("Goroutine point %d", i)))
+	}
+	descendants = append(descendants,
+		outlineChunk("c1", "channels", "Buffered channels"),
+		outlineChunk("c1a", "c1", "Capacity blocks senders"),
+		outlineChunk("c2", "channels", "Select statement"),
+	)
+	chunkService.On("GetDescendants", ctx, "page", 0).Return(descendants, nil)
+
+	service := NewSlideImageRecommendationService(nil, nil, nil, nil)
+	service.SetChunkService(chunkService)
+
+	outline, err := service.GenerateSlideOutline(ctx, &SlideOutlineRequest{PageChunkID: "page", PointsPerSlide: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "Go Concurrency", outline.Title)
+	assert.Equal(t, "page", outline.Source)
+	assert.Equal(t, "default", outline.Template)
+
+	titles := make([]string, len(outline.Slides))
+	for i, slide := range outline.Slides {
+		titles[i] = slide.Title
+		assert.Equal(t, i+1, slide.Index)
+	}
+	assert.Equal(t, []string{"Go Concurrency", "Goroutines (1/2)", "Goroutines (2/2)", "Channels (1/2)", "Channels (2/2)"}, titles)
+
+	// 子區塊依文件順序緊接在父區塊之後
+	assert.Equal(t, "Buffered channels", outline.Slides[3].Points[0].Text)
+	assert.Equal(t, "Capacity blocks senders", outline.Slides[3].Points[1].Text)
+	assert.Equal(t, "- Goroutine point 1\n- Goroutine point 2", outline.Slides[1].Fields["points"])
+
+	// 超過上限時保留分數最高的投影片並維持順序
+	outline, err = service.GenerateSlideOutline(ctx, &SlideOutlineRequest{PageChunkID: "page", PointsPerSlide: 2, MaxSlides: 2})
+	require.NoError(t, err)
+	assert.True(t, outline.Truncated)
+	require.Len(t, outline.Slides, 2)
+	assert.Equal(t, "Go Concurrency", outline.Slides[0].Title)
+}
+
+func TestSlideOutline_FromTopicWithTemplate(t *testing.T) {
+	ctx := context.Background()
+	chunkService := new(MockUnifiedChunkService)
+
+	template := models.UnifiedChunkRecord{ChunkID: "tpl", Contents: "Bullet Slide", IsTemplate: true,
+		Metadata: map[string]interface{}{"max_points": float64(3)}}
+	chunkService.On("GetChunk", ctx, "tpl").Return(&template, nil)
+	chunkService.On("GetChildren", ctx, "tpl").Return([]models.UnifiedChunkRecord{
+		{ChunkID: "s1", Contents: "標題", IsSlot: true},
+		{ChunkID: "s2", Contents: "body", IsSlot: true, Metadata: map[string]interface{}{"slot_name": "要點"}},
+		{ChunkID: "s3", Contents: "notes", IsSlot: true},
+		{ChunkID: "s4", Contents: "footer", IsSlot: true},
+	}, nil)
+
+	retries := models.UnifiedChunkRecord{ChunkID: "retries", Contents: "Retry Strategies", IsPage: true}
+	matches := []models.UnifiedChunkRecord{
+		outlineChunk("a", "", "Exponential backoff for retry loops"),
+		outlineChunk("b", "", "Backoff jitter"),
+		retries,
+	}
+	matches[0].Page, matches[1].Page = &retries.ChunkID, &retries.ChunkID
+	chunkService.On("SearchChunks", ctx, mock.MatchedBy(func(q *models.SearchQuery) bool { return q.Content == "retry backoff" })).
+		Return(&models.SearchResult{Chunks: matches}, nil)
+	chunkService.On("SearchChunks", ctx, mock.Anything).Return(&models.SearchResult{Chunks: []models.UnifiedChunkRecord{
+		outlineChunk("unrelated", "", "Nothing to see"),
+	}}, nil)
+
+	service := NewSlideImageRecommendationService(nil, nil, nil, nil)
+	service.SetChunkService(chunkService)
+
+	outline, err := service.GenerateSlideOutline(ctx, &SlideOutlineRequest{Topic: "retry backoff", TemplateChunkID: "tpl"})
+	require.NoError(t, err)
+	assert.Equal(t, "Bullet Slide", outline.Template)
+	assert.Equal(t, []string{"標題", "要點", "notes", "footer"}, outline.Slots)
+	require.Len(t, outline.Slides, 1)
+
+	slide := outline.Slides[0]
+	assert.Equal(t, "Retry Strategies", slide.Title)
+	require.Len(t, slide.Points, 2)
+	assert.Equal(t, "a", slide.Points[0].ChunkID, "chunks matching more keywords rank first")
+	assert.Equal(t, "Retry Strategies", slide.Fields["標題"])
+	assert.Equal(t, "Sources: a, b", slide.Fields["notes"])
+	assert.Equal(t, "", slide.Fields["footer"])
+
+	// 非模板區塊不能作為投影片模板
+	plain := outlineChunk("plain", "", "Just a note")
+	chunkService.On("GetChunk", ctx, "plain").Return(&plain, nil)
+	_, err = service.GenerateSlideOutline(ctx, &SlideOutlineRequest{Topic: "retry", TemplateChunkID: "plain"})
+	assert.Error(t, err)
+
+	_, err = service.GenerateSlideOutline(ctx, &SlideOutlineRequest{})
+	assert.Error(t, err)
+}
@@ -0,0 +1,737 @@
+package services
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/lib/pq"
+)
+
+// SnapshotService backs up the knowledge base to versioned JSONL archives and restores them
+type SnapshotService interface {
+	// Export writes a gzipped tar archive of chunks, tags, hierarchy, templates,
+	// embeddings and the reference graph. With opts.Since set, only chunks updated
+	// since then and their relations are exported; deletions are not captured.
+	Export(ctx context.Context, w io.Writer, opts models.SnapshotExportOptions) (*models.SnapshotManifest, error)
+
+	// Import restores an archive with its original chunk IDs. Full snapshots require
+	// an empty database; incremental snapshots are upserted over existing data.
+	Import(ctx context.Context, r io.Reader) (*models.SnapshotImportResult, error)
+}
+
+// snapshotService implements SnapshotService on PostgreSQL
+type snapshotService struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+}
+
+// NewSnapshotService creates a snapshot service
+func NewSnapshotService(db *sql.DB, monitor QueryPerformanceMonitor) SnapshotService {
+	return &snapshotService{db: db, monitor: monitor}
+}
+
+// snapshotQuerier is satisfied by *sql.DB and *sql.Tx
+type snapshotQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Export writes a snapshot archive from a consistent read-only transaction
+func (s *snapshotService) Export(ctx context.Context, w io.Writer, opts models.SnapshotExportOptions) (*models.SnapshotManifest, error) {
+	start := time.Now()
+	manifest := &models.SnapshotManifest{
+		FormatVersion: models.SnapshotFormatVersion,
+		CreatedAt:     start.UTC(),
+		Since:         opts.Since,
+		Counts:        make(map[string]int),
+	}
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var spools []*snapshotSpool
+	defer func() {
+		for _, spool := range spools {
+			spool.Close()
+		}
+	}()
+
+	exports := []struct {
+		name   string
+		export func(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error
+	}{
+		{models.SnapshotChunksFile, exportSnapshotChunks},
+		{models.SnapshotEmbeddingsFile, exportSnapshotEmbeddings},
+		{models.SnapshotChunkTagsFile, exportSnapshotChunkTags},
+		{models.SnapshotChunkHierarchyFile, exportSnapshotChunkHierarchy},
+		{models.SnapshotTagHierarchyFile, exportSnapshotTagHierarchy},
+		{models.SnapshotChunkRefsFile, exportSnapshotChunkRefs},
+	}
+	for _, export := range exports {
+		spool, err := newSnapshotSpool(export.name)
+		if err != nil {
+			return nil, err
+		}
+		spools = append(spools, spool)
+
+		if err := export.export(ctx, tx, opts.Since, manifest, spool); err != nil {
+			return nil, err
+		}
+		manifest.Counts[export.name] = spool.count
+	}
+
+	if err := writeSnapshotArchive(w, manifest, spools); err != nil {
+		return nil, err
+	}
+
+	s.monitor.RecordQuery("snapshot_export", time.Since(start), manifest.Counts[models.SnapshotChunksFile])
+	return manifest, nil
+}
+
+// changedChunksFilter restricts a relation export to chunks changed since the given time
+func changedChunksFilter(column string, since *time.Time) (string, []interface{}) {
+	if since == nil {
+		return "", nil
+	}
+	return fmt.Sprintf(" WHERE %s IN (SELECT chunk_id FROM chunks WHERE last_updated >= $1)", column), []interface{}{*since}
+}
+
+func exportSnapshotChunks(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error {
+	query := `SELECT ` + chunkColumnList("", chunkListColumns) + ` FROM chunks`
+	var args []interface{}
+	if since != nil {
+		query += " WHERE last_updated >= $1"
+		args = append(args, *since)
+	}
+	query += " ORDER BY created_time, chunk_id"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query chunks: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		chunk, err := scanUnifiedChunk(rows)
+		if err != nil {
+			return err
+		}
+		if chunk.IsPage {
+			manifest.Pages++
+		}
+		if chunk.IsTemplate {
+			manifest.Templates++
+		}
+		if err := spool.Write(chunk); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating chunk rows: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotEmbeddings(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error {
+	hasVectors, err := snapshotColumnExists(ctx, tx, "chunks", "vector")
+	if err != nil || !hasVectors {
+		return err
+	}
+
+	query := "SELECT chunk_id, vector::text, vector_type, vector_model, vector_metadata FROM chunks WHERE vector IS NOT NULL"
+	var args []interface{}
+	if since != nil {
+		query += " AND last_updated >= $1"
+		args = append(args, *since)
+	}
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var embedding models.SnapshotEmbedding
+		var vectorText string
+		var metadataBytes []byte
+		if err := rows.Scan(&embedding.ChunkID, &vectorText, &embedding.VectorType, &embedding.VectorModel, &metadataBytes); err != nil {
+			return fmt.Errorf("failed to scan embedding row: %w", err)
+		}
+		// pgvector's text form "[0.1,0.2]" is a JSON array
+		if err := json.Unmarshal([]byte(vectorText), &embedding.Vector); err != nil {
+			return fmt.Errorf("failed to parse vector for chunk %s: %w", embedding.ChunkID, err)
+		}
+		if len(metadataBytes) > 0 {
+			if err := json.Unmarshal(metadataBytes, &embedding.VectorMetadata); err != nil {
+				return fmt.Errorf("failed to parse vector metadata for chunk %s: %w", embedding.ChunkID, err)
+			}
+		}
+		if err := spool.Write(embedding); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating embedding rows: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChunkTags(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error {
+	filter, args := changedChunksFilter("source_chunk_id", since)
+	rows, err := tx.QueryContext(ctx, "SELECT source_chunk_id, tag_chunk_id, created_at FROM chunk_tags"+filter, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query chunk tags: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation models.ChunkTagRelation
+		if err := rows.Scan(&relation.SourceChunkID, &relation.TagChunkID, &relation.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan chunk tag row: %w", err)
+		}
+		if err := spool.Write(relation); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating chunk tag rows: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChunkHierarchy(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error {
+	filter, args := changedChunksFilter("descendant_id", since)
+	rows, err := tx.QueryContext(ctx, "SELECT ancestor_id, descendant_id, depth, path_ids FROM chunk_hierarchy"+filter, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query chunk hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation models.ChunkHierarchyRelation
+		if err := rows.Scan(&relation.AncestorID, &relation.DescendantID, &relation.Depth, pq.Array(&relation.PathIDs)); err != nil {
+			return fmt.Errorf("failed to scan chunk hierarchy row: %w", err)
+		}
+		if err := spool.Write(relation); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating chunk hierarchy rows: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotTagHierarchy(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error {
+	exists, err := snapshotTableExists(ctx, tx, "tag_hierarchy")
+	if err != nil || !exists {
+		return err
+	}
+
+	filter, args := changedChunksFilter("descendant_tag_id", since)
+	rows, err := tx.QueryContext(ctx, "SELECT ancestor_tag_id, descendant_tag_id, depth FROM tag_hierarchy"+filter, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query tag hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation models.TagHierarchyRelation
+		if err := rows.Scan(&relation.AncestorTagID, &relation.DescendantTagID, &relation.Depth); err != nil {
+			return fmt.Errorf("failed to scan tag hierarchy row: %w", err)
+		}
+		if err := spool.Write(relation); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating tag hierarchy rows: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChunkRefs(ctx context.Context, tx *sql.Tx, since *time.Time, manifest *models.SnapshotManifest, spool *snapshotSpool) error {
+	exists, err := snapshotTableExists(ctx, tx, "chunk_refs")
+	if err != nil || !exists {
+		return err
+	}
+
+	filter, args := changedChunksFilter("source_chunk_id", since)
+	rows, err := tx.QueryContext(ctx, "SELECT source_chunk_id, target_chunk_id, created_at FROM chunk_refs"+filter, args...)
+	if err != nil {
+		return fmt.Errorf("failed to query chunk references: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var relation models.ChunkRefRelation
+		if err := rows.Scan(&relation.SourceChunkID, &relation.TargetChunkID, &relation.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan chunk reference row: %w", err)
+		}
+		if err := spool.Write(relation); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating chunk reference rows: %w", err)
+	}
+	return nil
+}
+
+// Import restores a snapshot archive in a single transaction
+func (s *snapshotService) Import(ctx context.Context, r io.Reader) (*models.SnapshotImportResult, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+
+	archive, err := readSnapshotArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !archive.manifest.Incremental() {
+		var existing int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM chunks").Scan(&existing); err != nil {
+			return nil, fmt.Errorf("failed to count existing chunks: %w", err)
+		}
+		if existing > 0 {
+			return nil, fmt.Errorf("full snapshots can only be restored into an empty database (found %d chunks)", existing)
+		}
+	}
+
+	result := &models.SnapshotImportResult{Manifest: archive.manifest, Restored: make(map[string]int)}
+
+	if result.Restored[models.SnapshotChunksFile], err = restoreSnapshotChunks(ctx, tx, archive); err != nil {
+		return nil, err
+	}
+	if result.Restored[models.SnapshotChunkTagsFile], err = restoreSnapshotRows(ctx, tx, "chunk tags",
+		`INSERT INTO chunk_tags (source_chunk_id, tag_chunk_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (source_chunk_id, tag_chunk_id) DO UPDATE SET created_at = EXCLUDED.created_at`,
+		len(archive.chunkTags), func(i int) []interface{} {
+			relation := archive.chunkTags[i]
+			return []interface{}{relation.SourceChunkID, relation.TagChunkID, relation.CreatedAt}
+		}); err != nil {
+		return nil, err
+	}
+	if result.Restored[models.SnapshotChunkHierarchyFile], err = restoreSnapshotRows(ctx, tx, "chunk hierarchy",
+		`INSERT INTO chunk_hierarchy (ancestor_id, descendant_id, depth, path_ids) VALUES ($1, $2, $3, $4::uuid[])
+		ON CONFLICT (ancestor_id, descendant_id) DO UPDATE SET depth = EXCLUDED.depth, path_ids = EXCLUDED.path_ids`,
+		len(archive.chunkHierarchy), func(i int) []interface{} {
+			relation := archive.chunkHierarchy[i]
+			return []interface{}{relation.AncestorID, relation.DescendantID, relation.Depth, pq.Array(relation.PathIDs)}
+		}); err != nil {
+		return nil, err
+	}
+	if result.Restored[models.SnapshotTagHierarchyFile], err = restoreSnapshotRows(ctx, tx, "tag hierarchy",
+		`INSERT INTO tag_hierarchy (ancestor_tag_id, descendant_tag_id, depth) VALUES ($1, $2, $3)
+		ON CONFLICT (ancestor_tag_id, descendant_tag_id) DO UPDATE SET depth = EXCLUDED.depth`,
+		len(archive.tagHierarchy), func(i int) []interface{} {
+			relation := archive.tagHierarchy[i]
+			return []interface{}{relation.AncestorTagID, relation.DescendantTagID, relation.Depth}
+		}); err != nil {
+		return nil, err
+	}
+	if result.Restored[models.SnapshotChunkRefsFile], err = restoreSnapshotRows(ctx, tx, "chunk references",
+		`INSERT INTO chunk_refs (source_chunk_id, target_chunk_id, created_at) VALUES ($1, $2, $3)
+		ON CONFLICT (source_chunk_id, target_chunk_id) DO UPDATE SET created_at = EXCLUDED.created_at`,
+		len(archive.chunkRefs), func(i int) []interface{} {
+			relation := archive.chunkRefs[i]
+			return []interface{}{relation.SourceChunkID, relation.TargetChunkID, relation.CreatedAt}
+		}); err != nil {
+		return nil, err
+	}
+	result.Restored[models.SnapshotEmbeddingsFile] = len(archive.embeddings)
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+	s.monitor.RecordQuery("snapshot_import", result.Duration, result.Restored[models.SnapshotChunksFile])
+	return result, nil
+}
+
+// restoreSnapshotChunks upserts chunks so that parents, pages and tags are inserted
+// before the chunks that reference them. Chunks in reference cycles are inserted
+// without those references first and patched once every chunk exists.
+func restoreSnapshotChunks(ctx context.Context, tx *sql.Tx, archive *snapshotArchive) (int, error) {
+	hasVectors, err := snapshotColumnExists(ctx, tx, "chunks", "vector")
+	if err != nil {
+		return 0, err
+	}
+	if len(archive.embeddings) > 0 && !hasVectors {
+		return 0, fmt.Errorf("snapshot contains embeddings but chunks has no vector column; run the multimodal embeddings migration first")
+	}
+
+	embeddings := make(map[string]models.SnapshotEmbedding, len(archive.embeddings))
+	for _, embedding := range archive.embeddings {
+		embeddings[embedding.ChunkID] = embedding
+	}
+
+	const upsert = `
+		ON CONFLICT (chunk_id) DO UPDATE SET
+			contents = EXCLUDED.contents, parent = EXCLUDED.parent, page = EXCLUDED.page,
+			is_page = EXCLUDED.is_page, is_tag = EXCLUDED.is_tag,
+			is_template = EXCLUDED.is_template, is_slot = EXCLUDED.is_slot,
+			ref = EXCLUDED.ref, tags = EXCLUDED.tags, metadata = EXCLUDED.metadata,
+			created_time = EXCLUDED.created_time, last_updated = EXCLUDED.last_updated`
+	chunkStmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO chunks (
+			chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
+			ref, tags, metadata, created_time, last_updated
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`+upsert)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare chunk statement: %w", err)
+	}
+	defer chunkStmt.Close()
+
+	var vectorStmt *sql.Stmt
+	if hasVectors {
+		vectorStmt, err = tx.PrepareContext(ctx, `
+			INSERT INTO chunks (
+				chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
+				ref, tags, metadata, created_time, last_updated,
+				vector, vector_type, vector_model, vector_metadata
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14::vector, $15, $16, $17)`+upsert+`,
+				vector = EXCLUDED.vector, vector_type = EXCLUDED.vector_type,
+				vector_model = EXCLUDED.vector_model, vector_metadata = EXCLUDED.vector_metadata`)
+		if err != nil {
+			return 0, fmt.Errorf("failed to prepare chunk vector statement: %w", err)
+		}
+		defer vectorStmt.Close()
+	}
+
+	ordered, deferred := orderChunksForRestore(archive.chunks)
+	for _, chunk := range ordered {
+		parent, page, tags := chunk.Parent, chunk.Page, chunk.Tags
+		if deferred[chunk.ChunkID] {
+			parent, page, tags = nil, nil, nil
+		}
+		args := []interface{}{
+			chunk.ChunkID, chunk.Contents, parent, page,
+			chunk.IsPage, chunk.IsTag, chunk.IsTemplate, chunk.IsSlot,
+			chunk.Ref, pq.Array(tags), chunk.Metadata,
+			chunk.CreatedTime, chunk.LastUpdated,
+		}
+
+		stmt := chunkStmt
+		if embedding, ok := embeddings[chunk.ChunkID]; ok {
+			vector, err := json.Marshal(embedding.Vector)
+			if err != nil {
+				return 0, fmt.Errorf("failed to serialize vector for chunk %s: %w", chunk.ChunkID, err)
+			}
+			var vectorMetadata interface{}
+			if embedding.VectorMetadata != nil {
+				vectorMetadata = embedding.VectorMetadata
+			}
+			args = append(args, string(vector), embedding.VectorType, embedding.VectorModel, vectorMetadata)
+			stmt = vectorStmt
+		}
+
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return 0, fmt.Errorf("failed to restore chunk %s: %w", chunk.ChunkID, err)
+		}
+	}
+
+	for _, chunk := range ordered {
+		if !deferred[chunk.ChunkID] {
+			continue
+		}
+		_, err := tx.ExecContext(ctx,
+			"UPDATE chunks SET parent = $2, page = $3, tags = $4 WHERE chunk_id = $1",
+			chunk.ChunkID, chunk.Parent, chunk.Page, pq.Array(chunk.Tags))
+		if err != nil {
+			return 0, fmt.Errorf("failed to restore references of chunk %s: %w", chunk.ChunkID, err)
+		}
+	}
+
+	chunkIDs := make([]string, len(ordered))
+	for i, chunk := range ordered {
+		chunkIDs[i] = chunk.ChunkID
+	}
+	if _, err := rebuildSubtreeHierarchyTx(ctx, tx, chunkIDs); err != nil {
+		return 0, err
+	}
+
+	return len(ordered), nil
+}
+
+// restoreSnapshotRows executes a prepared insert for each of count rows
+func restoreSnapshotRows(ctx context.Context, tx *sql.Tx, what, query string, count int, args func(i int) []interface{}) (int, error) {
+	if count == 0 {
+		return 0, nil
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare %s statement: %w", what, err)
+	}
+	defer stmt.Close()
+
+	for i := 0; i < count; i++ {
+		if _, err := stmt.ExecContext(ctx, args(i)...); err != nil {
+			return 0, fmt.Errorf("failed to restore %s: %w", what, err)
+		}
+	}
+	return count, nil
+}
+
+// orderChunksForRestore orders chunks so that the parent, page and tag chunks each
+// one references come first. Chunks left in reference cycles are appended and
+// returned as deferred.
+func orderChunksForRestore(chunks []models.UnifiedChunkRecord) ([]models.UnifiedChunkRecord, map[string]bool) {
+	index := make(map[string]int, len(chunks))
+	for i, chunk := range chunks {
+		index[chunk.ChunkID] = i
+	}
+
+	pending := make([]int, len(chunks))
+	dependents := make(map[int][]int)
+	for i, chunk := range chunks {
+		dependencies := map[int]bool{}
+		references := append([]string{}, chunk.Tags...)
+		if chunk.Parent != nil {
+			references = append(references, *chunk.Parent)
+		}
+		if chunk.Page != nil {
+			references = append(references, *chunk.Page)
+		}
+		for _, reference := range references {
+			if j, ok := index[reference]; ok && j != i && !dependencies[j] {
+				dependencies[j] = true
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+		pending[i] = len(dependencies)
+	}
+
+	ordered := make([]models.UnifiedChunkRecord, 0, len(chunks))
+	placed := make([]bool, len(chunks))
+	var queue []int
+	for i := range chunks {
+		if pending[i] == 0 {
+			queue = append(queue, i)
+		}
+	}
+	for len(queue) > 0 {
+		i := queue[0]
+		queue = queue[1:]
+		placed[i] = true
+		ordered = append(ordered, chunks[i])
+		for _, dependent := range dependents[i] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	deferred := make(map[string]bool)
+	for i, chunk := range chunks {
+		if !placed[i] {
+			deferred[chunk.ChunkID] = true
+			ordered = append(ordered, chunk)
+		}
+	}
+	return ordered, deferred
+}
+
+func snapshotTableExists(ctx context.Context, q snapshotQuerier, table string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", table).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", table, err)
+	}
+	return exists, nil
+}
+
+func snapshotColumnExists(ctx context.Context, q snapshotQuerier, table, column string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = $1 AND column_name = $2
+		)`, table, column).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check column %s.%s: %w", table, column, err)
+	}
+	return exists, nil
+}
+
+// ============================================================================
+// ARCHIVE FORMAT
+// ============================================================================
+
+// snapshotSpool buffers one JSONL archive entry in a temporary file, since tar
+// headers need the entry size up front
+type snapshotSpool struct {
+	name    string
+	file    *os.File
+	encoder *json.Encoder
+	count   int
+}
+
+func newSnapshotSpool(name string) (*snapshotSpool, error) {
+	file, err := os.CreateTemp("", "ink-snapshot-*.jsonl")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create spool file for %s: %w", name, err)
+	}
+	return &snapshotSpool{name: name, file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// Write appends a record as one JSON line
+func (s *snapshotSpool) Write(record interface{}) error {
+	if err := s.encoder.Encode(record); err != nil {
+		return fmt.Errorf("failed to write %s record: %w", s.name, err)
+	}
+	s.count++
+	return nil
+}
+
+// Close removes the spool file
+func (s *snapshotSpool) Close() error {
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// writeSnapshotArchive writes the manifest followed by each spooled entry as a gzipped tar
+func writeSnapshotArchive(w io.Writer, manifest *models.SnapshotManifest, spools []*snapshotSpool) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to serialize snapshot manifest: %w", err)
+	}
+	header := &tar.Header{Name: models.SnapshotManifestFile, Mode: 0o644, Size: int64(len(manifestData)), ModTime: manifest.CreatedAt}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+	if _, err := tarWriter.Write(manifestData); err != nil {
+		return fmt.Errorf("failed to write snapshot manifest: %w", err)
+	}
+
+	for _, spool := range spools {
+		size, err := spool.file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("failed to size %s: %w", spool.name, err)
+		}
+		if _, err := spool.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to rewind %s: %w", spool.name, err)
+		}
+
+		header := &tar.Header{Name: spool.name, Mode: 0o644, Size: size, ModTime: manifest.CreatedAt}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write %s: %w", spool.name, err)
+		}
+		if _, err := io.CopyN(tarWriter, spool.file, size); err != nil {
+			return fmt.Errorf("failed to write %s: %w", spool.name, err)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("failed to finish snapshot archive: %w", err)
+	}
+	return nil
+}
+
+// snapshotArchive is a decoded snapshot archive
+type snapshotArchive struct {
+	manifest       *models.SnapshotManifest
+	chunks         []models.UnifiedChunkRecord
+	embeddings     []models.SnapshotEmbedding
+	chunkTags      []models.ChunkTagRelation
+	chunkHierarchy []models.ChunkHierarchyRelation
+	tagHierarchy   []models.TagHierarchyRelation
+	chunkRefs      []models.ChunkRefRelation
+}
+
+// readSnapshotArchive decodes a gzipped tar snapshot archive. Unknown entries are skipped.
+func readSnapshotArchive(r io.Reader) (*snapshotArchive, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open snapshot archive: %w", err)
+	}
+	defer gzipReader.Close()
+
+	archive := &snapshotArchive{}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot archive: %w", err)
+		}
+
+		switch header.Name {
+		case models.SnapshotManifestFile:
+			archive.manifest = &models.SnapshotManifest{}
+			if err := json.NewDecoder(tarReader).Decode(archive.manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse snapshot manifest: %w", err)
+			}
+		case models.SnapshotChunksFile:
+			err = decodeSnapshotLines(tarReader, header.Name, &archive.chunks)
+		case models.SnapshotEmbeddingsFile:
+			err = decodeSnapshotLines(tarReader, header.Name, &archive.embeddings)
+		case models.SnapshotChunkTagsFile:
+			err = decodeSnapshotLines(tarReader, header.Name, &archive.chunkTags)
+		case models.SnapshotChunkHierarchyFile:
+			err = decodeSnapshotLines(tarReader, header.Name, &archive.chunkHierarchy)
+		case models.SnapshotTagHierarchyFile:
+			err = decodeSnapshotLines(tarReader, header.Name, &archive.tagHierarchy)
+		case models.SnapshotChunkRefsFile:
+			err = decodeSnapshotLines(tarReader, header.Name, &archive.chunkRefs)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if archive.manifest == nil {
+		return nil, fmt.Errorf("snapshot archive has no %s", models.SnapshotManifestFile)
+	}
+	if archive.manifest.FormatVersion < 1 || archive.manifest.FormatVersion > models.SnapshotFormatVersion {
+		return nil, fmt.Errorf("unsupported snapshot format version %d", archive.manifest.FormatVersion)
+	}
+	return archive, nil
+}
+
+// decodeSnapshotLines decodes a JSONL entry into the slice pointed to by records
+func decodeSnapshotLines[T any](r io.Reader, name string, records *[]T) error {
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var record T
+		err := decoder.Decode(&record)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse %s record %d: %w", name, line, err)
+		}
+		*records = append(*records, record)
+	}
+}
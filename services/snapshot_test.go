@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderChunksForRestore(t *testing.T) {
+	page := "page"
+	tag := "tag"
+	cycleA, cycleB := "cycle-a", "cycle-b"
+	chunks := []models.UnifiedChunkRecord{
+		{ChunkID: "child", Parent: &page, Page: &page, Tags: []string{tag}},
+		{ChunkID: "page", IsPage: true},
+		{ChunkID: "tag", IsTag: true},
+		{ChunkID: "cycle-a", Parent: &cycleB},
+		{ChunkID: "cycle-b", Parent: &cycleA},
+		{ChunkID: "external", Parent: stringPtr("not-in-archive")},
+	}
+
+	ordered, deferred := orderChunksForRestore(chunks)
+
+	var ids []string
+	for _, chunk := range ordered {
+		ids = append(ids, chunk.ChunkID)
+	}
+	assert.Equal(t, []string{"page", "tag", "external", "child", "cycle-a", "cycle-b"}, ids)
+	assert.Equal(t, map[string]bool{"cycle-a": true, "cycle-b": true}, deferred)
+}
+
+func TestSnapshotArchive_RoundTrip(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	manifest := &models.SnapshotManifest{
+		FormatVersion: models.SnapshotFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		Since:         &since,
+		Counts:        map[string]int{},
+	}
+
+	chunks, err := newSnapshotSpool(models.SnapshotChunksFile)
+	require.NoError(t, err)
+	defer chunks.Close()
+	embeddings, err := newSnapshotSpool(models.SnapshotEmbeddingsFile)
+	require.NoError(t, err)
+	defer embeddings.Close()
+	hierarchy, err := newSnapshotSpool(models.SnapshotChunkHierarchyFile)
+	require.NoError(t, err)
+	defer hierarchy.Close()
+
+	require.NoError(t, chunks.Write(models.UnifiedChunkRecord{ChunkID: "c1", Contents: "First", Tags: []string{"t1"}}))
+	require.NoError(t, chunks.Write(models.UnifiedChunkRecord{ChunkID: "c2", Contents: "Second", Parent: stringPtr("c1")}))
+	require.NoError(t, embeddings.Write(models.SnapshotEmbedding{ChunkID: "c1", Vector: []float64{0.25, -1}}))
+	require.NoError(t, hierarchy.Write(models.ChunkHierarchyRelation{AncestorID: "c1", DescendantID: "c2", Depth: 1, PathIDs: []string{"c1", "c2"}}))
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSnapshotArchive(&buf, manifest, []*snapshotSpool{chunks, embeddings, hierarchy}))
+
+	archive, err := readSnapshotArchive(&buf)
+	require.NoError(t, err)
+	assert.True(t, archive.manifest.Incremental())
+	require.Len(t, archive.chunks, 2)
+	assert.Equal(t, "c1", *archive.chunks[1].Parent)
+	assert.Equal(t, []string{"t1"}, archive.chunks[0].Tags)
+	assert.Equal(t, []float64{0.25, -1}, archive.embeddings[0].Vector)
+	assert.Equal(t, []string{"c1", "c2"}, archive.chunkHierarchy[0].PathIDs)
+
+	t.Run("rejects newer format versions", func(t *testing.T) {
+		manifest.FormatVersion = models.SnapshotFormatVersion + 1
+		var buf bytes.Buffer
+		require.NoError(t, writeSnapshotArchive(&buf, manifest, nil))
+
+		_, err := readSnapshotArchive(&buf)
+		assert.ErrorContains(t, err, "unsupported snapshot format version")
+	})
+}
@@ -0,0 +1,465 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+// ErrInvalidStatsRequest is returned for unknown growth intervals and out-of-range options
+var ErrInvalidStatsRequest = errors.New("invalid statistics request")
+
+const (
+	defaultStatsPeriods = 30
+	maxStatsPeriods     = 366
+	defaultStatsTop     = 10
+	// maxStatsTop is also how many most-linked chunks and tags are computed, so readers
+	// whose page ACLs hide some of them still get a full list
+	maxStatsTop         = 50
+	statsSnippetChars   = 120
+	defaultStatsHistory = 30
+	maxStatsHistoryDays = 366
+	statsDateLayout     = "2006-01-02"
+	// statsSnapshotDays is how many days of daily growth a snapshot keeps. Requests reaching
+	// further back are computed live.
+	statsSnapshotDays = 400
+)
+
+// StatsService computes knowledge base statistics for dashboards: chunk counts by type,
+// growth over time, the most-linked chunks and most-used tags, orphan chunks and average
+// chunk length. A daily snapshot in chunk_stats_snapshots answers most requests without
+// scanning the chunks table.
+type StatsService interface {
+	// GetStats returns the statistics from today's snapshot, or computes them live when
+	// opts.Fresh is set, no snapshot was taken today or its growth does not reach back far enough
+	GetStats(ctx context.Context, opts models.StatsOptions) (*models.ChunkStats, error)
+	// Snapshot computes the statistics and stores them as today's snapshot. Needs admin permission.
+	Snapshot(ctx context.Context) (*models.ChunkStats, error)
+	// SnapshotIfDue takes today's snapshot unless it exists; the maintenance daemon runs it
+	SnapshotIfDue(ctx context.Context) error
+	// History returns the headline numbers of the snapshots of the last days days, oldest first
+	History(ctx context.Context, days int) ([]models.StatsSnapshot, error)
+}
+
+// statsService implements StatsService on PostgreSQL
+type statsService struct {
+	db      *sql.DB
+	access  SearchAccessFilter
+	monitor QueryPerformanceMonitor
+}
+
+// NewStatsService creates a statistics service. access hides the most-linked chunks and
+// tags on pages the caller may not read; it may be nil when page ACLs are disabled.
+func NewStatsService(db *sql.DB, access SearchAccessFilter, monitor QueryPerformanceMonitor) StatsService {
+	return &statsService{db: db, access: access, monitor: monitor}
+}
+
+// GetStats serves today's snapshot when it covers the request and falls back to live queries
+func (s *statsService) GetStats(ctx context.Context, opts models.StatsOptions) (*models.ChunkStats, error) {
+	if err := normalizeStatsOptions(&opts); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	now := start.UTC()
+
+	var stats *models.ChunkStats
+	if !opts.Fresh {
+		snapshot, err := s.loadSnapshot(ctx, statsDate(now))
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			stats = statsFromSnapshot(snapshot, opts, now)
+		}
+	}
+	if stats == nil {
+		var err error
+		if stats, err = s.compute(ctx, opts.Interval, opts.Periods, now); err != nil {
+			return nil, err
+		}
+	}
+	s.monitor.RecordQuery("chunk_stats_"+stats.Source, time.Since(start), 1)
+
+	var err error
+	if stats.MostLinked, err = s.readableUsage(ctx, stats.MostLinked, opts.Top); err != nil {
+		return nil, err
+	}
+	if stats.MostUsedTags, err = s.readableUsage(ctx, stats.MostUsedTags, opts.Top); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Snapshot stores today's statistics with daily growth. Older snapshots are trimmed to
+// their headline numbers, which is all History reads.
+func (s *statsService) Snapshot(ctx context.Context) (*models.ChunkStats, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	now := start.UTC()
+	stats, err := s.compute(ctx, models.StatsIntervalDay, statsSnapshotDays, now)
+	if err != nil {
+		return nil, err
+	}
+	stats.Source = models.StatsSourceSnapshot
+	encoded, err := json.Marshal(stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode statistics snapshot: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	date := statsDate(now).Format(statsDateLayout)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO chunk_stats_snapshots (snapshot_date, stats, created_at)
+		VALUES ($1::date, $2, NOW())
+		ON CONFLICT (snapshot_date) DO UPDATE SET stats = EXCLUDED.stats, created_at = EXCLUDED.created_at`,
+		date, encoded); err != nil {
+		return nil, fmt.Errorf("failed to store statistics snapshot: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE chunk_stats_snapshots
+		SET stats = stats - 'growth' - 'most_linked' - 'most_used_tags'
+		WHERE snapshot_date < $1::date AND stats ? 'growth'`,
+		date); err != nil {
+		return nil, fmt.Errorf("failed to trim old statistics snapshots: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit statistics snapshot: %w", err)
+	}
+
+	s.monitor.RecordQuery("chunk_stats_snapshot", time.Since(start), 1)
+	return stats, nil
+}
+
+// SnapshotIfDue takes at most one snapshot a day however often the daemon ticks
+func (s *statsService) SnapshotIfDue(ctx context.Context) error {
+	var exists bool
+	err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS (SELECT 1 FROM chunk_stats_snapshots WHERE snapshot_date = $1::date)`,
+		statsDate(time.Now().UTC()).Format(statsDateLayout)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check statistics snapshot: %w", err)
+	}
+	if exists {
+		return nil
+	}
+	_, err = s.Snapshot(ctx)
+	return err
+}
+
+// History reads the headline numbers without decoding the snapshots' growth series
+func (s *statsService) History(ctx context.Context, days int) ([]models.StatsSnapshot, error) {
+	if days == 0 {
+		days = defaultStatsHistory
+	}
+	if days < 0 || days > maxStatsHistoryDays {
+		return nil, fmt.Errorf("%w: days must be between 1 and %d", ErrInvalidStatsRequest, maxStatsHistoryDays)
+	}
+
+	start := time.Now()
+	since := statsDate(start.UTC()).AddDate(0, 0, -(days - 1))
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT snapshot_date, stats->'counts', COALESCE((stats->>'average_length')::float8, 0),
+			   COALESCE((stats->>'orphan_chunks')::int, 0), created_at
+		FROM chunk_stats_snapshots
+		WHERE snapshot_date >= $1::date
+		ORDER BY snapshot_date`,
+		since.Format(statsDateLayout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query statistics history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []models.StatsSnapshot{}
+	for rows.Next() {
+		var snapshot models.StatsSnapshot
+		var counts []byte
+		if err := rows.Scan(&snapshot.Date, &counts, &snapshot.AverageLength, &snapshot.OrphanChunks, &snapshot.ComputedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan statistics snapshot: %w", err)
+		}
+		if len(counts) > 0 {
+			if err := json.Unmarshal(counts, &snapshot.Counts); err != nil {
+				return nil, fmt.Errorf("failed to decode statistics snapshot: %w", err)
+			}
+		}
+		snapshot.Date = statsDate(snapshot.Date)
+		history = append(history, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating statistics snapshots: %w", err)
+	}
+
+	s.monitor.RecordQuery("chunk_stats_history", time.Since(start), len(history))
+	return history, nil
+}
+
+// loadSnapshot returns the snapshot of date, or nil if none was taken
+func (s *statsService) loadSnapshot(ctx context.Context, date time.Time) (*models.ChunkStats, error) {
+	var encoded []byte
+	err := s.db.QueryRowContext(ctx,
+		`SELECT stats FROM chunk_stats_snapshots WHERE snapshot_date = $1::date`,
+		date.Format(statsDateLayout)).Scan(&encoded)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load statistics snapshot: %w", err)
+	}
+
+	var stats models.ChunkStats
+	if err := json.Unmarshal(encoded, &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode statistics snapshot: %w", err)
+	}
+	return &stats, nil
+}
+
+// compute queries the statistics live, with growth in periods buckets of interval ending
+// with the one containing now and the full maxStatsTop usage lists
+func (s *statsService) compute(ctx context.Context, interval string, periods int, now time.Time) (*models.ChunkStats, error) {
+	stats := &models.ChunkStats{
+		Interval:   interval,
+		Source:     models.StatsSourceLive,
+		ComputedAt: now,
+	}
+	windowStart := growthStart(now, interval, periods)
+
+	var baseline int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COUNT(*),
+			   COUNT(*) FILTER (WHERE is_page),
+			   COUNT(*) FILTER (WHERE is_tag),
+			   COUNT(*) FILTER (WHERE is_template),
+			   COUNT(*) FILTER (WHERE is_slot),
+			   COUNT(*) FILTER (WHERE NOT COALESCE(is_page OR is_tag OR is_template OR is_slot, false)),
+			   COUNT(*) FILTER (WHERE NOT COALESCE(is_page OR is_tag OR is_template OR is_slot, false)
+									AND parent IS NULL AND page IS NULL),
+			   COALESCE(AVG(char_length(contents)) FILTER (WHERE contents NOT LIKE $1), 0)::float8,
+			   COUNT(*) FILTER (WHERE created_time < $2)
+		FROM chunks`,
+		encryptedContentPrefix+"%", windowStart).Scan(
+		&stats.Counts.Total, &stats.Counts.Pages, &stats.Counts.Tags, &stats.Counts.Templates,
+		&stats.Counts.Slots, &stats.Counts.Blocks, &stats.OrphanChunks, &stats.AverageLength, &baseline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count chunks: %w", err)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT date_trunc($1, created_time AT TIME ZONE 'UTC') AS period, COUNT(*)
+		FROM chunks
+		WHERE created_time >= $2
+		GROUP BY period`,
+		interval, windowStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunk growth: %w", err)
+	}
+	defer rows.Close()
+
+	created := make(map[time.Time]int)
+	for rows.Next() {
+		var period time.Time
+		var count int
+		if err := rows.Scan(&period, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan chunk growth: %w", err)
+		}
+		created[statsDate(period)] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk growth: %w", err)
+	}
+	stats.Growth = fillGrowth(windowStart, interval, periods, created, baseline)
+
+	// Aggregating before the join skips references to deleted chunks
+	stats.MostLinked, err = s.topUsage(ctx, `
+		SELECT r.target_chunk_id, left(c.contents, 300), r.links
+		FROM (SELECT target_chunk_id, COUNT(*) AS links FROM chunk_refs GROUP BY target_chunk_id) r
+		JOIN chunks c ON c.chunk_id = r.target_chunk_id
+		ORDER BY r.links DESC, r.target_chunk_id
+		LIMIT $1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query most-linked chunks: %w", err)
+	}
+	stats.MostUsedTags, err = s.topUsage(ctx, `
+		SELECT t.tag_chunk_id, left(c.contents, 300), t.uses
+		FROM (SELECT tag_chunk_id, COUNT(*) AS uses FROM chunk_tags GROUP BY tag_chunk_id) t
+		JOIN chunks c ON c.chunk_id = t.tag_chunk_id
+		ORDER BY t.uses DESC, t.tag_chunk_id
+		LIMIT $1`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query most-used tags: %w", err)
+	}
+
+	return stats, nil
+}
+
+// topUsage runs a ranking query selecting chunk ID, contents and count, limited by $1
+func (s *statsService) topUsage(ctx context.Context, query string) ([]models.ChunkUsage, error) {
+	rows, err := s.db.QueryContext(ctx, query, maxStatsTop)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []models.ChunkUsage{}
+	for rows.Next() {
+		var entry models.ChunkUsage
+		if err := rows.Scan(&entry.ChunkID, &entry.Contents, &entry.Count); err != nil {
+			return nil, err
+		}
+		if IsEncryptedContent(entry.Contents) {
+			entry.Contents = ""
+		} else {
+			entry.Contents = ragSnippet(entry.Contents, statsSnippetChars)
+		}
+		usage = append(usage, entry)
+	}
+	return usage, rows.Err()
+}
+
+// readableUsage drops the entries the caller may not read and keeps the first top
+func (s *statsService) readableUsage(ctx context.Context, usage []models.ChunkUsage, top int) ([]models.ChunkUsage, error) {
+	readable := make(map[string]bool, len(usage))
+	for _, entry := range usage {
+		readable[entry.ChunkID] = true
+	}
+	if s.access != nil && len(usage) > 0 {
+		ids := make([]string, len(usage))
+		for i, entry := range usage {
+			ids[i] = entry.ChunkID
+		}
+		allowed, err := s.access.FilterChunkIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		readable = make(map[string]bool, len(allowed))
+		for _, id := range allowed {
+			readable[id] = true
+		}
+	}
+
+	filtered := make([]models.ChunkUsage, 0, top)
+	for _, entry := range usage {
+		if len(filtered) == top {
+			break
+		}
+		if readable[entry.ChunkID] {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
+}
+
+// normalizeStatsOptions applies the defaults and rejects out-of-range options
+func normalizeStatsOptions(opts *models.StatsOptions) error {
+	switch opts.Interval {
+	case "":
+		opts.Interval = models.StatsIntervalDay
+	case models.StatsIntervalDay, models.StatsIntervalWeek, models.StatsIntervalMonth:
+	default:
+		return fmt.Errorf("%w: interval must be day, week or month", ErrInvalidStatsRequest)
+	}
+	if opts.Periods == 0 {
+		opts.Periods = defaultStatsPeriods
+	}
+	if opts.Periods < 0 || opts.Periods > maxStatsPeriods {
+		return fmt.Errorf("%w: periods must be between 1 and %d", ErrInvalidStatsRequest, maxStatsPeriods)
+	}
+	if opts.Top == 0 {
+		opts.Top = defaultStatsTop
+	}
+	if opts.Top < 0 || opts.Top > maxStatsTop {
+		return fmt.Errorf("%w: top must be between 1 and %d", ErrInvalidStatsRequest, maxStatsTop)
+	}
+	return nil
+}
+
+// statsFromSnapshot regroups a snapshot's daily growth into the requested buckets, or
+// returns nil if the snapshot does not reach back to the first of them
+func statsFromSnapshot(snapshot *models.ChunkStats, opts models.StatsOptions, now time.Time) *models.ChunkStats {
+	windowStart := growthStart(now, opts.Interval, opts.Periods)
+	if len(snapshot.Growth) == 0 || windowStart.Before(snapshot.Growth[0].Period) {
+		return nil
+	}
+
+	stats := *snapshot
+	stats.Interval = opts.Interval
+	stats.Growth = regroupGrowth(snapshot.Growth, opts.Interval, windowStart)
+	stats.Source = models.StatsSourceSnapshot
+	return &stats
+}
+
+// fillGrowth turns per-period creation counts into periods consecutive buckets from start,
+// including empty ones, with running totals on top of the chunks created before start
+func fillGrowth(start time.Time, interval string, periods int, created map[time.Time]int, baseline int) []models.GrowthBucket {
+	growth := make([]models.GrowthBucket, periods)
+	total := baseline
+	for i := range growth {
+		period := addStatsPeriods(start, interval, i)
+		total += created[period]
+		growth[i] = models.GrowthBucket{Period: period, Created: created[period], Total: total}
+	}
+	return growth
+}
+
+// regroupGrowth merges consecutive daily buckets from start into buckets of interval
+func regroupGrowth(daily []models.GrowthBucket, interval string, start time.Time) []models.GrowthBucket {
+	var growth []models.GrowthBucket
+	for _, day := range daily {
+		if day.Period.Before(start) {
+			continue
+		}
+		period := truncateStatsPeriod(day.Period, interval)
+		if n := len(growth); n == 0 || !growth[n-1].Period.Equal(period) {
+			growth = append(growth, models.GrowthBucket{Period: period})
+		}
+		bucket := &growth[len(growth)-1]
+		bucket.Created += day.Created
+		bucket.Total = day.Total
+	}
+	return growth
+}
+
+// growthStart returns the start of the first of periods buckets ending with the one containing now
+func growthStart(now time.Time, interval string, periods int) time.Time {
+	return addStatsPeriods(truncateStatsPeriod(now, interval), interval, -(periods - 1))
+}
+
+// truncateStatsPeriod returns the start of the bucket containing t, in UTC like date_trunc
+func truncateStatsPeriod(t time.Time, interval string) time.Time {
+	day := statsDate(t)
+	switch interval {
+	case models.StatsIntervalWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case models.StatsIntervalMonth:
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day
+}
+
+func addStatsPeriods(t time.Time, interval string, n int) time.Time {
+	switch interval {
+	case models.StatsIntervalWeek:
+		return t.AddDate(0, 0, 7*n)
+	case models.StatsIntervalMonth:
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, n)
+}
+
+// statsDate returns midnight UTC of t's calendar date. Dates scanned from PostgreSQL keep
+// their date whatever location the driver gives them.
+func statsDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+type fixedAccessFilter struct {
+	readable map[string]bool
+}
+
+func (f *fixedAccessFilter) FilterChunkIDs(ctx context.Context, chunkIDs []string) ([]string, error) {
+	var allowed []string
+	for _, id := range chunkIDs {
+		if f.readable[id] {
+			allowed = append(allowed, id)
+		}
+	}
+	return allowed, nil
+}
+
+func TestStatsGrowthBuckets(t *testing.T) {
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.UTC) // a Wednesday
+
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), truncateStatsPeriod(now, models.StatsIntervalWeek))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), truncateStatsPeriod(now, models.StatsIntervalMonth))
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), growthStart(now, models.StatsIntervalWeek, 2))
+	assert.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), growthStart(now, models.StatsIntervalMonth, 4))
+
+	start := growthStart(now, models.StatsIntervalDay, 3)
+	growth := fillGrowth(start, models.StatsIntervalDay, 3, map[time.Time]int{
+		time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC): 4,
+		time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC): 1,
+	}, 10)
+	assert.Equal(t, []models.GrowthBucket{
+		{Period: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Created: 4, Total: 14},
+		{Period: time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC), Created: 0, Total: 14},
+		{Period: time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), Created: 1, Total: 15},
+	}, growth)
+
+	// Feb 26 - Mar 13 regrouped into the weeks starting Mar 4 and Mar 11
+	daily := fillGrowth(time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), models.StatsIntervalDay, 17,
+		map[time.Time]int{
+			time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC):  2,
+			time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC):  3,
+			time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC): 1,
+			time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC): 5,
+		}, 100)
+	assert.Equal(t, []models.GrowthBucket{
+		{Period: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Created: 4, Total: 106},
+		{Period: time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), Created: 5, Total: 111},
+	}, regroupGrowth(daily, models.StatsIntervalWeek, growthStart(now, models.StatsIntervalWeek, 2)))
+}
+
+func TestStatsFromSnapshot(t *testing.T) {
+	now := time.Date(2024, 3, 13, 8, 0, 0, 0, time.UTC)
+	snapshot := &models.ChunkStats{
+		Counts:   models.ChunkTypeCounts{Total: 111, Pages: 7},
+		Interval: models.StatsIntervalDay,
+		Growth: fillGrowth(time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), models.StatsIntervalDay, 17,
+			map[time.Time]int{time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC): 5}, 106),
+		Source: models.StatsSourceSnapshot,
+	}
+
+	opts := models.StatsOptions{Interval: models.StatsIntervalDay, Periods: 7}
+	stats := statsFromSnapshot(snapshot, opts, now)
+	require.NotNil(t, stats)
+	require.Len(t, stats.Growth, 7)
+	assert.Equal(t, time.Date(2024, 3, 7, 0, 0, 0, 0, time.UTC), stats.Growth[0].Period)
+	assert.Equal(t, 111, stats.Growth[6].Total)
+	assert.Equal(t, 7, stats.Counts.Pages)
+	assert.Len(t, snapshot.Growth, 17, "the snapshot is not modified")
+
+	opts.Interval = models.StatsIntervalMonth
+	opts.Periods = 2
+	assert.Nil(t, statsFromSnapshot(snapshot, opts, now), "February 1 is before the snapshot's growth")
+}
+
+func TestStatsOptionsAndReadableUsage(t *testing.T) {
+	opts := models.StatsOptions{}
+	require.NoError(t, normalizeStatsOptions(&opts))
+	assert.Equal(t, models.StatsOptions{Interval: models.StatsIntervalDay, Periods: defaultStatsPeriods, Top: defaultStatsTop}, opts)
+
+	for _, invalid := range []models.StatsOptions{
+		{Interval: "year"},
+		{Periods: -1},
+		{Periods: maxStatsPeriods + 1},
+		{Top: maxStatsTop + 1},
+	} {
+		assert.ErrorIs(t, normalizeStatsOptions(&invalid), ErrInvalidStatsRequest)
+	}
+
+	service := &statsService{access: &fixedAccessFilter{readable: map[string]bool{"a": true, "c": true, "d": true}}}
+	usage := []models.ChunkUsage{{ChunkID: "a", Count: 9}, {ChunkID: "b", Count: 8}, {ChunkID: "c", Count: 7}, {ChunkID: "d", Count: 6}}
+	readable, err := service.readableUsage(context.Background(), usage, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []models.ChunkUsage{{ChunkID: "a", Count: 9}, {ChunkID: "c", Count: 7}}, readable)
+
+	readable, err = (&statsService{}).readableUsage(context.Background(), nil, 2)
+	require.NoError(t, err)
+	assert.NotNil(t, readable, "empty lists encode as []")
+}
+
+func TestStatsService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	stats := NewStatsService(db, nil, NewNoOpMonitor())
+
+	before, err := stats.GetStats(ctx, models.StatsOptions{Fresh: true})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatsSourceLive, before.Source)
+	require.Len(t, before.Growth, defaultStatsPeriods)
+
+	page := &models.UnifiedChunkRecord{Contents: "stats-test-page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer chunks.DeleteChunk(ctx, page.ChunkID)
+	orphan := &models.UnifiedChunkRecord{Contents: "stats-test-orphan"}
+	require.NoError(t, chunks.CreateChunk(ctx, orphan))
+	defer chunks.DeleteChunk(ctx, orphan.ChunkID)
+
+	after, err := stats.GetStats(ctx, models.StatsOptions{Fresh: true, Interval: models.StatsIntervalWeek, Periods: 4})
+	require.NoError(t, err)
+	assert.Equal(t, before.Counts.Total+2, after.Counts.Total)
+	assert.Equal(t, before.Counts.Pages+1, after.Counts.Pages)
+	assert.Equal(t, before.OrphanChunks+1, after.OrphanChunks)
+	require.Len(t, after.Growth, 4)
+	assert.Equal(t, after.Counts.Total, after.Growth[3].Total)
+
+	snapshot, err := stats.Snapshot(ctx)
+	require.NoError(t, err)
+	require.Len(t, snapshot.Growth, statsSnapshotDays)
+	require.NoError(t, stats.SnapshotIfDue(ctx))
+
+	cached, err := stats.GetStats(ctx, models.StatsOptions{Interval: models.StatsIntervalWeek, Periods: 4})
+	require.NoError(t, err)
+	assert.Equal(t, models.StatsSourceSnapshot, cached.Source)
+	assert.Equal(t, after.Counts, cached.Counts)
+	assert.Equal(t, after.Growth, cached.Growth)
+
+	history, err := stats.History(ctx, 7)
+	require.NoError(t, err)
+	require.NotEmpty(t, history)
+	assert.Equal(t, after.Counts, history[len(history)-1].Counts)
+}
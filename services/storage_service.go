@@ -10,8 +10,8 @@ import (
 	"semantic-text-processor/models"
 )
 
-// StorageService 統一的儲存服務
-type StorageService struct {
+// MediaStorageService 統一的媒體儲存服務（多適配器，帶重試和降級）
+type MediaStorageService struct {
 	manager         *StorageManager
 	config          *config.MultimodalConfig
 	retryCount      int
@@ -20,8 +20,8 @@ type StorageService struct {
 	lastHealthCheck map[models.StorageType]time.Time
 }
 
-// NewStorageService 建立新的儲存服務
-func NewStorageService(config *config.MultimodalConfig) (*StorageService, error) {
+// NewMediaStorageService 建立新的媒體儲存服務
+func NewMediaStorageService(config *config.MultimodalConfig) (*MediaStorageService, error) {
 	// 轉換配置格式
 	storageConfig := &StorageConfig{
 		Primary:  config.Storage.Primary,
@@ -40,7 +40,7 @@ func NewStorageService(config *config.MultimodalConfig) (*StorageService, error)
 		return nil, fmt.Errorf("failed to create storage manager: %w", err)
 	}
 	
-	service := &StorageService{
+	service := &MediaStorageService{
 		manager:         manager,
 		config:          config,
 		retryCount:      3,
@@ -53,7 +53,7 @@ func NewStorageService(config *config.MultimodalConfig) (*StorageService, error)
 }
 
 // Upload 上傳檔案（帶重試和降級機制）
-func (s *StorageService) Upload(ctx context.Context, file io.Reader, metadata *models.MediaMetadata) (*models.StorageResult, error) {
+func (s *MediaStorageService) Upload(ctx context.Context, file io.Reader, metadata *models.MediaMetadata) (*models.StorageResult, error) {
 	// 先嘗試主要儲存
 	result, err := s.uploadWithRetry(ctx, s.manager.GetPrimaryAdapter(), file, metadata)
 	if err == nil {
@@ -84,7 +84,7 @@ func (s *StorageService) Upload(ctx context.Context, file io.Reader, metadata *m
 }
 
 // uploadWithRetry 帶重試的上傳
-func (s *StorageService) uploadWithRetry(ctx context.Context, adapter MediaStorageAdapter, file io.Reader, metadata *models.MediaMetadata) (*models.StorageResult, error) {
+func (s *MediaStorageService) uploadWithRetry(ctx context.Context, adapter MediaStorageAdapter, file io.Reader, metadata *models.MediaMetadata) (*models.StorageResult, error) {
 	var lastErr error
 	
 	for attempt := 0; attempt <= s.retryCount; attempt++ {
@@ -119,7 +119,7 @@ func (s *StorageService) uploadWithRetry(ctx context.Context, adapter MediaStora
 }
 
 // GetURL 取得檔案 URL
-func (s *StorageService) GetURL(ctx context.Context, storageType models.StorageType, storageID string) (string, error) {
+func (s *MediaStorageService) GetURL(ctx context.Context, storageType models.StorageType, storageID string) (string, error) {
 	adapter, err := s.getAdapterByType(storageType)
 	if err != nil {
 		return "", err
@@ -129,7 +129,7 @@ func (s *StorageService) GetURL(ctx context.Context, storageType models.StorageT
 }
 
 // Download 下載檔案
-func (s *StorageService) Download(ctx context.Context, storageType models.StorageType, storageID string) (io.ReadCloser, error) {
+func (s *MediaStorageService) Download(ctx context.Context, storageType models.StorageType, storageID string) (io.ReadCloser, error) {
 	adapter, err := s.getAdapterByType(storageType)
 	if err != nil {
 		return nil, err
@@ -139,7 +139,7 @@ func (s *StorageService) Download(ctx context.Context, storageType models.Storag
 }
 
 // Delete 刪除檔案
-func (s *StorageService) Delete(ctx context.Context, storageType models.StorageType, storageID string) error {
+func (s *MediaStorageService) Delete(ctx context.Context, storageType models.StorageType, storageID string) error {
 	adapter, err := s.getAdapterByType(storageType)
 	if err != nil {
 		return err
@@ -149,13 +149,13 @@ func (s *StorageService) Delete(ctx context.Context, storageType models.StorageT
 }
 
 // ScanFolder 掃描資料夾
-func (s *StorageService) ScanFolder(ctx context.Context, folderPath string) ([]models.MediaFile, error) {
+func (s *MediaStorageService) ScanFolder(ctx context.Context, folderPath string) ([]models.MediaFile, error) {
 	// 使用主要儲存適配器進行掃描
 	return s.manager.GetPrimaryAdapter().ScanFolder(ctx, folderPath)
 }
 
 // HealthCheck 健康檢查
-func (s *StorageService) HealthCheck(ctx context.Context) error {
+func (s *MediaStorageService) HealthCheck(ctx context.Context) error {
 	// 檢查主要儲存
 	primaryAdapter := s.manager.GetPrimaryAdapter()
 	if err := s.checkAdapterHealth(ctx, primaryAdapter); err != nil {
@@ -175,7 +175,7 @@ func (s *StorageService) HealthCheck(ctx context.Context) error {
 }
 
 // checkAdapterHealth 檢查適配器健康狀態
-func (s *StorageService) checkAdapterHealth(ctx context.Context, adapter MediaStorageAdapter) error {
+func (s *MediaStorageService) checkAdapterHealth(ctx context.Context, adapter MediaStorageAdapter) error {
 	storageType := adapter.GetStorageType()
 	
 	// 檢查是否需要進行健康檢查
@@ -197,7 +197,7 @@ func (s *StorageService) checkAdapterHealth(ctx context.Context, adapter MediaSt
 }
 
 // getAdapterByType 根據類型取得適配器
-func (s *StorageService) getAdapterByType(storageType models.StorageType) (MediaStorageAdapter, error) {
+func (s *MediaStorageService) getAdapterByType(storageType models.StorageType) (MediaStorageAdapter, error) {
 	primaryAdapter := s.manager.GetPrimaryAdapter()
 	if primaryAdapter.GetStorageType() == storageType {
 		return primaryAdapter, nil
@@ -212,12 +212,12 @@ func (s *StorageService) getAdapterByType(storageType models.StorageType) (Media
 }
 
 // GetPrimaryStorageType 取得主要儲存類型
-func (s *StorageService) GetPrimaryStorageType() models.StorageType {
+func (s *MediaStorageService) GetPrimaryStorageType() models.StorageType {
 	return s.manager.GetPrimaryAdapter().GetStorageType()
 }
 
 // GetFallbackStorageType 取得備用儲存類型
-func (s *StorageService) GetFallbackStorageType() models.StorageType {
+func (s *MediaStorageService) GetFallbackStorageType() models.StorageType {
 	fallbackAdapter := s.manager.GetFallbackAdapter()
 	if fallbackAdapter == nil {
 		return ""
@@ -226,12 +226,12 @@ func (s *StorageService) GetFallbackStorageType() models.StorageType {
 }
 
 // SwitchPrimaryStorage 切換主要儲存
-func (s *StorageService) SwitchPrimaryStorage(newPrimary models.StorageType) error {
+func (s *MediaStorageService) SwitchPrimaryStorage(newPrimary models.StorageType) error {
 	return s.manager.SwitchPrimary(newPrimary)
 }
 
 // GetStorageStats 取得儲存統計資訊
-func (s *StorageService) GetStorageStats() map[string]interface{} {
+func (s *MediaStorageService) GetStorageStats() map[string]interface{} {
 	stats := map[string]interface{}{
 		"primary_storage":   string(s.GetPrimaryStorageType()),
 		"fallback_storage":  string(s.GetFallbackStorageType()),
@@ -251,7 +251,7 @@ func (s *StorageService) GetStorageStats() map[string]interface{} {
 }
 
 // ValidateFile 驗證檔案
-func (s *StorageService) ValidateFile(metadata *models.MediaMetadata) error {
+func (s *MediaStorageService) ValidateFile(metadata *models.MediaMetadata) error {
 	// 檢查檔案大小
 	maxSize := s.config.Processing.MaxImageSize
 	if metadata.Size > maxSize {
@@ -272,7 +272,7 @@ func (s *StorageService) ValidateFile(metadata *models.MediaMetadata) error {
 }
 
 // Close 關閉儲存服務
-func (s *StorageService) Close() error {
+func (s *MediaStorageService) Close() error {
 	// 清理資源
 	s.lastHealthCheck = nil
 	return nil
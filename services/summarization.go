@@ -0,0 +1,385 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+	"golang.org/x/sync/errgroup"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+const (
+	// summaryPromptVersion is part of every source hash, so changing the prompts
+	// invalidates cached summaries
+	summaryPromptVersion = "1"
+	// summaryMaxReduceRounds bounds how often child summaries are condensed before the
+	// rollup summary is generated from whatever they were reduced to
+	summaryMaxReduceRounds = 5
+)
+
+const summarySystemPrompt = `You summarize notes from a personal knowledge base.
+Write one concise paragraph in the language of the notes. Keep names, numbers, dates and decisions.
+Do not add information that is not in the notes and do not mention that this is a summary.`
+
+// SummarizationService produces cached summaries of chunks and rollup summaries of pages
+type SummarizationService interface {
+	// Summarize returns the summary of a chunk. Pages and blocks with children are
+	// summarized from their contents and the summaries of their children, reducing long
+	// pages over several completions. Cached summaries are reused while the text they were
+	// generated from is unchanged.
+	Summarize(ctx context.Context, chunkID string, opts models.SummaryOptions) (*models.ChunkSummary, error)
+}
+
+// summarizationService stores generated summaries in chunk_summaries keyed by a hash of
+// their source text. A parent's hash covers its children's hashes, so an edit anywhere
+// below a page invalidates every summary on the way up without explicit invalidation.
+type summarizationService struct {
+	db        *sql.DB
+	chunks    UnifiedChunkService
+	provider  CompletionProvider
+	config    *config.SummaryConfig
+	tokenizer Tokenizer
+	monitor   QueryPerformanceMonitor
+}
+
+// NewSummarizationService creates a summarization service generating with provider and
+// reading chunks through chunks, so page ACLs apply to summaries
+func NewSummarizationService(db *sql.DB, chunks UnifiedChunkService, provider CompletionProvider, cfg *config.SummaryConfig, monitor QueryPerformanceMonitor) SummarizationService {
+	return &summarizationService{
+		db:        db,
+		chunks:    chunks,
+		provider:  provider,
+		config:    cfg,
+		tokenizer: EstimatingTokenizer,
+		monitor:   monitor,
+	}
+}
+
+// summaryNode is a chunk in the summarized subtree
+type summaryNode struct {
+	chunk       *models.UnifiedChunkRecord
+	children    []*summaryNode
+	descendants int
+	hash        string
+
+	summary     string
+	model       string
+	cached      bool
+	generatedAt time.Time
+}
+
+// cachedSummary is a stored summary and the hash of the text it summarizes
+type cachedSummary struct {
+	hash      string
+	summary   string
+	model     string
+	createdAt time.Time
+}
+
+// Summarize summarizes the chunk bottom-up: children before their parents, the chunks of
+// one level in parallel
+func (s *summarizationService) Summarize(ctx context.Context, chunkID string, opts models.SummaryOptions) (*models.ChunkSummary, error) {
+	start := time.Now()
+	var generated atomic.Int64
+	defer func() {
+		s.monitor.RecordQuery("summarize_chunk", time.Since(start), int(generated.Load()))
+	}()
+
+	chunk, err := s.chunks.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	descendants, err := s.chunks.GetDescendants(ctx, chunkID, 0)
+	if err != nil {
+		return nil, err
+	}
+	root, levels := buildSummaryTree(chunk, descendants)
+
+	cached, err := s.loadCached(ctx, levels)
+	if err != nil {
+		return nil, err
+	}
+
+	for depth := len(levels) - 1; depth >= 0; depth-- {
+		group, groupCtx := errgroup.WithContext(ctx)
+		group.SetLimit(s.config.Concurrency)
+		for _, node := range levels[depth] {
+			refresh := opts.Refresh && node == root
+			group.Go(func() error {
+				made, err := s.summarizeNode(groupCtx, node, cached[node.chunk.ChunkID], refresh)
+				generated.Add(int64(made))
+				return err
+			})
+		}
+		if err := group.Wait(); err != nil {
+			return nil, err
+		}
+	}
+
+	kind := models.SummaryKindChunk
+	if len(root.children) > 0 {
+		kind = models.SummaryKindRollup
+	}
+	return &models.ChunkSummary{
+		ChunkID:     root.chunk.ChunkID,
+		Kind:        kind,
+		Summary:     root.summary,
+		Model:       root.model,
+		SourceHash:  root.hash,
+		Children:    len(root.children),
+		Descendants: root.descendants,
+		Cached:      root.cached,
+		Generated:   int(generated.Load()),
+		GeneratedAt: root.generatedAt,
+		Duration:    time.Since(start),
+	}, nil
+}
+
+// buildSummaryTree links descendants, ordered by depth, to their parents and hashes the
+// tree. Descendants whose parent is missing, for example filtered by page ACLs, are left
+// out. levels[d] holds the nodes d levels below the root.
+func buildSummaryTree(chunk *models.UnifiedChunkRecord, descendants []models.UnifiedChunkRecord) (*summaryNode, [][]*summaryNode) {
+	root := &summaryNode{chunk: chunk}
+	nodes := map[string]*summaryNode{chunk.ChunkID: root}
+	depths := map[string]int{chunk.ChunkID: 0}
+	levels := [][]*summaryNode{{root}}
+
+	for i := range descendants {
+		descendant := &descendants[i]
+		if descendant.Parent == nil {
+			continue
+		}
+		parent, ok := nodes[*descendant.Parent]
+		if !ok {
+			continue
+		}
+		node := &summaryNode{chunk: descendant}
+		parent.children = append(parent.children, node)
+		nodes[descendant.ChunkID] = node
+
+		depth := depths[*descendant.Parent] + 1
+		depths[descendant.ChunkID] = depth
+		if depth == len(levels) {
+			levels = append(levels, nil)
+		}
+		levels[depth] = append(levels[depth], node)
+	}
+
+	hashSummaryNode(root)
+	return root, levels
+}
+
+// hashSummaryNode hashes a chunk's contents with the hashes of its children
+func hashSummaryNode(node *summaryNode) {
+	hash := sha256.New()
+	hash.Write([]byte(summaryPromptVersion))
+	hash.Write([]byte{0})
+	hash.Write([]byte(node.chunk.Contents))
+	for _, child := range node.children {
+		hashSummaryNode(child)
+		node.descendants += child.descendants + 1
+		hash.Write([]byte{0})
+		hash.Write([]byte(child.hash))
+	}
+	node.hash = hex.EncodeToString(hash.Sum(nil))
+}
+
+// loadCached reads the stored summaries of the subtree
+func (s *summarizationService) loadCached(ctx context.Context, levels [][]*summaryNode) (map[string]cachedSummary, error) {
+	var ids []string
+	for _, level := range levels {
+		for _, node := range level {
+			ids = append(ids, node.chunk.ChunkID)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT chunk_id, source_hash, summary, model, created_at
+		FROM chunk_summaries
+		WHERE chunk_id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cached summaries: %w", err)
+	}
+	defer rows.Close()
+
+	cached := make(map[string]cachedSummary, len(ids))
+	for rows.Next() {
+		var id string
+		var summary cachedSummary
+		if err := rows.Scan(&id, &summary.hash, &summary.summary, &summary.model, &summary.createdAt); err != nil {
+			return nil, fmt.Errorf("failed to scan cached summary: %w", err)
+		}
+		cached[id] = summary
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cached summaries: %w", err)
+	}
+	return cached, nil
+}
+
+// summarizeNode sets a node's summary from the cache, from its text when that is short,
+// or from the completion provider, and returns the number of completions made
+func (s *summarizationService) summarizeNode(ctx context.Context, node *summaryNode, cached cachedSummary, refresh bool) (int, error) {
+	if cached.hash == node.hash && !refresh {
+		node.summary, node.model, node.cached, node.generatedAt = cached.summary, cached.model, true, cached.createdAt
+		return 0, nil
+	}
+
+	parts := make([]string, len(node.children))
+	for i, child := range node.children {
+		parts[i] = child.summary
+	}
+	node.generatedAt = time.Now()
+	if text := summarySourceText(node.chunk.Contents, parts); utf8.RuneCountInString(text) <= s.config.MinLength {
+		node.summary = text
+		return 0, nil
+	}
+
+	summary, model, made, err := s.generate(ctx, node.chunk, parts)
+	if err != nil {
+		return made, err
+	}
+	node.summary, node.model = summary, model
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO chunk_summaries (chunk_id, source_hash, summary, model) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (chunk_id) DO UPDATE
+		SET source_hash = EXCLUDED.source_hash, summary = EXCLUDED.summary, model = EXCLUDED.model, created_at = NOW()`,
+		node.chunk.ChunkID, node.hash, summary, model); err != nil {
+		// The summary is still returned; it is generated again next time
+		log.Printf("Warning: failed to cache summary of chunk %s: %v", node.chunk.ChunkID, err)
+	}
+	return made, nil
+}
+
+// generate summarizes a chunk from its contents and its children's summaries. While the
+// prompt exceeds the input budget, the children's summaries are condensed in groups.
+func (s *summarizationService) generate(ctx context.Context, chunk *models.UnifiedChunkRecord, parts []string) (string, string, int, error) {
+	made := 0
+	for round := 0; ; round++ {
+		prompt := buildSummaryPrompt(chunk, parts)
+		if len(parts) <= 1 || round == summaryMaxReduceRounds || s.tokenizer.CountTokens(prompt) <= s.config.MaxInputTokens {
+			summary, model, err := s.complete(ctx, s.truncate(prompt))
+			if err != nil {
+				return "", "", made, err
+			}
+			return summary, model, made + 1, nil
+		}
+
+		var reduced []string
+		for _, group := range s.groupSummaries(parts) {
+			summary, _, err := s.complete(ctx, buildSectionsPrompt(group))
+			if err != nil {
+				return "", "", made, err
+			}
+			made++
+			reduced = append(reduced, summary)
+		}
+		parts = reduced
+	}
+}
+
+// groupSummaries splits summaries into consecutive groups that fit the input budget
+func (s *summarizationService) groupSummaries(parts []string) [][]string {
+	var groups [][]string
+	var group []string
+	tokens := 0
+	for _, part := range parts {
+		partTokens := s.tokenizer.CountTokens(part)
+		if len(group) > 0 && tokens+partTokens > s.config.MaxInputTokens {
+			groups = append(groups, group)
+			group, tokens = nil, 0
+		}
+		group = append(group, part)
+		tokens += partTokens
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// truncate shortens a prompt to the input budget, for chunks too long to summarize whole
+func (s *summarizationService) truncate(prompt string) string {
+	if s.tokenizer.CountTokens(prompt) <= s.config.MaxInputTokens {
+		return prompt
+	}
+	runes := []rune(prompt)
+	low, high := 0, len(runes)
+	for low < high {
+		mid := (low + high + 1) / 2
+		if s.tokenizer.CountTokens(string(runes[:mid])) <= s.config.MaxInputTokens {
+			low = mid
+		} else {
+			high = mid - 1
+		}
+	}
+	return string(runes[:low])
+}
+
+// complete generates one summary
+func (s *summarizationService) complete(ctx context.Context, prompt string) (string, string, error) {
+	completion, err := s.provider.Complete(ctx, &CompletionRequest{
+		System:    summarySystemPrompt,
+		Prompt:    prompt,
+		MaxTokens: s.config.MaxTokens,
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate summary: %w", err)
+	}
+	return strings.TrimSpace(completion.Text), completion.Model, nil
+}
+
+// summarySourceText is the text a short chunk is summarized by: its contents followed by
+// its children's summaries
+func summarySourceText(contents string, parts []string) string {
+	if len(parts) == 0 {
+		return contents
+	}
+	lines := append([]string{contents}, parts...)
+	return strings.Join(lines, "\n- ")
+}
+
+// buildSummaryPrompt asks for the summary of a chunk, or of a page or block with the
+// summaries of the blocks below it
+func buildSummaryPrompt(chunk *models.UnifiedChunkRecord, parts []string) string {
+	if len(parts) == 0 {
+		return "Summarize this note:\n\n" + chunk.Contents
+	}
+
+	var prompt strings.Builder
+	if chunk.IsPage {
+		prompt.WriteString("Summarize this page from its title and the summaries of its blocks.\n\nPage: ")
+	} else {
+		prompt.WriteString("Summarize this block from its text and the summaries of the blocks nested under it.\n\nBlock: ")
+	}
+	prompt.WriteString(chunk.Contents)
+	prompt.WriteString("\n")
+	for _, part := range parts {
+		prompt.WriteString("\n- ")
+		prompt.WriteString(part)
+	}
+	return prompt.String()
+}
+
+// buildSectionsPrompt asks to condense consecutive block summaries of a long page
+func buildSectionsPrompt(parts []string) string {
+	var prompt strings.Builder
+	prompt.WriteString("Combine these summaries of consecutive blocks of one page into a single summary:\n")
+	for _, part := range parts {
+		prompt.WriteString("\n- ")
+		prompt.WriteString(part)
+	}
+	return prompt.String()
+}
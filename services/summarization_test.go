@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// countingCompletionProvider answers every prompt with a short summary and keeps the prompts
+type countingCompletionProvider struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *countingCompletionProvider) Name() string { return "counting" }
+
+func (p *countingCompletionProvider) Complete(ctx context.Context, req *CompletionRequest) (*CompletionResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, req.Prompt)
+	return &CompletionResponse{Text: "summary", Model: "counting-model"}, nil
+}
+
+func summaryChunk(id, parent, contents string) models.UnifiedChunkRecord {
+	chunk := models.UnifiedChunkRecord{ChunkID: id, Contents: contents}
+	if parent != "" {
+		chunk.Parent = &parent
+	}
+	return chunk
+}
+
+func TestBuildSummaryTree(t *testing.T) {
+	page := summaryChunk("page", "", "Reading list")
+	page.IsPage = true
+	descendants := []models.UnifiedChunkRecord{
+		summaryChunk("a", "page", "first book"),
+		summaryChunk("b", "page", "second book"),
+		summaryChunk("a1", "a", "a quote"),
+		summaryChunk("x1", "hidden", "block under a filtered parent"),
+	}
+
+	root, levels := buildSummaryTree(&page, descendants)
+	require.Len(t, levels, 3)
+	assert.Len(t, levels[1], 2)
+	assert.Len(t, root.children, 2)
+	assert.Equal(t, 3, root.descendants)
+	assert.Equal(t, 1, root.children[0].descendants)
+
+	// Editing a nested block changes the hashes of every summary above it only
+	edited := append([]models.UnifiedChunkRecord(nil), descendants...)
+	edited[2].Contents = "another quote"
+	editedRoot, _ := buildSummaryTree(&page, edited)
+	assert.NotEqual(t, root.hash, editedRoot.hash)
+	assert.NotEqual(t, root.children[0].hash, editedRoot.children[0].hash)
+	assert.Equal(t, root.children[1].hash, editedRoot.children[1].hash)
+
+	sameRoot, _ := buildSummaryTree(&page, descendants)
+	assert.Equal(t, root.hash, sameRoot.hash)
+}
+
+func TestSummarizationService_MapReduce(t *testing.T) {
+	provider := &countingCompletionProvider{}
+	service := NewSummarizationService(nil, nil, provider, &config.SummaryConfig{
+		MaxTokens:      20,
+		MaxInputTokens: 100,
+		Concurrency:    2,
+	}, NewNoOpMonitor()).(*summarizationService)
+
+	page := summaryChunk("page", "", "Meeting notes")
+	page.IsPage = true
+	parts := make([]string, 12)
+	for i := range parts {
+		parts[i] = strings.Repeat("decision ", 20)
+	}
+
+	summary, model, made, err := service.generate(context.Background(), &page, parts)
+	require.NoError(t, err)
+	assert.Equal(t, "summary", summary)
+	assert.Equal(t, "counting-model", model)
+	assert.Equal(t, len(provider.prompts), made)
+	assert.Greater(t, made, 1, "long pages are condensed before the rollup")
+
+	last := provider.prompts[len(provider.prompts)-1]
+	assert.True(t, strings.HasPrefix(last, "Summarize this page"))
+	for _, prompt := range provider.prompts {
+		assert.LessOrEqual(t, service.tokenizer.CountTokens(prompt), service.config.MaxInputTokens+service.tokenizer.CountTokens(parts[0]))
+	}
+}
+
+func TestSummarizationService_ShortTextIsItsOwnSummary(t *testing.T) {
+	provider := &countingCompletionProvider{}
+	service := NewSummarizationService(nil, nil, provider, &config.SummaryConfig{
+		MaxTokens:      20,
+		MaxInputTokens: 100,
+		MinLength:      50,
+		Concurrency:    1,
+	}, NewNoOpMonitor()).(*summarizationService)
+
+	chunk := summaryChunk("a", "", "a short note")
+	root, _ := buildSummaryTree(&chunk, nil)
+	made, err := service.summarizeNode(context.Background(), root, cachedSummary{}, false)
+	require.NoError(t, err)
+	assert.Zero(t, made)
+	assert.Equal(t, "a short note", root.summary)
+	assert.Empty(t, root.model)
+
+	made, err = service.summarizeNode(context.Background(), root, cachedSummary{hash: root.hash, summary: "cached", model: "m"}, false)
+	require.NoError(t, err)
+	assert.Zero(t, made)
+	assert.True(t, root.cached)
+	assert.Equal(t, "cached", root.summary)
+	assert.Empty(t, provider.prompts)
+}
+
+func TestSummarizationService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	chunks := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	provider := &countingCompletionProvider{}
+	service := NewSummarizationService(db, chunks, provider, &config.SummaryConfig{
+		MaxTokens:      50,
+		MaxInputTokens: 1000,
+		MinLength:      10,
+		Concurrency:    2,
+	}, NewNoOpMonitor())
+
+	page := &models.UnifiedChunkRecord{Contents: "summary-test-page", IsPage: true}
+	require.NoError(t, chunks.CreateChunk(ctx, page))
+	defer chunks.DeleteChunk(ctx, page.ChunkID)
+	block := &models.UnifiedChunkRecord{Contents: "a block long enough to be summarized", Parent: &page.ChunkID, Page: &page.ChunkID}
+	require.NoError(t, chunks.CreateChunk(ctx, block))
+	defer chunks.DeleteChunk(ctx, block.ChunkID)
+
+	first, err := service.Summarize(ctx, page.ChunkID, models.SummaryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, models.SummaryKindRollup, first.Kind)
+	assert.Equal(t, 2, first.Generated)
+	assert.False(t, first.Cached)
+
+	second, err := service.Summarize(ctx, page.ChunkID, models.SummaryOptions{})
+	require.NoError(t, err)
+	assert.True(t, second.Cached)
+	assert.Zero(t, second.Generated)
+
+	block.Contents = "the block was edited and is summarized again"
+	require.NoError(t, chunks.UpdateChunk(ctx, block))
+	third, err := service.Summarize(ctx, page.ChunkID, models.SummaryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, third.Generated)
+	assert.NotEqual(t, first.SourceHash, third.SourceHash)
+}
@@ -0,0 +1,321 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// supabaseObjectStorage Supabase Storage 物件儲存實作
+type supabaseObjectStorage struct {
+	baseURL    string
+	apiKey     string
+	bucket     string
+	public     bool
+	maxSize    int64
+	httpClient *http.Client
+}
+
+// NewSupabaseObjectStorage 建立 Supabase Storage 物件儲存服務
+func NewSupabaseObjectStorage(cfg config.SupabaseStorageConfig, maxSize int64) (StorageService, error) {
+	if cfg.URL == "" || cfg.APIKey == "" || cfg.Bucket == "" {
+		return nil, fmt.Errorf("%w: supabase storage requires url, api key and bucket", ErrInvalidStorageConfig)
+	}
+	if maxSize <= 0 {
+		maxSize = defaultMaxObjectSize
+	}
+
+	return &supabaseObjectStorage{
+		baseURL: strings.TrimRight(cfg.URL, "/"),
+		apiKey:  cfg.APIKey,
+		bucket:  cfg.Bucket,
+		public:  cfg.Public,
+		maxSize: maxSize,
+		httpClient: &http.Client{
+			Timeout: 60 * time.Second,
+		},
+	}, nil
+}
+
+// Put 上傳物件
+func (s *supabaseObjectStorage) Put(ctx context.Context, key string, r io.Reader, opts *models.PutObjectOptions) (*models.StorageObject, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, err
+	}
+
+	data, contentType, err := readObjectBody(key, r, opts, s.maxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.objectURL("object", key), bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload request: %w", err)
+	}
+	s.authorize(req)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("x-upsert", strconv.FormatBool(opts != nil && opts.Overwrite))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, NewStorageError(string(models.StorageTypeSupabase), "put", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil, fmt.Errorf("%w: %s", ErrObjectExists, key)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewStorageError(string(models.StorageTypeSupabase), "put", key,
+			fmt.Errorf("upload failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	object := &models.StorageObject{
+		Key:          key,
+		Size:         int64(len(data)),
+		ContentType:  contentType,
+		StorageType:  models.StorageTypeSupabase,
+		LastModified: time.Now(),
+	}
+	if s.public {
+		object.URL = s.objectURL("object/public", key)
+	}
+
+	return object, nil
+}
+
+// Get 下載物件
+func (s *supabaseObjectStorage) Get(ctx context.Context, key string) (io.ReadCloser, *models.StorageObject, error) {
+	if err := validateObjectKey(key); err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL("object/authenticated", key), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create download request: %w", err)
+	}
+	s.authorize(req)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, NewStorageError(string(models.StorageTypeSupabase), "get", key, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		if isSupabaseNotFound(resp) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+		}
+		return nil, nil, NewStorageError(string(models.StorageTypeSupabase), "get", key,
+			fmt.Errorf("download failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	object := objectFromResponse(key, models.StorageTypeSupabase, resp)
+	if s.public {
+		object.URL = s.objectURL("object/public", key)
+	}
+
+	return resp.Body, object, nil
+}
+
+// SignedURL 產生有時效的下載 URL
+func (s *supabaseObjectStorage) SignedURL(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if err := validateObjectKey(key); err != nil {
+		return "", err
+	}
+	if expiry < time.Second {
+		return "", fmt.Errorf("signed URL expiry must be at least one second")
+	}
+
+	payload, err := json.Marshal(map[string]int64{"expiresIn": int64(expiry / time.Second)})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal sign request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.objectURL("object/sign", key), bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create sign request: %w", err)
+	}
+	s.authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", NewStorageError(string(models.StorageTypeSupabase), "sign", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if isSupabaseNotFound(resp) {
+			return "", fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+		}
+		return "", NewStorageError(string(models.StorageTypeSupabase), "sign", key,
+			fmt.Errorf("sign failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	var result struct {
+		SignedURL string `json:"signedURL"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode sign response: %w", err)
+	}
+	if result.SignedURL == "" {
+		return "", fmt.Errorf("sign response did not include a URL")
+	}
+
+	// Supabase 回傳相對於 /storage/v1 的路徑
+	if strings.HasPrefix(result.SignedURL, "http://") || strings.HasPrefix(result.SignedURL, "https://") {
+		return result.SignedURL, nil
+	}
+	return s.baseURL + "/storage/v1" + result.SignedURL, nil
+}
+
+// Delete 刪除物件
+func (s *supabaseObjectStorage) Delete(ctx context.Context, key string) error {
+	if err := validateObjectKey(key); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL("object", key), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create delete request: %w", err)
+	}
+	s.authorize(req)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return NewStorageError(string(models.StorageTypeSupabase), "delete", key, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		if isSupabaseNotFound(resp) {
+			return fmt.Errorf("%w: %s", ErrObjectNotFound, key)
+		}
+		return NewStorageError(string(models.StorageTypeSupabase), "delete", key,
+			fmt.Errorf("delete failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	return nil
+}
+
+// List 列出指定前綴下的物件。Supabase 以資料夾為單位列出，前綴的最後一段作為檔名搜尋條件
+func (s *supabaseObjectStorage) List(ctx context.Context, prefix string, limit int) ([]models.StorageObject, error) {
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	folder, search := "", prefix
+	if idx := strings.LastIndex(prefix, "/"); idx >= 0 {
+		folder, search = prefix[:idx], prefix[idx+1:]
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"prefix": folder,
+		"search": search,
+		"limit":  limit,
+		"offset": 0,
+		"sortBy": map[string]string{"column": "name", "order": "asc"},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal list request: %w", err)
+	}
+
+	listURL := fmt.Sprintf("%s/storage/v1/object/list/%s", s.baseURL, url.PathEscape(s.bucket))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, listURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create list request: %w", err)
+	}
+	s.authorize(req)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, NewStorageError(string(models.StorageTypeSupabase), "list", prefix, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, NewStorageError(string(models.StorageTypeSupabase), "list", prefix,
+			fmt.Errorf("list failed with status %d: %s", resp.StatusCode, readStorageError(resp)))
+	}
+
+	var entries []struct {
+		ID        *string   `json:"id"`
+		Name      string    `json:"name"`
+		UpdatedAt time.Time `json:"updated_at"`
+		Metadata  struct {
+			Size     int64  `json:"size"`
+			MimeType string `json:"mimetype"`
+			ETag     string `json:"eTag"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, fmt.Errorf("failed to decode list response: %w", err)
+	}
+
+	objects := make([]models.StorageObject, 0, len(entries))
+	for _, entry := range entries {
+		// 沒有 id 的項目是資料夾
+		if entry.ID == nil {
+			continue
+		}
+		key := path.Join(folder, entry.Name)
+		object := models.StorageObject{
+			Key:          key,
+			Size:         entry.Metadata.Size,
+			ContentType:  entry.Metadata.MimeType,
+			ETag:         strings.Trim(entry.Metadata.ETag, `"`),
+			StorageType:  models.StorageTypeSupabase,
+			LastModified: entry.UpdatedAt,
+		}
+		if s.public {
+			object.URL = s.objectURL("object/public", key)
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// Backend 回傳儲存後端類型
+func (s *supabaseObjectStorage) Backend() models.StorageType {
+	return models.StorageTypeSupabase
+}
+
+// objectURL 建立物件 API URL
+func (s *supabaseObjectStorage) objectURL(endpoint, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return fmt.Sprintf("%s/storage/v1/%s/%s/%s", s.baseURL, endpoint, url.PathEscape(s.bucket), strings.Join(segments, "/"))
+}
+
+// authorize 設定認證標頭
+func (s *supabaseObjectStorage) authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("apikey", s.apiKey)
+}
+
+// isSupabaseNotFound Supabase 對不存在的物件可能回傳 404 或帶 not_found 的 400
+func isSupabaseNotFound(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusNotFound {
+		return true
+	}
+	if resp.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	return strings.Contains(strings.ToLower(readStorageError(resp)), "not_found")
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"semantic-text-processor/models"
+	"sort"
+	"time"
+)
+
+// ============================================================================
+// TAG HIERARCHY OPERATIONS IMPLEMENTATION
+// ============================================================================
+
+// SetTagParent places a tag under a parent tag, or detaches it when parentTagChunkID is empty.
+// The whole subtree below the tag moves with it and the tag_hierarchy closure table is rewritten
+// in a single transaction.
+func (s *unifiedChunkService) SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("set_tag_parent", time.Since(start), 1)
+	}()
+
+	if tagChunkID == parentTagChunkID {
+		return fmt.Errorf("tag cannot be its own parent")
+	}
+
+	if err := s.validateTagChunk(ctx, tagChunkID); err != nil {
+		return err
+	}
+	if parentTagChunkID != "" {
+		if err := s.validateTagChunk(ctx, parentTagChunkID); err != nil {
+			return err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = reparentTagTx(ctx, tx, tagChunkID, parentTagChunkID); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.invalidateTagHierarchyCaches(ctx)
+
+	return nil
+}
+
+// GetTagAncestors returns the broader tags of a tag, nearest parent first
+func (s *unifiedChunkService) GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("get_tag_ancestors", time.Since(start), 0)
+	}()
+
+	query := `
+		SELECT ` + unifiedChunkColumns + `
+		FROM chunks c
+		JOIN tag_hierarchy th ON c.chunk_id = th.ancestor_tag_id
+		WHERE th.descendant_tag_id = $1 AND th.depth > 0
+		ORDER BY th.depth ASC
+	`
+
+	return s.queryTagHierarchy(ctx, fmt.Sprintf("tag_ancestors:%s", tagChunkID), query, tagChunkID)
+}
+
+// GetTagDescendants returns all narrower tags of a tag, closest first
+func (s *unifiedChunkService) GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("get_tag_descendants", time.Since(start), 0)
+	}()
+
+	query := `
+		SELECT ` + unifiedChunkColumns + `
+		FROM chunks c
+		JOIN tag_hierarchy th ON c.chunk_id = th.descendant_tag_id
+		WHERE th.ancestor_tag_id = $1 AND th.depth > 0
+		ORDER BY th.depth ASC, c.contents ASC
+	`
+
+	return s.queryTagHierarchy(ctx, fmt.Sprintf("tag_descendants:%s", tagChunkID), query, tagChunkID)
+}
+
+// GetChunksByTagWithOptions retrieves chunks for a tag, optionally rolling up descendant tags
+func (s *unifiedChunkService) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	if opts == nil || !opts.IncludeDescendants {
+		return s.GetChunksByTag(ctx, tagChunkID)
+	}
+
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("get_chunks_by_tag_rollup", time.Since(start), 0)
+	}()
+
+	cacheKey := fmt.Sprintf("chunks_by_tag:%s:rollup:%d", tagChunkID, opts.MaxDepth)
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
+	}
+
+	if err := s.validateTagChunk(ctx, tagChunkID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT DISTINCT ` + unifiedChunkColumns + `
+		FROM chunks c
+		JOIN chunk_tags ct ON c.chunk_id = ct.source_chunk_id
+		WHERE ct.tag_chunk_id = $1
+		   OR ct.tag_chunk_id IN (
+				SELECT descendant_tag_id FROM tag_hierarchy
+				WHERE ancestor_tag_id = $1 AND depth > 0 AND ($2 = 0 OR depth <= $2)
+		   )
+		ORDER BY c.created_time DESC
+	`
+
+	rows, err := s.reader(ctx).QueryContext(ctx, query, tagChunkID, opts.MaxDepth)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chunks by tag hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	chunks, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if chunks == nil {
+		chunks = []models.UnifiedChunkRecord{}
+	}
+
+	s.chunkListCache.Set(ctx, cacheKey, chunks, 5*time.Minute)
+	s.monitor.RecordQuery("get_chunks_by_tag_rollup", time.Since(start), len(chunks))
+
+	return chunks, nil
+}
+
+// GetTagRollup returns direct and rolled-up chunk counts for a tag and its descendant tags
+func (s *unifiedChunkService) GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error) {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("get_tag_rollup", time.Since(start), 0)
+	}()
+
+	if err := s.validateTagChunk(ctx, tagChunkID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		WITH subtree AS (
+			SELECT $1::uuid AS tag_id, 0 AS depth
+			UNION
+			SELECT descendant_tag_id, depth FROM tag_hierarchy WHERE ancestor_tag_id = $1 AND depth > 0
+		)
+		SELECT s.tag_id, t.contents, s.depth,
+			   (SELECT p.ancestor_tag_id FROM tag_hierarchy p
+			    WHERE p.descendant_tag_id = s.tag_id AND p.depth = 1) AS parent_tag_id,
+			   (SELECT COUNT(*) FROM chunk_tags ct WHERE ct.tag_chunk_id = s.tag_id) AS direct_count,
+			   (SELECT COUNT(DISTINCT ct.source_chunk_id) FROM chunk_tags ct
+			    WHERE ct.tag_chunk_id = s.tag_id
+			       OR ct.tag_chunk_id IN (SELECT descendant_tag_id FROM tag_hierarchy WHERE ancestor_tag_id = s.tag_id)
+			   ) AS rollup_count
+		FROM subtree s
+		JOIN chunks t ON t.chunk_id = s.tag_id
+		ORDER BY s.depth ASC, t.contents ASC
+	`
+
+	rows, err := s.reader(ctx).QueryContext(ctx, query, tagChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag rollup: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []models.TagRollup
+	for rows.Next() {
+		var node models.TagRollup
+		var parentID sql.NullString
+		if err := rows.Scan(&node.TagChunkID, &node.TagContent, &node.Depth, &parentID, &node.DirectCount, &node.RollupCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag rollup row: %w", err)
+		}
+		if parentID.Valid {
+			node.ParentTagID = &parentID.String
+		}
+		nodes = append(nodes, node)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tag rollup rows: %w", err)
+	}
+
+	return buildTagRollupTree(tagChunkID, nodes), nil
+}
+
+// reparentTagTx rewrites the closure table so that tagChunkID and its subtree sit below
+// parentTagChunkID. An empty parent detaches the subtree.
+func reparentTagTx(ctx context.Context, tx *sql.Tx, tagChunkID, parentTagChunkID string) error {
+	var err error
+
+	// Make sure both tags have their depth 0 self rows
+	for _, id := range []string{tagChunkID, parentTagChunkID} {
+		if id == "" {
+			continue
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO tag_hierarchy (ancestor_tag_id, descendant_tag_id, depth) VALUES ($1, $1, 0) ON CONFLICT DO NOTHING",
+			id)
+		if err != nil {
+			return fmt.Errorf("failed to ensure tag hierarchy self row: %w", err)
+		}
+	}
+
+	if parentTagChunkID != "" {
+		// Reject cycles: the new parent must not sit below the tag being moved
+		var isDescendant bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM tag_hierarchy WHERE ancestor_tag_id = $1 AND descendant_tag_id = $2)",
+			tagChunkID, parentTagChunkID).Scan(&isDescendant)
+		if err != nil {
+			return fmt.Errorf("failed to check for circular tag hierarchy: %w", err)
+		}
+		if isDescendant {
+			return fmt.Errorf("cannot place tag under its own descendant: circular tag hierarchy detected")
+		}
+	}
+
+	// Detach the subtree from its current ancestors
+	_, err = tx.ExecContext(ctx, `
+		DELETE FROM tag_hierarchy
+		WHERE descendant_tag_id IN (SELECT descendant_tag_id FROM tag_hierarchy WHERE ancestor_tag_id = $1)
+		  AND ancestor_tag_id NOT IN (SELECT descendant_tag_id FROM tag_hierarchy WHERE ancestor_tag_id = $1)`,
+		tagChunkID)
+	if err != nil {
+		return fmt.Errorf("failed to detach tag subtree: %w", err)
+	}
+
+	// Attach the subtree below every ancestor of the new parent
+	if parentTagChunkID != "" {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO tag_hierarchy (ancestor_tag_id, descendant_tag_id, depth)
+			SELECT super.ancestor_tag_id, sub.descendant_tag_id, super.depth + sub.depth + 1
+			FROM tag_hierarchy super
+			CROSS JOIN tag_hierarchy sub
+			WHERE super.descendant_tag_id = $1 AND sub.ancestor_tag_id = $2
+			ON CONFLICT (ancestor_tag_id, descendant_tag_id) DO UPDATE SET depth = EXCLUDED.depth`,
+			parentTagChunkID, tagChunkID)
+		if err != nil {
+			return fmt.Errorf("failed to attach tag subtree: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// buildTagRollupTree assembles flat rollup rows into a tree rooted at rootID
+func buildTagRollupTree(rootID string, nodes []models.TagRollup) *models.TagRollup {
+	childrenOf := make(map[string][]models.TagRollup)
+	var root *models.TagRollup
+	for i := range nodes {
+		if nodes[i].TagChunkID == rootID {
+			root = &nodes[i]
+			continue
+		}
+		if nodes[i].ParentTagID != nil {
+			parentID := *nodes[i].ParentTagID
+			childrenOf[parentID] = append(childrenOf[parentID], nodes[i])
+		}
+	}
+
+	if root == nil {
+		return nil
+	}
+
+	var attach func(node *models.TagRollup)
+	attach = func(node *models.TagRollup) {
+		children := childrenOf[node.TagChunkID]
+		sort.SliceStable(children, func(i, j int) bool {
+			return children[i].TagContent < children[j].TagContent
+		})
+		for i := range children {
+			attach(&children[i])
+		}
+		node.Children = children
+	}
+
+	result := *root
+	attach(&result)
+	return &result
+}
+
+// validateTagChunk ensures the chunk exists and is a tag
+func (s *unifiedChunkService) validateTagChunk(ctx context.Context, tagChunkID string) error {
+	var isTag bool
+	err := s.db.QueryRowContext(ctx, "SELECT is_tag FROM chunks WHERE chunk_id = $1", tagChunkID).Scan(&isTag)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("tag chunk not found: %s", tagChunkID)
+		}
+		return fmt.Errorf("failed to validate tag chunk %s: %w", tagChunkID, err)
+	}
+	if !isTag {
+		return fmt.Errorf("chunk %s is not a tag", tagChunkID)
+	}
+	return nil
+}
+
+// queryTagHierarchy runs a cached tag hierarchy lookup
+func (s *unifiedChunkService) queryTagHierarchy(ctx context.Context, cacheKey, query string, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	if tags, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return tags, nil
+	}
+
+	if err := s.validateTagChunk(ctx, tagChunkID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.reader(ctx).QueryContext(ctx, query, tagChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tag hierarchy: %w", err)
+	}
+	defer rows.Close()
+
+	tags, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if tags == nil {
+		tags = []models.UnifiedChunkRecord{}
+	}
+
+	s.chunkListCache.Set(ctx, cacheKey, tags, 5*time.Minute)
+
+	return tags, nil
+}
+
+// invalidateTagHierarchyCaches drops every cache entry derived from the tag hierarchy
+func (s *unifiedChunkService) invalidateTagHierarchyCaches(ctx context.Context) {
+	s.markWrite()
+
+	patterns := []string{
+		"tag_ancestors:*",
+		"tag_descendants:*",
+		"chunks_by_tag:*",
+		"chunks_by_tags:*",
+	}
+
+	for _, pattern := range patterns {
+		s.cache.DeletePattern(ctx, pattern)
+	}
+}
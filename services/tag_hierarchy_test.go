@@ -0,0 +1,44 @@
+package services
+
+import (
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTagRollupTree(t *testing.T) {
+	programming := "programming"
+	golang := "golang"
+
+	nodes := []models.TagRollup{
+		{TagChunkID: programming, TagContent: "programming", Depth: 0, DirectCount: 1, RollupCount: 6},
+		{TagChunkID: golang, TagContent: "golang", ParentTagID: &programming, Depth: 1, DirectCount: 3, RollupCount: 4},
+		{TagChunkID: "python", TagContent: "python", ParentTagID: &programming, Depth: 1, DirectCount: 1, RollupCount: 1},
+		{TagChunkID: "generics", TagContent: "generics", ParentTagID: &golang, Depth: 2, DirectCount: 1, RollupCount: 1},
+	}
+
+	t.Run("nested children", func(t *testing.T) {
+		root := buildTagRollupTree(programming, nodes)
+		require.NotNil(t, root)
+		assert.Equal(t, 6, root.RollupCount)
+		require.Len(t, root.Children, 2)
+		assert.Equal(t, "golang", root.Children[0].TagContent)
+		assert.Equal(t, "python", root.Children[1].TagContent)
+		require.Len(t, root.Children[0].Children, 1)
+		assert.Equal(t, "generics", root.Children[0].Children[0].TagContent)
+	})
+
+	t.Run("subtree root", func(t *testing.T) {
+		root := buildTagRollupTree(golang, nodes[1:])
+		require.NotNil(t, root)
+		require.Len(t, root.Children, 1)
+		assert.Equal(t, "generics", root.Children[0].TagChunkID)
+	})
+
+	t.Run("missing root", func(t *testing.T) {
+		assert.Nil(t, buildTagRollupTree("unknown", nodes))
+	})
+}
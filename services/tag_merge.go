@@ -0,0 +1,231 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"semantic-text-processor/models"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ============================================================================
+// TAG RENAME AND MERGE OPERATIONS IMPLEMENTATION
+// ============================================================================
+
+// RenameTag changes the display content of a tag chunk. Tagged chunks store tag chunk IDs,
+// so their tags arrays stay valid; the rename fails if another tag already uses the new name.
+func (s *unifiedChunkService) RenameTag(ctx context.Context, tagChunkID, newName string) error {
+	start := time.Now()
+	defer func() {
+		s.monitor.RecordQuery("rename_tag", time.Since(start), 1)
+	}()
+
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("new tag name is required")
+	}
+
+	if err := s.validateTagChunk(ctx, tagChunkID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existingID string
+	err = tx.QueryRowContext(ctx,
+		"SELECT chunk_id FROM chunks WHERE is_tag = true AND contents = $1 AND chunk_id != $2 LIMIT 1",
+		newName, tagChunkID).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("tag already exists: %s (%s); merge the tags instead", newName, existingID)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check for existing tag: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		"UPDATE chunks SET contents = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
+		newName, tagChunkID)
+	if err != nil {
+		return fmt.Errorf("failed to rename tag: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	s.invalidateTagRewriteCaches(ctx)
+
+	return nil
+}
+
+// MergeTags folds the source tags into the target tag. Every chunk tagged with a source tag is
+// retagged with the target, child tags are moved under the target, and the source tag chunks
+// are deleted, all in a single transaction.
+func (s *unifiedChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	start := time.Now()
+	result := &models.TagMergeResult{TargetTagID: targetTagID}
+	defer func() {
+		s.monitor.RecordQuery("merge_tags", time.Since(start), result.AffectedChunks)
+	}()
+
+	if targetTagID == "" {
+		return nil, fmt.Errorf("target tag ID is required")
+	}
+
+	// Deduplicate sources and ignore the target itself
+	seen := map[string]bool{targetTagID: true}
+	sources := make([]string, 0, len(sourceTagIDs))
+	for _, id := range sourceTagIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		sources = append(sources, id)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("at least one source tag different from the target is required")
+	}
+
+	for _, id := range append([]string{targetTagID}, sources...) {
+		if err := s.validateTagChunk(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The target cannot live below a tag that is about to disappear
+	var targetIsDescendant bool
+	err = tx.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM tag_hierarchy WHERE ancestor_tag_id = ANY($1) AND descendant_tag_id = $2 AND depth > 0)",
+		pq.Array(sources), targetTagID).Scan(&targetIsDescendant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check tag hierarchy: %w", err)
+	}
+	if targetIsDescendant {
+		return nil, fmt.Errorf("cannot merge tags into a descendant tag: circular tag hierarchy detected")
+	}
+
+	// Move direct child tags of the sources under the target
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT descendant_tag_id FROM tag_hierarchy
+		WHERE ancestor_tag_id = ANY($1) AND depth = 1 AND NOT (descendant_tag_id = ANY($1))`,
+		pq.Array(sources))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query child tags: %w", err)
+	}
+	var childTags []string
+	for rows.Next() {
+		var childID string
+		if err := rows.Scan(&childID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan child tag: %w", err)
+		}
+		childTags = append(childTags, childID)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating child tags: %w", err)
+	}
+
+	for _, childID := range childTags {
+		if err = reparentTagTx(ctx, tx, childID, targetTagID); err != nil {
+			return nil, err
+		}
+	}
+	result.ReparentedTags = len(childTags)
+
+	// Rewrite the auxiliary table first so the target relationships exist even without triggers
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO chunk_tags (source_chunk_id, tag_chunk_id)
+		SELECT DISTINCT source_chunk_id, $2::uuid FROM chunk_tags WHERE tag_chunk_id = ANY($1)
+		ON CONFLICT DO NOTHING`,
+		pq.Array(sources), targetTagID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite chunk tag relationships: %w", err)
+	}
+
+	// Replace source tag IDs with the target in every tags array, collapsing duplicates
+	updateResult, err := tx.ExecContext(ctx, `
+		UPDATE chunks SET
+			tags = (
+				SELECT COALESCE(jsonb_agg(DISTINCT CASE WHEN t = ANY($1::text[]) THEN $2::text ELSE t END), '[]'::jsonb)
+				FROM jsonb_array_elements_text(chunks.tags) AS t
+			),
+			last_updated = NOW(),
+			version = version + 1
+		WHERE tags ?| $1::text[]`,
+		pq.Array(sources), targetTagID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewrite chunk tags: %w", err)
+	}
+	affected, err := updateResult.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	result.AffectedChunks = int(affected)
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM chunk_tags WHERE tag_chunk_id = ANY($1)", pq.Array(sources))
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove source tag relationships: %w", err)
+	}
+
+	// Keep any content nested under the source tag chunks
+	var movedChildren pq.StringArray
+	err = tx.QueryRowContext(ctx, `
+		WITH moved AS (
+			UPDATE chunks SET parent = $2, last_updated = NOW(), version = version + 1 WHERE parent = ANY($1) RETURNING chunk_id
+		)
+		SELECT COALESCE(array_agg(chunk_id), '{}') FROM moved`,
+		pq.Array(sources), targetTagID).Scan(&movedChildren)
+	if err != nil {
+		return nil, fmt.Errorf("failed to move children of source tags: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM chunks WHERE chunk_id = ANY($1) AND is_tag = true", pq.Array(sources))
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete source tags: %w", err)
+	}
+
+	if len(movedChildren) > 0 {
+		if _, err = rebuildSubtreeHierarchyTx(ctx, tx, movedChildren); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	result.MergedTagIDs = sources
+	s.invalidateTagRewriteCaches(ctx)
+
+	return result, nil
+}
+
+// invalidateTagRewriteCaches drops caches affected by renaming or merging tags.
+// Any chunk may carry one of the tags, so per-chunk entries are cleared wholesale.
+func (s *unifiedChunkService) invalidateTagRewriteCaches(ctx context.Context) {
+	patterns := []string{
+		"chunk:*",
+		"chunk_tags:*",
+		"chunk_children:*",
+	}
+
+	for _, pattern := range patterns {
+		s.cache.DeletePattern(ctx, pattern)
+	}
+
+	s.invalidateTagHierarchyCaches(ctx)
+}
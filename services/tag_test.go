@@ -54,6 +54,7 @@ func (m *MockSupabaseClientForTag) DeleteText(ctx context.Context, id string) er
 func (m *MockSupabaseClientForTag) InsertChunk(ctx context.Context, chunk *models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClientForTag) InsertChunks(ctx context.Context, chunks []models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClientForTag) GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error) { return nil, nil }
+func (m *MockSupabaseClientForTag) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) UpdateChunk(ctx context.Context, chunk *models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClientForTag) DeleteChunk(ctx context.Context, id string) error { return nil }
@@ -64,10 +65,13 @@ func (m *MockSupabaseClientForTag) GetAllTemplates(ctx context.Context) ([]model
 func (m *MockSupabaseClientForTag) CreateTemplateInstance(ctx context.Context, req *models.CreateInstanceRequest) (*models.TemplateInstance, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetTemplateInstances(ctx context.Context, templateChunkID string) ([]models.TemplateInstance, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) UpdateSlotValue(ctx context.Context, instanceChunkID, slotName, value string) error { return nil }
+func (m *MockSupabaseClientForTag) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetChunkHierarchy(ctx context.Context, rootChunkID string) (*models.ChunkHierarchy, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetChildrenChunks(ctx context.Context, parentChunkID string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetSiblingChunks(ctx context.Context, chunkID string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) MoveChunk(ctx context.Context, req *models.MoveChunkRequest) error { return nil }
+func (m *MockSupabaseClientForTag) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error { return nil }
+func (m *MockSupabaseClientForTag) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error { return nil }
 func (m *MockSupabaseClientForTag) BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error { return nil }
 func (m *MockSupabaseClientForTag) SearchChunks(ctx context.Context, query string, filters map[string]interface{}) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) SearchByTag(ctx context.Context, tagContent string) ([]models.ChunkWithTags, error) { return nil, nil }
@@ -75,11 +79,14 @@ func (m *MockSupabaseClientForTag) InsertEmbeddings(ctx context.Context, embeddi
 func (m *MockSupabaseClientForTag) SearchSimilar(ctx context.Context, queryVector []float64, limit int) ([]models.SimilarityResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) InsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error { return nil }
 func (m *MockSupabaseClientForTag) InsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error { return nil }
+func (m *MockSupabaseClientForTag) UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error { return nil }
+func (m *MockSupabaseClientForTag) UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error { return nil }
 func (m *MockSupabaseClientForTag) SearchGraph(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error) { return nil, nil }
+func (m *MockSupabaseClientForTag) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) { return nil, nil }
 func (m *MockSupabaseClientForTag) HealthCheck(ctx context.Context) error { return nil }
 
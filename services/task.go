@@ -0,0 +1,814 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+// ============================================================================
+// TASKS
+// ============================================================================
+//
+// A chunk whose first line starts with a marker such as TODO or DONE is a task, written the
+// way Logseq and Org mode write them:
+//
+//	TODO [#A] Pay rent #finance
+//	SCHEDULED: <2026-10-20 Tue 09:00>
+//	DEADLINE: <2026-11-01 Sun .+1m>
+//
+// Tasks are indexed in chunk_tasks as their chunks are written. The due date is the deadline,
+// or the scheduled date without one. Dates without a time are days in the configured time
+// zone. A due date with a repeater moves to its next occurrence when the task is marked DONE,
+// and the task is reopened: "+1w" adds a week to the date, "++1w" adds weeks until the date
+// is in the future and ".+1w" counts a week from today.
+
+var (
+	// ErrInvalidTaskQuery is returned for unknown views and out-of-range limits
+	ErrInvalidTaskQuery = errors.New("invalid task query")
+)
+
+const (
+	defaultTaskListLimit = 100
+	maxTaskListLimit     = 1000
+	// taskReminderBatch caps the reminders sent per check; the rest wait for the next one
+	taskReminderBatch  = 100
+	taskReindexBatch   = 500
+	taskWebhookTimeout = 10 * time.Second
+)
+
+var (
+	taskMarkerPattern    = regexp.MustCompile(`^[[:blank:]]*(?:[-*][[:blank:]]+)?(TODO|DOING|NOW|LATER|WAITING|DONE|CANCELED|CANCELLED)(?:[[:space:]]|$)`)
+	taskPriorityPattern  = regexp.MustCompile(`^\[#[A-Za-z]\]\s*`)
+	taskTimestampPattern = regexp.MustCompile(`(SCHEDULED|DEADLINE):[ \t]*<(\d{4}-\d{2}-\d{2})(?:[ \t]+[A-Za-z]{2,3})?(?:[ \t]+(\d{1,2}:\d{2}))?(?:[ \t]+((?:\.\+|\+\+|\+)\d+[hdwmy]))?>`)
+	taskRepeaterPattern  = regexp.MustCompile(`^(\.\+|\+\+|\+)(\d+)([hdwmy])$`)
+)
+
+// taskMarkerSQLPattern selects task chunks in PostgreSQL, matching taskMarkerPattern
+const taskMarkerSQLPattern = `^[[:blank:]]*([-*][[:blank:]]+)?(TODO|DOING|NOW|LATER|WAITING|DONE|CANCELED|CANCELLED)([[:space:]]|$)`
+
+// taskColumns are the chunk_tasks columns scanned by scanTasks
+const taskColumns = `t.chunk_id, t.page, t.status, t.title, t.tags, t.scheduled_at, t.deadline_at,
+	t.due_at, t.all_day, t.repeat, t.updated_at`
+
+// TaskService indexes the tasks written in chunks and reminds of them when they fall due
+type TaskService interface {
+	// IndexChunk updates the index entry of a written chunk. A recurring task marked DONE
+	// is rescheduled and reopened first.
+	IndexChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error
+	// List returns the open tasks of a view, optionally with a tag
+	List(ctx context.Context, query *models.TaskQuery) (*models.TaskList, error)
+	// Reindex rebuilds the index from chunk contents. Tasks already due are not reminded of.
+	Reindex(ctx context.Context) (*models.TaskReindexResult, error)
+	// SendDueReminders posts a reminder for each task that fell due since its last one and
+	// returns how many were sent
+	SendDueReminders(ctx context.Context) (int, error)
+	// Start sends reminders every reminder interval until Stop is called
+	Start(ctx context.Context)
+	// Stop stops sending reminders
+	Stop()
+}
+
+// taskService implements TaskService
+type taskService struct {
+	db       *sql.DB
+	chunks   UnifiedChunkService
+	pageACLs PageACLService
+	config   config.TaskConfig
+	location *time.Location
+	client   *http.Client
+	monitor  QueryPerformanceMonitor
+	now      func() time.Time
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewTaskService creates a task service. chunks reschedules recurring tasks, so it should
+// not be wrapped by NewTaskTrackingChunkService itself. pageACLs, when set, hides the tasks
+// of pages the caller may not read.
+func NewTaskService(db *sql.DB, chunks UnifiedChunkService, pageACLs PageACLService, cfg config.TaskConfig, monitor QueryPerformanceMonitor) (TaskService, error) {
+	location, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task time zone: %w", err)
+	}
+	return &taskService{
+		db:       db,
+		chunks:   chunks,
+		pageACLs: pageACLs,
+		config:   cfg,
+		location: location,
+		client:   &http.Client{Timeout: taskWebhookTimeout},
+		monitor:  monitor,
+		now:      time.Now,
+	}, nil
+}
+
+// IsTaskContent reports whether chunk contents start with a task marker
+func IsTaskContent(contents string) bool {
+	return taskMarkerPattern.MatchString(contents)
+}
+
+// IndexChunk indexes a task chunk and removes other chunks from the index. Sensitive chunks
+// are never indexed, since their titles would leak.
+func (s *taskService) IndexChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	task, ok := parseTask(chunk.Contents, s.location)
+	if !ok || IsSensitive(chunk.Metadata) {
+		return s.remove(ctx, chunk.ChunkID)
+	}
+	if task.status == models.TaskStatusDone && task.recurring() {
+		rescheduled, err := s.reschedule(ctx, chunk.ChunkID)
+		if err != nil {
+			return err
+		}
+		chunk = rescheduled
+		if task, ok = parseTask(chunk.Contents, s.location); !ok {
+			return s.remove(ctx, chunk.ChunkID)
+		}
+	}
+	return s.upsert(ctx, chunk, task)
+}
+
+// reschedule moves the repeating dates of a recurring task to their next occurrence and
+// reopens it. The chunk is read again so the patch applies to its latest version.
+func (s *taskService) reschedule(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error) {
+	current, err := s.chunks.GetChunk(ctx, chunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recurring task: %w", err)
+	}
+	task, ok := parseTask(current.Contents, s.location)
+	if !ok || task.status != models.TaskStatusDone || !task.recurring() {
+		return current, nil
+	}
+
+	contents := task.reschedule(current.Contents, s.now().In(s.location))
+	patched, err := s.chunks.PatchChunk(ctx, chunkID, &models.ChunkPatch{
+		ExpectedVersion: current.Version,
+		Contents:        &contents,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reschedule recurring task: %w", err)
+	}
+	return patched, nil
+}
+
+func (s *taskService) upsert(ctx context.Context, chunk *models.UnifiedChunkRecord, task *parsedTask) error {
+	start := time.Now()
+	due, allDay, repeat := task.due()
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO chunk_tasks (chunk_id, page, status, title, tags, scheduled_at, deadline_at, due_at, all_day, repeat, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		ON CONFLICT (chunk_id) DO UPDATE SET
+			page = EXCLUDED.page, status = EXCLUDED.status, title = EXCLUDED.title, tags = EXCLUDED.tags,
+			scheduled_at = EXCLUDED.scheduled_at, deadline_at = EXCLUDED.deadline_at, due_at = EXCLUDED.due_at,
+			all_day = EXCLUDED.all_day, repeat = EXCLUDED.repeat, updated_at = NOW()`,
+		chunk.ChunkID, chunk.Page, string(task.status), task.title, pq.Array(task.tags),
+		task.scheduled.value(), task.deadline.value(), due, allDay, repeat)
+	s.monitor.RecordQuery("index_task", time.Since(start), 1)
+	if err != nil {
+		return fmt.Errorf("failed to index task: %w", err)
+	}
+	return nil
+}
+
+func (s *taskService) remove(ctx context.Context, chunkID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM chunk_tasks WHERE chunk_id = $1", chunkID); err != nil {
+		return fmt.Errorf("failed to remove task: %w", err)
+	}
+	return nil
+}
+
+// List returns open tasks by due date, undated ones last. Tasks on pages the caller may not
+// read are dropped after the limit is applied, so a page of results can come up short.
+func (s *taskService) List(ctx context.Context, query *models.TaskQuery) (*models.TaskList, error) {
+	view := query.View
+	if view == "" {
+		view = models.TaskViewOpen
+	}
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultTaskListLimit
+	}
+	if limit < 0 || limit > maxTaskListLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidTaskQuery, maxTaskListLimit)
+	}
+
+	today := s.startOfDay(s.now())
+	tomorrow := today.AddDate(0, 0, 1)
+	conditions := []string{"t.status NOT IN ('DONE', 'CANCELED')"}
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+	switch view {
+	case models.TaskViewOpen:
+	case models.TaskViewOverdue:
+		conditions = append(conditions, "t.due_at < "+arg(today))
+	case models.TaskViewToday:
+		conditions = append(conditions, "t.due_at >= "+arg(today), "t.due_at < "+arg(tomorrow))
+	case models.TaskViewDue:
+		conditions = append(conditions, "t.due_at < "+arg(tomorrow))
+	default:
+		return nil, fmt.Errorf("%w: unknown view %q", ErrInvalidTaskQuery, view)
+	}
+	tag := strings.TrimPrefix(strings.TrimSpace(query.Tag), "#")
+	if tag != "" {
+		placeholder := arg(tag)
+		conditions = append(conditions, `(lower(`+placeholder+`) = ANY(t.tags) OR EXISTS (
+			SELECT 1 FROM chunk_tags ct JOIN chunks tc ON tc.chunk_id = ct.tag_chunk_id
+			WHERE ct.source_chunk_id = t.chunk_id
+				AND (tc.chunk_id::text = `+placeholder+` OR lower(tc.contents) = lower(`+placeholder+`))))`)
+	}
+
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+taskColumns+`
+		FROM chunk_tasks t
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY t.due_at NULLS LAST, t.updated_at DESC
+		LIMIT `+arg(limit), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+	tasks, err := scanTasks(rows)
+	if err != nil {
+		return nil, err
+	}
+	s.monitor.RecordQuery("list_tasks", time.Since(start), len(tasks))
+	s.localize(tasks)
+
+	if s.pageACLs != nil && len(tasks) > 0 {
+		if tasks, err = s.filterReadable(ctx, tasks); err != nil {
+			return nil, err
+		}
+	}
+	return &models.TaskList{View: view, Tag: tag, Tasks: tasks}, nil
+}
+
+// filterReadable drops the tasks on pages the caller may not read
+func (s *taskService) filterReadable(ctx context.Context, tasks []models.Task) ([]models.Task, error) {
+	chunkIDs := make([]string, len(tasks))
+	for i, task := range tasks {
+		chunkIDs[i] = task.ChunkID
+	}
+	readable, err := s.pageACLs.FilterChunkIDs(ctx, chunkIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter tasks: %w", err)
+	}
+	allowed := make(map[string]bool, len(readable))
+	for _, chunkID := range readable {
+		allowed[chunkID] = true
+	}
+	filtered := make([]models.Task, 0, len(readable))
+	for _, task := range tasks {
+		if allowed[task.ChunkID] {
+			filtered = append(filtered, task)
+		}
+	}
+	return filtered, nil
+}
+
+// Reindex parses every chunk starting with a task marker, then removes index entries of
+// chunks that no longer do. Encrypted contents never match, so sensitive chunks stay out.
+func (s *taskService) Reindex(ctx context.Context) (*models.TaskReindexResult, error) {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result := &models.TaskReindexResult{}
+
+	after := ""
+	for {
+		rows, err := s.db.QueryContext(ctx, `
+			SELECT chunk_id, contents, page FROM chunks
+			WHERE contents ~ $1 AND chunk_id::text > $2
+			ORDER BY chunk_id::text LIMIT $3`, taskMarkerSQLPattern, after, taskReindexBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query task chunks: %w", err)
+		}
+		var batch []models.UnifiedChunkRecord
+		for rows.Next() {
+			var chunk models.UnifiedChunkRecord
+			var page sql.NullString
+			if err := rows.Scan(&chunk.ChunkID, &chunk.Contents, &page); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan task chunk: %w", err)
+			}
+			if page.Valid {
+				chunk.Page = &page.String
+			}
+			batch = append(batch, chunk)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error iterating task chunks: %w", err)
+		}
+
+		for i := range batch {
+			// Recurring tasks left DONE are indexed as they are, not rescheduled
+			if task, ok := parseTask(batch[i].Contents, s.location); ok {
+				if err := s.upsert(ctx, &batch[i], task); err != nil {
+					return nil, err
+				}
+				result.Indexed++
+			}
+		}
+		if len(batch) < taskReindexBatch {
+			break
+		}
+		after = batch[len(batch)-1].ChunkID
+	}
+
+	removed, err := s.db.ExecContext(ctx, `
+		DELETE FROM chunk_tasks t USING chunks c
+		WHERE c.chunk_id = t.chunk_id AND c.contents !~ $1`, taskMarkerSQLPattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove stale tasks: %w", err)
+	}
+	if n, err := removed.RowsAffected(); err == nil {
+		result.Removed = int(n)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE chunk_tasks SET notified_due = due_at
+		WHERE notified_due IS NULL AND due_at <= $1`, s.now()); err != nil {
+		return nil, fmt.Errorf("failed to skip reminders of due tasks: %w", err)
+	}
+
+	s.monitor.RecordQuery("reindex_tasks", time.Since(start), result.Indexed)
+	return result, nil
+}
+
+// SendDueReminders claims due tasks by recording their due time as notified, so instances
+// sharing the database send each reminder once, then posts them. Claims of reminders that
+// could not be delivered are released for the next check.
+func (s *taskService) SendDueReminders(ctx context.Context) (int, error) {
+	if s.config.WebhookURL == "" {
+		return 0, nil
+	}
+	start := time.Now()
+	rows, err := s.db.QueryContext(ctx, `
+		UPDATE chunk_tasks t SET notified_due = t.due_at
+		WHERE t.chunk_id IN (
+			SELECT chunk_id FROM chunk_tasks
+			WHERE status NOT IN ('DONE', 'CANCELED') AND due_at <= $1
+				AND notified_due IS DISTINCT FROM due_at
+			ORDER BY due_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED)
+		RETURNING `+taskColumns, s.now(), taskReminderBatch)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim due tasks: %w", err)
+	}
+	tasks, err := scanTasks(rows)
+	rows.Close()
+	if err != nil {
+		return 0, err
+	}
+	s.monitor.RecordQuery("claim_task_reminders", time.Since(start), len(tasks))
+	s.localize(tasks)
+
+	sent := 0
+	var lastErr error
+	for i := range tasks {
+		if err := s.postReminder(ctx, &tasks[i]); err != nil {
+			lastErr = err
+			// ctx may already be done when the post failed because the worker is stopping
+			if _, err := s.db.ExecContext(context.Background(), `
+				UPDATE chunk_tasks SET notified_due = NULL
+				WHERE chunk_id = $1 AND notified_due = $2`, tasks[i].ChunkID, tasks[i].DueAt); err != nil {
+				log.Printf("Warning: failed to release reminder of task %s: %v", tasks[i].ChunkID, err)
+			}
+			continue
+		}
+		sent++
+	}
+	if lastErr != nil {
+		return sent, fmt.Errorf("failed to send %d of %d task reminders: %w", len(tasks)-sent, len(tasks), lastErr)
+	}
+	return sent, nil
+}
+
+// postReminder posts one reminder, signed with the webhook secret when one is configured
+func (s *taskService) postReminder(ctx context.Context, task *models.Task) error {
+	body, err := json.Marshal(models.TaskReminder{Event: models.TaskReminderEvent, Task: *task, SentAt: s.now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to encode task reminder: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create task reminder request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Ink-Event", models.TaskReminderEvent)
+	if s.config.WebhookSecret != "" {
+		req.Header.Set("X-Ink-Signature", SignTaskReminder(s.config.WebhookSecret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post task reminder: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("task webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignTaskReminder returns the X-Ink-Signature header of a reminder body: "sha256=" and the
+// hex HMAC-SHA256 of the body keyed with the webhook secret
+func SignTaskReminder(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Start runs the reminder worker in the background; without a webhook there is nothing to do
+func (s *taskService) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil || s.config.WebhookURL == "" {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go func() {
+		defer close(s.done)
+		s.run(ctx)
+	}()
+}
+
+// Stop stops the worker and waits for the current check
+func (s *taskService) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		s.cancel()
+		<-s.done
+		s.cancel = nil
+	}
+}
+
+func (s *taskService) run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.ReminderInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.SendDueReminders(ctx); err != nil {
+				log.Printf("Warning: %v", err)
+			}
+		}
+	}
+}
+
+// localize moves task dates to the task time zone, so all-day dates read as their day
+func (s *taskService) localize(tasks []models.Task) {
+	for i := range tasks {
+		for _, at := range []*time.Time{tasks[i].ScheduledAt, tasks[i].DeadlineAt, tasks[i].DueAt} {
+			if at != nil {
+				*at = at.In(s.location)
+			}
+		}
+	}
+}
+
+// startOfDay returns midnight of t's day in the task time zone
+func (s *taskService) startOfDay(t time.Time) time.Time {
+	t = t.In(s.location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+}
+
+// scanTasks reads rows selected with taskColumns
+func scanTasks(rows *sql.Rows) ([]models.Task, error) {
+	tasks := []models.Task{}
+	for rows.Next() {
+		var task models.Task
+		var page sql.NullString
+		var status string
+		var scheduled, deadline, due sql.NullTime
+		if err := rows.Scan(&task.ChunkID, &page, &status, &task.Title, pq.Array(&task.Tags),
+			&scheduled, &deadline, &due, &task.AllDay, &task.Repeat, &task.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan task: %w", err)
+		}
+		task.Status = models.TaskStatus(status)
+		if page.Valid {
+			task.Page = &page.String
+		}
+		task.ScheduledAt = nullTimePtr(scheduled)
+		task.DeadlineAt = nullTimePtr(deadline)
+		task.DueAt = nullTimePtr(due)
+		tasks = append(tasks, task)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// parsedTask is a task read from chunk contents
+type parsedTask struct {
+	status models.TaskStatus
+	// marker is the position of the marker in the contents
+	marker    [2]int
+	title     string
+	tags      []string
+	scheduled *taskTimestamp
+	deadline  *taskTimestamp
+}
+
+// taskTimestamp is a SCHEDULED or DEADLINE timestamp and its position in the contents
+type taskTimestamp struct {
+	kind   string
+	at     time.Time
+	allDay bool
+	repeat string
+	span   [2]int
+}
+
+// value returns the time for a nullable column
+func (t *taskTimestamp) value() interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.at
+}
+
+// parseTask reads the task in contents. Dates that do not exist, such as 2026-02-30, are
+// ignored, and the first timestamp of each kind wins.
+func parseTask(contents string, location *time.Location) (*parsedTask, bool) {
+	marker := taskMarkerPattern.FindStringSubmatchIndex(contents)
+	if marker == nil {
+		return nil, false
+	}
+	task := &parsedTask{
+		status: models.TaskStatus(contents[marker[2]:marker[3]]),
+		marker: [2]int{marker[2], marker[3]},
+	}
+	if task.status == "CANCELLED" {
+		task.status = models.TaskStatusCanceled
+	}
+
+	for _, match := range taskTimestampPattern.FindAllStringSubmatchIndex(contents, -1) {
+		timestamp, ok := parseTaskTimestamp(contents, match, location)
+		if !ok {
+			continue
+		}
+		switch {
+		case timestamp.kind == "SCHEDULED" && task.scheduled == nil:
+			task.scheduled = timestamp
+		case timestamp.kind == "DEADLINE" && task.deadline == nil:
+			task.deadline = timestamp
+		}
+	}
+
+	line := contents[marker[3]:]
+	if end := strings.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	}
+	line = taskTimestampPattern.ReplaceAllString(line, "")
+	line = taskPriorityPattern.ReplaceAllString(strings.TrimSpace(line), "")
+	title, tags := extractInlineTags(line)
+	task.title = title
+	for _, tag := range tags {
+		task.tags = append(task.tags, strings.ToLower(tag))
+	}
+	if task.tags == nil {
+		task.tags = []string{}
+	}
+	return task, true
+}
+
+func parseTaskTimestamp(contents string, match []int, location *time.Location) (*taskTimestamp, bool) {
+	group := func(i int) string {
+		if match[2*i] < 0 {
+			return ""
+		}
+		return contents[match[2*i]:match[2*i+1]]
+	}
+	day, err := time.ParseInLocation(models.JournalDateLayout, group(2), location)
+	if err != nil {
+		return nil, false
+	}
+	timestamp := &taskTimestamp{kind: group(1), at: day, allDay: true, repeat: group(4), span: [2]int{match[0], match[1]}}
+	if clock := group(3); clock != "" {
+		parsed, err := time.Parse("15:04", clock)
+		if err != nil {
+			return nil, false
+		}
+		timestamp.at = time.Date(day.Year(), day.Month(), day.Day(), parsed.Hour(), parsed.Minute(), 0, 0, location)
+		timestamp.allDay = false
+	}
+	return timestamp, true
+}
+
+// due returns the due time of the task, whether it is a whole day and its repeater
+func (t *parsedTask) due() (interface{}, bool, string) {
+	timestamp := t.deadline
+	if timestamp == nil {
+		timestamp = t.scheduled
+	}
+	if timestamp == nil {
+		return nil, false, ""
+	}
+	return timestamp.at, timestamp.allDay, timestamp.repeat
+}
+
+// recurring reports whether any date of the task repeats
+func (t *parsedTask) recurring() bool {
+	return (t.scheduled != nil && t.scheduled.repeat != "") || (t.deadline != nil && t.deadline.repeat != "")
+}
+
+// reschedule returns contents with the marker set back to TODO and each repeating date moved
+// to its next occurrence after now. Edits are made from the end so earlier spans stay valid.
+func (t *parsedTask) reschedule(contents string, now time.Time) string {
+	var timestamps []*taskTimestamp
+	for _, timestamp := range []*taskTimestamp{t.scheduled, t.deadline} {
+		if timestamp != nil && timestamp.repeat != "" {
+			timestamps = append(timestamps, timestamp)
+		}
+	}
+	if len(timestamps) == 2 && timestamps[0].span[0] < timestamps[1].span[0] {
+		timestamps[0], timestamps[1] = timestamps[1], timestamps[0]
+	}
+	for _, timestamp := range timestamps {
+		next := nextTaskOccurrence(timestamp, now)
+		contents = contents[:timestamp.span[0]] + formatTaskTimestamp(timestamp.kind, next, timestamp.allDay, timestamp.repeat) + contents[timestamp.span[1]:]
+	}
+	return contents[:t.marker[0]] + string(models.TaskStatusTodo) + contents[t.marker[1]:]
+}
+
+// nextTaskOccurrence applies a repeater the way Org mode does. now must be in the time zone
+// of the timestamp.
+func nextTaskOccurrence(timestamp *taskTimestamp, now time.Time) time.Time {
+	match := taskRepeaterPattern.FindStringSubmatch(timestamp.repeat)
+	if match == nil {
+		return timestamp.at
+	}
+	n, _ := strconv.Atoi(match[2])
+	if n <= 0 {
+		return timestamp.at
+	}
+	step := func(t time.Time) time.Time {
+		switch match[3] {
+		case "h":
+			return t.Add(time.Duration(n) * time.Hour)
+		case "d":
+			return t.AddDate(0, 0, n)
+		case "w":
+			return t.AddDate(0, 0, 7*n)
+		case "m":
+			return t.AddDate(0, n, 0)
+		default:
+			return t.AddDate(n, 0, 0)
+		}
+	}
+
+	switch match[1] {
+	case "++":
+		// Whole-day dates move past today, timed ones past now
+		threshold := now
+		if timestamp.allDay {
+			threshold = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		}
+		next := step(timestamp.at)
+		for !next.After(threshold) {
+			next = step(next)
+		}
+		return next
+	case ".+":
+		if match[3] == "h" {
+			return step(now.Truncate(time.Minute))
+		}
+		at := timestamp.at
+		return step(time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, now.Location()))
+	default:
+		return step(timestamp.at)
+	}
+}
+
+func formatTaskTimestamp(kind string, at time.Time, allDay bool, repeat string) string {
+	formatted := at.Format("2006-01-02 Mon")
+	if !allDay {
+		formatted += at.Format(" 15:04")
+	}
+	if repeat != "" {
+		formatted += " " + repeat
+	}
+	return kind + ": <" + formatted + ">"
+}
+
+// taskTrackingChunkService keeps the task index in step with chunk writes
+type taskTrackingChunkService struct {
+	UnifiedChunkService
+	tasks TaskService
+}
+
+// NewTaskTrackingChunkService wraps a UnifiedChunkService so that written chunks are indexed
+// as tasks. Index entries are removed with their chunks by the database. Indexing failures
+// are logged and do not fail the write; Reindex repairs the index.
+func NewTaskTrackingChunkService(base UnifiedChunkService, tasks TaskService) UnifiedChunkService {
+	return &taskTrackingChunkService{
+		UnifiedChunkService: base,
+		tasks:               tasks,
+	}
+}
+
+// CreateChunk creates a chunk and indexes it if it is a task
+func (s *taskTrackingChunkService) CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.CreateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	if IsTaskContent(chunk.Contents) {
+		s.track(ctx, chunk)
+	}
+	return nil
+}
+
+// UpdateChunk updates a chunk and its index entry
+func (s *taskTrackingChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.UpdateChunk(ctx, chunk); err != nil {
+		return err
+	}
+	s.track(ctx, chunk)
+	return nil
+}
+
+// PatchChunk patches a chunk and updates its index entry when its contents or sensitivity changed
+func (s *taskTrackingChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.UnifiedChunkService.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return nil, err
+	}
+	flagChanged := metadataKeyChanged(patch, SensitiveMetadataKey)
+	if patch.Contents != nil || flagChanged || patch.Page != nil {
+		s.track(ctx, chunk)
+	}
+	return chunk, nil
+}
+
+// MergeChunks merges two chunks and indexes the target, whose contents usually changed
+func (s *taskTrackingChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	result, err := s.UnifiedChunkService.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if result.Chunk != nil {
+		s.track(ctx, result.Chunk)
+	}
+	return result, nil
+}
+
+// BatchCreateChunks creates chunks and indexes the tasks among them
+func (s *taskTrackingChunkService) BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchCreateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		if IsTaskContent(chunks[i].Contents) {
+			s.track(ctx, &chunks[i])
+		}
+	}
+	return nil
+}
+
+// BatchUpdateChunks updates chunks and their index entries
+func (s *taskTrackingChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
+	if err := s.UnifiedChunkService.BatchUpdateChunks(ctx, chunks); err != nil {
+		return err
+	}
+	for i := range chunks {
+		s.track(ctx, &chunks[i])
+	}
+	return nil
+}
+
+func (s *taskTrackingChunkService) track(ctx context.Context, chunk *models.UnifiedChunkRecord) {
+	if err := s.tasks.IndexChunk(ctx, chunk); err != nil {
+		log.Printf("Warning: failed to index task of chunk %s: %v", chunk.ChunkID, err)
+	}
+}
@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+)
+
+func TestParseTask(t *testing.T) {
+	taipei, err := time.LoadLocation("Asia/Taipei")
+	require.NoError(t, err)
+
+	task, ok := parseTask("TODO [#A] Pay rent #Finance #home\nSCHEDULED: <2026-10-20 Tue 09:30>\nDEADLINE: <2026-11-01 Sun .+1m>", taipei)
+	require.True(t, ok)
+	assert.Equal(t, models.TaskStatusTodo, task.status)
+	assert.Equal(t, "Pay rent", task.title)
+	assert.Equal(t, []string{"finance", "home"}, task.tags)
+	require.NotNil(t, task.scheduled)
+	assert.Equal(t, time.Date(2026, 10, 20, 9, 30, 0, 0, taipei), task.scheduled.at)
+	assert.False(t, task.scheduled.allDay)
+	require.NotNil(t, task.deadline)
+	assert.True(t, task.deadline.allDay)
+	assert.Equal(t, ".+1m", task.deadline.repeat)
+
+	due, allDay, repeat := task.due()
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, taipei), due, "the deadline wins")
+	assert.True(t, allDay)
+	assert.Equal(t, ".+1m", repeat)
+	assert.True(t, task.recurring())
+
+	t.Run("markers", func(t *testing.T) {
+		for contents, status := range map[string]models.TaskStatus{
+			"- DOING write report":                 models.TaskStatusDoing,
+			"  LATER\nsecond line":                 models.TaskStatusLater,
+			"CANCELLED old plan":                   models.TaskStatusCanceled,
+			"DONE ship it SCHEDULED: <2026-10-16>": models.TaskStatusDone,
+		} {
+			task, ok := parseTask(contents, time.UTC)
+			require.True(t, ok, contents)
+			assert.Equal(t, status, task.status, contents)
+		}
+		for _, contents := range []string{"", "TODOS for the week", "Remember the TODO list", "todo lowercase"} {
+			_, ok := parseTask(contents, time.UTC)
+			assert.False(t, ok, contents)
+			assert.False(t, IsTaskContent(contents), contents)
+		}
+	})
+
+	t.Run("titles", func(t *testing.T) {
+		task, _ := parseTask("LATER\nsecond line", time.UTC)
+		assert.Empty(t, task.title, "the title is the marker's line")
+		task, _ = parseTask("DONE ship it SCHEDULED: <2026-10-16>", time.UTC)
+		assert.Equal(t, "ship it", task.title)
+		assert.True(t, task.scheduled.allDay)
+	})
+
+	t.Run("invalid dates are ignored", func(t *testing.T) {
+		task, ok := parseTask("TODO file taxes\nDEADLINE: <2026-02-30>\nDEADLINE: <2026-04-15 25:00>", time.UTC)
+		require.True(t, ok)
+		assert.Nil(t, task.deadline)
+		due, _, _ := task.due()
+		assert.Nil(t, due)
+	})
+}
+
+func TestTaskReschedule(t *testing.T) {
+	now := time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name     string
+		contents string
+		want     string
+	}{
+		{
+			name:     "plus adds one interval",
+			contents: "DONE Water plants\nSCHEDULED: <2026-10-01 Thu +1w>",
+			want:     "TODO Water plants\nSCHEDULED: <2026-10-08 Thu +1w>",
+		},
+		{
+			name:     "double plus moves past today",
+			contents: "DONE Water plants\nSCHEDULED: <2026-10-01 Thu ++1w>",
+			want:     "TODO Water plants\nSCHEDULED: <2026-10-22 Thu ++1w>",
+		},
+		{
+			name:     "dot plus counts from today and keeps the time",
+			contents: "- DONE Standup\nSCHEDULED: <2026-10-01 Thu 09:15 .+1d>",
+			want:     "- TODO Standup\nSCHEDULED: <2026-10-17 Sat 09:15 .+1d>",
+		},
+		{
+			name:     "months and dates without repeaters",
+			contents: "DONE Pay rent\nSCHEDULED: <2026-09-25 Fri>\nDEADLINE: <2026-10-01 Thu +1m>",
+			want:     "TODO Pay rent\nSCHEDULED: <2026-09-25 Fri>\nDEADLINE: <2026-11-01 Sun +1m>",
+		},
+		{
+			name:     "both dates repeat",
+			contents: "DONE Review SCHEDULED: <2026-10-14 Wed +2d> DEADLINE: <2026-10-15 Thu 18:00 +2d>",
+			want:     "TODO Review SCHEDULED: <2026-10-16 Fri +2d> DEADLINE: <2026-10-17 Sat 18:00 +2d>",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			task, ok := parseTask(tc.contents, time.UTC)
+			require.True(t, ok)
+			require.True(t, task.recurring())
+			assert.Equal(t, tc.want, task.reschedule(tc.contents, now))
+		})
+	}
+
+	t.Run("timed double plus moves past now", func(t *testing.T) {
+		task, _ := parseTask("DONE Check backups SCHEDULED: <2026-10-16 Fri 10:00 ++2h>", time.UTC)
+		assert.Equal(t, time.Date(2026, 10, 16, 16, 0, 0, 0, time.UTC), nextTaskOccurrence(task.scheduled, now))
+	})
+}
+
+func TestTaskService_WithoutDatabase(t *testing.T) {
+	service, err := NewTaskService(nil, nil, nil, config.TaskConfig{Timezone: "UTC", ReminderInterval: time.Minute}, NewNoOpMonitor())
+	require.NoError(t, err)
+	tasks := service.(*taskService)
+
+	t.Run("queries", func(t *testing.T) {
+		invalid := []models.TaskQuery{
+			{View: "someday"},
+			{Limit: -1},
+			{Limit: maxTaskListLimit + 1},
+		}
+		for _, query := range invalid {
+			_, err := tasks.List(context.Background(), &query)
+			assert.ErrorIs(t, err, ErrInvalidTaskQuery)
+		}
+	})
+
+	t.Run("reminders need a webhook", func(t *testing.T) {
+		sent, err := tasks.SendDueReminders(context.Background())
+		assert.NoError(t, err)
+		assert.Zero(t, sent)
+		tasks.Start(context.Background())
+		tasks.Stop()
+	})
+
+	t.Run("reindexing needs the admin permission", func(t *testing.T) {
+		_, err := tasks.Reindex(contextWithSubject("dave", models.RoleEditor))
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+	})
+
+	t.Run("webhook reminders are signed", func(t *testing.T) {
+		var received models.TaskReminder
+		var signature string
+		webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			signature = r.Header.Get("X-Ink-Signature")
+			assert.Equal(t, SignTaskReminder("s3cret", body), signature)
+			assert.NoError(t, json.Unmarshal(body, &received))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer webhook.Close()
+
+		tasks.config.WebhookURL = webhook.URL
+		tasks.config.WebhookSecret = "s3cret"
+		due := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+		require.NoError(t, tasks.postReminder(context.Background(), &models.Task{ChunkID: "task-1", Status: models.TaskStatusTodo, Title: "Call the bank", DueAt: &due}))
+		assert.Equal(t, models.TaskReminderEvent, received.Event)
+		assert.Equal(t, "Call the bank", received.Task.Title)
+		assert.Contains(t, signature, "sha256=")
+
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer failing.Close()
+		tasks.config.WebhookURL = failing.URL
+		assert.Error(t, tasks.postReminder(context.Background(), &models.Task{ChunkID: "task-1"}))
+	})
+
+	_, err = NewTaskService(nil, nil, nil, config.TaskConfig{Timezone: "Mars/Olympus"}, NewNoOpMonitor())
+	assert.Error(t, err)
+}
+
+func TestTaskService_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	base := NewUnifiedChunkService(db, NewInMemoryCache(100, 5*time.Minute), NewNoOpMonitor())
+	service, err := NewTaskService(db, base, nil, config.TaskConfig{Timezone: "UTC", ReminderInterval: time.Minute}, NewNoOpMonitor())
+	require.NoError(t, err)
+	tasks := service.(*taskService)
+	tasks.now = func() time.Time { return time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC) }
+	chunks := NewTaskTrackingChunkService(base, tasks)
+	ctx := context.Background()
+
+	tag := "tasktest" + time.Now().Format("150405")
+	overdue := &models.UnifiedChunkRecord{Contents: "TODO Renew passport #" + tag + "\nDEADLINE: <2026-10-10 Sat>"}
+	today := &models.UnifiedChunkRecord{Contents: "TODO Water plants #" + tag + "\nSCHEDULED: <2026-10-16 Fri ++1w>"}
+	note := &models.UnifiedChunkRecord{Contents: "Just a note #" + tag}
+	for _, chunk := range []*models.UnifiedChunkRecord{overdue, today, note} {
+		require.NoError(t, chunks.CreateChunk(ctx, chunk))
+		defer base.DeleteChunk(ctx, chunk.ChunkID)
+	}
+
+	list, err := tasks.List(ctx, &models.TaskQuery{View: models.TaskViewOverdue, Tag: tag})
+	require.NoError(t, err)
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, overdue.ChunkID, list.Tasks[0].ChunkID)
+
+	list, err = tasks.List(ctx, &models.TaskQuery{View: models.TaskViewToday, Tag: "#" + tag})
+	require.NoError(t, err)
+	require.Len(t, list.Tasks, 1)
+	assert.Equal(t, "Water plants", list.Tasks[0].Title)
+
+	// Completing a recurring task reopens it on its next date
+	done := "DONE Water plants #" + tag + "\nSCHEDULED: <2026-10-16 Fri ++1w>"
+	_, err = chunks.PatchChunk(ctx, today.ChunkID, &models.ChunkPatch{ExpectedVersion: today.Version, Contents: &done})
+	require.NoError(t, err)
+	rescheduled, err := base.GetChunk(ctx, today.ChunkID)
+	require.NoError(t, err)
+	assert.Equal(t, "TODO Water plants #"+tag+"\nSCHEDULED: <2026-10-23 Fri ++1w>", rescheduled.Contents)
+
+	list, err = tasks.List(ctx, &models.TaskQuery{View: models.TaskViewDue, Tag: tag})
+	require.NoError(t, err)
+	require.Len(t, list.Tasks, 1, "the rescheduled task is no longer due")
+
+	// Reminders are sent once per due time
+	var reminders []models.TaskReminder
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var reminder models.TaskReminder
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&reminder))
+		reminders = append(reminders, reminder)
+	}))
+	defer webhook.Close()
+	tasks.config.WebhookURL = webhook.URL
+	_, err = tasks.SendDueReminders(ctx)
+	require.NoError(t, err)
+	_, err = tasks.SendDueReminders(ctx)
+	require.NoError(t, err)
+	var ours int
+	for _, reminder := range reminders {
+		if reminder.Task.ChunkID == overdue.ChunkID {
+			ours++
+		}
+	}
+	assert.Equal(t, 1, ours)
+}
@@ -2,19 +2,34 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"semantic-text-processor/models"
+	"sort"
+	"strings"
 )
 
+// ErrInvalidTemplateSchema is returned for template schema updates without slots or with
+// blank or duplicate slot names
+var ErrInvalidTemplateSchema = errors.New("invalid template schema")
+
 // templateService implements TemplateService interface
 type templateService struct {
 	supabaseClient SupabaseClient
+	instanceIndex  TemplateInstanceIndex
 }
 
 // NewTemplateService creates a new template service instance
 func NewTemplateService(supabaseClient SupabaseClient) TemplateService {
+	return NewTemplateServiceWithIndex(supabaseClient, nil)
+}
+
+// NewTemplateServiceWithIndex creates a template service that queries instances by slot
+// value through index, or by filtering every instance when index is nil or unavailable
+func NewTemplateServiceWithIndex(supabaseClient SupabaseClient, index TemplateInstanceIndex) TemplateService {
 	return &templateService{
 		supabaseClient: supabaseClient,
+		instanceIndex:  index,
 	}
 }
 
@@ -29,7 +44,15 @@ func (s *templateService) CreateTemplate(ctx context.Context, req *models.Create
 	}
 	
 	// Delegate to Supabase client
-	return s.supabaseClient.CreateTemplate(ctx, req.TemplateName, req.SlotNames)
+	template, err := s.supabaseClient.CreateTemplate(ctx, req.TemplateName, req.SlotNames)
+	if err != nil || req.Layout == "" {
+		return template, err
+	}
+	
+	if err := s.setTemplateLayout(ctx, template.Template, req.Layout); err != nil {
+		return nil, err
+	}
+	return template, nil
 }
 
 // GetTemplate retrieves a template by content
@@ -74,4 +97,102 @@ func (s *templateService) UpdateSlotValue(ctx context.Context, instanceChunkID,
 	
 	// Delegate to Supabase client
 	return s.supabaseClient.UpdateSlotValue(ctx, instanceChunkID, slotName, value)
-}
\ No newline at end of file
+}
+
+// UpdateTemplateSchema sets a template's slots and migrates its instances
+func (s *templateService) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) {
+	if templateChunkID == "" {
+		return nil, fmt.Errorf("%w: template chunk ID is required", ErrInvalidTemplateSchema)
+	}
+	if req == nil || len(req.SlotNames) == 0 {
+		return nil, fmt.Errorf("%w: at least one slot name is required", ErrInvalidTemplateSchema)
+	}
+	
+	seen := make(map[string]bool, len(req.SlotNames))
+	for _, name := range req.SlotNames {
+		if strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("%w: slot names cannot be blank", ErrInvalidTemplateSchema)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: duplicate slot name %s", ErrInvalidTemplateSchema, name)
+		}
+		seen[name] = true
+	}
+	
+	// Delegate to Supabase client
+	migration, err := s.supabaseClient.UpdateTemplateSchema(ctx, templateChunkID, req)
+	if err != nil || req.Layout == nil || req.DryRun {
+		return migration, err
+	}
+	
+	templateChunk, err := s.supabaseClient.GetChunkByID(ctx, templateChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template chunk: %w", err)
+	}
+	if err := s.setTemplateLayout(ctx, templateChunk, *req.Layout); err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+// setTemplateLayout stores the layout instances of a template are rendered with
+func (s *templateService) setTemplateLayout(ctx context.Context, templateChunk *models.ChunkRecord, layout string) error {
+	metadata := make(map[string]interface{}, len(templateChunk.Metadata)+1)
+	for key, value := range templateChunk.Metadata {
+		metadata[key] = value
+	}
+	if layout == "" {
+		delete(metadata, TemplateLayoutMetadataKey)
+	} else {
+		metadata[TemplateLayoutMetadataKey] = layout
+	}
+	templateChunk.Metadata = metadata
+	
+	if err := s.supabaseClient.UpdateChunk(ctx, templateChunk); err != nil {
+		return fmt.Errorf("failed to set template layout: %w", err)
+	}
+	return nil
+}
+
+// TemplateContent returns the chunk content of the template called name
+func TemplateContent(name string) string {
+	if strings.HasSuffix(name, "#template") {
+		return name
+	}
+	return name + "#template"
+}
+
+// TemplateName returns the name a template was created with
+func TemplateName(template *models.ChunkRecord) string {
+	return strings.TrimSuffix(template.Content, "#template")
+}
+
+// TemplateSlotNames returns a template's slot names in slot order
+func TemplateSlotNames(template *models.TemplateWithInstances) []string {
+	names := make([]string, len(template.Slots))
+	for i, slot := range template.Slots {
+		names[i] = strings.TrimPrefix(slot.Content, "#")
+	}
+	return names
+}
+
+// ValidateSlotValues checks that every value fills one of slotNames. Slots without a
+// value are allowed and stay empty.
+func ValidateSlotValues(slotNames []string, values map[string]string) error {
+	known := make(map[string]bool, len(slotNames))
+	for _, name := range slotNames {
+		known[name] = true
+	}
+
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown slots %s: template slots are %s", strings.Join(unknown, ", "), strings.Join(slotNames, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"semantic-text-processor/models"
+)
+
+const (
+	defaultInstanceQueryLimit = 100
+	maxInstanceQueryLimit     = 1000
+)
+
+// ErrInvalidInstanceQuery is returned for instance queries with unknown slots or operators,
+// malformed range bounds or an out-of-range limit
+var ErrInvalidInstanceQuery = errors.New("invalid instance query")
+
+// ErrInstanceIndexUnavailable is returned by a TemplateInstanceIndex whose table has not
+// been created; callers fall back to filtering instances in memory
+var ErrInstanceIndexUnavailable = errors.New("template instance index unavailable")
+
+// TemplateInstanceIndex finds template instances by slot value without loading every
+// instance of the template
+type TemplateInstanceIndex interface {
+	// QueryInstances returns up to limit instances of a template whose slot values match
+	// every filter, newest first, with the template's slot names in slot order
+	QueryInstances(ctx context.Context, templateChunkID string, filters []models.SlotFilter, limit int) ([]models.TemplateInstance, []string, error)
+}
+
+// slotNumberPattern is the number syntax slot values are compared as numbers with; it
+// matches the pattern instance_slot_values_migration.sql indexes numbers with
+var slotNumberPattern = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+// slotDateLayouts are the date formats slot values are compared as dates with. Dates
+// without a zone are UTC.
+var slotDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+const slotDayLayout = "2006-01-02"
+
+// slotPredicate is a validated SlotFilter
+type slotPredicate struct {
+	filter models.SlotFilter
+	// text is the lowercased value of equals and contains
+	text string
+	// number is the value of equals when it is a number
+	number *float64
+	// dates is set for ranges over dates, otherwise the range is over numbers
+	dates            bool
+	fromNum, toNum   *float64
+	fromDate, toDate *time.Time
+}
+
+// compileSlotFilters validates filters, normalizing slot names and operators
+func compileSlotFilters(filters []models.SlotFilter) ([]slotPredicate, error) {
+	predicates := make([]slotPredicate, 0, len(filters))
+	for _, filter := range filters {
+		filter.Slot = strings.TrimPrefix(strings.TrimSpace(filter.Slot), "#")
+		filter.Op = strings.ToLower(strings.TrimSpace(filter.Op))
+		if filter.Slot == "" {
+			return nil, fmt.Errorf("%w: filters need a slot", ErrInvalidInstanceQuery)
+		}
+		predicate := slotPredicate{filter: filter}
+
+		switch filter.Op {
+		case models.SlotFilterEquals:
+			value := strings.TrimSpace(filter.Value)
+			predicate.text = strings.ToLower(value)
+			if number, ok := parseSlotNumber(value); ok {
+				predicate.number = &number
+			}
+		case models.SlotFilterContains:
+			predicate.text = strings.ToLower(strings.TrimSpace(filter.Value))
+			if predicate.text == "" {
+				return nil, fmt.Errorf("%w: contains on %s needs a value", ErrInvalidInstanceQuery, filter.Slot)
+			}
+		case models.SlotFilterRange:
+			if err := predicate.compileRange(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: unknown operator %q on %s: use equals, contains or range", ErrInvalidInstanceQuery, filter.Op, filter.Slot)
+		}
+		predicates = append(predicates, predicate)
+	}
+	return predicates, nil
+}
+
+// compileRange types a range by its bounds: numbers when every bound is a number, dates
+// when every bound is a date. A date without a time as the upper bound includes its day.
+func (p *slotPredicate) compileRange() error {
+	from, to := strings.TrimSpace(p.filter.From), strings.TrimSpace(p.filter.To)
+	if from == "" && to == "" {
+		return fmt.Errorf("%w: range on %s needs from or to", ErrInvalidInstanceQuery, p.filter.Slot)
+	}
+
+	numbers, dates := true, true
+	for _, bound := range []string{from, to} {
+		if bound == "" {
+			continue
+		}
+		_, isNumber := parseSlotNumber(bound)
+		_, isDate := parseSlotDate(bound)
+		numbers = numbers && isNumber
+		dates = dates && isDate
+	}
+
+	switch {
+	case numbers:
+		if from != "" {
+			number, _ := parseSlotNumber(from)
+			p.fromNum = &number
+		}
+		if to != "" {
+			number, _ := parseSlotNumber(to)
+			p.toNum = &number
+		}
+		if p.fromNum != nil && p.toNum != nil && *p.fromNum > *p.toNum {
+			return fmt.Errorf("%w: range on %s ends before it starts", ErrInvalidInstanceQuery, p.filter.Slot)
+		}
+	case dates:
+		p.dates = true
+		if from != "" {
+			date, _ := parseSlotDate(from)
+			p.fromDate = &date
+		}
+		if to != "" {
+			date, _ := parseSlotDate(to)
+			if _, err := time.Parse(slotDayLayout, to); err == nil {
+				date = date.AddDate(0, 0, 1).Add(-time.Microsecond)
+			}
+			p.toDate = &date
+		}
+		if p.fromDate != nil && p.toDate != nil && p.fromDate.After(*p.toDate) {
+			return fmt.Errorf("%w: range on %s ends before it starts", ErrInvalidInstanceQuery, p.filter.Slot)
+		}
+	default:
+		return fmt.Errorf("%w: range bounds on %s must both be numbers or dates", ErrInvalidInstanceQuery, p.filter.Slot)
+	}
+	return nil
+}
+
+// matches reports whether a slot value satisfies the predicate
+func (p *slotPredicate) matches(value string) bool {
+	value = strings.TrimSpace(value)
+	switch p.filter.Op {
+	case models.SlotFilterEquals:
+		if strings.ToLower(value) == p.text {
+			return true
+		}
+		number, ok := parseSlotNumber(value)
+		return ok && p.number != nil && number == *p.number
+	case models.SlotFilterContains:
+		return strings.Contains(strings.ToLower(value), p.text)
+	case models.SlotFilterRange:
+		if p.dates {
+			date, ok := parseSlotDate(value)
+			return ok && (p.fromDate == nil || !date.Before(*p.fromDate)) && (p.toDate == nil || !date.After(*p.toDate))
+		}
+		number, ok := parseSlotNumber(value)
+		return ok && (p.fromNum == nil || number >= *p.fromNum) && (p.toNum == nil || number <= *p.toNum)
+	}
+	return false
+}
+
+func parseSlotNumber(value string) (float64, bool) {
+	if !slotNumberPattern.MatchString(value) {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	return number, err == nil
+}
+
+func parseSlotDate(value string) (time.Time, bool) {
+	for _, layout := range slotDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// slotValueText is the text a slot value chunk holds
+func slotValueText(chunk *models.ChunkRecord) string {
+	if chunk.SlotValue != nil {
+		return *chunk.SlotValue
+	}
+	return chunk.Content
+}
+
+// checkFilterSlots rejects predicates on slots the template does not have
+func checkFilterSlots(slotNames []string, predicates []slotPredicate) error {
+	known := make(map[string]bool, len(slotNames))
+	for _, name := range slotNames {
+		known[name] = true
+	}
+	var unknown []string
+	for _, predicate := range predicates {
+		if !known[predicate.filter.Slot] {
+			unknown = append(unknown, predicate.filter.Slot)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("%w: unknown slots %s: template slots are %s", ErrInvalidInstanceQuery, strings.Join(unknown, ", "), strings.Join(slotNames, ", "))
+	}
+	return nil
+}
+
+func instanceQueryLimit(limit int) (int, error) {
+	switch {
+	case limit == 0:
+		return defaultInstanceQueryLimit, nil
+	case limit < 0 || limit > maxInstanceQueryLimit:
+		return 0, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInstanceQuery, maxInstanceQueryLimit)
+	}
+	return limit, nil
+}
+
+// QueryInstances finds the instances of a template whose slot values match every filter,
+// through the instance index when there is one
+func (s *templateService) QueryInstances(ctx context.Context, templateChunkID string, req *models.QueryInstancesRequest) (*models.InstanceQueryResult, error) {
+	if templateChunkID == "" {
+		return nil, fmt.Errorf("%w: template chunk ID is required", ErrInvalidInstanceQuery)
+	}
+	if req == nil {
+		req = &models.QueryInstancesRequest{}
+	}
+	limit, err := instanceQueryLimit(req.Limit)
+	if err != nil {
+		return nil, err
+	}
+	predicates, err := compileSlotFilters(req.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.InstanceQueryResult{TemplateChunkID: templateChunkID, Filters: slotFiltersOf(predicates)}
+	if _, _, result.Instances, err = s.matchInstances(ctx, templateChunkID, predicates, limit); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// matchInstances returns a template's chunk, its slot names in slot order and up to limit
+// of its instances matching every predicate, newest first
+func (s *templateService) matchInstances(ctx context.Context, templateChunkID string, predicates []slotPredicate, limit int) (*models.ChunkRecord, []string, []models.TemplateInstance, error) {
+	templateChunk, err := s.supabaseClient.GetChunkByID(ctx, templateChunkID)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get template chunk: %w", err)
+	}
+	if templateChunk == nil || !templateChunk.IsTemplate {
+		return nil, nil, nil, fmt.Errorf("chunk is not a template: %s", templateChunkID)
+	}
+
+	if s.instanceIndex != nil {
+		instances, slotNames, err := s.instanceIndex.QueryInstances(ctx, templateChunkID, slotFiltersOf(predicates), limit)
+		if err == nil {
+			return templateChunk, slotNames, instances, nil
+		}
+		if !errors.Is(err, ErrInstanceIndexUnavailable) {
+			return nil, nil, nil, err
+		}
+	}
+
+	template, err := s.supabaseClient.GetTemplateByContent(ctx, templateChunk.Content)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	slotNames := TemplateSlotNames(template)
+	if err := checkFilterSlots(slotNames, predicates); err != nil {
+		return nil, nil, nil, err
+	}
+	return templateChunk, slotNames, filterTemplateInstances(template, predicates, limit), nil
+}
+
+// slotFiltersOf returns the normalized filters of predicates
+func slotFiltersOf(predicates []slotPredicate) []models.SlotFilter {
+	filters := make([]models.SlotFilter, len(predicates))
+	for i, predicate := range predicates {
+		filters[i] = predicate.filter
+	}
+	return filters
+}
+
+// filterTemplateInstances keeps up to limit instances of template matching every predicate.
+// An instance without a value for a slot never matches a filter on it.
+func filterTemplateInstances(template *models.TemplateWithInstances, predicates []slotPredicate, limit int) []models.TemplateInstance {
+	instances := make([]models.TemplateInstance, 0)
+	for _, instance := range template.Instances {
+		matched := true
+		for i := range predicates {
+			value, ok := instance.SlotValues[predicates[i].filter.Slot]
+			if !ok || value == nil || !predicates[i].matches(slotValueText(value)) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			instances = append(instances, instance)
+			if len(instances) == limit {
+				break
+			}
+		}
+	}
+	return instances
+}
+
+// postgresTemplateInstanceIndex queries content_db.instance_slot_values, which triggers keep
+// in step with instances' slot value chunks
+type postgresTemplateInstanceIndex struct {
+	db      *sql.DB
+	monitor QueryPerformanceMonitor
+
+	mu      sync.Mutex
+	checked bool
+	exists  bool
+}
+
+// NewPostgresTemplateInstanceIndex creates an instance index on db. It is unavailable until
+// instance_slot_values_migration.sql has run there, which needs the template tables in the
+// same database.
+func NewPostgresTemplateInstanceIndex(db *sql.DB, monitor QueryPerformanceMonitor) TemplateInstanceIndex {
+	return &postgresTemplateInstanceIndex{db: db, monitor: monitor}
+}
+
+// available reports whether the index table exists. A missing table is remembered for
+// the life of the process, as migrations run at startup.
+func (x *postgresTemplateInstanceIndex) available(ctx context.Context) (bool, error) {
+	if x.db == nil {
+		return false, nil
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if !x.checked {
+		err := x.db.QueryRowContext(ctx, "SELECT to_regclass('content_db.instance_slot_values') IS NOT NULL").Scan(&x.exists)
+		if err != nil {
+			return false, fmt.Errorf("failed to check the template instance index: %w", err)
+		}
+		x.checked = true
+	}
+	return x.exists, nil
+}
+
+// templateChunkColumns returns the content_db.chunks columns scanTemplateChunk reads, of
+// the chunks aliased as alias
+func templateChunkColumns(alias string) string {
+	return strings.ReplaceAll(`c.id, c.text_id, c.content, COALESCE(c.is_template, false),
+		COALESCE(c.is_slot, false), c.parent_chunk_id, c.template_chunk_id, c.slot_value,
+		COALESCE(c.indent_level, 0), c.sequence_number, COALESCE(c.sort_key, ''),
+		c.metadata, c.created_at, c.updated_at`, "c.", alias+".")
+}
+
+func (x *postgresTemplateInstanceIndex) QueryInstances(ctx context.Context, templateChunkID string, filters []models.SlotFilter, limit int) ([]models.TemplateInstance, []string, error) {
+	if ok, err := x.available(ctx); err != nil || !ok {
+		if err == nil {
+			err = ErrInstanceIndexUnavailable
+		}
+		return nil, nil, err
+	}
+	predicates, err := compileSlotFilters(filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	start := time.Now()
+	instances := make([]models.TemplateInstance, 0)
+	defer func() {
+		x.monitor.RecordQuery("query_template_instances", time.Since(start), len(instances))
+	}()
+
+	slotNames, err := x.slotNames(ctx, templateChunkID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkFilterSlots(slotNames, predicates); err != nil {
+		return nil, nil, err
+	}
+
+	// Each filter selects the instances with a matching value through the
+	// (template, slot, value) indexes
+	args := []interface{}{templateChunkID}
+	var conditions strings.Builder
+	for _, predicate := range predicates {
+		args = append(args, predicate.filter.Slot)
+		slot := len(args)
+		var match string
+		switch predicate.filter.Op {
+		case models.SlotFilterEquals:
+			args = append(args, predicate.text)
+			match = fmt.Sprintf("lower(value_text) = $%d", len(args))
+			if predicate.number != nil {
+				args = append(args, strings.TrimSpace(predicate.filter.Value))
+				match = fmt.Sprintf("(%s OR value_number = $%d::numeric)", match, len(args))
+			}
+		case models.SlotFilterContains:
+			args = append(args, predicate.text)
+			match = fmt.Sprintf("strpos(lower(value_text), $%d) > 0", len(args))
+		case models.SlotFilterRange:
+			column, bounds := "value_number", make([]string, 0, 2)
+			if predicate.dates {
+				column = "value_date"
+				if predicate.fromDate != nil {
+					args = append(args, *predicate.fromDate)
+					bounds = append(bounds, fmt.Sprintf("value_date >= $%d", len(args)))
+				}
+				if predicate.toDate != nil {
+					args = append(args, *predicate.toDate)
+					bounds = append(bounds, fmt.Sprintf("value_date <= $%d", len(args)))
+				}
+			} else {
+				if predicate.fromNum != nil {
+					args = append(args, strings.TrimSpace(predicate.filter.From))
+					bounds = append(bounds, fmt.Sprintf("value_number >= $%d::numeric", len(args)))
+				}
+				if predicate.toNum != nil {
+					args = append(args, strings.TrimSpace(predicate.filter.To))
+					bounds = append(bounds, fmt.Sprintf("value_number <= $%d::numeric", len(args)))
+				}
+			}
+			match = column + " IS NOT NULL AND " + strings.Join(bounds, " AND ")
+		}
+		fmt.Fprintf(&conditions, `
+			AND i.id IN (
+				SELECT instance_chunk_id FROM content_db.instance_slot_values
+				WHERE template_chunk_id = $1 AND slot_name = $%d AND %s
+			)`, slot, match)
+	}
+	args = append(args, limit)
+
+	rows, err := x.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM content_db.chunks i
+		WHERE i.template_chunk_id = $1 AND i.parent_chunk_id IS NULL
+			AND NOT COALESCE(i.is_template, false) AND NOT COALESCE(i.is_slot, false)%s
+		ORDER BY i.created_at DESC, i.id
+		LIMIT $%d`, templateChunkColumns("i"), conditions.String(), len(args)), args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query template instances: %w", err)
+	}
+	defer rows.Close()
+
+	positions := make(map[string]int)
+	for rows.Next() {
+		chunk, err := scanTemplateChunk(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		positions[chunk.ID] = len(instances)
+		instances = append(instances, models.TemplateInstance{Instance: chunk, SlotValues: make(map[string]*models.ChunkRecord)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read template instances: %w", err)
+	}
+	if len(instances) == 0 {
+		return instances, slotNames, nil
+	}
+
+	instanceIDs := make([]string, 0, len(instances))
+	for id := range positions {
+		instanceIDs = append(instanceIDs, id)
+	}
+	rows, err = x.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT s.instance_chunk_id, s.slot_name, %s
+		FROM content_db.instance_slot_values s
+		JOIN content_db.chunks v ON v.id = s.value_chunk_id
+		WHERE s.instance_chunk_id = ANY($1)`, templateChunkColumns("v")), pq.Array(instanceIDs))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query slot values: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var instanceID, slotName string
+		chunk, err := scanTemplateChunk(rows, &instanceID, &slotName)
+		if err != nil {
+			return nil, nil, err
+		}
+		instances[positions[instanceID]].SlotValues[slotName] = chunk
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read slot values: %w", err)
+	}
+	return instances, slotNames, nil
+}
+
+// slotNames returns a template's slot names in slot order
+func (x *postgresTemplateInstanceIndex) slotNames(ctx context.Context, templateChunkID string) ([]string, error) {
+	rows, err := x.db.QueryContext(ctx, `
+		SELECT regexp_replace(s.content, '^#', '')
+		FROM content_db.template_slots ts
+		JOIN content_db.chunks s ON s.id = ts.slot_chunk_id
+		WHERE ts.template_chunk_id = $1
+		ORDER BY ts.slot_order`, templateChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template slots: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan template slot: %w", err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read template slots: %w", err)
+	}
+	return names, nil
+}
+
+// scanTemplateChunk reads leading followed by templateChunkColumns
+func scanTemplateChunk(row rowScanner, leading ...interface{}) (*models.ChunkRecord, error) {
+	chunk := &models.ChunkRecord{}
+	var parent, template, slotValue sql.NullString
+	var sequence sql.NullInt64
+	var metadata []byte
+	dest := append(leading, &chunk.ID, &chunk.TextID, &chunk.Content, &chunk.IsTemplate, &chunk.IsSlot,
+		&parent, &template, &slotValue, &chunk.IndentLevel, &sequence, &chunk.SortKey,
+		&metadata, &chunk.CreatedAt, &chunk.UpdatedAt)
+	if err := row.Scan(dest...); err != nil {
+		return nil, fmt.Errorf("failed to scan template chunk: %w", err)
+	}
+	if parent.Valid {
+		chunk.ParentChunkID = &parent.String
+	}
+	if template.Valid {
+		chunk.TemplateChunkID = &template.String
+	}
+	if slotValue.Valid {
+		chunk.SlotValue = &slotValue.String
+	}
+	if sequence.Valid {
+		number := int(sequence.Int64)
+		chunk.SequenceNumber = &number
+	}
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &chunk.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to parse chunk metadata: %w", err)
+		}
+	}
+	return chunk, nil
+}
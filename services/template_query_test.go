@@ -0,0 +1,321 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+// templateQueryClient serves a template chunk by ID on top of the template mock
+type templateQueryClient struct {
+	*MockSupabaseClientForTemplate
+	template *models.ChunkRecord
+}
+
+func (c *templateQueryClient) GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error) {
+	if c.template != nil && c.template.ID == id {
+		return c.template, nil
+	}
+	return nil, nil
+}
+
+// unavailableInstanceIndex is an index whose table has not been created
+type unavailableInstanceIndex struct{}
+
+func (unavailableInstanceIndex) QueryInstances(ctx context.Context, templateChunkID string, filters []models.SlotFilter, limit int) ([]models.TemplateInstance, []string, error) {
+	return nil, nil, ErrInstanceIndexUnavailable
+}
+
+func TestCompileSlotFilters(t *testing.T) {
+	predicates, err := compileSlotFilters([]models.SlotFilter{
+		{Slot: " #status ", Op: "EQUALS", Value: " Open "},
+		{Slot: "budget", Op: models.SlotFilterRange, From: "1000", To: "2.5e3"},
+		{Slot: "due", Op: models.SlotFilterRange, To: "2026-10-31"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "status", predicates[0].filter.Slot)
+	assert.Equal(t, models.SlotFilterEquals, predicates[0].filter.Op)
+	assert.Equal(t, "open", predicates[0].text)
+	assert.False(t, predicates[1].dates)
+	assert.Equal(t, 2500.0, *predicates[1].toNum)
+	assert.True(t, predicates[2].dates)
+	assert.Nil(t, predicates[2].fromDate)
+
+	invalid := map[string]models.SlotFilter{
+		"no slot":              {Op: models.SlotFilterEquals, Value: "x"},
+		"unknown operator":     {Slot: "status", Op: "like", Value: "x"},
+		"empty contains":       {Slot: "status", Op: models.SlotFilterContains, Value: " "},
+		"unbounded range":      {Slot: "budget", Op: models.SlotFilterRange},
+		"mixed bounds":         {Slot: "budget", Op: models.SlotFilterRange, From: "10", To: "2026-01-01"},
+		"text bounds":          {Slot: "budget", Op: models.SlotFilterRange, From: "low"},
+		"reversed numbers":     {Slot: "budget", Op: models.SlotFilterRange, From: "10", To: "1"},
+		"reversed dates":       {Slot: "due", Op: models.SlotFilterRange, From: "2026-02-01", To: "2026-01-01"},
+		"numbers in hex":       {Slot: "budget", Op: models.SlotFilterRange, From: "0x10"},
+		"impossible calendars": {Slot: "due", Op: models.SlotFilterRange, From: "2026-02-30"},
+	}
+	for name, filter := range invalid {
+		_, err := compileSlotFilters([]models.SlotFilter{filter})
+		assert.ErrorIs(t, err, ErrInvalidInstanceQuery, name)
+	}
+}
+
+func TestSlotPredicateMatches(t *testing.T) {
+	predicate := func(filter models.SlotFilter) slotPredicate {
+		predicates, err := compileSlotFilters([]models.SlotFilter{filter})
+		require.NoError(t, err)
+		return predicates[0]
+	}
+
+	equals := predicate(models.SlotFilter{Slot: "qty", Op: models.SlotFilterEquals, Value: "5"})
+	assert.True(t, equals.matches("5"))
+	assert.True(t, equals.matches(" 5.0 "), "numbers of equal value")
+	assert.False(t, equals.matches("50"))
+
+	text := predicate(models.SlotFilter{Slot: "status", Op: models.SlotFilterEquals, Value: "Open"})
+	assert.True(t, text.matches("OPEN"))
+	assert.False(t, text.matches("reopened"))
+
+	contains := predicate(models.SlotFilter{Slot: "people", Op: models.SlotFilterContains, Value: "ANN"})
+	assert.True(t, contains.matches("Bo, Ann"))
+	assert.False(t, contains.matches("Bo"))
+
+	numbers := predicate(models.SlotFilter{Slot: "budget", Op: models.SlotFilterRange, From: "100"})
+	assert.True(t, numbers.matches("100"))
+	assert.True(t, numbers.matches("1e3"))
+	assert.False(t, numbers.matches("99.5"))
+	assert.False(t, numbers.matches("lots"), "text never matches a numeric range")
+
+	dates := predicate(models.SlotFilter{Slot: "due", Op: models.SlotFilterRange, From: "2026-10-01", To: "2026-10-31"})
+	assert.True(t, dates.matches("2026-10-01"))
+	assert.True(t, dates.matches("2026-10-31 23:30"), "a date-only upper bound includes its day")
+	assert.True(t, dates.matches("2026-11-01T00:30:00+01:00"), "00:30 at +01:00 is still October 31 in UTC")
+	assert.False(t, dates.matches("2026-10-31T23:00:00-01:00"), "23:00 at -01:00 is November 1 in UTC")
+	assert.False(t, dates.matches("2026-11-01"))
+	assert.False(t, dates.matches("20261015"))
+}
+
+func TestTemplateService_QueryInstances(t *testing.T) {
+	value := func(text string) *models.ChunkRecord { return &models.ChunkRecord{Content: text} }
+	template := &models.TemplateWithInstances{
+		Template: &models.ChunkRecord{ID: "template-1", Content: "Deal#template", IsTemplate: true},
+		Slots:    []models.ChunkRecord{{Content: "#stage"}, {Content: "#amount"}, {Content: "#close"}},
+		Instances: []models.TemplateInstance{
+			{Instance: &models.ChunkRecord{ID: "deal-3"}, SlotValues: map[string]*models.ChunkRecord{"stage": value("Won"), "amount": value("12000"), "close": value("2026-10-02")}},
+			{Instance: &models.ChunkRecord{ID: "deal-2"}, SlotValues: map[string]*models.ChunkRecord{"stage": value("open"), "amount": value("800"), "close": value("2026-11-20")}},
+			{Instance: &models.ChunkRecord{ID: "deal-1"}, SlotValues: map[string]*models.ChunkRecord{"stage": value("Open"), "amount": value("5000")}},
+		},
+	}
+	mockClient := new(MockSupabaseClientForTemplate)
+	mockClient.On("GetTemplateByContent", mock.Anything, "Deal#template").Return(template, nil)
+	client := &templateQueryClient{MockSupabaseClientForTemplate: mockClient, template: template.Template}
+	ctx := context.Background()
+
+	ids := func(result *models.InstanceQueryResult) []string {
+		var ids []string
+		for _, instance := range result.Instances {
+			ids = append(ids, instance.Instance.ID)
+		}
+		return ids
+	}
+
+	for _, service := range []TemplateService{
+		NewTemplateService(client),
+		NewTemplateServiceWithIndex(client, unavailableInstanceIndex{}),
+	} {
+		result, err := service.QueryInstances(ctx, "template-1", &models.QueryInstancesRequest{Filters: []models.SlotFilter{
+			{Slot: "stage", Op: models.SlotFilterEquals, Value: "open"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deal-2", "deal-1"}, ids(result))
+
+		result, err = service.QueryInstances(ctx, "template-1", &models.QueryInstancesRequest{Filters: []models.SlotFilter{
+			{Slot: "stage", Op: models.SlotFilterEquals, Value: "open"},
+			{Slot: "amount", Op: models.SlotFilterRange, From: "1000"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deal-1"}, ids(result))
+
+		result, err = service.QueryInstances(ctx, "template-1", &models.QueryInstancesRequest{Filters: []models.SlotFilter{
+			{Slot: "close", Op: models.SlotFilterRange, To: "2026-10-31"},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deal-3"}, ids(result), "instances without a value never match")
+
+		result, err = service.QueryInstances(ctx, "template-1", &models.QueryInstancesRequest{Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"deal-3", "deal-2"}, ids(result), "no filters lists the newest instances")
+
+		_, err = service.QueryInstances(ctx, "template-1", &models.QueryInstancesRequest{Filters: []models.SlotFilter{
+			{Slot: "owner", Op: models.SlotFilterEquals, Value: "ann"},
+		}})
+		assert.ErrorIs(t, err, ErrInvalidInstanceQuery)
+		assert.Contains(t, err.Error(), "template slots are stage, amount, close")
+	}
+
+	service := NewTemplateService(client)
+	_, err := service.QueryInstances(ctx, "template-1", &models.QueryInstancesRequest{Limit: maxInstanceQueryLimit + 1})
+	assert.ErrorIs(t, err, ErrInvalidInstanceQuery)
+	_, err = service.QueryInstances(ctx, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidInstanceQuery)
+	_, err = service.QueryInstances(ctx, "deal-1", nil)
+	assert.ErrorContains(t, err, "not a template")
+}
+
+func TestPostgresTemplateInstanceIndex_WithoutDatabase(t *testing.T) {
+	index := NewPostgresTemplateInstanceIndex(nil, NewNoOpMonitor())
+	_, _, err := index.QueryInstances(context.Background(), "template-1", nil, defaultInstanceQueryLimit)
+	assert.ErrorIs(t, err, ErrInstanceIndexUnavailable)
+}
+
+func TestPostgresTemplateInstanceIndex_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	index := NewPostgresTemplateInstanceIndex(db, NewNoOpMonitor())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, _, err := index.QueryInstances(ctx, "00000000-0000-0000-0000-000000000000", []models.SlotFilter{
+		{Slot: "stage", Op: models.SlotFilterEquals, Value: "open"},
+	}, defaultInstanceQueryLimit)
+	if errors.Is(err, ErrInstanceIndexUnavailable) {
+		t.Skip("the template tables are not in this database")
+	}
+	assert.ErrorIs(t, err, ErrInvalidInstanceQuery, "a template without slots has no stage slot")
+}
+
+func TestTemplateService_TableView(t *testing.T) {
+	created := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	value := func(text string) *models.ChunkRecord { return &models.ChunkRecord{Content: text} }
+	instance := func(id, name string, day int, values map[string]*models.ChunkRecord) models.TemplateInstance {
+		return models.TemplateInstance{
+			Instance:   &models.ChunkRecord{ID: id, Content: name + "#Deal", CreatedAt: created.AddDate(0, 0, day)},
+			SlotValues: values,
+		}
+	}
+	template := &models.TemplateWithInstances{
+		Template: &models.ChunkRecord{ID: "template-1", Content: "Deal#template", IsTemplate: true},
+		Slots:    []models.ChunkRecord{{Content: "#stage"}, {Content: "#amount"}, {Content: "#close"}, {Content: "#notes"}},
+		Instances: []models.TemplateInstance{
+			instance("deal-4", "Delta", 4, map[string]*models.ChunkRecord{"stage": value("open"), "amount": value(" 900 "), "close": value("2026-12-01")}),
+			instance("deal-3", "Gamma", 3, map[string]*models.ChunkRecord{"stage": value("Won"), "amount": value("12000"), "close": value("2026-10-02")}),
+			instance("deal-2", "beta", 2, map[string]*models.ChunkRecord{"stage": value("open"), "amount": value("")}),
+			instance("deal-1", "Alpha", 1, map[string]*models.ChunkRecord{"stage": value("Open"), "amount": value("5000"), "close": value("2026-11-20")}),
+		},
+	}
+	mockClient := new(MockSupabaseClientForTemplate)
+	mockClient.On("GetTemplateByContent", mock.Anything, "Deal#template").Return(template, nil)
+	service := NewTemplateService(&templateQueryClient{MockSupabaseClientForTemplate: mockClient, template: template.Template})
+	ctx := context.Background()
+
+	names := func(view *models.TableView) []string {
+		var names []string
+		for _, row := range view.Rows {
+			names = append(names, row.Name)
+		}
+		return names
+	}
+
+	view, err := service.TableView(ctx, "template-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "Deal", view.Template)
+	assert.Equal(t, []models.TableColumn{
+		{Name: "stage", Type: models.TableColumnText},
+		{Name: "amount", Type: models.TableColumnNumber},
+		{Name: "close", Type: models.TableColumnDate},
+		{Name: "notes", Type: models.TableColumnText},
+	}, view.Columns)
+	assert.Equal(t, []string{"Delta", "Gamma", "beta", "Alpha"}, names(view), "newest first by default")
+	assert.Equal(t, []interface{}{"open", 900.0, "2026-12-01", nil}, view.Rows[0].Cells)
+	assert.Equal(t, []interface{}{"open", nil, nil, nil}, view.Rows[2].Cells)
+	assert.Equal(t, 4, view.Total)
+	assert.Equal(t, defaultTableViewLimit, view.Limit)
+
+	t.Run("sorting", func(t *testing.T) {
+		view, err := service.TableView(ctx, "template-1", &models.TableViewRequest{Sort: []models.TableSort{{Column: "amount", Desc: true}}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Gamma", "Alpha", "Delta", "beta"}, names(view), "numbers compare as numbers and empty cells go last")
+
+		view, err = service.TableView(ctx, "template-1", &models.TableViewRequest{Sort: []models.TableSort{{Column: "close"}}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Gamma", "Alpha", "Delta", "beta"}, names(view))
+
+		view, err = service.TableView(ctx, "template-1", &models.TableViewRequest{Sort: ParseTableSortParam("stage, -_created_at")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Delta", "beta", "Alpha", "Gamma"}, names(view), "text compares case-insensitively, ties by the next sort")
+
+		view, err = service.TableView(ctx, "template-1", &models.TableViewRequest{Sort: ParseTableSortParam("_name")})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Alpha", "beta", "Delta", "Gamma"}, names(view))
+
+		_, err = service.TableView(ctx, "template-1", &models.TableViewRequest{Sort: ParseTableSortParam("owner")})
+		assert.ErrorIs(t, err, ErrInvalidInstanceQuery)
+	})
+
+	t.Run("filters and pages", func(t *testing.T) {
+		filter, err := ParseSlotFilterParam("stage:equals:open")
+		require.NoError(t, err)
+		view, err := service.TableView(ctx, "template-1", &models.TableViewRequest{
+			Filters: []models.SlotFilter{filter},
+			Sort:    ParseTableSortParam("_name"),
+			Limit:   2,
+			Offset:  1,
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, view.Total)
+		assert.Equal(t, []string{"beta", "Delta"}, names(view))
+
+		view, err = service.TableView(ctx, "template-1", &models.TableViewRequest{Offset: 10})
+		require.NoError(t, err)
+		assert.Empty(t, view.Rows)
+		assert.Equal(t, 4, view.Total)
+
+		view, err = service.TableView(ctx, "template-1", &models.TableViewRequest{Limit: 1, Offset: 2, AllRows: true})
+		require.NoError(t, err)
+		assert.Len(t, view.Rows, 4, "exports ignore the page")
+
+		for _, req := range []models.TableViewRequest{{Limit: -1}, {Offset: -1}, {Limit: maxInstanceQueryLimit + 1}} {
+			_, err := service.TableView(ctx, "template-1", &req)
+			assert.ErrorIs(t, err, ErrInvalidInstanceQuery)
+		}
+	})
+}
+
+func TestParseSlotFilterParam(t *testing.T) {
+	filter, err := ParseSlotFilterParam("due:range:2026-10-01T09:00..")
+	require.NoError(t, err)
+	assert.Equal(t, models.SlotFilter{Slot: "due", Op: "range", From: "2026-10-01T09:00"}, filter)
+
+	filter, err = ParseSlotFilterParam("time:equals:10:30")
+	require.NoError(t, err)
+	assert.Equal(t, "10:30", filter.Value)
+
+	for _, raw := range []string{"stage", "stage:equals", "amount:range:100"} {
+		_, err := ParseSlotFilterParam(raw)
+		assert.ErrorIs(t, err, ErrInvalidInstanceQuery, raw)
+	}
+	assert.Equal(t, []models.TableSort{{Column: "amount", Desc: true}, {Column: "_name"}}, ParseTableSortParam("-amount,,_name"))
+}
+
+func TestWriteTableCSV(t *testing.T) {
+	view := &models.TableView{
+		Columns: []models.TableColumn{{Name: "amount", Type: models.TableColumnNumber}, {Name: "notes", Type: models.TableColumnText}},
+		Rows: []models.TableRow{
+			{InstanceChunkID: "deal-1", Name: "Alpha", Cells: []interface{}{-1250.5, "=HYPERLINK(\"x\")"}},
+			{InstanceChunkID: "deal-2", Name: "@beta", Cells: []interface{}{nil, "two, lines\nhere"}},
+		},
+	}
+	var buf strings.Builder
+	require.NoError(t, WriteTableCSV(&buf, view))
+	assert.Equal(t, "instance_chunk_id,name,amount,notes\n"+
+		"deal-1,Alpha,-1250.5,\"'=HYPERLINK(\"\"x\"\")\"\n"+
+		"deal-2,'@beta,,\"two, lines\nhere\"\n", buf.String())
+}
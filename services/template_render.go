@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"semantic-text-processor/models"
+)
+
+// TemplateLayoutMetadataKey is the template chunk metadata key holding the layout its
+// instances are rendered with
+const TemplateLayoutMetadataKey = "layout"
+
+// ErrInvalidRenderFormat is returned when an instance is rendered to an unknown format
+var ErrInvalidRenderFormat = errors.New("invalid render format")
+
+// TemplateLayout returns the layout stored on a template chunk, or "" when it has none
+func TemplateLayout(template *models.ChunkRecord) string {
+	if template == nil {
+		return ""
+	}
+	layout, _ := template.Metadata[TemplateLayoutMetadataKey].(string)
+	return layout
+}
+
+// InstanceName returns the name a template instance was created with
+func InstanceName(instance *models.ChunkRecord) string {
+	return strings.SplitN(instance.Content, "#", 2)[0]
+}
+
+// RenderInstance renders a template instance with its template's layout
+func (s *templateService) RenderInstance(ctx context.Context, instanceChunkID, format string) (*models.RenderedInstance, error) {
+	if instanceChunkID == "" {
+		return nil, fmt.Errorf("instance chunk ID is required")
+	}
+	if _, err := renderFormat(format); err != nil {
+		return nil, err
+	}
+
+	instanceChunk, err := s.supabaseClient.GetChunkByID(ctx, instanceChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get instance chunk: %w", err)
+	}
+	if instanceChunk == nil || instanceChunk.TemplateChunkID == nil || instanceChunk.IsTemplate || instanceChunk.IsSlot || instanceChunk.ParentChunkID != nil {
+		return nil, fmt.Errorf("chunk is not a template instance: %s", instanceChunkID)
+	}
+
+	templateChunk, err := s.supabaseClient.GetChunkByID(ctx, *instanceChunk.TemplateChunkID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get template chunk: %w", err)
+	}
+	template, err := s.supabaseClient.GetTemplateByContent(ctx, templateChunk.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range template.Instances {
+		if instance := &template.Instances[i]; instance.Instance != nil && instance.Instance.ID == instanceChunkID {
+			return RenderTemplateInstance(template, instance, format)
+		}
+	}
+	return nil, fmt.Errorf("template instance not found: %s", instanceChunkID)
+}
+
+// RenderTemplateInstance renders an instance of template to format. With a layout, each
+// #slot placeholder is replaced by the slot's value; without one, slots are listed in
+// order under the instance name.
+func RenderTemplateInstance(template *models.TemplateWithInstances, instance *models.TemplateInstance, format string) (*models.RenderedInstance, error) {
+	format, err := renderFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := &models.RenderedInstance{
+		InstanceChunkID: instance.Instance.ID,
+		TemplateChunkID: template.Template.ID,
+		Template:        TemplateName(template.Template),
+		Instance:        InstanceName(instance.Instance),
+		Format:          format,
+	}
+	values := make(map[string]string, len(template.Slots))
+	for _, name := range TemplateSlotNames(template) {
+		var value string
+		if chunk := instance.SlotValues[name]; chunk != nil {
+			value = chunk.Content
+		}
+		values[name] = value
+		rendered.Slots = append(rendered.Slots, models.RenderedSlot{Name: name, Value: value})
+	}
+
+	if layout := TemplateLayout(template.Template); layout != "" {
+		rendered.Content = fillLayout(layout, values)
+		return rendered, nil
+	}
+
+	var content strings.Builder
+	if format == models.RenderFormatMarkdown {
+		content.WriteString("# " + rendered.Instance + "\n\n")
+		for _, slot := range rendered.Slots {
+			content.WriteString(fmt.Sprintf("- **%s**: %s\n", slot.Name, slot.Value))
+		}
+	} else {
+		content.WriteString(rendered.Instance + "\n")
+		for _, slot := range rendered.Slots {
+			content.WriteString(fmt.Sprintf("%s: %s\n", slot.Name, slot.Value))
+		}
+	}
+	rendered.Content = content.String()
+	return rendered, nil
+}
+
+// renderFormat normalizes a render format, defaulting to plain text
+func renderFormat(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "":
+		return models.RenderFormatText, nil
+	case "md":
+		return models.RenderFormatMarkdown, nil
+	case models.RenderFormatText, models.RenderFormatMarkdown, models.RenderFormatJSON:
+		return format, nil
+	}
+	return "", fmt.Errorf("%w: %q (use text, markdown or json)", ErrInvalidRenderFormat, format)
+}
+
+// fillLayout replaces #slot placeholders with values. The longest slot name matching at a
+// placeholder wins, and a placeholder followed by an ASCII letter, digit or underscore is
+// taken to be another word, so "#name" does not match inside "#names". Hashtags that
+// name no slot are left as they are.
+func fillLayout(layout string, values map[string]string) string {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if len(names[i]) != len(names[j]) {
+			return len(names[i]) > len(names[j])
+		}
+		return names[i] < names[j]
+	})
+
+	var out strings.Builder
+	for i := 0; i < len(layout); {
+		if layout[i] == '#' {
+			if name, ok := placeholderAt(layout[i+1:], names); ok {
+				out.WriteString(values[name])
+				i += 1 + len(name)
+				continue
+			}
+		}
+		out.WriteByte(layout[i])
+		i++
+	}
+	return out.String()
+}
+
+// placeholderAt returns the slot name s starts with
+func placeholderAt(s string, names []string) (string, bool) {
+	for _, name := range names {
+		if !strings.HasPrefix(s, name) {
+			continue
+		}
+		if rest := s[len(name):]; rest != "" && isWordByte(rest[0]) {
+			continue
+		}
+		return name, true
+	}
+	return "", false
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"semantic-text-processor/models"
+)
+
+const (
+	defaultTableViewLimit = 50
+	// maxTableViewRows is the most instances a table view sorts; larger tables need filters
+	maxTableViewRows = 10000
+)
+
+// TableView returns a page of a template's instances as rows, with the template's slots as
+// columns typed by their values
+func (s *templateService) TableView(ctx context.Context, templateChunkID string, req *models.TableViewRequest) (*models.TableView, error) {
+	if templateChunkID == "" {
+		return nil, fmt.Errorf("%w: template chunk ID is required", ErrInvalidInstanceQuery)
+	}
+	if req == nil {
+		req = &models.TableViewRequest{}
+	}
+	limit, offset := req.Limit, req.Offset
+	switch {
+	case req.AllRows:
+		limit, offset = maxTableViewRows, 0
+	case limit == 0:
+		limit = defaultTableViewLimit
+	case limit < 0 || limit > maxInstanceQueryLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInstanceQuery, maxInstanceQueryLimit)
+	}
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: offset cannot be negative", ErrInvalidInstanceQuery)
+	}
+	predicates, err := compileSlotFilters(req.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Sorting needs every matching row, so tables beyond maxTableViewRows must be filtered
+	templateChunk, slotNames, instances, err := s.matchInstances(ctx, templateChunkID, predicates, maxTableViewRows+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) > maxTableViewRows {
+		return nil, fmt.Errorf("%w: more than %d instances match; add filters", ErrInvalidInstanceQuery, maxTableViewRows)
+	}
+
+	view := buildTableView(templateChunk, slotNames, instances)
+	if err := sortTableRows(view, req.Sort); err != nil {
+		return nil, err
+	}
+	view.Total, view.Offset, view.Limit = len(view.Rows), offset, limit
+	if offset > len(view.Rows) {
+		offset = len(view.Rows)
+	}
+	view.Rows = view.Rows[offset:]
+	if len(view.Rows) > limit {
+		view.Rows = view.Rows[:limit]
+	}
+	return view, nil
+}
+
+// buildTableView lays instances out as rows. A column is a number or date column when all
+// of its values are numbers or dates, and text otherwise.
+func buildTableView(templateChunk *models.ChunkRecord, slotNames []string, instances []models.TemplateInstance) *models.TableView {
+	view := &models.TableView{
+		TemplateChunkID: templateChunk.ID,
+		Template:        TemplateName(templateChunk),
+		Columns:         make([]models.TableColumn, len(slotNames)),
+		Rows:            make([]models.TableRow, len(instances)),
+	}
+
+	for i, name := range slotNames {
+		numbers, dates := true, true
+		for _, instance := range instances {
+			value := tableCellText(instance, name)
+			if value == "" {
+				continue
+			}
+			_, isNumber := parseSlotNumber(value)
+			_, isDate := parseSlotDate(value)
+			numbers = numbers && isNumber
+			dates = dates && isDate
+		}
+		column := models.TableColumn{Name: name, Type: models.TableColumnText}
+		switch {
+		case numbers && dates:
+			// A column without values stays text
+		case numbers:
+			column.Type = models.TableColumnNumber
+		case dates:
+			column.Type = models.TableColumnDate
+		}
+		view.Columns[i] = column
+	}
+
+	for i, instance := range instances {
+		row := models.TableRow{
+			InstanceChunkID: instance.Instance.ID,
+			Name:            InstanceName(instance.Instance),
+			CreatedAt:       instance.Instance.CreatedAt,
+			Cells:           make([]interface{}, len(view.Columns)),
+		}
+		for j, column := range view.Columns {
+			value := tableCellText(instance, column.Name)
+			switch {
+			case value == "":
+			case column.Type == models.TableColumnNumber:
+				row.Cells[j], _ = parseSlotNumber(value)
+			default:
+				row.Cells[j] = value
+			}
+		}
+		view.Rows[i] = row
+	}
+	return view
+}
+
+// tableCellText is the trimmed value of an instance's slot, or "" when it has none
+func tableCellText(instance models.TemplateInstance, slotName string) string {
+	value := instance.SlotValues[slotName]
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(slotValueText(value))
+}
+
+// sortTableRows orders rows by each sort in turn, keeping the existing order for ties.
+// Empty cells go last in either direction.
+func sortTableRows(view *models.TableView, sorts []models.TableSort) error {
+	type sortColumn struct {
+		index int // -1 for the instance's name or creation time
+		kind  string
+		desc  bool
+	}
+	columns := make([]sortColumn, 0, len(sorts))
+	for _, tableSort := range sorts {
+		name := strings.TrimPrefix(strings.TrimSpace(tableSort.Column), "#")
+		column := sortColumn{index: -1, desc: tableSort.Desc}
+		for i, viewColumn := range view.Columns {
+			if viewColumn.Name == name {
+				column.index, column.kind = i, viewColumn.Type
+				break
+			}
+		}
+		if column.index < 0 {
+			switch name {
+			case models.TableSortName:
+				column.kind = models.TableColumnText
+			case models.TableSortCreatedAt:
+				column.kind = models.TableColumnDate
+			default:
+				return fmt.Errorf("%w: unknown sort column %q", ErrInvalidInstanceQuery, name)
+			}
+		}
+		columns = append(columns, column)
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	// Sort keys are computed once per row: lowercased text, numbers or times, nil when empty
+	keys := make([][]interface{}, len(view.Rows))
+	for i, row := range view.Rows {
+		keys[i] = make([]interface{}, len(columns))
+		for j, column := range columns {
+			switch {
+			case column.index >= 0:
+				keys[i][j] = tableSortKey(column.kind, row.Cells[column.index])
+			case column.kind == models.TableColumnText:
+				keys[i][j] = strings.ToLower(row.Name)
+			default:
+				keys[i][j] = row.CreatedAt
+			}
+		}
+	}
+
+	order := make([]int, len(view.Rows))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		for j, column := range columns {
+			left, right := keys[order[a]][j], keys[order[b]][j]
+			switch {
+			case left == nil && right == nil:
+				continue
+			case left == nil || right == nil:
+				return right == nil
+			}
+			if c := compareTableKeys(left, right); c != 0 {
+				return (c < 0) != column.desc
+			}
+		}
+		return false
+	})
+
+	rows := make([]models.TableRow, len(order))
+	for i, index := range order {
+		rows[i] = view.Rows[index]
+	}
+	view.Rows = rows
+	return nil
+}
+
+func tableSortKey(kind string, cell interface{}) interface{} {
+	if cell == nil {
+		return nil
+	}
+	switch kind {
+	case models.TableColumnNumber:
+		return cell
+	case models.TableColumnDate:
+		date, _ := parseSlotDate(cell.(string))
+		return date
+	}
+	return strings.ToLower(cell.(string))
+}
+
+// compareTableKeys compares two sort keys of the same column
+func compareTableKeys(left, right interface{}) int {
+	switch l := left.(type) {
+	case float64:
+		r := right.(float64)
+		switch {
+		case l < r:
+			return -1
+		case l > r:
+			return 1
+		}
+		return 0
+	case time.Time:
+		return l.Compare(right.(time.Time))
+	}
+	return strings.Compare(left.(string), right.(string))
+}
+
+// WriteTableCSV writes a table view as CSV: the instance ID and name, then one column per
+// slot. Text that a spreadsheet would read as a formula is prefixed with an apostrophe.
+func WriteTableCSV(w io.Writer, view *models.TableView) error {
+	writer := csv.NewWriter(w)
+	header := []string{"instance_chunk_id", "name"}
+	for _, column := range view.Columns {
+		header = append(header, column.Name)
+	}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write table header: %w", err)
+	}
+
+	record := make([]string, len(header))
+	for _, row := range view.Rows {
+		record[0], record[1] = row.InstanceChunkID, csvCellText(row.Name)
+		for i, cell := range row.Cells {
+			switch value := cell.(type) {
+			case nil:
+				record[i+2] = ""
+			case float64:
+				record[i+2] = strconv.FormatFloat(value, 'f', -1, 64)
+			case string:
+				record[i+2] = csvCellText(value)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write table row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write table: %w", err)
+	}
+	return nil
+}
+
+func csvCellText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ParseSlotFilterParam parses a filter written as slot:op:value. Ranges are written as
+// slot:range:from..to, with either bound left out as needed.
+func ParseSlotFilterParam(raw string) (models.SlotFilter, error) {
+	parts := strings.SplitN(raw, ":", 3)
+	if len(parts) != 3 {
+		return models.SlotFilter{}, fmt.Errorf("%w: filter %q is not slot:op:value", ErrInvalidInstanceQuery, raw)
+	}
+	filter := models.SlotFilter{Slot: parts[0], Op: parts[1]}
+	if strings.EqualFold(strings.TrimSpace(filter.Op), models.SlotFilterRange) {
+		bounds := strings.SplitN(parts[2], "..", 2)
+		if len(bounds) != 2 {
+			return models.SlotFilter{}, fmt.Errorf("%w: range filter %q is not slot:range:from..to", ErrInvalidInstanceQuery, raw)
+		}
+		filter.From, filter.To = bounds[0], bounds[1]
+	} else {
+		filter.Value = parts[2]
+	}
+	return filter, nil
+}
+
+// ParseTableSortParam parses sorts written as comma-separated columns, each descending
+// when prefixed with a minus sign
+func ParseTableSortParam(raw string) []models.TableSort {
+	var sorts []models.TableSort
+	for _, column := range strings.Split(raw, ",") {
+		if column = strings.TrimSpace(column); column == "" {
+			continue
+		}
+		desc := strings.HasPrefix(column, "-")
+		sorts = append(sorts, models.TableSort{Column: strings.TrimPrefix(column, "-"), Desc: desc})
+	}
+	return sorts
+}
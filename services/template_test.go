@@ -10,6 +10,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockSupabaseClientForTemplate for testing template service
@@ -54,6 +55,14 @@ func (m *MockSupabaseClientForTemplate) UpdateSlotValue(ctx context.Context, ins
 	return args.Error(0)
 }
 
+func (m *MockSupabaseClientForTemplate) UpdateTemplateSchema(ctx context.Context, templateChunkID string, req *models.UpdateTemplateSchemaRequest) (*models.TemplateSchemaMigration, error) {
+	args := m.Called(ctx, templateChunkID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TemplateSchemaMigration), args.Error(1)
+}
+
 // Implement other required methods as no-ops for this test
 func (m *MockSupabaseClientForTemplate) InsertText(ctx context.Context, text *models.TextRecord) error { return nil }
 func (m *MockSupabaseClientForTemplate) GetTexts(ctx context.Context, pagination *models.Pagination) (*models.TextList, error) { return nil, nil }
@@ -63,6 +72,7 @@ func (m *MockSupabaseClientForTemplate) DeleteText(ctx context.Context, id strin
 func (m *MockSupabaseClientForTemplate) InsertChunk(ctx context.Context, chunk *models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClientForTemplate) InsertChunks(ctx context.Context, chunks []models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClientForTemplate) GetChunkByID(ctx context.Context, id string) (*models.ChunkRecord, error) { return nil, nil }
+func (m *MockSupabaseClientForTemplate) GetChunksByIDs(ctx context.Context, ids []string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) GetChunkByContent(ctx context.Context, content string) (*models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) UpdateChunk(ctx context.Context, chunk *models.ChunkRecord) error { return nil }
 func (m *MockSupabaseClientForTemplate) DeleteChunk(ctx context.Context, id string) error { return nil }
@@ -76,6 +86,8 @@ func (m *MockSupabaseClientForTemplate) GetChunkHierarchy(ctx context.Context, r
 func (m *MockSupabaseClientForTemplate) GetChildrenChunks(ctx context.Context, parentChunkID string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) GetSiblingChunks(ctx context.Context, chunkID string) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) MoveChunk(ctx context.Context, req *models.MoveChunkRequest) error { return nil }
+func (m *MockSupabaseClientForTemplate) InsertChunkAt(ctx context.Context, parentID, afterChunkID string, chunk *models.ChunkRecord) error { return nil }
+func (m *MockSupabaseClientForTemplate) ReorderChildren(ctx context.Context, parentID string, orderedChildIDs []string) error { return nil }
 func (m *MockSupabaseClientForTemplate) BulkUpdateChunks(ctx context.Context, req *models.BulkUpdateRequest) error { return nil }
 func (m *MockSupabaseClientForTemplate) SearchChunks(ctx context.Context, query string, filters map[string]interface{}) ([]models.ChunkRecord, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) SearchByTag(ctx context.Context, tagContent string) ([]models.ChunkWithTags, error) { return nil, nil }
@@ -83,11 +95,14 @@ func (m *MockSupabaseClientForTemplate) InsertEmbeddings(ctx context.Context, em
 func (m *MockSupabaseClientForTemplate) SearchSimilar(ctx context.Context, queryVector []float64, limit int) ([]models.SimilarityResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) InsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error { return nil }
 func (m *MockSupabaseClientForTemplate) InsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error { return nil }
+func (m *MockSupabaseClientForTemplate) UpsertGraphNodes(ctx context.Context, nodes []models.GraphNode) error { return nil }
+func (m *MockSupabaseClientForTemplate) UpsertGraphEdges(ctx context.Context, edges []models.GraphEdge) error { return nil }
 func (m *MockSupabaseClientForTemplate) SearchGraph(ctx context.Context, query *models.GraphQuery) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) GetNodesByEntity(ctx context.Context, entityName string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) GetNodeNeighbors(ctx context.Context, nodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) FindPathBetweenNodes(ctx context.Context, sourceNodeID, targetNodeID string, maxDepth int) (*models.GraphResult, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) GetNodesByChunk(ctx context.Context, chunkID string) ([]models.GraphNode, error) { return nil, nil }
+func (m *MockSupabaseClientForTemplate) GetNodesByIDs(ctx context.Context, ids []string) ([]models.GraphNode, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) GetEdgesByRelationType(ctx context.Context, relationType string) ([]models.GraphEdge, error) { return nil, nil }
 func (m *MockSupabaseClientForTemplate) HealthCheck(ctx context.Context) error { return nil }
 
@@ -485,6 +500,94 @@ func TestTemplateService_UpdateSlotValue(t *testing.T) {
 	}
 }
 
+func TestTemplateService_UpdateTemplateSchema(t *testing.T) {
+	mockClient := new(MockSupabaseClientForTemplate)
+	service := NewTemplateService(mockClient)
+	ctx := context.Background()
+
+	invalid := map[string]*models.UpdateTemplateSchemaRequest{
+		"no request":     nil,
+		"no slots":       {},
+		"blank slot":     {SlotNames: []string{"name", " "}},
+		"duplicate slot": {SlotNames: []string{"name", "phone", "name"}},
+	}
+	for name, req := range invalid {
+		_, err := service.UpdateTemplateSchema(ctx, "template-123", req)
+		assert.ErrorIs(t, err, ErrInvalidTemplateSchema, name)
+	}
+	_, err := service.UpdateTemplateSchema(ctx, "", &models.UpdateTemplateSchemaRequest{SlotNames: []string{"name"}})
+	assert.ErrorIs(t, err, ErrInvalidTemplateSchema)
+
+	req := &models.UpdateTemplateSchemaRequest{SlotNames: []string{"name", "email"}, DryRun: true}
+	expected := &models.TemplateSchemaMigration{
+		TemplateChunkID:   "template-123",
+		AddedSlots:        []string{"email"},
+		RemovedSlots:      []string{"phone"},
+		Instances:         2,
+		CreatedSlotValues: 2,
+		DryRun:            true,
+	}
+	mockClient.On("UpdateTemplateSchema", mock.Anything, "template-123", req).Return(expected, nil)
+
+	migration, err := service.UpdateTemplateSchema(ctx, "template-123", req)
+	require.NoError(t, err)
+	assert.Equal(t, expected, migration)
+	mockClient.AssertExpectations(t)
+}
+
+func TestRenderTemplateInstance(t *testing.T) {
+	template := &models.TemplateWithInstances{
+		Template: &models.ChunkRecord{ID: "template-123", Content: "Contact#template"},
+		Slots:    []models.ChunkRecord{{Content: "#name"}, {Content: "#name_full"}, {Content: "#email"}},
+	}
+	instance := &models.TemplateInstance{
+		Instance: &models.ChunkRecord{ID: "instance-1", Content: "Alice#Contact"},
+		SlotValues: map[string]*models.ChunkRecord{
+			"name":      {Content: "Alice"},
+			"name_full": {Content: "Alice Chen"},
+		},
+	}
+
+	rendered, err := RenderTemplateInstance(template, instance, "")
+	require.NoError(t, err)
+	assert.Equal(t, models.RenderFormatText, rendered.Format)
+	assert.Equal(t, "Alice\nname: Alice\nname_full: Alice Chen\nemail: \n", rendered.Content)
+	assert.Equal(t, []models.RenderedSlot{{Name: "name", Value: "Alice"}, {Name: "name_full", Value: "Alice Chen"}, {Name: "email", Value: ""}}, rendered.Slots)
+
+	rendered, err = RenderTemplateInstance(template, instance, "md")
+	require.NoError(t, err)
+	assert.Equal(t, "# Alice\n\n- **name**: Alice\n- **name_full**: Alice Chen\n- **email**: \n", rendered.Content)
+
+	// The longest slot name wins, words continuing a slot name and unknown hashtags are kept
+	template.Template.Metadata = map[string]interface{}{TemplateLayoutMetadataKey: "## #name_full (#name) <#email> #names #contact"}
+	rendered, err = RenderTemplateInstance(template, instance, models.RenderFormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "## Alice Chen (Alice) <> #names #contact", rendered.Content)
+	assert.Equal(t, "Contact", rendered.Template)
+	assert.Equal(t, "Alice", rendered.Instance)
+
+	_, err = RenderTemplateInstance(template, instance, "pdf")
+	assert.ErrorIs(t, err, ErrInvalidRenderFormat)
+}
+
+func TestValidateSlotValues(t *testing.T) {
+	template := &models.TemplateWithInstances{
+		Template: &models.ChunkRecord{Content: "Person#template"},
+		Slots:    []models.ChunkRecord{{Content: "#name"}, {Content: "#email"}},
+	}
+	slotNames := TemplateSlotNames(template)
+	assert.Equal(t, []string{"name", "email"}, slotNames)
+	assert.Equal(t, "Person", TemplateName(template.Template))
+	assert.Equal(t, "Person#template", TemplateContent("Person"))
+	assert.Equal(t, "Person#template", TemplateContent("Person#template"))
+
+	assert.NoError(t, ValidateSlotValues(slotNames, map[string]string{"name": "Ada"}))
+	assert.NoError(t, ValidateSlotValues(slotNames, nil))
+
+	err := ValidateSlotValues(slotNames, map[string]string{"name": "Ada", "phone": "1", "age": "36"})
+	assert.EqualError(t, err, "unknown slots age, phone: template slots are name, email")
+}
+
 // Helper function to create string pointers
 func stringPtr(s string) *string {
 	return &s
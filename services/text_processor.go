@@ -107,11 +107,18 @@ func (tp *TextProcessorImpl) ExtractKnowledge(ctx context.Context, chunks []mode
 			return nil, fmt.Errorf("failed to extract entities from chunk %s: %w", chunk.ID, err)
 		}
 		
-		// Set chunk ID for nodes
+		// Set chunk ID for nodes, and the contents they came from so that graph sync can
+		// tell when the chunk changes
+		hash := contentHash(chunk.Content)
 		for i := range nodes {
 			nodes[i].ID = uuid.New().String()
 			nodes[i].ChunkID = chunk.ID
+			if nodes[i].Properties == nil {
+				nodes[i].Properties = make(map[string]interface{})
+			}
+			nodes[i].Properties[graphContentHashProperty] = hash
 		}
+		locateMentions(chunk.Content, nodes)
 		
 		allNodes = append(allNodes, nodes...)
 	}
@@ -346,14 +353,21 @@ func (tp *TextProcessorImpl) generateKnowledgeEdges(nodes []models.GraphNode, ch
 		chunkNodes[node.ChunkID] = append(chunkNodes[node.ChunkID], node)
 	}
 	
+	contents := make(map[string][]rune, len(chunks))
+	for _, chunk := range chunks {
+		contents[chunk.ID] = []rune(chunk.Content)
+	}
+	
 	// Create co-occurrence edges within each chunk
 	for chunkID, chunkNodeList := range chunkNodes {
+		sourceChunkID := chunkID
 		for i, node1 := range chunkNodeList {
 			for j, node2 := range chunkNodeList {
 				if i >= j {
 					continue // Avoid duplicate and self-edges
 				}
 				
+				confidence := 0.7
 				edge := models.GraphEdge{
 					ID:               uuid.New().String(),
 					SourceNodeID:     node1.ID,
@@ -364,8 +378,12 @@ func (tp *TextProcessorImpl) generateKnowledgeEdges(nodes []models.GraphNode, ch
 						"confidence": 0.7,
 						"source":     "co_occurrence",
 					},
-					CreatedAt: time.Now(),
+					CreatedAt:     time.Now(),
+					SourceChunkID: &sourceChunkID,
+					Confidence:    &confidence,
 				}
+				// The evidence is the sentences from the first mention to the last
+				edge.SourceStart, edge.SourceEnd = cooccurrenceSpan(contents[chunkID], node1, node2)
 				
 				edges = append(edges, edge)
 			}
@@ -380,8 +398,10 @@ func (tp *TextProcessorImpl) generateKnowledgeEdges(nodes []models.GraphNode, ch
 			childNodes := chunkNodes[chunk.ID]
 			
 			// Create hierarchical edges
+			childChunkID := chunk.ID
 			for _, parentNode := range parentNodes {
 				for _, childNode := range childNodes {
+					confidence := 0.8
 					edge := models.GraphEdge{
 						ID:               uuid.New().String(),
 						SourceNodeID:     parentNode.ID,
@@ -393,7 +413,12 @@ func (tp *TextProcessorImpl) generateKnowledgeEdges(nodes []models.GraphNode, ch
 							"confidence":      0.8,
 							"source":          "hierarchy",
 						},
-						CreatedAt: time.Now(),
+						CreatedAt:     time.Now(),
+						// The child's mention is where it was found under the parent
+						SourceChunkID: &childChunkID,
+						SourceStart:   childNode.SourceStart,
+						SourceEnd:     childNode.SourceEnd,
+						Confidence:    &confidence,
 					}
 					
 					edges = append(edges, edge)
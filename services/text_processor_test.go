@@ -322,10 +322,11 @@ func TestTextProcessor_ExtractKnowledge(t *testing.T) {
 	assert.Len(t, result.Nodes, 2)
 	assert.NotEmpty(t, result.Edges)
 
-	// Verify nodes have chunk IDs set
+	// Verify nodes have chunk IDs set and record the contents they came from
 	for _, node := range result.Nodes {
 		assert.NotEmpty(t, node.ID)
 		assert.NotEmpty(t, node.ChunkID)
+		assert.Equal(t, contentHash("John works at Google"), node.Properties[graphContentHashProperty])
 	}
 
 	// Verify edges are created
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// CacheCodec serializes values for cache backends that store bytes
+type CacheCodec interface {
+	// Name identifies the codec in stored entries
+	Name() string
+	Marshal(value interface{}) ([]byte, error)
+	Unmarshal(data []byte, dest interface{}) error
+}
+
+// JSONCodec encodes cache values as JSON
+type JSONCodec struct{}
+
+// Name returns "json"
+func (JSONCodec) Name() string { return "json" }
+
+// Marshal encodes value as JSON
+func (JSONCodec) Marshal(value interface{}) ([]byte, error) { return json.Marshal(value) }
+
+// Unmarshal decodes JSON into dest
+func (JSONCodec) Unmarshal(data []byte, dest interface{}) error { return json.Unmarshal(data, dest) }
+
+// MsgpackCodec encodes cache values as MessagePack, which is smaller and faster to decode
+// than JSON. Struct fields keep their json names so both codecs agree on field naming.
+// Times decode in the local time zone.
+type MsgpackCodec struct{}
+
+// Name returns "msgpack"
+func (MsgpackCodec) Name() string { return "msgpack" }
+
+// Marshal encodes value as MessagePack
+func (MsgpackCodec) Marshal(value interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes MessagePack into dest
+func (MsgpackCodec) Unmarshal(data []byte, dest interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(dest)
+}
+
+// CacheCodecByName returns the codec for a CACHE_CODEC setting
+func CacheCodecByName(name string) (CacheCodec, error) {
+	switch name {
+	case "", "json":
+		return JSONCodec{}, nil
+	case "msgpack":
+		return MsgpackCodec{}, nil
+	default:
+		return nil, fmt.Errorf("unknown cache codec: %s", name)
+	}
+}
+
+// RawCacheStore is implemented by cache backends that keep encoded bytes, such as the
+// in-memory cache or a Redis client. Typed caches encode values for them with the
+// backend's codec.
+type RawCacheStore interface {
+	GetRaw(ctx context.Context, key string) ([]byte, bool)
+	SetRaw(ctx context.Context, key string, data []byte, ttl time.Duration) error
+	Codec() CacheCodec
+}
+
+// TypedCache stores values of one type in a CacheService. Values read back always have
+// type T: an entry written with another codec or for another type, for example by an
+// older release, is treated as a miss and removed instead of failing a type assertion.
+type TypedCache[T any] struct {
+	cache CacheService
+	tag   []byte
+}
+
+// NewTypedCache creates a typed view of cache. A nil cache never hits.
+func NewTypedCache[T any](cache CacheService) *TypedCache[T] {
+	return &TypedCache[T]{
+		cache: cache,
+		tag:   []byte(reflect.TypeOf((*T)(nil)).Elem().String()),
+	}
+}
+
+// Get returns the cached value for key
+func (c *TypedCache[T]) Get(ctx context.Context, key string) (T, bool) {
+	var zero T
+	if c.cache == nil {
+		return zero, false
+	}
+
+	if raw, ok := c.cache.(RawCacheStore); ok {
+		data, found := raw.GetRaw(ctx, key)
+		if !found {
+			return zero, false
+		}
+		value, err := c.decode(raw.Codec(), data)
+		if err != nil {
+			c.cache.Delete(ctx, key)
+			return zero, false
+		}
+		return value, true
+	}
+
+	// Backends that keep values as given return them unchanged
+	cached, found := c.cache.GetDirect(ctx, key)
+	if !found {
+		return zero, false
+	}
+	value, ok := cached.(T)
+	if !ok {
+		c.cache.Delete(ctx, key)
+		return zero, false
+	}
+	return value, true
+}
+
+// Set caches value under key for ttl
+func (c *TypedCache[T]) Set(ctx context.Context, key string, value T, ttl time.Duration) error {
+	if c.cache == nil {
+		return nil
+	}
+
+	if raw, ok := c.cache.(RawCacheStore); ok {
+		data, err := c.encode(raw.Codec(), value)
+		if err != nil {
+			return err
+		}
+		return raw.SetRaw(ctx, key, data, ttl)
+	}
+	return c.cache.Set(ctx, key, value, ttl)
+}
+
+// Delete removes key
+func (c *TypedCache[T]) Delete(ctx context.Context, key string) error {
+	if c.cache == nil {
+		return nil
+	}
+	return c.cache.Delete(ctx, key)
+}
+
+// encode prefixes the encoded value with the codec name and the type it was encoded from:
+// codec NUL type NUL payload
+func (c *TypedCache[T]) encode(codec CacheCodec, value T) ([]byte, error) {
+	payload, err := codec.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cache value as %s: %w", codec.Name(), err)
+	}
+
+	data := make([]byte, 0, len(codec.Name())+len(c.tag)+2+len(payload))
+	data = append(data, codec.Name()...)
+	data = append(data, 0)
+	data = append(data, c.tag...)
+	data = append(data, 0)
+	return append(data, payload...), nil
+}
+
+// decode checks an entry's header against the codec and T before decoding its payload
+func (c *TypedCache[T]) decode(codec CacheCodec, data []byte) (T, error) {
+	var value T
+
+	name, rest, ok := bytes.Cut(data, []byte{0})
+	if !ok || string(name) != codec.Name() {
+		return value, fmt.Errorf("cache entry was not encoded as %s", codec.Name())
+	}
+	tag, payload, ok := bytes.Cut(rest, []byte{0})
+	if !ok || !bytes.Equal(tag, c.tag) {
+		return value, fmt.Errorf("cache entry does not hold %s", c.tag)
+	}
+	if err := codec.Unmarshal(payload, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cache value: %w", err)
+	}
+	return value, nil
+}
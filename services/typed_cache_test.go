@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"semantic-text-processor/models"
+)
+
+func newTypedCacheTestChunk() *models.UnifiedChunkRecord {
+	parent := "parent-1"
+	return &models.UnifiedChunkRecord{
+		ChunkID:     "chunk-1",
+		Contents:    "cached contents",
+		Parent:      &parent,
+		IsPage:      true,
+		Tags:        []string{"tag-1", "tag-2"},
+		Metadata:    map[string]interface{}{"status": "done"},
+		Version:     3,
+		CreatedTime: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC),
+		LastUpdated: time.Date(2024, 1, 16, 8, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestTypedCache_RoundTripsThroughCodecs(t *testing.T) {
+	for _, codec := range []CacheCodec{JSONCodec{}, MsgpackCodec{}} {
+		t.Run(codec.Name(), func(t *testing.T) {
+			ctx := context.Background()
+			backend := NewInMemoryCache(10, time.Minute)
+			defer backend.Stop()
+			backend.SetCodec(codec)
+
+			chunks := NewTypedCache[*models.UnifiedChunkRecord](backend)
+			require.NoError(t, chunks.Set(ctx, "chunk:chunk-1", newTypedCacheTestChunk(), time.Minute))
+
+			cached, found := chunks.Get(ctx, "chunk:chunk-1")
+			require.True(t, found)
+			want := newTypedCacheTestChunk()
+			assert.True(t, want.CreatedTime.Equal(cached.CreatedTime))
+			assert.True(t, want.LastUpdated.Equal(cached.LastUpdated))
+			cached.CreatedTime, cached.LastUpdated = want.CreatedTime, want.LastUpdated
+			assert.Equal(t, want, cached)
+
+			lists := NewTypedCache[[]models.UnifiedChunkRecord](backend)
+			require.NoError(t, lists.Set(ctx, "chunks_by_tag:tag-1", []models.UnifiedChunkRecord{*newTypedCacheTestChunk()}, time.Minute))
+			list, found := lists.Get(ctx, "chunks_by_tag:tag-1")
+			require.True(t, found)
+			require.Len(t, list, 1)
+			assert.Equal(t, "chunk-1", list[0].ChunkID)
+		})
+	}
+}
+
+func TestTypedCache_MismatchedEntriesAreMisses(t *testing.T) {
+	ctx := context.Background()
+	backend := NewInMemoryCache(10, time.Minute)
+	defer backend.Stop()
+
+	// An entry written for another type, e.g. by an older release
+	lists := NewTypedCache[[]models.UnifiedChunkRecord](backend)
+	require.NoError(t, lists.Set(ctx, "chunk:chunk-1", []models.UnifiedChunkRecord{*newTypedCacheTestChunk()}, time.Minute))
+
+	chunks := NewTypedCache[*models.UnifiedChunkRecord](backend)
+	_, found := chunks.Get(ctx, "chunk:chunk-1")
+	assert.False(t, found)
+	_, found = backend.GetRaw(ctx, "chunk:chunk-1")
+	assert.False(t, found, "mismatched entries are removed")
+
+	// Entries written by the untyped API or with another codec
+	require.NoError(t, backend.Set(ctx, "chunk:chunk-2", newTypedCacheTestChunk(), time.Minute))
+	_, found = chunks.Get(ctx, "chunk:chunk-2")
+	assert.False(t, found)
+
+	require.NoError(t, chunks.Set(ctx, "chunk:chunk-3", newTypedCacheTestChunk(), time.Minute))
+	backend.SetCodec(MsgpackCodec{})
+	_, found = chunks.Get(ctx, "chunk:chunk-3")
+	assert.False(t, found)
+}
+
+func TestTypedCache_DirectBackends(t *testing.T) {
+	ctx := context.Background()
+	backend := &MockCacheService{}
+	backend.On("GetDirect", mock.Anything, "chunk:chunk-1").Return(newTypedCacheTestChunk(), true)
+	backend.On("GetDirect", mock.Anything, "chunk:chunk-2").Return(map[string]interface{}{"chunk_id": "chunk-2"}, true)
+	backend.On("Delete", mock.Anything, "chunk:chunk-2").Return(nil)
+
+	chunks := NewTypedCache[*models.UnifiedChunkRecord](backend)
+	cached, found := chunks.Get(ctx, "chunk:chunk-1")
+	require.True(t, found)
+	assert.Equal(t, "chunk-1", cached.ChunkID)
+
+	_, found = chunks.Get(ctx, "chunk:chunk-2")
+	assert.False(t, found, "values of another type are misses, not panics")
+	backend.AssertExpectations(t)
+
+	disabled := NewTypedCache[*models.UnifiedChunkRecord](nil)
+	assert.NoError(t, disabled.Set(ctx, "chunk:chunk-1", newTypedCacheTestChunk(), time.Minute))
+	_, found = disabled.Get(ctx, "chunk:chunk-1")
+	assert.False(t, found)
+}
+
+func TestUnifiedChunkService_GetChunk_InMemoryCacheHit(t *testing.T) {
+	ctx := context.Background()
+	backend := NewInMemoryCache(10, time.Minute)
+	defer backend.Stop()
+
+	// The database is nil, so the chunk can only come from the cache
+	service := NewUnifiedChunkService(nil, backend, NewNoOpMonitor())
+	require.NoError(t, NewTypedCache[*models.UnifiedChunkRecord](backend).Set(ctx, "chunk:chunk-1", newTypedCacheTestChunk(), time.Minute))
+
+	chunk, err := service.GetChunk(ctx, "chunk-1")
+	require.NoError(t, err)
+	assert.Equal(t, newTypedCacheTestChunk(), chunk)
+}
+
+func TestCacheCodecByName(t *testing.T) {
+	codec, err := CacheCodecByName("")
+	require.NoError(t, err)
+	assert.Equal(t, "json", codec.Name())
+
+	codec, err = CacheCodecByName("msgpack")
+	require.NoError(t, err)
+	assert.Equal(t, "msgpack", codec.Name())
+
+	_, err = CacheCodecByName("gob")
+	assert.Error(t, err)
+}
@@ -11,24 +11,49 @@ type UnifiedChunkService interface {
 	CreateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error
 	GetChunk(ctx context.Context, chunkID string) (*models.UnifiedChunkRecord, error)
 	UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error
+	PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error)
 	DeleteChunk(ctx context.Context, chunkID string) error
 
 	// Batch operations
 	BatchCreateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error
 	BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error
+	// BatchGetChunks returns the chunks among chunkIDs that exist, keyed by chunk ID
+	BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error)
 
 	// Tag operations
 	AddTags(ctx context.Context, chunkID string, tagChunkIDs []string) error
 	RemoveTags(ctx context.Context, chunkID string, tagChunkIDs []string) error
+	// BatchAddTags adds tags to many chunks in one transaction, returning how many chunks
+	// gained a tag
+	BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error)
 	GetChunkTags(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error)
+	// BatchGetChunkTags returns the tags of many chunks in one query, keyed by chunk ID
+	BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error)
 	GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error)
 	GetChunksByTags(ctx context.Context, tagChunkIDs []string, matchType string) ([]models.UnifiedChunkRecord, error)
 
+	// Tag hierarchy operations
+	SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error
+	GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error)
+	GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error)
+	GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error)
+	GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error)
+	RenameTag(ctx context.Context, tagChunkID, newName string) error
+	MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error)
+
 	// Hierarchy operations
 	GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error)
+	// BatchGetChildren returns the direct children of many chunks in one query, keyed by
+	// parent chunk ID
+	BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error)
 	GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error)
 	GetAncestors(ctx context.Context, chunkID string) ([]models.UnifiedChunkRecord, error)
 	MoveChunk(ctx context.Context, chunkID, newParentID string) error
+	MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error)
+	CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error)
+	BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error)
+	DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error)
+	MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error)
 
 	// Search operations
 	SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error)
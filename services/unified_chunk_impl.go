@@ -3,21 +3,37 @@ package services
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"semantic-text-processor/database"
 	"semantic-text-processor/models"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lib/pq"
+	"golang.org/x/sync/singleflight"
 )
 
 // unifiedChunkService implements UnifiedChunkService interface
 type unifiedChunkService struct {
 	db      *sql.DB
+	router  *database.ReplicaRouter // optional; routes reads to replicas
+	pool    *pgxpool.Pool           // optional; serves primary reads natively and enables COPY
 	cache   CacheService
 	monitor QueryPerformanceMonitor
+
+	// chunkCache and chunkListCache read cached chunks back with their stored types
+	chunkCache     *TypedCache[*models.UnifiedChunkRecord]
+	chunkListCache *TypedCache[[]models.UnifiedChunkRecord]
+	// notFoundCache remembers chunk and tag IDs that did not exist, under keys of their own
+	notFoundCache *TypedCache[bool]
+
+	// loads shares one database load among concurrent cache misses for the same key;
+	// writes counts mutations so that a miss after a write never joins an older load
+	loads  singleflight.Group
+	writes atomic.Uint64
 }
 
 // NewUnifiedChunkService creates a new instance of UnifiedChunkService
@@ -26,6 +42,91 @@ func NewUnifiedChunkService(db *sql.DB, cache CacheService, monitor QueryPerform
 		db:      db,
 		cache:   cache,
 		monitor: monitor,
+
+		chunkCache:     NewTypedCache[*models.UnifiedChunkRecord](cache),
+		chunkListCache: NewTypedCache[[]models.UnifiedChunkRecord](cache),
+		notFoundCache:  NewTypedCache[bool](cache),
+	}
+}
+
+// NewReplicaAwareUnifiedChunkService creates a UnifiedChunkService that sends mutations to the
+// router's primary and read queries to replicas within the configured replication lag
+func NewReplicaAwareUnifiedChunkService(router *database.ReplicaRouter, cache CacheService, monitor QueryPerformanceMonitor) UnifiedChunkService {
+	return &unifiedChunkService{
+		db:      router.Primary(),
+		router:  router,
+		cache:   cache,
+		monitor: monitor,
+
+		chunkCache:     NewTypedCache[*models.UnifiedChunkRecord](cache),
+		chunkListCache: NewTypedCache[[]models.UnifiedChunkRecord](cache),
+		notFoundCache:  NewTypedCache[bool](cache),
+	}
+}
+
+// reader returns the handle for read-only queries
+func (s *unifiedChunkService) reader(ctx context.Context) *sql.DB {
+	if s.router == nil {
+		return s.db
+	}
+	return s.router.Reader(ctx)
+}
+
+// queryRows runs a read query and reports it to the monitor when it is slow, so that its
+// plan can be captured
+func (s *unifiedChunkService) queryRows(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := s.reader(ctx).QueryContext(ctx, query, args...)
+	if err == nil && s.monitor != nil {
+		RecordStatement(s.monitor, query, time.Since(start), args...)
+	}
+	return rows, err
+}
+
+// sharedLoadTimeout bounds a load shared by concurrent cache misses, which runs detached
+// from the cancellation of the caller that started it
+const sharedLoadTimeout = 30 * time.Second
+
+// loadShared runs load once for concurrent misses of cacheKey and fans its result out to
+// every waiting caller, so a burst of identical misses costs one query. A caller that gives
+// up stops waiting without failing the load for the others. Results are shared, as cached
+// values already are, and must not be modified.
+func loadShared[T any](ctx context.Context, s *unifiedChunkService, cacheKey string, load func(context.Context) (T, error)) (T, error) {
+	key := fmt.Sprintf("%s@%d", cacheKey, s.writes.Load())
+	results := s.loads.DoChan(key, func() (value interface{}, err error) {
+		// The load runs on its own goroutine, where a panic would take down the process
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("failed to load %s: %v", cacheKey, r)
+			}
+		}()
+		loadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sharedLoadTimeout)
+		defer cancel()
+		return load(loadCtx)
+	})
+
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			var zero T
+			return zero, result.Err
+		}
+		if result.Shared && s.monitor != nil {
+			s.monitor.RecordQuery("shared_cache_miss", 0, 1)
+		}
+		return result.Val.(T), nil
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// markWrite tells the replica router that a mutation committed. It is called from the
+// cache invalidation helpers, which every mutation already goes through.
+func (s *unifiedChunkService) markWrite() {
+	s.writes.Add(1)
+	if s.router != nil {
+		s.router.MarkWrite()
 	}
 }
 
@@ -47,26 +148,33 @@ func (s *unifiedChunkService) CreateChunk(ctx context.Context, chunk *models.Uni
 	now := time.Now()
 	chunk.CreatedTime = now
 	chunk.LastUpdated = now
+	chunk.Version = 1
+	chunk.Lang = DetectLanguage(chunk.Contents)
 
-	query := `
-		INSERT INTO chunks (
-			chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
-			ref, tags, metadata, created_time, last_updated
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
-		)`
-
-	_, err := s.db.ExecContext(ctx, query,
-		chunk.ChunkID, chunk.Contents, chunk.Parent, chunk.Page,
-		chunk.IsPage, chunk.IsTag, chunk.IsTemplate, chunk.IsSlot,
-		chunk.Ref, pq.Array(chunk.Tags), chunk.Metadata,
-		chunk.CreatedTime, chunk.LastUpdated,
-	)
+	args, err := chunkInsertArgs(chunk)
+	if err != nil {
+		return err
+	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, chunkInsertQuery, args...)
 	if err != nil {
 		return fmt.Errorf("failed to create chunk: %w", err)
 	}
 
+	if err = insertSubtreeHierarchyTx(ctx, tx, []string{chunk.ChunkID}); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Invalidate related caches
 	s.invalidateChunkCaches(ctx, chunk.ChunkID)
 
@@ -84,76 +192,130 @@ func (s *unifiedChunkService) GetChunk(ctx context.Context, chunkID string) (*mo
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunk:%s", chunkID)
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.(*models.UnifiedChunkRecord), nil
+	if chunk, found := s.chunkCache.Get(ctx, cacheKey); found {
+		return chunk, nil
+	}
+	if s.cachedNotFound(ctx, notFoundChunkKey(chunkID)) {
+		return nil, fmt.Errorf("chunk not found: %s", chunkID)
 	}
 
-	query := `
-		SELECT chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
-			   ref, tags, metadata, created_time, last_updated
-		FROM chunks
-		WHERE chunk_id = $1`
-
-	var chunk models.UnifiedChunkRecord
-	var tags pq.StringArray
-	var metadataBytes []byte
-
-	err := s.db.QueryRowContext(ctx, query, chunkID).Scan(
-		&chunk.ChunkID, &chunk.Contents, &chunk.Parent, &chunk.Page,
-		&chunk.IsPage, &chunk.IsTag, &chunk.IsTemplate, &chunk.IsSlot,
-		&chunk.Ref, &tags, &metadataBytes,
-		&chunk.CreatedTime, &chunk.LastUpdated,
-	)
+	return loadShared(ctx, s, cacheKey, func(ctx context.Context) (*models.UnifiedChunkRecord, error) {
+		return s.loadChunk(ctx, cacheKey, chunkID)
+	})
+}
+
+// loadChunk loads a chunk and caches it
+func (s *unifiedChunkService) loadChunk(ctx context.Context, cacheKey string, chunkID string) (*models.UnifiedChunkRecord, error) {
+	query := `SELECT ` + chunkColumnList("", chunkColumnRegistry) + ` FROM chunks WHERE chunk_id = $1`
 
+	writes := s.writes.Load()
+	chunk, err := scanChunkColumns(s.queryRow(ctx, query, chunkID), chunkColumnRegistry)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
+			s.cacheNotFound(ctx, notFoundChunkKey(chunkID), writes)
 			return nil, fmt.Errorf("chunk not found: %s", chunkID)
 		}
 		return nil, fmt.Errorf("failed to get chunk: %w", err)
 	}
 
-	chunk.Tags = []string(tags)
+	// Cache the result
+	s.chunkCache.Set(ctx, cacheKey, chunk, 5*time.Minute)
+
+	return chunk, nil
+}
+
+// BatchGetChunks retrieves many chunks, serving cached chunks from the cache and loading
+// the rest in one query. IDs of chunks that do not exist are left out of the result.
+func (s *unifiedChunkService) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	chunks := make(map[string]*models.UnifiedChunkRecord, len(chunkIDs))
+	defer func() {
+		s.monitor.RecordQuery("batch_get_chunks", time.Since(start), len(chunks))
+	}()
 
-	// Parse metadata JSON if present
-	if len(metadataBytes) > 0 {
-		if err := json.Unmarshal(metadataBytes, &chunk.Metadata); err != nil {
-			log.Printf("Warning: failed to parse metadata for chunk %s: %v", chunkID, err)
-			chunk.Metadata = make(map[string]interface{})
+	var missing []string
+	for _, chunkID := range validChunkIDs(chunkIDs) {
+		if chunk, found := s.chunkCache.Get(ctx, fmt.Sprintf("chunk:%s", chunkID)); found {
+			chunks[chunkID] = chunk
+		} else if !s.cachedNotFound(ctx, notFoundChunkKey(chunkID)) {
+			missing = append(missing, chunkID)
 		}
-	} else {
-		chunk.Metadata = make(map[string]interface{})
 	}
+	if len(missing) == 0 {
+		return chunks, nil
+	}
+	writes := s.writes.Load()
 
-	// Cache the result
-	s.cache.Set(ctx, cacheKey, &chunk, 5*time.Minute)
+	query := `SELECT ` + chunkColumnList("", chunkColumnRegistry) + ` FROM chunks WHERE chunk_id = ANY($1)`
+
+	loaded, err := s.queryChunks(ctx, chunkColumnRegistry, query, pq.Array(missing))
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch get chunks: %w", err)
+	}
+
+	for _, chunk := range loaded {
+		s.chunkCache.Set(ctx, fmt.Sprintf("chunk:%s", chunk.ChunkID), chunk, 5*time.Minute)
+		chunks[chunk.ChunkID] = chunk
+	}
+
+	for _, chunkID := range missing {
+		if _, found := chunks[chunkID]; !found {
+			s.cacheNotFound(ctx, notFoundChunkKey(chunkID), writes)
+		}
+	}
+	return chunks, nil
+}
 
-	return &chunk, nil
+// validChunkIDs returns the distinct chunk IDs that are UUIDs. Other IDs cannot exist, and
+// would fail a whole batch query.
+func validChunkIDs(chunkIDs []string) []string {
+	seen := make(map[string]bool, len(chunkIDs))
+	valid := make([]string, 0, len(chunkIDs))
+	for _, chunkID := range chunkIDs {
+		if seen[chunkID] {
+			continue
+		}
+		seen[chunkID] = true
+		if _, err := uuid.Parse(chunkID); err == nil {
+			valid = append(valid, chunkID)
+		}
+	}
+	return valid
 }
 
-// UpdateChunk updates an existing chunk
+// UpdateChunk updates an existing chunk if chunk.Version is still the stored version,
+// returning a *VersionConflictError otherwise. On success chunk.Version is the new version.
 func (s *unifiedChunkService) UpdateChunk(ctx context.Context, chunk *models.UnifiedChunkRecord) error {
 	start := time.Now()
 	defer func() {
 		s.monitor.RecordQuery("update_chunk", time.Since(start), 1)
 	}()
 
+	if err := requireExpectedVersion(chunk.ChunkID, chunk.Version); err != nil {
+		return err
+	}
+
 	// Update timestamp
 	chunk.LastUpdated = time.Now()
+	chunk.Lang = DetectLanguage(chunk.Contents)
 
-	query := `
-		UPDATE chunks SET
-			contents = $2, parent = $3, page = $4, is_page = $5, is_tag = $6,
-			is_template = $7, is_slot = $8, ref = $9, tags = $10, metadata = $11,
-			last_updated = $12
-		WHERE chunk_id = $1`
-
-	result, err := s.db.ExecContext(ctx, query,
-		chunk.ChunkID, chunk.Contents, chunk.Parent, chunk.Page,
-		chunk.IsPage, chunk.IsTag, chunk.IsTemplate, chunk.IsSlot,
-		chunk.Ref, pq.Array(chunk.Tags), chunk.Metadata,
-		chunk.LastUpdated,
-	)
+	args, err := chunkUpdateArgs(chunk)
+	if err != nil {
+		return err
+	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	moved, err := parentChangesTx(ctx, tx, chunk.ChunkID, chunk.Parent)
+	if err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, chunkUpdateQuery, args...)
 	if err != nil {
 		return fmt.Errorf("failed to update chunk: %w", err)
 	}
@@ -164,8 +326,19 @@ func (s *unifiedChunkService) UpdateChunk(ctx context.Context, chunk *models.Uni
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("chunk not found: %s", chunk.ChunkID)
+		return explainVersionMismatch(ctx, tx, chunk.ChunkID, chunk.Version)
+	}
+
+	if moved {
+		if _, err = rebuildSubtreeHierarchyTx(ctx, tx, []string{chunk.ChunkID}); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
+	chunk.Version++
 
 	// Invalidate related caches
 	s.invalidateChunkCaches(ctx, chunk.ChunkID)
@@ -180,9 +353,21 @@ func (s *unifiedChunkService) DeleteChunk(ctx context.Context, chunkID string) e
 		s.monitor.RecordQuery("delete_chunk", time.Since(start), 1)
 	}()
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Children become roots when their parent goes, so their subtrees lose every ancestor
+	children, err := childChunkIDsTx(ctx, tx, chunkID)
+	if err != nil {
+		return err
+	}
+
 	query := `DELETE FROM chunks WHERE chunk_id = $1`
 
-	result, err := s.db.ExecContext(ctx, query, chunkID)
+	result, err := tx.ExecContext(ctx, query, chunkID)
 	if err != nil {
 		return fmt.Errorf("failed to delete chunk: %w", err)
 	}
@@ -196,6 +381,16 @@ func (s *unifiedChunkService) DeleteChunk(ctx context.Context, chunkID string) e
 		return fmt.Errorf("chunk not found: %s", chunkID)
 	}
 
+	if len(children) > 0 {
+		if _, err = rebuildSubtreeHierarchyTx(ctx, tx, children); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
 	// Invalidate related caches
 	s.invalidateChunkCaches(ctx, chunkID)
 
@@ -213,26 +408,25 @@ func (s *unifiedChunkService) BatchCreateChunks(ctx context.Context, chunks []mo
 		return nil
 	}
 
-	tx, err := s.db.BeginTx(ctx, nil)
+	// COPY runs on the connection underneath the transaction, so with the pgx pool the
+	// transaction is begun on a connection held for the whole batch
+	var conn *sql.Conn
+	var tx *sql.Tx
+	var err error
+	if s.pool != nil {
+		if conn, err = s.db.Conn(ctx); err != nil {
+			return fmt.Errorf("failed to get connection: %w", err)
+		}
+		defer conn.Close()
+		tx, err = conn.BeginTx(ctx, nil)
+	} else {
+		tx, err = s.db.BeginTx(ctx, nil)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	query := `
-		INSERT INTO chunks (
-			chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
-			ref, tags, metadata, created_time, last_updated
-		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
-		)`
-
-	stmt, err := tx.PrepareContext(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
-	}
-	defer stmt.Close()
-
 	now := time.Now()
 	for i := range chunks {
 		chunk := &chunks[i]
@@ -242,20 +436,29 @@ func (s *unifiedChunkService) BatchCreateChunks(ctx context.Context, chunks []mo
 			chunk.ChunkID = uuid.New().String()
 		}
 
-		// Set timestamps
-		chunk.CreatedTime = now
-		chunk.LastUpdated = now
+		// Set timestamps. Siblings are ordered by creation time, so each chunk is a
+		// microsecond, the column's precision, after the one before it to keep the batch order.
+		chunk.CreatedTime = now.Add(time.Duration(i) * time.Microsecond)
+		chunk.LastUpdated = chunk.CreatedTime
+		chunk.Version = 1
+		chunk.Lang = DetectLanguage(chunk.Contents)
+	}
 
-		_, err = stmt.ExecContext(ctx,
-			chunk.ChunkID, chunk.Contents, chunk.Parent, chunk.Page,
-			chunk.IsPage, chunk.IsTag, chunk.IsTemplate, chunk.IsSlot,
-			chunk.Ref, pq.Array(chunk.Tags), chunk.Metadata,
-			chunk.CreatedTime, chunk.LastUpdated,
-		)
+	if conn != nil {
+		err = copyChunksTx(ctx, conn, chunks)
+	} else {
+		err = insertChunksTx(ctx, tx, chunks)
+	}
+	if err != nil {
+		return err
+	}
 
-		if err != nil {
-			return fmt.Errorf("failed to insert chunk %s: %w", chunk.ChunkID, err)
-		}
+	chunkIDs := make([]string, len(chunks))
+	for i := range chunks {
+		chunkIDs[i] = chunks[i].ChunkID
+	}
+	if err = insertSubtreeHierarchyTx(ctx, tx, chunkIDs); err != nil {
+		return err
 	}
 
 	if err = tx.Commit(); err != nil {
@@ -270,7 +473,8 @@ func (s *unifiedChunkService) BatchCreateChunks(ctx context.Context, chunks []mo
 	return nil
 }
 
-// BatchUpdateChunks updates multiple chunks in a single transaction
+// BatchUpdateChunks updates multiple chunks in a single transaction. Every chunk must carry
+// its expected version; one conflict rolls back the whole batch.
 func (s *unifiedChunkService) BatchUpdateChunks(ctx context.Context, chunks []models.UnifiedChunkRecord) error {
 	start := time.Now()
 	defer func() {
@@ -281,46 +485,71 @@ func (s *unifiedChunkService) BatchUpdateChunks(ctx context.Context, chunks []mo
 		return nil
 	}
 
+	for i := range chunks {
+		if err := requireExpectedVersion(chunks[i].ChunkID, chunks[i].Version); err != nil {
+			return err
+		}
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	query := `
-		UPDATE chunks SET
-			contents = $2, parent = $3, page = $4, is_page = $5, is_tag = $6,
-			is_template = $7, is_slot = $8, ref = $9, tags = $10, metadata = $11,
-			last_updated = $12
-		WHERE chunk_id = $1`
-
-	stmt, err := tx.PrepareContext(ctx, query)
+	stmt, err := tx.PrepareContext(ctx, chunkUpdateQuery)
 	if err != nil {
 		return fmt.Errorf("failed to prepare statement: %w", err)
 	}
 	defer stmt.Close()
 
 	now := time.Now()
+	var movedIDs []string
 	for i := range chunks {
 		chunk := &chunks[i]
 		chunk.LastUpdated = now
+		chunk.Lang = DetectLanguage(chunk.Contents)
 
-		_, err = stmt.ExecContext(ctx,
-			chunk.ChunkID, chunk.Contents, chunk.Parent, chunk.Page,
-			chunk.IsPage, chunk.IsTag, chunk.IsTemplate, chunk.IsSlot,
-			chunk.Ref, pq.Array(chunk.Tags), chunk.Metadata,
-			chunk.LastUpdated,
-		)
+		moved, err := parentChangesTx(ctx, tx, chunk.ChunkID, chunk.Parent)
+		if err != nil {
+			return err
+		}
+		if moved {
+			movedIDs = append(movedIDs, chunk.ChunkID)
+		}
 
+		args, err := chunkUpdateArgs(chunk)
+		if err != nil {
+			return err
+		}
+		result, err := stmt.ExecContext(ctx, args...)
 		if err != nil {
 			return fmt.Errorf("failed to update chunk %s: %w", chunk.ChunkID, err)
 		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to get rows affected: %w", err)
+		}
+		if rowsAffected == 0 {
+			return explainVersionMismatch(ctx, tx, chunk.ChunkID, chunk.Version)
+		}
+	}
+
+	if len(movedIDs) > 0 {
+		if _, err = rebuildSubtreeHierarchyTx(ctx, tx, movedIDs); err != nil {
+			return err
+		}
 	}
 
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
+	for i := range chunks {
+		chunks[i].Version++
+	}
+
 	// Invalidate caches for all updated chunks
 	for _, chunk := range chunks {
 		s.invalidateChunkCaches(ctx, chunk.ChunkID)
@@ -331,22 +560,63 @@ func (s *unifiedChunkService) BatchUpdateChunks(ctx context.Context, chunks []mo
 
 // Helper methods for cache management and query execution
 func (s *unifiedChunkService) invalidateChunkCaches(ctx context.Context, chunkID string) {
-	patterns := []string{
+	s.markWrite()
+
+	for _, pattern := range chunkCacheInvalidationPatterns(chunkID) {
+		s.cache.DeletePattern(ctx, pattern)
+	}
+}
+
+// chunkCacheInvalidationPatterns lists the cache keys a change to chunkID makes stale
+func chunkCacheInvalidationPatterns(chunkID string) []string {
+	return []string{
 		fmt.Sprintf("chunk:%s", chunkID),
 		fmt.Sprintf("chunk_tags:%s", chunkID),
 		fmt.Sprintf("chunk_children:%s", chunkID),
 		fmt.Sprintf("chunk_descendants:%s:*", chunkID),
 		fmt.Sprintf("chunk_ancestors:%s", chunkID),
+		notFoundChunkKey(chunkID),
+		notFoundTagKey(chunkID),
 		"chunks_by_tag:*",
 		"chunks_by_tags:*",
 		"chunk_children:*",
 		"chunk_descendants:*",
 		"chunk_ancestors:*",
 	}
+}
 
-	for _, pattern := range patterns {
-		s.cache.DeletePattern(ctx, pattern)
+// notFoundCacheTTL is how long a missing chunk or tag is remembered. Creating the chunk
+// through the gateway forgets it at once; the TTL bounds how long a chunk created behind
+// the gateway's back keeps reading as missing.
+const notFoundCacheTTL = 30 * time.Second
+
+func notFoundChunkKey(chunkID string) string {
+	return fmt.Sprintf("not_found:chunk:%s", chunkID)
+}
+
+func notFoundTagKey(tagChunkID string) string {
+	return fmt.Sprintf("not_found:tag:%s", tagChunkID)
+}
+
+// cachedNotFound reports whether key records a recent lookup that found nothing
+func (s *unifiedChunkService) cachedNotFound(ctx context.Context, key string) bool {
+	missing, found := s.notFoundCache.Get(ctx, key)
+	if !found || !missing {
+		return false
+	}
+	if s.monitor != nil {
+		s.monitor.RecordQuery("not_found_cache_hit", 0, 1)
 	}
+	return true
+}
+
+// cacheNotFound records that a lookup found nothing, unless a write committed since the
+// lookup started: the write may have created what the lookup missed
+func (s *unifiedChunkService) cacheNotFound(ctx context.Context, key string, writesBefore uint64) {
+	if s.writes.Load() != writesBefore {
+		return
+	}
+	s.notFoundCache.Set(ctx, key, true, notFoundCacheTTL)
 }
 
 // ============================================================================
@@ -412,7 +682,7 @@ func (s *unifiedChunkService) AddTags(ctx context.Context, chunkID string, tagCh
 
 	// Update main table with merged tags
 	_, err = tx.ExecContext(ctx, 
-		"UPDATE chunks SET tags = $1, last_updated = NOW() WHERE chunk_id = $2",
+		"UPDATE chunks SET tags = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
 		pq.Array(allTags), chunkID)
 	if err != nil {
 		return fmt.Errorf("failed to update main table tags: %w", err)
@@ -481,7 +751,7 @@ func (s *unifiedChunkService) RemoveTags(ctx context.Context, chunkID string, ta
 
 	// Update main table with remaining tags
 	_, err = tx.ExecContext(ctx,
-		"UPDATE chunks SET tags = $1, last_updated = NOW() WHERE chunk_id = $2",
+		"UPDATE chunks SET tags = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
 		pq.Array(remainingTags), chunkID)
 	if err != nil {
 		return fmt.Errorf("failed to update main table tags: %w", err)
@@ -514,56 +784,79 @@ func (s *unifiedChunkService) GetChunkTags(ctx context.Context, chunkID string)
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunk_tags:%s", chunkID)
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.([]models.UnifiedChunkRecord), nil
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
+	}
+
+	tags, err := loadShared(ctx, s, cacheKey, func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		return s.loadChunkTags(ctx, cacheKey, chunkID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	// Update performance metrics
+	s.monitor.RecordQuery("get_chunk_tags", time.Since(start), len(tags))
+
+	return tags, nil
+}
+
+// loadChunkTags loads the tags of a chunk and caches them
+func (s *unifiedChunkService) loadChunkTags(ctx context.Context, cacheKey string, chunkID string) ([]models.UnifiedChunkRecord, error) {
 	query := `
-		SELECT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag, 
-			   c.is_template, c.is_slot, c.ref, c.tags, c.metadata, 
-			   c.created_time, c.last_updated
+		SELECT ` + unifiedChunkColumns + `
 		FROM chunks c
 		JOIN chunk_tags ct ON c.chunk_id = ct.tag_chunk_id
 		WHERE ct.source_chunk_id = $1 AND c.is_tag = true
 		ORDER BY c.contents ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, chunkID)
+	rows, err := s.queryRows(ctx, query, chunkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chunk tags: %w", err)
 	}
 	defer rows.Close()
 
-	var tags []models.UnifiedChunkRecord
-	for rows.Next() {
-		var tag models.UnifiedChunkRecord
-		var tagArray pq.StringArray
-
-		err := rows.Scan(
-			&tag.ChunkID, &tag.Contents, &tag.Parent, &tag.Page,
-			&tag.IsPage, &tag.IsTag, &tag.IsTemplate, &tag.IsSlot,
-			&tag.Ref, &tagArray, &tag.Metadata,
-			&tag.CreatedTime, &tag.LastUpdated,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan tag row: %w", err)
-		}
-
-		tag.Tags = []string(tagArray)
-		tags = append(tags, tag)
+	tags, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating tag rows: %w", err)
+	// Cache the result
+	s.chunkListCache.Set(ctx, cacheKey, tags, 5*time.Minute)
+
+	return tags, nil
+}
+
+// BatchGetChunkTags retrieves the tags of many chunks in one query
+func (s *unifiedChunkService) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	rowCount := 0
+	defer func() {
+		s.monitor.RecordQuery("batch_get_chunk_tags", time.Since(start), rowCount)
+	}()
+
+	chunkIDs = validChunkIDs(chunkIDs)
+	if len(chunkIDs) == 0 {
+		return map[string][]models.UnifiedChunkRecord{}, nil
 	}
 
-	// Cache the result
-	s.cache.Set(ctx, cacheKey, tags, 5*time.Minute)
+	query := `
+		SELECT ct.source_chunk_id, ` + unifiedChunkColumns + `
+		FROM chunks c
+		JOIN chunk_tags ct ON c.chunk_id = ct.tag_chunk_id
+		WHERE ct.source_chunk_id = ANY($1) AND c.is_tag = true
+		ORDER BY c.contents ASC
+	`
 
-	// Update performance metrics
-	s.monitor.RecordQuery("get_chunk_tags", time.Since(start), len(tags))
+	rows, err := s.queryRows(ctx, query, pq.Array(chunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch query chunk tags: %w", err)
+	}
+	defer rows.Close()
 
-	return tags, nil
+	tags, rowCount, err := scanKeyedUnifiedChunks(rows)
+	return tags, err
 }
 
 // GetChunksByTag retrieves all chunks that have a specific tag
@@ -575,15 +868,35 @@ func (s *unifiedChunkService) GetChunksByTag(ctx context.Context, tagChunkID str
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunks_by_tag:%s", tagChunkID)
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.([]models.UnifiedChunkRecord), nil
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
+	}
+	if s.cachedNotFound(ctx, notFoundTagKey(tagChunkID)) {
+		return nil, fmt.Errorf("tag chunk not found: %s", tagChunkID)
+	}
+
+	chunks, err := loadShared(ctx, s, cacheKey, func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		return s.loadChunksByTag(ctx, cacheKey, tagChunkID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	// Update performance metrics
+	s.monitor.RecordQuery("get_chunks_by_tag", time.Since(start), len(chunks))
+
+	return chunks, nil
+}
+
+// loadChunksByTag loads the chunks with a tag and caches them
+func (s *unifiedChunkService) loadChunksByTag(ctx context.Context, cacheKey string, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
 	// Validate that the tag chunk exists and is actually a tag
 	var isTag bool
-	err := s.db.QueryRowContext(ctx, "SELECT is_tag FROM chunks WHERE chunk_id = $1", tagChunkID).Scan(&isTag)
+	writes := s.writes.Load()
+	err := s.reader(ctx).QueryRowContext(ctx, "SELECT is_tag FROM chunks WHERE chunk_id = $1", tagChunkID).Scan(&isTag)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			s.cacheNotFound(ctx, notFoundTagKey(tagChunkID), writes)
 			return nil, fmt.Errorf("tag chunk not found: %s", tagChunkID)
 		}
 		return nil, fmt.Errorf("failed to validate tag chunk: %w", err)
@@ -593,49 +906,26 @@ func (s *unifiedChunkService) GetChunksByTag(ctx context.Context, tagChunkID str
 	}
 
 	query := `
-		SELECT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag,
-			   c.is_template, c.is_slot, c.ref, c.tags, c.metadata,
-			   c.created_time, c.last_updated
+		SELECT ` + unifiedChunkColumns + `
 		FROM chunks c
 		JOIN chunk_tags ct ON c.chunk_id = ct.source_chunk_id
 		WHERE ct.tag_chunk_id = $1
 		ORDER BY c.created_time DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, tagChunkID)
+	rows, err := s.queryRows(ctx, query, tagChunkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chunks by tag: %w", err)
 	}
 	defer rows.Close()
 
-	var chunks []models.UnifiedChunkRecord
-	for rows.Next() {
-		var chunk models.UnifiedChunkRecord
-		var tagArray pq.StringArray
-
-		err := rows.Scan(
-			&chunk.ChunkID, &chunk.Contents, &chunk.Parent, &chunk.Page,
-			&chunk.IsPage, &chunk.IsTag, &chunk.IsTemplate, &chunk.IsSlot,
-			&chunk.Ref, &tagArray, &chunk.Metadata,
-			&chunk.CreatedTime, &chunk.LastUpdated,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan chunk row: %w", err)
-		}
-
-		chunk.Tags = []string(tagArray)
-		chunks = append(chunks, chunk)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating chunk rows: %w", err)
+	chunks, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Cache the result
-	s.cache.Set(ctx, cacheKey, chunks, 5*time.Minute)
-
-	// Update performance metrics
-	s.monitor.RecordQuery("get_chunks_by_tag", time.Since(start), len(chunks))
+	s.chunkListCache.Set(ctx, cacheKey, chunks, 5*time.Minute)
 
 	return chunks, nil
 }
@@ -658,14 +948,29 @@ func (s *unifiedChunkService) GetChunksByTags(ctx context.Context, tagChunkIDs [
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunks_by_tags:%s:%s", matchType, fmt.Sprintf("%v", tagChunkIDs))
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.([]models.UnifiedChunkRecord), nil
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
+	}
+
+	chunks, err := loadShared(ctx, s, cacheKey, func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		return s.loadChunksByTags(ctx, cacheKey, tagChunkIDs, matchType)
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	// Update performance metrics
+	s.monitor.RecordQuery("get_chunks_by_tags", time.Since(start), len(chunks))
+
+	return chunks, nil
+}
+
+// loadChunksByTags loads the chunks matching tags and caches them
+func (s *unifiedChunkService) loadChunksByTags(ctx context.Context, cacheKey string, tagChunkIDs []string, matchType string) ([]models.UnifiedChunkRecord, error) {
 	// Validate that all tag chunks exist and are actually tags
 	for _, tagID := range tagChunkIDs {
 		var isTag bool
-		err := s.db.QueryRowContext(ctx, "SELECT is_tag FROM chunks WHERE chunk_id = $1", tagID).Scan(&isTag)
+		err := s.reader(ctx).QueryRowContext(ctx, "SELECT is_tag FROM chunks WHERE chunk_id = $1", tagID).Scan(&isTag)
 		if err != nil {
 			if err == sql.ErrNoRows {
 				return nil, fmt.Errorf("tag chunk not found: %s", tagID)
@@ -683,9 +988,7 @@ func (s *unifiedChunkService) GetChunksByTags(ctx context.Context, tagChunkIDs [
 	if matchType == "AND" {
 		// AND logic: chunks must have ALL specified tags
 		query = `
-			SELECT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag,
-				   c.is_template, c.is_slot, c.ref, c.tags, c.metadata,
-				   c.created_time, c.last_updated
+			SELECT ` + unifiedChunkColumns + `
 			FROM chunks c
 			WHERE c.chunk_id IN (
 				SELECT source_chunk_id 
@@ -700,9 +1003,7 @@ func (s *unifiedChunkService) GetChunksByTags(ctx context.Context, tagChunkIDs [
 	} else {
 		// OR logic: chunks must have ANY of the specified tags
 		query = `
-			SELECT DISTINCT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag,
-				   c.is_template, c.is_slot, c.ref, c.tags, c.metadata,
-				   c.created_time, c.last_updated
+			SELECT DISTINCT ` + unifiedChunkColumns + `
 			FROM chunks c
 			JOIN chunk_tags ct ON c.chunk_id = ct.source_chunk_id
 			WHERE ct.tag_chunk_id = ANY($1)
@@ -711,46 +1012,27 @@ func (s *unifiedChunkService) GetChunksByTags(ctx context.Context, tagChunkIDs [
 		args = []interface{}{pq.Array(tagChunkIDs)}
 	}
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.queryRows(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query chunks by tags: %w", err)
 	}
 	defer rows.Close()
 
-	var chunks []models.UnifiedChunkRecord
-	for rows.Next() {
-		var chunk models.UnifiedChunkRecord
-		var tagArray pq.StringArray
-
-		err := rows.Scan(
-			&chunk.ChunkID, &chunk.Contents, &chunk.Parent, &chunk.Page,
-			&chunk.IsPage, &chunk.IsTag, &chunk.IsTemplate, &chunk.IsSlot,
-			&chunk.Ref, &tagArray, &chunk.Metadata,
-			&chunk.CreatedTime, &chunk.LastUpdated,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan chunk row: %w", err)
-		}
-
-		chunk.Tags = []string(tagArray)
-		chunks = append(chunks, chunk)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating chunk rows: %w", err)
+	chunks, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Cache the result
-	s.cache.Set(ctx, cacheKey, chunks, 5*time.Minute)
-
-	// Update performance metrics
-	s.monitor.RecordQuery("get_chunks_by_tags", time.Since(start), len(chunks))
+	s.chunkListCache.Set(ctx, cacheKey, chunks, 5*time.Minute)
 
 	return chunks, nil
 }
 
 // Helper function to invalidate tag-related caches
 func (s *unifiedChunkService) invalidateTagCaches(ctx context.Context, chunkID string, tagChunkIDs []string) {
+	s.markWrite()
+
 	patterns := []string{
 		fmt.Sprintf("chunk_tags:%s", chunkID),
 		"chunks_by_tag:*",
@@ -780,13 +1062,28 @@ func (s *unifiedChunkService) GetChildren(ctx context.Context, parentChunkID str
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunk_children:%s", parentChunkID)
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.([]models.UnifiedChunkRecord), nil
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
 	}
 
+	children, err := loadShared(ctx, s, cacheKey, func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		return s.loadChildren(ctx, cacheKey, parentChunkID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Update performance metrics
+	s.monitor.RecordQuery("get_children", time.Since(start), len(children))
+
+	return children, nil
+}
+
+// loadChildren loads the direct children of a chunk and caches them
+func (s *unifiedChunkService) loadChildren(ctx context.Context, cacheKey string, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
 	// Validate that parent chunk exists
 	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", parentChunkID).Scan(&exists)
+	err := s.reader(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", parentChunkID).Scan(&exists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate parent chunk: %w", err)
 	}
@@ -796,54 +1093,63 @@ func (s *unifiedChunkService) GetChildren(ctx context.Context, parentChunkID str
 
 	// Query direct children using the hierarchy auxiliary table for optimal performance
 	query := `
-		SELECT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag,
-			   c.is_template, c.is_slot, c.ref, c.tags, c.metadata,
-			   c.created_time, c.last_updated
+		SELECT ` + unifiedChunkColumns + `
 		FROM chunks c
 		JOIN chunk_hierarchy ch ON c.chunk_id = ch.descendant_id
 		WHERE ch.ancestor_id = $1 AND ch.depth = 1
 		ORDER BY c.created_time ASC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, parentChunkID)
+	rows, err := s.queryRows(ctx, query, parentChunkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query children: %w", err)
 	}
 	defer rows.Close()
 
-	var children []models.UnifiedChunkRecord
-	for rows.Next() {
-		var child models.UnifiedChunkRecord
-		var tagArray pq.StringArray
-
-		err := rows.Scan(
-			&child.ChunkID, &child.Contents, &child.Parent, &child.Page,
-			&child.IsPage, &child.IsTag, &child.IsTemplate, &child.IsSlot,
-			&child.Ref, &tagArray, &child.Metadata,
-			&child.CreatedTime, &child.LastUpdated,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan child row: %w", err)
-		}
-
-		child.Tags = []string(tagArray)
-		children = append(children, child)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating child rows: %w", err)
+	children, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
 	}
 
 	// Cache the result
-	s.cache.Set(ctx, cacheKey, children, 5*time.Minute)
-
-	// Update performance metrics
-	s.monitor.RecordQuery("get_children", time.Since(start), len(children))
+	s.chunkListCache.Set(ctx, cacheKey, children, 5*time.Minute)
 
 	return children, nil
 }
 
 // GetDescendants retrieves all descendants of an ancestor chunk with optional depth limit
+// BatchGetChildren retrieves the direct children of many chunks in one query. Unlike
+// GetChildren, parents that do not exist simply have no children.
+func (s *unifiedChunkService) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	start := time.Now()
+	rowCount := 0
+	defer func() {
+		s.monitor.RecordQuery("batch_get_children", time.Since(start), rowCount)
+	}()
+
+	parentChunkIDs = validChunkIDs(parentChunkIDs)
+	if len(parentChunkIDs) == 0 {
+		return map[string][]models.UnifiedChunkRecord{}, nil
+	}
+
+	query := `
+		SELECT ch.ancestor_id, ` + unifiedChunkColumns + `
+		FROM chunks c
+		JOIN chunk_hierarchy ch ON c.chunk_id = ch.descendant_id
+		WHERE ch.ancestor_id = ANY($1) AND ch.depth = 1
+		ORDER BY c.created_time ASC
+	`
+
+	rows, err := s.queryRows(ctx, query, pq.Array(parentChunkIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch query children: %w", err)
+	}
+	defer rows.Close()
+
+	children, rowCount, err := scanKeyedUnifiedChunks(rows)
+	return children, err
+}
+
 func (s *unifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
 	start := time.Now()
 	defer func() {
@@ -852,13 +1158,28 @@ func (s *unifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkI
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunk_descendants:%s:%d", ancestorChunkID, maxDepth)
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.([]models.UnifiedChunkRecord), nil
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
 	}
 
+	descendants, err := loadShared(ctx, s, cacheKey, func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		return s.loadDescendants(ctx, cacheKey, ancestorChunkID, maxDepth)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Update performance metrics
+	s.monitor.RecordQuery("get_descendants", time.Since(start), len(descendants))
+
+	return descendants, nil
+}
+
+// loadDescendants loads the descendants of a chunk and caches them
+func (s *unifiedChunkService) loadDescendants(ctx context.Context, cacheKey string, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
 	// Validate that ancestor chunk exists
 	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", ancestorChunkID).Scan(&exists)
+	err := s.reader(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", ancestorChunkID).Scan(&exists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate ancestor chunk: %w", err)
 	}
@@ -868,9 +1189,7 @@ func (s *unifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkI
 
 	// Build query with optional depth limit
 	query := `
-		SELECT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag,
-			   c.is_template, c.is_slot, c.ref, c.tags, c.metadata,
-			   c.created_time, c.last_updated, ch.depth, ch.path_ids
+		SELECT ch.depth, ch.path_ids, ` + unifiedChunkColumns + `
 		FROM chunks c
 		JOIN chunk_hierarchy ch ON c.chunk_id = ch.descendant_id
 		WHERE ch.ancestor_id = $1 AND ch.depth > 0
@@ -884,7 +1203,7 @@ func (s *unifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkI
 
 	query += " ORDER BY ch.depth ASC, c.created_time ASC"
 
-	rows, err := s.db.QueryContext(ctx, query, args...)
+	rows, err := s.queryRows(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query descendants: %w", err)
 	}
@@ -892,30 +1211,19 @@ func (s *unifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkI
 
 	var descendants []models.UnifiedChunkRecord
 	for rows.Next() {
-		var descendant models.UnifiedChunkRecord
-		var tagArray pq.StringArray
 		var depth int
 		var pathIDs pq.StringArray
 
-		err := rows.Scan(
-			&descendant.ChunkID, &descendant.Contents, &descendant.Parent, &descendant.Page,
-			&descendant.IsPage, &descendant.IsTag, &descendant.IsTemplate, &descendant.IsSlot,
-			&descendant.Ref, &tagArray, &descendant.Metadata,
-			&descendant.CreatedTime, &descendant.LastUpdated, &depth, &pathIDs,
-		)
+		descendant, err := scanUnifiedChunk(rows, &depth, &pathIDs)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan descendant row: %w", err)
+			return nil, err
 		}
 
-		descendant.Tags = []string(tagArray)
 		// Store hierarchy information in metadata for client use
-		if descendant.Metadata == nil {
-			descendant.Metadata = make(map[string]interface{})
-		}
 		descendant.Metadata["hierarchy_depth"] = depth
 		descendant.Metadata["hierarchy_path"] = []string(pathIDs)
 
-		descendants = append(descendants, descendant)
+		descendants = append(descendants, *descendant)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -923,10 +1231,7 @@ func (s *unifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkI
 	}
 
 	// Cache the result
-	s.cache.Set(ctx, cacheKey, descendants, 5*time.Minute)
-
-	// Update performance metrics
-	s.monitor.RecordQuery("get_descendants", time.Since(start), len(descendants))
+	s.chunkListCache.Set(ctx, cacheKey, descendants, 5*time.Minute)
 
 	return descendants, nil
 }
@@ -940,13 +1245,28 @@ func (s *unifiedChunkService) GetAncestors(ctx context.Context, chunkID string)
 
 	// Check cache first
 	cacheKey := fmt.Sprintf("chunk_ancestors:%s", chunkID)
-	if cached, found := s.cache.GetDirect(ctx, cacheKey); found {
-		return cached.([]models.UnifiedChunkRecord), nil
+	if chunks, found := s.chunkListCache.Get(ctx, cacheKey); found {
+		return chunks, nil
 	}
 
+	ancestors, err := loadShared(ctx, s, cacheKey, func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		return s.loadAncestors(ctx, cacheKey, chunkID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Update performance metrics
+	s.monitor.RecordQuery("get_ancestors", time.Since(start), len(ancestors))
+
+	return ancestors, nil
+}
+
+// loadAncestors loads the ancestors of a chunk and caches them
+func (s *unifiedChunkService) loadAncestors(ctx context.Context, cacheKey string, chunkID string) ([]models.UnifiedChunkRecord, error) {
 	// Validate that chunk exists
 	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", chunkID).Scan(&exists)
+	err := s.reader(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", chunkID).Scan(&exists)
 	if err != nil {
 		return nil, fmt.Errorf("failed to validate chunk: %w", err)
 	}
@@ -956,16 +1276,14 @@ func (s *unifiedChunkService) GetAncestors(ctx context.Context, chunkID string)
 
 	// Query ancestors using the hierarchy auxiliary table
 	query := `
-		SELECT c.chunk_id, c.contents, c.parent, c.page, c.is_page, c.is_tag,
-			   c.is_template, c.is_slot, c.ref, c.tags, c.metadata,
-			   c.created_time, c.last_updated, ch.depth
+		SELECT ch.depth, ` + unifiedChunkColumns + `
 		FROM chunks c
 		JOIN chunk_hierarchy ch ON c.chunk_id = ch.ancestor_id
 		WHERE ch.descendant_id = $1 AND ch.depth > 0
 		ORDER BY ch.depth DESC
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, chunkID)
+	rows, err := s.queryRows(ctx, query, chunkID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query ancestors: %w", err)
 	}
@@ -973,28 +1291,17 @@ func (s *unifiedChunkService) GetAncestors(ctx context.Context, chunkID string)
 
 	var ancestors []models.UnifiedChunkRecord
 	for rows.Next() {
-		var ancestor models.UnifiedChunkRecord
-		var tagArray pq.StringArray
 		var depth int
 
-		err := rows.Scan(
-			&ancestor.ChunkID, &ancestor.Contents, &ancestor.Parent, &ancestor.Page,
-			&ancestor.IsPage, &ancestor.IsTag, &ancestor.IsTemplate, &ancestor.IsSlot,
-			&ancestor.Ref, &tagArray, &ancestor.Metadata,
-			&ancestor.CreatedTime, &ancestor.LastUpdated, &depth,
-		)
+		ancestor, err := scanUnifiedChunk(rows, &depth)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan ancestor row: %w", err)
+			return nil, err
 		}
 
-		ancestor.Tags = []string(tagArray)
 		// Store hierarchy information in metadata for client use
-		if ancestor.Metadata == nil {
-			ancestor.Metadata = make(map[string]interface{})
-		}
 		ancestor.Metadata["hierarchy_depth"] = depth
 
-		ancestors = append(ancestors, ancestor)
+		ancestors = append(ancestors, *ancestor)
 	}
 
 	if err = rows.Err(); err != nil {
@@ -1002,61 +1309,31 @@ func (s *unifiedChunkService) GetAncestors(ctx context.Context, chunkID string)
 	}
 
 	// Cache the result
-	s.cache.Set(ctx, cacheKey, ancestors, 5*time.Minute)
-
-	// Update performance metrics
-	s.monitor.RecordQuery("get_ancestors", time.Since(start), len(ancestors))
+	s.chunkListCache.Set(ctx, cacheKey, ancestors, 5*time.Minute)
 
 	return ancestors, nil
 }
 
-// MoveChunk moves a chunk to a new parent, automatically updating the hierarchy auxiliary table
+// MoveChunk moves a chunk to a new parent, updating the hierarchy auxiliary table in the
+// same transaction
 func (s *unifiedChunkService) MoveChunk(ctx context.Context, chunkID, newParentID string) error {
 	start := time.Now()
 	defer func() {
 		s.monitor.RecordQuery("move_chunk", time.Since(start), 1)
 	}()
 
-	// Validate that chunk exists
-	var exists bool
-	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", chunkID).Scan(&exists)
-	if err != nil {
-		return fmt.Errorf("failed to validate chunk: %w", err)
-	}
-	if !exists {
-		return fmt.Errorf("chunk not found: %s", chunkID)
-	}
-
-	// Validate new parent exists (if not null)
-	if newParentID != "" {
-		err = s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM chunks WHERE chunk_id = $1)", newParentID).Scan(&exists)
-		if err != nil {
-			return fmt.Errorf("failed to validate new parent chunk: %w", err)
-		}
-		if !exists {
-			return fmt.Errorf("new parent chunk not found: %s", newParentID)
-		}
-
-		// Check for circular reference - ensure new parent is not a descendant of the chunk being moved
-		var isDescendant bool
-		err = s.db.QueryRowContext(ctx, 
-			"SELECT EXISTS(SELECT 1 FROM chunk_hierarchy WHERE ancestor_id = $1 AND descendant_id = $2)",
-			chunkID, newParentID).Scan(&isDescendant)
-		if err != nil {
-			return fmt.Errorf("failed to check for circular reference: %w", err)
-		}
-		if isDescendant {
-			return fmt.Errorf("cannot move chunk to its own descendant: circular reference detected")
-		}
-	}
-
-	// Begin transaction for atomic operation
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
+	// Validate against parent pointers, which are authoritative even where the hierarchy
+	// table has drifted
+	if err = validateMoveTx(ctx, tx, chunkID, newParentID); err != nil {
+		return err
+	}
+
 	// Update the parent field in the main table
 	var parentPtr *string
 	if newParentID != "" {
@@ -1064,15 +1341,16 @@ func (s *unifiedChunkService) MoveChunk(ctx context.Context, chunkID, newParentI
 	}
 
 	_, err = tx.ExecContext(ctx,
-		"UPDATE chunks SET parent = $1, last_updated = NOW() WHERE chunk_id = $2",
+		"UPDATE chunks SET parent = $1, last_updated = NOW(), version = version + 1 WHERE chunk_id = $2",
 		parentPtr, chunkID)
 	if err != nil {
 		return fmt.Errorf("failed to update chunk parent: %w", err)
 	}
 
-	// The hierarchy auxiliary table will be automatically updated by the database trigger
-	// But we can also manually ensure consistency by clearing and rebuilding hierarchy for this chunk
-	// This is handled by the trigger, but we could add manual verification here if needed
+	// The moved chunk and all of its descendants get new ancestors
+	if _, err = rebuildSubtreeHierarchyTx(ctx, tx, []string{chunkID}); err != nil {
+		return err
+	}
 
 	if err = tx.Commit(); err != nil {
 		return fmt.Errorf("failed to commit transaction: %w", err)
@@ -1086,6 +1364,8 @@ func (s *unifiedChunkService) MoveChunk(ctx context.Context, chunkID, newParentI
 
 // Helper function to invalidate hierarchy-related caches
 func (s *unifiedChunkService) invalidateHierarchyCaches(ctx context.Context, chunkID, parentID string) {
+	s.markWrite()
+
 	patterns := []string{
 		fmt.Sprintf("chunk_children:%s", chunkID),
 		fmt.Sprintf("chunk_descendants:%s:*", chunkID),
@@ -1114,4 +1394,4 @@ func (s *unifiedChunkService) SearchChunks(ctx context.Context, query *models.Se
 
 func (s *unifiedChunkService) SearchByContent(ctx context.Context, content string, filters map[string]interface{}) ([]models.UnifiedChunkRecord, error) {
 	return nil, fmt.Errorf("not implemented - will be implemented in later tasks")
-}
\ No newline at end of file
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"semantic-text-processor/database"
+	"semantic-text-processor/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// NewPgxUnifiedChunkService creates a UnifiedChunkService that reads chunks by ID over the
+// native pgx pool whenever the primary serves the read, and loads batch creates with COPY.
+// This is a partial rollout: every other write, list read and LISTEN/NOTIFY still runs on
+// the router's database/sql handles, which must use the pgx stdlib driver and reach the
+// same primary as pool.
+func NewPgxUnifiedChunkService(pool *pgxpool.Pool, router *database.ReplicaRouter, cache CacheService, monitor QueryPerformanceMonitor) UnifiedChunkService {
+	service := NewReplicaAwareUnifiedChunkService(router, cache, monitor).(*unifiedChunkService)
+	service.pool = pool
+	return service
+}
+
+// queryRow runs a single-row read, over the pgx pool when the primary would serve it
+func (s *unifiedChunkService) queryRow(ctx context.Context, query string, args ...interface{}) rowScanner {
+	reader := s.reader(ctx)
+	if s.pool != nil && reader == s.db {
+		return s.pool.QueryRow(ctx, query, args...)
+	}
+	return reader.QueryRowContext(ctx, query, args...)
+}
+
+// queryChunks runs a read selecting columns and scans every row, over the pgx pool when the
+// primary would serve it
+func (s *unifiedChunkService) queryChunks(ctx context.Context, columns []chunkColumn, query string, args ...interface{}) ([]*models.UnifiedChunkRecord, error) {
+	reader := s.reader(ctx)
+	if s.pool == nil || reader != s.db {
+		rows, err := s.queryRows(ctx, query, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		return scanChunkRows(rows, rows.Next, rows.Err, columns)
+	}
+
+	start := time.Now()
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	if s.monitor != nil {
+		RecordStatement(s.monitor, query, time.Since(start), args...)
+	}
+	return scanChunkRows(rows, rows.Next, rows.Err, columns)
+}
+
+// scanChunkRows scans rows of either handle until next reports no more
+func scanChunkRows(row rowScanner, next func() bool, rowsErr func() error, columns []chunkColumn) ([]*models.UnifiedChunkRecord, error) {
+	var chunks []*models.UnifiedChunkRecord
+	for next() {
+		chunk, err := scanChunkColumns(row, columns)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+	if err := rowsErr(); err != nil {
+		return nil, fmt.Errorf("error iterating chunk rows: %w", err)
+	}
+	return chunks, nil
+}
+
+// copyChunksTx bulk-loads chunks with COPY, on the pgx connection underneath conn and so
+// inside the transaction open on it
+func copyChunksTx(ctx context.Context, conn *sql.Conn, chunks []models.UnifiedChunkRecord) error {
+	rows := make([][]interface{}, len(chunks))
+	for i := range chunks {
+		args, err := chunkInsertArgs(&chunks[i])
+		if err != nil {
+			return err
+		}
+		rows[i] = args
+	}
+	columns := make([]string, len(chunkInsertColumns))
+	for i, column := range chunkInsertColumns {
+		columns[i] = column.name
+	}
+
+	return conn.Raw(func(driverConn interface{}) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("COPY requires a pgx connection, got %T", driverConn)
+		}
+		if _, err := pgxConn.Conn().CopyFrom(ctx, pgx.Identifier{"chunks"}, columns, pgx.CopyFromRows(rows)); err != nil {
+			return fmt.Errorf("failed to copy chunks: %w", err)
+		}
+		return nil
+	})
+}
+
+// insertChunksTx inserts chunks one statement at a time
+func insertChunksTx(ctx context.Context, tx *sql.Tx, chunks []models.UnifiedChunkRecord) error {
+	stmt, err := tx.PrepareContext(ctx, chunkInsertQuery)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	defer stmt.Close()
+
+	for i := range chunks {
+		args, err := chunkInsertArgs(&chunks[i])
+		if err != nil {
+			return err
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return fmt.Errorf("failed to insert chunk %s: %w", chunks[i].ChunkID, err)
+		}
+	}
+	return nil
+}
@@ -38,64 +38,67 @@ func NewSearchCacheEnhancedUnifiedChunkService(
 // SearchChunks performs search with database cache integration
 func (s *SearchCacheEnhancedUnifiedChunkService) SearchChunks(ctx context.Context, query *models.SearchQuery) (*models.SearchResult, error) {
 	start := time.Now()
+	if err := ValidateFilterExpression(query.Filter); err != nil {
+		return nil, err
+	}
 	
 	// Convert query to cache parameters
 	queryParams := s.queryToParams(query)
 	
-	// Try to get from database cache first
-	cacheEntry, err := s.searchCache.GetCachedSearch(ctx, queryParams)
-	if err != nil {
-		s.monitor.RecordQuery("search_cache_error", time.Since(start), 0)
-		// Continue with actual search if cache fails
-	} else if cacheEntry != nil {
-		// Cache hit - reconstruct result from cached chunk IDs
-		chunks, err := s.getChunksByIDs(ctx, cacheEntry.ChunkIDs)
+	// Stale entries are served immediately while the cache refreshes them
+	entry, cacheHit, err := s.searchCache.GetOrComputeSearch(ctx, queryParams, s.getTTLForQuery(query), func(ctx context.Context) ([]string, json.RawMessage, error) {
+		result, err := s.performActualSearch(ctx, query)
 		if err != nil {
-			s.monitor.RecordQuery("search_cache_reconstruction_error", time.Since(start), 0)
-			// Fall back to actual search
-		} else {
-			result := &models.SearchResult{
-				Chunks:     chunks,
-				TotalCount: cacheEntry.ResultCount,
-				HasMore:    false, // Simplified for cached results
-				SearchTime: time.Since(start),
-				CacheHit:   true,
-			}
-			s.monitor.RecordQuery("search_chunks_cached", time.Since(start), len(chunks))
-			return result, nil
+			return nil, nil, err
 		}
-	}
-	
-	// Cache miss or error - perform actual search
-	result, err := s.performActualSearch(ctx, query)
+		chunkIDs := make([]string, len(result.Chunks))
+		for i, chunk := range result.Chunks {
+			chunkIDs[i] = chunk.ChunkID
+		}
+		payload, err := json.Marshal(cachedSearchSummary{TotalCount: result.TotalCount, HasMore: result.HasMore})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to serialize search summary: %w", err)
+		}
+		return chunkIDs, payload, nil
+	})
 	if err != nil {
 		s.monitor.RecordQuery("search_chunks_error", time.Since(start), 0)
 		return nil, err
 	}
 	
-	result.SearchTime = time.Since(start)
-	result.CacheHit = false
+	// Reconstruct result from cached chunk IDs
+	chunks, err := s.getChunksByIDs(ctx, entry.ChunkIDs)
+	if err != nil {
+		s.monitor.RecordQuery("search_cache_reconstruction_error", time.Since(start), 0)
+		return nil, err
+	}
 	
-	// Cache the result asynchronously
-	go func() {
-		cacheCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		
-		chunkIDs := make([]string, len(result.Chunks))
-		for i, chunk := range result.Chunks {
-			chunkIDs[i] = chunk.ChunkID
-		}
-		
-		ttl := s.getTTLForQuery(query)
-		if err := s.searchCache.SetCachedSearch(cacheCtx, queryParams, chunkIDs, ttl); err != nil {
-			s.monitor.RecordQuery("search_cache_set_error", 0, 0)
-		}
-	}()
+	result := &models.SearchResult{
+		Chunks:     chunks,
+		TotalCount: entry.ResultCount,
+		SearchTime: time.Since(start),
+		CacheHit:   cacheHit,
+	}
+	var summary cachedSearchSummary
+	if len(entry.Payload) > 0 && json.Unmarshal(entry.Payload, &summary) == nil {
+		result.TotalCount = summary.TotalCount
+		result.HasMore = summary.HasMore
+	}
 	
-	s.monitor.RecordQuery("search_chunks", time.Since(start), len(result.Chunks))
+	if cacheHit {
+		s.monitor.RecordQuery("search_chunks_cached", time.Since(start), len(chunks))
+	} else {
+		s.monitor.RecordQuery("search_chunks", time.Since(start), len(chunks))
+	}
 	return result, nil
 }
 
+// cachedSearchSummary is the cache payload for SearchChunks results
+type cachedSearchSummary struct {
+	TotalCount int  `json:"total_count"`
+	HasMore    bool `json:"has_more"`
+}
+
 // SearchByContent performs content search with database cache integration
 func (s *SearchCacheEnhancedUnifiedChunkService) SearchByContent(ctx context.Context, content string, filters map[string]interface{}) ([]models.UnifiedChunkRecord, error) {
 	
@@ -125,6 +128,9 @@ func (s *SearchCacheEnhancedUnifiedChunkService) SearchByContent(ctx context.Con
 		if page, ok := filters["page"].(string); ok {
 			query.Page = &page
 		}
+		if language, ok := filters["language"].(string); ok {
+			query.Language = language
+		}
 		if limit, ok := filters["limit"].(int); ok {
 			query.Limit = limit
 		}
@@ -148,11 +154,13 @@ func (s *SearchCacheEnhancedUnifiedChunkService) performActualSearch(ctx context
 	var args []interface{}
 	argIndex := 1
 	
-	// Build WHERE conditions
+	// Build WHERE conditions. Queries are parsed with the text search configuration of
+	// their language and matched against search_vector, which each chunk's language built.
+	language := SearchLanguage(query.Content, query.Language)
 	if query.Content != "" {
-		conditions = append(conditions, fmt.Sprintf("to_tsvector('english', contents) @@ plainto_tsquery('english', $%d)", argIndex))
-		args = append(args, query.Content)
-		argIndex++
+		conditions = append(conditions, fmt.Sprintf("search_vector @@ chunk_search_query($%d, $%d)", argIndex, argIndex+1))
+		args = append(args, language, query.Content)
+		argIndex += 2
 	}
 	
 	if query.IsPage != nil {
@@ -221,12 +229,22 @@ func (s *SearchCacheEnhancedUnifiedChunkService) performActualSearch(ctx context
 	// Handle metadata filtering
 	if query.Metadata != nil && len(query.Metadata) > 0 {
 		for key, value := range query.Metadata {
-			conditions = append(conditions, fmt.Sprintf("metadata->>'%s' = $%d", key, argIndex))
-			args = append(args, value)
-			argIndex++
+			conditions = append(conditions, fmt.Sprintf("metadata->>$%d = $%d", argIndex, argIndex+1))
+			args = append(args, key, value)
+			argIndex += 2
 		}
 	}
 	
+	if query.Filter != nil {
+		condition, filterArgs, err := compileFilterExpression(query.Filter, argIndex)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+		argIndex += len(filterArgs)
+	}
+	
 	// Build the main query
 	whereClause := ""
 	if len(conditions) > 0 {
@@ -243,17 +261,16 @@ func (s *SearchCacheEnhancedUnifiedChunkService) performActualSearch(ctx context
 	
 	// Main search query with pagination
 	searchQuery := fmt.Sprintf(`
-		SELECT chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
-			   ref, tags, metadata, created_time, last_updated
+		SELECT ` + chunkColumnList("", chunkListColumns) + `
 		FROM chunks %s
 		ORDER BY 
-			CASE WHEN $%d != '' THEN ts_rank(to_tsvector('english', contents), plainto_tsquery('english', $%d)) END DESC,
+			CASE WHEN $%d != '' THEN ts_rank(search_vector, chunk_search_query($%d, $%d)) END DESC,
 			created_time DESC
-	`, whereClause, argIndex, argIndex+1)
+	`, whereClause, argIndex, argIndex+1, argIndex+2)
 	
 	// Add content parameter for ranking (even if empty)
-	args = append(args, query.Content, query.Content)
-	argIndex += 2
+	args = append(args, query.Content, language, query.Content)
+	argIndex += 3
 	
 	// Add pagination
 	if query.Limit > 0 {
@@ -275,47 +292,9 @@ func (s *SearchCacheEnhancedUnifiedChunkService) performActualSearch(ctx context
 	}
 	defer rows.Close()
 	
-	var chunks []models.UnifiedChunkRecord
-	for rows.Next() {
-		var chunk models.UnifiedChunkRecord
-		var tags pq.StringArray
-		var metadata []byte
-		
-		err := rows.Scan(
-			&chunk.ChunkID,
-			&chunk.Contents,
-			&chunk.Parent,
-			&chunk.Page,
-			&chunk.IsPage,
-			&chunk.IsTag,
-			&chunk.IsTemplate,
-			&chunk.IsSlot,
-			&chunk.Ref,
-			&tags,
-			&metadata,
-			&chunk.CreatedTime,
-			&chunk.LastUpdated,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan search result: %w", err)
-		}
-		
-		chunk.Tags = []string(tags)
-		
-		// Parse metadata JSON
-		if len(metadata) > 0 {
-			chunk.Metadata = make(map[string]interface{})
-			if err := json.Unmarshal(metadata, &chunk.Metadata); err != nil {
-				// Continue with empty metadata if parsing fails
-				chunk.Metadata = make(map[string]interface{})
-			}
-		}
-		
-		chunks = append(chunks, chunk)
-	}
-	
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating search results: %w", err)
+	chunks, err := scanUnifiedChunks(rows)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Determine if there are more results
@@ -338,8 +317,7 @@ func (s *SearchCacheEnhancedUnifiedChunkService) getChunksByIDs(ctx context.Cont
 	}
 	
 	query := `
-		SELECT chunk_id, contents, parent, page, is_page, is_tag, is_template, is_slot,
-			   ref, tags, metadata, created_time, last_updated
+		SELECT ` + chunkColumnList("", chunkListColumns) + `
 		FROM chunks 
 		WHERE chunk_id = ANY($1)
 		ORDER BY array_position($1, chunk_id)
@@ -351,45 +329,7 @@ func (s *SearchCacheEnhancedUnifiedChunkService) getChunksByIDs(ctx context.Cont
 	}
 	defer rows.Close()
 	
-	var chunks []models.UnifiedChunkRecord
-	for rows.Next() {
-		var chunk models.UnifiedChunkRecord
-		var tags pq.StringArray
-		var metadata []byte
-		
-		err := rows.Scan(
-			&chunk.ChunkID,
-			&chunk.Contents,
-			&chunk.Parent,
-			&chunk.Page,
-			&chunk.IsPage,
-			&chunk.IsTag,
-			&chunk.IsTemplate,
-			&chunk.IsSlot,
-			&chunk.Ref,
-			&tags,
-			&metadata,
-			&chunk.CreatedTime,
-			&chunk.LastUpdated,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan chunk: %w", err)
-		}
-		
-		chunk.Tags = []string(tags)
-		
-		// Parse metadata JSON
-		if len(metadata) > 0 {
-			chunk.Metadata = make(map[string]interface{})
-			if err := json.Unmarshal(metadata, &chunk.Metadata); err != nil {
-				chunk.Metadata = make(map[string]interface{})
-			}
-		}
-		
-		chunks = append(chunks, chunk)
-	}
-	
-	return chunks, rows.Err()
+	return scanUnifiedChunks(rows)
 }
 
 // queryToParams converts SearchQuery to cache parameters
@@ -399,6 +339,9 @@ func (s *SearchCacheEnhancedUnifiedChunkService) queryToParams(query *models.Sea
 	if query.Content != "" {
 		params["content"] = query.Content
 	}
+	if query.Language != "" {
+		params["language"] = query.Language
+	}
 	if len(query.Tags) > 0 {
 		params["tags"] = query.Tags
 		params["tag_logic"] = query.TagLogic
@@ -424,6 +367,9 @@ func (s *SearchCacheEnhancedUnifiedChunkService) queryToParams(query *models.Sea
 	if query.Metadata != nil && len(query.Metadata) > 0 {
 		params["metadata"] = query.Metadata
 	}
+	if query.Filter != nil {
+		params["filter"] = query.Filter
+	}
 	if query.Limit > 0 {
 		params["limit"] = query.Limit
 	}
@@ -493,6 +439,22 @@ func (s *SearchCacheEnhancedUnifiedChunkService) UpdateChunk(ctx context.Context
 	return nil
 }
 
+func (s *SearchCacheEnhancedUnifiedChunkService) PatchChunk(ctx context.Context, chunkID string, patch *models.ChunkPatch) (*models.UnifiedChunkRecord, error) {
+	chunk, err := s.base.PatchChunk(ctx, chunkID, patch)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Invalidate search cache when content is patched
+	go func() {
+		cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.searchCache.InvalidateSearchCache(cacheCtx, []string{"*"})
+	}()
+	
+	return chunk, nil
+}
+
 func (s *SearchCacheEnhancedUnifiedChunkService) DeleteChunk(ctx context.Context, chunkID string) error {
 	err := s.base.DeleteChunk(ctx, chunkID)
 	if err != nil {
@@ -541,10 +503,18 @@ func (s *SearchCacheEnhancedUnifiedChunkService) BatchUpdateChunks(ctx context.C
 	return nil
 }
 
+func (s *SearchCacheEnhancedUnifiedChunkService) BatchGetChunks(ctx context.Context, chunkIDs []string) (map[string]*models.UnifiedChunkRecord, error) {
+	return s.base.BatchGetChunks(ctx, chunkIDs)
+}
+
 func (s *SearchCacheEnhancedUnifiedChunkService) AddTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
 	return s.base.AddTags(ctx, chunkID, tagChunkIDs)
 }
 
+func (s *SearchCacheEnhancedUnifiedChunkService) BatchAddTags(ctx context.Context, chunkIDs []string, tagChunkIDs []string) (int, error) {
+	return s.base.BatchAddTags(ctx, chunkIDs, tagChunkIDs)
+}
+
 func (s *SearchCacheEnhancedUnifiedChunkService) RemoveTags(ctx context.Context, chunkID string, tagChunkIDs []string) error {
 	return s.base.RemoveTags(ctx, chunkID, tagChunkIDs)
 }
@@ -553,6 +523,10 @@ func (s *SearchCacheEnhancedUnifiedChunkService) GetChunkTags(ctx context.Contex
 	return s.base.GetChunkTags(ctx, chunkID)
 }
 
+func (s *SearchCacheEnhancedUnifiedChunkService) BatchGetChunkTags(ctx context.Context, chunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	return s.base.BatchGetChunkTags(ctx, chunkIDs)
+}
+
 func (s *SearchCacheEnhancedUnifiedChunkService) GetChunksByTag(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
 	return s.base.GetChunksByTag(ctx, tagChunkID)
 }
@@ -561,10 +535,42 @@ func (s *SearchCacheEnhancedUnifiedChunkService) GetChunksByTags(ctx context.Con
 	return s.base.GetChunksByTags(ctx, tagChunkIDs, matchType)
 }
 
+func (s *SearchCacheEnhancedUnifiedChunkService) SetTagParent(ctx context.Context, tagChunkID, parentTagChunkID string) error {
+	return s.base.SetTagParent(ctx, tagChunkID, parentTagChunkID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) GetTagAncestors(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	return s.base.GetTagAncestors(ctx, tagChunkID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) GetTagDescendants(ctx context.Context, tagChunkID string) ([]models.UnifiedChunkRecord, error) {
+	return s.base.GetTagDescendants(ctx, tagChunkID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) GetChunksByTagWithOptions(ctx context.Context, tagChunkID string, opts *models.TagQueryOptions) ([]models.UnifiedChunkRecord, error) {
+	return s.base.GetChunksByTagWithOptions(ctx, tagChunkID, opts)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) GetTagRollup(ctx context.Context, tagChunkID string) (*models.TagRollup, error) {
+	return s.base.GetTagRollup(ctx, tagChunkID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) RenameTag(ctx context.Context, tagChunkID, newName string) error {
+	return s.base.RenameTag(ctx, tagChunkID, newName)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) MergeTags(ctx context.Context, sourceTagIDs []string, targetTagID string) (*models.TagMergeResult, error) {
+	return s.base.MergeTags(ctx, sourceTagIDs, targetTagID)
+}
+
 func (s *SearchCacheEnhancedUnifiedChunkService) GetChildren(ctx context.Context, parentChunkID string) ([]models.UnifiedChunkRecord, error) {
 	return s.base.GetChildren(ctx, parentChunkID)
 }
 
+func (s *SearchCacheEnhancedUnifiedChunkService) BatchGetChildren(ctx context.Context, parentChunkIDs []string) (map[string][]models.UnifiedChunkRecord, error) {
+	return s.base.BatchGetChildren(ctx, parentChunkIDs)
+}
+
 func (s *SearchCacheEnhancedUnifiedChunkService) GetDescendants(ctx context.Context, ancestorChunkID string, maxDepth int) ([]models.UnifiedChunkRecord, error) {
 	return s.base.GetDescendants(ctx, ancestorChunkID, maxDepth)
 }
@@ -575,4 +581,36 @@ func (s *SearchCacheEnhancedUnifiedChunkService) GetAncestors(ctx context.Contex
 
 func (s *SearchCacheEnhancedUnifiedChunkService) MoveChunk(ctx context.Context, chunkID, newParentID string) error {
 	return s.base.MoveChunk(ctx, chunkID, newParentID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) MoveSubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeMoveResult, error) {
+	return s.base.MoveSubtree(ctx, chunkID, newParentID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) CopySubtree(ctx context.Context, chunkID, newParentID string) (*models.SubtreeCopyResult, error) {
+	return s.base.CopySubtree(ctx, chunkID, newParentID)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) DeleteSubtree(ctx context.Context, chunkID string, opts models.SubtreeDeleteOptions) (*models.SubtreeDeleteResult, error) {
+	return s.base.DeleteSubtree(ctx, chunkID, opts)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) BulkMove(ctx context.Context, moves []models.ChunkMove) (*models.SubtreeMoveResult, error) {
+	return s.base.BulkMove(ctx, moves)
+}
+
+func (s *SearchCacheEnhancedUnifiedChunkService) MergeChunks(ctx context.Context, targetID, sourceID string, strategy models.ChunkMergeStrategy) (*models.ChunkMergeResult, error) {
+	result, err := s.base.MergeChunks(ctx, targetID, sourceID, strategy)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Invalidate search cache when a chunk is merged away
+	go func() {
+		cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.searchCache.InvalidateSearchCache(cacheCtx, []string{"*"})
+	}()
+	
+	return result, nil
 }
\ No newline at end of file
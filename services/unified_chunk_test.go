@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"fmt"
 	"semantic-text-processor/models"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -997,4 +999,173 @@ func TestUnifiedChunkService_HierarchyOperations_PathAndDepthQueries(t *testing.
 	for _, chunk := range chunks {
 		require.NoError(t, service.DeleteChunk(context.Background(), chunk.ChunkID))
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadShared_CoalescesConcurrentMisses(t *testing.T) {
+	service := &unifiedChunkService{monitor: NewNoOpMonitor()}
+	release := make(chan struct{})
+	var loads atomic.Int32
+
+	load := func(ctx context.Context) ([]models.UnifiedChunkRecord, error) {
+		loads.Add(1)
+		<-release
+		return []models.UnifiedChunkRecord{{ChunkID: "chunk-1"}}, nil
+	}
+
+	const callers = 50
+	var wg sync.WaitGroup
+	results := make(chan []models.UnifiedChunkRecord, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			chunks, err := loadShared(context.Background(), service, "chunks_by_tag:tag-1", load)
+			assert.NoError(t, err)
+			results <- chunks
+		}()
+	}
+
+	// Let every caller join the load before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(results)
+
+	assert.Equal(t, int32(1), loads.Load())
+	for chunks := range results {
+		require.Len(t, chunks, 1)
+		assert.Equal(t, "chunk-1", chunks[0].ChunkID)
+	}
+}
+
+func TestLoadShared_WriteStartsNewLoad(t *testing.T) {
+	service := &unifiedChunkService{monitor: NewNoOpMonitor()}
+	release := make(chan struct{})
+	var loads atomic.Int32
+
+	load := func(ctx context.Context) (int32, error) {
+		n := loads.Add(1)
+		<-release
+		return n, nil
+	}
+
+	first := make(chan int32)
+	go func() {
+		n, _ := loadShared(context.Background(), service, "chunk:chunk-1", load)
+		first <- n
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// A miss after a write must not be served by the load started before it
+	service.markWrite()
+	second := make(chan int32)
+	go func() {
+		n, _ := loadShared(context.Background(), service, "chunk:chunk-1", load)
+		second <- n
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+
+	assert.Equal(t, int32(1), <-first)
+	assert.Equal(t, int32(2), <-second)
+}
+
+func TestLoadShared_CancelledCallerDoesNotFailOthers(t *testing.T) {
+	service := &unifiedChunkService{monitor: NewNoOpMonitor()}
+	release := make(chan struct{})
+
+	load := func(ctx context.Context) (string, error) {
+		select {
+		case <-release:
+			return "loaded", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	leaderCtx, cancel := context.WithCancel(context.Background())
+	leaderErr := make(chan error)
+	go func() {
+		_, err := loadShared(leaderCtx, service, "chunk:chunk-1", load)
+		leaderErr <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	waiter := make(chan string)
+	go func() {
+		value, err := loadShared(context.Background(), service, "chunk:chunk-1", load)
+		assert.NoError(t, err)
+		waiter <- value
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-leaderErr, context.Canceled)
+	close(release)
+	assert.Equal(t, "loaded", <-waiter)
+}
+
+func TestLoadShared_RecoversPanics(t *testing.T) {
+	service := &unifiedChunkService{monitor: NewNoOpMonitor()}
+
+	_, err := loadShared(context.Background(), service, "chunk:chunk-1", func(ctx context.Context) (string, error) {
+		panic("nil database")
+	})
+	assert.ErrorContains(t, err, "nil database")
+}
+
+func TestUnifiedChunkService_NotFoundCache(t *testing.T) {
+	cache := NewInMemoryCache(100, time.Minute)
+	defer cache.Stop()
+	// A nil database panics if a lookup reaches it
+	service := NewUnifiedChunkService(nil, cache, NewNoOpMonitor()).(*unifiedChunkService)
+	ctx := context.Background()
+	chunkID := uuid.New().String()
+	tagID := uuid.New().String()
+
+	service.cacheNotFound(ctx, notFoundChunkKey(chunkID), service.writes.Load())
+	service.cacheNotFound(ctx, notFoundTagKey(tagID), service.writes.Load())
+
+	_, err := service.GetChunk(ctx, chunkID)
+	assert.EqualError(t, err, "chunk not found: "+chunkID)
+	_, err = service.GetChunksByTag(ctx, tagID)
+	assert.EqualError(t, err, "tag chunk not found: "+tagID)
+	chunks, err := service.BatchGetChunks(ctx, []string{chunkID})
+	require.NoError(t, err)
+	assert.Empty(t, chunks)
+
+	// Creating the chunk forgets that it was missing
+	service.invalidateChunkCaches(ctx, chunkID)
+	_, found := service.notFoundCache.Get(ctx, notFoundChunkKey(chunkID))
+	assert.False(t, found)
+	service.invalidateChunkCaches(ctx, tagID)
+	_, found = service.notFoundCache.Get(ctx, notFoundTagKey(tagID))
+	assert.False(t, found)
+
+	// A lookup that raced a write does not record its miss
+	writes := service.writes.Load()
+	service.markWrite()
+	service.cacheNotFound(ctx, notFoundChunkKey(chunkID), writes)
+	_, found = service.notFoundCache.Get(ctx, notFoundChunkKey(chunkID))
+	assert.False(t, found)
+}
+
+func TestUnifiedChunkService_NotFoundCache_RealDatabase(t *testing.T) {
+	db := setupIntegrationDB(t)
+	defer db.Close()
+
+	ctx := context.Background()
+	service := NewUnifiedChunkService(db, NewInMemoryCache(100, time.Minute), NewNoOpMonitor())
+	chunk := createTestChunk()
+
+	_, err := service.GetChunk(ctx, chunk.ChunkID)
+	assert.ErrorContains(t, err, "not found")
+	_, err = service.GetChunk(ctx, chunk.ChunkID)
+	assert.ErrorContains(t, err, "not found", "served from the not-found cache")
+
+	require.NoError(t, service.CreateChunk(ctx, chunk))
+	defer service.DeleteChunk(ctx, chunk.ChunkID)
+	found, err := service.GetChunk(ctx, chunk.ChunkID)
+	require.NoError(t, err, "creating a chunk forgets that it was missing")
+	assert.Equal(t, chunk.Contents, found.Contents)
+}
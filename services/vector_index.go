@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"semantic-text-processor/config"
+	"semantic-text-processor/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// VectorIndexManager creates, rebuilds and inspects pgvector indexes
+type VectorIndexManager interface {
+	// DefaultSpec returns the index spec described by configuration
+	DefaultSpec(name string) *models.VectorIndexSpec
+
+	// CreateIndex builds an index concurrently if it does not exist yet
+	CreateIndex(ctx context.Context, spec *models.VectorIndexSpec) error
+
+	// RebuildIndex builds the index under a temporary name and swaps it in, so parameter
+	// changes (m, ef_construction, lists) take effect without blocking writes
+	RebuildIndex(ctx context.Context, spec *models.VectorIndexSpec) error
+
+	// ListIndexes reports every HNSW and IVFFlat index on the configured table
+	ListIndexes(ctx context.Context) ([]models.VectorIndexInfo, error)
+
+	// ConfigureSearch applies query-time parameters (hnsw.ef_search, ivfflat.probes) to a transaction
+	ConfigureSearch(ctx context.Context, tx *sql.Tx) error
+
+	// SuggestIndexes produces index suggestions for the optimization analysis
+	SuggestIndexes(ctx context.Context) ([]models.IndexSuggestion, error)
+}
+
+// vectorIndexManager implements VectorIndexManager on top of PostgreSQL catalogs
+type vectorIndexManager struct {
+	db      *sql.DB
+	config  *config.VectorIndexConfig
+	monitor QueryPerformanceMonitor
+}
+
+// NewVectorIndexManager creates a new vector index manager
+func NewVectorIndexManager(db *sql.DB, cfg *config.VectorIndexConfig, monitor QueryPerformanceMonitor) VectorIndexManager {
+	return &vectorIndexManager{
+		db:      db,
+		config:  cfg,
+		monitor: monitor,
+	}
+}
+
+// identifierPattern limits DDL identifiers, which cannot be passed as query parameters
+var identifierPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+var vectorOperatorClasses = map[string]bool{
+	"vector_cosine_ops": true,
+	"vector_l2_ops":     true,
+	"vector_ip_ops":     true,
+	// quantized text embeddings (EMBEDDING_STORAGE=halfvec or int8)
+	"halfvec_cosine_ops": true,
+	"halfvec_l2_ops":     true,
+	"halfvec_ip_ops":     true,
+	"bit_hamming_ops":    true,
+}
+
+// DefaultSpec returns the index spec described by configuration
+func (m *vectorIndexManager) DefaultSpec(name string) *models.VectorIndexSpec {
+	if name == "" {
+		name = fmt.Sprintf("idx_%s_%s_%s", m.config.Table, m.config.Column, m.config.Type)
+	}
+
+	return &models.VectorIndexSpec{
+		Name:           name,
+		Table:          m.config.Table,
+		Column:         m.config.Column,
+		Method:         models.VectorIndexMethod(m.config.Type),
+		OperatorClass:  m.config.OperatorClass,
+		M:              m.config.HNSWM,
+		EfConstruction: m.config.HNSWEfConstruction,
+		Lists:          m.config.IVFFlatLists,
+	}
+}
+
+// CreateIndex builds an index concurrently if it does not exist yet
+func (m *vectorIndexManager) CreateIndex(ctx context.Context, spec *models.VectorIndexSpec) error {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return err
+	}
+	start := time.Now()
+	defer func() {
+		m.monitor.RecordQuery("create_vector_index", time.Since(start), 1)
+	}()
+
+	query, err := buildCreateVectorIndexSQL(spec, spec.Name)
+	if err != nil {
+		return err
+	}
+
+	// CONCURRENTLY cannot run inside a transaction block
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to create vector index %s: %w", spec.Name, err)
+	}
+
+	return nil
+}
+
+// RebuildIndex builds the index under a temporary name and swaps it in
+func (m *vectorIndexManager) RebuildIndex(ctx context.Context, spec *models.VectorIndexSpec) error {
+	if err := Authorize(ctx, PermissionAdmin); err != nil {
+		return err
+	}
+	start := time.Now()
+	defer func() {
+		m.monitor.RecordQuery("rebuild_vector_index", time.Since(start), 1)
+	}()
+
+	tempName := spec.Name + "_rebuild"
+	query, err := buildCreateVectorIndexSQL(spec, tempName)
+	if err != nil {
+		return err
+	}
+
+	// Clean up a leftover (possibly invalid) index from an interrupted rebuild
+	if _, err := m.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+tempName); err != nil {
+		return fmt.Errorf("failed to drop stale rebuild index: %w", err)
+	}
+
+	if _, err := m.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to build replacement index for %s: %w", spec.Name, err)
+	}
+
+	if _, err := m.db.ExecContext(ctx, "DROP INDEX CONCURRENTLY IF EXISTS "+spec.Name); err != nil {
+		return fmt.Errorf("failed to drop old vector index %s: %w", spec.Name, err)
+	}
+
+	if _, err := m.db.ExecContext(ctx, fmt.Sprintf("ALTER INDEX %s RENAME TO %s", tempName, spec.Name)); err != nil {
+		return fmt.Errorf("failed to rename rebuilt vector index: %w", err)
+	}
+
+	return nil
+}
+
+// ListIndexes reports every HNSW and IVFFlat index on the configured table
+func (m *vectorIndexManager) ListIndexes(ctx context.Context) ([]models.VectorIndexInfo, error) {
+	start := time.Now()
+	defer func() {
+		m.monitor.RecordQuery("list_vector_indexes", time.Since(start), 0)
+	}()
+
+	query := `
+		SELECT i.relname, t.relname, am.amname, pg_get_indexdef(i.oid),
+			   COALESCE(i.reloptions, '{}'), pg_relation_size(i.oid),
+			   COALESCE(s.idx_scan, 0), ix.indisvalid
+		FROM pg_index ix
+		JOIN pg_class i ON i.oid = ix.indexrelid
+		JOIN pg_class t ON t.oid = ix.indrelid
+		JOIN pg_am am ON am.oid = i.relam
+		LEFT JOIN pg_stat_user_indexes s ON s.indexrelid = ix.indexrelid
+		WHERE t.relname = $1 AND am.amname IN ('hnsw', 'ivfflat')
+		ORDER BY i.relname
+	`
+
+	rows, err := m.db.QueryContext(ctx, query, m.config.Table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query vector indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []models.VectorIndexInfo
+	now := time.Now()
+	for rows.Next() {
+		var info models.VectorIndexInfo
+		var method string
+		var options pq.StringArray
+		if err := rows.Scan(&info.Name, &info.Table, &method, &info.Definition,
+			&options, &info.SizeBytes, &info.IndexScans, &info.IsValid); err != nil {
+			return nil, fmt.Errorf("failed to scan vector index: %w", err)
+		}
+		info.Method = models.VectorIndexMethod(method)
+		info.Parameters = parseIndexOptions(options)
+		info.InspectedAt = now
+		indexes = append(indexes, info)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating vector indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// ConfigureSearch applies query-time parameters to a transaction
+func (m *vectorIndexManager) ConfigureSearch(ctx context.Context, tx *sql.Tx) error {
+	settings := []string{
+		fmt.Sprintf("SET LOCAL hnsw.ef_search = %d", m.config.HNSWEfSearch),
+		fmt.Sprintf("SET LOCAL ivfflat.probes = %d", m.config.IVFFlatProbes),
+	}
+
+	for _, setting := range settings {
+		if _, err := tx.ExecContext(ctx, setting); err != nil {
+			return fmt.Errorf("failed to apply vector search setting %q: %w", setting, err)
+		}
+	}
+
+	return nil
+}
+
+// SuggestIndexes produces index suggestions for the optimization analysis
+func (m *vectorIndexManager) SuggestIndexes(ctx context.Context) ([]models.IndexSuggestion, error) {
+	indexes, err := m.ListIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !identifierPattern.MatchString(m.config.Table) || !identifierPattern.MatchString(m.config.Column) {
+		return nil, fmt.Errorf("invalid vector index table or column name")
+	}
+
+	var vectorRows int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s IS NOT NULL", m.config.Table, m.config.Column)
+	if err := m.db.QueryRowContext(ctx, query).Scan(&vectorRows); err != nil {
+		return nil, fmt.Errorf("failed to count vectors: %w", err)
+	}
+
+	return vectorIndexSuggestions(indexes, vectorRows, m.DefaultSpec("")), nil
+}
+
+// buildCreateVectorIndexSQL renders a CREATE INDEX statement for a validated spec
+func buildCreateVectorIndexSQL(spec *models.VectorIndexSpec, indexName string) (string, error) {
+	if spec == nil {
+		return "", fmt.Errorf("vector index spec is required")
+	}
+	for _, ident := range []string{indexName, spec.Table, spec.Column} {
+		if !identifierPattern.MatchString(ident) {
+			return "", fmt.Errorf("invalid identifier in vector index spec: %q", ident)
+		}
+	}
+	if !vectorOperatorClasses[spec.OperatorClass] {
+		return "", fmt.Errorf("unsupported vector operator class: %s", spec.OperatorClass)
+	}
+
+	var with string
+	switch spec.Method {
+	case models.VectorIndexHNSW:
+		if spec.M <= 0 || spec.EfConstruction <= 0 {
+			return "", fmt.Errorf("hnsw index requires positive m and ef_construction")
+		}
+		with = fmt.Sprintf("m = %d, ef_construction = %d", spec.M, spec.EfConstruction)
+	case models.VectorIndexIVFFlat:
+		if spec.Lists <= 0 {
+			return "", fmt.Errorf("ivfflat index requires positive lists")
+		}
+		with = fmt.Sprintf("lists = %d", spec.Lists)
+	default:
+		return "", fmt.Errorf("unsupported vector index method: %s", spec.Method)
+	}
+
+	query := fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %s ON %s USING %s (%s %s) WITH (%s)",
+		indexName, spec.Table, spec.Method, spec.Column, spec.OperatorClass, with)
+
+	where := []string{spec.Column + " IS NOT NULL"}
+	if spec.VectorType != "" {
+		if !identifierPattern.MatchString(spec.VectorType) {
+			return "", fmt.Errorf("invalid vector type: %q", spec.VectorType)
+		}
+		where = append([]string{fmt.Sprintf("vector_type = '%s'", spec.VectorType)}, where...)
+	}
+
+	return query + " WHERE " + strings.Join(where, " AND "), nil
+}
+
+// parseIndexOptions converts pg_class.reloptions ("lists=100") into a map
+func parseIndexOptions(options []string) map[string]string {
+	params := make(map[string]string, len(options))
+	for _, option := range options {
+		if key, value, ok := strings.Cut(option, "="); ok {
+			params[key] = value
+		}
+	}
+	return params
+}
+
+// recommendedIVFFlatLists follows the pgvector guidance of rows/1000 up to 1M rows and sqrt(rows) beyond
+func recommendedIVFFlatLists(rows int64) int {
+	if rows <= 0 {
+		return 1
+	}
+	if rows <= 1000000 {
+		lists := int(rows / 1000)
+		if lists < 1 {
+			lists = 1
+		}
+		return lists
+	}
+	return int(math.Sqrt(float64(rows)))
+}
+
+// vectorIndexSuggestions compares existing vector indexes with the data they serve
+func vectorIndexSuggestions(indexes []models.VectorIndexInfo, vectorRows int64, defaults *models.VectorIndexSpec) []models.IndexSuggestion {
+	var suggestions []models.IndexSuggestion
+
+	if len(indexes) == 0 {
+		spec := *defaults
+		if spec.Method == models.VectorIndexIVFFlat {
+			spec.Lists = recommendedIVFFlatLists(vectorRows)
+		}
+		sqlCommand, _ := buildCreateVectorIndexSQL(&spec, spec.Name)
+		suggestions = append(suggestions, models.IndexSuggestion{
+			TableName:            spec.Table,
+			IndexName:            spec.Name,
+			IndexType:            string(spec.Method),
+			Columns:              []string{spec.Column},
+			Reasoning:            fmt.Sprintf("No vector index found; %d vectors are searched sequentially", vectorRows),
+			EstimatedImprovement: "Orders of magnitude faster similarity search on large tables",
+			Priority:             "high",
+			SQLCommand:           sqlCommand,
+		})
+		return suggestions
+	}
+
+	for _, index := range indexes {
+		if !index.IsValid {
+			suggestions = append(suggestions, models.IndexSuggestion{
+				TableName:            index.Table,
+				IndexName:            index.Name,
+				IndexType:            string(index.Method),
+				Reasoning:            "Index is marked invalid, most likely from an interrupted concurrent build",
+				EstimatedImprovement: "Restores index-backed similarity search",
+				Priority:             "high",
+				SQLCommand:           fmt.Sprintf("REINDEX INDEX CONCURRENTLY %s;", index.Name),
+			})
+			continue
+		}
+
+		if index.Method != models.VectorIndexIVFFlat {
+			continue
+		}
+
+		if vectorRows > 1000000 {
+			spec := *defaults
+			spec.Name = index.Name
+			spec.Method = models.VectorIndexHNSW
+			sqlCommand, _ := buildCreateVectorIndexSQL(&spec, spec.Name+"_hnsw")
+			suggestions = append(suggestions, models.IndexSuggestion{
+				TableName:            index.Table,
+				IndexName:            index.Name + "_hnsw",
+				IndexType:            string(models.VectorIndexHNSW),
+				Reasoning:            fmt.Sprintf("IVFFlat recall degrades at %d vectors; HNSW offers better speed/recall trade-offs", vectorRows),
+				EstimatedImprovement: "Higher recall at equal latency",
+				Priority:             "medium",
+				SQLCommand:           sqlCommand,
+			})
+			continue
+		}
+
+		lists, err := strconv.Atoi(index.Parameters["lists"])
+		if err != nil || lists <= 0 {
+			continue
+		}
+		recommended := recommendedIVFFlatLists(vectorRows)
+		ratio := float64(lists) / float64(recommended)
+		if ratio > 2 || ratio < 0.5 {
+			suggestions = append(suggestions, models.IndexSuggestion{
+				TableName:            index.Table,
+				IndexName:            index.Name,
+				IndexType:            string(models.VectorIndexIVFFlat),
+				Reasoning:            fmt.Sprintf("Index uses lists = %d but %d vectors suggest lists = %d", lists, vectorRows, recommended),
+				EstimatedImprovement: "Better recall/latency balance after rebuilding",
+				Priority:             "medium",
+				SQLCommand:           fmt.Sprintf("-- rebuild %s with lists = %d", index.Name, recommended),
+			})
+		}
+	}
+
+	return suggestions
+}
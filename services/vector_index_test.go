@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+
+	"semantic-text-processor/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCreateVectorIndexSQL(t *testing.T) {
+	t.Run("hnsw", func(t *testing.T) {
+		spec := &models.VectorIndexSpec{
+			Name: "idx_chunks_vector_hnsw", Table: "chunks", Column: "vector",
+			Method: models.VectorIndexHNSW, OperatorClass: "vector_cosine_ops", M: 16, EfConstruction: 64,
+		}
+		query, err := buildCreateVectorIndexSQL(spec, spec.Name)
+		require.NoError(t, err)
+		assert.Equal(t, "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_chunks_vector_hnsw ON chunks USING hnsw (vector vector_cosine_ops) WITH (m = 16, ef_construction = 64) WHERE vector IS NOT NULL", query)
+	})
+
+	t.Run("ivfflat with vector type", func(t *testing.T) {
+		spec := &models.VectorIndexSpec{
+			Name: "idx_text", Table: "chunks", Column: "vector",
+			Method: models.VectorIndexIVFFlat, OperatorClass: "vector_l2_ops", Lists: 100, VectorType: "text",
+		}
+		query, err := buildCreateVectorIndexSQL(spec, "idx_text_rebuild")
+		require.NoError(t, err)
+		assert.Equal(t, "CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_text_rebuild ON chunks USING ivfflat (vector vector_l2_ops) WITH (lists = 100) WHERE vector_type = 'text' AND vector IS NOT NULL", query)
+	})
+
+	t.Run("rejects unsafe input", func(t *testing.T) {
+		base := models.VectorIndexSpec{
+			Name: "idx", Table: "chunks", Column: "vector",
+			Method: models.VectorIndexIVFFlat, OperatorClass: "vector_cosine_ops", Lists: 10,
+		}
+
+		badTable := base
+		badTable.Table = "chunks; DROP TABLE chunks"
+		_, err := buildCreateVectorIndexSQL(&badTable, badTable.Name)
+		assert.Error(t, err)
+
+		badOps := base
+		badOps.OperatorClass = "text_ops"
+		_, err = buildCreateVectorIndexSQL(&badOps, badOps.Name)
+		assert.Error(t, err)
+
+		badMethod := base
+		badMethod.Method = "btree"
+		_, err = buildCreateVectorIndexSQL(&badMethod, badMethod.Name)
+		assert.Error(t, err)
+	})
+}
+
+func TestRecommendedIVFFlatLists(t *testing.T) {
+	assert.Equal(t, 1, recommendedIVFFlatLists(0))
+	assert.Equal(t, 1, recommendedIVFFlatLists(500))
+	assert.Equal(t, 250, recommendedIVFFlatLists(250000))
+	assert.Equal(t, 2000, recommendedIVFFlatLists(4000000))
+}
+
+func TestVectorIndexSuggestions(t *testing.T) {
+	defaults := &models.VectorIndexSpec{
+		Name: "idx_chunks_vector_ivfflat", Table: "chunks", Column: "vector",
+		Method: models.VectorIndexIVFFlat, OperatorClass: "vector_cosine_ops", M: 16, EfConstruction: 64, Lists: 100,
+	}
+
+	t.Run("missing index", func(t *testing.T) {
+		suggestions := vectorIndexSuggestions(nil, 50000, defaults)
+		require.Len(t, suggestions, 1)
+		assert.Equal(t, "high", suggestions[0].Priority)
+		assert.Contains(t, suggestions[0].SQLCommand, "lists = 50")
+	})
+
+	t.Run("well tuned ivfflat", func(t *testing.T) {
+		indexes := []models.VectorIndexInfo{{
+			Name: "idx_text", Table: "chunks", Method: models.VectorIndexIVFFlat,
+			Parameters: map[string]string{"lists": "100"}, IsValid: true,
+		}}
+		assert.Empty(t, vectorIndexSuggestions(indexes, 100000, defaults))
+	})
+
+	t.Run("mistuned lists and invalid index", func(t *testing.T) {
+		indexes := []models.VectorIndexInfo{
+			{Name: "idx_text", Table: "chunks", Method: models.VectorIndexIVFFlat, Parameters: map[string]string{"lists": "10"}, IsValid: true},
+			{Name: "idx_broken", Table: "chunks", Method: models.VectorIndexHNSW, IsValid: false},
+		}
+		suggestions := vectorIndexSuggestions(indexes, 100000, defaults)
+		require.Len(t, suggestions, 2)
+		assert.Equal(t, "idx_text", suggestions[0].IndexName)
+		assert.Equal(t, "REINDEX INDEX CONCURRENTLY idx_broken;", suggestions[1].SQLCommand)
+	})
+
+	t.Run("large ivfflat suggests hnsw", func(t *testing.T) {
+		indexes := []models.VectorIndexInfo{{
+			Name: "idx_text", Table: "chunks", Method: models.VectorIndexIVFFlat,
+			Parameters: map[string]string{"lists": "1500"}, IsValid: true,
+		}}
+		suggestions := vectorIndexSuggestions(indexes, 2000000, defaults)
+		require.Len(t, suggestions, 1)
+		assert.Equal(t, "hnsw", suggestions[0].IndexType)
+	})
+}
+
+func TestParseIndexOptions(t *testing.T) {
+	params := parseIndexOptions([]string{"m=16", "ef_construction=64", "invalid"})
+	assert.Equal(t, map[string]string{"m": "16", "ef_construction": "64"}, params)
+}